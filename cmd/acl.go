@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addACLFlags adds the chunk ACL flags to serve.
+func addACLFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("acl", false, "Enforce per-chunk ACLs from metadata against the request identity (deny by default)")
+	cmd.Flags().String("acl-groups-field", contextlab.DefaultACLGroupsField, "Metadata field listing the groups allowed to see a chunk")
+	cmd.Flags().String("acl-users-field", contextlab.DefaultACLUsersField, "Metadata field listing the users allowed to see a chunk")
+
+	_ = viper.BindPFlag("acl.enabled", cmd.Flags().Lookup("acl"))
+	_ = viper.BindPFlag("acl.groups_field", cmd.Flags().Lookup("acl-groups-field"))
+	_ = viper.BindPFlag("acl.users_field", cmd.Flags().Lookup("acl-users-field"))
+}
+
+// aclConfig returns the ACL the flags ask for.
+func aclConfig() contextlab.ACL {
+	return contextlab.ACL{
+		Enabled:     viper.GetBool("acl.enabled"),
+		GroupsField: viper.GetString("acl.groups_field"),
+		UsersField:  viper.GetString("acl.users_field"),
+	}
+}
+
+// IdentityRequest is the caller identity in a retrieval request, checked
+// against chunk ACLs when the server enforces them. The server trusts it
+// as given, so it should be set by an authenticating gateway rather than
+// by end users.
+type IdentityRequest struct {
+	User   string   `json:"user,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// identity converts r, returning nil for a missing identity.
+func (r *IdentityRequest) identity() *types.Identity {
+	if r == nil {
+		return nil
+	}
+	return &types.Identity{User: r.User, Groups: r.Groups}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/analytics"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/history"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/spf13/viper"
+)
+
+// analyticsExporter starts the exporter configured under analytics, or
+// returns nil when analytics.sink is empty.
+func analyticsExporter() (*analytics.Exporter, error) {
+	var sink analytics.Sink
+	var err error
+	switch name := viper.GetString("analytics.sink"); name {
+	case "":
+		return nil, nil
+	case "clickhouse":
+		sink, err = analytics.NewClickHouse(analytics.ClickHouseConfig{
+			URL:      viper.GetString("analytics.clickhouse.url"),
+			Database: viper.GetString("analytics.clickhouse.database"),
+			Table:    viper.GetString("analytics.clickhouse.table"),
+			User:     viper.GetString("analytics.clickhouse.user"),
+			Password: viper.GetString("analytics.clickhouse.password"),
+		})
+	case "bigquery":
+		sink, err = analytics.NewBigQuery(analytics.BigQueryConfig{
+			Project:     viper.GetString("analytics.bigquery.project"),
+			Dataset:     viper.GetString("analytics.bigquery.dataset"),
+			Table:       viper.GetString("analytics.bigquery.table"),
+			AccessToken: viper.GetString("analytics.bigquery.access_token"),
+		})
+	default:
+		err = fmt.Errorf("unsupported sink %q (supported: clickhouse, bigquery)", name)
+	}
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("analytics: %w", err))
+	}
+
+	return analytics.NewExporter(sink, analytics.Config{
+		SampleRate:    viper.GetFloat64("analytics.sample_rate"),
+		BatchSize:     viper.GetInt("analytics.batch_size"),
+		FlushInterval: viper.GetDuration("analytics.flush_interval"),
+		Buffer:        viper.GetInt("analytics.buffer"),
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: analytics export failed: %v\n", err)
+		},
+	}), nil
+}
+
+// closeAnalytics flushes e and reports records it could not export.
+func closeAnalytics(e *analytics.Exporter) {
+	if e == nil {
+		return
+	}
+	_ = e.Close()
+	if lost := e.Dropped() + e.Failed(); lost > 0 {
+		fmt.Fprintf(os.Stderr, "Analytics: %d records exported, %d dropped, %d failed\n", e.Written(), e.Dropped(), e.Failed())
+	}
+}
+
+// exportRetrieve exports a served /v1/retrieve or /v1/similar request.
+func (s *Server) exportRetrieve(endpoint string, req *types.RetrievalRequest, result *types.BrokerResult) {
+	if s.analytics == nil {
+		return
+	}
+	settings := s.retrieveSettings(req)
+	st := result.Stats
+	s.analytics.Record(analytics.Record{
+		Time:         time.Now().Add(-st.TotalLatency),
+		Endpoint:     endpoint,
+		Namespace:    req.Namespace,
+		Retrieved:    st.Retrieved,
+		Clusters:     st.Clustered,
+		Returned:     st.Returned,
+		Tokens:       st.Tokens,
+		CacheHit:     st.CacheHit,
+		BestEffort:   st.BestEffort,
+		TotalMs:      ms(st.TotalLatency),
+		RetrievalMs:  ms(st.RetrievalLatency),
+		ClusteringMs: ms(st.ClusteringLatency),
+		RerankMs:     ms(st.RerankLatency),
+		Fingerprint:  history.Fingerprint(settings),
+		Config:       settingsJSON(settings),
+	})
+}
+
+// exportDedupe exports a /v1/dedupe or /v1/dedupe/stream request.
+func (s *APIServer) exportDedupe(endpoint string, start time.Time, input, output, clusters int, settings map[string]interface{}) {
+	if s.analytics == nil {
+		return
+	}
+	s.analytics.Record(analytics.Record{
+		Time:        start,
+		Endpoint:    endpoint,
+		Retrieved:   input,
+		Clusters:    clusters,
+		Returned:    output,
+		TotalMs:     ms(time.Since(start)),
+		Fingerprint: history.Fingerprint(settings),
+		Config:      settingsJSON(settings),
+	})
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func settingsJSON(settings map[string]interface{}) string {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -10,10 +10,14 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/history"
 	"github.com/Siddhant-K-code/distill/pkg/dedup"
 	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 var analyzeCmd = &cobra.Command{
@@ -28,7 +32,17 @@ Example:
 The threshold controls duplicate sensitivity:
   - 0.01: Very strict (only near-identical vectors)
   - 0.05: Balanced (recommended default)
-  - 0.10: Loose (more aggressive deduplication)`,
+  - 0.10: Loose (more aggressive deduplication)
+
+For files too large to fit in memory, --sample streams the file and
+reservoir-samples N vectors, analyzes the sample, and extrapolates the
+duplicate rate to the whole file with a confidence interval:
+  distill analyze --file export.jsonl --sample 50000
+
+--groups streams each duplicate group (the kept vector, its removed
+duplicates and their distances) as JSONL while clusters are pruned,
+instead of holding every group in memory until the report:
+  distill analyze --file export.jsonl --groups groups.jsonl`,
 	RunE: runAnalyze,
 }
 
@@ -40,21 +54,49 @@ func init() {
 	analyzeCmd.Flags().IntP("clusters", "k", 0, "number of clusters (0 = auto: sqrt(N/2))")
 	analyzeCmd.Flags().IntP("workers", "w", 0, "number of parallel workers (0 = NumCPU)")
 	analyzeCmd.Flags().Int64("seed", 0, "random seed for reproducibility (0 = random)")
+	analyzeCmd.Flags().Int("sample", 0, "reservoir-sample N vectors instead of loading the whole file (0 = disabled)")
+	analyzeCmd.Flags().Float64("confidence", 0.95, "confidence level for extrapolated duplicate rates (with --sample)")
+	analyzeCmd.Flags().Bool("progress", false, "show a progress bar with ETA on stderr (default: when stderr is a terminal)")
+	analyzeCmd.Flags().String("groups", "", "stream duplicate groups (kept ID, removed IDs, distances) as JSONL to this file")
+
+	addHistoryFlags(analyzeCmd)
 
 	_ = analyzeCmd.MarkFlagRequired("file")
 
 	_ = viper.BindPFlag("analyze.threshold", analyzeCmd.Flags().Lookup("threshold"))
 	_ = viper.BindPFlag("analyze.clusters", analyzeCmd.Flags().Lookup("clusters"))
+	_ = viper.BindPFlag("analyze.sample", analyzeCmd.Flags().Lookup("sample"))
 }
 
-func runAnalyze(cmd *cobra.Command, args []string) error {
+func runAnalyze(cmd *cobra.Command, args []string) (err error) {
 	filePath, _ := cmd.Flags().GetString("file")
 	threshold, _ := cmd.Flags().GetFloat64("threshold")
 	clusters, _ := cmd.Flags().GetInt("clusters")
 	workers, _ := cmd.Flags().GetInt("workers")
 	seed, _ := cmd.Flags().GetInt64("seed")
+	sampleSize := viper.GetInt("analyze.sample")
+	confidence, _ := cmd.Flags().GetFloat64("confidence")
+	showProgress := progressEnabled(cmd)
+	groupsPath, _ := cmd.Flags().GetString("groups")
 	verbose := viper.GetBool("verbose")
 
+	if sampleSize < 0 {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("--sample must be non-negative"))
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("--confidence must be between 0 and 1 (exclusive)"))
+	}
+
+	job, err := startHistoryJob(cmd, history.KindAnalyze, filePath, map[string]interface{}{
+		"threshold": threshold,
+		"clusters":  clusters,
+		"sample":    sampleSize,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { job.finish(err) }()
+
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -74,9 +116,19 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	}
 
 	loadStart := time.Now()
-	vectors, err := loadVectorsFromFile(filePath)
+	var vectors []types.Vector
+	var population int64
+	if sampleSize > 0 {
+		reservoir := dedup.NewReservoir(sampleSize, seed)
+		err = scanVectorsFromFile(filePath, reservoir.Add)
+		vectors = reservoir.Sample()
+		population = reservoir.Seen()
+	} else {
+		vectors, err = loadVectorsFromFile(filePath)
+		population = int64(len(vectors))
+	}
 	if err != nil {
-		return fmt.Errorf("failed to load vectors: %w", err)
+		return fmt.Errorf("failed to load vectors: %w", errs.Wrap(errs.ErrConfig, err))
 	}
 	loadDuration := time.Since(loadStart)
 
@@ -86,7 +138,11 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	}
 
 	if verbose {
-		fmt.Fprintf(os.Stderr, "Loaded %d vectors in %v\n", len(vectors), loadDuration)
+		if sampleSize > 0 {
+			fmt.Fprintf(os.Stderr, "Sampled %d of %d vectors in %v\n", len(vectors), population, loadDuration)
+		} else {
+			fmt.Fprintf(os.Stderr, "Loaded %d vectors in %v\n", len(vectors), loadDuration)
+		}
 		fmt.Fprintf(os.Stderr, "Vector dimension: %d\n", vectors[0].Dimension())
 	}
 
@@ -99,6 +155,25 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		Seed:          seed,
 	}
 
+	var bar *progressbar.ProgressBar
+	if showProgress {
+		bar = newDedupProgressBar()
+		cfg.OnProgress = func(p dedup.Progress) {
+			updateDedupProgressBar(bar, p)
+		}
+	}
+
+	var groups *dedup.GroupWriter
+	if groupsPath != "" {
+		f, err := os.Create(groupsPath)
+		if err != nil {
+			return fmt.Errorf("failed to create groups file: %w", errs.Wrap(errs.ErrConfig, err))
+		}
+		defer f.Close()
+		groups = dedup.NewGroupWriter(f)
+		cfg.OnGroup = groups.Write
+	}
+
 	engine := dedup.NewEngine(cfg)
 
 	// Run deduplication
@@ -107,24 +182,99 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	}
 
 	result, err := engine.Deduplicate(ctx, vectors)
+	if bar != nil {
+		_ = bar.Finish()
+		fmt.Fprintln(os.Stderr)
+	}
 	if err != nil {
 		return fmt.Errorf("deduplication failed: %w", err)
 	}
+	if groups != nil {
+		if err := groups.Flush(); err != nil {
+			return fmt.Errorf("failed to write groups: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d duplicate groups to %s\n", groups.Count(), groupsPath)
+	}
+
+	job.counts(len(vectors), len(result.UniqueVectors))
+	job.detail("population", population)
+	job.detail("duplicates", result.DuplicateCount)
+	job.detail("clusters", result.ClusterCount)
 
 	// Print report
+	if sampleSize > 0 && population > int64(len(vectors)) {
+		printSampledAnalysisReport(dedup.EstimateDuplicates(result, population, confidence), result)
+		return nil
+	}
 	printAnalysisReport(result, verbose)
 
 	return nil
 }
 
+// progressEnabled reports whether to draw a progress bar: as --progress says
+// when it is given, otherwise only when stderr is a terminal, so redirected
+// stderr and CI logs do not fill with carriage-return redraws.
+func progressEnabled(cmd *cobra.Command) bool {
+	if cmd.Flags().Changed("progress") {
+		on, _ := cmd.Flags().GetBool("progress")
+		return on
+	}
+	return term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// dedupProgressScale is the progress bar resolution (per-mille).
+const dedupProgressScale = 1000
+
+func newDedupProgressBar() *progressbar.ProgressBar {
+	return progressbar.NewOptions(
+		dedupProgressScale,
+		progressbar.OptionSetDescription("Clustering"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionSetPredictTime(false),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+}
+
+func updateDedupProgressBar(bar *progressbar.ProgressBar, p dedup.Progress) {
+	var desc string
+	switch p.Phase {
+	case dedup.PhaseAssign:
+		desc = fmt.Sprintf("Clustering (iter %d/%d, %d/%d vectors)",
+			p.Iteration, p.MaxIterations, p.VectorsAssigned, p.TotalVectors)
+	case dedup.PhasePrune:
+		desc = fmt.Sprintf("Pruning (%d/%d clusters)", p.ClustersPruned, p.TotalClusters)
+	default:
+		desc = "Done"
+	}
+	if eta := p.ETA(); eta > 0 {
+		desc += fmt.Sprintf(" ETA %v", eta.Round(time.Second))
+	}
+	bar.Describe(desc)
+	_ = bar.Set(int(p.Fraction() * dedupProgressScale))
+}
+
 func loadVectorsFromFile(filePath string) ([]types.Vector, error) {
-	file, err := os.Open(filePath)
+	var vectors []types.Vector
+	err := scanVectorsFromFile(filePath, func(v types.Vector) {
+		vectors = append(vectors, v)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return vectors, nil
+}
+
+// scanVectorsFromFile streams vectors from a JSONL file, calling fn for each
+// valid line without holding the whole file in memory.
+func scanVectorsFromFile(filePath string, fn func(types.Vector)) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
 	defer func() { _ = file.Close() }()
 
-	var vectors []types.Vector
 	scanner := bufio.NewScanner(file)
 
 	// Increase buffer for large lines
@@ -147,7 +297,7 @@ func loadVectorsFromFile(filePath string) ([]types.Vector, error) {
 
 		if err := json.Unmarshal(line, &v); err != nil {
 			// Skip malformed lines but warn
-			fmt.Fprintf(os.Stderr, "Warning: skipping malformed line %d: %v\n", lineNum, err)
+			fmt.Fprintf(os.Stderr, "Warning: skipping malformed line %d of %s: %v\n", lineNum, filePath, err)
 			continue
 		}
 
@@ -155,18 +305,14 @@ func loadVectorsFromFile(filePath string) ([]types.Vector, error) {
 			continue
 		}
 
-		vectors = append(vectors, types.Vector{
+		fn(types.Vector{
 			ID:       v.ID,
 			Values:   v.Values,
 			Metadata: v.Metadata,
 		})
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	return vectors, nil
+	return scanner.Err()
 }
 
 func printAnalysisReport(result *types.DeduplicationResult, verbose bool) {
@@ -188,3 +334,22 @@ func printAnalysisReport(result *types.DeduplicationResult, verbose bool) {
 		fmt.Println("No duplicates found. Your dataset is already unique.")
 	}
 }
+
+func printSampledAnalysisReport(est dedup.Estimate, result *types.DeduplicationResult) {
+	fmt.Println()
+	fmt.Println("=== Semantic Deduplication Analysis (sampled) ===")
+	fmt.Println()
+	fmt.Printf("Total vectors in file:   %d\n", est.Population)
+	fmt.Printf("Vectors sampled:         %d (%.2f%%)\n", est.SampleSize, float64(est.SampleSize)/float64(est.Population)*100)
+	fmt.Printf("Duplicates in sample:    %d\n", result.DuplicateCount)
+	fmt.Println()
+	fmt.Printf("Estimated duplicate rate: %.1f%% (%.0f%% CI: %.1f%% - %.1f%%)\n",
+		est.DuplicateRate*100, est.Confidence*100, est.RateLow*100, est.RateHigh*100)
+	fmt.Printf("Estimated duplicates:     %d (%d - %d)\n", est.EstimatedDuplicates, est.EstimatedLow, est.EstimatedHigh)
+	fmt.Println()
+	fmt.Printf("Clusters used:           %d\n", result.ClusterCount)
+	fmt.Printf("Processing time:         %dms\n", result.ProcessingTimeMs)
+	fmt.Println()
+	fmt.Println("Note: duplicates whose partners fall outside the sample are not observed,")
+	fmt.Println("so the true rate may be higher. Increase --sample for a tighter estimate.")
+}
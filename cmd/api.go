@@ -4,6 +4,7 @@ import (
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,18 +13,29 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/analytics"
 	distillcache "github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/capture"
+	"github.com/Siddhant-K-code/distill/pkg/compress"
 	"github.com/Siddhant-K-code/distill/pkg/contextlab"
 	"github.com/Siddhant-K-code/distill/pkg/embedding"
 	_ "github.com/Siddhant-K-code/distill/pkg/embedding/cohere"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/fake"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/local"
 	_ "github.com/Siddhant-K-code/distill/pkg/embedding/ollama"
 	_ "github.com/Siddhant-K-code/distill/pkg/embedding/openai"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/voyage"
+	"github.com/Siddhant-K-code/distill/pkg/grpcapi/distillv1"
+	"github.com/Siddhant-K-code/distill/pkg/history"
 	"github.com/Siddhant-K-code/distill/pkg/metrics"
+	"github.com/Siddhant-K-code/distill/pkg/models"
 	"github.com/Siddhant-K-code/distill/pkg/sse"
 	"github.com/Siddhant-K-code/distill/pkg/telemetry"
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"google.golang.org/protobuf/proto"
 )
 
 //go:embed openapi.yaml
@@ -50,14 +62,25 @@ func init() {
 
 	apiCmd.Flags().IntP("port", "p", 8080, "HTTP server port")
 	apiCmd.Flags().String("host", "0.0.0.0", "HTTP server host")
-	apiCmd.Flags().String("openai-key", "", "OpenAI API key for embeddings (or use OPENAI_API_KEY)")
-	apiCmd.Flags().String("embedding-provider", "openai", "Embedding provider (openai, ollama, cohere)")
-	apiCmd.Flags().String("embedding-model", "text-embedding-3-small", "Embedding model name")
+	apiCmd.Flags().String("openai-key", "", "API key for embeddings (or use OPENAI_API_KEY / COHERE_API_KEY / VOYAGE_API_KEY)")
+	apiCmd.Flags().String("embedding-provider", "openai", "Embedding provider (openai, ollama, cohere, voyage, local, fake)")
+	apiCmd.Flags().String("embedding-model", "text-embedding-3-small", "Embedding model name (local: model directory)")
 	apiCmd.Flags().String("embedding-base-url", "", "Embedding provider base URL (e.g. http://localhost:11434 for Ollama)")
 	apiCmd.Flags().String("api-keys", "", "Comma-separated list of valid API keys (or use DISTILL_API_KEYS)")
 	apiCmd.Flags().Bool("memory", false, "Enable persistent memory store")
 	apiCmd.Flags().Bool("session", false, "Enable session management")
 	apiCmd.Flags().String("session-db", "distill-sessions.db", "SQLite database path for session store")
+	apiCmd.Flags().Duration("sent-ttl", distillcache.DefaultSentTTL, "How long a chunk counts as already sent within a session")
+
+	// Runtime tuning, input limits, capture, and HTTP transport
+	addRuntimeFlags(apiCmd)
+	addProfilingFlags(apiCmd)
+	addLimitFlags(apiCmd)
+	addCaptureFlags(apiCmd)
+	addHistoryFlags(apiCmd)
+	addHTTPFlags(apiCmd)
+	addListenFlags(apiCmd)
+	addEmbeddingOutputFlags(apiCmd)
 
 	// Bind to viper for config file support
 	_ = viper.BindPFlag("server.port", apiCmd.Flags().Lookup("port"))
@@ -74,6 +97,31 @@ type DedupeRequest struct {
 	Lambda    float64       `json:"lambda,omitempty"`
 	TargetK   int           `json:"target_k,omitempty"`
 	Options   DedupeOptions `json:"options,omitempty"`
+
+	// TokenBudget, when positive, replaces TargetK: representatives are
+	// selected until their tokens would exceed it. Model names
+	// a models.profiles entry whose tokenizer counts the tokens, and whose
+	// budget applies when TokenBudget is unset.
+	TokenBudget int    `json:"token_budget,omitempty"`
+	Model       string `json:"model,omitempty"`
+
+	// Compress compresses the deduplicated chunks after selection. The
+	// frozen cache prefix is never compressed.
+	Compress *CompressRequest `json:"compress,omitempty"`
+
+	// SessionID enables cross-request dedup: chunks already returned to
+	// this session within the sent TTL are dropped before clustering.
+	SessionID string `json:"session_id,omitempty"`
+
+	// Redact replaces secrets and PII in the returned chunks with typed
+	// placeholders, such as [EMAIL], listing each span's offsets under the
+	// chunk's redactions metadata.
+	Redact bool `json:"redact,omitempty"`
+
+	// Encoding is how the chunks' embeddings are encoded: float32 (the
+	// default), float64, or base64 for little-endian float32 bytes. An
+	// embedding of another form is rejected; see checkBodyEncoding.
+	Encoding string `json:"encoding,omitempty"`
 }
 
 // DedupeOptions controls optional dedup behaviour.
@@ -82,13 +130,28 @@ type DedupeOptions struct {
 	// so the dedup pipeline cannot reorder or remove them. This prevents
 	// Distill from silently invalidating Anthropic prompt cache prefixes.
 	PreserveCachePrefix bool `json:"preserve_cache_prefix,omitempty"`
+
+	// MarkRepeats keeps chunks already sent to the session and flags them
+	// with already_sent instead of dropping them.
+	MarkRepeats bool `json:"mark_repeats,omitempty"`
+
+	// ValidateEmbeddings checks supplied embeddings for a wrong dimension
+	// or values unlike the rest of the request: "reject" fails the
+	// request, "repair" re-embeds suspect chunks from their text.
+	ValidateEmbeddings string `json:"validate_embeddings,omitempty"`
+
+	// DedupHints adds dedup_cluster_size, dedup_duplicates_removed, and
+	// dedup_representative_reason to each deduped chunk's metadata.
+	DedupHints bool `json:"dedup_hints,omitempty"`
+
+	EmbeddingOptions
 }
 
 // DedupeChunk represents a chunk in the request.
 type DedupeChunk struct {
 	ID           string    `json:"id"`
 	Text         string    `json:"text"`
-	Embedding    []float32 `json:"embedding,omitempty"`
+	Embedding    Embedding `json:"embedding,omitzero"`
 	Score        float32   `json:"score,omitempty"`
 	// CacheControl mirrors the Anthropic cache_control field. When non-empty,
 	// this chunk is treated as a cache boundary marker. Used with
@@ -108,6 +171,16 @@ type DedupeChunkResponse struct {
 	Text      string  `json:"text"`
 	Score     float32 `json:"score"`
 	ClusterID int     `json:"cluster_id"`
+	// AlreadySent is set when options.mark_repeats kept a chunk that was
+	// already returned to the session.
+	AlreadySent bool `json:"already_sent,omitempty"`
+	// Embedding is set when options.include_embeddings is requested,
+	// reduced to options.embedding_dims if given.
+	Embedding []float32 `json:"embedding,omitempty"`
+	// Metadata holds the dedup hints when options.dedup_hints is set, and
+	// the redacted spans when redact is. Chunks in a frozen cache prefix
+	// have no hints.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // DedupeStats contains processing statistics.
@@ -124,6 +197,28 @@ type DedupeStats struct {
 	CachePrefixHash   string `json:"cache_prefix_hash,omitempty"`
 	SuffixInputCount  int    `json:"suffix_input_count,omitempty"`
 	SuffixOutputCount int    `json:"suffix_output_count,omitempty"`
+
+	// RepeatedCount is the number of input chunks already sent to the
+	// session. Populated when session_id is set.
+	RepeatedCount int `json:"repeated_count,omitempty"`
+
+	// EmbeddingsRepaired is the number of supplied embeddings replaced
+	// because options.validate_embeddings found them suspect.
+	EmbeddingsRepaired int `json:"embeddings_repaired,omitempty"`
+
+	// MatrixOverflow is "spill" or "window" when the input's distance
+	// matrix exceeded limits.max_matrix_bytes.
+	MatrixOverflow string `json:"matrix_overflow,omitempty"`
+
+	// Tokens is the tokens of the deduplicated chunks, set when
+	// a token budget applied.
+	Tokens int `json:"tokens,omitempty"`
+
+	// Compression is set when compress was requested.
+	Compression *CompressionStats `json:"compression,omitempty"`
+
+	// Redacted counts the spans redact replaced.
+	Redacted int `json:"redacted,omitempty"`
 }
 
 // APIServer holds the API server state.
@@ -133,9 +228,21 @@ type APIServer struct {
 	hasAuth   bool
 	metrics   *metrics.Metrics
 	tracing   *telemetry.Provider
+	sent      *distillcache.SentFilter
+	limits    contextlab.Limits
+	matrix    contextlab.MatrixBudget
+	captures  *capture.Recorder
+	history   *history.Writer
+	analytics *analytics.Exporter
+	embedOut  embeddingOutput
+	models    *models.Registry
 }
 
 func runAPI(cmd *cobra.Command, args []string) error {
+	if err := applyRuntimeTuning(cmd); err != nil {
+		return err
+	}
+
 	// Config file values are used as fallbacks via viper bindings
 	port := viper.GetInt("server.port")
 	host := viper.GetString("server.host")
@@ -150,51 +257,21 @@ func runAPI(cmd *cobra.Command, args []string) error {
 	}
 
 	// Resolve from environment
-	if openaiKey == "" {
-		openaiKey = os.Getenv("OPENAI_API_KEY")
-	}
 	if apiKeysStr == "" {
 		apiKeysStr = os.Getenv("DISTILL_API_KEYS")
 	}
 
-	// Parse API keys
-	validKeys := make(map[string]bool)
-	if apiKeysStr != "" {
-		for _, key := range strings.Split(apiKeysStr, ",") {
-			key = strings.TrimSpace(key)
-			if key != "" {
-				validKeys[key] = true
-			}
-		}
-	}
+	validKeys := parseAPIKeys(apiKeysStr)
 
-	// Create embedding provider via registry
-	var embedder embedding.Provider
-	needsAPIKey := embeddingProvider == "" || embeddingProvider == "openai" || embeddingProvider == "cohere"
-	if needsAPIKey && openaiKey == "" {
-		// No API key and cloud provider selected — embeddings disabled
-	} else {
-		apiKey := openaiKey
-		if embeddingProvider == "cohere" && apiKey == "" {
-			apiKey = os.Getenv("COHERE_API_KEY")
-		}
-		if embeddingProvider == "" {
-			embeddingProvider = "openai"
-		}
-		var err error
-		embedder, err = embedding.NewProvider(embedding.ProviderConfig{
-			Type:      embedding.ProviderType(embeddingProvider),
-			APIKey:    apiKey,
-			Model:     embeddingModel,
-			BaseURL:   embeddingBaseURL,
-			CacheSize: -1, // caching handled at a higher layer
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create embedding provider: %w", err)
-		}
+	// Create embedding provider via registry; a cloud provider without
+	// an API key leaves embeddings disabled
+	embedder, err := newEmbedder(embeddingProvider, openaiKey, embeddingModel, embeddingBaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to create embedding provider: %w", err)
 	}
 
 	m := metrics.New()
+	defer startUsage("api", "", usageProvider(embeddingProvider, embedder != nil), m).Stop()
 
 	// Initialize tracing
 	tracingCfg := telemetry.DefaultConfig()
@@ -219,12 +296,78 @@ func runAPI(cmd *cobra.Command, args []string) error {
 		_ = tp.Shutdown(shutdownCtx)
 	}()
 
+	stopProfiling, err := startProfiling(cmd)
+	if err != nil {
+		return err
+	}
+	defer stopProfiling()
+
+	limits, err := inputLimits(cmd)
+	if err != nil {
+		return err
+	}
+	matrix, err := matrixBudget(cmd)
+	if err != nil {
+		return err
+	}
+	captures, err := captureRecorder(cmd, false)
+	if err != nil {
+		return err
+	}
+	historyW, err := historyWriter(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = historyW.Close() }()
+
+	exporter, err := analyticsExporter()
+	if err != nil {
+		return err
+	}
+	defer closeAnalytics(exporter)
+
+	httpOpts, err := resolveHTTPOptions(cmd)
+	if err != nil {
+		return err
+	}
+	target, err := resolveListenTarget(cmd, host, port, true)
+	if err != nil {
+		return err
+	}
+	renderer, err := newRenderer(viper.GetString("render.default"))
+	if err != nil {
+		return err
+	}
+	modelProfiles, err := modelRegistry(renderer)
+	if err != nil {
+		return err
+	}
+	embedOut, err := resolveEmbeddingOutput(cmd)
+	if err != nil {
+		return err
+	}
+
+	sentTTL, _ := cmd.Flags().GetDuration("sent-ttl")
+	sentCache, err := sessionCache()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sentCache.Close() }()
+
 	server := &APIServer{
 		embedder:  embedder,
 		validKeys: validKeys,
 		hasAuth:   len(validKeys) > 0,
 		metrics:   m,
 		tracing:   tp,
+		sent:      distillcache.NewSentFilter(sentCache, sentTTL),
+		limits:    limits,
+		matrix:    matrix,
+		captures:  captures,
+		history:   historyW,
+		analytics: exporter,
+		embedOut:  embedOut,
+		models:    modelProfiles,
 	}
 
 	// Setup routes
@@ -272,8 +415,14 @@ func runAPI(cmd *cobra.Command, args []string) error {
 
 	// Pipeline and batch routes.
 	pipelineAPI := NewPipelineAPI()
+	pipelineAPI.limits = limits
+	pipelineAPI.metrics = m
 	pipelineAPI.RegisterPipelineRoutes(mux, m.Middleware)
 
+	if captures != nil {
+		mux.HandleFunc("/debug/captures", server.requireAuth(captures.Handler()))
+	}
+
 	mux.HandleFunc("/health", server.handleHealth)
 	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
 		m.Handler().ServeHTTP(w, r)
@@ -286,14 +435,20 @@ func runAPI(cmd *cobra.Command, args []string) error {
 	handler := corsMiddleware(mux)
 
 	// Create HTTP server
-	addr := fmt.Sprintf("%s:%d", host, port)
+	addr := target.urlHost()
 	httpServer := &http.Server{
-		Addr:         addr,
+		Addr:         target.addr,
 		Handler:      handler,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 60 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
+	httpOpts.apply(httpServer)
+
+	ln, err := target.listen()
+	if err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
 
 	// Graceful shutdown
 	done := make(chan bool)
@@ -314,20 +469,26 @@ func runAPI(cmd *cobra.Command, args []string) error {
 	}()
 
 	// Start server
-	fmt.Printf("Distill API server starting on %s\n", addr)
+	fmt.Printf("Distill API server starting on %s\n", target)
 	fmt.Printf("  Embeddings: %v\n", embedder != nil)
 	fmt.Printf("  Auth: %v (%d keys)\n", server.hasAuth, len(validKeys))
 	fmt.Printf("  Memory: %v\n", enableMemory)
 	fmt.Printf("  Sessions: %v\n", enableSession)
+	if exporter != nil {
+		fmt.Printf("  Analytics: %s (sample rate %g)\n", viper.GetString("analytics.sink"), exporter.SampleRate())
+	}
 	fmt.Println()
 	fmt.Println("Endpoints:")
 	fmt.Printf("  POST http://%s/v1/dedupe\n", addr)
 	fmt.Printf("  POST http://%s/v1/dedupe/stream\n", addr)
 	fmt.Printf("  GET  http://%s/health\n", addr)
 	fmt.Printf("  GET  http://%s/metrics\n", addr)
+	if captures != nil {
+		fmt.Printf("  GET  http://%s/debug/captures\n", addr)
+	}
 	fmt.Println()
 
-	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+	if err := httpServer.Serve(ln); err != http.ErrServerClosed {
 		return fmt.Errorf("server error: %w", err)
 	}
 
@@ -370,6 +531,12 @@ func (s *APIServer) handleRoot(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// requireAuth rejects requests without a valid API key when auth is
+// enabled. Used for debug endpoints, which can expose chunk text.
+func (s *APIServer) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return requireKey(s.validKeys, next)
+}
+
 func (s *APIServer) handleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/yaml")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -422,9 +589,15 @@ func (s *APIServer) handleDedupe(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	limitBody(w, r, s.limits.MaxInputBytes, s.metrics, "/v1/dedupe")
 	var req DedupeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+	if isProtobuf(r) {
+		var pb distillv1.DeduplicateRequest
+		if !readProtobuf(w, r, &pb) {
+			return
+		}
+		req = dedupeRequestFromProto(&pb)
+	} else if !readBody(w, r, &req) {
 		return
 	}
 
@@ -432,12 +605,50 @@ func (s *APIServer) handleDedupe(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "At least one chunk is required", http.StatusBadRequest)
 		return
 	}
+	if err := s.limits.Check(dedupeChunksToTypes(req.Chunks)); err != nil {
+		writeTooLarge(w, s.metrics, "/v1/dedupe", err)
+		return
+	}
+	if err := s.embedOut.check(req.Options.EmbeddingOptions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkEmbeddingMode(req.Options.ValidateEmbeddings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.TokenBudget < 0 {
+		http.Error(w, "'token_budget' must be non-negative", http.StatusBadRequest)
+		return
+	}
+	model, err := s.models.Resolve(req.Model)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var compressor compress.Compressor
+	var compressOpts compress.Options
+	if req.Compress != nil {
+		if compressor, compressOpts, err = req.Compress.compressor(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	tokenBudget := req.TokenBudget
+	countTokens := tokens.Count
+	if model != nil {
+		if tokenBudget == 0 {
+			tokenBudget = model.Budget()
+		}
+		countTokens = model.Tokens
+	}
 
 	// Start root tracing span
 	ctx, rootSpan := s.tracing.StartRequest(r.Context(), "/v1/dedupe")
 	defer rootSpan.End()
 
 	start := time.Now()
+	var stages []capture.Stage
 
 	// Convert to internal types, preserving cache_control metadata.
 	chunks := make([]types.Chunk, len(req.Chunks))
@@ -446,14 +657,14 @@ func (s *APIServer) handleDedupe(w http.ResponseWriter, r *http.Request) {
 		chunks[i] = types.Chunk{
 			ID:        c.ID,
 			Text:      c.Text,
-			Embedding: c.Embedding,
+			Embedding: c.Embedding.Values,
 			Score:     c.Score,
 			Metadata:  make(map[string]interface{}),
 		}
 		if c.CacheControl != "" {
 			chunks[i].Metadata["cache_control"] = c.CacheControl
 		}
-		if len(c.Embedding) == 0 {
+		if len(c.Embedding.Values) == 0 {
 			needsEmbedding = true
 		}
 	}
@@ -466,14 +677,31 @@ func (s *APIServer) handleDedupe(w http.ResponseWriter, r *http.Request) {
 		dedupChunks = partition.Suffix
 	}
 
+	// Drop (or mark) suffix chunks already sent to this session.
+	dedupChunks, repeated := s.sent.Filter(ctx, req.SessionID, dedupChunks, req.Options.MarkRepeats)
+
+	// Check supplied embeddings. When any chunk lacks one the whole suffix
+	// is re-embedded below, so there is nothing to check.
+	var repaired int
+	if req.Options.ValidateEmbeddings != "" && !needsEmbedding {
+		var err error
+		repaired, err = s.checkChunkEmbeddings(ctx, req.Options.ValidateEmbeddings, dedupChunks)
+		if err != nil {
+			telemetry.RecordError(rootSpan, err)
+			http.Error(w, embeddingCheckMessage(err), embeddingCheckStatus(err))
+			return
+		}
+	}
+
 	// Generate embeddings if needed (only for the dedup-eligible suffix).
-	if needsEmbedding {
+	if needsEmbedding && len(dedupChunks) > 0 {
 		if s.embedder == nil {
 			http.Error(w, "Embeddings required but no embedding provider configured. Either provide embeddings in request or configure OPENAI_API_KEY.", http.StatusBadRequest)
 			return
 		}
 
 		_, embSpan := s.tracing.StartEmbedding(ctx, len(dedupChunks))
+		embStart := time.Now()
 		texts := make([]string, len(dedupChunks))
 		for i, c := range dedupChunks {
 			texts[i] = c.Text
@@ -487,6 +715,7 @@ func (s *APIServer) handleDedupe(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		embSpan.End()
+		stages = append(stages, capture.StageSince("embedding", embStart))
 
 		for i := range dedupChunks {
 			dedupChunks[i].Embedding = embeddings[i]
@@ -509,12 +738,23 @@ func (s *APIServer) handleDedupe(w http.ResponseWriter, r *http.Request) {
 
 	// Cluster the dedup-eligible suffix only.
 	_, clusterSpan := s.tracing.StartClustering(ctx, len(dedupChunks), threshold)
+	clusterStart := time.Now()
 	clusterer := contextlab.NewClusterer(contextlab.ClusterConfig{
 		Threshold: threshold,
 		Linkage:   "average",
+		Matrix:    s.matrix,
 	})
-	clusterResult := clusterer.Cluster(dedupChunks)
+	ctx = contextlab.WithVectorNorms(ctx)
+	clusterResult, err := clusterer.ClusterContext(ctx, dedupChunks)
 	clusterSpan.End()
+	stages = append(stages, capture.StageSince("clustering", clusterStart))
+	if err != nil {
+		telemetry.RecordError(rootSpan, err)
+		if !writeInterrupted(w, err) {
+			http.Error(w, fmt.Sprintf("Clustering failed: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
 
 	// Select representatives
 	_, selectSpan := s.tracing.StartSelection(ctx, clusterResult.ClusterCount)
@@ -524,21 +764,78 @@ func (s *APIServer) handleDedupe(w http.ResponseWriter, r *http.Request) {
 	representatives := selector.Select(clusterResult)
 	selectSpan.End()
 
-	// Apply MMR if we have more representatives than target
-	if targetK > 0 && len(representatives) > targetK {
+	// Cluster-merge compression needs the clusters, so it merges here
+	merged := 0
+	if compressor != nil && compressOpts.Mode == compress.ModeClusterMerge {
+		representatives, merged = contextlab.MergeClusters(representatives, clusterResult, compressOpts)
+	}
+
+	// Apply MMR if we have more representatives than target, or a token
+	// budget to fit
+	if tokenBudget > 0 || (targetK > 0 && len(representatives) > targetK) {
 		_, mmrSpan := s.tracing.StartMMR(ctx, len(representatives), lambda)
+		mmrStart := time.Now()
 		mmrCfg := contextlab.MMRConfig{
-			Lambda:  lambda,
-			TargetK: targetK,
+			Lambda:      lambda,
+			TargetK:     targetK,
+			TokenBudget: tokenBudget,
+			Tokens:      countTokens,
 		}
 		mmr := contextlab.NewMMR(mmrCfg)
-		representatives = mmr.Rerank(representatives)
+		representatives, err = mmr.RerankContext(ctx, representatives)
 		mmrSpan.End()
+		stages = append(stages, capture.StageSince("mmr", mmrStart))
+		if err != nil {
+			telemetry.RecordError(rootSpan, err)
+			if !writeInterrupted(w, err) {
+				http.Error(w, fmt.Sprintf("MMR failed: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	if req.Options.DedupHints {
+		contextlab.AnnotateDedup(representatives, clusterResult, selectorCfg.Strategy)
+	}
+
+	var compression *CompressionStats
+	if compressor != nil && len(representatives) > 0 {
+		compressStart := time.Now()
+		compressed, cs, err := compressor.Compress(ctx, representatives, compressOpts)
+		stages = append(stages, capture.StageSince("compression", compressStart))
+		if err != nil {
+			telemetry.RecordError(rootSpan, err)
+			if !writeInterrupted(w, err) {
+				http.Error(w, fmt.Sprintf("Compression failed: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+		changed := 0
+		for i := range compressed {
+			if compressed[i].Text != representatives[i].Text {
+				changed++
+			}
+		}
+		compression = mergedStats(compressionStats(cs.InputTokens, cs.OutputTokens, changed), merged)
+		representatives = compressed
+	}
+
+	// Redact last, so the recorded offsets hold for the returned text
+	var redacted int
+	if req.Redact {
+		partition.Prefix, redacted = contextlab.RedactTyped(partition.Prefix)
+		var n int
+		representatives, n = contextlab.RedactTyped(representatives)
+		redacted += n
 	}
 
 	// Prepend the frozen prefix to the deduped suffix.
 	finalChunks := append(partition.Prefix, representatives...)
 
+	if err := s.sent.Record(ctx, req.SessionID, representatives); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to record sent chunks: %v\n", err)
+	}
+
 	latency := time.Since(start)
 
 	// Record result on root span
@@ -548,12 +845,18 @@ func (s *APIServer) handleDedupe(w http.ResponseWriter, r *http.Request) {
 	outputChunks := make([]DedupeChunkResponse, len(finalChunks))
 	for i, c := range finalChunks {
 		outputChunks[i] = DedupeChunkResponse{
-			ID:        c.ID,
-			Text:      c.Text,
-			Score:     c.Score,
-			ClusterID: c.ClusterID,
+			ID:          c.ID,
+			Text:        c.Text,
+			Score:       c.Score,
+			ClusterID:   c.ClusterID,
+			AlreadySent: distillcache.IsAlreadySent(c),
+			Metadata:    dedupHints(c),
 		}
 	}
+	embeddings, _ := s.embedOut.embeddings(req.Options.EmbeddingOptions, finalChunks)
+	for i := range embeddings {
+		outputChunks[i].Embedding = embeddings[i]
+	}
 
 	reductionPct := 0
 	if len(req.Chunks) > 0 {
@@ -561,11 +864,22 @@ func (s *APIServer) handleDedupe(w http.ResponseWriter, r *http.Request) {
 	}
 
 	stats := DedupeStats{
-		InputCount:   len(req.Chunks),
-		OutputCount:  len(finalChunks),
-		ClusterCount: clusterResult.ClusterCount,
-		ReductionPct: reductionPct,
-		LatencyMs:    latency.Milliseconds(),
+		InputCount:    len(req.Chunks),
+		OutputCount:   len(finalChunks),
+		ClusterCount:  clusterResult.ClusterCount,
+		ReductionPct:  reductionPct,
+		LatencyMs:     latency.Milliseconds(),
+		RepeatedCount: repeated,
+
+		EmbeddingsRepaired: repaired,
+		MatrixOverflow:     clusterResult.Overflow,
+		Compression:        compression,
+		Redacted:           redacted,
+	}
+	if tokenBudget > 0 {
+		for _, c := range representatives {
+			stats.Tokens += countTokens(c.Text)
+		}
 	}
 	if req.Options.PreserveCachePrefix && partition.MarkerCount > 0 {
 		stats.CachePrefixFrozen = true
@@ -582,9 +896,30 @@ func (s *APIServer) handleDedupe(w http.ResponseWriter, r *http.Request) {
 
 	// Record dedup-specific metrics
 	s.metrics.RecordDedup("/v1/dedupe", len(req.Chunks), len(finalChunks), clusterResult.ClusterCount)
+	s.recordDedupe("/v1/dedupe", start, len(req.Chunks), len(finalChunks), clusterResult.ClusterCount, threshold, lambda, targetK, req.Options.PreserveCachePrefix)
+
+	if reasons := s.captures.Anomalies(latency, len(req.Chunks), len(finalChunks)); reasons != nil {
+		s.captures.Record(capture.Trace{
+			Endpoint:     "/v1/dedupe",
+			Reasons:      reasons,
+			LatencyMs:    float64(latency.Microseconds()) / 1000,
+			InputCount:   len(req.Chunks),
+			OutputCount:  len(finalChunks),
+			ClusterCount: clusterResult.ClusterCount,
+			ReductionPct: reductionPct,
+			Params: map[string]interface{}{
+				"threshold":             threshold,
+				"lambda":                lambda,
+				"target_k":              targetK,
+				"preserve_cache_prefix": req.Options.PreserveCachePrefix,
+				"session":               req.SessionID != "",
+			},
+			Stages: stages,
+			Chunks: dedupeTrace(partition.Prefix, dedupChunks, finalChunks),
+		})
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(resp)
+	writeResponse(w, r, resp, func() (proto.Message, error) { return dedupeResponseProto(resp) })
 }
 
 func (s *APIServer) handleDedupeStream(w http.ResponseWriter, r *http.Request) {
@@ -607,9 +942,10 @@ func (s *APIServer) handleDedupeStream(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	limitBody(w, r, s.limits.MaxInputBytes, s.metrics, "/v1/dedupe/stream")
 	var req DedupeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		writeBodyError(w, "JSON", err)
 		return
 	}
 
@@ -617,6 +953,14 @@ func (s *APIServer) handleDedupeStream(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "At least one chunk is required", http.StatusBadRequest)
 		return
 	}
+	if err := s.limits.Check(dedupeChunksToTypes(req.Chunks)); err != nil {
+		writeTooLarge(w, s.metrics, "/v1/dedupe/stream", err)
+		return
+	}
+	if err := checkEmbeddingMode(req.Options.ValidateEmbeddings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Initialize SSE writer
 	sw := sse.NewWriter(w)
@@ -637,14 +981,14 @@ func (s *APIServer) handleDedupeStream(w http.ResponseWriter, r *http.Request) {
 		chunks[i] = types.Chunk{
 			ID:        c.ID,
 			Text:      c.Text,
-			Embedding: c.Embedding,
+			Embedding: c.Embedding.Values,
 			Score:     c.Score,
 			Metadata:  make(map[string]interface{}),
 		}
 		if c.CacheControl != "" {
 			chunks[i].Metadata["cache_control"] = c.CacheControl
 		}
-		if len(c.Embedding) == 0 {
+		if len(c.Embedding.Values) == 0 {
 			needsEmbedding = true
 		}
 	}
@@ -657,8 +1001,22 @@ func (s *APIServer) handleDedupeStream(w http.ResponseWriter, r *http.Request) {
 		dedupChunks = partition.Suffix
 	}
 
+	// Drop (or mark) suffix chunks already sent to this session.
+	dedupChunks, repeated := s.sent.Filter(ctx, req.SessionID, dedupChunks, req.Options.MarkRepeats)
+
+	var repaired int
+	if req.Options.ValidateEmbeddings != "" && !needsEmbedding {
+		var err error
+		repaired, err = s.checkChunkEmbeddings(ctx, req.Options.ValidateEmbeddings, dedupChunks)
+		if err != nil {
+			telemetry.RecordError(rootSpan, err)
+			_ = sw.SendError(sse.StageEmbedding, embeddingCheckMessage(err))
+			return
+		}
+	}
+
 	// Stage 1: Embedding (suffix only).
-	if needsEmbedding {
+	if needsEmbedding && len(dedupChunks) > 0 {
 		if s.embedder == nil {
 			_ = sw.SendError(sse.StageEmbedding, "Embeddings required but no embedding provider configured. Either provide embeddings in request or configure OPENAI_API_KEY.")
 			return
@@ -709,9 +1067,16 @@ func (s *APIServer) handleDedupeStream(w http.ResponseWriter, r *http.Request) {
 	clusterer := contextlab.NewClusterer(contextlab.ClusterConfig{
 		Threshold: threshold,
 		Linkage:   "average",
+		Matrix:    s.matrix,
 	})
-	clusterResult := clusterer.Cluster(dedupChunks)
+	ctx = contextlab.WithVectorNorms(ctx)
+	clusterResult, err := clusterer.ClusterContext(ctx, dedupChunks)
 	clusterSpan.End()
+	if err != nil {
+		telemetry.RecordError(rootSpan, err)
+		_ = sw.SendError(sse.StageClustering, fmt.Sprintf("Clustering interrupted: %v", err))
+		return
+	}
 
 	_ = sw.SendProgressWithStats(sse.StageClustering, 1.0, map[string]interface{}{
 		"clusters_formed": clusterResult.ClusterCount,
@@ -742,17 +1107,30 @@ func (s *APIServer) handleDedupeStream(w http.ResponseWriter, r *http.Request) {
 			TargetK: targetK,
 		}
 		mmr := contextlab.NewMMR(mmrCfg)
-		representatives = mmr.Rerank(representatives)
+		representatives, err = mmr.RerankContext(ctx, representatives)
 		mmrSpan.End()
+		if err != nil {
+			telemetry.RecordError(rootSpan, err)
+			_ = sw.SendError(sse.StageMMR, fmt.Sprintf("MMR interrupted: %v", err))
+			return
+		}
 
 		_ = sw.SendProgressWithStats(sse.StageMMR, 1.0, map[string]interface{}{
 			"output_count": len(representatives),
 		})
 	}
 
+	if req.Options.DedupHints {
+		contextlab.AnnotateDedup(representatives, clusterResult, selectorCfg.Strategy)
+	}
+
 	// Prepend frozen prefix to deduped suffix.
 	finalChunks := append(partition.Prefix, representatives...)
 
+	if err := s.sent.Record(ctx, req.SessionID, representatives); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to record sent chunks: %v\n", err)
+	}
+
 	latency := time.Since(start)
 
 	telemetry.RecordResult(rootSpan, len(req.Chunks), len(finalChunks), clusterResult.ClusterCount, latency)
@@ -761,10 +1139,12 @@ func (s *APIServer) handleDedupeStream(w http.ResponseWriter, r *http.Request) {
 	outputChunks := make([]DedupeChunkResponse, len(finalChunks))
 	for i, c := range finalChunks {
 		outputChunks[i] = DedupeChunkResponse{
-			ID:        c.ID,
-			Text:      c.Text,
-			Score:     c.Score,
-			ClusterID: c.ClusterID,
+			ID:          c.ID,
+			Text:        c.Text,
+			Score:       c.Score,
+			ClusterID:   c.ClusterID,
+			AlreadySent: distillcache.IsAlreadySent(c),
+			Metadata:    dedupHints(c),
 		}
 	}
 
@@ -774,11 +1154,15 @@ func (s *APIServer) handleDedupeStream(w http.ResponseWriter, r *http.Request) {
 	}
 
 	stats := DedupeStats{
-		InputCount:   len(req.Chunks),
-		OutputCount:  len(finalChunks),
-		ClusterCount: clusterResult.ClusterCount,
-		ReductionPct: reductionPct,
-		LatencyMs:    latency.Milliseconds(),
+		InputCount:    len(req.Chunks),
+		OutputCount:   len(finalChunks),
+		ClusterCount:  clusterResult.ClusterCount,
+		ReductionPct:  reductionPct,
+		LatencyMs:     latency.Milliseconds(),
+		RepeatedCount: repeated,
+
+		EmbeddingsRepaired: repaired,
+		MatrixOverflow:     clusterResult.Overflow,
 	}
 	if req.Options.PreserveCachePrefix && partition.MarkerCount > 0 {
 		stats.CachePrefixFrozen = true
@@ -789,12 +1173,48 @@ func (s *APIServer) handleDedupeStream(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.metrics.RecordDedup("/v1/dedupe/stream", len(req.Chunks), len(finalChunks), clusterResult.ClusterCount)
+	s.recordDedupe("/v1/dedupe/stream", start, len(req.Chunks), len(finalChunks), clusterResult.ClusterCount, threshold, lambda, targetK, req.Options.PreserveCachePrefix)
 
 	// Send final complete event
 	_ = sw.SendComplete(outputChunks, stats)
 }
 
+// statusClientClosedRequest is the nginx convention for a request the
+// client abandoned. The client never sees it; it shows up in access logs
+// and request metrics.
+const statusClientClosedRequest = 499
+
+// writeInterrupted writes the response for a request whose processing was
+// stopped by its context. It returns false, writing nothing, if err is not
+// a context error.
+func writeInterrupted(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "Request timed out", http.StatusGatewayTimeout)
+	case errors.Is(err, context.Canceled):
+		http.Error(w, "Request canceled", statusClientClosedRequest)
+	default:
+		return false
+	}
+	return true
+}
+
 func (s *APIServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
+
+// dedupHints returns the dedup hints AnnotateDedup set on c, the spans
+// RedactTyped recorded, and the members MergeClusters merged, or nil.
+func dedupHints(c types.Chunk) map[string]interface{} {
+	var hints map[string]interface{}
+	for _, key := range []string{contextlab.HintClusterSize, contextlab.HintDuplicatesRemoved, contextlab.HintRepresentativeReason, contextlab.MetaRedactions, contextlab.MetaMergedFrom} {
+		if v, ok := c.Metadata[key]; ok {
+			if hints == nil {
+				hints = make(map[string]interface{}, 3)
+			}
+			hints[key] = v
+		}
+	}
+	return hints
+}
@@ -94,7 +94,7 @@ func (m *MemoryAPI) handleRecall(w http.ResponseWriter, r *http.Request) {
 	if len(req.QueryEmbedding) == 0 && m.embedder != nil && req.Query != "" {
 		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 		defer cancel()
-		emb, err := m.embedder.Embed(ctx, req.Query)
+		emb, err := retriever.EmbedQuery(ctx, m.embedder, req.Query)
 		if err != nil {
 			writeJSONError(w, fmt.Sprintf("embedding error: %v", err), http.StatusInternalServerError)
 			return
@@ -6,6 +6,9 @@ import (
 	"strings"
 
 	"github.com/Siddhant-K-code/distill/pkg/batch"
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/metrics"
 	"github.com/Siddhant-K-code/distill/pkg/pipeline"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
@@ -14,6 +17,9 @@ import (
 type PipelineRequest struct {
 	Chunks  []DedupeChunk   `json:"chunks"`
 	Options PipelineOptions `json:"options,omitempty"`
+
+	// Recipe replays a recipe from an earlier response instead of Options.
+	Recipe *pipeline.Recipe `json:"recipe,omitempty"`
 }
 
 // PipelineOptions mirrors pipeline.Options for JSON serialisation.
@@ -33,6 +39,7 @@ type PipelineDedupOptions struct {
 type PipelineCompressOptions struct {
 	Enabled         bool    `json:"enabled"`
 	TargetReduction float64 `json:"target_reduction,omitempty"`
+	MaxTokens       int     `json:"max_tokens,omitempty"`
 }
 
 type PipelineSummarizeOptions struct {
@@ -45,6 +52,22 @@ type PipelineSummarizeOptions struct {
 type PipelineResponse struct {
 	Chunks []DedupeChunk        `json:"chunks"`
 	Stats  PipelineStatsPayload `json:"stats"`
+
+	// Offsets maps chunk IDs to the ranges of their text copied from the
+	// input chunk, so UIs can highlight sources; see compress.Span.
+	Offsets map[string][]SpanPayload `json:"offsets,omitempty"`
+
+	// Recipe records the effective configuration, for replaying the run.
+	Recipe *pipeline.Recipe `json:"recipe"`
+}
+
+// SpanPayload is the serialisable form of compress.Span: byte offsets,
+// end-exclusive, into the output and input chunk text.
+type SpanPayload struct {
+	Start       int `json:"start"`
+	End         int `json:"end"`
+	SourceStart int `json:"source_start"`
+	SourceEnd   int `json:"source_end"`
 }
 
 // PipelineStatsPayload is the serialisable form of pipeline.Stats.
@@ -54,6 +77,14 @@ type PipelineStatsPayload struct {
 	TotalReduction float64                   `json:"total_reduction"`
 	LatencyMs      float64                   `json:"latency_ms"`
 	Stages         map[string]StageStatsPL   `json:"stages"`
+
+	RepeatsRemoved    int `json:"repeats_removed"`
+	RepeatTokensSaved int `json:"repeat_tokens_saved"`
+
+	// CompressionLevels maps chunk IDs to the level compress.max_tokens
+	// pushed them to; see compress.Level.
+	CompressionLevels  map[string]string `json:"compression_levels,omitempty"`
+	CompressOverBudget bool              `json:"compress_over_budget,omitempty"`
 }
 
 // StageStatsPL is the serialisable form of pipeline.StageStats.
@@ -67,8 +98,9 @@ type StageStatsPL struct {
 
 // BatchSubmitRequest is the JSON body for POST /v1/batch.
 type BatchSubmitRequest struct {
-	Chunks  []DedupeChunk   `json:"chunks"`
-	Options PipelineOptions `json:"options,omitempty"`
+	Chunks  []DedupeChunk    `json:"chunks"`
+	Options PipelineOptions  `json:"options,omitempty"`
+	Recipe  *pipeline.Recipe `json:"recipe,omitempty"`
 }
 
 // BatchSubmitResponse is the JSON response for POST /v1/batch.
@@ -94,11 +126,16 @@ type BatchResultsResponse struct {
 	Status string               `json:"status"`
 	Chunks []DedupeChunk        `json:"chunks"`
 	Stats  PipelineStatsPayload `json:"stats"`
+	Recipe *pipeline.Recipe     `json:"recipe,omitempty"`
+
+	Offsets map[string][]SpanPayload `json:"offsets,omitempty"`
 }
 
 // PipelineAPI holds the pipeline runner and batch processor.
 type PipelineAPI struct {
 	processor *batch.Processor
+	limits    contextlab.Limits
+	metrics   *metrics.Metrics
 }
 
 // NewPipelineAPI creates a PipelineAPI with a default batch processor.
@@ -129,18 +166,31 @@ func (a *PipelineAPI) handlePipeline(w http.ResponseWriter, r *http.Request) {
 	}
 
 	chunks := dedupeChunksToTypes(req.Chunks)
-	opts := pipelineOptsFromRequest(req.Options)
+	if err := a.limits.Check(chunks); err != nil {
+		writeTooLarge(w, a.metrics, "/v1/pipeline", err)
+		return
+	}
+	opts, err := pipelineOpts(req.Options, req.Recipe)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	runner := pipeline.New()
 	result, stats, err := runner.Run(r.Context(), chunks, opts)
 	if err != nil {
-		http.Error(w, "pipeline error: "+err.Error(), http.StatusInternalServerError)
+		if !writeInterrupted(w, err) {
+			http.Error(w, "pipeline error: "+err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
 	resp := PipelineResponse{
 		Chunks: typesToDedupeChunks(result),
 		Stats:  marshalStats(stats),
+		Recipe: pipeline.NewRecipe(opts, chunks),
+
+		Offsets: marshalOffsets(stats.Offsets),
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
@@ -159,9 +209,21 @@ func (a *PipelineAPI) handleBatchSubmit(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	chunks := dedupeChunksToTypes(req.Chunks)
+	if err := a.limits.Check(chunks); err != nil {
+		writeTooLarge(w, a.metrics, "/v1/batch", err)
+		return
+	}
+
+	opts, err := pipelineOpts(req.Options, req.Recipe)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	job, err := a.processor.Submit(batch.SubmitRequest{
-		Chunks:  dedupeChunksToTypes(req.Chunks),
-		Options: pipelineOptsFromRequest(req.Options),
+		Chunks:  chunks,
+		Options: opts,
 	})
 	if err != nil {
 		http.Error(w, "submit error: "+err.Error(), http.StatusServiceUnavailable)
@@ -239,6 +301,11 @@ func (a *PipelineAPI) handleBatchResults(w http.ResponseWriter, _ *http.Request,
 		Status: string(batch.StatusCompleted),
 		Chunks: typesToDedupeChunks(chunks),
 		Stats:  marshalStats(stats),
+
+		Offsets: marshalOffsets(stats.Offsets),
+	}
+	if job, err := a.processor.Get(id); err == nil {
+		resp.Recipe = pipeline.NewRecipe(job.Options, job.Chunks)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
@@ -252,7 +319,7 @@ func dedupeChunksToTypes(in []DedupeChunk) []types.Chunk {
 		out[i] = types.Chunk{
 			ID:        c.ID,
 			Text:      c.Text,
-			Embedding: c.Embedding,
+			Embedding: c.Embedding.Values,
 			Score:     c.Score,
 		}
 	}
@@ -265,13 +332,22 @@ func typesToDedupeChunks(in []types.Chunk) []DedupeChunk {
 		out[i] = DedupeChunk{
 			ID:        c.ID,
 			Text:      c.Text,
-			Embedding: c.Embedding,
+			Embedding: Embedding{Values: c.Embedding},
 			Score:     c.Score,
 		}
 	}
 	return out
 }
 
+// pipelineOpts returns the options replaying recipe, or the request
+// options when there is none.
+func pipelineOpts(o PipelineOptions, recipe *pipeline.Recipe) (pipeline.Options, error) {
+	if recipe != nil {
+		return recipe.Options()
+	}
+	return pipelineOptsFromRequest(o), nil
+}
+
 func pipelineOptsFromRequest(o PipelineOptions) pipeline.Options {
 	return pipeline.Options{
 		DedupEnabled:            o.Dedup.Enabled,
@@ -280,6 +356,7 @@ func pipelineOptsFromRequest(o PipelineOptions) pipeline.Options {
 		DedupTargetK:            o.Dedup.TargetK,
 		CompressEnabled:         o.Compress.Enabled,
 		CompressTargetReduction: o.Compress.TargetReduction,
+		CompressMaxTokens:       o.Compress.MaxTokens,
 		SummarizeEnabled:        o.Summarize.Enabled,
 		SummarizeMaxTokens:      o.Summarize.MaxTokens,
 		SummarizeRecent:         o.Summarize.KeepRecent,
@@ -297,11 +374,44 @@ func marshalStats(s pipeline.Stats) PipelineStatsPayload {
 			LatencyMs:    float64(v.Latency.Microseconds()) / 1000.0,
 		}
 	}
+	var levels map[string]string
+	if s.CompressionLevels != nil {
+		levels = make(map[string]string, len(s.CompressionLevels))
+		for id, level := range s.CompressionLevels {
+			levels[id] = level.String()
+		}
+	}
 	return PipelineStatsPayload{
 		OriginalTokens: s.OriginalTokens,
 		FinalTokens:    s.FinalTokens,
 		TotalReduction: s.TotalReduction,
 		LatencyMs:      float64(s.TotalLatency.Microseconds()) / 1000.0,
 		Stages:         stages,
+
+		RepeatsRemoved:    s.RepeatsRemoved,
+		RepeatTokensSaved: s.RepeatTokensSaved,
+
+		CompressionLevels:  levels,
+		CompressOverBudget: s.CompressOverBudget,
+	}
+}
+
+func marshalOffsets(offsets map[string][]compress.Span) map[string][]SpanPayload {
+	if len(offsets) == 0 {
+		return nil
 	}
+	out := make(map[string][]SpanPayload, len(offsets))
+	for id, spans := range offsets {
+		payload := make([]SpanPayload, len(spans))
+		for i, sp := range spans {
+			payload[i] = SpanPayload{
+				Start:       sp.Start,
+				End:         sp.End,
+				SourceStart: sp.SourceStart,
+				SourceEnd:   sp.SourceEnd,
+			}
+		}
+		out[id] = payload
+	}
+	return out
 }
@@ -0,0 +1,629 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	distillcache "github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/gctune"
+	"github.com/Siddhant-K-code/distill/pkg/history"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and prime a running server's query caches",
+}
+
+var cacheWarmCmd = &cobra.Command{
+	Use:   "warm",
+	Short: "Prime a server's caches with frequent queries before traffic arrives",
+	Long: `Sends frequent queries to a running distill serve so their embeddings and
+results are cached before real traffic arrives, cutting cold-start latency
+after a deployment.
+
+Queries come from a file, one per line, or from the most frequent queries in
+the history database. Recording query text needs serve to run with
+--history-queries (config: history.record_queries).
+
+When history is available, the report includes an estimated hit rate: the
+share of recorded /v1/retrieve requests whose query was warmed. Requests
+with a session_id or an identity only benefit from the embedding cache.
+
+Examples:
+  distill cache warm --server http://localhost:8080 --queries top-queries.txt
+  distill cache warm --server http://localhost:8080 --from-history 500 --db history.db --since 168h`,
+	RunE: runCacheWarm,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show a server's cache sizes and hit rates",
+	RunE:  runCacheStats,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheWarmCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+
+	cacheCmd.PersistentFlags().String("server", "http://localhost:8080", "distill serve URL")
+	cacheCmd.PersistentFlags().Duration("timeout", 30*time.Second, "Timeout for each server request")
+
+	cacheWarmCmd.Flags().String("queries", "", "File with one query per line")
+	cacheWarmCmd.Flags().Int("from-history", 0, "Warm the N most frequent recorded queries")
+	cacheWarmCmd.Flags().String("db", "", "History database (default: history.path, or "+defaultHistoryDB+")")
+	cacheWarmCmd.Flags().Duration("since", 0, "Only count requests recorded within this long (0 = all)")
+	cacheWarmCmd.Flags().StringP("namespace", "n", "", "Namespace for queries from --queries")
+	cacheWarmCmd.Flags().Int("concurrency", 4, "Requests in flight")
+	cacheWarmCmd.Flags().Bool("progress", false, "Show a progress bar on stderr (default: when stderr is a terminal)")
+	cacheStatsCmd.Flags().Bool("json", false, "Print the stats as JSON")
+}
+
+// addCacheFlags adds serve's query cache flags.
+func addCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("embedding-cache-size", 10000, "Query embeddings kept (0 = off)")
+	cmd.Flags().Duration("embedding-cache-ttl", 24*time.Hour, "How long a query embedding is kept (0 = until evicted)")
+	cmd.Flags().Duration("result-cache-ttl", 0, "How long /v1/retrieve results without a session_id are reused (0 = off)")
+	cmd.Flags().Int("result-cache-size", 10000, "Retrieve results kept")
+	cmd.Flags().String("result-cache-tenant-bytes-per-process", "", "Result bytes each namespace may keep cached by this process, e.g. 64MiB (default unlimited; config: cache.tenant_max_bytes_per_process, cache.tenant_quotas_per_process)")
+	cmd.Flags().String("cache-backend", "memory", "Where query caches live: memory, disk to survive restarts, or redis to share them (config: cache.redis)")
+	cmd.Flags().String("cache-dir", "distill-cache", "Directory for disk caches")
+
+	_ = viper.BindPFlag("cache.embedding_size", cmd.Flags().Lookup("embedding-cache-size"))
+	_ = viper.BindPFlag("cache.embedding_ttl", cmd.Flags().Lookup("embedding-cache-ttl"))
+	_ = viper.BindPFlag("cache.result_ttl", cmd.Flags().Lookup("result-cache-ttl"))
+	_ = viper.BindPFlag("cache.result_size", cmd.Flags().Lookup("result-cache-size"))
+	_ = viper.BindPFlag("cache.tenant_max_bytes_per_process", cmd.Flags().Lookup("result-cache-tenant-bytes-per-process"))
+	_ = viper.BindPFlag("cache.backend", cmd.Flags().Lookup("cache-backend"))
+	_ = viper.BindPFlag("cache.dir", cmd.Flags().Lookup("cache-dir"))
+}
+
+// queryCaches holds serve's query caches. Either is nil when turned off.
+type queryCaches struct {
+	backend      string
+	embeddings   distillcache.Cache
+	embeddingTTL time.Duration
+	results      distillcache.Cache
+	resultTTL    time.Duration
+}
+
+// newQueryCaches creates the caches the flags ask for.
+func newQueryCaches() (*queryCaches, error) {
+	size := viper.GetInt("cache.embedding_size")
+	embeddingTTL := viper.GetDuration("cache.embedding_ttl")
+	resultTTL := viper.GetDuration("cache.result_ttl")
+	resultSize := viper.GetInt("cache.result_size")
+	switch {
+	case size < 0:
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--embedding-cache-size must be non-negative, got %d", size))
+	case embeddingTTL < 0:
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--embedding-cache-ttl must be non-negative, got %s", embeddingTTL))
+	case resultTTL < 0:
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--result-cache-ttl must be non-negative, got %s", resultTTL))
+	case resultTTL > 0 && resultSize <= 0:
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--result-cache-size must be positive, got %d", resultSize))
+	}
+
+	backend := viper.GetString("cache.backend")
+	if backend == "" {
+		backend = "memory"
+	}
+	dir := viper.GetString("cache.dir")
+	switch {
+	case backend != "memory" && backend != "disk" && backend != "redis":
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--cache-backend must be memory, disk, or redis, got %q", backend))
+	case backend == "disk" && dir == "":
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--cache-dir is required for the disk cache backend"))
+	case backend == "disk" && (size > 0 || resultTTL > 0):
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("cache dir: %w", err))
+		}
+	}
+
+	// Entries must not expire on a default TTL when embedding_ttl is 0
+	open := func(name string, size int) (distillcache.Cache, error) {
+		cfg := distillcache.Config{MaxSize: int64(size)}
+		switch backend {
+		case "redis":
+			return newRedisCache(name)
+		case "disk":
+			c, err := distillcache.NewDiskCache(filepath.Join(dir, name+".db"), cfg)
+			if err != nil {
+				return nil, errs.Wrap(errs.ErrConfig, err)
+			}
+			return c, nil
+		}
+		return distillcache.NewMemoryCache(cfg), nil
+	}
+
+	c := &queryCaches{backend: backend, embeddingTTL: embeddingTTL, resultTTL: resultTTL}
+	if size > 0 {
+		embeddings, err := open("embeddings", size)
+		if err != nil {
+			return nil, err
+		}
+		c.embeddings = embeddings
+	}
+	if resultTTL > 0 {
+		tenants, err := tenantQuotas()
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		results, err := open("results", resultSize)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		tenants.MaxEntries = resultSize
+		c.results = distillcache.NewTenantCache(results, tenants)
+	}
+	return c, nil
+}
+
+// tenantQuotas reads the result cache's per-namespace byte quotas.
+func tenantQuotas() (distillcache.TenantConfig, error) {
+	var cfg distillcache.TenantConfig
+	if s := viper.GetString("cache.tenant_max_bytes_per_process"); s != "" {
+		n, err := gctune.ParseBytes(s)
+		if err != nil {
+			return cfg, errs.Wrap(errs.ErrConfig, fmt.Errorf("--result-cache-tenant-bytes-per-process: %w", err))
+		}
+		cfg.MaxBytes = n
+	}
+	for ns, s := range viper.GetStringMapString("cache.tenant_quotas_per_process") {
+		n, err := gctune.ParseBytes(s)
+		if err != nil {
+			return cfg, errs.Wrap(errs.ErrConfig, fmt.Errorf("cache.tenant_quotas_per_process.%s: %w", ns, err))
+		}
+		if cfg.Quotas == nil {
+			cfg.Quotas = make(map[string]int64)
+		}
+		cfg.Quotas[ns] = n
+	}
+	return cfg, nil
+}
+
+// newRedisCache connects to the Redis server under cache.redis, with keys
+// under key_prefix + name + ":". Entries expire only on the TTL callers
+// pass.
+func newRedisCache(name string) (*distillcache.RedisCache, error) {
+	prefix := viper.GetString("cache.redis.key_prefix")
+	if prefix == "" {
+		prefix = distillcache.DefaultRedisConfig().KeyPrefix
+	}
+	c, err := distillcache.NewRedisCache(distillcache.RedisConfig{
+		URL:          viper.GetString("cache.redis.url"),
+		Password:     viper.GetString("cache.redis.password"),
+		DB:           viper.GetInt("cache.redis.db"),
+		KeyPrefix:    prefix + name + ":",
+		PoolSize:     viper.GetInt("cache.redis.pool_size"),
+		DialTimeout:  viper.GetDuration("cache.redis.dial_timeout"),
+		ReadTimeout:  viper.GetDuration("cache.redis.read_timeout"),
+		WriteTimeout: viper.GetDuration("cache.redis.write_timeout"),
+	})
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrBackend, err)
+	}
+	return c, nil
+}
+
+// sessionCache returns the cache tracking chunks already sent to each
+// session: Redis when cache.backend is redis, so sessions can move
+// between replicas, and memory otherwise.
+func sessionCache() (distillcache.Cache, error) {
+	if viper.GetString("cache.backend") == "redis" {
+		return newRedisCache("sent")
+	}
+	return distillcache.NewMemoryCache(distillcache.DefaultConfig()), nil
+}
+
+// options returns the broker options for the caches that are on.
+func (c *queryCaches) options() []contextlab.Option {
+	var opts []contextlab.Option
+	if c.embeddings != nil {
+		opts = append(opts, contextlab.WithEmbeddingCache(c.embeddings, c.embeddingTTL))
+	}
+	if c.results != nil {
+		opts = append(opts, contextlab.WithCache(c.results, c.resultTTL))
+	}
+	return opts
+}
+
+// Close stops the caches' cleanup goroutines and closes disk caches.
+func (c *queryCaches) Close() {
+	if c.embeddings != nil {
+		_ = c.embeddings.Close()
+	}
+	if c.results != nil {
+		_ = c.results.Close()
+	}
+}
+
+// CacheStatsResponse is the JSON response for GET /v1/cache/stats.
+type CacheStatsResponse struct {
+	Embeddings CacheStats `json:"embeddings"`
+	Results    CacheStats `json:"results"`
+}
+
+// CacheStats describes one cache. HitRate is hits over lookups, 0-1.
+type CacheStats struct {
+	Enabled    bool    `json:"enabled"`
+	Entries    int64   `json:"entries"`
+	MaxEntries int64   `json:"max_entries"`
+	TTLSeconds float64 `json:"ttl_seconds"`
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	HitRate    float64 `json:"hit_rate"`
+	Evictions  int64   `json:"evictions"`
+
+	// Tenants breaks the result cache down by namespace, with the
+	// default namespace as "default".
+	Tenants map[string]distillcache.TenantStats `json:"tenants,omitempty"`
+}
+
+func cacheStats(c distillcache.Cache, ttl time.Duration) CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	st := c.Stats()
+	out := CacheStats{
+		Enabled:    true,
+		Entries:    st.Size,
+		MaxEntries: st.MaxSize,
+		TTLSeconds: ttl.Seconds(),
+		Hits:       st.Hits,
+		Misses:     st.Misses,
+		HitRate:    st.HitRate() / 100,
+		Evictions:  st.Evictions,
+	}
+	if tc, ok := c.(*distillcache.TenantCache); ok {
+		out.Tenants = make(map[string]distillcache.TenantStats)
+		for ns, ts := range tc.TenantStats() {
+			if ns == "" {
+				ns = "default"
+			}
+			out.Tenants[ns] = ts
+		}
+	}
+	return out
+}
+
+func (s *Server) handleCacheStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	resp := CacheStatsResponse{
+		Embeddings: cacheStats(s.caches.embeddings, s.caches.embeddingTTL),
+		Results:    cacheStats(s.caches.results, s.caches.resultTTL),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// warmQuery is one query to warm and, from history, how often it was
+// requested.
+type warmQuery struct {
+	Query     string
+	Namespace string
+	Count     int
+}
+
+func runCacheWarm(cmd *cobra.Command, _ []string) error {
+	server, _ := cmd.Flags().GetString("server")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	queriesPath, _ := cmd.Flags().GetString("queries")
+	topN, _ := cmd.Flags().GetInt("from-history")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	showProgress := progressEnabled(cmd)
+
+	switch {
+	case (queriesPath == "") == (topN == 0):
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("pass exactly one of --queries or --from-history"))
+	case topN < 0:
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("--from-history must be positive, got %d", topN))
+	case concurrency <= 0:
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("--concurrency must be positive, got %d", concurrency))
+	case !isURL(server):
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("--server must be an http(s) URL, got %q", server))
+	}
+
+	// Recorded traffic picks the queries for --from-history and, when
+	// available, estimates the hit rate for either source.
+	var recorded []warmQuery
+	var total int
+	dbSet := cmd.Flags().Changed("db") || viper.GetString("history.path") != ""
+	if topN > 0 || dbSet {
+		store, err := openHistoryStore(cmd)
+		if err != nil {
+			return err
+		}
+		recorded, total, err = recordedQueries(cmd, store)
+		_ = store.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	var queries []warmQuery
+	if topN > 0 {
+		if len(recorded) == 0 {
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("no recorded query text in history; run serve with --history-queries"))
+		}
+		queries = recorded[:min(topN, len(recorded))]
+	} else {
+		var err error
+		if queries, err = readWarmQueries(queriesPath, namespace); err != nil {
+			return err
+		}
+		if len(queries) == 0 {
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("%s has no queries", queriesPath))
+		}
+	}
+
+	// Past flag validation, failures are not usage errors
+	cmd.SilenceUsage = true
+
+	client := &http.Client{Timeout: timeout}
+	base := strings.TrimSuffix(server, "/")
+	before, err := fetchCacheStats(client, base)
+	if err != nil {
+		return err
+	}
+	if !before.Embeddings.Enabled && !before.Results.Enabled {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("%s has no query caches enabled (see --embedding-cache-size and --result-cache-ttl)", server))
+	}
+
+	var bar *progressbar.ProgressBar
+	if showProgress {
+		bar = progressbar.NewOptions(len(queries),
+			progressbar.OptionSetDescription("Warming"),
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionShowCount(),
+			progressbar.OptionThrottle(100*time.Millisecond),
+			progressbar.OptionFullWidth(),
+			progressbar.OptionSetRenderBlankState(true),
+		)
+	}
+
+	start := time.Now()
+	var failed, cached atomic.Int64
+	var errOnce sync.Once
+	var firstErr error
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, q := range queries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(q warmQuery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			hit, err := warmOne(client, base, q)
+			switch {
+			case err != nil:
+				failed.Add(1)
+				errOnce.Do(func() { firstErr = err })
+			case hit:
+				cached.Add(1)
+			}
+			if bar != nil {
+				_ = bar.Add(1)
+			}
+		}(q)
+	}
+	wg.Wait()
+	if bar != nil {
+		_ = bar.Finish()
+		fmt.Fprintln(os.Stderr)
+	}
+	elapsed := time.Since(start)
+
+	after, err := fetchCacheStats(client, base)
+	if err != nil {
+		return err
+	}
+
+	warmed := len(queries) - int(failed.Load())
+	fmt.Printf("Warmed %d/%d queries in %v (%d already cached, %d failed)\n",
+		warmed, len(queries), elapsed.Round(time.Millisecond), cached.Load(), failed.Load())
+	fmt.Printf("  Embeddings: %s\n", cacheSummary(after.Embeddings, before.Embeddings))
+	fmt.Printf("  Results:    %s\n", cacheSummary(after.Results, before.Results))
+	if total > 0 {
+		covered := warmedRequests(queries, recorded)
+		fmt.Printf("Estimated hit rate: %.1f%% (%d of %d recorded requests use a warmed query)\n",
+			float64(covered)/float64(total)*100, covered, total)
+	}
+
+	if n := failed.Load(); n > 0 {
+		return fmt.Errorf("%d of %d queries failed, first error: %w", n, len(queries), firstErr)
+	}
+	return nil
+}
+
+// recordedQueries counts the recorded /v1/retrieve queries by namespace
+// and text, most frequent first, and returns the number of requests
+// recorded in the window, with or without query text.
+func recordedQueries(cmd *cobra.Command, store *history.SQLiteStore) ([]warmQuery, int, error) {
+	f := history.Filter{Kind: history.KindRequest, Name: "/v1/retrieve"}
+	if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+		f.Since = time.Now().Add(-since)
+	}
+	records, err := store.List(context.Background(), f)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	counts := make(map[warmQuery]int)
+	for _, r := range records {
+		query, _ := r.Details["query"].(string)
+		if query == "" {
+			continue
+		}
+		ns, _ := r.Details["namespace"].(string)
+		counts[warmQuery{Query: query, Namespace: ns}]++
+	}
+	queries := make([]warmQuery, 0, len(counts))
+	for q, n := range counts {
+		q.Count = n
+		queries = append(queries, q)
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		if queries[i].Count != queries[j].Count {
+			return queries[i].Count > queries[j].Count
+		}
+		if queries[i].Namespace != queries[j].Namespace {
+			return queries[i].Namespace < queries[j].Namespace
+		}
+		return queries[i].Query < queries[j].Query
+	})
+	return queries, len(records), nil
+}
+
+// warmedRequests returns how many recorded requests used a warmed query.
+func warmedRequests(warmed, recorded []warmQuery) int {
+	counts := make(map[warmQuery]int, len(recorded))
+	for _, q := range recorded {
+		counts[warmQuery{Query: q.Query, Namespace: q.Namespace}] = q.Count
+	}
+	covered := 0
+	for _, q := range warmed {
+		covered += counts[warmQuery{Query: q.Query, Namespace: q.Namespace}]
+	}
+	return covered
+}
+
+// readWarmQueries reads one query per line, skipping blank lines,
+// comments, and repeats.
+func readWarmQueries(path, namespace string) ([]warmQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("failed to read queries: %w", err))
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	var queries []warmQuery
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		queries = append(queries, warmQuery{Query: line, Namespace: namespace})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("failed to read queries: %w", err))
+	}
+	return queries, nil
+}
+
+// warmOne sends q to /v1/retrieve and reports whether it was already a
+// result cache hit.
+func warmOne(client *http.Client, base string, q warmQuery) (bool, error) {
+	payload, err := json.Marshal(RetrieveRequest{Query: q.Query, Namespace: q.Namespace})
+	if err != nil {
+		return false, err
+	}
+	resp, err := client.Post(base+"/v1/retrieve", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return false, errs.Wrap(errs.ErrBackend, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, errs.Wrap(errs.ErrBackend, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, errs.Wrap(errs.ErrBackend, fmt.Errorf("query %q: server returned %s: %s", q.Query, resp.Status, strings.TrimSpace(string(body))))
+	}
+	var out RetrieveResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return false, errs.Wrap(errs.ErrBackend, fmt.Errorf("query %q: %w", q.Query, err))
+	}
+	return out.Stats.CacheHit, nil
+}
+
+// fetchCacheStats reads a server's /v1/cache/stats.
+func fetchCacheStats(client *http.Client, base string) (CacheStatsResponse, error) {
+	var stats CacheStatsResponse
+	resp, err := client.Get(base + "/v1/cache/stats")
+	if err != nil {
+		return stats, errs.Wrap(errs.ErrBackend, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return stats, errs.Wrap(errs.ErrBackend, fmt.Errorf("%s/v1/cache/stats returned %s: %s", base, resp.Status, strings.TrimSpace(string(body))))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return stats, errs.Wrap(errs.ErrBackend, fmt.Errorf("decoding cache stats: %w", err))
+	}
+	return stats, nil
+}
+
+// cacheSummary describes a cache, with the entries added since before.
+func cacheSummary(st, before CacheStats) string {
+	if !st.Enabled {
+		return "off"
+	}
+	out := fmt.Sprintf("%d/%d entries", st.Entries, st.MaxEntries)
+	if added := st.Entries - before.Entries; added != 0 {
+		out += fmt.Sprintf(" (%+d)", added)
+	}
+	return out + fmt.Sprintf(", hit rate %.1f%% since start", st.HitRate*100)
+}
+
+func runCacheStats(cmd *cobra.Command, _ []string) error {
+	server, _ := cmd.Flags().GetString("server")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	asJSON, _ := cmd.Flags().GetBool("json")
+	if !isURL(server) {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("--server must be an http(s) URL, got %q", server))
+	}
+	cmd.SilenceUsage = true
+
+	stats, err := fetchCacheStats(&http.Client{Timeout: timeout}, strings.TrimSuffix(server, "/"))
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+	fmt.Printf("Caches on %s:\n", server)
+	fmt.Printf("  Embeddings: %s\n", cacheSummary(stats.Embeddings, stats.Embeddings))
+	fmt.Printf("  Results:    %s\n", cacheSummary(stats.Results, stats.Results))
+	for _, ns := range slices.Sorted(maps.Keys(stats.Results.Tenants)) {
+		ts := stats.Results.Tenants[ns]
+		quota := "unlimited"
+		if ts.MaxBytes > 0 {
+			quota = fmt.Sprintf("%d", ts.MaxBytes)
+		}
+		fmt.Printf("    %s: %d entries, %d/%s bytes, %d hits, %d misses, %d evictions\n",
+			ns, ts.Entries, ts.Bytes, quota, ts.Hits, ts.Misses, ts.Evictions)
+	}
+	return nil
+}
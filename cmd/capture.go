@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/capture"
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addCaptureFlags registers the flight recorder flags on a server command.
+// Like the limit flags, they are read directly so several commands can
+// share the capture.* config keys.
+func addCaptureFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("capture", false, "Record anomalous requests at /debug/captures (config: capture.enabled)")
+	cmd.Flags().Int("capture-size", 100, "Number of captures kept (config: capture.size)")
+	cmd.Flags().Duration("capture-latency", 0, "Capture requests slower than this, 0 = off (config: capture.latency_threshold)")
+	cmd.Flags().Int("capture-max-reduction", 0, "Capture requests removing more than this % of chunks, 0 = off (config: capture.max_reduction_pct)")
+	cmd.Flags().Int("capture-min-reduction", 0, "Capture requests removing less than this % of chunks, 0 = off (config: capture.min_reduction_pct)")
+	cmd.Flags().Bool("capture-privacy", false, "Omit chunk text from captures (config: capture.privacy)")
+}
+
+// addDebugKeyFlag registers the API keys guarding /debug/captures on
+// serve, which has no API keys of its own. distill api uses --api-keys.
+func addDebugKeyFlag(cmd *cobra.Command) {
+	cmd.Flags().String("debug-api-keys", "", "Comma-separated API keys required on /debug/captures (or use DISTILL_API_KEYS; config: auth.api_keys)")
+}
+
+// debugKeys returns the API keys guarding serve's debug endpoints from
+// the flag, DISTILL_API_KEYS, or auth.api_keys, in that order.
+func debugKeys(cmd *cobra.Command) map[string]bool {
+	keys, _ := cmd.Flags().GetString("debug-api-keys")
+	if keys == "" {
+		keys = os.Getenv("DISTILL_API_KEYS")
+	}
+	if keys == "" {
+		keys = strings.Join(viper.GetStringSlice("auth.api_keys"), ",")
+	}
+	return parseAPIKeys(keys)
+}
+
+// parseAPIKeys parses a comma-separated list of API keys.
+func parseAPIKeys(s string) map[string]bool {
+	keys := make(map[string]bool)
+	for _, key := range strings.Split(s, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// requireKey rejects requests whose bearer token is not one of keys. With
+// no keys, every request is let through.
+func requireKey(keys map[string]bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(keys) > 0 && !keys[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] {
+			http.Error(w, "Invalid API key", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// captureRecorder builds the flight recorder from flags, falling back to
+// config. It returns nil when capture is disabled. private forces
+// privacy mode, for servers whose chunks are access-controlled: captures
+// are not filtered by caller, so they must not hold text.
+func captureRecorder(cmd *cobra.Command, private bool) (*capture.Recorder, error) {
+	enabled := viper.GetBool("capture.enabled")
+	if cmd.Flags().Changed("capture") {
+		enabled, _ = cmd.Flags().GetBool("capture")
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	cfg := capture.Config{Size: 100}
+	if viper.IsSet("capture.size") {
+		cfg.Size = viper.GetInt("capture.size")
+	}
+	if cmd.Flags().Changed("capture-size") {
+		cfg.Size, _ = cmd.Flags().GetInt("capture-size")
+	}
+	if viper.IsSet("capture.latency_threshold") {
+		cfg.LatencyThreshold = viper.GetDuration("capture.latency_threshold")
+	}
+	if cmd.Flags().Changed("capture-latency") {
+		cfg.LatencyThreshold, _ = cmd.Flags().GetDuration("capture-latency")
+	}
+	if viper.IsSet("capture.max_reduction_pct") {
+		cfg.MaxReductionPct = viper.GetInt("capture.max_reduction_pct")
+	}
+	if cmd.Flags().Changed("capture-max-reduction") {
+		cfg.MaxReductionPct, _ = cmd.Flags().GetInt("capture-max-reduction")
+	}
+	if viper.IsSet("capture.min_reduction_pct") {
+		cfg.MinReductionPct = viper.GetInt("capture.min_reduction_pct")
+	}
+	if cmd.Flags().Changed("capture-min-reduction") {
+		cfg.MinReductionPct, _ = cmd.Flags().GetInt("capture-min-reduction")
+	}
+	cfg.Privacy = viper.GetBool("capture.privacy")
+	if cmd.Flags().Changed("capture-privacy") {
+		cfg.Privacy, _ = cmd.Flags().GetBool("capture-privacy")
+	}
+	cfg.Privacy = cfg.Privacy || private
+
+	if cfg.Size <= 0 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("capture size must be positive, got %d", cfg.Size))
+	}
+	if cfg.LatencyThreshold == 0 && cfg.MaxReductionPct == 0 && cfg.MinReductionPct == 0 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("capture enabled without a trigger: set --capture-latency, --capture-max-reduction, or --capture-min-reduction"))
+	}
+	return capture.NewRecorder(cfg), nil
+}
+
+// dedupeTrace lists every chunk a dedupe request considered and whether it
+// survived. Frozen cache-prefix chunks are always kept and have cluster -1.
+func dedupeTrace(prefix, clustered, final []types.Chunk) []capture.ChunkTrace {
+	kept := make(map[string]bool, len(final))
+	for _, c := range final {
+		kept[c.ID] = true
+	}
+
+	out := make([]capture.ChunkTrace, 0, len(prefix)+len(clustered))
+	for _, c := range prefix {
+		out = append(out, capture.ChunkTrace{ID: c.ID, Text: c.Text, Score: c.Score, ClusterID: -1, Kept: true})
+	}
+	for _, c := range clustered {
+		out = append(out, capture.ChunkTrace{ID: c.ID, Text: c.Text, Score: c.Score, ClusterID: c.ClusterID, Kept: kept[c.ID]})
+	}
+	return out
+}
+
+// retrieveTrace builds a capture for a /v1/retrieve or /v1/similar
+// request. The broker only returns the selected chunks, so only those are
+// listed.
+func retrieveTrace(endpoint string, reasons []string, cfg contextlab.BrokerConfig, req *types.RetrievalRequest, result *types.BrokerResult) capture.Trace {
+	st := result.Stats
+	chunks := make([]capture.ChunkTrace, len(result.Chunks))
+	for i, c := range result.Chunks {
+		chunks[i] = capture.ChunkTrace{ID: c.ID, Text: c.Text, Score: c.Score, ClusterID: c.ClusterID, Kept: true}
+	}
+
+	return capture.Trace{
+		Endpoint:     endpoint,
+		Reasons:      reasons,
+		LatencyMs:    float64(st.TotalLatency.Microseconds()) / 1000,
+		InputCount:   st.Retrieved,
+		OutputCount:  st.Returned,
+		ClusterCount: st.Clustered,
+		ReductionPct: capture.ReductionPct(st.Retrieved, st.Returned),
+		Params: map[string]interface{}{
+			"namespace":    req.Namespace,
+			"over_fetch_k": cfg.OverFetchK,
+			"target_k":     cfg.TargetK,
+			"threshold":    cfg.ClusterThreshold,
+			"lambda":       cfg.MMRLambda,
+			"mmr":          cfg.EnableMMR,
+			"excluded":     st.Excluded,
+			"repeated":     st.Repeated,
+			"truncated":    st.Truncated,
+			"cache_hit":    st.CacheHit,
+		},
+		Stages: []capture.Stage{
+			{Name: "retrieval", DurationMs: float64(st.RetrievalLatency.Microseconds()) / 1000},
+			{Name: "clustering", DurationMs: float64(st.ClusteringLatency.Microseconds()) / 1000},
+		},
+		Chunks: chunks,
+	}
+}
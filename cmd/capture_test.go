@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/capture"
+	"github.com/spf13/cobra"
+)
+
+func TestServeCaptures_RequireKey(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.captures = capture.NewRecorder(capture.Config{Size: 10, LatencyThreshold: 1})
+	s.captures.Record(capture.Trace{Endpoint: "/v1/retrieve", Chunks: []capture.ChunkTrace{{ID: "a", Text: "secret"}}})
+	s.debugKeys = parseAPIKeys("k1, k2")
+	routes := s.routes()
+
+	for _, tc := range []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer k2", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/debug/captures", nil)
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("Authorization %q: status = %d, want %d", tc.auth, rec.Code, tc.want)
+		}
+	}
+
+	// Without keys the endpoint stays open, as on distill api
+	s.debugKeys = parseAPIKeys("")
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/captures", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("no keys: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestCaptureRecorder_Private(t *testing.T) {
+	cmd := &cobra.Command{}
+	addCaptureFlags(cmd)
+	if err := cmd.Flags().Parse([]string{"--capture", "--capture-latency=1ms"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, private := range []bool{false, true} {
+		r, err := captureRecorder(cmd, private)
+		if err != nil {
+			t.Fatalf("captureRecorder: %v", err)
+		}
+		r.Record(capture.Trace{Chunks: []capture.ChunkTrace{{ID: "a", Text: "secret"}}})
+		rec := httptest.NewRecorder()
+		r.Handler()(rec, httptest.NewRequest(http.MethodGet, "/debug/captures", nil))
+		var body struct {
+			Captures []capture.Trace `json:"captures"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(body.Captures) != 1 {
+			t.Fatalf("captures = %+v", body.Captures)
+		}
+		if got := body.Captures[0].Chunks[0].Text; (got == "") != private {
+			t.Errorf("private %v: chunk text = %q", private, got)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Siddhant-K-code/distill/pkg/transcript"
+	"github.com/spf13/cobra"
+)
+
+var compactCmd = &cobra.Command{
+	Use:   "compact",
+	Short: "Compact an agent conversation transcript (dedup → placeholders → prune)",
+	Long: `Compacts a JSONL agent conversation export, one message per line, and
+reports the savings. Useful for curating fine-tuning or eval datasets from
+agent logs.
+
+Three stages run in order:
+  dedup     A message or tool result that repeats an earlier one is replaced
+            with "[duplicate of line N]"
+  compress  Structured tool outputs (JSON, XML, tables) are replaced with
+            compact placeholders
+  prune     Filler phrases and extra whitespace are removed from prose
+
+Messages are read from "role" and "content", or from a nested "message"
+object, so OpenAI- and Anthropic-style exports both work. Every other field
+is written back unchanged. The report is printed to stderr.
+
+Example:
+  distill compact --file transcript.jsonl --output compacted.jsonl
+
+Example (JSON report, no pruning):
+  distill compact --file transcript.jsonl --output compacted.jsonl --report savings.json --no-prune`,
+	RunE: runCompact,
+}
+
+func init() {
+	rootCmd.AddCommand(compactCmd)
+
+	def := transcript.DefaultOptions()
+	compactCmd.Flags().String("file", "", "Transcript JSONL file (required)")
+	compactCmd.Flags().String("output", "", "Output JSONL file (default: stdout)")
+	compactCmd.Flags().String("report", "", "Also write the savings report to this file as JSON")
+
+	compactCmd.Flags().Bool("no-dedup", false, "Disable the dedup stage")
+	compactCmd.Flags().Bool("no-compress", false, "Disable tool output compression")
+	compactCmd.Flags().Bool("no-prune", false, "Disable prose pruning")
+	compactCmd.Flags().Int("min-dedup-length", def.MinDedupLength, "Shortest message, in characters, replaced as a duplicate")
+	compactCmd.Flags().Int("min-tool-output", def.MinToolOutputLength, "Shortest tool output, in characters, that is compressed")
+
+	_ = compactCmd.MarkFlagRequired("file")
+}
+
+// CompactReport is the JSON savings report written by --report.
+type CompactReport struct {
+	Messages         int                `json:"messages"`
+	Segments         int                `json:"segments"`
+	InputTokens      int                `json:"input_tokens"`
+	OutputTokens     int                `json:"output_tokens"`
+	ReductionPercent float64            `json:"reduction_percent"`
+	Stages           CompactStageReport `json:"stages"`
+}
+
+// CompactStageReport breaks the savings down by stage.
+type CompactStageReport struct {
+	Dedup    CompactStage `json:"dedup"`
+	Compress CompactStage `json:"compress"`
+	Prune    CompactStage `json:"prune"`
+}
+
+// CompactStage is how many segments a stage changed and the tokens it saved.
+type CompactStage struct {
+	Changed     int `json:"changed"`
+	TokensSaved int `json:"tokens_saved"`
+}
+
+func runCompact(cmd *cobra.Command, _ []string) error {
+	path, _ := cmd.Flags().GetString("file")
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading input: %w", err)
+	}
+	msgs, err := transcript.Parse(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	noDedup, _ := cmd.Flags().GetBool("no-dedup")
+	noCompress, _ := cmd.Flags().GetBool("no-compress")
+	noPrune, _ := cmd.Flags().GetBool("no-prune")
+	minDedup, _ := cmd.Flags().GetInt("min-dedup-length")
+	minTool, _ := cmd.Flags().GetInt("min-tool-output")
+
+	opts := transcript.Options{
+		Dedup:               !noDedup,
+		CompressToolOutputs: !noCompress,
+		Prune:               !noPrune,
+		MinDedupLength:      minDedup,
+		MinToolOutputLength: minTool,
+	}
+	report, err := transcript.Compact(context.Background(), msgs, opts)
+	if err != nil {
+		return err
+	}
+
+	// Write output.
+	out := os.Stdout
+	outputFile, _ := cmd.Flags().GetString("output")
+	if outputFile != "" {
+		if out, err = os.Create(outputFile); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		defer out.Close()
+	}
+	w := bufio.NewWriter(out)
+	if err := transcript.Write(w, msgs); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Compaction report:\n")
+	fmt.Fprintf(os.Stderr, "  messages:        %d\n", report.Messages)
+	fmt.Fprintf(os.Stderr, "  input_tokens:    %d\n", report.InputTokens)
+	fmt.Fprintf(os.Stderr, "  output_tokens:   %d\n", report.OutputTokens)
+	fmt.Fprintf(os.Stderr, "  total_reduction: %.1f%%\n", report.ReductionPercent())
+	fmt.Fprintf(os.Stderr, "  stage[dedup]:    %d replaced, %d tokens saved\n", report.Deduplicated, report.DedupTokensSaved)
+	fmt.Fprintf(os.Stderr, "  stage[compress]: %d tool outputs, %d tokens saved\n", report.ToolOutputsCompressed, report.ToolTokensSaved)
+	fmt.Fprintf(os.Stderr, "  stage[prune]:    %d messages, %d tokens saved\n", report.Pruned, report.PruneTokensSaved)
+
+	reportFile, _ := cmd.Flags().GetString("report")
+	if reportFile != "" {
+		data, err := json.MarshalIndent(CompactReport{
+			Messages:         report.Messages,
+			Segments:         report.Segments,
+			InputTokens:      report.InputTokens,
+			OutputTokens:     report.OutputTokens,
+			ReductionPercent: report.ReductionPercent(),
+			Stages: CompactStageReport{
+				Dedup:    CompactStage{Changed: report.Deduplicated, TokensSaved: report.DedupTokensSaved},
+				Compress: CompactStage{Changed: report.ToolOutputsCompressed, TokensSaved: report.ToolTokensSaved},
+				Prune:    CompactStage{Changed: report.Pruned, TokensSaved: report.PruneTokensSaved},
+			},
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshalling report: %w", err)
+		}
+		if err := os.WriteFile(reportFile, data, 0644); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+	}
+
+	return nil
+}
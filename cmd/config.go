@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/Siddhant-K-code/distill/pkg/config"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var configCmd = &cobra.Command{
@@ -38,10 +43,57 @@ Example:
 	RunE: runConfigValidate,
 }
 
+var configLintCmd = &cobra.Command{
+	Use:   "lint [file]",
+	Short: "Warn about settings that are valid but likely mistakes",
+	Long: `Validates a configuration file like validate, then checks it against
+best practices:
+
+  over-fetch       retriever.top_k is under 3x retriever.target_k
+  threshold        dedup.threshold is unusual for embedding.model
+  mmr              MMR is off with a large target_k
+  embedding-cache  the embedding cache is off with a provider configured
+  auth             no API keys while listening beyond localhost
+
+Each warning names the key and links to the docs for its rule. Only the
+file is checked; settings from flags and DISTILL_* variables are not.
+Exits non-zero on warnings with --strict.
+
+Example:
+  distill config lint
+  distill config lint distill.yaml --json
+  distill config lint --strict`,
+	RunE: runConfigLint,
+}
+
+var configEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "List the DISTILL_* environment variables for every setting",
+	Long: `Prints the environment variable for every config key and every flag.
+
+Config keys map to DISTILL_<SECTION>_<KEY> (server.port → DISTILL_SERVER_PORT).
+Flags map to DISTILL_<FLAG> (--api-keys → DISTILL_API_KEYS) on every command
+that defines them. Precedence: command-line flag > flag variable >
+config key variable > config file > default.
+
+Example:
+  distill config env
+  distill config env --flags=false`,
+	RunE: runConfigEnv,
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(configInitCmd)
 	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configEnvCmd)
+	configCmd.AddCommand(configLintCmd)
+
+	configLintCmd.Flags().Bool("json", false, "Print the warnings as JSON")
+	configLintCmd.Flags().Bool("strict", false, "Exit non-zero if there are warnings")
+
+	configEnvCmd.Flags().Bool("keys", true, "list config key variables")
+	configEnvCmd.Flags().Bool("flags", true, "list flag variables")
 
 	configInitCmd.Flags().StringP("output", "o", "distill.yaml", "output file path")
 	configInitCmd.Flags().Bool("stdout", false, "print to stdout instead of file")
@@ -71,7 +123,9 @@ func runConfigInit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func runConfigValidate(cmd *cobra.Command, args []string) error {
+// configFilePath returns the file named in args, set with --config, or
+// found in the default locations.
+func configFilePath(cmd *cobra.Command, args []string) (string, error) {
 	var cfgPath string
 
 	if len(args) > 0 {
@@ -100,9 +154,17 @@ func runConfigValidate(cmd *cobra.Command, args []string) error {
 		}
 
 		if cfgPath == "" {
-			return fmt.Errorf("no config file found (try: distill config validate <file>)")
+			return "", fmt.Errorf("no config file found (try: distill %s <file>)", strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" "))
 		}
 	}
+	return cfgPath, nil
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	cfgPath, err := configFilePath(cmd, args)
+	if err != nil {
+		return err
+	}
 
 	cfg, err := config.LoadFromFile(cfgPath)
 	if err != nil {
@@ -114,3 +176,113 @@ func runConfigValidate(cmd *cobra.Command, args []string) error {
 	fmt.Fprintf(os.Stderr, "Config file %s is valid\n", cfgPath)
 	return nil
 }
+
+func runConfigLint(cmd *cobra.Command, args []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	strict, _ := cmd.Flags().GetBool("strict")
+	cfgPath, err := configFilePath(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadFromFile(cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Validation failed for %s:\n%v\n", cfgPath, err)
+		os.Exit(1)
+	}
+
+	warnings := config.Lint(cfg)
+	if asJSON {
+		if warnings == nil {
+			warnings = []config.Warning{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(warnings); err != nil {
+			return err
+		}
+	} else {
+		for _, w := range warnings {
+			fmt.Printf("  WARN  %-20s  %s\n", w.Key, w.Message)
+			fmt.Printf("        see %s\n", w.Docs)
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d warning(s)\n", cfgPath, len(warnings))
+	}
+
+	if strict && len(warnings) > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d lint warning(s) in %s, first %s: %s", len(warnings), cfgPath, warnings[0].Key, warnings[0].Message)
+	}
+	return nil
+}
+
+func runConfigEnv(cmd *cobra.Command, args []string) error {
+	showKeys, _ := cmd.Flags().GetBool("keys")
+	showFlags, _ := cmd.Flags().GetBool("flags")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	if showKeys {
+		fmt.Fprintln(w, "VARIABLE\tCONFIG KEY")
+		for _, key := range config.Keys() {
+			fmt.Fprintf(w, "%s\t%s\n", config.EnvVar(key), key)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if showFlags {
+		fmt.Fprintln(w, "VARIABLE\tFLAG\tCOMMANDS")
+		for _, fe := range flagEnvVars(rootCmd) {
+			fmt.Fprintf(w, "%s\t--%s\t%s\n", config.EnvVar(fe.name), fe.name, strings.Join(fe.commands, ", "))
+		}
+	}
+
+	return w.Flush()
+}
+
+type flagEnv struct {
+	name     string
+	commands []string
+}
+
+// flagEnvVars lists every flag under root, sorted by name, with the
+// commands that accept it.
+func flagEnvVars(root *cobra.Command) []flagEnv {
+	byName := make(map[string]*flagEnv)
+
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		path := strings.TrimPrefix(c.CommandPath(), root.Name()+" ")
+		if c == root {
+			path = root.Name()
+		}
+		add := func(f *pflag.Flag) {
+			if f.Name == "help" {
+				return
+			}
+			fe, ok := byName[f.Name]
+			if !ok {
+				fe = &flagEnv{name: f.Name}
+				byName[f.Name] = fe
+			}
+			fe.commands = append(fe.commands, path)
+		}
+		c.LocalNonPersistentFlags().VisitAll(add)
+		c.PersistentFlags().VisitAll(add)
+
+		for _, sub := range c.Commands() {
+			if sub.Hidden || sub.Name() == "help" {
+				continue
+			}
+			walk(sub)
+		}
+	}
+	walk(root)
+
+	out := make([]flagEnv, 0, len(byName))
+	for _, fe := range byName {
+		out = append(out, *fe)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
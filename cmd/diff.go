@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextdiff"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [query]",
+	Short: "Compare the context two servers or snapshots return for a query",
+	Long: `Runs the same /v1/retrieve request against two sides and shows how the
+assembled context differs: chunks added, removed, reordered, or with
+changed text (for example from different compression settings).
+
+Each side is a distill serve URL or a snapshot file, which is a saved
+/v1/retrieve response. Compare two configs by serving each on its own
+port, or two builds by running each binary. Save a side with --save to
+compare against it later.
+
+Example:
+  distill serve --config current.yaml --port 8081 &
+  distill serve --config proposed.yaml --port 8082 &
+  distill diff --a http://localhost:8081 --b http://localhost:8082 "reset my password"
+
+  # Snapshot now, check again after a change
+  distill diff --a http://localhost:8080 --b http://localhost:8080 --save before.json "refunds"
+  distill diff --a before.json --b http://localhost:8080 --exit-code "refunds"`,
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().String("a", "", "Side A: server URL or snapshot file (required)")
+	diffCmd.Flags().String("b", "", "Side B: server URL or snapshot file (required)")
+	diffCmd.Flags().String("request", "", "JSON file with the /v1/retrieve request body; the query argument and flags override it")
+	diffCmd.Flags().StringP("namespace", "n", "", "Namespace to query")
+	diffCmd.Flags().Int("target-k", 0, "Chunks to return (0 = server default)")
+	diffCmd.Flags().String("template", "", "Also diff the context rendered with this template")
+	diffCmd.Flags().String("save", "", "Write side B's response to this snapshot file")
+	diffCmd.Flags().Duration("timeout", 30*time.Second, "Timeout for each server request")
+
+	// Output settings
+	diffCmd.Flags().Bool("show-text", false, "Show the text of added, removed, and changed chunks")
+	diffCmd.Flags().Int("text-limit", 200, "Max characters of text to show per chunk (0 = all)")
+	diffCmd.Flags().Bool("json", false, "Print the comparison as JSON")
+	diffCmd.Flags().Bool("exit-code", false, "Exit with status 1 when the contexts differ")
+}
+
+// diffSide is one side of a comparison: its label and response.
+type diffSide struct {
+	label string
+	resp  RetrieveResponse
+	raw   []byte
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	sourceA, _ := cmd.Flags().GetString("a")
+	sourceB, _ := cmd.Flags().GetString("b")
+	requestPath, _ := cmd.Flags().GetString("request")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	targetK, _ := cmd.Flags().GetInt("target-k")
+	templateName, _ := cmd.Flags().GetString("template")
+	savePath, _ := cmd.Flags().GetString("save")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	showText, _ := cmd.Flags().GetBool("show-text")
+	textLimit, _ := cmd.Flags().GetInt("text-limit")
+	asJSON, _ := cmd.Flags().GetBool("json")
+	exitCode, _ := cmd.Flags().GetBool("exit-code")
+
+	if sourceA == "" || sourceB == "" {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("both --a and --b are required"))
+	}
+
+	// Build the request body shared by both sides
+	body := map[string]interface{}{}
+	if requestPath != "" {
+		data, err := os.ReadFile(requestPath)
+		if err != nil {
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("failed to read request: %w", err))
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("failed to parse request %s: %w", requestPath, err))
+		}
+	}
+	if len(args) > 0 {
+		body["query"] = strings.Join(args, " ")
+	}
+	if namespace != "" {
+		body["namespace"] = namespace
+	}
+	if targetK > 0 {
+		body["target_k"] = targetK
+	}
+	if templateName != "" {
+		body["template"] = templateName
+	}
+	if isURL(sourceA) || isURL(sourceB) {
+		if _, ok := body["query"]; !ok {
+			if _, ok := body["query_embedding"]; !ok {
+				return errs.Wrap(errs.ErrConfig, fmt.Errorf("a query is required: pass it as an argument or in --request"))
+			}
+		}
+	}
+
+	// Past flag validation, failures are not usage errors
+	cmd.SilenceUsage = true
+
+	client := &http.Client{Timeout: timeout}
+	a, err := loadDiffSide(client, sourceA, body)
+	if err != nil {
+		return fmt.Errorf("side A: %w", err)
+	}
+	b, err := loadDiffSide(client, sourceB, body)
+	if err != nil {
+		return fmt.Errorf("side B: %w", err)
+	}
+	if savePath != "" {
+		if err := os.WriteFile(savePath, b.raw, 0644); err != nil {
+			return fmt.Errorf("failed to save snapshot: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Saved side B to %s\n", savePath)
+	}
+
+	result := contextdiff.Compare(diffChunks(a.resp), diffChunks(b.resp))
+
+	// A snapshot may carry a rendering the other side was not asked for
+	_, rendering := body["template"]
+	compareRendered := rendering || (a.resp.Rendered != "" && b.resp.Rendered != "")
+	renderedDiffers := compareRendered && a.resp.Rendered != b.resp.Rendered
+
+	if asJSON {
+		out := map[string]interface{}{
+			"a":       map[string]interface{}{"source": a.label, "stats": a.resp.Stats},
+			"b":       map[string]interface{}{"source": b.label, "stats": b.resp.Stats},
+			"diff":    result,
+			"equal":   result.Equal() && !renderedDiffers,
+			"summary": diffSummary(result),
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	} else {
+		printDiff(a, b, result, compareRendered, showText, textLimit)
+	}
+
+	if exitCode && (!result.Equal() || renderedDiffers) {
+		return fmt.Errorf("contexts differ: %s", diffSummary(result))
+	}
+	return nil
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// loadDiffSide posts body to a server's /v1/retrieve, or reads a snapshot
+// file.
+func loadDiffSide(client *http.Client, source string, body map[string]interface{}) (diffSide, error) {
+	side := diffSide{label: source}
+	if isURL(source) {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return side, err
+		}
+		endpoint := strings.TrimSuffix(source, "/") + "/v1/retrieve"
+		resp, err := client.Post(endpoint, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			return side, errs.Wrap(errs.ErrBackend, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		side.raw, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return side, errs.Wrap(errs.ErrBackend, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := fmt.Errorf("%s returned %s: %s", endpoint, resp.Status, strings.TrimSpace(string(side.raw)))
+			if resp.StatusCode == http.StatusBadRequest {
+				return side, errs.Wrap(errs.ErrConfig, err)
+			}
+			return side, errs.Wrap(errs.ErrBackend, err)
+		}
+	} else {
+		var err error
+		side.raw, err = os.ReadFile(source)
+		if err != nil {
+			return side, errs.Wrap(errs.ErrConfig, fmt.Errorf("failed to read snapshot: %w", err))
+		}
+	}
+	if err := json.Unmarshal(side.raw, &side.resp); err != nil {
+		return side, errs.Wrap(errs.ErrConfig, fmt.Errorf("%s is not a /v1/retrieve response: %w", source, err))
+	}
+	return side, nil
+}
+
+func diffChunks(resp RetrieveResponse) []contextdiff.Chunk {
+	chunks := make([]contextdiff.Chunk, len(resp.Chunks))
+	for i, c := range resp.Chunks {
+		chunks[i] = contextdiff.Chunk{ID: c.ID, Text: c.Text, Score: c.Score}
+	}
+	return chunks
+}
+
+func diffSummary(r contextdiff.Result) string {
+	return fmt.Sprintf("%d added, %d removed, %d moved, %d changed, %d unchanged",
+		r.Added, r.Removed, r.Moved, r.Changed, r.Unchanged)
+}
+
+// printDiff writes the side-by-side comparison.
+func printDiff(a, b diffSide, r contextdiff.Result, compareRendered, showText bool, textLimit int) {
+	fmt.Printf("A: %s (%d chunks from %d retrieved, %d clusters)\n", a.label, len(a.resp.Chunks), a.resp.Stats.Retrieved, a.resp.Stats.Clustered)
+	fmt.Printf("B: %s (%d chunks from %d retrieved, %d clusters)\n\n", b.label, len(b.resp.Chunks), b.resp.Stats.Retrieved, b.resp.Stats.Clustered)
+
+	rank := func(n int) string {
+		if n == 0 {
+			return "-"
+		}
+		return fmt.Sprint(n)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "A\tB\tID\tCHANGE")
+	for _, e := range r.Entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", rank(e.RankA), rank(e.RankB), diffID(e), diffChange(e))
+	}
+	_ = tw.Flush()
+
+	if showText {
+		for _, e := range r.Entries {
+			if e.Unchanged() || (e.Moved() && !e.TextChanged()) {
+				continue
+			}
+			fmt.Printf("\n--- %s (%s)\n", diffID(e), diffChange(e))
+			if e.TextA != "" {
+				fmt.Printf("A: %s\n", limitText(e.TextA, textLimit))
+			}
+			if e.TextB != "" {
+				fmt.Printf("B: %s\n", limitText(e.TextB, textLimit))
+			}
+		}
+	}
+
+	if compareRendered {
+		fmt.Println("\n=== Rendered ===")
+		if a.resp.Rendered == b.resp.Rendered {
+			fmt.Println("(identical)")
+		} else {
+			for _, l := range contextdiff.Lines(a.resp.Rendered, b.resp.Rendered) {
+				fmt.Printf("%c %s\n", l.Op, l.Text)
+			}
+		}
+	}
+
+	fmt.Printf("\n%s\n", diffSummary(r))
+}
+
+func diffID(e contextdiff.Entry) string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return "(no id)"
+}
+
+func diffChange(e contextdiff.Entry) string {
+	switch {
+	case e.Added():
+		return "added"
+	case e.Removed():
+		return "removed"
+	}
+	var parts []string
+	if e.Moved() {
+		parts = append(parts, fmt.Sprintf("moved %d → %d", e.RankA, e.RankB))
+	}
+	if e.TextChanged() {
+		parts = append(parts, fmt.Sprintf("text changed (%d → %d chars)", len(e.TextA), len(e.TextB)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func limitText(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); n > 0 && len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return s
+}
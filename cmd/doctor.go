@@ -0,0 +1,589 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	distillcache "github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/config"
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/dedup"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	fakeretriever "github.com/Siddhant-K-code/distill/pkg/retriever/fake"
+	pcretriever "github.com/Siddhant-K-code/distill/pkg/retriever/pinecone"
+	qdretriever "github.com/Siddhant-K-code/distill/pkg/retriever/qdrant"
+	"github.com/Siddhant-K-code/distill/pkg/telemetry"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that config, backend, and embedder work together",
+	Long: `Runs the checks a first serve depends on and prints a fix for each
+failure:
+
+  config       the config file, environment, and defaults validate
+  backend      the vector database client can be created
+  embedder     the embedding provider embeds a sample query
+  retrieval    a sample query returns matches
+  dimension    the index's vectors match the embedder's dimension
+  clustering   the sample clusters and deduplicates
+  cache        the query caches can be created and used
+  telemetry    traces export to the configured collector
+
+Settings are read like serve reads them, from the config file and
+DISTILL_* variables; the flags below override them. Checks that depend
+on a failed check are skipped. Exits non-zero if any check fails.
+
+Example:
+  distill doctor
+  distill doctor --backend qdrant --db-host localhost --index docs
+  distill doctor --backend fake --embedding-provider fake --json`,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().String("backend", "", "Vector DB backend (pinecone, qdrant, fake) (config: retriever.backend)")
+	doctorCmd.Flags().StringP("index", "i", "", "Index/collection name (config: retriever.index)")
+	doctorCmd.Flags().String("api-key", "", "Vector DB API key (or PINECONE_API_KEY)")
+	doctorCmd.Flags().String("db-host", "", "Vector DB host for Qdrant (config: retriever.host)")
+	doctorCmd.Flags().StringP("namespace", "n", "", "Namespace (config: retriever.namespace)")
+	doctorCmd.Flags().String("openai-key", "", "API key for embeddings (or OPENAI_API_KEY / COHERE_API_KEY / VOYAGE_API_KEY)")
+	doctorCmd.Flags().String("embedding-provider", "", "Embedding provider (openai, ollama, cohere, voyage, local, fake) (config: embedding.provider)")
+	doctorCmd.Flags().String("embedding-model", "", "Embedding model (config: embedding.model)")
+	doctorCmd.Flags().String("embedding-base-url", "", "Embedding API base URL (config: embedding.base_url)")
+	doctorCmd.Flags().String("query", "how do I get started", "Sample query for the retrieval checks")
+	doctorCmd.Flags().Int("sample-size", 50, "Matches to retrieve for the sample")
+	doctorCmd.Flags().Duration("timeout", 15*time.Second, "Time limit for each check")
+	doctorCmd.Flags().Bool("json", false, "Print the checks as JSON")
+}
+
+// Doctor check statuses.
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+	doctorSkip = "skip"
+)
+
+// DoctorCheck is the outcome of one doctor check.
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Fix    string `json:"fix,omitempty"`
+
+	err error
+}
+
+// doctorSettings are the settings doctor checks, resolved from flags and
+// config.
+type doctorSettings struct {
+	backend        string
+	index          string
+	apiKey         string
+	dbHost         string
+	namespace      string
+	openaiKey      string
+	provider       string
+	model          string
+	baseURL        string
+	query          string
+	sampleSize     int
+	timeout        time.Duration
+	threshold      float64
+	lambda         float64
+	targetK        int
+	enableMMR      bool
+	tracingEnabled bool
+}
+
+// doctorString returns flag when it was passed and the config key
+// otherwise.
+func doctorString(cmd *cobra.Command, flag, key string) string {
+	if cmd.Flags().Changed(flag) {
+		v, _ := cmd.Flags().GetString(flag)
+		return v
+	}
+	return viper.GetString(key)
+}
+
+func resolveDoctorSettings(cmd *cobra.Command) doctorSettings {
+	s := doctorSettings{
+		backend:        doctorString(cmd, "backend", "retriever.backend"),
+		index:          doctorString(cmd, "index", "retriever.index"),
+		dbHost:         doctorString(cmd, "db-host", "retriever.host"),
+		namespace:      doctorString(cmd, "namespace", "retriever.namespace"),
+		provider:       doctorString(cmd, "embedding-provider", "embedding.provider"),
+		model:          doctorString(cmd, "embedding-model", "embedding.model"),
+		baseURL:        doctorString(cmd, "embedding-base-url", "embedding.base_url"),
+		threshold:      viper.GetFloat64("dedup.threshold"),
+		lambda:         viper.GetFloat64("dedup.lambda"),
+		targetK:        viper.GetInt("retriever.target_k"),
+		enableMMR:      viper.GetBool("dedup.enable_mmr"),
+		tracingEnabled: viper.GetBool("telemetry.tracing.enabled"),
+	}
+	s.apiKey, _ = cmd.Flags().GetString("api-key")
+	if s.apiKey == "" {
+		s.apiKey = os.Getenv("PINECONE_API_KEY")
+	}
+	s.openaiKey, _ = cmd.Flags().GetString("openai-key")
+	if s.provider == "" {
+		s.provider = "openai"
+	}
+	s.query, _ = cmd.Flags().GetString("query")
+	s.sampleSize, _ = cmd.Flags().GetInt("sample-size")
+	s.timeout, _ = cmd.Flags().GetDuration("timeout")
+	return s
+}
+
+// doctorRun holds what earlier checks produced for later ones.
+type doctorRun struct {
+	s        doctorSettings
+	ret      retriever.Retriever
+	embedder retriever.EmbeddingProvider
+	vector   []float32
+	sample   []types.Chunk
+	checks   []DoctorCheck
+}
+
+// add records a check. A failed check needs an error, which decides the
+// exit code.
+func (r *doctorRun) add(c DoctorCheck) {
+	if c.Status == doctorFail && c.err == nil {
+		c.err = errors.New(c.Detail)
+	}
+	r.checks = append(r.checks, c)
+}
+
+// skip records a check that cannot run.
+func (r *doctorRun) skip(name, reason string) {
+	r.add(DoctorCheck{Name: name, Status: doctorSkip, Detail: reason})
+}
+
+// fail records a failed check.
+func (r *doctorRun) fail(name string, err error, fix string) {
+	r.add(DoctorCheck{Name: name, Status: doctorFail, Detail: err.Error(), Fix: fix, err: err})
+}
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	run := &doctorRun{s: resolveDoctorSettings(cmd)}
+	if run.s.timeout <= 0 {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("--timeout must be positive, got %s", run.s.timeout))
+	}
+	if run.s.sampleSize <= 0 {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("--sample-size must be positive, got %d", run.s.sampleSize))
+	}
+	cmd.SilenceUsage = true
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	run.checkConfig()
+	run.checkBackend(ctx)
+	run.checkEmbedder(ctx)
+	run.checkRetrieval(ctx)
+	run.checkDimension()
+	run.checkClustering()
+	run.checkCache(ctx)
+	run.checkTelemetry(ctx)
+	if run.ret != nil {
+		_ = run.ret.Close()
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(run.checks); err != nil {
+			return err
+		}
+	} else {
+		printDoctorChecks(run.checks)
+	}
+
+	var failed []DoctorCheck
+	for _, c := range run.checks {
+		if c.Status == doctorFail {
+			failed = append(failed, c)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d checks failed, first %s: %w", len(failed), len(run.checks), failed[0].Name, failed[0].err)
+	}
+	return nil
+}
+
+// printDoctorChecks prints one line per check, with its fix indented
+// below, and a summary.
+func printDoctorChecks(checks []DoctorCheck) {
+	counts := map[string]int{}
+	for _, c := range checks {
+		counts[c.Status]++
+		fmt.Printf("  %-4s  %-10s  %s\n", strings.ToUpper(c.Status), c.Name, c.Detail)
+		if c.Fix != "" {
+			fmt.Printf("               fix: %s\n", c.Fix)
+		}
+	}
+	fmt.Printf("\n%d ok, %d warnings, %d failed, %d skipped\n", counts[doctorOK], counts[doctorWarn], counts[doctorFail], counts[doctorSkip])
+}
+
+func (r *doctorRun) checkConfig() {
+	const name = "config"
+	if _, err := config.Load(viper.GetViper()); err != nil {
+		r.fail(name, errs.Wrap(errs.ErrConfig, err), "Fix the keys listed, then run distill config validate. distill config init writes a template with every key.")
+		return
+	}
+	source := "no config file, using defaults and environment"
+	if used := viper.ConfigFileUsed(); used != "" {
+		source = used + " is valid"
+	}
+	r.add(DoctorCheck{Name: name, Status: doctorOK, Detail: source})
+}
+
+func (r *doctorRun) checkBackend(ctx context.Context) {
+	const name = "backend"
+	ctx, cancel := context.WithTimeout(ctx, r.s.timeout)
+	defer cancel()
+
+	s := r.s
+	var err error
+	var detail string
+	switch s.backend {
+	case "pinecone":
+		if s.apiKey == "" {
+			r.fail(name, errs.Wrap(errs.ErrConfig, fmt.Errorf("pinecone API key missing")), "Set PINECONE_API_KEY or pass --api-key.")
+			return
+		}
+		var params pcretriever.Params
+		if params, err = pineconeParams(); err != nil {
+			r.fail(name, err, "Fix retriever.params.pinecone; see docs/reference/configuration.md.")
+			return
+		}
+		if s.index == "" && params.IndexHost == "" {
+			r.fail(name, errs.Wrap(errs.ErrConfig, fmt.Errorf("pinecone index missing")), "Set retriever.index or pass --index with the index name.")
+			return
+		}
+		r.ret, err = pcretriever.NewClient(ctx, pcretriever.Config{
+			Config: retriever.Config{
+				APIKey:           s.apiKey,
+				DefaultNamespace: s.namespace,
+				MetadataFields:   metadataFields(),
+				TimeoutSeconds:   retrieverTimeout(),
+				MaxRetries:       retrieverMaxRetries(),
+			},
+			IndexName: s.index,
+			Params:    params,
+		})
+		detail = fmt.Sprintf("pinecone index %s", s.index)
+
+	case "qdrant":
+		if s.dbHost == "" {
+			r.fail(name, errs.Wrap(errs.ErrConfig, fmt.Errorf("qdrant host missing")), "Set retriever.host or pass --db-host with the Qdrant host, without a port (gRPC uses 6334).")
+			return
+		}
+		if s.index == "" {
+			r.fail(name, errs.Wrap(errs.ErrConfig, fmt.Errorf("qdrant collection missing")), "Set retriever.index or pass --index with the collection name.")
+			return
+		}
+		var params qdretriever.Params
+		if params, err = qdrantParams(); err != nil {
+			r.fail(name, err, "Fix retriever.params.qdrant; see docs/reference/configuration.md.")
+			return
+		}
+		r.ret, err = qdretriever.NewClient(ctx, qdretriever.Config{
+			Config: retriever.Config{
+				APIKey:           s.apiKey,
+				Host:             s.dbHost,
+				DefaultNamespace: s.namespace,
+				MetadataFields:   metadataFields(),
+				TimeoutSeconds:   retrieverTimeout(),
+				MaxRetries:       retrieverMaxRetries(),
+			},
+			Collection: s.index,
+			Params:     params,
+		})
+		detail = fmt.Sprintf("qdrant collection %s at %s", s.index, s.dbHost)
+
+	case fakeBackend:
+		var fr *fakeretriever.Client
+		if fr, err = newFakeRetriever(); err == nil {
+			r.ret = fr
+			detail = fmt.Sprintf("fake corpus of %d chunks", fr.Len())
+		}
+
+	default:
+		r.fail(name, errs.Wrap(errs.ErrConfig, fmt.Errorf("unsupported backend %q", s.backend)), "Set retriever.backend or pass --backend: pinecone, qdrant, or fake.")
+		return
+	}
+
+	if err != nil {
+		r.ret = nil
+		r.fail(name, err, remoteFix(err, "the vector database", "Check the host and index name, and that the database is reachable from here."))
+		return
+	}
+	r.add(DoctorCheck{Name: name, Status: doctorOK, Detail: detail})
+}
+
+func (r *doctorRun) checkEmbedder(ctx context.Context) {
+	const name = "embedder"
+	ctx, cancel := context.WithTimeout(ctx, r.s.timeout)
+	defer cancel()
+
+	s := r.s
+	var source string
+	if fr, ok := r.ret.(*fakeretriever.Client); ok {
+		// Queries must share the synthetic corpus's vector space.
+		r.embedder = fr.Embedder()
+		source = "fake corpus embedder"
+	} else {
+		if env, cloud := embeddingKeyEnv[s.provider]; cloud && embeddingAPIKey(s.provider, s.openaiKey) == "" {
+			r.fail(name, errs.Wrap(errs.ErrConfig, fmt.Errorf("%s API key missing", s.provider)),
+				fmt.Sprintf("Set %s (or pass --openai-key), or use a local provider with --embedding-provider ollama or local.", env))
+			return
+		}
+		if _, err := embeddingNormalization(s.provider); err != nil {
+			r.fail(name, err, "Fix the embedding.normalization section for this provider.")
+			return
+		}
+		embedder, err := newEmbedder(s.provider, s.openaiKey, s.model, s.baseURL)
+		if err != nil {
+			r.fail(name, errs.Wrap(errs.ErrConfig, err), "Set embedding.provider to openai, ollama, cohere, voyage, local, or fake, and embedding.model to a model it serves (for local, a model directory).")
+			return
+		}
+		r.embedder = embedder
+		source = s.provider
+	}
+
+	start := time.Now()
+	vector, err := retriever.EmbedQuery(ctx, r.embedder, s.query)
+	if err != nil {
+		r.embedder = nil
+		r.fail(name, errs.ClassifyRemote(err), remoteFix(errs.ClassifyRemote(err), "the embedding provider",
+			"Check embedding.base_url and that the provider is reachable; for Ollama, run ollama serve and ollama pull the model."))
+		return
+	}
+	if dim := r.embedder.Dimension(); dim > 0 && len(vector) != dim {
+		r.fail(name, errs.Wrap(errs.ErrConfig, fmt.Errorf("model %s returned %d dimensions, expected %d", r.embedder.ModelName(), len(vector), dim)),
+			"Set embedding.model to the model the provider actually serves.")
+		r.embedder = nil
+		return
+	}
+	r.vector = vector
+	r.add(DoctorCheck{Name: name, Status: doctorOK, Detail: fmt.Sprintf("%s %s, %d dimensions, %s", source, r.embedder.ModelName(), len(vector), time.Since(start).Round(time.Millisecond))})
+}
+
+func (r *doctorRun) checkRetrieval(ctx context.Context) {
+	const name = "retrieval"
+	switch {
+	case r.ret == nil:
+		r.skip(name, "needs a working backend")
+		return
+	case r.vector == nil:
+		r.skip(name, "needs a working embedder")
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.s.timeout)
+	defer cancel()
+
+	req := &types.RetrievalRequest{
+		QueryEmbedding:    r.vector,
+		TopK:              r.s.sampleSize,
+		Namespace:         r.s.namespace,
+		IncludeEmbeddings: true,
+		IncludeMetadata:   true,
+		ExcludeFilter:     map[string]interface{}{dedup.TombstoneKey: true},
+	}
+	start := time.Now()
+	result, err := r.ret.Query(ctx, req)
+	if err != nil {
+		err = errs.ClassifyRemote(err)
+		fix := remoteFix(err, "the vector database", "Check that the database is reachable and the index or collection exists.")
+		if strings.Contains(strings.ToLower(err.Error()), "dimension") {
+			fix = "The index expects a different vector size than the embedder produces. Set embedding.model to the model the index was built with, or rebuild the index with distill reindex."
+		}
+		r.fail(name, err, fix)
+		return
+	}
+	r.sample = retriever.DropExcluded(result.Chunks, req.ExcludeFilter)
+	if len(r.sample) == 0 {
+		where := "the index"
+		if r.s.namespace != "" {
+			where = fmt.Sprintf("namespace %q", r.s.namespace)
+		}
+		r.add(DoctorCheck{Name: name, Status: doctorWarn, Detail: "no matches for the sample query",
+			Fix: fmt.Sprintf("Check that %s has vectors; load some with distill sync.", where)})
+		return
+	}
+	r.add(DoctorCheck{Name: name, Status: doctorOK, Detail: fmt.Sprintf("%d matches in %s, top score %.3f", len(r.sample), time.Since(start).Round(time.Millisecond), r.sample[0].Score)})
+}
+
+func (r *doctorRun) checkDimension() {
+	const name = "dimension"
+	if len(r.sample) == 0 {
+		r.skip(name, "needs sample matches")
+		return
+	}
+	want := len(r.vector)
+	missing, mismatched := 0, 0
+	got := 0
+	for _, c := range r.sample {
+		switch {
+		case len(c.Embedding) == 0:
+			missing++
+		case len(c.Embedding) != want:
+			mismatched++
+			got = len(c.Embedding)
+		}
+	}
+	switch {
+	case mismatched > 0:
+		r.fail(name, errs.Wrap(errs.ErrConfig, fmt.Errorf("%d of %d vectors have %d dimensions, the embedder has %d", mismatched, len(r.sample), got, want)),
+			"Set embedding.model to the model the index was built with, or re-embed the corpus with distill reindex.")
+	case missing == len(r.sample):
+		r.add(DoctorCheck{Name: name, Status: doctorWarn, Detail: "the backend returned no vectors",
+			Fix: "Clustering needs stored vectors; check that the index stores dense vectors rather than sparse-only or payload-only points."})
+	case missing > 0:
+		r.add(DoctorCheck{Name: name, Status: doctorWarn, Detail: fmt.Sprintf("%d of %d matches have no vector", missing, len(r.sample)),
+			Fix: "Those chunks cannot be clustered; re-upload them with distill sync."})
+	default:
+		r.add(DoctorCheck{Name: name, Status: doctorOK, Detail: fmt.Sprintf("index and embedder both use %d dimensions", want)})
+	}
+}
+
+func (r *doctorRun) checkClustering() {
+	const name = "clustering"
+	var embedded []types.Chunk
+	for _, c := range r.sample {
+		if len(c.Embedding) == len(r.vector) {
+			embedded = append(embedded, c)
+		}
+	}
+	if len(embedded) == 0 {
+		r.skip(name, "needs sample matches with vectors")
+		return
+	}
+
+	broker := contextlab.NewBroker(nil, contextlab.BrokerConfig{
+		TargetK:           r.s.targetK,
+		ClusterThreshold:  r.s.threshold,
+		ClusterLinkage:    "average",
+		SelectionStrategy: contextlab.SelectByScore,
+		EnableMMR:         r.s.enableMMR,
+		MMRLambda:         r.s.lambda,
+	})
+	result := broker.ProcessChunks(embedded)
+	st := result.Stats
+	detail := fmt.Sprintf("%d matches, %d clusters, %d returned in %s", st.Retrieved, st.Clustered, st.Returned, st.ClusteringLatency.Round(time.Microsecond))
+	if st.Clustered == 1 && st.Retrieved > 2 {
+		r.add(DoctorCheck{Name: name, Status: doctorWarn, Detail: detail,
+			Fix: fmt.Sprintf("Every match merged into one cluster; lower dedup.threshold (now %g) or check that vectors are not all identical.", broker.GetConfig().ClusterThreshold)})
+		return
+	}
+	r.add(DoctorCheck{Name: name, Status: doctorOK, Detail: detail})
+}
+
+func (r *doctorRun) checkCache(ctx context.Context) {
+	const name = "cache"
+	caches, err := newQueryCaches()
+	if err != nil {
+		r.fail(name, err, "Fix the cache.* keys or --*-cache-* flags; sizes and TTLs must be non-negative. A disk cache can only be opened by one process at a time; a Redis cache needs cache.redis.url to be reachable.")
+		return
+	}
+	defer caches.Close()
+
+	var parts []string
+	for _, c := range []struct {
+		label string
+		cache distillcache.Cache
+	}{{"embeddings", caches.embeddings}, {"results", caches.results}} {
+		if c.cache == nil {
+			parts = append(parts, c.label+" off")
+			continue
+		}
+		if err := probeCache(ctx, c.cache); err != nil {
+			r.fail(name, fmt.Errorf("%s cache: %w", c.label, err), "Lower cache.embedding_size or cache.result_size, or turn the cache off with a size or TTL of 0.")
+			return
+		}
+		parts = append(parts, fmt.Sprintf("%s on (%s)", c.label, caches.backend))
+	}
+	r.add(DoctorCheck{Name: name, Status: doctorOK, Detail: strings.Join(parts, ", ")})
+}
+
+// probeCache writes, reads, and deletes a key.
+func probeCache(ctx context.Context, c distillcache.Cache) error {
+	const key = "doctor:probe"
+	want := []byte("ok")
+	if err := c.Set(ctx, key, want, time.Minute); err != nil {
+		return err
+	}
+	got, err := c.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if string(got) != string(want) {
+		return fmt.Errorf("read back %q, wrote %q", got, want)
+	}
+	return c.Delete(ctx, key)
+}
+
+func (r *doctorRun) checkTelemetry(ctx context.Context) {
+	const name = "telemetry"
+	if !r.s.tracingEnabled {
+		r.skip(name, "tracing is off")
+		return
+	}
+	cfg := telemetry.DefaultConfig()
+	cfg.Enabled = true
+	if ep := viper.GetString("telemetry.tracing.endpoint"); ep != "" {
+		cfg.Endpoint = ep
+	}
+	if exp := viper.GetString("telemetry.tracing.exporter"); exp != "" {
+		cfg.Exporter = exp
+	}
+	if cfg.Exporter == "none" {
+		r.skip(name, "the exporter is none")
+		return
+	}
+	if cfg.Exporter == "stdout" {
+		r.add(DoctorCheck{Name: name, Status: doctorOK, Detail: "traces print to stdout"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.s.timeout)
+	defer cancel()
+	tp, err := telemetry.Init(ctx, cfg)
+	if err != nil {
+		r.fail(name, errs.Wrap(errs.ErrConfig, err), "Set telemetry.tracing.exporter to otlp, stdout, or none.")
+		return
+	}
+	_, span := tp.StartRequest(ctx, "doctor")
+	span.End()
+	// Shutdown flushes the span, so an unreachable collector fails here
+	if err := tp.Shutdown(ctx); err != nil {
+		r.fail(name, errs.Wrap(errs.ErrBackend, fmt.Errorf("exporting to %s: %w", cfg.Endpoint, err)),
+			"Check that an OTLP gRPC collector listens on telemetry.tracing.endpoint, or set telemetry.tracing.enabled to false.")
+		return
+	}
+	r.add(DoctorCheck{Name: name, Status: doctorOK, Detail: fmt.Sprintf("exported a test span to %s", cfg.Endpoint)})
+}
+
+// remoteFix is the fix for an error from service: credentials for auth
+// failures, otherwise fallback.
+func remoteFix(err error, service, fallback string) string {
+	if errors.Is(err, errs.ErrAuth) {
+		return fmt.Sprintf("Check the API key for %s; it was rejected.", service)
+	}
+	return fallback
+}
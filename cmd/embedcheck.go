@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// batchEmbedder is the part of an embedding provider that embedding
+// validation needs. Both server commands' providers satisfy it.
+type batchEmbedder interface {
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	Dimension() int
+}
+
+// checkEmbeddingMode rejects an unknown validate_embeddings value.
+func checkEmbeddingMode(mode string) error {
+	if !contextlab.ValidEmbeddingCheck(mode) {
+		return fmt.Errorf("unknown validate_embeddings mode %q (use %q or %q)", mode, contextlab.EmbeddingCheckReject, contextlab.EmbeddingCheckRepair)
+	}
+	return nil
+}
+
+// embeddingRepair is the outcome of validating caller-supplied embeddings.
+type embeddingRepair struct {
+	// Repaired are the indexes re-embedded from their text.
+	Repaired []int
+
+	// Unrepaired are the suspect embeddings left as they were: every
+	// issue in reject mode, and in repair mode those without text or
+	// without an embedder to repair them with.
+	Unrepaired []contextlab.EmbeddingIssue
+}
+
+// validateEmbeddings checks vectors as contextlab.CheckEmbeddings does,
+// expecting the embedder's dimension when there is one. In repair mode
+// suspect vectors whose text is non-empty are re-embedded in place.
+// names label vectors in the returned error, which is a
+// *contextlab.EmbeddingCheckError when unrepaired issues remain.
+func validateEmbeddings(ctx context.Context, mode string, embedder batchEmbedder, vectors [][]float32, texts, names []string) (embeddingRepair, error) {
+	var out embeddingRepair
+	if mode == "" {
+		return out, nil
+	}
+
+	cfg := contextlab.DefaultEmbeddingCheckConfig()
+	if embedder != nil {
+		cfg.Dimension = embedder.Dimension()
+	}
+	issues := contextlab.CheckEmbeddings(vectors, cfg)
+	if len(issues) == 0 {
+		return out, nil
+	}
+
+	var fix []int
+	var fixTexts []string
+	for _, issue := range issues {
+		if mode == contextlab.EmbeddingCheckRepair && embedder != nil && texts[issue.Index] != "" {
+			fix = append(fix, issue.Index)
+			fixTexts = append(fixTexts, texts[issue.Index])
+			continue
+		}
+		out.Unrepaired = append(out.Unrepaired, issue)
+	}
+
+	if len(fix) > 0 {
+		embeddings, err := embedder.EmbedBatch(ctx, fixTexts)
+		if err != nil {
+			return out, fmt.Errorf("failed to re-embed %d chunk(s): %w", len(fix), err)
+		}
+		for j, i := range fix {
+			vectors[i] = embeddings[j]
+		}
+		out.Repaired = fix
+	}
+	if len(out.Unrepaired) > 0 {
+		return out, &contextlab.EmbeddingCheckError{Issues: out.Unrepaired, Names: names}
+	}
+	return out, nil
+}
+
+// checkChunkEmbeddings validates the embeddings chunks carry, repairing
+// them in place in repair mode, and returns how many were repaired.
+func (s *APIServer) checkChunkEmbeddings(ctx context.Context, mode string, chunks []types.Chunk) (int, error) {
+	vectors := make([][]float32, len(chunks))
+	texts := make([]string, len(chunks))
+	names := make([]string, len(chunks))
+	for i, c := range chunks {
+		vectors[i], texts[i], names[i] = c.Embedding, c.Text, c.ID
+	}
+
+	var embedder batchEmbedder
+	if s.embedder != nil {
+		embedder = s.embedder
+	}
+	out, err := validateEmbeddings(ctx, mode, embedder, vectors, texts, names)
+	for _, i := range out.Repaired {
+		chunks[i].Embedding = vectors[i]
+	}
+	return len(out.Repaired), err
+}
+
+// embeddingCheckStatus is the HTTP status for an error from
+// validateEmbeddings: 400 for suspect embeddings, 500 if re-embedding
+// failed.
+func embeddingCheckStatus(err error) int {
+	var checkErr *contextlab.EmbeddingCheckError
+	if errors.As(err, &checkErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// embeddingCheckMessage is the client-facing text for an error from
+// validateEmbeddings.
+func embeddingCheckMessage(err error) string {
+	var checkErr *contextlab.EmbeddingCheckError
+	if errors.As(err, &checkErr) {
+		return "Invalid embeddings: " + err.Error()
+	}
+	return err.Error()
+}
+
+// queryCheck counts query embeddings changed by validate_embeddings.
+type queryCheck struct {
+	repaired int
+	dropped  int
+}
+
+// checkQueryEmbeddings validates req's query vectors. In repair mode
+// query_embedding is re-embedded from the query text, and suspect
+// query_embeddings entries, which have no text, are dropped as long as
+// another query remains.
+func (s *Server) checkQueryEmbeddings(ctx context.Context, mode string, req *types.RetrievalRequest) (queryCheck, error) {
+	var checked queryCheck
+	if mode == "" {
+		return checked, nil
+	}
+
+	vectors := append([][]float32{req.QueryEmbedding}, req.QueryEmbeddings...)
+	texts := make([]string, len(vectors))
+	names := make([]string, len(vectors))
+	texts[0], names[0] = req.Query, "query_embedding"
+	for i := 1; i < len(vectors); i++ {
+		names[i] = fmt.Sprintf("query_embeddings[%d]", i-1)
+	}
+
+	var embedder batchEmbedder
+	if s.embedder != nil {
+		embedder = s.embedder
+	}
+	out, err := validateEmbeddings(ctx, mode, embedder, vectors, texts, names)
+	var checkErr *contextlab.EmbeddingCheckError
+	if err != nil && !(mode == contextlab.EmbeddingCheckRepair && errors.As(err, &checkErr)) {
+		return checked, err
+	}
+
+	drop := make(map[int]bool, len(out.Unrepaired))
+	for _, issue := range out.Unrepaired {
+		drop[issue.Index] = true
+	}
+	remaining := len(req.Queries)
+	if req.Query != "" {
+		remaining++
+	}
+	for i, v := range vectors {
+		if len(v) > 0 && !drop[i] && (i > 0 || req.Query == "") {
+			remaining++
+		}
+	}
+	if remaining == 0 {
+		return checked, err
+	}
+
+	if drop[0] {
+		req.QueryEmbedding = nil
+	} else {
+		req.QueryEmbedding = vectors[0]
+	}
+	kept := req.QueryEmbeddings[:0:0]
+	for i, v := range vectors[1:] {
+		if !drop[i+1] {
+			kept = append(kept, v)
+		}
+	}
+	req.QueryEmbeddings = kept
+	checked.repaired = len(out.Repaired)
+	checked.dropped = len(drop)
+	return checked, nil
+}
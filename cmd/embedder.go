@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/cohere"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/fake"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/local"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/ollama"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/openai"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/voyage"
+	"github.com/spf13/viper"
+)
+
+// defaultEmbeddingModel is the --embedding-model default, an OpenAI model.
+const defaultEmbeddingModel = "text-embedding-3-small"
+
+// embeddingKeyEnv names the environment variable holding each cloud
+// embedding provider's API key.
+var embeddingKeyEnv = map[string]string{
+	"openai": "OPENAI_API_KEY",
+	"cohere": "COHERE_API_KEY",
+	"voyage": "VOYAGE_API_KEY",
+}
+
+// embeddingAPIKey returns the API key for provider: flagKey, given with
+// --openai-key, or else the provider's environment variable. Providers
+// that need no key get "".
+func embeddingAPIKey(provider, flagKey string) string {
+	env, ok := embeddingKeyEnv[provider]
+	if !ok {
+		return ""
+	}
+	if flagKey != "" {
+		return flagKey
+	}
+	return os.Getenv(env)
+}
+
+// newEmbedder builds the embedding provider named by provider (default
+// openai) with its normalization contract and the embedding.cache_size
+// cache of embedded texts. It returns nil, leaving text
+// queries disabled, when a cloud provider has no API key. The OpenAI
+// default model is dropped for other providers, so they use their own.
+func newEmbedder(provider, flagKey, model, baseURL string) (embedding.Provider, error) {
+	if provider == "" {
+		provider = string(embedding.ProviderOpenAI)
+	}
+	apiKey := embeddingAPIKey(provider, flagKey)
+	if _, cloud := embeddingKeyEnv[provider]; cloud && apiKey == "" {
+		return nil, nil
+	}
+	if model == defaultEmbeddingModel && provider != string(embedding.ProviderOpenAI) {
+		model = ""
+	}
+	norm, err := embeddingNormalization(provider)
+	if err != nil {
+		return nil, err
+	}
+	return embedding.NewProvider(embedding.ProviderConfig{
+		Type:          embedding.ProviderType(provider),
+		APIKey:        apiKey,
+		Model:         model,
+		BaseURL:       baseURL,
+		CacheSize:     embeddingCacheSize(),
+		CacheTTL:      embeddingCacheTTL(),
+		Normalization: norm,
+	})
+}
+
+// embeddingCacheSize returns embedding.cache_size as
+// ProviderConfig.CacheSize, where 0 means the default rather than off.
+func embeddingCacheSize() int {
+	if !viper.IsSet("embedding.cache_size") {
+		return 0
+	}
+	if size := viper.GetInt("embedding.cache_size"); size > 0 {
+		return size
+	}
+	return -1
+}
+
+// embeddingCacheTTL returns embedding.cache_ttl, 24 hours when unset.
+func embeddingCacheTTL() time.Duration {
+	if !viper.IsSet("embedding.cache_ttl") {
+		return 24 * time.Hour
+	}
+	return viper.GetDuration("embedding.cache_ttl")
+}
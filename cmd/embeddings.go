@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	distillmath "github.com/Siddhant-K-code/distill/pkg/math"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// EmbeddingOptions asks for chunk embeddings in a response, optionally
+// reduced server-side so clients that only plot similarity don't
+// download full-size vectors.
+type EmbeddingOptions struct {
+	IncludeEmbeddings bool `json:"include_embeddings,omitempty"`
+
+	// EmbeddingDims is the dimension to reduce returned embeddings to.
+	// Zero uses the server default; vectors already this small are
+	// returned as-is.
+	EmbeddingDims int `json:"embedding_dims,omitempty"`
+
+	// EmbeddingReduction is "truncate" (keep leading dimensions and
+	// renormalize) or "pca" (project onto the principal components of
+	// the returned set). Empty uses the server default.
+	EmbeddingReduction string `json:"embedding_reduction,omitempty"`
+}
+
+// addEmbeddingOutputFlags registers the defaults for embeddings returned
+// to clients. Like the limit flags, they are read directly so several
+// commands can share the embedding.* config keys.
+func addEmbeddingOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("embedding-response-dims", 0, "Default dimension for embeddings returned with include_embeddings, 0 = full (config: embedding.response_dims)")
+	cmd.Flags().String("embedding-response-reduction", distillmath.ReduceTruncate, "Default reduction for returned embeddings: truncate or pca (config: embedding.response_reduction)")
+}
+
+// embeddingOutput holds the resolved defaults for returned embeddings.
+type embeddingOutput struct {
+	dims      int
+	reduction string
+}
+
+// resolveEmbeddingOutput reads the embedding output flags, falling back
+// to config.
+func resolveEmbeddingOutput(cmd *cobra.Command) (embeddingOutput, error) {
+	out := embeddingOutput{reduction: distillmath.ReduceTruncate}
+
+	if viper.IsSet("embedding.response_dims") {
+		out.dims = viper.GetInt("embedding.response_dims")
+	}
+	if cmd.Flags().Changed("embedding-response-dims") {
+		out.dims, _ = cmd.Flags().GetInt("embedding-response-dims")
+	}
+	if viper.IsSet("embedding.response_reduction") {
+		out.reduction = viper.GetString("embedding.response_reduction")
+	}
+	if cmd.Flags().Changed("embedding-response-reduction") {
+		out.reduction, _ = cmd.Flags().GetString("embedding-response-reduction")
+	}
+
+	if err := checkEmbeddingReduction(out.dims, out.reduction); err != nil {
+		return out, errs.Wrap(errs.ErrConfig, err)
+	}
+	return out, nil
+}
+
+// settings resolves the dimension and reduction for a request, falling
+// back to the server defaults.
+func (o embeddingOutput) settings(opts EmbeddingOptions) (int, string, error) {
+	dims, reduction := o.dims, o.reduction
+	if opts.EmbeddingDims != 0 {
+		dims = opts.EmbeddingDims
+	}
+	if opts.EmbeddingReduction != "" {
+		reduction = opts.EmbeddingReduction
+	}
+	return dims, reduction, checkEmbeddingReduction(dims, reduction)
+}
+
+// check reports whether opts is a valid embedding request, so handlers
+// can reject it before doing any work.
+func (o embeddingOutput) check(opts EmbeddingOptions) error {
+	if !opts.IncludeEmbeddings {
+		return nil
+	}
+	_, _, err := o.settings(opts)
+	return err
+}
+
+// embeddings returns the embeddings of chunks as requested by opts, or
+// nil if opts does not ask for them.
+func (o embeddingOutput) embeddings(opts EmbeddingOptions, chunks []types.Chunk) ([][]float32, error) {
+	if !opts.IncludeEmbeddings {
+		return nil, nil
+	}
+	dims, reduction, err := o.settings(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(chunks))
+	for i, c := range chunks {
+		vectors[i] = c.Embedding
+	}
+	if dims == 0 {
+		return vectors, nil
+	}
+	return distillmath.Reduce(vectors, dims, reduction), nil
+}
+
+func checkEmbeddingReduction(dims int, reduction string) error {
+	if dims < 0 {
+		return fmt.Errorf("embedding dims must be non-negative, got %d", dims)
+	}
+	switch reduction {
+	case distillmath.ReduceTruncate, distillmath.ReducePCA:
+		return nil
+	default:
+		return fmt.Errorf("unknown embedding reduction %q (supported: truncate, pca)", reduction)
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Siddhant-K-code/distill/pkg/enrich"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/spf13/viper"
+)
+
+// enrichmentHook builds the hook configured in the enrichment section of
+// distill.yaml, or returns nil if enrichment.type is unset.
+func enrichmentHook() (*enrich.Hook, error) {
+	kind := viper.GetString("enrichment.type")
+	if kind == "" {
+		return nil, nil
+	}
+
+	cfg := enrich.DefaultConfig()
+	if viper.IsSet("enrichment.timeout") {
+		cfg.Timeout = viper.GetDuration("enrichment.timeout")
+	}
+	if viper.IsSet("enrichment.batch_size") {
+		cfg.BatchSize = viper.GetInt("enrichment.batch_size")
+	}
+	if viper.IsSet("enrichment.concurrency") {
+		cfg.Concurrency = viper.GetInt("enrichment.concurrency")
+	}
+	if p := viper.GetString("enrichment.failure_policy"); p != "" {
+		cfg.Policy = enrich.Policy(p)
+	}
+
+	var e enrich.Enricher
+	var err error
+	switch kind {
+	case "http":
+		headers := viper.GetStringMapString("enrichment.headers")
+		for k, v := range headers {
+			headers[k] = os.ExpandEnv(v)
+		}
+		e, err = enrich.NewHTTP(enrich.HTTPConfig{URL: viper.GetString("enrichment.url"), Headers: headers})
+	case "plugin":
+		e, err = enrich.LoadPlugin(viper.GetString("enrichment.plugin"))
+	default:
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("unsupported enrichment.type %q (use 'http' or 'plugin')", kind))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return enrich.New(e, cfg)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Siddhant-K-code/distill/pkg/config"
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addEntityFlags registers the entity veto flags.
+func addEntityFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("entity-veto", false, "Keep chunks naming different entities apart when clustering")
+	cmd.Flags().StringSlice("entity-terms", nil, "Entity vocabulary for --entity-veto (default: heuristic key terms)")
+}
+
+// entityConfig reads the entity veto flags, falling back to the
+// dedup.entities config section for flags left unset.
+func entityConfig(cmd *cobra.Command) contextlab.EntityConfig {
+	cfg := contextlab.EntityConfig{
+		Enabled: viper.GetBool("dedup.entities.enabled"),
+		Terms:   viper.GetStringSlice("dedup.entities.terms"),
+	}
+	if f := cmd.Flags().Lookup("entity-veto"); f != nil && f.Changed {
+		cfg.Enabled, _ = cmd.Flags().GetBool("entity-veto")
+	}
+	if f := cmd.Flags().Lookup("entity-terms"); f != nil && f.Changed {
+		cfg.Terms, _ = cmd.Flags().GetStringSlice("entity-terms")
+	}
+	return cfg
+}
+
+// namespaceEntities reads the per-namespace overrides in
+// dedup.entities.namespaces.
+func namespaceEntities() (map[string]contextlab.EntityConfig, error) {
+	var list []config.NamespaceEntities
+	if err := viper.UnmarshalKey("dedup.entities.namespaces", &list); err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("dedup.entities.namespaces: %w", err))
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	byNamespace := make(map[string]contextlab.EntityConfig, len(list))
+	for _, ns := range list {
+		if ns.Namespace == "" {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("dedup.entities.namespaces: entry without a namespace"))
+		}
+		if _, dup := byNamespace[ns.Namespace]; dup {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("dedup.entities.namespaces: %q is listed more than once", ns.Namespace))
+		}
+		byNamespace[ns.Namespace] = contextlab.EntityConfig{Enabled: ns.Enabled, Terms: ns.Terms}
+	}
+	return byNamespace, nil
+}
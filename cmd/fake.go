@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+
+	fakeembed "github.com/Siddhant-K-code/distill/pkg/embedding/fake"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	fakeretriever "github.com/Siddhant-K-code/distill/pkg/retriever/fake"
+	"github.com/spf13/viper"
+)
+
+// fakeBackend is the --backend value for the in-memory synthetic corpus.
+const fakeBackend = "fake"
+
+// newFakeRetriever builds the fake backend from the retriever.fake.*
+// config keys (or their DISTILL_RETRIEVER_FAKE_* variables). Commands
+// should embed queries with its Embedder so they share the corpus's
+// vector space.
+func newFakeRetriever() (*fakeretriever.Client, error) {
+	latency, err := fakeembed.ParseLatency(viper.GetString("retriever.fake.latency"))
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("retriever.fake.latency: %w", err))
+	}
+	errorRate := viper.GetFloat64("retriever.fake.error_rate")
+	if errorRate < 0 || errorRate > 1 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("retriever.fake.error_rate must be between 0 and 1, got %g", errorRate))
+	}
+	seed := viper.GetInt64("retriever.fake.seed")
+
+	return fakeretriever.NewClient(fakeretriever.Config{
+		CorpusSize:    viper.GetInt("retriever.fake.corpus_size"),
+		DuplicateRate: viper.GetFloat64("retriever.fake.duplicate_rate"),
+		Seed:          seed,
+		Embedder: fakeembed.NewEmbedder(fakeembed.Config{
+			Dimension: viper.GetInt("retriever.fake.dimension"),
+			Latency:   latency,
+			ErrorRate: errorRate,
+			Seed:      seed,
+		}),
+		Latency:        latency,
+		ErrorRate:      errorRate,
+		MetadataFields: metadataFields(),
+	})
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	distillcache "github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/garbage"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// garbageSightings is the most distinct texts remembered for the
+// repetition signal.
+const garbageSightings = 100000
+
+// addGarbageFlags adds the boilerplate filter flags to serve.
+func addGarbageFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("garbage-filter", false, "Drop boilerplate chunks such as cookie banners, navigation menus, and footers")
+	cmd.Flags().Float64("garbage-threshold", garbage.DefaultThreshold, "Boilerplate score (0-1) at or above which a chunk is dropped")
+	cmd.Flags().Int("garbage-min-repeats", garbage.DefaultMinRepeats, "Treat text shared by this many distinct chunks as boilerplate (0 = off)")
+	cmd.Flags().StringSlice("garbage-allow", nil, "Regular expressions for chunks the garbage filter never drops")
+
+	_ = viper.BindPFlag("garbage.enabled", cmd.Flags().Lookup("garbage-filter"))
+	_ = viper.BindPFlag("garbage.threshold", cmd.Flags().Lookup("garbage-threshold"))
+	_ = viper.BindPFlag("garbage.min_repeats", cmd.Flags().Lookup("garbage-min-repeats"))
+	_ = viper.BindPFlag("garbage.allow", cmd.Flags().Lookup("garbage-allow"))
+}
+
+// garbageFilter returns the boilerplate filter the flags ask for, or nil
+// when it is off. Texts seen for the repetition signal are kept in memory.
+func garbageFilter() (*garbage.Filter, error) {
+	if !viper.GetBool("garbage.enabled") {
+		return nil, nil
+	}
+	cfg := garbage.Config{
+		Threshold:  viper.GetFloat64("garbage.threshold"),
+		MinRepeats: viper.GetInt("garbage.min_repeats"),
+		RepeatTTL:  viper.GetDuration("garbage.repeat_ttl"),
+		Allow:      viper.GetStringSlice("garbage.allow"),
+	}
+	if cfg.MinRepeats > 0 {
+		cfg.Cache = distillcache.NewMemoryCache(distillcache.Config{MaxSize: garbageSightings})
+	}
+	return garbage.New(cfg)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/Siddhant-K-code/distill/pkg/grpcapi"
+	"github.com/Siddhant-K-code/distill/pkg/grpcapi/distillv1"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+)
+
+// addGRPCFlags adds the flag for serve's gRPC listener.
+func addGRPCFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("grpc-port", 0, "Also serve the gRPC API (Deduplicate, Retrieve, AnalyzeRedundancy) on this port (0 = off)")
+
+	_ = viper.BindPFlag("server.grpc_port", cmd.Flags().Lookup("grpc-port"))
+}
+
+// newGRPCServer returns a gRPC server for s's broker, embedder, limits,
+// and metrics.
+func newGRPCServer(s *Server) *grpc.Server {
+	api := grpcapi.NewServer(grpcapi.Config{
+		Broker:   s.broker,
+		Embedder: s.embedder,
+		Limits:   s.limits,
+		Metrics:  s.metrics,
+	})
+	g := grpc.NewServer(api.ServerOptions()...)
+	distillv1.RegisterDistillServer(g, api)
+	return g
+}
+
+// stopGRPC stops g gracefully, cutting off RPCs still running when ctx
+// ends.
+func stopGRPC(ctx context.Context, g *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		g.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		g.Stop()
+	}
+}
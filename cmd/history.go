@@ -0,0 +1,455 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/history"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultHistoryDB is the database the history commands read when neither
+// --db nor history.path is set.
+const defaultHistoryDB = "distill-history.db"
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List and export recorded job and request summaries",
+	Long: `Reads the history database that sync, analyze, serve, and api record into
+when started with --history-db (config: history.path).
+
+Each record holds item counts in and out, the reduction, the duration, and
+a fingerprint of the settings, so runs with the same configuration can be
+grouped.
+
+Examples:
+  distill sync --file data.jsonl --index my-index --history-db history.db
+  distill history list --db history.db --kind sync
+  distill history show --db history.db 6650c1a2e4b0f3a1c2d3e4f5
+  distill history export --db history.db --since 168h --output history.json
+  distill history prune --db history.db --older-than 720h`,
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded jobs and requests, newest first",
+	RunE:  runHistoryList,
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show one record",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHistoryShow,
+}
+
+var historyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export records as a JSON array",
+	RunE:  runHistoryExport,
+}
+
+var historyPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old records",
+	RunE:  runHistoryPrune,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyListCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyExportCmd)
+	historyCmd.AddCommand(historyPruneCmd)
+
+	historyCmd.PersistentFlags().String("db", "", "History database (default: history.path, or "+defaultHistoryDB+")")
+
+	for _, c := range []*cobra.Command{historyListCmd, historyExportCmd} {
+		c.Flags().String("kind", "", "Only records of this kind (sync, analyze, request, namespace_stats)")
+		c.Flags().String("name", "", "Only records with this name (input file or endpoint)")
+		c.Flags().Duration("since", 0, "Only records started within this long (0 = all)")
+	}
+	historyListCmd.Flags().Int("limit", 20, "Maximum records to list (0 = all)")
+	historyListCmd.Flags().Bool("json", false, "Print records as JSON")
+	historyShowCmd.Flags().Bool("json", false, "Print the record as JSON")
+	historyExportCmd.Flags().String("output", "", "Output JSON file (default: stdout)")
+	historyPruneCmd.Flags().Duration("older-than", 0, "Delete records started longer ago than this (required)")
+	_ = historyPruneCmd.MarkFlagRequired("older-than")
+}
+
+// addHistoryFlags registers the flag that turns on history recording.
+// Like the capture flags, it is read directly so several commands can
+// share the history.path config key.
+func addHistoryFlags(cmd *cobra.Command) {
+	cmd.Flags().String("history-db", "", "Record a summary of each run or request in this SQLite database (config: history.path)")
+}
+
+// historyPath returns the database to record into, or "" when recording
+// is off.
+func historyPath(cmd *cobra.Command) string {
+	if cmd.Flags().Changed("history-db") {
+		path, _ := cmd.Flags().GetString("history-db")
+		return path
+	}
+	return viper.GetString("history.path")
+}
+
+// historyWriter opens the history database for a server, or returns nil
+// when recording is off. Requests are recorded in the background.
+func historyWriter(cmd *cobra.Command) (*history.Writer, error) {
+	path := historyPath(cmd)
+	if path == "" {
+		return nil, nil
+	}
+	store, err := history.NewSQLiteStore(path)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("opening history database: %w", err))
+	}
+	return history.NewWriter(store, 0), nil
+}
+
+// historyJob records one sync or analyze run. A nil *historyJob records
+// nothing.
+type historyJob struct {
+	store  *history.SQLiteStore
+	record history.Record
+}
+
+// startHistoryJob opens the history database and starts timing a run of
+// kind over name, or returns nil when recording is off.
+func startHistoryJob(cmd *cobra.Command, kind, name string, settings map[string]interface{}) (*historyJob, error) {
+	path := historyPath(cmd)
+	if path == "" {
+		return nil, nil
+	}
+	store, err := history.NewSQLiteStore(path)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("opening history database: %w", err))
+	}
+	return &historyJob{
+		store: store,
+		record: history.Record{
+			Kind:        kind,
+			Name:        name,
+			StartedAt:   time.Now(),
+			Fingerprint: history.Fingerprint(settings),
+			Details:     map[string]interface{}{"settings": settings},
+		},
+	}, nil
+}
+
+// counts sets the items that went in and came out of the run.
+func (j *historyJob) counts(input, output int) {
+	if j == nil {
+		return
+	}
+	j.record.Input, j.record.Output = input, output
+}
+
+// detail adds a stat to the record.
+func (j *historyJob) detail(key string, value interface{}) {
+	if j == nil {
+		return
+	}
+	j.record.Details[key] = value
+}
+
+// finish records the run with err as its outcome. Recording failures are
+// reported but never fail the run.
+func (j *historyJob) finish(err error) {
+	if j == nil {
+		return
+	}
+	defer func() { _ = j.store.Close() }()
+
+	j.record.Duration = time.Since(j.record.StartedAt)
+	if err != nil {
+		j.record.Status = history.StatusError
+		j.record.Error = err.Error()
+	}
+	if addErr := j.store.Add(context.Background(), &j.record); addErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record history: %v\n", addErr)
+	}
+}
+
+// recordRetrieve records a served /v1/retrieve or /v1/similar request in
+// the history and its namespace's aggregates, and exports it to analytics.
+func (s *Server) recordRetrieve(endpoint string, req *types.RetrievalRequest, result *types.BrokerResult) {
+	s.exportRetrieve(endpoint, req, result)
+	s.observeNamespace(req, result)
+	if s.history == nil {
+		return
+	}
+	settings := s.retrieveSettings(req)
+	details := map[string]interface{}{
+		"namespace": req.Namespace,
+		"clustered": result.Stats.Clustered,
+		"repeated":  result.Stats.Repeated,
+		"truncated": result.Stats.Truncated,
+	}
+	if s.queries && req.Query != "" {
+		details["query"] = req.Query
+	}
+	s.history.Record(history.Record{
+		Kind:        history.KindRequest,
+		Name:        endpoint,
+		StartedAt:   time.Now().Add(-result.Stats.TotalLatency),
+		Duration:    result.Stats.TotalLatency,
+		Input:       result.Stats.Retrieved,
+		Output:      result.Stats.Returned,
+		Fingerprint: history.Fingerprint(settings),
+		Details:     details,
+	})
+}
+
+// retrieveSettings returns the settings in effect for req, including
+// per-request and online tuner overrides, for fingerprinting.
+func (s *Server) retrieveSettings(req *types.RetrievalRequest) map[string]interface{} {
+	cfg := s.broker.GetConfig()
+	settings := map[string]interface{}{
+		"namespace":    req.Namespace,
+		"threshold":    cfg.ClusterThreshold,
+		"linkage":      cfg.ClusterLinkage,
+		"mmr":          cfg.EnableMMR,
+		"lambda":       cfg.MMRLambda,
+		"target_k":     cfg.TargetK,
+		"over_fetch_k": cfg.OverFetchK,
+	}
+	if req.Threshold > 0 {
+		settings["threshold"] = req.Threshold
+	}
+	if req.Lambda > 0 {
+		settings["lambda"] = req.Lambda
+	}
+	return settings
+}
+
+// recordDedupe records a /v1/dedupe or /v1/dedupe/stream request in the
+// history and exports it to analytics.
+func (s *APIServer) recordDedupe(endpoint string, start time.Time, input, output, clusters int, threshold, lambda float64, targetK int, preservePrefix bool) {
+	if s.history == nil && s.analytics == nil {
+		return
+	}
+	settings := map[string]interface{}{
+		"threshold":             threshold,
+		"lambda":                lambda,
+		"target_k":              targetK,
+		"preserve_cache_prefix": preservePrefix,
+	}
+	s.exportDedupe(endpoint, start, input, output, clusters, settings)
+	if s.history == nil {
+		return
+	}
+	s.history.Record(history.Record{
+		Kind:        history.KindRequest,
+		Name:        endpoint,
+		StartedAt:   start,
+		Duration:    time.Since(start),
+		Input:       input,
+		Output:      output,
+		Fingerprint: history.Fingerprint(settings),
+		Details:     map[string]interface{}{"clusters": clusters},
+	})
+}
+
+// HistoryRecordResponse is the JSON form of a history record.
+type HistoryRecordResponse struct {
+	ID          string                 `json:"id"`
+	Kind        string                 `json:"kind"`
+	Name        string                 `json:"name"`
+	StartedAt   time.Time              `json:"started_at"`
+	DurationMs  float64                `json:"duration_ms"`
+	Input       int                    `json:"input"`
+	Output      int                    `json:"output"`
+	Reduction   float64                `json:"reduction"`
+	Fingerprint string                 `json:"fingerprint,omitempty"`
+	Status      string                 `json:"status"`
+	Error       string                 `json:"error,omitempty"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+}
+
+func historyRecordResponse(r history.Record) HistoryRecordResponse {
+	return HistoryRecordResponse{
+		ID:          r.ID,
+		Kind:        r.Kind,
+		Name:        r.Name,
+		StartedAt:   r.StartedAt,
+		DurationMs:  float64(r.Duration.Microseconds()) / 1000,
+		Input:       r.Input,
+		Output:      r.Output,
+		Reduction:   r.Reduction,
+		Fingerprint: r.Fingerprint,
+		Status:      r.Status,
+		Error:       r.Error,
+		Details:     r.Details,
+	}
+}
+
+func openHistoryStore(cmd *cobra.Command) (*history.SQLiteStore, error) {
+	path, _ := cmd.Flags().GetString("db")
+	if path == "" {
+		path = viper.GetString("history.path")
+	}
+	if path == "" {
+		path = defaultHistoryDB
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("history database %s: %w", path, err))
+	}
+	return history.NewSQLiteStore(path)
+}
+
+func historyFilter(cmd *cobra.Command) history.Filter {
+	var f history.Filter
+	f.Kind, _ = cmd.Flags().GetString("kind")
+	f.Name, _ = cmd.Flags().GetString("name")
+	if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+		f.Since = time.Now().Add(-since)
+	}
+	return f
+}
+
+func runHistoryList(cmd *cobra.Command, _ []string) error {
+	store, err := openHistoryStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	f := historyFilter(cmd)
+	f.Limit, _ = cmd.Flags().GetInt("limit")
+	records, err := store.List(context.Background(), f)
+	if err != nil {
+		return err
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		return printHistoryJSON(os.Stdout, records)
+	}
+	if len(records) == 0 {
+		fmt.Println("No records.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tKIND\tNAME\tIN\tOUT\tREDUCTION\tDURATION\tCONFIG\tSTATUS")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%.1f%%\t%v\t%s\t%s\n",
+			r.ID, r.StartedAt.Local().Format("2006-01-02 15:04:05"), r.Kind, r.Name,
+			r.Input, r.Output, r.Reduction*100, r.Duration.Round(time.Millisecond), r.Fingerprint, r.Status)
+	}
+	return w.Flush()
+}
+
+func runHistoryShow(cmd *cobra.Command, args []string) error {
+	store, err := openHistoryStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	r, err := store.Get(context.Background(), args[0])
+	if errors.Is(err, history.ErrNotFound) {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("no history record %q", args[0]))
+	}
+	if err != nil {
+		return err
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		out, _ := json.MarshalIndent(historyRecordResponse(*r), "", "  ")
+		fmt.Println(string(out))
+		return nil
+	}
+
+	fmt.Printf("ID:          %s\n", r.ID)
+	fmt.Printf("Kind:        %s\n", r.Kind)
+	fmt.Printf("Name:        %s\n", r.Name)
+	fmt.Printf("Started:     %s\n", r.StartedAt.Local().Format(time.RFC3339))
+	fmt.Printf("Duration:    %v\n", r.Duration.Round(time.Millisecond))
+	fmt.Printf("Input:       %d\n", r.Input)
+	fmt.Printf("Output:      %d\n", r.Output)
+	fmt.Printf("Reduction:   %.1f%%\n", r.Reduction*100)
+	fmt.Printf("Fingerprint: %s\n", r.Fingerprint)
+	fmt.Printf("Status:      %s\n", r.Status)
+	if r.Error != "" {
+		fmt.Printf("Error:       %s\n", r.Error)
+	}
+	if len(r.Details) > 0 {
+		out, _ := json.MarshalIndent(r.Details, "", "  ")
+		fmt.Printf("Details:\n%s\n", out)
+	}
+	return nil
+}
+
+func runHistoryExport(cmd *cobra.Command, _ []string) error {
+	store, err := openHistoryStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	records, err := store.List(context.Background(), historyFilter(cmd))
+	if err != nil {
+		return err
+	}
+
+	outputFile, _ := cmd.Flags().GetString("output")
+	if outputFile == "" {
+		return printHistoryJSON(os.Stdout, records)
+	}
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	if err := printHistoryJSON(f, records); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d records to %s\n", len(records), outputFile)
+	return nil
+}
+
+func printHistoryJSON(out *os.File, records []history.Record) error {
+	resp := make([]HistoryRecordResponse, len(records))
+	for i, r := range records {
+		resp[i] = historyRecordResponse(r)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(resp); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+func runHistoryPrune(cmd *cobra.Command, _ []string) error {
+	olderThan, _ := cmd.Flags().GetDuration("older-than")
+	if olderThan <= 0 {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("--older-than must be positive"))
+	}
+
+	store, err := openHistoryStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	n, err := store.Prune(context.Background(), time.Now().Add(-olderThan))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d records\n", n)
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/gctune"
+	"github.com/Siddhant-K-code/distill/pkg/httpcompress"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addHTTPFlags registers response compression and HTTP/2 flags on a
+// server command. Like the limit flags, they are read directly so several
+// commands can share the server.* config keys.
+func addHTTPFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("compress", true, "Compress responses with zstd or gzip when the client accepts it (config: server.compression)")
+	cmd.Flags().Int("compress-min-bytes", 1024, "Smallest response worth compressing (config: server.compress_min_bytes)")
+	cmd.Flags().Bool("h2c", false, "Accept HTTP/2 without TLS (h2c), for internal traffic (config: server.h2c)")
+	cmd.Flags().String("h2-stream-buffer", "", "HTTP/2 receive buffer per stream, e.g. 1MiB (config: server.h2_stream_buffer)")
+	cmd.Flags().String("h2-conn-buffer", "", "HTTP/2 receive buffer per connection, e.g. 4MiB (config: server.h2_conn_buffer)")
+}
+
+// httpOptions holds the resolved compression and HTTP/2 settings.
+type httpOptions struct {
+	compress     bool
+	compressMin  int
+	h2c          bool
+	streamBuffer int64
+	connBuffer   int64
+}
+
+// resolveHTTPOptions reads the HTTP flags, falling back to config.
+func resolveHTTPOptions(cmd *cobra.Command) (httpOptions, error) {
+	opts := httpOptions{compress: true, compressMin: 1024}
+
+	if viper.IsSet("server.compression") {
+		opts.compress = viper.GetBool("server.compression")
+	}
+	if cmd.Flags().Changed("compress") {
+		opts.compress, _ = cmd.Flags().GetBool("compress")
+	}
+	if viper.IsSet("server.compress_min_bytes") {
+		opts.compressMin = viper.GetInt("server.compress_min_bytes")
+	}
+	if cmd.Flags().Changed("compress-min-bytes") {
+		opts.compressMin, _ = cmd.Flags().GetInt("compress-min-bytes")
+	}
+	if viper.IsSet("server.h2c") {
+		opts.h2c = viper.GetBool("server.h2c")
+	}
+	if cmd.Flags().Changed("h2c") {
+		opts.h2c, _ = cmd.Flags().GetBool("h2c")
+	}
+
+	for _, b := range []struct {
+		flag, key string
+		dst       *int64
+	}{
+		{"h2-stream-buffer", "server.h2_stream_buffer", &opts.streamBuffer},
+		{"h2-conn-buffer", "server.h2_conn_buffer", &opts.connBuffer},
+	} {
+		s := viper.GetString(b.key)
+		if cmd.Flags().Changed(b.flag) {
+			s, _ = cmd.Flags().GetString(b.flag)
+		}
+		if s == "" {
+			continue
+		}
+		n, err := gctune.ParseBytes(s)
+		if err != nil {
+			return opts, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid --%s: %w", b.flag, err))
+		}
+		*b.dst = n
+	}
+
+	if opts.compressMin < 0 {
+		return opts, errs.Wrap(errs.ErrConfig, fmt.Errorf("compress min bytes must be non-negative"))
+	}
+	return opts, nil
+}
+
+// apply wraps srv's handler with compression and configures HTTP/2.
+func (o httpOptions) apply(srv *http.Server) {
+	if o.compress {
+		cfg := httpcompress.DefaultConfig()
+		if o.compressMin > 0 {
+			cfg.MinSize = o.compressMin
+		}
+		srv.Handler = httpcompress.Middleware(cfg, srv.Handler)
+	}
+
+	if o.h2c {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+		srv.Protocols = protocols
+	}
+
+	if o.streamBuffer > 0 || o.connBuffer > 0 {
+		srv.HTTP2 = &http.HTTP2Config{
+			MaxReceiveBufferPerStream:     int(o.streamBuffer),
+			MaxReceiveBufferPerConnection: int(o.connBuffer),
+		}
+	}
+}
@@ -0,0 +1,445 @@
+//go:build integration
+
+// Integration tests run the serve and MCP HTTP handlers in-process against
+// a real Qdrant and Redis started with dockertest. They need a Docker
+// daemon:
+//
+//	go test -tags integration ./cmd/
+//
+// The fixture corpus comes from the fake backend, so no embedding
+// credentials are needed. Serve's caches use pkg/cache's Redis backend,
+// as with --cache-backend redis.
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	distillcache "github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	fakeembed "github.com/Siddhant-K-code/distill/pkg/embedding/fake"
+	"github.com/Siddhant-K-code/distill/pkg/metrics"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	fakeretriever "github.com/Siddhant-K-code/distill/pkg/retriever/fake"
+	qdretriever "github.com/Siddhant-K-code/distill/pkg/retriever/qdrant"
+	"github.com/Siddhant-K-code/distill/pkg/telemetry"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/ory/dockertest/v3"
+	"github.com/qdrant/go-client/qdrant"
+	"github.com/spf13/viper"
+)
+
+const (
+	qdrantImage       = "qdrant/qdrant"
+	qdrantTag         = "v1.15.1"
+	redisImage        = "redis"
+	redisTag          = "7.4-alpine"
+	fixtureCollection = "distill_fixture"
+	fixtureSize       = 300
+	fixtureSeed       = 1
+)
+
+// qdrantPort is the host port mapped to the container's gRPC port.
+var qdrantPort int
+
+// redisURL is the URL of the Redis container.
+var redisURL string
+
+// fixtureEmbedder embeds the fixture corpus and queries.
+var fixtureEmbedder = fakeembed.NewEmbedder(fakeembed.Config{})
+
+func TestMain(m *testing.M) {
+	os.Exit(runIntegration(m))
+}
+
+func runIntegration(m *testing.M) int {
+	pool, err := dockertest.NewPool("")
+	if err == nil {
+		err = pool.Client.Ping()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: docker unavailable: %v\n", err)
+		return 1
+	}
+	pool.MaxWait = 2 * time.Minute
+
+	res, err := pool.Run(qdrantImage, qdrantTag, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: start qdrant: %v\n", err)
+		return 1
+	}
+	defer func() { _ = pool.Purge(res) }()
+	_ = res.Expire(600) // reap the container if the test binary is killed
+
+	if _, err := fmt.Sscan(res.GetPort("6334/tcp"), &qdrantPort); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: qdrant port: %v\n", err)
+		return 1
+	}
+
+	redisRes, err := pool.Run(redisImage, redisTag, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integration: start redis: %v\n", err)
+		return 1
+	}
+	defer func() { _ = pool.Purge(redisRes) }()
+	_ = redisRes.Expire(600)
+	redisURL = "redis://localhost:" + redisRes.GetPort("6379/tcp")
+
+	ctx := context.Background()
+	if err := pool.Retry(func() error { return ingestFixture(ctx) }); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: ingest fixture: %v\n", err)
+		return 1
+	}
+	if err := pool.Retry(func() error {
+		c, err := distillcache.NewRedisCache(distillcache.RedisConfig{URL: redisURL, KeyPrefix: "distill-it:"})
+		if err != nil {
+			return err
+		}
+		return c.Close()
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "integration: connect redis: %v\n", err)
+		return 1
+	}
+	return m.Run()
+}
+
+// ingestFixture loads the synthetic corpus into a fresh collection.
+func ingestFixture(ctx context.Context) error {
+	client, err := qdrant.NewClient(&qdrant.Config{Host: "localhost", Port: qdrantPort})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	exists, err := client.CollectionExists(ctx, fixtureCollection)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	err = client.CreateCollection(ctx, &qdrant.CreateCollection{
+		CollectionName: fixtureCollection,
+		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
+			Size:     uint64(fixtureEmbedder.Dimension()),
+			Distance: qdrant.Distance_Cosine,
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	corpus := fakeretriever.Corpus(fixtureSize, fakeretriever.DefaultDuplicateRate, fixtureSeed)
+	points := make([]*qdrant.PointStruct, len(corpus))
+	for i, c := range corpus {
+		payload := map[string]any{"text": c.Text, "doc_id": c.ID, "topic": c.Metadata["topic"]}
+		points[i] = &qdrant.PointStruct{
+			Id:      qdrant.NewIDNum(uint64(i + 1)),
+			Vectors: qdrant.NewVectors(fixtureEmbedder.Vector(c.Text)...),
+			Payload: qdrant.NewValueMap(payload),
+		}
+	}
+	wait := true
+	_, err = client.Upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: fixtureCollection,
+		Wait:           &wait,
+		Points:         points,
+	})
+	return err
+}
+
+// newRetriever connects a retriever to the fixture collection.
+func newRetriever(t *testing.T) retriever.Retriever {
+	t.Helper()
+	ret, err := qdretriever.NewClient(context.Background(), qdretriever.Config{
+		Config:     retriever.Config{Host: "localhost"},
+		Collection: fixtureCollection,
+		GRPCPort:   qdrantPort,
+	})
+	if err != nil {
+		t.Fatalf("connect qdrant: %v", err)
+	}
+	t.Cleanup(func() { _ = ret.Close() })
+	return ret
+}
+
+// useRedis points the cache.* settings at the Redis container, with
+// keys under the test's own prefix, as serve with --cache-backend redis.
+func useRedis(t *testing.T) {
+	t.Helper()
+	viper.Set("cache.backend", "redis")
+	viper.Set("cache.redis.url", redisURL)
+	viper.Set("cache.redis.key_prefix", "distill-it:"+t.Name()+":")
+	viper.Set("cache.embedding_size", 1000)
+	viper.Set("cache.result_ttl", time.Minute)
+	viper.Set("cache.result_size", 1000)
+	t.Cleanup(viper.Reset)
+}
+
+// startServe runs the serve handlers in-process, wired as runServe wires
+// them, with the sent, embedding, and result caches in Redis. Servers
+// started by one test share the caches, as replicas do.
+func startServe(t *testing.T) string {
+	t.Helper()
+	useRedis(t)
+
+	sentCache, err := sessionCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sentCache.Close() })
+	caches, err := newQueryCaches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(caches.Close)
+
+	opts := []contextlab.Option{
+		contextlab.WithConfig(contextlab.DefaultBrokerConfig()),
+		contextlab.WithEmbedder(fixtureEmbedder),
+		contextlab.WithSentFilter(distillcache.NewSentFilter(sentCache, time.Hour)),
+		contextlab.WithLimits(contextlab.DefaultLimits()),
+	}
+	broker, err := contextlab.NewBrokerWithOptions(newRetriever(t), append(opts, caches.options()...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = broker.Close() })
+
+	tp, err := telemetry.Init(context.Background(), telemetry.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &Server{
+		broker:  broker,
+		metrics: metrics.New(),
+		tracing: tp,
+		limits:  contextlab.DefaultLimits(),
+		caches:  caches,
+		embedOut: embeddingOutput{
+			reduction: "truncate",
+		},
+	}
+	ts := httptest.NewServer(server.routes())
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func retrieve(t *testing.T, url string, req RetrieveRequest) RetrieveResponse {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	resp, err := http.Post(url+"/v1/retrieve", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("retrieve: status %d", resp.StatusCode)
+	}
+	var out RetrieveResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestIntegration_Retrieve(t *testing.T) {
+	url := startServe(t)
+
+	out := retrieve(t, url, RetrieveRequest{Query: "how are invoices and refunds handled", TargetK: 5})
+	if out.Stats.Retrieved != 50 {
+		t.Errorf("expected 50 over-fetched chunks, got %d", out.Stats.Retrieved)
+	}
+	if len(out.Chunks) == 0 || len(out.Chunks) > 5 {
+		t.Fatalf("expected 1-5 chunks, got %d", len(out.Chunks))
+	}
+	if out.Stats.Clustered >= out.Stats.Retrieved {
+		t.Errorf("expected near-duplicates to cluster: %d clusters from %d chunks", out.Stats.Clustered, out.Stats.Retrieved)
+	}
+	if out.Chunks[0].Metadata["topic"] != "billing" {
+		t.Errorf("expected billing chunk first, got %v", out.Chunks[0].Metadata["topic"])
+	}
+	for _, c := range out.Chunks {
+		if c.Text == "" {
+			t.Errorf("chunk %s has no text", c.ID)
+		}
+	}
+
+	out = retrieve(t, url, RetrieveRequest{
+		Query:            "database replicas",
+		TargetK:          3,
+		EmbeddingOptions: EmbeddingOptions{IncludeEmbeddings: true, EmbeddingDims: 2, EmbeddingReduction: "pca"},
+	})
+	for _, c := range out.Chunks {
+		if len(c.Embedding) != 2 {
+			t.Errorf("expected 2-dim embedding, got %d", len(c.Embedding))
+		}
+	}
+}
+
+func TestIntegration_Similar(t *testing.T) {
+	url := startServe(t)
+
+	// Fixture points have numeric IDs 1..fixtureSize
+	body, _ := json.Marshal(SimilarRequest{IDs: []string{"1", "2"}, TargetK: 4})
+	resp, err := http.Post(url+"/v1/similar", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("similar: status %d", resp.StatusCode)
+	}
+	var out RetrieveResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Chunks) == 0 || len(out.Chunks) > 4 {
+		t.Fatalf("expected 1-4 chunks, got %d", len(out.Chunks))
+	}
+	for _, c := range out.Chunks {
+		if c.ID == "1" || c.ID == "2" {
+			t.Errorf("source item %s returned as its own neighbor", c.ID)
+		}
+	}
+}
+
+func TestIntegration_SentCache(t *testing.T) {
+	url := startServe(t)
+	req := RetrieveRequest{Query: "deployment rollbacks and canaries", TargetK: 4, SessionID: "it-session"}
+
+	first := retrieve(t, url, req)
+	if len(first.Chunks) == 0 {
+		t.Fatal("expected chunks on first request")
+	}
+
+	second := retrieve(t, url, req)
+	if second.Stats.Repeated == 0 {
+		t.Error("expected chunks already sent to the session to be filtered")
+	}
+	seen := make(map[string]bool)
+	for _, c := range first.Chunks {
+		seen[c.ID] = true
+	}
+	for _, c := range second.Chunks {
+		if seen[c.ID] {
+			t.Errorf("chunk %s returned twice to the same session", c.ID)
+		}
+	}
+
+	req.MarkRepeats = true
+	third := retrieve(t, url, req)
+	marked := 0
+	for _, c := range third.Chunks {
+		if c.AlreadySent {
+			marked++
+		}
+	}
+	if marked == 0 {
+		t.Error("expected mark_repeats to flag already-sent chunks")
+	}
+}
+
+func TestIntegration_RedisReplicas(t *testing.T) {
+	first, second := startServe(t), startServe(t)
+	req := RetrieveRequest{Query: "how are invoices and refunds handled", TargetK: 4}
+
+	miss := retrieve(t, first, req)
+	if miss.Stats.CacheHit || !miss.Stats.CacheMiss {
+		t.Errorf("first request: cache_hit=%v cache_miss=%v, want a miss", miss.Stats.CacheHit, miss.Stats.CacheMiss)
+	}
+	hit := retrieve(t, second, req)
+	if !hit.Stats.CacheHit {
+		t.Error("expected the other replica to answer from the shared result cache")
+	}
+	if len(hit.Chunks) != len(miss.Chunks) {
+		t.Errorf("cached result has %d chunks, want %d", len(hit.Chunks), len(miss.Chunks))
+	}
+
+	// A session's sent chunks are visible to every replica
+	req = RetrieveRequest{Query: "deployment rollbacks and canaries", TargetK: 4, SessionID: "it-replicas"}
+	sent := retrieve(t, first, req)
+	again := retrieve(t, second, req)
+	if again.Stats.Repeated == 0 {
+		t.Error("expected the other replica to filter chunks already sent to the session")
+	}
+	seen := make(map[string]bool)
+	for _, c := range sent.Chunks {
+		seen[c.ID] = true
+	}
+	for _, c := range again.Chunks {
+		if seen[c.ID] {
+			t.Errorf("chunk %s returned twice to the same session across replicas", c.ID)
+		}
+	}
+}
+
+func TestIntegration_MCPHTTP(t *testing.T) {
+	cfg := contextlab.DefaultBrokerConfig()
+	broker := contextlab.NewBrokerWithEmbedder(newRetriever(t), fixtureEmbedder, cfg)
+	t.Cleanup(func() { _ = broker.Close() })
+
+	srv := &MCPServer{broker: broker, embedder: fixtureEmbedder, cfg: cfg}
+	ts := httptest.NewServer(httpHandler(srv.newServer()))
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mcpclient.NewStreamableHttpClient(ts.URL + "/mcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	if err := client.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initReq.Params.ClientInfo = mcp.Implementation{Name: "distill-integration", Version: "test"}
+	if _, err := client.Initialize(ctx, initReq); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+
+	call := mcp.CallToolRequest{}
+	call.Params.Name = "retrieve_deduplicated"
+	call.Params.Arguments = map[string]any{"query": "monitoring alerts and dashboards", "target_k": 3}
+	res, err := client.CallTool(ctx, call)
+	if err != nil {
+		t.Fatalf("call tool: %v", err)
+	}
+	if res.IsError || len(res.Content) == 0 {
+		t.Fatalf("tool returned error: %+v", res.Content)
+	}
+	if _, ok := res.Content[0].(mcp.TextContent); !ok {
+		t.Fatalf("expected a summary text block, got %T", res.Content[0])
+	}
+
+	var out struct {
+		Chunks []map[string]any `json:"chunks"`
+		Stats  map[string]any   `json:"stats"`
+	}
+	structured, err := json.Marshal(res.StructuredContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(structured, &out); err != nil {
+		t.Fatalf("decode tool result: %v\n%s", err, structured)
+	}
+	if len(res.Content) != len(out.Chunks)+1 {
+		t.Errorf("expected one embedded resource per chunk, got %d content blocks for %d chunks", len(res.Content), len(out.Chunks))
+	}
+	if len(out.Chunks) == 0 || len(out.Chunks) > 3 {
+		t.Errorf("expected 1-3 chunks, got %d", len(out.Chunks))
+	}
+}
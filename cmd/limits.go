@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/gctune"
+	"github.com/Siddhant-K-code/distill/pkg/metrics"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addLimitFlags registers input size limit flags on a server command.
+// Like the runtime flags, they are read directly so several commands can
+// share the limits.* config keys.
+func addLimitFlags(cmd *cobra.Command) {
+	def := contextlab.DefaultLimits()
+	cmd.Flags().Int("max-chunks", def.MaxChunks, "Maximum chunks per request, 0 = unlimited (config: limits.max_chunks)")
+	cmd.Flags().Int("max-dimension", def.MaxDimension, "Maximum embedding dimension, 0 = unlimited (config: limits.max_dimension)")
+	cmd.Flags().String("max-input-bytes", "16MiB", "Maximum chunk text + embedding bytes per request, 0 = unlimited (config: limits.max_input_bytes)")
+	cmd.Flags().String("max-matrix-bytes", "", "Distance matrix memory cap, e.g. 256MiB; empty = no cap (config: limits.max_matrix_bytes)")
+	cmd.Flags().String("matrix-overflow", contextlab.OverflowSpill, "Over the matrix cap: spill or window (config: limits.matrix_overflow)")
+	cmd.Flags().String("spill-dir", "", "Directory for spilled distance matrices (config: limits.spill_dir)")
+}
+
+// inputLimits resolves input size limits from flags, falling back to
+// config and then to contextlab.DefaultLimits.
+func inputLimits(cmd *cobra.Command) (contextlab.Limits, error) {
+	limits := contextlab.DefaultLimits()
+
+	if viper.IsSet("limits.max_chunks") {
+		limits.MaxChunks = viper.GetInt("limits.max_chunks")
+	}
+	if cmd.Flags().Changed("max-chunks") {
+		limits.MaxChunks, _ = cmd.Flags().GetInt("max-chunks")
+	}
+	if viper.IsSet("limits.max_dimension") {
+		limits.MaxDimension = viper.GetInt("limits.max_dimension")
+	}
+	if cmd.Flags().Changed("max-dimension") {
+		limits.MaxDimension, _ = cmd.Flags().GetInt("max-dimension")
+	}
+
+	maxBytes := ""
+	if viper.IsSet("limits.max_input_bytes") {
+		maxBytes = viper.GetString("limits.max_input_bytes")
+	}
+	if cmd.Flags().Changed("max-input-bytes") {
+		maxBytes, _ = cmd.Flags().GetString("max-input-bytes")
+	}
+	if maxBytes != "" {
+		n, err := gctune.ParseBytes(maxBytes)
+		if err != nil {
+			return limits, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid max input bytes: %w", err))
+		}
+		limits.MaxInputBytes = n
+	}
+
+	if limits.MaxChunks < 0 || limits.MaxDimension < 0 {
+		return limits, errs.Wrap(errs.ErrConfig, fmt.Errorf("input limits must be non-negative"))
+	}
+	return limits, nil
+}
+
+// matrixBudget resolves the distance matrix memory cap from flags, falling
+// back to config. Commands without the limit flags read config only.
+func matrixBudget(cmd *cobra.Command) (contextlab.MatrixBudget, error) {
+	budget := contextlab.MatrixBudget{
+		Overflow: viper.GetString("limits.matrix_overflow"),
+		SpillDir: viper.GetString("limits.spill_dir"),
+	}
+	maxBytes := viper.GetString("limits.max_matrix_bytes")
+	if cmd.Flags().Changed("max-matrix-bytes") {
+		maxBytes, _ = cmd.Flags().GetString("max-matrix-bytes")
+	}
+	if cmd.Flags().Changed("matrix-overflow") {
+		budget.Overflow, _ = cmd.Flags().GetString("matrix-overflow")
+	}
+	if cmd.Flags().Changed("spill-dir") {
+		budget.SpillDir, _ = cmd.Flags().GetString("spill-dir")
+	}
+
+	if maxBytes != "" {
+		n, err := gctune.ParseBytes(maxBytes)
+		if err != nil {
+			return budget, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid max matrix bytes: %w", err))
+		}
+		budget.MaxBytes = n
+	}
+	switch budget.Overflow {
+	case "", contextlab.OverflowSpill, contextlab.OverflowWindow:
+	default:
+		return budget, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid matrix overflow %q (supported: spill, window)", budget.Overflow))
+	}
+	return budget, nil
+}
+
+// writeTooLarge writes a 413 response and records the rejection if err is
+// a *contextlab.LimitError. It returns false, writing nothing, otherwise.
+func writeTooLarge(w http.ResponseWriter, m *metrics.Metrics, endpoint string, err error) bool {
+	var limErr *contextlab.LimitError
+	if !errors.As(err, &limErr) {
+		return false
+	}
+	if m != nil {
+		m.RecordRejected(endpoint, limErr.Limit)
+	}
+	http.Error(w, limErr.Error(), http.StatusRequestEntityTooLarge)
+	return true
+}
+
+// limitBody caps r's body at max bytes, 0 = unlimited, recording the
+// rejection of a larger body for endpoint. Limits.Check only runs once
+// the body is decoded, so without the cap an oversized body would be
+// read and parsed in full before being rejected. Readers report the
+// overflow with writeBodyError.
+func limitBody(w http.ResponseWriter, r *http.Request, max int64, m *metrics.Metrics, endpoint string) {
+	if max <= 0 {
+		return
+	}
+	r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, max), reject: func() {
+		if m != nil {
+			m.RecordRejected(endpoint, contextlab.LimitMaxInputBytes)
+		}
+	}}
+}
+
+// limitedBody calls reject the first time its body goes over the cap.
+type limitedBody struct {
+	io.ReadCloser
+	reject func()
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var tooLarge *http.MaxBytesError
+	if b.reject != nil && errors.As(err, &tooLarge) {
+		b.reject()
+		b.reject = nil
+	}
+	return n, err
+}
+
+// writeBodyError writes a 413 if err is from a body over limitBody's cap,
+// and a 400 naming format otherwise.
+func writeBodyError(w http.ResponseWriter, format string, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("input too large: request body exceeds the %s limit of %d", contextlab.LimitMaxInputBytes, tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Invalid %s: %v", format, err), http.StatusBadRequest)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/grpcapi/distillv1"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestLimitBody_Oversized(t *testing.T) {
+	limits := contextlab.DefaultLimits()
+	limits.MaxInputBytes = 1024
+	s := newTestServer(t, nil)
+	s.limits = limits
+	api := &APIServer{limits: limits}
+
+	text := strings.Repeat("x", 4096)
+	dedupe, _ := json.Marshal(DedupeRequest{Chunks: []DedupeChunk{{ID: "a", Text: text}}})
+	retrieve, _ := json.Marshal(RetrieveRequest{Query: text})
+	analyze, _ := json.Marshal(AnalyzeRequest{Chunks: []DedupeChunk{{ID: "a", Text: text}}})
+	// Unknown fields are never checked against the input limits, only
+	// the body cap catches them
+	stream := []byte(`{"chunks": [{"id": "a", "text": "a"}], "padding": "` + text + `"}`)
+	similar := []byte(`{"ids": ["a"], "padding": "` + text + `"}`)
+	pb, _ := proto.Marshal(&distillv1.DeduplicateRequest{Chunks: []*distillv1.Chunk{{Id: "a", Text: text}}})
+	// Trailing bytes past a valid document must be read, and rejected, too
+	padded := append([]byte(`{"query": "refunds"}`), bytes.Repeat([]byte(" "), 2048)...)
+
+	for _, tc := range []struct {
+		name        string
+		handler     http.HandlerFunc
+		contentType string
+		body        []byte
+	}{
+		{"dedupe json", api.handleDedupe, "application/json", dedupe},
+		{"dedupe protobuf", api.handleDedupe, contentTypeProtobuf, pb},
+		{"dedupe stream", api.handleDedupeStream, "application/json", stream},
+		{"retrieve json", s.handleRetrieve, "application/json", retrieve},
+		{"retrieve padded", s.handleRetrieve, "application/json", padded},
+		{"analyze json", s.handleAnalyze, "application/json", analyze},
+		{"similar json", s.handleSimilar, "application/json", similar},
+	} {
+		rec := post(tc.handler, "/", tc.contentType, tc.body)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: status = %d, want 413 (%s)", tc.name, rec.Code, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+
+	// A malformed body within the cap is still a 400
+	rec := post(s.handleRetrieve, "/", "application/json", []byte(`{"query": `))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed: status = %d, want 400", rec.Code)
+	}
+}
+
+// countingUpserter counts the vectors written to it.
+type countingUpserter struct{ n int }
+
+func (u *countingUpserter) Upsert(ctx context.Context, vectors []types.Vector, namespace string) error {
+	u.n += len(vectors)
+	return nil
+}
+
+func TestHandleVectors_OversizedBody(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.limits.MaxInputBytes = 1024
+	writer := &countingUpserter{}
+	s.writer = writer
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/v1/vectors", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.handleVectors(rec, req)
+		return rec
+	}
+
+	// Metadata is not counted by the vector limits, only the body cap
+	// catches it
+	big := `{"vectors": [{"id": "a", "values": [1, 0], "metadata": {"text": "` + strings.Repeat("x", 4096) + `"}}]}`
+	if rec := put(big); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized: status = %d, want 413 (%s)", rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+	if writer.n != 0 {
+		t.Errorf("wrote %d vectors from an oversized body", writer.n)
+	}
+	if rec := put(`{"vectors": [{"id": "a", "values": [1, 0]}]}`); rec.Code != http.StatusOK || writer.n != 1 {
+		t.Errorf("within the cap: status = %d, wrote %d (%s)", rec.Code, writer.n, strings.TrimSpace(rec.Body.String()))
+	}
+}
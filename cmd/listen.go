@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/config"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addListenFlags registers the Unix socket flags on a server command.
+// Like the HTTP flags, they are read directly so several commands can
+// share the server.socket config keys.
+func addListenFlags(cmd *cobra.Command) {
+	cmd.Flags().String("socket", "", "Serve HTTP on this Unix domain socket instead of host and port (config: server.socket)")
+	cmd.Flags().String("socket-mode", "0660", "Permissions of the --socket file, in octal (config: server.socket_mode)")
+}
+
+// listenTarget is where a server's HTTP listener binds: a Unix socket
+// when socket is set, otherwise the TCP address addr.
+type listenTarget struct {
+	addr   string
+	socket string
+	mode   os.FileMode
+}
+
+// resolveListenTarget reads the socket flags, falling back to config when
+// fromConfig is set, and otherwise listens on host and port. Commands
+// whose host and port are not config keys, like mcp, pass false so they
+// do not take serve's socket.
+func resolveListenTarget(cmd *cobra.Command, host string, port int, fromConfig bool) (listenTarget, error) {
+	if !config.ValidHost(host) {
+		return listenTarget{}, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid host %q (write IPv6 literals as :: or [::1])", host))
+	}
+	t := listenTarget{addr: config.ListenAddr(host, port)}
+
+	var modeStr string
+	if fromConfig {
+		t.socket = viper.GetString("server.socket")
+		modeStr = viper.GetString("server.socket_mode")
+	}
+	if cmd.Flags().Changed("socket") {
+		t.socket, _ = cmd.Flags().GetString("socket")
+	}
+	if cmd.Flags().Changed("socket-mode") {
+		modeStr, _ = cmd.Flags().GetString("socket-mode")
+	}
+	mode, err := config.ParseSocketMode(modeStr)
+	if err != nil {
+		return t, errs.Wrap(errs.ErrConfig, err)
+	}
+	t.mode = mode
+	return t, nil
+}
+
+// String returns the address for logs: "unix:<path>" or host:port.
+func (t listenTarget) String() string {
+	if t.socket != "" {
+		return "unix:" + t.socket
+	}
+	return t.addr
+}
+
+// urlHost is the host to print in endpoint URLs. Clients of a socket
+// pass its path separately (curl --unix-socket) and any host in the URL.
+func (t listenTarget) urlHost() string {
+	if t.socket != "" {
+		return "localhost"
+	}
+	return t.addr
+}
+
+// listen opens the listener. A socket file left behind by a server that
+// did not shut down cleanly is replaced; one a server still answers on is
+// not. Closing the listener removes the socket file.
+func (t listenTarget) listen() (net.Listener, error) {
+	if t.socket == "" {
+		return net.Listen("tcp", t.addr)
+	}
+	if err := removeStaleSocket(t.socket); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", t.socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(t.socket, t.mode); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("set socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// removeStaleSocket removes the socket at path unless something is
+// listening on it. It refuses to remove anything that is not a socket.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != os.ModeSocket {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("%s exists and is not a socket", path))
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+	return os.Remove(path)
+}
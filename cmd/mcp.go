@@ -6,18 +6,22 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/Siddhant-K-code/distill/pkg/contextlab"
-	"github.com/Siddhant-K-code/distill/pkg/embedding/openai"
 	"github.com/Siddhant-K-code/distill/pkg/memory"
 	"github.com/Siddhant-K-code/distill/pkg/retriever"
 	"github.com/Siddhant-K-code/distill/pkg/session"
+	fakeretriever "github.com/Siddhant-K-code/distill/pkg/retriever/fake"
 	pcretriever "github.com/Siddhant-K-code/distill/pkg/retriever/pinecone"
 	qdretriever "github.com/Siddhant-K-code/distill/pkg/retriever/qdrant"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var mcpCmd = &cobra.Command{
@@ -78,17 +82,20 @@ func init() {
 	mcpCmd.Flags().String("transport", "stdio", "Transport type: stdio or http")
 	mcpCmd.Flags().Int("port", 8081, "HTTP server port (for http transport)")
 	mcpCmd.Flags().String("host", "0.0.0.0", "HTTP server host (for http transport)")
+	mcpCmd.Flags().String("socket", "", "Unix domain socket to serve on instead of host and port (for http transport)")
+	mcpCmd.Flags().String("socket-mode", "0660", "Permissions of the --socket file, in octal (for http transport)")
 
 	// Backend settings (optional - only needed for retrieve_deduplicated)
-	mcpCmd.Flags().String("backend", "", "Vector DB backend (pinecone, qdrant)")
+	mcpCmd.Flags().String("backend", "", "Vector DB backend (pinecone, qdrant, fake)")
 	mcpCmd.Flags().StringP("index", "i", "", "Index/collection name")
 	mcpCmd.Flags().String("api-key", "", "Vector DB API key (or use PINECONE_API_KEY)")
 	mcpCmd.Flags().String("db-host", "", "Vector DB host (for Qdrant)")
 	mcpCmd.Flags().StringP("namespace", "n", "", "Default namespace")
 
 	// Embedding settings
-	mcpCmd.Flags().String("openai-key", "", "OpenAI API key for embeddings (or use OPENAI_API_KEY)")
-	mcpCmd.Flags().String("embedding-model", "text-embedding-3-small", "OpenAI embedding model")
+	mcpCmd.Flags().String("openai-key", "", "API key for embeddings (or use OPENAI_API_KEY / COHERE_API_KEY / VOYAGE_API_KEY)")
+	mcpCmd.Flags().String("embedding-provider", "", "Embedding provider (openai, ollama, cohere, voyage, local) (config: embedding.provider)")
+	mcpCmd.Flags().String("embedding-model", defaultEmbeddingModel, "Embedding model (local: model directory)")
 
 	// Memory store
 	mcpCmd.Flags().Bool("memory", false, "Enable persistent memory store")
@@ -99,8 +106,16 @@ func init() {
 	// Default deduplication settings
 	mcpCmd.Flags().Int("over-fetch-k", 50, "Default over-fetch count")
 	mcpCmd.Flags().Int("target-k", 8, "Default target chunk count")
+	mcpCmd.Flags().Float64("min-score", 0, "Default minimum match score (0 = off)")
+	mcpCmd.Flags().Bool("include-tombstoned", false, "Return duplicates soft-deleted by sync --tombstone")
 	mcpCmd.Flags().Float64("threshold", 0.15, "Default clustering threshold")
 	mcpCmd.Flags().Float64("lambda", 0.5, "Default MMR lambda")
+	mcpCmd.Flags().Bool("enable-mmr", true, "Enable MMR re-ranking")
+	mcpCmd.Flags().Float64("recency-weight", 0, "Weight of recency in MMR relevance, 0-1 (0 = off)")
+	mcpCmd.Flags().Duration("recency-half-life", contextlab.DefaultRecencyHalfLife, "Age at which a chunk's recency halves")
+	mcpCmd.Flags().String("timestamp-field", contextlab.DefaultTimestampField, "Metadata field holding chunk timestamps")
+	addEntityFlags(mcpCmd)
+	addStageFlags(mcpCmd)
 }
 
 // MCPServer wraps the MCP server with Distill capabilities
@@ -123,32 +138,56 @@ func runMCP(cmd *cobra.Command, args []string) error {
 	dbHost, _ := cmd.Flags().GetString("db-host")
 	namespace, _ := cmd.Flags().GetString("namespace")
 	openaiKey, _ := cmd.Flags().GetString("openai-key")
+	embeddingProvider, _ := cmd.Flags().GetString("embedding-provider")
 	embeddingModel, _ := cmd.Flags().GetString("embedding-model")
 	overFetchK, _ := cmd.Flags().GetInt("over-fetch-k")
 	targetK, _ := cmd.Flags().GetInt("target-k")
+	minScore, _ := cmd.Flags().GetFloat64("min-score")
+	includeTombstoned, _ := cmd.Flags().GetBool("include-tombstoned")
 	threshold, _ := cmd.Flags().GetFloat64("threshold")
 	lambda, _ := cmd.Flags().GetFloat64("lambda")
+	enableMMR, _ := cmd.Flags().GetBool("enable-mmr")
+	recencyWeight, _ := cmd.Flags().GetFloat64("recency-weight")
+	recencyHalfLife, _ := cmd.Flags().GetDuration("recency-half-life")
+	timestampField, _ := cmd.Flags().GetString("timestamp-field")
 
 	// Resolve API keys from environment
 	if apiKey == "" {
 		apiKey = os.Getenv("PINECONE_API_KEY")
 	}
-	if openaiKey == "" {
-		openaiKey = os.Getenv("OPENAI_API_KEY")
+	if embeddingProvider == "" {
+		embeddingProvider = viper.GetString("embedding.provider")
 	}
 
 	ctx := context.Background()
 
+	nsEntities, err := namespaceEntities()
+	if err != nil {
+		return err
+	}
+
 	// Create broker config
 	brokerCfg := contextlab.BrokerConfig{
 		OverFetchK:        overFetchK,
 		TargetK:           targetK,
 		ClusterThreshold:  threshold,
 		ClusterLinkage:    "average",
-		SelectionStrategy: contextlab.SelectByScore,
-		EnableMMR:         true,
+		EnableMMR:         enableMMR,
 		MMRLambda:         lambda,
+		RecencyWeight:     recencyWeight,
+		RecencyHalfLife:   recencyHalfLife,
+		TimestampField:    timestampField,
+		Entities:          entityConfig(cmd),
+		NamespaceEntities: nsEntities,
+		MinScore:          minScore,
 		IncludeMetadata:   true,
+		IncludeTombstoned: includeTombstoned,
+	}
+	if err := applyStageFlags(cmd, &brokerCfg); err != nil {
+		return err
+	}
+	if brokerCfg.Matrix, err = matrixBudget(cmd); err != nil {
+		return err
 	}
 
 	// Create MCP server wrapper
@@ -184,20 +223,17 @@ func runMCP(cmd *cobra.Command, args []string) error {
 		mcpSrv.sessStore = sessStore
 	}
 
-	// Create embedding provider if OpenAI key is provided
-	if openaiKey != "" {
-		embedder, err := openai.NewClient(openai.Config{
-			APIKey: openaiKey,
-			Model:  embeddingModel,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create embedding provider: %w", err)
-		}
+	// Create embedding provider, unless a cloud provider has no API key
+	embedder, err := newEmbedder(embeddingProvider, openaiKey, embeddingModel, viper.GetString("embedding.base_url"))
+	if err != nil {
+		return fmt.Errorf("failed to create embedding provider: %w", err)
+	}
+	if embedder != nil {
 		mcpSrv.embedder = embedder
 	}
 
 	// Create retriever if backend is configured
-	if backend != "" && index != "" {
+	if backend != "" && (index != "" || backend == fakeBackend) {
 		var ret retriever.Retriever
 		var err error
 
@@ -206,27 +242,52 @@ func runMCP(cmd *cobra.Command, args []string) error {
 			if apiKey == "" {
 				return fmt.Errorf("pinecone API key required (--api-key or PINECONE_API_KEY)")
 			}
+			var params pcretriever.Params
+			if params, err = pineconeParams(); err != nil {
+				return err
+			}
 			ret, err = pcretriever.NewClient(ctx, pcretriever.Config{
 				Config: retriever.Config{
 					APIKey:           apiKey,
 					DefaultNamespace: namespace,
+					MetadataFields:   metadataFields(),
+					TimeoutSeconds:   retrieverTimeout(),
+					MaxRetries:       retrieverMaxRetries(),
 				},
 				IndexName: index,
+				Params:    params,
 			})
 
 		case "qdrant":
 			if dbHost == "" {
 				return fmt.Errorf("qdrant host required (--db-host)")
 			}
+			var params qdretriever.Params
+			if params, err = qdrantParams(); err != nil {
+				return err
+			}
 			ret, err = qdretriever.NewClient(ctx, qdretriever.Config{
 				Config: retriever.Config{
 					APIKey:           apiKey,
 					Host:             dbHost,
 					DefaultNamespace: namespace,
+					MetadataFields:   metadataFields(),
+					TimeoutSeconds:   retrieverTimeout(),
+					MaxRetries:       retrieverMaxRetries(),
 				},
 				Collection: index,
+				Params:     params,
 			})
 
+		case fakeBackend:
+			var fr *fakeretriever.Client
+			fr, err = newFakeRetriever()
+			if err == nil {
+				// Queries must share the synthetic corpus's vector space.
+				mcpSrv.embedder = fr.Embedder()
+				ret = fr
+			}
+
 		default:
 			return fmt.Errorf("unsupported backend: %s", backend)
 		}
@@ -245,19 +306,7 @@ func runMCP(cmd *cobra.Command, args []string) error {
 		defer func() { _ = mcpSrv.broker.Close() }()
 	}
 
-	// Create MCP server with capabilities
-	s := server.NewMCPServer(
-		"Distill",
-		"1.0.0",
-		server.WithToolCapabilities(false),
-		server.WithResourceCapabilities(true, false),
-		server.WithPromptCapabilities(false),
-	)
-
-	// Register tools, resources, and prompts
-	mcpSrv.registerTools(s)
-	mcpSrv.registerResources(s)
-	mcpSrv.registerPrompts(s)
+	s := mcpSrv.newServer()
 
 	// Start server based on transport
 	switch transport {
@@ -267,34 +316,42 @@ func runMCP(cmd *cobra.Command, args []string) error {
 		}
 
 	case "http":
-		addr := fmt.Sprintf("%s:%d", host, port)
-		fmt.Printf("Distill MCP server starting on http://%s\n", addr)
+		target, err := resolveListenTarget(cmd, host, port, false)
+		if err != nil {
+			return err
+		}
+		ln, err := target.listen()
+		if err != nil {
+			return fmt.Errorf("HTTP server error: %w", err)
+		}
+		addr := target.urlHost()
+		fmt.Printf("Distill MCP server starting on %s\n", target)
 		fmt.Printf("  Endpoint: http://%s/mcp\n", addr)
 		fmt.Printf("  Health:   http://%s/health\n", addr)
 		fmt.Println()
 
-		// Create HTTP handler with stateful session management
-		mux := http.NewServeMux()
-
-		// Health check endpoint
-		mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-			_, _ = w.Write([]byte(`{"status":"ok","server":"distill-mcp"}`))
-		})
-
-		// MCP endpoint with stateful sessions
-		mcpHandler := server.NewStreamableHTTPServer(s, server.WithStateful(true))
-		mux.Handle("/mcp", mcpHandler)
-
 		// Start HTTP server
 		httpServer := &http.Server{
-			Addr:    addr,
-			Handler: mux,
+			Addr:    target.addr,
+			Handler: httpHandler(s),
 		}
 
-		if err := httpServer.ListenAndServe(); err != nil {
+		// Shut down on signal so a socket file is removed
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		done := make(chan struct{})
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			_ = httpServer.Shutdown(shutdownCtx)
+			close(done)
+		}()
+
+		if err := httpServer.Serve(ln); err != http.ErrServerClosed {
 			return fmt.Errorf("HTTP server error: %w", err)
 		}
+		<-done
 
 	default:
 		return fmt.Errorf("unsupported transport: %s (use 'stdio' or 'http')", transport)
@@ -303,6 +360,41 @@ func runMCP(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// newServer creates the MCP server and registers Distill's tools,
+// resources, and prompts.
+func (m *MCPServer) newServer() *server.MCPServer {
+	calls := newMCPCalls()
+	opts := append([]server.ServerOption{
+		server.WithToolCapabilities(false),
+		server.WithResourceCapabilities(true, false),
+		server.WithPromptCapabilities(false),
+	}, calls.serverOptions()...)
+	s := server.NewMCPServer("Distill", "1.0.0", opts...)
+	s.AddNotificationHandler("notifications/cancelled", calls.handleCancelled)
+
+	// Register tools, resources, and prompts
+	m.registerTools(s)
+	m.registerResources(s)
+	m.registerPrompts(s)
+	return s
+}
+
+// httpHandler serves s over the streamable HTTP transport at /mcp, with
+// a health check at /health.
+func httpHandler(s *server.MCPServer) http.Handler {
+	mux := http.NewServeMux()
+
+	// Health check endpoint
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok","server":"distill-mcp"}`))
+	})
+
+	// MCP endpoint with stateful sessions
+	mux.Handle("/mcp", server.NewStreamableHTTPServer(s, server.WithStateful(true)))
+	return mux
+}
+
 func (m *MCPServer) registerTools(s *server.MCPServer) {
 	// Tool 1: deduplicate_chunks - works without vector DB
 	// Description is action-oriented to encourage AI to use it
@@ -318,10 +410,13 @@ BENEFITS:
 - Prevents LLM confusion from repetitive content
 
 INPUT: Array of chunks with text and embeddings (from your RAG pipeline)
-OUTPUT: Deduplicated chunks with diversity optimization`),
+OUTPUT: Deduplicated chunks with diversity optimization
+
+Chunks without embeddings are embedded by the server if it has an embedding
+provider. Send a progress token to follow long calls.`),
 		mcp.WithArray("chunks",
 			mcp.Required(),
-			mcp.Description("Array of chunk objects. Each chunk must have 'text' (string) and 'embedding' (array of floats). Optional: 'id' (string), 'score' (float), 'metadata' (object)."),
+			mcp.Description("Array of chunk objects. Each chunk must have 'text' (string) and, unless the server has an embedding provider, 'embedding' (array of floats). Optional: 'id' (string), 'score' (float), 'metadata' (object)."),
 		),
 		mcp.WithNumber("target_k",
 			mcp.Description("Target number of chunks to return (default: 8)"),
@@ -333,6 +428,7 @@ OUTPUT: Deduplicated chunks with diversity optimization`),
 			mcp.Description("MMR lambda - 1.0 for pure relevance, 0.0 for pure diversity (default: 0.5)"),
 		),
 	)
+	withStageParams(&deduplicateTool)
 
 	s.AddTool(deduplicateTool, m.handleDeduplicateChunks)
 
@@ -365,6 +461,7 @@ USE THIS instead of raw vector DB queries when you need diverse, non-redundant c
 				mcp.Description("MMR lambda for relevance vs diversity (default: 0.5)"),
 			),
 		)
+		withStageParams(&retrieveTool)
 
 		s.AddTool(retrieveTool, m.handleRetrieveDeduplicated)
 	}
@@ -666,10 +763,14 @@ func (m *MCPServer) handleDeduplicateChunks(ctx context.Context, request mcp.Cal
 		return mcp.NewToolResultError("chunks array is empty"), nil
 	}
 
-	// Validate chunks have embeddings
+	// Chunks without embeddings need the embedding provider
+	var missing []int
 	for i, c := range inputChunks {
 		if len(c.Embedding) == 0 {
-			return mcp.NewToolResultError(fmt.Sprintf("chunk %d missing embedding", i)), nil
+			if m.embedder == nil {
+				return mcp.NewToolResultError(fmt.Sprintf("chunk %d missing embedding and no embedding provider is configured", i)), nil
+			}
+			missing = append(missing, i)
 		}
 	}
 
@@ -708,28 +809,33 @@ func (m *MCPServer) handleDeduplicateChunks(ctx context.Context, request mcp.Cal
 		cfg.MMRLambda = lambda
 	}
 
-	// Create a temporary broker for processing
-	clusterer := contextlab.NewClusterer(contextlab.ClusterConfig{
-		Threshold: cfg.ClusterThreshold,
-		Linkage:   cfg.ClusterLinkage,
-	})
-	selector := contextlab.NewSelector(contextlab.SelectorConfig{
-		Strategy: cfg.SelectionStrategy,
-	})
-	mmr := contextlab.NewMMR(contextlab.MMRConfig{
-		Lambda:  cfg.MMRLambda,
-		TargetK: cfg.TargetK,
-	})
+	req, err := stageRequest(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	progress := newMCPProgress(ctx, request,
+		contextlab.StageEmbedding, contextlab.StageClustering, contextlab.StageSelection, contextlab.StageMMR)
+	if err := m.embedChunks(ctx, chunks, missing, progress); err != nil {
+		return mcpInterrupted(err, "embedding"), nil
+	}
 
-	// Process chunks
-	clusterResult := clusterer.Cluster(chunks)
-	representatives := selector.Select(clusterResult)
+	// Process chunks with a temporary broker; the chunks are the caller's,
+	// so the retrieval score floor does not apply
+	cfg.MinScore = 0
+	if progress != nil {
+		ctx = contextlab.WithStageObserver(ctx, progress.observe)
+	}
+	brokerResult, err := contextlab.NewBroker(nil, cfg).ProcessRequest(ctx, req, chunks)
+	if err != nil {
+		return mcpInterrupted(err, "deduplication"), nil
+	}
+	progress.done()
 
-	var finalChunks []types.Chunk
-	if len(representatives) > cfg.TargetK {
-		finalChunks = mmr.Rerank(representatives)
-	} else {
-		finalChunks = representatives
+	finalChunks := brokerResult.Chunks
+	var reduction float64
+	if clusters := brokerResult.Stats.Clustered; clusters > 0 {
+		reduction = float64(len(chunks)-clusters) / float64(len(chunks)) * 100
 	}
 
 	// Build response
@@ -737,16 +843,21 @@ func (m *MCPServer) handleDeduplicateChunks(ctx context.Context, request mcp.Cal
 		"chunks": formatChunksForResponse(finalChunks),
 		"stats": map[string]interface{}{
 			"input_count":    len(inputChunks),
-			"cluster_count":  clusterResult.ClusterCount,
+			"cluster_count":  brokerResult.Stats.Clustered,
 			"output_count":   len(finalChunks),
-			"reduction_pct":  clusterResult.ReductionPercent(),
+			"reduction_pct":  reduction,
 			"threshold_used": cfg.ClusterThreshold,
 			"lambda_used":    cfg.MMRLambda,
 		},
+		"stages": brokerResult.Stages,
+	}
+	if brokerResult.Stats.Sensitivity != "" {
+		result["stats"].(map[string]interface{})["sensitivity"] = brokerResult.Stats.Sensitivity
 	}
 
-	resultJSON, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(resultJSON)), nil
+	summary := fmt.Sprintf("Kept %d of %d chunks (%d clusters, %.0f%% reduction).",
+		len(finalChunks), len(inputChunks), brokerResult.Stats.Clustered, reduction)
+	return toolResult(summary, result, chunkResources(finalChunks)...), nil
 }
 
 func (m *MCPServer) handleRetrieveDeduplicated(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -777,11 +888,24 @@ func (m *MCPServer) handleRetrieveDeduplicated(ctx context.Context, request mcp.
 	}
 	m.broker.SetConfig(cfg)
 
+	req, err := stageRequest(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	req.Query = query
+	req.Namespace = namespace
+
 	// Execute retrieval
-	brokerResult, err := m.broker.RetrieveByText(ctx, query, namespace)
+	progress := newMCPProgress(ctx, request,
+		contextlab.StageEmbedding, contextlab.StageRetrieval, contextlab.StageClustering, contextlab.StageSelection, contextlab.StageMMR)
+	if progress != nil {
+		ctx = contextlab.WithStageObserver(ctx, progress.observe)
+	}
+	brokerResult, err := m.broker.Retrieve(ctx, req)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("retrieval failed: %v", err)), nil
+		return mcpInterrupted(err, "retrieval"), nil
 	}
+	progress.done()
 
 	// Build response
 	result := map[string]interface{}{
@@ -794,10 +918,84 @@ func (m *MCPServer) handleRetrieveDeduplicated(ctx context.Context, request mcp.
 			"clustering_latency_ms": brokerResult.Stats.ClusteringLatency.Milliseconds(),
 			"total_latency_ms":      brokerResult.Stats.TotalLatency.Milliseconds(),
 		},
+		"stages": brokerResult.Stages,
+	}
+	if brokerResult.Stats.Truncated {
+		result["stats"].(map[string]interface{})["truncated"] = true
+	}
+	if brokerResult.Stats.Vetoed > 0 {
+		result["stats"].(map[string]interface{})["vetoed"] = brokerResult.Stats.Vetoed
+	}
+	if brokerResult.Stats.Sensitivity != "" {
+		result["stats"].(map[string]interface{})["sensitivity"] = brokerResult.Stats.Sensitivity
 	}
 
-	resultJSON, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(resultJSON)), nil
+	summary := fmt.Sprintf("Returned %d of %d retrieved chunks (%d clusters) in %dms.",
+		brokerResult.Stats.Returned, brokerResult.Stats.Retrieved, brokerResult.Stats.Clustered, brokerResult.Stats.TotalLatency.Milliseconds())
+	if brokerResult.Stats.Truncated {
+		summary += " Retrieval was truncated at the over-fetch limit."
+	}
+	return toolResult(summary, result, chunkResources(brokerResult.Chunks)...), nil
+}
+
+// withStageParams adds the per-call stage switches to a tool.
+func withStageParams(tool *mcp.Tool) {
+	for _, opt := range []mcp.ToolOption{
+		mcp.WithBoolean("clustering",
+			mcp.Description("Cluster near-duplicates and keep one representative per cluster (default: server setting)"),
+		),
+		mcp.WithBoolean("mmr",
+			mcp.Description("Re-rank with MMR for diversity (default: server setting)"),
+		),
+		mcp.WithBoolean("compression",
+			mcp.Description("Compress returned chunks (default: server setting)"),
+		),
+		mcp.WithBoolean("redaction",
+			mcp.Description("Redact credentials and PII from returned chunks (default: server setting)"),
+		),
+		mcp.WithBoolean("classification",
+			mcp.Description("Tag returned chunks with their sensitivity level: none, pii, internal, or credentials (default: server setting)"),
+		),
+		mcp.WithBoolean("scoring",
+			mcp.Description("Apply the recency weight to MMR relevance (default: server setting)"),
+		),
+		mcp.WithString("selection",
+			mcp.Description("How cluster representatives are picked: score, centroid, length, or hybrid (default: server setting)"),
+		),
+	} {
+		opt(tool)
+	}
+}
+
+// stageRequest reads the stage switches added by withStageParams. Switches
+// left out keep the server's setting.
+func stageRequest(request mcp.CallToolRequest) (*types.RetrievalRequest, error) {
+	args := request.GetArguments()
+	toggle := func(key string) *bool {
+		if _, ok := args[key]; !ok {
+			return nil
+		}
+		on := request.GetBool(key, false)
+		return &on
+	}
+	req := &types.RetrievalRequest{
+		Stages: types.StageToggles{
+			Clustering:  toggle("clustering"),
+			MMR:         toggle("mmr"),
+			Compression: toggle("compression"),
+			Redaction:   toggle("redaction"),
+			Scoring:     toggle("scoring"),
+
+			Classification: toggle("classification"),
+		},
+		Selection: request.GetString("selection", ""),
+	}
+	if req.Selection != "" {
+		if _, err := contextlab.ParseSelectionStrategy(req.Selection); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
 }
 
 func (m *MCPServer) handleAnalyzeRedundancy(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -851,65 +1049,8 @@ func (m *MCPServer) handleAnalyzeRedundancy(ctx context.Context, request mcp.Cal
 		threshold = t
 	}
 
-	// Cluster without selecting
-	clusterer := contextlab.NewClusterer(contextlab.ClusterConfig{
-		Threshold: threshold,
-		Linkage:   "average",
-	})
-	clusterResult := clusterer.Cluster(chunks)
-
-	// Build cluster details
-	clusterDetails := make([]map[string]interface{}, len(clusterResult.Clusters))
-	for i, cluster := range clusterResult.Clusters {
-		memberIDs := make([]string, len(cluster.Members))
-		memberTexts := make([]string, len(cluster.Members))
-		for j, member := range cluster.Members {
-			memberIDs[j] = member.ID
-			if len(member.Text) > 100 {
-				memberTexts[j] = member.Text[:100] + "..."
-			} else {
-				memberTexts[j] = member.Text
-			}
-		}
-
-		clusterDetails[i] = map[string]interface{}{
-			"cluster_id":   cluster.ID,
-			"size":         cluster.Size(),
-			"member_ids":   memberIDs,
-			"member_texts": memberTexts,
-			"is_redundant": cluster.Size() > 1,
-		}
-	}
-
-	// Calculate redundancy stats
-	redundantChunks := 0
-	for _, cluster := range clusterResult.Clusters {
-		if cluster.Size() > 1 {
-			redundantChunks += cluster.Size() - 1
-		}
-	}
-
-	result := map[string]interface{}{
-		"summary": map[string]interface{}{
-			"total_chunks":     len(inputChunks),
-			"cluster_count":    clusterResult.ClusterCount,
-			"redundant_chunks": redundantChunks,
-			"redundancy_pct":   float64(redundantChunks) / float64(len(inputChunks)) * 100,
-			"unique_concepts":  clusterResult.ClusterCount,
-			"threshold_used":   threshold,
-		},
-		"clusters": clusterDetails,
-		"recommendation": fmt.Sprintf(
-			"Found %d clusters from %d chunks. %.1f%% redundancy detected. Consider using deduplicate_chunks to reduce to %d unique chunks.",
-			clusterResult.ClusterCount,
-			len(inputChunks),
-			float64(redundantChunks)/float64(len(inputChunks))*100,
-			clusterResult.ClusterCount,
-		),
-	}
-
-	resultJSON, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(resultJSON)), nil
+	result := analyzeRedundancy(chunks, threshold, m.cfg.Matrix, "deduplicate_chunks")
+	return toolResult(result.Recommendation, result), nil
 }
 
 func formatChunksForResponse(chunks []types.Chunk) []map[string]interface{} {
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"net/url"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolResult builds a tool result following the MCP content model: a
+// short summary text block, then any embedded resources (one per
+// returned item, so hosts can show them as cards), with the full result
+// as structured content for programmatic use.
+func toolResult(summary string, structured any, resources ...mcp.Content) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content:           append([]mcp.Content{mcp.NewTextContent(summary)}, resources...),
+		StructuredContent: structured,
+	}
+}
+
+// textResource embeds text under uri, with meta describing it.
+func textResource(uri, text string, meta map[string]any) mcp.Content {
+	return mcp.NewEmbeddedResource(mcp.TextResourceContents{
+		Meta:     meta,
+		URI:      uri,
+		MIMEType: "text/plain",
+		Text:     text,
+	})
+}
+
+// chunkResources embeds each chunk's text, with its ID, score, cluster,
+// and metadata in the resource's _meta.
+func chunkResources(chunks []types.Chunk) []mcp.Content {
+	out := make([]mcp.Content, len(chunks))
+	for i, c := range chunks {
+		meta := map[string]any{
+			"id":         c.ID,
+			"score":      c.Score,
+			"cluster_id": c.ClusterID,
+		}
+		if len(c.Metadata) > 0 {
+			meta["metadata"] = c.Metadata
+		}
+		out[i] = textResource("distill://chunk/"+url.PathEscape(c.ID), c.Text, meta)
+	}
+	return out
+}
@@ -2,10 +2,11 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"net/url"
 
 	"github.com/Siddhant-K-code/distill/pkg/memory"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -51,8 +52,12 @@ func (m *MCPServer) handleStoreMemory(ctx context.Context, request mcp.CallToolR
 		return mcp.NewToolResultError(fmt.Sprintf("store error: %v", err)), nil
 	}
 
-	out, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(out)), nil
+	summary := fmt.Sprintf("Stored %d, merged %d, deduplicated %d; %d memories total.",
+		result.Stored, result.Merged, result.Deduplicated, result.TotalMemories)
+	if len(result.Conflicts) > 0 {
+		summary += fmt.Sprintf(" %d conflict(s) with existing memories.", len(result.Conflicts))
+	}
+	return toolResult(summary, result), nil
 }
 
 func (m *MCPServer) handleRecallMemory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -91,7 +96,7 @@ func (m *MCPServer) handleRecallMemory(ctx context.Context, request mcp.CallTool
 	}
 
 	if m.embedder != nil {
-		emb, err := m.embedder.Embed(ctx, query)
+		emb, err := retriever.EmbedQuery(ctx, m.embedder, query)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("embedding error: %v", err)), nil
 		}
@@ -103,8 +108,23 @@ func (m *MCPServer) handleRecallMemory(ctx context.Context, request mcp.CallTool
 		return mcp.NewToolResultError(fmt.Sprintf("recall error: %v", err)), nil
 	}
 
-	out, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(out)), nil
+	resources := make([]mcp.Content, len(result.Memories))
+	for i, mem := range result.Memories {
+		meta := map[string]any{
+			"id":          mem.ID,
+			"relevance":   mem.Relevance,
+			"decay_level": mem.DecayLevel,
+		}
+		if mem.Source != "" {
+			meta["source"] = mem.Source
+		}
+		if len(mem.Tags) > 0 {
+			meta["tags"] = mem.Tags
+		}
+		resources[i] = textResource("distill://memory/"+url.PathEscape(mem.ID), mem.Text, meta)
+	}
+	summary := fmt.Sprintf("Recalled %d memories for %q.", len(result.Memories), query)
+	return toolResult(summary, result, resources...), nil
 }
 
 func (m *MCPServer) handleForgetMemory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -140,8 +160,8 @@ func (m *MCPServer) handleForgetMemory(ctx context.Context, request mcp.CallTool
 		return mcp.NewToolResultError(fmt.Sprintf("forget error: %v", err)), nil
 	}
 
-	out, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(out)), nil
+	summary := fmt.Sprintf("Removed %d memories; %d remain.", result.Removed, result.TotalMemories)
+	return toolResult(summary, result), nil
 }
 
 func (m *MCPServer) handleExpireMemory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -165,8 +185,7 @@ func (m *MCPServer) handleExpireMemory(ctx context.Context, request mcp.CallTool
 		return mcp.NewToolResultError(fmt.Sprintf("expire error: %v", err)), nil
 	}
 
-	out, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(out)), nil
+	return toolResult(fmt.Sprintf("Expired %d memories.", result.Expired), result), nil
 }
 
 func (m *MCPServer) handleSupersedeMemory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -187,8 +206,13 @@ func (m *MCPServer) handleSupersedeMemory(ctx context.Context, request mcp.CallT
 		return mcp.NewToolResultError(fmt.Sprintf("supersede error: %v", err)), nil
 	}
 
-	out, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(out)), nil
+	summary := fmt.Sprintf("Memory %s was not superseded.", oldID)
+	if result.Superseded && newID != "" {
+		summary = fmt.Sprintf("Memory %s superseded by %s.", oldID, newID)
+	} else if result.Superseded {
+		summary = fmt.Sprintf("Memory %s expired.", oldID)
+	}
+	return toolResult(summary, result), nil
 }
 
 func (m *MCPServer) handleMemoryStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -197,6 +221,6 @@ func (m *MCPServer) handleMemoryStats(ctx context.Context, request mcp.CallToolR
 		return mcp.NewToolResultError(fmt.Sprintf("stats error: %v", err)), nil
 	}
 
-	out, _ := json.MarshalIndent(stats, "", "  ")
-	return mcp.NewToolResultText(string(out)), nil
+	summary := fmt.Sprintf("%d memories: %d active, %d expired.", stats.TotalMemories, stats.ActiveCount, stats.ExpiredCount)
+	return toolResult(summary, stats), nil
 }
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// callKeyMeta is the _meta field that carries a tool call's cancellation
+// key from the before-call hook to the handler middleware.
+const callKeyMeta = "distill/callKey"
+
+// mcpCalls tracks in-flight tool calls so a notifications/cancelled from
+// the client cancels the matching call's context. The streamable HTTP
+// transport also cancels a call when its request is dropped; over stdio
+// the notification is the only signal.
+type mcpCalls struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func newMCPCalls() *mcpCalls {
+	return &mcpCalls{cancels: make(map[string]context.CancelFunc)}
+}
+
+// callKey identifies a request by session and JSON-RPC ID, since IDs are
+// only unique within a session.
+func callKey(ctx context.Context, id any) string {
+	sessionID := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	return sessionID + "/" + mcp.NewRequestId(id).String()
+}
+
+// serverOptions wires cancellation into an MCP server. Tool handlers only
+// see a copy of the request, so the hook, which knows the request ID,
+// passes the call's key through its _meta.
+func (c *mcpCalls) serverOptions() []server.ServerOption {
+	hooks := &server.Hooks{}
+	hooks.AddBeforeCallTool(func(ctx context.Context, id any, req *mcp.CallToolRequest) {
+		if req.Params.Meta == nil {
+			req.Params.Meta = &mcp.Meta{}
+		}
+		if req.Params.Meta.AdditionalFields == nil {
+			req.Params.Meta.AdditionalFields = make(map[string]any)
+		}
+		req.Params.Meta.AdditionalFields[callKeyMeta] = callKey(ctx, id)
+	})
+	return []server.ServerOption{
+		server.WithHooks(hooks),
+		server.WithToolHandlerMiddleware(c.middleware),
+	}
+}
+
+// middleware runs a tool call with a context that handleCancelled can
+// cancel.
+func (c *mcpCalls) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var key string
+		if req.Params.Meta != nil {
+			key, _ = req.Params.Meta.AdditionalFields[callKeyMeta].(string)
+		}
+		if key == "" {
+			return next(ctx, req)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		c.mu.Lock()
+		c.cancels[key] = cancel
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			delete(c.cancels, key)
+			c.mu.Unlock()
+		}()
+		return next(ctx, req)
+	}
+}
+
+// handleCancelled is the notifications/cancelled handler.
+func (c *mcpCalls) handleCancelled(ctx context.Context, n mcp.JSONRPCNotification) {
+	id, ok := n.Params.AdditionalFields["requestId"]
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	cancel := c.cancels[callKey(ctx, id)]
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// mcpProgress sends notifications/progress for a tool call whose client
+// asked for them with a progress token. Progress runs from 0 to the
+// number of stages, moving through each stage in order. A nil
+// *mcpProgress ignores every report.
+type mcpProgress struct {
+	ctx    context.Context
+	srv    *server.MCPServer
+	token  mcp.ProgressToken
+	stages []string
+	last   float64
+}
+
+// newMCPProgress returns a reporter for req's progress token, or nil if
+// the client did not send one.
+func newMCPProgress(ctx context.Context, req mcp.CallToolRequest, stages ...string) *mcpProgress {
+	srv := server.ServerFromContext(ctx)
+	if srv == nil || req.Params.Meta == nil || req.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	return &mcpProgress{ctx: ctx, srv: srv, token: req.Params.Meta.ProgressToken, stages: stages, last: -1}
+}
+
+// stage reports done of total chunks through stage.
+func (p *mcpProgress) stage(stage string, done, total int) {
+	if p == nil || total <= 0 {
+		return
+	}
+	if pos := p.position(stage); pos >= 0 {
+		p.send(float64(pos)+float64(done)/float64(total), fmt.Sprintf("%s %d/%d", stage, done, total))
+	}
+}
+
+// observe reports entering a stage with n items. It is a contextlab
+// stage observer.
+func (p *mcpProgress) observe(stage string, n int) {
+	if p == nil {
+		return
+	}
+	if pos := p.position(stage); pos >= 0 {
+		p.send(float64(pos), fmt.Sprintf("%s (%d)", stage, n))
+	}
+}
+
+func (p *mcpProgress) position(stage string) int {
+	for i, s := range p.stages {
+		if s == stage {
+			return i
+		}
+	}
+	return -1
+}
+
+// done reports completion.
+func (p *mcpProgress) done() {
+	if p == nil {
+		return
+	}
+	p.send(float64(len(p.stages)), "done")
+}
+
+// send emits a notification unless it would not move progress forward,
+// which the protocol requires.
+func (p *mcpProgress) send(progress float64, message string) {
+	if progress <= p.last {
+		return
+	}
+	p.last = progress
+	_ = p.srv.SendNotificationToClient(p.ctx, "notifications/progress", map[string]any{
+		"progressToken": p.token,
+		"progress":      progress,
+		"total":         float64(len(p.stages)),
+		"message":       message,
+	})
+}
+
+// mcpEmbedBatch is how many chunk texts embedChunks sends per call, so
+// progress moves and a cancellation is noticed between calls.
+const mcpEmbedBatch = 64
+
+// embedChunks embeds the text of chunks[i] for each i in missing.
+func (m *MCPServer) embedChunks(ctx context.Context, chunks []types.Chunk, missing []int, progress *mcpProgress) error {
+	for start := 0; start < len(missing); start += mcpEmbedBatch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		progress.stage(contextlab.StageEmbedding, start, len(missing))
+
+		batch := missing[start:min(start+mcpEmbedBatch, len(missing))]
+		texts := make([]string, len(batch))
+		for j, i := range batch {
+			texts[j] = chunks[i].Text
+		}
+		embeddings, err := m.embedder.EmbedBatch(ctx, texts)
+		if err != nil {
+			return err
+		}
+		for j, i := range batch {
+			chunks[i].Embedding = embeddings[j]
+		}
+	}
+	return nil
+}
+
+// mcpInterrupted is the tool result for a call that failed during stage,
+// saying plainly when the client cancelled it.
+func mcpInterrupted(err error, stage string) *mcp.CallToolResult {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return mcp.NewToolResultError(stage + " cancelled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return mcp.NewToolResultError(stage + " timed out")
+	}
+	return mcp.NewToolResultError(fmt.Sprintf("%s failed: %v", stage, err))
+}
@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"net/url"
 
 	"github.com/Siddhant-K-code/distill/pkg/session"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -26,8 +26,8 @@ func (m *MCPServer) handleCreateSession(ctx context.Context, request mcp.CallToo
 		return mcp.NewToolResultError(fmt.Sprintf("create session: %v", err)), nil
 	}
 
-	data, _ := json.MarshalIndent(sess, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	summary := fmt.Sprintf("Created session %s with a %d-token budget.", sess.ID, sess.MaxTokens)
+	return toolResult(summary, sess), nil
 }
 
 func (m *MCPServer) handlePushSession(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -70,8 +70,9 @@ func (m *MCPServer) handlePushSession(ctx context.Context, request mcp.CallToolR
 		return mcp.NewToolResultError(fmt.Sprintf("push: %v", err)), nil
 	}
 
-	data, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	summary := fmt.Sprintf("Accepted %d, deduplicated %d, compressed %d, evicted %d; %d tokens of budget remaining.",
+		result.Accepted, result.Deduplicated, result.Compressed, result.Evicted, result.BudgetRemaining)
+	return toolResult(summary, result), nil
 }
 
 func (m *MCPServer) handleSessionContext(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -98,8 +99,23 @@ func (m *MCPServer) handleSessionContext(ctx context.Context, request mcp.CallTo
 		return mcp.NewToolResultError(fmt.Sprintf("context: %v", err)), nil
 	}
 
-	data, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	resources := make([]mcp.Content, len(result.Entries))
+	for i, e := range result.Entries {
+		meta := map[string]any{
+			"id":     e.ID,
+			"role":   e.Role,
+			"level":  e.Level,
+			"tokens": e.Tokens,
+			"age":    e.Age,
+		}
+		if e.Source != "" {
+			meta["source"] = e.Source
+		}
+		uri := "distill://session/" + url.PathEscape(sessionID) + "/entry/" + url.PathEscape(e.ID)
+		resources[i] = textResource(uri, e.Content, meta)
+	}
+	summary := fmt.Sprintf("Session %s: %d entries, %d tokens.", sessionID, len(result.Entries), result.Stats.TotalTokens)
+	return toolResult(summary, result, resources...), nil
 }
 
 func (m *MCPServer) handleDeleteSession(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -115,6 +131,6 @@ func (m *MCPServer) handleDeleteSession(ctx context.Context, request mcp.CallToo
 		return mcp.NewToolResultError(fmt.Sprintf("delete: %v", err)), nil
 	}
 
-	data, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	summary := fmt.Sprintf("Deleted session %s (%d entries).", result.SessionID, result.EntriesRemoved)
+	return toolResult(summary, result), nil
 }
@@ -4,13 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 
 	"github.com/Siddhant-K-code/distill/pkg/embedding"
 	_ "github.com/Siddhant-K-code/distill/pkg/embedding/cohere"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/local"
 	_ "github.com/Siddhant-K-code/distill/pkg/embedding/ollama"
 	_ "github.com/Siddhant-K-code/distill/pkg/embedding/openai"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/voyage"
 	"github.com/Siddhant-K-code/distill/pkg/memory"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -70,8 +72,8 @@ func init() {
 	memoryStoreCmd.Flags().String("source", "", "Source of the memory (e.g., code_review, docs)")
 	memoryStoreCmd.Flags().StringSlice("tags", nil, "Tags for the memory")
 	memoryStoreCmd.Flags().String("session-id", "", "Session ID")
-	memoryStoreCmd.Flags().String("openai-key", "", "API key for embeddings (or OPENAI_API_KEY / COHERE_API_KEY)")
-	memoryStoreCmd.Flags().String("embedding-provider", "", "Embedding provider (openai, ollama, cohere)")
+	memoryStoreCmd.Flags().String("openai-key", "", "API key for embeddings (or OPENAI_API_KEY / COHERE_API_KEY / VOYAGE_API_KEY)")
+	memoryStoreCmd.Flags().String("embedding-provider", "", "Embedding provider (openai, ollama, cohere, voyage, local)")
 
 	// Recall flags
 	memoryRecallCmd.Flags().String("query", "", "Query text")
@@ -79,8 +81,8 @@ func init() {
 	memoryRecallCmd.Flags().Int("max-results", 10, "Maximum results to return")
 	memoryRecallCmd.Flags().Int("max-tokens", 0, "Maximum token budget (0 = unlimited)")
 	memoryRecallCmd.Flags().Float64("recency-weight", 0.3, "Weight for recency vs relevance (0-1)")
-	memoryRecallCmd.Flags().String("openai-key", "", "API key for embeddings (or OPENAI_API_KEY / COHERE_API_KEY)")
-	memoryRecallCmd.Flags().String("embedding-provider", "", "Embedding provider (openai, ollama, cohere)")
+	memoryRecallCmd.Flags().String("openai-key", "", "API key for embeddings (or OPENAI_API_KEY / COHERE_API_KEY / VOYAGE_API_KEY)")
+	memoryRecallCmd.Flags().String("embedding-provider", "", "Embedding provider (openai, ollama, cohere, voyage, local)")
 
 	// Forget flags
 	memoryForgetCmd.Flags().StringSlice("tags", nil, "Remove memories with these tags")
@@ -100,42 +102,13 @@ func openMemoryStore(cmd *cobra.Command) (*memory.SQLiteStore, error) {
 // createEmbedder builds an embedding.Provider from CLI flags and config.
 func createEmbedder(cmd *cobra.Command) (embedding.Provider, error) {
 	apiKey, _ := cmd.Flags().GetString("openai-key")
-	if apiKey == "" {
-		apiKey = os.Getenv("OPENAI_API_KEY")
-	}
-
 	providerName, _ := cmd.Flags().GetString("embedding-provider")
 	if providerName == "" {
 		providerName = viper.GetString("embedding.provider")
 	}
-	if providerName == "" {
-		providerName = "openai"
-	}
-
-	// Ollama doesn't need an API key
-	needsKey := providerName == "openai" || providerName == "cohere"
-	if needsKey && apiKey == "" {
-		if providerName == "cohere" {
-			apiKey = os.Getenv("COHERE_API_KEY")
-		}
-		if apiKey == "" {
-			return nil, nil // no key available, skip embedding
-		}
-	}
 
-	model := viper.GetString("embedding.model")
-	if model == "" {
-		model = "text-embedding-3-small"
-	}
-	baseURL := viper.GetString("embedding.base_url")
-
-	return embedding.NewProvider(embedding.ProviderConfig{
-		Type:      embedding.ProviderType(providerName),
-		APIKey:    apiKey,
-		Model:     model,
-		BaseURL:   baseURL,
-		CacheSize: -1,
-	})
+	// Without an API key for a cloud provider, embedding is skipped
+	return newEmbedder(providerName, apiKey, viper.GetString("embedding.model"), viper.GetString("embedding.base_url"))
 }
 
 func runMemoryStore(cmd *cobra.Command, args []string) error {
@@ -217,7 +190,7 @@ func runMemoryRecall(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("create embedder: %w", err)
 	}
 	if embedder != nil {
-		emb, err := embedder.Embed(context.Background(), query)
+		emb, err := retriever.EmbedQuery(context.Background(), embedder, query)
 		if err != nil {
 			return fmt.Errorf("embed query: %w", err)
 		}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/models"
+	"github.com/Siddhant-K-code/distill/pkg/render"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addModelFlags adds the default model profile flag to serve.
+func addModelFlags(cmd *cobra.Command) {
+	cmd.Flags().String("model", "", "Model profile (from models.profiles) for requests that name none")
+	_ = viper.BindPFlag("models.default", cmd.Flags().Lookup("model"))
+}
+
+// modelRegistry reads the profiles in models.profiles, or returns nil
+// when there are none. Each profile's format must be a template renderer
+// knows.
+func modelRegistry(renderer *render.Renderer) (*models.Registry, error) {
+	var profiles []models.Profile
+	if err := viper.UnmarshalKey("models.profiles", &profiles); err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("models.profiles: %w", err))
+	}
+	def := viper.GetString("models.default")
+	if len(profiles) == 0 && def == "" {
+		return nil, nil
+	}
+	for _, p := range profiles {
+		if err := renderer.Check(p.Format); err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("model %q: %w", p.Name, err))
+		}
+	}
+	return models.New(profiles, def)
+}
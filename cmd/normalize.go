@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/config"
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/spf13/viper"
+)
+
+// embeddingNormalization returns the text contract for provider: the
+// provider's default, with the embedding.normalization.<provider> section
+// applied over it.
+func embeddingNormalization(provider string) (*embedding.Normalization, error) {
+	provider = strings.ToLower(provider)
+	n := embedding.DefaultNormalization(embedding.ProviderType(provider))
+
+	var override config.NormalizationConfig
+	key := "embedding.normalization." + provider
+	if err := viper.UnmarshalKey(key, &override); err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("%s: %w", key, err))
+	}
+	if override.StripControl != nil {
+		n.StripControl = *override.StripControl
+	}
+	if override.MaxChars != nil {
+		if *override.MaxChars < 0 {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("%s.max_chars: must be non-negative", key))
+		}
+		n.MaxChars = *override.MaxChars
+	}
+	n.QueryPrefix = override.QueryPrefix
+	n.PassagePrefix = override.PassagePrefix
+	n.ModelPrefixes = override.ModelPrefixes
+	return &n, nil
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/history"
+	"github.com/Siddhant-K-code/distill/pkg/nsstats"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addNamespaceStatsFlags adds the per-namespace aggregate flags to serve.
+func addNamespaceStatsFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("namespace-stats-window", nsstats.DefaultWindow, "How far back /v1/stats/namespaces aggregates look")
+	cmd.Flags().Duration("namespace-snapshot-interval", 5*time.Minute, "How often namespace aggregates are recorded in --history-db (0 = never)")
+
+	_ = viper.BindPFlag("history.namespace_window", cmd.Flags().Lookup("namespace-stats-window"))
+	_ = viper.BindPFlag("history.namespace_snapshot_interval", cmd.Flags().Lookup("namespace-snapshot-interval"))
+}
+
+// namespaceTracker returns the tracker behind /v1/stats/namespaces.
+func namespaceTracker() (*nsstats.Tracker, error) {
+	window := viper.GetDuration("history.namespace_window")
+	if window < 0 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--namespace-stats-window must be non-negative, got %v", window))
+	}
+	if interval := viper.GetDuration("history.namespace_snapshot_interval"); interval < 0 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--namespace-snapshot-interval must be non-negative, got %v", interval))
+	}
+	return nsstats.New(window), nil
+}
+
+// observeNamespace adds a served retrieve to its namespace's aggregates.
+// Multi-namespace requests count under their namespaces joined by "+".
+func (s *Server) observeNamespace(req *types.RetrievalRequest, result *types.BrokerResult) {
+	ns := req.Namespace
+	if len(req.Namespaces) > 0 {
+		names := make([]string, len(req.Namespaces))
+		for i, q := range req.Namespaces {
+			names[i] = q.Name
+		}
+		ns = strings.Join(names, "+")
+	}
+	s.namespaces.Observe(nsstats.Sample{
+		Namespace: ns,
+		Retrieved: result.Stats.Retrieved,
+		Returned:  result.Stats.Returned,
+		Clusters:  result.Stats.Clustered,
+		CacheHit:  result.Stats.CacheHit,
+		Latency:   result.Stats.TotalLatency,
+	})
+}
+
+// snapshotNamespaces records each namespace's aggregates in the history
+// every interval until ctx is done. It does nothing without a history
+// database or with a zero interval.
+func snapshotNamespaces(ctx context.Context, t *nsstats.Tracker, w *history.Writer, interval time.Duration) {
+	if w == nil || t == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, st := range t.Snapshot() {
+				w.Record(namespaceStatsRecord(st, t.Window(), now))
+			}
+		}
+	}
+}
+
+// namespaceStatsRecord is the history record of st, aggregated over the
+// window up to now.
+func namespaceStatsRecord(st nsstats.Stats, window time.Duration, now time.Time) history.Record {
+	return history.Record{
+		Kind:      history.KindNamespaceStats,
+		Name:      st.Namespace,
+		StartedAt: now.Add(-window),
+		Duration:  window,
+		Input:     st.Retrieved,
+		Output:    st.Returned,
+		Details: map[string]interface{}{
+			"requests":       st.Requests,
+			"avg_reduction":  st.AvgReduction,
+			"avg_clusters":   st.AvgClusters,
+			"cache_hit_rate": st.CacheHitRate,
+			"p95_latency_ms": ms(st.P95Latency),
+		},
+	}
+}
+
+// NamespaceStatsResponse is the JSON response for /v1/stats/namespaces.
+type NamespaceStatsResponse struct {
+	// WindowSeconds is how far back the aggregates look.
+	WindowSeconds float64 `json:"window_seconds"`
+
+	// Namespaces are ordered by avg_reduction, most redundant first.
+	// The default namespace is "".
+	Namespaces []NamespaceStats `json:"namespaces"`
+}
+
+// NamespaceStats is one namespace's aggregates over the window.
+type NamespaceStats struct {
+	Namespace    string  `json:"namespace"`
+	Requests     int     `json:"requests"`
+	AvgReduction float64 `json:"avg_reduction"`
+	AvgClusters  float64 `json:"avg_clusters"`
+	CacheHitRate float64 `json:"cache_hit_rate"`
+	P95LatencyMs float64 `json:"p95_latency_ms"`
+}
+
+// handleNamespaceStats serves GET /v1/stats/namespaces.
+func (s *Server) handleNamespaceStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	stats := s.namespaces.Snapshot()
+	resp := NamespaceStatsResponse{Namespaces: make([]NamespaceStats, len(stats))}
+	if s.namespaces != nil {
+		resp.WindowSeconds = s.namespaces.Window().Seconds()
+	}
+	for i, st := range stats {
+		resp.Namespaces[i] = NamespaceStats{
+			Namespace:    st.Namespace,
+			Requests:     st.Requests,
+			AvgReduction: st.AvgReduction,
+			AvgClusters:  st.AvgClusters,
+			CacheHitRate: st.CacheHitRate,
+			P95LatencyMs: ms(st.P95Latency),
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
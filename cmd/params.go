@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	pcretriever "github.com/Siddhant-K-code/distill/pkg/retriever/pinecone"
+	qdretriever "github.com/Siddhant-K-code/distill/pkg/retriever/qdrant"
+	"github.com/spf13/viper"
+)
+
+// qdrantParams parses the retriever.params.qdrant config section.
+func qdrantParams() (qdretriever.Params, error) {
+	p, err := qdretriever.ParseParams(viper.GetStringMap("retriever.params.qdrant"))
+	if err != nil {
+		return p, fmt.Errorf("retriever.params.qdrant: %w", err)
+	}
+	return p, nil
+}
+
+// pineconeParams parses the retriever.params.pinecone config section.
+func pineconeParams() (pcretriever.Params, error) {
+	p, err := pcretriever.ParseParams(viper.GetStringMap("retriever.params.pinecone"))
+	if err != nil {
+		return p, fmt.Errorf("retriever.params.pinecone: %w", err)
+	}
+	return p, nil
+}
+
+// metadataFields returns the retriever.include_metadata_fields and
+// exclude_metadata_fields lists, keeping the fields serve reads after
+// retrieval: ACL lists when ACLs are enforced and the recency timestamp.
+func metadataFields() retriever.MetadataFields {
+	fields := retriever.MetadataFields{
+		Include: viper.GetStringSlice("retriever.include_metadata_fields"),
+		Exclude: viper.GetStringSlice("retriever.exclude_metadata_fields"),
+	}
+	if acl := aclConfig(); acl.Enabled {
+		fields = fields.Require(acl.GroupsField, acl.UsersField)
+	}
+	if viper.GetFloat64("dedup.recency_weight") > 0 {
+		fields = fields.Require(viper.GetString("dedup.timestamp_field"))
+	}
+	return fields
+}
+
+// retrieverTimeout returns retriever.timeout in whole seconds, rounded up;
+// zero leaves the adapter default.
+func retrieverTimeout() int {
+	return int(math.Ceil(viper.GetDuration("retriever.timeout").Seconds()))
+}
+
+// retrieverMaxRetries returns retriever.max_retries; zero leaves the
+// adapter default and a negative value disables retries.
+func retrieverMaxRetries() int {
+	return viper.GetInt("retriever.max_retries")
+}
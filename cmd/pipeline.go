@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
 	"github.com/Siddhant-K-code/distill/pkg/pipeline"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 	"github.com/spf13/cobra"
@@ -24,7 +26,11 @@ Example (file):
   distill pipeline --input chunks.json --output optimised.json
 
 Example (disable compress):
-  distill pipeline --no-compress --dedup-threshold 0.2`,
+  distill pipeline --no-compress --dedup-threshold 0.2
+
+Example (record the effective settings, then replay them exactly):
+  distill pipeline --input chunks.json --write-recipe recipe.json
+  distill pipeline --input chunks.json --recipe recipe.json`,
 	RunE: runPipeline,
 }
 
@@ -43,6 +49,7 @@ func init() {
 	// Compress flags.
 	pipelineCmd.Flags().Bool("no-compress", false, "Disable compression stage")
 	pipelineCmd.Flags().Float64("compress-ratio", 0.5, "Target compression ratio (0.5 = reduce to 50% of tokens)")
+	pipelineCmd.Flags().Int("compress-max-tokens", 0, "Output token budget, compressing chunks harder until it is met (0 = none)")
 
 	// Summarize flags.
 	pipelineCmd.Flags().Bool("summarize", false, "Enable summarization stage")
@@ -51,6 +58,17 @@ func init() {
 
 	// Output flags.
 	pipelineCmd.Flags().Bool("stats", false, "Print pipeline statistics to stderr")
+
+	// Recipe flags.
+	pipelineCmd.Flags().String("recipe", "", "Replay the settings in a recipe file instead of the stage flags")
+	pipelineCmd.Flags().String("write-recipe", "", "Write the effective settings, versions, and input hash to a recipe file")
+}
+
+// pipelineStageFlags are the flags a recipe replaces.
+var pipelineStageFlags = []string{
+	"no-dedup", "dedup-threshold", "dedup-lambda", "dedup-target-k",
+	"no-compress", "compress-ratio", "compress-max-tokens",
+	"summarize", "summarize-max-tokens", "summarize-recent",
 }
 
 func runPipeline(cmd *cobra.Command, _ []string) error {
@@ -81,6 +99,7 @@ func runPipeline(cmd *cobra.Command, _ []string) error {
 	lambda, _ := cmd.Flags().GetFloat64("dedup-lambda")
 	targetK, _ := cmd.Flags().GetInt("dedup-target-k")
 	compressRatio, _ := cmd.Flags().GetFloat64("compress-ratio")
+	compressMaxTokens, _ := cmd.Flags().GetInt("compress-max-tokens")
 	maxTokens, _ := cmd.Flags().GetInt("summarize-max-tokens")
 	keepRecent, _ := cmd.Flags().GetInt("summarize-recent")
 
@@ -91,11 +110,19 @@ func runPipeline(cmd *cobra.Command, _ []string) error {
 		DedupTargetK:            targetK,
 		CompressEnabled:         !noCompress,
 		CompressTargetReduction: compressRatio,
+		CompressMaxTokens:       compressMaxTokens,
 		SummarizeEnabled:        doSummarize,
 		SummarizeMaxTokens:      maxTokens,
 		SummarizeRecent:         keepRecent,
 	}
 
+	recipeFile, _ := cmd.Flags().GetString("recipe")
+	if recipeFile != "" {
+		if opts, err = replayRecipe(cmd, recipeFile, chunks); err != nil {
+			return err
+		}
+	}
+
 	// Run.
 	runner := pipeline.New()
 	result, stats, err := runner.Run(context.Background(), chunks, opts)
@@ -118,6 +145,16 @@ func runPipeline(cmd *cobra.Command, _ []string) error {
 		fmt.Println(string(out))
 	}
 
+	if path, _ := cmd.Flags().GetString("write-recipe"); path != "" {
+		data, err := json.MarshalIndent(pipeline.NewRecipe(opts, chunks), "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshalling recipe: %w", err)
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("writing recipe: %w", err)
+		}
+	}
+
 	// Print stats if requested.
 	printStats, _ := cmd.Flags().GetBool("stats")
 	if printStats {
@@ -132,11 +169,49 @@ func runPipeline(cmd *cobra.Command, _ []string) error {
 					name, s.Reduction*100, s.Latency)
 			}
 		}
+		for _, c := range result {
+			if level, ok := stats.CompressionLevels[c.ID]; ok && level != compress.LevelBase {
+				fmt.Fprintf(os.Stderr, "  compression[%s]: %s\n", c.ID, level)
+			}
+		}
+		if stats.CompressOverBudget {
+			fmt.Fprintf(os.Stderr, "  warning: output exceeds --compress-max-tokens at the most aggressive compression\n")
+		}
 	}
 
 	return nil
 }
 
+// replayRecipe loads the options in a recipe file. Stage flags cannot be
+// combined with a recipe. A different distill version or input is
+// reported on stderr, since the output may then differ.
+func replayRecipe(cmd *cobra.Command, path string, chunks []types.Chunk) (pipeline.Options, error) {
+	for _, name := range pipelineStageFlags {
+		if cmd.Flags().Changed(name) {
+			return pipeline.Options{}, errs.Wrap(errs.ErrConfig, fmt.Errorf("--%s cannot be combined with --recipe", name))
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return pipeline.Options{}, fmt.Errorf("reading recipe: %w", err)
+	}
+	var recipe pipeline.Recipe
+	if err := json.Unmarshal(data, &recipe); err != nil {
+		return pipeline.Options{}, errs.Wrap(errs.ErrConfig, fmt.Errorf("parsing recipe %s: %w", path, err))
+	}
+	opts, err := recipe.Options()
+	if err != nil {
+		return pipeline.Options{}, err
+	}
+	if v := pipeline.Version(); recipe.DistillVersion != v {
+		fmt.Fprintf(os.Stderr, "warning: recipe was recorded with distill %s, running %s\n", recipe.DistillVersion, v)
+	}
+	if recipe.InputSHA256 != "" && recipe.InputSHA256 != pipeline.InputHash(chunks) {
+		fmt.Fprintf(os.Stderr, "warning: input differs from the one the recipe was recorded with\n")
+	}
+	return opts, nil
+}
+
 // readStdin reads all of stdin.
 func readStdin() ([]byte, error) {
 	var buf []byte
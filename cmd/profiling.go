@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/profiling"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addProfilingFlags registers continuous profiling flags on a server
+// command. Like the runtime flags, they are read directly so several
+// commands can share the telemetry.profiling.* config keys.
+func addProfilingFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("profiling", false, "Enable continuous profiling (config: telemetry.profiling.enabled)")
+	cmd.Flags().String("pprof-addr", "", "Internal address for net/http/pprof, e.g. 127.0.0.1:6060 (config: telemetry.profiling.pprof_addr)")
+	cmd.Flags().String("profiling-push-url", "", "Pyroscope server to push CPU profiles to (config: telemetry.profiling.push_url)")
+}
+
+// startProfiling starts continuous profiling from flags, falling back to
+// config. It returns a no-op stop function when profiling is off.
+func startProfiling(cmd *cobra.Command) (stop func(), err error) {
+	enabled := viper.GetBool("telemetry.profiling.enabled")
+	if cmd.Flags().Changed("profiling") {
+		enabled, _ = cmd.Flags().GetBool("profiling")
+	}
+	if !enabled {
+		return func() {}, nil
+	}
+
+	cfg := profiling.Config{
+		PprofAddr:         "127.0.0.1:6060",
+		PushURL:           viper.GetString("telemetry.profiling.push_url"),
+		AppName:           viper.GetString("telemetry.profiling.app_name"),
+		Interval:          viper.GetDuration("telemetry.profiling.interval"),
+		Labels:            viper.GetStringMapString("telemetry.profiling.labels"),
+		AuthToken:         viper.GetString("telemetry.profiling.auth_token"),
+		BasicAuthUser:     viper.GetString("telemetry.profiling.basic_auth_user"),
+		BasicAuthPassword: viper.GetString("telemetry.profiling.basic_auth_password"),
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "profiling: %v\n", err)
+		},
+	}
+	if viper.IsSet("telemetry.profiling.pprof_addr") {
+		cfg.PprofAddr = viper.GetString("telemetry.profiling.pprof_addr")
+	}
+	if cmd.Flags().Changed("pprof-addr") {
+		cfg.PprofAddr, _ = cmd.Flags().GetString("pprof-addr")
+	}
+	if cmd.Flags().Changed("profiling-push-url") {
+		cfg.PushURL, _ = cmd.Flags().GetString("profiling-push-url")
+	}
+	if cfg.PprofAddr == "" && cfg.PushURL == "" {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("profiling enabled without a pprof address or push URL"))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("profiling: %w", err))
+	}
+
+	p, err := profiling.Start(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start profiling: %w", err)
+	}
+
+	var desc []string
+	if addr := p.Addr(); addr != "" {
+		desc = append(desc, "pprof on "+addr)
+	}
+	if cfg.PushURL != "" {
+		desc = append(desc, "pushing to "+cfg.PushURL)
+	}
+	fmt.Fprintf(os.Stderr, "Profiling: %s\n", strings.Join(desc, ", "))
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = p.Stop(ctx)
+	}, nil
+}
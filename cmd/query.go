@@ -9,13 +9,17 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/errs"
 	"github.com/Siddhant-K-code/distill/pkg/contextlab"
-	"github.com/Siddhant-K-code/distill/pkg/embedding/openai"
+	"github.com/Siddhant-K-code/distill/pkg/dedup"
+	"github.com/Siddhant-K-code/distill/pkg/render"
 	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	fakeretriever "github.com/Siddhant-K-code/distill/pkg/retriever/fake"
 	pcretriever "github.com/Siddhant-K-code/distill/pkg/retriever/pinecone"
 	qdretriever "github.com/Siddhant-K-code/distill/pkg/retriever/qdrant"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var queryCmd = &cobra.Command{
@@ -24,10 +28,16 @@ var queryCmd = &cobra.Command{
 	Long: `Performs a semantic search with deduplication and displays results.
 Useful for testing and tuning ContextLab parameters.
 
+--repeat runs the query several times on one connection and reports
+latency percentiles. --export writes every run's chunks and stats to a
+CSV or JSON file, chosen by its extension.
+
 Example:
   distill query "How do I configure authentication?" --index my-index
+  distill query "refund policy" --index my-index --repeat 20 --export runs.csv
 
-Requires PINECONE_API_KEY and OPENAI_API_KEY environment variables.`,
+Requires PINECONE_API_KEY and the embedding provider's API key
+(OPENAI_API_KEY, COHERE_API_KEY, or VOYAGE_API_KEY).`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runQuery,
 }
@@ -36,22 +46,30 @@ func init() {
 	rootCmd.AddCommand(queryCmd)
 
 	// Backend settings
-	queryCmd.Flags().String("backend", "pinecone", "Vector DB backend (pinecone, qdrant)")
+	queryCmd.Flags().String("backend", "pinecone", "Vector DB backend (pinecone, qdrant, fake)")
 	queryCmd.Flags().StringP("index", "i", "", "Index/collection name (required)")
 	queryCmd.Flags().String("api-key", "", "Vector DB API key")
 	queryCmd.Flags().String("db-host", "", "Vector DB host (for Qdrant)")
 	queryCmd.Flags().StringP("namespace", "n", "", "Namespace")
 
 	// Embedding settings
-	queryCmd.Flags().String("openai-key", "", "OpenAI API key")
-	queryCmd.Flags().String("embedding-model", "text-embedding-3-small", "Embedding model")
+	queryCmd.Flags().String("openai-key", "", "API key for embeddings (or OPENAI_API_KEY / COHERE_API_KEY / VOYAGE_API_KEY)")
+	queryCmd.Flags().String("embedding-provider", "", "Embedding provider (openai, ollama, cohere, voyage, local) (config: embedding.provider)")
+	queryCmd.Flags().String("embedding-model", defaultEmbeddingModel, "Embedding model (local: model directory)")
 
 	// ContextLab settings
 	queryCmd.Flags().Int("over-fetch-k", 50, "Number of chunks to over-fetch")
 	queryCmd.Flags().Int("target-k", 8, "Target number of chunks")
+	queryCmd.Flags().Float64("min-score", 0, "Drop matches scoring below this (0 = off)")
+	queryCmd.Flags().Bool("include-tombstoned", false, "Return duplicates soft-deleted by sync --tombstone")
 	queryCmd.Flags().Float64("threshold", 0.15, "Clustering threshold")
 	queryCmd.Flags().Float64("lambda", 0.5, "MMR lambda")
 	queryCmd.Flags().Bool("enable-mmr", true, "Enable MMR re-ranking")
+	queryCmd.Flags().Float64("recency-weight", 0, "Weight of recency in MMR relevance, 0-1 (0 = off)")
+	queryCmd.Flags().Duration("recency-half-life", contextlab.DefaultRecencyHalfLife, "Age at which a chunk's recency halves")
+	queryCmd.Flags().String("timestamp-field", contextlab.DefaultTimestampField, "Metadata field holding chunk timestamps")
+	addEntityFlags(queryCmd)
+	addStageFlags(queryCmd)
 	queryCmd.Flags().Bool("no-dedup", false, "Disable deduplication (raw retrieval)")
 
 	// Output settings
@@ -59,6 +77,9 @@ func init() {
 	queryCmd.Flags().Bool("show-metadata", false, "Show chunk metadata")
 	queryCmd.Flags().Bool("show-stats", true, "Show processing statistics")
 	queryCmd.Flags().Int("text-limit", 200, "Max characters of text to show per chunk")
+	queryCmd.Flags().String("template", "", "Print results rendered with a template (plain, numbered, markdown, xml, or a render.templates name)")
+	queryCmd.Flags().String("export", "", "Write each run's chunk IDs, scores, cluster IDs, and stats to this .csv or .json file")
+	queryCmd.Flags().Int("repeat", 1, "Run the query this many times and report latency percentiles")
 }
 
 func runQuery(cmd *cobra.Command, args []string) error {
@@ -71,32 +92,59 @@ func runQuery(cmd *cobra.Command, args []string) error {
 	dbHost, _ := cmd.Flags().GetString("db-host")
 	namespace, _ := cmd.Flags().GetString("namespace")
 	openaiKey, _ := cmd.Flags().GetString("openai-key")
+	embeddingProvider, _ := cmd.Flags().GetString("embedding-provider")
 	embeddingModel, _ := cmd.Flags().GetString("embedding-model")
 	overFetchK, _ := cmd.Flags().GetInt("over-fetch-k")
 	targetK, _ := cmd.Flags().GetInt("target-k")
+	minScore, _ := cmd.Flags().GetFloat64("min-score")
+	includeTombstoned, _ := cmd.Flags().GetBool("include-tombstoned")
 	threshold, _ := cmd.Flags().GetFloat64("threshold")
 	lambda, _ := cmd.Flags().GetFloat64("lambda")
 	enableMMR, _ := cmd.Flags().GetBool("enable-mmr")
+	recencyWeight, _ := cmd.Flags().GetFloat64("recency-weight")
+	recencyHalfLife, _ := cmd.Flags().GetDuration("recency-half-life")
+	timestampField, _ := cmd.Flags().GetString("timestamp-field")
 	noDedup, _ := cmd.Flags().GetBool("no-dedup")
 	showText, _ := cmd.Flags().GetBool("show-text")
 	showMetadata, _ := cmd.Flags().GetBool("show-metadata")
 	showStats, _ := cmd.Flags().GetBool("show-stats")
 	textLimit, _ := cmd.Flags().GetInt("text-limit")
+	templateName, _ := cmd.Flags().GetString("template")
+	exportPath, _ := cmd.Flags().GetString("export")
+	repeat, _ := cmd.Flags().GetInt("repeat")
 
 	// Resolve API keys from environment
 	if apiKey == "" {
 		apiKey = os.Getenv("PINECONE_API_KEY")
 	}
-	if openaiKey == "" {
-		openaiKey = os.Getenv("OPENAI_API_KEY")
+	if embeddingProvider == "" {
+		embeddingProvider = viper.GetString("embedding.provider")
+	}
+	if embeddingProvider == "" {
+		embeddingProvider = "openai"
 	}
 
 	// Validate
-	if index == "" {
-		return fmt.Errorf("index name required (--index)")
+	renderer, err := newRenderer("")
+	if err != nil {
+		return err
 	}
-	if openaiKey == "" {
-		return fmt.Errorf("openai API key required for text queries (--openai-key or OPENAI_API_KEY)")
+	if err := renderer.Check(templateName); err != nil {
+		return errs.Wrap(errs.ErrConfig, err)
+	}
+	if repeat < 1 {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("--repeat must be at least 1, got %d", repeat))
+	}
+	if exportPath != "" {
+		if _, err := queryExportFormat(exportPath); err != nil {
+			return err
+		}
+	}
+	if index == "" && backend != fakeBackend {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("index name required (--index)"))
+	}
+	if env, cloud := embeddingKeyEnv[embeddingProvider]; cloud && embeddingAPIKey(embeddingProvider, openaiKey) == "" && backend != fakeBackend {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("%s API key required for text queries (--openai-key or %s)", embeddingProvider, env))
 	}
 
 	// Setup context with cancellation
@@ -113,36 +161,54 @@ func runQuery(cmd *cobra.Command, args []string) error {
 
 	// Create retriever
 	var ret retriever.Retriever
-	var err error
 
 	switch backend {
 	case "pinecone":
 		if apiKey == "" {
-			return fmt.Errorf("pinecone API key required")
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("pinecone API key required"))
+		}
+		var params pcretriever.Params
+		if params, err = pineconeParams(); err != nil {
+			return err
 		}
 		ret, err = pcretriever.NewClient(ctx, pcretriever.Config{
 			Config: retriever.Config{
 				APIKey:           apiKey,
 				DefaultNamespace: namespace,
+				MetadataFields:   metadataFields(),
+				TimeoutSeconds:   retrieverTimeout(),
+				MaxRetries:       retrieverMaxRetries(),
 			},
 			IndexName: index,
+			Params:    params,
 		})
 
 	case "qdrant":
 		if dbHost == "" {
-			return fmt.Errorf("qdrant host required (--db-host)")
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("qdrant host required (--db-host)"))
+		}
+		var params qdretriever.Params
+		if params, err = qdrantParams(); err != nil {
+			return err
 		}
 		ret, err = qdretriever.NewClient(ctx, qdretriever.Config{
 			Config: retriever.Config{
 				APIKey:           apiKey,
 				Host:             dbHost,
 				DefaultNamespace: namespace,
+				MetadataFields:   metadataFields(),
+				TimeoutSeconds:   retrieverTimeout(),
+				MaxRetries:       retrieverMaxRetries(),
 			},
 			Collection: index,
+			Params:     params,
 		})
 
+	case fakeBackend:
+		ret, err = newFakeRetriever()
+
 	default:
-		return fmt.Errorf("unsupported backend: %s", backend)
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("unsupported backend: %s", backend))
 	}
 
 	if err != nil {
@@ -151,25 +217,28 @@ func runQuery(cmd *cobra.Command, args []string) error {
 	defer func() { _ = ret.Close() }()
 
 	// Create embedding provider
-	embedder, err := openai.NewClient(openai.Config{
-		APIKey: openaiKey,
-		Model:  embeddingModel,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create embedding provider: %w", err)
+	var embedder retriever.EmbeddingProvider
+	if fr, ok := ret.(*fakeretriever.Client); ok {
+		embedder = fr.Embedder()
+	} else {
+		embedder, err = newEmbedder(embeddingProvider, openaiKey, embeddingModel, viper.GetString("embedding.base_url"))
+		if err != nil {
+			return fmt.Errorf("failed to create embedding provider: %w", errs.Wrap(errs.ErrConfig, err))
+		}
 	}
 
 	fmt.Fprintf(os.Stderr, "Query: %s\n", query)
 	fmt.Fprintf(os.Stderr, "Embedding query...\n")
 
 	// Embed query
-	embedding, err := embedder.Embed(ctx, query)
+	embedding, err := retriever.EmbedQuery(ctx, embedder, query)
 	if err != nil {
-		return fmt.Errorf("failed to embed query: %w", err)
+		return fmt.Errorf("failed to embed query: %w", errs.ClassifyRemote(err))
 	}
 
-	var chunks []types.Chunk
-	var stats types.BrokerStats
+	// retrieve runs the query once; --repeat runs it again on the same
+	// retriever and broker
+	var retrieve func() (queryRun, error)
 
 	if noDedup {
 		// Raw retrieval without deduplication
@@ -179,60 +248,106 @@ func runQuery(cmd *cobra.Command, args []string) error {
 			QueryEmbedding:    embedding,
 			TopK:              targetK,
 			Namespace:         namespace,
+			MinScore:          float32(minScore),
 			IncludeEmbeddings: true,
 			IncludeMetadata:   true,
 		}
-
-		start := time.Now()
-		result, err := ret.Query(ctx, req)
-		if err != nil {
-			return fmt.Errorf("retrieval failed: %w", err)
+		if !includeTombstoned {
+			req.ExcludeFilter = map[string]interface{}{dedup.TombstoneKey: true}
 		}
 
-		chunks = result.Chunks
-		stats = types.BrokerStats{
-			Retrieved:        len(chunks),
-			Returned:         len(chunks),
-			RetrievalLatency: result.Latency,
-			TotalLatency:     time.Since(start),
+		retrieve = func() (queryRun, error) {
+			start := time.Now()
+			result, err := ret.Query(ctx, req)
+			if err != nil {
+				return queryRun{}, fmt.Errorf("retrieval failed: %w", err)
+			}
+
+			chunks := retriever.DropExcluded(retriever.DropBelow(result.Chunks, req.MinScore), req.ExcludeFilter)
+			return queryRun{Chunks: chunks, Stats: types.BrokerStats{
+				Retrieved:        len(chunks),
+				Returned:         len(chunks),
+				Truncated:        result.Truncated,
+				RetrievalLatency: result.Latency,
+				TotalLatency:     time.Since(start),
+			}}, nil
 		}
 	} else {
 		// Use ContextLab broker
 		fmt.Fprintf(os.Stderr, "Retrieving with deduplication...\n")
 
+		nsEntities, err := namespaceEntities()
+		if err != nil {
+			return err
+		}
 		brokerCfg := contextlab.BrokerConfig{
 			OverFetchK:        overFetchK,
 			TargetK:           targetK,
 			ClusterThreshold:  threshold,
 			ClusterLinkage:    "average",
-			SelectionStrategy: contextlab.SelectByScore,
 			EnableMMR:         enableMMR,
 			MMRLambda:         lambda,
+			RecencyWeight:     recencyWeight,
+			RecencyHalfLife:   recencyHalfLife,
+			TimestampField:    timestampField,
+			Entities:          entityConfig(cmd),
+			NamespaceEntities: nsEntities,
+			MinScore:          minScore,
 			IncludeMetadata:   true,
+			IncludeTombstoned: includeTombstoned,
+		}
+		if err := applyStageFlags(cmd, &brokerCfg); err != nil {
+			return err
 		}
 
 		broker := contextlab.NewBrokerWithEmbedder(ret, embedder, brokerCfg)
 		defer func() { _ = broker.Close() }()
 
-		req := &types.RetrievalRequest{
-			QueryEmbedding: embedding,
-			Namespace:      namespace,
+		retrieve = func() (queryRun, error) {
+			req := &types.RetrievalRequest{
+				QueryEmbedding: embedding,
+				Namespace:      namespace,
+			}
+			result, err := broker.Retrieve(ctx, req)
+			if err != nil {
+				return queryRun{}, fmt.Errorf("retrieval failed: %w", err)
+			}
+			return queryRun{Chunks: result.Chunks, Stats: result.Stats, Stages: result.Stages}, nil
 		}
+	}
 
-		result, err := broker.Retrieve(ctx, req)
-		if err != nil {
-			return fmt.Errorf("retrieval failed: %w", err)
-		}
+	runs, err := repeatQuery(retrieve, repeat)
+	if err != nil {
+		return err
+	}
+	last := runs[len(runs)-1]
+	chunks, stats, stages := last.Chunks, last.Stats, last.Stages
 
-		chunks = result.Chunks
-		stats = result.Stats
+	if exportPath != "" {
+		if err := exportQueryRuns(exportPath, query, runs); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d runs to %s\n", len(runs), exportPath)
 	}
 
 	fmt.Fprintln(os.Stderr)
 
+	// Rendered output replaces the listing, for piping into a prompt
+	if templateName != "" {
+		rendered, err := renderer.Render(templateName, render.NewData(query, &types.BrokerResult{Chunks: chunks, Stats: stats}))
+		if err != nil {
+			return err
+		}
+		fmt.Println(rendered)
+		return nil
+	}
+
 	// Display results
 	if len(chunks) == 0 {
 		fmt.Println("No results found.")
+		if len(runs) > 1 {
+			printQueryLatency(runs)
+		}
 		return nil
 	}
 
@@ -268,9 +383,18 @@ func runQuery(cmd *cobra.Command, args []string) error {
 	if showStats {
 		fmt.Println("=== Statistics ===")
 		fmt.Printf("Retrieved:    %d chunks\n", stats.Retrieved)
+		if len(stages) > 0 {
+			fmt.Printf("Stages:       %s\n", strings.Join(stages, " -> "))
+		}
+		if stats.Truncated {
+			fmt.Printf("Truncated:    backend capped the over-fetch\n")
+		}
 		if stats.Clustered > 0 {
 			fmt.Printf("Clusters:     %d\n", stats.Clustered)
 		}
+		if stats.Vetoed > 0 {
+			fmt.Printf("Vetoed:       %d near-duplicate pairs naming different entities\n", stats.Vetoed)
+		}
 		fmt.Printf("Returned:     %d chunks\n", stats.Returned)
 		if stats.Retrieved > 0 && stats.Returned > 0 {
 			reduction := float64(stats.Retrieved-stats.Returned) / float64(stats.Retrieved) * 100
@@ -282,6 +406,9 @@ func runQuery(cmd *cobra.Command, args []string) error {
 		}
 		fmt.Printf("Total:        %dms\n", stats.TotalLatency.Milliseconds())
 	}
+	if len(runs) > 1 {
+		printQueryLatency(runs)
+	}
 
 	return nil
 }
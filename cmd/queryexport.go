@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/schollz/progressbar/v3"
+)
+
+// queryRun is one execution of distill query.
+type queryRun struct {
+	Chunks []types.Chunk
+	Stats  types.BrokerStats
+	Stages []string
+}
+
+// repeatQuery runs retrieve n times, showing progress when n > 1, and
+// returns every run. It stops at the first error.
+func repeatQuery(retrieve func() (queryRun, error), n int) ([]queryRun, error) {
+	var bar *progressbar.ProgressBar
+	if n > 1 {
+		bar = progressbar.NewOptions(n,
+			progressbar.OptionSetDescription("Running"),
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionShowCount(),
+			progressbar.OptionSetItsString("runs"),
+			progressbar.OptionThrottle(100*time.Millisecond),
+			progressbar.OptionFullWidth(),
+			progressbar.OptionSetRenderBlankState(true),
+		)
+	}
+	runs := make([]queryRun, 0, n)
+	for i := 0; i < n; i++ {
+		run, err := retrieve()
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+		if bar != nil {
+			_ = bar.Add(1)
+		}
+	}
+	if bar != nil {
+		_ = bar.Finish()
+		fmt.Fprintln(os.Stderr)
+	}
+	return runs, nil
+}
+
+// queryExportFormat returns csv or json from path's extension.
+func queryExportFormat(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv", ".json":
+		return ext[1:], nil
+	default:
+		return "", errs.Wrap(errs.ErrConfig, fmt.Errorf("--export must end in .csv or .json, got %q", path))
+	}
+}
+
+// QueryExport is the JSON file written by distill query --export.
+type QueryExport struct {
+	Query   string           `json:"query"`
+	Runs    []QueryExportRun `json:"runs"`
+	Latency *QueryLatency    `json:"latency,omitempty"`
+}
+
+// QueryExportRun is one run in a QueryExport. Runs are numbered from 1.
+type QueryExportRun struct {
+	Run    int                `json:"run"`
+	Chunks []QueryExportChunk `json:"chunks"`
+	Stats  QueryExportStats   `json:"stats"`
+}
+
+// QueryExportChunk is a returned chunk, in rank order. ClusterID is -1
+// for chunks that were not clustered.
+type QueryExportChunk struct {
+	ID        string  `json:"id"`
+	Score     float32 `json:"score"`
+	ClusterID int     `json:"cluster_id"`
+}
+
+// QueryExportStats are a run's counts and latencies.
+type QueryExportStats struct {
+	Retrieved    int     `json:"retrieved"`
+	Clustered    int     `json:"clustered"`
+	Returned     int     `json:"returned"`
+	Truncated    bool    `json:"truncated,omitempty"`
+	RetrievalMs  float64 `json:"retrieval_ms"`
+	ClusteringMs float64 `json:"clustering_ms"`
+	TotalMs      float64 `json:"total_ms"`
+}
+
+// QueryLatency summarizes the total latency of repeated runs.
+type QueryLatency struct {
+	Runs   int     `json:"runs"`
+	MinMs  float64 `json:"min_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+	MeanMs float64 `json:"mean_ms"`
+}
+
+// queryLatency computes the percentiles of the runs' total latency by
+// nearest rank.
+func queryLatency(runs []queryRun) QueryLatency {
+	ms := make([]float64, len(runs))
+	sum := 0.0
+	for i, r := range runs {
+		ms[i] = millis(r.Stats.TotalLatency)
+		sum += ms[i]
+	}
+	sort.Float64s(ms)
+	at := func(q float64) float64 {
+		return ms[int(math.Ceil(q*float64(len(ms))))-1]
+	}
+	return QueryLatency{
+		Runs:   len(ms),
+		MinMs:  ms[0],
+		P50Ms:  at(0.5),
+		P90Ms:  at(0.9),
+		P99Ms:  at(0.99),
+		MaxMs:  ms[len(ms)-1],
+		MeanMs: sum / float64(len(ms)),
+	}
+}
+
+// printQueryLatency prints the latency summary of repeated runs.
+func printQueryLatency(runs []queryRun) {
+	l := queryLatency(runs)
+	fmt.Println()
+	fmt.Printf("=== Latency (%d runs) ===\n", l.Runs)
+	fmt.Printf("p50:          %.1fms\n", l.P50Ms)
+	fmt.Printf("p90:          %.1fms\n", l.P90Ms)
+	fmt.Printf("p99:          %.1fms\n", l.P99Ms)
+	fmt.Printf("Min / max:    %.1fms / %.1fms\n", l.MinMs, l.MaxMs)
+	fmt.Printf("Mean:         %.1fms\n", l.MeanMs)
+}
+
+// exportQueryRuns writes runs to path as CSV or JSON, by its extension.
+func exportQueryRuns(path, query string, runs []queryRun) error {
+	format, err := queryExportFormat(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("writing export: %w", errs.Wrap(errs.ErrConfig, err))
+	}
+	if format == "csv" {
+		err = writeQueryCSV(f, runs)
+	} else {
+		err = writeQueryJSON(f, query, runs)
+	}
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("writing export: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing export: %w", err)
+	}
+	return nil
+}
+
+func writeQueryJSON(w io.Writer, query string, runs []queryRun) error {
+	export := QueryExport{Query: query, Runs: make([]QueryExportRun, len(runs))}
+	for i, r := range runs {
+		run := QueryExportRun{Run: i + 1, Chunks: make([]QueryExportChunk, len(r.Chunks)), Stats: queryExportStats(r.Stats)}
+		for j, c := range r.Chunks {
+			run.Chunks[j] = QueryExportChunk{ID: c.ID, Score: c.Score, ClusterID: c.ClusterID}
+		}
+		export.Runs[i] = run
+	}
+	if len(runs) > 1 {
+		l := queryLatency(runs)
+		export.Latency = &l
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
+
+// writeQueryCSV writes one row per returned chunk, with its run's stats
+// repeated on each row. A run that returned nothing has one row with an
+// empty rank and ID.
+func writeQueryCSV(out io.Writer, runs []queryRun) error {
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"run", "rank", "id", "score", "cluster_id", "retrieved", "clustered", "returned", "truncated", "retrieval_ms", "clustering_ms", "total_ms"})
+	for i, r := range runs {
+		s := queryExportStats(r.Stats)
+		stats := []string{
+			strconv.Itoa(s.Retrieved),
+			strconv.Itoa(s.Clustered),
+			strconv.Itoa(s.Returned),
+			strconv.FormatBool(s.Truncated),
+			strconv.FormatFloat(s.RetrievalMs, 'f', -1, 64),
+			strconv.FormatFloat(s.ClusteringMs, 'f', -1, 64),
+			strconv.FormatFloat(s.TotalMs, 'f', -1, 64),
+		}
+		run := strconv.Itoa(i + 1)
+		if len(r.Chunks) == 0 {
+			_ = w.Write(append([]string{run, "", "", "", ""}, stats...))
+		}
+		for j, c := range r.Chunks {
+			row := []string{run, strconv.Itoa(j + 1), c.ID, strconv.FormatFloat(float64(c.Score), 'f', -1, 32), strconv.Itoa(c.ClusterID)}
+			_ = w.Write(append(row, stats...))
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func queryExportStats(s types.BrokerStats) QueryExportStats {
+	return QueryExportStats{
+		Retrieved:    s.Retrieved,
+		Clustered:    s.Clustered,
+		Returned:     s.Returned,
+		Truncated:    s.Truncated,
+		RetrievalMs:  millis(s.RetrievalLatency),
+		ClusteringMs: millis(s.ClusteringLatency),
+		TotalMs:      millis(s.TotalLatency),
+	}
+}
+
+// millis returns d in milliseconds, to the microsecond.
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// runsTaking returns one run per total latency, in milliseconds.
+func runsTaking(ms ...float64) []queryRun {
+	runs := make([]queryRun, len(ms))
+	for i, m := range ms {
+		runs[i].Stats.TotalLatency = time.Duration(m * float64(time.Millisecond))
+	}
+	return runs
+}
+
+func TestQueryLatency(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		ms   []float64
+		want QueryLatency
+	}{
+		{"one run", []float64{7.5}, QueryLatency{Runs: 1, MinMs: 7.5, P50Ms: 7.5, P90Ms: 7.5, P99Ms: 7.5, MaxMs: 7.5, MeanMs: 7.5}},
+		{"two runs", []float64{20, 10}, QueryLatency{Runs: 2, MinMs: 10, P50Ms: 10, P90Ms: 20, P99Ms: 20, MaxMs: 20, MeanMs: 15}},
+		{"four runs", []float64{4, 1, 3, 2}, QueryLatency{Runs: 4, MinMs: 1, P50Ms: 2, P90Ms: 4, P99Ms: 4, MaxMs: 4, MeanMs: 2.5}},
+		{"odd runs", []float64{5, 1, 3}, QueryLatency{Runs: 3, MinMs: 1, P50Ms: 3, P90Ms: 5, P99Ms: 5, MaxMs: 5, MeanMs: 3}},
+		{"ten runs", []float64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, QueryLatency{Runs: 10, MinMs: 1, P50Ms: 5, P90Ms: 9, P99Ms: 10, MaxMs: 10, MeanMs: 5.5}},
+	} {
+		if got := queryLatency(runsTaking(tc.ms...)); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+
+	// 100 runs of 1..100ms: nearest rank picks the 50th, 90th, and 99th
+	l := queryLatency(runsTaking(seq(100)...))
+	if l.P50Ms != 50 || l.P90Ms != 90 || l.P99Ms != 99 {
+		t.Errorf("100 runs: p50=%g p90=%g p99=%g, want 50, 90, 99", l.P50Ms, l.P90Ms, l.P99Ms)
+	}
+}
+
+// seq returns 1..n.
+func seq(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = float64(i + 1)
+	}
+	return out
+}
+
+// exportRuns are two runs, the second empty, with IDs that need quoting.
+func exportRuns() []queryRun {
+	return []queryRun{
+		{
+			Chunks: []types.Chunk{
+				{ID: "docs/refunds, returns.md", Score: 0.9, ClusterID: 0},
+				{ID: "line one\nline \"two\"", Score: 0.5, ClusterID: -1},
+			},
+			Stats: types.BrokerStats{Retrieved: 10, Clustered: 4, Returned: 2, TotalLatency: 1500 * time.Microsecond},
+		},
+		{Stats: types.BrokerStats{Retrieved: 3, Truncated: true, TotalLatency: 2 * time.Millisecond}},
+	}
+}
+
+func TestWriteQueryCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeQueryCSV(&buf, exportRuns()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading the export back: %v\n%s", err, buf.String())
+	}
+	want := [][]string{
+		{"run", "rank", "id", "score", "cluster_id", "retrieved", "clustered", "returned", "truncated", "retrieval_ms", "clustering_ms", "total_ms"},
+		{"1", "1", "docs/refunds, returns.md", "0.9", "0", "10", "4", "2", "false", "0", "0", "1.5"},
+		{"1", "2", "line one\nline \"two\"", "0.5", "-1", "10", "4", "2", "false", "0", "0", "1.5"},
+		{"2", "", "", "", "", "3", "0", "0", "true", "0", "0", "2"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows:\n got %q\nwant %q", rows, want)
+	}
+}
+
+func TestWriteQueryJSON(t *testing.T) {
+	query := "refunds, returns\nand \"exchanges\""
+	for _, tc := range []struct {
+		name    string
+		runs    []queryRun
+		latency bool
+	}{
+		{"one run", exportRuns()[:1], false},
+		{"repeated", exportRuns(), true},
+	} {
+		var buf bytes.Buffer
+		if err := writeQueryJSON(&buf, query, tc.runs); err != nil {
+			t.Fatal(err)
+		}
+		var got QueryExport
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got.Query != query || len(got.Runs) != len(tc.runs) {
+			t.Fatalf("%s: query %q with %d runs", tc.name, got.Query, len(got.Runs))
+		}
+		first := got.Runs[0]
+		wantChunks := []QueryExportChunk{{ID: "docs/refunds, returns.md", Score: 0.9}, {ID: "line one\nline \"two\"", Score: 0.5, ClusterID: -1}}
+		if first.Run != 1 || !reflect.DeepEqual(first.Chunks, wantChunks) || first.Stats.TotalMs != 1.5 {
+			t.Errorf("%s: first run = %+v", tc.name, first)
+		}
+		if (got.Latency != nil) != tc.latency {
+			t.Errorf("%s: latency = %+v", tc.name, got.Latency)
+		}
+		if tc.latency && (got.Latency.Runs != 2 || got.Latency.MaxMs != 2 || len(got.Runs[1].Chunks) != 0) {
+			t.Errorf("%s: latency = %+v, second run = %+v", tc.name, got.Latency, got.Runs[1])
+		}
+	}
+}
+
+func TestExportQueryRuns_Format(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"runs.csv", "runs.JSON"} {
+		if err := exportQueryRuns(filepath.Join(dir, name), "refunds", exportRuns()); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if err := exportQueryRuns(filepath.Join(dir, "runs.txt"), "refunds", exportRuns()); err == nil {
+		t.Error("expected an error for a .txt export")
+	}
+}
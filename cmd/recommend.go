@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+// defaultRecommendSample is how many chunks /v1/recommend samples when
+// the request does not say.
+const defaultRecommendSample = 200
+
+// RecommendResponse is the JSON response for /v1/recommend.
+type RecommendResponse struct {
+	Namespace   string          `json:"namespace"`
+	Profile     ProfileResponse `json:"profile"`
+	Recommended TuningResponse  `json:"recommended"`
+	Current     TuningResponse  `json:"current"`
+	Redundancy  float64         `json:"redundancy"`
+	Confidence  string          `json:"confidence"`
+	Notes       []string        `json:"notes,omitempty"`
+}
+
+// ProfileResponse describes the sampled chunks.
+type ProfileResponse struct {
+	SampleSize         int                 `json:"sample_size"`
+	Dimension          int                 `json:"dimension,omitempty"`
+	NearestDistance    PercentilesResponse `json:"nearest_distance"`
+	PairwiseDistance   PercentilesResponse `json:"pairwise_distance"`
+	AvgChunkTokens     float64             `json:"avg_chunk_tokens"`
+	StructuredFraction float64             `json:"structured_fraction"`
+}
+
+// PercentilesResponse holds the 10th, 50th, and 90th percentiles of a
+// distance distribution.
+type PercentilesResponse struct {
+	P10 float64 `json:"p10"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+}
+
+// TuningResponse is a set of broker settings.
+type TuningResponse struct {
+	Threshold   float64 `json:"threshold"`
+	Linkage     string  `json:"linkage"`
+	Lambda      float64 `json:"lambda"`
+	TargetK     int     `json:"target_k"`
+	OverFetchK  int     `json:"over_fetch_k"`
+	Compression string  `json:"compression,omitempty"`
+}
+
+func (s *Server) handleRecommend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	sample, dimension := defaultRecommendSample, 0
+	var seed int64
+	var err error
+	if v := q.Get("sample"); v != "" {
+		if sample, err = strconv.Atoi(v); err != nil || sample < 2 {
+			http.Error(w, "'sample' must be an integer of at least 2", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("dimension"); v != "" {
+		if dimension, err = strconv.Atoi(v); err != nil || dimension < 1 {
+			http.Error(w, "'dimension' must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("seed"); v != "" {
+		if seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "'seed' must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+	if !s.checkLimits(w, "/v1/recommend", sample) {
+		return
+	}
+
+	namespace := q.Get("namespace")
+	chunks, err := s.broker.Sample(r.Context(), namespace, sample, dimension, seed)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errs.ErrConfig) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if len(chunks) == 0 {
+		http.Error(w, fmt.Sprintf("Namespace %q returned no chunks with embeddings", namespace), http.StatusNotFound)
+		return
+	}
+
+	profile := contextlab.ProfileChunks(chunks)
+	rec := contextlab.Recommend(profile)
+	cfg := s.broker.GetConfig()
+	resp := RecommendResponse{
+		Namespace: namespace,
+		Profile: ProfileResponse{
+			SampleSize:         profile.SampleSize,
+			Dimension:          profile.Dimension,
+			NearestDistance:    PercentilesResponse(profile.NearestDistance),
+			PairwiseDistance:   PercentilesResponse(profile.PairwiseDistance),
+			AvgChunkTokens:     profile.AvgTokens,
+			StructuredFraction: profile.StructuredFraction,
+		},
+		Recommended: TuningResponse{
+			Threshold:   rec.Threshold,
+			Linkage:     rec.Linkage,
+			Lambda:      rec.Lambda,
+			TargetK:     rec.TargetK,
+			OverFetchK:  rec.OverFetchK,
+			Compression: string(rec.Compression),
+		},
+		Current: TuningResponse{
+			Threshold:  cfg.ClusterThreshold,
+			Linkage:    cfg.ClusterLinkage,
+			Lambda:     cfg.MMRLambda,
+			TargetK:    cfg.TargetK,
+			OverFetchK: cfg.OverFetchK,
+		},
+		Redundancy: rec.Redundancy,
+		Confidence: rec.Confidence,
+		Notes:      rec.Notes,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
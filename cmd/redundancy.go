@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// AnalyzeRequest is the JSON request body for /v1/analyze. With chunks
+// it analyzes them; without, it samples SampleSize chunks from
+// Namespace the way /v1/recommend does.
+type AnalyzeRequest struct {
+	// Chunks without an embedding are embedded from their text.
+	Chunks []DedupeChunk `json:"chunks,omitempty"`
+
+	Namespace  string `json:"namespace,omitempty"`
+	SampleSize int    `json:"sample_size,omitempty"`
+
+	// Dimension sizes the sampling probes when serve has no embedding
+	// provider, and Seed makes the sample repeatable.
+	Dimension int   `json:"dimension,omitempty"`
+	Seed      int64 `json:"seed,omitempty"`
+
+	// Threshold is the clustering threshold (default: the server's).
+	Threshold float64 `json:"threshold,omitempty"`
+
+	// Identity is checked against chunk ACLs when serve runs with --acl.
+	// Sampled chunks it may not see are left out.
+	Identity *IdentityRequest `json:"identity,omitempty"`
+}
+
+// AnalyzeResponse is the JSON response for /v1/analyze, and the
+// structured result of the MCP analyze_redundancy tool.
+type AnalyzeResponse struct {
+	Summary        AnalyzeSummary `json:"summary"`
+	Clusters       []ClusterInfo  `json:"clusters"`
+	Recommendation string         `json:"recommendation"`
+}
+
+// AnalyzeSummary counts the redundancy among the analyzed chunks.
+type AnalyzeSummary struct {
+	TotalChunks     int     `json:"total_chunks"`
+	ClusterCount    int     `json:"cluster_count"`
+	RedundantChunks int     `json:"redundant_chunks"`
+	RedundancyPct   float64 `json:"redundancy_pct"`
+	UniqueConcepts  int     `json:"unique_concepts"`
+	ThresholdUsed   float64 `json:"threshold_used"`
+
+	// Namespace and LatencyMs are set by /v1/analyze, Namespace when the
+	// chunks were sampled from it.
+	Namespace string `json:"namespace,omitempty"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+}
+
+// ClusterInfo describes one cluster of the analyzed chunks. Member texts
+// are cut to their first 100 bytes.
+type ClusterInfo struct {
+	ClusterID   int      `json:"cluster_id"`
+	Size        int      `json:"size"`
+	MemberIDs   []string `json:"member_ids"`
+	MemberTexts []string `json:"member_texts"`
+	IsRedundant bool     `json:"is_redundant"`
+}
+
+// analyzeRedundancy clusters chunks at threshold without selecting
+// representatives, and reports how many duplicate another. dedupeWith
+// names what the recommendation suggests for removing them.
+func analyzeRedundancy(chunks []types.Chunk, threshold float64, matrix contextlab.MatrixBudget, dedupeWith string) AnalyzeResponse {
+	clusterer := contextlab.NewClusterer(contextlab.ClusterConfig{
+		Threshold: threshold,
+		Linkage:   "average",
+		Matrix:    matrix,
+	})
+	clusterResult := clusterer.Cluster(chunks)
+
+	clusters := make([]ClusterInfo, len(clusterResult.Clusters))
+	redundant := 0
+	for i, cluster := range clusterResult.Clusters {
+		info := ClusterInfo{
+			ClusterID:   cluster.ID,
+			Size:        cluster.Size(),
+			MemberIDs:   make([]string, len(cluster.Members)),
+			MemberTexts: make([]string, len(cluster.Members)),
+			IsRedundant: cluster.Size() > 1,
+		}
+		for j, member := range cluster.Members {
+			info.MemberIDs[j] = member.ID
+			if len(member.Text) > 100 {
+				info.MemberTexts[j] = member.Text[:100] + "..."
+			} else {
+				info.MemberTexts[j] = member.Text
+			}
+		}
+		clusters[i] = info
+		if cluster.Size() > 1 {
+			redundant += cluster.Size() - 1
+		}
+	}
+
+	pct := float64(redundant) / float64(len(chunks)) * 100
+	return AnalyzeResponse{
+		Summary: AnalyzeSummary{
+			TotalChunks:     len(chunks),
+			ClusterCount:    clusterResult.ClusterCount,
+			RedundantChunks: redundant,
+			RedundancyPct:   pct,
+			UniqueConcepts:  clusterResult.ClusterCount,
+			ThresholdUsed:   threshold,
+		},
+		Clusters: clusters,
+		Recommendation: fmt.Sprintf(
+			"Found %d clusters from %d chunks. %.1f%% redundancy detected. Consider using %s to reduce to %d unique chunks.",
+			clusterResult.ClusterCount, len(chunks), pct, dedupeWith, clusterResult.ClusterCount),
+	}
+}
+
+func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limitBody(w, r, s.limits.MaxInputBytes, s.metrics, "/v1/analyze")
+	var req AnalyzeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, "JSON", err)
+		return
+	}
+	if req.SampleSize < 0 || req.Dimension < 0 {
+		http.Error(w, "'sample_size' and 'dimension' must be non-negative", http.StatusBadRequest)
+		return
+	}
+	start := time.Now()
+
+	var chunks []types.Chunk
+	if len(req.Chunks) > 0 {
+		chunks = dedupeChunksToTypes(req.Chunks)
+		if err := s.limits.Check(chunks); writeTooLarge(w, s.metrics, "/v1/analyze", err) {
+			return
+		}
+		if err := s.embedMissing(r, chunks); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errs.ErrConfig) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+	} else {
+		sample := req.SampleSize
+		if sample == 0 {
+			sample = defaultRecommendSample
+		}
+		if !s.checkLimits(w, "/v1/analyze", sample) {
+			return
+		}
+		var err error
+		chunks, err = s.broker.SampleFor(r.Context(), req.Namespace, sample, req.Dimension, req.Seed, req.Identity.identity())
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errs.ErrConfig) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if len(chunks) == 0 {
+			http.Error(w, fmt.Sprintf("Namespace %q returned no chunks with embeddings", req.Namespace), http.StatusNotFound)
+			return
+		}
+	}
+
+	cfg := s.broker.GetConfig()
+	threshold := req.Threshold
+	if threshold <= 0 {
+		threshold = cfg.ClusterThreshold
+	}
+	var resp AnalyzeResponse
+	if len(req.Chunks) > 0 {
+		resp = analyzeRedundancy(chunks, threshold, cfg.Matrix, "/v1/dedupe")
+	} else {
+		// A namespace is best deduplicated when it is written
+		resp = analyzeRedundancy(chunks, threshold, cfg.Matrix, "distill sync --dedup")
+		resp.Summary.Namespace = req.Namespace
+	}
+	resp.Summary.LatencyMs = time.Since(start).Milliseconds()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// embedMissing embeds the chunks that came without an embedding.
+func (s *Server) embedMissing(r *http.Request, chunks []types.Chunk) error {
+	var texts []string
+	var missing []int
+	for i, c := range chunks {
+		if len(c.Embedding) == 0 {
+			texts = append(texts, c.Text)
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if s.embedder == nil {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("chunks without embeddings need an embedding provider"))
+	}
+	embeddings, err := s.embedder.EmbedBatch(r.Context(), texts)
+	if err != nil {
+		return fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	for i, idx := range missing {
+		chunks[idx].Embedding = embeddings[i]
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// aclCorpus has a public chunk, a chunk only the billing group may see,
+// and a chunk without ACL metadata.
+func aclCorpus() []types.Chunk {
+	return []types.Chunk{
+		{ID: "public", Text: "Public refund policy", Embedding: []float32{1, 0}, Metadata: map[string]interface{}{"allowed_groups": "*"}},
+		{ID: "billing", Text: "Billing-only card numbers", Embedding: []float32{0.99, 0.1}, Metadata: map[string]interface{}{"allowed_groups": []string{"billing"}}},
+		{ID: "unlabeled", Text: "Unlabeled internal notes", Embedding: []float32{0, 1}},
+	}
+}
+
+func analyze(t *testing.T, s *Server, req AnalyzeRequest) (int, AnalyzeResponse) {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	rec := post(s.handleAnalyze, "/v1/analyze", "application/json", body)
+	var resp AnalyzeResponse
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec.Code, resp
+}
+
+// analyzedIDs returns the IDs of every analyzed chunk, sorted.
+func analyzedIDs(resp AnalyzeResponse) []string {
+	var ids []string
+	for _, c := range resp.Clusters {
+		ids = append(ids, c.MemberIDs...)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestHandleAnalyze_Chunks(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, resp := analyze(t, s, AnalyzeRequest{Chunks: []DedupeChunk{
+		{ID: "a", Text: "refunds", Embedding: Embedding{Values: []float32{1, 0}}},
+		{ID: "b", Text: "refunds again", Embedding: Embedding{Values: []float32{1, 0.01}}},
+		{ID: "c", Text: "shipping", Embedding: Embedding{Values: []float32{0, 1}}},
+	}})
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if resp.Summary.TotalChunks != 3 || resp.Summary.RedundantChunks != 1 || resp.Summary.Namespace != "" {
+		t.Errorf("summary = %+v, want 3 chunks with 1 redundant", resp.Summary)
+	}
+
+	if code, _ := analyze(t, s, AnalyzeRequest{SampleSize: -1}); code != http.StatusBadRequest {
+		t.Errorf("negative sample_size: status = %d, want 400", code)
+	}
+}
+
+func TestHandleAnalyze_SampleACL(t *testing.T) {
+	s := newTestServer(t, aclCorpus(), contextlab.WithACL(contextlab.ACL{Enabled: true}))
+	sample := AnalyzeRequest{Namespace: "docs", SampleSize: 10, Dimension: 2, Seed: 1}
+
+	code, resp := analyze(t, s, sample)
+	if code != http.StatusOK {
+		t.Fatalf("anonymous: status = %d", code)
+	}
+	if got := analyzedIDs(resp); !slices.Equal(got, []string{"public"}) {
+		t.Errorf("anonymous sample = %v, want only the public chunk", got)
+	}
+
+	sample.Identity = &IdentityRequest{User: "ana", Groups: []string{"billing"}}
+	code, resp = analyze(t, s, sample)
+	if code != http.StatusOK {
+		t.Fatalf("billing: status = %d", code)
+	}
+	if got := analyzedIDs(resp); !slices.Equal(got, []string{"billing", "public"}) {
+		t.Errorf("billing sample = %v, want billing and public", got)
+	}
+}
+
+func TestHandleAnalyze_SampleACLDenied(t *testing.T) {
+	chunks := aclCorpus()[1:]
+	s := newTestServer(t, chunks, contextlab.WithACL(contextlab.ACL{Enabled: true}))
+
+	code, _ := analyze(t, s, AnalyzeRequest{
+		Namespace:  "docs",
+		SampleSize: 10,
+		Dimension:  2,
+		Identity:   &IdentityRequest{User: "eve", Groups: []string{"support"}},
+	})
+	if code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 with every chunk denied", code)
+	}
+
+	// Without the ACL, the same sample sees everything
+	s = newTestServer(t, chunks)
+	code, resp := analyze(t, s, AnalyzeRequest{Namespace: "docs", SampleSize: 10, Dimension: 2})
+	if code != http.StatusOK || len(analyzedIDs(resp)) != 2 {
+		t.Errorf("no ACL: status = %d, sampled %v", code, analyzedIDs(resp))
+	}
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/config"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var reindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuild into a new namespace, verify it, and switch serving to it",
+	Long: `Ingests vectors into a fresh Pinecone namespace with the same pipeline
+as distill sync, fetches a sample back to verify it, and only then
+switches retriever.namespace in the config file. The old namespace is
+kept and recorded as retriever.previous_namespace, so
+'distill reindex rollback' switches back instantly.
+
+A distill serve started with the same config file picks up the switch
+without a restart (unless its --namespace flag pins the namespace). If
+any step fails, the config is left unchanged.
+
+Example:
+  distill reindex --config distill.yaml --file 'exports/*.jsonl'
+  distill reindex rollback --config distill.yaml
+
+Environment Variables:
+  PINECONE_API_KEY    Your Pinecone API key (required)`,
+	RunE: runReindex,
+}
+
+var reindexRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Switch serving back to the namespace before the last reindex",
+	Long: `Swaps retriever.namespace and retriever.previous_namespace in the config
+file, so serving returns to the namespace used before the last reindex.
+Running it again undoes the rollback.
+
+Example:
+  distill reindex rollback --config distill.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runReindexRollback,
+}
+
+func init() {
+	rootCmd.AddCommand(reindexCmd)
+	reindexCmd.AddCommand(reindexRollbackCmd)
+
+	addSyncFlags(reindexCmd)
+	reindexCmd.Flags().Lookup("namespace").Usage = "namespace to build (default: the serving namespace plus a timestamp)"
+	reindexCmd.Flags().Lookup("index").Usage = "Pinecone index name (default: retriever.index)"
+	reindexCmd.Flags().Bool("no-switch", false, "build and verify the namespace but leave the config unchanged")
+
+	// Verification is what makes the switch safe, so it always runs
+	_ = reindexCmd.Flags().MarkHidden("verify")
+}
+
+func runReindex(cmd *cobra.Command, args []string) error {
+	noSwitch, _ := cmd.Flags().GetBool("no-switch")
+	target, _ := cmd.Flags().GetString("namespace")
+
+	cfgPath := viper.ConfigFileUsed()
+	if cfgPath == "" && !noSwitch {
+		return errs.New(errs.ErrConfig, "reindex switches retriever.namespace in a config file: pass --config, or --no-switch")
+	}
+	if backend := viper.GetString("retriever.backend"); backend != "" && backend != "pinecone" {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("reindex supports the pinecone backend, not %q", backend))
+	}
+
+	current := viper.GetString("retriever.namespace")
+	if target == "" {
+		target = reindexNamespace(current, time.Now())
+	}
+	if target == current {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("namespace %q is already serving; pick a new one", target))
+	}
+	if !cmd.Flags().Changed("index") {
+		if index := viper.GetString("retriever.index"); index != "" {
+			_ = cmd.Flags().Set("index", index)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Reindexing into namespace %q (serving %q)\n", target, current)
+	stats, err := syncVectors(cmd, target, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Reindex failed; still serving namespace %q\n", current)
+		return err
+	}
+	if stats == nil || stats.UploadedVectors == 0 {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("no vectors were uploaded to %q; still serving namespace %q", target, current))
+	}
+
+	if noSwitch {
+		fmt.Printf("Built namespace %q. Still serving %q; set retriever.namespace to switch.\n", target, current)
+		return nil
+	}
+	if err := switchNamespace(cfgPath, target, current); err != nil {
+		return err
+	}
+	fmt.Printf("Switched serving namespace from %q to %q in %s\n", current, target, cfgPath)
+	fmt.Println("Roll back with: distill reindex rollback --config " + cfgPath)
+	return nil
+}
+
+func runReindexRollback(cmd *cobra.Command, args []string) error {
+	cfgPath := viper.ConfigFileUsed()
+	if cfgPath == "" {
+		return errs.New(errs.ErrConfig, "rollback edits retriever.namespace in a config file: pass --config")
+	}
+	if !viper.IsSet("retriever.previous_namespace") {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("%s records no previous namespace to roll back to", cfgPath))
+	}
+
+	current := viper.GetString("retriever.namespace")
+	previous := viper.GetString("retriever.previous_namespace")
+	if err := switchNamespace(cfgPath, previous, current); err != nil {
+		return err
+	}
+	fmt.Printf("Rolled back serving namespace from %q to %q in %s\n", current, previous, cfgPath)
+	return nil
+}
+
+// switchNamespace points retriever.namespace at to, recording from as the
+// previous namespace.
+func switchNamespace(cfgPath, to, from string) error {
+	err := config.SetValues(cfgPath, map[string]string{
+		"retriever.namespace":          to,
+		"retriever.previous_namespace": from,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", cfgPath, errs.Wrap(errs.ErrConfig, err))
+	}
+	return nil
+}
+
+// reindexSuffix matches the timestamp reindexNamespace appends.
+var reindexSuffix = regexp.MustCompile(`-\d{14}$`)
+
+// reindexNamespace names a new namespace after the serving one, replacing
+// any timestamp an earlier reindex added.
+func reindexNamespace(current string, now time.Time) string {
+	base := reindexSuffix.ReplaceAllString(current, "")
+	if base == "" {
+		base = "distill"
+	}
+	return base + "-" + now.UTC().Format("20060102150405")
+}
+
+// watchServingNamespace reloads the config file when it changes and
+// points ret at the new retriever.namespace, so a reindex switch or
+// rollback takes effect without a restart. onSwitch, if set, is called
+// with the new namespace.
+func watchServingNamespace(ret retriever.Retriever, current string, onSwitch func(ns string)) {
+	sw, ok := ret.(retriever.NamespaceSwitcher)
+	if !ok {
+		return
+	}
+	var mu sync.Mutex
+	viper.OnConfigChange(func(fsnotify.Event) {
+		ns := viper.GetString("retriever.namespace")
+		mu.Lock()
+		defer mu.Unlock()
+		if ns == current {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Serving namespace changed from %q to %q\n", current, ns)
+		sw.SetDefaultNamespace(ns)
+		current = ns
+		if onSwitch != nil {
+			onSwitch(ns)
+		}
+	})
+	viper.WatchConfig()
+}
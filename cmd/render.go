@@ -0,0 +1,12 @@
+package cmd
+
+import (
+	"github.com/Siddhant-K-code/distill/pkg/render"
+	"github.com/spf13/viper"
+)
+
+// newRenderer compiles the render.templates config section alongside the
+// built-in templates. defaultName is rendered when a request names none.
+func newRenderer(defaultName string) (*render.Renderer, error) {
+	return render.New(viper.GetStringMapString("render.templates"), defaultName)
+}
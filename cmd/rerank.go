@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/rerank"
+	rerankhttp "github.com/Siddhant-K-code/distill/pkg/rerank/http"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addRerankFlags adds the reranker flags, bound to the rerank section of
+// distill.yaml.
+func addRerankFlags(cmd *cobra.Command) {
+	cmd.Flags().String("rerank-url", "", "Base URL of a self-hosted reranker for rerank pipeline stages (POST {url}/rerank)")
+	cmd.Flags().String("rerank-model", "", "Model sent to the reranker, for servers hosting several")
+	_ = viper.BindPFlag("rerank.url", cmd.Flags().Lookup("rerank-url"))
+	_ = viper.BindPFlag("rerank.model", cmd.Flags().Lookup("rerank-model"))
+}
+
+// reranker builds the client configured in the rerank section, or returns
+// nil if rerank.url is unset.
+func reranker() (rerank.Reranker, error) {
+	url := viper.GetString("rerank.url")
+	if url == "" {
+		return nil, nil
+	}
+	cfg := rerankhttp.Config{
+		BaseURL:   url,
+		Model:     viper.GetString("rerank.model"),
+		APIKey:    os.ExpandEnv(viper.GetString("rerank.api_key")),
+		BatchSize: viper.GetInt("rerank.batch_size"),
+		Timeout:   viper.GetDuration("rerank.timeout"),
+	}
+	c, err := rerankhttp.NewClient(cfg)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, err)
+	}
+	return c, nil
+}
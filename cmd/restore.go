@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/Siddhant-K-code/distill/pkg/dedup"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	pc "github.com/Siddhant-K-code/distill/pkg/pinecone"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore duplicates soft-deleted by sync --tombstone",
+	Long: `Clears the tombstone on vectors that distill sync --tombstone marked as
+duplicates, so retrieval returns them again. Vectors to restore come
+from a removal manifest (sync --manifest) or an explicit list of IDs.
+
+Pinecone cannot delete metadata keys, so restored vectors keep
+distill_duplicate=false and an empty distill_duplicate_of.
+
+Example:
+  distill restore --index my-index --manifest removed.jsonl
+  distill restore --index my-index --ids doc-12,doc-40
+
+Environment Variables:
+  PINECONE_API_KEY    Your Pinecone API key (required)`,
+	RunE: runRestore,
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().String("manifest", "", "removal manifest written by sync --manifest")
+	restoreCmd.Flags().StringSlice("ids", nil, "IDs of tombstoned vectors to restore")
+
+	// Pinecone settings
+	restoreCmd.Flags().StringP("index", "i", "", "Pinecone index name (required)")
+	restoreCmd.Flags().StringP("namespace", "n", "", "Pinecone namespace (optional)")
+	restoreCmd.Flags().String("api-key", "", "Pinecone API key (or use PINECONE_API_KEY env)")
+	restoreCmd.Flags().IntP("workers", "w", 8, "concurrent metadata updates")
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	manifestPath, _ := cmd.Flags().GetString("manifest")
+	ids, _ := cmd.Flags().GetStringSlice("ids")
+	indexName, _ := cmd.Flags().GetString("index")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	workers, _ := cmd.Flags().GetInt("workers")
+
+	if apiKey == "" {
+		apiKey = viper.GetString("api_key")
+	}
+	if apiKey == "" {
+		apiKey = os.Getenv("PINECONE_API_KEY")
+	}
+	if apiKey == "" {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("pinecone API key is required: set PINECONE_API_KEY or use --api-key"))
+	}
+	if indexName == "" {
+		indexName = viper.GetString("index")
+	}
+	if indexName == "" {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("pinecone index name is required: use --index flag"))
+	}
+
+	if manifestPath != "" {
+		fromManifest, err := readRemovalManifest(manifestPath)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, fromManifest...)
+	}
+	ids = uniqueIDs(ids)
+	if len(ids) == 0 {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("nothing to restore: use --manifest or --ids"))
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		fmt.Fprintln(os.Stderr, "\nInterrupted, cleaning up...")
+		cancel()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Connecting to Pinecone index %q...\n", indexName)
+	client, err := pc.NewClient(ctx, pc.Config{
+		APIKey:    apiKey,
+		IndexName: indexName,
+		Namespace: namespace,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to Pinecone: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	fmt.Fprintf(os.Stderr, "Restoring %d vectors...\n", len(ids))
+	restored, failed := restoreTombstones(ctx, client, ids, workers)
+
+	fmt.Println()
+	fmt.Println("=== Restore Complete ===")
+	fmt.Println()
+	fmt.Printf("Vectors restored:    %d\n", restored)
+	fmt.Printf("Vectors failed:      %d\n", failed)
+	fmt.Println()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("restore interrupted: %w", err)
+	}
+	if failed > 0 {
+		failErr := fmt.Errorf("%d vectors failed to restore", failed)
+		if restored > 0 {
+			return errs.Wrap(errs.ErrPartialFailure, failErr)
+		}
+		return errs.Wrap(errs.ErrBackend, failErr)
+	}
+	return nil
+}
+
+// restoreTombstones clears the tombstone on each ID using workers
+// concurrent updates. Failures are logged and counted, not fatal.
+func restoreTombstones(ctx context.Context, client *pc.Client, ids []string, workers int) (restored, failed int64) {
+	metadata := dedup.RestoreMetadata()
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range work {
+				if err := client.UpdateMetadata(ctx, id, metadata); err != nil {
+					if ctx.Err() == nil {
+						fmt.Fprintf(os.Stderr, "  %v\n", err)
+					}
+					atomic.AddInt64(&failed, 1)
+					continue
+				}
+				atomic.AddInt64(&restored, 1)
+			}
+		}()
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- id
+	}
+	close(work)
+	wg.Wait()
+	return restored, failed
+}
+
+// readRemovalManifest returns the removed IDs in a sync --manifest file.
+func readRemovalManifest(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", errs.Wrap(errs.ErrConfig, err))
+	}
+	defer func() { _ = f.Close() }()
+
+	removed, err := dedup.ReadManifest(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", path, errs.Wrap(errs.ErrConfig, err))
+	}
+	ids := make([]string, len(removed))
+	for i, r := range removed {
+		ids[i] = r.RemovedID
+	}
+	return ids, nil
+}
+
+// uniqueIDs drops empty and repeated IDs, keeping the first occurrence.
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := ids[:0]
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
@@ -5,7 +5,10 @@ import (
 	"os"
 	"strings"
 
+	"github.com/Siddhant-K-code/distill/pkg/config"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -26,20 +29,35 @@ Features:
 
 Environment Variables:
   OPENAI_API_KEY      For text → embedding conversion
+  COHERE_API_KEY      For the cohere embedding provider
+  VOYAGE_API_KEY      For the voyage embedding provider
   PINECONE_API_KEY    For Pinecone backend
-  QDRANT_URL          For Qdrant backend`,
+  QDRANT_URL          For Qdrant backend
+  DISTILL_*           Any flag or config key (see 'distill config env')
+
+Exit Codes:
+  0    Success
+  1    Unclassified error
+  2    Configuration or input error
+  3    Authentication error
+  4    Backend error (vector DB or embedding provider)
+  5    Partial failure (some items failed)
+  130  Interrupted`,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(errs.ExitCode(err))
 	}
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return applyFlagEnv(cmd)
+	}
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.distill.yaml)")
@@ -47,11 +65,19 @@ func init() {
 
 	// Bind to viper
 	_ = viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+
+	// Flag parsing errors are usage errors.
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return errs.Wrap(errs.ErrConfig, err)
+	})
 }
 
 // initConfig reads in config file and ENV variables if set.
 // Config loading priority: CLI flags > environment variables > config file > defaults.
 func initConfig() {
+	if cfgFile == "" {
+		cfgFile = os.Getenv(config.EnvVar("config"))
+	}
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
@@ -64,10 +90,12 @@ func initConfig() {
 		viper.SetConfigName("distill")
 	}
 
-	// Read environment variables with DISTILL_ prefix
-	viper.SetEnvPrefix("DISTILL")
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	// Read environment variables with DISTILL_ prefix. Config keys are
+	// bound explicitly so env-only values also reach Unmarshal.
+	viper.SetEnvPrefix(config.EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
 	viper.AutomaticEnv()
+	config.BindEnv(viper.GetViper())
 
 	// Also check for PINECONE_API_KEY without prefix
 	_ = viper.BindEnv("pinecone_api_key", "PINECONE_API_KEY")
@@ -80,3 +108,23 @@ func initConfig() {
 		}
 	}
 }
+
+// applyFlagEnv sets every flag the user did not pass from its DISTILL_*
+// environment variable (see config.EnvVar), so each flag can be set from
+// the environment in containers. Command-line flags still win.
+func applyFlagEnv(cmd *cobra.Command) error {
+	var firstErr error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if firstErr != nil || f.Changed || f.Name == "help" || f.Name == "config" {
+			return
+		}
+		val, ok := os.LookupEnv(config.EnvVar(f.Name))
+		if !ok {
+			return
+		}
+		if err := cmd.Flags().Set(f.Name, val); err != nil {
+			firstErr = errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid %s: %w", config.EnvVar(f.Name), err))
+		}
+	})
+	return firstErr
+}
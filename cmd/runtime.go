@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/gctune"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addRuntimeFlags registers GC tuning flags on a server command.
+// The flags are read directly rather than bound to viper so that several
+// commands can share the runtime.* config keys.
+func addRuntimeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("memory-limit", "", "Soft heap limit, e.g. 1536MiB (sets GOMEMLIMIT; config: runtime.memory_limit)")
+	cmd.Flags().Int("gc-percent", 0, "GC target percentage, 0 = Go default (sets GOGC; config: runtime.gc_percent)")
+	cmd.Flags().Int("gc-ballast-mb", 0, "Heap ballast size in MiB (config: runtime.ballast_mb)")
+}
+
+// applyRuntimeTuning applies GC settings from flags, falling back to config.
+func applyRuntimeTuning(cmd *cobra.Command) error {
+	cfg := gctune.Config{
+		MemoryLimit: viper.GetString("runtime.memory_limit"),
+		GCPercent:   viper.GetInt("runtime.gc_percent"),
+		BallastMB:   viper.GetInt("runtime.ballast_mb"),
+	}
+	if cmd.Flags().Changed("memory-limit") {
+		cfg.MemoryLimit, _ = cmd.Flags().GetString("memory-limit")
+	}
+	if cmd.Flags().Changed("gc-percent") {
+		cfg.GCPercent, _ = cmd.Flags().GetInt("gc-percent")
+	}
+	if cmd.Flags().Changed("gc-ballast-mb") {
+		cfg.BallastMB, _ = cmd.Flags().GetInt("gc-ballast-mb")
+	}
+
+	if cfg == (gctune.Config{}) {
+		return nil
+	}
+
+	applied, err := gctune.Apply(cfg)
+	if err != nil {
+		return errs.Wrap(errs.ErrConfig, err)
+	}
+
+	if viper.GetBool("verbose") {
+		fmt.Fprintf(os.Stderr, "GC tuning: memory_limit=%d gc_percent=%d ballast_mb=%d\n",
+			applied.MemoryLimit, applied.GCPercent, applied.BallastMB)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Siddhant-K-code/distill/pkg/config"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/safety"
+	"github.com/Siddhant-K-code/distill/pkg/sensitivity"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addInjectionFlags adds the prompt-injection filter flags to serve.
+func addInjectionFlags(cmd *cobra.Command) {
+	cmd.Flags().String("injection-filter", string(safety.ModeOff), "Prompt-injection filter for retrieved chunks: off, flag, strip, or block")
+	cmd.Flags().Float64("injection-threshold", safety.DefaultThreshold, "Injection score (0-1) at or above which a chunk is flagged")
+
+	_ = viper.BindPFlag("safety.injection_filter", cmd.Flags().Lookup("injection-filter"))
+	_ = viper.BindPFlag("safety.injection_threshold", cmd.Flags().Lookup("injection-threshold"))
+}
+
+// injectionFilter returns the injection filter the flags ask for.
+func injectionFilter() (safety.Filter, error) {
+	mode, err := safety.ParseMode(viper.GetString("safety.injection_filter"))
+	if err != nil {
+		return safety.Filter{}, err
+	}
+	return safety.Filter{
+		Mode:      mode,
+		Threshold: viper.GetFloat64("safety.injection_threshold"),
+	}, nil
+}
+
+// sensitivityClassifier returns the classifier behind
+// --enable-classification. Examples in safety.sensitivity_examples are
+// embedded once with emb, so chunks close in meaning to one take its
+// level; they need an embedding provider.
+func sensitivityClassifier(ctx context.Context, emb retriever.EmbeddingProvider) (*sensitivity.Classifier, error) {
+	cfg := sensitivity.DefaultConfig()
+	cfg.ExampleThreshold = viper.GetFloat64("safety.sensitivity_threshold")
+	if t := cfg.ExampleThreshold; t < 0 || t > 1 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("safety.sensitivity_threshold must be between 0 and 1, got %g", t))
+	}
+
+	var examples []config.SensitivityExample
+	if err := viper.UnmarshalKey("safety.sensitivity_examples", &examples); err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("safety.sensitivity_examples: %w", err))
+	}
+	if len(examples) == 0 {
+		return sensitivity.New(cfg), nil
+	}
+	if emb == nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("safety.sensitivity_examples need an embedding provider"))
+	}
+
+	texts := make([]string, len(examples))
+	for i, ex := range examples {
+		level, err := sensitivity.ParseLevel(ex.Level)
+		if err != nil || level == sensitivity.None {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("sensitivity example %q: level must be pii, internal, or credentials, got %q", ex.Name, ex.Level))
+		}
+		if ex.Text == "" {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("sensitivity example %q has no text", ex.Name))
+		}
+		cfg.Examples = append(cfg.Examples, sensitivity.Example{Name: ex.Name, Level: level})
+		texts[i] = ex.Text
+	}
+	embeddings, err := emb.EmbedBatch(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed sensitivity examples: %w", err)
+	}
+	for i := range cfg.Examples {
+		cfg.Examples[i].Embedding = embeddings[i]
+	}
+	return sensitivity.New(cfg), nil
+}
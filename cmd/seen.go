@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/bloom"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addSeenFlags registers the flags for the filter of content already
+// ingested. Like the capture flags, they are read directly so sync,
+// reindex, and serve can share the seen.* config keys.
+func addSeenFlags(cmd *cobra.Command) {
+	cmd.Flags().String("seen-filter", "", "Skip vectors already ingested into the namespace, remembered in this Bloom filter file (config: seen.path)")
+	cmd.Flags().Int("seen-capacity", 1000000, "Vectors per namespace the filter is sized for before it grows (config: seen.capacity)")
+	cmd.Flags().Float64("seen-fp-rate", 0.001, "Share of new vectors the filter may wrongly skip as seen (config: seen.fp_rate)")
+}
+
+// addSeenSaveFlag registers the save interval of a server's filter.
+func addSeenSaveFlag(cmd *cobra.Command) {
+	cmd.Flags().Duration("seen-save-interval", time.Minute, "How often the --seen-filter file is saved, 0 = only at shutdown (config: seen.save_interval)")
+}
+
+// seenSaveInterval returns how often a server saves its filter.
+func seenSaveInterval(cmd *cobra.Command) (time.Duration, error) {
+	interval := time.Minute
+	if viper.IsSet("seen.save_interval") {
+		interval = viper.GetDuration("seen.save_interval")
+	}
+	if cmd.Flags().Changed("seen-save-interval") {
+		interval, _ = cmd.Flags().GetDuration("seen-save-interval")
+	}
+	if interval < 0 {
+		return 0, errs.Wrap(errs.ErrConfig, fmt.Errorf("--seen-save-interval must be non-negative, got %v", interval))
+	}
+	return interval, nil
+}
+
+// seenRegistry opens the filter of ingested content from flags, falling
+// back to config. It returns nil and an empty path when the filter is off.
+func seenRegistry(cmd *cobra.Command) (*bloom.Registry, string, error) {
+	path := viper.GetString("seen.path")
+	if cmd.Flags().Changed("seen-filter") {
+		path, _ = cmd.Flags().GetString("seen-filter")
+	}
+	if path == "" {
+		return nil, "", nil
+	}
+
+	capacity := 1000000
+	if viper.IsSet("seen.capacity") {
+		capacity = viper.GetInt("seen.capacity")
+	}
+	if cmd.Flags().Changed("seen-capacity") {
+		capacity, _ = cmd.Flags().GetInt("seen-capacity")
+	}
+	fpRate := 0.001
+	if viper.IsSet("seen.fp_rate") {
+		fpRate = viper.GetFloat64("seen.fp_rate")
+	}
+	if cmd.Flags().Changed("seen-fp-rate") {
+		fpRate, _ = cmd.Flags().GetFloat64("seen-fp-rate")
+	}
+
+	if capacity <= 0 {
+		return nil, "", errs.Wrap(errs.ErrConfig, fmt.Errorf("--seen-capacity must be positive, got %d", capacity))
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, "", errs.Wrap(errs.ErrConfig, fmt.Errorf("--seen-fp-rate must be between 0 and 1, got %g", fpRate))
+	}
+	registry, err := bloom.Open(path, capacity, fpRate)
+	if err != nil {
+		return nil, "", errs.Wrap(errs.ErrConfig, fmt.Errorf("opening seen filter: %w", err))
+	}
+	return registry, path, nil
+}
+
+// saveSeen saves the filter to path every interval until ctx is done. It
+// does nothing without a filter or with a zero interval; serve saves once
+// more at shutdown either way.
+func saveSeen(ctx context.Context, r *bloom.Registry, path string, interval time.Duration) {
+	if r == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			flushSeen(r, path)
+		}
+	}
+}
+
+// flushSeen saves the filter to path if it changed. Failures are
+// reported but never fail the run: the next save retries, and a filter
+// missing recent writes only lets their duplicates through as before.
+func flushSeen(r *bloom.Registry, path string) {
+	if err := r.Save(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save seen filter: %v\n", err)
+	}
+}
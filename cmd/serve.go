@@ -1,28 +1,52 @@
 package cmd
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/analytics"
+	"github.com/Siddhant-K-code/distill/pkg/bloom"
+	distillcache "github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/capture"
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/config"
 	"github.com/Siddhant-K-code/distill/pkg/contextlab"
-	"github.com/Siddhant-K-code/distill/pkg/embedding"
 	_ "github.com/Siddhant-K-code/distill/pkg/embedding/cohere"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/fake"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/local"
 	_ "github.com/Siddhant-K-code/distill/pkg/embedding/ollama"
 	_ "github.com/Siddhant-K-code/distill/pkg/embedding/openai"
+	_ "github.com/Siddhant-K-code/distill/pkg/embedding/voyage"
+	"github.com/Siddhant-K-code/distill/pkg/enrich"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/grpcapi/distillv1"
+	"github.com/Siddhant-K-code/distill/pkg/history"
 	"github.com/Siddhant-K-code/distill/pkg/metrics"
+	"github.com/Siddhant-K-code/distill/pkg/models"
+	"github.com/Siddhant-K-code/distill/pkg/nsstats"
+	"github.com/Siddhant-K-code/distill/pkg/render"
 	"github.com/Siddhant-K-code/distill/pkg/retriever"
-	"github.com/Siddhant-K-code/distill/pkg/telemetry"
+	fakeretriever "github.com/Siddhant-K-code/distill/pkg/retriever/fake"
 	pcretriever "github.com/Siddhant-K-code/distill/pkg/retriever/pinecone"
 	qdretriever "github.com/Siddhant-K-code/distill/pkg/retriever/qdrant"
+	"github.com/Siddhant-K-code/distill/pkg/supervise"
+	"github.com/Siddhant-K-code/distill/pkg/telemetry"
+	"github.com/Siddhant-K-code/distill/pkg/tuner"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 )
 
 var serveCmd = &cobra.Command{
@@ -36,8 +60,24 @@ Example:
 
 The server exposes:
   POST /v1/retrieve  - Deduplicated retrieval endpoint
+  POST /v1/similar   - Deduplicated neighbors of stored items
+  GET  /v1/recommend - Suggested settings from a namespace sample
+  POST /v1/analyze   - Redundancy report for chunks or a namespace sample
+  POST /v1/feedback  - Report how useful a response was (--online-tuning)
+  GET  /v1/tuner     - Online tuner state; POST {"enabled": false} stops it
+  PUT  /v1/vectors   - Validate, deduplicate, and upsert vectors (--allow-writes)
   GET  /health       - Health check
-  GET  /metrics      - Basic metrics`,
+  GET  /health/ready - Readiness: 503 while the backend is unreachable
+  GET  /metrics      - Basic metrics
+
+With --grpc-port the server also serves the gRPC API in
+proto/distill/v1/distill.proto: Deduplicate, Retrieve, and
+AnalyzeRedundancy, with client-streaming variants for large chunk sets.
+
+Under systemd (Type=notify) the server reports readiness once it is
+listening, pings the watchdog when WatchdogSec= is set, and reports
+STOPPING during graceful shutdown. On Windows it can run directly as a
+service registered with the Service Control Manager.`,
 	RunE: runServe,
 }
 
@@ -49,24 +89,72 @@ func init() {
 	serveCmd.Flags().String("host", "0.0.0.0", "HTTP server host")
 
 	// Backend settings
-	serveCmd.Flags().String("backend", "pinecone", "Vector DB backend (pinecone, qdrant)")
+	serveCmd.Flags().String("backend", "pinecone", "Vector DB backend (pinecone, qdrant, fake)")
 	serveCmd.Flags().StringP("index", "i", "", "Index/collection name")
 	serveCmd.Flags().String("api-key", "", "Vector DB API key (or use PINECONE_API_KEY)")
 	serveCmd.Flags().String("db-host", "", "Vector DB host (for Qdrant)")
 	serveCmd.Flags().StringP("namespace", "n", "", "Default namespace")
+	serveCmd.Flags().Bool("watch-config", true, "Switch the default namespace when retriever.namespace changes in the config file (see distill reindex)")
 
 	// Embedding settings
-	serveCmd.Flags().String("openai-key", "", "API key for embeddings (or use OPENAI_API_KEY / COHERE_API_KEY)")
-	serveCmd.Flags().String("embedding-provider", "openai", "Embedding provider (openai, ollama, cohere)")
-	serveCmd.Flags().String("embedding-model", "text-embedding-3-small", "Embedding model name")
+	serveCmd.Flags().String("openai-key", "", "API key for embeddings (or use OPENAI_API_KEY / COHERE_API_KEY / VOYAGE_API_KEY)")
+	serveCmd.Flags().String("embedding-provider", "openai", "Embedding provider (openai, ollama, cohere, voyage, local, fake)")
+	serveCmd.Flags().String("embedding-model", "text-embedding-3-small", "Embedding model name (local: model directory)")
 	serveCmd.Flags().String("embedding-base-url", "", "Embedding provider base URL (e.g. http://localhost:11434 for Ollama)")
 
 	// ContextLab settings
 	serveCmd.Flags().Int("over-fetch-k", 50, "Number of chunks to over-fetch")
 	serveCmd.Flags().Int("target-k", 8, "Target number of chunks to return")
+	serveCmd.Flags().Int("token-budget", 0, "Return chunks until their tokens would exceed this, instead of --target-k (0 = off)")
+	serveCmd.Flags().Int("min-candidates", 4, "Skip clustering and MMR when fewer candidates than this remain, and report the result sparse (0 = off)")
+	serveCmd.Flags().Float64("min-score", 0, "Drop matches scoring below this (0 = off)")
+	serveCmd.Flags().Bool("include-tombstoned", false, "Return duplicates soft-deleted by sync --tombstone")
+	serveCmd.Flags().StringSlice("include-metadata-fields", nil, "Keep only these metadata fields of each match")
+	serveCmd.Flags().StringSlice("exclude-metadata-fields", nil, "Drop these metadata fields from each match")
+	serveCmd.Flags().StringSlice("cross-namespace-groups", nil, "Only identities in these groups may search several namespaces in one request")
+	serveCmd.Flags().Int("warm-sample", 50, "Chunks to sample from each namespace at startup to reject mismatched queries (0 = off)")
+	serveCmd.Flags().StringSlice("warm-namespaces", nil, "Namespaces to sample at startup (default: the default namespace; \"*\" = all)")
 	serveCmd.Flags().Float64("threshold", 0.15, "Clustering threshold")
 	serveCmd.Flags().Float64("lambda", 0.5, "MMR lambda (relevance vs diversity)")
 	serveCmd.Flags().Bool("enable-mmr", true, "Enable MMR re-ranking")
+	serveCmd.Flags().Float64("recency-weight", 0, "Weight of recency in MMR relevance, 0-1 (0 = off)")
+	serveCmd.Flags().Duration("recency-half-life", contextlab.DefaultRecencyHalfLife, "Age at which a chunk's recency halves")
+	serveCmd.Flags().String("timestamp-field", contextlab.DefaultTimestampField, "Metadata field holding chunk timestamps")
+	serveCmd.Flags().Int64("mmr-seed", 0, "Order of chunks with tied MMR scores (0 = retrieval order)")
+	serveCmd.Flags().Bool("dedup-hints", false, "Add cluster size, duplicates removed, and representative reason to every chunk's metadata")
+	addEntityFlags(serveCmd)
+	addStageFlags(serveCmd)
+	serveCmd.Flags().String("template", "", "Template rendered into every response: plain, numbered, markdown, xml, or a render.templates name")
+
+	// Session filtering
+	serveCmd.Flags().Duration("sent-ttl", distillcache.DefaultSentTTL, "How long a chunk counts as already sent within a session")
+
+	// Runtime tuning, input limits, capture, and HTTP transport
+	addRuntimeFlags(serveCmd)
+	addProfilingFlags(serveCmd)
+	addLimitFlags(serveCmd)
+	addCaptureFlags(serveCmd)
+	addDebugKeyFlag(serveCmd)
+	addHistoryFlags(serveCmd)
+	addNamespaceStatsFlags(serveCmd)
+	addHTTPFlags(serveCmd)
+	addListenFlags(serveCmd)
+	addEmbeddingOutputFlags(serveCmd)
+	addTuningFlags(serveCmd)
+	addACLFlags(serveCmd)
+	addInjectionFlags(serveCmd)
+	addGarbageFlags(serveCmd)
+	addModelFlags(serveCmd)
+	addRerankFlags(serveCmd)
+	addCacheFlags(serveCmd)
+	addWriteFlags(serveCmd)
+	addSeenFlags(serveCmd)
+	addSeenSaveFlag(serveCmd)
+	addGRPCFlags(serveCmd)
+	serveCmd.Flags().Bool("history-queries", false, "Also record each request's query text, for distill cache warm --from-history")
+
+	// Supervisor settings
+	serveCmd.Flags().String("service-name", "distill", "Windows service name (when run under the Service Control Manager)")
 
 	// Bind to viper for config file support
 	_ = viper.BindPFlag("server.port", serveCmd.Flags().Lookup("port"))
@@ -79,17 +167,57 @@ func init() {
 	_ = viper.BindPFlag("embedding.base_url", serveCmd.Flags().Lookup("embedding-base-url"))
 	_ = viper.BindPFlag("retriever.top_k", serveCmd.Flags().Lookup("over-fetch-k"))
 	_ = viper.BindPFlag("retriever.target_k", serveCmd.Flags().Lookup("target-k"))
+	_ = viper.BindPFlag("retriever.token_budget", serveCmd.Flags().Lookup("token-budget"))
+	_ = viper.BindPFlag("retriever.min_candidates", serveCmd.Flags().Lookup("min-candidates"))
+	_ = viper.BindPFlag("retriever.min_score", serveCmd.Flags().Lookup("min-score"))
+	_ = viper.BindPFlag("retriever.include_tombstoned", serveCmd.Flags().Lookup("include-tombstoned"))
+	_ = viper.BindPFlag("retriever.include_metadata_fields", serveCmd.Flags().Lookup("include-metadata-fields"))
+	_ = viper.BindPFlag("retriever.exclude_metadata_fields", serveCmd.Flags().Lookup("exclude-metadata-fields"))
+	_ = viper.BindPFlag("retriever.cross_namespace_groups", serveCmd.Flags().Lookup("cross-namespace-groups"))
+	_ = viper.BindPFlag("retriever.warm_scan.sample", serveCmd.Flags().Lookup("warm-sample"))
+	_ = viper.BindPFlag("retriever.warm_scan.namespaces", serveCmd.Flags().Lookup("warm-namespaces"))
 	_ = viper.BindPFlag("dedup.threshold", serveCmd.Flags().Lookup("threshold"))
 	_ = viper.BindPFlag("dedup.lambda", serveCmd.Flags().Lookup("lambda"))
+	_ = viper.BindPFlag("dedup.recency_weight", serveCmd.Flags().Lookup("recency-weight"))
+	_ = viper.BindPFlag("dedup.recency_half_life", serveCmd.Flags().Lookup("recency-half-life"))
+	_ = viper.BindPFlag("dedup.timestamp_field", serveCmd.Flags().Lookup("timestamp-field"))
+	_ = viper.BindPFlag("dedup.mmr_seed", serveCmd.Flags().Lookup("mmr-seed"))
+	_ = viper.BindPFlag("dedup.hints", serveCmd.Flags().Lookup("dedup-hints"))
+	_ = viper.BindPFlag("render.default", serveCmd.Flags().Lookup("template"))
 	_ = viper.BindPFlag("dedup.enable_mmr", serveCmd.Flags().Lookup("enable-mmr"))
+	_ = viper.BindPFlag("history.record_queries", serveCmd.Flags().Lookup("history-queries"))
 }
 
 // Server holds the HTTP server state.
 type Server struct {
-	broker  *contextlab.Broker
-	cfg     ServerConfig
-	metrics *metrics.Metrics
-	tracing *telemetry.Provider
+	broker   *contextlab.Broker
+	cfg      ServerConfig
+	metrics  *metrics.Metrics
+	tracing  *telemetry.Provider
+	limits   contextlab.Limits
+	captures *capture.Recorder
+	history  *history.Writer
+
+	// debugKeys guard /debug/captures; empty leaves it open.
+	debugKeys map[string]bool
+
+	// namespaces aggregates served requests for /v1/stats/namespaces.
+	namespaces *nsstats.Tracker
+	queries    bool
+	analytics  *analytics.Exporter
+	caches     *queryCaches
+	embedOut   embeddingOutput
+	renderer   *render.Renderer
+	models     *models.Registry
+	embedder   retriever.EmbeddingProvider
+	tuner      *tuner.Tuner
+	backend    retriever.ConnectionReporter
+	writer     retriever.Upserter
+
+	// seen remembers the content written through /v1/vectors per
+	// namespace, saved to seenPath.
+	seen     *bloom.Registry
+	seenPath string
 }
 
 // ServerConfig holds server configuration.
@@ -101,42 +229,387 @@ type ServerConfig struct {
 // RetrieveRequest is the JSON request body for /v1/retrieve.
 type RetrieveRequest struct {
 	Query          string                 `json:"query,omitempty"`
-	QueryEmbedding []float32              `json:"query_embedding,omitempty"`
+	QueryEmbedding Embedding              `json:"query_embedding,omitzero"`
 	Index          string                 `json:"index,omitempty"`
 	Namespace      string                 `json:"namespace,omitempty"`
 	OverFetchK     int                    `json:"over_fetch_k,omitempty"`
 	TargetK        int                    `json:"target_k,omitempty"`
 	Threshold      float64                `json:"threshold,omitempty"`
 	Lambda         float64                `json:"lambda,omitempty"`
+	MinScore       float32                `json:"min_score,omitempty"`
 	Filter         map[string]interface{} `json:"filter,omitempty"`
+
+	// Namespaces searches several namespaces concurrently instead of
+	// Namespace, returning at most each one's top_k after deduplicating
+	// across all of them.
+	Namespaces []NamespaceRequest `json:"namespaces,omitempty"`
+
+	// Queries and QueryEmbeddings add query vectors for a multi-vector
+	// query. Combine is "average" (default) or "fanout".
+	Queries         []string    `json:"queries,omitempty"`
+	QueryEmbeddings []Embedding `json:"query_embeddings,omitempty"`
+	Combine         string      `json:"combine,omitempty"`
+
+	// Encoding is how query_embedding and query_embeddings are encoded:
+	// float32 (the default), float64, or base64 for little-endian float32
+	// bytes. An embedding of another form is rejected.
+	Encoding string `json:"encoding,omitempty"`
+
+	// SessionID enables cross-request dedup: chunks already returned to
+	// this session within the sent TTL are excluded, or marked with
+	// already_sent when MarkRepeats is set.
+	SessionID   string `json:"session_id,omitempty"`
+	MarkRepeats bool   `json:"mark_repeats,omitempty"`
+
+	// Exclude lists chunk IDs or content hashes (SHA-256 of the trimmed
+	// text) to drop from results before clustering.
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Identity is checked against chunk ACLs when serve runs with --acl.
+	Identity *IdentityRequest `json:"identity,omitempty"`
+
+	// DedupHints adds dedup_cluster_size, dedup_duplicates_removed, and
+	// dedup_representative_reason to each chunk's metadata.
+	DedupHints bool `json:"dedup_hints,omitempty"`
+
+	// Explain lists the transformations applied to each chunk, such as
+	// selected-from-cluster-3 or compressed-extractive-42%, under
+	// explain_transforms in its metadata.
+	Explain bool `json:"explain,omitempty"`
+
+	// Template renders the result into RetrieveResponse.Rendered. Empty
+	// uses the server's default template, if any.
+	Template string `json:"template,omitempty"`
+
+	// ValidateEmbeddings checks supplied query embeddings for a wrong
+	// dimension or values unlike the rest: "reject" fails the request,
+	// "repair" re-embeds query_embedding from query and drops suspect
+	// query_embeddings entries while another query remains.
+	ValidateEmbeddings string `json:"validate_embeddings,omitempty"`
+
+	// Enable and Selection override the server's stage switches and
+	// selection strategy for this request.
+	Enable    *StageTogglesRequest `json:"enable,omitempty"`
+	Selection string               `json:"selection,omitempty"`
+
+	// Model names a models.profiles entry whose token budget the result
+	// must fit. Empty uses the server's default profile, if any.
+	Model string `json:"model,omitempty"`
+
+	// Compress overrides the server's compression settings and turns
+	// compression on, unless enable.compression turns it off.
+	Compress *CompressRequest `json:"compress,omitempty"`
+
+	// Mode is "dedupe" (default) or "passthrough", which skips
+	// deduplication and returns the top chunks by retriever score, each
+	// with the cluster_id deduplication would have assigned it.
+	Mode string `json:"mode,omitempty"`
+
+	// Redact replaces secrets and PII with typed placeholders, such as
+	// [EMAIL], listing each span's offsets under the chunk's redactions
+	// metadata. It turns redaction on unless enable.redaction turns it off.
+	Redact bool `json:"redact,omitempty"`
+
+	// DeadlineMs is the request's time budget. Within it the pipeline
+	// skips or cuts short optional stages rather than answer late, and
+	// reports best_effort with notes when it did.
+	DeadlineMs int `json:"deadline_ms,omitempty"`
+
+	EmbeddingOptions
+}
+
+// NamespaceRequest is one namespace of a multi-namespace retrieve. A zero
+// TopK gets an even share of target_k.
+type NamespaceRequest struct {
+	Name string `json:"name"`
+	TopK int    `json:"top_k,omitempty"`
+}
+
+// namespaceQuotas converts r.
+func namespaceQuotas(r []NamespaceRequest) []types.NamespaceQuota {
+	if len(r) == 0 {
+		return nil
+	}
+	quotas := make([]types.NamespaceQuota, len(r))
+	for i, ns := range r {
+		quotas[i] = types.NamespaceQuota{Name: ns.Name, TopK: ns.TopK}
+	}
+	return quotas
+}
+
+// StageTogglesRequest turns optional stages on or off for one request;
+// omitted stages keep the server's setting.
+type StageTogglesRequest struct {
+	Clustering  *bool `json:"clustering,omitempty"`
+	MMR         *bool `json:"mmr,omitempty"`
+	Compression *bool `json:"compression,omitempty"`
+	Redaction   *bool `json:"redaction,omitempty"`
+	Scoring     *bool `json:"scoring,omitempty"`
+	Rerank      *bool `json:"rerank,omitempty"`
+
+	Classification *bool `json:"classification,omitempty"`
+}
+
+// toggles converts r, which may be nil, for a types.RetrievalRequest.
+func (r *StageTogglesRequest) toggles() types.StageToggles {
+	if r == nil {
+		return types.StageToggles{}
+	}
+	return types.StageToggles{
+		Clustering:  r.Clustering,
+		MMR:         r.MMR,
+		Compression: r.Compression,
+		Redaction:   r.Redaction,
+		Scoring:     r.Scoring,
+		Rerank:      r.Rerank,
+
+		Classification: r.Classification,
+	}
 }
 
-// RetrieveResponse is the JSON response for /v1/retrieve.
+// CompressRequest sets how one request's chunks are compressed; omitted
+// fields keep the server's setting.
+type CompressRequest struct {
+	// Mode is extractive, placeholder, hybrid, or cluster-merge.
+	Mode string `json:"mode,omitempty"`
+
+	// TargetReduction is the share of each chunk's tokens to keep, in
+	// (0, 1].
+	TargetReduction float64 `json:"target_reduction,omitempty"`
+
+	PreserveStructure *bool `json:"preserve_structure,omitempty"`
+
+	// MergeTokens caps each chunk cluster-merge builds; 0 keeps the
+	// representative's own length.
+	MergeTokens int `json:"merge_tokens,omitempty"`
+}
+
+// options converts r, which may be nil, for a types.RetrievalRequest.
+func (r *CompressRequest) options() *types.CompressOptions {
+	if r == nil {
+		return nil
+	}
+	return &types.CompressOptions{
+		Mode:              r.Mode,
+		TargetReduction:   r.TargetReduction,
+		PreserveStructure: r.PreserveStructure,
+		MergeTokens:       r.MergeTokens,
+	}
+}
+
+// compressor returns the compressor and options r selects, for callers
+// without a broker. An empty mode is extractive, the broker's default.
+func (r *CompressRequest) compressor() (compress.Compressor, compress.Options, error) {
+	mode, err := compress.ParseMode(cmp.Or(r.Mode, string(compress.ModeExtractive)))
+	if err != nil {
+		return nil, compress.Options{}, err
+	}
+	if r.TargetReduction < 0 || r.TargetReduction > 1 {
+		return nil, compress.Options{}, fmt.Errorf("target_reduction must be in (0, 1], got %g", r.TargetReduction)
+	}
+	if r.MergeTokens < 0 {
+		return nil, compress.Options{}, fmt.Errorf("merge_tokens must be non-negative, got %d", r.MergeTokens)
+	}
+	c, _ := compress.ForMode(mode)
+	opts := compress.DefaultOptions()
+	opts.Mode = mode
+	if r.TargetReduction > 0 {
+		opts.TargetReduction = r.TargetReduction
+	}
+	if r.PreserveStructure != nil {
+		opts.PreserveStructure = *r.PreserveStructure
+	}
+	opts.MergeTokens = r.MergeTokens
+	return c, opts, nil
+}
+
+// CompressionStats reports what compression did to the returned chunks.
+type CompressionStats struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	SavedTokens  int     `json:"saved_tokens"`
+	ReductionPct float64 `json:"reduction_pct"`
+	Compressed   int     `json:"chunks_compressed"`
+
+	// Merged counts the chunks cluster-merge rebuilt from their cluster.
+	Merged int `json:"chunks_merged,omitempty"`
+}
+
+// compressionStats returns the stats for compression from in to out
+// tokens changing n chunks, or nil if compression did not run.
+func compressionStats(in, out, n int) *CompressionStats {
+	if in == 0 {
+		return nil
+	}
+	return &CompressionStats{
+		InputTokens:  in,
+		OutputTokens: out,
+		SavedTokens:  in - out,
+		ReductionPct: math.Round(1000*float64(in-out)/float64(in)) / 10,
+		Compressed:   n,
+	}
+}
+
+// mergedStats sets the chunks cluster-merge rebuilt on s, which may be
+// nil, and returns it.
+func mergedStats(s *CompressionStats, merged int) *CompressionStats {
+	if s != nil {
+		s.Merged = merged
+	}
+	return s
+}
+
+// SimilarRequest is the JSON request body for /v1/similar. The response
+// is a RetrieveResponse.
+type SimilarRequest struct {
+	// IDs are stored items whose neighbors are wanted. The items
+	// themselves are never returned.
+	IDs        []string `json:"ids"`
+	Namespace  string   `json:"namespace,omitempty"`
+	OverFetchK int      `json:"over_fetch_k,omitempty"`
+	TargetK    int      `json:"target_k,omitempty"`
+	Threshold  float64  `json:"threshold,omitempty"`
+	Lambda     float64  `json:"lambda,omitempty"`
+	MinScore   float32  `json:"min_score,omitempty"`
+
+	SessionID   string   `json:"session_id,omitempty"`
+	MarkRepeats bool     `json:"mark_repeats,omitempty"`
+	Exclude     []string `json:"exclude,omitempty"`
+	Template    string   `json:"template,omitempty"`
+
+	Identity   *IdentityRequest `json:"identity,omitempty"`
+	DedupHints bool             `json:"dedup_hints,omitempty"`
+	Explain    bool             `json:"explain,omitempty"`
+
+	Enable    *StageTogglesRequest `json:"enable,omitempty"`
+	Selection string               `json:"selection,omitempty"`
+	Model     string               `json:"model,omitempty"`
+	Compress  *CompressRequest     `json:"compress,omitempty"`
+
+	DeadlineMs int `json:"deadline_ms,omitempty"`
+
+	EmbeddingOptions
+}
+
+// RetrieveResponse is the JSON response for /v1/retrieve and /v1/similar.
 type RetrieveResponse struct {
 	Chunks []ChunkResponse `json:"chunks"`
 	Stats  StatsResponse   `json:"stats"`
+
+	// Rendered is the result rendered with the requested or default
+	// template.
+	Rendered string `json:"rendered,omitempty"`
+
+	// FeedbackID identifies the request in /v1/feedback when online
+	// tuning chose its threshold and lambda.
+	FeedbackID string `json:"feedback_id,omitempty"`
+
+	// Stages lists the pipeline stages that ran, in order.
+	Stages []string `json:"stages,omitempty"`
 }
 
 // ChunkResponse represents a chunk in the response.
 type ChunkResponse struct {
-	ID        string                 `json:"id"`
-	Text      string                 `json:"text,omitempty"`
-	Score     float32                `json:"score"`
-	ClusterID int                    `json:"cluster_id"`
-	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	ID          string                 `json:"id"`
+	Text        string                 `json:"text,omitempty"`
+	Score       float32                `json:"score"`
+	ClusterID   int                    `json:"cluster_id"`
+	AlreadySent bool                   `json:"already_sent,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Embedding   []float32              `json:"embedding,omitempty"`
 }
 
 // StatsResponse contains processing statistics.
 type StatsResponse struct {
-	Retrieved           int   `json:"retrieved"`
-	Clustered           int   `json:"clustered"`
-	Returned            int   `json:"returned"`
-	RetrievalLatencyMs  int64 `json:"retrieval_latency_ms"`
-	ClusteringLatencyMs int64 `json:"clustering_latency_ms"`
-	TotalLatencyMs      int64 `json:"total_latency_ms"`
+	Retrieved           int    `json:"retrieved"`
+	Clustered           int    `json:"clustered"`
+	Returned            int    `json:"returned"`
+	Repeated            int    `json:"repeated,omitempty"`
+	Excluded            int    `json:"excluded,omitempty"`
+	Vetoed              int    `json:"vetoed,omitempty"`
+	MatrixOverflow      string `json:"matrix_overflow,omitempty"`
+	Truncated           bool   `json:"truncated,omitempty"`
+	RetrievalRetries    int    `json:"retrieval_retries,omitempty"`
+	RetrievalLatencyMs  int64  `json:"retrieval_latency_ms"`
+	ClusteringLatencyMs int64  `json:"clustering_latency_ms"`
+	TotalLatencyMs      int64  `json:"total_latency_ms"`
+
+	// Enriched and Denied count chunks an enrichment hook added metadata
+	// to or dropped; EnrichmentFailed counts failed hook calls.
+	Enriched            int   `json:"enriched,omitempty"`
+	Denied              int   `json:"denied,omitempty"`
+	EnrichmentFailed    int   `json:"enrichment_failed,omitempty"`
+	EnrichmentLatencyMs int64 `json:"enrichment_latency_ms,omitempty"`
+
+	// ACLDenied counts chunks the request identity may not see, including
+	// ACLUnlabeled chunks that had no ACL metadata.
+	ACLDenied    int `json:"acl_denied,omitempty"`
+	ACLUnlabeled int `json:"acl_unlabeled,omitempty"`
+
+	// InjectionFlagged counts chunks scored as prompt injection,
+	// InjectionStripped the sentences removed from them, and
+	// InjectionBlocked the chunks dropped.
+	InjectionFlagged  int `json:"injection_flagged,omitempty"`
+	InjectionStripped int `json:"injection_stripped,omitempty"`
+	InjectionBlocked  int `json:"injection_blocked,omitempty"`
+
+	// GarbageDropped counts chunks dropped as boilerplate, and
+	// GarbageAllowed those kept for matching an allow pattern.
+	GarbageDropped int `json:"garbage_dropped,omitempty"`
+	GarbageAllowed int `json:"garbage_allowed,omitempty"`
+
+	// Model is the profile the result was fitted to, Tokens and CostUSD
+	// the returned chunks' tokens and input price under it, and
+	// BudgetDropped the chunks that did not fit its budget.
+	Model         string  `json:"model,omitempty"`
+	Tokens        int     `json:"tokens,omitempty"`
+	CostUSD       float64 `json:"cost_usd,omitempty"`
+	BudgetDropped int     `json:"budget_dropped,omitempty"`
+
+	// Redacted counts sensitive spans replaced by a redact stage.
+	Redacted int `json:"redacted,omitempty"`
+
+	// Compression is set when the returned chunks were compressed.
+	Compression *CompressionStats `json:"compression,omitempty"`
+
+	// Reranked counts chunks scored by a rerank stage; RerankFailed is
+	// set when the reranker failed and chunks kept their order.
+	Reranked        int   `json:"reranked,omitempty"`
+	RerankFailed    bool  `json:"rerank_failed,omitempty"`
+	RerankLatencyMs int64 `json:"rerank_latency_ms,omitempty"`
+
+	// CacheHit is set when the result came from the result cache, and
+	// CacheMiss when the cache was consulted but had no result.
+	CacheHit  bool `json:"cache_hit,omitempty"`
+	CacheMiss bool `json:"cache_miss,omitempty"`
+
+	// BestEffort is set when deadline_ms cut the pipeline short; Notes
+	// says which stages were skipped or reduced.
+	BestEffort bool     `json:"best_effort,omitempty"`
+	Notes      []string `json:"notes,omitempty"`
+
+	// Sparse is set when too few candidates were left to deduplicate;
+	// clustering and MMR were skipped, and Notes has a hint.
+	Sparse bool `json:"sparse,omitempty"`
+
+	// Passthrough is set when mode=passthrough skipped deduplication.
+	Passthrough bool `json:"passthrough,omitempty"`
+
+	// Sensitivity is the highest level among the returned chunks when
+	// classification ran: none, pii, internal, or credentials.
+	Sensitivity string `json:"sensitivity,omitempty"`
+
+	// EmbeddingsRepaired and EmbeddingsDropped count query embeddings
+	// re-embedded or dropped by validate_embeddings.
+	EmbeddingsRepaired int `json:"embeddings_repaired,omitempty"`
+	EmbeddingsDropped  int `json:"embeddings_dropped,omitempty"`
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
+	if err := applyRuntimeTuning(cmd); err != nil {
+		return err
+	}
+
 	// Config file values are used as fallbacks via viper bindings
 	port := viper.GetInt("server.port")
 	host := viper.GetString("server.host")
@@ -152,17 +625,19 @@ func runServe(cmd *cobra.Command, args []string) error {
 	embeddingModel := viper.GetString("embedding.model")
 	overFetchK := viper.GetInt("retriever.top_k")
 	targetK := viper.GetInt("retriever.target_k")
+	minScore := viper.GetFloat64("retriever.min_score")
+	includeTombstoned := viper.GetBool("retriever.include_tombstoned")
 	threshold := viper.GetFloat64("dedup.threshold")
 	lambda := viper.GetFloat64("dedup.lambda")
 	enableMMR := viper.GetBool("dedup.enable_mmr")
+	recencyWeight := viper.GetFloat64("dedup.recency_weight")
+	recencyHalfLife := viper.GetDuration("dedup.recency_half_life")
+	timestampField := viper.GetString("dedup.timestamp_field")
 
 	// Resolve API keys from environment
 	if apiKey == "" {
 		apiKey = os.Getenv("PINECONE_API_KEY")
 	}
-	if openaiKey == "" {
-		openaiKey = os.Getenv("OPENAI_API_KEY")
-	}
 
 	ctx := context.Background()
 
@@ -173,37 +648,56 @@ func runServe(cmd *cobra.Command, args []string) error {
 	switch backend {
 	case "pinecone":
 		if apiKey == "" {
-			return fmt.Errorf("pinecone API key required (--api-key or PINECONE_API_KEY)")
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("pinecone API key required (--api-key or PINECONE_API_KEY)"))
 		}
-		if index == "" {
-			return fmt.Errorf("index name required (--index)")
+		var params pcretriever.Params
+		if params, err = pineconeParams(); err != nil {
+			return err
+		}
+		if index == "" && params.IndexHost == "" {
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("index name required (--index or retriever.params.pinecone.index_host)"))
 		}
 		ret, err = pcretriever.NewClient(ctx, pcretriever.Config{
 			Config: retriever.Config{
 				APIKey:           apiKey,
 				DefaultNamespace: namespace,
+				MetadataFields:   metadataFields(),
+				TimeoutSeconds:   retrieverTimeout(),
+				MaxRetries:       retrieverMaxRetries(),
 			},
 			IndexName: index,
+			Params:    params,
 		})
 
 	case "qdrant":
 		if dbHost == "" {
-			return fmt.Errorf("qdrant host required (--db-host)")
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("qdrant host required (--db-host)"))
 		}
 		if index == "" {
-			return fmt.Errorf("collection name required (--index)")
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("collection name required (--index)"))
+		}
+		var params qdretriever.Params
+		if params, err = qdrantParams(); err != nil {
+			return err
 		}
 		ret, err = qdretriever.NewClient(ctx, qdretriever.Config{
 			Config: retriever.Config{
 				APIKey:           apiKey,
 				Host:             dbHost,
 				DefaultNamespace: namespace,
+				MetadataFields:   metadataFields(),
+				TimeoutSeconds:   retrieverTimeout(),
+				MaxRetries:       retrieverMaxRetries(),
 			},
 			Collection: index,
+			Params:     params,
 		})
 
+	case fakeBackend:
+		ret, err = newFakeRetriever()
+
 	default:
-		return fmt.Errorf("unsupported backend: %s (use 'pinecone' or 'qdrant')", backend)
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("unsupported backend: %s (use 'pinecone', 'qdrant', or 'fake')", backend))
 	}
 
 	if err != nil {
@@ -218,51 +712,171 @@ func runServe(cmd *cobra.Command, args []string) error {
 		embeddingBaseURL = viper.GetString("embedding.base_url")
 	}
 
+	// A cloud provider without an API key leaves embeddings disabled
 	var embedder retriever.EmbeddingProvider
-	needsAPIKey := embeddingProvider == "" || embeddingProvider == "openai" || embeddingProvider == "cohere"
-	if needsAPIKey && openaiKey == "" {
-		// No API key and cloud provider selected — embeddings disabled
-	} else {
-		apiKeyForEmbed := openaiKey
-		if embeddingProvider == "cohere" && apiKeyForEmbed == "" {
-			apiKeyForEmbed = os.Getenv("COHERE_API_KEY")
-		}
-		if embeddingProvider == "" {
-			embeddingProvider = "openai"
-		}
-		embedder, err = embedding.NewProvider(embedding.ProviderConfig{
-			Type:      embedding.ProviderType(embeddingProvider),
-			APIKey:    apiKeyForEmbed,
-			Model:     embeddingModel,
-			BaseURL:   embeddingBaseURL,
-			CacheSize: -1,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create embedding provider: %w", err)
-		}
+	provider, err := newEmbedder(embeddingProvider, openaiKey, embeddingModel, embeddingBaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to create embedding provider: %w", errs.Wrap(errs.ErrConfig, err))
+	}
+	if provider != nil {
+		embedder = provider
+	}
+	if fr, ok := ret.(*fakeretriever.Client); ok {
+		// Queries must share the synthetic corpus's vector space.
+		embedder = fr.Embedder()
+	}
+
+	nsEntities, err := namespaceEntities()
+	if err != nil {
+		return err
 	}
 
 	// Create broker
 	brokerCfg := contextlab.BrokerConfig{
 		OverFetchK:        overFetchK,
 		TargetK:           targetK,
+		TokenBudget:       viper.GetInt("retriever.token_budget"),
+		MinCandidates:     viper.GetInt("retriever.min_candidates"),
 		ClusterThreshold:  threshold,
 		ClusterLinkage:    "average",
-		SelectionStrategy: contextlab.SelectByScore,
 		EnableMMR:         enableMMR,
 		MMRLambda:         lambda,
+		RecencyWeight:     recencyWeight,
+		RecencyHalfLife:   recencyHalfLife,
+		TimestampField:    timestampField,
+		MMRSeed:           viper.GetInt64("dedup.mmr_seed"),
+		Entities:          entityConfig(cmd),
+		NamespaceEntities: nsEntities,
+		MinScore:          minScore,
 		IncludeMetadata:   true,
+		IncludeTombstoned: includeTombstoned,
+		DedupHints:        viper.GetBool("dedup.hints"),
+	}
+	if err := applyStageFlags(cmd, &brokerCfg); err != nil {
+		return err
+	}
+	if brokerCfg.Matrix, err = matrixBudget(cmd); err != nil {
+		return err
+	}
+
+	limits, err := inputLimits(cmd)
+	if err != nil {
+		return err
+	}
+	captures, err := captureRecorder(cmd, aclConfig().Enabled)
+	if err != nil {
+		return err
+	}
+	historyW, err := historyWriter(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = historyW.Close() }()
+	namespaces, err := namespaceTracker()
+	if err != nil {
+		return err
 	}
 
-	var broker *contextlab.Broker
-	if embedder != nil {
-		broker = contextlab.NewBrokerWithEmbedder(ret, embedder, brokerCfg)
-	} else {
-		broker = contextlab.NewBroker(ret, brokerCfg)
+	exporter, err := analyticsExporter()
+	if err != nil {
+		return err
+	}
+	defer closeAnalytics(exporter)
+	httpOpts, err := resolveHTTPOptions(cmd)
+	if err != nil {
+		return err
+	}
+	embedOut, err := resolveEmbeddingOutput(cmd)
+	if err != nil {
+		return err
+	}
+	renderer, err := newRenderer(viper.GetString("render.default"))
+	if err != nil {
+		return err
+	}
+	modelProfiles, err := modelRegistry(renderer)
+	if err != nil {
+		return err
+	}
+	enricher, err := enrichmentHook()
+	if err != nil {
+		return err
+	}
+
+	caches, err := newQueryCaches()
+	if err != nil {
+		return err
+	}
+	defer caches.Close()
+
+	sentTTL, _ := cmd.Flags().GetDuration("sent-ttl")
+	sentCache, err := sessionCache()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sentCache.Close() }()
+
+	injection, err := injectionFilter()
+	if err != nil {
+		return err
+	}
+	junk, err := garbageFilter()
+	if err != nil {
+		return err
+	}
+	stages, err := pipelineStages()
+	if err != nil {
+		return err
+	}
+	rr, err := reranker()
+	if err != nil {
+		return err
+	}
+	classifier, err := sensitivityClassifier(ctx, embedder)
+	if err != nil {
+		return err
+	}
+
+	broker, err := contextlab.NewBrokerWithOptions(ret, append([]contextlab.Option{
+		contextlab.WithConfig(brokerCfg),
+		contextlab.WithEmbedder(embedder),
+		contextlab.WithSentFilter(distillcache.NewSentFilter(sentCache, sentTTL)),
+		contextlab.WithLimits(limits),
+		contextlab.WithEnrichment(enricher),
+		contextlab.WithACL(aclConfig()),
+		contextlab.WithInjectionFilter(injection),
+		contextlab.WithClassifier(classifier),
+		contextlab.WithGarbageFilter(junk),
+		contextlab.WithModels(modelProfiles),
+		contextlab.WithReranker(rr),
+		contextlab.WithCrossNamespaceGroups(viper.GetStringSlice("retriever.cross_namespace_groups")...),
+		contextlab.WithStages(stages...),
+	}, caches.options()...)...)
+	if err != nil {
+		return err
 	}
 	defer func() { _ = broker.Close() }()
 
+	warmNamespaces, warmSample := warmScanNamespaces()
+	warmScan(broker, embedder, warmNamespaces, warmSample)
+	if watch, _ := cmd.Flags().GetBool("watch-config"); watch && viper.ConfigFileUsed() != "" {
+		// The default namespace's sample no longer describes it
+		watchServingNamespace(ret, namespace, func(string) {
+			broker.ForgetFingerprint("")
+			if slices.Contains(warmNamespaces, "") {
+				warmScan(broker, embedder, []string{""}, warmSample)
+			}
+		})
+	}
+
+	onlineTuner, err := newTuner(broker.GetConfig())
+	if err != nil {
+		return err
+	}
+
 	m := metrics.New()
+	m.SetTunerEnabled(onlineTuner != nil)
+	defer startUsage("serve", backend, usageProvider(embeddingProvider, provider != nil), m).Stop()
 
 	// Initialize tracing
 	tracingCfg := telemetry.DefaultConfig()
@@ -287,6 +901,12 @@ func runServe(cmd *cobra.Command, args []string) error {
 		_ = tp.Shutdown(shutdownCtx)
 	}()
 
+	stopProfiling, err := startProfiling(cmd)
+	if err != nil {
+		return err
+	}
+	defer stopProfiling()
+
 	// Create server
 	server := &Server{
 		broker: broker,
@@ -294,64 +914,208 @@ func runServe(cmd *cobra.Command, args []string) error {
 			Host: host,
 			Port: port,
 		},
-		metrics: m,
-		tracing: tp,
+		metrics:    m,
+		tracing:    tp,
+		limits:     limits,
+		captures:   captures,
+		debugKeys:  debugKeys(cmd),
+		history:    historyW,
+		namespaces: namespaces,
+		analytics:  exporter,
+		queries:    viper.GetBool("history.record_queries"),
+		caches:     caches,
+		embedOut:   embedOut,
+		renderer:   renderer,
+		models:     modelProfiles,
+		embedder:   embedder,
+		tuner:      onlineTuner,
+	}
+	if cr, ok := ret.(retriever.ConnectionReporter); ok {
+		server.backend = cr
 	}
+	if viper.GetBool("server.allow_writes") {
+		u, ok := ret.(retriever.Upserter)
+		if !ok {
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("--allow-writes: backend %s does not support writes", backend))
+		}
+		server.writer = u
 
-	// Setup routes
-	mux := http.NewServeMux()
-	mux.HandleFunc("/v1/retrieve", m.Middleware("/v1/retrieve", server.handleRetrieve))
-	mux.HandleFunc("/health", server.handleHealth)
-	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
-		m.Handler().ServeHTTP(w, r)
-	})
+		if server.seen, server.seenPath, err = seenRegistry(cmd); err != nil {
+			return err
+		}
+	}
+	seenInterval, err := seenSaveInterval(cmd)
+	if err != nil {
+		return err
+	}
 
 	// Create HTTP server
-	addr := fmt.Sprintf("%s:%d", host, port)
+	target, err := resolveListenTarget(cmd, host, port, true)
+	if err != nil {
+		return err
+	}
+	addr := target.urlHost()
 	httpServer := &http.Server{
-		Addr:         addr,
-		Handler:      mux,
+		Addr:         target.addr,
+		Handler:      server.routes(),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 60 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
+	httpOpts.apply(httpServer)
 
-	// Graceful shutdown
-	done := make(chan bool)
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	var grpcServer *grpc.Server
+	grpcAddr := config.ListenAddr(host, viper.GetInt("server.grpc_port"))
+	if viper.GetInt("server.grpc_port") > 0 {
+		grpcServer = newGRPCServer(server)
+	}
 
-	go func() {
-		<-quit
-		fmt.Fprintln(os.Stderr, "\nShutting down server...")
+	serviceName, _ := cmd.Flags().GetString("service-name")
+	return supervise.Run(serviceName, func(ctx context.Context) error {
+		ln, err := target.listen()
+		if err != nil {
+			return fmt.Errorf("server error: %w", err)
+		}
+		if grpcServer != nil {
+			gln, err := net.Listen("tcp", grpcAddr)
+			if err != nil {
+				_ = ln.Close()
+				return fmt.Errorf("gRPC server error: %w", err)
+			}
+			go func() {
+				if err := grpcServer.Serve(gln); err != nil {
+					fmt.Fprintf(os.Stderr, "gRPC server error: %v\n", err)
+				}
+			}()
+		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+		go snapshotNamespaces(ctx, namespaces, historyW, viper.GetDuration("history.namespace_snapshot_interval"))
+		go saveSeen(ctx, server.seen, server.seenPath, seenInterval)
+
+		// Graceful shutdown on signal or service-manager stop request
+		done := make(chan struct{})
+		go func() {
+			<-ctx.Done()
+			fmt.Fprintln(os.Stderr, "\nShutting down server...")
+			supervise.Stopping()
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+				fmt.Fprintf(os.Stderr, "Server shutdown error: %v\n", err)
+			}
+			if grpcServer != nil {
+				stopGRPC(shutdownCtx, grpcServer)
+			}
+			if server.seen != nil {
+				flushSeen(server.seen, server.seenPath)
+			}
+			close(done)
+		}()
 
-		if err := httpServer.Shutdown(ctx); err != nil {
-			fmt.Fprintf(os.Stderr, "Server shutdown error: %v\n", err)
+		// Start server
+		fmt.Printf("ContextLab server starting on %s\n", target)
+		fmt.Printf("  Backend: %s\n", backend)
+		fmt.Printf("  Index: %s\n", index)
+		fmt.Printf("  Embeddings: %v\n", embedder != nil)
+		if acl := aclConfig(); acl.Enabled {
+			fmt.Printf("  ACL: %s, %s (deny by default)\n", acl.GroupsField, acl.UsersField)
 		}
-		close(done)
-	}()
+		if injection.Enabled() {
+			fmt.Printf("  Injection filter: %s (threshold %g)\n", injection.Mode, injection.Threshold)
+		}
+		if junk != nil {
+			fmt.Printf("  Garbage filter: threshold %g\n", viper.GetFloat64("garbage.threshold"))
+		}
+		if def := viper.GetString("models.default"); def != "" {
+			fmt.Printf("  Model: %s\n", def)
+		}
+		if rr != nil {
+			fmt.Printf("  Reranker: %s\n", viper.GetString("rerank.url"))
+		}
+		if enricher != nil {
+			fmt.Printf("  Enrichment: %s\n", viper.GetString("enrichment.type"))
+		}
+		if exporter != nil {
+			fmt.Printf("  Analytics: %s (sample rate %g)\n", viper.GetString("analytics.sink"), exporter.SampleRate())
+		}
+		if stages != nil {
+			names := make([]string, len(stages))
+			for i, st := range stages {
+				names[i] = st.Name
+			}
+			fmt.Printf("  Pipeline: %s\n", strings.Join(names, " -> "))
+		}
+		if server.seen != nil {
+			fmt.Printf("  Seen filter: %s\n", server.seenPath)
+		}
+		if caches.results != nil {
+			fmt.Printf("  Result cache: %v\n", caches.resultTTL)
+		}
+		if caches.backend == "disk" && (caches.embeddings != nil || caches.results != nil) {
+			fmt.Printf("  Cache dir: %s\n", viper.GetString("cache.dir"))
+		}
+		fmt.Println()
+		fmt.Println("Endpoints:")
+		fmt.Printf("  POST http://%s/v1/retrieve\n", addr)
+		fmt.Printf("  POST http://%s/v1/similar\n", addr)
+		fmt.Printf("  GET  http://%s/v1/recommend\n", addr)
+		fmt.Printf("  POST http://%s/v1/analyze\n", addr)
+		if onlineTuner != nil {
+			fmt.Printf("  POST http://%s/v1/feedback\n", addr)
+			fmt.Printf("  GET  http://%s/v1/tuner\n", addr)
+		}
+		fmt.Printf("  GET  http://%s/v1/cache/stats\n", addr)
+		fmt.Printf("  GET  http://%s/v1/stats/namespaces\n", addr)
+		if server.writer != nil {
+			fmt.Printf("  PUT  http://%s/v1/vectors\n", addr)
+		}
+		fmt.Printf("  GET  http://%s/health\n", addr)
+		fmt.Printf("  GET  http://%s/health/ready\n", addr)
+		if captures != nil {
+			fmt.Printf("  GET  http://%s/debug/captures (API key required: %v)\n", addr, len(server.debugKeys) > 0)
+		}
+		if grpcServer != nil {
+			fmt.Printf("  gRPC %s (distill.v1.Distill)\n", grpcAddr)
+		}
+		fmt.Println()
 
-	// Start server
-	fmt.Printf("ContextLab server starting on %s\n", addr)
-	fmt.Printf("  Backend: %s\n", backend)
-	fmt.Printf("  Index: %s\n", index)
-	fmt.Printf("  Embeddings: %v\n", embedder != nil)
-	fmt.Println()
-	fmt.Println("Endpoints:")
-	fmt.Printf("  POST http://%s/v1/retrieve\n", addr)
-	fmt.Printf("  GET  http://%s/health\n", addr)
-	fmt.Println()
+		supervise.Ready()
+		supervise.Status(fmt.Sprintf("serving %s on %s", backend, target))
+		supervise.Watchdog(ctx)
 
-	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
-		return fmt.Errorf("server error: %w", err)
-	}
+		if err := httpServer.Serve(ln); err != http.ErrServerClosed {
+			return fmt.Errorf("server error: %w", err)
+		}
+
+		<-done
+		fmt.Println("Server stopped")
+		return nil
+	})
+}
 
-	<-done
-	fmt.Println("Server stopped")
-	return nil
+// routes returns the server's HTTP handler.
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/retrieve", s.metrics.Middleware("/v1/retrieve", s.handleRetrieve))
+	mux.HandleFunc("/v1/similar", s.metrics.Middleware("/v1/similar", s.handleSimilar))
+	mux.HandleFunc("/v1/recommend", s.metrics.Middleware("/v1/recommend", s.handleRecommend))
+	mux.HandleFunc("/v1/analyze", s.metrics.Middleware("/v1/analyze", s.handleAnalyze))
+	mux.HandleFunc("/v1/feedback", s.metrics.Middleware("/v1/feedback", s.handleFeedback))
+	mux.HandleFunc("/v1/tuner", s.metrics.Middleware("/v1/tuner", s.handleTuner))
+	mux.HandleFunc("/v1/cache/stats", s.metrics.Middleware("/v1/cache/stats", s.handleCacheStats))
+	mux.HandleFunc("/v1/stats/namespaces", s.metrics.Middleware("/v1/stats/namespaces", s.handleNamespaceStats))
+	mux.HandleFunc("/v1/vectors", s.metrics.Middleware("/v1/vectors", s.handleVectors))
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/health/ready", s.handleReady)
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		s.metrics.Handler().ServeHTTP(w, r)
+	})
+	if s.captures != nil {
+		mux.HandleFunc("/debug/captures", requireKey(s.debugKeys, s.captures.Handler()))
+	}
+	return mux
 }
 
 func (s *Server) handleRetrieve(w http.ResponseWriter, r *http.Request) {
@@ -360,70 +1124,312 @@ func (s *Server) handleRetrieve(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	limitBody(w, r, s.limits.MaxInputBytes, s.metrics, "/v1/retrieve")
 	var req RetrieveRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+	if isProtobuf(r) {
+		var pb distillv1.RetrieveRequest
+		if !readProtobuf(w, r, &pb) {
+			return
+		}
+		req = retrieveRequestFromProto(&pb)
+	} else if !readBody(w, r, &req) {
 		return
 	}
 
 	// Validate request
-	if req.Query == "" && len(req.QueryEmbedding) == 0 {
-		http.Error(w, "Either 'query' or 'query_embedding' is required", http.StatusBadRequest)
+	if req.Query == "" && len(req.QueryEmbedding.Values) == 0 && len(req.Queries) == 0 && len(req.QueryEmbeddings) == 0 {
+		http.Error(w, "One of 'query', 'query_embedding', 'queries', or 'query_embeddings' is required", http.StatusBadRequest)
+		return
+	}
+	if n := len(req.Queries) + len(req.QueryEmbeddings); n > retriever.MaxFanOut {
+		http.Error(w, retriever.ErrTooManyQueries.Error(), http.StatusBadRequest)
+		return
+	}
+	if !retriever.ValidCombine(req.Combine) {
+		http.Error(w, fmt.Sprintf("Unknown combine mode %q (use %q or %q)", req.Combine, retriever.CombineAverage, retriever.CombineFanOut), http.StatusBadRequest)
 		return
 	}
 
-	// Build retrieval request
-	retrievalReq := &types.RetrievalRequest{
-		Query:          req.Query,
-		QueryEmbedding: req.QueryEmbedding,
-		Namespace:      req.Namespace,
-		Filter:         req.Filter,
+	if req.MinScore < 0 {
+		http.Error(w, "'min_score' must be non-negative", http.StatusBadRequest)
+		return
 	}
 
-	// Override broker config if specified in request
-	if req.OverFetchK > 0 || req.TargetK > 0 || req.Threshold > 0 || req.Lambda > 0 {
-		cfg := s.broker.GetConfig()
-		if req.OverFetchK > 0 {
-			cfg.OverFetchK = req.OverFetchK
-		}
-		if req.TargetK > 0 {
-			cfg.TargetK = req.TargetK
-		}
-		if req.Threshold > 0 {
-			cfg.ClusterThreshold = req.Threshold
-		}
-		if req.Lambda > 0 {
-			cfg.MMRLambda = req.Lambda
+	if !s.checkLimits(w, "/v1/retrieve", req.OverFetchK) {
+		return
+	}
+	for _, v := range append([]Embedding{req.QueryEmbedding}, req.QueryEmbeddings...) {
+		if limit := s.limits.MaxDimension; limit > 0 && len(v.Values) > limit {
+			writeTooLarge(w, s.metrics, "/v1/retrieve", &contextlab.LimitError{
+				Limit: contextlab.LimitMaxDimension,
+				Value: int64(len(v.Values)),
+				Max:   int64(limit),
+			})
+			return
 		}
-		s.broker.SetConfig(cfg)
+	}
+	if err := s.embedOut.check(req.EmbeddingOptions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkEmbeddingMode(req.ValidateEmbeddings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.renderer.Check(req.Template); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !checkSelection(w, req.Selection) || !checkDeadline(w, req.DeadlineMs) {
+		return
+	}
+
+	// Build retrieval request
+	retrievalReq := &types.RetrievalRequest{
+		Query:           req.Query,
+		QueryEmbedding:  req.QueryEmbedding.Values,
+		Queries:         req.Queries,
+		QueryEmbeddings: embeddings(req.QueryEmbeddings),
+		Combine:         req.Combine,
+		Namespace:       req.Namespace,
+		Namespaces:      namespaceQuotas(req.Namespaces),
+		Filter:          req.Filter,
+		MinScore:        req.MinScore,
+		SessionID:       req.SessionID,
+		MarkRepeats:     req.MarkRepeats,
+		Exclude:         req.Exclude,
+		Identity:        req.Identity.identity(),
+		DedupHints:      req.DedupHints,
+		Explain:         req.Explain,
+		Stages:          req.Enable.toggles(),
+		Selection:       req.Selection,
+		Model:           req.Model,
+		Compress:        req.Compress.options(),
+		Mode:            req.Mode,
+		Redact:          req.Redact,
+		Deadline:        time.Duration(req.DeadlineMs) * time.Millisecond,
 	}
 
+	s.overrideConfig(req.OverFetchK, req.TargetK, req.Threshold, req.Lambda)
+	feedbackID := s.tune(&req, retrievalReq)
+
 	// Start tracing span
 	ctx, rootSpan := s.tracing.StartRequest(r.Context(), "/v1/retrieve")
 	defer rootSpan.End()
 
+	checked, err := s.checkQueryEmbeddings(ctx, req.ValidateEmbeddings, retrievalReq)
+	if err != nil {
+		telemetry.RecordError(rootSpan, err)
+		http.Error(w, embeddingCheckMessage(err), embeddingCheckStatus(err))
+		return
+	}
+
 	// Execute retrieval
 	result, err := s.broker.Retrieve(ctx, retrievalReq)
 	if err != nil {
 		telemetry.RecordError(rootSpan, err)
+		if writeInterrupted(w, err) || writeTooLarge(w, s.metrics, "/v1/retrieve", err) {
+			return
+		}
+		if errors.Is(err, enrich.ErrFailed) {
+			http.Error(w, fmt.Sprintf("Enrichment failed: %v", err), http.StatusBadGateway)
+			return
+		}
+		if errors.Is(err, contextlab.ErrNamespaceDenied) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		// Mismatched query vector dimensions are only found after embedding
+		if errors.Is(err, errs.ErrConfig) {
+			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Retrieval failed: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Record result on root span
 	telemetry.RecordResult(rootSpan, result.Stats.Retrieved, result.Stats.Returned, result.Stats.Clustered, result.Stats.TotalLatency)
+	if feedbackID != "" {
+		ids := make([]string, len(result.Chunks))
+		for i, c := range result.Chunks {
+			ids[i] = c.ID
+		}
+		s.tuner.Served(feedbackID, ids)
+	}
+	s.writeResult(w, r, "/v1/retrieve", req.EmbeddingOptions, req.Template, retrievalReq, result, checked, feedbackID)
+}
+
+func (s *Server) handleSimilar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limitBody(w, r, s.limits.MaxInputBytes, s.metrics, "/v1/similar")
+	var req SimilarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, "JSON", err)
+		return
+	}
+
+	// Validate request
+	if len(req.IDs) == 0 {
+		http.Error(w, "'ids' is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) > retriever.MaxFanOut {
+		http.Error(w, retriever.ErrTooManyQueries.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.MinScore < 0 {
+		http.Error(w, "'min_score' must be non-negative", http.StatusBadRequest)
+		return
+	}
+	if !s.checkLimits(w, "/v1/similar", req.OverFetchK) {
+		return
+	}
+	if err := s.embedOut.check(req.EmbeddingOptions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.renderer.Check(req.Template); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !checkSelection(w, req.Selection) || !checkDeadline(w, req.DeadlineMs) {
+		return
+	}
+
+	retrievalReq := &types.RetrievalRequest{
+		Namespace:   req.Namespace,
+		MinScore:    req.MinScore,
+		SessionID:   req.SessionID,
+		MarkRepeats: req.MarkRepeats,
+		Exclude:     req.Exclude,
+		Identity:    req.Identity.identity(),
+		DedupHints:  req.DedupHints,
+		Explain:     req.Explain,
+		Stages:      req.Enable.toggles(),
+		Selection:   req.Selection,
+		Model:       req.Model,
+		Compress:    req.Compress.options(),
+		Deadline:    time.Duration(req.DeadlineMs) * time.Millisecond,
+	}
+
+	s.overrideConfig(req.OverFetchK, req.TargetK, req.Threshold, req.Lambda)
+
+	ctx, rootSpan := s.tracing.StartRequest(r.Context(), "/v1/similar")
+	defer rootSpan.End()
+
+	result, err := s.broker.RetrieveSimilar(ctx, req.IDs, retrievalReq)
+	if err != nil {
+		telemetry.RecordError(rootSpan, err)
+		if errors.Is(err, retriever.ErrNotFound) {
+			http.Error(w, fmt.Sprintf("Not found: %v", err), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, enrich.ErrFailed) {
+			http.Error(w, fmt.Sprintf("Enrichment failed: %v", err), http.StatusBadGateway)
+			return
+		}
+		if !writeInterrupted(w, err) && !writeTooLarge(w, s.metrics, "/v1/similar", err) {
+			http.Error(w, fmt.Sprintf("Retrieval failed: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	telemetry.RecordResult(rootSpan, result.Stats.Retrieved, result.Stats.Returned, result.Stats.Clustered, result.Stats.TotalLatency)
+	s.writeResult(w, r, "/v1/similar", req.EmbeddingOptions, req.Template, retrievalReq, result, queryCheck{}, "")
+}
+
+// checkSelection rejects an unknown selection strategy override.
+func checkSelection(w http.ResponseWriter, selection string) bool {
+	if selection == "" {
+		return true
+	}
+	if _, err := contextlab.ParseSelectionStrategy(selection); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// checkDeadline rejects a negative deadline_ms.
+func checkDeadline(w http.ResponseWriter, ms int) bool {
+	if ms < 0 {
+		http.Error(w, fmt.Sprintf("Invalid request: deadline_ms must be non-negative, got %d", ms), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// checkLimits rejects an over-fetch above the chunk limit before any
+// retrieval happens.
+func (s *Server) checkLimits(w http.ResponseWriter, endpoint string, overFetchK int) bool {
+	if limit := s.limits.MaxChunks; limit > 0 && overFetchK > limit {
+		writeTooLarge(w, s.metrics, endpoint, &contextlab.LimitError{
+			Limit: contextlab.LimitMaxChunks,
+			Value: int64(overFetchK),
+			Max:   int64(limit),
+		})
+		return false
+	}
+	return true
+}
+
+// overrideConfig applies per-request broker settings. Zero values keep
+// the current setting.
+func (s *Server) overrideConfig(overFetchK, targetK int, threshold, lambda float64) {
+	if overFetchK <= 0 && targetK <= 0 && threshold <= 0 && lambda <= 0 {
+		return
+	}
+	cfg := s.broker.GetConfig()
+	if overFetchK > 0 {
+		cfg.OverFetchK = overFetchK
+	}
+	if targetK > 0 {
+		cfg.TargetK = targetK
+	}
+	if threshold > 0 {
+		cfg.ClusterThreshold = threshold
+	}
+	if lambda > 0 {
+		cfg.MMRLambda = lambda
+	}
+	s.broker.SetConfig(cfg)
+}
+
+// writeResult encodes a broker result as a RetrieveResponse, rendered
+// with template if set, and records metrics and anomaly captures for
+// endpoint. feedbackID is the online tuner's ID for the request, if any.
+func (s *Server) writeResult(w http.ResponseWriter, r *http.Request, endpoint string, opts EmbeddingOptions, template string, req *types.RetrievalRequest, result *types.BrokerResult, checked queryCheck, feedbackID string) {
+	// The model's format stands in for a missing template; the broker has
+	// already rejected unknown models
+	if p, _ := s.models.Resolve(req.Model); p != nil && template == "" {
+		template = p.Format
+	}
+	rendered, err := s.renderer.Render(template, render.NewData(req.Query, result))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Rendering failed: %v", err), http.StatusInternalServerError)
+		return
+	}
 
-	// Build response
 	chunks := make([]ChunkResponse, len(result.Chunks))
 	for i, c := range result.Chunks {
 		chunks[i] = ChunkResponse{
-			ID:        c.ID,
-			Text:      c.Text,
-			Score:     c.Score,
-			ClusterID: c.ClusterID,
-			Metadata:  c.Metadata,
+			ID:          c.ID,
+			Text:        c.Text,
+			Score:       c.Score,
+			ClusterID:   c.ClusterID,
+			AlreadySent: distillcache.IsAlreadySent(c),
+			Metadata:    c.Metadata,
 		}
 	}
+	embeddings, _ := s.embedOut.embeddings(opts, result.Chunks)
+	for i := range embeddings {
+		chunks[i].Embedding = embeddings[i]
+	}
 
 	resp := RetrieveResponse{
 		Chunks: chunks,
@@ -431,20 +1437,94 @@ func (s *Server) handleRetrieve(w http.ResponseWriter, r *http.Request) {
 			Retrieved:           result.Stats.Retrieved,
 			Clustered:           result.Stats.Clustered,
 			Returned:            result.Stats.Returned,
+			Repeated:            result.Stats.Repeated,
+			Excluded:            result.Stats.Excluded,
+			Vetoed:              result.Stats.Vetoed,
+			MatrixOverflow:      result.Stats.MatrixOverflow,
+			Truncated:           result.Stats.Truncated,
+			RetrievalRetries:    result.Stats.RetrievalRetries,
 			RetrievalLatencyMs:  result.Stats.RetrievalLatency.Milliseconds(),
 			ClusteringLatencyMs: result.Stats.ClusteringLatency.Milliseconds(),
 			TotalLatencyMs:      result.Stats.TotalLatency.Milliseconds(),
+			Enriched:            result.Stats.Enriched,
+			Denied:              result.Stats.Denied,
+			EnrichmentFailed:    result.Stats.EnrichmentFailed,
+			EnrichmentLatencyMs: result.Stats.EnrichmentLatency.Milliseconds(),
+			ACLDenied:           result.Stats.ACLDenied,
+			ACLUnlabeled:        result.Stats.ACLUnlabeled,
+			InjectionFlagged:    result.Stats.InjectionFlagged,
+			InjectionStripped:   result.Stats.InjectionStripped,
+			InjectionBlocked:    result.Stats.InjectionBlocked,
+			GarbageDropped:      result.Stats.GarbageDropped,
+			GarbageAllowed:      result.Stats.GarbageAllowed,
+			Model:               result.Stats.Model,
+			Tokens:              result.Stats.Tokens,
+			CostUSD:             result.Stats.CostUSD,
+			BudgetDropped:       result.Stats.BudgetDropped,
+			Redacted:            result.Stats.Redacted,
+			Compression:         mergedStats(compressionStats(result.Stats.CompressInputTokens, result.Stats.CompressOutputTokens, result.Stats.Compressed), result.Stats.Merged),
+			Reranked:            result.Stats.Reranked,
+			RerankFailed:        result.Stats.RerankFailed,
+			RerankLatencyMs:     result.Stats.RerankLatency.Milliseconds(),
+			CacheHit:            result.Stats.CacheHit,
+			CacheMiss:           result.Stats.CacheMiss,
+			BestEffort:          result.Stats.BestEffort,
+			Notes:               result.Stats.Notes,
+			Sparse:              result.Stats.Sparse,
+			Passthrough:         result.Stats.Passthrough,
+			Sensitivity:         result.Stats.Sensitivity,
+
+			EmbeddingsRepaired: checked.repaired,
+			EmbeddingsDropped:  checked.dropped,
 		},
+		Rendered:   rendered,
+		FeedbackID: feedbackID,
+		Stages:     result.Stages,
 	}
 
 	// Record dedup-specific metrics
-	s.metrics.RecordDedup("/v1/retrieve", result.Stats.Retrieved, result.Stats.Returned, result.Stats.Clustered)
+	s.metrics.RecordDedup(endpoint, result.Stats.Retrieved, result.Stats.Returned, result.Stats.Clustered)
+	if st := result.Stats; st.ACLAllowed+st.ACLDenied > 0 {
+		s.metrics.RecordACL(endpoint, st.ACLAllowed, st.ACLDenied, st.ACLUnlabeled)
+	}
+	if st := result.Stats; st.InjectionFlagged > 0 {
+		s.metrics.RecordInjection(endpoint, st.InjectionFlagged, st.InjectionBlocked)
+	}
+	if st := result.Stats; st.CacheHit || st.CacheMiss {
+		s.metrics.RecordResultCache(endpoint, st.CacheHit)
+	}
+	if result.Stats.Sparse {
+		s.metrics.RecordSparse(endpoint, req.Namespace, result.Stats.Returned == 0)
+	}
+	s.recordRetrieve(endpoint, req, result)
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(resp)
+	if reasons := s.captures.Anomalies(result.Stats.TotalLatency, result.Stats.Retrieved, result.Stats.Returned); reasons != nil {
+		s.captures.Record(retrieveTrace(endpoint, reasons, s.broker.GetConfig(), req, result))
+	}
+
+	writeResponse(w, r, resp, func() (proto.Message, error) { return retrieveResponseProto(resp) })
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
+
+// handleReady reports whether queries can reach the backend: 200 while its
+// connection is up and 503 while it is down or reconnecting. Backends
+// without a long-lived connection are always ready.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]string{"status": "ready"}
+	code := http.StatusOK
+	if s.backend != nil {
+		state, ready := s.backend.ConnectionState()
+		resp["backend"] = state
+		if !ready {
+			resp["status"] = "unavailable"
+			code = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
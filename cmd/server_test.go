@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/metrics"
+	"github.com/Siddhant-K-code/distill/pkg/render"
+	"github.com/Siddhant-K-code/distill/pkg/telemetry"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// staticRetriever returns the same chunks for every query, stripped of
+// what the request did not ask for.
+type staticRetriever struct {
+	chunks []types.Chunk
+}
+
+func (r *staticRetriever) Query(ctx context.Context, req *types.RetrievalRequest) (*types.RetrievalResult, error) {
+	out := make([]types.Chunk, len(r.chunks))
+	for i, c := range r.chunks {
+		if !req.IncludeEmbeddings {
+			c.Embedding = nil
+		}
+		if !req.IncludeMetadata {
+			c.Metadata = nil
+		}
+		out[i] = c
+	}
+	return &types.RetrievalResult{Chunks: out}, nil
+}
+
+func (r *staticRetriever) QueryByID(ctx context.Context, id string, topK int, namespace string) (*types.RetrievalResult, error) {
+	return r.Query(ctx, &types.RetrievalRequest{TopK: topK, Namespace: namespace})
+}
+
+func (r *staticRetriever) Close() error { return nil }
+
+// newTestServer returns a Server over chunks, with the broker built
+// from opts.
+func newTestServer(t *testing.T, chunks []types.Chunk, opts ...contextlab.Option) *Server {
+	t.Helper()
+	broker, err := contextlab.NewBrokerWithOptions(&staticRetriever{chunks: chunks}, opts...)
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+	t.Cleanup(func() { _ = broker.Close() })
+	tracing, err := telemetry.Init(context.Background(), telemetry.DefaultConfig())
+	if err != nil {
+		t.Fatalf("telemetry.Init: %v", err)
+	}
+	renderer, err := render.New(nil, "")
+	if err != nil {
+		t.Fatalf("render.New: %v", err)
+	}
+	return &Server{
+		broker:   broker,
+		metrics:  metrics.New(),
+		tracing:  tracing,
+		limits:   contextlab.DefaultLimits(),
+		renderer: renderer,
+	}
+}
+
+// post sends body to handler and returns the recorded response.
+func post(handler http.HandlerFunc, path, contentType string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// pipelineStages returns the stages declared under pipeline.stages in the
+// config file, or nil to keep the built-in pipeline.
+func pipelineStages() ([]contextlab.StageSpec, error) {
+	raw, _ := viper.Get("pipeline.stages").([]interface{})
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return contextlab.ParseStages(raw)
+}
+
+// addStageFlags registers the switches for the optional pipeline stages
+// other than MMR, which each command registers itself.
+func addStageFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("enable-clustering", true, "Cluster near-duplicates and keep one representative per cluster")
+	cmd.Flags().String("selection", string(contextlab.SelectByScore), "How cluster representatives are picked: score, centroid, length, or hybrid")
+	cmd.Flags().Bool("enable-compression", false, "Compress returned chunks")
+	cmd.Flags().String("compression-mode", "", "Compression strategy: extractive, placeholder, hybrid, or cluster-merge (default: extractive)")
+	cmd.Flags().Float64("compression-target", 0, "Share of each chunk's tokens compression keeps, 0-1 (0 = 0.5)")
+	cmd.Flags().Int("compression-merge-tokens", 0, "Token cap of each chunk cluster-merge builds (0 = the representative's length)")
+	cmd.Flags().Bool("enable-redaction", false, "Redact credentials and PII from returned chunks")
+	cmd.Flags().Bool("enable-classification", false, "Tag returned chunks with their sensitivity level")
+	cmd.Flags().Bool("enable-scoring", true, "Apply the recency weight to MMR relevance")
+}
+
+// applyStageFlags sets cfg's stage switches from the flags, falling back
+// to the dedup config section for flags left unset.
+func applyStageFlags(cmd *cobra.Command, cfg *contextlab.BrokerConfig) error {
+	cfg.DisableClustering = !stageSwitch(cmd, "enable-clustering", "dedup.enable_clustering", true)
+	cfg.EnableCompression = stageSwitch(cmd, "enable-compression", "dedup.enable_compression", false)
+	cfg.EnableRedaction = stageSwitch(cmd, "enable-redaction", "dedup.enable_redaction", false)
+	cfg.EnableClassification = stageSwitch(cmd, "enable-classification", "dedup.enable_classification", false)
+	if !stageSwitch(cmd, "enable-scoring", "dedup.enable_scoring", true) {
+		cfg.RecencyWeight = 0
+	}
+
+	cfg.Compress.Mode = viper.GetString("dedup.compression_mode")
+	if cmd.Flags().Changed("compression-mode") {
+		cfg.Compress.Mode, _ = cmd.Flags().GetString("compression-mode")
+	}
+	if _, err := compress.ParseMode(cfg.Compress.Mode); err != nil {
+		return err
+	}
+	cfg.Compress.TargetReduction = viper.GetFloat64("dedup.compression_target")
+	if cmd.Flags().Changed("compression-target") {
+		cfg.Compress.TargetReduction, _ = cmd.Flags().GetFloat64("compression-target")
+	}
+	if t := cfg.Compress.TargetReduction; t < 0 || t > 1 {
+		return fmt.Errorf("compression target must be between 0 and 1, got %g", t)
+	}
+	cfg.Compress.MergeTokens = viper.GetInt("dedup.compression_merge_tokens")
+	if cmd.Flags().Changed("compression-merge-tokens") {
+		cfg.Compress.MergeTokens, _ = cmd.Flags().GetInt("compression-merge-tokens")
+	}
+	if n := cfg.Compress.MergeTokens; n < 0 {
+		return fmt.Errorf("compression merge tokens must be non-negative, got %d", n)
+	}
+	if viper.IsSet("dedup.preserve_structure") {
+		preserve := viper.GetBool("dedup.preserve_structure")
+		cfg.Compress.PreserveStructure = &preserve
+	}
+
+	selection := string(contextlab.SelectByScore)
+	if viper.IsSet("dedup.selection") {
+		selection = viper.GetString("dedup.selection")
+	}
+	if cmd.Flags().Changed("selection") {
+		selection, _ = cmd.Flags().GetString("selection")
+	}
+	strategy, err := contextlab.ParseSelectionStrategy(selection)
+	if err != nil {
+		return err
+	}
+	cfg.SelectionStrategy = strategy
+	return nil
+}
+
+// stageSwitch reads a boolean flag, falling back to key and then def.
+func stageSwitch(cmd *cobra.Command, flag, key string, def bool) bool {
+	on := def
+	if viper.IsSet(key) {
+		on = viper.GetBool(key)
+	}
+	if cmd.Flags().Changed(flag) {
+		on, _ = cmd.Flags().GetBool(flag)
+	}
+	return on
+}
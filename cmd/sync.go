@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/history"
 	"github.com/Siddhant-K-code/distill/pkg/dedup"
 	"github.com/Siddhant-K-code/distill/pkg/ingest"
 	pc "github.com/Siddhant-K-code/distill/pkg/pinecone"
+	"github.com/Siddhant-K-code/distill/pkg/types"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -19,11 +23,16 @@ import (
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Sync vectors to Pinecone with optional deduplication",
-	Long: `Reads vectors from a JSONL file, optionally deduplicates them,
+	Long: `Reads vectors from JSONL files, optionally deduplicates them,
 and uploads to a Pinecone index using parallel workers.
 
+--file takes a file, a directory (searched recursively for .jsonl and
+.ndjson files), or a glob, and can be repeated. Files are loaded
+concurrently and deduplicated together.
+
 Example:
   distill sync --file data.jsonl --index my-index --dedup=true
+  distill sync --file 'exports/*.jsonl' --index my-index --file-manifest files.jsonl
 
 Environment Variables:
   PINECONE_API_KEY    Your Pinecone API key (required)`,
@@ -33,43 +42,104 @@ Environment Variables:
 func init() {
 	rootCmd.AddCommand(syncCmd)
 
+	addSyncFlags(syncCmd)
+
+	// Bind to viper
+	_ = viper.BindPFlag("api_key", syncCmd.Flags().Lookup("api-key"))
+	_ = viper.BindPFlag("index", syncCmd.Flags().Lookup("index"))
+	_ = viper.BindPFlag("namespace", syncCmd.Flags().Lookup("namespace"))
+}
+
+// addSyncFlags registers the flags for loading, deduplicating, and
+// uploading vectors, shared by sync and reindex.
+func addSyncFlags(cmd *cobra.Command) {
 	// File input
-	syncCmd.Flags().StringP("file", "f", "", "path to JSONL file containing vectors (required)")
-	_ = syncCmd.MarkFlagRequired("file")
+	cmd.Flags().StringArrayP("file", "f", nil, "JSONL file, directory, or glob of vector files; repeatable (required)")
+	cmd.Flags().Int("file-workers", 4, "number of files to load concurrently")
+	cmd.Flags().String("file-manifest", "", "write per-file results (counts, durations, errors) as JSONL to this file")
+	_ = cmd.MarkFlagRequired("file")
 
 	// Pinecone settings
-	syncCmd.Flags().StringP("index", "i", "", "Pinecone index name (required)")
-	syncCmd.Flags().StringP("namespace", "n", "", "Pinecone namespace (optional)")
-	syncCmd.Flags().String("api-key", "", "Pinecone API key (or use PINECONE_API_KEY env)")
+	cmd.Flags().StringP("index", "i", "", "Pinecone index name (required)")
+	cmd.Flags().StringP("namespace", "n", "", "Pinecone namespace (optional)")
+	cmd.Flags().String("api-key", "", "Pinecone API key (or use PINECONE_API_KEY env)")
 
 	// Deduplication settings
-	syncCmd.Flags().Bool("dedup", true, "enable semantic deduplication before upload")
-	syncCmd.Flags().Float64P("threshold", "t", 0.05, "cosine distance threshold for duplicates")
-	syncCmd.Flags().IntP("clusters", "k", 0, "number of clusters (0 = auto)")
+	cmd.Flags().Bool("dedup", true, "enable semantic deduplication before upload")
+	cmd.Flags().Float64P("threshold", "t", 0.05, "cosine distance threshold for duplicates")
+	cmd.Flags().IntP("clusters", "k", 0, "number of clusters (0 = auto)")
+	cmd.Flags().String("manifest", "", "write removed duplicates (removed ID, kept ID, distance, cluster) as JSONL to this file")
+	// Validation settings
+	cmd.Flags().Bool("validate", false, "reject NaN/Inf, zero, and wrong-dimension vectors before upload")
+	cmd.Flags().Int("dimension", 0, "expected vector dimension for --validate (0 = read from index)")
+	cmd.Flags().Bool("normalize", false, "L2-normalize vectors during --validate")
+	cmd.Flags().Bool("strict", false, "fail the run on the first invalid vector instead of skipping it")
+
+	// Verification settings
+	cmd.Flags().Bool("verify", false, "after upload, fetch a sample of vectors back and check values and metadata")
+	cmd.Flags().Int("verify-sample", 1000, "vectors to fetch back for --verify (0 = all)")
+	cmd.Flags().Duration("verify-delay", 2*time.Second, "wait between re-fetches of vectors not yet visible in the index")
+
+	cmd.Flags().Bool("tombstone", false, "upsert removed duplicates with distill_duplicate metadata instead of dropping them")
+	addSeenFlags(cmd)
 
 	// Performance settings
-	syncCmd.Flags().IntP("workers", "w", 0, "number of upload workers (0 = NumCPU*2)")
-	syncCmd.Flags().IntP("batch-size", "b", 100, "vectors per batch (Pinecone optimal: 100)")
-
-	// Bind to viper
-	_ = viper.BindPFlag("api_key", syncCmd.Flags().Lookup("api-key"))
-	_ = viper.BindPFlag("index", syncCmd.Flags().Lookup("index"))
-	_ = viper.BindPFlag("namespace", syncCmd.Flags().Lookup("namespace"))
+	cmd.Flags().IntP("workers", "w", 0, "number of upload workers (0 = NumCPU*2)")
+	cmd.Flags().IntP("batch-size", "b", 100, "vectors per batch (Pinecone optimal: 100)")
+	cmd.Flags().Bool("adaptive", false, "tune batch size and workers from throttling and latency (--batch-size and --workers become ceilings)")
+	cmd.Flags().Int("min-batch-size", 10, "smallest batch --adaptive shrinks to, and the step it grows by")
+	cmd.Flags().Duration("target-latency", 0, "with --adaptive, treat batches slower than this as congestion (0 = react to throttling only)")
+	cmd.Flags().Float64("rate-limit", 0, "max vectors per second across all workers (0 = unlimited)")
+
+	addHistoryFlags(cmd)
 }
 
 func runSync(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	verify, _ := cmd.Flags().GetBool("verify")
+	_, err := syncVectors(cmd, namespace, verify)
+	return err
+}
+
+// syncVectors loads, validates, deduplicates, and uploads vectors into
+// namespace as configured by cmd's sync flags, then checks a sample of
+// them if verify is set.
+func syncVectors(cmd *cobra.Command, namespace string, verify bool) (_ *ingest.Stats, err error) {
 	// Get flags
-	filePath, _ := cmd.Flags().GetString("file")
+	filePatterns, _ := cmd.Flags().GetStringArray("file")
+	fileWorkers, _ := cmd.Flags().GetInt("file-workers")
+	fileManifestPath, _ := cmd.Flags().GetString("file-manifest")
 	indexName, _ := cmd.Flags().GetString("index")
-	namespace, _ := cmd.Flags().GetString("namespace")
 	apiKey, _ := cmd.Flags().GetString("api-key")
 	dedupEnabled, _ := cmd.Flags().GetBool("dedup")
 	threshold, _ := cmd.Flags().GetFloat64("threshold")
 	clusters, _ := cmd.Flags().GetInt("clusters")
+	manifestPath, _ := cmd.Flags().GetString("manifest")
+	tombstone, _ := cmd.Flags().GetBool("tombstone")
+	validate, _ := cmd.Flags().GetBool("validate")
+	dimension, _ := cmd.Flags().GetInt("dimension")
+	normalize, _ := cmd.Flags().GetBool("normalize")
+	strict, _ := cmd.Flags().GetBool("strict")
+	verifySample, _ := cmd.Flags().GetInt("verify-sample")
+	verifyDelay, _ := cmd.Flags().GetDuration("verify-delay")
 	workers, _ := cmd.Flags().GetInt("workers")
 	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	adaptive, _ := cmd.Flags().GetBool("adaptive")
+	minBatchSize, _ := cmd.Flags().GetInt("min-batch-size")
+	targetLatency, _ := cmd.Flags().GetDuration("target-latency")
+	rateLimit, _ := cmd.Flags().GetFloat64("rate-limit")
 	verbose := viper.GetBool("verbose")
 
+	if verifySample < 0 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--verify-sample must be >= 0, got %d", verifySample))
+	}
+	if rateLimit < 0 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--rate-limit must be >= 0, got %g", rateLimit))
+	}
+	if adaptive && (minBatchSize <= 0 || minBatchSize > batchSize) {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--min-batch-size must be between 1 and --batch-size (%d), got %d", batchSize, minBatchSize))
+	}
+
 	// Resolve API key from env if not provided
 	if apiKey == "" {
 		apiKey = viper.GetString("api_key")
@@ -78,7 +148,7 @@ func runSync(cmd *cobra.Command, args []string) error {
 		apiKey = os.Getenv("PINECONE_API_KEY")
 	}
 	if apiKey == "" {
-		return fmt.Errorf("pinecone API key is required: set PINECONE_API_KEY or use --api-key")
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("pinecone API key is required: set PINECONE_API_KEY or use --api-key"))
 	}
 
 	// Resolve index from env if not provided
@@ -86,9 +156,33 @@ func runSync(cmd *cobra.Command, args []string) error {
 		indexName = viper.GetString("index")
 	}
 	if indexName == "" {
-		return fmt.Errorf("pinecone index name is required: use --index flag")
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("pinecone index name is required: use --index flag"))
 	}
 
+	seen, seenPath, err := seenRegistry(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := startHistoryJob(cmd, history.KindSync, strings.Join(filePatterns, ","), map[string]interface{}{
+		"index":      indexName,
+		"namespace":  namespace,
+		"dedup":      dedupEnabled,
+		"threshold":  threshold,
+		"clusters":   clusters,
+		"validate":   validate,
+		"normalize":  normalize,
+		"tombstone":  tombstone,
+		"batch_size": batchSize,
+		"workers":    workers,
+		"adaptive":   adaptive,
+		"seen":       seenPath != "",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { job.finish(err) }()
+
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -103,23 +197,98 @@ func runSync(cmd *cobra.Command, args []string) error {
 	}()
 
 	// Load vectors
-	fmt.Fprintf(os.Stderr, "Loading vectors from %s...\n", filePath)
-	loadStart := time.Now()
-	vectors, err := loadVectorsFromFile(filePath)
+	paths, err := ingest.ExpandPaths(filePatterns)
 	if err != nil {
-		return fmt.Errorf("failed to load vectors: %w", err)
+		return nil, err
 	}
+	if len(paths) == 1 {
+		fmt.Fprintf(os.Stderr, "Loading vectors from %s...\n", paths[0])
+	} else {
+		fmt.Fprintf(os.Stderr, "Loading vectors from %d files...\n", len(paths))
+	}
+	loadStart := time.Now()
+	vectors, files := loadSyncFiles(paths, fileWorkers)
 	loadDuration := time.Since(loadStart)
 
+	loadErrors := files.reportLoadErrors()
+	if loadErrors == len(paths) {
+		if err := files.write(fileManifestPath); err != nil {
+			return nil, err
+		}
+		if len(paths) == 1 {
+			return nil, fmt.Errorf("failed to load vectors: %w", errs.New(errs.ErrConfig, files.results[0].Error))
+		}
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("failed to load vectors: none of %d files could be read", len(paths)))
+	}
+
 	if len(vectors) == 0 {
 		fmt.Println("No vectors found in file.")
-		return nil
+		return nil, files.write(fileManifestPath)
 	}
 
 	fmt.Fprintf(os.Stderr, "Loaded %d vectors in %v\n", len(vectors), loadDuration)
+	loaded := len(vectors)
+
+	// Connect to Pinecone
+	fmt.Fprintf(os.Stderr, "Connecting to Pinecone index %q...\n", indexName)
+
+	pcCfg := pc.Config{
+		APIKey:    apiKey,
+		IndexName: indexName,
+		Namespace: namespace,
+	}
+
+	client, err := pc.NewClient(ctx, pcCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Pinecone: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	// Validation phase: runs before dedup so NaN or zero vectors never
+	// reach clustering
+	if validate {
+		if dimension == 0 {
+			if stats, err := client.DescribeIndexStats(ctx); err == nil && stats.Dimension != nil {
+				dimension = int(*stats.Dimension)
+			}
+		}
+
+		validator := ingest.NewValidator(ingest.ValidationConfig{
+			Dimension: dimension,
+			Normalize: normalize,
+			Strict:    strict,
+		})
+		vectors, err = validator.Filter(vectors)
+		if err != nil {
+			return nil, fmt.Errorf("validation failed: %w", err)
+		}
+		printValidationReport(validator.Report(), validator.Dimension())
+
+		if len(vectors) == 0 {
+			fmt.Println("No valid vectors to upload.")
+			files.queued(nil)
+			return nil, files.write(fileManifestPath)
+		}
+	}
+
+	// Seen filter: exact copies of content already uploaded to the
+	// namespace are dropped without querying the index
+	if seen != nil {
+		var skipped int
+		vectors, skipped = ingest.SkipSeen(vectors, seen, namespace)
+		job.detail("seen", skipped)
+		fmt.Fprintf(os.Stderr, "Skipped %d vectors already ingested (%s)\n", skipped, seenPath)
+
+		if len(vectors) == 0 {
+			fmt.Println("No new vectors to upload.")
+			files.queued(nil)
+			return nil, files.write(fileManifestPath)
+		}
+	}
 
 	// Deduplication phase
 	var uploadVectors = vectors
+	job.counts(loaded, len(vectors))
 	if dedupEnabled {
 		fmt.Fprintln(os.Stderr, "Running semantic deduplication...")
 
@@ -133,34 +302,43 @@ func runSync(cmd *cobra.Command, args []string) error {
 		engine := dedup.NewEngine(cfg)
 		result, err := engine.Deduplicate(ctx, vectors)
 		if err != nil {
-			return fmt.Errorf("deduplication failed: %w", err)
+			return nil, fmt.Errorf("deduplication failed: %w", err)
 		}
 
 		uploadVectors = result.UniqueVectors
+		job.counts(loaded, len(uploadVectors))
+		job.detail("duplicates", result.DuplicateCount)
 
 		fmt.Fprintf(os.Stderr, "Deduplication complete: %d unique vectors (removed %d duplicates, %.1f%% savings)\n",
 			len(uploadVectors), result.DuplicateCount, result.SavingsPercent())
-	}
 
-	// Connect to Pinecone
-	fmt.Fprintf(os.Stderr, "Connecting to Pinecone index %q...\n", indexName)
+		if manifestPath != "" {
+			if err := writeRemovalManifest(manifestPath, result.Removed); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(os.Stderr, "Wrote removal manifest to %s\n", manifestPath)
+		}
 
-	pcCfg := pc.Config{
-		APIKey:    apiKey,
-		IndexName: indexName,
-		Namespace: namespace,
+		if tombstone {
+			tombs := dedup.Tombstones(vectors, result.Removed)
+			uploadVectors = append(uploadVectors, tombs...)
+			fmt.Fprintf(os.Stderr, "Tombstoning %d duplicates with %s=true\n", len(tombs), dedup.TombstoneKey)
+		}
 	}
 
-	client, err := pc.NewClient(ctx, pcCfg)
-	if err != nil {
-		return fmt.Errorf("failed to connect to Pinecone: %w", err)
-	}
-	defer func() { _ = client.Close() }()
+	files.queued(uploadVectors)
 
 	// Create ingestion pipeline
 	ingestCfg := ingest.Config{
 		BatchSize: batchSize,
 		Workers:   workers,
+		Adaptive: ingest.AdaptiveConfig{
+			Enabled:       adaptive,
+			MinBatchSize:  minBatchSize,
+			TargetLatency: targetLatency,
+		},
+		RateLimit: rateLimit,
+		OnBatch:   files.observe,
 	}
 
 	pipeline := ingest.NewPipeline(client, ingestCfg)
@@ -194,29 +372,141 @@ func runSync(cmd *cobra.Command, args []string) error {
 	fmt.Fprintln(os.Stderr, "Starting upload...")
 	stats, err := pipeline.IngestVectors(ctx, uploadVectors, progressFn)
 	if err != nil {
-		return fmt.Errorf("ingestion failed: %w", err)
+		return nil, fmt.Errorf("ingestion failed: %w", err)
 	}
 
 	_ = bar.Finish()
 	fmt.Fprintln(os.Stderr)
 
+	ingest.MarkSeen(uploadVectors, seen, namespace, pipeline.FailedIDs())
+	flushSeen(seen, seenPath)
+
 	// Print summary
-	printSyncSummary(stats, verbose)
+	printSyncSummary(stats, adaptive, verbose)
+	job.detail("uploaded", stats.UploadedVectors)
+	job.detail("failed", stats.FailedVectors)
+	job.detail("files", len(paths))
+
+	files.finish()
+	if err := files.write(fileManifestPath); err != nil {
+		return nil, err
+	}
+
+	// Verification phase: batches that reported failure are already
+	// counted, so only vectors the index accepted are checked
+	if verify && stats.UploadedVectors > 0 {
+		failed := make(map[string]bool)
+		for _, id := range pipeline.FailedIDs() {
+			failed[id] = true
+		}
+		accepted := make([]types.Vector, 0, len(uploadVectors)-len(failed))
+		for _, v := range uploadVectors {
+			if !failed[v.ID] {
+				accepted = append(accepted, v)
+			}
+		}
+
+		verifyCfg := ingest.DefaultVerifyConfig()
+		verifyCfg.SampleSize = verifySample
+		verifyCfg.RetryDelay = verifyDelay
+
+		fmt.Fprintln(os.Stderr, "Verifying upload...")
+		report, err := ingest.Verify(ctx, client, accepted, verifyCfg)
+		if err != nil {
+			return nil, fmt.Errorf("verification failed: %w", err)
+		}
+		printVerifyReport(report, verbose)
+
+		if !report.OK() {
+			return nil, errs.Wrap(errs.ErrPartialFailure, fmt.Errorf("%d of %d verified vectors missing or mismatched in the index",
+				report.Failed(), report.Checked))
+		}
+	}
+
+	if loadErrors > 0 && stats.FailedVectors == 0 {
+		return nil, errs.Wrap(errs.ErrPartialFailure, fmt.Errorf("%d of %d files could not be read", loadErrors, len(paths)))
+	}
 
 	if stats.FailedVectors > 0 {
-		return fmt.Errorf("%d vectors failed to upload", stats.FailedVectors)
+		failErr := fmt.Errorf("%d vectors failed to upload", stats.FailedVectors)
+		if stats.UploadedVectors > 0 {
+			return nil, errs.Wrap(errs.ErrPartialFailure, failErr)
+		}
+		return nil, errs.Wrap(errs.ErrBackend, failErr)
+	}
+
+	return stats, nil
+}
+
+// writeRemovalManifest writes the dedup removal manifest to path.
+func writeRemovalManifest(path string, removed []types.Removal) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %w", errs.Wrap(errs.ErrConfig, err))
+	}
+	if err := dedup.WriteManifest(f, removed); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write manifest: %w", err)
 	}
+	return f.Close()
+}
 
-	return nil
+func printValidationReport(r ingest.ValidationReport, dimension int) {
+	fmt.Fprintf(os.Stderr, "Validation complete: %d checked, %d skipped, %d normalized (dimension %d)\n",
+		r.Checked, r.Skipped, r.Normalized, dimension)
+	for _, v := range []ingest.Violation{ingest.ViolationNaN, ingest.ViolationInf, ingest.ViolationDimension, ingest.ViolationZero} {
+		if n := r.Violations[v]; n > 0 {
+			fmt.Fprintf(os.Stderr, "  %-10s %d\n", v+":", n)
+		}
+	}
 }
 
-func printSyncSummary(stats *ingest.Stats, verbose bool) {
+// verifyReportLimit is how many problems printVerifyReport lists unless
+// verbose.
+const verifyReportLimit = 10
+
+func printVerifyReport(r *ingest.VerifyReport, verbose bool) {
+	fmt.Println("=== Verification ===")
+	fmt.Println()
+	fmt.Printf("Vectors checked:     %d of %d\n", r.Checked, r.Population)
+	fmt.Printf("Missing:             %d\n", len(r.Missing))
+	fmt.Printf("Mismatched:          %d\n", len(r.Mismatched))
+
+	shown := 0
+	more := func() bool {
+		shown++
+		return verbose || shown <= verifyReportLimit
+	}
+	for _, id := range r.Missing {
+		if more() {
+			fmt.Printf("  %s: missing\n", id)
+		}
+	}
+	for _, m := range r.Mismatched {
+		if more() {
+			fmt.Printf("  %s: %s: %s\n", m.ID, m.Field, m.Detail)
+		}
+	}
+	if hidden := shown - verifyReportLimit; !verbose && hidden > 0 {
+		fmt.Printf("  ... and %d more (use --verbose to list all)\n", hidden)
+	}
+	fmt.Println()
+}
+
+func printSyncSummary(stats *ingest.Stats, adaptive, verbose bool) {
 	fmt.Println()
 	fmt.Println("=== Sync Complete ===")
 	fmt.Println()
 	fmt.Printf("Vectors uploaded:    %d\n", stats.UploadedVectors)
 	fmt.Printf("Vectors failed:      %d\n", stats.FailedVectors)
 	fmt.Printf("Batches processed:   %d\n", stats.BatchesProcessed)
+	if stats.ThrottledBatches > 0 {
+		fmt.Printf("Batches throttled:   %d\n", stats.ThrottledBatches)
+	}
+	if adaptive {
+		fmt.Printf("Final batch size:    %d\n", stats.BatchSize)
+		fmt.Printf("Final workers:       %d\n", stats.Workers)
+	}
 	fmt.Printf("Duration:            %v\n", stats.Duration().Round(time.Millisecond))
 	fmt.Printf("Throughput:          %.0f vectors/sec\n", stats.VectorsPerSecond())
 	fmt.Println()
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/ingest"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/schollz/progressbar/v3"
+)
+
+// syncFiles tracks per-file results across a sync of one or more input
+// files. Vectors are attributed to the last file their ID was read from,
+// matching which copy an upsert keeps.
+type syncFiles struct {
+	results []ingest.FileResult
+	source  map[string]int
+
+	mu       sync.Mutex
+	failed   map[string]bool
+	uploaded map[string]bool
+	first    []time.Time
+	last     []time.Time
+}
+
+// loadSyncFiles reads paths with up to workers files in flight and returns
+// their vectors in path order. A file that cannot be read is recorded in
+// its result and skipped.
+func loadSyncFiles(paths []string, workers int) ([]types.Vector, *syncFiles) {
+	f := &syncFiles{
+		results:  make([]ingest.FileResult, len(paths)),
+		source:   make(map[string]int),
+		failed:   make(map[string]bool),
+		uploaded: make(map[string]bool),
+		first:    make([]time.Time, len(paths)),
+		last:     make([]time.Time, len(paths)),
+	}
+	perFile := make([][]types.Vector, len(paths))
+
+	var bar *progressbar.ProgressBar
+	if len(paths) > 1 {
+		bar = progressbar.NewOptions(len(paths),
+			progressbar.OptionSetDescription("Loading"),
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionShowCount(),
+			progressbar.OptionSetItsString("files"),
+			progressbar.OptionFullWidth(),
+		)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(1, workers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				start := time.Now()
+				vectors, err := loadVectorsFromFile(paths[i])
+				f.results[i] = ingest.FileResult{
+					Path:   paths[i],
+					Read:   len(vectors),
+					LoadMS: time.Since(start).Milliseconds(),
+				}
+				if err != nil {
+					f.results[i].Error = err.Error()
+					vectors = nil
+				}
+				perFile[i] = vectors
+				if bar != nil {
+					_ = bar.Add(1)
+				}
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if bar != nil {
+		_ = bar.Finish()
+		fmt.Fprintln(os.Stderr)
+	}
+
+	var all []types.Vector
+	for i, vectors := range perFile {
+		for _, v := range vectors {
+			f.source[v.ID] = i
+		}
+		all = append(all, vectors...)
+	}
+	return all, f
+}
+
+// reportLoadErrors warns about each file that could not be read and
+// returns how many there were.
+func (f *syncFiles) reportLoadErrors() int {
+	n := 0
+	for _, r := range f.results {
+		if r.Error != "" {
+			n++
+			fmt.Fprintf(os.Stderr, "Warning: failed to read %s: %s\n", r.Path, r.Error)
+		}
+	}
+	return n
+}
+
+// queued records which vectors survived validation and dedup, so the
+// rest count as dropped.
+func (f *syncFiles) queued(vectors []types.Vector) {
+	ids := make(map[string]bool, len(vectors))
+	for _, v := range vectors {
+		ids[v.ID] = true
+	}
+	kept := make([]int, len(f.results))
+	for id := range ids {
+		kept[f.source[id]]++
+	}
+	for i := range f.results {
+		if f.results[i].Error == "" {
+			f.results[i].Dropped = f.results[i].Read - kept[i]
+		}
+	}
+}
+
+// observe is the pipeline's OnBatch callback.
+func (f *syncFiles) observe(batch []types.Vector, start time.Time, err error) {
+	end := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, v := range batch {
+		i := f.source[v.ID]
+		if f.first[i].IsZero() || start.Before(f.first[i]) {
+			f.first[i] = start
+		}
+		if end.After(f.last[i]) {
+			f.last[i] = end
+		}
+		if err != nil {
+			f.failed[v.ID] = true
+		} else {
+			f.uploaded[v.ID] = true
+		}
+	}
+}
+
+// finish tallies uploads per file. A vector sent more than once counts
+// as failed if any copy failed.
+func (f *syncFiles) finish() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, i := range f.source {
+		switch {
+		case f.failed[id]:
+			f.results[i].Failed++
+		case f.uploaded[id]:
+			f.results[i].Uploaded++
+		}
+	}
+	for i := range f.results {
+		if !f.first[i].IsZero() {
+			f.results[i].UploadMS = f.last[i].Sub(f.first[i]).Milliseconds()
+		}
+	}
+}
+
+// write saves the file manifest to path, if set.
+func (f *syncFiles) write(path string) error {
+	if path == "" {
+		return nil
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file manifest: %w", errs.Wrap(errs.ErrConfig, err))
+	}
+	if err := ingest.WriteFileManifest(out, f.results); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to write file manifest: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote file manifest to %s\n", path)
+	return nil
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+	"github.com/Siddhant-K-code/distill/pkg/metrics"
+	"github.com/Siddhant-K-code/distill/pkg/pipeline"
+	"github.com/Siddhant-K-code/distill/pkg/usage"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var telemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Inspect anonymized usage statistics",
+	Long: `Distill can send anonymized usage statistics to help maintainers decide
+which backends and providers to prioritize. They are off unless you opt in
+with telemetry.usage.enabled: true.`,
+}
+
+var telemetryShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show whether usage statistics are sent and exactly what they contain",
+	Long: `Prints whether usage statistics are sent, the payload schema, and an
+example payload built from the current configuration.
+
+A payload holds the version, OS and architecture, backend and embedding
+provider types, and request and chunk counts. It never holds text,
+queries, embeddings, keys, hostnames, or identifiers.
+
+Statistics are off unless telemetry.usage.enabled is true. Setting
+DO_NOT_TRACK turns them off, and binaries built with -tags no_telemetry
+cannot send them at all.
+
+Example:
+  distill telemetry show
+  distill telemetry show --config /etc/distill/distill.yaml`,
+	RunE: runTelemetryShow,
+}
+
+func init() {
+	rootCmd.AddCommand(telemetryCmd)
+	telemetryCmd.AddCommand(telemetryShowCmd)
+}
+
+func usageConfig() usage.Config {
+	return usage.Config{
+		Enabled:  viper.GetBool("telemetry.usage.enabled"),
+		Endpoint: viper.GetString("telemetry.usage.endpoint"),
+		Interval: viper.GetDuration("telemetry.usage.interval"),
+	}
+}
+
+// usageProvider names the embedding provider type for a ping, or "" when
+// no provider is configured.
+func usageProvider(name string, configured bool) string {
+	if !configured {
+		return ""
+	}
+	if name == "" {
+		return string(embedding.ProviderOpenAI)
+	}
+	return name
+}
+
+// startUsage starts the usage statistics ping for a server if the user
+// opted in; the returned sender is nil otherwise.
+func startUsage(command, backend, provider string, m *metrics.Metrics) *usage.Sender {
+	return usage.Start(usageConfig(), usage.NewPayload(pipeline.Version(), command, backend, provider), func() usage.Counts {
+		requests, in, out := m.Totals()
+		return usage.Counts{Requests: requests, ChunksIn: in, ChunksOut: out}
+	})
+}
+
+func runTelemetryShow(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	on, why := usage.Status(usageConfig())
+	state := "off"
+	if on {
+		state = "on"
+	}
+	fmt.Fprintf(out, "Usage statistics: %s, %s\n\n", state, why)
+
+	fmt.Fprintf(out, "Payload schema %d, sent as JSON:\n\n", usage.SchemaVersion)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tTYPE\tDESCRIPTION")
+	for _, f := range usage.Schema {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Name, f.Type, f.Description)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	example := usage.NewPayload(pipeline.Version(), "serve", viper.GetString("retriever.backend"),
+		usageProvider(viper.GetString("embedding.provider"), true))
+	example.PeriodHours = usageConfig().Interval.Hours()
+	if example.PeriodHours == 0 {
+		example.PeriodHours = 24
+	}
+	example.Requests, example.ChunksIn, example.ChunksOut = 1200, 60000, 9600
+	data, err := json.MarshalIndent(example, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nExample for this configuration:\n\n%s\n", data)
+	if !on {
+		fmt.Fprintln(os.Stderr, "\nTo opt in, set telemetry.usage.enabled: true in distill.yaml.")
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/tuner"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addTuningFlags adds the online tuner flags to serve.
+func addTuningFlags(cmd *cobra.Command) {
+	def := tuner.DefaultConfig()
+	cmd.Flags().Bool("online-tuning", false, "Tune threshold and lambda per namespace from /v1/feedback reports")
+	cmd.Flags().Float64("tuning-fraction", def.Fraction, "Share of /v1/retrieve requests served with a perturbed threshold or lambda")
+	cmd.Flags().Int("tuning-min-feedback", def.MinFeedback, "Feedback reports each setting needs before the tuner compares them")
+	cmd.Flags().Float64("tuning-min-lift", def.MinLift, "How much higher a setting's mean reward must be to replace the current one")
+	cmd.Flags().Duration("tuning-feedback-window", def.FeedbackWindow, "How long after a request its feedback is accepted")
+
+	_ = viper.BindPFlag("tuning.enabled", cmd.Flags().Lookup("online-tuning"))
+	_ = viper.BindPFlag("tuning.fraction", cmd.Flags().Lookup("tuning-fraction"))
+	_ = viper.BindPFlag("tuning.min_feedback", cmd.Flags().Lookup("tuning-min-feedback"))
+	_ = viper.BindPFlag("tuning.min_lift", cmd.Flags().Lookup("tuning-min-lift"))
+	_ = viper.BindPFlag("tuning.feedback_window", cmd.Flags().Lookup("tuning-feedback-window"))
+}
+
+// newTuner returns the online tuner the flags ask for, starting from
+// cfg's threshold and lambda, or nil if tuning is off.
+func newTuner(cfg contextlab.BrokerConfig) (*tuner.Tuner, error) {
+	if !viper.GetBool("tuning.enabled") {
+		return nil, nil
+	}
+	tc := tuner.DefaultConfig()
+	tc.Fraction = viper.GetFloat64("tuning.fraction")
+	tc.MinFeedback = viper.GetInt("tuning.min_feedback")
+	tc.MinLift = viper.GetFloat64("tuning.min_lift")
+	tc.FeedbackWindow = viper.GetDuration("tuning.feedback_window")
+	if tc.Fraction <= 0 || tc.Fraction > 1 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--tuning-fraction must be in (0, 1], got %v", tc.Fraction))
+	}
+	if !cfg.EnableMMR {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("--online-tuning needs MMR enabled, since it tunes lambda"))
+	}
+	return tuner.New(tc, tuner.Params{Threshold: cfg.ClusterThreshold, Lambda: cfg.MMRLambda}), nil
+}
+
+// tune applies the tuner's settings for req's namespace and returns the
+// feedback ID, or "" when the tuner is off or the request set threshold
+// or lambda itself.
+func (s *Server) tune(req *RetrieveRequest, retrievalReq *types.RetrievalRequest) string {
+	if s.tuner == nil || req.Threshold > 0 || req.Lambda > 0 {
+		return ""
+	}
+	a := s.tuner.Choose(req.Namespace)
+	if a.ID == "" {
+		return ""
+	}
+	retrievalReq.Threshold = a.Threshold
+	retrievalReq.Lambda = a.Lambda
+	s.metrics.RecordTunerServed(req.Namespace, a.Arm)
+	if a.Arm == tuner.ArmIncumbent {
+		s.metrics.RecordTunerIncumbent(req.Namespace, a.Threshold, a.Lambda, false)
+	}
+	return a.ID
+}
+
+// FeedbackRequest is the JSON request body for /v1/feedback. Set reward
+// (0-1) or cited, the returned chunk IDs the model used.
+type FeedbackRequest struct {
+	FeedbackID string   `json:"feedback_id"`
+	Reward     *float64 `json:"reward,omitempty"`
+	Cited      []string `json:"cited,omitempty"`
+}
+
+// FeedbackResponse is the JSON response for /v1/feedback.
+type FeedbackResponse struct {
+	Reward  float64 `json:"reward"`
+	Adopted bool    `json:"adopted,omitempty"`
+}
+
+func (s *Server) handleFeedback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.tuner == nil {
+		http.Error(w, "Online tuning is not enabled (start the server with --online-tuning)", http.StatusNotFound)
+		return
+	}
+
+	var req FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.FeedbackID == "" {
+		http.Error(w, "'feedback_id' is required", http.StatusBadRequest)
+		return
+	}
+	if req.Reward == nil && req.Cited == nil {
+		http.Error(w, "One of 'reward' or 'cited' is required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := s.tuner.Feedback(req.FeedbackID, tuner.Feedback{Reward: req.Reward, Cited: req.Cited})
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, tuner.ErrUnknownFeedback) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.metrics.RecordTunerFeedback(res.Namespace, res.Arm, res.Reward)
+	if res.Adopted {
+		s.metrics.RecordTunerIncumbent(res.Namespace, res.Incumbent.Threshold, res.Incumbent.Lambda, true)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(FeedbackResponse{Reward: res.Reward, Adopted: res.Adopted})
+}
+
+// TunerRequest is the JSON request body for POST /v1/tuner. Enabled
+// switches exploration on or off; Reset forgets what was learned for a
+// namespace, or for all of them with "*".
+type TunerRequest struct {
+	Enabled *bool   `json:"enabled,omitempty"`
+	Reset   *string `json:"reset,omitempty"`
+}
+
+// TunerResponse is the JSON response for /v1/tuner.
+type TunerResponse struct {
+	Enabled    bool                   `json:"enabled"`
+	Base       TunerParamsResponse    `json:"base"`
+	Pending    int                    `json:"pending"`
+	Namespaces []TunerNamespaceStatus `json:"namespaces"`
+}
+
+// TunerParamsResponse is a threshold and lambda.
+type TunerParamsResponse struct {
+	Threshold float64 `json:"threshold"`
+	Lambda    float64 `json:"lambda"`
+}
+
+// TunerNamespaceStatus describes one namespace's settings. The first arm
+// is the incumbent.
+type TunerNamespaceStatus struct {
+	Namespace string           `json:"namespace"`
+	Adoptions int              `json:"adoptions"`
+	Arms      []TunerArmStatus `json:"arms"`
+}
+
+// TunerArmStatus describes one candidate setting.
+type TunerArmStatus struct {
+	Name string `json:"name"`
+	TunerParamsResponse
+	Served     int     `json:"served"`
+	Feedback   int     `json:"feedback"`
+	MeanReward float64 `json:"mean_reward"`
+}
+
+func (s *Server) handleTuner(w http.ResponseWriter, r *http.Request) {
+	if s.tuner == nil {
+		http.Error(w, "Online tuning is not enabled (start the server with --online-tuning)", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req TunerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Enabled != nil {
+			s.tuner.SetEnabled(*req.Enabled)
+			s.metrics.SetTunerEnabled(*req.Enabled)
+		}
+		if req.Reset != nil {
+			s.tuner.Reset(*req.Reset)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	st := s.tuner.Status()
+	resp := TunerResponse{
+		Enabled:    st.Enabled,
+		Base:       TunerParamsResponse(st.Base),
+		Pending:    st.Pending,
+		Namespaces: make([]TunerNamespaceStatus, len(st.Namespaces)),
+	}
+	for i, ns := range st.Namespaces {
+		out := TunerNamespaceStatus{Namespace: ns.Namespace, Adoptions: ns.Adoptions}
+		for _, a := range ns.Arms {
+			out.Arms = append(out.Arms, TunerArmStatus{
+				Name:                a.Name,
+				TunerParamsResponse: TunerParamsResponse(a.Params),
+				Served:              a.Served,
+				Feedback:            a.Feedback,
+				MeanReward:          a.MeanReward,
+			})
+		}
+		resp.Namespaces[i] = out
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/dedup"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/ingest"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addWriteFlags adds the flags for serve's write endpoint.
+func addWriteFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("allow-writes", false, "Accept vectors on PUT /v1/vectors and write them to the backend")
+
+	_ = viper.BindPFlag("server.allow_writes", cmd.Flags().Lookup("allow-writes"))
+}
+
+// VectorsRequest is the JSON request body for PUT /v1/vectors. Vectors
+// are validated and deduplicated against each other as distill sync does
+// before they are written.
+type VectorsRequest struct {
+	Vectors   []VectorPayload `json:"vectors"`
+	Namespace string          `json:"namespace,omitempty"`
+
+	// Dedup drops vectors within Threshold cosine distance of another in
+	// the request (default true). Tombstone writes them with
+	// distill_duplicate metadata instead, as sync --tombstone does.
+	Dedup     *bool   `json:"dedup,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+	Tombstone bool    `json:"tombstone,omitempty"`
+
+	// Dimension is the expected vector length; 0 takes the first valid
+	// vector's. Normalize scales vectors to unit length. Strict rejects
+	// the request on the first invalid vector instead of skipping it.
+	Dimension int  `json:"dimension,omitempty"`
+	Normalize bool `json:"normalize,omitempty"`
+	Strict    bool `json:"strict,omitempty"`
+}
+
+// VectorPayload is one vector in a VectorsRequest, in the JSONL format
+// distill sync reads.
+type VectorPayload struct {
+	ID       string                 `json:"id"`
+	Values   []float32              `json:"values"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// VectorsResponse is the JSON response for PUT /v1/vectors.
+type VectorsResponse struct {
+	Received int `json:"received"`
+
+	// Repeated counts vectors superseded by a later one with the same ID,
+	// Invalid those skipped by validation, Seen those whose content was
+	// already written to the namespace (--seen-filter), and Duplicates
+	// those removed by dedup, listed in Removed.
+	Repeated   int                        `json:"repeated,omitempty"`
+	Invalid    int                        `json:"invalid,omitempty"`
+	Violations map[ingest.Violation]int64 `json:"violations,omitempty"`
+	Normalized int64                      `json:"normalized,omitempty"`
+	Seen       int                        `json:"seen,omitempty"`
+	Duplicates int                        `json:"duplicates,omitempty"`
+	Removed    []types.Removal            `json:"removed,omitempty"`
+
+	// Written counts vectors written, including Tombstoned duplicates.
+	Written    int   `json:"written"`
+	Tombstoned int   `json:"tombstoned,omitempty"`
+	LatencyMs  int64 `json:"latency_ms"`
+}
+
+func (s *Server) handleVectors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.writer == nil {
+		http.Error(w, "Writes are not enabled (start the server with --allow-writes on a backend that supports them)", http.StatusNotFound)
+		return
+	}
+	start := time.Now()
+
+	limitBody(w, r, s.limits.MaxInputBytes, s.metrics, "/v1/vectors")
+	var req VectorsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, "JSON", err)
+		return
+	}
+	if len(req.Vectors) == 0 {
+		http.Error(w, "'vectors' is required", http.StatusBadRequest)
+		return
+	}
+	for _, v := range req.Vectors {
+		if v.ID == "" {
+			http.Error(w, "Every vector needs an 'id'", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Threshold < 0 || req.Dimension < 0 {
+		http.Error(w, "'threshold' and 'dimension' must be non-negative", http.StatusBadRequest)
+		return
+	}
+	if err := s.checkVectorLimits(req.Vectors); err != nil {
+		writeTooLarge(w, s.metrics, "/v1/vectors", err)
+		return
+	}
+
+	vectors, repeated := latestVectors(req.Vectors)
+	resp := VectorsResponse{Received: len(req.Vectors), Repeated: repeated}
+
+	validator := ingest.NewValidator(ingest.ValidationConfig{
+		Dimension: req.Dimension,
+		Normalize: req.Normalize,
+		Strict:    req.Strict,
+	})
+	vectors, err := validator.Filter(vectors)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	report := validator.Report()
+	resp.Invalid = int(report.Skipped)
+	resp.Normalized = report.Normalized
+	if len(report.Violations) > 0 {
+		resp.Violations = report.Violations
+	}
+
+	vectors, resp.Seen = ingest.SkipSeen(vectors, s.seen, req.Namespace)
+
+	ctx := r.Context()
+	upload := vectors
+	if req.Dedup == nil || *req.Dedup {
+		cfg := dedup.DefaultConfig()
+		if req.Threshold > 0 {
+			cfg.Threshold = req.Threshold
+		}
+		result, err := dedup.NewEngine(cfg).Deduplicate(ctx, vectors)
+		if err != nil {
+			if !writeInterrupted(w, err) {
+				http.Error(w, fmt.Sprintf("Deduplication failed: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+		upload = result.UniqueVectors
+		resp.Duplicates = result.DuplicateCount
+		resp.Removed = result.Removed
+		if req.Tombstone {
+			tombs := dedup.Tombstones(vectors, result.Removed)
+			upload = append(upload, tombs...)
+			resp.Tombstoned = len(tombs)
+		}
+	}
+
+	if len(upload) > 0 {
+		if err := s.writer.Upsert(ctx, upload, req.Namespace); err != nil {
+			if writeInterrupted(w, err) {
+				return
+			}
+			if errors.Is(err, errs.ErrConfig) {
+				http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Write failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		ingest.MarkSeen(upload, s.seen, req.Namespace, nil)
+		// Cached results may now be missing the new vectors
+		if s.caches != nil && s.caches.results != nil {
+			_ = s.caches.results.Clear(context.Background())
+		}
+	}
+	resp.Written = len(upload)
+	resp.LatencyMs = time.Since(start).Milliseconds()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// checkVectorLimits applies the input limits to a write: max_chunks caps
+// the number of vectors and max_input_bytes their values, at 4 bytes each.
+func (s *Server) checkVectorLimits(vectors []VectorPayload) error {
+	if limit := s.limits.MaxChunks; limit > 0 && len(vectors) > limit {
+		return &contextlab.LimitError{Limit: contextlab.LimitMaxChunks, Value: int64(len(vectors)), Max: int64(limit)}
+	}
+	var total int64
+	for _, v := range vectors {
+		if limit := s.limits.MaxDimension; limit > 0 && len(v.Values) > limit {
+			return &contextlab.LimitError{Limit: contextlab.LimitMaxDimension, Value: int64(len(v.Values)), Max: int64(limit)}
+		}
+		total += 4 * int64(len(v.Values))
+	}
+	if limit := s.limits.MaxInputBytes; limit > 0 && total > limit {
+		return &contextlab.LimitError{Limit: contextlab.LimitMaxInputBytes, Value: total, Max: limit}
+	}
+	return nil
+}
+
+// latestVectors converts payloads to vectors, keeping only the last
+// vector sent for each ID, as the upsert itself would, so repeats are not
+// mistaken for duplicates of themselves. Metadata keys with null values
+// are dropped, since Pinecone rejects them. It returns the vectors in
+// request order and how many repeats were dropped.
+func latestVectors(payloads []VectorPayload) ([]types.Vector, int) {
+	last := make(map[string]int, len(payloads))
+	for i, p := range payloads {
+		last[p.ID] = i
+	}
+	vectors := make([]types.Vector, 0, len(last))
+	for i, p := range payloads {
+		if last[p.ID] != i {
+			continue
+		}
+		metadata := make(map[string]interface{}, len(p.Metadata))
+		for k, v := range p.Metadata {
+			if v != nil {
+				metadata[k] = v
+			}
+		}
+		vectors = append(vectors, types.Vector{ID: p.ID, Values: p.Values, Metadata: metadata})
+	}
+	return vectors, len(payloads) - len(vectors)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/spf13/viper"
+)
+
+// warmScanTimeout bounds the startup scan, so a slow backend cannot
+// keep it running.
+const warmScanTimeout = time.Minute
+
+// warmScanNamespaces returns the namespaces the startup scan samples,
+// the default namespace when none are configured, and how many chunks
+// it samples from each. A zero sample disables the scan.
+func warmScanNamespaces() ([]string, int) {
+	namespaces := viper.GetStringSlice("retriever.warm_scan.namespaces")
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	return namespaces, viper.GetInt("retriever.warm_scan.sample")
+}
+
+// warmScan samples namespaces in the background so the broker can reject
+// requests that cannot match them, and reports each fingerprint on
+// stderr. Requests are served meanwhile, unchecked until their
+// namespace's sample is in.
+func warmScan(broker *contextlab.Broker, embedder retriever.EmbeddingProvider, namespaces []string, n int) {
+	if n <= 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), warmScanTimeout)
+		defer cancel()
+		taken, err := broker.WarmScan(ctx, namespaces, n)
+		for _, fp := range taken {
+			fmt.Fprintf(os.Stderr, "Warm scan: %s: %d chunks, %d dimensions, nearest-neighbor similarity p10/p50/p90 %.2f/%.2f/%.2f\n",
+				namespaceName(fp.Namespace), fp.SampleSize, fp.Dimension, fp.NearestScore.P10, fp.NearestScore.P50, fp.NearestScore.P90)
+			if embedder != nil && embedder.Dimension() != fp.Dimension {
+				fmt.Fprintf(os.Stderr, "Warning: the embedding provider returns %d dimensions but %s holds %d; text queries to it will be rejected (check embedding.model)\n",
+					embedder.Dimension(), namespaceName(fp.Namespace), fp.Dimension)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warm scan: %v\n", err)
+		}
+	}()
+}
+
+// namespaceName names a namespace in a message.
+func namespaceName(ns string) string {
+	if ns == "" {
+		return "default namespace"
+	}
+	return fmt.Sprintf("namespace %q", ns)
+}
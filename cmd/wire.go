@@ -0,0 +1,675 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/grpcapi"
+	"github.com/Siddhant-K-code/distill/pkg/grpcapi/distillv1"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+	"google.golang.org/protobuf/proto"
+)
+
+// contentTypeProtobuf is the binary alternative to JSON on /v1/dedupe and
+// /v1/retrieve. Bodies are the distill.v1 messages of
+// proto/distill/v1/distill.proto, which carry embeddings as packed
+// floats rather than decimal text.
+const contentTypeProtobuf = "application/x-protobuf"
+
+// contentTypeMsgpack is the MessagePack alternative to JSON on the same
+// endpoints. Bodies have JSON's field names and shapes, with embeddings
+// as float32s; application/x-msgpack is accepted too.
+const contentTypeMsgpack = "application/msgpack"
+
+// isMediaType reports whether mt is one of types, treating
+// application/x-msgpack as contentTypeMsgpack.
+func isMediaType(mt string, types ...string) bool {
+	if mt == "application/x-msgpack" {
+		mt = contentTypeMsgpack
+	}
+	for _, t := range types {
+		if mt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// isProtobuf reports whether r's body is protobuf.
+func isProtobuf(r *http.Request) bool {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && isMediaType(mt, contentTypeProtobuf)
+}
+
+// isMsgpack reports whether r's body is MessagePack.
+func isMsgpack(r *http.Request) bool {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && isMediaType(mt, contentTypeMsgpack)
+}
+
+// Response types writeResponse offers, in order of preference when an
+// Accept header ranks them the same. JSON is the default.
+var responseTypes = []string{contentTypeProtobuf, contentTypeMsgpack, "application/json"}
+
+// negotiate returns the offer r's Accept header gives the highest
+// quality. The last offer is the default: wildcards such as */* select
+// only it, and it is returned without an Accept header or when no offer
+// is acceptable. Each offer's quality comes from the most specific range
+// matching it, so "application/msgpack;q=0.5, */*" prefers the default.
+// Ties go to the offer named exactly, then to the earlier offer.
+func negotiate(r *http.Request, offers []string) string {
+	def := offers[len(offers)-1]
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return def
+	}
+	best, bestQ, bestSpec := def, 0.0, -1
+	for _, offer := range offers {
+		q, spec := acceptQuality(accept, offer)
+		if offer != def && spec < 2 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && q > 0 && spec > bestSpec) {
+			best, bestQ, bestSpec = offer, q, spec
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality accept gives contentType, and the
+// specificity of the range it came from: 2 for the type itself, 1 for
+// type/*, 0 for */*, and -1 when no range matches.
+func acceptQuality(accept, contentType string) (float64, int) {
+	q, spec := 0.0, -1
+	major, _, _ := strings.Cut(contentType, "/")
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		s := -1
+		switch {
+		case isMediaType(mt, contentType):
+			s = 2
+		case mt == major+"/*":
+			s = 1
+		case mt == "*/*":
+			s = 0
+		}
+		if s <= spec {
+			continue
+		}
+		v := 1.0
+		if qs, ok := params["q"]; ok {
+			if v, err = strconv.ParseFloat(qs, 64); err != nil || v < 0 || v > 1 {
+				continue
+			}
+		}
+		q, spec = v, s
+	}
+	return q, spec
+}
+
+// readProtobuf decodes r's body into m, writing a 400 and returning false
+// when it is not a valid m.
+func readProtobuf(w http.ResponseWriter, r *http.Request, m proto.Message) bool {
+	data, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = proto.Unmarshal(data, m)
+	}
+	if err != nil {
+		writeBodyError(w, "protobuf", err)
+		return false
+	}
+	return true
+}
+
+// newMsgpackDecoder returns a decoder mapping MessagePack onto the JSON
+// request types: keys are json tag names, and values decoded into an
+// interface{} are what encoding/json would give, but with integers as
+// int64 or uint64.
+func newMsgpackDecoder(r io.Reader) *msgpack.Decoder {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	dec.UseLooseInterfaceDecoding(true)
+	return dec
+}
+
+// marshalMsgpack encodes v with the same mapping, so clients decode
+// responses into their JSON types. Embeddings are 5-byte float32s.
+func marshalMsgpack(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readMsgpack decodes r's MessagePack body into v, writing a 400 and
+// returning false when it is not valid.
+func readMsgpack(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := newMsgpackDecoder(r.Body).Decode(v)
+	if err == nil {
+		err = checkBodyEncoding(v)
+	}
+	if err != nil {
+		writeBodyError(w, "MessagePack", err)
+		return false
+	}
+	return true
+}
+
+// readBody decodes r's JSON or MessagePack body into v; see readJSON and
+// readMsgpack.
+func readBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if isMsgpack(r) {
+		return readMsgpack(w, r, v)
+	}
+	return readJSON(w, r, v)
+}
+
+// writeResponse writes v as JSON, as MessagePack, or as the protobuf
+// message toProto converts it to, whichever r's Accept header ranks
+// highest.
+func writeResponse(w http.ResponseWriter, r *http.Request, v interface{}, toProto func() (proto.Message, error)) {
+	switch negotiate(r, responseTypes) {
+	case contentTypeMsgpack:
+		data, err := marshalMsgpack(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Encoding MessagePack failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentTypeMsgpack)
+		_, _ = w.Write(data)
+		return
+	case "application/json":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+		return
+	}
+	m, err := toProto()
+	var data []byte
+	if err == nil {
+		data, err = proto.Marshal(m)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Encoding protobuf failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeProtobuf)
+	_, _ = w.Write(data)
+}
+
+// Embedding encodings a /v1/dedupe or /v1/retrieve body names in its
+// encoding field, for clients that cannot send float32 arrays.
+const (
+	// encodingFloat32 is the default: embeddings are arrays of numbers.
+	encodingFloat32 = "float32"
+
+	// encodingFloat64 is also arrays of numbers, from clients that hold
+	// float64 vectors. Values are rounded to float32; ones out of its
+	// range are rejected.
+	encodingFloat64 = "float64"
+
+	// encodingBase64 is base64 strings of little-endian float32s, which
+	// are smaller than decimal text and exact.
+	encodingBase64 = "base64"
+)
+
+// checkEncoding reports an error unless encoding names an embedding
+// encoding.
+func checkEncoding(encoding string) error {
+	switch encoding {
+	case "", encodingFloat32, encodingFloat64, encodingBase64:
+		return nil
+	}
+	return fmt.Errorf("unknown encoding %q (supported: %s, %s, %s)", encoding, encodingFloat32, encodingFloat64, encodingBase64)
+}
+
+// encodedBody is a request body naming its embeddings' encoding.
+type encodedBody interface {
+	embeddingEncoding() string
+	bodyEmbeddings() []Embedding
+}
+
+func (r *DedupeRequest) embeddingEncoding() string   { return r.Encoding }
+func (r *RetrieveRequest) embeddingEncoding() string { return r.Encoding }
+
+func (r *DedupeRequest) bodyEmbeddings() []Embedding {
+	out := make([]Embedding, len(r.Chunks))
+	for i, c := range r.Chunks {
+		out[i] = c.Embedding
+	}
+	return out
+}
+
+func (r *RetrieveRequest) bodyEmbeddings() []Embedding {
+	return append([]Embedding{r.QueryEmbedding}, r.QueryEmbeddings...)
+}
+
+// Embedding is an embedding in a request body. It decodes from an array
+// of numbers or from a base64 string of little-endian float32s, in one
+// pass, and remembers which so checkBodyEncoding can hold it to the
+// body's encoding field.
+type Embedding struct {
+	Values []float32
+	base64 bool
+}
+
+// IsZero reports whether e has no values, so omitzero leaves it out.
+func (e Embedding) IsZero() bool { return len(e.Values) == 0 }
+
+// MarshalJSON encodes e as an array of numbers.
+func (e Embedding) MarshalJSON() ([]byte, error) { return json.Marshal(e.Values) }
+
+// EncodeMsgpack encodes e as an array of numbers.
+func (e Embedding) EncodeMsgpack(enc *msgpack.Encoder) error { return enc.Encode(e.Values) }
+
+// UnmarshalJSON decodes an array of numbers or a base64 string.
+func (e *Embedding) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case string(data) == "null":
+		*e = Embedding{}
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		vec, err := decodeBase64(s)
+		if err != nil {
+			return err
+		}
+		*e = Embedding{Values: vec, base64: true}
+		return nil
+	case len(data) < 2 || data[0] != '[':
+		return fmt.Errorf("embedding: expected an array of numbers or a base64 string")
+	}
+	vec, err := parseFloats(data[1 : len(data)-1])
+	if err != nil {
+		return err
+	}
+	*e = Embedding{Values: vec}
+	return nil
+}
+
+// DecodeMsgpack decodes an array of numbers or a base64 string. Numbers
+// may be any MessagePack int or float, as clients often pack float64s.
+func (e *Embedding) DecodeMsgpack(dec *msgpack.Decoder) error {
+	c, err := dec.PeekCode()
+	if err != nil {
+		return err
+	}
+	if msgpcode.IsString(c) {
+		s, err := dec.DecodeString()
+		if err != nil {
+			return err
+		}
+		vec, err := decodeBase64(s)
+		if err != nil {
+			return err
+		}
+		*e = Embedding{Values: vec, base64: true}
+		return nil
+	}
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return fmt.Errorf("embedding: expected an array of numbers or a base64 string: %w", err)
+	}
+	if n < 0 {
+		*e = Embedding{}
+		return nil
+	}
+	// n is untrusted until the values are read
+	vec := make([]float32, 0, min(n, 1<<16))
+	for i := 0; i < n; i++ {
+		f, err := dec.DecodeFloat64()
+		if err != nil {
+			return fmt.Errorf("embedding: expected an array of numbers: %w", err)
+		}
+		if math.Abs(f) > math.MaxFloat32 {
+			return fmt.Errorf("embedding: value %g at %d is out of float32 range", f, i)
+		}
+		vec = append(vec, float32(f))
+	}
+	*e = Embedding{Values: vec}
+	return nil
+}
+
+// parseFloats parses the elements of a JSON array of numbers, which the
+// JSON decoder has already checked is well formed, narrowing them to
+// float32.
+func parseFloats(list []byte) ([]float32, error) {
+	list = bytes.TrimSpace(list)
+	if len(list) == 0 {
+		return []float32{}, nil
+	}
+	vec := make([]float32, 0, bytes.Count(list, []byte(","))+1)
+	for i := 0; len(list) > 0; i++ {
+		item := list
+		if j := bytes.IndexByte(list, ','); j >= 0 {
+			item, list = list[:j], list[j+1:]
+		} else {
+			list = nil
+		}
+		f, err := strconv.ParseFloat(string(bytes.TrimSpace(item)), 32)
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, fmt.Errorf("embedding: value %s at %d is out of float32 range", bytes.TrimSpace(item), i)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("embedding: expected an array of numbers")
+		}
+		vec = append(vec, float32(f))
+	}
+	return vec, nil
+}
+
+// embeddings converts v for code that takes plain vectors.
+func embeddings(v []Embedding) [][]float32 {
+	if v == nil {
+		return nil
+	}
+	out := make([][]float32, len(v))
+	for i, e := range v {
+		out[i] = e.Values
+	}
+	return out
+}
+
+// readJSON decodes r's body into v, writing a 400 and returning false when
+// it is not valid.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	data, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err == nil {
+		err = checkBodyEncoding(v)
+	}
+	if err != nil {
+		writeBodyError(w, "JSON", err)
+		return false
+	}
+	return true
+}
+
+// checkBodyEncoding checks the encoding field of v, if it has one, and
+// that each of its embeddings has the form the encoding declares: a
+// base64 string for base64, an array of numbers otherwise.
+func checkBodyEncoding(v interface{}) error {
+	b, ok := v.(encodedBody)
+	if !ok {
+		return nil
+	}
+	encoding := b.embeddingEncoding()
+	if err := checkEncoding(encoding); err != nil {
+		return err
+	}
+	if encoding == "" {
+		encoding = encodingFloat32
+	}
+	for _, e := range b.bodyEmbeddings() {
+		switch {
+		case e.Values == nil:
+		case e.base64 && encoding != encodingBase64:
+			return fmt.Errorf("embedding is a base64 string, but encoding is %s; set \"encoding\": %q", encoding, encodingBase64)
+		case !e.base64 && encoding == encodingBase64:
+			return fmt.Errorf("embedding is an array of numbers, but encoding is %s", encodingBase64)
+		}
+	}
+	return nil
+}
+
+// decodeBase64 decodes a base64 string of little-endian float32s.
+func decodeBase64(s string) ([]float32, error) {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("embedding: %w", err)
+	}
+	if len(raw)%4 != 0 {
+		return nil, fmt.Errorf("embedding: %d bytes is not a whole number of float32s", len(raw))
+	}
+	vec := make([]float32, len(raw)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+	}
+	return vec, nil
+}
+
+// dedupeRequestFromProto converts a protobuf /v1/dedupe body.
+func dedupeRequestFromProto(pb *distillv1.DeduplicateRequest) DedupeRequest {
+	req := DedupeRequest{
+		Chunks:    make([]DedupeChunk, len(pb.GetChunks())),
+		Threshold: pb.GetThreshold(),
+		Lambda:    pb.GetLambda(),
+		TargetK:   int(pb.GetTargetK()),
+		SessionID: pb.GetSessionId(),
+
+		TokenBudget: int(pb.GetTokenBudget()),
+		Model:       pb.GetModel(),
+		Compress:    compressRequestFromProto(pb.GetCompress()),
+		Redact:      pb.GetRedact(),
+		Options: DedupeOptions{
+			PreserveCachePrefix: pb.GetPreserveCachePrefix(),
+			MarkRepeats:         pb.GetMarkRepeats(),
+			ValidateEmbeddings:  pb.GetValidateEmbeddings(),
+			DedupHints:          pb.GetDedupHints(),
+			EmbeddingOptions: EmbeddingOptions{
+				IncludeEmbeddings:  pb.GetIncludeEmbeddings(),
+				EmbeddingDims:      int(pb.GetEmbeddingDims()),
+				EmbeddingReduction: pb.GetEmbeddingReduction(),
+			},
+		},
+	}
+	for i, c := range pb.GetChunks() {
+		req.Chunks[i] = DedupeChunk{
+			ID:           c.GetId(),
+			Text:         c.GetText(),
+			Embedding:    Embedding{Values: c.GetEmbedding()},
+			Score:        c.GetScore(),
+			CacheControl: c.GetCacheControl(),
+		}
+	}
+	return req
+}
+
+// dedupeResponseProto converts a /v1/dedupe response to protobuf.
+func dedupeResponseProto(resp DedupeResponse) (proto.Message, error) {
+	chunks := make([]*distillv1.Chunk, len(resp.Chunks))
+	for i, c := range resp.Chunks {
+		metadata, err := grpcapi.MetadataStruct(c.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %s metadata: %w", c.ID, err)
+		}
+		chunks[i] = &distillv1.Chunk{
+			Id:          c.ID,
+			Text:        c.Text,
+			Embedding:   c.Embedding,
+			Score:       c.Score,
+			ClusterId:   int32(c.ClusterID),
+			Metadata:    metadata,
+			AlreadySent: c.AlreadySent,
+		}
+	}
+	st := resp.Stats
+	return &distillv1.DeduplicateResponse{
+		Chunks: chunks,
+		Stats: &distillv1.DeduplicateStats{
+			InputCount:         int32(st.InputCount),
+			OutputCount:        int32(st.OutputCount),
+			ClusterCount:       int32(st.ClusterCount),
+			ReductionPct:       int32(st.ReductionPct),
+			LatencyMs:          st.LatencyMs,
+			CachePrefixFrozen:  st.CachePrefixFrozen,
+			CachePrefixTokens:  int32(st.CachePrefixTokens),
+			CachePrefixHash:    st.CachePrefixHash,
+			SuffixInputCount:   int32(st.SuffixInputCount),
+			SuffixOutputCount:  int32(st.SuffixOutputCount),
+			RepeatedCount:      int32(st.RepeatedCount),
+			EmbeddingsRepaired: int32(st.EmbeddingsRepaired),
+			MatrixOverflow:     st.MatrixOverflow,
+			Tokens:             int32(st.Tokens),
+			Compression:        compressionStatsProto(st.Compression),
+			Redacted:           int32(st.Redacted),
+		},
+	}, nil
+}
+
+// retrieveRequestFromProto converts a protobuf /v1/retrieve body.
+func retrieveRequestFromProto(pb *distillv1.RetrieveRequest) RetrieveRequest {
+	req := RetrieveRequest{
+		Query:              pb.GetQuery(),
+		QueryEmbedding:     Embedding{Values: pb.GetQueryEmbedding()},
+		Index:              pb.GetIndex(),
+		Namespace:          pb.GetNamespace(),
+		OverFetchK:         int(pb.GetTopK()),
+		TargetK:            int(pb.GetTargetK()),
+		Threshold:          pb.GetThreshold(),
+		Lambda:             pb.GetLambda(),
+		MinScore:           pb.GetMinScore(),
+		Queries:            pb.GetQueries(),
+		Combine:            pb.GetCombine(),
+		SessionID:          pb.GetSessionId(),
+		MarkRepeats:        pb.GetMarkRepeats(),
+		Exclude:            pb.GetExclude(),
+		DedupHints:         pb.GetDedupHints(),
+		Explain:            pb.GetExplain(),
+		Template:           pb.GetTemplate(),
+		ValidateEmbeddings: pb.GetValidateEmbeddings(),
+		Selection:          pb.GetSelection(),
+		Model:              pb.GetModel(),
+		Compress:           compressRequestFromProto(pb.GetCompress()),
+		Mode:               pb.GetMode(),
+		Redact:             pb.GetRedact(),
+		DeadlineMs:         int(pb.GetDeadlineMs()),
+		EmbeddingOptions: EmbeddingOptions{
+			IncludeEmbeddings:  pb.GetIncludeEmbeddings(),
+			EmbeddingDims:      int(pb.GetEmbeddingDims()),
+			EmbeddingReduction: pb.GetEmbeddingReduction(),
+		},
+	}
+	if pb.GetFilter() != nil {
+		req.Filter = pb.GetFilter().AsMap()
+	}
+	if id := pb.GetIdentity(); id != nil {
+		req.Identity = &IdentityRequest{User: id.GetUser(), Groups: id.GetGroups()}
+	}
+	for _, ns := range pb.GetNamespaces() {
+		req.Namespaces = append(req.Namespaces, NamespaceRequest{Name: ns.GetName(), TopK: int(ns.GetTopK())})
+	}
+	for _, e := range pb.GetQueryEmbeddings() {
+		req.QueryEmbeddings = append(req.QueryEmbeddings, Embedding{Values: e.GetValues()})
+	}
+	if e := pb.GetEnable(); e != nil {
+		req.Enable = &StageTogglesRequest{
+			Clustering:  e.Clustering,
+			MMR:         e.Mmr,
+			Compression: e.Compression,
+			Redaction:   e.Redaction,
+			Scoring:     e.Scoring,
+			Rerank:      e.Rerank,
+
+			Classification: e.Classification,
+		}
+	}
+	return req
+}
+
+// compressRequestFromProto converts pb, which may be nil.
+func compressRequestFromProto(pb *distillv1.CompressOptions) *CompressRequest {
+	if pb == nil {
+		return nil
+	}
+	return &CompressRequest{
+		Mode:              pb.GetMode(),
+		TargetReduction:   pb.GetTargetReduction(),
+		PreserveStructure: pb.PreserveStructure,
+		MergeTokens:       int(pb.GetMergeTokens()),
+	}
+}
+
+// compressionStatsProto converts s, which may be nil.
+func compressionStatsProto(s *CompressionStats) *distillv1.CompressionStats {
+	if s == nil {
+		return nil
+	}
+	return &distillv1.CompressionStats{
+		InputTokens:      int32(s.InputTokens),
+		OutputTokens:     int32(s.OutputTokens),
+		SavedTokens:      int32(s.SavedTokens),
+		ReductionPct:     s.ReductionPct,
+		ChunksCompressed: int32(s.Compressed),
+		ChunksMerged:     int32(s.Merged),
+	}
+}
+
+// retrieveResponseProto converts a /v1/retrieve response to protobuf.
+func retrieveResponseProto(resp RetrieveResponse) (proto.Message, error) {
+	chunks := make([]*distillv1.Chunk, len(resp.Chunks))
+	for i, c := range resp.Chunks {
+		metadata, err := grpcapi.MetadataStruct(c.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %s metadata: %w", c.ID, err)
+		}
+		chunks[i] = &distillv1.Chunk{
+			Id:          c.ID,
+			Text:        c.Text,
+			Embedding:   c.Embedding,
+			Score:       c.Score,
+			ClusterId:   int32(c.ClusterID),
+			Metadata:    metadata,
+			AlreadySent: c.AlreadySent,
+		}
+	}
+	st := resp.Stats
+	return &distillv1.RetrieveResponse{
+		Chunks: chunks,
+		Stats: &distillv1.RetrieveStats{
+			Retrieved:  int32(st.Retrieved),
+			Clustered:  int32(st.Clustered),
+			Returned:   int32(st.Returned),
+			Repeated:   int32(st.Repeated),
+			LatencyMs:  st.TotalLatencyMs,
+			CacheHit:   st.CacheHit,
+			Excluded:   int32(st.Excluded),
+			CacheMiss:  st.CacheMiss,
+			Model:      st.Model,
+			Tokens:     int32(st.Tokens),
+			CostUsd:    st.CostUSD,
+			BestEffort: st.BestEffort,
+			Notes:      st.Notes,
+
+			AclDenied:         int32(st.ACLDenied),
+			AclUnlabeled:      int32(st.ACLUnlabeled),
+			Compression:       compressionStatsProto(st.Compression),
+			Redacted:          int32(st.Redacted),
+			BudgetDropped:     int32(st.BudgetDropped),
+			Sparse:            st.Sparse,
+			Passthrough:       st.Passthrough,
+			Sensitivity:       st.Sensitivity,
+			InjectionFlagged:  int32(st.InjectionFlagged),
+			InjectionStripped: int32(st.InjectionStripped),
+			InjectionBlocked:  int32(st.InjectionBlocked),
+			GarbageDropped:    int32(st.GarbageDropped),
+		},
+		Rendered:   resp.Rendered,
+		FeedbackId: resp.FeedbackID,
+		Stages:     resp.Stages,
+	}, nil
+}
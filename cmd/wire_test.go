@@ -0,0 +1,540 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/grpcapi/distillv1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// roundTrip marshals m and unmarshals it into out, as a protobuf body
+// would travel.
+func roundTrip[T proto.Message](t *testing.T, m proto.Message, out T) T {
+	t.Helper()
+	data, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if err := proto.Unmarshal(data, out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	return out
+}
+
+func TestRetrieveRequestFromProto(t *testing.T) {
+	on, off := true, false
+	filter, _ := structpb.NewStruct(map[string]interface{}{"lang": "en"})
+	pb := &distillv1.RetrieveRequest{
+		Query:              "refunds",
+		QueryEmbedding:     []float32{1, 0},
+		Namespace:          "docs",
+		TopK:               40,
+		Threshold:          0.2,
+		Lambda:             0.7,
+		Filter:             filter,
+		MinScore:           0.1,
+		SessionId:          "s1",
+		Exclude:            []string{"x"},
+		IncludeEmbeddings:  true,
+		Explain:            true,
+		MarkRepeats:        true,
+		DedupHints:         true,
+		TargetK:            5,
+		EmbeddingDims:      64,
+		EmbeddingReduction: "truncate",
+		Identity:           &distillv1.Identity{User: "ana", Groups: []string{"billing"}},
+		Namespaces:         []*distillv1.NamespaceQuota{{Name: "docs", TopK: 3}, {Name: "faq"}},
+		Queries:            []string{"returns"},
+		QueryEmbeddings:    []*distillv1.Embedding{{Values: []float32{0, 1}}},
+		Combine:            "fanout",
+		Template:           "markdown",
+		ValidateEmbeddings: "reject",
+		Enable:             &distillv1.StageToggles{Mmr: &off, Redaction: &on},
+		Selection:          "mmr",
+		Model:              "claude",
+		Compress:           &distillv1.CompressOptions{Mode: "hybrid", TargetReduction: 0.5, PreserveStructure: &on, MergeTokens: 200},
+		Mode:               "passthrough",
+		Redact:             true,
+		DeadlineMs:         250,
+		Index:              "main",
+	}
+	want := RetrieveRequest{
+		Query:              "refunds",
+		QueryEmbedding:     Embedding{Values: []float32{1, 0}},
+		Index:              "main",
+		Namespace:          "docs",
+		OverFetchK:         40,
+		TargetK:            5,
+		Threshold:          0.2,
+		Lambda:             0.7,
+		MinScore:           0.1,
+		Filter:             map[string]interface{}{"lang": "en"},
+		Namespaces:         []NamespaceRequest{{Name: "docs", TopK: 3}, {Name: "faq"}},
+		Queries:            []string{"returns"},
+		QueryEmbeddings:    []Embedding{{Values: []float32{0, 1}}},
+		Combine:            "fanout",
+		SessionID:          "s1",
+		MarkRepeats:        true,
+		Exclude:            []string{"x"},
+		Identity:           &IdentityRequest{User: "ana", Groups: []string{"billing"}},
+		DedupHints:         true,
+		Explain:            true,
+		Template:           "markdown",
+		ValidateEmbeddings: "reject",
+		Enable:             &StageTogglesRequest{MMR: &off, Redaction: &on},
+		Selection:          "mmr",
+		Model:              "claude",
+		Compress:           &CompressRequest{Mode: "hybrid", TargetReduction: 0.5, PreserveStructure: &on, MergeTokens: 200},
+		Mode:               "passthrough",
+		Redact:             true,
+		DeadlineMs:         250,
+		EmbeddingOptions: EmbeddingOptions{
+			IncludeEmbeddings:  true,
+			EmbeddingDims:      64,
+			EmbeddingReduction: "truncate",
+		},
+	}
+
+	got := retrieveRequestFromProto(roundTrip(t, pb, &distillv1.RetrieveRequest{}))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("retrieveRequestFromProto:\n got %+v\nwant %+v", got, want)
+	}
+
+	// Unset messages stay nil rather than turning into empty overrides
+	got = retrieveRequestFromProto(&distillv1.RetrieveRequest{Query: "refunds"})
+	if got.Identity != nil || got.Enable != nil || got.Compress != nil || got.Namespaces != nil || got.QueryEmbeddings != nil {
+		t.Errorf("empty options converted to %+v", got)
+	}
+}
+
+func TestRetrieveResponseProto(t *testing.T) {
+	resp := RetrieveResponse{
+		Chunks: []ChunkResponse{{ID: "a", Text: "refunds", Score: 0.9, ClusterID: 2, AlreadySent: true, Metadata: map[string]interface{}{"lang": "en"}}},
+		Stats: StatsResponse{
+			Retrieved:         10,
+			Clustered:         4,
+			Returned:          1,
+			TotalLatencyMs:    12,
+			ACLDenied:         3,
+			ACLUnlabeled:      1,
+			InjectionFlagged:  2,
+			InjectionStripped: 5,
+			InjectionBlocked:  1,
+			GarbageDropped:    4,
+			Model:             "claude",
+			Tokens:            120,
+			CostUSD:           0.01,
+			BudgetDropped:     2,
+			Redacted:          3,
+			Compression:       &CompressionStats{InputTokens: 200, OutputTokens: 120, SavedTokens: 80, ReductionPct: 40, Compressed: 1, Merged: 1},
+			BestEffort:        true,
+			Notes:             []string{"skipped rerank"},
+			Sparse:            true,
+			Passthrough:       true,
+			Sensitivity:       "pii",
+		},
+		Rendered:   "refunds",
+		FeedbackID: "f1",
+		Stages:     []string{"retrieve", "cluster"},
+	}
+	m, err := retrieveResponseProto(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := roundTrip(t, m, &distillv1.RetrieveResponse{})
+	want := &distillv1.RetrieveResponse{
+		Chunks: []*distillv1.Chunk{{Id: "a", Text: "refunds", Score: 0.9, ClusterId: 2, AlreadySent: true, Metadata: got.GetChunks()[0].GetMetadata()}},
+		Stats: &distillv1.RetrieveStats{
+			Retrieved:         10,
+			Clustered:         4,
+			Returned:          1,
+			LatencyMs:         12,
+			Model:             "claude",
+			Tokens:            120,
+			CostUsd:           0.01,
+			BestEffort:        true,
+			Notes:             []string{"skipped rerank"},
+			AclDenied:         3,
+			AclUnlabeled:      1,
+			Compression:       &distillv1.CompressionStats{InputTokens: 200, OutputTokens: 120, SavedTokens: 80, ReductionPct: 40, ChunksCompressed: 1, ChunksMerged: 1},
+			Redacted:          3,
+			BudgetDropped:     2,
+			Sparse:            true,
+			Passthrough:       true,
+			Sensitivity:       "pii",
+			InjectionFlagged:  2,
+			InjectionStripped: 5,
+			InjectionBlocked:  1,
+			GarbageDropped:    4,
+		},
+		Rendered:   "refunds",
+		FeedbackId: "f1",
+		Stages:     []string{"retrieve", "cluster"},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("retrieveResponseProto:\n got %v\nwant %v", got, want)
+	}
+	if lang := got.GetChunks()[0].GetMetadata().AsMap()["lang"]; lang != "en" {
+		t.Errorf("metadata lang = %v", lang)
+	}
+}
+
+func TestDedupeProtoRoundTrip(t *testing.T) {
+	on := true
+	pb := &distillv1.DeduplicateRequest{
+		Chunks:              []*distillv1.Chunk{{Id: "a", Text: "refunds", Embedding: []float32{1, 0}, Score: 0.5, CacheControl: "ephemeral"}},
+		Threshold:           0.1,
+		Lambda:              0.6,
+		TargetK:             3,
+		IncludeEmbeddings:   true,
+		SessionId:           "s1",
+		PreserveCachePrefix: true,
+		MarkRepeats:         true,
+		ValidateEmbeddings:  "repair",
+		DedupHints:          true,
+		EmbeddingDims:       32,
+		EmbeddingReduction:  "pca",
+		TokenBudget:         500,
+		Model:               "claude",
+		Compress:            &distillv1.CompressOptions{Mode: "extractive", TargetReduction: 0.6, PreserveStructure: &on},
+		Redact:              true,
+	}
+	want := DedupeRequest{
+		Chunks:      []DedupeChunk{{ID: "a", Text: "refunds", Embedding: Embedding{Values: []float32{1, 0}}, Score: 0.5, CacheControl: "ephemeral"}},
+		Threshold:   0.1,
+		Lambda:      0.6,
+		TargetK:     3,
+		TokenBudget: 500,
+		Model:       "claude",
+		Compress:    &CompressRequest{Mode: "extractive", TargetReduction: 0.6, PreserveStructure: &on},
+		SessionID:   "s1",
+		Redact:      true,
+		Options: DedupeOptions{
+			PreserveCachePrefix: true,
+			MarkRepeats:         true,
+			ValidateEmbeddings:  "repair",
+			DedupHints:          true,
+			EmbeddingOptions: EmbeddingOptions{
+				IncludeEmbeddings:  true,
+				EmbeddingDims:      32,
+				EmbeddingReduction: "pca",
+			},
+		},
+	}
+	if got := dedupeRequestFromProto(roundTrip(t, pb, &distillv1.DeduplicateRequest{})); !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupeRequestFromProto:\n got %+v\nwant %+v", got, want)
+	}
+
+	m, err := dedupeResponseProto(DedupeResponse{
+		Chunks: []DedupeChunkResponse{{ID: "a", Text: "refunds", ClusterID: 1}},
+		Stats: DedupeStats{
+			InputCount:   2,
+			OutputCount:  1,
+			ClusterCount: 1,
+			ReductionPct: 50,
+			Tokens:       40,
+			Compression:  &CompressionStats{InputTokens: 60, OutputTokens: 40, SavedTokens: 20, ReductionPct: 33.3, Compressed: 1},
+			Redacted:     2,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := roundTrip(t, m, &distillv1.DeduplicateResponse{}).GetStats()
+	if st.GetInputCount() != 2 || st.GetTokens() != 40 || st.GetRedacted() != 2 || st.GetCompression().GetSavedTokens() != 20 || st.GetCompression().GetReductionPct() != 33.3 {
+		t.Errorf("stats = %v", st)
+	}
+}
+
+func TestHandleRetrieve_ProtobufIdentity(t *testing.T) {
+	s := newTestServer(t, aclCorpus(), contextlab.WithACL(contextlab.ACL{Enabled: true}))
+
+	for _, tc := range []struct {
+		identity *distillv1.Identity
+		want     []string
+	}{
+		{nil, []string{"public"}},
+		{&distillv1.Identity{User: "ana", Groups: []string{"billing"}}, []string{"billing", "public"}},
+	} {
+		body, _ := proto.Marshal(&distillv1.RetrieveRequest{
+			QueryEmbedding: []float32{1, 0},
+			Identity:       tc.identity,
+			Enable:         &distillv1.StageToggles{Clustering: new(bool)},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/v1/retrieve", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentTypeProtobuf)
+		req.Header.Set("Accept", contentTypeProtobuf)
+		rec := httptest.NewRecorder()
+		s.handleRetrieve(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("identity %v: status = %d (%s)", tc.identity, rec.Code, rec.Body.String())
+		}
+		var resp distillv1.RetrieveResponse
+		if err := proto.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		var ids []string
+		for _, c := range resp.GetChunks() {
+			ids = append(ids, c.GetId())
+		}
+		slices.Sort(ids)
+		if !slices.Equal(ids, tc.want) {
+			t.Errorf("identity %v: chunks = %v, want %v", tc.identity, ids, tc.want)
+		}
+		if denied := resp.GetStats().GetAclDenied(); int(denied) != 3-len(tc.want) {
+			t.Errorf("identity %v: acl_denied = %d", tc.identity, denied)
+		}
+	}
+}
+
+func TestEmbedding_UnmarshalJSON(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    Embedding
+		wantErr string
+	}{
+		{`[0.5, -1.25, 3e2]`, Embedding{Values: []float32{0.5, -1.25, 300}}, ""},
+		{`[ ]`, Embedding{Values: []float32{}}, ""},
+		{`null`, Embedding{}, ""},
+		{`[0.1000000000000000055511151231257827]`, Embedding{Values: []float32{0.1}}, ""},
+		// 0.5 and -1.25 as little-endian float32s
+		{`"AAAAPwAAoL8="`, Embedding{Values: []float32{0.5, -1.25}, base64: true}, ""},
+		{`[1e39]`, Embedding{}, "out of float32 range"},
+		{`["1"]`, Embedding{}, "expected an array of numbers"},
+		{`[[1], [2]]`, Embedding{}, "expected an array of numbers"},
+		{`{"a": 1}`, Embedding{}, "expected an array of numbers or a base64 string"},
+		{`"AAAAPw"`, Embedding{}, "illegal base64"},
+		{`"AAAA"`, Embedding{}, "not a whole number of float32s"},
+	} {
+		var got Embedding
+		err := json.Unmarshal([]byte(tc.in), &got)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tc.in, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, %v; want %v", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+// readRequest decodes body into a RetrieveRequest the way handlers do.
+func readRequest(contentType string, body []byte) (int, RetrieveRequest) {
+	r := httptest.NewRequest(http.MethodPost, "/v1/retrieve", bytes.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	var req RetrieveRequest
+	if !readBody(rec, r, &req) {
+		return rec.Code, req
+	}
+	return http.StatusOK, req
+}
+
+func TestReadBody_Encodings(t *testing.T) {
+	base64Body := map[string]interface{}{
+		"encoding":         "base64",
+		"query_embedding":  "AAAAPwAAoL8=",
+		"query_embeddings": []interface{}{"AACAPw==", "AAAAQA=="},
+		// Other fields named embedding are not embeddings
+		"filter": map[string]interface{}{"embedding": "AAAAPw=="},
+	}
+	jsonBody, _ := json.Marshal(base64Body)
+	msgpackBody, err := marshalMsgpack(base64Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"application/json", jsonBody},
+		{contentTypeMsgpack, msgpackBody},
+	} {
+		code, req := readRequest(tc.contentType, tc.body)
+		if code != http.StatusOK {
+			t.Fatalf("%s: status = %d", tc.contentType, code)
+		}
+		if !reflect.DeepEqual(req.QueryEmbedding.Values, []float32{0.5, -1.25}) {
+			t.Errorf("%s: query_embedding = %v", tc.contentType, req.QueryEmbedding)
+		}
+		if !reflect.DeepEqual(embeddings(req.QueryEmbeddings), [][]float32{{1}, {2}}) {
+			t.Errorf("%s: query_embeddings = %v", tc.contentType, req.QueryEmbeddings)
+		}
+		if got := req.Filter["embedding"]; got != "AAAAPw==" {
+			t.Errorf("%s: filter.embedding = %#v, want it unchanged", tc.contentType, got)
+		}
+	}
+
+	// Embeddings must have the form the encoding declares, an unknown
+	// encoding is rejected, and an absent one means float32
+	for _, tc := range []struct {
+		name string
+		body map[string]interface{}
+		want int
+	}{
+		{"unknown encoding", map[string]interface{}{"encoding": "float16", "query_embedding": []float64{1}}, http.StatusBadRequest},
+		{"float32 with base64", map[string]interface{}{"encoding": "float32", "query_embedding": "AAAAPw=="}, http.StatusBadRequest},
+		{"float64 with base64", map[string]interface{}{"encoding": "float64", "query_embeddings": []interface{}{[]float64{1}, "AAAAPw=="}}, http.StatusBadRequest},
+		{"default with base64", map[string]interface{}{"query_embedding": "AAAAPw=="}, http.StatusBadRequest},
+		{"base64 with an array", map[string]interface{}{"encoding": "base64", "query_embeddings": []interface{}{"AAAAPw==", []float64{2}}}, http.StatusBadRequest},
+		{"float64 with arrays", map[string]interface{}{"encoding": "float64", "query_embedding": []float64{0.1}}, http.StatusOK},
+		{"base64 without embeddings", map[string]interface{}{"encoding": "base64", "query": "refunds"}, http.StatusOK},
+	} {
+		jsonBody, _ := json.Marshal(tc.body)
+		msgpackBody, _ := marshalMsgpack(tc.body)
+		if code, _ := readRequest("application/json", jsonBody); code != tc.want {
+			t.Errorf("json %s: status = %d, want %d", tc.name, code, tc.want)
+		}
+		if code, _ := readRequest(contentTypeMsgpack, msgpackBody); code != tc.want {
+			t.Errorf("msgpack %s: status = %d, want %d", tc.name, code, tc.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/*", "application/json"},
+		{"application/msgpack", contentTypeMsgpack},
+		{"application/x-msgpack", contentTypeMsgpack},
+		{"application/x-protobuf", contentTypeProtobuf},
+		{"application/msgpack, application/x-protobuf", contentTypeProtobuf},
+		{"application/msgpack, */*", contentTypeMsgpack},
+		{"application/msgpack;q=0.5, */*", "application/json"},
+		{"application/x-protobuf;q=0.2, application/msgpack;q=0.8", contentTypeMsgpack},
+		{"application/json;q=0.1, application/msgpack", contentTypeMsgpack},
+		{"application/json, application/msgpack;q=0.9", "application/json"},
+		{"application/msgpack;q=0", "application/json"},
+		{"text/html", "application/json"},
+		{"application/msgpack;q=bogus, application/x-protobuf;q=0.3", contentTypeProtobuf},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tc.accept != "" {
+			r.Header.Set("Accept", tc.accept)
+		}
+		if got := negotiate(r, responseTypes); got != tc.want {
+			t.Errorf("Accept %q: got %s, want %s", tc.accept, got, tc.want)
+		}
+	}
+}
+
+func TestMsgpack_RequestMapping(t *testing.T) {
+	body, err := marshalMsgpack(map[string]interface{}{
+		"query":              "refunds",
+		"query_embedding":    []float64{0.5, 1e-3},
+		"query_embeddings":   []interface{}{[]int{1, 2}},
+		"target_k":           5,
+		"include_embeddings": true,
+		"filter":             map[string]interface{}{"n": 3, "tags": []string{"x"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, req := readRequest(contentTypeMsgpack, body)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if req.Query != "refunds" || req.TargetK != 5 || !req.IncludeEmbeddings {
+		t.Errorf("request = %+v", req)
+	}
+	if !reflect.DeepEqual(req.QueryEmbedding.Values, []float32{0.5, 1e-3}) || !reflect.DeepEqual(embeddings(req.QueryEmbeddings), [][]float32{{1, 2}}) {
+		t.Errorf("embeddings = %v, %v", req.QueryEmbedding, req.QueryEmbeddings)
+	}
+	// Integers in interfaces decode as int64, as JSON's decode as float64
+	if want := map[string]interface{}{"n": int64(3), "tags": []interface{}{"x"}}; !reflect.DeepEqual(req.Filter, want) {
+		t.Errorf("filter = %#v, want %#v", req.Filter, want)
+	}
+
+	bad, _ := marshalMsgpack(map[string]interface{}{"query_embedding": []float64{1e39}})
+	if code, _ := readRequest(contentTypeMsgpack, bad); code != http.StatusBadRequest {
+		t.Errorf("out of range: status = %d, want 400", code)
+	}
+	if code, _ := readRequest(contentTypeMsgpack, []byte{0xdd, 0xff, 0xff, 0xff, 0xff}); code != http.StatusBadRequest {
+		t.Errorf("truncated body: status = %d, want 400", code)
+	}
+}
+
+func TestMsgpack_ResponseMatchesJSON(t *testing.T) {
+	resp := RetrieveResponse{
+		Chunks: []ChunkResponse{{ID: "a", Text: "refunds", Score: 0.5, ClusterID: 1, Embedding: []float32{0.25, -1}}},
+		Stats:  StatsResponse{Retrieved: 3, Returned: 1, Compression: &CompressionStats{InputTokens: 10, OutputTokens: 5}},
+	}
+	data, err := marshalMsgpack(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var viaMsgpack map[string]interface{}
+	if err := newMsgpackDecoder(bytes.NewReader(data)).Decode(&viaMsgpack); err != nil {
+		t.Fatal(err)
+	}
+	js, _ := json.Marshal(resp)
+	var viaJSON map[string]interface{}
+	_ = json.Unmarshal(js, &viaJSON)
+
+	// Numbers differ in type between the formats; compare them as JSON
+	got, _ := json.Marshal(viaMsgpack)
+	want, _ := json.Marshal(viaJSON)
+	if !bytes.Equal(got, want) {
+		t.Errorf("MessagePack response:\n got %s\nwant %s", got, want)
+	}
+}
+
+// benchmarkDedupeBody is a /v1/dedupe body of 100 chunks with
+// 1536-dimension embeddings, the size of text-embedding-3-small vectors.
+func benchmarkDedupeBody() DedupeRequest {
+	req := DedupeRequest{Threshold: 0.15, TargetK: 10}
+	for i := 0; i < 100; i++ {
+		emb := make([]float32, 1536)
+		for j := range emb {
+			emb[j] = float32(math.Sin(float64(i*1536+j))) / 40
+		}
+		req.Chunks = append(req.Chunks, DedupeChunk{ID: fmt.Sprintf("chunk-%d", i), Text: strings.Repeat("Refunds are processed within five days. ", 8), Embedding: Embedding{Values: emb}, Score: 0.8})
+	}
+	return req
+}
+
+func BenchmarkReadBody(b *testing.B) {
+	req := benchmarkDedupeBody()
+	js, _ := json.Marshal(req)
+	mp, _ := marshalMsgpack(req)
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        []byte
+	}{
+		{"json", "application/json", js},
+		{"msgpack", contentTypeMsgpack, mp},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportMetric(float64(len(tc.body)), "body-bytes")
+			b.SetBytes(int64(len(tc.body)))
+			for i := 0; i < b.N; i++ {
+				r := httptest.NewRequest(http.MethodPost, "/v1/dedupe", bytes.NewReader(tc.body))
+				r.Header.Set("Content-Type", tc.contentType)
+				var out DedupeRequest
+				if !readBody(httptest.NewRecorder(), r, &out) {
+					b.Fatal("readBody failed")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Package analytics exports per-request analytic records (counts,
+// reduction, latencies, the effective settings) to a warehouse such as
+// ClickHouse or BigQuery, so dedup effectiveness can be analyzed offline
+// across millions of requests instead of from Prometheus aggregates.
+//
+// Records are sampled, queued, and written in batches from a background
+// goroutine; like the history writer, the exporter drops records rather
+// than slow requests down when the sink falls behind.
+package analytics
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Record is one served request, a row in the sink's table.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Endpoint  string    `json:"endpoint"`
+	Namespace string    `json:"namespace"`
+
+	// Retrieved, Clusters, and Returned are the chunks going in, the
+	// clusters formed, and the chunks returned; Reduction is the
+	// fraction of Retrieved removed.
+	Retrieved int     `json:"retrieved"`
+	Clusters  int     `json:"clusters"`
+	Returned  int     `json:"returned"`
+	Reduction float64 `json:"reduction"`
+	Tokens    int     `json:"tokens"`
+
+	CacheHit   bool `json:"cache_hit"`
+	BestEffort bool `json:"best_effort"`
+
+	// Stage latencies in milliseconds.
+	TotalMs      float64 `json:"total_ms"`
+	RetrievalMs  float64 `json:"retrieval_ms"`
+	ClusteringMs float64 `json:"clustering_ms"`
+	RerankMs     float64 `json:"rerank_ms"`
+
+	// Fingerprint identifies the effective settings, which Config holds
+	// as a JSON object.
+	Fingerprint string `json:"fingerprint"`
+	Config      string `json:"config"`
+}
+
+// Sink writes batches of records.
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+}
+
+// Config holds exporter settings.
+type Config struct {
+	// SampleRate is the fraction of requests recorded, 0 to 1.
+	// Default: 1
+	SampleRate float64
+
+	// BatchSize is the most records per write. Default: 500
+	BatchSize int
+
+	// FlushInterval bounds how long a record waits for its batch to
+	// fill. Default: 5s
+	FlushInterval time.Duration
+
+	// Buffer is the number of records queued for writing; more are
+	// dropped. Default: 10000
+	Buffer int
+
+	// Timeout bounds each write. Default: 30s
+	Timeout time.Duration
+
+	// OnError is called when a batch fails to write. The batch is
+	// dropped.
+	OnError func(error)
+}
+
+// Exporter samples records and writes them to a sink in batches.
+type Exporter struct {
+	sink Sink
+	cfg  Config
+	ch   chan Record
+	done chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+
+	written atomic.Int64
+	dropped atomic.Int64
+	failed  atomic.Int64
+}
+
+// NewExporter starts an exporter writing to sink.
+func NewExporter(sink Sink, cfg Config) *Exporter {
+	if cfg.SampleRate <= 0 || cfg.SampleRate > 1 {
+		cfg.SampleRate = 1
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 10000
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	e := &Exporter{
+		sink: sink,
+		cfg:  cfg,
+		ch:   make(chan Record, cfg.Buffer),
+		done: make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Record queues r if it is sampled. It is safe to call on a nil Exporter.
+func (e *Exporter) Record(r Record) {
+	if e == nil {
+		return
+	}
+	if e.cfg.SampleRate < 1 && rand.Float64() >= e.cfg.SampleRate {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if r.Reduction == 0 && r.Retrieved > 0 {
+		r.Reduction = float64(r.Retrieved-r.Returned) / float64(r.Retrieved)
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		e.dropped.Add(1)
+		return
+	}
+	select {
+	case e.ch <- r:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, e.cfg.BatchSize)
+	for {
+		select {
+		case r, ok := <-e.ch:
+			if !ok {
+				e.flush(batch)
+				return
+			}
+			batch = append(batch, r)
+			if len(batch) < e.cfg.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		e.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+func (e *Exporter) flush(batch []Record) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.Timeout)
+	defer cancel()
+	if err := e.sink.Write(ctx, batch); err != nil {
+		e.failed.Add(int64(len(batch)))
+		if e.cfg.OnError != nil {
+			e.cfg.OnError(err)
+		}
+		return
+	}
+	e.written.Add(int64(len(batch)))
+}
+
+// SampleRate returns the fraction of requests recorded.
+func (e *Exporter) SampleRate() float64 { return e.cfg.SampleRate }
+
+// Written returns the number of records written to the sink.
+func (e *Exporter) Written() int64 { return e.written.Load() }
+
+// Dropped returns the number of sampled records dropped because the
+// buffer was full or the exporter was closed.
+func (e *Exporter) Dropped() int64 { return e.dropped.Load() }
+
+// Failed returns the number of records in batches the sink rejected.
+func (e *Exporter) Failed() int64 { return e.failed.Load() }
+
+// Close writes the queued records. Records passed to Record after Close
+// are dropped.
+func (e *Exporter) Close() error {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.ch)
+	}
+	e.mu.Unlock()
+	<-e.done
+	return nil
+}
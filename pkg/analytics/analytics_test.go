@@ -0,0 +1,111 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memSink collects written batches.
+type memSink struct {
+	mu      sync.Mutex
+	batches [][]Record
+	err     error
+}
+
+func (s *memSink) Write(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, append([]Record(nil), records...))
+	return nil
+}
+
+func (s *memSink) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []int
+	for _, b := range s.batches {
+		out = append(out, len(b))
+	}
+	return out
+}
+
+func TestExporter_Batches(t *testing.T) {
+	sink := &memSink{}
+	e := NewExporter(sink, Config{BatchSize: 2, FlushInterval: time.Hour})
+	for range 5 {
+		e.Record(Record{Endpoint: "/v1/retrieve", Retrieved: 10, Returned: 4})
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got := sink.sizes()
+	if len(got) != 3 || got[0] != 2 || got[1] != 2 || got[2] != 1 {
+		t.Errorf("batch sizes = %v, want [2 2 1]", got)
+	}
+	r := sink.batches[0][0]
+	if r.Reduction != 0.6 || r.Time.IsZero() {
+		t.Errorf("reduction = %v, time = %v, want 0.6 and set", r.Reduction, r.Time)
+	}
+	if e.Written() != 5 {
+		t.Errorf("written = %d, want 5", e.Written())
+	}
+}
+
+func TestExporter_FlushInterval(t *testing.T) {
+	sink := &memSink{}
+	e := NewExporter(sink, Config{FlushInterval: 10 * time.Millisecond})
+	defer e.Close() //nolint:errcheck
+
+	e.Record(Record{Endpoint: "/v1/retrieve"})
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sink.sizes()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("record not flushed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExporter_SampleRate(t *testing.T) {
+	sink := &memSink{}
+	e := NewExporter(sink, Config{SampleRate: 0.1, FlushInterval: time.Hour})
+	for range 2000 {
+		e.Record(Record{})
+	}
+	_ = e.Close()
+	if n := e.Written(); n < 100 || n > 300 {
+		t.Errorf("wrote %d of 2000 at rate 0.1", n)
+	}
+}
+
+func TestExporter_FailedAndDropped(t *testing.T) {
+	var reported error
+	sink := &memSink{err: errors.New("down")}
+	e := NewExporter(sink, Config{FlushInterval: time.Hour, OnError: func(err error) { reported = err }})
+	e.Record(Record{})
+	e.Record(Record{})
+	_ = e.Close()
+	e.Record(Record{})
+
+	if e.Failed() != 2 || e.Dropped() != 1 || e.Written() != 0 {
+		t.Errorf("failed = %d, dropped = %d, written = %d, want 2, 1, 0", e.Failed(), e.Dropped(), e.Written())
+	}
+	if reported == nil {
+		t.Error("OnError not called")
+	}
+}
+
+func TestExporter_Nil(t *testing.T) {
+	var e *Exporter
+	e.Record(Record{})
+	if err := e.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
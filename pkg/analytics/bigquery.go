@@ -0,0 +1,173 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBigQueryURL = "https://bigquery.googleapis.com/bigquery/v2"
+	defaultMetadataURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// BigQueryConfig holds BigQuery sink settings.
+type BigQueryConfig struct {
+	// Project, Dataset, and Table name the table records are streamed
+	// into. Table defaults to distill_requests.
+	Project string
+	Dataset string
+	Table   string
+
+	// AccessToken is an OAuth2 token with BigQuery insert rights. When
+	// empty, tokens for the attached service account are fetched from
+	// the GCE metadata server, as on GKE with Workload Identity or on
+	// Cloud Run.
+	AccessToken string
+
+	// BaseURL and MetadataURL override the API and metadata endpoints.
+	BaseURL     string
+	MetadataURL string
+}
+
+// BigQuery streams records with the tabledata.insertAll API.
+type BigQuery struct {
+	cfg    BigQueryConfig
+	client *http.Client
+	url    string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewBigQuery returns a BigQuery sink.
+func NewBigQuery(cfg BigQueryConfig) (*BigQuery, error) {
+	if cfg.Project == "" || cfg.Dataset == "" {
+		return nil, fmt.Errorf("bigquery project and dataset are required")
+	}
+	if cfg.Table == "" {
+		cfg.Table = "distill_requests"
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBigQueryURL
+	}
+	if cfg.MetadataURL == "" {
+		cfg.MetadataURL = defaultMetadataURL
+	}
+	return &BigQuery{
+		cfg:    cfg,
+		client: &http.Client{},
+		url: fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll",
+			strings.TrimRight(cfg.BaseURL, "/"), cfg.Project, cfg.Dataset, cfg.Table),
+	}, nil
+}
+
+type insertRow struct {
+	JSON Record `json:"json"`
+}
+
+type insertAllRequest struct {
+	Rows []insertRow `json:"rows"`
+}
+
+type insertAllResponse struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+// Write streams records in one insertAll call. Rows BigQuery rejects fail
+// the whole call, which reports the first rejection.
+func (b *BigQuery) Write(ctx context.Context, records []Record) error {
+	token, err := b.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	rows := make([]insertRow, len(records))
+	for i, r := range records {
+		rows[i] = insertRow{JSON: r}
+	}
+	body, err := json.Marshal(insertAllRequest{Rows: rows})
+	if err != nil {
+		return fmt.Errorf("marshal rows: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("bigquery insert: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("bigquery insert: %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var result insertAllResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if n := len(result.InsertErrors); n > 0 {
+		first := result.InsertErrors[0]
+		reason := "rejected"
+		if len(first.Errors) > 0 {
+			reason = first.Errors[0].Reason + ": " + first.Errors[0].Message
+		}
+		return fmt.Errorf("bigquery insert: %d of %d rows rejected, row %d: %s", n, len(records), first.Index, reason)
+	}
+	return nil
+}
+
+// accessToken returns the configured token, or a metadata server token
+// refreshed a minute before it expires.
+func (b *BigQuery) accessToken(ctx context.Context) (string, error) {
+	if b.cfg.AccessToken != "" {
+		return b.cfg.AccessToken, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token != "" && time.Now().Before(b.expires) {
+		return b.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.cfg.MetadataURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("build token request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("bigquery token from metadata server: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("bigquery token from metadata server: %d", resp.StatusCode)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("decode token: %w", err)
+	}
+	b.token = tok.AccessToken
+	b.expires = time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - time.Minute)
+	return b.token, nil
+}
@@ -0,0 +1,78 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBigQuery_Write(t *testing.T) {
+	tokenFetches := 0
+	var path, auth string
+	var body insertAllRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		tokenFetches++
+		_, _ = w.Write([]byte(`{"access_token":"tok","expires_in":3600,"token_type":"Bearer"}`))
+	})
+	mux.HandleFunc("/bq/", func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"kind":"bigquery#tableDataInsertAllResponse"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	sink, err := NewBigQuery(BigQueryConfig{
+		Project:     "p",
+		Dataset:     "d",
+		BaseURL:     srv.URL + "/bq",
+		MetadataURL: srv.URL + "/token",
+	})
+	if err != nil {
+		t.Fatalf("NewBigQuery: %v", err)
+	}
+	for range 2 {
+		if err := sink.Write(context.Background(), []Record{{Endpoint: "/v1/retrieve", Returned: 3}}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	if path != "/bq/projects/p/datasets/d/tables/distill_requests/insertAll" {
+		t.Errorf("path = %q", path)
+	}
+	if auth != "Bearer tok" || tokenFetches != 1 {
+		t.Errorf("auth = %q after %d token fetches, want Bearer tok after 1", auth, tokenFetches)
+	}
+	if len(body.Rows) != 1 || body.Rows[0].JSON.Returned != 3 {
+		t.Errorf("rows = %+v", body.Rows)
+	}
+}
+
+func TestBigQuery_InsertErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"insertErrors":[{"index":1,"errors":[{"reason":"invalid","message":"no such field: tokens"}]}]}`))
+	}))
+	defer srv.Close()
+
+	sink, err := NewBigQuery(BigQueryConfig{Project: "p", Dataset: "d", AccessToken: "tok", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewBigQuery: %v", err)
+	}
+	err = sink.Write(context.Background(), []Record{{}, {}})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 rows rejected, row 1: invalid: no such field") {
+		t.Errorf("got %v", err)
+	}
+
+	if _, err := NewBigQuery(BigQueryConfig{Project: "p"}); err == nil {
+		t.Error("expected error without a dataset")
+	}
+}
@@ -0,0 +1,96 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ClickHouseConfig holds ClickHouse sink settings.
+type ClickHouseConfig struct {
+	// URL is the ClickHouse HTTP interface, e.g. http://clickhouse:8123.
+	URL string
+
+	// Database and Table name the table records are inserted into.
+	// Defaults: default, distill_requests
+	Database string
+	Table    string
+
+	User     string
+	Password string
+}
+
+// ClickHouse inserts records through the ClickHouse HTTP interface as
+// JSONEachRow.
+type ClickHouse struct {
+	cfg    ClickHouseConfig
+	client *http.Client
+	query  string
+}
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// NewClickHouse returns a ClickHouse sink.
+func NewClickHouse(cfg ClickHouseConfig) (*ClickHouse, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("clickhouse URL is required")
+	}
+	if cfg.Database == "" {
+		cfg.Database = "default"
+	}
+	if cfg.Table == "" {
+		cfg.Table = "distill_requests"
+	}
+	for _, id := range []string{cfg.Database, cfg.Table} {
+		if !identifier.MatchString(id) {
+			return nil, fmt.Errorf("invalid clickhouse identifier %q", id)
+		}
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	return &ClickHouse{
+		cfg:    cfg,
+		client: &http.Client{},
+		query:  fmt.Sprintf("INSERT INTO %s.%s FORMAT JSONEachRow", cfg.Database, cfg.Table),
+	}, nil
+}
+
+// Write inserts records in one request.
+func (c *ClickHouse) Write(ctx context.Context, records []Record) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("encode record: %w", err)
+		}
+	}
+
+	q := url.Values{}
+	q.Set("query", c.query)
+	// Accept the RFC 3339 timestamps encoding/json writes
+	q.Set("date_time_input_format", "best_effort")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL+"/?"+q.Encode(), &body)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	if c.cfg.User != "" {
+		req.Header.Set("X-ClickHouse-User", c.cfg.User)
+		req.Header.Set("X-ClickHouse-Key", c.cfg.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("clickhouse insert: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("clickhouse insert: %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
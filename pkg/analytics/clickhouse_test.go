@@ -0,0 +1,72 @@
+package analytics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClickHouse_Write(t *testing.T) {
+	var query, user string
+	var rows []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("query")
+		user = r.Header.Get("X-ClickHouse-User")
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(sc.Bytes(), &row); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			rows = append(rows, row)
+		}
+	}))
+	defer srv.Close()
+
+	sink, err := NewClickHouse(ClickHouseConfig{URL: srv.URL + "/", Database: "analytics", User: "distill", Password: "pw"})
+	if err != nil {
+		t.Fatalf("NewClickHouse: %v", err)
+	}
+	err = sink.Write(context.Background(), []Record{
+		{Time: time.Now(), Endpoint: "/v1/retrieve", Retrieved: 50, Returned: 10},
+		{Time: time.Now(), Endpoint: "/v1/similar", CacheHit: true},
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if query != "INSERT INTO analytics.distill_requests FORMAT JSONEachRow" {
+		t.Errorf("query = %q", query)
+	}
+	if user != "distill" {
+		t.Errorf("user = %q", user)
+	}
+	if len(rows) != 2 || rows[0]["retrieved"] != 50.0 || rows[1]["cache_hit"] != true {
+		t.Errorf("rows = %v", rows)
+	}
+}
+
+func TestClickHouse_Errors(t *testing.T) {
+	if _, err := NewClickHouse(ClickHouseConfig{URL: "http://ch:8123", Table: "x; DROP TABLE y"}); err == nil {
+		t.Error("expected invalid identifier error")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Code: 60. Table default.distill_requests does not exist", http.StatusNotFound)
+	}))
+	defer srv.Close()
+	sink, err := NewClickHouse(ClickHouseConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewClickHouse: %v", err)
+	}
+	err = sink.Write(context.Background(), []Record{{}})
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("got %v, want the server's message", err)
+	}
+}
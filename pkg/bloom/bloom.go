@@ -0,0 +1,107 @@
+// Package bloom provides Bloom filters for remembering which keys have
+// been seen, and a registry of them per namespace that persists to a file.
+//
+// A filter never forgets a key it was given, but may report a key it was
+// never given as seen, at the false-positive rate it was sized for.
+package bloom
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// Filter is a fixed-size Bloom filter. It is not safe for concurrent use;
+// Registry adds the locking.
+type Filter struct {
+	bits     []uint64
+	m        uint64 // bits
+	k        uint32 // hash functions
+	n        uint64 // keys added
+	capacity uint64
+}
+
+// New returns a filter sized to hold capacity keys with the given
+// false-positive rate. Past capacity the rate rises.
+func New(capacity int, fpRate float64) *Filter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.001
+	}
+	n := float64(capacity)
+	m := uint64(math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint32(math.Round(float64(m) / n * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &Filter{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: uint64(capacity),
+	}
+}
+
+// Add records key. It reports whether key was new, that is, whether at
+// least one of its bits was unset.
+func (f *Filter) Add(key []byte) bool {
+	h1, h2 := hashes(key)
+	added := false
+	for i := uint32(0); i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if f.bits[word]&mask == 0 {
+			f.bits[word] |= mask
+			added = true
+		}
+	}
+	if added {
+		f.n++
+	}
+	return added
+}
+
+// Test reports whether key may have been added. False means it certainly
+// was not.
+func (f *Filter) Test(key []byte) bool {
+	h1, h2 := hashes(key)
+	for i := uint32(0); i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		if f.bits[bit/64]&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the number of keys added.
+func (f *Filter) Len() int {
+	return int(f.n)
+}
+
+// Full reports whether the filter holds the keys it was sized for.
+func (f *Filter) Full() bool {
+	return f.n >= f.capacity
+}
+
+// FPRate estimates the current false-positive rate from the keys added.
+func (f *Filter) FPRate() float64 {
+	return math.Pow(1-math.Exp(-float64(f.k)*float64(f.n)/float64(f.m)), float64(f.k))
+}
+
+// hashes returns the two halves of key's 128-bit FNV-1a hash, which
+// derive the k bit positions by double hashing. FNV is stable across
+// processes, so saved filters stay valid. h2 is odd so the positions
+// never collapse onto one bit.
+func hashes(key []byte) (uint64, uint64) {
+	h := fnv.New128a()
+	_, _ = h.Write(key)
+	var sum [16]byte
+	h.Sum(sum[:0])
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
@@ -0,0 +1,131 @@
+package bloom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func key(i int) []byte {
+	return []byte(fmt.Sprintf("chunk-%d", i))
+}
+
+func TestFilter_FalsePositiveRate(t *testing.T) {
+	const n = 10000
+	f := New(n, 0.01)
+	added := 0
+	for i := 0; i < n; i++ {
+		if f.Add(key(i)) {
+			added++
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !f.Test(key(i)) {
+			t.Fatalf("key %d forgotten", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := n; i < 2*n; i++ {
+		if f.Test(key(i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / n; rate > 0.02 {
+		t.Errorf("false-positive rate = %.4f, want about 0.01", rate)
+	}
+	if est := f.FPRate(); est < 0.005 || est > 0.02 {
+		t.Errorf("estimated rate = %.4f, want about 0.01", est)
+	}
+	// Keys colliding with earlier ones are not counted as new
+	if f.Len() != added || added < n*98/100 {
+		t.Errorf("Len = %d, %d new of %d keys", f.Len(), added, n)
+	}
+}
+
+func TestRegistry_NamespacesAndGrowth(t *testing.T) {
+	r := NewRegistry(100, 0.01)
+	for i := 0; i < 1000; i++ {
+		r.Add("docs", key(i))
+	}
+	for i := 0; i < 1000; i++ {
+		if !r.Seen("docs", key(i)) {
+			t.Fatalf("key %d forgotten after growth", i)
+		}
+	}
+	if r.Seen("tickets", key(0)) {
+		t.Error("key seen in a namespace it was never added to")
+	}
+	if got := len(r.filters["docs"]); got < 2 {
+		t.Errorf("filters = %d, want growth past capacity", got)
+	}
+
+	// Growth keeps the combined rate under the configured one
+	falsePositives := 0
+	for i := 1000; i < 11000; i++ {
+		if r.Seen("docs", key(i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 10000; rate > 0.015 {
+		t.Errorf("false-positive rate = %.4f after growth, want under 0.01", rate)
+	}
+}
+
+func TestRegistry_SaveAndOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.bloom")
+
+	r, err := Open(path, 100, 0.01)
+	if err != nil {
+		t.Fatalf("Open missing file: %v", err)
+	}
+	for i := 0; i < 250; i++ {
+		r.Add("docs", key(i))
+	}
+	if err := r.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := Open(path, 100, 0.01)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for i := 0; i < 250; i++ {
+		if !loaded.Seen("docs", key(i)) {
+			t.Fatalf("key %d lost on reload", i)
+		}
+	}
+	if loaded.Len("docs") != r.Len("docs") {
+		t.Errorf("Len = %d after reload, want %d", loaded.Len("docs"), r.Len("docs"))
+	}
+
+	// Nothing added since opening: nothing to write
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("unchanged registry was written")
+	}
+
+	if err := os.WriteFile(path, []byte("not a registry"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, 100, 0.01); err == nil {
+		t.Error("expected an error opening a corrupt file")
+	}
+}
+
+func TestRegistry_Nil(t *testing.T) {
+	var r *Registry
+	r.Add("docs", key(0))
+	if r.Seen("docs", key(0)) || r.Len("docs") != 0 {
+		t.Error("nil registry remembered a key")
+	}
+	if err := r.Save(filepath.Join(t.TempDir(), "seen.bloom")); err != nil {
+		t.Errorf("Save on nil registry: %v", err)
+	}
+}
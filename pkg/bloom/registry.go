@@ -0,0 +1,177 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/Siddhant-K-code/distill/internal/atomicfile"
+)
+
+// fileVersion is written at the start of saved registries.
+const fileVersion = 1
+
+// Registry keeps a growing filter per namespace. When a namespace's
+// newest filter is full, a filter twice its size at half its
+// false-positive rate is added, so the combined rate stays below the
+// configured one however many keys arrive. A nil *Registry has seen
+// nothing and records nothing.
+type Registry struct {
+	mu       sync.Mutex
+	capacity int
+	fpRate   float64
+	filters  map[string][]*Filter
+	dirty    bool
+}
+
+// NewRegistry returns an empty registry whose namespaces start with
+// filters for capacity keys. fpRate bounds each namespace's combined
+// false-positive rate.
+func NewRegistry(capacity int, fpRate float64) *Registry {
+	return &Registry{
+		capacity: capacity,
+		fpRate:   fpRate,
+		filters:  make(map[string][]*Filter),
+	}
+}
+
+// Open loads the registry saved at path, or returns an empty one if
+// there is no file yet. Saved filters keep their sizes; capacity and
+// fpRate apply to namespaces and growth filters added from now on.
+func Open(path string, capacity int, fpRate float64) (*Registry, error) {
+	r := NewRegistry(capacity, fpRate)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved savedRegistry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if saved.Version != fileVersion {
+		return nil, fmt.Errorf("reading %s: unsupported version %d", path, saved.Version)
+	}
+	for ns, states := range saved.Namespaces {
+		for _, s := range states {
+			if s.M == 0 || s.K == 0 || uint64(len(s.Bits)) != (s.M+63)/64 {
+				return nil, fmt.Errorf("reading %s: corrupt filter for namespace %q", path, ns)
+			}
+			r.filters[ns] = append(r.filters[ns], &Filter{bits: s.Bits, m: s.M, k: s.K, n: s.N, capacity: s.Capacity})
+		}
+	}
+	return r, nil
+}
+
+// Seen reports whether key may have been added to namespace.
+func (r *Registry) Seen(namespace string, key []byte) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, f := range r.filters[namespace] {
+		if f.Test(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add records key under namespace.
+func (r *Registry) Add(namespace string, key []byte) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	filters := r.filters[namespace]
+	for _, f := range filters {
+		if f.Test(key) {
+			return
+		}
+	}
+	if len(filters) == 0 {
+		filters = []*Filter{New(r.capacity, r.fpRate/2)}
+	} else if last := filters[len(filters)-1]; last.Full() {
+		rate := r.fpRate / float64(uint64(2)<<len(filters))
+		filters = append(filters, New(2*int(last.capacity), rate))
+	}
+	filters[len(filters)-1].Add(key)
+	r.filters[namespace] = filters
+	r.dirty = true
+}
+
+// Len returns the number of keys added to namespace.
+func (r *Registry) Len(namespace string) int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := 0
+	for _, f := range r.filters[namespace] {
+		total += f.Len()
+	}
+	return total
+}
+
+// Save writes the registry to path if anything was added since it was
+// opened or last saved. The file is replaced atomically, so a crash
+// mid-save leaves the previous one.
+func (r *Registry) Save(path string) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return nil
+	}
+	saved := savedRegistry{Version: fileVersion, Namespaces: make(map[string][]savedFilter, len(r.filters))}
+	for ns, filters := range r.filters {
+		for _, f := range filters {
+			saved.Namespaces[ns] = append(saved.Namespaces[ns], savedFilter{
+				Bits: f.bits, M: f.m, K: f.k, N: f.n, Capacity: f.capacity,
+			})
+		}
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(saved)
+	if err == nil {
+		r.dirty = false
+	}
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := atomicfile.Write(path, buf.Bytes(), 0o600); err != nil {
+		r.mu.Lock()
+		r.dirty = true
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// savedRegistry is the file format of a saved registry.
+type savedRegistry struct {
+	Version    int
+	Namespaces map[string][]savedFilter
+}
+
+type savedFilter struct {
+	Bits     []uint64
+	M        uint64
+	K        uint32
+	N        uint64
+	Capacity uint64
+}
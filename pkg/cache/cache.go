@@ -41,6 +41,20 @@ type Cache interface {
 	Close() error
 }
 
+// BatchCache is implemented by caches that read and write several keys
+// in one round trip, such as RedisCache. Callers with many keys should
+// use it when available and fall back to Cache otherwise.
+type BatchCache interface {
+	Cache
+
+	// GetMulti retrieves several keys. Missing keys are absent from the
+	// result.
+	GetMulti(ctx context.Context, keys []string) (map[string][]byte, error)
+
+	// SetMulti stores several values with the same TTL.
+	SetMulti(ctx context.Context, entries map[string][]byte, ttl time.Duration) error
+}
+
 // Stats holds cache performance metrics.
 type Stats struct {
 	// Hits is the number of successful cache retrievals.
@@ -0,0 +1,406 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// diskBucket holds every entry of a DiskCache.
+var diskBucket = []byte("entries")
+
+// diskHeader is the size of the times stored before each value: when the
+// entry was set and when it expires (zero for never), in Unix nanoseconds.
+const diskHeader = 16
+
+// DiskCache is a BoltDB-backed LRU cache with TTL support. Entries
+// survive restarts; recency is tracked in memory, so after a restart the
+// least recently set entries are evicted first.
+type DiskCache struct {
+	db      *bolt.DB
+	mu      sync.Mutex
+	items   map[string]*list.Element
+	lru     *list.List
+	cfg     Config
+	stats   Stats
+	stopCh  chan struct{}
+	stopped atomic.Bool
+}
+
+// diskItem indexes an entry stored on disk.
+type diskItem struct {
+	key       string
+	size      int64
+	expiresAt time.Time
+}
+
+// NewDiskCache opens or creates the cache file at path. Only one process
+// may open a file at a time; a second open waits up to a second and
+// fails.
+func NewDiskCache(path string, cfg Config) (*DiskCache, error) {
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = DefaultConfig().MaxSize
+	}
+	if cfg.CleanupInterval == 0 {
+		cfg.CleanupInterval = DefaultConfig().CleanupInterval
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open cache %s: %w", path, err)
+	}
+	c := &DiskCache{
+		db:     db,
+		items:  make(map[string]*list.Element),
+		lru:    list.New(),
+		cfg:    cfg,
+		stopCh: make(chan struct{}),
+		stats: Stats{
+			MaxSize:      cfg.MaxSize,
+			MaxSizeBytes: cfg.MaxSizeBytes,
+		},
+	}
+	if err := c.load(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("open cache %s: %w", path, err)
+	}
+
+	go c.cleanupLoop()
+	return c, nil
+}
+
+// load indexes the stored entries, most recently set first, and drops
+// expired ones.
+func (c *DiskCache) load() error {
+	type stored struct {
+		item  *diskItem
+		setAt int64
+	}
+	var entries []stored
+	now := time.Now()
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(diskBucket)
+		if err != nil {
+			return err
+		}
+		var expired [][]byte
+		err = b.ForEach(func(k, v []byte) error {
+			if len(v) < diskHeader {
+				expired = append(expired, k)
+				return nil
+			}
+			setAt, expiresAt := decodeTimes(v)
+			if !expiresAt.IsZero() && now.After(expiresAt) {
+				expired = append(expired, k)
+				return nil
+			}
+			entries = append(entries, stored{
+				item:  &diskItem{key: string(k), size: int64(len(k) + len(v) - diskHeader), expiresAt: expiresAt},
+				setAt: setAt,
+			})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Oldest first, so each push to the front leaves the newest in front
+	sort.Slice(entries, func(i, j int) bool { return entries[i].setAt < entries[j].setAt })
+	for _, e := range entries {
+		c.items[e.item.key] = c.lru.PushFront(e.item)
+		c.stats.Size++
+		c.stats.SizeBytes += e.item.size
+	}
+	return nil
+}
+
+func encodeEntry(value []byte, setAt, expiresAt time.Time) []byte {
+	buf := make([]byte, diskHeader+len(value))
+	binary.BigEndian.PutUint64(buf[0:8], uint64(setAt.UnixNano()))
+	if !expiresAt.IsZero() {
+		binary.BigEndian.PutUint64(buf[8:16], uint64(expiresAt.UnixNano()))
+	}
+	copy(buf[diskHeader:], value)
+	return buf
+}
+
+func decodeTimes(v []byte) (int64, time.Time) {
+	setAt := int64(binary.BigEndian.Uint64(v[0:8]))
+	var expiresAt time.Time
+	if exp := int64(binary.BigEndian.Uint64(v[8:16])); exp != 0 {
+		expiresAt = time.Unix(0, exp)
+	}
+	return setAt, expiresAt
+}
+
+// Get retrieves a value by key.
+func (c *DiskCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		atomic.AddInt64(&c.stats.Misses, 1)
+		return nil, ErrNotFound
+	}
+	item := elem.Value.(*diskItem)
+	if !item.expiresAt.IsZero() && time.Now().After(item.expiresAt) {
+		_ = c.remove([]*list.Element{elem})
+		atomic.AddInt64(&c.stats.Misses, 1)
+		atomic.AddInt64(&c.stats.Expirations, 1)
+		return nil, ErrNotFound
+	}
+
+	var value []byte
+	err := c.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(diskBucket).Get([]byte(key))
+		if len(v) < diskHeader {
+			return ErrNotFound
+		}
+		// Bolt's slices are only valid inside the transaction
+		value = append([]byte(nil), v[diskHeader:]...)
+		return nil
+	})
+	if err != nil {
+		atomic.AddInt64(&c.stats.Misses, 1)
+		return nil, err
+	}
+
+	c.lru.MoveToFront(elem)
+	atomic.AddInt64(&c.stats.Hits, 1)
+	return value, nil
+}
+
+// Set stores a value with optional TTL.
+func (c *DiskCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := int64(len(key) + len(value))
+	if c.cfg.MaxSizeBytes > 0 && size > c.cfg.MaxSizeBytes {
+		return ErrValueTooLarge
+	}
+
+	now := time.Now()
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = now.Add(ttl)
+	} else if c.cfg.DefaultTTL > 0 {
+		expiresAt = now.Add(c.cfg.DefaultTTL)
+	}
+
+	// Evict in the same transaction as the write
+	elem, exists := c.items[key]
+	var evict []*list.Element
+	if !exists {
+		entries, total := atomic.LoadInt64(&c.stats.Size), atomic.LoadInt64(&c.stats.SizeBytes)
+		for e := c.lru.Back(); e != nil && c.overLimit(entries, total+size); e = e.Prev() {
+			evict = append(evict, e)
+			entries--
+			total -= e.Value.(*diskItem).size
+		}
+	}
+
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(diskBucket)
+		for _, e := range evict {
+			if err := b.Delete([]byte(e.Value.(*diskItem).key)); err != nil {
+				return err
+			}
+		}
+		return b.Put([]byte(key), encodeEntry(value, now, expiresAt))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range evict {
+		c.unindex(e)
+		atomic.AddInt64(&c.stats.Evictions, 1)
+	}
+	item := &diskItem{key: key, size: size, expiresAt: expiresAt}
+	if exists {
+		atomic.AddInt64(&c.stats.SizeBytes, size-elem.Value.(*diskItem).size)
+		elem.Value = item
+		c.lru.MoveToFront(elem)
+	} else {
+		c.items[key] = c.lru.PushFront(item)
+		atomic.AddInt64(&c.stats.Size, 1)
+		atomic.AddInt64(&c.stats.SizeBytes, size)
+	}
+	atomic.AddInt64(&c.stats.Sets, 1)
+	return nil
+}
+
+// overLimit reports whether one more entry is too many for a cache of
+// entries, or total bytes including the new entry too large.
+func (c *DiskCache) overLimit(entries, total int64) bool {
+	if c.cfg.MaxSize > 0 && entries >= c.cfg.MaxSize {
+		return true
+	}
+	return c.cfg.MaxSizeBytes > 0 && total > c.cfg.MaxSizeBytes
+}
+
+// Delete removes a key from the cache.
+func (c *DiskCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := c.remove([]*list.Element{elem}); err != nil {
+		return err
+	}
+	atomic.AddInt64(&c.stats.Deletes, 1)
+	return nil
+}
+
+// Has checks if a key exists.
+func (c *DiskCache) Has(ctx context.Context, key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	item := elem.Value.(*diskItem)
+	return item.expiresAt.IsZero() || !time.Now().After(item.expiresAt)
+}
+
+// Clear removes all entries.
+func (c *DiskCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(diskBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(diskBucket)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+	atomic.StoreInt64(&c.stats.Size, 0)
+	atomic.StoreInt64(&c.stats.SizeBytes, 0)
+	return nil
+}
+
+// Stats returns cache statistics. SizeBytes counts keys and values, not
+// the file's size on disk.
+func (c *DiskCache) Stats() Stats {
+	return Stats{
+		Hits:         atomic.LoadInt64(&c.stats.Hits),
+		Misses:       atomic.LoadInt64(&c.stats.Misses),
+		Sets:         atomic.LoadInt64(&c.stats.Sets),
+		Deletes:      atomic.LoadInt64(&c.stats.Deletes),
+		Evictions:    atomic.LoadInt64(&c.stats.Evictions),
+		Expirations:  atomic.LoadInt64(&c.stats.Expirations),
+		Size:         atomic.LoadInt64(&c.stats.Size),
+		SizeBytes:    atomic.LoadInt64(&c.stats.SizeBytes),
+		MaxSize:      c.cfg.MaxSize,
+		MaxSizeBytes: c.cfg.MaxSizeBytes,
+	}
+}
+
+// Close stops the cleanup goroutine and closes the file.
+func (c *DiskCache) Close() error {
+	if !c.stopped.CompareAndSwap(false, true) {
+		return nil
+	}
+	close(c.stopCh)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.db.Close()
+}
+
+// remove deletes elems from disk and the index.
+func (c *DiskCache) remove(elems []*list.Element) error {
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(diskBucket)
+		for _, e := range elems {
+			if err := b.Delete([]byte(e.Value.(*diskItem).key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, e := range elems {
+		c.unindex(e)
+	}
+	return nil
+}
+
+// unindex removes an element from the in-memory index.
+func (c *DiskCache) unindex(elem *list.Element) {
+	item := elem.Value.(*diskItem)
+	delete(c.items, item.key)
+	c.lru.Remove(elem)
+	atomic.AddInt64(&c.stats.Size, -1)
+	atomic.AddInt64(&c.stats.SizeBytes, -item.size)
+}
+
+// cleanupLoop periodically removes expired entries.
+func (c *DiskCache) cleanupLoop() {
+	ticker := time.NewTicker(c.cfg.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.cleanup()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// cleanup removes expired entries.
+func (c *DiskCache) cleanup() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped.Load() {
+		return
+	}
+
+	now := time.Now()
+	var expired []*list.Element
+	for elem := c.lru.Back(); elem != nil; elem = elem.Prev() {
+		item := elem.Value.(*diskItem)
+		if !item.expiresAt.IsZero() && now.After(item.expiresAt) {
+			expired = append(expired, elem)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	if err := c.remove(expired); err == nil {
+		atomic.AddInt64(&c.stats.Expirations, int64(len(expired)))
+	}
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDiskCache(t *testing.T, path string, cfg Config) *DiskCache {
+	t.Helper()
+	c, err := NewDiskCache(path, cfg)
+	if err != nil {
+		t.Fatalf("NewDiskCache: %v", err)
+	}
+	return c
+}
+
+func TestDiskCache_GetSetDelete(t *testing.T) {
+	c := newTestDiskCache(t, filepath.Join(t.TempDir(), "cache.db"), Config{MaxSize: 100})
+	defer func() { _ = c.Close() }()
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "key1", []byte("value1"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	value, err := c.Get(ctx, "key1")
+	if err != nil || string(value) != "value1" {
+		t.Fatalf("Get = %q, %v; want value1", value, err)
+	}
+	if _, err := c.Get(ctx, "missing"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if !c.Has(ctx, "key1") {
+		t.Error("Has(key1) = false")
+	}
+
+	if err := c.Set(ctx, "key1", []byte("v2"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if st := c.Stats(); st.Size != 1 || st.SizeBytes != int64(len("key1")+len("v2")) {
+		t.Errorf("after overwrite size = %d entries, %d bytes", st.Size, st.SizeBytes)
+	}
+
+	if err := c.Delete(ctx, "key1"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Has(ctx, "key1") {
+		t.Error("key1 still present after Delete")
+	}
+	if err := c.Delete(ctx, "key1"); err != ErrNotFound {
+		t.Errorf("second Delete = %v, want ErrNotFound", err)
+	}
+
+	st := c.Stats()
+	if st.Hits != 1 || st.Misses != 1 || st.Sets != 2 || st.Deletes != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestDiskCache_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	ctx := context.Background()
+
+	c := newTestDiskCache(t, path, Config{MaxSize: 100})
+	_ = c.Set(ctx, "kept", []byte("value"), 0)
+	_ = c.Set(ctx, "expiring", []byte("value"), 20*time.Millisecond)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	c = newTestDiskCache(t, path, Config{MaxSize: 100})
+	defer func() { _ = c.Close() }()
+	if value, err := c.Get(ctx, "kept"); err != nil || string(value) != "value" {
+		t.Errorf("Get(kept) after reopen = %q, %v", value, err)
+	}
+	if c.Has(ctx, "expiring") {
+		t.Error("expired entry loaded after reopen")
+	}
+	if st := c.Stats(); st.Size != 1 {
+		t.Errorf("Size after reopen = %d, want 1", st.Size)
+	}
+}
+
+func TestDiskCache_Eviction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	ctx := context.Background()
+
+	c := newTestDiskCache(t, path, Config{MaxSize: 3})
+	for i := 0; i < 3; i++ {
+		_ = c.Set(ctx, fmt.Sprintf("key%d", i), []byte("value"), 0)
+	}
+	// key0 becomes the most recently used, so key1 is evicted
+	_, _ = c.Get(ctx, "key0")
+	_ = c.Set(ctx, "key3", []byte("value"), 0)
+
+	if c.Has(ctx, "key1") {
+		t.Error("key1 should have been evicted")
+	}
+	for _, key := range []string{"key0", "key2", "key3"} {
+		if !c.Has(ctx, key) {
+			t.Errorf("%s missing", key)
+		}
+	}
+	if st := c.Stats(); st.Evictions != 1 || st.Size != 3 {
+		t.Errorf("stats = %+v", st)
+	}
+	_ = c.Close()
+
+	// The eviction is on disk too
+	c = newTestDiskCache(t, path, Config{MaxSize: 3})
+	defer func() { _ = c.Close() }()
+	if c.Has(ctx, "key1") {
+		t.Error("evicted key1 present after reopen")
+	}
+	if st := c.Stats(); st.Size != 3 {
+		t.Errorf("Size after reopen = %d, want 3", st.Size)
+	}
+}
+
+func TestDiskCache_MaxSizeBytes(t *testing.T) {
+	c := newTestDiskCache(t, filepath.Join(t.TempDir(), "cache.db"), Config{MaxSize: 100, MaxSizeBytes: 20})
+	defer func() { _ = c.Close() }()
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "big", make([]byte, 30), 0); err != ErrValueTooLarge {
+		t.Errorf("Set oversized = %v, want ErrValueTooLarge", err)
+	}
+	_ = c.Set(ctx, "a", make([]byte, 10), 0)
+	_ = c.Set(ctx, "b", make([]byte, 10), 0)
+	if c.Has(ctx, "a") || !c.Has(ctx, "b") {
+		t.Error("expected a evicted to fit b")
+	}
+}
+
+func TestDiskCache_Clear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	c := newTestDiskCache(t, path, Config{})
+	ctx := context.Background()
+	_ = c.Set(ctx, "key", []byte("value"), 0)
+	if err := c.Clear(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Has(ctx, "key") || c.Stats().Size != 0 {
+		t.Error("entries remain after Clear")
+	}
+	_ = c.Close()
+
+	c = newTestDiskCache(t, path, Config{})
+	defer func() { _ = c.Close() }()
+	if c.Stats().Size != 0 {
+		t.Error("cleared entries reloaded")
+	}
+}
+
+func TestDiskCache_Interface(t *testing.T) {
+	var _ Cache = (*DiskCache)(nil)
+}
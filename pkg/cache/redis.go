@@ -2,22 +2,29 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync/atomic"
 	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 // RedisConfig holds Redis connection configuration.
 type RedisConfig struct {
 	// URL is the Redis connection URL (e.g., redis://localhost:6379).
+	// rediss:// connects over TLS.
 	URL string
 
-	// Password for Redis authentication.
+	// Password for Redis authentication. Overrides a password in URL.
 	Password string
 
-	// DB is the Redis database number.
+	// DB is the Redis database number. Overrides a database in URL when
+	// non-zero.
 	DB int
 
-	// KeyPrefix is prepended to all keys.
+	// KeyPrefix is prepended to all keys. Clear removes only keys with
+	// the prefix, so it must not be empty.
 	KeyPrefix string
 
 	// DefaultTTL is the default expiration for keys.
@@ -50,65 +57,200 @@ func DefaultRedisConfig() RedisConfig {
 	}
 }
 
-// RedisCache implements Cache using Redis as the backend.
-// This is a stub implementation - actual Redis client integration
-// would require adding a Redis client dependency (e.g., go-redis).
+// clearBatch is the number of keys each SCAN step of Clear asks for.
+const clearBatch = 1000
+
+// errNoPrefix is returned by Clear when KeyPrefix is empty, which would
+// clear the whole database.
+var errNoPrefix = errors.New("redis cache: refusing to clear without a key prefix")
+
+// RedisCache implements Cache using Redis as the backend, so several
+// replicas share one cache. Redis enforces memory limits with its own
+// eviction policy; Stats counts only this process's operations.
 type RedisCache struct {
-	cfg   RedisConfig
-	stats Stats
+	cfg    RedisConfig
+	client *redis.Client
+	stats  Stats
 }
 
-// NewRedisCache creates a new Redis-backed cache.
-// Note: This is a placeholder. Full implementation requires a Redis client.
+// NewRedisCache connects to Redis and checks the connection with Ping.
 func NewRedisCache(cfg RedisConfig) (*RedisCache, error) {
-	// In a full implementation, this would:
-	// 1. Parse the Redis URL
-	// 2. Create a connection pool
-	// 3. Test the connection
-	//
-	// For now, we return a stub that can be extended.
+	if cfg.URL == "" {
+		cfg.URL = DefaultRedisConfig().URL
+	}
+	opts, err := redis.ParseURL(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("redis cache: %w", err)
+	}
+	if cfg.Password != "" {
+		opts.Password = cfg.Password
+	}
+	if cfg.DB != 0 {
+		opts.DB = cfg.DB
+	}
+	if cfg.PoolSize > 0 {
+		opts.PoolSize = cfg.PoolSize
+	}
+	if cfg.DialTimeout > 0 {
+		opts.DialTimeout = cfg.DialTimeout
+	}
+	if cfg.ReadTimeout > 0 {
+		opts.ReadTimeout = cfg.ReadTimeout
+	}
+	if cfg.WriteTimeout > 0 {
+		opts.WriteTimeout = cfg.WriteTimeout
+	}
+
+	c := &RedisCache{cfg: cfg, client: redis.NewClient(opts)}
+	ctx := context.Background()
+	if opts.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.DialTimeout)
+		defer cancel()
+	}
+	if err := c.Ping(ctx); err != nil {
+		_ = c.client.Close()
+		return nil, err
+	}
+	return c, nil
+}
 
-	return &RedisCache{
-		cfg: cfg,
-	}, nil
+// Ping checks that Redis is reachable.
+func (c *RedisCache) Ping(ctx context.Context) error {
+	if err := c.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis cache: ping: %w", err)
+	}
+	return nil
 }
 
 // Get retrieves a value by key.
 func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
-	// Stub: In full implementation, this would call Redis GET
-	atomic.AddInt64(&c.stats.Misses, 1)
-	return nil, ErrNotFound
+	value, err := c.client.Get(ctx, c.PrefixKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		atomic.AddInt64(&c.stats.Misses, 1)
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		atomic.AddInt64(&c.stats.Misses, 1)
+		return nil, fmt.Errorf("redis cache: get: %w", err)
+	}
+	atomic.AddInt64(&c.stats.Hits, 1)
+	return value, nil
+}
+
+// GetMulti retrieves several keys with one MGET. Missing keys are absent
+// from the result.
+func (c *RedisCache) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if len(keys) == 0 {
+		return map[string][]byte{}, nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.PrefixKey(key)
+	}
+	values, err := c.client.MGet(ctx, prefixed...).Result()
+	if err != nil {
+		atomic.AddInt64(&c.stats.Misses, int64(len(keys)))
+		return nil, fmt.Errorf("redis cache: mget: %w", err)
+	}
+
+	found := make(map[string][]byte, len(keys))
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		found[keys[i]] = []byte(s)
+	}
+	atomic.AddInt64(&c.stats.Hits, int64(len(found)))
+	atomic.AddInt64(&c.stats.Misses, int64(len(keys)-len(found)))
+	return found, nil
 }
 
 // Set stores a value with optional TTL.
 func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
-	// Stub: In full implementation, this would call Redis SET with EX
+	if err := c.client.Set(ctx, c.PrefixKey(key), value, c.GetTTL(ttl)).Err(); err != nil {
+		return fmt.Errorf("redis cache: set: %w", err)
+	}
 	atomic.AddInt64(&c.stats.Sets, 1)
 	return nil
 }
 
+// SetMulti stores several values with the same TTL in one pipelined
+// round trip.
+func (c *RedisCache) SetMulti(ctx context.Context, entries map[string][]byte, ttl time.Duration) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	ttl = c.GetTTL(ttl)
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range entries {
+			pipe.Set(ctx, c.PrefixKey(key), value, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis cache: set: %w", err)
+	}
+	atomic.AddInt64(&c.stats.Sets, int64(len(entries)))
+	return nil
+}
+
 // Delete removes a key from the cache.
 func (c *RedisCache) Delete(ctx context.Context, key string) error {
-	// Stub: In full implementation, this would call Redis DEL
+	n, err := c.client.Del(ctx, c.PrefixKey(key)).Result()
+	if err != nil {
+		return fmt.Errorf("redis cache: del: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
 	atomic.AddInt64(&c.stats.Deletes, 1)
 	return nil
 }
 
 // Has checks if a key exists.
 func (c *RedisCache) Has(ctx context.Context, key string) bool {
-	// Stub: In full implementation, this would call Redis EXISTS
-	return false
+	n, err := c.client.Exists(ctx, c.PrefixKey(key)).Result()
+	return err == nil && n > 0
 }
 
-// Clear removes all entries with the configured prefix.
+// Clear removes all entries with the configured prefix, scanning in
+// batches and deleting each batch with one UNLINK.
 func (c *RedisCache) Clear(ctx context.Context) error {
-	// Stub: In full implementation, this would use SCAN + DEL
+	if c.cfg.KeyPrefix == "" {
+		return errNoPrefix
+	}
+	iter := c.client.Scan(ctx, 0, c.cfg.KeyPrefix+"*", clearBatch).Iterator()
+	batch := make([]string, 0, clearBatch)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := c.client.Unlink(ctx, batch...).Err()
+		batch = batch[:0]
+		return err
+	}
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == clearBatch {
+			if err := flush(); err != nil {
+				return fmt.Errorf("redis cache: clear: %w", err)
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("redis cache: clear: %w", err)
+	}
+	if err := flush(); err != nil {
+		return fmt.Errorf("redis cache: clear: %w", err)
+	}
 	return nil
 }
 
-// Stats returns cache statistics.
+// Stats returns this process's cache statistics. Size and SizeBytes are
+// not tracked, since the keys are shared with other processes.
 func (c *RedisCache) Stats() Stats {
-	// Stub: In full implementation, this would query Redis INFO
 	return Stats{
 		Hits:    atomic.LoadInt64(&c.stats.Hits),
 		Misses:  atomic.LoadInt64(&c.stats.Misses),
@@ -119,8 +261,7 @@ func (c *RedisCache) Stats() Stats {
 
 // Close releases the Redis connection pool.
 func (c *RedisCache) Close() error {
-	// Stub: In full implementation, this would close the connection pool
-	return nil
+	return c.client.Close()
 }
 
 // PrefixKey adds the configured prefix to a key.
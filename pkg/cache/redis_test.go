@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedisCache(t *testing.T, prefix string) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	cfg := DefaultRedisConfig()
+	cfg.URL = "redis://" + srv.Addr()
+	cfg.KeyPrefix = prefix
+	c, err := NewRedisCache(cfg)
+	if err != nil {
+		t.Fatalf("NewRedisCache: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c, srv
+}
+
+func TestRedisCache_GetSetDelete(t *testing.T) {
+	c, srv := newTestRedisCache(t, "distill:")
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "key1", []byte("value1"), time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got, err := srv.Get("distill:key1"); err != nil || got != "value1" {
+		t.Errorf("stored %q, %v; want prefixed key1", got, err)
+	}
+	if ttl := srv.TTL("distill:key1"); ttl != time.Minute {
+		t.Errorf("TTL = %v, want 1m", ttl)
+	}
+
+	value, err := c.Get(ctx, "key1")
+	if err != nil || string(value) != "value1" {
+		t.Fatalf("Get = %q, %v", value, err)
+	}
+	if _, err := c.Get(ctx, "missing"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if !c.Has(ctx, "key1") || c.Has(ctx, "missing") {
+		t.Error("Has reported wrong presence")
+	}
+
+	if err := c.Delete(ctx, "key1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, "key1"); err != ErrNotFound {
+		t.Errorf("second Delete = %v, want ErrNotFound", err)
+	}
+
+	st := c.Stats()
+	if st.Hits != 1 || st.Misses != 1 || st.Sets != 1 || st.Deletes != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestRedisCache_DefaultTTL(t *testing.T) {
+	c, srv := newTestRedisCache(t, "distill:")
+	if err := c.Set(context.Background(), "key", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := srv.TTL("distill:key"); ttl != time.Hour {
+		t.Errorf("TTL = %v, want the 1h default", ttl)
+	}
+
+	c.cfg.DefaultTTL = 0
+	if err := c.Set(context.Background(), "forever", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := srv.TTL("distill:forever"); ttl != 0 {
+		t.Errorf("TTL = %v, want none", ttl)
+	}
+}
+
+func TestRedisCache_Multi(t *testing.T) {
+	c, _ := newTestRedisCache(t, "distill:")
+	ctx := context.Background()
+
+	if err := c.SetMulti(ctx, map[string][]byte{"a": []byte("1"), "b": []byte("2")}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	found, err := c.GetMulti(ctx, []string{"a", "missing", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || string(found["a"]) != "1" || string(found["b"]) != "2" {
+		t.Errorf("GetMulti = %q", found)
+	}
+	if st := c.Stats(); st.Hits != 2 || st.Misses != 1 || st.Sets != 2 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestRedisCache_ClearKeepsOtherPrefixes(t *testing.T) {
+	c, srv := newTestRedisCache(t, "distill:results:")
+	ctx := context.Background()
+	for i := 0; i < 2500; i++ {
+		_ = srv.Set(fmt.Sprintf("distill:results:%d", i), "v")
+	}
+	_ = srv.Set("distill:embeddings:q", "v")
+	_ = srv.Set("other", "v")
+
+	if err := c.Clear(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if keys := srv.Keys(); len(keys) != 2 {
+		t.Errorf("keys after Clear = %d, want the 2 outside the prefix", len(keys))
+	}
+
+	c.cfg.KeyPrefix = ""
+	if err := c.Clear(ctx); err != errNoPrefix {
+		t.Errorf("Clear without prefix = %v, want errNoPrefix", err)
+	}
+}
+
+func TestRedisCache_Unreachable(t *testing.T) {
+	srv := miniredis.RunT(t)
+	addr := srv.Addr()
+	srv.Close()
+
+	cfg := DefaultRedisConfig()
+	cfg.URL = "redis://" + addr
+	cfg.DialTimeout = 100 * time.Millisecond
+	if _, err := NewRedisCache(cfg); err == nil {
+		t.Fatal("expected an error connecting to a closed server")
+	}
+
+	cfg.URL = "http://" + addr
+	if _, err := NewRedisCache(cfg); err == nil {
+		t.Fatal("expected an error for a non-redis URL")
+	}
+}
+
+func TestRedisCache_Ping(t *testing.T) {
+	c, srv := newTestRedisCache(t, "distill:")
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := c.Ping(ctx); err == nil {
+		t.Error("expected Ping to fail after the server stopped")
+	}
+}
+
+func TestSentFilter_Batch(t *testing.T) {
+	c, srv := newTestRedisCache(t, "distill:sent:")
+	f := NewSentFilter(c, time.Minute)
+	ctx := context.Background()
+	chunks := []types.Chunk{{ID: "1", Text: "alpha"}, {ID: "2", Text: "beta"}}
+
+	if err := f.Record(ctx, "s1", chunks[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if keys := srv.Keys(); len(keys) != 1 {
+		t.Fatalf("recorded %d keys, want 1", len(keys))
+	}
+	out, repeated := f.Filter(ctx, "s1", chunks, false)
+	if repeated != 1 || len(out) != 1 || out[0].ID != "2" {
+		t.Errorf("Filter = %d chunks, %d repeated", len(out), repeated)
+	}
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// DefaultSentTTL is how long a chunk counts as "already sent" in a session.
+const DefaultSentTTL = 30 * time.Minute
+
+// AlreadySentKey is the chunk metadata key set when a repeated chunk is
+// marked rather than excluded.
+const AlreadySentKey = "already_sent"
+
+// SentFilter tracks which chunks have already been returned to a session so
+// agents that retrieve the same top chunks turn after turn do not resend
+// them. Entries are stored in a Cache with a TTL, so a chunk becomes
+// eligible again once it has not been sent for the TTL window.
+type SentFilter struct {
+	cache Cache
+	ttl   time.Duration
+}
+
+// NewSentFilter creates a filter backed by c. Pass 0 to use DefaultSentTTL.
+func NewSentFilter(c Cache, ttl time.Duration) *SentFilter {
+	if ttl <= 0 {
+		ttl = DefaultSentTTL
+	}
+	return &SentFilter{cache: c, ttl: ttl}
+}
+
+// ChunkHash returns a stable content hash for a chunk. Text is used when
+// present so the same content under different IDs is recognized; otherwise
+// the ID is hashed.
+func ChunkHash(c types.Chunk) string {
+	key := strings.TrimSpace(c.Text)
+	if key == "" {
+		key = "id:" + c.ID
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:32]
+}
+
+func (f *SentFilter) key(sessionID, hash string) string {
+	return "sent:" + sessionID + ":" + hash
+}
+
+// Filter checks chunks against those already sent in the session. When mark
+// is false, repeated chunks are removed; when true, they are kept and
+// tagged with Metadata[AlreadySentKey] = true. Returns the resulting chunks
+// and the number of repeats found. An empty sessionID disables filtering.
+func (f *SentFilter) Filter(ctx context.Context, sessionID string, chunks []types.Chunk, mark bool) ([]types.Chunk, int) {
+	if f == nil || sessionID == "" || len(chunks) == 0 {
+		return chunks, 0
+	}
+
+	keys := make([]string, len(chunks))
+	for i, c := range chunks {
+		keys[i] = f.key(sessionID, ChunkHash(c))
+	}
+	sent := f.sent(ctx, keys)
+
+	out := make([]types.Chunk, 0, len(chunks))
+	repeated := 0
+	for i, c := range chunks {
+		if !sent(keys[i]) {
+			out = append(out, c)
+			continue
+		}
+		repeated++
+		if mark {
+			meta := make(map[string]interface{}, len(c.Metadata)+1)
+			for k, v := range c.Metadata {
+				meta[k] = v
+			}
+			meta[AlreadySentKey] = true
+			c.Metadata = meta
+			out = append(out, c)
+		}
+	}
+	return out, repeated
+}
+
+// sent returns a lookup of which keys are in the cache, fetching them in
+// one batch when the cache supports it. A failed batch counts as none
+// sent, like a miss.
+func (f *SentFilter) sent(ctx context.Context, keys []string) func(string) bool {
+	if bc, ok := f.cache.(BatchCache); ok {
+		found, err := bc.GetMulti(ctx, keys)
+		if err != nil {
+			found = nil
+		}
+		return func(key string) bool {
+			_, ok := found[key]
+			return ok
+		}
+	}
+	return func(key string) bool { return f.cache.Has(ctx, key) }
+}
+
+// Record marks chunks as sent to the session, refreshing the TTL of any
+// that were sent before.
+func (f *SentFilter) Record(ctx context.Context, sessionID string, chunks []types.Chunk) error {
+	if f == nil || sessionID == "" {
+		return nil
+	}
+	if bc, ok := f.cache.(BatchCache); ok {
+		entries := make(map[string][]byte, len(chunks))
+		for _, c := range chunks {
+			entries[f.key(sessionID, ChunkHash(c))] = []byte{1}
+		}
+		return bc.SetMulti(ctx, entries, f.ttl)
+	}
+	for _, c := range chunks {
+		if err := f.cache.Set(ctx, f.key(sessionID, ChunkHash(c)), []byte{1}, f.ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsAlreadySent reports whether a chunk was tagged by Filter in mark mode.
+func IsAlreadySent(c types.Chunk) bool {
+	v, _ := c.Metadata[AlreadySentKey].(bool)
+	return v
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestSentFilter_ExcludesRepeats(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(DefaultConfig())
+	defer func() { _ = c.Close() }()
+	f := NewSentFilter(c, time.Minute)
+
+	first := []types.Chunk{{ID: "a", Text: "alpha"}, {ID: "b", Text: "beta"}}
+	out, repeated := f.Filter(ctx, "s1", first, false)
+	if len(out) != 2 || repeated != 0 {
+		t.Fatalf("first turn: got %d chunks, %d repeated", len(out), repeated)
+	}
+	if err := f.Record(ctx, "s1", out); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	// Same text under a new ID is still a repeat.
+	second := []types.Chunk{{ID: "a2", Text: "alpha"}, {ID: "c", Text: "gamma"}}
+	out, repeated = f.Filter(ctx, "s1", second, false)
+	if repeated != 1 || len(out) != 1 || out[0].ID != "c" {
+		t.Errorf("second turn: got %v, %d repeated", out, repeated)
+	}
+
+	// Other sessions are unaffected.
+	out, repeated = f.Filter(ctx, "s2", second, false)
+	if repeated != 0 || len(out) != 2 {
+		t.Errorf("other session: got %d chunks, %d repeated", len(out), repeated)
+	}
+}
+
+func TestSentFilter_MarkMode(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(DefaultConfig())
+	defer func() { _ = c.Close() }()
+	f := NewSentFilter(c, time.Minute)
+
+	chunks := []types.Chunk{{ID: "a", Text: "alpha"}}
+	_ = f.Record(ctx, "s1", chunks)
+
+	out, repeated := f.Filter(ctx, "s1", chunks, true)
+	if repeated != 1 || len(out) != 1 {
+		t.Fatalf("expected 1 marked chunk, got %d (%d repeated)", len(out), repeated)
+	}
+	if !IsAlreadySent(out[0]) {
+		t.Error("expected chunk to be marked already_sent")
+	}
+}
+
+func TestSentFilter_TTLExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(DefaultConfig())
+	defer func() { _ = c.Close() }()
+	f := NewSentFilter(c, 20*time.Millisecond)
+
+	chunks := []types.Chunk{{ID: "a", Text: "alpha"}}
+	_ = f.Record(ctx, "s1", chunks)
+	time.Sleep(40 * time.Millisecond)
+
+	if _, repeated := f.Filter(ctx, "s1", chunks, false); repeated != 0 {
+		t.Error("expected chunk to be eligible again after TTL")
+	}
+}
+
+func TestSentFilter_NoSession(t *testing.T) {
+	var f *SentFilter
+	chunks := []types.Chunk{{ID: "a"}}
+	out, repeated := f.Filter(context.Background(), "", chunks, false)
+	if len(out) != 1 || repeated != 0 {
+		t.Error("nil filter should pass chunks through")
+	}
+}
@@ -0,0 +1,325 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type tenantKey struct{}
+
+// WithTenant returns ctx marking cache operations as tenant's, for a
+// TenantCache.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant ctx marks, or "".
+func TenantFrom(ctx context.Context) string {
+	t, _ := ctx.Value(tenantKey{}).(string)
+	return t
+}
+
+// DefaultMaxTenants is the number of tenant partitions a TenantCache
+// keeps when TenantConfig.MaxTenants is 0.
+const DefaultMaxTenants = 1024
+
+// TenantConfig sets each tenant's share of a TenantCache.
+type TenantConfig struct {
+	// MaxBytes is the most value bytes a tenant may keep cached through
+	// this TenantCache (0 = unlimited). Other processes sharing the inner
+	// cache count their own.
+	MaxBytes int64
+
+	// Quotas overrides MaxBytes by tenant.
+	Quotas map[string]int64
+
+	// MaxEntries caps the entries tracked across tenants (0 = unlimited).
+	// Set it to the inner cache's capacity: past it, the inner cache has
+	// evicted entries the partitions would still count, so the least
+	// recently used are forgotten.
+	MaxEntries int
+
+	// MaxTenants caps the partitions kept (0 = DefaultMaxTenants). Past
+	// it, the least recently used tenant's partition is forgotten with
+	// its stats; its values stay cached until they expire or are
+	// evicted.
+	MaxTenants int
+}
+
+// quota returns tenant's byte quota, 0 for unlimited.
+func (c TenantConfig) quota(tenant string) int64 {
+	if q, ok := c.Quotas[tenant]; ok {
+		return q
+	}
+	return c.MaxBytes
+}
+
+// TenantStats describes one tenant's partition of a TenantCache.
+type TenantStats struct {
+	Entries   int64 `json:"entries"`
+	Bytes     int64 `json:"bytes"`
+	MaxBytes  int64 `json:"max_bytes"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+}
+
+// TenantCache partitions a Cache by the tenant in each call's context
+// (see WithTenant): keys are prefixed with the tenant, and a tenant over
+// its byte quota has its own least recently used entries evicted, so
+// one tenant's large values cannot push out another's. The partitions
+// are tracked in memory, so with a cache shared by several processes,
+// such as Redis, each process enforces quotas on what it wrote.
+// Tracking is bounded by TenantConfig.MaxEntries and MaxTenants, and
+// expired entries are pruned as entries are set.
+type TenantCache struct {
+	inner Cache
+	cfg   TenantConfig
+
+	mu      sync.Mutex
+	tenants map[string]*tenantPartition
+
+	// order holds the partitions and all the tracked entries, each most
+	// recently used first.
+	order *list.List
+	all   *list.List
+
+	// sets counts Sets since expired entries were last pruned.
+	sets int
+}
+
+// tenantPartition is one tenant's entries, most recently used first.
+type tenantPartition struct {
+	tenant  string
+	lru     *list.List
+	entries map[string]*list.Element
+	stats   TenantStats
+	order   *list.Element
+}
+
+// tenantEntry is a tracked entry of a partition, listed in both the
+// partition's lru and the cache's all.
+type tenantEntry struct {
+	part      *tenantPartition
+	key       string
+	size      int64
+	expiresAt time.Time
+	elem      *list.Element
+	all       *list.Element
+}
+
+// NewTenantCache partitions inner by tenant with cfg's quotas.
+func NewTenantCache(inner Cache, cfg TenantConfig) *TenantCache {
+	if cfg.MaxTenants <= 0 {
+		cfg.MaxTenants = DefaultMaxTenants
+	}
+	return &TenantCache{
+		inner:   inner,
+		cfg:     cfg,
+		tenants: make(map[string]*tenantPartition),
+		order:   list.New(),
+		all:     list.New(),
+	}
+}
+
+// tenantPrefix prefixes a tenant's keys. The length keeps tenants whose
+// names contain ":" apart.
+func tenantPrefix(tenant string) string {
+	return fmt.Sprintf("tenant:%d:%s:", len(tenant), tenant)
+}
+
+// partition returns tenant's partition, creating it and forgetting the
+// least recently used partitions over MaxTenants. c.mu must be held.
+func (c *TenantCache) partition(tenant string) *tenantPartition {
+	if p, ok := c.tenants[tenant]; ok {
+		c.order.MoveToFront(p.order)
+		return p
+	}
+	p := &tenantPartition{tenant: tenant, lru: list.New(), entries: make(map[string]*list.Element)}
+	p.order = c.order.PushFront(p)
+	c.tenants[tenant] = p
+	for len(c.tenants) > c.cfg.MaxTenants {
+		c.forget(c.order.Back().Value.(*tenantPartition))
+	}
+	return p
+}
+
+// forget stops tracking p and its entries. c.mu must be held.
+func (c *TenantCache) forget(p *tenantPartition) {
+	for elem := p.lru.Front(); elem != nil; elem = elem.Next() {
+		c.all.Remove(elem.Value.(*tenantEntry).all)
+	}
+	c.order.Remove(p.order)
+	delete(c.tenants, p.tenant)
+}
+
+// track starts tracking key in p. c.mu must be held.
+func (c *TenantCache) track(p *tenantPartition, key string, size int64, ttl time.Duration) {
+	e := &tenantEntry{part: p, key: key, size: size}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	e.elem = p.lru.PushFront(e)
+	e.all = c.all.PushFront(e)
+	p.entries[key] = e.elem
+	p.stats.Entries++
+	p.stats.Bytes += size
+}
+
+// untrack stops tracking e. c.mu must be held.
+func (c *TenantCache) untrack(e *tenantEntry) {
+	p := e.part
+	p.lru.Remove(e.elem)
+	c.all.Remove(e.all)
+	delete(p.entries, e.key)
+	p.stats.Entries--
+	p.stats.Bytes -= e.size
+}
+
+// pruneExpired stops tracking expired entries. c.mu must be held.
+func (c *TenantCache) pruneExpired() {
+	now := time.Now()
+	for elem := c.all.Front(); elem != nil; {
+		next := elem.Next()
+		if e := elem.Value.(*tenantEntry); e.expiredAt(now) {
+			c.untrack(e)
+		}
+		elem = next
+	}
+	c.sets = 0
+}
+
+// Get retrieves a value from the context's tenant's partition.
+func (c *TenantCache) Get(ctx context.Context, key string) ([]byte, error) {
+	tenant := TenantFrom(ctx)
+	value, err := c.inner.Get(ctx, tenantPrefix(tenant)+key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.partition(tenant)
+	elem, tracked := p.entries[key]
+	switch {
+	case err != nil:
+		p.stats.Misses++
+		// Expired or evicted by the inner cache
+		if tracked {
+			c.untrack(elem.Value.(*tenantEntry))
+		}
+	case tracked:
+		p.stats.Hits++
+		p.lru.MoveToFront(elem)
+		c.all.MoveToFront(elem.Value.(*tenantEntry).all)
+	default:
+		// Written by another process sharing the cache
+		p.stats.Hits++
+	}
+	return value, err
+}
+
+// Set stores a value in the context's tenant's partition, first evicting
+// the tenant's least recently used entries until it fits the quota. A
+// value larger than the whole quota is not stored.
+func (c *TenantCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	tenant := TenantFrom(ctx)
+	quota := c.cfg.quota(tenant)
+	size := int64(len(value))
+	if quota > 0 && size > quota {
+		return ErrValueTooLarge
+	}
+
+	c.mu.Lock()
+	p := c.partition(tenant)
+	if elem, ok := p.entries[key]; ok {
+		c.untrack(elem.Value.(*tenantEntry))
+	}
+	// Pruning costs a pass over the tracked entries, so it runs once per
+	// that many Sets
+	if c.sets++; c.sets >= c.all.Len() {
+		c.pruneExpired()
+	}
+	var evict []string
+	for quota > 0 && p.stats.Bytes+size > quota && p.lru.Len() > 0 {
+		oldest := p.lru.Back().Value.(*tenantEntry)
+		c.untrack(oldest)
+		if !oldest.expiredAt(time.Now()) {
+			evict = append(evict, oldest.key)
+			p.stats.Evictions++
+		}
+	}
+	c.track(p, key, size, ttl)
+	// The inner cache has evicted what it cannot hold
+	for c.cfg.MaxEntries > 0 && c.all.Len() > c.cfg.MaxEntries {
+		c.untrack(c.all.Back().Value.(*tenantEntry))
+	}
+	c.mu.Unlock()
+
+	prefix := tenantPrefix(tenant)
+	for _, k := range evict {
+		_ = c.inner.Delete(ctx, prefix+k)
+	}
+	return c.inner.Set(ctx, prefix+key, value, ttl)
+}
+
+// Delete removes a key from the context's tenant's partition.
+func (c *TenantCache) Delete(ctx context.Context, key string) error {
+	tenant := TenantFrom(ctx)
+	c.mu.Lock()
+	if p, ok := c.tenants[tenant]; ok {
+		if elem, ok := p.entries[key]; ok {
+			c.untrack(elem.Value.(*tenantEntry))
+		}
+	}
+	c.mu.Unlock()
+	return c.inner.Delete(ctx, tenantPrefix(tenant)+key)
+}
+
+// Has checks the context's tenant's partition for key.
+func (c *TenantCache) Has(ctx context.Context, key string) bool {
+	return c.inner.Has(ctx, tenantPrefix(TenantFrom(ctx))+key)
+}
+
+// Clear removes every tenant's entries. Hit and miss counts are kept.
+func (c *TenantCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	for _, p := range c.tenants {
+		p.lru.Init()
+		clear(p.entries)
+		p.stats.Entries, p.stats.Bytes = 0, 0
+	}
+	c.all.Init()
+	c.sets = 0
+	c.mu.Unlock()
+	return c.inner.Clear(ctx)
+}
+
+// Stats returns the inner cache's statistics.
+func (c *TenantCache) Stats() Stats {
+	return c.inner.Stats()
+}
+
+// TenantStats returns each tenant's statistics, by tenant, without the
+// entries that have expired.
+func (c *TenantCache) TenantStats() map[string]TenantStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pruneExpired()
+	out := make(map[string]TenantStats, len(c.tenants))
+	for tenant, p := range c.tenants {
+		st := p.stats
+		st.MaxBytes = c.cfg.quota(tenant)
+		out[tenant] = st
+	}
+	return out
+}
+
+// Close closes the inner cache.
+func (c *TenantCache) Close() error {
+	return c.inner.Close()
+}
+
+func (e *tenantEntry) expiredAt(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
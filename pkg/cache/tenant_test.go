@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTenantCache_Partitions(t *testing.T) {
+	c := NewTenantCache(NewMemoryCache(Config{MaxSize: 100}), TenantConfig{})
+	defer func() { _ = c.Close() }()
+	acme := WithTenant(context.Background(), "acme")
+	globex := WithTenant(context.Background(), "globex")
+
+	if err := c.Set(acme, "k", []byte("acme"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(globex, "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("another tenant read acme's entry: %v", err)
+	}
+	if v, err := c.Get(acme, "k"); err != nil || string(v) != "acme" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+
+	st := c.TenantStats()
+	if st["acme"].Hits != 1 || st["acme"].Entries != 1 || st["acme"].Bytes != 4 || st["globex"].Misses != 1 {
+		t.Errorf("TenantStats = %+v", st)
+	}
+}
+
+func TestTenantCache_Quota(t *testing.T) {
+	c := NewTenantCache(NewMemoryCache(Config{MaxSize: 100}), TenantConfig{
+		MaxBytes: 10,
+		Quotas:   map[string]int64{"big": 100},
+	})
+	defer func() { _ = c.Close() }()
+	ctx := WithTenant(context.Background(), "small")
+	big := WithTenant(context.Background(), "big")
+	value := bytes.Repeat([]byte("x"), 4)
+
+	if err := c.Set(big, "b", bytes.Repeat([]byte("x"), 50), 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "b"} {
+		if err := c.Set(ctx, k, value, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a is used, so b is the least recently used when c needs room
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "c", value, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Has(ctx, "b") || !c.Has(ctx, "a") || !c.Has(ctx, "c") {
+		t.Error("expected b to be evicted")
+	}
+	if !c.Has(big, "b") {
+		t.Error("evicting small's entry evicted big's")
+	}
+	st := c.TenantStats()
+	if s := st["small"]; s.Bytes != 8 || s.Entries != 2 || s.Evictions != 1 || s.MaxBytes != 10 {
+		t.Errorf("small stats = %+v", s)
+	}
+	if s := st["big"]; s.Bytes != 50 || s.MaxBytes != 100 {
+		t.Errorf("big stats = %+v", s)
+	}
+
+	if err := c.Set(ctx, "huge", bytes.Repeat([]byte("x"), 11), 0); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Set over the quota = %v, want ErrValueTooLarge", err)
+	}
+
+	if err := c.Clear(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.TenantStats()["small"]; s.Bytes != 0 || s.Entries != 0 {
+		t.Errorf("stats after Clear = %+v", s)
+	}
+}
+
+func TestTenantCache_TrackingBounded(t *testing.T) {
+	// The inner cache holds 100 entries; without a quota, tracking must
+	// not outgrow it
+	c := NewTenantCache(NewMemoryCache(Config{MaxSize: 100}), TenantConfig{MaxEntries: 100})
+	defer func() { _ = c.Close() }()
+	ctx := WithTenant(context.Background(), "acme")
+	value := bytes.Repeat([]byte("x"), 100)
+	for i := 0; i < 100000; i++ {
+		if err := c.Set(ctx, fmt.Sprintf("k%d", i), value, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := c.TenantStats()["acme"]; st.Entries != 100 || st.Bytes != 100*100 {
+		t.Errorf("stats after 100000 Sets = %+v, want 100 entries", st)
+	}
+	if !c.Has(ctx, "k99999") {
+		t.Error("latest entry is missing")
+	}
+}
+
+func TestTenantCache_PrunesExpired(t *testing.T) {
+	c := NewTenantCache(NewMemoryCache(Config{}), TenantConfig{})
+	defer func() { _ = c.Close() }()
+	ctx := WithTenant(context.Background(), "acme")
+	for i := 0; i < 1000; i++ {
+		if err := c.Set(ctx, fmt.Sprintf("k%d", i), []byte("v"), time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// Sets prune as they go, so tracking stays near the live entries
+	for i := 0; i < 2000; i++ {
+		if err := c.Set(ctx, fmt.Sprintf("live%d", i%10), []byte("v"), time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.mu.Lock()
+	tracked := c.all.Len()
+	c.mu.Unlock()
+	if tracked != 10 {
+		t.Errorf("tracking %d entries, want the 10 live ones", tracked)
+	}
+	if st := c.TenantStats()["acme"]; st.Entries != 10 || st.Bytes != 10 {
+		t.Errorf("stats = %+v, want 10 entries", st)
+	}
+}
+
+func TestTenantCache_MaxTenants(t *testing.T) {
+	c := NewTenantCache(NewMemoryCache(Config{MaxSize: 1000}), TenantConfig{MaxTenants: 3})
+	defer func() { _ = c.Close() }()
+	for i := 0; i < 50; i++ {
+		ctx := WithTenant(context.Background(), fmt.Sprintf("ns%d", i))
+		if _, err := c.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+			t.Fatal(err)
+		}
+		if err := c.Set(ctx, "k", []byte("v"), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := c.TenantStats()
+	if len(st) != 3 {
+		t.Fatalf("kept %d partitions, want 3", len(st))
+	}
+	for _, tenant := range []string{"ns47", "ns48", "ns49"} {
+		if st[tenant].Entries != 1 {
+			t.Errorf("%s stats = %+v, want the latest tenants kept", tenant, st[tenant])
+		}
+	}
+	c.mu.Lock()
+	tracked := c.all.Len()
+	c.mu.Unlock()
+	if tracked != 3 {
+		t.Errorf("tracking %d entries of forgotten tenants", tracked-3)
+	}
+	// Forgetting a partition leaves its values cached
+	if !c.Has(WithTenant(context.Background(), "ns0"), "k") {
+		t.Error("forgotten tenant's value was deleted")
+	}
+}
@@ -0,0 +1,221 @@
+// Package capture is a flight recorder for anomalous requests. Requests
+// that are unusually slow or whose reduction falls outside the expected
+// range are recorded with their full dedup trace in a fixed-size ring
+// buffer, so rare production issues can be diagnosed after the fact
+// without logging every request.
+package capture
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Reasons a request is captured.
+const (
+	ReasonLatency       = "latency"
+	ReasonHighReduction = "high_reduction"
+	ReasonLowReduction  = "low_reduction"
+)
+
+// Config controls when requests are captured.
+type Config struct {
+	// Size is the ring buffer capacity (default 100).
+	Size int
+
+	// LatencyThreshold captures requests slower than this. 0 disables.
+	LatencyThreshold time.Duration
+
+	// MaxReductionPct captures requests that removed more than this
+	// percentage of their input, e.g. a threshold that collapses
+	// everything into one cluster. 0 disables.
+	MaxReductionPct int
+
+	// MinReductionPct captures requests that removed less than this
+	// percentage of their input. 0 disables.
+	MinReductionPct int
+
+	// Privacy omits chunk text from captures, keeping IDs, scores, and
+	// cluster assignments only.
+	Privacy bool
+}
+
+// Stage is the duration of one pipeline stage.
+type Stage struct {
+	Name       string  `json:"name"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// ChunkTrace records what happened to one input chunk.
+type ChunkTrace struct {
+	ID        string  `json:"id"`
+	Text      string  `json:"text,omitempty"`
+	Score     float32 `json:"score"`
+	ClusterID int     `json:"cluster_id"`
+	Kept      bool    `json:"kept"`
+}
+
+// Trace is the explain-mode record of a single request.
+type Trace struct {
+	ID           uint64                 `json:"id"`
+	Time         time.Time              `json:"time"`
+	Endpoint     string                 `json:"endpoint"`
+	Reasons      []string               `json:"reasons"`
+	LatencyMs    float64                `json:"latency_ms"`
+	InputCount   int                    `json:"input_count"`
+	OutputCount  int                    `json:"output_count"`
+	ClusterCount int                    `json:"cluster_count"`
+	ReductionPct int                    `json:"reduction_pct"`
+	Params       map[string]interface{} `json:"params,omitempty"`
+	Stages       []Stage                `json:"stages,omitempty"`
+	Chunks       []ChunkTrace           `json:"chunks,omitempty"`
+}
+
+// Recorder holds the most recent captures. A nil *Recorder captures
+// nothing, so callers need not check whether capture is enabled.
+type Recorder struct {
+	cfg Config
+
+	mu   sync.Mutex
+	ring []Trace
+	next int
+	seq  uint64
+}
+
+// NewRecorder creates a recorder with the given config.
+func NewRecorder(cfg Config) *Recorder {
+	if cfg.Size <= 0 {
+		cfg.Size = 100
+	}
+	return &Recorder{
+		cfg:  cfg,
+		ring: make([]Trace, 0, cfg.Size),
+	}
+}
+
+// Anomalies returns the reasons a request with these results should be
+// captured, or nil. Call it before building a Trace so normal requests
+// pay nothing for the trace.
+func (r *Recorder) Anomalies(latency time.Duration, inputCount, outputCount int) []string {
+	if r == nil {
+		return nil
+	}
+
+	var reasons []string
+	if r.cfg.LatencyThreshold > 0 && latency > r.cfg.LatencyThreshold {
+		reasons = append(reasons, ReasonLatency)
+	}
+	if inputCount > 0 {
+		pct := ReductionPct(inputCount, outputCount)
+		if r.cfg.MaxReductionPct > 0 && pct > r.cfg.MaxReductionPct {
+			reasons = append(reasons, ReasonHighReduction)
+		}
+		if r.cfg.MinReductionPct > 0 && pct < r.cfg.MinReductionPct {
+			reasons = append(reasons, ReasonLowReduction)
+		}
+	}
+	return reasons
+}
+
+// Record stores t, evicting the oldest capture when the buffer is full.
+// Chunk text is dropped in privacy mode.
+func (r *Recorder) Record(t Trace) {
+	if r == nil {
+		return
+	}
+
+	if r.cfg.Privacy {
+		chunks := make([]ChunkTrace, len(t.Chunks))
+		for i, c := range t.Chunks {
+			c.Text = ""
+			chunks[i] = c
+		}
+		t.Chunks = chunks
+	}
+	if t.Time.IsZero() {
+		t.Time = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	t.ID = r.seq
+	if len(r.ring) < r.cfg.Size {
+		r.ring = append(r.ring, t)
+		return
+	}
+	r.ring[r.next] = t
+	r.next = (r.next + 1) % r.cfg.Size
+}
+
+// Captures returns the recorded traces, newest first.
+func (r *Recorder) Captures() []Trace {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Trace, 0, len(r.ring))
+	for i := len(r.ring) - 1; i >= 0; i-- {
+		out = append(out, r.ring[(r.next+i)%len(r.ring)])
+	}
+	return out
+}
+
+// Reset discards all captures.
+func (r *Recorder) Reset() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ring = r.ring[:0]
+	r.next = 0
+}
+
+// Handler serves the captures as JSON on GET and clears them on DELETE.
+func (r *Recorder) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"captures": r.Captures(),
+				"config":   r.describe(),
+			})
+		case http.MethodDelete:
+			r.Reset()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func (r *Recorder) describe() map[string]interface{} {
+	return map[string]interface{}{
+		"size":              r.cfg.Size,
+		"latency_threshold": r.cfg.LatencyThreshold.String(),
+		"max_reduction_pct": r.cfg.MaxReductionPct,
+		"min_reduction_pct": r.cfg.MinReductionPct,
+		"privacy":           r.cfg.Privacy,
+	}
+}
+
+// ReductionPct is the percentage of input chunks removed, matching the
+// reduction_pct reported by the API.
+func ReductionPct(inputCount, outputCount int) int {
+	if inputCount == 0 {
+		return 0
+	}
+	return int((1 - float64(outputCount)/float64(inputCount)) * 100)
+}
+
+// StageSince returns a Stage for a stage that started at start.
+func StageSince(name string, start time.Time) Stage {
+	return Stage{Name: name, DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+}
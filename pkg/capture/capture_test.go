@@ -0,0 +1,116 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRecorder_Anomalies(t *testing.T) {
+	r := NewRecorder(Config{
+		LatencyThreshold: 100 * time.Millisecond,
+		MaxReductionPct:  90,
+		MinReductionPct:  10,
+	})
+
+	tests := []struct {
+		name    string
+		latency time.Duration
+		in, out int
+		want    []string
+	}{
+		{"normal", 10 * time.Millisecond, 10, 5, nil},
+		{"slow", 200 * time.Millisecond, 10, 5, []string{ReasonLatency}},
+		{"collapsed", 10 * time.Millisecond, 100, 1, []string{ReasonHighReduction}},
+		{"no reduction", 10 * time.Millisecond, 10, 10, []string{ReasonLowReduction}},
+		{"slow and collapsed", time.Second, 100, 1, []string{ReasonLatency, ReasonHighReduction}},
+		{"empty input", time.Millisecond, 0, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.Anomalies(tt.latency, tt.in, tt.out)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRecorder_RingBuffer(t *testing.T) {
+	r := NewRecorder(Config{Size: 3})
+	for i := 0; i < 5; i++ {
+		r.Record(Trace{Endpoint: fmt.Sprintf("/e%d", i)})
+	}
+
+	got := r.Captures()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 captures, got %d", len(got))
+	}
+	for i, want := range []string{"/e4", "/e3", "/e2"} {
+		if got[i].Endpoint != want {
+			t.Errorf("capture %d: expected %s, got %s", i, want, got[i].Endpoint)
+		}
+	}
+	if got[0].ID != 5 {
+		t.Errorf("expected newest capture ID 5, got %d", got[0].ID)
+	}
+
+	r.Reset()
+	if len(r.Captures()) != 0 {
+		t.Error("expected no captures after reset")
+	}
+}
+
+func TestRecorder_Privacy(t *testing.T) {
+	chunks := []ChunkTrace{{ID: "a", Text: "secret", Kept: true}}
+
+	r := NewRecorder(Config{Privacy: true})
+	r.Record(Trace{Chunks: chunks})
+
+	got := r.Captures()[0].Chunks[0]
+	if got.Text != "" || got.ID != "a" {
+		t.Errorf("expected text stripped and ID kept, got %+v", got)
+	}
+	if chunks[0].Text != "secret" {
+		t.Error("caller's chunks should not be modified")
+	}
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var r *Recorder
+	if r.Anomalies(time.Hour, 10, 0) != nil {
+		t.Error("nil recorder should report no anomalies")
+	}
+	r.Record(Trace{})
+	if r.Captures() != nil {
+		t.Error("nil recorder should have no captures")
+	}
+}
+
+func TestRecorder_Handler(t *testing.T) {
+	r := NewRecorder(Config{Size: 2})
+	r.Record(Trace{Endpoint: "/v1/dedupe", Reasons: []string{ReasonLatency}})
+
+	rec := httptest.NewRecorder()
+	r.Handler()(rec, httptest.NewRequest(http.MethodGet, "/debug/captures", nil))
+
+	var body struct {
+		Captures []Trace `json:"captures"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Captures) != 1 || body.Captures[0].Endpoint != "/v1/dedupe" {
+		t.Errorf("unexpected captures: %+v", body.Captures)
+	}
+
+	rec = httptest.NewRecorder()
+	r.Handler()(rec, httptest.NewRequest(http.MethodDelete, "/debug/captures", nil))
+	if rec.Code != http.StatusNoContent || len(r.Captures()) != 0 {
+		t.Errorf("expected DELETE to clear captures, got status %d", rec.Code)
+	}
+}
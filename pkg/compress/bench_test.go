@@ -30,3 +30,20 @@ func BenchmarkCompress_LongText(b *testing.B) {
 		_, _, _ = c.Compress(ctx, []types.Chunk{chunk}, opts)
 	}
 }
+
+func BenchmarkCompress_ManySentences(b *testing.B) {
+	c := NewExtractiveCompressor()
+	ctx := context.Background()
+	var sb strings.Builder
+	for i := 0; i < 500; i++ {
+		sb.WriteString("Sentence ")
+		sb.WriteString(strings.Repeat("word ", i%13+3))
+		sb.WriteString("ends here. ")
+	}
+	chunk := types.Chunk{ID: "bench", Text: sb.String()}
+	opts := DefaultOptions()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = c.Compress(ctx, []types.Chunk{chunk}, opts)
+	}
+}
@@ -0,0 +1,171 @@
+package compress
+
+import (
+	"context"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Level is how aggressively BudgetCompressor compressed a chunk. Each
+// level applies its strategy on top of the ones below it.
+type Level int
+
+const (
+	// LevelBase is the output of the first compression pass.
+	LevelBase Level = iota
+	// LevelPrune also strips filler phrases.
+	LevelPrune
+	// LevelExtractive also keeps only the most salient sentences.
+	LevelExtractive
+	// LevelPlaceholder also replaces structured content with summaries.
+	LevelPlaceholder
+)
+
+// String returns the level name.
+func (l Level) String() string {
+	switch l {
+	case LevelBase:
+		return "base"
+	case LevelPrune:
+		return "prune"
+	case LevelExtractive:
+		return "extractive"
+	case LevelPlaceholder:
+		return "placeholder"
+	}
+	return "unknown"
+}
+
+// BudgetCompressor guarantees an output size where it can. It runs one
+// compression pass and, while the output exceeds Options.MaxOutputTokens,
+// raises the largest remaining chunk to the next Level, until the budget
+// is met or every chunk is at LevelPlaceholder. Levels that leave a chunk
+// unchanged are skipped. Stats.Levels reports the level each chunk ended
+// at and Stats.OverBudget whether the floor was hit first.
+type BudgetCompressor struct {
+	// Base is the first compression pass; nil starts from the input text.
+	Base Compressor
+
+	Pruner      *Pruner
+	Extractive  *ExtractiveCompressor
+	Placeholder *PlaceholderCompressor
+}
+
+// NewBudgetCompressor creates a budget compressor that escalates from
+// base's output with the default strategies.
+func NewBudgetCompressor(base Compressor) *BudgetCompressor {
+	return &BudgetCompressor{
+		Base:        base,
+		Pruner:      NewPruner(),
+		Extractive:  NewExtractiveCompressor(),
+		Placeholder: NewPlaceholderCompressor(),
+	}
+}
+
+// Compress runs the base pass and escalates chunks until the output fits
+// opts.MaxOutputTokens. Without a budget it only runs the base pass.
+func (b *BudgetCompressor) Compress(ctx context.Context, chunks []types.Chunk, opts Options) ([]types.Chunk, Stats, error) {
+	start := time.Now()
+
+	result := chunks
+	var stats Stats
+	if b.Base != nil {
+		compressed, baseStats, err := b.Base.Compress(ctx, chunks, opts)
+		if err != nil {
+			return nil, Stats{}, err
+		}
+		result, stats = compressed, baseStats
+	} else {
+		for _, c := range chunks {
+			stats.InputTokens += tokens.Count(c.Text)
+		}
+		stats.OutputTokens = stats.InputTokens
+	}
+	if opts.MaxOutputTokens <= 0 {
+		return result, stats, nil
+	}
+
+	// rung is how far up the ladder each chunk has been tried; levels is
+	// the highest level that changed it.
+	rung := make([]Level, len(result))
+	levels := make([]Level, len(result))
+	counts := make([]int, len(result))
+	total := 0
+	for i, c := range result {
+		counts[i] = tokens.Count(c.Text)
+		total += counts[i]
+	}
+
+	var escalated []types.Chunk
+	for total > opts.MaxOutputTokens {
+		if err := ctx.Err(); err != nil {
+			return nil, Stats{}, err
+		}
+		i := -1
+		for j := range result {
+			if rung[j] < LevelPlaceholder && len(result[j].Text) >= opts.MinChunkLength && (i < 0 || counts[j] > counts[i]) {
+				i = j
+			}
+		}
+		if i < 0 {
+			break
+		}
+
+		rung[i]++
+		text := b.apply(rung[i], result[i].Text, opts)
+		if text == result[i].Text {
+			continue
+		}
+		if escalated == nil {
+			escalated = make([]types.Chunk, len(result))
+			copy(escalated, result)
+			result = escalated
+		}
+		c := result[i].Clone()
+		c.Text = text
+		result[i] = *c
+		levels[i] = rung[i]
+
+		n := tokens.Count(text)
+		total += n - counts[i]
+		counts[i] = n
+	}
+
+	stats.OutputTokens = total
+	stats.OverBudget = total > opts.MaxOutputTokens
+	stats.Levels = make(map[string]Level, len(result))
+	for i, c := range result {
+		stats.Levels[c.ID] = levels[i]
+		if levels[i] != LevelBase {
+			// Escalation starts with pruning, which rewrites text
+			delete(stats.Offsets, c.ID)
+		}
+	}
+	stats.Latency = time.Since(start)
+	stats.ReductionPercent = 0
+	if stats.InputTokens > 0 {
+		stats.ReductionPercent = float64(stats.InputTokens-stats.OutputTokens) / float64(stats.InputTokens) * 100
+	}
+
+	return result, stats, nil
+}
+
+// apply compresses text with the strategy level adds. Extraction skips
+// structured text, which it would break apart.
+func (b *BudgetCompressor) apply(level Level, text string, opts Options) string {
+	switch level {
+	case LevelPrune:
+		return b.Pruner.prune(text)
+	case LevelExtractive:
+		if looksStructured(text) {
+			return text
+		}
+		text, _ = b.Extractive.extractSalientSpans(text, opts.TargetReduction)
+		return text
+	case LevelPlaceholder:
+		return b.Placeholder.compressStructured(text, opts.PreserveStructure)
+	}
+	return text
+}
@@ -4,6 +4,7 @@ package compress
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/Siddhant-K-code/distill/pkg/types"
@@ -19,8 +20,41 @@ const (
 	ModePlaceholder Mode = "placeholder"
 	// ModeHybrid combines extractive and placeholder strategies.
 	ModeHybrid Mode = "hybrid"
+	// ModeClusterMerge rebuilds each representative from the salient
+	// sentences of its whole cluster; see ClusterMerger.
+	ModeClusterMerge Mode = "cluster-merge"
 )
 
+// ParseMode returns the mode named s. Empty is ModeHybrid.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case "":
+		return ModeHybrid, nil
+	case ModeExtractive, ModePlaceholder, ModeHybrid, ModeClusterMerge:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown compression mode %q (supported: %s, %s, %s, %s)", s, ModeExtractive, ModePlaceholder, ModeHybrid, ModeClusterMerge)
+}
+
+// ForMode returns the compressor for mode. Every mode drops repeated
+// paragraphs first; see RepeatRemover. ModeClusterMerge needs the
+// clusters, so callers merge with ClusterMerger at selection, and its
+// compressor only drops repeats from the merged text.
+func ForMode(mode Mode) (Compressor, error) {
+	switch mode {
+	case ModeClusterMerge:
+		return NewPipeline(NewRepeatRemover()), nil
+	case ModeExtractive:
+		return NewPipeline(NewRepeatRemover(), NewExtractiveCompressor()), nil
+	case ModePlaceholder:
+		return NewPipeline(NewRepeatRemover(), NewPlaceholderCompressor()), nil
+	case ModeHybrid:
+		return NewPipeline(NewRepeatRemover(), NewHybridCompressor()), nil
+	}
+	_, err := ParseMode(string(mode))
+	return nil, err
+}
+
 // Options configures compression behavior.
 type Options struct {
 	// TargetReduction is the desired reduction ratio (e.g., 0.3 = reduce to 30% of original).
@@ -35,8 +69,13 @@ type Options struct {
 	// MinChunkLength is the minimum length to consider for compression.
 	MinChunkLength int
 
-	// MaxOutputTokens caps the total output tokens (0 = no limit).
+	// MaxOutputTokens caps the total output tokens (0 = no limit). It is
+	// enforced by BudgetCompressor.
 	MaxOutputTokens int
+
+	// MergeTokens caps each chunk ClusterMerger builds (0 = the
+	// representative's own length).
+	MergeTokens int
 }
 
 // DefaultOptions returns sensible defaults for compression.
@@ -52,10 +91,11 @@ func DefaultOptions() Options {
 
 // Stats tracks compression metrics.
 type Stats struct {
-	// InputTokens is the estimated token count before compression.
+	// InputTokens is the token count before compression, under the
+	// default tokenizer (tokens.Count).
 	InputTokens int
 
-	// OutputTokens is the estimated token count after compression.
+	// OutputTokens is the token count after compression.
 	OutputTokens int
 
 	// ReductionPercent is the percentage of tokens removed.
@@ -67,6 +107,26 @@ type Stats struct {
 	// ChunksSkipped is the number of chunks below MinChunkLength.
 	ChunksSkipped int
 
+	// RepeatsRemoved is the number of paragraphs and sentences dropped for
+	// repeating earlier text in the same chunk.
+	RepeatsRemoved int
+
+	// RepeatTokensSaved is the tokens saved by dropping them.
+	RepeatTokensSaved int
+
+	// Levels is the Level BudgetCompressor left each chunk at, by chunk ID.
+	Levels map[string]Level
+
+	// OverBudget is set when every chunk reached the most aggressive level
+	// and the output still exceeds MaxOutputTokens.
+	OverBudget bool
+
+	// Offsets maps chunk IDs to the spans of their compressed text copied
+	// from the input, for compressors that track provenance. Pipeline
+	// reports spans over its own input and leaves out chunks a compressor
+	// rewrote without tracking.
+	Offsets map[string][]Span
+
 	// Latency is the compression processing time.
 	Latency time.Duration
 }
@@ -102,18 +162,33 @@ func (p *Pipeline) Compress(ctx context.Context, chunks []types.Chunk, opts Opti
 	result := chunks
 	var totalStats Stats
 
-	for _, c := range p.compressors {
+	offsets := make(map[string][]Span, len(chunks))
+	for _, c := range chunks {
+		offsets[c.ID] = identitySpans(c.Text)
+	}
+
+	for i, c := range p.compressors {
 		compressed, stats, err := c.Compress(ctx, result, opts)
 		if err != nil {
 			return nil, Stats{}, err
 		}
+		offsets = composeOffsets(offsets, result, compressed, stats.Offsets)
 		result = compressed
-		totalStats.InputTokens = stats.InputTokens
+		if i == 0 {
+			totalStats.InputTokens = stats.InputTokens
+		}
 		totalStats.OutputTokens = stats.OutputTokens
 		totalStats.ChunksProcessed += stats.ChunksProcessed
 		totalStats.ChunksSkipped += stats.ChunksSkipped
+		totalStats.RepeatsRemoved += stats.RepeatsRemoved
+		totalStats.RepeatTokensSaved += stats.RepeatTokensSaved
+		if stats.Levels != nil {
+			totalStats.Levels = stats.Levels
+		}
+		totalStats.OverBudget = totalStats.OverBudget || stats.OverBudget
 	}
 
+	totalStats.Offsets = offsets
 	totalStats.Latency = time.Since(start)
 	if totalStats.InputTokens > 0 {
 		totalStats.ReductionPercent = float64(totalStats.InputTokens-totalStats.OutputTokens) / float64(totalStats.InputTokens) * 100
@@ -2,8 +2,10 @@ package compress
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
 
@@ -209,25 +211,6 @@ func TestPipeline(t *testing.T) {
 	}
 }
 
-func TestEstimateTokens(t *testing.T) {
-	tests := []struct {
-		input string
-		want  int
-	}{
-		{"", 0},
-		{"test", 1},
-		{"hello world", 3},
-		{"this is a longer sentence", 7},
-	}
-
-	for _, tt := range tests {
-		got := estimateTokens(tt.input)
-		if got != tt.want {
-			t.Errorf("estimateTokens(%q) = %d, want %d", tt.input, got, tt.want)
-		}
-	}
-}
-
 func TestDefaultOptions(t *testing.T) {
 	opts := DefaultOptions()
 
@@ -244,6 +227,94 @@ func TestDefaultOptions(t *testing.T) {
 	}
 }
 
+func TestForMode(t *testing.T) {
+	prose := "As mentioned earlier, this is the first important sentence. " +
+		"Basically, this is the second sentence. " +
+		"It is important to note that this is the third sentence. " +
+		"This is the fourth sentence with key information."
+	structured := `{"id": 1, "name": "replica", "description": "` + strings.Repeat("verbose ", 20) + `"}`
+	chunks := []types.Chunk{{ID: "json", Text: structured}, {ID: "prose", Text: prose}}
+	opts := DefaultOptions()
+
+	for _, mode := range []Mode{ModeExtractive, ModePlaceholder, ModeHybrid} {
+		c, err := ForMode(mode)
+		if err != nil {
+			t.Fatalf("ForMode(%s): %v", mode, err)
+		}
+		result, stats, err := c.Compress(context.Background(), chunks, opts)
+		if err != nil {
+			t.Fatalf("%s: Compress: %v", mode, err)
+		}
+		if len(result) != 2 || result[0].ID != "json" || result[1].ID != "prose" {
+			t.Fatalf("%s: chunks out of order: %v", mode, result)
+		}
+		if stats.OutputTokens >= stats.InputTokens {
+			t.Errorf("%s: expected fewer tokens, got %d of %d", mode, stats.OutputTokens, stats.InputTokens)
+		}
+		if mode == ModeHybrid && (result[0].Text == structured || result[1].Text == prose) {
+			t.Errorf("hybrid: expected both chunks compressed, got %q and %q", result[0].Text, result[1].Text)
+		}
+	}
+
+	if _, err := ForMode("abstractive"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+	if m, err := ParseMode("cluster-merge"); err != nil || m != ModeClusterMerge {
+		t.Errorf("ParseMode(\"cluster-merge\") = %q, %v", m, err)
+	}
+	if m, err := ParseMode(""); err != nil || m != ModeHybrid {
+		t.Errorf("ParseMode(\"\") = %q, %v, want hybrid", m, err)
+	}
+}
+
+func TestClusterMerger(t *testing.T) {
+	rep := types.Chunk{ID: "rep", Score: 0.9, Text: "Refunds are issued to the original payment method. " +
+		"Most refunds arrive within five business days."}
+	members := []types.Chunk{
+		rep,
+		{ID: "faq", Score: 0.8, Text: "Refunds are issued to the original payment method! " +
+			"Gift cards are refunded as store credit instead."},
+		{ID: "wiki", Score: 0.7, Text: "Most refunds arrive within five business days. " +
+			"Refunds are issued to the original payment method."},
+	}
+	merger := NewClusterMerger()
+	opts := DefaultOptions()
+	opts.MergeTokens = 100
+
+	merged, from := merger.Merge(rep, members, opts)
+	if !strings.HasPrefix(merged.Text, rep.Text) {
+		t.Errorf("representative's sentences should come first, got %q", merged.Text)
+	}
+	if !strings.Contains(merged.Text, "Gift cards are refunded as store credit") {
+		t.Errorf("complementary detail from faq missing: %q", merged.Text)
+	}
+	if n := strings.Count(strings.ToLower(merged.Text), "original payment method"); n != 1 {
+		t.Errorf("repeated sentence kept %d times: %q", n, merged.Text)
+	}
+	if len(from) != 1 || from[0] != "faq" {
+		t.Errorf("merged from %v, want [faq]: wiki adds nothing new", from)
+	}
+	if merged.ID != "rep" || merged.Score != rep.Score {
+		t.Errorf("merged chunk should keep the representative's ID and score, got %+v", merged)
+	}
+
+	// The default budget is the representative's own length
+	opts.MergeTokens = 0
+	merged, _ = merger.Merge(rep, members, opts)
+	if got, limit := tokens.Count(merged.Text), tokens.Count(rep.Text)+1; got > limit {
+		t.Errorf("merged chunk has %d tokens, want at most about %d", got, limit)
+	}
+
+	// A singleton cluster, or one whose members add nothing, is left alone
+	if got, from := merger.Merge(rep, []types.Chunk{rep, members[2]}, opts); got.Text != rep.Text || from != nil {
+		t.Errorf("nothing to add, got %q from %v", got.Text, from)
+	}
+	structured := types.Chunk{ID: "json", Text: `{"refunds": "five days"}`}
+	if got, _ := merger.Merge(structured, members, opts); got.Text != structured.Text {
+		t.Errorf("structured representative merged: %q", got.Text)
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
 		(len(s) > 0 && len(substr) > 0 && findSubstring(s, substr)))
@@ -274,3 +345,284 @@ func toLower(s string) string {
 	}
 	return string(result)
 }
+
+func TestRepeatRemover(t *testing.T) {
+	remover := NewRepeatRemover()
+	ctx := context.Background()
+
+	para := "Distill removes redundant chunks before they reach the model. " +
+		"It clusters near-duplicates and keeps one representative per cluster."
+
+	tests := []struct {
+		name        string
+		input       string
+		want        string
+		wantRemoved int
+	}{
+		{
+			name:        "repeated paragraph",
+			input:       para + "\n\nSee the docs for details on tuning.\n\n" + para,
+			want:        para + "\n\nSee the docs for details on tuning.",
+			wantRemoved: 1,
+		},
+		{
+			name: "near-repeat paragraph",
+			input: para + "\n\n" +
+				"DISTILL removes redundant chunks before they reach the model!  " +
+				"It clusters near-duplicates, and keeps one representative per cluster.",
+			want:        para,
+			wantRemoved: 1,
+		},
+		{
+			name: "repeated sentence",
+			input: "The cache is keyed by the query embedding and options. " +
+				"Entries expire after the configured TTL.\n\n" +
+				"Entries expire after the configured TTL. Stale entries are evicted lazily on read.",
+			want: "The cache is keyed by the query embedding and options. " +
+				"Entries expire after the configured TTL.\n\n" +
+				"Stale entries are evicted lazily on read.",
+			wantRemoved: 1,
+		},
+		{
+			name:  "short repeats kept",
+			input: "Yes. The request was accepted by the server.\n\nYes. Done.\n\nDone.",
+			want:  "Yes. The request was accepted by the server.\n\nYes. Done.\n\nDone.",
+		},
+		{
+			name:  "no repeats",
+			input: para,
+			want:  para,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := []types.Chunk{{ID: "1", Text: tt.input}}
+			result, stats, err := remover.Compress(ctx, chunks, Options{MinChunkLength: 10})
+			if err != nil {
+				t.Fatalf("Compress() error = %v", err)
+			}
+			if result[0].Text != tt.want {
+				t.Errorf("text = %q, want %q", result[0].Text, tt.want)
+			}
+			if stats.RepeatsRemoved != tt.wantRemoved {
+				t.Errorf("RepeatsRemoved = %d, want %d", stats.RepeatsRemoved, tt.wantRemoved)
+			}
+			wantSaved := tokens.Count(tt.input) - tokens.Count(tt.want)
+			if stats.RepeatTokensSaved != wantSaved {
+				t.Errorf("RepeatTokensSaved = %d, want %d", stats.RepeatTokensSaved, wantSaved)
+			}
+			if chunks[0].Text != tt.input {
+				t.Error("input chunk was modified")
+			}
+		})
+	}
+}
+
+func TestRepeatRemover_PreserveStructure(t *testing.T) {
+	remover := NewRepeatRemover()
+	code := "```go\nif err != nil { return err }\nif err != nil { return err }\n```"
+	input := "Handle every error returned by the client call.\n\n" + code
+
+	opts := Options{MinChunkLength: 10, PreserveStructure: true}
+	result, stats, err := remover.Compress(context.Background(), []types.Chunk{{ID: "1", Text: input}}, opts)
+	if err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	if result[0].Text != input || stats.RepeatsRemoved != 0 {
+		t.Errorf("structured paragraph changed: %q (%d removed)", result[0].Text, stats.RepeatsRemoved)
+	}
+}
+
+func TestPipeline_RepeatStats(t *testing.T) {
+	para := "Chunks are embedded once and cached for later requests. " +
+		"Cache hits skip the embedding provider entirely."
+	input := para + "\n\n" + para + "\n\n" + para
+
+	p := NewPipeline(NewRepeatRemover(), NewPruner())
+	_, stats, err := p.Compress(context.Background(), []types.Chunk{{ID: "1", Text: input}}, Options{MinChunkLength: 10})
+	if err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	if stats.RepeatsRemoved != 2 {
+		t.Errorf("RepeatsRemoved = %d, want 2", stats.RepeatsRemoved)
+	}
+	if stats.InputTokens != tokens.Count(input) {
+		t.Errorf("InputTokens = %d, want %d", stats.InputTokens, tokens.Count(input))
+	}
+	if stats.RepeatTokensSaved <= 0 || stats.OutputTokens >= stats.InputTokens {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestBudgetCompressor(t *testing.T) {
+	prose := "As mentioned earlier, the broker retrieves candidates from the vector store. " +
+		"Basically, it clusters them by cosine distance. " +
+		"It then picks one representative per cluster. " +
+		"MMR reorders the representatives for diversity. " +
+		"The result is cached by query and options."
+	structured := `{"id": "run-1", "status": "ok", "items": [{"n": 1}, {"n": 2}, {"n": 3}, {"n": 4}, {"n": 5}, {"n": 6}], "notes": "verbose tool output that goes on and on"}`
+	short := "Short note."
+	chunks := []types.Chunk{
+		{ID: "prose", Text: prose},
+		{ID: "json", Text: structured},
+		{ID: "short", Text: short},
+	}
+	total := 0
+	for _, c := range chunks {
+		total += tokens.Count(c.Text)
+	}
+	opts := Options{TargetReduction: 0.5, MinChunkLength: 20, PreserveStructure: true}
+
+	t.Run("within budget", func(t *testing.T) {
+		opts := opts
+		opts.MaxOutputTokens = total
+		result, stats, err := NewBudgetCompressor(nil).Compress(context.Background(), chunks, opts)
+		if err != nil {
+			t.Fatalf("Compress() error = %v", err)
+		}
+		for i, c := range result {
+			if c.Text != chunks[i].Text || stats.Levels[c.ID] != LevelBase {
+				t.Errorf("chunk %s changed to level %s", c.ID, stats.Levels[c.ID])
+			}
+		}
+		if stats.OverBudget {
+			t.Error("unexpected OverBudget")
+		}
+	})
+
+	t.Run("escalates to budget", func(t *testing.T) {
+		opts := opts
+		opts.MaxOutputTokens = total / 2
+		result, stats, err := NewBudgetCompressor(nil).Compress(context.Background(), chunks, opts)
+		if err != nil {
+			t.Fatalf("Compress() error = %v", err)
+		}
+		if stats.OutputTokens > opts.MaxOutputTokens || stats.OverBudget {
+			t.Fatalf("OutputTokens = %d over budget %d", stats.OutputTokens, opts.MaxOutputTokens)
+		}
+		if stats.InputTokens != total {
+			t.Errorf("InputTokens = %d, want %d", stats.InputTokens, total)
+		}
+		if stats.Levels["short"] != LevelBase || result[2].Text != short {
+			t.Errorf("short chunk compressed to %s", stats.Levels["short"])
+		}
+		if stats.Levels["prose"] == LevelBase && stats.Levels["json"] == LevelBase {
+			t.Error("expected a chunk to be escalated")
+		}
+		if stats.Levels["json"] == LevelExtractive {
+			t.Error("extraction applied to structured chunk")
+		}
+		if chunks[0].Text != prose || chunks[1].Text != structured {
+			t.Error("input chunks were modified")
+		}
+	})
+
+	t.Run("floor", func(t *testing.T) {
+		opts := opts
+		opts.MaxOutputTokens = 1
+		result, stats, err := NewBudgetCompressor(nil).Compress(context.Background(), chunks, opts)
+		if err != nil {
+			t.Fatalf("Compress() error = %v", err)
+		}
+		if !stats.OverBudget {
+			t.Error("expected OverBudget")
+		}
+		if stats.Levels["json"] != LevelPlaceholder {
+			t.Errorf("json level = %s, want placeholder", stats.Levels["json"])
+		}
+		if stats.Levels["prose"] != LevelExtractive {
+			t.Errorf("prose level = %s, want extractive", stats.Levels["prose"])
+		}
+		if len(result[0].Text) >= len(prose) {
+			t.Error("expected prose to be compressed")
+		}
+	})
+}
+
+func TestBudgetCompressor_Base(t *testing.T) {
+	input := "This is the first sentence. This is the second sentence. " +
+		"This is the third sentence. This is the fourth sentence."
+	chunks := []types.Chunk{{ID: "1", Text: input}}
+	opts := Options{TargetReduction: 0.5, MinChunkLength: 10}
+
+	want, _, _ := NewExtractiveCompressor().Compress(context.Background(), chunks, opts)
+	result, stats, err := NewBudgetCompressor(NewExtractiveCompressor()).Compress(context.Background(), chunks, opts)
+	if err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	if result[0].Text != want[0].Text || stats.Levels != nil {
+		t.Errorf("without a budget want the base pass only, got %q (levels %v)", result[0].Text, stats.Levels)
+	}
+}
+
+// checkSpans verifies every span copies source text verbatim.
+func checkSpans(t *testing.T, in, out string, spans []Span) {
+	t.Helper()
+	if len(spans) == 0 {
+		t.Fatal("no spans")
+	}
+	for _, s := range spans {
+		if s.Start < 0 || s.End > len(out) || s.SourceStart < 0 || s.SourceEnd > len(in) {
+			t.Fatalf("span %+v out of range", s)
+		}
+		if out[s.Start:s.End] != in[s.SourceStart:s.SourceEnd] {
+			t.Errorf("span %+v: %q != %q", s, out[s.Start:s.End], in[s.SourceStart:s.SourceEnd])
+		}
+	}
+}
+
+func TestExtractiveCompressor_Offsets(t *testing.T) {
+	c := NewExtractiveCompressor()
+	in := "  This is the first sentence with important information.   Filler sentence here. " +
+		"Another filler sentence follows.\nThe final sentence contains a critical conclusion! Trailing note"
+	chunks := []types.Chunk{{ID: "1", Text: in}}
+
+	opts := DefaultOptions()
+	opts.TargetReduction = 0.5
+	result, stats, err := c.Compress(context.Background(), chunks, opts)
+	if err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	if result[0].Text == in {
+		t.Fatal("expected compression")
+	}
+	checkSpans(t, in, result[0].Text, stats.Offsets["1"])
+}
+
+func TestPipeline_Offsets(t *testing.T) {
+	para := "Crawled pages often repeat the same paragraph twice. Repeats waste tokens in every prompt."
+	in := "Distill basically cleans retrieved context before it reaches the model.\n\n" + para + "\n\n" + para +
+		"\n\nThe final paragraph covers caching of compressed results."
+	short := "Too short."
+	chunks := []types.Chunk{{ID: "a", Text: in}, {ID: "b", Text: short}}
+
+	opts := DefaultOptions()
+	opts.TargetReduction = 0.5
+
+	t.Run("repeats and extractive", func(t *testing.T) {
+		p := NewPipeline(NewRepeatRemover(), NewExtractiveCompressor())
+		result, stats, err := p.Compress(context.Background(), chunks, opts)
+		if err != nil {
+			t.Fatalf("Compress: %v", err)
+		}
+		checkSpans(t, in, result[0].Text, stats.Offsets["a"])
+		if got := stats.Offsets["b"]; len(got) != 1 || got[0] != (Span{End: len(short), SourceEnd: len(short)}) {
+			t.Errorf("unchanged chunk offsets = %+v, want identity", got)
+		}
+	})
+
+	t.Run("rewriting compressor drops offsets", func(t *testing.T) {
+		p := NewPipeline(NewRepeatRemover(), NewPruner())
+		result, stats, err := p.Compress(context.Background(), chunks, opts)
+		if err != nil {
+			t.Fatalf("Compress: %v", err)
+		}
+		if result[0].Text == in {
+			t.Fatal("expected compression")
+		}
+		if _, ok := stats.Offsets["a"]; ok {
+			t.Error("offsets kept for chunk the pruner rewrote")
+		}
+	})
+}
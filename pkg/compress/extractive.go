@@ -2,10 +2,13 @@ package compress
 
 import (
 	"context"
+	"sort"
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
 
@@ -32,7 +35,7 @@ func (e *ExtractiveCompressor) Compress(ctx context.Context, chunks []types.Chun
 	result := make([]types.Chunk, 0, len(chunks))
 
 	for _, chunk := range chunks {
-		inputTokens := estimateTokens(chunk.Text)
+		inputTokens := tokens.Count(chunk.Text)
 		stats.InputTokens += inputTokens
 
 		if len(chunk.Text) < opts.MinChunkLength {
@@ -42,9 +45,13 @@ func (e *ExtractiveCompressor) Compress(ctx context.Context, chunks []types.Chun
 			continue
 		}
 
-		compressed := e.extractSalientSpans(chunk.Text, opts.TargetReduction)
+		compressed, spans := e.extractSalientSpans(chunk.Text, opts.TargetReduction)
 		stats.ChunksProcessed++
-		stats.OutputTokens += estimateTokens(compressed)
+		if stats.Offsets == nil {
+			stats.Offsets = make(map[string][]Span, len(chunks))
+		}
+		stats.Offsets[chunk.ID] = spans
+		stats.OutputTokens += tokens.Count(compressed)
 
 		newChunk := chunk.Clone()
 		newChunk.Text = compressed
@@ -59,20 +66,21 @@ func (e *ExtractiveCompressor) Compress(ctx context.Context, chunks []types.Chun
 	return result, stats, nil
 }
 
-// extractSalientSpans selects the most important sentences to meet target reduction.
-func (e *ExtractiveCompressor) extractSalientSpans(text string, targetReduction float64) string {
-	sentences := e.splitSentences(text)
-	if len(sentences) <= 1 {
-		return text
+// extractSalientSpans selects the most important sentences to meet target
+// reduction, returning the spans of text it kept.
+func (e *ExtractiveCompressor) extractSalientSpans(text string, targetReduction float64) (string, []Span) {
+	bounds := e.sentenceBounds(text)
+	if len(bounds) <= 1 {
+		return text, identitySpans(text)
 	}
 
 	// Score sentences by position and content signals
-	scored := make([]scoredSentence, len(sentences))
-	for i, s := range sentences {
+	scored := make([]scoredSentence, len(bounds))
+	for i, b := range bounds {
 		scored[i] = scoredSentence{
-			text:  s,
+			text:  text[b[0]:b[1]],
 			index: i,
-			score: e.scoreSentence(s, i, len(sentences)),
+			score: e.scoreSentence(text[b[0]:b[1]], i, len(bounds)),
 		}
 	}
 
@@ -80,56 +88,57 @@ func (e *ExtractiveCompressor) extractSalientSpans(text string, targetReduction
 	sortByScore(scored)
 
 	// Select top sentences until we hit target
-	targetTokens := int(float64(estimateTokens(text)) * targetReduction)
+	targetTokens := int(float64(tokens.Count(text)) * targetReduction)
 	var selected []scoredSentence
 	currentTokens := 0
 
 	for _, s := range scored {
-		tokens := estimateTokens(s.text)
-		if currentTokens+tokens > targetTokens && len(selected) > 0 {
+		n := tokens.Count(s.text)
+		if currentTokens+n > targetTokens && len(selected) > 0 {
 			break
 		}
 		selected = append(selected, s)
-		currentTokens += tokens
+		currentTokens += n
 	}
 
 	// Sort selected by original position to maintain coherence
 	sortByIndex(selected)
 
 	// Reconstruct text
-	var result strings.Builder
+	result := spanBuilder{src: text}
 	for i, s := range selected {
 		if i > 0 {
-			result.WriteString(" ")
+			result.write(" ")
 		}
-		result.WriteString(strings.TrimSpace(s.text))
+		result.copy(bounds[s.index][0], bounds[s.index][1])
 	}
 
-	return result.String()
+	return result.String(), result.spans
 }
 
-// splitSentences breaks text into sentences.
-func (e *ExtractiveCompressor) splitSentences(text string) []string {
-	var sentences []string
-	var current strings.Builder
+// sentenceBounds returns the byte ranges of the sentences in text, with
+// surrounding whitespace trimmed.
+func (e *ExtractiveCompressor) sentenceBounds(text string) [][2]int {
+	var bounds [][2]int
+	add := func(start, end int) {
+		if start, end = trimBounds(text, start, end); start < end {
+			bounds = append(bounds, [2]int{start, end})
+		}
+	}
 
-	for _, r := range text {
-		current.WriteRune(r)
+	start := 0
+	for i, r := range text {
 		if strings.ContainsRune(e.SentenceDelimiters, r) {
-			s := strings.TrimSpace(current.String())
-			if len(s) > 0 {
-				sentences = append(sentences, s)
-			}
-			current.Reset()
+			end := i + utf8.RuneLen(r)
+			add(start, end)
+			start = end
 		}
 	}
 
 	// Handle remaining text without delimiter
-	if remaining := strings.TrimSpace(current.String()); len(remaining) > 0 {
-		sentences = append(sentences, remaining)
-	}
+	add(start, len(text))
 
-	return sentences
+	return bounds
 }
 
 // scoreSentence assigns importance based on position and content.
@@ -174,30 +183,12 @@ type scoredSentence struct {
 	score float64
 }
 
+// sortByScore orders sentences by score, highest first. Equal scores keep
+// their order, so earlier sentences win ties.
 func sortByScore(sentences []scoredSentence) {
-	for i := 0; i < len(sentences)-1; i++ {
-		for j := i + 1; j < len(sentences); j++ {
-			if sentences[j].score > sentences[i].score {
-				sentences[i], sentences[j] = sentences[j], sentences[i]
-			}
-		}
-	}
+	sort.SliceStable(sentences, func(i, j int) bool { return sentences[i].score > sentences[j].score })
 }
 
 func sortByIndex(sentences []scoredSentence) {
-	for i := 0; i < len(sentences)-1; i++ {
-		for j := i + 1; j < len(sentences); j++ {
-			if sentences[j].index < sentences[i].index {
-				sentences[i], sentences[j] = sentences[j], sentences[i]
-			}
-		}
-	}
-}
-
-// estimateTokens provides a rough token count (avg 4 chars per token).
-func estimateTokens(text string) int {
-	if len(text) == 0 {
-		return 0
-	}
-	return (len(text) + 3) / 4
+	sort.Slice(sentences, func(i, j int) bool { return sentences[i].index < sentences[j].index })
 }
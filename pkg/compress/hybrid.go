@@ -0,0 +1,80 @@
+package compress
+
+import (
+	"context"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// HybridCompressor compresses structured chunks (JSON, XML, tables, code)
+// with placeholders and prose with extraction, which would break
+// structured text apart.
+type HybridCompressor struct {
+	Placeholder *PlaceholderCompressor
+	Extractive  *ExtractiveCompressor
+}
+
+// NewHybridCompressor creates a hybrid compressor with default settings.
+func NewHybridCompressor() *HybridCompressor {
+	return &HybridCompressor{
+		Placeholder: NewPlaceholderCompressor(),
+		Extractive:  NewExtractiveCompressor(),
+	}
+}
+
+// Compress routes each chunk to the placeholder or extractive compressor
+// and merges their stats. Chunks keep their order.
+func (h *HybridCompressor) Compress(ctx context.Context, chunks []types.Chunk, opts Options) ([]types.Chunk, Stats, error) {
+	start := time.Now()
+
+	var structured, prose []types.Chunk
+	var structuredIdx, proseIdx []int
+	for i, c := range chunks {
+		if looksStructured(c.Text) {
+			structured = append(structured, c)
+			structuredIdx = append(structuredIdx, i)
+		} else {
+			prose = append(prose, c)
+			proseIdx = append(proseIdx, i)
+		}
+	}
+
+	result := make([]types.Chunk, len(chunks))
+	var stats Stats
+	for _, part := range []struct {
+		c      Compressor
+		chunks []types.Chunk
+		idx    []int
+	}{
+		{h.Placeholder, structured, structuredIdx},
+		{h.Extractive, prose, proseIdx},
+	} {
+		if len(part.chunks) == 0 {
+			continue
+		}
+		out, st, err := part.c.Compress(ctx, part.chunks, opts)
+		if err != nil {
+			return nil, Stats{}, err
+		}
+		for j, c := range out {
+			result[part.idx[j]] = c
+		}
+		stats.InputTokens += st.InputTokens
+		stats.OutputTokens += st.OutputTokens
+		stats.ChunksProcessed += st.ChunksProcessed
+		stats.ChunksSkipped += st.ChunksSkipped
+		for id, spans := range st.Offsets {
+			if stats.Offsets == nil {
+				stats.Offsets = make(map[string][]Span, len(chunks))
+			}
+			stats.Offsets[id] = spans
+		}
+	}
+
+	stats.Latency = time.Since(start)
+	if stats.InputTokens > 0 {
+		stats.ReductionPercent = float64(stats.InputTokens-stats.OutputTokens) / float64(stats.InputTokens) * 100
+	}
+	return result, stats, nil
+}
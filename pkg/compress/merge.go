@@ -0,0 +1,155 @@
+package compress
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// ClusterMerger synthesizes one chunk from a cluster of near-duplicates:
+// the union of its members' salient sentences, with repeats dropped,
+// within a token budget. Compressing only the representative would leave
+// the details other members add unused.
+type ClusterMerger struct {
+	// Repeats decides which sentences repeat one already taken.
+	Repeats *RepeatRemover
+
+	// Scorer ranks the sentences that remain.
+	Scorer *ExtractiveCompressor
+
+	// RepresentativeBonus is added to the scores of the representative's
+	// sentences, so they win ties with other members'.
+	RepresentativeBonus float64
+}
+
+// NewClusterMerger creates a cluster merger with default settings.
+func NewClusterMerger() *ClusterMerger {
+	return &ClusterMerger{
+		Repeats:             NewRepeatRemover(),
+		Scorer:              NewExtractiveCompressor(),
+		RepresentativeBonus: 0.5,
+	}
+}
+
+// mergeSentence is a candidate sentence of a merged chunk.
+type mergeSentence struct {
+	source int // 0 is the representative
+	index  int // position in the source
+	text   string
+	score  float64
+	tokens int
+}
+
+// Merge returns rep with its text rebuilt from its own sentences and
+// those of members, which may include rep, and the IDs of the other
+// members that contributed. Sentences that repeat an earlier one, as
+// RepeatRemover compares them, are dropped; the rest are ranked as
+// ExtractiveCompressor ranks them and taken until opts.MergeTokens.
+// The representative's sentences come first, in order, followed by each
+// contributing member's in a paragraph of their own, members in score
+// order. rep is returned unchanged when no other member contributes, and
+// with opts.PreserveStructure, structured chunks are neither merged nor
+// merged from.
+func (m *ClusterMerger) Merge(rep types.Chunk, members []types.Chunk, opts Options) (types.Chunk, []string) {
+	if opts.PreserveStructure && looksStructured(rep.Text) {
+		return rep, nil
+	}
+	sources := []types.Chunk{rep}
+	for _, c := range members {
+		if c.ID == rep.ID || c.Text == "" || (opts.PreserveStructure && looksStructured(c.Text)) {
+			continue
+		}
+		sources = append(sources, c)
+	}
+	if len(sources) == 1 {
+		return rep, nil
+	}
+	sort.SliceStable(sources[1:], func(i, j int) bool { return sources[1+i].Score > sources[1+j].Score })
+
+	budget := opts.MergeTokens
+	if budget <= 0 {
+		budget = tokens.Count(rep.Text)
+	}
+
+	seen := newShingleIndex(m.Repeats.Threshold)
+	short := make(map[string]bool)
+	var candidates []mergeSentence
+	for src, c := range sources {
+		bounds := sentenceBounds(c.Text, 0, len(c.Text))
+		for i, b := range bounds {
+			sentence := c.Text[b[0]:b[1]]
+			words := normalizedWords(sentence)
+			if len(words) < m.Repeats.MinSentenceWords {
+				// Too short for shingles: compare the words themselves
+				key := strings.Join(words, " ")
+				if short[key] {
+					continue
+				}
+				short[key] = true
+			} else {
+				shingles := m.Repeats.shingles(sentence)
+				if seen.contains(shingles) {
+					continue
+				}
+				seen.add(shingles)
+			}
+			score := m.Scorer.scoreSentence(sentence, i, len(bounds))
+			if src == 0 {
+				score += m.RepresentativeBonus
+			}
+			candidates = append(candidates, mergeSentence{
+				source: src,
+				index:  i,
+				text:   sentence,
+				score:  score,
+				tokens: tokens.Count(sentence),
+			})
+		}
+	}
+
+	// Take the highest-scoring sentences that fit, then restore source
+	// and sentence order
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	var selected []mergeSentence
+	used := 0
+	contributed := false
+	for _, s := range candidates {
+		if used+s.tokens > budget && len(selected) > 0 {
+			continue
+		}
+		selected = append(selected, s)
+		used += s.tokens
+		contributed = contributed || s.source > 0
+	}
+	if !contributed {
+		return rep, nil
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if selected[i].source != selected[j].source {
+			return selected[i].source < selected[j].source
+		}
+		return selected[i].index < selected[j].index
+	})
+
+	var text strings.Builder
+	var from []string
+	for i, s := range selected {
+		switch {
+		case i == 0:
+		case s.source != selected[i-1].source:
+			text.WriteString("\n\n")
+		default:
+			text.WriteString(" ")
+		}
+		if s.source > 0 && (i == 0 || s.source != selected[i-1].source) {
+			from = append(from, sources[s.source].ID)
+		}
+		text.WriteString(s.text)
+	}
+
+	merged := rep.Clone()
+	merged.Text = text.String()
+	return *merged, from
+}
@@ -0,0 +1,136 @@
+package compress
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Span maps a range of compressed text to the range of the original text
+// it was copied from, so citations can be traced back to the source.
+// Offsets are in bytes and end-exclusive. Text between spans, such as the
+// separators joining kept sentences, has no source.
+type Span struct {
+	// Start and End locate the range in the compressed text.
+	Start, End int
+
+	// SourceStart and SourceEnd locate it in the original text.
+	SourceStart, SourceEnd int
+}
+
+// identitySpans maps text to itself.
+func identitySpans(text string) []Span {
+	if text == "" {
+		return []Span{}
+	}
+	return []Span{{Start: 0, End: len(text), SourceStart: 0, SourceEnd: len(text)}}
+}
+
+// composeSpans maps later, whose sources are offsets in an intermediate
+// text, through earlier, which maps that text to the original. Ranges of
+// the intermediate text earlier has no source for are dropped.
+func composeSpans(later, earlier []Span) []Span {
+	out := make([]Span, 0, len(later))
+	for _, l := range later {
+		for _, e := range earlier {
+			lo, hi := max(l.SourceStart, e.Start), min(l.SourceEnd, e.End)
+			if lo >= hi {
+				continue
+			}
+			out = appendSpan(out, Span{
+				Start:       l.Start + lo - l.SourceStart,
+				End:         l.Start + hi - l.SourceStart,
+				SourceStart: e.SourceStart + lo - e.Start,
+				SourceEnd:   e.SourceStart + hi - e.Start,
+			})
+		}
+	}
+	return out
+}
+
+// appendSpan appends s, merging it into the last span when both ranges
+// continue it.
+func appendSpan(spans []Span, s Span) []Span {
+	if n := len(spans); n > 0 && spans[n-1].End == s.Start && spans[n-1].SourceEnd == s.SourceStart {
+		spans[n-1].End, spans[n-1].SourceEnd = s.End, s.SourceEnd
+		return spans
+	}
+	return append(spans, s)
+}
+
+// composeOffsets carries provenance across one compressor. offsets maps
+// chunk IDs to spans over the original texts; in and out are the
+// compressor's input and output and stage its Stats.Offsets. Chunks the
+// compressor changed without reporting spans lose their provenance.
+func composeOffsets(offsets map[string][]Span, in, out []types.Chunk, stage map[string][]Span) map[string][]Span {
+	before := make(map[string]string, len(in))
+	for _, c := range in {
+		before[c.ID] = c.Text
+	}
+	next := make(map[string][]Span, len(out))
+	for _, c := range out {
+		prev, ok := offsets[c.ID]
+		if !ok {
+			continue
+		}
+		if spans, ok := stage[c.ID]; ok {
+			next[c.ID] = composeSpans(spans, prev)
+		} else if text, ok := before[c.ID]; ok && text == c.Text {
+			next[c.ID] = prev
+		}
+	}
+	return next
+}
+
+// trimBounds narrows text[start:end] to exclude surrounding whitespace.
+func trimBounds(text string, start, end int) (int, int) {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		r, size := utf8.DecodeLastRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= size
+	}
+	return start, end
+}
+
+// spanBuilder assembles compressed text from ranges of a source text,
+// recording the span of each range.
+type spanBuilder struct {
+	src   string
+	out   strings.Builder
+	spans []Span
+}
+
+// copy appends src[start:end].
+func (b *spanBuilder) copy(start, end int) {
+	if start >= end {
+		return
+	}
+	at := b.out.Len()
+	b.out.WriteString(b.src[start:end])
+	b.spans = appendSpan(b.spans, Span{Start: at, End: b.out.Len(), SourceStart: start, SourceEnd: end})
+}
+
+// write appends text with no source, e.g. a separator.
+func (b *spanBuilder) write(text string) {
+	b.out.WriteString(text)
+}
+
+// Len returns the length of the text built so far.
+func (b *spanBuilder) Len() int {
+	return b.out.Len()
+}
+
+func (b *spanBuilder) String() string {
+	return b.out.String()
+}
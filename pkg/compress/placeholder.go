@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
 
@@ -42,7 +43,7 @@ func (p *PlaceholderCompressor) Compress(ctx context.Context, chunks []types.Chu
 	result := make([]types.Chunk, 0, len(chunks))
 
 	for _, chunk := range chunks {
-		inputTokens := estimateTokens(chunk.Text)
+		inputTokens := tokens.Count(chunk.Text)
 		stats.InputTokens += inputTokens
 
 		if len(chunk.Text) < opts.MinChunkLength {
@@ -54,7 +55,7 @@ func (p *PlaceholderCompressor) Compress(ctx context.Context, chunks []types.Chu
 
 		compressed := p.compressStructured(chunk.Text, opts.PreserveStructure)
 		stats.ChunksProcessed++
-		stats.OutputTokens += estimateTokens(compressed)
+		stats.OutputTokens += tokens.Count(compressed)
 
 		newChunk := chunk.Clone()
 		newChunk.Text = compressed
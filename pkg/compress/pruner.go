@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
 
@@ -74,7 +75,7 @@ func (p *Pruner) Compress(ctx context.Context, chunks []types.Chunk, opts Option
 	result := make([]types.Chunk, 0, len(chunks))
 
 	for _, chunk := range chunks {
-		inputTokens := estimateTokens(chunk.Text)
+		inputTokens := tokens.Count(chunk.Text)
 		stats.InputTokens += inputTokens
 
 		if len(chunk.Text) < opts.MinChunkLength {
@@ -86,7 +87,7 @@ func (p *Pruner) Compress(ctx context.Context, chunks []types.Chunk, opts Option
 
 		pruned := p.prune(chunk.Text)
 		stats.ChunksProcessed++
-		stats.OutputTokens += estimateTokens(pruned)
+		stats.OutputTokens += tokens.Count(pruned)
 
 		newChunk := chunk.Clone()
 		newChunk.Text = pruned
@@ -0,0 +1,310 @@
+package compress
+
+import (
+	"context"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// paragraphBreak matches the blank lines between paragraphs.
+var paragraphBreak = regexp.MustCompile(`\n[ \t]*\n\s*`)
+
+// RepeatRemover drops paragraphs and sentences that repeat earlier text in
+// the same chunk, as bad crawls often duplicate a paragraph or boilerplate
+// line. Text is compared by word shingles, so repeats that differ in case,
+// whitespace, punctuation or a few words are caught too. It belongs at the
+// front of a Pipeline so later compressors don't spend budget on repeats.
+type RepeatRemover struct {
+	// ShingleSize is the number of words per shingle. Text shorter than
+	// this is compared as a whole.
+	ShingleSize int
+
+	// Threshold is the Jaccard similarity of shingle sets at or above which
+	// text counts as a repeat.
+	Threshold float64
+
+	// MinSentenceWords is the fewest words a paragraph or sentence needs
+	// to be dropped, so short lines such as "Yes." or headings are left
+	// alone.
+	MinSentenceWords int
+}
+
+// NewRepeatRemover creates a repeat remover with default settings.
+func NewRepeatRemover() *RepeatRemover {
+	return &RepeatRemover{
+		ShingleSize:      5,
+		Threshold:        0.8,
+		MinSentenceWords: 6,
+	}
+}
+
+// Compress removes repeated paragraphs and sentences within each chunk.
+// Paragraphs that look like code or structured data are only dropped when
+// repeated whole, unless opts.PreserveStructure is off.
+func (r *RepeatRemover) Compress(ctx context.Context, chunks []types.Chunk, opts Options) ([]types.Chunk, Stats, error) {
+	start := time.Now()
+	stats := Stats{}
+
+	result := make([]types.Chunk, 0, len(chunks))
+
+	for _, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, Stats{}, err
+		}
+
+		inputTokens := tokens.Count(chunk.Text)
+		stats.InputTokens += inputTokens
+
+		if len(chunk.Text) < opts.MinChunkLength {
+			stats.ChunksSkipped++
+			stats.OutputTokens += inputTokens
+			result = append(result, chunk)
+			continue
+		}
+
+		text, spans, removed := r.removeRepeats(chunk.Text, opts.PreserveStructure)
+		stats.ChunksProcessed++
+		if stats.Offsets == nil {
+			stats.Offsets = make(map[string][]Span, len(chunks))
+		}
+		stats.Offsets[chunk.ID] = spans
+		if removed == 0 {
+			stats.OutputTokens += inputTokens
+			result = append(result, chunk)
+			continue
+		}
+
+		outputTokens := tokens.Count(text)
+		stats.OutputTokens += outputTokens
+		stats.RepeatsRemoved += removed
+		stats.RepeatTokensSaved += inputTokens - outputTokens
+
+		newChunk := chunk.Clone()
+		newChunk.Text = text
+		result = append(result, *newChunk)
+	}
+
+	stats.Latency = time.Since(start)
+	if stats.InputTokens > 0 {
+		stats.ReductionPercent = float64(stats.InputTokens-stats.OutputTokens) / float64(stats.InputTokens) * 100
+	}
+
+	return result, stats, nil
+}
+
+// removeRepeats returns text without repeated paragraphs and sentences,
+// the spans of text it kept, and the number removed. Text is returned
+// unchanged when nothing is.
+func (r *RepeatRemover) removeRepeats(text string, preserveStructure bool) (string, []Span, int) {
+	seenParagraphs := newShingleIndex(r.Threshold)
+	seenSentences := newShingleIndex(r.Threshold)
+
+	out := spanBuilder{src: text}
+	removed := 0
+	for _, p := range paragraphBounds(text) {
+		paragraph := text[p[0]:p[1]]
+		shingles := r.shingles(paragraph)
+		if len(normalizedWords(paragraph)) >= r.MinSentenceWords && seenParagraphs.contains(shingles) {
+			removed++
+			continue
+		}
+		seenParagraphs.add(shingles)
+
+		kept := [][2]int{p}
+		if !preserveStructure || !looksStructured(paragraph) {
+			var n int
+			kept, n = r.removeRepeatedSentences(text, p, seenSentences)
+			removed += n
+		}
+		for i, k := range kept {
+			switch {
+			case i > 0:
+				out.write(" ")
+			case out.Len() > 0:
+				out.write("\n\n")
+			}
+			out.copy(k[0], k[1])
+		}
+	}
+
+	if removed == 0 {
+		return text, identitySpans(text), 0
+	}
+	return out.String(), out.spans, removed
+}
+
+// removeRepeatedSentences returns the ranges of the sentences in
+// text[p[0]:p[1]] not already in seen, adding them to it, or p itself when
+// none are dropped.
+func (r *RepeatRemover) removeRepeatedSentences(text string, p [2]int, seen *shingleIndex) ([][2]int, int) {
+	sentences := sentenceBounds(text, p[0], p[1])
+	kept := make([][2]int, 0, len(sentences))
+	removed := 0
+	for _, b := range sentences {
+		sentence := text[b[0]:b[1]]
+		if len(normalizedWords(sentence)) < r.MinSentenceWords {
+			kept = append(kept, b)
+			continue
+		}
+		shingles := r.shingles(sentence)
+		if seen.contains(shingles) {
+			removed++
+			continue
+		}
+		seen.add(shingles)
+		kept = append(kept, b)
+	}
+
+	if removed == 0 {
+		return [][2]int{p}, 0
+	}
+	return kept, removed
+}
+
+// shingles returns the hashed word shingles of text.
+func (r *RepeatRemover) shingles(text string) map[uint64]struct{} {
+	words := normalizedWords(text)
+	if len(words) == 0 {
+		return nil
+	}
+	size := max(1, r.ShingleSize)
+	if len(words) < size {
+		size = len(words)
+	}
+
+	set := make(map[uint64]struct{}, len(words)-size+1)
+	for i := 0; i+size <= len(words); i++ {
+		h := fnv.New64a()
+		for _, w := range words[i : i+size] {
+			h.Write([]byte(w))
+			h.Write([]byte{0})
+		}
+		set[h.Sum64()] = struct{}{}
+	}
+	return set
+}
+
+// normalizedWords returns the lowercase words of text with surrounding
+// punctuation trimmed.
+func normalizedWords(text string) []string {
+	fields := strings.Fields(strings.ToLower(text))
+	words := fields[:0]
+	for _, f := range fields {
+		w := strings.TrimFunc(f, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// paragraphBounds returns the byte ranges of the paragraphs in text, with
+// surrounding whitespace trimmed.
+func paragraphBounds(text string) [][2]int {
+	var bounds [][2]int
+	begin := 0
+	for _, brk := range append(paragraphBreak.FindAllStringIndex(text, -1), []int{len(text), len(text)}) {
+		if start, end := trimBounds(text, begin, brk[0]); start < end {
+			bounds = append(bounds, [2]int{start, end})
+		}
+		begin = brk[1]
+	}
+	return bounds
+}
+
+// sentenceBounds returns the byte ranges of the sentences in
+// text[start:end], trimmed, ending a sentence at terminal punctuation
+// followed by whitespace.
+func sentenceBounds(text string, start, end int) [][2]int {
+	var bounds [][2]int
+	add := func(from, to int) {
+		if from, to = trimBounds(text, from, to); from < to {
+			bounds = append(bounds, [2]int{from, to})
+		}
+	}
+	begin := start
+	for i, c := range text[start:end] {
+		if !strings.ContainsRune(".!?", c) {
+			continue
+		}
+		next := start + i + 1
+		if next < end {
+			if r, _ := utf8.DecodeRuneInString(text[next:end]); !unicode.IsSpace(r) {
+				continue
+			}
+		}
+		add(begin, next)
+		begin = next
+	}
+	add(begin, end)
+	return bounds
+}
+
+// looksStructured reports whether a paragraph looks like code, JSON or
+// markup, where repeated lines are usually meaningful.
+func looksStructured(paragraph string) bool {
+	if strings.Contains(paragraph, "```") {
+		return true
+	}
+	trimmed := strings.TrimSpace(paragraph)
+	if trimmed != "" && strings.ContainsRune("{[<", rune(trimmed[0])) {
+		return true
+	}
+	for _, line := range strings.Split(paragraph, "\n") {
+		if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ") {
+			return true
+		}
+	}
+	return false
+}
+
+// shingleIndex finds earlier text whose shingle set is similar to a new
+// one, looking up candidates through shared shingles.
+type shingleIndex struct {
+	threshold float64
+	sets      []map[uint64]struct{}
+	postings  map[uint64][]int
+}
+
+func newShingleIndex(threshold float64) *shingleIndex {
+	return &shingleIndex{
+		threshold: threshold,
+		postings:  make(map[uint64][]int),
+	}
+}
+
+// contains reports whether a set at least threshold similar to shingles
+// has been added.
+func (x *shingleIndex) contains(shingles map[uint64]struct{}) bool {
+	shared := make(map[int]int)
+	for h := range shingles {
+		for _, id := range x.postings[h] {
+			shared[id]++
+		}
+	}
+	for id, n := range shared {
+		union := len(shingles) + len(x.sets[id]) - n
+		if float64(n)/float64(union) >= x.threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// add records shingles for later lookups.
+func (x *shingleIndex) add(shingles map[uint64]struct{}) {
+	id := len(x.sets)
+	x.sets = append(x.sets, shingles)
+	for h := range shingles {
+		x.postings[h] = append(x.postings[h], id)
+	}
+}
@@ -5,22 +5,51 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/embedding/fake"
+	"github.com/Siddhant-K-code/distill/pkg/gctune"
+	"github.com/Siddhant-K-code/distill/pkg/models"
+	"github.com/Siddhant-K-code/distill/pkg/profiling"
+	"github.com/Siddhant-K-code/distill/pkg/render"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/retriever/pinecone"
+	"github.com/Siddhant-K-code/distill/pkg/retriever/qdrant"
+	"github.com/Siddhant-K-code/distill/pkg/sensitivity"
 	"github.com/spf13/viper"
 )
 
 // Config represents the full Distill configuration.
 type Config struct {
-	Server    ServerConfig    `mapstructure:"server"`
-	Embedding EmbeddingConfig `mapstructure:"embedding"`
-	Dedup     DedupConfig     `mapstructure:"dedup"`
-	Retriever RetrieverConfig `mapstructure:"retriever"`
-	Auth      AuthConfig      `mapstructure:"auth"`
-	Telemetry TelemetryConfig `mapstructure:"telemetry"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Embedding  EmbeddingConfig  `mapstructure:"embedding"`
+	Dedup      DedupConfig      `mapstructure:"dedup"`
+	Retriever  RetrieverConfig  `mapstructure:"retriever"`
+	Auth       AuthConfig       `mapstructure:"auth"`
+	Telemetry  TelemetryConfig  `mapstructure:"telemetry"`
+	Runtime    RuntimeConfig    `mapstructure:"runtime"`
+	Limits     LimitsConfig     `mapstructure:"limits"`
+	Capture    CaptureConfig    `mapstructure:"capture"`
+	Render     RenderConfig     `mapstructure:"render"`
+	Tuning     TuningConfig     `mapstructure:"tuning"`
+	History    HistoryConfig    `mapstructure:"history"`
+	Seen       SeenConfig       `mapstructure:"seen"`
+	Analytics  AnalyticsConfig  `mapstructure:"analytics"`
+	Enrichment EnrichmentConfig `mapstructure:"enrichment"`
+	ACL        ACLConfig        `mapstructure:"acl"`
+	Safety     SafetyConfig     `mapstructure:"safety"`
+	Garbage    GarbageConfig    `mapstructure:"garbage"`
+	Cache      CacheConfig      `mapstructure:"cache"`
+	Pipeline   PipelineConfig   `mapstructure:"pipeline"`
+	Rerank     RerankConfig     `mapstructure:"rerank"`
+	Models     ModelsConfig     `mapstructure:"models"`
 }
 
 // ServerConfig holds HTTP server settings.
@@ -29,6 +58,24 @@ type ServerConfig struct {
 	Host         string        `mapstructure:"host"`
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+
+	// Response compression and HTTP/2 transport.
+	Compression      bool   `mapstructure:"compression"`
+	CompressMinBytes int    `mapstructure:"compress_min_bytes"`
+	H2C              bool   `mapstructure:"h2c"`
+	H2StreamBuffer   string `mapstructure:"h2_stream_buffer"`
+	H2ConnBuffer     string `mapstructure:"h2_conn_buffer"`
+
+	// AllowWrites serves PUT /v1/vectors.
+	AllowWrites bool `mapstructure:"allow_writes"`
+
+	// GRPCPort also serves the gRPC API on serve (0 = off).
+	GRPCPort int `mapstructure:"grpc_port"`
+
+	// Socket serves HTTP on this Unix domain socket instead of Host and
+	// Port. SocketMode is its permissions in octal (default 0660).
+	Socket     string `mapstructure:"socket"`
+	SocketMode string `mapstructure:"socket_mode"`
 }
 
 // EmbeddingConfig holds embedding provider settings.
@@ -37,6 +84,31 @@ type EmbeddingConfig struct {
 	Model     string `mapstructure:"model"`
 	BaseURL   string `mapstructure:"base_url"`
 	BatchSize int    `mapstructure:"batch_size"`
+
+	// CacheSize is how many embedded texts the provider keeps in memory
+	// (0 = off), each for CacheTTL (0 = until evicted). Repeated texts,
+	// such as system prompts, are not sent to the provider again.
+	CacheSize int           `mapstructure:"cache_size"`
+	CacheTTL  time.Duration `mapstructure:"cache_ttl"`
+
+	// Embeddings returned to clients with include_embeddings.
+	ResponseDims      int    `mapstructure:"response_dims"`
+	ResponseReduction string `mapstructure:"response_reduction"`
+
+	// Normalization overrides the text contract applied before
+	// embedding, keyed by provider, so each provider keeps its own when
+	// switching between them.
+	Normalization map[string]NormalizationConfig `mapstructure:"normalization"`
+}
+
+// NormalizationConfig overrides one provider's text normalization. Unset
+// fields keep embedding.DefaultNormalization.
+type NormalizationConfig struct {
+	StripControl  *bool  `mapstructure:"strip_control"`
+	MaxChars      *int   `mapstructure:"max_chars"`
+	QueryPrefix   string `mapstructure:"query_prefix"`
+	PassagePrefix string `mapstructure:"passage_prefix"`
+	ModelPrefixes bool   `mapstructure:"model_prefixes"`
 }
 
 // DedupConfig holds deduplication settings.
@@ -46,6 +118,62 @@ type DedupConfig struct {
 	Linkage   string  `mapstructure:"linkage"`
 	Lambda    float64 `mapstructure:"lambda"`
 	EnableMMR bool    `mapstructure:"enable_mmr"`
+
+	// Switches for the other optional stages. Selection is the cluster
+	// representative strategy: score, centroid, length, or hybrid.
+	// EnableScoring applies RecencyWeight. Requests can override each.
+	EnableClustering     bool   `mapstructure:"enable_clustering"`
+	Selection            string `mapstructure:"selection"`
+	EnableCompression    bool   `mapstructure:"enable_compression"`
+	EnableRedaction      bool   `mapstructure:"enable_redaction"`
+	EnableClassification bool   `mapstructure:"enable_classification"`
+	EnableScoring        bool   `mapstructure:"enable_scoring"`
+
+	// CompressionMode (extractive, placeholder, hybrid, or
+	// cluster-merge), CompressionTarget, CompressionMergeTokens, and
+	// PreserveStructure configure EnableCompression; see
+	// compress.Options.
+	CompressionMode        string  `mapstructure:"compression_mode"`
+	CompressionTarget      float64 `mapstructure:"compression_target"`
+	CompressionMergeTokens int     `mapstructure:"compression_merge_tokens"`
+	PreserveStructure      *bool   `mapstructure:"preserve_structure"`
+
+	// RecencyWeight blends a chunk's recency into MMR relevance (0 = off).
+	// Recency halves every RecencyHalfLife, measured from the
+	// TimestampField metadata value.
+	RecencyWeight   float64       `mapstructure:"recency_weight"`
+	RecencyHalfLife time.Duration `mapstructure:"recency_half_life"`
+	TimestampField  string        `mapstructure:"timestamp_field"`
+
+	// MMRSeed orders chunks with tied MMR scores: 0 keeps retrieval
+	// order, other values a fixed permutation.
+	MMRSeed int64 `mapstructure:"mmr_seed"`
+
+	// Entities vetoes merging chunks that name different entities.
+	Entities EntityConfig `mapstructure:"entities"`
+
+	// Hints adds dedup context (cluster size, duplicates removed,
+	// representative reason) to returned chunks' metadata.
+	Hints bool `mapstructure:"hints"`
+}
+
+// EntityConfig configures the entity veto on cluster merges.
+type EntityConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Terms is the entity vocabulary; empty extracts key terms heuristically.
+	Terms []string `mapstructure:"terms"`
+
+	// Namespaces overrides Enabled and Terms for individual namespaces.
+	// It is a list rather than a map so namespace names keep their case.
+	Namespaces []NamespaceEntities `mapstructure:"namespaces"`
+}
+
+// NamespaceEntities is the entity veto for one namespace.
+type NamespaceEntities struct {
+	Namespace string   `mapstructure:"namespace"`
+	Enabled   bool     `mapstructure:"enabled"`
+	Terms     []string `mapstructure:"terms"`
 }
 
 // RetrieverConfig holds vector DB settings.
@@ -56,6 +184,75 @@ type RetrieverConfig struct {
 	Namespace string `mapstructure:"namespace"`
 	TopK      int    `mapstructure:"top_k"`
 	TargetK   int    `mapstructure:"target_k"`
+
+	// TokenBudget, when positive, replaces TargetK: serve returns chunks
+	// until their tokens would exceed it.
+	TokenBudget int `mapstructure:"token_budget"`
+
+	// MinCandidates is the fewest candidates worth clustering; queries
+	// left with fewer skip clustering and MMR and are reported sparse.
+	// Zero disables it.
+	MinCandidates int `mapstructure:"min_candidates"`
+
+	// PreviousNamespace is the namespace served before the last
+	// `distill reindex`, kept for `distill reindex rollback`.
+	PreviousNamespace string `mapstructure:"previous_namespace"`
+
+	// MinScore drops matches scoring below it, server-side on Qdrant.
+	MinScore float64 `mapstructure:"min_score"`
+
+	// Timeout bounds each query, including retries. MaxRetries is how
+	// often a call failing with a transient gRPC error (Unavailable or
+	// ResourceExhausted) is retried; negative disables retries.
+	Timeout    time.Duration `mapstructure:"timeout"`
+	MaxRetries int           `mapstructure:"max_retries"`
+
+	// IncludeTombstoned returns duplicates soft-deleted by
+	// `distill sync --tombstone`, which are excluded by default.
+	IncludeTombstoned bool `mapstructure:"include_tombstoned"`
+
+	// IncludeMetadataFields, when set, keeps only these metadata fields
+	// of each match, and ExcludeMetadataFields drops fields. Qdrant
+	// applies them server-side; other backends trim matches as they
+	// arrive.
+	IncludeMetadataFields []string `mapstructure:"include_metadata_fields"`
+	ExcludeMetadataFields []string `mapstructure:"exclude_metadata_fields"`
+
+	// CrossNamespaceGroups, when set, limits requests searching several
+	// namespaces (or "*", every namespace) to identities in these groups.
+	CrossNamespaceGroups []string `mapstructure:"cross_namespace_groups"`
+
+	// WarmScan samples namespaces when the server starts, so requests
+	// that cannot match them fail with a descriptive error.
+	WarmScan WarmScanConfig `mapstructure:"warm_scan"`
+
+	// Params holds backend-specific tuning, keyed by backend name. Each
+	// section is free-form here and validated by its adapter.
+	Params map[string]map[string]interface{} `mapstructure:"params"`
+
+	Fake FakeConfig `mapstructure:"fake"`
+}
+
+// WarmScanConfig configures the startup sample of each namespace.
+type WarmScanConfig struct {
+	// Sample is how many chunks to sample per namespace; 0 disables the
+	// scan.
+	Sample int `mapstructure:"sample"`
+
+	// Namespaces lists the namespaces to sample; "*" is every namespace
+	// the backend lists. Empty samples the default namespace.
+	Namespaces []string `mapstructure:"namespaces"`
+}
+
+// FakeConfig configures the "fake" backend: an in-memory synthetic
+// corpus for tests, benchmarks, and demos.
+type FakeConfig struct {
+	CorpusSize    int     `mapstructure:"corpus_size"`
+	DuplicateRate float64 `mapstructure:"duplicate_rate"`
+	Dimension     int     `mapstructure:"dimension"`
+	Seed          int64   `mapstructure:"seed"`
+	Latency       string  `mapstructure:"latency"`
+	ErrorRate     float64 `mapstructure:"error_rate"`
 }
 
 // AuthConfig holds authentication settings.
@@ -65,7 +262,9 @@ type AuthConfig struct {
 
 // TelemetryConfig holds observability settings.
 type TelemetryConfig struct {
-	Tracing TracingConfig `mapstructure:"tracing"`
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	Profiling ProfilingConfig `mapstructure:"profiling"`
+	Usage     UsageConfig     `mapstructure:"usage"`
 }
 
 // TracingConfig holds OpenTelemetry tracing settings.
@@ -77,31 +276,321 @@ type TracingConfig struct {
 	Insecure   bool    `mapstructure:"insecure"`
 }
 
+// ProfilingConfig holds continuous profiling settings: net/http/pprof on
+// an internal address and CPU profiles pushed to a Pyroscope server.
+type ProfilingConfig struct {
+	Enabled           bool              `mapstructure:"enabled"`
+	PprofAddr         string            `mapstructure:"pprof_addr"`
+	PushURL           string            `mapstructure:"push_url"`
+	AppName           string            `mapstructure:"app_name"`
+	Interval          time.Duration     `mapstructure:"interval"`
+	Labels            map[string]string `mapstructure:"labels"`
+	AuthToken         string            `mapstructure:"auth_token"`
+	BasicAuthUser     string            `mapstructure:"basic_auth_user"`
+	BasicAuthPassword string            `mapstructure:"basic_auth_password"`
+}
+
+// UsageConfig controls the opt-in anonymized usage statistics ping; see
+// distill telemetry show for its payload.
+type UsageConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Endpoint string        `mapstructure:"endpoint"`
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// RuntimeConfig holds Go runtime GC tuning for serving workloads.
+type RuntimeConfig struct {
+	MemoryLimit string `mapstructure:"memory_limit"`
+	GCPercent   int    `mapstructure:"gc_percent"`
+	BallastMB   int    `mapstructure:"ballast_mb"`
+}
+
+// LimitsConfig caps request sizes accepted by the api and serve commands.
+// Zero disables a limit.
+type LimitsConfig struct {
+	MaxChunks     int    `mapstructure:"max_chunks"`
+	MaxDimension  int    `mapstructure:"max_dimension"`
+	MaxInputBytes string `mapstructure:"max_input_bytes"`
+
+	// MaxMatrixBytes caps clustering's distance matrix memory; larger
+	// inputs are clustered per MatrixOverflow ("spill" or "window")
+	// instead of rejected. Empty disables the cap.
+	MaxMatrixBytes string `mapstructure:"max_matrix_bytes"`
+	MatrixOverflow string `mapstructure:"matrix_overflow"`
+	SpillDir       string `mapstructure:"spill_dir"`
+}
+
+// CaptureConfig controls the flight recorder for anomalous requests.
+type CaptureConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Size             int           `mapstructure:"size"`
+	LatencyThreshold time.Duration `mapstructure:"latency_threshold"`
+	MaxReductionPct  int           `mapstructure:"max_reduction_pct"`
+	MinReductionPct  int           `mapstructure:"min_reduction_pct"`
+	Privacy          bool          `mapstructure:"privacy"`
+}
+
+// RenderConfig holds the templates that render results into the
+// rendered field of retrieval responses.
+type RenderConfig struct {
+	// Default is the template rendered when a request names none. Empty
+	// leaves responses unrendered unless a request asks.
+	Default string `mapstructure:"default"`
+
+	// Templates are Go text/templates by name, alongside the built-ins
+	// (plain, numbered, markdown, xml).
+	Templates map[string]string `mapstructure:"templates"`
+}
+
+// TuningConfig controls online tuning of threshold and lambda from
+// feedback on serve's /v1/retrieve responses.
+type TuningConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Fraction       float64       `mapstructure:"fraction"`
+	MinFeedback    int           `mapstructure:"min_feedback"`
+	MinLift        float64       `mapstructure:"min_lift"`
+	FeedbackWindow time.Duration `mapstructure:"feedback_window"`
+}
+
+// HistoryConfig controls the job and request history store.
+type HistoryConfig struct {
+	// Path is the SQLite database sync, analyze, and the servers record
+	// into. Empty disables recording.
+	Path string `mapstructure:"path"`
+
+	// RecordQueries stores the query text of served requests, which
+	// distill cache warm --from-history replays.
+	RecordQueries bool `mapstructure:"record_queries"`
+
+	// NamespaceWindow is how far back serve's per-namespace aggregates
+	// look, and NamespaceSnapshotInterval how often they are recorded
+	// into Path.
+	NamespaceWindow           time.Duration `mapstructure:"namespace_window"`
+	NamespaceSnapshotInterval time.Duration `mapstructure:"namespace_snapshot_interval"`
+}
+
+// SeenConfig controls the Bloom filter of content already ingested into
+// each namespace, which sync and PUT /v1/vectors consult to skip exact
+// duplicates without querying the index.
+type SeenConfig struct {
+	// Path is the file the filters are loaded from and saved to. Empty
+	// disables the filter.
+	Path string `mapstructure:"path"`
+
+	// Capacity is the number of vectors a namespace's filter is sized
+	// for before it grows, and FPRate the share of new vectors it may
+	// wrongly skip.
+	Capacity int     `mapstructure:"capacity"`
+	FPRate   float64 `mapstructure:"fp_rate"`
+
+	// SaveInterval is how often serve saves the filters to Path. They
+	// are also saved at shutdown.
+	SaveInterval time.Duration `mapstructure:"save_interval"`
+}
+
+// AnalyticsConfig controls the export of per-request analytic records to
+// a warehouse.
+type AnalyticsConfig struct {
+	// Sink is clickhouse or bigquery. Empty disables the export.
+	Sink string `mapstructure:"sink"`
+
+	// SampleRate is the fraction of requests recorded.
+	SampleRate float64 `mapstructure:"sample_rate"`
+
+	BatchSize     int           `mapstructure:"batch_size"`
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+	Buffer        int           `mapstructure:"buffer"`
+
+	ClickHouse ClickHouseSinkConfig `mapstructure:"clickhouse"`
+	BigQuery   BigQuerySinkConfig   `mapstructure:"bigquery"`
+}
+
+// ClickHouseSinkConfig locates the ClickHouse analytics table.
+type ClickHouseSinkConfig struct {
+	URL      string `mapstructure:"url"`
+	Database string `mapstructure:"database"`
+	Table    string `mapstructure:"table"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+}
+
+// BigQuerySinkConfig locates the BigQuery analytics table.
+type BigQuerySinkConfig struct {
+	Project     string `mapstructure:"project"`
+	Dataset     string `mapstructure:"dataset"`
+	Table       string `mapstructure:"table"`
+	AccessToken string `mapstructure:"access_token"`
+}
+
+// EnrichmentConfig configures the hook that adds metadata to retrieved
+// chunks before clustering.
+type EnrichmentConfig struct {
+	// Type is "http" or "plugin". Empty disables enrichment.
+	Type string `mapstructure:"type"`
+
+	// URL receives batches when Type is "http"; Headers are sent with
+	// every call and may reference ${ENV_VARS}.
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+
+	// Plugin is the Go plugin (.so) loaded when Type is "plugin".
+	Plugin string `mapstructure:"plugin"`
+
+	Timeout       time.Duration `mapstructure:"timeout"`
+	BatchSize     int           `mapstructure:"batch_size"`
+	Concurrency   int           `mapstructure:"concurrency"`
+	FailurePolicy string        `mapstructure:"failure_policy"`
+}
+
+// ACLConfig controls per-chunk access control in serve.
+type ACLConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	GroupsField string `mapstructure:"groups_field"`
+	UsersField  string `mapstructure:"users_field"`
+}
+
+// SafetyConfig controls serve's prompt-injection filter.
+type SafetyConfig struct {
+	// InjectionFilter is off, flag, strip, or block.
+	InjectionFilter    string  `mapstructure:"injection_filter"`
+	InjectionThreshold float64 `mapstructure:"injection_threshold"`
+
+	// SensitivityExamples classify chunks by meaning for
+	// dedup.enable_classification: a chunk whose embedding is within
+	// SensitivityThreshold of an example's takes its level.
+	SensitivityExamples  []SensitivityExample `mapstructure:"sensitivity_examples"`
+	SensitivityThreshold float64              `mapstructure:"sensitivity_threshold"`
+}
+
+// SensitivityExample is a text whose neighbours share its sensitivity
+// level: pii, internal, or credentials.
+type SensitivityExample struct {
+	Name  string `mapstructure:"name"`
+	Level string `mapstructure:"level"`
+	Text  string `mapstructure:"text"`
+}
+
+// GarbageConfig controls serve's boilerplate filter.
+type GarbageConfig struct {
+	Enabled   bool    `mapstructure:"enabled"`
+	Threshold float64 `mapstructure:"threshold"`
+
+	// MinRepeats is how many distinct chunks must share a text for it to
+	// count as boilerplate (0 = off), within RepeatTTL.
+	MinRepeats int           `mapstructure:"min_repeats"`
+	RepeatTTL  time.Duration `mapstructure:"repeat_ttl"`
+
+	// Allow lists regular expressions for chunks never dropped.
+	Allow []string `mapstructure:"allow"`
+}
+
+// CacheConfig controls serve's query embedding and result caches.
+type CacheConfig struct {
+	// EmbeddingSize is the most query embeddings kept (0 = off).
+	EmbeddingSize int           `mapstructure:"embedding_size"`
+	EmbeddingTTL  time.Duration `mapstructure:"embedding_ttl"`
+
+	// ResultTTL is how long retrieve results are kept (0 = off).
+	ResultTTL  time.Duration `mapstructure:"result_ttl"`
+	ResultSize int           `mapstructure:"result_size"`
+
+	// TenantMaxBytesPerProcess caps the result bytes each namespace keeps
+	// cached, e.g. "64MiB" (empty = unlimited), evicting its least
+	// recently used results first. TenantQuotasPerProcess overrides it by
+	// namespace. Usage is tracked in each process, so replicas sharing a
+	// Redis cache each enforce the quota on the results they wrote.
+	TenantMaxBytesPerProcess string            `mapstructure:"tenant_max_bytes_per_process"`
+	TenantQuotasPerProcess   map[string]string `mapstructure:"tenant_quotas_per_process"`
+
+	// Backend is "memory", "disk", or "redis". Disk caches live in Dir
+	// and survive restarts; Redis caches are shared by every replica.
+	Backend string           `mapstructure:"backend"`
+	Dir     string           `mapstructure:"dir"`
+	Redis   RedisCacheConfig `mapstructure:"redis"`
+}
+
+// RedisCacheConfig connects the redis cache backend.
+type RedisCacheConfig struct {
+	URL          string        `mapstructure:"url"`
+	Password     string        `mapstructure:"password"`
+	DB           int           `mapstructure:"db"`
+	KeyPrefix    string        `mapstructure:"key_prefix"`
+	PoolSize     int           `mapstructure:"pool_size"`
+	DialTimeout  time.Duration `mapstructure:"dial_timeout"`
+	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+}
+
+// PipelineConfig declares the stages serve runs after retrieval. Empty
+// keeps the built-in cluster, select, and MMR pipeline.
+type PipelineConfig struct {
+	// Stages are stage names or maps of one name to its params; see
+	// contextlab.ParseStages.
+	Stages []interface{} `mapstructure:"stages"`
+}
+
+// RerankConfig points rerank pipeline stages at a self-hosted reranker
+// speaking the pkg/rerank HTTP contract.
+type RerankConfig struct {
+	// URL is the server's base URL; requests go to URL/rerank. APIKey,
+	// if set, is sent as a bearer token and may reference ${ENV_VARS}.
+	URL    string `mapstructure:"url"`
+	Model  string `mapstructure:"model"`
+	APIKey string `mapstructure:"api_key"`
+
+	// BatchSize caps the documents per call (0 = all in one).
+	BatchSize int           `mapstructure:"batch_size"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+// ModelsConfig defines the downstream models requests can name with
+// model, each with its context window, tokenizer, pricing, and template.
+type ModelsConfig struct {
+	// Default is the profile for requests that name none. Empty leaves
+	// them unfitted.
+	Default  string           `mapstructure:"default"`
+	Profiles []models.Profile `mapstructure:"profiles"`
+}
+
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:         8080,
-			Host:         "0.0.0.0",
-			ReadTimeout:  30 * time.Second,
-			WriteTimeout: 60 * time.Second,
+			Port:             8080,
+			Host:             "0.0.0.0",
+			ReadTimeout:      30 * time.Second,
+			WriteTimeout:     60 * time.Second,
+			Compression:      true,
+			CompressMinBytes: 1024,
 		},
 		Embedding: EmbeddingConfig{
-			Provider:  "openai",
-			Model:     "text-embedding-3-small",
-			BatchSize: 100,
+			Provider:          "openai",
+			Model:             "text-embedding-3-small",
+			BatchSize:         100,
+			CacheSize:         10000,
+			CacheTTL:          24 * time.Hour,
+			ResponseReduction: "truncate",
 		},
 		Dedup: DedupConfig{
-			Threshold: 0.15,
-			Method:    "agglomerative",
-			Linkage:   "average",
-			Lambda:    0.5,
-			EnableMMR: true,
+			Threshold:        0.15,
+			Method:           "agglomerative",
+			Linkage:          "average",
+			Lambda:           0.5,
+			EnableMMR:        true,
+			EnableClustering: true,
+			Selection:        "score",
+			EnableScoring:    true,
+			RecencyHalfLife:  7 * 24 * time.Hour,
+			TimestampField:   "timestamp",
 		},
 		Retriever: RetrieverConfig{
-			Backend: "pinecone",
-			TopK:    50,
-			TargetK: 8,
+			Backend:       "pinecone",
+			TopK:          50,
+			TargetK:       8,
+			MinCandidates: 4,
+			Timeout:       30 * time.Second,
+			MaxRetries:    3,
+			WarmScan:      WarmScanConfig{Sample: 50},
 		},
 		Auth: AuthConfig{
 			APIKeys: []string{},
@@ -114,6 +603,88 @@ func DefaultConfig() *Config {
 				SampleRate: 1.0,
 				Insecure:   true,
 			},
+			Profiling: ProfilingConfig{
+				PprofAddr: "127.0.0.1:6060",
+				AppName:   "distill",
+				Interval:  15 * time.Second,
+			},
+			Usage: UsageConfig{
+				Interval: 24 * time.Hour,
+			},
+		},
+		Limits: LimitsConfig{
+			MaxChunks:      2000,
+			MaxDimension:   4096,
+			MaxInputBytes:  "16MiB",
+			MatrixOverflow: "spill",
+		},
+		Capture: CaptureConfig{
+			Size: 100,
+		},
+		History: HistoryConfig{
+			NamespaceWindow:           time.Hour,
+			NamespaceSnapshotInterval: 5 * time.Minute,
+		},
+		Seen: SeenConfig{
+			Capacity:     1000000,
+			FPRate:       0.001,
+			SaveInterval: time.Minute,
+		},
+		Tuning: TuningConfig{
+			Fraction:       0.1,
+			MinFeedback:    200,
+			MinLift:        0.03,
+			FeedbackWindow: 15 * time.Minute,
+		},
+		Analytics: AnalyticsConfig{
+			SampleRate:    1.0,
+			BatchSize:     500,
+			FlushInterval: 5 * time.Second,
+			Buffer:        10000,
+			ClickHouse: ClickHouseSinkConfig{
+				Database: "default",
+				Table:    "distill_requests",
+			},
+			BigQuery: BigQuerySinkConfig{
+				Table: "distill_requests",
+			},
+		},
+		Enrichment: EnrichmentConfig{
+			Timeout:       500 * time.Millisecond,
+			BatchSize:     100,
+			Concurrency:   4,
+			FailurePolicy: "open",
+		},
+		ACL: ACLConfig{
+			GroupsField: "allowed_groups",
+			UsersField:  "allowed_users",
+		},
+		Safety: SafetyConfig{
+			InjectionFilter:    "off",
+			InjectionThreshold: 0.5,
+		},
+		Garbage: GarbageConfig{
+			Threshold:  0.6,
+			MinRepeats: 5,
+			RepeatTTL:  24 * time.Hour,
+		},
+		Cache: CacheConfig{
+			EmbeddingSize: 10000,
+			EmbeddingTTL:  24 * time.Hour,
+			ResultSize:    10000,
+			Backend:       "memory",
+			Dir:           "distill-cache",
+			Redis: RedisCacheConfig{
+				URL:          "redis://localhost:6379",
+				KeyPrefix:    "distill:",
+				PoolSize:     10,
+				DialTimeout:  5 * time.Second,
+				ReadTimeout:  3 * time.Second,
+				WriteTimeout: 3 * time.Second,
+			},
+		},
+		Rerank: RerankConfig{
+			Timeout: 5 * time.Second,
 		},
 	}
 }
@@ -159,21 +730,67 @@ func Validate(cfg *Config) error {
 	if cfg.Server.Port < 0 || cfg.Server.Port > 65535 {
 		errs = append(errs, fmt.Sprintf("server.port: must be between 0 and 65535, got %d", cfg.Server.Port))
 	}
+	if cfg.Server.GRPCPort < 0 || cfg.Server.GRPCPort > 65535 {
+		errs = append(errs, fmt.Sprintf("server.grpc_port: must be between 0 and 65535, got %d", cfg.Server.GRPCPort))
+	} else if cfg.Server.GRPCPort != 0 && cfg.Server.GRPCPort == cfg.Server.Port {
+		errs = append(errs, "server.grpc_port: must differ from server.port")
+	}
+	if !ValidHost(cfg.Server.Host) {
+		errs = append(errs, fmt.Sprintf("server.host: invalid host %q (write IPv6 literals as :: or [::1])", cfg.Server.Host))
+	}
+	if _, err := ParseSocketMode(cfg.Server.SocketMode); err != nil {
+		errs = append(errs, fmt.Sprintf("server.socket_mode: %v", err))
+	}
 	if cfg.Server.ReadTimeout < 0 {
 		errs = append(errs, "server.read_timeout: must be non-negative")
 	}
 	if cfg.Server.WriteTimeout < 0 {
 		errs = append(errs, "server.write_timeout: must be non-negative")
 	}
+	if cfg.Server.CompressMinBytes < 0 {
+		errs = append(errs, "server.compress_min_bytes: must be non-negative")
+	}
+	if cfg.Server.H2StreamBuffer != "" {
+		if _, err := gctune.ParseBytes(cfg.Server.H2StreamBuffer); err != nil {
+			errs = append(errs, fmt.Sprintf("server.h2_stream_buffer: %v", err))
+		}
+	}
+	if cfg.Server.H2ConnBuffer != "" {
+		if _, err := gctune.ParseBytes(cfg.Server.H2ConnBuffer); err != nil {
+			errs = append(errs, fmt.Sprintf("server.h2_conn_buffer: %v", err))
+		}
+	}
 
 	// Embedding validation
-	validProviders := map[string]bool{"openai": true, "ollama": true, "cohere": true, "": true}
+	validProviders := map[string]bool{"openai": true, "ollama": true, "cohere": true, "voyage": true, "local": true, "fake": true, "": true}
 	if !validProviders[cfg.Embedding.Provider] {
-		errs = append(errs, fmt.Sprintf("embedding.provider: unsupported provider %q (supported: openai, ollama, cohere)", cfg.Embedding.Provider))
+		errs = append(errs, fmt.Sprintf("embedding.provider: unsupported provider %q (supported: openai, ollama, cohere, voyage, local, fake)", cfg.Embedding.Provider))
 	}
 	if cfg.Embedding.BatchSize < 0 {
 		errs = append(errs, "embedding.batch_size: must be non-negative")
 	}
+	if cfg.Embedding.CacheSize < 0 {
+		errs = append(errs, fmt.Sprintf("embedding.cache_size: must be non-negative, got %d", cfg.Embedding.CacheSize))
+	}
+	if cfg.Embedding.CacheTTL < 0 {
+		errs = append(errs, "embedding.cache_ttl: must be non-negative")
+	}
+	if cfg.Embedding.ResponseDims < 0 {
+		errs = append(errs, "embedding.response_dims: must be non-negative")
+	}
+	for _, provider := range slices.Sorted(maps.Keys(cfg.Embedding.Normalization)) {
+		n := cfg.Embedding.Normalization[provider]
+		if !validProviders[provider] || provider == "" || provider == "fake" {
+			errs = append(errs, fmt.Sprintf("embedding.normalization.%s: unsupported provider (supported: openai, ollama, cohere, voyage, local)", provider))
+		}
+		if n.MaxChars != nil && *n.MaxChars < 0 {
+			errs = append(errs, fmt.Sprintf("embedding.normalization.%s.max_chars: must be non-negative", provider))
+		}
+	}
+	validReductions := map[string]bool{"truncate": true, "pca": true, "": true}
+	if !validReductions[cfg.Embedding.ResponseReduction] {
+		errs = append(errs, fmt.Sprintf("embedding.response_reduction: unsupported reduction %q (supported: truncate, pca)", cfg.Embedding.ResponseReduction))
+	}
 
 	// Dedup validation
 	if cfg.Dedup.Threshold < 0 || cfg.Dedup.Threshold > 1 {
@@ -190,11 +807,45 @@ func Validate(cfg *Config) error {
 	if cfg.Dedup.Lambda < 0 || cfg.Dedup.Lambda > 1 {
 		errs = append(errs, fmt.Sprintf("dedup.lambda: must be between 0 and 1, got %f", cfg.Dedup.Lambda))
 	}
+	validSelections := map[string]bool{"score": true, "centroid": true, "length": true, "hybrid": true, "": true}
+	if !validSelections[cfg.Dedup.Selection] {
+		errs = append(errs, fmt.Sprintf("dedup.selection: unsupported strategy %q (supported: score, centroid, length, hybrid)", cfg.Dedup.Selection))
+	}
+	if cfg.Dedup.CompressionMode != "" {
+		if _, err := compress.ParseMode(cfg.Dedup.CompressionMode); err != nil {
+			errs = append(errs, fmt.Sprintf("dedup.compression_mode: %v", err))
+		}
+	}
+	if cfg.Dedup.CompressionTarget < 0 || cfg.Dedup.CompressionTarget > 1 {
+		errs = append(errs, fmt.Sprintf("dedup.compression_target: must be between 0 and 1, got %f", cfg.Dedup.CompressionTarget))
+	}
+	if cfg.Dedup.CompressionMergeTokens < 0 {
+		errs = append(errs, fmt.Sprintf("dedup.compression_merge_tokens: must be non-negative, got %d", cfg.Dedup.CompressionMergeTokens))
+	}
+	if cfg.Dedup.RecencyWeight < 0 || cfg.Dedup.RecencyWeight > 1 {
+		errs = append(errs, fmt.Sprintf("dedup.recency_weight: must be between 0 and 1, got %f", cfg.Dedup.RecencyWeight))
+	}
+	if cfg.Dedup.RecencyHalfLife < 0 {
+		errs = append(errs, fmt.Sprintf("dedup.recency_half_life: must be non-negative, got %s", cfg.Dedup.RecencyHalfLife))
+	}
+	errs = append(errs, validateTerms("dedup.entities.terms", cfg.Dedup.Entities.Terms)...)
+	seenNamespaces := make(map[string]bool)
+	for i, ns := range cfg.Dedup.Entities.Namespaces {
+		key := fmt.Sprintf("dedup.entities.namespaces[%d]", i)
+		switch {
+		case ns.Namespace == "":
+			errs = append(errs, key+".namespace: must not be empty")
+		case seenNamespaces[ns.Namespace]:
+			errs = append(errs, fmt.Sprintf("%s.namespace: %q is listed more than once", key, ns.Namespace))
+		}
+		seenNamespaces[ns.Namespace] = true
+		errs = append(errs, validateTerms(key+".terms", ns.Terms)...)
+	}
 
 	// Retriever validation
-	validBackends := map[string]bool{"pinecone": true, "qdrant": true, "": true}
+	validBackends := map[string]bool{"pinecone": true, "qdrant": true, "fake": true, "": true}
 	if !validBackends[cfg.Retriever.Backend] {
-		errs = append(errs, fmt.Sprintf("retriever.backend: unsupported backend %q (supported: pinecone, qdrant)", cfg.Retriever.Backend))
+		errs = append(errs, fmt.Sprintf("retriever.backend: unsupported backend %q (supported: pinecone, qdrant, fake)", cfg.Retriever.Backend))
 	}
 	if cfg.Retriever.TopK < 0 {
 		errs = append(errs, "retriever.top_k: must be non-negative")
@@ -202,6 +853,54 @@ func Validate(cfg *Config) error {
 	if cfg.Retriever.TargetK < 0 {
 		errs = append(errs, "retriever.target_k: must be non-negative")
 	}
+	if cfg.Retriever.TokenBudget < 0 {
+		errs = append(errs, fmt.Sprintf("retriever.token_budget: must be non-negative, got %d", cfg.Retriever.TokenBudget))
+	}
+	if cfg.Retriever.MinCandidates < 0 {
+		errs = append(errs, fmt.Sprintf("retriever.min_candidates: must be non-negative, got %d", cfg.Retriever.MinCandidates))
+	}
+	if cfg.Retriever.MinScore < 0 {
+		errs = append(errs, fmt.Sprintf("retriever.min_score: must be non-negative, got %f", cfg.Retriever.MinScore))
+	}
+	if cfg.Retriever.Timeout < 0 {
+		errs = append(errs, "retriever.timeout: must be non-negative")
+	}
+	if cfg.Retriever.WarmScan.Sample < 0 {
+		errs = append(errs, fmt.Sprintf("retriever.warm_scan.sample: must be non-negative, got %d", cfg.Retriever.WarmScan.Sample))
+	}
+	fields := retriever.MetadataFields{Include: cfg.Retriever.IncludeMetadataFields, Exclude: cfg.Retriever.ExcludeMetadataFields}
+	if err := fields.Validate(); err != nil {
+		errs = append(errs, fmt.Sprintf("retriever.include_metadata_fields: %v", err))
+	}
+	for backend, raw := range cfg.Retriever.Params {
+		var err error
+		switch backend {
+		case "qdrant":
+			_, err = qdrant.ParseParams(raw)
+		case "pinecone":
+			_, err = pinecone.ParseParams(raw)
+		default:
+			err = fmt.Errorf("no tunable params for backend %q (supported: pinecone, qdrant)", backend)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("retriever.params.%s: %v", backend, err))
+		}
+	}
+	if cfg.Retriever.Fake.CorpusSize < 0 {
+		errs = append(errs, "retriever.fake.corpus_size: must be non-negative")
+	}
+	if cfg.Retriever.Fake.Dimension < 0 {
+		errs = append(errs, "retriever.fake.dimension: must be non-negative")
+	}
+	if cfg.Retriever.Fake.DuplicateRate > 1 {
+		errs = append(errs, fmt.Sprintf("retriever.fake.duplicate_rate: must be at most 1, got %f", cfg.Retriever.Fake.DuplicateRate))
+	}
+	if _, err := fake.ParseLatency(cfg.Retriever.Fake.Latency); err != nil {
+		errs = append(errs, fmt.Sprintf("retriever.fake.latency: %v", err))
+	}
+	if cfg.Retriever.Fake.ErrorRate < 0 || cfg.Retriever.Fake.ErrorRate > 1 {
+		errs = append(errs, fmt.Sprintf("retriever.fake.error_rate: must be between 0 and 1, got %f", cfg.Retriever.Fake.ErrorRate))
+	}
 
 	// Telemetry validation
 	validExporters := map[string]bool{"otlp": true, "stdout": true, "none": true, "": true}
@@ -212,6 +911,273 @@ func Validate(cfg *Config) error {
 		errs = append(errs, fmt.Sprintf("telemetry.tracing.sample_rate: must be between 0 and 1, got %f", cfg.Telemetry.Tracing.SampleRate))
 	}
 
+	if p := cfg.Telemetry.Profiling; p.Enabled {
+		if p.PprofAddr == "" && p.PushURL == "" {
+			errs = append(errs, "telemetry.profiling: enabled without pprof_addr or push_url")
+		}
+		err := profiling.Config{PushURL: p.PushURL, Interval: p.Interval, Labels: p.Labels}.Validate()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("telemetry.profiling: %v", err))
+		}
+	}
+
+	if u := cfg.Telemetry.Usage; u.Enabled {
+		if u.Interval < time.Hour {
+			errs = append(errs, fmt.Sprintf("telemetry.usage.interval: must be at least 1h, got %s", u.Interval))
+		}
+		if u.Endpoint != "" && !strings.HasPrefix(u.Endpoint, "https://") {
+			errs = append(errs, fmt.Sprintf("telemetry.usage.endpoint: must be an https URL, got %q", u.Endpoint))
+		}
+	}
+
+	// Runtime validation
+	if cfg.Runtime.MemoryLimit != "" {
+		if _, err := gctune.ParseBytes(cfg.Runtime.MemoryLimit); err != nil {
+			errs = append(errs, fmt.Sprintf("runtime.memory_limit: %v", err))
+		}
+	}
+	if cfg.Runtime.GCPercent < -1 {
+		errs = append(errs, fmt.Sprintf("runtime.gc_percent: must be -1 (off), 0 (default), or positive, got %d", cfg.Runtime.GCPercent))
+	}
+	if cfg.Runtime.BallastMB < 0 {
+		errs = append(errs, "runtime.ballast_mb: must be non-negative")
+	}
+
+	// Limits validation
+	if cfg.Limits.MaxChunks < 0 {
+		errs = append(errs, "limits.max_chunks: must be non-negative")
+	}
+	if cfg.Limits.MaxDimension < 0 {
+		errs = append(errs, "limits.max_dimension: must be non-negative")
+	}
+	if cfg.Limits.MaxInputBytes != "" {
+		if _, err := gctune.ParseBytes(cfg.Limits.MaxInputBytes); err != nil {
+			errs = append(errs, fmt.Sprintf("limits.max_input_bytes: %v", err))
+		}
+	}
+	if cfg.Limits.MaxMatrixBytes != "" {
+		if _, err := gctune.ParseBytes(cfg.Limits.MaxMatrixBytes); err != nil {
+			errs = append(errs, fmt.Sprintf("limits.max_matrix_bytes: %v", err))
+		}
+	}
+	switch cfg.Limits.MatrixOverflow {
+	case "", "spill", "window":
+	default:
+		errs = append(errs, fmt.Sprintf("limits.matrix_overflow: must be spill or window, got %q", cfg.Limits.MatrixOverflow))
+	}
+
+	// Capture validation
+	if cfg.Capture.Size < 0 {
+		errs = append(errs, "capture.size: must be non-negative")
+	}
+	if cfg.Capture.LatencyThreshold < 0 {
+		errs = append(errs, "capture.latency_threshold: must be non-negative")
+	}
+	if cfg.Capture.MaxReductionPct < 0 || cfg.Capture.MaxReductionPct > 100 {
+		errs = append(errs, fmt.Sprintf("capture.max_reduction_pct: must be between 0 and 100, got %d", cfg.Capture.MaxReductionPct))
+	}
+	if cfg.Capture.MinReductionPct < 0 || cfg.Capture.MinReductionPct > 100 {
+		errs = append(errs, fmt.Sprintf("capture.min_reduction_pct: must be between 0 and 100, got %d", cfg.Capture.MinReductionPct))
+	}
+
+	// Tuning validation
+	if cfg.Tuning.Fraction <= 0 || cfg.Tuning.Fraction > 1 {
+		errs = append(errs, fmt.Sprintf("tuning.fraction: must be greater than 0 and at most 1, got %f", cfg.Tuning.Fraction))
+	}
+	if cfg.Tuning.MinFeedback < 1 {
+		errs = append(errs, "tuning.min_feedback: must be positive")
+	}
+	if cfg.Tuning.MinLift < 0 {
+		errs = append(errs, "tuning.min_lift: must be non-negative")
+	}
+	if cfg.Tuning.FeedbackWindow <= 0 {
+		errs = append(errs, "tuning.feedback_window: must be positive")
+	}
+
+	if cfg.History.NamespaceWindow < 0 {
+		errs = append(errs, "history.namespace_window: must be non-negative")
+	}
+	if cfg.History.NamespaceSnapshotInterval < 0 {
+		errs = append(errs, "history.namespace_snapshot_interval: must be non-negative")
+	}
+
+	if cfg.Seen.Capacity <= 0 {
+		errs = append(errs, "seen.capacity: must be positive")
+	}
+	if cfg.Seen.FPRate <= 0 || cfg.Seen.FPRate >= 1 {
+		errs = append(errs, "seen.fp_rate: must be between 0 and 1")
+	}
+	if cfg.Seen.SaveInterval < 0 {
+		errs = append(errs, "seen.save_interval: must be non-negative")
+	}
+
+	// Analytics validation
+	switch cfg.Analytics.Sink {
+	case "":
+	case "clickhouse":
+		if cfg.Analytics.ClickHouse.URL == "" {
+			errs = append(errs, "analytics.clickhouse.url: required when analytics.sink is clickhouse")
+		}
+	case "bigquery":
+		if cfg.Analytics.BigQuery.Project == "" || cfg.Analytics.BigQuery.Dataset == "" {
+			errs = append(errs, "analytics.bigquery: project and dataset required when analytics.sink is bigquery")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("analytics.sink: unsupported sink %q (supported: clickhouse, bigquery)", cfg.Analytics.Sink))
+	}
+	if cfg.Analytics.Sink != "" {
+		if cfg.Analytics.SampleRate <= 0 || cfg.Analytics.SampleRate > 1 {
+			errs = append(errs, fmt.Sprintf("analytics.sample_rate: must be greater than 0 and at most 1, got %f", cfg.Analytics.SampleRate))
+		}
+		if cfg.Analytics.BatchSize < 0 || cfg.Analytics.Buffer < 0 || cfg.Analytics.FlushInterval < 0 {
+			errs = append(errs, "analytics: batch_size, buffer, and flush_interval must be non-negative")
+		}
+	}
+
+	// Enrichment validation
+	switch cfg.Enrichment.Type {
+	case "":
+	case "http":
+		if cfg.Enrichment.URL == "" {
+			errs = append(errs, "enrichment.url: required when enrichment.type is http")
+		}
+	case "plugin":
+		if cfg.Enrichment.Plugin == "" {
+			errs = append(errs, "enrichment.plugin: required when enrichment.type is plugin")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("enrichment.type: unsupported type %q (supported: http, plugin)", cfg.Enrichment.Type))
+	}
+	if cfg.Enrichment.Timeout <= 0 {
+		errs = append(errs, "enrichment.timeout: must be positive")
+	}
+	if cfg.Enrichment.BatchSize < 1 {
+		errs = append(errs, "enrichment.batch_size: must be positive")
+	}
+	if cfg.Enrichment.Concurrency < 1 {
+		errs = append(errs, "enrichment.concurrency: must be positive")
+	}
+	validPolicies := map[string]bool{"open": true, "closed": true, "drop": true}
+	if !validPolicies[cfg.Enrichment.FailurePolicy] {
+		errs = append(errs, fmt.Sprintf("enrichment.failure_policy: unsupported policy %q (supported: open, closed, drop)", cfg.Enrichment.FailurePolicy))
+	}
+
+	// Safety validation
+	validFilters := map[string]bool{"off": true, "flag": true, "strip": true, "block": true}
+	if !validFilters[cfg.Safety.InjectionFilter] {
+		errs = append(errs, fmt.Sprintf("safety.injection_filter: unsupported mode %q (supported: off, flag, strip, block)", cfg.Safety.InjectionFilter))
+	}
+	if cfg.Safety.InjectionThreshold < 0 || cfg.Safety.InjectionThreshold > 1 {
+		errs = append(errs, "safety.injection_threshold: must be between 0 and 1")
+	}
+	if cfg.Safety.SensitivityThreshold < 0 || cfg.Safety.SensitivityThreshold > 1 {
+		errs = append(errs, "safety.sensitivity_threshold: must be between 0 and 1")
+	}
+	for i, ex := range cfg.Safety.SensitivityExamples {
+		if _, err := sensitivity.ParseLevel(ex.Level); err != nil || ex.Level == "none" {
+			errs = append(errs, fmt.Sprintf("safety.sensitivity_examples[%d]: level must be pii, internal, or credentials", i))
+		}
+	}
+
+	// Garbage filter validation
+	if cfg.Garbage.Threshold < 0 || cfg.Garbage.Threshold > 1 {
+		errs = append(errs, "garbage.threshold: must be between 0 and 1")
+	}
+	if cfg.Garbage.MinRepeats < 0 {
+		errs = append(errs, "garbage.min_repeats: must be non-negative")
+	}
+	if cfg.Garbage.RepeatTTL < 0 {
+		errs = append(errs, "garbage.repeat_ttl: must be non-negative")
+	}
+	for i, pattern := range cfg.Garbage.Allow {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Sprintf("garbage.allow[%d]: %v", i, err))
+		}
+	}
+
+	// Cache validation
+	if cfg.Cache.EmbeddingSize < 0 {
+		errs = append(errs, "cache.embedding_size: must be non-negative")
+	}
+	if cfg.Cache.EmbeddingTTL < 0 {
+		errs = append(errs, "cache.embedding_ttl: must be non-negative")
+	}
+	if cfg.Cache.ResultTTL < 0 {
+		errs = append(errs, "cache.result_ttl: must be non-negative")
+	}
+	if cfg.Cache.ResultSize < 0 {
+		errs = append(errs, "cache.result_size: must be non-negative")
+	}
+	if cfg.Cache.TenantMaxBytesPerProcess != "" {
+		if _, err := gctune.ParseBytes(cfg.Cache.TenantMaxBytesPerProcess); err != nil {
+			errs = append(errs, fmt.Sprintf("cache.tenant_max_bytes_per_process: %v", err))
+		}
+	}
+	for _, ns := range slices.Sorted(maps.Keys(cfg.Cache.TenantQuotasPerProcess)) {
+		if _, err := gctune.ParseBytes(cfg.Cache.TenantQuotasPerProcess[ns]); err != nil {
+			errs = append(errs, fmt.Sprintf("cache.tenant_quotas_per_process.%s: %v", ns, err))
+		}
+	}
+	switch cfg.Cache.Backend {
+	case "", "memory":
+	case "disk":
+		if cfg.Cache.Dir == "" {
+			errs = append(errs, "cache.dir: required when cache.backend is disk")
+		}
+	case "redis":
+		if !strings.HasPrefix(cfg.Cache.Redis.URL, "redis://") && !strings.HasPrefix(cfg.Cache.Redis.URL, "rediss://") {
+			errs = append(errs, "cache.redis.url: must be a redis:// or rediss:// URL")
+		}
+		if cfg.Cache.Redis.KeyPrefix == "" {
+			errs = append(errs, "cache.redis.key_prefix: required, so clearing the cache cannot delete other keys")
+		}
+		if cfg.Cache.Redis.PoolSize < 0 || cfg.Cache.Redis.DB < 0 {
+			errs = append(errs, "cache.redis: pool_size and db must be non-negative")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("cache.backend: must be memory, disk, or redis, got %q", cfg.Cache.Backend))
+	}
+
+	// Pipeline validation
+	if len(cfg.Pipeline.Stages) > 0 {
+		specs, err := contextlab.ParseStages(cfg.Pipeline.Stages)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("pipeline.stages: %v", err))
+		}
+		for _, s := range specs {
+			if s.Name == contextlab.PipelineRerank && cfg.Rerank.URL == "" {
+				errs = append(errs, "rerank.url: required by the rerank pipeline stage")
+				break
+			}
+		}
+	}
+
+	// Rerank validation
+	if cfg.Rerank.Timeout <= 0 {
+		errs = append(errs, "rerank.timeout: must be positive")
+	}
+	if cfg.Rerank.BatchSize < 0 {
+		errs = append(errs, "rerank.batch_size: must be non-negative")
+	}
+
+	// Render validation
+	if _, err := render.New(cfg.Render.Templates, cfg.Render.Default); err != nil {
+		errs = append(errs, fmt.Sprintf("render: %v", err))
+	}
+
+	// Model profile validation
+	if _, err := models.New(cfg.Models.Profiles, cfg.Models.Default); err != nil {
+		errs = append(errs, fmt.Sprintf("models: %v", err))
+	}
+	for i, p := range cfg.Models.Profiles {
+		if p.Format == "" {
+			continue
+		}
+		if _, err := render.New(cfg.Render.Templates, p.Format); err != nil {
+			errs = append(errs, fmt.Sprintf("models.profiles[%d].format: %v", i, err))
+		}
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("configuration errors:\n  - %s", strings.Join(errs, "\n  - "))
 	}
@@ -219,6 +1185,17 @@ func Validate(cfg *Config) error {
 	return nil
 }
 
+// validateTerms reports blank entries in an entity vocabulary.
+func validateTerms(key string, terms []string) []string {
+	var errs []string
+	for i, t := range terms {
+		if strings.TrimSpace(t) == "" {
+			errs = append(errs, fmt.Sprintf("%s[%d]: must not be blank", key, i))
+		}
+	}
+	return errs
+}
+
 // envVarPattern matches ${VAR} or ${VAR:-default} syntax.
 var envVarPattern = regexp.MustCompile(`\$\{([^}:]+)(?::-([^}]*))?\}`)
 
@@ -267,6 +1244,25 @@ func interpolateConfig(cfg *Config) {
 
 	cfg.Telemetry.Tracing.Exporter = InterpolateEnv(cfg.Telemetry.Tracing.Exporter)
 	cfg.Telemetry.Tracing.Endpoint = InterpolateEnv(cfg.Telemetry.Tracing.Endpoint)
+	cfg.Telemetry.Profiling.PushURL = InterpolateEnv(cfg.Telemetry.Profiling.PushURL)
+	cfg.Telemetry.Usage.Endpoint = InterpolateEnv(cfg.Telemetry.Usage.Endpoint)
+	cfg.Telemetry.Profiling.AuthToken = InterpolateEnv(cfg.Telemetry.Profiling.AuthToken)
+	cfg.Telemetry.Profiling.BasicAuthUser = InterpolateEnv(cfg.Telemetry.Profiling.BasicAuthUser)
+	cfg.Telemetry.Profiling.BasicAuthPassword = InterpolateEnv(cfg.Telemetry.Profiling.BasicAuthPassword)
+	cfg.Runtime.MemoryLimit = InterpolateEnv(cfg.Runtime.MemoryLimit)
+	cfg.Limits.MaxInputBytes = InterpolateEnv(cfg.Limits.MaxInputBytes)
+	cfg.Limits.MaxMatrixBytes = InterpolateEnv(cfg.Limits.MaxMatrixBytes)
+	cfg.Limits.SpillDir = InterpolateEnv(cfg.Limits.SpillDir)
+	cfg.Cache.Dir = InterpolateEnv(cfg.Cache.Dir)
+	cfg.Cache.Redis.URL = InterpolateEnv(cfg.Cache.Redis.URL)
+	cfg.Cache.Redis.Password = InterpolateEnv(cfg.Cache.Redis.Password)
+	cfg.Rerank.URL = InterpolateEnv(cfg.Rerank.URL)
+	cfg.Rerank.APIKey = InterpolateEnv(cfg.Rerank.APIKey)
+	cfg.Analytics.ClickHouse.URL = InterpolateEnv(cfg.Analytics.ClickHouse.URL)
+	cfg.Analytics.ClickHouse.User = InterpolateEnv(cfg.Analytics.ClickHouse.User)
+	cfg.Analytics.ClickHouse.Password = InterpolateEnv(cfg.Analytics.ClickHouse.Password)
+	cfg.Analytics.BigQuery.Project = InterpolateEnv(cfg.Analytics.BigQuery.Project)
+	cfg.Analytics.BigQuery.AccessToken = InterpolateEnv(cfg.Analytics.BigQuery.AccessToken)
 }
 
 // GenerateTemplate returns a YAML template string with all available
@@ -281,12 +1277,32 @@ server:
   host: 0.0.0.0
   read_timeout: 30s
   write_timeout: 60s
+  compression: true        # zstd/gzip via Accept-Encoding
+  compress_min_bytes: 1024
+  h2c: false               # HTTP/2 without TLS, for internal traffic
+  # h2_stream_buffer: 1MiB
+  # h2_conn_buffer: 4MiB
+  allow_writes: false      # accept PUT /v1/vectors on serve
+  grpc_port: 0             # also serve the gRPC API on this port, 0 = off
+  # socket: /run/distill/distill.sock  # serve HTTP on a Unix socket instead of host:port
+  # socket_mode: "0660"
 
 embedding:
-  provider: openai       # openai, ollama, or cohere
-  model: text-embedding-3-small
+  provider: openai       # openai, ollama, cohere, voyage, or local
+  model: text-embedding-3-small  # local: a sentence-transformers ONNX model directory
   batch_size: 100
+  cache_size: 10000      # texts embedded recently, reused without a provider call, 0 = off
+  cache_ttl: 24h         # 0 = until evicted
   # base_url: ""         # override API endpoint (e.g. http://localhost:11434 for Ollama)
+  response_dims: 0       # reduce embeddings returned with include_embeddings, 0 = full
+  response_reduction: truncate  # truncate or pca
+  # normalization:       # text contract before embedding, per provider
+  #   ollama:
+  #     strip_control: true  # drop control characters and invalid UTF-8
+  #     max_chars: 8000      # 0 = no limit; openai 30000, ollama 8000 by default
+  #     model_prefixes: true # the model family's prefixes, e.g. E5's "query: "
+  #     query_prefix: ""     # explicit prefixes win over model_prefixes
+  #     passage_prefix: ""
 
 dedup:
   threshold: 0.15
@@ -294,14 +1310,70 @@ dedup:
   linkage: average
   lambda: 0.5
   enable_mmr: true
+  enable_clustering: true
+  selection: score       # score, centroid, length, or hybrid
+  enable_compression: false
+  compression_mode: ""   # extractive, placeholder, hybrid, or cluster-merge; empty = extractive
+  compression_target: 0  # share of each chunk's tokens to keep, 0 = 0.5
+  compression_merge_tokens: 0  # cap of each cluster-merge chunk, 0 = the representative's length
+  preserve_structure: true  # keep JSON and code structure intact
+  enable_redaction: false  # redact credentials and PII from results
+  enable_classification: false  # tag results with their sensitivity level
+  enable_scoring: true   # apply recency_weight
+  recency_weight: 0      # blend recency into MMR relevance, 0 = off
+  recency_half_life: 168h
+  timestamp_field: timestamp
+  mmr_seed: 0            # order of tied MMR scores, 0 = retrieval order
+  hints: false           # add cluster size and duplicates removed to chunk metadata
+  entities:
+    enabled: false       # keep chunks naming different entities apart
+    terms: []            # entity vocabulary; empty = heuristic key terms
+    # namespaces:        # per-namespace overrides
+    #   - namespace: support
+    #     enabled: true
+    #     terms: [Okta, Google Workspace, Azure AD]
 
 retriever:
-  backend: pinecone    # pinecone or qdrant
+  backend: pinecone    # pinecone, qdrant, or fake
   index: ""
   host: ""             # required for qdrant
   namespace: ""
+  # previous_namespace: ""  # set by distill reindex, for rollback
   top_k: 50
   target_k: 8
+  # token_budget: 4000 # return chunks up to this many tokens instead of target_k
+  min_candidates: 4    # fewer candidates skip clustering and MMR, 0 = off
+  min_score: 0         # drop matches scoring below this, 0 = off
+  timeout: 30s         # per query, including retries
+  max_retries: 3       # on Unavailable/ResourceExhausted, -1 = off
+  include_tombstoned: false  # return duplicates soft-deleted by sync --tombstone
+  # include_metadata_fields: []  # keep only these metadata fields per match
+  # exclude_metadata_fields: []  # drop these, e.g. [body_html, raw]
+  # cross_namespace_groups: []   # only these groups may search several namespaces
+  warm_scan:           # sample namespaces at startup to reject mismatched requests
+    sample: 50         # chunks per namespace, 0 = off
+    # namespaces: []   # default: the default namespace; "*" = all
+  # params:            # backend-specific tuning, validated per backend
+  #   qdrant:
+  #     hnsw_ef: 128
+  #     exact: false
+  #     indexed_only: false
+  #     consistency: majority  # all, majority, quorum, or a replica count
+  #     quantization:
+  #       ignore: false
+  #       rescore: true
+  #       oversampling: 2.0
+  #   pinecone:
+  #     index_host: ""         # skip the DescribeIndex lookup
+  #     max_top_k: 0           # lower the per-query cap, 0 = Pinecone's limit
+  #     max_top_k_with_data: 0
+  # fake:              # synthetic corpus for --backend fake
+  #   corpus_size: 1000
+  #   duplicate_rate: 0.3
+  #   dimension: 256
+  #   seed: 0
+  #   latency: normal:20ms:5ms  # fixed, uniform, normal, or exponential
+  #   error_rate: 0.0
 
 auth:
   api_keys:
@@ -314,5 +1386,163 @@ telemetry:
     endpoint: localhost:4317
     sample_rate: 1.0     # 0.0 to 1.0
     insecure: true
+  profiling:
+    enabled: false
+    pprof_addr: 127.0.0.1:6060  # net/http/pprof for Parca or go tool pprof; keep it internal
+    push_url: ""         # Pyroscope server, e.g. http://pyroscope:4040
+    app_name: distill
+    interval: 15s        # length of each pushed CPU profile
+    labels: {}           # e.g. {env: prod, region: eu-west-1}
+    auth_token: ""       # bearer token, e.g. ${PYROSCOPE_TOKEN}
+    basic_auth_user: ""
+    basic_auth_password: ""
+  usage:
+    enabled: false       # opt in to anonymized usage statistics; see distill telemetry show
+    endpoint: ""         # empty = the build's default endpoint
+    interval: 24h
+
+runtime:
+  memory_limit: ""       # soft heap limit, e.g. 1536MiB (~80% of container memory)
+  gc_percent: 0          # 0 = Go default (100); 200-400 for high QPS
+  ballast_mb: 0          # optional heap ballast; prefer memory_limit + gc_percent
+
+limits:
+  max_chunks: 2000       # per request; clustering memory grows with n^2
+  max_dimension: 4096    # embedding dimension
+  max_input_bytes: 16MiB # chunk text + embeddings; 0 disables
+  max_matrix_bytes: ""   # distance matrix memory cap, e.g. 256MiB; empty disables
+  matrix_overflow: spill # over the cap: spill (mmap temp file) or window (approximate)
+  spill_dir: ""          # spill files; empty = system temp dir
+
+capture:
+  enabled: false         # record anomalous requests at /debug/captures
+  size: 100
+  latency_threshold: 0s  # e.g. 500ms
+  max_reduction_pct: 0   # e.g. 90
+  min_reduction_pct: 0
+  privacy: false         # omit chunk text from captures
+
+render:
+  default: ""            # template for every response; empty = only on request
+  # templates:           # Go text/templates; built-ins: plain, numbered, markdown, xml
+  #   cited: |
+  #     {{range .Chunks}}[{{.Index}}] {{.Text}} ({{meta . "source"}})
+  #     {{end}}
+
+tuning:
+  enabled: false         # tune threshold/lambda per namespace from /v1/feedback
+  fraction: 0.1          # share of retrieve requests that try a perturbed setting
+  min_feedback: 200      # reports each setting needs before they are compared
+  min_lift: 0.03         # mean reward gain needed to switch settings
+  feedback_window: 15m   # how long after a request its feedback is accepted
+
+history:
+  path: ""               # SQLite file recording job and request summaries; empty = off
+  record_queries: false  # also store served query text, for distill cache warm --from-history
+  namespace_window: 1h   # how far back /v1/stats/namespaces aggregates look
+  namespace_snapshot_interval: 5m  # how often serve records them into path
+
+seen:
+  path: ""               # Bloom filter file of content already ingested; empty = off
+  capacity: 1000000      # vectors per namespace before the filter grows
+  fp_rate: 0.001         # share of new vectors that may be wrongly skipped
+  save_interval: 1m      # how often serve saves the filter
+
+analytics:
+  sink: ""               # clickhouse or bigquery; empty = off
+  sample_rate: 1.0       # fraction of requests exported
+  batch_size: 500
+  flush_interval: 5s
+  buffer: 10000          # records queued for export; more are dropped
+  clickhouse:
+    url: ""              # HTTP interface, e.g. http://clickhouse:8123
+    database: default
+    table: distill_requests
+    user: ""
+    password: ""         # e.g. ${CLICKHOUSE_PASSWORD}
+  bigquery:
+    project: ""
+    dataset: ""
+    table: distill_requests
+    access_token: ""     # empty = token from the GCE metadata server
+
+enrichment:
+  type: ""               # http or plugin; empty = off
+  url: ""                # http: POST endpoint receiving chunk batches
+  plugin: ""             # plugin: Go plugin (.so) exporting Enricher or Enrich
+  # headers:
+  #   Authorization: Bearer ${ENRICH_TOKEN}
+  timeout: 500ms         # per call
+  batch_size: 100        # chunks per call
+  concurrency: 4         # calls in flight per request
+  failure_policy: open   # open (keep unenriched), closed (fail request), drop (drop chunks)
+
+acl:
+  enabled: false         # enforce chunk ACLs against the request identity; deny by default
+  groups_field: allowed_groups  # metadata listing allowed groups ("*" = everyone)
+  users_field: allowed_users    # metadata listing allowed users
+
+safety:
+  injection_filter: "off"  # prompt-injection filter: off, flag, strip (remove sentences), block (drop chunks)
+  injection_threshold: 0.5  # score (0-1) at or above which a chunk is flagged
+  sensitivity_threshold: 0  # similarity to a sensitivity example that matches, 0 = 0.85
+  sensitivity_examples: []  # {name, level, text} entries embedded at startup
+
+garbage:
+  enabled: false         # drop boilerplate chunks (cookie banners, menus, footers)
+  threshold: 0.6         # score (0-1) at or above which a chunk is dropped
+  min_repeats: 5         # text shared by this many chunks is boilerplate; 0 = off
+  repeat_ttl: 24h        # how long shared texts are remembered
+  allow: []              # regular expressions for chunks never dropped
+
+cache:
+  embedding_size: 10000  # query embeddings kept in memory; 0 = off
+  embedding_ttl: 24h     # 0 = until evicted
+  result_ttl: 0s         # how long retrieve results are reused; 0 = off
+  result_size: 10000     # retrieve results kept
+  tenant_max_bytes_per_process: ""  # result bytes each namespace may keep per process, e.g. 64MiB; "" = unlimited
+  # tenant_quotas_per_process:      # per-namespace overrides
+  #   acme: 256MiB
+  backend: memory        # memory, disk (survives restarts), or redis (shared by replicas)
+  dir: distill-cache     # disk cache files
+  redis:
+    url: redis://localhost:6379  # rediss:// for TLS
+    password: ""         # e.g. ${REDIS_PASSWORD}
+    db: 0
+    key_prefix: "distill:"
+    pool_size: 10
+    dial_timeout: 5s
+    read_timeout: 3s
+    write_timeout: 3s
+
+pipeline:
+  stages: []             # stages after retrieval, in order; empty = cluster, select, mmr
+  # stages:
+  #   - retrieve
+  #   - redact: {levels: [credentials, pii]}
+  #   - cluster: {threshold: 0.2}
+  #   - select: {strategy: centroid}
+  #   - mmr: {lambda: 0.7}
+  #   - rerank: {k: 20}        # needs rerank.url
+  #   - compress: {method: extractive, target_reduction: 0.5}
+
+rerank:
+  url: ""                # self-hosted reranker; POST {url}/rerank
+  model: ""              # sent to servers hosting several models
+  api_key: ""            # sent as a bearer token; may be ${RERANK_API_KEY}
+  batch_size: 0          # documents per call; 0 = all in one
+  timeout: 5s            # per call
+
+models:
+  default: ""            # profile for requests without model; empty = no budget
+  profiles: []
+  # profiles:
+  #   - name: llama-3-8b
+  #     context_tokens: 8192   # context window; 0 = no budget
+  #     reserve_tokens: 2048   # left free for the prompt and answer
+  #     tokenizer: chars       # chars, words, cl100k_base, or o200k_base
+  #     chars_per_token: 3.8
+  #     input_per_1k: 0        # USD per 1,000 input tokens
+  #     format: plain          # render template when a request names none
 `
 }
@@ -5,6 +5,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/models"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -43,6 +46,81 @@ func TestValidate_InvalidPort(t *testing.T) {
 	}
 }
 
+func TestValidate_GRPCPort(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.GRPCPort = 9090
+	if err := Validate(cfg); err != nil {
+		t.Errorf("grpc_port 9090: %v", err)
+	}
+
+	cfg.Server.GRPCPort = cfg.Server.Port
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "server.grpc_port") {
+		t.Errorf("grpc_port equal to port: %v", err)
+	}
+}
+
+func TestValidate_TokenBudget(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Retriever.TokenBudget = 4000
+	if err := Validate(cfg); err != nil {
+		t.Errorf("token_budget 4000: %v", err)
+	}
+
+	cfg.Retriever.TokenBudget = -1
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "retriever.token_budget") {
+		t.Errorf("negative token_budget: %v", err)
+	}
+}
+
+func TestValidate_MinCandidates(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Retriever.MinCandidates != 4 {
+		t.Errorf("default min_candidates = %d, want 4", cfg.Retriever.MinCandidates)
+	}
+
+	cfg.Retriever.MinCandidates = -1
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "retriever.min_candidates") {
+		t.Errorf("negative min_candidates: %v", err)
+	}
+}
+
+func TestValidate_NamespaceStats(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.History.NamespaceWindow != time.Hour || cfg.History.NamespaceSnapshotInterval != 5*time.Minute {
+		t.Errorf("unexpected namespace stats defaults: %+v", cfg.History)
+	}
+
+	cfg.History.NamespaceWindow = -time.Minute
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "history.namespace_window") {
+		t.Errorf("negative namespace_window: %v", err)
+	}
+}
+
+func TestValidate_Seen(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("default seen filter config: %v", err)
+	}
+
+	cfg.Seen.FPRate = 1
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "seen.fp_rate") {
+		t.Errorf("fp_rate of 1: %v", err)
+	}
+}
+
+func TestValidate_SensitivityExamples(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Safety.SensitivityExamples = []SensitivityExample{{Name: "roadmap", Level: "internal", Text: "Unreleased roadmap"}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("valid example: %v", err)
+	}
+
+	cfg.Safety.SensitivityExamples[0].Level = "secret"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "safety.sensitivity_examples[0]") {
+		t.Errorf("unknown level: %v", err)
+	}
+}
+
 func TestValidate_InvalidThreshold(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Dedup.Threshold = 1.5
@@ -291,3 +369,491 @@ func TestGenerateTemplate(t *testing.T) {
 		}
 	}
 }
+
+func TestValidate_Runtime(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Runtime.MemoryLimit = "2GiB"
+	cfg.Runtime.GCPercent = 300
+	if err := Validate(cfg); err != nil {
+		t.Errorf("valid runtime config rejected: %v", err)
+	}
+
+	cfg.Runtime.MemoryLimit = "lots"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "runtime.memory_limit") {
+		t.Errorf("expected runtime.memory_limit error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Runtime.GCPercent = -5
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for gc_percent < -1")
+	}
+}
+
+func TestValidate_Profiling(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Telemetry.Profiling.Enabled = true
+	cfg.Telemetry.Profiling.PushURL = "http://pyroscope:4040"
+	cfg.Telemetry.Profiling.Labels = map[string]string{"env": "prod"}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("valid profiling config rejected: %v", err)
+	}
+
+	cfg.Telemetry.Profiling.PprofAddr = ""
+	cfg.Telemetry.Profiling.PushURL = ""
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "without pprof_addr or push_url") {
+		t.Errorf("expected error for nothing to profile, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Telemetry.Profiling.Enabled = true
+	cfg.Telemetry.Profiling.PushURL = "pyroscope:4040"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "telemetry.profiling") {
+		t.Errorf("expected telemetry.profiling error, got %v", err)
+	}
+}
+
+func TestValidate_Analytics(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Analytics.Sink = "clickhouse"
+	cfg.Analytics.ClickHouse.URL = "http://clickhouse:8123"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("valid analytics config rejected: %v", err)
+	}
+
+	cfg.Analytics.SampleRate = 0
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "analytics.sample_rate") {
+		t.Errorf("expected analytics.sample_rate error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Analytics.Sink = "bigquery"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "analytics.bigquery") {
+		t.Errorf("expected analytics.bigquery error, got %v", err)
+	}
+
+	cfg.Analytics.Sink = "snowflake"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "unsupported sink") {
+		t.Errorf("expected unsupported sink error, got %v", err)
+	}
+}
+
+func TestValidate_Usage(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Telemetry.Usage.Enabled {
+		t.Error("usage statistics must be off by default")
+	}
+	cfg.Telemetry.Usage.Enabled = true
+	cfg.Telemetry.Usage.Endpoint = "https://example.com/ping"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("valid usage config rejected: %v", err)
+	}
+
+	cfg.Telemetry.Usage.Endpoint = "http://example.com/ping"
+	cfg.Telemetry.Usage.Interval = time.Minute
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "telemetry.usage.endpoint") || !strings.Contains(err.Error(), "telemetry.usage.interval") {
+		t.Errorf("expected endpoint and interval errors, got %v", err)
+	}
+}
+
+func TestValidate_Limits(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := Validate(cfg); err != nil {
+		t.Errorf("default limits rejected: %v", err)
+	}
+
+	cfg.Limits.MaxInputBytes = "huge"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "limits.max_input_bytes") {
+		t.Errorf("expected limits.max_input_bytes error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Limits.MaxChunks = -1
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "limits.max_chunks") {
+		t.Errorf("expected limits.max_chunks error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Limits.MaxMatrixBytes = "256MiB"
+	cfg.Limits.MatrixOverflow = "window"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("matrix cap rejected: %v", err)
+	}
+	cfg.Limits.MaxMatrixBytes = "lots"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "limits.max_matrix_bytes") {
+		t.Errorf("expected limits.max_matrix_bytes error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Limits.MatrixOverflow = "swap"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "limits.matrix_overflow") {
+		t.Errorf("expected limits.matrix_overflow error, got %v", err)
+	}
+}
+
+func TestValidate_Tuning(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Tuning.Fraction = 1.5
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "tuning.fraction") {
+		t.Errorf("expected tuning.fraction error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Tuning.FeedbackWindow = 0
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "tuning.feedback_window") {
+		t.Errorf("expected tuning.feedback_window error, got %v", err)
+	}
+}
+
+func TestValidate_Enrichment(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Enrichment.Type = "http"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "enrichment.url") {
+		t.Errorf("expected enrichment.url error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Enrichment.FailurePolicy = "ignore"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "enrichment.failure_policy") {
+		t.Errorf("expected enrichment.failure_policy error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Enrichment.Type = "plugin"
+	cfg.Enrichment.Plugin = "acl.so"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected a valid plugin config, got %v", err)
+	}
+}
+
+func TestValidate_Cache(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Cache.ResultTTL = -time.Minute
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "cache.result_ttl") {
+		t.Errorf("expected cache.result_ttl error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Cache.EmbeddingSize = 0
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected a disabled embedding cache to be valid, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Cache.Backend = "disk"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected a disk cache to be valid, got %v", err)
+	}
+	cfg.Cache.Dir = ""
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "cache.dir") {
+		t.Errorf("expected cache.dir error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Cache.TenantMaxBytesPerProcess = "64MiB"
+	cfg.Cache.TenantQuotasPerProcess = map[string]string{"acme": "1GiB"}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected tenant quotas to be valid, got %v", err)
+	}
+	cfg.Cache.TenantQuotasPerProcess["globex"] = "lots"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "cache.tenant_quotas_per_process.globex") {
+		t.Errorf("expected cache.tenant_quotas_per_process.globex error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Cache.Backend = "memcached"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "cache.backend") {
+		t.Errorf("expected cache.backend error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Cache.Backend = "redis"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected the default redis config to be valid, got %v", err)
+	}
+	cfg.Cache.Redis.URL = "localhost:6379"
+	cfg.Cache.Redis.KeyPrefix = ""
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "cache.redis.url") || !strings.Contains(err.Error(), "cache.redis.key_prefix") {
+		t.Errorf("expected cache.redis.url and key_prefix errors, got %v", err)
+	}
+}
+
+func TestValidate_Safety(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Safety.InjectionFilter = "redact"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "safety.injection_filter") {
+		t.Errorf("expected safety.injection_filter error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Safety.InjectionThreshold = 1.5
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "safety.injection_threshold") {
+		t.Errorf("expected safety.injection_threshold error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Safety.InjectionFilter = "block"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected block mode to be valid, got %v", err)
+	}
+}
+
+func TestValidate_RetrieverTimeout(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Retriever.Timeout = -time.Second
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "retriever.timeout") {
+		t.Errorf("expected retriever.timeout error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Retriever.MaxRetries = -1
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected disabled retries to be valid, got %v", err)
+	}
+}
+
+func TestValidate_EmbeddingCache(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Embedding.CacheSize = -1
+	cfg.Embedding.CacheTTL = -time.Second
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "embedding.cache_size") || !strings.Contains(err.Error(), "embedding.cache_ttl") {
+		t.Errorf("expected embedding cache errors, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Embedding.CacheSize = 0
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected a disabled cache to be valid, got %v", err)
+	}
+}
+
+func TestValidate_WarmScan(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Retriever.WarmScan.Sample != 50 {
+		t.Errorf("default warm_scan.sample = %d, want 50", cfg.Retriever.WarmScan.Sample)
+	}
+	cfg.Retriever.WarmScan.Sample = -1
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "retriever.warm_scan.sample") {
+		t.Errorf("expected retriever.warm_scan.sample error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Retriever.WarmScan.Sample = 0
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected a disabled scan to be valid, got %v", err)
+	}
+}
+
+func TestValidate_Garbage(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Garbage.Threshold = 2
+	cfg.Garbage.Allow = []string{"(unclosed"}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "garbage.threshold") || !strings.Contains(err.Error(), "garbage.allow[0]") {
+		t.Errorf("expected garbage.threshold and garbage.allow errors, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Garbage.Enabled = true
+	cfg.Garbage.Allow = []string{`(?i)terms of service`}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected garbage config to be valid, got %v", err)
+	}
+}
+
+func TestValidate_Normalization(t *testing.T) {
+	cfg := DefaultConfig()
+	negative := -1
+	cfg.Embedding.Normalization = map[string]NormalizationConfig{
+		"mistral": {},
+		"ollama":  {MaxChars: &negative},
+	}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "embedding.normalization.mistral") || !strings.Contains(err.Error(), "embedding.normalization.ollama.max_chars") {
+		t.Errorf("expected normalization provider and max_chars errors, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Embedding.Normalization = map[string]NormalizationConfig{"ollama": {ModelPrefixes: true}}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected normalization config to be valid, got %v", err)
+	}
+}
+
+func TestValidate_Models(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Models.Default = "gpt-4o"
+	cfg.Models.Profiles = []models.Profile{{Name: "claude", ContextTokens: 200000, Format: "yaml"}}
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "models:") || !strings.Contains(err.Error(), "models.profiles[0].format") {
+		t.Errorf("expected models and models.profiles[0].format errors, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Models.Default = "claude"
+	cfg.Models.Profiles = []models.Profile{{Name: "claude", ContextTokens: 200000, ReserveTokens: 8000, InputPer1K: 0.003, Format: "xml"}}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected models config to be valid, got %v", err)
+	}
+}
+
+func TestValidate_Rerank(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Pipeline.Stages = []interface{}{"retrieve", map[string]interface{}{"rerank": map[string]interface{}{"k": 10}}}
+	cfg.Rerank.BatchSize = -1
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "rerank.url") || !strings.Contains(err.Error(), "rerank.batch_size") {
+		t.Errorf("expected rerank.url and rerank.batch_size errors, got %v", err)
+	}
+
+	cfg.Rerank.URL = "http://localhost:8787"
+	cfg.Rerank.BatchSize = 32
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected rerank config to be valid, got %v", err)
+	}
+}
+
+func TestValidate_EmbeddingProvider(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Embedding.Provider = "mistral"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "embedding.provider") {
+		t.Errorf("expected embedding.provider error, got %v", err)
+	}
+
+	for _, provider := range []string{"cohere", "voyage"} {
+		cfg.Embedding.Provider = provider
+		if err := Validate(cfg); err != nil {
+			t.Errorf("expected %s to be valid, got %v", provider, err)
+		}
+	}
+}
+
+func TestValidate_Selection(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Dedup.Selection = "newest"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "dedup.selection") {
+		t.Errorf("expected dedup.selection error, got %v", err)
+	}
+
+	cfg.Dedup.Selection = "centroid"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected centroid to be valid, got %v", err)
+	}
+}
+
+func TestValidate_MetadataFields(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Retriever.IncludeMetadataFields = []string{"title", "url"}
+	cfg.Retriever.ExcludeMetadataFields = []string{"body_html"}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected valid field lists, got %v", err)
+	}
+
+	cfg.Retriever.ExcludeMetadataFields = []string{"url"}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "retriever.include_metadata_fields") {
+		t.Errorf("expected retriever.include_metadata_fields error, got %v", err)
+	}
+}
+
+func TestLoadFromFile_RetrieverParams(t *testing.T) {
+	content := `
+retriever:
+  backend: qdrant
+  host: localhost
+  params:
+    qdrant:
+      hnsw_ef: 128
+      consistency: majority
+      quantization:
+        oversampling: 2
+    pinecone:
+      max_top_k: 500
+`
+	cfgPath := filepath.Join(t.TempDir(), "distill.yaml")
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromFile(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if cfg.Retriever.Params["qdrant"]["hnsw_ef"] != 128 {
+		t.Errorf("expected qdrant hnsw_ef 128, got %v", cfg.Retriever.Params["qdrant"])
+	}
+
+	cfg = DefaultConfig()
+	cfg.Retriever.Params = map[string]map[string]interface{}{
+		"qdrant": {"hnsw": 64},
+		"fake":   {"seed": 1},
+	}
+	err = Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "retriever.params.qdrant") || !strings.Contains(err.Error(), "retriever.params.fake") {
+		t.Errorf("expected errors for both params sections, got %v", err)
+	}
+}
+
+func TestLoadFromFile_Entities(t *testing.T) {
+	content := `
+dedup:
+  entities:
+    enabled: true
+    namespaces:
+      - namespace: Support-KB
+        terms: [Okta, Google Workspace]
+`
+	cfgPath := filepath.Join(t.TempDir(), "distill.yaml")
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromFile(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	ns := cfg.Dedup.Entities.Namespaces
+	if !cfg.Dedup.Entities.Enabled || len(ns) != 1 || ns[0].Namespace != "Support-KB" || len(ns[0].Terms) != 2 {
+		t.Errorf("unexpected entities config: %+v", cfg.Dedup.Entities)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Dedup.Entities.Terms = []string{"Okta", " "}
+	cfg.Dedup.Entities.Namespaces = []NamespaceEntities{{Namespace: "a"}, {Namespace: "a"}, {}}
+	err = Validate(cfg)
+	for _, want := range []string{"dedup.entities.terms[1]", "namespaces[1].namespace", "namespaces[2].namespace"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error mentioning %s, got %v", want, err)
+		}
+	}
+}
+
+func TestValidate_Pipeline(t *testing.T) {
+	content := `
+pipeline:
+  stages:
+    - retrieve
+    - redact: {levels: [pii]}
+    - cluster:
+        threshold: 0.2
+    - select: {strategy: centroid}
+    - mmr
+`
+	cfgPath := filepath.Join(t.TempDir(), "distill.yaml")
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+	cfg, err := LoadFromFile(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if len(cfg.Pipeline.Stages) != 5 {
+		t.Errorf("expected 5 stages, got %v", cfg.Pipeline.Stages)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Pipeline.Stages = []interface{}{"retrieve", "select"}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "pipeline.stages") {
+		t.Errorf("expected pipeline.stages error, got %v", err)
+	}
+}
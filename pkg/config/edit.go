@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/internal/atomicfile"
+	"gopkg.in/yaml.v3"
+)
+
+// SetValues sets dotted keys (e.g. "retriever.namespace") to string values
+// in the YAML config file at path, creating missing sections and keeping
+// everything else, comments included. The file is replaced atomically, so
+// a server watching it never reads a half-written config.
+func SetValues(path string, values map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: top level is not a mapping", path)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := setNode(root, strings.Split(key, "."), values[key]); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return atomicfile.Write(path, out.Bytes(), info.Mode().Perm())
+}
+
+// setNode sets path under mapping m to value.
+func setNode(m *yaml.Node, path []string, value string) error {
+	for i := 0; i < len(m.Content); i += 2 {
+		k, v := m.Content[i], m.Content[i+1]
+		if k.Value != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			v.Kind, v.Tag, v.Value, v.Content = yaml.ScalarNode, "!!str", value, nil
+			v.Style = 0
+			if value == "" {
+				v.Style = yaml.DoubleQuotedStyle
+			}
+			return nil
+		}
+		if v.Kind == yaml.ScalarNode && (v.Tag == "!!null" || v.Value == "") {
+			v.Kind, v.Tag, v.Value = yaml.MappingNode, "!!map", ""
+		}
+		if v.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a section", path[0])
+		}
+		return setNode(v, path[1:], value)
+	}
+
+	// Missing key: append it, building sections as needed
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}
+	v := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	m.Content = append(m.Content, k, v)
+	if len(path) == 1 {
+		v.Kind, v.Tag = yaml.ScalarNode, "!!str"
+		v.Value = value
+		if value == "" {
+			v.Style = yaml.DoubleQuotedStyle
+		}
+		return nil
+	}
+	return setNode(v, path[1:], value)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "distill.yaml")
+	orig := `# Production config
+server:
+  port: 8080
+
+retriever:
+  backend: pinecone    # pinecone, qdrant, or fake
+  index: docs
+  namespace: ""
+`
+	if err := os.WriteFile(path, []byte(orig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := SetValues(path, map[string]string{
+		"retriever.namespace":          "docs-v2",
+		"retriever.previous_namespace": "",
+		"telemetry.tracing.exporter":   "none",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	out := string(data)
+	for _, want := range []string{"# Production config", "# pinecone, qdrant, or fake", "\n  namespace: docs-v2\n", `previous_namespace: ""`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Retriever.Namespace != "docs-v2" || cfg.Retriever.Index != "docs" || cfg.Server.Port != 8080 {
+		t.Errorf("unexpected config after edit: %+v", cfg.Retriever)
+	}
+	if cfg.Telemetry.Tracing.Exporter != "none" {
+		t.Errorf("expected missing sections to be created, got %q", cfg.Telemetry.Tracing.Exporter)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected file mode kept, got %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected no temp files left behind, got %d entries", len(entries))
+	}
+
+	if err := SetValues(path, map[string]string{"server.port.value": "1"}); err == nil {
+		t.Error("expected an error setting a key under a scalar")
+	}
+}
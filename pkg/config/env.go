@@ -0,0 +1,57 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix is the prefix for all Distill environment variables.
+const EnvPrefix = "DISTILL"
+
+// EnvVar returns the environment variable for a config key or flag name:
+// the key upper-cased, with dots and dashes replaced by underscores and
+// EnvPrefix prepended. "server.port" becomes DISTILL_SERVER_PORT and
+// "api-keys" becomes DISTILL_API_KEYS.
+func EnvVar(key string) string {
+	r := strings.NewReplacer(".", "_", "-", "_")
+	return EnvPrefix + "_" + strings.ToUpper(r.Replace(key))
+}
+
+// Keys returns every config file key in dotted form (e.g.
+// "server.port"), in declaration order.
+func Keys() []string {
+	return structKeys(reflect.TypeOf(Config{}), "")
+}
+
+func structKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+		if f.Type.Kind() == reflect.Map {
+			// Free-form sections have no fixed keys to bind
+			continue
+		}
+		if f.Type.Kind() == reflect.Struct && f.Type.PkgPath() == t.PkgPath() {
+			keys = append(keys, structKeys(f.Type, key+".")...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// BindEnv binds every config key to its environment variable, so values
+// set only in the environment are seen by Unmarshal and AllSettings as
+// well as by Get.
+func BindEnv(v *viper.Viper) {
+	for _, key := range Keys() {
+		_ = v.BindEnv(key, EnvVar(key))
+	}
+}
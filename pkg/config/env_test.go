@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestEnvVar(t *testing.T) {
+	tests := map[string]string{
+		"server.port":               "DISTILL_SERVER_PORT",
+		"api-keys":                  "DISTILL_API_KEYS",
+		"telemetry.tracing.enabled": "DISTILL_TELEMETRY_TRACING_ENABLED",
+	}
+	for key, want := range tests {
+		if got := EnvVar(key); got != want {
+			t.Errorf("EnvVar(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	keys := make(map[string]bool)
+	for _, k := range Keys() {
+		keys[k] = true
+	}
+	for _, want := range []string{"server.port", "auth.api_keys", "telemetry.tracing.sample_rate", "limits.max_input_bytes"} {
+		if !keys[want] {
+			t.Errorf("expected key %q in Keys()", want)
+		}
+	}
+	if keys["telemetry.tracing"] {
+		t.Error("nested sections should be flattened, not listed as keys")
+	}
+}
+
+func TestBindEnv_Unmarshal(t *testing.T) {
+	t.Setenv("DISTILL_SERVER_PORT", "9090")
+	t.Setenv("DISTILL_RETRIEVER_HOST", "qdrant:6334")
+
+	v := viper.New()
+	BindEnv(v)
+
+	cfg, err := Load(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 9090 {
+		t.Errorf("expected port 9090 from env, got %d", cfg.Server.Port)
+	}
+	if cfg.Retriever.Host != "qdrant:6334" {
+		t.Errorf("expected retriever host from env, got %q", cfg.Retriever.Host)
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// lintDocs is where each lint rule is explained, followed by the rule
+// name as the anchor.
+const lintDocs = "docs/reference/configuration.md#"
+
+// Lint rules, also the anchors of their docs.
+const (
+	LintOverFetch      = "over-fetch"
+	LintThreshold      = "threshold"
+	LintMMR            = "mmr"
+	LintEmbeddingCache = "embedding-cache"
+	LintAuth           = "auth"
+)
+
+// Warning is a lint finding: a setting that is valid but likely to serve
+// worse results or be unsafe.
+type Warning struct {
+	Rule    string `json:"rule"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+	Docs    string `json:"docs"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s (see %s)", w.Key, w.Message, w.Docs)
+}
+
+// minOverFetch is how many times target_k the over-fetch should be, so
+// deduplication has duplicates to drop and still fills target_k.
+const minOverFetch = 3
+
+// largeTargetK is the target_k from which MMR is expected, since a long
+// result without it tends to repeat one topic.
+const largeTargetK = 20
+
+// thresholdRange is the usual dedup.threshold range, in cosine distance,
+// for a family of embedding models. Models that place unrelated text close
+// together need lower thresholds.
+type thresholdRange struct {
+	model    string
+	min, max float64
+}
+
+// modelThresholds holds thresholdRanges by model name substring. The
+// first match wins, so more specific names come first.
+var modelThresholds = []thresholdRange{
+	{"text-embedding-ada-002", 0.03, 0.12},
+	{"text-embedding-3", 0.08, 0.30},
+	{"embed-english", 0.08, 0.30},
+	{"embed-multilingual", 0.08, 0.30},
+	{"voyage", 0.06, 0.25},
+	{"nomic-embed", 0.05, 0.25},
+	{"bge", 0.05, 0.20},
+	{"e5", 0.04, 0.18},
+	{"minilm", 0.10, 0.35},
+	{"mpnet", 0.10, 0.35},
+}
+
+// defaultThresholds is the range for models not in modelThresholds.
+var defaultThresholds = thresholdRange{min: 0.03, max: 0.40}
+
+// Lint checks a valid configuration for settings that are likely
+// mistakes. Unlike Validate it never rejects a config; each Warning names
+// the key, the reason, and the docs explaining the rule.
+func Lint(cfg *Config) []Warning {
+	var warnings []Warning
+	warn := func(rule, key, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{Rule: rule, Key: key, Message: fmt.Sprintf(format, args...), Docs: lintDocs + rule})
+	}
+
+	r := cfg.Retriever
+	if r.TopK > 0 && r.TargetK > 0 && r.TopK < minOverFetch*r.TargetK {
+		warn(LintOverFetch, "retriever.top_k",
+			"over-fetching %d chunks for target_k %d leaves little to deduplicate; use at least %d", r.TopK, r.TargetK, minOverFetch*r.TargetK)
+	}
+
+	if cfg.Dedup.Threshold > 0 {
+		rng := thresholdsFor(cfg.Embedding.Model)
+		switch {
+		case cfg.Dedup.Threshold < rng.min:
+			warn(LintThreshold, "dedup.threshold",
+				"%g is low for %s, so few duplicates will merge; usual range is %g-%g", cfg.Dedup.Threshold, modelLabel(cfg.Embedding.Model), rng.min, rng.max)
+		case cfg.Dedup.Threshold > rng.max:
+			warn(LintThreshold, "dedup.threshold",
+				"%g is high for %s, so distinct chunks may merge; usual range is %g-%g", cfg.Dedup.Threshold, modelLabel(cfg.Embedding.Model), rng.min, rng.max)
+		}
+	}
+
+	// Declared pipeline stages replace the built-in MMR switch
+	if !cfg.Dedup.EnableMMR && len(cfg.Pipeline.Stages) == 0 && r.TargetK >= largeTargetK {
+		warn(LintMMR, "dedup.enable_mmr",
+			"MMR is off with target_k %d, so results may cluster around one topic", r.TargetK)
+	}
+
+	if cfg.Embedding.Provider != "" && cfg.Embedding.Provider != "fake" && cfg.Cache.EmbeddingSize == 0 {
+		warn(LintEmbeddingCache, "cache.embedding_size",
+			"the embedding cache is off, so repeated queries are embedded by %s every time", cfg.Embedding.Provider)
+	}
+
+	if len(cfg.Auth.APIKeys) == 0 && !isLoopback(cfg.Server.Host) {
+		host := cfg.Server.Host
+		if host == "" {
+			host = "every interface"
+		}
+		warn(LintAuth, "auth.api_keys",
+			"no API keys are set while the server listens on %s, so anyone who can reach it can query it", host)
+	}
+	return warnings
+}
+
+// thresholdsFor returns the usual threshold range for an embedding model.
+func thresholdsFor(model string) thresholdRange {
+	model = strings.ToLower(model)
+	for _, t := range modelThresholds {
+		if strings.Contains(model, t.model) {
+			return t
+		}
+	}
+	return defaultThresholds
+}
+
+// modelLabel names an embedding model in a warning.
+func modelLabel(model string) string {
+	if model == "" {
+		return "the embedding model"
+	}
+	return model
+}
+
+// isLoopback reports whether host only accepts local connections.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
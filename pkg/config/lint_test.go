@@ -0,0 +1,93 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// lintRules returns the rules of warnings, in order.
+func lintRules(warnings []Warning) []string {
+	rules := make([]string, len(warnings))
+	for i, w := range warnings {
+		rules[i] = w.Rule
+	}
+	return rules
+}
+
+func TestLint_Clean(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.Host = "127.0.0.1"
+	if warnings := Lint(cfg); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+
+	cfg.Server.Host = "0.0.0.0"
+	cfg.Auth.APIKeys = []string{"key"}
+	if warnings := Lint(cfg); len(warnings) != 0 {
+		t.Errorf("expected no warnings with API keys, got %v", warnings)
+	}
+}
+
+func TestLint_Rules(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Retriever.TopK = 40
+	cfg.Retriever.TargetK = 20
+	cfg.Dedup.Threshold = 0.5
+	cfg.Dedup.EnableMMR = false
+	cfg.Cache.EmbeddingSize = 0
+	cfg.Server.Host = ""
+
+	warnings := Lint(cfg)
+	got := strings.Join(lintRules(warnings), ",")
+	if want := "over-fetch,threshold,mmr,embedding-cache,auth"; got != want {
+		t.Fatalf("rules = %s, want %s", got, want)
+	}
+	for _, w := range warnings {
+		if w.Key == "" || w.Message == "" || w.Docs != lintDocs+w.Rule {
+			t.Errorf("incomplete warning %+v", w)
+		}
+	}
+	if !strings.Contains(warnings[1].Message, "text-embedding-3-small") || !strings.Contains(warnings[4].Message, "every interface") {
+		t.Errorf("messages = %v", warnings)
+	}
+}
+
+func TestLint_Threshold(t *testing.T) {
+	tests := []struct {
+		model     string
+		threshold float64
+		warn      bool
+	}{
+		{"text-embedding-3-small", 0.15, false},
+		{"text-embedding-ada-002", 0.15, true},
+		{"text-embedding-ada-002", 0.05, false},
+		{"BAAI/bge-small-en-v1.5", 0.01, true},
+		{"all-MiniLM-L6-v2", 0.3, false},
+		{"custom-model", 0.3, false},
+		{"custom-model", 0.6, true},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.Server.Host = "localhost"
+		cfg.Embedding.Model = tt.model
+		cfg.Dedup.Threshold = tt.threshold
+		warned := len(Lint(cfg)) > 0
+		if warned != tt.warn {
+			t.Errorf("%s at %g: warned = %v, want %v", tt.model, tt.threshold, warned, tt.warn)
+		}
+	}
+}
+
+func TestLint_Exemptions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.Host = "::1"
+	cfg.Embedding.Provider = "fake"
+	cfg.Cache.EmbeddingSize = 0
+	cfg.Dedup.EnableMMR = false
+	cfg.Retriever.TopK = 100
+	cfg.Retriever.TargetK = 25
+	cfg.Pipeline.Stages = []interface{}{"retrieve", "cluster"}
+	if warnings := Lint(cfg); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+}
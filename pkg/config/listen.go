@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultSocketMode is the permission of a server's Unix socket file:
+// read and write for its owner and group, so a sidecar sharing the group
+// can connect.
+const DefaultSocketMode os.FileMode = 0o660
+
+// ListenAddr returns the TCP address for host and port. An IPv6 literal
+// host may be written with or without brackets ("::1" or "[::1]").
+func ListenAddr(host string, port int) string {
+	return net.JoinHostPort(trimBrackets(host), strconv.Itoa(port))
+}
+
+// ValidHost reports whether host is usable in ListenAddr: a name, an IPv4
+// address, or an IPv6 literal, bracketed or not. Empty means all
+// interfaces.
+func ValidHost(host string) bool {
+	h := trimBrackets(host)
+	if strings.ContainsAny(h, "[]/ ") {
+		return false
+	}
+	if strings.Contains(h, ":") {
+		// Zones ("fe80::1%eth0") are allowed for link-local addresses.
+		ip, _, _ := strings.Cut(h, "%")
+		return net.ParseIP(ip) != nil
+	}
+	return true
+}
+
+// ParseSocketMode parses an octal socket permission such as "0660" or
+// "660". Empty means DefaultSocketMode.
+func ParseSocketMode(s string) (os.FileMode, error) {
+	if s == "" {
+		return DefaultSocketMode, nil
+	}
+	mode, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid socket mode %q: want octal permissions such as 0660", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+func trimBrackets(host string) string {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
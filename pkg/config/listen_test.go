@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestListenAddr(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"0.0.0.0", "0.0.0.0:8080"},
+		{"localhost", "localhost:8080"},
+		{"", ":8080"},
+		{"::", "[::]:8080"},
+		{"::1", "[::1]:8080"},
+		{"[::1]", "[::1]:8080"},
+		{"fe80::1%eth0", "[fe80::1%eth0]:8080"},
+	}
+	for _, tt := range tests {
+		if got := ListenAddr(tt.host, 8080); got != tt.want {
+			t.Errorf("ListenAddr(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestValidHost(t *testing.T) {
+	for _, host := range []string{"", "0.0.0.0", "localhost", "::", "[::1]", "2001:db8::1", "fe80::1%eth0"} {
+		if !ValidHost(host) {
+			t.Errorf("ValidHost(%q) = false, want true", host)
+		}
+	}
+	for _, host := range []string{"::1:8080:zz", "[::1", "2001:db8::1]", "/run/distill.sock", "a b"} {
+		if ValidHost(host) {
+			t.Errorf("ValidHost(%q) = true, want false", host)
+		}
+	}
+}
+
+func TestParseSocketMode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want os.FileMode
+	}{
+		{"", DefaultSocketMode},
+		{"0660", 0o660},
+		{"600", 0o600},
+		{"0o777", 0o777},
+	}
+	for _, tt := range tests {
+		got, err := ParseSocketMode(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSocketMode(%q) = %o, %v; want %o", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"0680", "rw", "01000"} {
+		if _, err := ParseSocketMode(in); err == nil {
+			t.Errorf("ParseSocketMode(%q): expected error", in)
+		}
+	}
+}
+
+func TestValidate_Listen(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.Host = "::"
+	cfg.Server.Socket = "/run/distill/distill.sock"
+	cfg.Server.SocketMode = "0600"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("IPv6 host and socket: %v", err)
+	}
+
+	cfg.Server.Host = "[::1"
+	cfg.Server.SocketMode = "0999"
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "server.host") || !strings.Contains(err.Error(), "server.socket_mode") {
+		t.Errorf("invalid host and socket mode: %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+// Package contextdiff compares two assembled contexts, such as the chunks
+// two configurations return for the same query, to show which chunks were
+// added, dropped, reordered, or rewritten (for example by compression).
+package contextdiff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// Chunk is one chunk of an assembled context, in rank order.
+type Chunk struct {
+	ID    string  `json:"id"`
+	Text  string  `json:"text,omitempty"`
+	Score float32 `json:"score"`
+}
+
+// key identifies a chunk across the two sides: its ID, or the SHA-256 of
+// its trimmed text when it has none.
+func (c Chunk) key() string {
+	if c.ID != "" {
+		return c.ID
+	}
+	sum := sha256.Sum256([]byte(strings.TrimSpace(c.Text)))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Entry is one chunk's fate between side A and side B.
+type Entry struct {
+	ID string `json:"id"`
+
+	// RankA and RankB are 1-based positions on each side, 0 if absent.
+	RankA int `json:"rank_a"`
+	RankB int `json:"rank_b"`
+
+	TextA  string  `json:"text_a,omitempty"`
+	TextB  string  `json:"text_b,omitempty"`
+	ScoreA float32 `json:"score_a,omitempty"`
+	ScoreB float32 `json:"score_b,omitempty"`
+}
+
+// Added reports whether the chunk is only on side B.
+func (e Entry) Added() bool { return e.RankA == 0 }
+
+// Removed reports whether the chunk is only on side A.
+func (e Entry) Removed() bool { return e.RankB == 0 }
+
+// Moved reports whether the chunk is on both sides at different ranks.
+func (e Entry) Moved() bool { return e.RankA > 0 && e.RankB > 0 && e.RankA != e.RankB }
+
+// TextChanged reports whether the chunk is on both sides with different
+// text, as when compression settings differ.
+func (e Entry) TextChanged() bool {
+	return e.RankA > 0 && e.RankB > 0 && e.TextA != e.TextB
+}
+
+// Unchanged reports whether the chunk has the same rank and text on both
+// sides.
+func (e Entry) Unchanged() bool {
+	return e.RankA > 0 && e.RankB > 0 && !e.Moved() && !e.TextChanged()
+}
+
+// Result is the comparison of two contexts.
+type Result struct {
+	// Entries are in side A's order, followed by chunks added in B, in
+	// B's order.
+	Entries []Entry `json:"entries"`
+
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Moved     int `json:"moved"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Equal reports whether both sides hold the same chunks, in the same
+// order, with the same text.
+func (r Result) Equal() bool {
+	return r.Added == 0 && r.Removed == 0 && r.Moved == 0 && r.Changed == 0
+}
+
+// Compare diffs context a against context b. A chunk repeated on one side
+// is matched by its first occurrence.
+func Compare(a, b []Chunk) Result {
+	entries := make([]Entry, 0, len(a)+len(b))
+	index := make(map[string]int, len(a))
+	for i, c := range a {
+		k := c.key()
+		if _, dup := index[k]; dup {
+			continue
+		}
+		index[k] = len(entries)
+		entries = append(entries, Entry{ID: c.ID, RankA: i + 1, TextA: c.Text, ScoreA: c.Score})
+	}
+	for i, c := range b {
+		k := c.key()
+		j, ok := index[k]
+		if !ok {
+			index[k] = len(entries)
+			entries = append(entries, Entry{ID: c.ID, RankB: i + 1, TextB: c.Text, ScoreB: c.Score})
+			continue
+		}
+		if entries[j].RankB == 0 {
+			entries[j].RankB = i + 1
+			entries[j].TextB = c.Text
+			entries[j].ScoreB = c.Score
+		}
+	}
+
+	// Chunks added in B are appended in B's order; A's entries are
+	// already in A's order.
+	sort.SliceStable(entries, func(i, j int) bool {
+		ei, ej := entries[i], entries[j]
+		if ei.Added() != ej.Added() {
+			return !ei.Added()
+		}
+		return ei.Added() && ei.RankB < ej.RankB
+	})
+
+	r := Result{Entries: entries}
+	for _, e := range entries {
+		switch {
+		case e.Added():
+			r.Added++
+		case e.Removed():
+			r.Removed++
+		default:
+			if e.Moved() {
+				r.Moved++
+			}
+			if e.TextChanged() {
+				r.Changed++
+			}
+			if e.Unchanged() {
+				r.Unchanged++
+			}
+		}
+	}
+	return r
+}
+
+// Line is one line of a line diff.
+type Line struct {
+	// Op is ' ' for a line on both sides, '-' for A only, '+' for B only.
+	Op   byte
+	Text string
+}
+
+// Lines diffs two texts line by line using the longest common
+// subsequence, for comparing rendered contexts.
+func Lines(a, b string) []Line {
+	la, lb := strings.Split(a, "\n"), strings.Split(b, "\n")
+	n, m := len(la), len(lb)
+
+	// lcs[i][j] is the LCS length of la[i:] and lb[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if la[i] == lb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []Line
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case la[i] == lb[j]:
+			out = append(out, Line{' ', la[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, Line{'-', la[i]})
+			i++
+		default:
+			out = append(out, Line{'+', lb[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		out = append(out, Line{'-', la[i]})
+	}
+	for ; j < m; j++ {
+		out = append(out, Line{'+', lb[j]})
+	}
+	return out
+}
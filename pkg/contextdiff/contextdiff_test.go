@@ -0,0 +1,66 @@
+package contextdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	a := []Chunk{
+		{ID: "1", Text: "one"},
+		{ID: "2", Text: "two"},
+		{ID: "3", Text: "three"},
+		{ID: "4", Text: "four, at full length"},
+	}
+	b := []Chunk{
+		{ID: "1", Text: "one"},
+		{ID: "3", Text: "three"},
+		{ID: "5", Text: "five"},
+		{ID: "4", Text: "four"},
+	}
+	r := Compare(a, b)
+
+	want := Result{Added: 1, Removed: 1, Moved: 1, Changed: 1, Unchanged: 1}
+	got := r
+	got.Entries = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summary = %+v, want %+v", got, want)
+	}
+
+	var order []string
+	for _, e := range r.Entries {
+		order = append(order, e.ID)
+	}
+	if !reflect.DeepEqual(order, []string{"1", "2", "3", "4", "5"}) {
+		t.Errorf("entry order = %v", order)
+	}
+	if e := r.Entries[3]; e.Moved() || !e.TextChanged() || e.TextB != "four" {
+		t.Errorf("expected chunk 4 rewritten in place, got %+v", e)
+	}
+	if e := r.Entries[2]; !e.Moved() || e.RankA != 3 || e.RankB != 2 {
+		t.Errorf("expected chunk 3 moved from 3 to 2, got %+v", e)
+	}
+	if r.Equal() {
+		t.Error("expected contexts to differ")
+	}
+	if !Compare(a, a).Equal() {
+		t.Error("expected a context to equal itself")
+	}
+}
+
+func TestCompare_TextKey(t *testing.T) {
+	a := []Chunk{{Text: "same text"}, {Text: "dropped"}}
+	b := []Chunk{{Text: " same text\n"}}
+	r := Compare(a, b)
+	if r.Removed != 1 || r.Changed != 1 || r.Added != 0 {
+		t.Errorf("expected chunks without IDs matched by trimmed text, got %+v", r)
+	}
+}
+
+func TestLines(t *testing.T) {
+	got := Lines("a\nb\nc", "a\nc\nd")
+	want := []Line{{' ', "a"}, {'-', "b"}, {' ', "c"}, {'+', "d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+package contextlab
+
+import (
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Default metadata fields holding a chunk's ACL, and the entry that
+// grants access to every caller.
+const (
+	DefaultACLGroupsField = "allowed_groups"
+	DefaultACLUsersField  = "allowed_users"
+	ACLEveryone           = "*"
+)
+
+// ACL enforces per-chunk access control from chunk metadata. A chunk is
+// visible to a caller whose user is listed in UsersField or who belongs
+// to a group listed in GroupsField; ACLEveryone in either list makes it
+// public. Access is denied by default: chunks without ACL metadata, and
+// every chunk for requests without an identity, are dropped.
+type ACL struct {
+	Enabled bool
+
+	// GroupsField and UsersField name the metadata fields listing allowed
+	// groups and users. Empty uses the defaults.
+	GroupsField string
+	UsersField  string
+}
+
+// ACLStats counts the decisions of one Filter call.
+type ACLStats struct {
+	// Allowed is the number of chunks the caller may see.
+	Allowed int
+
+	// Denied is the number of chunks dropped, including Unlabeled.
+	Denied int
+
+	// Unlabeled is the number of chunks dropped for having no ACL
+	// metadata.
+	Unlabeled int
+}
+
+// Filter returns the chunks id may see, in order. With the ACL disabled
+// it returns chunks unchanged.
+func (a ACL) Filter(chunks []types.Chunk, id *types.Identity) ([]types.Chunk, ACLStats) {
+	var stats ACLStats
+	if !a.Enabled {
+		return chunks, stats
+	}
+	groupsField, usersField := a.GroupsField, a.UsersField
+	if groupsField == "" {
+		groupsField = DefaultACLGroupsField
+	}
+	if usersField == "" {
+		usersField = DefaultACLUsersField
+	}
+
+	out := make([]types.Chunk, 0, len(chunks))
+	for _, c := range chunks {
+		groups, hasGroups := aclEntries(c.Metadata, groupsField)
+		users, hasUsers := aclEntries(c.Metadata, usersField)
+		switch {
+		case !hasGroups && !hasUsers:
+			stats.Unlabeled++
+			stats.Denied++
+		case aclAllows(users, groups, id):
+			out = append(out, c)
+			stats.Allowed++
+		default:
+			stats.Denied++
+		}
+	}
+	return out, stats
+}
+
+// aclAllows reports whether id matches an allowed user or group.
+func aclAllows(users, groups []string, id *types.Identity) bool {
+	for _, u := range users {
+		if u == ACLEveryone || (id != nil && id.User != "" && u == id.User) {
+			return true
+		}
+	}
+	for _, g := range groups {
+		if g == ACLEveryone {
+			return true
+		}
+		if id == nil {
+			continue
+		}
+		for _, mine := range id.Groups {
+			if mine != "" && g == mine {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// aclEntries reads an ACL list from metadata. Lists may be stored as
+// arrays or as comma-separated strings. The bool reports whether the
+// field is present at all; an empty list is present and allows no one.
+func aclEntries(metadata map[string]interface{}, field string) ([]string, bool) {
+	v, ok := metadata[field]
+	if !ok || v == nil {
+		return nil, false
+	}
+	var out []string
+	switch v := v.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	case []string:
+		out = v
+	case []interface{}:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+	}
+	return out, true
+}
@@ -0,0 +1,86 @@
+package contextlab
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func aclChunks() []types.Chunk {
+	chunks := orthogonalChunks(5)
+	chunks[0].Metadata = map[string]interface{}{"allowed_groups": []interface{}{"eng", "sales"}}
+	chunks[1].Metadata = map[string]interface{}{"allowed_groups": "finance, legal"}
+	chunks[2].Metadata = map[string]interface{}{"allowed_users": []string{"alice"}}
+	chunks[3].Metadata = map[string]interface{}{"allowed_groups": []interface{}{"*"}}
+	// chunks[4] has no ACL
+	return chunks
+}
+
+func chunkIDs(chunks []types.Chunk) string {
+	var ids string
+	for _, c := range chunks {
+		ids += c.ID
+	}
+	return ids
+}
+
+func TestACL_Filter(t *testing.T) {
+	acl := ACL{Enabled: true}
+	tests := []struct {
+		name string
+		id   *types.Identity
+		want string
+	}{
+		{"no identity", nil, "d"},
+		{"group member", &types.Identity{User: "bob", Groups: []string{"legal"}}, "bd"},
+		{"listed user", &types.Identity{User: "alice", Groups: []string{"eng"}}, "acd"},
+		{"no match", &types.Identity{User: "carol", Groups: []string{"ops"}}, "d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, stats := acl.Filter(aclChunks(), tt.id)
+			if got := chunkIDs(out); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if stats.Allowed != len(tt.want) || stats.Denied != 5-len(tt.want) || stats.Unlabeled != 1 {
+				t.Errorf("unexpected stats: %+v", stats)
+			}
+		})
+	}
+
+	if out, stats := (ACL{}).Filter(aclChunks(), nil); len(out) != 5 || stats.Denied != 0 {
+		t.Errorf("disabled ACL filtered chunks: %d left, %+v", len(out), stats)
+	}
+	custom := ACL{Enabled: true, GroupsField: "teams"}
+	chunks := orthogonalChunks(1)
+	chunks[0].Metadata = map[string]interface{}{"teams": []interface{}{"eng"}, "allowed_groups": []interface{}{"*"}}
+	if out, _ := custom.Filter(chunks, &types.Identity{Groups: []string{"ops"}}); len(out) != 0 {
+		t.Errorf("custom field ignored: %v", out)
+	}
+}
+
+func TestBroker_ACL(t *testing.T) {
+	ret := &stubRetriever{chunks: aclChunks()}
+	broker, err := NewBrokerWithOptions(ret, WithTargetK(10), WithMetadata(false), WithACL(ACL{Enabled: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1, 0, 0, 0, 0},
+		Identity:       &types.Identity{User: "alice"},
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if !ret.last.IncludeMetadata {
+		t.Error("expected metadata to be fetched for ACL checks")
+	}
+	if got := chunkIDs(result.Chunks); len(got) != 2 || result.Stats.Retrieved != 2 {
+		t.Errorf("got chunks %q, retrieved %d", got, result.Stats.Retrieved)
+	}
+	if result.Stats.ACLAllowed != 2 || result.Stats.ACLDenied != 3 || result.Stats.ACLUnlabeled != 1 {
+		t.Errorf("unexpected ACL stats: %+v", result.Stats)
+	}
+}
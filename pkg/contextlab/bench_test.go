@@ -1,6 +1,8 @@
 package contextlab
 
 import (
+	"context"
+	"fmt"
 	"math/rand"
 	"testing"
 
@@ -98,3 +100,39 @@ func BenchmarkSelector_50Clusters(b *testing.B) {
 		_ = sel.Select(result)
 	}
 }
+
+func BenchmarkSelectTopK_500Reps(b *testing.B) {
+	chunks := makeBenchChunks(500, 16)
+	for i := range chunks {
+		chunks[i].Score = float32(i%97) / 97
+	}
+	// Threshold 0 leaves every chunk in its own cluster
+	result := ClusterByThreshold(chunks, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = SelectTopK(result, 8, SelectByScore)
+	}
+}
+
+func BenchmarkDistanceMatrix_300x1536(b *testing.B) {
+	chunks := makeBenchChunks(300, 1536)
+	ctx := WithVectorNorms(context.Background())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m, _ := newDistanceMatrix(len(chunks), false, false, "")
+		_ = m.fill(ctx, chunks, 1)
+	}
+}
+
+func BenchmarkDistanceMatrix_1000x1536(b *testing.B) {
+	chunks := makeBenchChunks(1000, 1536)
+	ctx := WithVectorNorms(context.Background())
+	for _, workers := range []int{1, 0} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m, _ := newDistanceMatrix(len(chunks), false, false, "")
+				_ = m.fill(ctx, chunks, workers)
+			}
+		})
+	}
+}
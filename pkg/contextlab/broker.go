@@ -3,9 +3,22 @@ package contextlab
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/dedup"
+	"github.com/Siddhant-K-code/distill/pkg/enrich"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/garbage"
+	"github.com/Siddhant-K-code/distill/pkg/models"
+	"github.com/Siddhant-K-code/distill/pkg/rerank"
 	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/safety"
+	"github.com/Siddhant-K-code/distill/pkg/sensitivity"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
 
@@ -19,6 +32,19 @@ type BrokerConfig struct {
 	// TargetK is the final number of chunks to return.
 	TargetK int
 
+	// TokenBudget, when positive, replaces TargetK: selection keeps
+	// representatives until their tokens would exceed it, so
+	// results fit a context budget rather than a chunk count. Tokens are
+	// counted with the request's model profile, or with tokens.Count.
+	// Multi-namespace requests keep their per-namespace quotas.
+	TokenBudget int
+
+	// MinCandidates is the fewest candidates worth deduplicating. With
+	// fewer left after filtering, clustering and MMR are skipped, the
+	// candidates are returned in score order, and the result is marked
+	// Sparse. Declared pipeline stages always run. Zero disables it.
+	MinCandidates int
+
 	// ClusterThreshold is the cosine distance threshold for clustering.
 	// Lower = more clusters, less aggressive deduplication.
 	ClusterThreshold float64
@@ -34,16 +60,71 @@ type BrokerConfig struct {
 	// EnableMMR enables Maximal Marginal Relevance re-ranking.
 	EnableMMR bool
 
+	// DisableClustering skips clustering and selection, leaving MMR or
+	// score order to pick the TargetK chunks.
+	DisableClustering bool
+
+	// EnableCompression compresses returned chunks with the compressor
+	// set by WithCompression, or an extractive one.
+	EnableCompression bool
+
+	// Compress sets the mode, target reduction, and structure handling
+	// of compression. A Mode replaces the compressor set by
+	// WithCompression. Requests override it field by field.
+	Compress types.CompressOptions
+
+	// EnableRedaction replaces credentials and PII in returned chunks
+	// with sensitivity.DefaultRedaction.
+	EnableRedaction bool
+
+	// EnableClassification tags returned chunks with their sensitivity
+	// level; see MetaSensitivity.
+	EnableClassification bool
+
 	// MMRLambda controls relevance vs diversity tradeoff (0-1).
 	// 1.0 = pure relevance, 0.0 = pure diversity, 0.5 = balanced
 	MMRLambda float64
 
+	// RecencyWeight blends recency into MMR relevance (0 = off, 1 = recency
+	// only). Recency decays with RecencyHalfLife over the TimestampField
+	// metadata value; see MMRConfig.
+	RecencyWeight   float64
+	RecencyHalfLife time.Duration
+	TimestampField  string
+
+	// MMRSeed orders candidates with tied MMR scores; see MMRConfig.Seed.
+	MMRSeed int64
+
+	// Entities configures the entity veto on cluster merges: chunks that
+	// name different entities stay apart even under ClusterThreshold.
+	Entities EntityConfig
+
+	// NamespaceEntities overrides Entities for requests to a namespace.
+	NamespaceEntities map[string]EntityConfig
+
+	// Matrix caps clustering's distance matrix memory; see MatrixBudget.
+	Matrix MatrixBudget
+
+	// MinScore is the default RetrievalRequest.MinScore, applied when a
+	// request does not set one. Zero disables it.
+	MinScore float64
+
 	// IncludeEmbeddings requests embeddings in retrieval results.
 	// Required for clustering - will be enabled automatically if false.
 	IncludeEmbeddings bool
 
 	// IncludeMetadata requests metadata in retrieval results.
 	IncludeMetadata bool
+
+	// IncludeTombstoned returns duplicates soft-deleted by
+	// `distill sync --tombstone` (dedup.TombstoneKey set). By default
+	// they are excluded.
+	IncludeTombstoned bool
+
+	// DedupHints adds cluster size, duplicates removed, and the
+	// representative reason to returned chunks' metadata; see
+	// AnnotateDedup. RetrievalRequest.DedupHints turns it on per request.
+	DedupHints bool
 }
 
 // DefaultBrokerConfig returns sensible defaults.
@@ -71,6 +152,39 @@ type Broker struct {
 	clusterer *Clusterer
 	selector  *Selector
 	mmr       *MMR
+	sent      *cache.SentFilter
+
+	// Optional stages, set via NewBrokerWithOptions.
+	compressor   compress.Compressor
+	compressOpts compress.Options
+	results      cache.Cache
+	resultTTL    time.Duration
+	embeddings   cache.Cache
+	embeddingTTL time.Duration
+	limits       Limits
+	enricher     *enrich.Hook
+	acl          ACL
+	injection    safety.Filter
+	garbage      *garbage.Filter
+	models       *models.Registry
+	reranker     rerank.Reranker
+	crossGroups  []string
+	stages       []pipelineStage
+	classifier   *sensitivity.Classifier
+
+	// nsClusterers holds clusterers for namespaces with their own
+	// entity settings.
+	nsClusterers map[string]*Clusterer
+
+	// latencies tracks how long stages take, for best-effort requests.
+	latencies latencies
+
+	// resultHits and resultMisses count result cache lookups.
+	resultHits, resultMisses atomic.Int64
+
+	// fingerprints holds the namespace samples taken by WarmScan.
+	fingerprintMu sync.RWMutex
+	fingerprints  map[string]Fingerprint
 }
 
 // NewBroker creates a new ContextLab broker.
@@ -93,10 +207,7 @@ func NewBroker(ret retriever.Retriever, cfg BrokerConfig) *Broker {
 	}
 
 	// Create sub-components
-	clusterer := NewClusterer(ClusterConfig{
-		Threshold: cfg.ClusterThreshold,
-		Linkage:   cfg.ClusterLinkage,
-	})
+	clusterer, nsClusterers := newClusterers(cfg)
 
 	selector := NewSelector(SelectorConfig{
 		Strategy: cfg.SelectionStrategy,
@@ -104,21 +215,96 @@ func NewBroker(ret retriever.Retriever, cfg BrokerConfig) *Broker {
 
 	var mmr *MMR
 	if cfg.EnableMMR {
-		mmr = NewMMR(MMRConfig{
-			Lambda:  cfg.MMRLambda,
-			TargetK: cfg.TargetK,
-		})
+		mmr = NewMMR(mmrConfig(cfg))
 	}
 
 	return &Broker{
-		cfg:       cfg,
-		retriever: ret,
-		clusterer: clusterer,
-		selector:  selector,
-		mmr:       mmr,
+		cfg:          cfg,
+		retriever:    ret,
+		clusterer:    clusterer,
+		selector:     selector,
+		nsClusterers: nsClusterers,
+		mmr:          mmr,
+	}
+}
+
+// newClusterers builds the default clusterer and one per namespace with
+// its own entity settings.
+func newClusterers(cfg BrokerConfig) (*Clusterer, map[string]*Clusterer) {
+	clusterFor := func(entities EntityConfig) *Clusterer {
+		return NewClusterer(ClusterConfig{
+			Threshold: cfg.ClusterThreshold,
+			Linkage:   cfg.ClusterLinkage,
+			Entities:  NewEntityExtractor(entities),
+			Matrix:    cfg.Matrix,
+		})
+	}
+	var byNamespace map[string]*Clusterer
+	for ns, entities := range cfg.NamespaceEntities {
+		if byNamespace == nil {
+			byNamespace = make(map[string]*Clusterer, len(cfg.NamespaceEntities))
+		}
+		byNamespace[ns] = clusterFor(entities)
+	}
+	return clusterFor(cfg.Entities), byNamespace
+}
+
+// clustererFor returns the clusterer for requests to namespace.
+func (b *Broker) clustererFor(namespace string) *Clusterer {
+	if c, ok := b.nsClusterers[namespace]; ok {
+		return c
+	}
+	return b.clusterer
+}
+
+// requestClusterer returns the clusterer for req, honoring its threshold
+// override.
+func (b *Broker) requestClusterer(req *types.RetrievalRequest) *Clusterer {
+	c := b.clustererFor(req.Namespace)
+	if req.Threshold <= 0 || req.Threshold == c.cfg.Threshold {
+		return c
+	}
+	cfg := c.cfg
+	cfg.Threshold = req.Threshold
+	return NewClusterer(cfg)
+}
+
+// mmrConfig returns the MMR settings in cfg.
+func mmrConfig(cfg BrokerConfig) MMRConfig {
+	return MMRConfig{
+		Lambda:          cfg.MMRLambda,
+		TargetK:         cfg.TargetK,
+		TokenBudget:     cfg.TokenBudget,
+		RecencyWeight:   cfg.RecencyWeight,
+		RecencyHalfLife: cfg.RecencyHalfLife,
+		TimestampField:  cfg.TimestampField,
+		Seed:            cfg.MMRSeed,
 	}
 }
 
+// requestMMR returns the re-ranker for req, honoring its lambda override
+// and stage toggles, or nil when MMR is off for req.
+func (b *Broker) requestMMR(req *types.RetrievalRequest) *MMR {
+	if !enabled(req.Stages.MMR, b.mmr != nil) {
+		return nil
+	}
+	base := b.mmr
+	if base == nil {
+		base = NewMMR(mmrConfig(b.cfg))
+	}
+	cfg := base.cfg
+	if req.Lambda > 0 && req.Lambda <= 1 {
+		cfg.Lambda = req.Lambda
+	}
+	if !enabled(req.Stages.Scoring, true) {
+		cfg.RecencyWeight = 0
+	}
+	if cfg.Lambda == base.cfg.Lambda && cfg.RecencyWeight == base.cfg.RecencyWeight {
+		return base
+	}
+	return NewMMR(cfg)
+}
+
 // NewBrokerWithEmbedder creates a broker that can handle text queries.
 func NewBrokerWithEmbedder(ret retriever.Retriever, emb retriever.EmbeddingProvider, cfg BrokerConfig) *Broker {
 	broker := NewBroker(ret, cfg)
@@ -126,74 +312,562 @@ func NewBrokerWithEmbedder(ret retriever.Retriever, emb retriever.EmbeddingProvi
 	return broker
 }
 
+// SetSentFilter enables session-scoped "already sent" filtering for
+// requests that carry a SessionID. Pass nil to disable.
+func (b *Broker) SetSentFilter(f *cache.SentFilter) {
+	b.sent = f
+}
+
 // Retrieve performs the full deduplication pipeline.
 func (b *Broker) Retrieve(ctx context.Context, req *types.RetrievalRequest) (*types.BrokerResult, error) {
 	totalStart := time.Now()
 	stats := types.BrokerStats{}
+	if err := b.authorizeNamespaces(req); err != nil {
+		return nil, err
+	}
+	ctx, cancel := withBudget(ctx, req)
+	defer cancel()
 
-	// Step 1: Embed query if needed
-	if req.Query != "" && len(req.QueryEmbedding) == 0 {
-		if b.embedder == nil {
-			return nil, fmt.Errorf("embedding provider required for text queries")
+	if req.MinScore == 0 {
+		req.MinScore = float32(b.cfg.MinScore)
+	}
+	b.excludeTombstoned(req)
+
+	// Text queries are looked up before they are embedded, so a repeat
+	// costs neither an embedding call nor a vector DB query. Results are
+	// charged to the request's namespace when the cache is a
+	// cache.TenantCache.
+	cacheCtx := cache.WithTenant(ctx, req.Namespace)
+	var cacheKey string
+	if b.cachesResults(req) {
+		cacheKey = b.queryCacheKey(req)
+	}
+	if cached := b.cachedResult(cacheCtx, cacheKey); cached != nil {
+		cached.Stats.CacheHit, cached.Stats.CacheMiss = true, false
+		cached.Stats.TotalLatency = time.Since(totalStart)
+		return cached, nil
+	}
+
+	// Step 1: Embed query if needed and combine multiple query vectors
+	if err := b.resolveQuery(ctx, req); err != nil {
+		return nil, err
+	}
+
+	var namespaces []string
+	var quotas []int
+	if len(req.Namespaces) > 0 {
+		var err error
+		if err = b.expandNamespaces(ctx, req); err != nil {
+			return nil, err
 		}
-		embedding, err := b.embedder.Embed(ctx, req.Query)
-		if err != nil {
-			return nil, fmt.Errorf("failed to embed query: %w", err)
+		if namespaces, quotas, err = b.namespaceQuotas(req); err != nil {
+			return nil, err
 		}
-		req.QueryEmbedding = embedding
+	}
+	if err := b.checkFingerprints(req, namespaces); err != nil {
+		return nil, err
 	}
 
-	if len(req.QueryEmbedding) == 0 {
-		return nil, retriever.ErrInvalidQuery
+	if cacheKey == "" && b.cachesResults(req) {
+		cacheKey = b.resultCacheKey(req)
+		if cached := b.cachedResult(cacheCtx, cacheKey); cached != nil {
+			cached.Stats.CacheHit, cached.Stats.CacheMiss = true, false
+			cached.Stats.TotalLatency = time.Since(totalStart)
+			return cached, nil
+		}
 	}
+	stats.CacheMiss = cacheKey != ""
 
-	// Step 2: Over-fetch from vector DB
-	req.TopK = b.cfg.OverFetchK
+	// Step 2: Over-fetch from vector DB, splitting the budget across
+	// namespaces and fanned-out vectors
+	fetchK := b.overFetch(ctx, req.TopK, &stats)
+	req.TopK = fetchK
 	req.IncludeEmbeddings = true
-	req.IncludeMetadata = b.cfg.IncludeMetadata
+	req.IncludeMetadata = b.cfg.IncludeMetadata || b.acl.Enabled
+
+	observeStage(ctx, StageRetrieval, req.TopK)
+	retrievalStart := time.Now()
+	var result *types.RetrievalResult
+	var err error
+	if len(namespaces) > 0 {
+		result, err = retriever.QueryNamespaces(ctx, b.retriever, req, namespaces, b.namespaceFetchK(fetchK, quotas, len(req.QueryEmbeddings)))
+	} else if len(req.QueryEmbeddings) > 0 {
+		req.TopK = b.perQueryK(fetchK, len(req.QueryEmbeddings))
+		result, err = retriever.QueryVectors(ctx, b.retriever, req, req.QueryEmbeddings)
+	} else {
+		result, err = b.retriever.Query(ctx, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("retrieval failed: %w", err)
+	}
+	stats.RetrievalLatency = time.Since(retrievalStart)
+	stats.Truncated = result.Truncated
+	stats.RetrievalRetries = result.Retries
+	b.latencies.observe(PipelineRetrieve, stats.RetrievalLatency)
+
+	out, err := b.dedupe(ctx, req, result.Chunks, stats)
+	if err != nil {
+		return nil, err
+	}
+	out.Stages = append([]string{PipelineRetrieve}, out.Stages...)
+	out.Stats.TotalLatency = time.Since(totalStart)
+	// A result cut short by the deadline is not worth serving again
+	if !out.Stats.BestEffort {
+		b.storeResult(cacheCtx, cacheKey, out)
+	}
+	return out, nil
+}
+
+// RetrieveSimilar finds deduplicated neighbors of stored items, e.g. for
+// "related documents". Each item's neighbors are fetched by ID and
+// merged, and the items themselves are excluded. req supplies the
+// namespace, session, and exclusions; its query fields are ignored, and
+// Namespaces is rejected.
+func (b *Broker) RetrieveSimilar(ctx context.Context, ids []string, req *types.RetrievalRequest) (*types.BrokerResult, error) {
+	totalStart := time.Now()
+	stats := types.BrokerStats{}
+
+	if len(ids) == 0 {
+		return nil, retriever.ErrInvalidQuery
+	}
+	ctx, cancel := withBudget(ctx, req)
+	defer cancel()
+	if len(req.Namespaces) > 0 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("similar lookups search one namespace; namespaces is not supported"))
+	}
+	if req.MinScore == 0 {
+		req.MinScore = float32(b.cfg.MinScore)
+	}
+	if err := b.checkFingerprints(req, nil); err != nil {
+		return nil, err
+	}
+	b.excludeTombstoned(req)
 
+	// Each item is its own nearest neighbor, so fetch one extra per item
+	observeStage(ctx, StageRetrieval, len(ids))
 	retrievalStart := time.Now()
-	result, err := b.retriever.Query(ctx, req)
+	result, err := retriever.QueryByIDs(ctx, b.retriever, ids, b.perQueryK(b.cfg.OverFetchK, len(ids))+1, req.Namespace)
 	if err != nil {
 		return nil, fmt.Errorf("retrieval failed: %w", err)
 	}
 	stats.RetrievalLatency = time.Since(retrievalStart)
-	stats.Retrieved = len(result.Chunks)
+	stats.Truncated = result.Truncated
+	stats.RetrievalRetries = result.Retries
+
+	req.Exclude = append(append([]string(nil), req.Exclude...), ids...)
+	out, err := b.dedupe(ctx, req, result.Chunks, stats)
+	if err != nil {
+		return nil, err
+	}
+	out.Stages = append([]string{PipelineRetrieve}, out.Stages...)
+	out.Stats.TotalLatency = time.Since(totalStart)
+	return out, nil
+}
+
+// excludeTombstoned adds the tombstone to req's exclude filter unless
+// tombstoned duplicates are wanted. The caller's map is not modified.
+func (b *Broker) excludeTombstoned(req *types.RetrievalRequest) {
+	if b.cfg.IncludeTombstoned {
+		return
+	}
+	exclude := make(map[string]interface{}, len(req.ExcludeFilter)+1)
+	for k, v := range req.ExcludeFilter {
+		exclude[k] = v
+	}
+	exclude[dedup.TombstoneKey] = true
+	req.ExcludeFilter = exclude
+}
+
+// resolveQuery embeds text queries and leaves req with either a single
+// QueryEmbedding or, for fan-out, every vector in QueryEmbeddings.
+func (b *Broker) resolveQuery(ctx context.Context, req *types.RetrievalRequest) error {
+	if !retriever.ValidCombine(req.Combine) {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("unknown combine mode %q (use %q or %q)",
+			req.Combine, retriever.CombineAverage, retriever.CombineFanOut))
+	}
+
+	var texts []string
+	if req.Query != "" && len(req.QueryEmbedding) == 0 {
+		texts = append(texts, req.Query)
+	}
+	texts = append(texts, req.Queries...)
+
+	var vectors [][]float32
+	if len(req.QueryEmbedding) > 0 {
+		vectors = append(vectors, req.QueryEmbedding)
+	}
+	if len(texts) > 0 {
+		if b.embedder == nil {
+			return fmt.Errorf("embedding provider required for text queries")
+		}
+		if len(texts)+len(req.QueryEmbeddings) > retriever.MaxFanOut {
+			return retriever.ErrTooManyQueries
+		}
+		observeStage(ctx, StageEmbedding, len(texts))
+		embedded, err := b.embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed query: %w", err)
+		}
+		vectors = append(vectors, embedded...)
+	}
+	vectors = append(vectors, req.QueryEmbeddings...)
+
+	switch {
+	case len(vectors) == 0:
+		return retriever.ErrInvalidQuery
+	case len(vectors) > retriever.MaxFanOut:
+		return retriever.ErrTooManyQueries
+	case len(vectors) == 1:
+		req.QueryEmbedding, req.QueryEmbeddings = vectors[0], nil
+	case req.Combine == retriever.CombineFanOut:
+		req.QueryEmbedding, req.QueryEmbeddings = nil, vectors
+	default:
+		mean, err := retriever.AverageVectors(vectors)
+		if err != nil {
+			return errs.Wrap(errs.ErrConfig, err)
+		}
+		req.QueryEmbedding, req.QueryEmbeddings = mean, nil
+	}
+	req.Queries = nil
+	return nil
+}
+
+// embed embeds query texts, in one batch when there are several. Texts
+// in the embedding cache are not sent to the provider.
+func (b *Broker) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if b.embeddings == nil {
+		return b.embedUncached(ctx, texts)
+	}
+
+	vectors := make([][]float32, len(texts))
+	var missing []string
+	var missingIdx []int
+	for i, text := range texts {
+		if v := b.cachedEmbedding(ctx, text); v != nil {
+			vectors[i] = v
+			continue
+		}
+		missing = append(missing, text)
+		missingIdx = append(missingIdx, i)
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	embedded, err := b.embedUncached(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missing) {
+		return nil, fmt.Errorf("embedding provider returned %d vectors for %d texts", len(embedded), len(missing))
+	}
+	for i, v := range embedded {
+		vectors[missingIdx[i]] = v
+		b.storeEmbedding(ctx, missing[i], v)
+	}
+	return vectors, nil
+}
+
+// embedUncached embeds query texts with the provider.
+func (b *Broker) embedUncached(ctx context.Context, texts []string) ([][]float32, error) {
+	return retriever.EmbedQueries(ctx, b.embedder, texts)
+}
+
+// perQueryK splits the over-fetch budget of fetchK chunks across n
+// fanned-out queries, fetching at least TargetK for each.
+func (b *Broker) perQueryK(fetchK, n int) int {
+	k := fetchK / n
+	if k < b.cfg.TargetK {
+		k = b.cfg.TargetK
+	}
+	return k
+}
+
+// dedupe runs the pipeline after retrieval: ACL, injection filtering, score
+// threshold, metadata exclusion, limits, garbage filtering, ID exclusion, session filtering,
+// enrichment, clustering, selection, and MMR (or the stages set with
+// WithStages), redaction, compression, and the model's token budget. The
+// result lists the stages that ran after the filters.
+func (b *Broker) dedupe(ctx context.Context, req *types.RetrievalRequest, chunks []types.Chunk, stats types.BrokerStats) (*types.BrokerResult, error) {
+	ctx = WithVectorNorms(ctx)
+	plan, err := b.planFor(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Chunks the caller may not see never reach the rest of the pipeline
+	chunks, acl := b.acl.Filter(chunks, req.Identity)
+	stats.ACLAllowed = acl.Allowed
+	stats.ACLDenied = acl.Denied
+	stats.ACLUnlabeled = acl.Unlabeled
+
+	// Blocked injection content never takes a cluster's place
+	chunks, injection := b.injection.Apply(chunks)
+	stats.InjectionFlagged = injection.Flagged
+	stats.InjectionStripped = injection.Stripped
+	stats.InjectionBlocked = injection.Blocked
+
+	// Backends without server-side thresholds and filters (and QueryByID)
+	// return low-scoring and excluded matches too
+	chunks = retriever.DropBelow(chunks, req.MinScore)
+	chunks = retriever.DropExcluded(chunks, req.ExcludeFilter)
+	stats.Retrieved = len(chunks)
+
+	if err := b.limits.Check(chunks); err != nil {
+		return nil, err
+	}
+
+	// Boilerplate is unique often enough to survive clustering
+	chunks, junk := b.garbage.Apply(ctx, chunks)
+	stats.GarbageDropped = junk.Dropped
+	stats.GarbageAllowed = junk.Allowed
 
-	if len(result.Chunks) == 0 {
+	// Drop chunks the caller excluded, then drop (or mark) chunks already
+	// sent to this session
+	candidates, excluded := ExcludeChunks(chunks, req.Exclude)
+	stats.Excluded = excluded
+
+	candidates, repeated := b.sent.Filter(ctx, req.SessionID, candidates, req.MarkRepeats)
+	stats.Repeated = repeated
+
+	if b.enricher != nil && len(candidates) > 0 {
+		observeStage(ctx, StageEnrichment, len(candidates))
+		var es enrich.Stats
+		candidates, es, err = b.enricher.Apply(ctx, req.Query, req.Namespace, req.SessionID, candidates)
+		if err != nil {
+			return nil, err
+		}
+		stats.Enriched = es.Enriched
+		stats.Denied = es.Dropped
+		stats.EnrichmentFailed = es.Failed
+		stats.EnrichmentLatency = es.Latency
+	}
+
+	// A handful of candidates has nothing to deduplicate, and clustering
+	// them would only report a misleading reduction
+	sparse := !plan.passthrough && b.stages == nil && len(candidates) < b.cfg.MinCandidates
+	if sparse || len(candidates) == 0 {
+		markSparse(&stats, len(candidates))
+	}
+
+	if len(candidates) == 0 {
 		return &types.BrokerResult{
 			Chunks: []types.Chunk{},
 			Stats:  stats,
 		}, nil
 	}
 
-	// Step 3: Cluster retrieved chunks
-	clusterStart := time.Now()
-	clusterResult := b.clusterer.Cluster(result.Chunks)
-	stats.ClusteringLatency = time.Since(clusterStart)
-	stats.Clustered = clusterResult.ClusterCount
+	var finalChunks []types.Chunk
+	var ran []string
+	switch {
+	case plan.passthrough:
+		finalChunks, ran, err = b.passthrough(ctx, req, plan, candidates, &stats)
+	case b.stages != nil:
+		finalChunks, ran, err = b.runStages(ctx, req, plan, candidates, &stats)
+	case sparse:
+		finalChunks = b.topByScore(candidates, plan)
+	default:
+		finalChunks, ran, err = b.defaultStages(ctx, req, plan, candidates, &stats)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	// Step 4: Select representatives from each cluster
-	representatives := b.selector.Select(clusterResult)
+	// Step 6: Redact and compress if enabled and not already declared.
+	// Typed redaction runs after compression, so the offsets it records
+	// hold for the returned text.
+	redact := func() {
+		if plan.redact && len(finalChunks) > 0 && !slices.Contains(ran, PipelineRedact) {
+			observeStage(ctx, StageRedaction, len(finalChunks))
+			finalChunks = defaultRedactor().apply(finalChunks, &stats, req.Explain, plan.typed)
+			ran = append(ran, PipelineRedact)
+		}
+	}
+	if !plan.typed {
+		redact()
+	}
+	if plan.compress && len(finalChunks) > 0 && !slices.Contains(ran, PipelineCompress) {
+		ok, err := b.budgeted(ctx, PipelineCompress, &stats, func(ctx context.Context) error {
+			compressed, err := b.compressChunks(ctx, finalChunks, plan)
+			if err != nil {
+				return err
+			}
+			recordCompression(&stats, finalChunks, compressed)
+			if req.Explain {
+				explainCompressed(finalChunks, compressed, plan.compression.method)
+			}
+			finalChunks = compressed
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			ran = append(ran, PipelineCompress)
+		}
+	}
+
+	if plan.typed {
+		redact()
+	}
+
+	// Step 7: Fit the model's budget, in rank order
+	if plan.model != nil {
+		fitted, tokens := plan.model.Fit(finalChunks)
+		stats.Model = plan.model.Name
+		stats.BudgetDropped = len(finalChunks) - len(fitted)
+		stats.Tokens = tokens
+		stats.CostUSD = plan.model.Cost(tokens)
+		finalChunks = fitted
+	}
+
+	// Step 8: Tag what the caller receives
+	if plan.classify && len(finalChunks) > 0 {
+		stats.Sensitivity = b.classify(finalChunks).String()
+		ran = append(ran, PipelineClassify)
+	}
+
+	if err := b.sent.Record(ctx, req.SessionID, finalChunks); err != nil {
+		return nil, fmt.Errorf("failed to record sent chunks: %w", err)
+	}
+
+	stats.Returned = len(finalChunks)
+	return &types.BrokerResult{
+		Chunks: finalChunks,
+		Stats:  stats,
+		Stages: ran,
+	}, nil
+}
+
+// defaultStages clusters candidates, selects one representative per
+// cluster, and applies MMR or a top-k cut down to TargetK, skipping the
+// stages plan turns off. It returns the chunks and the stages that ran.
+func (b *Broker) defaultStages(ctx context.Context, req *types.RetrievalRequest, plan stagePlan, candidates []types.Chunk, stats *types.BrokerStats) ([]types.Chunk, []string, error) {
+	var ran []string
+	representatives := candidates
+	var clusterResult *types.ClusterResult
+	if plan.cluster {
+		// Step 3: Cluster retrieved chunks
+		clustered, err := b.budgeted(ctx, PipelineCluster, stats, func(ctx context.Context) error {
+			observeStage(ctx, StageClustering, len(candidates))
+			clusterStart := time.Now()
+			result, err := b.requestClusterer(req).ClusterContext(ctx, candidates)
+			if err != nil {
+				return fmt.Errorf("clustering interrupted: %w", err)
+			}
+			stats.ClusteringLatency = time.Since(clusterStart)
+			clusterResult = result
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if clustered {
+			stats.Clustered = clusterResult.ClusterCount
+			stats.Vetoed = clusterResult.Vetoed
+			stats.MatrixOverflow = clusterResult.Overflow
+
+			// Step 4: Select representatives from each cluster
+			observeStage(ctx, StageSelection, clusterResult.ClusterCount)
+			representatives = b.selectorFor(plan.strategy).Select(clusterResult)
+			if req.Explain {
+				explainSelected(representatives, clusterResult)
+			}
+			if plan.merge() {
+				representatives, stats.Merged = MergeClusters(representatives, clusterResult, plan.compression.opts)
+				if req.Explain {
+					explainMerged(representatives)
+				}
+			}
+			ran = append(ran, PipelineCluster, PipelineSelect)
+		}
+	}
 
 	// Step 5: Apply MMR if enabled
 	var finalChunks []types.Chunk
-	if b.cfg.EnableMMR && b.mmr != nil && len(representatives) > b.cfg.TargetK {
-		finalChunks = b.mmr.Rerank(representatives)
-	} else if len(representatives) > b.cfg.TargetK {
+	mmr := b.requestMMR(req)
+	if len(req.Namespaces) > 0 {
+		// Multi-namespace requests fill each namespace's quota instead of
+		// one target
+		var err error
+		finalChunks, err = b.selectByNamespace(ctx, req, representatives)
+		if err != nil {
+			return nil, nil, err
+		}
+		if mmr != nil {
+			ran = append(ran, mmrStages(mmr.cfg)...)
+		}
+	} else if mmr != nil && b.overTarget(representatives, plan) {
+		if b.cfg.TokenBudget > 0 && plan.model != nil {
+			cfg := mmr.cfg
+			cfg.Tokens = plan.model.Tokens
+			mmr = NewMMR(cfg)
+		}
+		reranked, err := b.budgeted(ctx, PipelineMMR, stats, func(ctx context.Context) error {
+			observeStage(ctx, StageMMR, len(representatives))
+			chunks, err := mmr.RerankContext(ctx, representatives)
+			if err != nil {
+				return fmt.Errorf("mmr interrupted: %w", err)
+			}
+			if req.Explain {
+				explainRanks(chunks, PipelineMMR)
+			}
+			finalChunks = chunks
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if reranked {
+			ran = append(ran, mmrStages(mmr.cfg)...)
+		} else {
+			finalChunks = b.topByScore(representatives, plan)
+		}
+	} else if b.overTarget(representatives, plan) {
 		// Just take top K by score
-		finalChunks = SelectTopK(clusterResult, b.cfg.TargetK, b.cfg.SelectionStrategy)
+		if b.cfg.TokenBudget > 0 {
+			finalChunks = b.topByScore(representatives, plan)
+		} else if clusterResult != nil {
+			finalChunks = SelectTopK(clusterResult, b.cfg.TargetK, plan.strategy)
+		} else {
+			finalChunks = topByScore(representatives, b.cfg.TargetK)
+		}
 	} else {
 		finalChunks = representatives
 	}
+	if b.cfg.DedupHints || req.DedupHints {
+		AnnotateDedup(finalChunks, clusterResult, plan.strategy)
+	}
+	return finalChunks, ran, nil
+}
 
-	stats.Returned = len(finalChunks)
-	stats.TotalLatency = time.Since(totalStart)
+// overTarget reports whether chunks are more than the broker returns:
+// over TokenBudget when it is set, otherwise over TargetK.
+func (b *Broker) overTarget(chunks []types.Chunk, plan stagePlan) bool {
+	if b.cfg.TokenBudget > 0 {
+		return sumInts(chunkTokens(chunks, tokenCounter(plan.model))) > b.cfg.TokenBudget
+	}
+	return len(chunks) > b.cfg.TargetK
+}
 
-	return &types.BrokerResult{
-		Chunks: finalChunks,
-		Stats:  stats,
-	}, nil
+// markSparse marks stats as a result with n candidates, too few to
+// deduplicate.
+func markSparse(stats *types.BrokerStats, n int) {
+	stats.Sparse = true
+	note := fmt.Sprintf("namespace sparse for this query: %d candidates left after filtering", n)
+	if n > 0 {
+		note += "; clustering and MMR skipped"
+	}
+	stats.Notes = append(stats.Notes, note)
+}
+
+// topByScore keeps the highest-scoring chunks: TargetK of them, or those
+// that fit TokenBudget when it is set.
+func (b *Broker) topByScore(chunks []types.Chunk, plan stagePlan) []types.Chunk {
+	if b.cfg.TokenBudget > 0 {
+		return fitTokens(topByScore(chunks, len(chunks)), b.cfg.TokenBudget, tokenCounter(plan.model))
+	}
+	return topByScore(chunks, b.cfg.TargetK)
 }
 
 // RetrieveByText is a convenience method for text queries.
@@ -225,20 +899,14 @@ func (b *Broker) SetConfig(cfg BrokerConfig) {
 	b.cfg = cfg
 	b.cfg.IncludeEmbeddings = true
 
-	b.clusterer = NewClusterer(ClusterConfig{
-		Threshold: cfg.ClusterThreshold,
-		Linkage:   cfg.ClusterLinkage,
-	})
+	b.clusterer, b.nsClusterers = newClusterers(cfg)
 
 	b.selector = NewSelector(SelectorConfig{
 		Strategy: cfg.SelectionStrategy,
 	})
 
 	if cfg.EnableMMR {
-		b.mmr = NewMMR(MMRConfig{
-			Lambda:  cfg.MMRLambda,
-			TargetK: cfg.TargetK,
-		})
+		b.mmr = NewMMR(mmrConfig(cfg))
 	} else {
 		b.mmr = nil
 	}
@@ -265,9 +933,16 @@ func (b *Broker) ProcessChunks(chunks []types.Chunk) *types.BrokerResult {
 		Retrieved: len(chunks),
 	}
 
-	if len(chunks) == 0 {
+	if len(chunks) == 0 || len(chunks) < b.cfg.MinCandidates {
+		markSparse(&stats, len(chunks))
+		finalChunks := []types.Chunk{}
+		if len(chunks) > 0 {
+			finalChunks = b.topByScore(chunks, stagePlan{})
+		}
+		stats.Returned = len(finalChunks)
+		stats.TotalLatency = time.Since(totalStart)
 		return &types.BrokerResult{
-			Chunks: []types.Chunk{},
+			Chunks: finalChunks,
 			Stats:  stats,
 		}
 	}
@@ -277,19 +952,26 @@ func (b *Broker) ProcessChunks(chunks []types.Chunk) *types.BrokerResult {
 	clusterResult := b.clusterer.Cluster(chunks)
 	stats.ClusteringLatency = time.Since(clusterStart)
 	stats.Clustered = clusterResult.ClusterCount
+	stats.Vetoed = clusterResult.Vetoed
+	stats.MatrixOverflow = clusterResult.Overflow
 
 	// Select representatives
 	representatives := b.selector.Select(clusterResult)
 
 	// Apply MMR if enabled
 	var finalChunks []types.Chunk
-	if b.cfg.EnableMMR && b.mmr != nil && len(representatives) > b.cfg.TargetK {
+	if b.cfg.EnableMMR && b.mmr != nil && b.overTarget(representatives, stagePlan{}) {
 		finalChunks = b.mmr.Rerank(representatives)
-	} else if len(representatives) > b.cfg.TargetK {
+	} else if b.cfg.TokenBudget > 0 && b.overTarget(representatives, stagePlan{}) {
+		finalChunks = b.topByScore(representatives, stagePlan{})
+	} else if b.overTarget(representatives, stagePlan{}) {
 		finalChunks = SelectTopK(clusterResult, b.cfg.TargetK, b.cfg.SelectionStrategy)
 	} else {
 		finalChunks = representatives
 	}
+	if b.cfg.DedupHints {
+		AnnotateDedup(finalChunks, clusterResult, b.cfg.SelectionStrategy)
+	}
 
 	stats.Returned = len(finalChunks)
 	stats.TotalLatency = time.Since(totalStart)
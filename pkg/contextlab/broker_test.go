@@ -0,0 +1,531 @@
+package contextlab
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/dedup"
+	"github.com/Siddhant-K-code/distill/pkg/enrich"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	fakeretriever "github.com/Siddhant-K-code/distill/pkg/retriever/fake"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// stubRetriever returns a fixed set of chunks for every query and records
+// the last request.
+type stubRetriever struct {
+	chunks  []types.Chunk
+	queries int
+	last    *types.RetrievalRequest
+}
+
+func (r *stubRetriever) Query(ctx context.Context, req *types.RetrievalRequest) (*types.RetrievalResult, error) {
+	r.queries++
+	r.last = req
+	out := make([]types.Chunk, len(r.chunks))
+	copy(out, r.chunks)
+	return &types.RetrievalResult{Chunks: out}, nil
+}
+
+func (r *stubRetriever) QueryByID(ctx context.Context, id string, topK int, namespace string) (*types.RetrievalResult, error) {
+	return r.Query(ctx, nil)
+}
+
+func (r *stubRetriever) Close() error { return nil }
+
+// orthogonalChunks returns n chunks that never cluster together.
+func orthogonalChunks(n int) []types.Chunk {
+	chunks := make([]types.Chunk, n)
+	for i := range chunks {
+		emb := make([]float32, n)
+		emb[i] = 1
+		chunks[i] = types.Chunk{
+			ID:        string(rune('a' + i)),
+			Text:      "chunk " + string(rune('a'+i)),
+			Score:     float32(n-i) / float32(n),
+			Embedding: emb,
+		}
+	}
+	return chunks
+}
+
+func TestBroker_Exclude(t *testing.T) {
+	chunks := orthogonalChunks(4)
+	broker := NewBroker(&stubRetriever{chunks: chunks}, BrokerConfig{TargetK: 10})
+
+	full := sha256.Sum256([]byte(chunks[2].Text))
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1, 0, 0, 0},
+		Exclude:        []string{"a", hex.EncodeToString(full[:]), "unknown"},
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+
+	if result.Stats.Excluded != 2 {
+		t.Errorf("expected 2 excluded, got %d", result.Stats.Excluded)
+	}
+	for _, c := range result.Chunks {
+		if c.ID == "a" || c.ID == "c" {
+			t.Errorf("excluded chunk %q returned", c.ID)
+		}
+	}
+	if len(result.Chunks) != 2 {
+		t.Errorf("expected 2 chunks, got %d", len(result.Chunks))
+	}
+}
+
+func TestBroker_Enrichment(t *testing.T) {
+	hook, err := enrich.New(enrich.Func(func(ctx context.Context, req enrich.Request) ([]enrich.Result, error) {
+		var out []enrich.Result
+		for _, c := range req.Chunks {
+			if c.ID == "b" {
+				out = append(out, enrich.Result{ID: c.ID, Drop: true})
+				continue
+			}
+			out = append(out, enrich.Result{ID: c.ID, Metadata: map[string]interface{}{"title": "Doc " + c.ID}})
+		}
+		return out, nil
+	}), enrich.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: orthogonalChunks(3)}, WithTargetK(10), WithEnrichment(hook))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stages []string
+	ctx := WithStageObserver(context.Background(), func(stage string, n int) { stages = append(stages, stage) })
+	result, err := broker.Retrieve(ctx, &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(result.Chunks) != 2 || result.Stats.Enriched != 2 || result.Stats.Denied != 1 {
+		t.Fatalf("got %d chunks, %d enriched, %d denied", len(result.Chunks), result.Stats.Enriched, result.Stats.Denied)
+	}
+	for _, c := range result.Chunks {
+		if c.Metadata["title"] != "Doc "+c.ID {
+			t.Errorf("chunk %s: missing title, metadata %v", c.ID, c.Metadata)
+		}
+	}
+	if len(stages) < 2 || stages[1] != StageEnrichment {
+		t.Errorf("expected enrichment after retrieval, got stages %v", stages)
+	}
+}
+
+func TestExcludeChunks_ByChunkHash(t *testing.T) {
+	chunks := orthogonalChunks(3)
+	out, n := ExcludeChunks(chunks, []string{cache.ChunkHash(chunks[1])})
+	if n != 1 || len(out) != 2 || out[1].ID != "c" {
+		t.Errorf("unexpected result: n=%d out=%v", n, out)
+	}
+}
+
+func TestBroker_SessionFilter(t *testing.T) {
+	broker := NewBroker(&stubRetriever{chunks: orthogonalChunks(3)}, BrokerConfig{TargetK: 10})
+	mem := cache.NewMemoryCache(cache.DefaultConfig())
+	defer func() { _ = mem.Close() }()
+	broker.SetSentFilter(cache.NewSentFilter(mem, 0))
+
+	req := func() *types.RetrievalRequest {
+		return &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0}, SessionID: "s1"}
+	}
+
+	first, err := broker.Retrieve(context.Background(), req())
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(first.Chunks) != 3 || first.Stats.Repeated != 0 {
+		t.Fatalf("first call: got %d chunks, %d repeated", len(first.Chunks), first.Stats.Repeated)
+	}
+
+	second, err := broker.Retrieve(context.Background(), req())
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(second.Chunks) != 0 || second.Stats.Repeated != 3 {
+		t.Errorf("second call: got %d chunks, %d repeated", len(second.Chunks), second.Stats.Repeated)
+	}
+}
+
+func TestClusterContext_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	chunks := orthogonalChunks(5)
+	result, err := NewClusterer(DefaultClusterConfig()).ClusterContext(ctx, chunks)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if result == nil || result.ClusterCount != 5 {
+		t.Errorf("expected unmerged partial result, got %+v", result)
+	}
+}
+
+func TestRerankContext_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewMMR(MMRConfig{Lambda: 0.5, TargetK: 2}).RerankContext(ctx, orthogonalChunks(5))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBroker_RetrieveCanceled(t *testing.T) {
+	broker := NewBroker(&stubRetriever{chunks: orthogonalChunks(4)}, BrokerConfig{TargetK: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := broker.Retrieve(ctx, &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0, 0}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBroker_StageObserver(t *testing.T) {
+	broker := newFakeBroker(t, BrokerConfig{OverFetchK: 40, TargetK: 4, EnableMMR: true, MMRLambda: 0.5})
+
+	var stages []string
+	ctx := WithStageObserver(context.Background(), func(stage string, n int) {
+		if n <= 0 {
+			t.Errorf("stage %s: expected a positive count, got %d", stage, n)
+		}
+		stages = append(stages, stage)
+	})
+	if _, err := broker.RetrieveByText(ctx, "invoices refunds and payment methods", ""); err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+
+	want := []string{StageEmbedding, StageRetrieval, StageClustering, StageSelection, StageMMR}
+	if len(stages) != len(want) {
+		t.Fatalf("got stages %v, want %v", stages, want)
+	}
+	for i := range want {
+		if stages[i] != want[i] {
+			t.Fatalf("got stages %v, want %v", stages, want)
+		}
+	}
+}
+
+func newFakeBroker(t *testing.T, cfg BrokerConfig) *Broker {
+	t.Helper()
+	ret, err := fakeretriever.NewClient(fakeretriever.Config{CorpusSize: 300, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewBrokerWithEmbedder(ret, ret.Embedder(), cfg)
+}
+
+func topics(chunks []types.Chunk) map[interface{}]int {
+	out := make(map[interface{}]int)
+	for _, c := range chunks {
+		out[c.Metadata["topic"]]++
+	}
+	return out
+}
+
+func TestBroker_MultiVector(t *testing.T) {
+	broker := newFakeBroker(t, BrokerConfig{TargetK: 6, IncludeMetadata: true})
+	queries := []string{"invoices refunds and payment methods", "database replicas and backups"}
+
+	fanout, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		Queries: queries,
+		Combine: retriever.CombineFanOut,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	got := topics(fanout.Chunks)
+	if got["billing"] == 0 || got["database"] == 0 {
+		t.Errorf("expected both topics from fan-out, got %v", got)
+	}
+	if fanout.Stats.Retrieved > 50 {
+		t.Errorf("expected fan-out to share the over-fetch budget, got %d", fanout.Stats.Retrieved)
+	}
+
+	avg, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		Query:   queries[0],
+		Queries: queries[1:],
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(avg.Chunks) == 0 {
+		t.Error("expected chunks for the averaged query")
+	}
+
+	_, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{Queries: queries, Combine: "sum"})
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected config error for unknown combine mode, got %v", err)
+	}
+}
+
+func TestBroker_RetrieveSimilar(t *testing.T) {
+	broker := newFakeBroker(t, BrokerConfig{TargetK: 5, IncludeMetadata: true})
+	ids := []string{"doc-00003", "doc-00004"}
+
+	result, err := broker.RetrieveSimilar(context.Background(), ids, &types.RetrievalRequest{})
+	if err != nil {
+		t.Fatalf("RetrieveSimilar: %v", err)
+	}
+	if len(result.Chunks) == 0 || len(result.Chunks) > 5 {
+		t.Fatalf("expected 1-5 chunks, got %d", len(result.Chunks))
+	}
+	if result.Stats.Excluded != len(ids) {
+		t.Errorf("expected the source items excluded, got %d", result.Stats.Excluded)
+	}
+	for _, c := range result.Chunks {
+		if c.ID == ids[0] || c.ID == ids[1] {
+			t.Errorf("source item %s returned as its own neighbor", c.ID)
+		}
+	}
+
+	_, err = broker.RetrieveSimilar(context.Background(), []string{"missing"}, &types.RetrievalRequest{})
+	if !errors.Is(err, retriever.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestBroker_TruncatedOverFetch(t *testing.T) {
+	ret, err := fakeretriever.NewClient(fakeretriever.Config{CorpusSize: 300, MaxTopK: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := NewBrokerWithEmbedder(ret, ret.Embedder(), BrokerConfig{OverFetchK: 100, TargetK: 5})
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{Query: "database replicas"})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if !result.Stats.Truncated || result.Stats.Retrieved != 20 {
+		t.Errorf("expected truncated retrieval of 20, got truncated=%v retrieved=%d", result.Stats.Truncated, result.Stats.Retrieved)
+	}
+
+	broker.SetConfig(BrokerConfig{OverFetchK: 20, TargetK: 5})
+	result, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{Query: "database replicas"})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if result.Stats.Truncated {
+		t.Error("expected no truncation within the backend cap")
+	}
+}
+
+func TestBroker_RequestTopK(t *testing.T) {
+	ret := &stubRetriever{chunks: orthogonalChunks(4)}
+	broker := NewBroker(ret, BrokerConfig{OverFetchK: 50, TargetK: 2})
+
+	for _, tc := range []struct{ topK, want int }{{0, 50}, {8, 8}, {200, 200}} {
+		if _, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0, 0}, TopK: tc.topK}); err != nil {
+			t.Fatalf("Retrieve: %v", err)
+		}
+		if ret.last.TopK != tc.want {
+			t.Errorf("top_k %d: fetched %d, want %d", tc.topK, ret.last.TopK, tc.want)
+		}
+	}
+}
+
+func TestBroker_MinScore(t *testing.T) {
+	ret, err := fakeretriever.NewClient(fakeretriever.Config{CorpusSize: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := NewBrokerWithEmbedder(ret, ret.Embedder(), BrokerConfig{OverFetchK: 100, TargetK: 5})
+
+	all, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{Query: "database replicas"})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	cut := all.Chunks[0].Score - 0.01
+
+	broker.SetConfig(BrokerConfig{OverFetchK: 100, TargetK: 5, MinScore: float64(cut)})
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{Query: "database replicas"})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if result.Stats.Retrieved >= all.Stats.Retrieved || result.Stats.Retrieved == 0 {
+		t.Errorf("expected config MinScore to drop matches, retrieved %d of %d", result.Stats.Retrieved, all.Stats.Retrieved)
+	}
+
+	similar, err := broker.RetrieveSimilar(context.Background(), []string{all.Chunks[0].ID}, &types.RetrievalRequest{MinScore: 0.999})
+	if err != nil {
+		t.Fatalf("RetrieveSimilar: %v", err)
+	}
+	for _, ch := range similar.Chunks {
+		if ch.Score < 0.999 {
+			t.Errorf("expected chunks below MinScore dropped, got %s at %g", ch.ID, ch.Score)
+		}
+	}
+}
+
+func TestBroker_Sparse(t *testing.T) {
+	chunks := orthogonalChunks(3)
+	chunks[0].Score, chunks[2].Score = 0.1, 0.9
+	broker := NewBroker(&stubRetriever{chunks: chunks}, BrokerConfig{TargetK: 10, EnableMMR: true, MinCandidates: 4})
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if !result.Stats.Sparse || len(result.Stats.Notes) != 1 || result.Stats.Clustered != 0 || len(result.Stages) != 1 {
+		t.Errorf("expected a sparse result with a note and no stages, got stats %+v, stages %v", result.Stats, result.Stages)
+	}
+	if len(result.Chunks) != 3 || result.Chunks[0].ID != "c" || result.Chunks[2].ID != "a" {
+		t.Errorf("expected all chunks in score order, got %v", result.Chunks)
+	}
+
+	// Nothing left after filtering is sparse too, even with the minimum off
+	broker.SetConfig(BrokerConfig{TargetK: 10})
+	result, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0}, Exclude: []string{"a", "b", "c"}})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if !result.Stats.Sparse || len(result.Chunks) != 0 {
+		t.Errorf("expected an empty sparse result, got %d chunks, stats %+v", len(result.Chunks), result.Stats)
+	}
+
+	result, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if result.Stats.Sparse || result.Stats.Clustered != 3 {
+		t.Errorf("expected clustering with the minimum off, got stats %+v", result.Stats)
+	}
+}
+
+func TestBroker_Passthrough(t *testing.T) {
+	chunks := orthogonalChunks(3)
+	chunks[1].Embedding = chunks[0].Embedding // b duplicates a
+	broker := NewBroker(&stubRetriever{chunks: chunks}, BrokerConfig{TargetK: 3, ClusterThreshold: 0.15, EnableMMR: true})
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0}, Mode: ModePassthrough})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if !result.Stats.Passthrough || result.Stats.Clustered != 2 || len(result.Stages) != 1 {
+		t.Errorf("expected a passthrough result clustered for analysis only, got stats %+v, stages %v", result.Stats, result.Stages)
+	}
+	if len(result.Chunks) != 3 || result.Chunks[0].ID != "a" || result.Chunks[1].ID != "b" {
+		t.Fatalf("expected every chunk in score order, got %v", result.Chunks)
+	}
+	a, b := result.Chunks[0], result.Chunks[1]
+	if a.ClusterID != b.ClusterID || a.Score != chunks[0].Score {
+		t.Errorf("expected a and b in one cluster with scores kept, got %+v, %+v", a, b)
+	}
+	if a.Metadata[HintWouldKeep] != true || b.Metadata[HintWouldKeep] != false || b.Metadata[HintClusterSize] != 2 {
+		t.Errorf("expected a kept and b dropped by deduplication, got %v, %v", a.Metadata, b.Metadata)
+	}
+
+	deduped, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if deduped.Stats.Passthrough || len(deduped.Chunks) != 2 {
+		t.Errorf("expected the default mode to deduplicate, got %d chunks", len(deduped.Chunks))
+	}
+
+	_, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0}, Mode: "raw"})
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected ErrConfig for an unknown mode, got %v", err)
+	}
+}
+
+func TestBroker_RequestThreshold(t *testing.T) {
+	broker := newFakeBroker(t, BrokerConfig{OverFetchK: 50, TargetK: 50})
+
+	base, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{Query: "database replicas"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loose, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{Query: "database replicas", Threshold: 0.9, Lambda: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loose.Stats.Clustered >= base.Stats.Clustered {
+		t.Errorf("expected a looser threshold to form fewer clusters, got %d vs %d", loose.Stats.Clustered, base.Stats.Clustered)
+	}
+	if cfg := broker.GetConfig(); cfg.ClusterThreshold != 0.15 || cfg.MMRLambda != 0 {
+		t.Errorf("request overrides leaked into the config: %+v", cfg)
+	}
+}
+
+func TestBroker_ExcludesTombstoned(t *testing.T) {
+	chunks := orthogonalChunks(4)
+	chunks[1].Metadata = map[string]interface{}{dedup.TombstoneKey: true, dedup.DuplicateOfKey: "a"}
+	chunks[2].Metadata = map[string]interface{}{dedup.TombstoneKey: false}
+	stub := &stubRetriever{chunks: chunks}
+	broker := NewBroker(stub, BrokerConfig{TargetK: 10})
+
+	filter := map[string]interface{}{"lang": "go"}
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1, 0, 0, 0},
+		ExcludeFilter:  filter,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stub.last.ExcludeFilter; got[dedup.TombstoneKey] != true || got["lang"] != "go" {
+		t.Errorf("expected tombstone exclusion pushed to the retriever, got %v", got)
+	}
+	if len(filter) != 1 {
+		t.Error("caller's exclude filter should not be modified")
+	}
+	if len(result.Chunks) != 3 {
+		t.Errorf("expected 3 chunks, got %d", len(result.Chunks))
+	}
+	for _, ch := range result.Chunks {
+		if ch.ID == "b" {
+			t.Error("expected tombstoned chunk b dropped")
+		}
+	}
+
+	broker.SetConfig(BrokerConfig{TargetK: 10, IncludeTombstoned: true})
+	result, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Chunks) != 4 || stub.last.ExcludeFilter != nil {
+		t.Errorf("expected tombstoned chunks with IncludeTombstoned, got %d", len(result.Chunks))
+	}
+}
+
+func TestBroker_NamespaceEntities(t *testing.T) {
+	chunks := []types.Chunk{
+		{ID: "okta", Text: "Reset a password in Okta.", Score: 0.9, Embedding: []float32{1, 0}},
+		{ID: "gws", Text: "Reset a password in Google Workspace.", Score: 0.8, Embedding: []float32{1, 0.05}},
+	}
+	broker := NewBroker(&stubRetriever{chunks: chunks}, BrokerConfig{
+		TargetK:           5,
+		NamespaceEntities: map[string]EntityConfig{"support": {Enabled: true}},
+	})
+
+	tests := []struct {
+		namespace string
+		returned  int
+		vetoed    int
+	}{
+		{"", 1, 0},
+		{"support", 2, 1},
+	}
+	for _, tt := range tests {
+		result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+			QueryEmbedding: []float32{1, 0},
+			Namespace:      tt.namespace,
+		})
+		if err != nil {
+			t.Fatalf("Retrieve(%q): %v", tt.namespace, err)
+		}
+		if result.Stats.Returned != tt.returned || result.Stats.Vetoed != tt.vetoed {
+			t.Errorf("namespace %q: returned %d, vetoed %d; want %d, %d",
+				tt.namespace, result.Stats.Returned, result.Stats.Vetoed, tt.returned, tt.vetoed)
+		}
+	}
+}
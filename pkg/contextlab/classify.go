@@ -0,0 +1,55 @@
+package contextlab
+
+import (
+	"sync"
+
+	"github.com/Siddhant-K-code/distill/pkg/sensitivity"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Metadata keys set by classification.
+const (
+	// MetaSensitivity is the chunk's sensitivity level: "none", "pii",
+	// "internal", or "credentials".
+	MetaSensitivity = "sensitivity"
+
+	// MetaSensitivityMatches lists the detectors that matched, such as
+	// "email_address" or "example:roadmap". It is left out for "none".
+	MetaSensitivityMatches = "sensitivity_matches"
+)
+
+// defaultClassifier backs EnableClassification without WithClassifier.
+var defaultClassifier = sync.OnceValue(func() *sensitivity.Classifier {
+	return sensitivity.New(sensitivity.DefaultConfig())
+})
+
+// classify tags each chunk with MetaSensitivity and
+// MetaSensitivityMatches and returns the highest level among them.
+// Metadata maps are copied before they are changed, since they may be
+// shared with a cache or retriever.
+func (b *Broker) classify(chunks []types.Chunk) sensitivity.Level {
+	c := b.classifier
+	if c == nil {
+		c = defaultClassifier()
+	}
+	highest := sensitivity.None
+	for i := range chunks {
+		r := c.ClassifyChunk(chunks[i].Text, chunks[i].Embedding)
+		highest = max(highest, r.Level)
+
+		metadata := make(map[string]interface{}, len(chunks[i].Metadata)+2)
+		for k, v := range chunks[i].Metadata {
+			metadata[k] = v
+		}
+		metadata[MetaSensitivity] = r.Level.String()
+		if len(r.Matches) > 0 {
+			names := make([]string, len(r.Matches))
+			for j, m := range r.Matches {
+				names[j] = m.Pattern
+			}
+			metadata[MetaSensitivityMatches] = names
+		}
+		chunks[i].Metadata = metadata
+	}
+	return highest
+}
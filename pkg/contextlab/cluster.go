@@ -1,10 +1,10 @@
 package contextlab
 
 import (
+	"context"
 	"sort"
 	"time"
 
-	"github.com/Siddhant-K-code/distill/pkg/math"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
 
@@ -26,6 +26,19 @@ type ClusterConfig struct {
 	// Linkage determines how inter-cluster distance is computed.
 	// Options: "single", "complete", "average" (default: "average")
 	Linkage string
+
+	// Entities, if set, vetoes merging two clusters when any pair of
+	// their members names conflicting entities. See EntityConfig.
+	Entities EntityExtractor
+
+	// Matrix caps the memory of the pairwise distance matrix. See
+	// MatrixBudget.
+	Matrix MatrixBudget
+
+	// Workers is the number of goroutines computing the distance
+	// matrix. 0 uses GOMAXPROCS; 1 computes it on the calling goroutine.
+	// Small inputs always use one.
+	Workers int
 }
 
 // DefaultClusterConfig returns sensible defaults.
@@ -65,6 +78,14 @@ type clusterNode struct {
 // Cluster performs agglomerative clustering on the given chunks.
 // Returns clusters with assigned members and centroids.
 func (c *Clusterer) Cluster(chunks []types.Chunk) *types.ClusterResult {
+	result, _ := c.ClusterContext(context.Background(), chunks)
+	return result
+}
+
+// ClusterContext is Cluster with cancellation. ctx is checked once per
+// distance-matrix row and once per merge iteration. If ctx is done, the
+// clusters merged so far are returned along with ctx.Err().
+func (c *Clusterer) ClusterContext(ctx context.Context, chunks []types.Chunk) (*types.ClusterResult, error) {
 	start := time.Now()
 
 	n := len(chunks)
@@ -75,7 +96,7 @@ func (c *Clusterer) Cluster(chunks []types.Chunk) *types.ClusterResult {
 			InputCount:      0,
 			ClusterCount:    0,
 			Latency:         time.Since(start),
-		}
+		}, nil
 	}
 
 	if n == 1 {
@@ -90,7 +111,7 @@ func (c *Clusterer) Cluster(chunks []types.Chunk) *types.ClusterResult {
 			InputCount:      1,
 			ClusterCount:    1,
 			Latency:         time.Since(start),
-		}
+		}, nil
 	}
 
 	// Check if embeddings are present
@@ -118,8 +139,24 @@ func (c *Clusterer) Cluster(chunks []types.Chunk) *types.ClusterResult {
 			InputCount:      n,
 			ClusterCount:    n,
 			Latency:         time.Since(start),
-		}
+		}, nil
+	}
+
+	entities := c.cfg.Entities != nil
+	overflow := ""
+	if c.cfg.Matrix.exceeded(n, entities) {
+		overflow = c.cfg.Matrix.overflow()
 	}
+	if overflow == OverflowWindow {
+		return c.clusterWindowed(ctx, chunks, start)
+	}
+	matrix, err := newDistanceMatrix(n, entities, overflow == OverflowSpill, c.cfg.Matrix.SpillDir)
+	if err != nil {
+		// The spill file could not be mapped; windowing still keeps
+		// memory under the budget.
+		return c.clusterWindowed(ctx, chunks, start)
+	}
+	defer matrix.close() //nolint:errcheck
 
 	// Initialize each chunk as its own cluster
 	nodes := make([]*clusterNode, n)
@@ -135,46 +172,54 @@ func (c *Clusterer) Cluster(chunks []types.Chunk) *types.ClusterResult {
 	}
 
 	// Compute initial distance matrix (upper triangular)
-	distMatrix := c.computeDistanceMatrix(chunks)
-
-	// Agglomerative merging
+	if err := matrix.fill(ctx, chunks, c.cfg.Workers); err != nil {
+		return c.buildResult(nodes, chunks, n, start), err
+	}
+	vetoed := c.computeConflicts(chunks, matrix)
+
+	// Agglomerative merging. A cluster is stored at the index of its
+	// lowest member, and the matrix rows of active clusters hold
+	// cluster distances, updated as clusters merge. nearest holds each
+	// cluster's closest mergeable cluster at a higher index, so finding
+	// the closest pair takes one pass over the clusters instead of over
+	// every pair.
+	nearest := newNearestNeighbors(n)
+	for i := 0; i < n; i++ {
+		nearest.update(i, nodes, matrix)
+	}
 	activeCount := n
 	for activeCount > 1 {
+		if err := ctx.Err(); err != nil {
+			return c.buildResult(nodes, chunks, activeCount, start), err
+		}
+
 		// Check stopping conditions
 		if c.cfg.MinClusters > 0 && activeCount <= c.cfg.MinClusters {
 			break
 		}
 
-		// Find closest pair of clusters
-		minDist := float64(2.0) // Max cosine distance
-		minI, minJ := -1, -1
-
+		// Find closest pair of clusters. Ties go to the lowest indices,
+		// as in a scan of every pair.
+		minDist := maxDistance
+		minI := -1
 		for i := 0; i < n; i++ {
-			if !nodes[i].active {
-				continue
-			}
-			for j := i + 1; j < n; j++ {
-				if !nodes[j].active {
-					continue
-				}
-
-				dist := c.clusterDistance(nodes[i], nodes[j], chunks, distMatrix)
-				if dist < minDist {
-					minDist = dist
-					minI, minJ = i, j
-				}
+			if nodes[i].active && nearest.dist[i] < minDist {
+				minDist, minI = nearest.dist[i], i
 			}
 		}
 
 		// Check if we should stop merging
-		if minDist > c.cfg.Threshold {
+		if minI < 0 || minDist > c.cfg.Threshold {
 			break
 		}
 
 		// Merge clusters i and j into i
+		minJ := nearest.index[minI]
+		c.updateDistances(nodes, minI, minJ, matrix)
 		c.mergeClusters(nodes[minI], nodes[minJ], chunks)
 		nodes[minJ].active = false
 		activeCount--
+		nearest.merged(minI, minJ, nodes, matrix)
 
 		// Check max clusters limit
 		if c.cfg.MaxClusters > 0 && activeCount <= c.cfg.MaxClusters {
@@ -182,7 +227,14 @@ func (c *Clusterer) Cluster(chunks []types.Chunk) *types.ClusterResult {
 		}
 	}
 
-	// Build result from active clusters
+	result := c.buildResult(nodes, chunks, activeCount, start)
+	result.Vetoed = vetoed
+	result.Overflow = overflow
+	return result, nil
+}
+
+// buildResult assigns cluster IDs and collects the active clusters.
+func (c *Clusterer) buildResult(nodes []*clusterNode, chunks []types.Chunk, activeCount int, start time.Time) *types.ClusterResult {
 	clusters := make([]types.Cluster, 0, activeCount)
 	clusterID := 0
 
@@ -207,83 +259,113 @@ func (c *Clusterer) Cluster(chunks []types.Chunk) *types.ClusterResult {
 
 	return &types.ClusterResult{
 		Clusters:     clusters,
-		InputCount:   n,
+		InputCount:   len(chunks),
 		ClusterCount: len(clusters),
 		Latency:      time.Since(start),
 	}
 }
 
-// computeDistanceMatrix computes pairwise cosine distances.
-func (c *Clusterer) computeDistanceMatrix(chunks []types.Chunk) [][]float64 {
+// computeConflicts marks chunk pairs whose entities conflict, and counts
+// those close enough to merge on distance alone. It does nothing when no
+// entity extractor is configured.
+func (c *Clusterer) computeConflicts(chunks []types.Chunk, matrix *distanceMatrix) int {
+	if c.cfg.Entities == nil {
+		return 0
+	}
 	n := len(chunks)
-	matrix := make([][]float64, n)
-
-	// Initialize all rows first
-	for i := 0; i < n; i++ {
-		matrix[i] = make([]float64, n)
+	entities := make([][]string, n)
+	for i := range chunks {
+		entities[i] = c.cfg.Entities.Entities(chunks[i].Text)
 	}
 
-	// Compute distances
+	vetoed := 0
 	for i := 0; i < n; i++ {
 		for j := i + 1; j < n; j++ {
-			// Handle missing embeddings gracefully
-			if len(chunks[i].Embedding) == 0 || len(chunks[j].Embedding) == 0 {
-				matrix[i][j] = 2.0 // Max distance
-				matrix[j][i] = 2.0
-				continue
+			if entitiesConflict(entities[i], entities[j]) {
+				k := matrix.index(i, j)
+				matrix.conflicts[k] = true
+				if matrix.dist[k] <= c.cfg.Threshold {
+					vetoed++
+				}
 			}
-			dist := math.CosineDistance(chunks[i].Embedding, chunks[j].Embedding)
-			matrix[i][j] = dist
-			matrix[j][i] = dist
 		}
 	}
+	return vetoed
+}
 
-	return matrix
+// maxDistance is the largest cosine distance. Pairs this far apart are
+// never merged.
+const maxDistance = 2.0
+
+// updateDistances sets the matrix distances of cluster i to those of i
+// merged with j, by the Lance-Williams formula for the linkage, and
+// marks it conflicting with every cluster either conflicts with. It runs
+// before the members are merged.
+func (c *Clusterer) updateDistances(nodes []*clusterNode, i, j int, matrix *distanceMatrix) {
+	ni, nj := float64(len(nodes[i].members)), float64(len(nodes[j].members))
+	for k, node := range nodes {
+		if k == i || k == j || !node.active {
+			continue
+		}
+		ik, jk := matrix.index(i, k), matrix.index(j, k)
+		switch c.cfg.Linkage {
+		case "single":
+			matrix.dist[ik] = min(matrix.dist[ik], matrix.dist[jk])
+		case "complete":
+			matrix.dist[ik] = max(matrix.dist[ik], matrix.dist[jk])
+		default:
+			matrix.dist[ik] = (ni*matrix.dist[ik] + nj*matrix.dist[jk]) / (ni + nj)
+		}
+		if matrix.conflicts != nil && matrix.conflicts[jk] {
+			matrix.conflicts[ik] = true
+		}
+	}
 }
 
-// clusterDistance computes distance between two clusters based on linkage type.
-func (c *Clusterer) clusterDistance(a, b *clusterNode, chunks []types.Chunk, distMatrix [][]float64) float64 {
-	switch c.cfg.Linkage {
-	case "single":
-		// Minimum distance between any pair
-		minDist := float64(2.0)
-		for _, i := range a.members {
-			for _, j := range b.members {
-				if distMatrix[i][j] < minDist {
-					minDist = distMatrix[i][j]
-				}
-			}
+// nearestNeighbors holds, for each active cluster i, the closest
+// cluster at a higher index that i may merge with, and its distance.
+// Clusters with none have index -1 and distance maxDistance.
+type nearestNeighbors struct {
+	index []int
+	dist  []float64
+}
+
+func newNearestNeighbors(n int) *nearestNeighbors {
+	return &nearestNeighbors{index: make([]int, n), dist: make([]float64, n)}
+}
+
+// update finds cluster i's nearest neighbor by scanning its row.
+func (nn *nearestNeighbors) update(i int, nodes []*clusterNode, matrix *distanceMatrix) {
+	nn.index[i], nn.dist[i] = -1, maxDistance
+	for k := i + 1; k < len(nodes); k++ {
+		if !nodes[k].active {
+			continue
 		}
-		return minDist
-
-	case "complete":
-		// Maximum distance between any pair
-		maxDist := float64(0.0)
-		for _, i := range a.members {
-			for _, j := range b.members {
-				if distMatrix[i][j] > maxDist {
-					maxDist = distMatrix[i][j]
-				}
-			}
+		if d := matrix.at(i, k); d < nn.dist[i] && (matrix.conflicts == nil || !matrix.conflict(i, k)) {
+			nn.index[i], nn.dist[i] = k, d
 		}
-		return maxDist
-
-	case "average":
-		fallthrough
-	default:
-		// Average distance between all pairs
-		var sum float64
-		count := 0
-		for _, i := range a.members {
-			for _, j := range b.members {
-				sum += distMatrix[i][j]
-				count++
-			}
+	}
+}
+
+// merged updates the neighbors after j merged into i, i < j. Rows past
+// j never see either cluster. Rows whose neighbor was i or j are
+// rescanned, since i's distances may have grown; the rest only need
+// checking against i's new distance.
+func (nn *nearestNeighbors) merged(i, j int, nodes []*clusterNode, matrix *distanceMatrix) {
+	for r := 0; r < j; r++ {
+		if !nodes[r].active {
+			continue
 		}
-		if count == 0 {
-			return 2.0
+		switch {
+		case r == i || nn.index[r] == i || nn.index[r] == j:
+			nn.update(r, nodes, matrix)
+		case r < i:
+			d := matrix.at(r, i)
+			closer := d < nn.dist[r] || (d == nn.dist[r] && nn.index[r] >= 0 && i < nn.index[r])
+			if closer && (matrix.conflicts == nil || !matrix.conflict(r, i)) {
+				nn.index[r], nn.dist[r] = i, d
+			}
 		}
-		return sum / float64(count)
 	}
 }
 
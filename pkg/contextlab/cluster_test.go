@@ -0,0 +1,204 @@
+package contextlab
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/math"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// referenceCluster is exhaustive agglomerative clustering: each merge
+// scans every pair of active clusters and computes their linkage from
+// the member distances. It returns each cluster's member indices in the
+// order Clusterer reports them.
+func referenceCluster(cfg ClusterConfig, chunks []types.Chunk) [][]int {
+	n := len(chunks)
+	dist := func(i, j int) float64 {
+		return math.CosineDistance(chunks[i].Embedding, chunks[j].Embedding)
+	}
+	entities := make([][]string, n)
+	if cfg.Entities != nil {
+		for i := range chunks {
+			entities[i] = cfg.Entities.Entities(chunks[i].Text)
+		}
+	}
+
+	clusters := make([][]int, n)
+	for i := range clusters {
+		clusters[i] = []int{i}
+	}
+	linkage := func(a, b []int) float64 {
+		var d float64
+		switch cfg.Linkage {
+		case "single":
+			d = 2
+			for _, i := range a {
+				for _, j := range b {
+					d = min(d, dist(i, j))
+				}
+			}
+		case "complete":
+			for _, i := range a {
+				for _, j := range b {
+					d = max(d, dist(i, j))
+				}
+			}
+		default:
+			for _, i := range a {
+				for _, j := range b {
+					d += dist(i, j)
+				}
+			}
+			d /= float64(len(a) * len(b))
+		}
+		return d
+	}
+	conflict := func(a, b []int) bool {
+		for _, i := range a {
+			for _, j := range b {
+				if entitiesConflict(entities[i], entities[j]) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	active := n
+	for active > 1 && (cfg.MinClusters == 0 || active > cfg.MinClusters) {
+		best, bi, bj := 2.0, -1, -1
+		for i := range clusters {
+			for j := i + 1; j < n && clusters[i] != nil; j++ {
+				if clusters[j] == nil {
+					continue
+				}
+				if d := linkage(clusters[i], clusters[j]); d < best && !conflict(clusters[i], clusters[j]) {
+					best, bi, bj = d, i, j
+				}
+			}
+		}
+		if bi < 0 || best > cfg.Threshold {
+			break
+		}
+		clusters[bi] = append(clusters[bi], clusters[bj]...)
+		clusters[bj] = nil
+		active--
+		if cfg.MaxClusters > 0 && active <= cfg.MaxClusters {
+			break
+		}
+	}
+
+	var out [][]int
+	for _, members := range clusters {
+		if members != nil {
+			out = append(out, members)
+		}
+	}
+	return out
+}
+
+// letterEntities names one entity per chunk text, or none for "".
+type letterEntities struct{}
+
+func (letterEntities) Entities(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return []string{text}
+}
+
+// noisyTopicChunks returns n chunks around topics random directions,
+// with enough noise that clusters merge at many different distances.
+func noisyTopicChunks(rng *rand.Rand, n, topics, dim int, noise float64) []types.Chunk {
+	centers := make([][]float32, topics)
+	for t := range centers {
+		centers[t] = make([]float32, dim)
+		for d := range centers[t] {
+			centers[t][d] = float32(rng.NormFloat64())
+		}
+	}
+	chunks := make([]types.Chunk, n)
+	for i := range chunks {
+		emb := make([]float32, dim)
+		for d, v := range centers[rng.IntN(topics)] {
+			emb[d] = v + float32(rng.NormFloat64()*noise)
+		}
+		chunks[i] = types.Chunk{ID: fmt.Sprint(i), Embedding: emb}
+	}
+	return chunks
+}
+
+func TestCluster_MatchesReference(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	for trial := range 40 {
+		cfg := ClusterConfig{
+			Threshold: []float64{0.25, 0.4, 0.7, 1.2}[trial%4],
+			Linkage:   []string{"single", "complete", "average"}[trial%3],
+		}
+		switch trial % 5 {
+		case 1:
+			cfg.MinClusters = 3
+		case 2:
+			cfg.MaxClusters = 6
+		case 3:
+			cfg.Entities = letterEntities{}
+		}
+		chunks := noisyTopicChunks(rng, 20+rng.IntN(60), 2+rng.IntN(8), 16, 0.3+rng.Float64())
+		if cfg.Entities != nil {
+			for i := range chunks {
+				chunks[i].Text = []string{"", "", "x", "y"}[rng.IntN(4)]
+			}
+		}
+
+		want := referenceCluster(cfg, chunks)
+		result := NewClusterer(cfg).Cluster(chunks)
+		got := make([][]int, len(result.Clusters))
+		for k, cluster := range result.Clusters {
+			for _, m := range cluster.Members {
+				var idx int
+				_, _ = fmt.Sscan(m.ID, &idx)
+				got[k] = append(got[k], idx)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("trial %d (%+v): clusters\n%v\nwant\n%v", trial, cfg, got, want)
+		}
+	}
+}
+
+func TestCluster_Centroid(t *testing.T) {
+	chunks := []types.Chunk{
+		{ID: "a", Embedding: []float32{1, 0}},
+		{ID: "b", Embedding: []float32{0, 1}},
+		{ID: "c", Embedding: []float32{1, 0.1}},
+	}
+	result := NewClusterer(ClusterConfig{Threshold: 0.1}).Cluster(chunks)
+	if result.ClusterCount != 2 {
+		t.Fatalf("got %d clusters, want 2", result.ClusterCount)
+	}
+	if got, want := result.Clusters[0].Centroid, []float32{1, 0.05}; !reflect.DeepEqual(got, want) {
+		t.Errorf("merged centroid = %v, want %v", got, want)
+	}
+	if got := result.Clusters[1].Centroid; !reflect.DeepEqual(got, chunks[1].Embedding) {
+		t.Errorf("singleton centroid = %v, want its embedding", got)
+	}
+}
+
+// BenchmarkCluster_Linkage clusters retrieval-sized inputs: near-
+// duplicates around one topic per five chunks.
+func BenchmarkCluster_Linkage(b *testing.B) {
+	for _, n := range []int{500, 2000} {
+		chunks := noisyTopicChunks(rand.New(rand.NewPCG(1, 2)), n, n/5, 384, 0.15)
+		for _, linkage := range []string{"single", "complete", "average"} {
+			b.Run(fmt.Sprintf("%s/%d", linkage, n), func(b *testing.B) {
+				c := NewClusterer(ClusterConfig{Threshold: 0.15, Linkage: linkage})
+				for i := 0; i < b.N; i++ {
+					_ = c.Cluster(chunks)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package contextlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// budget is the time budget of a best-effort request; see
+// RetrievalRequest.Deadline.
+type budget struct {
+	end time.Time
+
+	// reserve is kept back from optional stages for assembling the
+	// result: redaction, the model's budget, and session recording.
+	reserve time.Duration
+}
+
+type budgetKey struct{}
+
+// withBudget bounds ctx by req.Deadline and marks it best-effort, so
+// optional stages fit themselves into what is left. Without a deadline
+// ctx is returned as is.
+func withBudget(ctx context.Context, req *types.RetrievalRequest) (context.Context, context.CancelFunc) {
+	if req.Deadline <= 0 {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, req.Deadline)
+	end, _ := ctx.Deadline()
+	return context.WithValue(ctx, budgetKey{}, budget{end: end, reserve: req.Deadline / 10}), cancel
+}
+
+// left returns the time optional stages may still use.
+func (bg budget) left() time.Duration {
+	return time.Until(bg.end) - bg.reserve
+}
+
+// latencies keeps a moving average of how long each stage takes, so a
+// best-effort request can tell whether one fits in its budget.
+type latencies struct {
+	mu  sync.Mutex
+	avg map[string]time.Duration
+}
+
+// latencyWeight is the weight of the newest sample in the average.
+const latencyWeight = 0.3
+
+func (l *latencies) observe(stage string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.avg == nil {
+		l.avg = make(map[string]time.Duration)
+	}
+	if prev, ok := l.avg[stage]; ok {
+		d = time.Duration(latencyWeight*float64(d) + (1-latencyWeight)*float64(prev))
+	}
+	l.avg[stage] = d
+}
+
+func (l *latencies) estimate(stage string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	d, ok := l.avg[stage]
+	return d, ok
+}
+
+// overFetch returns how many chunks to retrieve: topK when positive,
+// otherwise OverFetchK. A best-effort request whose retrieval usually
+// takes more than half its budget fetches proportionally fewer, down to
+// TargetK, leaving clustering and MMR less work.
+func (b *Broker) overFetch(ctx context.Context, topK int, stats *types.BrokerStats) int {
+	k := b.cfg.OverFetchK
+	if topK > 0 {
+		k = topK
+	}
+	bg, ok := ctx.Value(budgetKey{}).(budget)
+	if !ok {
+		return k
+	}
+	est, ok := b.latencies.estimate(PipelineRetrieve)
+	half := bg.left() / 2
+	if !ok || est <= half {
+		return k
+	}
+	reduced := max(b.cfg.TargetK, int(float64(k)*float64(max(half, 0))/float64(est)))
+	if reduced < k {
+		bestEffort(stats, fmt.Sprintf("over-fetch reduced from %d to %d: retrieval usually takes %s", k, reduced, round(est)))
+	}
+	return min(reduced, k)
+}
+
+// budgeted runs an optional stage. For a best-effort request the stage is
+// skipped, with a note in stats, when less of the budget is left than it
+// usually takes, and abandoned when it runs out of time; either way
+// budgeted reports false and the pipeline carries on without it. fn must
+// leave the pipeline unchanged when it fails.
+func (b *Broker) budgeted(ctx context.Context, stage string, stats *types.BrokerStats, fn func(ctx context.Context) error) (bool, error) {
+	bg, ok := ctx.Value(budgetKey{}).(budget)
+	if !ok {
+		start := time.Now()
+		if err := fn(ctx); err != nil {
+			return false, err
+		}
+		b.latencies.observe(stage, time.Since(start))
+		return true, nil
+	}
+
+	left := bg.left()
+	if est, ok := b.latencies.estimate(stage); left <= 0 || (ok && est > left) {
+		bestEffort(stats, fmt.Sprintf("%s skipped: %s of the deadline left", stage, round(max(left, 0))))
+		return false, nil
+	}
+	stageCtx, cancel := context.WithTimeout(ctx, left)
+	defer cancel()
+	start := time.Now()
+	err := fn(stageCtx)
+	elapsed := time.Since(start)
+	if err != nil && errors.Is(stageCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		// Count the abandoned run, so the next request skips the stage
+		b.latencies.observe(stage, elapsed)
+		bestEffort(stats, fmt.Sprintf("%s stopped at the deadline after %s", stage, round(elapsed)))
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	b.latencies.observe(stage, elapsed)
+	return true, nil
+}
+
+// bestEffort marks stats as cut short by the deadline.
+func bestEffort(stats *types.BrokerStats, note string) {
+	stats.BestEffort = true
+	stats.Notes = append(stats.Notes, note)
+}
+
+// round rounds d for notes.
+func round(d time.Duration) time.Duration {
+	if d >= time.Millisecond {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Microsecond)
+}
@@ -0,0 +1,108 @@
+package contextlab
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// blockingReranker waits for its context to end.
+type blockingReranker struct{}
+
+func (blockingReranker) Rerank(ctx context.Context, _ string, _ []string) ([]float64, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestBroker_DeadlineStopsSlowStage(t *testing.T) {
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: orthogonalChunks(4)},
+		WithTargetK(2),
+		WithReranker(blockingReranker{}),
+		WithStages(StageSpec{Name: PipelineRetrieve}, StageSpec{Name: PipelineRerank}),
+	)
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+
+	start := time.Now()
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		Query:          "q",
+		QueryEmbedding: []float32{1, 0, 0, 0},
+		Deadline:       200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("took %s, past the 200ms deadline", elapsed)
+	}
+	if got := chunkIDs(result.Chunks); got != "ab" {
+		t.Errorf("got chunks %q, want ab", got)
+	}
+	if !result.Stats.BestEffort || len(result.Stats.Notes) != 1 || !strings.HasPrefix(result.Stats.Notes[0], "rerank stopped") {
+		t.Errorf("best effort = %v, notes = %q, want rerank stopped", result.Stats.BestEffort, result.Stats.Notes)
+	}
+}
+
+func TestBroker_DeadlineSkipsStage(t *testing.T) {
+	broker := NewBroker(&stubRetriever{chunks: orthogonalChunks(4)}, BrokerConfig{TargetK: 2, EnableMMR: true, MMRLambda: 0.5})
+	broker.latencies.observe(PipelineMMR, time.Hour)
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1, 0, 0, 0},
+		Deadline:       time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if got := chunkIDs(result.Chunks); got != "ab" {
+		t.Errorf("got chunks %q, want ab", got)
+	}
+	if got := strings.Join(result.Stages, ","); got != "retrieve,cluster,select" {
+		t.Errorf("stages = %s, want retrieve,cluster,select", got)
+	}
+	if !result.Stats.BestEffort || len(result.Stats.Notes) != 1 || !strings.HasPrefix(result.Stats.Notes[0], "mmr skipped") {
+		t.Errorf("best effort = %v, notes = %q, want mmr skipped", result.Stats.BestEffort, result.Stats.Notes)
+	}
+}
+
+func TestBroker_DeadlineReducesOverFetch(t *testing.T) {
+	ret := &stubRetriever{chunks: orthogonalChunks(4)}
+	broker := NewBroker(ret, BrokerConfig{OverFetchK: 50, TargetK: 2})
+	broker.latencies.observe(PipelineRetrieve, 2*time.Second)
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1, 0, 0, 0},
+		Deadline:       time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if ret.last.TopK >= 50 || ret.last.TopK < 2 {
+		t.Errorf("fetched %d, want between target (2) and over-fetch (50)", ret.last.TopK)
+	}
+	if !result.Stats.BestEffort || len(result.Stats.Notes) != 1 || !strings.HasPrefix(result.Stats.Notes[0], "over-fetch reduced") {
+		t.Errorf("best effort = %v, notes = %q, want over-fetch reduced", result.Stats.BestEffort, result.Stats.Notes)
+	}
+}
+
+func TestBroker_NoDeadline(t *testing.T) {
+	ret := &stubRetriever{chunks: orthogonalChunks(4)}
+	broker := NewBroker(ret, BrokerConfig{OverFetchK: 50, TargetK: 2, EnableMMR: true, MMRLambda: 0.5})
+	broker.latencies.observe(PipelineRetrieve, time.Hour)
+	broker.latencies.observe(PipelineMMR, time.Hour)
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0, 0}})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if ret.last.TopK != 50 {
+		t.Errorf("fetched %d, want 50", ret.last.TopK)
+	}
+	if result.Stats.BestEffort || result.Stats.Notes != nil {
+		t.Errorf("best effort = %v, notes = %q, want neither", result.Stats.BestEffort, result.Stats.Notes)
+	}
+}
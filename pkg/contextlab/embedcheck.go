@@ -0,0 +1,254 @@
+package contextlab
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+// Embedding validation modes for caller-supplied embeddings.
+const (
+	// EmbeddingCheckReject fails the request if any embedding is suspect.
+	EmbeddingCheckReject = "reject"
+
+	// EmbeddingCheckRepair re-embeds suspect chunks from their text.
+	EmbeddingCheckRepair = "repair"
+)
+
+// ValidEmbeddingCheck reports whether mode is empty (no validation) or a
+// known validation mode.
+func ValidEmbeddingCheck(mode string) bool {
+	return mode == "" || mode == EmbeddingCheckReject || mode == EmbeddingCheckRepair
+}
+
+// EmbeddingProblem names why an embedding looks like it came from a
+// different model than the rest.
+type EmbeddingProblem string
+
+const (
+	// ProblemDimension is an embedding of the wrong length.
+	ProblemDimension EmbeddingProblem = "dimension"
+
+	// ProblemNonFinite is an embedding containing NaN or Inf.
+	ProblemNonFinite EmbeddingProblem = "non_finite"
+
+	// ProblemZero is an all-zero embedding.
+	ProblemZero EmbeddingProblem = "zero"
+
+	// ProblemNorm is an embedding whose length is far from the others'.
+	ProblemNorm EmbeddingProblem = "norm"
+
+	// ProblemDistribution is an embedding pointing away from the region
+	// the others share.
+	ProblemDistribution EmbeddingProblem = "distribution"
+)
+
+// EmbeddingCheckConfig tunes CheckEmbeddings.
+type EmbeddingCheckConfig struct {
+	// Dimension is the expected embedding length, typically the
+	// embedder's. If 0, the most common length in the input is used.
+	Dimension int
+
+	// NormRatio flags embeddings whose L2 norm is more than NormRatio
+	// times above or below the median norm.
+	NormRatio float64
+
+	// SharedSimilarity is the median similarity to the centroid above
+	// which the inputs are taken to share a model's embedding space.
+	// Below it the distribution check is skipped.
+	SharedSimilarity float64
+
+	// OutlierRatio flags embeddings whose similarity to the centroid of
+	// the others is below OutlierRatio times the median.
+	OutlierRatio float64
+}
+
+// DefaultEmbeddingCheckConfig returns thresholds that tolerate off-topic
+// chunks from the same model but catch vectors from another model.
+func DefaultEmbeddingCheckConfig() EmbeddingCheckConfig {
+	return EmbeddingCheckConfig{
+		NormRatio:        2,
+		SharedSimilarity: 0.2,
+		OutlierRatio:     0.25,
+	}
+}
+
+// EmbeddingIssue describes one suspect embedding.
+type EmbeddingIssue struct {
+	// Index is the position of the embedding in the checked slice.
+	Index   int
+	Problem EmbeddingProblem
+	Detail  string
+}
+
+// EmbeddingCheckError reports suspect embeddings that were not repaired.
+type EmbeddingCheckError struct {
+	Issues []EmbeddingIssue
+
+	// Names labels embeddings by index in messages, e.g. chunk IDs.
+	Names []string
+}
+
+func (e *EmbeddingCheckError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d embedding(s) failed validation", len(e.Issues))
+	for i, issue := range e.Issues {
+		if i == 5 {
+			fmt.Fprintf(&b, "; and %d more", len(e.Issues)-i)
+			break
+		}
+		name := fmt.Sprintf("#%d", issue.Index)
+		if issue.Index < len(e.Names) && e.Names[issue.Index] != "" {
+			name = fmt.Sprintf("%q", e.Names[issue.Index])
+		}
+		fmt.Fprintf(&b, "; %s: %s (%s)", name, issue.Problem, issue.Detail)
+	}
+	return b.String()
+}
+
+// Is makes errors.Is(err, errs.ErrConfig) match.
+func (e *EmbeddingCheckError) Is(target error) bool {
+	return target == errs.ErrConfig
+}
+
+// CheckEmbeddings looks for embeddings that were likely produced by a
+// different model: wrong dimension, NaN/Inf or zero values, a norm far
+// from the median, or (with at least three embeddings) a direction
+// unlike the rest. Empty embeddings are skipped. The distribution checks
+// are heuristics compared against the input itself, so they only catch a
+// minority of foreign vectors.
+func CheckEmbeddings(vectors [][]float32, cfg EmbeddingCheckConfig) []EmbeddingIssue {
+	dim := cfg.Dimension
+	if dim == 0 {
+		dim = commonDimension(vectors)
+	}
+
+	var issues []EmbeddingIssue
+	var valid []int
+	norms := make([]float64, len(vectors))
+	for i, v := range vectors {
+		if len(v) == 0 {
+			continue
+		}
+		if len(v) != dim {
+			issues = append(issues, EmbeddingIssue{i, ProblemDimension, fmt.Sprintf("got %d values, expected %d", len(v), dim)})
+			continue
+		}
+		var sumSq float64
+		finite := true
+		for _, x := range v {
+			f := float64(x)
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				finite = false
+				break
+			}
+			sumSq += f * f
+		}
+		switch {
+		case !finite:
+			issues = append(issues, EmbeddingIssue{i, ProblemNonFinite, "contains NaN or Inf"})
+		case sumSq == 0:
+			issues = append(issues, EmbeddingIssue{i, ProblemZero, "L2 norm is 0"})
+		default:
+			norms[i] = math.Sqrt(sumSq)
+			valid = append(valid, i)
+		}
+	}
+	if len(valid) < 3 {
+		return sortIssues(issues)
+	}
+
+	if cfg.NormRatio > 1 {
+		ns := make([]float64, len(valid))
+		for j, i := range valid {
+			ns[j] = norms[i]
+		}
+		med := median(ns)
+		kept := valid[:0:0]
+		for _, i := range valid {
+			if r := norms[i] / med; r > cfg.NormRatio || r < 1/cfg.NormRatio {
+				issues = append(issues, EmbeddingIssue{i, ProblemNorm, fmt.Sprintf("norm %.3g, median %.3g", norms[i], med)})
+				continue
+			}
+			kept = append(kept, i)
+		}
+		valid = kept
+	}
+	if len(valid) >= 3 && cfg.OutlierRatio > 0 {
+		issues = append(issues, distributionOutliers(vectors, norms, valid, dim, cfg)...)
+	}
+	return sortIssues(issues)
+}
+
+// distributionOutliers compares each embedding with the centroid of the
+// others. Embeddings from one model share a region of the space, so a
+// vector from another model is close to orthogonal to their centroid.
+func distributionOutliers(vectors [][]float32, norms []float64, valid []int, dim int, cfg EmbeddingCheckConfig) []EmbeddingIssue {
+	sum := make([]float64, dim)
+	for _, i := range valid {
+		for d, x := range vectors[i] {
+			sum[d] += float64(x) / norms[i]
+		}
+	}
+
+	sims := make([]float64, len(valid))
+	for j, i := range valid {
+		// Leave-one-out centroid: sum minus this unit vector
+		var dot, sumSq float64
+		for d, x := range vectors[i] {
+			u := float64(x) / norms[i]
+			c := sum[d] - u
+			dot += u * c
+			sumSq += c * c
+		}
+		if sumSq > 0 {
+			sims[j] = dot / math.Sqrt(sumSq)
+		}
+	}
+
+	med := median(append([]float64(nil), sims...))
+	if med < cfg.SharedSimilarity {
+		return nil
+	}
+	var issues []EmbeddingIssue
+	for j, i := range valid {
+		if sims[j] < med*cfg.OutlierRatio {
+			issues = append(issues, EmbeddingIssue{i, ProblemDistribution, fmt.Sprintf("similarity to the others %.2f, median %.2f", sims[j], med)})
+		}
+	}
+	return issues
+}
+
+// commonDimension returns the most common non-zero length, preferring the
+// one that reached the winning count first.
+func commonDimension(vectors [][]float32) int {
+	counts := make(map[int]int)
+	best, bestN := 0, 0
+	for _, v := range vectors {
+		if len(v) == 0 {
+			continue
+		}
+		counts[len(v)]++
+		if n := counts[len(v)]; n > bestN {
+			best, bestN = len(v), n
+		}
+	}
+	return best
+}
+
+func median(xs []float64) float64 {
+	sort.Float64s(xs)
+	n := len(xs)
+	if n%2 == 1 {
+		return xs[n/2]
+	}
+	return (xs[n/2-1] + xs[n/2]) / 2
+}
+
+func sortIssues(issues []EmbeddingIssue) []EmbeddingIssue {
+	sort.SliceStable(issues, func(a, b int) bool { return issues[a].Index < issues[b].Index })
+	return issues
+}
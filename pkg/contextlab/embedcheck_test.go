@@ -0,0 +1,117 @@
+package contextlab
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+// modelVectors returns n vectors sharing a common direction, the way one
+// model's embeddings cluster in a region of the space.
+func modelVectors(rng *rand.Rand, n, dim int, spread float64) [][]float32 {
+	base := make([]float64, dim)
+	for d := range base {
+		base[d] = rng.NormFloat64()
+	}
+	out := make([][]float32, n)
+	for i := range out {
+		v := make([]float32, dim)
+		for d := range v {
+			v[d] = float32(base[d] + spread*rng.NormFloat64())
+		}
+		out[i] = v
+	}
+	return out
+}
+
+func problems(issues []EmbeddingIssue) map[int]EmbeddingProblem {
+	out := make(map[int]EmbeddingProblem, len(issues))
+	for _, issue := range issues {
+		out[issue.Index] = issue.Problem
+	}
+	return out
+}
+
+func TestCheckEmbeddings_Basic(t *testing.T) {
+	vectors := [][]float32{
+		{1, 0, 0},
+		{0, 1, 0, 0},
+		{float32(math.NaN()), 0, 0},
+		{0, 0, 0},
+		nil,
+	}
+	got := problems(CheckEmbeddings(vectors, DefaultEmbeddingCheckConfig()))
+	want := map[int]EmbeddingProblem{1: ProblemDimension, 2: ProblemNonFinite, 3: ProblemZero}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i, p := range want {
+		if got[i] != p {
+			t.Errorf("vector %d: got %q, want %q", i, got[i], p)
+		}
+	}
+
+	// An expected dimension overrides the majority
+	cfg := DefaultEmbeddingCheckConfig()
+	cfg.Dimension = 4
+	got = problems(CheckEmbeddings([][]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1, 0}}, cfg))
+	if got[0] != ProblemDimension || got[1] != ProblemDimension || len(got) != 2 {
+		t.Errorf("expected the two 3-value vectors flagged, got %v", got)
+	}
+}
+
+func TestCheckEmbeddings_Distribution(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	vectors := modelVectors(rng, 10, 64, 0.8)
+
+	if issues := CheckEmbeddings(vectors, DefaultEmbeddingCheckConfig()); len(issues) != 0 {
+		t.Fatalf("same-model vectors flagged: %v", issues)
+	}
+
+	// A vector from another model has the right dimension but points
+	// somewhere else
+	foreign := modelVectors(rng, 1, 64, 0)[0]
+	scaled := make([]float32, 64)
+	for d, x := range vectors[0] {
+		scaled[d] = x * 10
+	}
+	vectors = append(vectors, foreign, scaled)
+
+	got := problems(CheckEmbeddings(vectors, DefaultEmbeddingCheckConfig()))
+	if got[10] != ProblemDistribution || got[11] != ProblemNorm || len(got) != 2 {
+		t.Errorf("expected foreign vector and scaled vector flagged, got %v", got)
+	}
+}
+
+func TestCheckEmbeddings_UnrelatedInputsSkipDistribution(t *testing.T) {
+	// Independent random unit-ish vectors share no region, so none of
+	// them can be singled out
+	rng := rand.New(rand.NewSource(2))
+	var vectors [][]float32
+	for i := 0; i < 8; i++ {
+		vectors = append(vectors, modelVectors(rng, 1, 64, 0)[0])
+	}
+	if issues := CheckEmbeddings(vectors, DefaultEmbeddingCheckConfig()); len(issues) != 0 {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+}
+
+func TestEmbeddingCheckError(t *testing.T) {
+	err := &EmbeddingCheckError{
+		Issues: []EmbeddingIssue{{Index: 1, Problem: ProblemDimension, Detail: "got 3 values, expected 4"}},
+		Names:  []string{"a", "b"},
+	}
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Error("expected errors.Is(err, errs.ErrConfig)")
+	}
+	if msg := err.Error(); !strings.Contains(msg, `"b": dimension`) {
+		t.Errorf("unexpected message: %s", msg)
+	}
+	if !ValidEmbeddingCheck("") || !ValidEmbeddingCheck(EmbeddingCheckRepair) || ValidEmbeddingCheck("fix") {
+		t.Error("unexpected ValidEmbeddingCheck result")
+	}
+}
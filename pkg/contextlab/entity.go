@@ -0,0 +1,152 @@
+package contextlab
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// EntityConfig configures the entity veto: chunks that name different
+// entities are never clustered together, however close their embeddings.
+// "Reset your Okta password" and "Reset your Google Workspace password"
+// embed almost identically but must not be collapsed into one.
+type EntityConfig struct {
+	// Enabled turns the veto on.
+	Enabled bool
+
+	// Terms is a vocabulary of entities to look for, matched as whole
+	// words, case-insensitively. When empty, key terms are extracted
+	// heuristically: capitalized words and phrases that do not start a
+	// sentence, acronyms, and words mixing letters and digits.
+	Terms []string
+}
+
+// EntityExtractor finds the entities a chunk of text mentions.
+type EntityExtractor interface {
+	// Entities returns the normalized entities in text, without repeats.
+	Entities(text string) []string
+}
+
+// NewEntityExtractor returns the extractor for cfg, or nil if the veto
+// is disabled.
+func NewEntityExtractor(cfg EntityConfig) EntityExtractor {
+	if !cfg.Enabled {
+		return nil
+	}
+	var alts []string
+	for _, t := range cfg.Terms {
+		if t = strings.TrimSpace(t); t != "" {
+			alts = append(alts, regexp.QuoteMeta(strings.Join(strings.Fields(t), " ")))
+		}
+	}
+	if len(alts) == 0 {
+		return keytermExtractor{}
+	}
+	// Longest first, so "Google Workspace" wins over "Google"
+	sort.Slice(alts, func(i, j int) bool { return len(alts[i]) > len(alts[j]) })
+	pattern := `(?i)\b(?:` + strings.Join(alts, "|") + `)\b`
+	return &termExtractor{re: regexp.MustCompile(strings.ReplaceAll(pattern, " ", `\s+`))}
+}
+
+// termExtractor matches a fixed vocabulary.
+type termExtractor struct {
+	re *regexp.Regexp
+}
+
+func (e *termExtractor) Entities(text string) []string {
+	var out []string
+	for _, m := range e.re.FindAllString(text, -1) {
+		out = appendEntity(out, strings.Join(strings.Fields(m), " "))
+	}
+	return out
+}
+
+// keytermExtractor is the heuristic extractor used without a vocabulary.
+type keytermExtractor struct{}
+
+func (keytermExtractor) Entities(text string) []string {
+	var out, phrase []string
+	flush := func() {
+		if len(phrase) > 0 {
+			out = appendEntity(out, strings.Join(phrase, " "))
+			phrase = phrase[:0]
+		}
+	}
+
+	sentenceStart := true
+	for _, field := range strings.Fields(text) {
+		word := strings.TrimFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		switch {
+		case word == "":
+		case isAcronym(word) || hasLetterAndDigit(word):
+			flush()
+			out = appendEntity(out, word)
+		case !sentenceStart && isCapitalized(word):
+			phrase = append(phrase, word)
+		default:
+			flush()
+		}
+
+		// A phrase ends at punctuation; a sentence at terminal punctuation
+		if word != field && !strings.HasSuffix(field, word) {
+			flush()
+		}
+		sentenceStart = strings.ContainsAny(field[len(field)-1:], ".!?:")
+	}
+	flush()
+	return out
+}
+
+// entitiesConflict reports whether two entity sets name different
+// things: both are non-empty and they share nothing. A chunk without
+// entities never conflicts, since there is nothing to compare.
+func entitiesConflict(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func appendEntity(out []string, entity string) []string {
+	entity = strings.ToLower(entity)
+	for _, e := range out {
+		if e == entity {
+			return out
+		}
+	}
+	return append(out, entity)
+}
+
+func isAcronym(word string) bool {
+	letters := 0
+	for _, r := range word {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters >= 2
+}
+
+func isCapitalized(word string) bool {
+	for _, r := range word {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+func hasLetterAndDigit(word string) bool {
+	return strings.IndexFunc(word, unicode.IsLetter) >= 0 && strings.IndexFunc(word, unicode.IsDigit) >= 0
+}
@@ -0,0 +1,68 @@
+package contextlab
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestKeytermExtractor(t *testing.T) {
+	ex := NewEntityExtractor(EntityConfig{Enabled: true})
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"To reset your password in Okta, open the admin console.", []string{"okta"}},
+		{"Reset a password for Google Workspace users.", []string{"google workspace"}},
+		{"Enable SSO for v2 of the API.", []string{"sso", "v2", "api"}},
+		{"Passwords expire after 90 days. Users must reset them.", nil},
+		{"Okta and Okta again, with okta.", []string{"okta"}},
+	}
+	for _, tt := range tests {
+		if got := ex.Entities(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Entities(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	if NewEntityExtractor(EntityConfig{Terms: []string{"Okta"}}) != nil {
+		t.Error("expected nil extractor when disabled")
+	}
+}
+
+func TestTermExtractor(t *testing.T) {
+	ex := NewEntityExtractor(EntityConfig{Enabled: true, Terms: []string{"google", "Google  Workspace", " ", "okta"}})
+	got := ex.Entities("Move users from OKTA to google\nworkspace; Googler is not a term.")
+	if want := []string{"okta", "google workspace"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Entities = %q, want %q", got, want)
+	}
+}
+
+func TestCluster_EntityVeto(t *testing.T) {
+	chunks := []types.Chunk{
+		{ID: "okta", Text: "How to reset a password in Okta.", Embedding: []float32{1, 0, 0}},
+		{ID: "gws", Text: "How to reset a password in Google Workspace.", Embedding: []float32{1, 0.05, 0}},
+		{ID: "okta-2", Text: "Steps to reset a password in Okta.", Embedding: []float32{1, 0, 0.05}},
+		{ID: "plain", Text: "how to reset a password", Embedding: []float32{1, 0.02, 0.02}},
+	}
+
+	plain := NewClusterer(ClusterConfig{Threshold: 0.1}).Cluster(chunks)
+	if plain.ClusterCount != 1 {
+		t.Fatalf("expected one cluster without the veto, got %d", plain.ClusterCount)
+	}
+
+	vetoed := NewClusterer(ClusterConfig{
+		Threshold: 0.1,
+		Entities:  NewEntityExtractor(EntityConfig{Enabled: true}),
+	}).Cluster(chunks)
+	if vetoed.ClusterCount != 2 {
+		t.Fatalf("expected two clusters with the veto, got %d", vetoed.ClusterCount)
+	}
+	if chunks[0].ClusterID != chunks[2].ClusterID || chunks[0].ClusterID == chunks[1].ClusterID {
+		t.Errorf("expected Okta chunks together and apart from Google Workspace, got IDs %d %d %d",
+			chunks[0].ClusterID, chunks[1].ClusterID, chunks[2].ClusterID)
+	}
+	if vetoed.Vetoed != 2 {
+		t.Errorf("expected 2 vetoed pairs, got %d", vetoed.Vetoed)
+	}
+}
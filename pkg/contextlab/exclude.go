@@ -0,0 +1,39 @@
+package contextlab
+
+import (
+	"github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// ExcludeChunks removes chunks whose ID or content hash appears in
+// exclude. Content hashes are cache.ChunkHash values; a full 64-character
+// SHA-256 hex digest of the trimmed text is also accepted. Returns the
+// remaining chunks and the number removed.
+func ExcludeChunks(chunks []types.Chunk, exclude []string) ([]types.Chunk, int) {
+	if len(exclude) == 0 || len(chunks) == 0 {
+		return chunks, 0
+	}
+
+	set := make(map[string]struct{}, len(exclude))
+	for _, e := range exclude {
+		if e == "" {
+			continue
+		}
+		set[e] = struct{}{}
+		if len(e) == 64 {
+			set[e[:32]] = struct{}{}
+		}
+	}
+
+	out := make([]types.Chunk, 0, len(chunks))
+	for _, c := range chunks {
+		if _, ok := set[c.ID]; ok {
+			continue
+		}
+		if _, ok := set[cache.ChunkHash(c)]; ok {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, len(chunks) - len(out)
+}
@@ -0,0 +1,100 @@
+package contextlab
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// MetaTransforms is the metadata key listing, in the order applied, the
+// transformations a chunk went through when RetrievalRequest.Explain is
+// set, e.g.
+//
+//	selected-from-cluster-3   the representative of cluster 3
+//	mmr-rank-2                ranked second by MMR
+//	rerank-rank-1             ranked first by the reranker
+//	compressed-extractive-42% 42% of its text removed by extractive compression
+//	redacted-2-email_address  two email addresses redacted
+const MetaTransforms = "explain_transforms"
+
+// Transforms returns the transformations recorded on c, including ones
+// that went through a JSON round trip, such as a cached result.
+func Transforms(c types.Chunk) []string {
+	switch v := c.Metadata[MetaTransforms].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// addTransform appends label to c's transforms. The metadata map is
+// copied before it is changed, since it may be shared with a cache or
+// retriever.
+func addTransform(c *types.Chunk, label string) {
+	metadata := make(map[string]interface{}, len(c.Metadata)+1)
+	for k, v := range c.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetaTransforms] = append(slices.Clip(Transforms(*c)), label)
+	c.Metadata = metadata
+}
+
+// explainSelected records the cluster each of chunks, the
+// representatives selected from clusters, stands for.
+func explainSelected(chunks []types.Chunk, clusters *types.ClusterResult) {
+	if clusters == nil {
+		return
+	}
+	ids := make(map[int]bool, len(clusters.Clusters))
+	for _, c := range clusters.Clusters {
+		ids[c.ID] = true
+	}
+	for i := range chunks {
+		if ids[chunks[i].ClusterID] {
+			addTransform(&chunks[i], fmt.Sprintf("selected-from-cluster-%d", chunks[i].ClusterID))
+		}
+	}
+}
+
+// explainRanks records each chunk's 1-based position after stage
+// reordered chunks.
+func explainRanks(chunks []types.Chunk, stage string) {
+	for i := range chunks {
+		addTransform(&chunks[i], fmt.Sprintf("%s-rank-%d", stage, i+1))
+	}
+}
+
+// explainCompressed records how much of each chunk's text compression
+// with method removed, comparing after with before by chunk ID. Chunks
+// compression left unchanged are not annotated. An empty method, such as
+// for a compressor set with WithCompression, is left out of the label.
+func explainCompressed(before, after []types.Chunk, method string) {
+	original := make(map[string]string, len(before))
+	for _, c := range before {
+		if _, ok := original[c.ID]; !ok {
+			original[c.ID] = c.Text
+		}
+	}
+	prefix := "compressed-"
+	if method != "" {
+		prefix += method + "-"
+	}
+	for i := range after {
+		text, ok := original[after[i].ID]
+		if !ok || text == "" || text == after[i].Text {
+			continue
+		}
+		pct := math.Round(100 * (1 - float64(len(after[i].Text))/float64(len(text))))
+		addTransform(&after[i], fmt.Sprintf("%s%.0f%%", prefix, max(pct, 0)))
+	}
+}
@@ -0,0 +1,110 @@
+package contextlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestExplainCompressed(t *testing.T) {
+	shared := map[string]interface{}{"source": "wiki"}
+	before := []types.Chunk{
+		{ID: "a", Text: strings.Repeat("x", 100), Metadata: shared},
+		{ID: "b", Text: "unchanged"},
+	}
+	after := []types.Chunk{
+		{ID: "a", Text: strings.Repeat("x", 58), Metadata: shared},
+		{ID: "b", Text: "unchanged"},
+	}
+
+	explainCompressed(before, after, "extractive")
+	if got := Transforms(after[0]); len(got) != 1 || got[0] != "compressed-extractive-42%" {
+		t.Errorf("transforms of a = %v", got)
+	}
+	if _, ok := shared[MetaTransforms]; ok {
+		t.Error("shared metadata map was modified")
+	}
+	if got := Transforms(after[1]); got != nil {
+		t.Errorf("unchanged chunk was annotated: %v", got)
+	}
+
+	explainCompressed(before, after, "")
+	if got := Transforms(after[0]); len(got) != 2 || got[1] != "compressed-42%" {
+		t.Errorf("transforms of a = %v", got)
+	}
+}
+
+func TestTransforms_JSON(t *testing.T) {
+	c := types.Chunk{ID: "a"}
+	addTransform(&c, "mmr-rank-1")
+	data, err := json.Marshal(c.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Metadata = nil
+	if err := json.Unmarshal(data, &c.Metadata); err != nil {
+		t.Fatal(err)
+	}
+	if got := Transforms(c); len(got) != 1 || got[0] != "mmr-rank-1" {
+		t.Errorf("Transforms after a JSON round trip = %v", got)
+	}
+}
+
+func TestBroker_Explain(t *testing.T) {
+	chunks := orthogonalChunks(4)
+	// b duplicates a
+	chunks[1].Embedding = chunks[0].Embedding
+	chunks[2].Text = "Reach alice@example.com or bob@example.com."
+
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: chunks},
+		WithTargetK(2),
+		WithOverFetchK(10),
+		WithStages(
+			StageSpec{Name: PipelineRetrieve},
+			StageSpec{Name: PipelineCluster},
+			StageSpec{Name: PipelineSelect},
+			StageSpec{Name: PipelineMMR},
+			StageSpec{Name: PipelineRedact, Params: map[string]interface{}{"levels": []string{"pii"}}},
+		),
+	)
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+	ctx := context.Background()
+	req := &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0, 0}}
+
+	plain, err := broker.Retrieve(ctx, req)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	for _, c := range plain.Chunks {
+		if got := Transforms(c); got != nil {
+			t.Fatalf("transforms added without explain: %v", got)
+		}
+	}
+
+	req.Explain = true
+	result, err := broker.Retrieve(ctx, req)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(result.Chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(result.Chunks))
+	}
+	for i, c := range result.Chunks {
+		got := Transforms(c)
+		if len(got) < 2 || !strings.HasPrefix(got[0], "selected-from-cluster-") || got[1] != fmt.Sprintf("mmr-rank-%d", i+1) {
+			t.Errorf("transforms of %s = %v", c.ID, got)
+		}
+		if c.ID == "c" && (len(got) != 3 || got[2] != "redacted-2-email_address") {
+			t.Errorf("transforms of c = %v", got)
+		}
+	}
+	if chunks[0].Metadata[MetaTransforms] != nil {
+		t.Error("explain changed the retriever's chunk")
+	}
+}
@@ -0,0 +1,169 @@
+package contextlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Fingerprint summarizes a sample of a namespace: the dimension of its
+// embeddings and how similar its chunks are to each other. Requests
+// that cannot match it are rejected up front; see WarmScan.
+type Fingerprint struct {
+	Namespace  string
+	SampleSize int
+	Dimension  int
+
+	// NearestScore is the distribution of cosine similarities from each
+	// sampled chunk to its nearest sampled neighbor, and MaxScore the
+	// highest similarity between two sampled chunks.
+	NearestScore Percentiles
+	MaxScore     float64
+
+	// SampledAt is when the sample was taken.
+	SampledAt time.Time
+}
+
+// FingerprintChunks fingerprints chunks sampled from namespace. Chunks
+// without embeddings are ignored; with fewer than two, only the
+// dimension is known and MaxScore is 0.
+func FingerprintChunks(namespace string, chunks []types.Chunk) Fingerprint {
+	fp := Fingerprint{Namespace: namespace, SampledAt: time.Now()}
+	var embedded []types.Chunk
+	for _, c := range chunks {
+		if len(c.Embedding) > 0 {
+			embedded = append(embedded, c)
+		}
+	}
+	fp.SampleSize = len(embedded)
+	if len(embedded) == 0 {
+		return fp
+	}
+	fp.Dimension = len(embedded[0].Embedding)
+	if len(embedded) < 2 {
+		return fp
+	}
+
+	p := ProfileChunks(embedded)
+	fp.NearestScore = Percentiles{
+		P10: 1 - p.NearestDistance.P90,
+		P50: 1 - p.NearestDistance.P50,
+		P90: 1 - p.NearestDistance.P10,
+	}
+	fp.MaxScore = 1 - p.nearest[0]
+	return fp
+}
+
+// WarmScan samples up to n chunks from each namespace, as Sample does,
+// and keeps their fingerprints to check later requests against. A
+// retriever.AllNamespaces entry is replaced by every namespace the
+// backend lists. Namespaces that return no chunks get no fingerprint.
+// A namespace that fails does not stop the others; the fingerprints
+// taken are returned with the failures joined.
+func (b *Broker) WarmScan(ctx context.Context, namespaces []string, n int) ([]Fingerprint, error) {
+	if i := slices.Index(namespaces, retriever.AllNamespaces); i >= 0 {
+		lister, ok := b.retriever.(retriever.NamespaceLister)
+		if !ok {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("the backend cannot list namespaces for %q", retriever.AllNamespaces))
+		}
+		names, err := lister.ListNamespaces(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing namespaces: %w", err)
+		}
+		expanded := slices.Delete(slices.Clone(namespaces), i, i+1)
+		for _, name := range names {
+			if !slices.Contains(expanded, name) {
+				expanded = append(expanded, name)
+			}
+		}
+		namespaces = expanded
+	}
+
+	var taken []Fingerprint
+	var failed []error
+	for _, ns := range namespaces {
+		chunks, err := b.Sample(ctx, ns, n, 0, 0)
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", namespaceLabel(ns), err))
+			continue
+		}
+		if len(chunks) == 0 {
+			continue
+		}
+		fp := FingerprintChunks(ns, chunks)
+		b.fingerprintMu.Lock()
+		if b.fingerprints == nil {
+			b.fingerprints = make(map[string]Fingerprint)
+		}
+		b.fingerprints[ns] = fp
+		b.fingerprintMu.Unlock()
+		taken = append(taken, fp)
+	}
+	return taken, errors.Join(failed...)
+}
+
+// Fingerprint returns the fingerprint WarmScan took of namespace.
+func (b *Broker) Fingerprint(namespace string) (Fingerprint, bool) {
+	b.fingerprintMu.RLock()
+	defer b.fingerprintMu.RUnlock()
+	fp, ok := b.fingerprints[namespace]
+	return fp, ok
+}
+
+// ForgetFingerprint drops namespace's fingerprint, for instance when the
+// default namespace is switched to another index.
+func (b *Broker) ForgetFingerprint(namespace string) {
+	b.fingerprintMu.Lock()
+	defer b.fingerprintMu.Unlock()
+	delete(b.fingerprints, namespace)
+}
+
+// checkFingerprints rejects a request that cannot match the namespaces
+// it searches: query vectors of another dimension than a namespace's
+// embeddings, which usually means another embedding model, or a
+// MinScore above any similarity seen between that namespace's chunks,
+// which would likely filter out every match. Namespaces without a
+// fingerprint are not checked.
+func (b *Broker) checkFingerprints(req *types.RetrievalRequest, namespaces []string) error {
+	if len(namespaces) == 0 {
+		namespaces = []string{req.Namespace}
+	}
+	vectors := req.QueryEmbeddings
+	if len(req.QueryEmbedding) > 0 {
+		vectors = append([][]float32{req.QueryEmbedding}, vectors...)
+	}
+
+	for _, ns := range namespaces {
+		fp, ok := b.Fingerprint(ns)
+		if !ok {
+			continue
+		}
+		for _, v := range vectors {
+			if len(v) != fp.Dimension {
+				return errs.Wrap(errs.ErrConfig, fmt.Errorf(
+					"query vector has %d dimensions but %s holds %d-dimensional embeddings; was the query embedded with a different model?",
+					len(v), namespaceLabel(ns), fp.Dimension))
+			}
+		}
+		if fp.SampleSize >= 2 && float64(req.MinScore) > fp.MaxScore {
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf(
+				"min_score %.3f is above the highest similarity (%.3f) between any two of %d chunks sampled from %s, so it would likely filter out every match",
+				req.MinScore, fp.MaxScore, fp.SampleSize, namespaceLabel(ns)))
+		}
+	}
+	return nil
+}
+
+// namespaceLabel names a namespace in an error message.
+func namespaceLabel(ns string) string {
+	if ns == "" {
+		return "the default namespace"
+	}
+	return fmt.Sprintf("namespace %q", ns)
+}
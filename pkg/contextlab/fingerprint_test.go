@@ -0,0 +1,83 @@
+package contextlab
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestFingerprintChunks(t *testing.T) {
+	chunks := []types.Chunk{
+		{ID: "a", Embedding: []float32{1, 0, 0}},
+		{ID: "b", Embedding: []float32{1, 0.1, 0}},
+		{ID: "c", Embedding: []float32{0, 0, 1}},
+		{ID: "text-only", Text: "no embedding"},
+	}
+	fp := FingerprintChunks("docs", chunks)
+	if fp.Namespace != "docs" || fp.SampleSize != 3 || fp.Dimension != 3 {
+		t.Fatalf("fingerprint = %+v", fp)
+	}
+	if fp.MaxScore < 0.99 || fp.MaxScore > 1 {
+		t.Errorf("MaxScore = %f, want a and b's similarity", fp.MaxScore)
+	}
+	if fp.NearestScore.P10 > fp.NearestScore.P90 {
+		t.Errorf("NearestScore out of order: %+v", fp.NearestScore)
+	}
+
+	one := FingerprintChunks("", chunks[:1])
+	if one.Dimension != 3 || one.MaxScore != 0 {
+		t.Errorf("single-chunk fingerprint = %+v", one)
+	}
+}
+
+func TestBroker_WarmScan(t *testing.T) {
+	b := newFakeBroker(t, DefaultBrokerConfig())
+	ctx := context.Background()
+
+	taken, err := b.WarmScan(ctx, []string{"", "docs"}, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(taken) != 2 {
+		t.Fatalf("took %d fingerprints, want 2", len(taken))
+	}
+	fp, ok := b.Fingerprint("docs")
+	if !ok || fp.SampleSize != 20 || fp.Dimension != b.embedder.Dimension() {
+		t.Fatalf("Fingerprint(docs) = %+v, %v", fp, ok)
+	}
+
+	// A vector of another model's dimension is rejected with a reason
+	_, err = b.Retrieve(ctx, &types.RetrievalRequest{QueryEmbedding: make([]float32, fp.Dimension+1), Namespace: "docs"})
+	if !errors.Is(err, errs.ErrConfig) || !strings.Contains(err.Error(), "different model") {
+		t.Errorf("wrong-dimension query = %v", err)
+	}
+
+	// So is a min_score no sampled pair reaches
+	_, err = b.Retrieve(ctx, &types.RetrievalRequest{Query: "refunds", Namespace: "docs", MinScore: float32(fp.MaxScore) + 0.001})
+	if !errors.Is(err, errs.ErrConfig) || !strings.Contains(err.Error(), "min_score") {
+		t.Errorf("unreachable min_score = %v", err)
+	}
+
+	// Namespaces without a fingerprint are not checked
+	if _, err := b.Retrieve(ctx, &types.RetrievalRequest{Query: "refunds", Namespace: "other"}); err != nil {
+		t.Errorf("unscanned namespace: %v", err)
+	}
+	if _, err := b.Retrieve(ctx, &types.RetrievalRequest{Query: "refunds", Namespace: "docs"}); err != nil {
+		t.Errorf("matching query: %v", err)
+	}
+}
+
+func TestBroker_WarmScanErrors(t *testing.T) {
+	b := NewBroker(&stubRetriever{}, DefaultBrokerConfig())
+	taken, err := b.WarmScan(context.Background(), []string{"docs"}, 20)
+	if len(taken) != 0 || !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("WarmScan without embedder = %v, %v", taken, err)
+	}
+	if _, err := b.WarmScan(context.Background(), []string{"*"}, 20); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("WarmScan(*) without a lister = %v", err)
+	}
+}
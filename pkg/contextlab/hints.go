@@ -0,0 +1,84 @@
+package contextlab
+
+import (
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Metadata keys set by AnnotateDedup.
+const (
+	// HintClusterSize is the number of retrieved chunks in the cluster the
+	// chunk represents, itself included.
+	HintClusterSize = "dedup_cluster_size"
+
+	// HintDuplicatesRemoved is the number of those chunks left out.
+	HintDuplicatesRemoved = "dedup_duplicates_removed"
+
+	// HintRepresentativeReason says why the chunk was picked; see the
+	// Reason constants.
+	HintRepresentativeReason = "dedup_representative_reason"
+
+	// HintWouldKeep is set in passthrough mode: true when deduplication
+	// would have kept the chunk as its cluster's representative.
+	HintWouldKeep = "dedup_would_keep"
+)
+
+// Representative reasons.
+const (
+	ReasonUnique   = "unique"
+	ReasonScore    = "highest_score"
+	ReasonCentroid = "closest_to_centroid"
+	ReasonLength   = "longest_text"
+	ReasonHybrid   = "best_hybrid_score"
+)
+
+// RepresentativeReason says why strategy picks a cluster's representative,
+// matching the fallbacks Selector makes.
+func RepresentativeReason(strategy SelectionStrategy, cluster *types.Cluster) string {
+	if cluster == nil || len(cluster.Members) <= 1 {
+		return ReasonUnique
+	}
+	switch strategy {
+	case SelectByCentroid:
+		if len(cluster.Centroid) > 0 {
+			return ReasonCentroid
+		}
+	case SelectByLength:
+		return ReasonLength
+	case SelectByHybrid:
+		if len(cluster.Centroid) > 0 {
+			return ReasonHybrid
+		}
+	}
+	return ReasonScore
+}
+
+// AnnotateDedup adds dedup hints to the metadata of chunks, the
+// representatives selected from clusters with strategy, so rerankers and
+// prompts can weigh a chunk that stands for many near-identical sources.
+// Metadata maps are copied before they are changed, since they may be
+// shared with a cache or retriever. Chunks not from clusters are left
+// unchanged.
+func AnnotateDedup(chunks []types.Chunk, clusters *types.ClusterResult, strategy SelectionStrategy) {
+	if clusters == nil {
+		return
+	}
+	byID := make(map[int]*types.Cluster, len(clusters.Clusters))
+	for i := range clusters.Clusters {
+		byID[clusters.Clusters[i].ID] = &clusters.Clusters[i]
+	}
+
+	for i := range chunks {
+		cluster, ok := byID[chunks[i].ClusterID]
+		if !ok {
+			continue
+		}
+		metadata := make(map[string]interface{}, len(chunks[i].Metadata)+3)
+		for k, v := range chunks[i].Metadata {
+			metadata[k] = v
+		}
+		metadata[HintClusterSize] = cluster.Size()
+		metadata[HintDuplicatesRemoved] = cluster.Size() - 1
+		metadata[HintRepresentativeReason] = RepresentativeReason(strategy, cluster)
+		chunks[i].Metadata = metadata
+	}
+}
@@ -0,0 +1,94 @@
+package contextlab
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestRepresentativeReason(t *testing.T) {
+	single := &types.Cluster{Members: []types.Chunk{{ID: "a"}}}
+	pair := &types.Cluster{Members: []types.Chunk{{ID: "a"}, {ID: "b"}}}
+	withCentroid := &types.Cluster{Members: pair.Members, Centroid: []float32{1, 0}}
+
+	tests := []struct {
+		strategy SelectionStrategy
+		cluster  *types.Cluster
+		want     string
+	}{
+		{SelectByCentroid, single, ReasonUnique},
+		{SelectByScore, pair, ReasonScore},
+		{SelectByCentroid, withCentroid, ReasonCentroid},
+		{SelectByCentroid, pair, ReasonScore}, // no centroid to compare against
+		{SelectByLength, pair, ReasonLength},
+		{SelectByHybrid, withCentroid, ReasonHybrid},
+		{"", pair, ReasonScore},
+	}
+	for _, tt := range tests {
+		if got := RepresentativeReason(tt.strategy, tt.cluster); got != tt.want {
+			t.Errorf("RepresentativeReason(%q, %d members) = %q, want %q", tt.strategy, len(tt.cluster.Members), got, tt.want)
+		}
+	}
+}
+
+func TestAnnotateDedup(t *testing.T) {
+	shared := map[string]interface{}{"source": "wiki"}
+	clusters := &types.ClusterResult{Clusters: []types.Cluster{
+		{ID: 0, Members: []types.Chunk{{ID: "a"}, {ID: "b"}, {ID: "c"}}},
+		{ID: 1, Members: []types.Chunk{{ID: "d"}}},
+	}}
+	chunks := []types.Chunk{
+		{ID: "a", ClusterID: 0, Metadata: shared},
+		{ID: "d", ClusterID: 1},
+		{ID: "prefix", ClusterID: -1},
+	}
+
+	AnnotateDedup(chunks, clusters, SelectByScore)
+
+	a := chunks[0].Metadata
+	if a[HintClusterSize] != 3 || a[HintDuplicatesRemoved] != 2 || a[HintRepresentativeReason] != ReasonScore || a["source"] != "wiki" {
+		t.Errorf("unexpected hints for a: %v", a)
+	}
+	if _, ok := shared[HintClusterSize]; ok {
+		t.Error("shared metadata map was modified")
+	}
+	d := chunks[1].Metadata
+	if d[HintClusterSize] != 1 || d[HintDuplicatesRemoved] != 0 || d[HintRepresentativeReason] != ReasonUnique {
+		t.Errorf("unexpected hints for d: %v", d)
+	}
+	if chunks[2].Metadata != nil {
+		t.Errorf("unclustered chunk was annotated: %v", chunks[2].Metadata)
+	}
+}
+
+func TestBroker_DedupHints(t *testing.T) {
+	broker := newFakeBroker(t, BrokerConfig{OverFetchK: 100, TargetK: 5})
+	ctx := context.Background()
+
+	plain, err := broker.Retrieve(ctx, &types.RetrievalRequest{Query: "database replicas"})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	for _, c := range plain.Chunks {
+		if _, ok := c.Metadata[HintClusterSize]; ok {
+			t.Fatal("hints added without being asked for")
+		}
+	}
+
+	result, err := broker.Retrieve(ctx, &types.RetrievalRequest{Query: "database replicas", DedupHints: true})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	removed := 0
+	for _, c := range result.Chunks {
+		size, ok := c.Metadata[HintClusterSize].(int)
+		if !ok || size < 1 || c.Metadata[HintDuplicatesRemoved] != size-1 {
+			t.Fatalf("bad hints on %s: %v", c.ID, c.Metadata)
+		}
+		removed += size - 1
+	}
+	if removed == 0 {
+		t.Error("expected the fake corpus to have near-duplicates")
+	}
+}
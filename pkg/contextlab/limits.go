@@ -0,0 +1,82 @@
+package contextlab
+
+import (
+	"fmt"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// ErrInputTooLarge matches every *LimitError under errors.Is.
+var ErrInputTooLarge = errs.New(errs.ErrConfig, "input too large")
+
+// Limits caps the size of a clustering input. Clustering builds an n×n
+// distance matrix, so an unbounded chunk count can exhaust memory and
+// stall the server. Zero disables a limit.
+type Limits struct {
+	// MaxChunks is the maximum number of chunks per request.
+	MaxChunks int
+
+	// MaxDimension is the maximum embedding dimension.
+	MaxDimension int
+
+	// MaxInputBytes caps the total size of chunk text plus embeddings
+	// (4 bytes per value).
+	MaxInputBytes int64
+}
+
+// DefaultLimits returns limits that keep the distance matrix around 16MB.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxChunks:     2000,
+		MaxDimension:  4096,
+		MaxInputBytes: 16 << 20,
+	}
+}
+
+// Limit names reported in LimitError.Limit and used as metric labels.
+const (
+	LimitMaxChunks     = "max_chunks"
+	LimitMaxDimension  = "max_dimension"
+	LimitMaxInputBytes = "max_input_bytes"
+)
+
+// LimitError reports which limit an input exceeded.
+type LimitError struct {
+	Limit string
+	Value int64
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("input too large: %s is %d, limit is %d", e.Limit, e.Value, e.Max)
+}
+
+// Is makes errors.Is(err, ErrInputTooLarge) and errors.Is(err, errs.ErrConfig) match.
+func (e *LimitError) Is(target error) bool {
+	return target == ErrInputTooLarge || target == errs.ErrConfig
+}
+
+// Check returns a *LimitError if chunks exceed any limit.
+func (l Limits) Check(chunks []types.Chunk) error {
+	if l.MaxChunks > 0 && len(chunks) > l.MaxChunks {
+		return &LimitError{Limit: LimitMaxChunks, Value: int64(len(chunks)), Max: int64(l.MaxChunks)}
+	}
+
+	var total int64
+	for _, c := range chunks {
+		if l.MaxDimension > 0 && len(c.Embedding) > l.MaxDimension {
+			return &LimitError{Limit: LimitMaxDimension, Value: int64(len(c.Embedding)), Max: int64(l.MaxDimension)}
+		}
+		total += InputBytes(c)
+	}
+	if l.MaxInputBytes > 0 && total > l.MaxInputBytes {
+		return &LimitError{Limit: LimitMaxInputBytes, Value: total, Max: l.MaxInputBytes}
+	}
+	return nil
+}
+
+// InputBytes is the size of a chunk as counted against MaxInputBytes.
+func InputBytes(c types.Chunk) int64 {
+	return int64(len(c.Text)) + 4*int64(len(c.Embedding))
+}
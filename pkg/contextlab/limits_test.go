@@ -0,0 +1,69 @@
+package contextlab
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestLimits_Check(t *testing.T) {
+	chunks := orthogonalChunks(4)
+
+	tests := []struct {
+		name   string
+		limits Limits
+		want   string
+	}{
+		{"unlimited", Limits{}, ""},
+		{"within", Limits{MaxChunks: 4, MaxDimension: 4, MaxInputBytes: 1 << 10}, ""},
+		{"chunks", Limits{MaxChunks: 3}, LimitMaxChunks},
+		{"dimension", Limits{MaxDimension: 3}, LimitMaxDimension},
+		{"bytes", Limits{MaxInputBytes: 50}, LimitMaxInputBytes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Check(chunks)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var limErr *LimitError
+			if !errors.As(err, &limErr) || limErr.Limit != tt.want {
+				t.Fatalf("expected %s LimitError, got %v", tt.want, err)
+			}
+			if !errors.Is(err, ErrInputTooLarge) || !errors.Is(err, errs.ErrConfig) {
+				t.Error("LimitError should match ErrInputTooLarge and ErrConfig")
+			}
+		})
+	}
+}
+
+func TestBroker_WithLimits(t *testing.T) {
+	ret := &stubRetriever{chunks: orthogonalChunks(4)}
+
+	if _, err := NewBrokerWithOptions(ret, WithLimits(Limits{MaxChunks: 10})); err == nil ||
+		!strings.Contains(err.Error(), "max_chunks") {
+		t.Fatalf("expected over_fetch_k vs max_chunks error, got %v", err)
+	}
+
+	broker, err := NewBrokerWithOptions(ret,
+		WithOverFetchK(10), WithTargetK(2),
+		WithLimits(Limits{MaxChunks: 10, MaxDimension: 3}),
+	)
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+
+	_, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0, 0}})
+	if !errors.Is(err, ErrInputTooLarge) {
+		t.Fatalf("expected ErrInputTooLarge, got %v", err)
+	}
+}
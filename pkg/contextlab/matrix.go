@@ -0,0 +1,198 @@
+package contextlab
+
+import (
+	"context"
+	"errors"
+	stdmath "math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Siddhant-K-code/distill/pkg/math"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Matrix overflow policies for MatrixBudget.Overflow.
+const (
+	// OverflowSpill keeps exact clustering and moves the distance matrix
+	// to a memory-mapped temp file, which the kernel can page out.
+	OverflowSpill = "spill"
+
+	// OverflowWindow clusters windows of chunks that fit the budget, then
+	// clusters the windows' centroids. Results approximate exact
+	// clustering.
+	OverflowWindow = "window"
+)
+
+// minWindow is the smallest window OverflowWindow clusters, however low
+// MaxBytes is; its matrix is a few kilobytes.
+const minWindow = 64
+
+// errSpillUnsupported is returned by mapSpill where memory-mapped files
+// are unavailable; clustering then falls back to OverflowWindow.
+var errSpillUnsupported = errors.New("spilling the distance matrix is not supported on this platform")
+
+// MatrixBudget caps the memory clustering holds for pairwise distances.
+// The matrix grows with the square of the chunk count, so without a cap a
+// single large request can exceed a container's memory limit. Inputs
+// whose matrix would exceed MaxBytes are clustered per Overflow instead
+// of in memory. Zero MaxBytes disables the cap.
+type MatrixBudget struct {
+	// MaxBytes is the largest matrix held in memory; see MatrixBytes.
+	MaxBytes int64
+
+	// Overflow is OverflowSpill (default) or OverflowWindow.
+	Overflow string
+
+	// SpillDir holds spill files. Default: os.TempDir()
+	SpillDir string
+}
+
+// MatrixBytes estimates the memory clustering n chunks takes: 8 bytes per
+// chunk pair for the distance, plus 1 for the entity conflict when the
+// entity veto is on.
+func MatrixBytes(n int, entities bool) int64 {
+	per := int64(8)
+	if entities {
+		per++
+	}
+	return pairs(n) * per
+}
+
+// exceeded reports whether clustering n chunks is over the budget.
+func (b MatrixBudget) exceeded(n int, entities bool) bool {
+	return b.MaxBytes > 0 && MatrixBytes(n, entities) > b.MaxBytes
+}
+
+func (b MatrixBudget) overflow() string {
+	if b.Overflow == "" {
+		return OverflowSpill
+	}
+	return b.Overflow
+}
+
+// window returns the most chunks whose matrix fits the budget.
+func (b MatrixBudget) window(entities bool) int {
+	per := float64(MatrixBytes(2, entities))
+	w := int((1 + stdmath.Sqrt(1+8*float64(b.MaxBytes)/per)) / 2)
+	for w > 2 && MatrixBytes(w, entities) > b.MaxBytes {
+		w--
+	}
+	return max(w, minWindow)
+}
+
+func pairs(n int) int64 {
+	return int64(n) * int64(n-1) / 2
+}
+
+// distanceMatrix holds the distance and entity conflict of every chunk
+// pair, condensed to the upper triangle. Clustering overwrites them with
+// those of the clusters the chunks merge into. Its storage is either on
+// the heap or in a memory-mapped spill file.
+type distanceMatrix struct {
+	n         int
+	dist      []float64
+	conflicts []bool // nil without an entity veto
+	release   func() error
+	spilled   bool
+}
+
+// newDistanceMatrix allocates a matrix for n chunks, spilled to a file
+// under dir when spill is set.
+func newDistanceMatrix(n int, entities, spill bool, dir string) (*distanceMatrix, error) {
+	m := &distanceMatrix{n: n, release: func() error { return nil }}
+	size := pairs(n)
+	if spill {
+		dist, conflicts, release, err := mapSpill(dir, size, entities)
+		if err != nil {
+			return nil, err
+		}
+		m.dist, m.conflicts, m.release, m.spilled = dist, conflicts, release, true
+		return m, nil
+	}
+	m.dist = make([]float64, size)
+	if entities {
+		m.conflicts = make([]bool, size)
+	}
+	return m, nil
+}
+
+// index returns the position of pair (i, j), i != j, in the condensed
+// upper triangle.
+func (m *distanceMatrix) index(i, j int) int {
+	if i > j {
+		i, j = j, i
+	}
+	return i*m.n - i*(i+1)/2 + j - i - 1
+}
+
+func (m *distanceMatrix) at(i, j int) float64 {
+	return m.dist[m.index(i, j)]
+}
+
+func (m *distanceMatrix) conflict(i, j int) bool {
+	return m.conflicts[m.index(i, j)]
+}
+
+// close frees the matrix, unmapping and deleting a spill file.
+func (m *distanceMatrix) close() error {
+	return m.release()
+}
+
+// fillParallelMin is the fewest pairs fill splits across workers; below
+// it, starting them costs more than they save.
+const fillParallelMin = 1 << 14
+
+// fill computes pairwise cosine distances with up to workers goroutines,
+// 0 meaning GOMAXPROCS. Workers take rows in order, so the long early
+// rows do not all land on one. ctx is checked once per row.
+func (m *distanceMatrix) fill(ctx context.Context, chunks []types.Chunk, workers int) error {
+	norms := squaredNorms(ctx, chunks)
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers == 1 || pairs(m.n) < fillParallelMin {
+		for i := 0; i < m.n; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			m.fillRow(i, chunks, norms)
+		}
+		return nil
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(workers, m.n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1)) - 1
+				if i >= m.n {
+					return
+				}
+				m.fillRow(i, chunks, norms)
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// fillRow computes the distances from chunk i to the chunks after it.
+func (m *distanceMatrix) fillRow(i int, chunks []types.Chunk, norms []float64) {
+	if i+1 >= m.n {
+		return
+	}
+	k := m.index(i, i+1)
+	for j := i + 1; j < m.n; j++ {
+		// Handle missing embeddings gracefully
+		if len(chunks[i].Embedding) == 0 || len(chunks[j].Embedding) == 0 {
+			m.dist[k] = 2.0 // Max distance
+		} else {
+			m.dist[k] = math.CosineDistanceNorms(chunks[i].Embedding, chunks[j].Embedding, norms[i], norms[j])
+		}
+		k++
+	}
+}
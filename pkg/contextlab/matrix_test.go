@@ -0,0 +1,180 @@
+package contextlab
+
+import (
+	"context"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// topicChunks returns n chunks spread over topics well-separated
+// directions, with small noise. Chunk i belongs to topic i % topics.
+func topicChunks(n, topics int) []types.Chunk {
+	rng := rand.New(rand.NewPCG(1, 2))
+	const dim = 32
+	chunks := make([]types.Chunk, n)
+	for i := range chunks {
+		emb := make([]float32, dim)
+		emb[i%topics] = 1
+		for d := range emb {
+			emb[d] += float32(rng.Float64()-0.5) * 0.05
+		}
+		chunks[i] = types.Chunk{ID: string(rune('a' + i%topics)), Embedding: emb}
+	}
+	return chunks
+}
+
+// assertTopics checks that every cluster holds exactly one topic.
+func assertTopics(t *testing.T, result *types.ClusterResult, topics int) {
+	t.Helper()
+	if result.ClusterCount != topics {
+		t.Fatalf("got %d clusters, want %d", result.ClusterCount, topics)
+	}
+	for _, cluster := range result.Clusters {
+		for _, m := range cluster.Members {
+			if m.ID != cluster.Members[0].ID {
+				t.Fatalf("cluster %d mixes topics %q and %q", cluster.ID, cluster.Members[0].ID, m.ID)
+			}
+		}
+	}
+}
+
+func TestMatrixBytes(t *testing.T) {
+	if got := MatrixBytes(2000, false); got != 2000*1999/2*8 {
+		t.Errorf("MatrixBytes(2000) = %d", got)
+	}
+	if got := MatrixBytes(2000, true); got != 2000*1999/2*9 {
+		t.Errorf("MatrixBytes(2000, entities) = %d", got)
+	}
+	if got := MatrixBytes(1, true); got != 0 {
+		t.Errorf("MatrixBytes(1) = %d, want 0", got)
+	}
+}
+
+func TestMatrixBudget_Window(t *testing.T) {
+	b := MatrixBudget{MaxBytes: 1 << 20}
+	w := b.window(false)
+	if MatrixBytes(w, false) > b.MaxBytes || MatrixBytes(w+1, false) <= b.MaxBytes {
+		t.Errorf("window %d is not the largest fitting 1MiB", w)
+	}
+	if w := (MatrixBudget{MaxBytes: 1}).window(true); w != minWindow {
+		t.Errorf("window = %d, want the minimum %d", w, minWindow)
+	}
+}
+
+func TestDistanceMatrix_Workers(t *testing.T) {
+	chunks := topicChunks(300, 7)
+	chunks[5].Embedding = nil
+	serial, _ := newDistanceMatrix(len(chunks), false, false, "")
+	if err := serial.fill(context.Background(), chunks, 1); err != nil {
+		t.Fatal(err)
+	}
+	parallel, _ := newDistanceMatrix(len(chunks), false, false, "")
+	if err := parallel.fill(context.Background(), chunks, 4); err != nil {
+		t.Fatal(err)
+	}
+	for k := range serial.dist {
+		if serial.dist[k] != parallel.dist[k] {
+			t.Fatalf("pair %d: %v with 4 workers, %v with 1", k, parallel.dist[k], serial.dist[k])
+		}
+	}
+	if d := parallel.at(5, 6); d != 2 {
+		t.Errorf("distance to a chunk without an embedding = %v, want 2", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := parallel.fill(ctx, chunks, 4); err != context.Canceled {
+		t.Errorf("fill with a canceled context = %v", err)
+	}
+}
+
+func TestCluster_Spill(t *testing.T) {
+	chunks := topicChunks(200, 7)
+	want := NewClusterer(ClusterConfig{Threshold: 0.1}).Cluster(chunks)
+
+	dir := t.TempDir()
+	got := NewClusterer(ClusterConfig{
+		Threshold: 0.1,
+		Matrix:    MatrixBudget{MaxBytes: 1024, SpillDir: dir},
+	}).Cluster(topicChunks(200, 7))
+
+	if got.Overflow != OverflowSpill {
+		t.Fatalf("Overflow = %q, want spill", got.Overflow)
+	}
+	if want.Overflow != "" {
+		t.Errorf("unbudgeted Overflow = %q, want empty", want.Overflow)
+	}
+	assertTopics(t, got, 7)
+	for i := range want.Clusters {
+		if len(want.Clusters[i].Members) != len(got.Clusters[i].Members) {
+			t.Fatalf("cluster %d has %d members spilled, %d in memory", i, len(got.Clusters[i].Members), len(want.Clusters[i].Members))
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("spill dir holds %d files after clustering", len(entries))
+	}
+}
+
+func TestCluster_SpillEntityVeto(t *testing.T) {
+	chunks := []types.Chunk{
+		{ID: "okta", Text: "How to reset a password in Okta.", Embedding: []float32{1, 0, 0}},
+		{ID: "gws", Text: "How to reset a password in Google Workspace.", Embedding: []float32{1, 0.05, 0}},
+		{ID: "okta-2", Text: "Steps to reset a password in Okta.", Embedding: []float32{1, 0, 0.05}},
+	}
+	result := NewClusterer(ClusterConfig{
+		Threshold: 0.1,
+		Entities:  NewEntityExtractor(EntityConfig{Enabled: true}),
+		Matrix:    MatrixBudget{MaxBytes: 1, SpillDir: t.TempDir()},
+	}).Cluster(chunks)
+	if result.Overflow != OverflowSpill {
+		t.Fatalf("Overflow = %q, want spill", result.Overflow)
+	}
+	if result.ClusterCount != 2 || result.Vetoed != 2 {
+		t.Errorf("got %d clusters and %d vetoed, want 2 and 2", result.ClusterCount, result.Vetoed)
+	}
+}
+
+func TestCluster_Window(t *testing.T) {
+	chunks := topicChunks(500, 5)
+	result := NewClusterer(ClusterConfig{
+		Threshold: 0.1,
+		Matrix:    MatrixBudget{MaxBytes: 1, Overflow: OverflowWindow},
+	}).Cluster(chunks)
+
+	if result.Overflow != OverflowWindow {
+		t.Fatalf("Overflow = %q, want window", result.Overflow)
+	}
+	assertTopics(t, result, 5)
+	members := 0
+	for _, cluster := range result.Clusters {
+		members += len(cluster.Members)
+		for _, m := range cluster.Members {
+			if m.ClusterID != cluster.ID {
+				t.Fatalf("member ClusterID %d in cluster %d", m.ClusterID, cluster.ID)
+			}
+		}
+	}
+	if members != len(chunks) {
+		t.Errorf("clusters hold %d chunks, want %d", members, len(chunks))
+	}
+}
+
+func TestCluster_SpillDirMissingFallsBackToWindow(t *testing.T) {
+	result := NewClusterer(ClusterConfig{
+		Threshold: 0.1,
+		Matrix:    MatrixBudget{MaxBytes: 1, SpillDir: filepath.Join(t.TempDir(), "missing")},
+	}).Cluster(topicChunks(100, 4))
+	if result.Overflow != OverflowWindow {
+		t.Fatalf("Overflow = %q, want window", result.Overflow)
+	}
+	assertTopics(t, result, 4)
+}
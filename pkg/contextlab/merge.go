@@ -0,0 +1,61 @@
+package contextlab
+
+import (
+	"fmt"
+
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// MetaMergedFrom lists the IDs of the cluster members whose sentences
+// cluster-merge compression added to a representative.
+const MetaMergedFrom = "merged_from"
+
+// MergeClusters rebuilds each representative from the sentences of its
+// whole cluster, as compress.ModeClusterMerge does, and records the
+// members merged from under MetaMergedFrom. Representatives without a
+// cluster in clusters, or whose members add nothing, are left as they
+// are. It returns the representatives and how many were merged.
+func MergeClusters(representatives []types.Chunk, clusters *types.ClusterResult, opts compress.Options) ([]types.Chunk, int) {
+	if clusters == nil {
+		return representatives, 0
+	}
+	byID := make(map[int]*types.Cluster, len(clusters.Clusters))
+	for i := range clusters.Clusters {
+		byID[clusters.Clusters[i].ID] = &clusters.Clusters[i]
+	}
+
+	merger := compress.NewClusterMerger()
+	out := make([]types.Chunk, len(representatives))
+	merged := 0
+	for i, rep := range representatives {
+		out[i] = rep
+		cluster, ok := byID[rep.ClusterID]
+		if !ok || cluster.Size() < 2 {
+			continue
+		}
+		chunk, from := merger.Merge(rep, cluster.Members, opts)
+		if len(from) == 0 {
+			continue
+		}
+		metadata := make(map[string]interface{}, len(chunk.Metadata)+1)
+		for k, v := range chunk.Metadata {
+			metadata[k] = v
+		}
+		metadata[MetaMergedFrom] = from
+		chunk.Metadata = metadata
+		out[i] = chunk
+		merged++
+	}
+	return out, merged
+}
+
+// explainMerged records on each merged chunk how many members it was
+// merged from.
+func explainMerged(chunks []types.Chunk) {
+	for i := range chunks {
+		if from, ok := chunks[i].Metadata[MetaMergedFrom].([]string); ok {
+			addTransform(&chunks[i], fmt.Sprintf("merged-%d", len(from)))
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package contextlab
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestBroker_ClusterMerge(t *testing.T) {
+	chunks := []types.Chunk{
+		{ID: "a", Score: 0.9, Embedding: []float32{1, 0}, Text: "Refunds are issued to the original payment method. They usually arrive within five business days."},
+		{ID: "b", Score: 0.8, Embedding: []float32{0.999, 0.01}, Text: "Refunds are issued to the original payment method. Gift card purchases are refunded as store credit instead."},
+		{ID: "c", Score: 0.7, Embedding: []float32{0, 1}, Text: "Shipping is free on orders over fifty dollars."},
+	}
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: chunks}, WithTargetK(3))
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1, 0},
+		Compress:       &types.CompressOptions{Mode: "cluster-merge", MergeTokens: 200},
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(result.Chunks) != 2 || result.Stats.Merged != 1 {
+		t.Fatalf("got %d chunks, merged %d; want 2 and 1", len(result.Chunks), result.Stats.Merged)
+	}
+	merged := result.Chunks[0]
+	if merged.ID != "a" || !strings.Contains(merged.Text, "five business days") || !strings.Contains(merged.Text, "store credit") {
+		t.Errorf("merged chunk %s = %q, want both members' details", merged.ID, merged.Text)
+	}
+	if strings.Count(merged.Text, "original payment method") != 1 {
+		t.Errorf("merged text repeats a shared sentence: %q", merged.Text)
+	}
+	if from, _ := merged.Metadata[MetaMergedFrom].([]string); !slices.Equal(from, []string{"b"}) {
+		t.Errorf("merged_from = %v, want [b]", from)
+	}
+	if _, ok := result.Chunks[1].Metadata[MetaMergedFrom]; ok {
+		t.Error("a cluster of one was merged")
+	}
+	if chunks[0].Metadata != nil {
+		t.Error("retrieved chunk metadata was modified")
+	}
+
+	// Without clusters there is nothing to merge
+	result, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1, 0},
+		Compress:       &types.CompressOptions{Mode: "cluster-merge"},
+		Stages:         types.StageToggles{Clustering: boolPtr(false)},
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if result.Stats.Merged != 0 {
+		t.Errorf("merged %d chunks without clustering", result.Stats.Merged)
+	}
+}
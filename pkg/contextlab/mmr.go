@@ -1,10 +1,22 @@
 package contextlab
 
 import (
+	"context"
+	stdmath "math"
+	"math/rand"
+	"strconv"
+	"time"
+
 	"github.com/Siddhant-K-code/distill/pkg/math"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
 
+// DefaultTimestampField is the metadata key read for chunk timestamps.
+const DefaultTimestampField = "timestamp"
+
+// DefaultRecencyHalfLife is the default age at which recency halves.
+const DefaultRecencyHalfLife = 7 * 24 * time.Hour
+
 // MMRConfig holds Maximal Marginal Relevance parameters.
 type MMRConfig struct {
 	// Lambda controls the relevance vs diversity tradeoff.
@@ -15,6 +27,35 @@ type MMRConfig struct {
 
 	// TargetK is the number of chunks to select.
 	TargetK int
+
+	// TokenBudget, when positive, replaces TargetK: chunks are selected
+	// until their tokens would exceed it. A candidate too long for the
+	// budget left is passed over, so a shorter one can take the room.
+	// Tokens counts a chunk text's tokens (default: tokens.Count).
+	TokenBudget int
+	Tokens      func(text string) int
+
+	// RecencyWeight blends a recency term into relevance, in [0, 1]:
+	// relevance = (1-w) * score + w * recency. Zero is classic MMR.
+	RecencyWeight float64
+
+	// RecencyHalfLife is the age at which a chunk's recency is 0.5.
+	// Recency decays exponentially: 0.5^(age/half-life).
+	RecencyHalfLife time.Duration
+
+	// TimestampField is the metadata key holding each chunk's timestamp:
+	// RFC 3339 or a date string, or Unix seconds or milliseconds. Chunks
+	// without one get no recency credit.
+	TimestampField string
+
+	// Now returns the current time for computing ages (default time.Now).
+	Now func() time.Time
+
+	// Seed orders candidates whose MMR scores tie. Zero prefers the
+	// earlier input chunk; other values draw a fixed permutation of the
+	// input from Seed, so ties are reproducible but not biased toward
+	// backend order.
+	Seed int64
 }
 
 // DefaultMMRConfig returns sensible defaults.
@@ -42,52 +83,97 @@ func NewMMR(cfg MMRConfig) *MMR {
 	if cfg.TargetK <= 0 {
 		cfg.TargetK = 8
 	}
+	cfg.RecencyWeight = stdmath.Max(0, stdmath.Min(1, cfg.RecencyWeight))
+	if cfg.RecencyHalfLife <= 0 {
+		cfg.RecencyHalfLife = DefaultRecencyHalfLife
+	}
+	if cfg.TimestampField == "" {
+		cfg.TimestampField = DefaultTimestampField
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Tokens == nil {
+		cfg.Tokens = defaultTokens
+	}
 	return &MMR{cfg: cfg}
 }
 
 // Rerank selects diverse chunks using MMR algorithm.
 // Formula: MMR = λ * score(chunk) - (1-λ) * max(similarity(chunk, selected))
+// With a RecencyWeight, score(chunk) blends in the chunk's recency.
 func (m *MMR) Rerank(chunks []types.Chunk) []types.Chunk {
+	result, _ := m.RerankContext(context.Background(), chunks)
+	return result
+}
+
+// RerankContext is Rerank with cancellation. ctx is checked once per
+// similarity-matrix row and once per greedy selection step. If ctx is
+// done, the chunks selected so far are returned along with ctx.Err().
+func (m *MMR) RerankContext(ctx context.Context, chunks []types.Chunk) ([]types.Chunk, error) {
 	if len(chunks) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	if len(chunks) <= m.cfg.TargetK {
-		return chunks
+	var tokens []int
+	if m.cfg.TokenBudget > 0 {
+		tokens = chunkTokens(chunks, m.cfg.Tokens)
+		if sumInts(tokens) <= m.cfg.TokenBudget {
+			return chunks, nil
+		}
+	} else if len(chunks) <= m.cfg.TargetK {
+		return chunks, nil
 	}
 
 	// Normalize scores to [0, 1] for fair comparison with similarity
 	normalizedScores := m.normalizeScores(chunks)
+	m.blendRecency(normalizedScores, chunks)
 
-	// Track selected and remaining indices
-	selected := make([]int, 0, m.cfg.TargetK)
-	remaining := make(map[int]bool, len(chunks))
+	// Track selected and remaining indices, in input order
+	selected := make([]int, 0, min(m.cfg.TargetK, len(chunks)))
+	remaining := make([]int, len(chunks))
 	for i := range chunks {
-		remaining[i] = true
+		remaining[i] = i
 	}
+	rank := m.tieRanks(len(chunks))
 
 	// Precompute similarity matrix for efficiency
-	simMatrix := m.computeSimilarityMatrix(chunks)
+	simMatrix, err := m.computeSimilarityMatrix(ctx, chunks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Greedy selection, until TargetK chunks or no candidate fits the
+	// tokens left
+	left := m.cfg.TokenBudget
+	for (tokens != nil || len(selected) < m.cfg.TargetK) && len(remaining) > 0 {
+		if err = ctx.Err(); err != nil {
+			break
+		}
 
-	// Greedy selection
-	for len(selected) < m.cfg.TargetK && len(remaining) > 0 {
-		bestIdx := -1
-		bestMMR := float64(-2) // MMR can be negative
+		// Equal scores go to the lower tie rank, never to iteration order
+		best := -1
+		var bestMMR float64
 
-		for idx := range remaining {
+		for pos, idx := range remaining {
+			if tokens != nil && tokens[idx] > left {
+				continue
+			}
 			mmrScore := m.computeMMRScore(idx, selected, normalizedScores, simMatrix)
-			if mmrScore > bestMMR {
+			if best < 0 || mmrScore > bestMMR || (mmrScore == bestMMR && rank[idx] < rank[remaining[best]]) {
 				bestMMR = mmrScore
-				bestIdx = idx
+				best = pos
 			}
 		}
 
-		if bestIdx >= 0 {
-			selected = append(selected, bestIdx)
-			delete(remaining, bestIdx)
-		} else {
+		if best < 0 {
 			break
 		}
+		if tokens != nil {
+			left -= tokens[remaining[best]]
+		}
+		selected = append(selected, remaining[best])
+		remaining = append(remaining[:best], remaining[best+1:]...)
 	}
 
 	// Build result
@@ -96,20 +182,38 @@ func (m *MMR) Rerank(chunks []types.Chunk) []types.Chunk {
 		result[i] = chunks[idx]
 	}
 
-	return result
+	return result, err
+}
+
+// tieRanks returns each input index's precedence among tied candidates:
+// the index itself, or a permutation drawn from a non-zero Seed.
+func (m *MMR) tieRanks(n int) []int {
+	if m.cfg.Seed == 0 {
+		rank := make([]int, n)
+		for i := range rank {
+			rank[i] = i
+		}
+		return rank
+	}
+	return rand.New(rand.NewSource(m.cfg.Seed)).Perm(n)
 }
 
-// normalizeScores normalizes chunk scores to [0, 1].
+// normalizeScores normalizes chunk scores to [0, 1]. Backends can return
+// NaN or infinite scores; NaN and -Inf normalize to 0, +Inf to 1, and the
+// range is taken over the finite scores only.
 func (m *MMR) normalizeScores(chunks []types.Chunk) []float64 {
 	if len(chunks) == 0 {
 		return nil
 	}
 
-	minScore := float64(chunks[0].Score)
-	maxScore := float64(chunks[0].Score)
+	minScore := stdmath.Inf(1)
+	maxScore := stdmath.Inf(-1)
 
-	for _, c := range chunks[1:] {
+	for _, c := range chunks {
 		s := float64(c.Score)
+		if stdmath.IsNaN(s) || stdmath.IsInf(s, 0) {
+			continue
+		}
 		if s < minScore {
 			minScore = s
 		}
@@ -121,22 +225,93 @@ func (m *MMR) normalizeScores(chunks []types.Chunk) []float64 {
 	normalized := make([]float64, len(chunks))
 	scoreRange := maxScore - minScore
 
-	if scoreRange == 0 {
-		// All scores are equal
-		for i := range normalized {
+	for i, c := range chunks {
+		s := float64(c.Score)
+		switch {
+		case stdmath.IsNaN(s), stdmath.IsInf(s, -1):
+			normalized[i] = 0
+		case stdmath.IsInf(s, 1):
+			normalized[i] = 1
+		case scoreRange == 0:
+			// All finite scores are equal
 			normalized[i] = 1.0
-		}
-	} else {
-		for i, c := range chunks {
-			normalized[i] = (float64(c.Score) - minScore) / scoreRange
+		default:
+			normalized[i] = (s - minScore) / scoreRange
 		}
 	}
 
 	return normalized
 }
 
+// blendRecency mixes each chunk's recency into its normalized score.
+func (m *MMR) blendRecency(scores []float64, chunks []types.Chunk) {
+	w := m.cfg.RecencyWeight
+	if w == 0 {
+		return
+	}
+	now := m.cfg.Now()
+	for i, c := range chunks {
+		scores[i] = (1-w)*scores[i] + w*m.recency(c, now)
+	}
+}
+
+// recency is 1 for a chunk timestamped now, halving every half-life, and
+// 0 for chunks without a timestamp. Future timestamps count as now.
+func (m *MMR) recency(c types.Chunk, now time.Time) float64 {
+	t, ok := parseTimestamp(c.Metadata[m.cfg.TimestampField])
+	if !ok {
+		return 0
+	}
+	age := now.Sub(t)
+	if age <= 0 {
+		return 1
+	}
+	return stdmath.Exp2(-float64(age) / float64(m.cfg.RecencyHalfLife))
+}
+
+// parseTimestamp reads a metadata timestamp: a time.Time, an RFC 3339 or
+// YYYY-MM-DD string, or a Unix time in seconds or milliseconds.
+func parseTimestamp(v interface{}) (time.Time, bool) {
+	var unix float64
+	switch t := v.(type) {
+	case time.Time:
+		return t, !t.IsZero()
+	case string:
+		if ts, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return ts, true
+		}
+		if ts, err := time.Parse(time.DateOnly, t); err == nil {
+			return ts, true
+		}
+		f, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		unix = f
+	case float64:
+		unix = t
+	case float32:
+		unix = float64(t)
+	case int:
+		unix = float64(t)
+	case int64:
+		unix = float64(t)
+	default:
+		return time.Time{}, false
+	}
+	if unix <= 0 {
+		return time.Time{}, false
+	}
+	// Seconds would put anything this large past the year 33000
+	if unix >= 1e12 {
+		return time.UnixMilli(int64(unix)), true
+	}
+	sec, frac := stdmath.Modf(unix)
+	return time.Unix(int64(sec), int64(frac*1e9)), true
+}
+
 // computeSimilarityMatrix computes pairwise cosine similarities.
-func (m *MMR) computeSimilarityMatrix(chunks []types.Chunk) [][]float64 {
+func (m *MMR) computeSimilarityMatrix(ctx context.Context, chunks []types.Chunk) ([][]float64, error) {
 	n := len(chunks)
 	matrix := make([][]float64, n)
 
@@ -147,7 +322,11 @@ func (m *MMR) computeSimilarityMatrix(chunks []types.Chunk) [][]float64 {
 	}
 
 	// Compute similarities
+	norms := squaredNorms(ctx, chunks)
 	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		for j := i + 1; j < n; j++ {
 			// Handle missing embeddings
 			if len(chunks[i].Embedding) == 0 || len(chunks[j].Embedding) == 0 {
@@ -155,14 +334,17 @@ func (m *MMR) computeSimilarityMatrix(chunks []types.Chunk) [][]float64 {
 				matrix[j][i] = 0.0
 				continue
 			}
-			// Similarity = 1 - distance
-			sim := 1.0 - math.CosineDistance(chunks[i].Embedding, chunks[j].Embedding)
+			// Similarity = 1 - distance; NaN components count as unrelated
+			sim := 1.0 - math.CosineDistanceNorms(chunks[i].Embedding, chunks[j].Embedding, norms[i], norms[j])
+			if stdmath.IsNaN(sim) {
+				sim = 0
+			}
 			matrix[i][j] = sim
 			matrix[j][i] = sim
 		}
 	}
 
-	return matrix
+	return matrix, nil
 }
 
 // computeMMRScore computes the MMR score for a candidate chunk.
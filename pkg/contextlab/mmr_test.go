@@ -0,0 +1,173 @@
+package contextlab
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestMMR_RecencyWeight(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	chunks := orthogonalChunks(4)
+	// Relevance order is a, b, c, d; timestamps make d the newest.
+	ages := []time.Duration{90 * 24 * time.Hour, 30 * 24 * time.Hour, 0, 0}
+	for i := range chunks {
+		chunks[i].Metadata = map[string]interface{}{"created": now.Add(-ages[i]).Format(time.RFC3339)}
+	}
+	chunks[3].Metadata["created"] = float64(now.Unix())
+
+	classic := NewMMR(MMRConfig{Lambda: 1, TargetK: 2}).Rerank(chunks)
+	if classic[0].ID != "a" || classic[1].ID != "b" {
+		t.Fatalf("classic MMR = %s, %s, want a, b", classic[0].ID, classic[1].ID)
+	}
+
+	recent := NewMMR(MMRConfig{
+		Lambda:          1,
+		TargetK:         2,
+		RecencyWeight:   0.8,
+		RecencyHalfLife: 7 * 24 * time.Hour,
+		TimestampField:  "created",
+		Now:             func() time.Time { return now },
+	}).Rerank(chunks)
+	if recent[0].ID != "c" || recent[1].ID != "d" {
+		t.Errorf("recency MMR = %s, %s, want c, d", recent[0].ID, recent[1].ID)
+	}
+}
+
+func TestMMR_TokenBudget(t *testing.T) {
+	chunks := orthogonalChunks(3)
+	tokens := func(text string) int { return map[string]int{"chunk a": 5, "chunk b": 8, "chunk c": 3}[text] }
+
+	got := NewMMR(MMRConfig{Lambda: 0.5, TargetK: 1, TokenBudget: 9, Tokens: tokens}).Rerank(chunks)
+	if ids := ids(got); !slices.Equal(ids, []string{"a", "c"}) {
+		t.Errorf("budget 9 = %v, want [a c]", ids)
+	}
+
+	// Input that fits the budget is returned as is
+	got = NewMMR(MMRConfig{TokenBudget: 16, Tokens: tokens}).Rerank(chunks)
+	if ids := ids(got); !slices.Equal(ids, []string{"a", "b", "c"}) {
+		t.Errorf("budget 16 = %v, want all", ids)
+	}
+}
+
+func TestMMR_Recency(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMMR(MMRConfig{RecencyWeight: 1, RecencyHalfLife: 24 * time.Hour, Now: func() time.Time { return now }})
+
+	tests := []struct {
+		name string
+		ts   interface{}
+		want float64
+	}{
+		{"now", now, 1},
+		{"future", now.Add(time.Hour).Format(time.RFC3339), 1},
+		{"one half-life", now.Add(-24 * time.Hour).Format(time.RFC3339Nano), 0.5},
+		{"date", "2026-05-30", 0.25},
+		{"unix seconds", float64(now.Add(-24 * time.Hour).Unix()), 0.5},
+		{"unix millis", now.Add(-48 * time.Hour).UnixMilli(), 0.25},
+		{"numeric string", "1780185600", 0.5},
+		{"missing", nil, 0},
+		{"garbage", "yesterday", 0},
+	}
+	for _, tt := range tests {
+		c := orthogonalChunks(1)[0]
+		c.Metadata = map[string]interface{}{DefaultTimestampField: tt.ts}
+		if got := m.recency(c, now); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: recency = %g, want %g", tt.name, got, tt.want)
+		}
+	}
+}
+
+// tiedChunks returns n chunks with equal scores and embeddings drawn from
+// a few directions, so most MMR steps end in ties.
+func tiedChunks(rng *rand.Rand, n int) []types.Chunk {
+	chunks := make([]types.Chunk, n)
+	for i := range chunks {
+		emb := make([]float32, 3)
+		emb[rng.Intn(3)] = 1
+		chunks[i] = types.Chunk{ID: string(rune('a' + i)), Score: 0.5, Embedding: emb}
+	}
+	return chunks
+}
+
+func ids(chunks []types.Chunk) []string {
+	out := make([]string, len(chunks))
+	for i, c := range chunks {
+		out[i] = c.ID
+	}
+	return out
+}
+
+func TestMMR_TiesDeterministic(t *testing.T) {
+	property := func(corpusSeed, tieSeed int64) bool {
+		chunks := tiedChunks(rand.New(rand.NewSource(corpusSeed)), 12)
+		m := NewMMR(MMRConfig{Lambda: 0.5, TargetK: 5, Seed: tieSeed})
+		want := ids(m.Rerank(chunks))
+		for range 20 {
+			again := NewMMR(MMRConfig{Lambda: 0.5, TargetK: 5, Seed: tieSeed})
+			if !slices.Equal(ids(again.Rerank(chunks)), want) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMMR_TiesIndexOrder(t *testing.T) {
+	// Equal scores, orthogonal embeddings: every step is a tie
+	chunks := orthogonalChunks(6)
+	for i := range chunks {
+		chunks[i].Score = 0.5
+	}
+	got := ids(NewMMR(MMRConfig{Lambda: 0.5, TargetK: 3}).Rerank(chunks))
+	if !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("seed 0 picked %v, want input order a, b, c", got)
+	}
+
+	// Seed 7 picks in the order of its permutation's ranks
+	rank := rand.New(rand.NewSource(7)).Perm(6)
+	byRank := ids(chunks)
+	slices.SortFunc(byRank, func(a, b string) int { return rank[a[0]-'a'] - rank[b[0]-'a'] })
+	seeded := ids(NewMMR(MMRConfig{Lambda: 0.5, TargetK: 3, Seed: 7}).Rerank(chunks))
+	if !slices.Equal(seeded, byRank[:3]) {
+		t.Errorf("seed 7 picked %v, want %v", seeded, byRank[:3])
+	}
+}
+
+func TestMMR_NormalizeNonFinite(t *testing.T) {
+	m := NewMMR(MMRConfig{})
+	property := func(bits []uint32) bool {
+		chunks := make([]types.Chunk, len(bits))
+		for i, b := range bits {
+			chunks[i].Score = math.Float32frombits(b)
+		}
+		for _, s := range m.normalizeScores(chunks) {
+			if math.IsNaN(s) || s < 0 || s > 1 {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+
+	chunks := orthogonalChunks(5)
+	chunks[0].Score = float32(math.NaN())
+	chunks[2].Score = float32(math.Inf(1))
+	chunks[4].Score = float32(math.Inf(-1))
+	chunks[3].Embedding[3] = float32(math.NaN())
+	got := NewMMR(MMRConfig{Lambda: 0.7, TargetK: 3}).Rerank(chunks)
+	// +Inf ties with the top finite score b; NaN, -Inf, and d tie at 0
+	if !slices.Equal(ids(got), []string{"b", "c", "a"}) {
+		t.Errorf("non-finite scores picked %v, want b, c, a", ids(got))
+	}
+}
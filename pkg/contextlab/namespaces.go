@@ -0,0 +1,163 @@
+package contextlab
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// ErrNamespaceDenied is returned when a request searches several
+// namespaces without belonging to a group allowed to; see
+// WithCrossNamespaceGroups.
+var ErrNamespaceDenied = errs.New(errs.ErrAuth, "identity may not search across namespaces")
+
+// authorizeNamespaces checks a request searching more than one namespace
+// against the groups allowed to.
+func (b *Broker) authorizeNamespaces(req *types.RetrievalRequest) error {
+	if len(b.crossGroups) == 0 {
+		return nil
+	}
+	cross := len(req.Namespaces) > 1
+	for _, ns := range req.Namespaces {
+		cross = cross || ns.Name == retriever.AllNamespaces
+	}
+	if !cross {
+		return nil
+	}
+	if req.Identity != nil {
+		for _, g := range req.Identity.Groups {
+			if slices.Contains(b.crossGroups, g) {
+				return nil
+			}
+		}
+	}
+	return ErrNamespaceDenied
+}
+
+// expandNamespaces replaces a retriever.AllNamespaces entry with every
+// namespace the retriever lists that the request does not name itself,
+// each with the entry's TopK. An unnamed default namespace is skipped,
+// since requests cannot name it either.
+func (b *Broker) expandNamespaces(ctx context.Context, req *types.RetrievalRequest) error {
+	i := slices.IndexFunc(req.Namespaces, func(ns types.NamespaceQuota) bool {
+		return ns.Name == retriever.AllNamespaces
+	})
+	if i < 0 {
+		return nil
+	}
+	lister, ok := b.retriever.(retriever.NamespaceLister)
+	if !ok {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("namespaces[%d]: the backend cannot list namespaces for %q", i, retriever.AllNamespaces))
+	}
+	names, err := lister.ListNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("listing namespaces: %w", err)
+	}
+
+	wildcard := req.Namespaces[i]
+	expanded := slices.Delete(slices.Clone(req.Namespaces), i, i+1)
+	for _, name := range names {
+		if name != "" && !slices.ContainsFunc(expanded, func(ns types.NamespaceQuota) bool { return ns.Name == name }) {
+			expanded = append(expanded, types.NamespaceQuota{Name: name, TopK: wildcard.TopK})
+		}
+	}
+	if len(expanded) == 0 {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("namespaces[%d]: the backend has no namespaces", i))
+	}
+	req.Namespaces = expanded
+	return nil
+}
+
+// namespaceQuotas returns the namespaces of a multi-namespace request and
+// the most chunks returned from each, filling in an even share of the
+// target for namespaces without a TopK.
+func (b *Broker) namespaceQuotas(req *types.RetrievalRequest) ([]string, []int, error) {
+	if len(req.Namespaces) > retriever.MaxFanOut {
+		return nil, nil, retriever.ErrTooManyQueries
+	}
+	share := max(1, b.cfg.TargetK/len(req.Namespaces))
+	names := make([]string, len(req.Namespaces))
+	quotas := make([]int, len(req.Namespaces))
+	seen := make(map[string]bool, len(req.Namespaces))
+	for i, ns := range req.Namespaces {
+		switch {
+		case ns.Name == "":
+			return nil, nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("namespaces[%d]: name is required", i))
+		case seen[ns.Name]:
+			return nil, nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("namespaces[%d]: %q is listed twice", i, ns.Name))
+		case ns.TopK < 0:
+			return nil, nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("namespaces[%d]: top_k must be non-negative, got %d", i, ns.TopK))
+		}
+		seen[ns.Name] = true
+		names[i] = ns.Name
+		quotas[i] = ns.TopK
+		if quotas[i] == 0 {
+			quotas[i] = share
+		}
+	}
+	return names, quotas, nil
+}
+
+// namespaceFetchK splits the over-fetch budget of fetchK chunks across
+// namespaces, and across fanned-out vectors within each, fetching at least
+// each namespace's quota.
+func (b *Broker) namespaceFetchK(fetchK int, quotas []int, vectors int) []int {
+	k := make([]int, len(quotas))
+	for i, quota := range quotas {
+		k[i] = max(fetchK/len(quotas), quota)
+		if vectors > 1 {
+			k[i] = max(k[i]/vectors, quota)
+		}
+	}
+	return k
+}
+
+// selectByNamespace picks up to each namespace's quota from the cluster
+// representatives, by MMR within the namespace when it is on and by score
+// otherwise, and returns them by descending score. Clustering has already
+// merged duplicates across namespaces, so a namespace's picks are the
+// representatives that came from it.
+func (b *Broker) selectByNamespace(ctx context.Context, req *types.RetrievalRequest, representatives []types.Chunk) ([]types.Chunk, error) {
+	names, quotas, err := b.namespaceQuotas(req)
+	if err != nil {
+		return nil, err
+	}
+	byNamespace := make(map[string][]types.Chunk, len(names))
+	for _, c := range representatives {
+		ns, _ := c.Metadata[retriever.NamespaceKey].(string)
+		byNamespace[ns] = append(byNamespace[ns], c)
+	}
+
+	var selected []types.Chunk
+	for i, ns := range names {
+		chunks := byNamespace[ns]
+		if len(chunks) <= quotas[i] {
+			selected = append(selected, chunks...)
+			continue
+		}
+		if mmr := b.requestMMR(req); mmr != nil {
+			cfg := mmr.cfg
+			cfg.TargetK = quotas[i]
+			observeStage(ctx, StageMMR, len(chunks))
+			picked, err := NewMMR(cfg).RerankContext(ctx, chunks)
+			if err != nil {
+				return nil, fmt.Errorf("mmr interrupted: %w", err)
+			}
+			selected = append(selected, picked...)
+			continue
+		}
+		sort.SliceStable(chunks, func(a, b int) bool {
+			return chunks[a].Score > chunks[b].Score
+		})
+		selected = append(selected, chunks[:quotas[i]]...)
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].Score > selected[j].Score
+	})
+	return selected, nil
+}
@@ -0,0 +1,205 @@
+package contextlab
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// namespaceRetriever serves a fixed set of chunks per namespace and
+// records the TopK asked of each.
+type namespaceRetriever struct {
+	mu     sync.Mutex
+	chunks map[string][]types.Chunk
+	topK   map[string]int
+}
+
+func (r *namespaceRetriever) Query(ctx context.Context, req *types.RetrievalRequest) (*types.RetrievalResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.topK[req.Namespace] = req.TopK
+	out := make([]types.Chunk, len(r.chunks[req.Namespace]))
+	copy(out, r.chunks[req.Namespace])
+	return &types.RetrievalResult{Chunks: out}, nil
+}
+
+func (r *namespaceRetriever) QueryByID(ctx context.Context, id string, topK int, namespace string) (*types.RetrievalResult, error) {
+	return nil, retriever.ErrNotFound
+}
+
+func (r *namespaceRetriever) Close() error { return nil }
+
+// basisChunk returns a chunk whose embedding is the i-th unit vector.
+func basisChunk(id string, i int, score float32) types.Chunk {
+	emb := make([]float32, 10)
+	emb[i] = 1
+	return types.Chunk{ID: id, Text: "chunk " + id, Score: score, Embedding: emb}
+}
+
+func TestBroker_Namespaces(t *testing.T) {
+	ret := &namespaceRetriever{
+		chunks: map[string][]types.Chunk{
+			"docs": {
+				basisChunk("a", 0, 0.9), basisChunk("b", 1, 0.8), basisChunk("c", 2, 0.7),
+				basisChunk("d", 3, 0.6), basisChunk("e", 4, 0.5),
+			},
+			"tickets": {
+				// The same text as docs "a", scoring higher
+				basisChunk("t1", 0, 0.95), basisChunk("t2", 6, 0.4), basisChunk("b", 7, 0.3),
+			},
+		},
+		topK: map[string]int{},
+	}
+	broker := NewBroker(ret, BrokerConfig{OverFetchK: 40, TargetK: 8})
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1},
+		Namespaces:     []types.NamespaceQuota{{Name: "docs", TopK: 2}, {Name: "tickets", TopK: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range result.Chunks {
+		got = append(got, c.Metadata[retriever.NamespaceKey].(string)+"/"+c.ID)
+	}
+	// docs "a" merges into tickets "t1", so docs fills its quota with b, c
+	want := []string{"tickets/t1", "docs/b", "docs/c"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if ret.topK["docs"] != 20 || ret.topK["tickets"] != 20 {
+		t.Errorf("expected the over-fetch split evenly, got %v", ret.topK)
+	}
+	if result.Stats.Retrieved != 8 {
+		t.Errorf("expected chunks sharing an ID across namespaces kept, retrieved %d", result.Stats.Retrieved)
+	}
+
+	// Without a TopK a namespace gets an even share of the target
+	result, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1},
+		Namespaces:     []types.NamespaceQuota{{Name: "docs"}, {Name: "tickets"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Chunks) != 7 {
+		t.Errorf("expected 4 docs and 3 tickets, got %d chunks", len(result.Chunks))
+	}
+}
+
+func TestBroker_NamespacesInvalid(t *testing.T) {
+	broker := NewBroker(&namespaceRetriever{topK: map[string]int{}}, BrokerConfig{})
+	for _, namespaces := range [][]types.NamespaceQuota{
+		{{Name: ""}},
+		{{Name: "docs"}, {Name: "docs"}},
+		{{Name: "docs", TopK: -1}},
+	} {
+		_, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+			QueryEmbedding: []float32{1},
+			Namespaces:     namespaces,
+		})
+		if !errors.Is(err, errs.ErrConfig) {
+			t.Errorf("%v: expected a config error, got %v", namespaces, err)
+		}
+	}
+}
+
+// listingRetriever adds namespace listing to a namespaceRetriever.
+type listingRetriever struct {
+	*namespaceRetriever
+	names []string
+}
+
+func (r *listingRetriever) ListNamespaces(ctx context.Context) ([]string, error) {
+	return r.names, nil
+}
+
+func TestBroker_AllNamespaces(t *testing.T) {
+	ret := &namespaceRetriever{
+		chunks: map[string][]types.Chunk{
+			"docs":    {basisChunk("a", 0, 0.9), basisChunk("b", 1, 0.8), basisChunk("c", 2, 0.7)},
+			"tickets": {basisChunk("t1", 3, 0.6), basisChunk("t2", 4, 0.5)},
+		},
+		topK: map[string]int{},
+	}
+	broker := NewBroker(&listingRetriever{ret, []string{"", "docs", "tickets"}}, BrokerConfig{OverFetchK: 40, TargetK: 8})
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1},
+		Namespaces:     []types.NamespaceQuota{{Name: "tickets", TopK: 1}, {Name: retriever.AllNamespaces, TopK: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for _, c := range result.Chunks {
+		counts[c.Metadata[retriever.NamespaceKey].(string)]++
+	}
+	if len(ret.topK) != 2 || counts["docs"] != 2 || counts["tickets"] != 1 {
+		t.Errorf("expected docs and tickets searched with their quotas, got queries %v and chunks %v", ret.topK, counts)
+	}
+
+	// Backends that cannot list namespaces reject the wildcard
+	_, err = NewBroker(ret, BrokerConfig{}).Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1},
+		Namespaces:     []types.NamespaceQuota{{Name: retriever.AllNamespaces}},
+	})
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected a config error without a namespace lister, got %v", err)
+	}
+}
+
+func TestBroker_CrossNamespaceGroups(t *testing.T) {
+	ret := &namespaceRetriever{
+		chunks: map[string][]types.Chunk{
+			"acme":   {basisChunk("a", 0, 0.9)},
+			"globex": {basisChunk("g", 1, 0.8)},
+		},
+		topK: map[string]int{},
+	}
+	broker, err := NewBrokerWithOptions(ret, WithCrossNamespaceGroups("support"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		namespaces []types.NamespaceQuota
+		identity   *types.Identity
+		denied     bool
+	}{
+		{"one namespace", []types.NamespaceQuota{{Name: "acme"}}, nil, false},
+		{"no identity", []types.NamespaceQuota{{Name: "acme"}, {Name: "globex"}}, nil, true},
+		{"other group", []types.NamespaceQuota{{Name: "acme"}, {Name: "globex"}}, &types.Identity{User: "u", Groups: []string{"acme"}}, true},
+		{"wildcard", []types.NamespaceQuota{{Name: retriever.AllNamespaces}}, &types.Identity{Groups: []string{"acme"}}, true},
+		{"allowed group", []types.NamespaceQuota{{Name: "acme"}, {Name: "globex"}}, &types.Identity{Groups: []string{"acme", "support"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+				QueryEmbedding: []float32{1},
+				Namespaces:     tt.namespaces,
+				Identity:       tt.identity,
+			})
+			if tt.denied {
+				if !errors.Is(err, ErrNamespaceDenied) || !errors.Is(err, errs.ErrAuth) {
+					t.Errorf("expected ErrNamespaceDenied, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
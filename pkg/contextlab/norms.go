@@ -0,0 +1,65 @@
+package contextlab
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Siddhant-K-code/distill/pkg/math"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+type vectorNormsKey struct{}
+
+// vectorNorms caches the squared L2 norms of a request's embeddings.
+// The stages see the same embeddings in different subsets and orders, so
+// norms are keyed by the embedding's backing array.
+type vectorNorms struct {
+	mu sync.Mutex
+	sq map[normKey]float64
+}
+
+type normKey struct {
+	data *float32
+	n    int
+}
+
+// WithVectorNorms returns ctx carrying a cache of embedding norms, so
+// clustering and MMR run with it compute each chunk's norm once. The
+// cache lives as long as ctx, so use it for one request whose embeddings
+// are not modified in place. A ctx that already carries one is returned
+// as is.
+func WithVectorNorms(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(vectorNormsKey{}).(*vectorNorms); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, vectorNormsKey{}, &vectorNorms{sq: make(map[normKey]float64)})
+}
+
+// squaredNorms returns the squared norm of each chunk's embedding, from
+// ctx's cache when it has one.
+func squaredNorms(ctx context.Context, chunks []types.Chunk) []float64 {
+	out := make([]float64, len(chunks))
+	cache, _ := ctx.Value(vectorNormsKey{}).(*vectorNorms)
+	if cache == nil {
+		for i, c := range chunks {
+			out[i] = math.SquaredNorm(c.Embedding)
+		}
+		return out
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for i, c := range chunks {
+		if len(c.Embedding) == 0 {
+			continue
+		}
+		key := normKey{&c.Embedding[0], len(c.Embedding)}
+		sq, ok := cache.sq[key]
+		if !ok {
+			sq = math.SquaredNorm(c.Embedding)
+			cache.sq[key] = sq
+		}
+		out[i] = sq
+	}
+	return out
+}
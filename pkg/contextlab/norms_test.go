@@ -0,0 +1,73 @@
+package contextlab
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestWithVectorNorms(t *testing.T) {
+	ctx := WithVectorNorms(context.Background())
+	if WithVectorNorms(ctx) != ctx {
+		t.Error("WithVectorNorms replaced an existing cache")
+	}
+	cache := ctx.Value(vectorNormsKey{}).(*vectorNorms)
+
+	chunks := []types.Chunk{
+		{ID: "a", Embedding: []float32{3, 4}},
+		{ID: "b", Embedding: []float32{1, 0}},
+		{ID: "empty"},
+	}
+	if got := squaredNorms(ctx, chunks); !reflect.DeepEqual(got, []float64{25, 1, 0}) {
+		t.Errorf("squaredNorms = %v", got)
+	}
+
+	// A later stage sees a reordered subset sharing the embeddings
+	cache.sq[normKey{&chunks[0].Embedding[0], 2}] = 100
+	if got := squaredNorms(ctx, []types.Chunk{chunks[1], chunks[0]}); !reflect.DeepEqual(got, []float64{1, 100}) {
+		t.Errorf("squaredNorms of the subset = %v, want the cached norms", got)
+	}
+	if len(cache.sq) != 2 {
+		t.Errorf("cache holds %d norms, want 2", len(cache.sq))
+	}
+}
+
+func TestVectorNorms_SameResults(t *testing.T) {
+	chunks := makeBenchChunks(60, 32)
+	for i := 1; i < len(chunks); i += 3 {
+		chunks[i].Embedding = chunks[i-1].Embedding
+	}
+	for i := range chunks {
+		chunks[i].Score = float32(len(chunks) - i)
+	}
+
+	run := func(ctx context.Context) ([]int, []string) {
+		work := append([]types.Chunk(nil), chunks...)
+		clusters, err := NewClusterer(DefaultClusterConfig()).ClusterContext(ctx, work)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reps := NewSelector(DefaultSelectorConfig()).Select(clusters)
+		ranked, err := NewMMR(MMRConfig{Lambda: 0.5, TargetK: 10}).RerankContext(ctx, reps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]string, len(ranked))
+		for i, c := range ranked {
+			ids[i] = c.ID
+		}
+		labels := make([]int, len(work))
+		for i, c := range work {
+			labels[i] = c.ClusterID
+		}
+		return labels, ids
+	}
+
+	wantLabels, wantIDs := run(context.Background())
+	gotLabels, gotIDs := run(WithVectorNorms(context.Background()))
+	if !reflect.DeepEqual(gotLabels, wantLabels) || !reflect.DeepEqual(gotIDs, wantIDs) {
+		t.Errorf("shared norms changed the result:\n got %v %v\nwant %v %v", gotLabels, gotIDs, wantLabels, wantIDs)
+	}
+}
@@ -0,0 +1,564 @@
+package contextlab
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/enrich"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/garbage"
+	"github.com/Siddhant-K-code/distill/pkg/models"
+	"github.com/Siddhant-K-code/distill/pkg/rerank"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/safety"
+	"github.com/Siddhant-K-code/distill/pkg/sensitivity"
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Option configures a Broker built with NewBrokerWithOptions.
+type Option func(*brokerBuilder)
+
+// brokerBuilder accumulates options before the Broker is validated and built.
+type brokerBuilder struct {
+	cfg          BrokerConfig
+	embedder     retriever.EmbeddingProvider
+	sent         *cache.SentFilter
+	compressor   compress.Compressor
+	compressOpts compress.Options
+	results      cache.Cache
+	resultTTL    time.Duration
+	embeddings   cache.Cache
+	embeddingTTL time.Duration
+	limits       Limits
+	enricher     *enrich.Hook
+	acl          ACL
+	injection    safety.Filter
+	garbage      *garbage.Filter
+	models       *models.Registry
+	reranker     rerank.Reranker
+	crossGroups  []string
+	stages       []StageSpec
+	classifier   *sensitivity.Classifier
+}
+
+// WithConfig replaces the whole configuration, e.g. one loaded from a
+// config file. Options after it override individual fields.
+func WithConfig(cfg BrokerConfig) Option {
+	return func(b *brokerBuilder) { b.cfg = cfg }
+}
+
+// WithOverFetchK sets the number of chunks fetched from the vector DB.
+func WithOverFetchK(k int) Option {
+	return func(b *brokerBuilder) { b.cfg.OverFetchK = k }
+}
+
+// WithTargetK sets the number of chunks returned.
+func WithTargetK(k int) Option {
+	return func(b *brokerBuilder) { b.cfg.TargetK = k }
+}
+
+// WithTokenBudget selects chunks until their tokens would
+// exceed budget, instead of returning TargetK of them.
+func WithTokenBudget(budget int) Option {
+	return func(b *brokerBuilder) { b.cfg.TokenBudget = budget }
+}
+
+// WithClusterThreshold sets the cosine distance threshold for clustering.
+func WithClusterThreshold(threshold float64) Option {
+	return func(b *brokerBuilder) { b.cfg.ClusterThreshold = threshold }
+}
+
+// WithClusterLinkage sets the linkage: "single", "complete", or "average".
+func WithClusterLinkage(linkage string) Option {
+	return func(b *brokerBuilder) { b.cfg.ClusterLinkage = linkage }
+}
+
+// WithSelectionStrategy sets how cluster representatives are picked.
+func WithSelectionStrategy(s SelectionStrategy) Option {
+	return func(b *brokerBuilder) { b.cfg.SelectionStrategy = s }
+}
+
+// WithMMR enables MMR re-ranking with the given lambda (0-1).
+func WithMMR(lambda float64) Option {
+	return func(b *brokerBuilder) {
+		b.cfg.EnableMMR = true
+		b.cfg.MMRLambda = lambda
+	}
+}
+
+// WithoutMMR disables MMR re-ranking; the top TargetK by score are kept.
+func WithoutMMR() Option {
+	return func(b *brokerBuilder) { b.cfg.EnableMMR = false }
+}
+
+// WithoutClustering skips clustering and selection; MMR or score order
+// picks the TargetK chunks.
+func WithoutClustering() Option {
+	return func(b *brokerBuilder) { b.cfg.DisableClustering = true }
+}
+
+// WithRedaction controls whether credentials and PII are redacted from
+// returned chunks.
+func WithRedaction(on bool) Option {
+	return func(b *brokerBuilder) { b.cfg.EnableRedaction = on }
+}
+
+// WithClassification controls whether returned chunks are tagged with
+// their sensitivity level.
+func WithClassification(on bool) Option {
+	return func(b *brokerBuilder) { b.cfg.EnableClassification = on }
+}
+
+// WithMetadata controls whether chunk metadata is requested from the vector DB.
+func WithMetadata(include bool) Option {
+	return func(b *brokerBuilder) { b.cfg.IncludeMetadata = include }
+}
+
+// WithDedupHints controls whether returned chunks carry dedup hints in
+// their metadata; see AnnotateDedup.
+func WithDedupHints(on bool) Option {
+	return func(b *brokerBuilder) { b.cfg.DedupHints = on }
+}
+
+// WithEmbedder sets the provider used to embed text queries.
+func WithEmbedder(emb retriever.EmbeddingProvider) Option {
+	return func(b *brokerBuilder) { b.embedder = emb }
+}
+
+// WithSentFilter enables session-scoped "already sent" filtering.
+func WithSentFilter(f *cache.SentFilter) Option {
+	return func(b *brokerBuilder) { b.sent = f }
+}
+
+// WithCompression compresses the chunks returned by Retrieve with c.
+func WithCompression(c compress.Compressor, opts compress.Options) Option {
+	return func(b *brokerBuilder) {
+		b.compressor = c
+		b.compressOpts = opts
+	}
+}
+
+// WithCache caches Retrieve results in c for ttl. Requests with a
+// SessionID bypass the cache because their results depend on what the
+// session has already seen.
+func WithCache(c cache.Cache, ttl time.Duration) Option {
+	return func(b *brokerBuilder) {
+		b.results = c
+		b.resultTTL = ttl
+	}
+}
+
+// WithEmbeddingCache caches query text embeddings in c for ttl, so
+// repeated queries skip the embedding provider. Unlike WithCache it also
+// helps session requests and requests with differing filters.
+func WithEmbeddingCache(c cache.Cache, ttl time.Duration) Option {
+	return func(b *brokerBuilder) {
+		b.embeddings = c
+		b.embeddingTTL = ttl
+	}
+}
+
+// WithLimits rejects retrieval results that exceed l with a *LimitError
+// instead of clustering them.
+func WithLimits(l Limits) Option {
+	return func(b *brokerBuilder) { b.limits = l }
+}
+
+// WithEnrichment runs h on retrieved chunks before clustering, so hooks
+// can add metadata or drop chunks (e.g. failed ACL checks). Cached results
+// keep the metadata they were enriched with until the cache TTL expires.
+func WithEnrichment(h *enrich.Hook) Option {
+	return func(b *brokerBuilder) { b.enricher = h }
+}
+
+// WithACL drops chunks the request's identity may not see before any
+// other stage runs. Chunk metadata is always fetched while a is enabled.
+func WithACL(a ACL) Option {
+	return func(b *brokerBuilder) { b.acl = a }
+}
+
+// WithInjectionFilter applies f to chunks after the ACL check, flagging,
+// stripping, or dropping prompt-injection content before it can be
+// selected.
+func WithInjectionFilter(f safety.Filter) Option {
+	return func(b *brokerBuilder) { b.injection = f }
+}
+
+// WithGarbageFilter applies f to chunks after the score and limit checks,
+// dropping boilerplate such as cookie banners and navigation menus before
+// clustering and selection.
+func WithGarbageFilter(f *garbage.Filter) Option {
+	return func(b *brokerBuilder) { b.garbage = f }
+}
+
+// WithModels fits results to the token budget of the model profile a
+// request names, or r's default. Compression targets the budget, chunks
+// that still do not fit are dropped, and stats report tokens and cost.
+func WithModels(r *models.Registry) Option {
+	return func(b *brokerBuilder) { b.models = r }
+}
+
+// WithReranker sets the reranker used by rerank pipeline stages, which
+// need one.
+func WithReranker(r rerank.Reranker) Option {
+	return func(b *brokerBuilder) { b.reranker = r }
+}
+
+// WithCrossNamespaceGroups restricts requests that search more than one
+// namespace, including retriever.AllNamespaces, to identities in one of
+// groups. Without it any request may search several namespaces.
+func WithCrossNamespaceGroups(groups ...string) Option {
+	return func(b *brokerBuilder) { b.crossGroups = groups }
+}
+
+// WithClassifier sets the classifier EnableClassification tags chunks
+// with, e.g. one with embedding examples. The default uses
+// sensitivity.DefaultConfig.
+func WithClassifier(c *sensitivity.Classifier) Option {
+	return func(b *brokerBuilder) { b.classifier = c }
+}
+
+// WithStages replaces clustering, selection, and MMR with the stages in
+// specs, run in order after retrieval and the filters that precede
+// clustering. specs must start with retrieve; see ParseStages. Chunks
+// beyond TargetK left by the last stage are dropped lowest score first.
+func WithStages(specs ...StageSpec) Option {
+	return func(b *brokerBuilder) { b.stages = specs }
+}
+
+// NewBrokerWithOptions builds a Broker starting from DefaultBrokerConfig.
+// Unlike NewBroker, invalid settings are reported as errors (tagged
+// errs.ErrConfig) instead of being silently replaced with defaults.
+func NewBrokerWithOptions(ret retriever.Retriever, opts ...Option) (*Broker, error) {
+	if ret == nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("broker: retriever is required"))
+	}
+
+	b := &brokerBuilder{cfg: DefaultBrokerConfig()}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if err := b.cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := b.injection.Validate(); err != nil {
+		return nil, err
+	}
+	if b.limits.MaxChunks > 0 && b.cfg.OverFetchK > b.limits.MaxChunks {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid broker config: over_fetch_k (%d) exceeds max_chunks limit (%d)", b.cfg.OverFetchK, b.limits.MaxChunks))
+	}
+	var stages []pipelineStage
+	if b.stages != nil {
+		var err error
+		if stages, err = newStages(b.stages); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range stages {
+		if s.name() == PipelineRerank && b.reranker == nil {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid pipeline: the %q stage needs a reranker", PipelineRerank))
+		}
+	}
+
+	broker := NewBroker(ret, b.cfg)
+	broker.embedder = b.embedder
+	broker.sent = b.sent
+	broker.compressor = b.compressor
+	broker.compressOpts = b.compressOpts
+	broker.results = b.results
+	broker.resultTTL = b.resultTTL
+	broker.embeddings = b.embeddings
+	broker.embeddingTTL = b.embeddingTTL
+	broker.limits = b.limits
+	broker.enricher = b.enricher
+	broker.acl = b.acl
+	broker.injection = b.injection
+	broker.garbage = b.garbage
+	broker.models = b.models
+	broker.reranker = b.reranker
+	broker.crossGroups = b.crossGroups
+	broker.stages = stages
+	broker.classifier = b.classifier
+	return broker, nil
+}
+
+// Validate reports configuration values that NewBroker would otherwise
+// silently replace with defaults.
+func (c BrokerConfig) Validate() error {
+	var problem string
+	switch {
+	case c.OverFetchK <= 0:
+		problem = fmt.Sprintf("over_fetch_k must be positive, got %d", c.OverFetchK)
+	case c.TargetK <= 0:
+		problem = fmt.Sprintf("target_k must be positive, got %d", c.TargetK)
+	case c.TargetK > c.OverFetchK:
+		problem = fmt.Sprintf("target_k (%d) must not exceed over_fetch_k (%d)", c.TargetK, c.OverFetchK)
+	case c.TokenBudget < 0:
+		problem = fmt.Sprintf("token budget must be non-negative, got %d", c.TokenBudget)
+	case c.ClusterThreshold <= 0 || c.ClusterThreshold > 2:
+		problem = fmt.Sprintf("cluster threshold must be in (0, 2], got %g", c.ClusterThreshold)
+	case c.MMRLambda < 0 || c.MMRLambda > 1:
+		problem = fmt.Sprintf("mmr lambda must be in [0, 1], got %g", c.MMRLambda)
+	case c.RecencyWeight < 0 || c.RecencyWeight > 1:
+		problem = fmt.Sprintf("recency weight must be in [0, 1], got %g", c.RecencyWeight)
+	case c.RecencyHalfLife < 0:
+		problem = fmt.Sprintf("recency half-life must be non-negative, got %s", c.RecencyHalfLife)
+	case c.MinScore < 0:
+		problem = fmt.Sprintf("min score must be non-negative, got %g", c.MinScore)
+	}
+	if problem == "" {
+		switch c.ClusterLinkage {
+		case "", "single", "complete", "average":
+		default:
+			problem = fmt.Sprintf("unknown cluster linkage %q", c.ClusterLinkage)
+		}
+	}
+	if problem == "" {
+		switch c.SelectionStrategy {
+		case "", SelectByScore, SelectByCentroid, SelectByLength, SelectByHybrid:
+		default:
+			problem = fmt.Sprintf("unknown selection strategy %q", c.SelectionStrategy)
+		}
+	}
+
+	if problem != "" {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid broker config: %s", problem))
+	}
+	return nil
+}
+
+// resultCacheKey derives a cache key from everything that affects the
+// result of a non-session request. Returns "" if the request cannot be
+// keyed (e.g. a filter value that does not marshal to JSON).
+func (b *Broker) resultCacheKey(req *types.RetrievalRequest) string {
+	parts := resultParams(req, b.cfg)
+	if parts == nil {
+		return ""
+	}
+
+	h := sha256.New()
+	buf := make([]byte, 4)
+	for _, v := range req.QueryEmbedding {
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(v))
+		h.Write(buf)
+	}
+	// Fanned-out vectors are length-prefixed so that different splits of
+	// the same values hash differently.
+	for _, vec := range req.QueryEmbeddings {
+		binary.LittleEndian.PutUint32(buf, uint32(len(vec)))
+		h.Write(buf)
+		for _, v := range vec {
+			binary.LittleEndian.PutUint32(buf, math.Float32bits(v))
+			h.Write(buf)
+		}
+	}
+	h.Write(parts)
+	return "broker:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// queryCacheKey keys the result of a plain text query by its text, the
+// namespace and other request params, and the broker and embedding
+// config, so it can be looked up before the query is embedded. Returns
+// "" for requests with vectors or several queries, which resultCacheKey
+// keys once they are embedded.
+func (b *Broker) queryCacheKey(req *types.RetrievalRequest) string {
+	if req.Query == "" || b.embedder == nil || len(req.QueryEmbedding) > 0 || len(req.Queries) > 0 || len(req.QueryEmbeddings) > 0 {
+		return ""
+	}
+	parts := resultParams(req, b.cfg)
+	if parts == nil {
+		return ""
+	}
+	sum := sha256.Sum256(append([]byte(b.embeddingContract()+"\x00"+req.Combine+"\x00"), parts...))
+	return cache.CacheKeyForQuery("broker:"+hex.EncodeToString(sum[:16]), req.Query, b.cfg.TargetK)
+}
+
+// resultParams marshals everything besides the query that affects the
+// result of a request, or returns nil if a value (e.g. in a filter) does
+// not marshal to JSON. Maps marshal with sorted keys, so equal filters
+// hash equally.
+func resultParams(req *types.RetrievalRequest, cfg BrokerConfig) []byte {
+	parts, err := json.Marshal([]interface{}{req.Namespace, req.Filter, req.Exclude, req.MinScore, req.ExcludeFilter, req.Threshold, req.Lambda, req.Identity, req.DedupHints, req.Explain, req.Namespaces, req.Stages, req.Selection, req.Model, req.Compress, req.Mode, req.Redact, req.TopK, cfg})
+	if err != nil {
+		return nil
+	}
+	return parts
+}
+
+// cachesResults reports whether req's result is looked up in and stored
+// to the result cache. Session results depend on what the session has
+// already seen, so they are never cached.
+func (b *Broker) cachesResults(req *types.RetrievalRequest) bool {
+	return b.results != nil && req.SessionID == ""
+}
+
+// cachedResult returns the result cached under key, if any, and counts
+// the lookup as a hit or a miss. An empty key is not looked up.
+func (b *Broker) cachedResult(ctx context.Context, key string) *types.BrokerResult {
+	if key == "" {
+		return nil
+	}
+	data, err := b.results.Get(ctx, key)
+	if err == nil {
+		var result types.BrokerResult
+		if json.Unmarshal(data, &result) == nil {
+			b.resultHits.Add(1)
+			return &result
+		}
+	}
+	b.resultMisses.Add(1)
+	return nil
+}
+
+// ResultCacheStats returns how many result cache lookups hit and missed
+// since the broker was created.
+func (b *Broker) ResultCacheStats() (hits, misses int64) {
+	return b.resultHits.Load(), b.resultMisses.Load()
+}
+
+// storeResult caches result under key. Failures only cost a future miss.
+func (b *Broker) storeResult(ctx context.Context, key string, result *types.BrokerResult) {
+	if b.results == nil || key == "" {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	_ = b.results.Set(ctx, key, data, b.resultTTL)
+}
+
+// embeddingCacheKey keys a query text's embedding under the embedder's
+// model and normalization, so switching either cannot serve vectors from
+// another. Texts are hashed so long queries do not make long keys.
+func (b *Broker) embeddingCacheKey(text string) string {
+	sum := sha256.Sum256([]byte(b.embeddingContract() + "\x00" + text))
+	return "embedding:" + hex.EncodeToString(sum[:])
+}
+
+// embeddingContract names the embedder's model and normalization.
+func (b *Broker) embeddingContract() string {
+	if q, ok := b.embedder.(retriever.QueryEmbedder); ok {
+		return q.Contract()
+	}
+	return b.embedder.ModelName()
+}
+
+// cachedEmbedding returns the cached embedding of text, if any.
+func (b *Broker) cachedEmbedding(ctx context.Context, text string) []float32 {
+	data, err := b.embeddings.Get(ctx, b.embeddingCacheKey(text))
+	if err != nil || len(data)%4 != 0 {
+		return nil
+	}
+	v := make([]float32, len(data)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return v
+}
+
+// storeEmbedding caches the embedding of text. Failures only cost a
+// future miss.
+func (b *Broker) storeEmbedding(ctx context.Context, text string, v []float32) {
+	data := make([]byte, len(v)*4)
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(f))
+	}
+	_ = b.embeddings.Set(ctx, b.embeddingCacheKey(text), data, b.embeddingTTL)
+}
+
+// compressChunks applies plan's compressor. A positive model budget caps
+// the output tokens, escalating chunks as BudgetCompressor does.
+func (b *Broker) compressChunks(ctx context.Context, chunks []types.Chunk, plan stagePlan) ([]types.Chunk, error) {
+	c, opts := plan.compression.compressor, plan.compression.opts
+	if budget := plan.budget(); budget > 0 && (opts.MaxOutputTokens <= 0 || budget < opts.MaxOutputTokens) {
+		if _, ok := c.(*compress.BudgetCompressor); !ok {
+			c = compress.NewBudgetCompressor(c)
+		}
+		opts.MaxOutputTokens = budget
+	}
+	observeStage(ctx, StageCompression, len(chunks))
+	compressed, _, err := c.Compress(ctx, chunks, opts)
+	if err != nil {
+		return nil, fmt.Errorf("compression failed: %w", err)
+	}
+	return compressed, nil
+}
+
+// compression is the compressor a request runs, with its options and
+// the method named in explain output.
+type compression struct {
+	compressor compress.Compressor
+	opts       compress.Options
+	method     string
+}
+
+// compressionFor resolves the compressor set with WithCompression, or the
+// default one, under the broker's Compress settings and the request's
+// overrides o, which may be nil. An invalid override is an errs.ErrConfig
+// error.
+func (b *Broker) compressionFor(o *types.CompressOptions) (compression, error) {
+	out := compression{compressor: b.compressor, opts: b.compressOpts}
+	if out.compressor == nil {
+		d := defaultCompressor()
+		out = compression{compressor: d.compressor, opts: d.opts, method: "extractive"}
+	}
+
+	settings := b.cfg.Compress
+	if o != nil {
+		settings.Mode = cmp.Or(o.Mode, settings.Mode)
+		settings.TargetReduction = cmp.Or(o.TargetReduction, settings.TargetReduction)
+		if o.PreserveStructure != nil {
+			settings.PreserveStructure = o.PreserveStructure
+		}
+		settings.MergeTokens = cmp.Or(o.MergeTokens, settings.MergeTokens)
+	}
+	if settings.Mode != "" {
+		mode, err := compress.ParseMode(settings.Mode)
+		if err != nil {
+			return compression{}, errs.Wrap(errs.ErrConfig, err)
+		}
+		out.compressor, _ = compress.ForMode(mode)
+		out.opts.Mode, out.method = mode, string(mode)
+	}
+	if settings.TargetReduction != 0 {
+		if settings.TargetReduction < 0 || settings.TargetReduction > 1 {
+			return compression{}, errs.Wrap(errs.ErrConfig, fmt.Errorf("target_reduction must be in (0, 1], got %g", settings.TargetReduction))
+		}
+		out.opts.TargetReduction = settings.TargetReduction
+	}
+	if settings.PreserveStructure != nil {
+		out.opts.PreserveStructure = *settings.PreserveStructure
+	}
+	if settings.MergeTokens < 0 {
+		return compression{}, errs.Wrap(errs.ErrConfig, fmt.Errorf("merge_tokens must be non-negative, got %d", settings.MergeTokens))
+	}
+	out.opts.MergeTokens = settings.MergeTokens
+	return out, nil
+}
+
+// recordCompression adds the tokens of chunks before and after
+// compression, and the chunks it changed, to stats.
+func recordCompression(stats *types.BrokerStats, before, after []types.Chunk) {
+	original := make(map[string]string, len(before))
+	for _, c := range before {
+		stats.CompressInputTokens += tokens.Count(c.Text)
+		original[c.ID] = c.Text
+	}
+	for _, c := range after {
+		stats.CompressOutputTokens += tokens.Count(c.Text)
+		if text, ok := original[c.ID]; ok && text != c.Text {
+			stats.Compressed++
+		}
+	}
+}
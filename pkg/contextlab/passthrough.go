@@ -0,0 +1,101 @@
+package contextlab
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Retrieval modes, set per request with types.RetrievalRequest.Mode.
+const (
+	// ModeDedupe runs the pipeline. It is the default.
+	ModeDedupe = "dedupe"
+
+	// ModePassthrough returns what plain retrieval would: the
+	// highest-scoring candidates, with their retriever scores and text.
+	// Candidates are still clustered, for analysis only, so each returned
+	// chunk carries the cluster it would have been deduplicated in. Teams
+	// can then compare answers with and without deduplication through the
+	// same endpoint and response schema.
+	ModePassthrough = "passthrough"
+)
+
+// passthrough clusters candidates without selecting from them, and
+// returns the highest-scoring candidates annotated with their clusters,
+// as ModePassthrough describes. Filters, session tracking, redaction, and
+// the model budget still apply; MMR, compression, and the other declared
+// stages do not.
+func (b *Broker) passthrough(ctx context.Context, req *types.RetrievalRequest, plan stagePlan, candidates []types.Chunk, stats *types.BrokerStats) ([]types.Chunk, []string, error) {
+	stats.Passthrough = true
+
+	var clusters *types.ClusterResult
+	if plan.cluster {
+		if _, err := b.budgeted(ctx, PipelineCluster, stats, func(ctx context.Context) error {
+			observeStage(ctx, StageClustering, len(candidates))
+			clusterStart := time.Now()
+			result, err := b.requestClusterer(req).ClusterContext(ctx, candidates)
+			if err != nil {
+				return fmt.Errorf("clustering interrupted: %w", err)
+			}
+			stats.ClusteringLatency = time.Since(clusterStart)
+			clusters = result
+			return nil
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Clustering set each candidate's ClusterID in place
+	chunks := b.topByScore(candidates, plan)
+	if clusters != nil {
+		stats.Clustered = clusters.ClusterCount
+		stats.Vetoed = clusters.Vetoed
+		stats.MatrixOverflow = clusters.Overflow
+		annotateWouldKeep(chunks, clusters, b.selectorFor(plan.strategy).Select(clusters))
+		stats.Notes = append(stats.Notes, fmt.Sprintf("passthrough: deduplication skipped; it would keep %d of %d candidates", clusters.ClusterCount, len(candidates)))
+	} else {
+		stats.Notes = append(stats.Notes, "passthrough: deduplication skipped")
+	}
+
+	// A declared redact stage still runs, with its own params
+	p := &pipelineRun{req: req, plan: plan, chunks: chunks, stats: stats}
+	for _, stage := range b.stages {
+		if stage.name() != PipelineRedact || !plan.declared(PipelineRedact) || len(p.chunks) == 0 {
+			continue
+		}
+		if err := stage.run(ctx, b, p); err != nil {
+			return nil, nil, err
+		}
+	}
+	return p.chunks, p.ran, nil
+}
+
+// annotateWouldKeep sets HintClusterSize and HintWouldKeep on chunks
+// from clusters, given the representatives selection would keep.
+// Metadata maps are copied before they are changed, as in AnnotateDedup.
+func annotateWouldKeep(chunks []types.Chunk, clusters *types.ClusterResult, representatives []types.Chunk) {
+	sizes := make(map[int]int, len(clusters.Clusters))
+	for _, c := range clusters.Clusters {
+		sizes[c.ID] = c.Size()
+	}
+	keep := make(map[string]bool, len(representatives))
+	for _, r := range representatives {
+		keep[r.ID] = true
+	}
+
+	for i := range chunks {
+		size, ok := sizes[chunks[i].ClusterID]
+		if !ok {
+			continue
+		}
+		metadata := make(map[string]interface{}, len(chunks[i].Metadata)+2)
+		for k, v := range chunks[i].Metadata {
+			metadata[k] = v
+		}
+		metadata[HintClusterSize] = size
+		metadata[HintWouldKeep] = keep[chunks[i].ID]
+		chunks[i].Metadata = metadata
+	}
+}
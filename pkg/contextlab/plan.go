@@ -0,0 +1,157 @@
+package contextlab
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/models"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// stagePlan is the optional stages one request runs, after its
+// types.StageToggles and selection override are applied to the broker's
+// settings.
+type stagePlan struct {
+	toggles  types.StageToggles
+	cluster  bool
+	compress bool
+	redact   bool
+	classify bool
+
+	// typed redacts with typed placeholders; see types.RetrievalRequest.Redact.
+	typed bool
+
+	strategy SelectionStrategy
+
+	// passthrough skips deduplication; see ModePassthrough.
+	passthrough bool
+
+	// compression is what compress runs, when it is on.
+	compression compression
+
+	// model is the request's model profile, or nil for no budget.
+	model *models.Profile
+}
+
+// planFor resolves req's mode, stage toggles, selection override,
+// compression settings, and model profile. An unknown mode, selection
+// strategy, or model, or invalid compression settings, is an
+// errs.ErrConfig error.
+func (b *Broker) planFor(req *types.RetrievalRequest) (stagePlan, error) {
+	p := stagePlan{
+		toggles:  req.Stages,
+		cluster:  enabled(req.Stages.Clustering, !b.cfg.DisableClustering),
+		compress: enabled(req.Stages.Compression, b.cfg.EnableCompression || b.compressor != nil || req.Compress != nil),
+		redact:   enabled(req.Stages.Redaction, b.cfg.EnableRedaction || req.Redact),
+		typed:    req.Redact,
+		classify: enabled(req.Stages.Classification, b.cfg.EnableClassification),
+		strategy: b.cfg.SelectionStrategy,
+	}
+	if p.strategy == "" {
+		p.strategy = SelectByScore
+	}
+	if req.Selection != "" {
+		strategy, err := ParseSelectionStrategy(req.Selection)
+		if err != nil {
+			return stagePlan{}, err
+		}
+		p.strategy = strategy
+	}
+	switch req.Mode {
+	case "", ModeDedupe:
+	case ModePassthrough:
+		// Returned text is left as retrieved, apart from redaction
+		p.passthrough = true
+		p.compress = false
+	default:
+		return stagePlan{}, errs.Wrap(errs.ErrConfig, fmt.Errorf("unknown mode %q (supported: %s, %s)", req.Mode, ModeDedupe, ModePassthrough))
+	}
+	if p.compress {
+		var err error
+		if p.compression, err = b.compressionFor(req.Compress); err != nil {
+			return stagePlan{}, err
+		}
+	}
+	model, err := b.models.Resolve(req.Model)
+	if err != nil {
+		return stagePlan{}, err
+	}
+	p.model = model
+	return p, nil
+}
+
+// merge reports whether compression rebuilds representatives from their
+// clusters at selection; see compress.ModeClusterMerge.
+func (p stagePlan) merge() bool {
+	return p.compress && p.compression.opts.Mode == compress.ModeClusterMerge
+}
+
+// budget is the request model's token budget, or 0 for none.
+func (p stagePlan) budget() int {
+	if p.model == nil {
+		return 0
+	}
+	return p.model.Budget()
+}
+
+// declared reports whether a stage declared with WithStages runs: it does
+// unless the request turns it off.
+func (p stagePlan) declared(name string) bool {
+	var toggle *bool
+	switch name {
+	case PipelineCluster, PipelineSelect:
+		toggle = p.toggles.Clustering
+	case PipelineMMR:
+		toggle = p.toggles.MMR
+	case PipelineCompress:
+		toggle = p.toggles.Compression
+	case PipelineRedact:
+		toggle = p.toggles.Redaction
+	case PipelineRerank:
+		toggle = p.toggles.Rerank
+	}
+	return enabled(toggle, true)
+}
+
+// enabled returns the toggle's value, or def when it is unset.
+func enabled(toggle *bool, def bool) bool {
+	if toggle != nil {
+		return *toggle
+	}
+	return def
+}
+
+// selectorFor returns the broker's selector, or a new one when strategy
+// differs from the broker's.
+func (b *Broker) selectorFor(strategy SelectionStrategy) *Selector {
+	if strategy == b.selector.cfg.Strategy {
+		return b.selector
+	}
+	return NewSelector(SelectorConfig{Strategy: strategy})
+}
+
+// mmrStages returns the stage names echoed for an MMR pass with cfg.
+func mmrStages(cfg MMRConfig) []string {
+	if cfg.RecencyWeight > 0 {
+		return []string{PipelineScoring, PipelineMMR}
+	}
+	return []string{PipelineMMR}
+}
+
+// ProcessRequest runs the pipeline after retrieval on pre-fetched chunks,
+// honoring req as Retrieve does. Unlike ProcessChunks it applies the
+// request's filters, stage toggles, and selection override.
+func (b *Broker) ProcessRequest(ctx context.Context, req *types.RetrievalRequest, chunks []types.Chunk) (*types.BrokerResult, error) {
+	start := time.Now()
+	ctx, cancel := withBudget(ctx, req)
+	defer cancel()
+	out, err := b.dedupe(ctx, req, chunks, types.BrokerStats{Retrieved: len(chunks)})
+	if err != nil {
+		return nil, err
+	}
+	out.Stats.TotalLatency = time.Since(start)
+	return out, nil
+}
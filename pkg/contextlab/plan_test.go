@@ -0,0 +1,276 @@
+package contextlab
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/sensitivity"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func boolPtr(v bool) *bool { return &v }
+
+func TestBroker_StageToggles(t *testing.T) {
+	chunks := orthogonalChunks(4)
+	// b duplicates a
+	chunks[1].Embedding = chunks[0].Embedding
+	chunks[2].Text = "Reach alice@example.com for access."
+
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: chunks}, WithTargetK(3), WithoutMMR())
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		stages types.StageToggles
+		ids    string
+		echo   string
+	}{
+		{"defaults", types.StageToggles{}, "acd", "retrieve,cluster,select"},
+		{"no clustering", types.StageToggles{Clustering: boolPtr(false)}, "abc", "retrieve"},
+		{"mmr on", types.StageToggles{MMR: boolPtr(true)}, "acd", "retrieve,cluster,select"},
+		{"redaction", types.StageToggles{Redaction: boolPtr(true)}, "acd", "retrieve,cluster,select,redact"},
+		{"no clustering with mmr", types.StageToggles{Clustering: boolPtr(false), MMR: boolPtr(true)}, "acd", "retrieve,mmr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+				QueryEmbedding: []float32{1, 0, 0, 0},
+				Stages:         tt.stages,
+			})
+			if err != nil {
+				t.Fatalf("Retrieve: %v", err)
+			}
+			if got := chunkIDs(result.Chunks); got != tt.ids {
+				t.Errorf("chunks = %q, want %q", got, tt.ids)
+			}
+			if got := strings.Join(result.Stages, ","); got != tt.echo {
+				t.Errorf("stages = %q, want %q", got, tt.echo)
+			}
+		})
+	}
+}
+
+func TestBroker_StageToggles_Redaction(t *testing.T) {
+	chunks := orthogonalChunks(2)
+	chunks[1].Text = "Reach alice@example.com for access."
+
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: chunks}, WithTargetK(2), WithRedaction(true))
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if result.Chunks[1].Text != "Reach [REDACTED] for access." || result.Stats.Redacted != 1 {
+		t.Errorf("text = %q, redacted = %d", result.Chunks[1].Text, result.Stats.Redacted)
+	}
+
+	// The request can turn redaction back off
+	result, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1, 0},
+		Stages:         types.StageToggles{Redaction: boolPtr(false)},
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if result.Chunks[1].Text != chunks[1].Text {
+		t.Errorf("text = %q, want it unredacted", result.Chunks[1].Text)
+	}
+}
+
+func TestBroker_TypedRedaction(t *testing.T) {
+	chunks := orthogonalChunks(2)
+	chunks[1].Text = "Reach alice@example.com for access."
+
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: chunks}, WithTargetK(2))
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0}, Redact: true})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	got := result.Chunks[1]
+	if got.Text != "Reach [EMAIL] for access." || result.Stats.Redacted != 1 {
+		t.Fatalf("text = %q, redacted = %d", got.Text, result.Stats.Redacted)
+	}
+	spans, _ := got.Metadata[MetaRedactions].([]sensitivity.Span)
+	want := sensitivity.Span{Pattern: "email_address", Placeholder: "[EMAIL]", Start: 6, End: 23, Offset: 6}
+	if len(spans) != 1 || spans[0] != want {
+		t.Errorf("redactions = %+v, want [%+v]", spans, want)
+	}
+	if _, ok := result.Chunks[0].Metadata[MetaRedactions]; ok {
+		t.Error("clean chunk has redactions")
+	}
+
+	// Stages can still turn redaction off
+	result, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1, 0},
+		Redact:         true,
+		Stages:         types.StageToggles{Redaction: boolPtr(false)},
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if result.Chunks[1].Text != chunks[1].Text {
+		t.Errorf("text = %q, want it unredacted", result.Chunks[1].Text)
+	}
+}
+
+func TestBroker_StageToggles_Classification(t *testing.T) {
+	chunks := orthogonalChunks(2)
+	chunks[1].Text = "Reach alice@example.com for access."
+
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: chunks}, WithTargetK(2), WithClassification(true))
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if got := result.Chunks[0].Metadata[MetaSensitivity]; got != "none" {
+		t.Errorf("first chunk sensitivity = %v, want none", got)
+	}
+	if got := result.Chunks[1].Metadata[MetaSensitivity]; got != "pii" {
+		t.Errorf("second chunk sensitivity = %v, want pii", got)
+	}
+	if got, _ := result.Chunks[1].Metadata[MetaSensitivityMatches].([]string); len(got) != 1 || got[0] != "email_address" {
+		t.Errorf("second chunk matches = %v, want [email_address]", got)
+	}
+	if result.Stats.Sensitivity != "pii" {
+		t.Errorf("stats sensitivity = %q, want pii", result.Stats.Sensitivity)
+	}
+	if got := result.Stages[len(result.Stages)-1]; got != PipelineClassify {
+		t.Errorf("last stage = %q, want %q", got, PipelineClassify)
+	}
+	// The retriever's chunks are left untouched
+	if _, ok := chunks[1].Metadata[MetaSensitivity]; ok {
+		t.Error("classification changed the retriever's metadata")
+	}
+
+	// The request can turn classification back off
+	result, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1, 0},
+		Stages:         types.StageToggles{Classification: boolPtr(false)},
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if _, ok := result.Chunks[1].Metadata[MetaSensitivity]; ok || result.Stats.Sensitivity != "" {
+		t.Errorf("expected no classification, got %v / %q", result.Chunks[1].Metadata, result.Stats.Sensitivity)
+	}
+}
+
+func TestBroker_StageToggles_Scoring(t *testing.T) {
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: orthogonalChunks(4)}, WithTargetK(2), WithMMR(0.5))
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+	broker.cfg.RecencyWeight = 0.3
+	broker.mmr = NewMMR(mmrConfig(broker.cfg))
+
+	for _, tt := range []struct {
+		scoring *bool
+		echo    string
+	}{
+		{nil, "retrieve,cluster,select,scoring,mmr"},
+		{boolPtr(false), "retrieve,cluster,select,mmr"},
+	} {
+		result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+			QueryEmbedding: []float32{1, 0, 0, 0},
+			Stages:         types.StageToggles{Scoring: tt.scoring},
+		})
+		if err != nil {
+			t.Fatalf("Retrieve: %v", err)
+		}
+		if got := strings.Join(result.Stages, ","); got != tt.echo {
+			t.Errorf("stages = %q, want %q", got, tt.echo)
+		}
+	}
+}
+
+func TestBroker_SelectionOverride(t *testing.T) {
+	chunks := orthogonalChunks(2)
+	chunks[1].Embedding = chunks[0].Embedding
+	chunks[1].Text = "a much longer chunk that the length strategy prefers"
+
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: chunks}, WithTargetK(2))
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+	for selection, want := range map[string]string{"": "a", "score": "a", "length": "b"} {
+		result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+			QueryEmbedding: []float32{1, 0},
+			Selection:      selection,
+		})
+		if err != nil {
+			t.Fatalf("Retrieve(%q): %v", selection, err)
+		}
+		if got := chunkIDs(result.Chunks); got != want {
+			t.Errorf("selection %q: chunks = %q, want %q", selection, got, want)
+		}
+	}
+
+	_, err = broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0}, Selection: "newest"})
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Fatalf("error = %v, want ErrConfig", err)
+	}
+}
+
+func TestBroker_WithStages_Toggles(t *testing.T) {
+	chunks := orthogonalChunks(3)
+	chunks[1].Embedding = chunks[0].Embedding
+
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: chunks},
+		WithTargetK(3),
+		WithStages(
+			StageSpec{Name: PipelineRetrieve},
+			StageSpec{Name: PipelineCluster},
+			StageSpec{Name: PipelineSelect},
+			StageSpec{Name: PipelineRedact},
+		),
+	)
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1, 0, 0},
+		Stages:         types.StageToggles{Clustering: boolPtr(false), Redaction: boolPtr(false)},
+	})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if got := chunkIDs(result.Chunks); got != "abc" {
+		t.Errorf("chunks = %q, want abc", got)
+	}
+	if got := strings.Join(result.Stages, ","); got != "retrieve" {
+		t.Errorf("stages = %q, want retrieve", got)
+	}
+}
+
+func TestBroker_ProcessRequest(t *testing.T) {
+	chunks := orthogonalChunks(3)
+	chunks[1].Embedding = chunks[0].Embedding
+
+	cfg := DefaultBrokerConfig()
+	cfg.TargetK = 3
+	broker := NewBroker(nil, cfg)
+	result, err := broker.ProcessRequest(context.Background(), &types.RetrievalRequest{Selection: "score"}, chunks)
+	if err != nil {
+		t.Fatalf("ProcessRequest: %v", err)
+	}
+	if got := chunkIDs(result.Chunks); got != "ac" {
+		t.Errorf("chunks = %q, want ac", got)
+	}
+	if got := strings.Join(result.Stages, ","); got != "cluster,select" {
+		t.Errorf("stages = %q", got)
+	}
+}
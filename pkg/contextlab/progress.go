@@ -0,0 +1,39 @@
+package contextlab
+
+import (
+	"context"
+
+	"github.com/Siddhant-K-code/distill/pkg/profiling"
+)
+
+// Stages reported to a stage observer, in the order a retrieval runs
+// them. Stages that do not apply to a request are skipped.
+const (
+	StageEmbedding   = "embedding"
+	StageRetrieval   = "retrieval"
+	StageEnrichment  = "enrichment"
+	StageClustering  = "clustering"
+	StageSelection   = "selection"
+	StageMMR         = "mmr"
+	StageReranking   = "reranking"
+	StageCompression = "compression"
+	StageRedaction   = "redaction"
+)
+
+type stageObserverKey struct{}
+
+// WithStageObserver returns a context that makes broker calls report each
+// stage they enter to fn, with the number of items going into it. Callers
+// use it to show progress on long requests.
+func WithStageObserver(ctx context.Context, fn func(stage string, n int)) context.Context {
+	return context.WithValue(ctx, stageObserverKey{}, fn)
+}
+
+// observeStage reports stage to the stage observer and, while profiling
+// is on, labels the samples taken from here on with it.
+func observeStage(ctx context.Context, stage string, n int) {
+	profiling.SetStage(ctx, stage)
+	if fn, _ := ctx.Value(stageObserverKey{}).(func(string, int)); fn != nil {
+		fn(stage, n)
+	}
+}
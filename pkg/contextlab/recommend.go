@@ -0,0 +1,308 @@
+package contextlab
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	distillmath "github.com/Siddhant-K-code/distill/pkg/math"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Sample draws up to n chunks with embeddings from namespace by querying
+// with random probe vectors of dimension dim. Zero dim uses the
+// embedder's dimension. The retrievers expose no scan, so the sample is
+// approximate: each probe returns a neighborhood, which makes
+// near-duplicates likely to be sampled together. Tombstoned duplicates
+// are skipped unless the broker includes them. If seed is 0, the current
+// time is used.
+func (b *Broker) Sample(ctx context.Context, namespace string, n, dim int, seed int64) ([]types.Chunk, error) {
+	return b.sample(ctx, namespace, n, dim, seed, ACL{}, nil)
+}
+
+// SampleFor is Sample for a caller whose results are returned to them:
+// chunks id may not see under the broker's ACL are never sampled.
+func (b *Broker) SampleFor(ctx context.Context, namespace string, n, dim int, seed int64, id *types.Identity) ([]types.Chunk, error) {
+	return b.sample(ctx, namespace, n, dim, seed, b.acl, id)
+}
+
+func (b *Broker) sample(ctx context.Context, namespace string, n, dim int, seed int64, acl ACL, id *types.Identity) ([]types.Chunk, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if dim <= 0 {
+		if b.embedder == nil {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("sampling needs an embedding provider or an explicit dimension"))
+		}
+		dim = b.embedder.Dimension()
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	// Many small neighborhoods spread the sample further than a few
+	// large ones; asking each probe for twice its share covers overlap.
+	probes := max(4, n/25)
+	perProbe := (2*n + probes - 1) / probes
+
+	seen := make(map[string]bool, n)
+	var out []types.Chunk
+	for p := 0; p < probes && len(out) < n; p++ {
+		probe := make([]float32, dim)
+		for d := range probe {
+			probe[d] = float32(rng.NormFloat64())
+		}
+		req := &types.RetrievalRequest{
+			QueryEmbedding:    probe,
+			TopK:              perProbe,
+			Namespace:         namespace,
+			IncludeEmbeddings: true,
+			IncludeMetadata:   b.cfg.IncludeMetadata || acl.Enabled,
+		}
+		b.excludeTombstoned(req)
+		result, err := b.retriever.Query(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("sampling failed: %w", err)
+		}
+		visible, _ := acl.Filter(result.Chunks, id)
+		for _, c := range visible {
+			if len(out) == n {
+				break
+			}
+			if seen[c.ID] || len(c.Embedding) == 0 {
+				continue
+			}
+			seen[c.ID] = true
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// Percentiles holds the 10th, 50th, and 90th percentiles of a
+// distribution.
+type Percentiles struct {
+	P10, P50, P90 float64
+}
+
+// Profile describes a sample of a namespace: how far apart its
+// embeddings sit and how long its chunks are.
+type Profile struct {
+	SampleSize int
+	Dimension  int
+
+	// NearestDistance is the distribution of cosine distances from each
+	// sampled chunk to its nearest sampled neighbor; PairwiseDistance is
+	// over all sampled pairs.
+	NearestDistance  Percentiles
+	PairwiseDistance Percentiles
+
+	// AvgTokens is the mean estimated chunk length in tokens.
+	AvgTokens float64
+
+	// StructuredFraction is the share of chunks that look like JSON,
+	// XML, or tables, which placeholder compression summarizes.
+	StructuredFraction float64
+
+	// nearest holds each chunk's nearest-neighbor distance, sorted.
+	nearest []float64
+}
+
+// ProfileChunks measures chunks' embedding distances and lengths. Chunks
+// without embeddings count toward lengths only.
+func ProfileChunks(chunks []types.Chunk) Profile {
+	var p Profile
+	var embedded []types.Chunk
+	tokens, structured := 0, 0
+	for _, c := range chunks {
+		tokens += defaultTokens(c.Text)
+		if looksStructured(c.Text) {
+			structured++
+		}
+		if len(c.Embedding) > 0 {
+			embedded = append(embedded, c)
+		}
+	}
+	p.SampleSize = len(chunks)
+	if len(chunks) > 0 {
+		p.AvgTokens = float64(tokens) / float64(len(chunks))
+		p.StructuredFraction = float64(structured) / float64(len(chunks))
+	}
+	if len(embedded) < 2 {
+		return p
+	}
+	p.Dimension = len(embedded[0].Embedding)
+
+	nearest := make([]float64, len(embedded))
+	for i := range nearest {
+		nearest[i] = math.Inf(1)
+	}
+	pairwise := make([]float64, 0, len(embedded)*(len(embedded)-1)/2)
+	for i := range embedded {
+		for j := i + 1; j < len(embedded); j++ {
+			d := distillmath.CosineDistance(embedded[i].Embedding, embedded[j].Embedding)
+			pairwise = append(pairwise, d)
+			nearest[i] = math.Min(nearest[i], d)
+			nearest[j] = math.Min(nearest[j], d)
+		}
+	}
+	sort.Float64s(nearest)
+	sort.Float64s(pairwise)
+	p.nearest = nearest
+	p.NearestDistance = percentiles(nearest)
+	p.PairwiseDistance = percentiles(pairwise)
+	return p
+}
+
+// percentiles reads percentiles from sorted values.
+func percentiles(sorted []float64) Percentiles {
+	at := func(q float64) float64 {
+		return sorted[int(q*float64(len(sorted)-1))]
+	}
+	return Percentiles{P10: at(0.1), P50: at(0.5), P90: at(0.9)}
+}
+
+// looksStructured reports whether text is mostly data rather than prose.
+func looksStructured(text string) bool {
+	t := strings.TrimSpace(text)
+	if t == "" {
+		return false
+	}
+	switch t[0] {
+	case '{', '[', '<':
+		return true
+	}
+	return strings.Count(t, "|") >= 4 && strings.Contains(t, "\n")
+}
+
+// Recommendation is a suggested configuration for a namespace.
+type Recommendation struct {
+	Threshold  float64
+	Linkage    string
+	Lambda     float64
+	TargetK    int
+	OverFetchK int
+
+	// Compression is the suggested compression mode, empty when chunks
+	// are short enough to leave alone.
+	Compression compress.Mode
+
+	// Redundancy is the share of sampled chunks with a neighbor within
+	// Threshold, which clustering would merge.
+	Redundancy float64
+
+	// Confidence is "low", "medium", or "high", from the sample size.
+	Confidence string
+
+	// Notes explain the choices and anything that weakens them.
+	Notes []string
+}
+
+// Confidence levels reported in Recommendation.Confidence.
+const (
+	ConfidenceLow    = "low"
+	ConfidenceMedium = "medium"
+	ConfidenceHigh   = "high"
+)
+
+// Recommendation tuning. The 0.15 default threshold suits text
+// embeddings whose unrelated chunks sit around 0.75 apart, a fifth of
+// that distance; target_k fills a context budget of recommendBudget
+// tokens, which the default of 8 does for 250-token chunks.
+const (
+	thresholdRatio     = 0.2
+	minThreshold       = 0.05
+	maxThreshold       = 0.30
+	recommendBudget    = 2000
+	compressTokens     = 400
+	structuredShare    = 0.3
+	denseRedundancy    = 0.3
+	highConfidenceSize = 150
+	lowConfidenceSize  = 30
+)
+
+// Recommend suggests settings for the namespace p profiles: a threshold
+// scaled to how far apart its unrelated chunks sit, complete linkage and
+// a more diverse lambda when much of it is redundant, a target_k that
+// fits its chunk length into a fixed token budget, and compression for
+// long or structured chunks.
+func Recommend(p Profile) Recommendation {
+	def := DefaultBrokerConfig()
+	rec := Recommendation{
+		Threshold:  def.ClusterThreshold,
+		Linkage:    def.ClusterLinkage,
+		Lambda:     def.MMRLambda,
+		TargetK:    def.TargetK,
+		OverFetchK: def.OverFetchK,
+		Confidence: ConfidenceHigh,
+	}
+	switch {
+	case p.SampleSize < lowConfidenceSize:
+		rec.Confidence = ConfidenceLow
+		rec.Notes = append(rec.Notes, fmt.Sprintf("Only %d chunks sampled; treat these as a starting point.", p.SampleSize))
+	case p.SampleSize < highConfidenceSize:
+		rec.Confidence = ConfidenceMedium
+	}
+
+	if p.AvgTokens > 0 {
+		rec.TargetK = clampInt(int(math.Round(recommendBudget/p.AvgTokens)), 3, 20)
+	}
+	switch long, structured := p.AvgTokens >= compressTokens, p.StructuredFraction >= structuredShare; {
+	case long && structured:
+		rec.Compression = compress.ModeHybrid
+	case structured:
+		rec.Compression = compress.ModePlaceholder
+	case long:
+		rec.Compression = compress.ModeExtractive
+	}
+	if rec.Compression != "" {
+		rec.Notes = append(rec.Notes, fmt.Sprintf("Chunks average %.0f tokens and %.0f%% look structured; %s compression fits them.",
+			p.AvgTokens, 100*p.StructuredFraction, rec.Compression))
+	}
+
+	if len(p.nearest) == 0 {
+		rec.Confidence = ConfidenceLow
+		rec.Notes = append(rec.Notes, "No embeddings in the sample; distance-based settings are left at their defaults.")
+		rec.OverFetchK = 5 * rec.TargetK
+		return rec
+	}
+
+	rec.Threshold = math.Round(100*clampFloat(thresholdRatio*p.PairwiseDistance.P50, minThreshold, maxThreshold)) / 100
+	rec.Notes = append(rec.Notes, fmt.Sprintf("Sampled chunks sit a median %.2f apart; the threshold is a fifth of that.", p.PairwiseDistance.P50))
+
+	near := 0
+	for _, d := range p.nearest {
+		if d <= rec.Threshold {
+			rec.Redundancy++
+		} else if d <= 1.25*rec.Threshold {
+			near++
+		}
+	}
+	rec.Redundancy /= float64(len(p.nearest))
+	if float64(near)/float64(len(p.nearest)) >= 0.2 {
+		rec.Notes = append(rec.Notes, "Many chunks have a neighbor just past the threshold; small changes to it will change results noticeably.")
+	}
+
+	if rec.Redundancy >= denseRedundancy {
+		rec.Linkage = "complete"
+		rec.Notes = append(rec.Notes, fmt.Sprintf("%.0f%% of sampled chunks have a near-duplicate; complete linkage keeps groups of them from chaining distinct chunks together.", 100*rec.Redundancy))
+	}
+	rec.Lambda = math.Round(20*clampFloat(0.7-0.5*rec.Redundancy, 0.3, 0.7)) / 20
+	rec.OverFetchK = int(math.Round(float64(rec.TargetK) * (3 + 2*rec.Redundancy)))
+	return rec
+}
+
+func clampInt(v, lo, hi int) int {
+	return min(max(v, lo), hi)
+}
+
+func clampFloat(v, lo, hi float64) float64 {
+	return math.Min(math.Max(v, lo), hi)
+}
@@ -0,0 +1,99 @@
+package contextlab
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestBroker_Sample(t *testing.T) {
+	b := newFakeBroker(t, DefaultBrokerConfig())
+
+	sample, err := b.Sample(context.Background(), "", 100, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sample) != 100 {
+		t.Fatalf("expected 100 chunks, got %d", len(sample))
+	}
+	seen := make(map[string]bool)
+	for _, c := range sample {
+		if seen[c.ID] {
+			t.Fatalf("chunk %s sampled twice", c.ID)
+		}
+		seen[c.ID] = true
+		if len(c.Embedding) == 0 {
+			t.Fatalf("chunk %s has no embedding", c.ID)
+		}
+	}
+	if got := topics(sample); len(got) < 5 {
+		t.Errorf("expected the sample to span topics, got %v", got)
+	}
+
+	// Without an embedder the dimension must be given
+	bare := NewBroker(&stubRetriever{}, DefaultBrokerConfig())
+	if _, err := bare.Sample(context.Background(), "", 10, 0, 1); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected a config error, got %v", err)
+	}
+}
+
+// sampleChunks returns n chunks of the given text drawn around a shared
+// direction, with every dupEvery-th chunk a copy of the one before it.
+func sampleChunks(n, dupEvery int, text string) []types.Chunk {
+	rng := rand.New(rand.NewSource(3))
+	vectors := modelVectors(rng, n, 64, 1.5)
+	chunks := make([]types.Chunk, n)
+	for i := range chunks {
+		if dupEvery > 0 && i%dupEvery == dupEvery-1 {
+			vectors[i] = vectors[i-1]
+		}
+		chunks[i] = types.Chunk{ID: string(rune('a' + i%26)), Text: text, Embedding: vectors[i]}
+	}
+	return chunks
+}
+
+func TestRecommend(t *testing.T) {
+	prose := strings.Repeat("word ", 250) // 251 tokens
+
+	distinct := Recommend(ProfileChunks(sampleChunks(60, 0, prose)))
+	if distinct.Redundancy != 0 || distinct.Linkage != "average" {
+		t.Errorf("distinct chunks: redundancy %v, linkage %q", distinct.Redundancy, distinct.Linkage)
+	}
+	if distinct.TargetK != 8 || distinct.Compression != "" {
+		t.Errorf("250-token chunks: target_k %d, compression %q", distinct.TargetK, distinct.Compression)
+	}
+	if distinct.Threshold < minThreshold || distinct.Threshold > maxThreshold {
+		t.Errorf("threshold %v out of range", distinct.Threshold)
+	}
+	if distinct.Confidence != ConfidenceMedium {
+		t.Errorf("60 chunks: confidence %q", distinct.Confidence)
+	}
+
+	// Half the chunks duplicated: more diversity, complete linkage
+	redundant := Recommend(ProfileChunks(sampleChunks(60, 2, prose)))
+	if redundant.Redundancy < 0.9 || redundant.Linkage != "complete" {
+		t.Errorf("duplicated chunks: redundancy %v, linkage %q", redundant.Redundancy, redundant.Linkage)
+	}
+	if redundant.Lambda >= distinct.Lambda || redundant.OverFetchK <= distinct.OverFetchK {
+		t.Errorf("expected a lower lambda and larger over-fetch, got %v/%d vs %v/%d",
+			redundant.Lambda, redundant.OverFetchK, distinct.Lambda, distinct.OverFetchK)
+	}
+
+	// Long JSON chunks
+	long := Recommend(ProfileChunks(sampleChunks(10, 0, "{"+strings.Repeat(`"k": 1, `, 83)+"}")))
+	if long.Compression != compress.ModeHybrid || long.TargetK != 4 || long.Confidence != ConfidenceLow {
+		t.Errorf("long JSON: compression %q, target_k %d, confidence %q", long.Compression, long.TargetK, long.Confidence)
+	}
+
+	// No embeddings keeps the distance settings
+	bare := Recommend(ProfileChunks([]types.Chunk{{Text: prose}, {Text: prose}}))
+	if bare.Threshold != DefaultBrokerConfig().ClusterThreshold || bare.Confidence != ConfidenceLow {
+		t.Errorf("no embeddings: threshold %v, confidence %q", bare.Threshold, bare.Confidence)
+	}
+}
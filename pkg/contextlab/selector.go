@@ -1,6 +1,10 @@
 package contextlab
 
 import (
+	"container/heap"
+	"fmt"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
 	"github.com/Siddhant-K-code/distill/pkg/math"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
@@ -25,6 +29,15 @@ const (
 	SelectByHybrid SelectionStrategy = "hybrid"
 )
 
+// ParseSelectionStrategy returns the strategy named s.
+func ParseSelectionStrategy(s string) (SelectionStrategy, error) {
+	switch st := SelectionStrategy(s); st {
+	case SelectByScore, SelectByCentroid, SelectByLength, SelectByHybrid:
+		return st, nil
+	}
+	return "", errs.Wrap(errs.ErrConfig, fmt.Errorf("unknown selection strategy %q (supported: score, centroid, length, hybrid)", s))
+}
+
 // SelectorConfig holds selection parameters.
 type SelectorConfig struct {
 	// Strategy determines the selection method.
@@ -237,7 +250,8 @@ func (s *Selector) selectByHybrid(cluster *types.Cluster) *types.Chunk {
 	return best
 }
 
-// SelectTopK selects representatives and returns the top K by score.
+// SelectTopK selects representatives and returns the top K by score,
+// highest first. Equal scores keep their representative order.
 func SelectTopK(result *types.ClusterResult, k int, strategy SelectionStrategy) []types.Chunk {
 	cfg := DefaultSelectorConfig()
 	cfg.Strategy = strategy
@@ -248,15 +262,58 @@ func SelectTopK(result *types.ClusterResult, k int, strategy SelectionStrategy)
 	if len(reps) <= k {
 		return reps
 	}
+	return topKByScore(reps, k)
+}
 
-	// Sort by score descending
-	for i := 0; i < len(reps)-1; i++ {
-		for j := i + 1; j < len(reps); j++ {
-			if reps[j].Score > reps[i].Score {
-				reps[i], reps[j] = reps[j], reps[i]
-			}
+// topKByScore returns the k highest-scoring chunks, highest first, in
+// O(n log k): a min-heap holds the best k seen so far.
+func topKByScore(chunks []types.Chunk, k int) []types.Chunk {
+	if k <= 0 {
+		return nil
+	}
+	h := &scoreHeap{chunks: chunks, idx: make([]int, 0, k)}
+	for i := range chunks {
+		if len(h.idx) < k {
+			heap.Push(h, i)
+			continue
+		}
+		// Later chunks lose ties, so only a strictly higher score enters
+		if chunks[i].Score > chunks[h.idx[0]].Score {
+			h.idx[0] = i
+			heap.Fix(h, 0)
 		}
 	}
 
-	return reps[:k]
+	out := make([]types.Chunk, len(h.idx))
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = chunks[heap.Pop(h).(int)]
+	}
+	return out
+}
+
+// scoreHeap is a min-heap of chunk indices: the root is the lowest score,
+// and among equal scores the latest chunk.
+type scoreHeap struct {
+	chunks []types.Chunk
+	idx    []int
+}
+
+func (h *scoreHeap) Len() int { return len(h.idx) }
+
+func (h *scoreHeap) Less(i, j int) bool {
+	a, b := h.chunks[h.idx[i]], h.chunks[h.idx[j]]
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	return h.idx[i] > h.idx[j]
+}
+
+func (h *scoreHeap) Swap(i, j int) { h.idx[i], h.idx[j] = h.idx[j], h.idx[i] }
+
+func (h *scoreHeap) Push(x any) { h.idx = append(h.idx, x.(int)) }
+
+func (h *scoreHeap) Pop() any {
+	last := h.idx[len(h.idx)-1]
+	h.idx = h.idx[:len(h.idx)-1]
+	return last
 }
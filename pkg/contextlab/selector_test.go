@@ -0,0 +1,44 @@
+package contextlab
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+	"testing/quick"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestTopKByScore(t *testing.T) {
+	// Matches a stable sort by score, including ties from coarse scores
+	property := func(seed int64, n, k uint8) bool {
+		rng := rand.New(rand.NewSource(seed))
+		chunks := make([]types.Chunk, n)
+		for i := range chunks {
+			chunks[i] = types.Chunk{ID: string(rune('a' + i%26)), Score: float32(rng.Intn(5)) / 4}
+		}
+		want := slices.Clone(chunks)
+		sort.SliceStable(want, func(i, j int) bool { return want[i].Score > want[j].Score })
+		want = want[:min(int(k), len(want))]
+
+		got := topKByScore(chunks, int(k))
+		return slices.EqualFunc(got, want, func(a, b types.Chunk) bool {
+			return a.ID == b.ID && a.Score == b.Score
+		})
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSelectTopK(t *testing.T) {
+	chunks := orthogonalChunks(5)
+	slices.Reverse(chunks)
+	result := ClusterByThreshold(chunks, 0.15)
+
+	got := SelectTopK(result, 3, SelectByScore)
+	if ids := ids(got); !slices.Equal(ids, []string{"a", "b", "c"}) {
+		t.Errorf("SelectTopK = %v, want a, b, c", ids)
+	}
+}
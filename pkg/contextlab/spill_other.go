@@ -0,0 +1,7 @@
+//go:build !unix
+
+package contextlab
+
+func mapSpill(dir string, size int64, entities bool) ([]float64, []bool, func() error, error) {
+	return nil, nil, nil, errSpillUnsupported
+}
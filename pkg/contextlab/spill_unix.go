@@ -0,0 +1,47 @@
+//go:build unix
+
+package contextlab
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mapSpill maps a temp file under dir holding size distances followed by
+// size conflict flags. The file is unlinked at once, so it disappears
+// even if the process dies before release unmaps it.
+func mapSpill(dir string, size int64, entities bool) ([]float64, []bool, func() error, error) {
+	if size == 0 {
+		return nil, nil, func() error { return nil }, nil
+	}
+	length := size * 8
+	if entities {
+		length += size
+	}
+	if int64(int(length)) != length {
+		return nil, nil, nil, fmt.Errorf("spill matrix: %d bytes exceeds the address space", length)
+	}
+
+	f, err := os.CreateTemp(dir, "distill-matrix-*")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("spill matrix: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+	_ = os.Remove(f.Name())
+	if err := f.Truncate(length); err != nil {
+		return nil, nil, nil, fmt.Errorf("spill matrix: %w", err)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(length), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("spill matrix: mmap: %w", err)
+	}
+
+	dist := unsafe.Slice((*float64)(unsafe.Pointer(&data[0])), size)
+	var conflicts []bool
+	if entities {
+		conflicts = unsafe.Slice((*bool)(unsafe.Pointer(&data[size*8])), size)
+	}
+	return dist, conflicts, func() error { return syscall.Munmap(data) }, nil
+}
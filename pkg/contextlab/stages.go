@@ -0,0 +1,621 @@
+package contextlab
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/rerank"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/sensitivity"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Stage names for a declarative pipeline; see WithStages.
+const (
+	PipelineRetrieve = "retrieve"
+	PipelineCluster  = "cluster"
+	PipelineSelect   = "select"
+	PipelineMMR      = "mmr"
+	PipelineCompress = "compress"
+	PipelineRedact   = "redact"
+	PipelineRerank   = "rerank"
+
+	// PipelineScoring is not a stage of its own: it is echoed in
+	// BrokerResult.Stages before "mmr" when MMR applied recency weighting.
+	PipelineScoring = "scoring"
+
+	// PipelineClassify cannot be declared either: it is echoed last when
+	// EnableClassification tagged the returned chunks.
+	PipelineClassify = "classify"
+)
+
+// StageSpec is one stage of a declarative pipeline, as written under
+// pipeline.stages in distill.yaml. Params are decoded by the stage; unknown
+// keys are an error.
+type StageSpec struct {
+	Name   string
+	Params map[string]interface{}
+}
+
+// ParseStages converts the pipeline.stages list, where each entry is a
+// stage name or a map from one stage name to its params, and validates
+// the result as WithStages would.
+func ParseStages(raw []interface{}) ([]StageSpec, error) {
+	specs := make([]StageSpec, 0, len(raw))
+	for i, entry := range raw {
+		switch e := entry.(type) {
+		case string:
+			specs = append(specs, StageSpec{Name: e})
+		case map[string]interface{}:
+			spec, err := parseStageMap(e)
+			if err != nil {
+				return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("stage %d: %w", i, err))
+			}
+			specs = append(specs, spec)
+		case map[interface{}]interface{}:
+			m := make(map[string]interface{}, len(e))
+			for k, v := range e {
+				m[fmt.Sprint(k)] = v
+			}
+			spec, err := parseStageMap(m)
+			if err != nil {
+				return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("stage %d: %w", i, err))
+			}
+			specs = append(specs, spec)
+		default:
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("stage %d: want a stage name or a map of one name to params, got %T", i, entry))
+		}
+	}
+	if _, err := newStages(specs); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// parseStageMap reads a {name: params} entry. A nil params value, as YAML
+// gives for "cluster:", means no params.
+func parseStageMap(m map[string]interface{}) (StageSpec, error) {
+	if len(m) != 1 {
+		return StageSpec{}, fmt.Errorf("want a map of one stage name to params, got %d keys", len(m))
+	}
+	for name, v := range m {
+		switch p := v.(type) {
+		case nil:
+			return StageSpec{Name: name}, nil
+		case map[string]interface{}:
+			return StageSpec{Name: name, Params: p}, nil
+		case map[interface{}]interface{}:
+			params := make(map[string]interface{}, len(p))
+			for k, pv := range p {
+				params[fmt.Sprint(k)] = pv
+			}
+			return StageSpec{Name: name, Params: params}, nil
+		default:
+			return StageSpec{}, fmt.Errorf("%s: params must be a map, got %T", name, v)
+		}
+	}
+	return StageSpec{}, nil
+}
+
+// pipelineStage is a configured stage after retrieval.
+type pipelineStage interface {
+	name() string
+	run(ctx context.Context, b *Broker, p *pipelineRun) error
+}
+
+// pipelineRun is the state threaded through the stages of one request.
+type pipelineRun struct {
+	req    *types.RetrievalRequest
+	plan   stagePlan
+	chunks []types.Chunk
+	stats  *types.BrokerStats
+
+	// ran lists the stages that ran, for BrokerResult.Stages.
+	ran []string
+
+	// clusters is the result of the cluster stage just run, for select.
+	clusters *types.ClusterResult
+
+	// hints and strategy are the clusters and strategy of the last
+	// select, for dedup hints.
+	hints    *types.ClusterResult
+	strategy SelectionStrategy
+}
+
+// newStages validates specs and builds the stages after retrieve: the
+// pipeline starts with exactly one retrieve, and each cluster is followed
+// by a select, which only follows a cluster.
+func newStages(specs []StageSpec) ([]pipelineStage, error) {
+	if len(specs) == 0 || specs[0].Name != PipelineRetrieve {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid pipeline: the first stage must be %q", PipelineRetrieve))
+	}
+	if err := retriever.DecodeParams(specs[0].Params, &struct{}{}); err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid pipeline: stage 0 (retrieve): %w", err))
+	}
+
+	stages := make([]pipelineStage, 0, len(specs)-1)
+	for i, spec := range specs[1:] {
+		i++
+		prev := specs[i-1].Name
+		stage, err := newStage(spec)
+		if err == nil {
+			switch {
+			case spec.Name == PipelineSelect && prev != PipelineCluster:
+				err = fmt.Errorf("must follow %q", PipelineCluster)
+			case spec.Name != PipelineSelect && prev == PipelineCluster:
+				err = fmt.Errorf("%q must be followed by %q", PipelineCluster, PipelineSelect)
+			}
+		}
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid pipeline: stage %d (%s): %w", i, spec.Name, err))
+		}
+		stages = append(stages, stage)
+	}
+	if specs[len(specs)-1].Name == PipelineCluster {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid pipeline: %q must be followed by %q", PipelineCluster, PipelineSelect))
+	}
+	return stages, nil
+}
+
+// newStage decodes spec's params into its stage.
+func newStage(spec StageSpec) (pipelineStage, error) {
+	var stage pipelineStage
+	switch spec.Name {
+	case PipelineRetrieve:
+		return nil, fmt.Errorf("may only be the first stage")
+	case PipelineCluster:
+		stage = &clusterStage{}
+	case PipelineSelect:
+		stage = &selectStage{}
+	case PipelineMMR:
+		stage = &mmrStage{}
+	case PipelineCompress:
+		stage = &compressStage{}
+	case PipelineRedact:
+		stage = &redactStage{}
+	case PipelineRerank:
+		stage = &rerankStage{}
+	default:
+		return nil, fmt.Errorf("unknown stage (supported: cluster, select, mmr, rerank, compress, redact)")
+	}
+	if err := retriever.DecodeParams(spec.Params, stage); err != nil {
+		return nil, err
+	}
+	if v, ok := stage.(interface{ init() error }); ok {
+		if err := v.init(); err != nil {
+			return nil, err
+		}
+	}
+	return stage, nil
+}
+
+// runStages runs the configured stages over candidates, skipping those
+// the request turns off, and returns the chunks and the stages that ran.
+// If more than TargetK chunks (or TokenBudget tokens) are left, the
+// highest-scoring are kept; multi-namespace requests fill each
+// namespace's quota instead.
+func (b *Broker) runStages(ctx context.Context, req *types.RetrievalRequest, plan stagePlan, candidates []types.Chunk, stats *types.BrokerStats) ([]types.Chunk, []string, error) {
+	p := &pipelineRun{req: req, plan: plan, chunks: candidates, stats: stats}
+	for _, stage := range b.stages {
+		if len(p.chunks) == 0 {
+			break
+		}
+		if !plan.declared(stage.name()) || (stage.name() == PipelineSelect && p.clusters == nil) {
+			continue
+		}
+		// Redaction is never skipped, and selection is cheap
+		if stage.name() == PipelineRedact || stage.name() == PipelineSelect {
+			if err := stage.run(ctx, b, p); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if _, err := b.budgeted(ctx, stage.name(), stats, func(ctx context.Context) error {
+			return stage.run(ctx, b, p)
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	chunks := p.chunks
+	if len(req.Namespaces) > 0 {
+		var err error
+		if chunks, err = b.selectByNamespace(ctx, req, chunks); err != nil {
+			return nil, nil, err
+		}
+	} else if b.overTarget(chunks, plan) {
+		chunks = b.topByScore(chunks, plan)
+	}
+	if b.cfg.DedupHints || req.DedupHints {
+		AnnotateDedup(chunks, p.hints, p.strategy)
+	}
+	return chunks, p.ran, nil
+}
+
+// topByScore returns the k highest-scoring chunks, without reordering
+// chunks itself.
+func topByScore(chunks []types.Chunk, k int) []types.Chunk {
+	chunks = append([]types.Chunk(nil), chunks...)
+	sort.SliceStable(chunks, func(i, j int) bool { return chunks[i].Score > chunks[j].Score })
+	return chunks[:min(k, len(chunks))]
+}
+
+// clusterStage groups near-duplicates; the select stage after it keeps
+// one chunk per cluster. Zero params use the broker's settings.
+type clusterStage struct {
+	Threshold float64 `mapstructure:"threshold"`
+	Linkage   string  `mapstructure:"linkage"`
+}
+
+func (s *clusterStage) init() error {
+	if s.Threshold < 0 || s.Threshold > 2 {
+		return fmt.Errorf("threshold must be in (0, 2], got %g", s.Threshold)
+	}
+	switch s.Linkage {
+	case "", "single", "complete", "average":
+	default:
+		return fmt.Errorf("unknown linkage %q", s.Linkage)
+	}
+	return nil
+}
+
+func (s *clusterStage) name() string { return PipelineCluster }
+
+func (s *clusterStage) run(ctx context.Context, b *Broker, p *pipelineRun) error {
+	c := b.requestClusterer(p.req)
+	if s.Threshold > 0 || s.Linkage != "" {
+		cfg := c.cfg
+		if s.Threshold > 0 {
+			cfg.Threshold = s.Threshold
+		}
+		if s.Linkage != "" {
+			cfg.Linkage = s.Linkage
+		}
+		c = NewClusterer(cfg)
+	}
+
+	observeStage(ctx, StageClustering, len(p.chunks))
+	start := time.Now()
+	result, err := c.ClusterContext(ctx, p.chunks)
+	if err != nil {
+		return fmt.Errorf("clustering interrupted: %w", err)
+	}
+	p.stats.ClusteringLatency += time.Since(start)
+	p.stats.Clustered = result.ClusterCount
+	p.stats.Vetoed += result.Vetoed
+	if result.Overflow != "" {
+		p.stats.MatrixOverflow = result.Overflow
+	}
+	p.clusters = result
+	p.ran = append(p.ran, PipelineCluster)
+	return nil
+}
+
+// selectStage keeps one representative per cluster.
+type selectStage struct {
+	Strategy SelectionStrategy `mapstructure:"strategy"`
+}
+
+func (s *selectStage) init() error {
+	if s.Strategy == "" {
+		return nil
+	}
+	_, err := ParseSelectionStrategy(string(s.Strategy))
+	return err
+}
+
+func (s *selectStage) name() string { return PipelineSelect }
+
+// run selects with the request's strategy, then the stage's, then the
+// broker's.
+func (s *selectStage) run(ctx context.Context, b *Broker, p *pipelineRun) error {
+	strategy := p.plan.strategy
+	if s.Strategy != "" && p.req.Selection == "" {
+		strategy = s.Strategy
+	}
+	observeStage(ctx, StageSelection, p.clusters.ClusterCount)
+	p.chunks = b.selectorFor(strategy).Select(p.clusters)
+	if p.req.Explain {
+		explainSelected(p.chunks, p.clusters)
+	}
+	if p.plan.merge() {
+		var merged int
+		p.chunks, merged = MergeClusters(p.chunks, p.clusters, p.plan.compression.opts)
+		p.stats.Merged += merged
+		if p.req.Explain {
+			explainMerged(p.chunks)
+		}
+	}
+	p.hints, p.strategy = p.clusters, strategy
+	p.clusters = nil
+	p.ran = append(p.ran, PipelineSelect)
+	return nil
+}
+
+// mmrStage re-ranks for diversity and keeps K chunks (default TargetK,
+// or as many as fit TokenBudget).
+// A zero Lambda uses the request's or the broker's. Multi-namespace
+// requests skip it, since their quotas are filled after the last stage.
+type mmrStage struct {
+	Lambda float64 `mapstructure:"lambda"`
+	K      int     `mapstructure:"k"`
+}
+
+func (s *mmrStage) init() error {
+	if s.Lambda < 0 || s.Lambda > 1 {
+		return fmt.Errorf("lambda must be in [0, 1], got %g", s.Lambda)
+	}
+	if s.K < 0 {
+		return fmt.Errorf("k must be non-negative, got %d", s.K)
+	}
+	return nil
+}
+
+func (s *mmrStage) name() string { return PipelineMMR }
+
+func (s *mmrStage) run(ctx context.Context, b *Broker, p *pipelineRun) error {
+	cfg := mmrConfig(b.cfg)
+	if p.req.Lambda > 0 && p.req.Lambda <= 1 {
+		cfg.Lambda = p.req.Lambda
+	}
+	if !enabled(p.req.Stages.Scoring, true) {
+		cfg.RecencyWeight = 0
+	}
+	if s.Lambda > 0 {
+		cfg.Lambda = s.Lambda
+	}
+	cfg.Tokens = tokenCounter(p.plan.model)
+	over := b.overTarget(p.chunks, p.plan)
+	if s.K > 0 {
+		// An explicit k counts chunks even under a token budget
+		cfg.TargetK, cfg.TokenBudget = s.K, 0
+		over = len(p.chunks) > s.K
+	}
+	if len(p.req.Namespaces) > 0 || !over {
+		// Namespace quotas are filled after the last stage
+		return nil
+	}
+
+	observeStage(ctx, StageMMR, len(p.chunks))
+	chunks, err := NewMMR(cfg).RerankContext(ctx, p.chunks)
+	if err != nil {
+		return fmt.Errorf("mmr interrupted: %w", err)
+	}
+	if p.req.Explain {
+		explainRanks(chunks, PipelineMMR)
+	}
+	p.chunks = chunks
+	p.ran = append(p.ran, mmrStages(cfg)...)
+	return nil
+}
+
+// rerankStage reorders chunks by the broker's reranker and keeps the top
+// K (default all). When the reranker fails, chunks keep their order unless
+// FailClosed is set. Requests without query text skip it.
+type rerankStage struct {
+	K          int  `mapstructure:"k"`
+	FailClosed bool `mapstructure:"fail_closed"`
+}
+
+func (s *rerankStage) init() error {
+	if s.K < 0 {
+		return fmt.Errorf("k must be non-negative, got %d", s.K)
+	}
+	return nil
+}
+
+func (s *rerankStage) name() string { return PipelineRerank }
+
+func (s *rerankStage) run(ctx context.Context, b *Broker, p *pipelineRun) error {
+	if p.req.Query == "" {
+		return nil
+	}
+	observeStage(ctx, StageReranking, len(p.chunks))
+	start := time.Now()
+	chunks, err := rerank.Chunks(ctx, b.reranker, p.req.Query, p.chunks)
+	p.stats.RerankLatency += time.Since(start)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("rerank interrupted: %w", err)
+		}
+		if s.FailClosed {
+			return errs.Wrap(errs.ErrBackend, fmt.Errorf("rerank failed: %w", err))
+		}
+		p.stats.RerankFailed = true
+		return nil
+	}
+	p.stats.Reranked += len(chunks)
+	if s.K > 0 && len(chunks) > s.K {
+		chunks = chunks[:s.K]
+	}
+	if p.req.Explain {
+		explainRanks(chunks, PipelineRerank)
+	}
+	p.chunks = chunks
+	p.ran = append(p.ran, PipelineRerank)
+	return nil
+}
+
+// compressStage shortens chunk text. Method is extractive (the default,
+// after dropping repeats), prune, placeholder, or repeats. MaxTokens caps
+// the total, escalating chunks as BudgetCompressor does.
+type compressStage struct {
+	Method          string  `mapstructure:"method"`
+	TargetReduction float64 `mapstructure:"target_reduction"`
+	MaxTokens       int     `mapstructure:"max_tokens"`
+
+	compressor compress.Compressor
+	opts       compress.Options
+}
+
+func (s *compressStage) init() error {
+	switch s.Method {
+	case "", "extractive":
+		s.compressor = compress.NewPipeline(compress.NewRepeatRemover(), compress.NewExtractiveCompressor())
+	case "prune":
+		s.compressor = compress.NewPruner()
+	case "placeholder":
+		s.compressor = compress.NewPlaceholderCompressor()
+	case "repeats":
+		s.compressor = compress.NewRepeatRemover()
+	default:
+		return fmt.Errorf("unknown method %q (supported: extractive, prune, placeholder, repeats)", s.Method)
+	}
+	if s.TargetReduction < 0 || s.TargetReduction > 1 {
+		return fmt.Errorf("target_reduction must be in [0, 1], got %g", s.TargetReduction)
+	}
+	if s.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must be non-negative, got %d", s.MaxTokens)
+	}
+
+	s.opts = compress.DefaultOptions()
+	if s.TargetReduction > 0 {
+		s.opts.TargetReduction = s.TargetReduction
+	}
+	if s.MaxTokens > 0 {
+		s.opts.MaxOutputTokens = s.MaxTokens
+		s.compressor = compress.NewBudgetCompressor(s.compressor)
+	}
+	return nil
+}
+
+func (s *compressStage) name() string { return PipelineCompress }
+
+func (s *compressStage) run(ctx context.Context, b *Broker, p *pipelineRun) error {
+	observeStage(ctx, StageCompression, len(p.chunks))
+	chunks, _, err := s.compressor.Compress(ctx, p.chunks, s.opts)
+	if err != nil {
+		return fmt.Errorf("compression failed: %w", err)
+	}
+	recordCompression(p.stats, p.chunks, chunks)
+	if p.req.Explain {
+		explainCompressed(p.chunks, chunks, cmp.Or(s.Method, "extractive"))
+	}
+	p.chunks = chunks
+	p.ran = append(p.ran, PipelineCompress)
+	return nil
+}
+
+// MetaRedactions lists the sensitivity.Spans typed redaction replaced in
+// a chunk, in order. Their offsets are into the chunk's text as the
+// redact stage saw it and left it.
+const MetaRedactions = "redactions"
+
+// redactStage replaces sensitive text in chunks with Replacement. Levels
+// are sensitivity level names; the default redacts credentials and PII.
+// Typed replaces each span with its type's placeholder instead, such as
+// "[EMAIL]", and records the spans under MetaRedactions.
+type redactStage struct {
+	Levels      []string `mapstructure:"levels"`
+	Replacement string   `mapstructure:"replacement"`
+	Typed       bool     `mapstructure:"typed"`
+
+	classifier *sensitivity.Classifier
+	levels     []sensitivity.Level
+}
+
+func (s *redactStage) init() error {
+	names := s.Levels
+	if len(names) == 0 {
+		names = []string{sensitivity.Credentials.String(), sensitivity.PII.String()}
+	}
+	for _, name := range names {
+		l, err := sensitivity.ParseLevel(name)
+		if err != nil || l == sensitivity.None {
+			return fmt.Errorf("unknown level %q (supported: pii, internal, credentials)", name)
+		}
+		s.levels = append(s.levels, l)
+	}
+	s.classifier = sensitivity.New(sensitivity.DefaultConfig())
+	return nil
+}
+
+func (s *redactStage) name() string { return PipelineRedact }
+
+func (s *redactStage) run(ctx context.Context, b *Broker, p *pipelineRun) error {
+	observeStage(ctx, StageRedaction, len(p.chunks))
+	p.chunks = s.apply(p.chunks, p.stats, p.req.Explain, s.Typed || p.req.Redact)
+	p.ran = append(p.ran, PipelineRedact)
+	return nil
+}
+
+// apply returns chunks with sensitive text redacted, cloning only the
+// chunks it changes, and counts the redactions in stats. Typed redaction
+// uses typed placeholders and records MetaRedactions. With explain, each
+// redacted chunk records what was redacted; see MetaTransforms.
+func (s *redactStage) apply(chunks []types.Chunk, stats *types.BrokerStats, explain, typed bool) []types.Chunk {
+	var redacted []types.Chunk
+	for i, c := range chunks {
+		var text string
+		var counts map[string]int
+		var spans []sensitivity.Span
+		if typed {
+			text, spans = s.classifier.RedactTyped(c.Text, s.levels...)
+			for _, sp := range spans {
+				if counts == nil {
+					counts = make(map[string]int)
+				}
+				counts[sp.Pattern]++
+			}
+		} else {
+			text, counts = s.classifier.RedactCounts(c.Text, s.Replacement, s.levels...)
+		}
+		if counts == nil {
+			continue
+		}
+		if redacted == nil {
+			redacted = make([]types.Chunk, len(chunks))
+			copy(redacted, chunks)
+		}
+		clone := c.Clone()
+		clone.Text = text
+		if spans != nil {
+			clone.Metadata[MetaRedactions] = spans
+		}
+		for _, pattern := range slices.Sorted(maps.Keys(counts)) {
+			stats.Redacted += counts[pattern]
+			if explain {
+				addTransform(clone, fmt.Sprintf("redacted-%d-%s", counts[pattern], pattern))
+			}
+		}
+		redacted[i] = *clone
+	}
+	if redacted == nil {
+		return chunks
+	}
+	return redacted
+}
+
+// defaultRedactor and defaultCompressor back EnableRedaction and
+// EnableCompression, with the stages' default params.
+var (
+	defaultRedactor = sync.OnceValue(func() *redactStage {
+		s := &redactStage{}
+		_ = s.init()
+		return s
+	})
+	defaultCompressor = sync.OnceValue(func() *compressStage {
+		s := &compressStage{}
+		_ = s.init()
+		return s
+	})
+)
+
+// RedactTyped replaces credentials and PII in chunks with typed
+// placeholders and records the spans under MetaRedactions, as
+// types.RetrievalRequest.Redact does, for callers outside the broker. It
+// clones only the chunks it changes and returns the number of spans
+// replaced.
+func RedactTyped(chunks []types.Chunk) ([]types.Chunk, int) {
+	var stats types.BrokerStats
+	chunks = defaultRedactor().apply(chunks, &stats, false, true)
+	return chunks, stats.Redacted
+}
@@ -0,0 +1,217 @@
+package contextlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestParseStages(t *testing.T) {
+	raw := []interface{}{
+		"retrieve",
+		map[string]interface{}{"cluster": map[string]interface{}{"threshold": "0.2"}},
+		map[interface{}]interface{}{"select": map[interface{}]interface{}{"strategy": "centroid"}},
+		map[string]interface{}{"mmr": nil},
+		"redact",
+	}
+	specs, err := ParseStages(raw)
+	if err != nil {
+		t.Fatalf("ParseStages: %v", err)
+	}
+
+	var names []string
+	for _, s := range specs {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "retrieve,cluster,select,mmr,redact" {
+		t.Errorf("stages = %s", got)
+	}
+	if specs[2].Params["strategy"] != "centroid" {
+		t.Errorf("select params = %v", specs[2].Params)
+	}
+}
+
+func TestParseStages_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []interface{}
+		want string
+	}{
+		{"empty", nil, "first stage must be"},
+		{"no retrieve", []interface{}{"cluster", "select"}, "first stage must be"},
+		{"retrieve twice", []interface{}{"retrieve", "retrieve"}, "may only be the first"},
+		{"unknown stage", []interface{}{"retrieve", "rewrite"}, "unknown stage"},
+		{"select alone", []interface{}{"retrieve", "select"}, `must follow "cluster"`},
+		{"cluster alone", []interface{}{"retrieve", "cluster"}, `must be followed by "select"`},
+		{"cluster then mmr", []interface{}{"retrieve", "cluster", "mmr", "select"}, `must be followed by "select"`},
+		{"unknown param", []interface{}{"retrieve", map[string]interface{}{"mmr": map[string]interface{}{"lamda": 0.5}}}, "unknown keys"},
+		{"bad param", []interface{}{"retrieve", map[string]interface{}{"mmr": map[string]interface{}{"lambda": 2}}}, "lambda"},
+		{"bad method", []interface{}{"retrieve", map[string]interface{}{"compress": map[string]interface{}{"method": "zip"}}}, "unknown method"},
+		{"bad rerank k", []interface{}{"retrieve", map[string]interface{}{"rerank": map[string]interface{}{"k": -1}}}, "k must be"},
+		{"bad level", []interface{}{"retrieve", map[string]interface{}{"redact": map[string]interface{}{"levels": []interface{}{"none"}}}}, "unknown level"},
+		{"two keys", []interface{}{"retrieve", map[string]interface{}{"cluster": nil, "select": nil}}, "one stage name"},
+		{"not a name", []interface{}{"retrieve", 3}, "want a stage name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseStages(tt.raw)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want %q", err, tt.want)
+			}
+			if !errors.Is(err, errs.ErrConfig) {
+				t.Errorf("error not tagged ErrConfig: %v", err)
+			}
+		})
+	}
+}
+
+func TestBroker_WithStages(t *testing.T) {
+	chunks := orthogonalChunks(4)
+	// b duplicates a
+	chunks[1].Embedding = chunks[0].Embedding
+	chunks[2].Text = "Reach alice@example.com for access."
+
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: chunks},
+		WithTargetK(2),
+		WithOverFetchK(10),
+		WithStages(
+			StageSpec{Name: PipelineRetrieve},
+			StageSpec{Name: PipelineCluster},
+			StageSpec{Name: PipelineSelect},
+			StageSpec{Name: PipelineRedact, Params: map[string]interface{}{"levels": []string{"pii"}}},
+		),
+	)
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0, 0}})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	// a, c, d survive clustering; the top two by score are kept
+	if got := chunkIDs(result.Chunks); got != "ac" {
+		t.Fatalf("got chunks %q, want ac", got)
+	}
+	if result.Chunks[1].Text != "Reach [REDACTED] for access." {
+		t.Errorf("redacted text = %q", result.Chunks[1].Text)
+	}
+	if chunks[2].Text != "Reach alice@example.com for access." {
+		t.Error("redaction changed the retriever's chunk")
+	}
+	if st := result.Stats; st.Clustered != 3 || st.Redacted != 1 || st.Returned != 2 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestBroker_WithStages_Reordered(t *testing.T) {
+	chunks := orthogonalChunks(3)
+	chunks[0].Text = "token: abc123 and more"
+
+	// Redacting before clustering and without MMR
+	broker, err := NewBrokerWithOptions(&stubRetriever{chunks: chunks},
+		WithTargetK(3),
+		WithStages(
+			StageSpec{Name: PipelineRetrieve},
+			StageSpec{Name: PipelineRedact, Params: map[string]interface{}{"replacement": "***"}},
+			StageSpec{Name: PipelineCluster, Params: map[string]interface{}{"threshold": 0.5}},
+			StageSpec{Name: PipelineSelect, Params: map[string]interface{}{"strategy": "length"}},
+		),
+	)
+	if err != nil {
+		t.Fatalf("NewBrokerWithOptions: %v", err)
+	}
+
+	result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(result.Chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(result.Chunks))
+	}
+	for _, c := range result.Chunks {
+		if c.ID == "a" && c.Text != "*** and more" {
+			t.Errorf("redacted text = %q", c.Text)
+		}
+	}
+}
+
+func TestNewBrokerWithOptions_InvalidStages(t *testing.T) {
+	_, err := NewBrokerWithOptions(&stubRetriever{}, WithStages(StageSpec{Name: PipelineCluster}))
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Fatalf("error = %v, want ErrConfig", err)
+	}
+}
+
+// reverseReranker scores documents in reverse input order, or fails.
+type reverseReranker struct{ err error }
+
+func (r reverseReranker) Rerank(_ context.Context, _ string, documents []string) ([]float64, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	scores := make([]float64, len(documents))
+	for i := range scores {
+		scores[i] = float64(i)
+	}
+	return scores, nil
+}
+
+func TestBroker_RerankStage(t *testing.T) {
+	tests := []struct {
+		name       string
+		reranker   reverseReranker
+		failClosed bool
+		query      string
+		want       string
+		wantErr    bool
+	}{
+		{name: "reordered", query: "q", want: "dc"},
+		{name: "no query text", want: "abc"},
+		{name: "fail open", reranker: reverseReranker{err: fmt.Errorf("down")}, query: "q", want: "abc"},
+		{name: "fail closed", reranker: reverseReranker{err: fmt.Errorf("down")}, failClosed: true, query: "q", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker, err := NewBrokerWithOptions(&stubRetriever{chunks: orthogonalChunks(4)},
+				WithTargetK(3),
+				WithReranker(tt.reranker),
+				WithStages(
+					StageSpec{Name: PipelineRetrieve},
+					StageSpec{Name: PipelineRerank, Params: map[string]interface{}{"k": 2, "fail_closed": tt.failClosed}},
+				),
+			)
+			if err != nil {
+				t.Fatalf("NewBrokerWithOptions: %v", err)
+			}
+			result, err := broker.Retrieve(context.Background(), &types.RetrievalRequest{Query: tt.query, QueryEmbedding: []float32{1, 0, 0, 0}})
+			if tt.wantErr {
+				if !errors.Is(err, errs.ErrBackend) {
+					t.Fatalf("error = %v, want ErrBackend", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Retrieve: %v", err)
+			}
+			if got := chunkIDs(result.Chunks); got != tt.want {
+				t.Errorf("got chunks %q, want %s", got, tt.want)
+			}
+			if failed := tt.reranker.err != nil; result.Stats.RerankFailed != failed {
+				t.Errorf("RerankFailed = %v, want %v", result.Stats.RerankFailed, failed)
+			}
+		})
+	}
+}
+
+func TestNewBrokerWithOptions_RerankWithoutReranker(t *testing.T) {
+	_, err := NewBrokerWithOptions(&stubRetriever{}, WithStages(StageSpec{Name: PipelineRetrieve}, StageSpec{Name: PipelineRerank}))
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Fatalf("error = %v, want ErrConfig", err)
+	}
+}
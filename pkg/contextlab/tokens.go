@@ -0,0 +1,53 @@
+package contextlab
+
+import (
+	"github.com/Siddhant-K-code/distill/pkg/models"
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// defaultTokens counts tokens with the default tokenizer.
+func defaultTokens(text string) int {
+	return tokens.Count(text)
+}
+
+// tokenCounter returns model's token count, or defaultTokens for no
+// model.
+func tokenCounter(model *models.Profile) func(string) int {
+	if model == nil {
+		return defaultTokens
+	}
+	return model.Tokens
+}
+
+// chunkTokens estimates each chunk's tokens.
+func chunkTokens(chunks []types.Chunk, tokens func(string) int) []int {
+	out := make([]int, len(chunks))
+	for i, c := range chunks {
+		out[i] = tokens(c.Text)
+	}
+	return out
+}
+
+func sumInts(xs []int) int {
+	total := 0
+	for _, x := range xs {
+		total += x
+	}
+	return total
+}
+
+// fitTokens keeps chunks, in order, while their tokens fit
+// budget. A chunk that does not fit is skipped, so a shorter one after it
+// can still take the room.
+func fitTokens(chunks []types.Chunk, budget int, tokens func(string) int) []types.Chunk {
+	kept := make([]types.Chunk, 0, len(chunks))
+	left := budget
+	for _, c := range chunks {
+		if n := tokens(c.Text); n <= left {
+			kept = append(kept, c)
+			left -= n
+		}
+	}
+	return kept
+}
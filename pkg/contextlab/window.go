@@ -0,0 +1,130 @@
+package contextlab
+
+import (
+	"context"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// clusterWindowed clusters chunks too many for the matrix budget. It
+// clusters consecutive windows of chunks small enough to fit, then
+// clusters the resulting clusters' centroids the same way, level by
+// level, until one window holds them all or a level merges nothing.
+//
+// Above the first level clusters are compared by centroid rather than by
+// Linkage, and the entity veto applies only within first-level windows,
+// so results approximate exact clustering.
+func (c *Clusterer) clusterWindowed(ctx context.Context, chunks []types.Chunk, start time.Time) (*types.ClusterResult, error) {
+	w := c.cfg.Matrix.window(c.cfg.Entities != nil)
+
+	sub := &Clusterer{cfg: c.cfg}
+	sub.cfg.Matrix = MatrixBudget{}
+	sub.cfg.MinClusters, sub.cfg.MaxClusters = 0, 0
+
+	groups := make([][]int, len(chunks))
+	for i := range chunks {
+		groups[i] = []int{i}
+	}
+	items := chunks
+	vetoed := 0
+	var err error
+	for {
+		final := len(groups) <= w
+		if final {
+			sub.cfg.MinClusters, sub.cfg.MaxClusters = c.cfg.MinClusters, c.cfg.MaxClusters
+		}
+		var labels []int
+		var count, v int
+		labels, count, v, err = sub.labelWindows(ctx, items, w)
+		if err != nil {
+			break
+		}
+		vetoed += v
+
+		merged := make([][]int, count)
+		for k, label := range labels {
+			merged[label] = append(merged[label], groups[k]...)
+		}
+		progress := count < len(groups)
+		groups = merged
+		if final || !progress {
+			break
+		}
+
+		sub.cfg.Entities = nil
+		items = make([]types.Chunk, len(groups))
+		for k, members := range groups {
+			items[k] = types.Chunk{Embedding: meanEmbedding(chunks, members)}
+		}
+	}
+
+	nodes := make([]*clusterNode, len(groups))
+	for k, members := range groups {
+		nodes[k] = &clusterNode{
+			id:       k,
+			members:  members,
+			centroid: meanEmbedding(chunks, members),
+			active:   true,
+		}
+	}
+	result := c.buildResult(nodes, chunks, len(nodes), start)
+	result.Vetoed = vetoed
+	result.Overflow = OverflowWindow
+	return result, err
+}
+
+// labelWindows clusters items in consecutive windows of w and returns
+// each item's cluster, numbered across windows, with the cluster count
+// and vetoed pairs.
+func (c *Clusterer) labelWindows(ctx context.Context, items []types.Chunk, w int) ([]int, int, int, error) {
+	labels := make([]int, len(items))
+	count, vetoed := 0, 0
+	for lo := 0; lo < len(items); lo += w {
+		hi := min(lo+w, len(items))
+		window := make([]types.Chunk, hi-lo)
+		copy(window, items[lo:hi])
+
+		result, err := c.ClusterContext(ctx, window)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		for k := range window {
+			labels[lo+k] = count + window[k].ClusterID
+		}
+		count += result.ClusterCount
+		vetoed += result.Vetoed
+	}
+	return labels, count, vetoed, nil
+}
+
+// meanEmbedding averages the embeddings of chunks at members, skipping
+// chunks without one. It returns nil if none has an embedding.
+func meanEmbedding(chunks []types.Chunk, members []int) []float32 {
+	var mean []float32
+	count := 0
+	for _, idx := range members {
+		emb := chunks[idx].Embedding
+		if len(emb) == 0 {
+			continue
+		}
+		if mean == nil {
+			mean = make([]float32, len(emb))
+		}
+		if len(emb) != len(mean) {
+			continue
+		}
+		for d, v := range emb {
+			mean[d] += v
+		}
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+	inv := float32(1.0 / float64(count))
+	for d := range mean {
+		mean[d] *= inv
+	}
+	return mean
+}
@@ -0,0 +1,91 @@
+package dedup
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// DuplicateGroup is a vector Deduplicate kept and the duplicates removed
+// in its favor, all from one cluster.
+type DuplicateGroup struct {
+	Cluster    int              `json:"cluster"`
+	KeptID     string           `json:"kept_id"`
+	Duplicates []GroupDuplicate `json:"duplicates"`
+}
+
+// GroupDuplicate is a removed duplicate and its distance to the kept
+// vector.
+type GroupDuplicate struct {
+	ID       string  `json:"id"`
+	Distance float64 `json:"distance"`
+}
+
+// GroupFunc receives duplicate groups as Deduplicate prunes clusters.
+// Calls are serialized. An error stops further calls and is returned by
+// Deduplicate.
+type GroupFunc func(DuplicateGroup) error
+
+// groupFlushInterval is how long a GroupWriter buffers groups before
+// flushing them.
+const groupFlushInterval = time.Second
+
+// GroupWriter writes duplicate groups as JSON lines, one group per line.
+// It flushes whenever a second has passed since the last flush, so a
+// reader can process groups while a long run continues; call Flush once
+// the run is done.
+type GroupWriter struct {
+	bw        *bufio.Writer
+	enc       *json.Encoder
+	lastFlush time.Time
+	count     int
+}
+
+// NewGroupWriter returns a GroupWriter writing to w.
+func NewGroupWriter(w io.Writer) *GroupWriter {
+	bw := bufio.NewWriter(w)
+	return &GroupWriter{bw: bw, enc: json.NewEncoder(bw), lastFlush: time.Now()}
+}
+
+// Write writes group. Its signature matches GroupFunc.
+func (g *GroupWriter) Write(group DuplicateGroup) error {
+	if err := g.enc.Encode(group); err != nil {
+		return err
+	}
+	g.count++
+	if time.Since(g.lastFlush) >= groupFlushInterval {
+		return g.Flush()
+	}
+	return nil
+}
+
+// Flush writes buffered groups to the underlying writer.
+func (g *GroupWriter) Flush() error {
+	g.lastFlush = time.Now()
+	return g.bw.Flush()
+}
+
+// Count returns the number of groups written.
+func (g *GroupWriter) Count() int {
+	return g.count
+}
+
+// group collects a pruned cluster's removals into a DuplicateGroup.
+// removed must be non-empty and share one kept vector, as pruneCluster
+// returns them.
+func group(removed []types.Removal) DuplicateGroup {
+	g := DuplicateGroup{
+		Cluster:    removed[0].Cluster,
+		KeptID:     removed[0].KeptID,
+		Duplicates: make([]GroupDuplicate, len(removed)),
+	}
+	for i, r := range removed {
+		g.Duplicates[i] = GroupDuplicate{ID: r.RemovedID, Distance: r.Distance}
+	}
+	sort.Slice(g.Duplicates, func(i, j int) bool { return g.Duplicates[i].ID < g.Duplicates[j].ID })
+	return g
+}
@@ -0,0 +1,90 @@
+package dedup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestDeduplicate_StreamsGroups(t *testing.T) {
+	vectors := makeVectors(200)
+	want, err := NewEngine(Config{Threshold: 0.05, Workers: 2, Seed: 7}).Deduplicate(context.Background(), vectors)
+	if err != nil {
+		t.Fatalf("Deduplicate: %v", err)
+	}
+	if len(want.Removed) == 0 {
+		t.Fatal("expected duplicates")
+	}
+
+	var buf bytes.Buffer
+	w := NewGroupWriter(&buf)
+	got, err := NewEngine(Config{Threshold: 0.05, Workers: 2, Seed: 7, OnGroup: w.Write}).Deduplicate(context.Background(), vectors)
+	if err != nil {
+		t.Fatalf("Deduplicate with OnGroup: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got.Removed != nil {
+		t.Errorf("Removed = %d entries, want none when streaming", len(got.Removed))
+	}
+	if got.DuplicateCount != want.DuplicateCount {
+		t.Errorf("DuplicateCount = %d, want %d", got.DuplicateCount, want.DuplicateCount)
+	}
+
+	var removed []types.Removal
+	lines := 0
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var g DuplicateGroup
+		if err := json.Unmarshal(sc.Bytes(), &g); err != nil {
+			t.Fatalf("line %d: %v", lines+1, err)
+		}
+		lines++
+		if len(g.Duplicates) == 0 {
+			t.Errorf("group for %s has no duplicates", g.KeptID)
+		}
+		for _, d := range g.Duplicates {
+			removed = append(removed, types.Removal{RemovedID: d.ID, KeptID: g.KeptID, Distance: d.Distance, Cluster: g.Cluster})
+		}
+	}
+	if lines != w.Count() {
+		t.Errorf("read %d groups, Count = %d", lines, w.Count())
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		if removed[i].Cluster != removed[j].Cluster {
+			return removed[i].Cluster < removed[j].Cluster
+		}
+		return removed[i].RemovedID < removed[j].RemovedID
+	})
+	if !reflect.DeepEqual(removed, want.Removed) {
+		t.Errorf("streamed groups do not match Removed:\n%v\nwant\n%v", removed, want.Removed)
+	}
+}
+
+func TestDeduplicate_GroupError(t *testing.T) {
+	errFull := errors.New("disk full")
+	calls := 0
+	engine := NewEngine(Config{
+		Threshold: 0.05,
+		Workers:   2,
+		Seed:      7,
+		OnGroup: func(DuplicateGroup) error {
+			calls++
+			return errFull
+		},
+	})
+	if _, err := engine.Deduplicate(context.Background(), makeVectors(200)); !errors.Is(err, errFull) {
+		t.Fatalf("err = %v, want %v", err, errFull)
+	}
+	if calls != 1 {
+		t.Errorf("OnGroup called %d times after failing, want 1", calls)
+	}
+}
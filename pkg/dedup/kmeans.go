@@ -5,6 +5,7 @@ import (
 	"math"
 	"math/rand"
 	"runtime"
+	"sort"
 	"sync"
 	"time"
 
@@ -29,6 +30,15 @@ type Config struct {
 
 	// Seed for reproducible clustering. If 0, uses current time.
 	Seed int64
+
+	// OnProgress, if set, receives progress updates during Deduplicate.
+	OnProgress ProgressFunc
+
+	// OnGroup, if set, receives each cluster's duplicates as soon as the
+	// cluster is pruned, in no particular cluster order. The result's
+	// Removed is then left empty, so memory does not grow with the
+	// number of duplicates found.
+	OnGroup GroupFunc
 }
 
 // DefaultConfig returns sensible defaults for deduplication.
@@ -94,14 +104,20 @@ func (e *Engine) Deduplicate(ctx context.Context, vectors []types.Vector) (*type
 		k = len(vectors)
 	}
 
+	progress := newProgressReporter(e.cfg.OnProgress, len(vectors), e.cfg.MaxIterations)
+
 	// Run K-Means clustering
-	clusters, err := e.kMeans(ctx, vectors, k)
+	clusters, err := e.kMeans(ctx, vectors, k, progress)
 	if err != nil {
 		return nil, err
 	}
 
 	// Prune duplicates within each cluster
-	uniqueIndices := e.pruneClustersConcurrent(ctx, vectors, clusters)
+	uniqueIndices, removed, err := e.pruneClustersConcurrent(ctx, vectors, clusters, progress)
+	if err != nil {
+		return nil, err
+	}
+	progress.done()
 
 	// Build result
 	uniqueVectors := make([]types.Vector, 0, len(uniqueIndices))
@@ -111,6 +127,7 @@ func (e *Engine) Deduplicate(ctx context.Context, vectors []types.Vector) (*type
 
 	return &types.DeduplicationResult{
 		UniqueVectors:    uniqueVectors,
+		Removed:          removed,
 		DuplicateCount:   len(vectors) - len(uniqueVectors),
 		TotalProcessed:   len(vectors),
 		ClusterCount:     k,
@@ -119,7 +136,7 @@ func (e *Engine) Deduplicate(ctx context.Context, vectors []types.Vector) (*type
 }
 
 // kMeans performs K-Means clustering on vectors.
-func (e *Engine) kMeans(ctx context.Context, vectors []types.Vector, k int) ([]cluster, error) {
+func (e *Engine) kMeans(ctx context.Context, vectors []types.Vector, k int, progress *progressReporter) ([]cluster, error) {
 	if len(vectors) == 0 || k == 0 {
 		return nil, nil
 	}
@@ -139,8 +156,10 @@ func (e *Engine) kMeans(ctx context.Context, vectors []types.Vector, k int) ([]c
 		default:
 		}
 
+		progress.startIteration(iter + 1)
+
 		// Assignment step: parallel
-		changed := e.assignVectorsConcurrent(vectors, centroids, assignments)
+		changed := e.assignVectorsConcurrent(vectors, centroids, assignments, progress)
 
 		// If no assignments changed, we've converged
 		if !changed && iter > 0 {
@@ -181,7 +200,7 @@ func (e *Engine) initCentroids(vectors []types.Vector, k, dim int) [][]float32 {
 
 // assignVectorsConcurrent assigns each vector to nearest centroid in parallel.
 // Returns true if any assignment changed.
-func (e *Engine) assignVectorsConcurrent(vectors []types.Vector, centroids [][]float32, assignments []int) bool {
+func (e *Engine) assignVectorsConcurrent(vectors []types.Vector, centroids [][]float32, assignments []int, progress *progressReporter) bool {
 	n := len(vectors)
 	workers := e.cfg.Workers
 	if workers > n {
@@ -207,12 +226,20 @@ func (e *Engine) assignVectorsConcurrent(vectors []types.Vector, centroids [][]f
 			defer wg.Done()
 			changed := false
 
+			pending := 0
 			for i := start; i < end; i++ {
 				nearest := e.findNearestCentroid(vectors[i].Values, centroids)
 				if assignments[i] != nearest {
 					assignments[i] = nearest
 					changed = true
 				}
+				if pending++; pending == progressReportEvery {
+					progress.assigned(pending)
+					pending = 0
+				}
+			}
+			if pending > 0 {
+				progress.assigned(pending)
 			}
 
 			changedFlags[workerID] = changed
@@ -274,47 +301,73 @@ func (e *Engine) updateCentroids(vectors []types.Vector, assignments []int, cent
 	}
 }
 
-// pruneClustersConcurrent identifies unique vectors within each cluster.
-func (e *Engine) pruneClustersConcurrent(ctx context.Context, vectors []types.Vector, clusters []cluster) []int {
+// pruneClustersConcurrent identifies unique vectors within each cluster
+// and records which vector each removed duplicate collapsed into, or
+// hands each cluster's removals to OnGroup when it is set.
+func (e *Engine) pruneClustersConcurrent(ctx context.Context, vectors []types.Vector, clusters []cluster, progress *progressReporter) ([]int, []types.Removal, error) {
+	progress.startPrune(len(clusters))
+
 	var mu sync.Mutex
 	uniqueIndices := make([]int, 0, len(vectors))
+	var removed []types.Removal
+	var groupErr error
 
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, e.cfg.Workers)
 
-	for _, cl := range clusters {
+	for ci, cl := range clusters {
 		if len(cl.members) == 0 {
+			progress.pruned()
 			continue
 		}
 
 		wg.Add(1)
 		sem <- struct{}{}
 
-		go func(c cluster) {
+		go func(id int, c cluster) {
 			defer wg.Done()
 			defer func() { <-sem }()
 
-			unique := e.pruneCluster(vectors, c)
+			unique, dropped := e.pruneCluster(vectors, id, c)
 
 			mu.Lock()
 			uniqueIndices = append(uniqueIndices, unique...)
+			switch {
+			case e.cfg.OnGroup == nil:
+				removed = append(removed, dropped...)
+			case len(dropped) > 0 && groupErr == nil:
+				groupErr = e.cfg.OnGroup(group(dropped))
+			}
 			mu.Unlock()
-		}(cl)
+
+			progress.pruned()
+		}(ci, cl)
 	}
 
 	wg.Wait()
-	return uniqueIndices
+	if groupErr != nil {
+		return nil, nil, groupErr
+	}
+
+	// Workers finish in any order; keep the manifest stable.
+	sort.Slice(removed, func(i, j int) bool {
+		if removed[i].Cluster != removed[j].Cluster {
+			return removed[i].Cluster < removed[j].Cluster
+		}
+		return removed[i].RemovedID < removed[j].RemovedID
+	})
+	return uniqueIndices, removed, nil
 }
 
 // pruneCluster identifies unique vectors within a single cluster.
 // Uses medoid-based comparison for efficiency.
-func (e *Engine) pruneCluster(vectors []types.Vector, cl cluster) []int {
+func (e *Engine) pruneCluster(vectors []types.Vector, clusterID int, cl cluster) ([]int, []types.Removal) {
 	if len(cl.members) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	if len(cl.members) == 1 {
-		return cl.members
+		return cl.members, nil
 	}
 
 	// Find medoid: vector closest to centroid
@@ -334,6 +387,7 @@ func (e *Engine) pruneCluster(vectors []types.Vector, cl cluster) []int {
 	unique = append(unique, medoidIdx) // Medoid is always kept
 
 	medoidVec := vectors[medoidIdx].Values
+	var removed []types.Removal
 
 	for _, idx := range cl.members {
 		if idx == medoidIdx {
@@ -344,8 +398,15 @@ func (e *Engine) pruneCluster(vectors []types.Vector, cl cluster) []int {
 		if dist >= e.cfg.Threshold {
 			// Not a duplicate - distance exceeds threshold
 			unique = append(unique, idx)
+			continue
 		}
+		removed = append(removed, types.Removal{
+			RemovedID: vectors[idx].ID,
+			KeptID:    vectors[medoidIdx].ID,
+			Distance:  dist,
+			Cluster:   clusterID,
+		})
 	}
 
-	return unique
+	return unique, removed
 }
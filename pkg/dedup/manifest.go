@@ -0,0 +1,95 @@
+package dedup
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Metadata keys set on tombstoned duplicates.
+const (
+	// TombstoneKey marks a vector as a removed duplicate.
+	TombstoneKey = "distill_duplicate"
+
+	// DuplicateOfKey holds the ID of the vector kept in its place.
+	DuplicateOfKey = "distill_duplicate_of"
+)
+
+// WriteManifest writes one JSON object per removal to w, in the order
+// given.
+func WriteManifest(w io.Writer, removed []types.Removal) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, r := range removed {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadManifest reads removals written by WriteManifest. Blank lines are
+// skipped.
+func ReadManifest(r io.Reader) ([]types.Removal, error) {
+	var removed []types.Removal
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var rm types.Removal
+		if err := json.Unmarshal([]byte(text), &rm); err != nil {
+			return nil, fmt.Errorf("manifest line %d: %w", line, err)
+		}
+		if rm.RemovedID == "" {
+			return nil, fmt.Errorf("manifest line %d: missing removed_id", line)
+		}
+		removed = append(removed, rm)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// RestoreMetadata returns the metadata update that restores a tombstoned
+// vector. Pinecone updates cannot delete metadata keys, so the tombstone
+// is cleared instead: TombstoneKey becomes false and DuplicateOfKey empty.
+func RestoreMetadata() map[string]interface{} {
+	return map[string]interface{}{
+		TombstoneKey:   false,
+		DuplicateOfKey: "",
+	}
+}
+
+// Tombstones returns copies of the removed vectors with TombstoneKey and
+// DuplicateOfKey set in their metadata, so they can be upserted instead of
+// silently dropped. Removals whose ID is not in vectors are skipped.
+func Tombstones(vectors []types.Vector, removed []types.Removal) []types.Vector {
+	if len(removed) == 0 {
+		return nil
+	}
+
+	keptBy := make(map[string]string, len(removed))
+	for _, r := range removed {
+		keptBy[r.RemovedID] = r.KeptID
+	}
+
+	out := make([]types.Vector, 0, len(removed))
+	for i := range vectors {
+		keptID, ok := keptBy[vectors[i].ID]
+		if !ok {
+			continue
+		}
+		v := vectors[i].Clone()
+		v.Metadata[TombstoneKey] = true
+		v.Metadata[DuplicateOfKey] = keptID
+		out = append(out, *v)
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package dedup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestDeduplicate_RecordsRemovals(t *testing.T) {
+	vectors := []types.Vector{
+		{ID: "a", Values: []float32{1, 0, 0}},
+		{ID: "a-dup", Values: []float32{1, 0.001, 0}},
+		{ID: "b", Values: []float32{0, 1, 0}},
+	}
+
+	engine := NewEngine(Config{Threshold: 0.05, K: 1, Seed: 1})
+	result, err := engine.Deduplicate(context.Background(), vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Removed) != result.DuplicateCount {
+		t.Fatalf("expected %d removals, got %d", result.DuplicateCount, len(result.Removed))
+	}
+	if len(result.Removed) != 1 {
+		t.Fatalf("expected 1 removal, got %+v", result.Removed)
+	}
+
+	r := result.Removed[0]
+	pair := []string{r.RemovedID, r.KeptID}
+	if !(pair[0] == "a" && pair[1] == "a-dup") && !(pair[0] == "a-dup" && pair[1] == "a") {
+		t.Errorf("expected a/a-dup pair, got %+v", r)
+	}
+	if r.Distance >= 0.05 {
+		t.Errorf("expected distance below threshold, got %f", r.Distance)
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	removed := []types.Removal{
+		{RemovedID: "x", KeptID: "y", Distance: 0.01, Cluster: 2},
+		{RemovedID: "z", KeptID: "y", Distance: 0.02, Cluster: 2},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteManifest(&buf, removed); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+
+	var got types.Removal
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != removed[0] {
+		t.Errorf("expected %+v, got %+v", removed[0], got)
+	}
+}
+
+func TestTombstones(t *testing.T) {
+	vectors := []types.Vector{
+		{ID: "keep", Values: []float32{1}},
+		{ID: "drop", Values: []float32{1}, Metadata: map[string]interface{}{"src": "a"}},
+	}
+	removed := []types.Removal{{RemovedID: "drop", KeptID: "keep"}}
+
+	tombs := Tombstones(vectors, removed)
+	if len(tombs) != 1 {
+		t.Fatalf("expected 1 tombstone, got %d", len(tombs))
+	}
+	if tombs[0].Metadata[TombstoneKey] != true || tombs[0].Metadata[DuplicateOfKey] != "keep" {
+		t.Errorf("unexpected tombstone metadata: %v", tombs[0].Metadata)
+	}
+	if tombs[0].Metadata["src"] != "a" {
+		t.Error("expected existing metadata to be preserved")
+	}
+	if _, ok := vectors[1].Metadata[TombstoneKey]; ok {
+		t.Error("input vector metadata should not be modified")
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	removed := []types.Removal{
+		{RemovedID: "x", KeptID: "y", Distance: 0.01, Cluster: 2},
+		{RemovedID: "z", KeptID: "y", Distance: 0.02, Cluster: 2},
+	}
+	var buf bytes.Buffer
+	if err := WriteManifest(&buf, removed); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("\n")
+
+	got, err := ReadManifest(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != removed[0] || got[1] != removed[1] {
+		t.Errorf("expected round trip of %+v, got %+v", removed, got)
+	}
+
+	for _, bad := range []string{"{not json}\n", `{"kept_id":"y"}` + "\n"} {
+		if _, err := ReadManifest(strings.NewReader(bad)); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("expected line 1 error for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestRestoreMetadata(t *testing.T) {
+	md := RestoreMetadata()
+	if md[TombstoneKey] != false || md[DuplicateOfKey] != "" {
+		t.Errorf("expected tombstone cleared, got %v", md)
+	}
+}
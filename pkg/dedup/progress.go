@@ -0,0 +1,172 @@
+package dedup
+
+import (
+	"sync"
+	"time"
+)
+
+// Phase identifies the stage of a deduplication run.
+type Phase string
+
+const (
+	// PhaseAssign is the K-Means assignment/update loop.
+	PhaseAssign Phase = "assign"
+
+	// PhasePrune is the per-cluster duplicate pruning pass.
+	PhasePrune Phase = "prune"
+
+	// PhaseDone is reported once when deduplication completes.
+	PhaseDone Phase = "done"
+)
+
+// Progress is a snapshot of deduplication progress.
+type Progress struct {
+	Phase Phase `json:"phase"`
+
+	// Iteration is the current K-Means iteration (1-based).
+	Iteration     int `json:"iteration"`
+	MaxIterations int `json:"max_iterations"`
+
+	// VectorsAssigned counts vectors assigned in the current iteration.
+	VectorsAssigned int `json:"vectors_assigned"`
+	TotalVectors    int `json:"total_vectors"`
+
+	// ClustersPruned counts clusters whose duplicates have been pruned.
+	ClustersPruned int `json:"clusters_pruned"`
+	TotalClusters  int `json:"total_clusters"`
+
+	Elapsed time.Duration `json:"elapsed_ns"`
+}
+
+// assignWeight is the share of overall progress attributed to clustering;
+// the remainder belongs to pruning.
+const assignWeight = 0.9
+
+// Fraction returns overall completion in [0, 1]. Clustering assumes every
+// iteration runs; early convergence makes progress jump ahead.
+func (p Progress) Fraction() float64 {
+	if p.Phase == PhaseDone {
+		return 1
+	}
+
+	var assign, prune float64
+	if p.TotalVectors > 0 && p.MaxIterations > 0 {
+		done := float64(p.Iteration-1)*float64(p.TotalVectors) + float64(p.VectorsAssigned)
+		assign = done / (float64(p.MaxIterations) * float64(p.TotalVectors))
+	}
+	if p.Phase == PhasePrune {
+		assign = 1
+		if p.TotalClusters > 0 {
+			prune = float64(p.ClustersPruned) / float64(p.TotalClusters)
+		}
+	}
+
+	f := assign*assignWeight + prune*(1-assignWeight)
+	if f > 1 {
+		f = 1
+	}
+	return f
+}
+
+// ETA estimates the remaining time from elapsed time and Fraction.
+// Returns 0 when no estimate is available yet.
+func (p Progress) ETA() time.Duration {
+	f := p.Fraction()
+	if f <= 0 || f >= 1 {
+		return 0
+	}
+	return time.Duration(float64(p.Elapsed) * (1 - f) / f)
+}
+
+// ProgressFunc receives progress snapshots. Calls are serialized, but may
+// come from worker goroutines, so implementations should return quickly.
+type ProgressFunc func(Progress)
+
+// progressReportEvery is the number of vectors a worker assigns between
+// progress reports.
+const progressReportEvery = 4096
+
+// progressReporter serializes progress callbacks from concurrent workers.
+type progressReporter struct {
+	mu    sync.Mutex
+	fn    ProgressFunc
+	start time.Time
+	state Progress
+}
+
+func newProgressReporter(fn ProgressFunc, totalVectors, maxIterations int) *progressReporter {
+	if fn == nil {
+		return nil
+	}
+	return &progressReporter{
+		fn:    fn,
+		start: time.Now(),
+		state: Progress{
+			Phase:         PhaseAssign,
+			TotalVectors:  totalVectors,
+			MaxIterations: maxIterations,
+		},
+	}
+}
+
+// startIteration resets the per-iteration assignment counter.
+func (r *progressReporter) startIteration(iter int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.state.Iteration = iter
+	r.state.VectorsAssigned = 0
+	r.emitLocked()
+	r.mu.Unlock()
+}
+
+// assigned records n newly assigned vectors.
+func (r *progressReporter) assigned(n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.state.VectorsAssigned += n
+	r.emitLocked()
+	r.mu.Unlock()
+}
+
+// startPrune switches to the pruning phase.
+func (r *progressReporter) startPrune(totalClusters int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.state.Phase = PhasePrune
+	r.state.TotalClusters = totalClusters
+	r.emitLocked()
+	r.mu.Unlock()
+}
+
+// pruned records one more pruned cluster.
+func (r *progressReporter) pruned() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.state.ClustersPruned++
+	r.emitLocked()
+	r.mu.Unlock()
+}
+
+// done reports completion.
+func (r *progressReporter) done() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.state.Phase = PhaseDone
+	r.emitLocked()
+	r.mu.Unlock()
+}
+
+func (r *progressReporter) emitLocked() {
+	r.state.Elapsed = time.Since(r.start)
+	r.fn(r.state)
+}
@@ -0,0 +1,78 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func makeVectors(n int) []types.Vector {
+	vectors := make([]types.Vector, n)
+	for i := range vectors {
+		vectors[i] = types.Vector{
+			ID:     fmt.Sprintf("v%d", i),
+			Values: []float32{float32(i % 7), float32(i % 5), 1},
+		}
+	}
+	return vectors
+}
+
+func TestDeduplicate_ReportsProgress(t *testing.T) {
+	var updates []Progress
+	engine := NewEngine(Config{
+		Threshold: 0.05,
+		Workers:   2,
+		Seed:      7,
+		OnProgress: func(p Progress) {
+			updates = append(updates, p)
+		},
+	})
+
+	if _, err := engine.Deduplicate(context.Background(), makeVectors(200)); err != nil {
+		t.Fatalf("Deduplicate: %v", err)
+	}
+
+	if len(updates) == 0 {
+		t.Fatal("expected progress updates")
+	}
+
+	last := updates[len(updates)-1]
+	if last.Phase != PhaseDone || last.Fraction() != 1 {
+		t.Errorf("expected final update to be done, got %+v", last)
+	}
+
+	sawPrune := false
+	prev := 0.0
+	for _, u := range updates {
+		if u.Phase == PhasePrune {
+			sawPrune = true
+		}
+		if f := u.Fraction(); f < prev {
+			t.Errorf("progress went backwards: %f -> %f", prev, f)
+		} else {
+			prev = f
+		}
+	}
+	if !sawPrune {
+		t.Error("expected prune phase updates")
+	}
+}
+
+func TestProgress_FractionAndETA(t *testing.T) {
+	p := Progress{
+		Phase:           PhaseAssign,
+		Iteration:       1,
+		MaxIterations:   10,
+		VectorsAssigned: 50,
+		TotalVectors:    100,
+		Elapsed:         1000,
+	}
+	if f := p.Fraction(); f < 0.044 || f > 0.046 {
+		t.Errorf("expected fraction ~0.045, got %f", f)
+	}
+	if p.ETA() <= 0 {
+		t.Error("expected positive ETA mid-run")
+	}
+}
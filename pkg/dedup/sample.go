@@ -0,0 +1,134 @@
+package dedup
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Reservoir keeps a uniform random sample of at most Size vectors from a
+// stream of unknown length (Vitter's Algorithm R). Memory use is bounded by
+// the sample size regardless of how many vectors are offered.
+type Reservoir struct {
+	size  int
+	seen  int64
+	rng   *rand.Rand
+	items []types.Vector
+}
+
+// NewReservoir creates a reservoir holding at most size vectors.
+// If seed is 0, the current time is used.
+func NewReservoir(size int, seed int64) *Reservoir {
+	if size < 1 {
+		size = 1
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Reservoir{
+		size:  size,
+		rng:   rand.New(rand.NewSource(seed)),
+		items: make([]types.Vector, 0, size),
+	}
+}
+
+// Add offers a vector to the reservoir.
+func (r *Reservoir) Add(v types.Vector) {
+	r.seen++
+	if len(r.items) < r.size {
+		r.items = append(r.items, v)
+		return
+	}
+	j := r.rng.Int63n(r.seen)
+	if j < int64(r.size) {
+		r.items[j] = v
+	}
+}
+
+// Seen returns the number of vectors offered so far.
+func (r *Reservoir) Seen() int64 {
+	return r.seen
+}
+
+// Sample returns the sampled vectors.
+func (r *Reservoir) Sample() []types.Vector {
+	return r.items
+}
+
+// Estimate extrapolates a sample deduplication result to the full population.
+type Estimate struct {
+	// SampleSize is the number of vectors analyzed.
+	SampleSize int
+
+	// Population is the total number of vectors in the source.
+	Population int64
+
+	// Confidence is the confidence level of the interval (e.g. 0.95).
+	Confidence float64
+
+	// DuplicateRate is the fraction of sampled vectors flagged as duplicates.
+	DuplicateRate float64
+
+	// RateLow and RateHigh bound DuplicateRate at the given confidence.
+	RateLow  float64
+	RateHigh float64
+
+	// EstimatedDuplicates is DuplicateRate projected onto Population,
+	// with EstimatedLow/EstimatedHigh as the projected interval.
+	EstimatedDuplicates int64
+	EstimatedLow        int64
+	EstimatedHigh       int64
+}
+
+// EstimateDuplicates projects the duplicate rate observed in a sample onto
+// the population it was drawn from. The interval is a Wilson score interval
+// with a finite population correction, so it narrows to the observed rate
+// as the sample approaches the population size.
+//
+// The estimate describes duplicate density among sampled vectors. Duplicates
+// whose partners fall outside the sample are not observed, so for datasets
+// dominated by small duplicate groups the true rate is likely higher.
+func EstimateDuplicates(result *types.DeduplicationResult, population int64, confidence float64) Estimate {
+	if confidence <= 0 || confidence >= 1 {
+		confidence = 0.95
+	}
+
+	est := Estimate{
+		SampleSize: result.TotalProcessed,
+		Population: population,
+		Confidence: confidence,
+	}
+
+	n := float64(result.TotalProcessed)
+	if n == 0 || population <= 0 {
+		return est
+	}
+
+	p := float64(result.DuplicateCount) / n
+	z := math.Sqrt2 * math.Erfinv(confidence)
+
+	// Finite population correction shrinks the effective z as the sample
+	// covers more of the population.
+	fpc := 0.0
+	if N := float64(population); n < N {
+		fpc = (N - n) / (N - 1)
+	}
+	zz := z * math.Sqrt(fpc)
+
+	denom := 1 + zz*zz/n
+	center := (p + zz*zz/(2*n)) / denom
+	margin := zz * math.Sqrt(p*(1-p)/n+zz*zz/(4*n*n)) / denom
+
+	est.DuplicateRate = p
+	est.RateLow = math.Max(0, center-margin)
+	est.RateHigh = math.Min(1, center+margin)
+
+	pop := float64(population)
+	est.EstimatedDuplicates = int64(math.Round(p * pop))
+	est.EstimatedLow = int64(math.Round(est.RateLow * pop))
+	est.EstimatedHigh = int64(math.Round(est.RateHigh * pop))
+
+	return est
+}
@@ -0,0 +1,67 @@
+package dedup
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestReservoir_BoundedSample(t *testing.T) {
+	r := NewReservoir(10, 42)
+	for i := 0; i < 1000; i++ {
+		r.Add(types.Vector{ID: fmt.Sprintf("v%d", i), Values: []float32{float32(i)}})
+	}
+
+	if r.Seen() != 1000 {
+		t.Errorf("expected 1000 seen, got %d", r.Seen())
+	}
+	if len(r.Sample()) != 10 {
+		t.Fatalf("expected sample of 10, got %d", len(r.Sample()))
+	}
+
+	seen := make(map[string]bool)
+	for _, v := range r.Sample() {
+		if seen[v.ID] {
+			t.Errorf("duplicate vector %s in sample", v.ID)
+		}
+		seen[v.ID] = true
+	}
+}
+
+func TestReservoir_SmallStream(t *testing.T) {
+	r := NewReservoir(10, 1)
+	for i := 0; i < 3; i++ {
+		r.Add(types.Vector{ID: fmt.Sprintf("v%d", i)})
+	}
+	if len(r.Sample()) != 3 {
+		t.Errorf("expected all 3 vectors kept, got %d", len(r.Sample()))
+	}
+}
+
+func TestEstimateDuplicates_Interval(t *testing.T) {
+	result := &types.DeduplicationResult{TotalProcessed: 1000, DuplicateCount: 200}
+
+	est := EstimateDuplicates(result, 1_000_000, 0.95)
+	if est.DuplicateRate != 0.2 {
+		t.Errorf("expected rate 0.2, got %f", est.DuplicateRate)
+	}
+	if est.RateLow >= 0.2 || est.RateHigh <= 0.2 {
+		t.Errorf("interval [%f, %f] should contain 0.2", est.RateLow, est.RateHigh)
+	}
+	if est.RateLow < 0.17 || est.RateHigh > 0.23 {
+		t.Errorf("interval [%f, %f] unexpectedly wide", est.RateLow, est.RateHigh)
+	}
+	if est.EstimatedDuplicates != 200_000 {
+		t.Errorf("expected 200000 estimated duplicates, got %d", est.EstimatedDuplicates)
+	}
+}
+
+func TestEstimateDuplicates_FullPopulation(t *testing.T) {
+	result := &types.DeduplicationResult{TotalProcessed: 500, DuplicateCount: 50}
+
+	est := EstimateDuplicates(result, 500, 0.95)
+	if est.RateLow != 0.1 || est.RateHigh != 0.1 {
+		t.Errorf("full-population interval should collapse to 0.1, got [%f, %f]", est.RateLow, est.RateHigh)
+	}
+}
@@ -0,0 +1,300 @@
+// Package distill is the one-call library API for embedding Distill in Go
+// programs. It wires the clusterer, selector, MMR re-ranker, and optional
+// compressor the same way the HTTP server does:
+//
+//	d := distill.New(distill.Config{TargetK: 8, Embedder: emb})
+//	res, err := d.Dedupe(ctx, chunks)
+//
+// Set Config.Retriever to query a vector database with Retrieve.
+package distill
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Chunk is the unit of context Distill operates on.
+type Chunk = types.Chunk
+
+var (
+	// ErrNoEmbedder is returned when chunks or queries need embedding but
+	// Config.Embedder is nil.
+	ErrNoEmbedder = errs.New(errs.ErrConfig, "distill: embedder required for chunks without embeddings")
+
+	// ErrNoRetriever is returned by Retrieve when Config.Retriever is nil.
+	ErrNoRetriever = errs.New(errs.ErrConfig, "distill: retriever required for Retrieve")
+)
+
+// Config configures a Distiller. Zero values use the same defaults as
+// the distill api and serve commands.
+type Config struct {
+	// Threshold is the cosine distance threshold for clustering (default 0.15).
+	Threshold float64
+
+	// Linkage is the cluster linkage: "single", "complete", or "average" (default).
+	Linkage string
+
+	// Selection picks each cluster's representative (default SelectByScore).
+	Selection contextlab.SelectionStrategy
+
+	// TargetK caps the number of chunks returned. For Dedupe, 0 keeps one
+	// representative per cluster; for Retrieve, 0 means 8.
+	TargetK int
+
+	// Lambda is the MMR relevance/diversity trade-off (default 0.5).
+	Lambda float64
+
+	// DisableMMR picks the top TargetK by score instead of MMR re-ranking.
+	DisableMMR bool
+
+	// OverFetchK is the number of chunks Retrieve fetches (default 50).
+	OverFetchK int
+
+	// Namespace is the vector database namespace used by Retrieve.
+	Namespace string
+
+	// Compressor, when set, compresses the selected chunks.
+	Compressor compress.Compressor
+
+	// CompressOptions configures Compressor (default compress.DefaultOptions).
+	CompressOptions *compress.Options
+
+	// Embedder embeds chunks without embeddings and text queries.
+	Embedder retriever.EmbeddingProvider
+
+	// Retriever is the vector database queried by Retrieve.
+	Retriever retriever.Retriever
+}
+
+// Stats summarizes one Dedupe or Retrieve call.
+type Stats struct {
+	// InputCount is the number of chunks deduplicated (retrieved, for Retrieve).
+	InputCount int
+
+	// ClusterCount is the number of clusters formed.
+	ClusterCount int
+
+	// OutputCount is the number of chunks returned.
+	OutputCount int
+
+	// Compression holds compressor metrics when Config.Compressor is set.
+	Compression *compress.Stats
+
+	// Latency is the total processing time.
+	Latency time.Duration
+}
+
+// Result holds the chunks returned by Dedupe or Retrieve.
+type Result struct {
+	Chunks []Chunk
+	Stats  Stats
+}
+
+// Distiller runs the Distill pipeline in-process. It is safe for
+// concurrent use as long as the configured Embedder, Retriever, and
+// Compressor are.
+type Distiller struct {
+	cfg       Config
+	clusterer *contextlab.Clusterer
+	selector  *contextlab.Selector
+	broker    *contextlab.Broker
+}
+
+// New creates a Distiller from cfg, applying defaults for zero values.
+func New(cfg Config) *Distiller {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 0.15
+	}
+	if cfg.Linkage == "" {
+		cfg.Linkage = "average"
+	}
+	if cfg.Selection == "" {
+		cfg.Selection = contextlab.SelectByScore
+	}
+	if cfg.Lambda <= 0 || cfg.Lambda > 1 {
+		cfg.Lambda = 0.5
+	}
+
+	d := &Distiller{
+		cfg: cfg,
+		clusterer: contextlab.NewClusterer(contextlab.ClusterConfig{
+			Threshold: cfg.Threshold,
+			Linkage:   cfg.Linkage,
+		}),
+		selector: contextlab.NewSelector(contextlab.SelectorConfig{
+			Strategy: cfg.Selection,
+		}),
+	}
+
+	if cfg.Retriever != nil {
+		d.broker = contextlab.NewBrokerWithEmbedder(cfg.Retriever, cfg.Embedder, contextlab.BrokerConfig{
+			OverFetchK:        cfg.OverFetchK,
+			TargetK:           cfg.TargetK,
+			ClusterThreshold:  cfg.Threshold,
+			ClusterLinkage:    cfg.Linkage,
+			SelectionStrategy: cfg.Selection,
+			EnableMMR:         !cfg.DisableMMR,
+			MMRLambda:         cfg.Lambda,
+			IncludeMetadata:   true,
+		})
+	}
+
+	return d
+}
+
+// Dedupe clusters chunks, keeps one representative per cluster, and
+// re-ranks down to TargetK. Chunks without embeddings are embedded with
+// Config.Embedder. The input slice is not modified.
+func (d *Distiller) Dedupe(ctx context.Context, chunks []Chunk) (*Result, error) {
+	start := time.Now()
+
+	work := make([]Chunk, len(chunks))
+	copy(work, chunks)
+	if err := d.embedMissing(ctx, work); err != nil {
+		return nil, err
+	}
+
+	ctx = contextlab.WithVectorNorms(ctx)
+	clusterResult, err := d.clusterer.ClusterContext(ctx, work)
+	if err != nil {
+		return nil, err
+	}
+	selected := d.selector.Select(clusterResult)
+
+	targetK := d.cfg.TargetK
+	if targetK > 0 && len(selected) > targetK {
+		if d.cfg.DisableMMR {
+			selected = contextlab.SelectTopK(clusterResult, targetK, d.cfg.Selection)
+		} else {
+			selected, err = contextlab.NewMMR(contextlab.MMRConfig{
+				Lambda:  d.cfg.Lambda,
+				TargetK: targetK,
+			}).RerankContext(ctx, selected)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	res := &Result{
+		Chunks: selected,
+		Stats: Stats{
+			InputCount:   len(chunks),
+			ClusterCount: clusterResult.ClusterCount,
+		},
+	}
+	if err := d.compress(ctx, res); err != nil {
+		return nil, err
+	}
+
+	res.Stats.OutputCount = len(res.Chunks)
+	res.Stats.Latency = time.Since(start)
+	return res, nil
+}
+
+// Retrieve queries Config.Retriever with a text query, over-fetching and
+// deduplicating the results the same way distill serve does.
+func (d *Distiller) Retrieve(ctx context.Context, query string) (*Result, error) {
+	return d.RetrieveRequest(ctx, &types.RetrievalRequest{
+		Query:     query,
+		Namespace: d.cfg.Namespace,
+	})
+}
+
+// RetrieveRequest is Retrieve with full control over the request, e.g.
+// to pass a precomputed query embedding, a metadata filter, or an
+// exclude list.
+func (d *Distiller) RetrieveRequest(ctx context.Context, req *types.RetrievalRequest) (*Result, error) {
+	if d.broker == nil {
+		return nil, ErrNoRetriever
+	}
+	if req.Query != "" && len(req.QueryEmbedding) == 0 && d.cfg.Embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+
+	start := time.Now()
+	br, err := d.broker.Retrieve(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{
+		Chunks: br.Chunks,
+		Stats: Stats{
+			InputCount:   br.Stats.Retrieved,
+			ClusterCount: br.Stats.Clustered,
+		},
+	}
+	if err := d.compress(ctx, res); err != nil {
+		return nil, err
+	}
+
+	res.Stats.OutputCount = len(res.Chunks)
+	res.Stats.Latency = time.Since(start)
+	return res, nil
+}
+
+// Close releases the retriever, if any.
+func (d *Distiller) Close() error {
+	if d.broker != nil {
+		return d.broker.Close()
+	}
+	return nil
+}
+
+// embedMissing fills in embeddings for chunks that lack one.
+func (d *Distiller) embedMissing(ctx context.Context, chunks []Chunk) error {
+	var idx []int
+	var texts []string
+	for i, c := range chunks {
+		if len(c.Embedding) == 0 {
+			idx = append(idx, i)
+			texts = append(texts, c.Text)
+		}
+	}
+	if len(idx) == 0 {
+		return nil
+	}
+	if d.cfg.Embedder == nil {
+		return ErrNoEmbedder
+	}
+
+	embeddings, err := d.cfg.Embedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	if len(embeddings) != len(idx) {
+		return fmt.Errorf("embedder returned %d embeddings for %d chunks", len(embeddings), len(idx))
+	}
+	for j, i := range idx {
+		chunks[i].Embedding = embeddings[j]
+	}
+	return nil
+}
+
+// compress applies the configured compressor to res in place.
+func (d *Distiller) compress(ctx context.Context, res *Result) error {
+	if d.cfg.Compressor == nil || len(res.Chunks) == 0 {
+		return nil
+	}
+
+	opts := compress.DefaultOptions()
+	if d.cfg.CompressOptions != nil {
+		opts = *d.cfg.CompressOptions
+	}
+
+	compressed, stats, err := d.cfg.Compressor.Compress(ctx, res.Chunks, opts)
+	if err != nil {
+		return fmt.Errorf("compression failed: %w", err)
+	}
+	res.Chunks = compressed
+	res.Stats.Compression = &stats
+	return nil
+}
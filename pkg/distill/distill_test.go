@@ -0,0 +1,130 @@
+package distill
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// letterEmbedder embeds text by its first byte, so texts sharing a first
+// letter are identical and others are orthogonal.
+type letterEmbedder struct{ calls int }
+
+func (e *letterEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	v := make([]float32, 26)
+	if text != "" {
+		v[(text[0]-'a')%26] = 1
+	}
+	return v, nil
+}
+
+func (e *letterEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i], _ = e.Embed(ctx, t)
+	}
+	return out, nil
+}
+
+func (e *letterEmbedder) Dimension() int    { return 26 }
+func (e *letterEmbedder) ModelName() string { return "letters" }
+
+type stubRetriever struct{ chunks []types.Chunk }
+
+func (r *stubRetriever) Query(ctx context.Context, req *types.RetrievalRequest) (*types.RetrievalResult, error) {
+	out := make([]types.Chunk, len(r.chunks))
+	copy(out, r.chunks)
+	return &types.RetrievalResult{Chunks: out}, nil
+}
+
+func (r *stubRetriever) QueryByID(ctx context.Context, id string, topK int, namespace string) (*types.RetrievalResult, error) {
+	return r.Query(ctx, nil)
+}
+
+func (r *stubRetriever) Close() error { return nil }
+
+func sampleChunks() []Chunk {
+	return []Chunk{
+		{ID: "1", Text: "apple pie recipe", Score: 0.9},
+		{ID: "2", Text: "apple pie recipe, again", Score: 0.8},
+		{ID: "3", Text: "banana bread", Score: 0.7},
+		{ID: "4", Text: "cherry tart", Score: 0.6},
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	emb := &letterEmbedder{}
+	d := New(Config{Embedder: emb})
+
+	input := sampleChunks()
+	res, err := d.Dedupe(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Dedupe: %v", err)
+	}
+
+	if res.Stats.InputCount != 4 || res.Stats.OutputCount != 3 || res.Stats.ClusterCount != 3 {
+		t.Errorf("unexpected stats: %+v", res.Stats)
+	}
+	if emb.calls != 1 {
+		t.Errorf("expected one batch embed call, got %d", emb.calls)
+	}
+	if input[0].Embedding != nil {
+		t.Error("Dedupe should not modify the input slice")
+	}
+}
+
+func TestDedupe_TargetKAndCompression(t *testing.T) {
+	d := New(Config{
+		TargetK:    2,
+		Embedder:   &letterEmbedder{},
+		Compressor: compress.NewExtractiveCompressor(),
+	})
+
+	res, err := d.Dedupe(context.Background(), sampleChunks())
+	if err != nil {
+		t.Fatalf("Dedupe: %v", err)
+	}
+	if len(res.Chunks) != 2 {
+		t.Errorf("expected 2 chunks, got %d", len(res.Chunks))
+	}
+	if res.Stats.Compression == nil {
+		t.Error("expected compression stats")
+	}
+}
+
+func TestDedupe_NoEmbedder(t *testing.T) {
+	_, err := New(Config{}).Dedupe(context.Background(), sampleChunks())
+	if !errors.Is(err, ErrNoEmbedder) || !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected ErrNoEmbedder, got %v", err)
+	}
+}
+
+func TestRetrieve(t *testing.T) {
+	emb := &letterEmbedder{}
+	chunks := sampleChunks()
+	for i := range chunks {
+		chunks[i].Embedding, _ = emb.Embed(context.Background(), chunks[i].Text)
+	}
+
+	d := New(Config{Embedder: emb, Retriever: &stubRetriever{chunks: chunks}})
+	defer func() { _ = d.Close() }()
+
+	res, err := d.Retrieve(context.Background(), "apple")
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if res.Stats.InputCount != 4 || len(res.Chunks) != 3 {
+		t.Errorf("unexpected result: %d chunks, stats %+v", len(res.Chunks), res.Stats)
+	}
+}
+
+func TestRetrieve_NoRetriever(t *testing.T) {
+	if _, err := New(Config{}).Retrieve(context.Background(), "q"); !errors.Is(err, ErrNoRetriever) {
+		t.Errorf("expected ErrNoRetriever, got %v", err)
+	}
+}
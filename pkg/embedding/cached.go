@@ -0,0 +1,230 @@
+package embedding
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/cache"
+)
+
+// CachedProvider wraps a Provider with a cache of text to vector, so
+// texts embedded again, such as system prompts and tool definitions
+// sent with every request, are not sent to the provider. Keys hash the
+// text with its detected pattern type, model, and input type; see
+// cache.PatternDetector.
+type CachedProvider struct {
+	provider Provider
+	cache    cache.Cache
+	ttl      time.Duration
+	detector *cache.PatternDetector
+
+	mu   sync.Mutex
+	hits map[cache.PatternType]int64
+}
+
+// NewCachedProvider creates an embedding provider caching up to maxSize
+// vectors in memory, evicting the least recently used.
+func NewCachedProvider(provider Provider, maxSize int) *CachedProvider {
+	if maxSize <= 0 {
+		maxSize = 10000
+	}
+	return NewCachedProviderWithCache(provider, cache.NewMemoryCache(cache.Config{MaxSize: int64(maxSize)}), 0)
+}
+
+// NewCachedProviderWithCache creates an embedding provider caching vectors
+// in c for ttl, or until evicted when ttl is 0. c may be shared, e.g. a
+// Redis cache shared by replicas.
+func NewCachedProviderWithCache(provider Provider, c cache.Cache, ttl time.Duration) *CachedProvider {
+	return &CachedProvider{
+		provider: provider,
+		cache:    c,
+		ttl:      ttl,
+		detector: cache.NewPatternDetector(),
+		hits:     make(map[cache.PatternType]int64),
+	}
+}
+
+// Embed returns the cached embedding of text or computes and caches it.
+func (c *CachedProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	key, pattern := c.key(ctx, text)
+	if v := c.get(ctx, key, pattern); v != nil {
+		return v, nil
+	}
+
+	embedding, err := c.provider.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	c.store(ctx, key, embedding)
+	return embedding, nil
+}
+
+// EmbedBatch embeds texts, sending only those not cached to the provider.
+// A text repeated within the batch is sent once.
+func (c *CachedProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	results := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+	patterns := make([]cache.PatternType, len(texts))
+	for i, text := range texts {
+		keys[i], patterns[i] = c.key(ctx, text)
+	}
+
+	var found map[string][]byte
+	if bc, ok := c.cache.(cache.BatchCache); ok {
+		found, _ = bc.GetMulti(ctx, keys)
+	}
+
+	// Misses by key, so duplicates within texts share one upstream slot
+	missing := make(map[string][]int)
+	var uncached []string
+	var uncachedKeys []string
+	for i, key := range keys {
+		var v []float32
+		if found != nil {
+			if data, ok := found[key]; ok {
+				v = decodeVector(data)
+				c.countHit(patterns[i], v)
+			}
+		} else {
+			v = c.get(ctx, key, patterns[i])
+		}
+		if v != nil {
+			results[i] = v
+			continue
+		}
+		if _, seen := missing[key]; !seen {
+			uncached = append(uncached, texts[i])
+			uncachedKeys = append(uncachedKeys, key)
+		}
+		missing[key] = append(missing[key], i)
+	}
+	if len(uncached) == 0 {
+		return results, nil
+	}
+
+	embeddings, err := c.provider.EmbedBatch(ctx, uncached)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(uncached) {
+		return nil, fmt.Errorf("embedding provider returned %d vectors for %d texts", len(embeddings), len(uncached))
+	}
+	for j, embedding := range embeddings {
+		key := uncachedKeys[j]
+		for n, i := range missing[key] {
+			if n == 0 {
+				results[i] = embedding
+				continue
+			}
+			results[i] = append([]float32(nil), embedding...)
+		}
+		c.store(ctx, key, embedding)
+	}
+	return results, nil
+}
+
+// key returns the cache key of text and its pattern type. A query and a
+// document with the same text may embed differently, and so may two
+// models, so both are part of the key.
+func (c *CachedProvider) key(ctx context.Context, text string) (string, cache.PatternType) {
+	prefix := "embedding:" + c.provider.ModelName() + ":" + string(InputTypeFrom(ctx))
+	if p := c.detector.DetectPattern(text); p != nil {
+		return cache.CacheKey(prefix, p), p.Type
+	}
+	return cache.CacheKeyForText(prefix, text), cache.PatternTypeUnknown
+}
+
+// get returns the cached vector under key, or nil.
+func (c *CachedProvider) get(ctx context.Context, key string, pattern cache.PatternType) []float32 {
+	data, err := c.cache.Get(ctx, key)
+	if err != nil {
+		return nil
+	}
+	v := decodeVector(data)
+	c.countHit(pattern, v)
+	return v
+}
+
+// countHit counts a hit on pattern when v decoded.
+func (c *CachedProvider) countHit(pattern cache.PatternType, v []float32) {
+	if v == nil {
+		return
+	}
+	c.mu.Lock()
+	c.hits[pattern]++
+	c.mu.Unlock()
+}
+
+// store caches v under key. Failures only cost a future miss.
+func (c *CachedProvider) store(ctx context.Context, key string, v []float32) {
+	_ = c.cache.Set(ctx, key, encodeVector(v), c.ttl)
+}
+
+// encodeVector packs v as little-endian float32s.
+func encodeVector(v []float32) []byte {
+	data := make([]byte, len(v)*4)
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(f))
+	}
+	return data
+}
+
+// decodeVector unpacks encodeVector's output, or returns nil if data is
+// not a vector.
+func decodeVector(data []byte) []float32 {
+	if len(data) == 0 || len(data)%4 != 0 {
+		return nil
+	}
+	v := make([]float32, len(data)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return v
+}
+
+// Dimension returns the embedding dimension.
+func (c *CachedProvider) Dimension() int {
+	return c.provider.Dimension()
+}
+
+// ModelName returns the model name.
+func (c *CachedProvider) ModelName() string {
+	return c.provider.ModelName()
+}
+
+// CacheSize returns the number of cached embeddings.
+func (c *CachedProvider) CacheSize() int {
+	return int(c.cache.Stats().Size)
+}
+
+// CacheStats returns the underlying cache's statistics.
+func (c *CachedProvider) CacheStats() cache.Stats {
+	return c.cache.Stats()
+}
+
+// PatternHits returns cache hits by the pattern type of the text, e.g.
+// how often a system prompt was served from the cache.
+func (c *CachedProvider) PatternHits() map[cache.PatternType]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[cache.PatternType]int64, len(c.hits))
+	for p, n := range c.hits {
+		out[p] = n
+	}
+	return out
+}
+
+// ClearCache clears the embedding cache.
+func (c *CachedProvider) ClearCache() {
+	_ = c.cache.Clear(context.Background())
+}
+
+// Close closes the cache, including one given to
+// NewCachedProviderWithCache.
+func (c *CachedProvider) Close() error {
+	return c.cache.Close()
+}
@@ -0,0 +1,115 @@
+package embedding_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+)
+
+// countingProvider embeds a text as its length and records every text
+// sent to it.
+type countingProvider struct {
+	sent []string
+}
+
+func (p *countingProvider) Embed(_ context.Context, text string) ([]float32, error) {
+	p.sent = append(p.sent, text)
+	return []float32{float32(len(text)), 1}, nil
+}
+
+func (p *countingProvider) EmbedBatch(_ context.Context, texts []string) ([][]float32, error) {
+	p.sent = append(p.sent, texts...)
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = []float32{float32(len(text)), 1}
+	}
+	return out, nil
+}
+
+func (p *countingProvider) Dimension() int    { return 2 }
+func (p *countingProvider) ModelName() string { return "counting" }
+
+const systemPrompt = "You are a helpful assistant that answers billing questions in two sentences."
+
+func TestCachedProvider_EmbedBatchSendsOnlyMisses(t *testing.T) {
+	inner := &countingProvider{}
+	p := embedding.NewCachedProvider(inner, 100)
+	ctx := context.Background()
+
+	if _, err := p.EmbedBatch(ctx, []string{systemPrompt, "first question"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.EmbedBatch(ctx, []string{systemPrompt, "second question", "second question", "first question"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{systemPrompt, "first question", "second question"}
+	if strings.Join(inner.sent, "|") != strings.Join(want, "|") {
+		t.Errorf("sent upstream %q, want %q", inner.sent, want)
+	}
+	for i, text := range []string{systemPrompt, "second question", "second question", "first question"} {
+		if len(got[i]) != 2 || got[i][0] != float32(len(text)) {
+			t.Errorf("result %d = %v, want the embedding of %q", i, got[i], text)
+		}
+	}
+
+	// Duplicates in a batch get their own copies
+	got[1][0] = -1
+	if got[2][0] == -1 {
+		t.Error("repeated text shares its vector")
+	}
+
+	hits := p.PatternHits()
+	if hits[cache.PatternTypeSystem] != 1 || hits[cache.PatternTypeUnknown] != 1 {
+		t.Errorf("pattern hits = %v", hits)
+	}
+	if st := p.CacheStats(); st.Size != 3 {
+		t.Errorf("cache size = %d, want 3", st.Size)
+	}
+}
+
+func TestCachedProvider_Embed(t *testing.T) {
+	inner := &countingProvider{}
+	c := cache.NewMemoryCache(cache.Config{MaxSize: 10})
+	p := embedding.NewCachedProviderWithCache(inner, c, time.Minute)
+	defer func() { _ = p.Close() }()
+	ctx := context.Background()
+
+	first, _ := p.Embed(ctx, "refunds")
+	first[0] = -1
+	again, err := p.Embed(ctx, "refunds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.sent) != 1 {
+		t.Errorf("sent %d texts upstream, want 1", len(inner.sent))
+	}
+	if again[0] != float32(len("refunds")) {
+		t.Errorf("cached vector was mutated through a returned slice: %v", again)
+	}
+
+	p.ClearCache()
+	if p.CacheSize() != 0 {
+		t.Errorf("CacheSize after ClearCache = %d", p.CacheSize())
+	}
+}
+
+// shortProvider returns fewer vectors than texts.
+type shortProvider struct{ countingProvider }
+
+func (p *shortProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out, _ := p.countingProvider.EmbedBatch(ctx, texts)
+	return out[:len(out)-1], nil
+}
+
+func TestCachedProvider_ShortBatch(t *testing.T) {
+	p := embedding.NewCachedProvider(&shortProvider{}, 10)
+	if _, err := p.EmbedBatch(context.Background(), []string{"a", "b"}); err == nil {
+		t.Error("expected an error when the provider returns too few vectors")
+	}
+}
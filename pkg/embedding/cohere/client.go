@@ -8,15 +8,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Siddhant-K-code/distill/pkg/embedding"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
 )
 
 const (
-	defaultBaseURL = "https://api.cohere.ai/v1"
-	defaultModel   = "embed-english-v3.0"
-	defaultTimeout = 30 * time.Second
+	defaultBaseURL   = "https://api.cohere.ai/v1"
+	defaultModel     = "embed-english-v3.0"
+	defaultTimeout   = 30 * time.Second
+	defaultBatchSize = 96 // the most texts the API takes per call
 )
 
 // InputType controls how Cohere classifies the input for retrieval tasks.
@@ -31,9 +34,11 @@ const (
 
 // Model dimensions for common Cohere embedding models.
 var modelDimensions = map[string]int{
-	"embed-english-v3.0":       1024,
-	"embed-multilingual-v3.0":  1024,
-	"embed-english-light-v3.0": 384,
+	"embed-english-v3.0":            1024,
+	"embed-multilingual-v3.0":       1024,
+	"embed-english-light-v3.0":      384,
+	"embed-multilingual-light-v3.0": 384,
+	"embed-v4.0":                    1536,
 }
 
 // Config holds Cohere client configuration.
@@ -44,9 +49,18 @@ type Config struct {
 	// Model is the embedding model. Default: embed-english-v3.0
 	Model string
 
-	// InputType controls retrieval optimisation. Default: search_document
+	// BaseURL overrides the API endpoint. Default: https://api.cohere.ai/v1
+	BaseURL string
+
+	// InputType fixes the input type of every call. Default: empty, which
+	// sends search_query for texts embedded as queries (see
+	// embedding.WithInputType) and search_document otherwise.
 	InputType InputType
 
+	// BatchSize caps the texts per call; larger batches are split.
+	// Default: 96
+	BatchSize int
+
 	// Timeout for API requests. Default: 30s
 	Timeout time.Duration
 }
@@ -66,8 +80,12 @@ func NewClient(cfg Config) (*Client, error) {
 	if cfg.Model == "" {
 		cfg.Model = defaultModel
 	}
-	if cfg.InputType == "" {
-		cfg.InputType = InputTypeSearchDocument
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
 	}
 	if cfg.Timeout <= 0 {
 		cfg.Timeout = defaultTimeout
@@ -102,23 +120,42 @@ func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
 	return results[0], nil
 }
 
-// EmbedBatch embeds multiple texts in a single API call.
+// EmbedBatch embeds texts, BatchSize per API call.
 func (c *Client) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
 	if len(texts) == 0 {
 		return nil, nil
 	}
+	inputType := c.cfg.InputType
+	if inputType == "" {
+		inputType = InputTypeSearchDocument
+		if embedding.InputTypeFrom(ctx) == embedding.InputQuery {
+			inputType = InputTypeSearchQuery
+		}
+	}
+
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += c.cfg.BatchSize {
+		vecs, err := c.embedBatch(ctx, texts[start:min(start+c.cfg.BatchSize, len(texts))], inputType)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vecs...)
+	}
+	return out, nil
+}
 
+func (c *Client) embedBatch(ctx context.Context, texts []string, inputType InputType) ([][]float32, error) {
 	body, err := json.Marshal(embedRequest{
 		Texts:     texts,
 		Model:     c.cfg.Model,
-		InputType: c.cfg.InputType,
+		InputType: inputType,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
-		defaultBaseURL+"/embed", bytes.NewReader(body))
+		c.cfg.BaseURL+"/embed", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("build request: %w", err)
 	}
@@ -138,8 +175,8 @@ func (c *Client) EmbedBatch(ctx context.Context, texts []string) ([][]float32, e
 		return nil, embedding.ErrInvalidAPIKey
 	}
 	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("cohere %d: %s", resp.StatusCode, string(b))
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &errs.StatusError{StatusCode: resp.StatusCode, Err: fmt.Errorf("cohere %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))}
 	}
 
 	var result embedResponse
@@ -0,0 +1,121 @@
+package cohere
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+)
+
+// fakeServer answers /embed with one vector per text, [index in batch,
+// text length], and records each request.
+func fakeServer(t *testing.T, requests *[]embedRequest) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embed" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req embedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		*requests = append(*requests, req)
+		var resp embedResponse
+		for i, text := range req.Texts {
+			resp.Embeddings = append(resp.Embeddings, []float32{float32(i), float32(len(text))})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEmbedBatch_Batches(t *testing.T) {
+	var requests []embedRequest
+	srv := fakeServer(t, &requests)
+	client, err := NewClient(Config{APIKey: "key", BaseURL: srv.URL + "/", BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := client.EmbedBatch(context.Background(), []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	if len(requests) != 2 || len(requests[0].Texts) != 2 || len(requests[1].Texts) != 1 {
+		t.Fatalf("requests = %+v, want batches of 2 and 1", requests)
+	}
+	if len(got) != 3 || got[2][1] != 3 {
+		t.Errorf("got %v, want three vectors in input order", got)
+	}
+	if requests[0].Model != defaultModel {
+		t.Errorf("model = %q, want %q", requests[0].Model, defaultModel)
+	}
+	if client.Dimension() != 1024 {
+		t.Errorf("Dimension = %d, want 1024", client.Dimension())
+	}
+}
+
+func TestEmbedBatch_InputType(t *testing.T) {
+	tests := []struct {
+		name  string
+		fixed InputType
+		ctx   context.Context
+		want  InputType
+	}{
+		{name: "document by default", ctx: context.Background(), want: InputTypeSearchDocument},
+		{name: "query", ctx: embedding.WithInputType(context.Background(), embedding.InputQuery), want: InputTypeSearchQuery},
+		{name: "fixed", fixed: InputTypeClustering, ctx: embedding.WithInputType(context.Background(), embedding.InputQuery), want: InputTypeClustering},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []embedRequest
+			srv := fakeServer(t, &requests)
+			client, err := NewClient(Config{APIKey: "key", BaseURL: srv.URL, InputType: tt.fixed})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.Embed(tt.ctx, "refunds"); err != nil {
+				t.Fatalf("Embed: %v", err)
+			}
+			if got := requests[0].InputType; got != tt.want {
+				t.Errorf("input_type = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbed_Errors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, embedding.ErrInvalidAPIKey},
+		{http.StatusTooManyRequests, embedding.ErrRateLimited},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		client, err := NewClient(Config{APIKey: "key", BaseURL: srv.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Embed(context.Background(), "x"); !errors.Is(err, tt.want) {
+			t.Errorf("status %d: error = %v, want %v", tt.status, err, tt.want)
+		}
+		srv.Close()
+	}
+}
+
+func TestNewClient_RequiresKey(t *testing.T) {
+	if _, err := NewClient(Config{}); err == nil {
+		t.Error("expected an error without an API key")
+	}
+}
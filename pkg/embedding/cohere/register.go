@@ -7,8 +7,9 @@ import (
 func init() {
 	embedding.RegisterFactory(embedding.ProviderCohere, func(cfg embedding.ProviderConfig) (embedding.Provider, error) {
 		return NewClient(Config{
-			APIKey: cfg.APIKey,
-			Model:  cfg.Model,
+			APIKey:  cfg.APIKey,
+			Model:   cfg.Model,
+			BaseURL: cfg.BaseURL,
 		})
 	})
 }
@@ -0,0 +1,159 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+// ErrInjected is returned by calls chosen to fail by an Injector. It is
+// classified as a backend error, like a real provider outage.
+var ErrInjected = errs.New(errs.ErrBackend, "fake: injected failure")
+
+// Latency distributions.
+const (
+	Fixed       = "fixed"
+	Uniform     = "uniform"
+	Normal      = "normal"
+	Exponential = "exponential"
+)
+
+// Latency describes a distribution of simulated call latencies.
+type Latency struct {
+	// Distribution is Fixed, Uniform, Normal, or Exponential. Empty
+	// means Fixed.
+	Distribution string
+
+	// Mean is the average latency.
+	Mean time.Duration
+
+	// Spread is the half-width for Uniform and the standard deviation
+	// for Normal. It is ignored by Fixed and Exponential.
+	Spread time.Duration
+}
+
+// ParseLatency parses "distribution:mean[:spread]", e.g. "fixed:10ms",
+// "uniform:50ms:20ms", "normal:80ms:15ms", or "exponential:30ms". A bare
+// duration such as "10ms" is fixed; "" means no latency.
+func ParseLatency(s string) (Latency, error) {
+	if s == "" {
+		return Latency{}, nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) == 1 {
+		parts = []string{Fixed, parts[0]}
+	}
+	if len(parts) > 3 {
+		return Latency{}, fmt.Errorf("invalid latency %q: want distribution:mean[:spread]", s)
+	}
+
+	l := Latency{Distribution: strings.ToLower(parts[0])}
+	switch l.Distribution {
+	case Fixed, Uniform, Normal, Exponential:
+	default:
+		return Latency{}, fmt.Errorf("invalid latency %q: unknown distribution %q (supported: fixed, uniform, normal, exponential)", s, parts[0])
+	}
+
+	var err error
+	if l.Mean, err = time.ParseDuration(parts[1]); err != nil {
+		return Latency{}, fmt.Errorf("invalid latency %q: %w", s, err)
+	}
+	if len(parts) == 3 {
+		if l.Spread, err = time.ParseDuration(parts[2]); err != nil {
+			return Latency{}, fmt.Errorf("invalid latency %q: %w", s, err)
+		}
+	}
+	if l.Mean < 0 || l.Spread < 0 {
+		return Latency{}, fmt.Errorf("invalid latency %q: durations must be non-negative", s)
+	}
+	return l, nil
+}
+
+// String returns l in the form accepted by ParseLatency.
+func (l Latency) String() string {
+	if l.Mean == 0 && l.Spread == 0 {
+		return ""
+	}
+	dist := l.Distribution
+	if dist == "" {
+		dist = Fixed
+	}
+	if l.Spread > 0 && (dist == Uniform || dist == Normal) {
+		return fmt.Sprintf("%s:%s:%s", dist, l.Mean, l.Spread)
+	}
+	return fmt.Sprintf("%s:%s", dist, l.Mean)
+}
+
+// Sample draws one latency from the distribution. Results are never
+// negative.
+func (l Latency) Sample(rng *rand.Rand) time.Duration {
+	var d float64
+	mean := float64(l.Mean)
+	switch l.Distribution {
+	case Uniform:
+		d = mean + (rng.Float64()*2-1)*float64(l.Spread)
+	case Normal:
+		d = mean + rng.NormFloat64()*float64(l.Spread)
+	case Exponential:
+		d = rng.ExpFloat64() * mean
+	default:
+		d = mean
+	}
+	return time.Duration(math.Max(d, 0))
+}
+
+// Injector adds simulated latency and failures to calls. A nil Injector
+// injects nothing. It is safe for concurrent use.
+type Injector struct {
+	latency   Latency
+	errorRate float64
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewInjector creates an Injector. It returns nil if latency and
+// errorRate are both zero.
+func NewInjector(latency Latency, errorRate float64, seed int64) *Injector {
+	if latency.Mean == 0 && latency.Spread == 0 && errorRate <= 0 {
+		return nil
+	}
+	return &Injector{
+		latency:   latency,
+		errorRate: errorRate,
+		rng:       rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Inject waits for a sampled latency, then fails with ErrInjected at the
+// configured rate. It returns ctx.Err() if ctx ends while waiting.
+func (i *Injector) Inject(ctx context.Context) error {
+	if i == nil {
+		return nil
+	}
+
+	i.mu.Lock()
+	delay := i.latency.Sample(i.rng)
+	fail := i.errorRate > 0 && i.rng.Float64() < i.errorRate
+	i.mu.Unlock()
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	if fail {
+		return ErrInjected
+	}
+	return nil
+}
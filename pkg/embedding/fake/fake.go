@@ -0,0 +1,156 @@
+// Package fake provides a deterministic, credential-free embedding
+// provider for tests, benchmarks, and demos. Texts are embedded by
+// feature hashing their words, so texts that share vocabulary land close
+// together and dedup behaves much as it would on real embeddings.
+//
+// Latency and failures can be injected to exercise timeouts, retries,
+// and error paths.
+package fake
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+	distillmath "github.com/Siddhant-K-code/distill/pkg/math"
+)
+
+// Provider is the registry name of the fake provider.
+const Provider embedding.ProviderType = "fake"
+
+// Defaults for Config.
+const (
+	DefaultDimension = 256
+	DefaultModel     = "fake-hash"
+)
+
+// Config configures an Embedder.
+type Config struct {
+	// Dimension of the generated vectors (default 256).
+	Dimension int
+
+	// Model is the name reported by ModelName (default "fake-hash").
+	Model string
+
+	// Latency is added to every Embed and EmbedBatch call.
+	Latency Latency
+
+	// ErrorRate is the probability, in [0, 1], that a call fails with
+	// ErrInjected.
+	ErrorRate float64
+
+	// Seed makes injected latency and failures reproducible.
+	Seed int64
+}
+
+// Embedder is a fake embedding.Provider. It is safe for concurrent use.
+type Embedder struct {
+	dim      int
+	model    string
+	injector *Injector
+}
+
+// NewEmbedder creates a fake embedder.
+func NewEmbedder(cfg Config) *Embedder {
+	if cfg.Dimension <= 0 {
+		cfg.Dimension = DefaultDimension
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultModel
+	}
+	return &Embedder{
+		dim:      cfg.Dimension,
+		model:    cfg.Model,
+		injector: NewInjector(cfg.Latency, cfg.ErrorRate, cfg.Seed),
+	}
+}
+
+// Embed returns the vector for text after any injected latency or failure.
+func (e *Embedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, embedding.ErrEmptyInput
+	}
+	if err := e.injector.Inject(ctx); err != nil {
+		return nil, err
+	}
+	return e.Vector(text), nil
+}
+
+// EmbedBatch embeds texts with a single injection for the whole batch,
+// like one round trip to a real provider.
+func (e *Embedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	for _, t := range texts {
+		if t == "" {
+			return nil, embedding.ErrEmptyInput
+		}
+	}
+	if err := e.injector.Inject(ctx); err != nil {
+		return nil, err
+	}
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i] = e.Vector(t)
+	}
+	return out, nil
+}
+
+// Dimension returns the embedding dimension.
+func (e *Embedder) Dimension() int {
+	return e.dim
+}
+
+// ModelName returns the configured model name.
+func (e *Embedder) ModelName() string {
+	return e.model
+}
+
+// Vector embeds text directly, without injected latency or failures.
+// The same text always yields the same unit vector.
+func (e *Embedder) Vector(text string) []float32 {
+	v := make([]float32, e.dim)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	kept := words[:0]
+	for _, w := range words {
+		if !stopwords[w] {
+			kept = append(kept, w)
+		}
+	}
+	if len(kept) == 0 {
+		kept = []string{text}
+	}
+	for _, w := range kept {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(w))
+		sum := h.Sum64()
+		idx := int(sum % uint64(e.dim))
+		if sum&(1<<63) != 0 {
+			v[idx]--
+		} else {
+			v[idx]++
+		}
+	}
+
+	distillmath.NormalizeInPlace(v)
+	return v
+}
+
+// stopwords are ignored so that vectors reflect content words, as real
+// embeddings largely do.
+var stopwords = map[string]bool{
+	"a": true, "also": true, "an": true, "and": true, "are": true, "as": true,
+	"at": true, "be": true, "by": true, "do": true, "does": true, "for": true,
+	"from": true, "how": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "the": true, "to": true, "what": true, "when": true,
+	"with": true, "work": true,
+}
+
+func init() {
+	embedding.RegisterFactory(Provider, func(cfg embedding.ProviderConfig) (embedding.Provider, error) {
+		return NewEmbedder(Config{Model: cfg.Model}), nil
+	})
+}
@@ -0,0 +1,161 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	distillmath "github.com/Siddhant-K-code/distill/pkg/math"
+)
+
+var _ embedding.Provider = (*Embedder)(nil)
+
+func TestEmbedder_Deterministic(t *testing.T) {
+	e := NewEmbedder(Config{Dimension: 64})
+	a, err := e.Embed(context.Background(), "tokens expire after one hour")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewEmbedder(Config{Dimension: 64}).Vector("tokens expire after one hour")
+
+	if len(a) != 64 {
+		t.Fatalf("expected dimension 64, got %d", len(a))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("expected identical vectors, differ at %d", i)
+		}
+	}
+}
+
+func TestEmbedder_Similarity(t *testing.T) {
+	e := NewEmbedder(Config{})
+	base := e.Vector("Invoices are generated on the first day of each month.")
+	near := e.Vector("Note: invoices are generated on the first day of each month.")
+	far := e.Vector("Load balancers terminate TLS at the edge.")
+
+	dNear := distillmath.CosineDistance(base, near)
+	dFar := distillmath.CosineDistance(base, far)
+	if dNear >= dFar {
+		t.Errorf("expected paraphrase closer than unrelated text: near=%f far=%f", dNear, dFar)
+	}
+	if dNear > 0.15 {
+		t.Errorf("expected paraphrase within default dedup threshold, got %f", dNear)
+	}
+}
+
+func TestEmbedder_EmptyInput(t *testing.T) {
+	e := NewEmbedder(Config{})
+	if _, err := e.Embed(context.Background(), ""); !errors.Is(err, embedding.ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+	if _, err := e.EmbedBatch(context.Background(), []string{"a", ""}); !errors.Is(err, embedding.ErrEmptyInput) {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+}
+
+func TestEmbedder_ErrorRate(t *testing.T) {
+	e := NewEmbedder(Config{ErrorRate: 1})
+	_, err := e.Embed(context.Background(), "text")
+	if !errors.Is(err, ErrInjected) || !errors.Is(err, errs.ErrBackend) {
+		t.Errorf("expected injected backend error, got %v", err)
+	}
+
+	e = NewEmbedder(Config{ErrorRate: 0.5, Seed: 7})
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		if _, err := e.Embed(context.Background(), "text"); err != nil {
+			failed++
+		}
+	}
+	if failed < 400 || failed > 600 {
+		t.Errorf("expected about 500 failures, got %d", failed)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	p, err := embedding.NewProvider(embedding.ProviderConfig{Type: Provider, CacheSize: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Dimension() != DefaultDimension || p.ModelName() != DefaultModel {
+		t.Errorf("unexpected provider %s/%d", p.ModelName(), p.Dimension())
+	}
+}
+
+func TestParseLatency(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Latency
+		wantErr bool
+	}{
+		{"", Latency{}, false},
+		{"10ms", Latency{Fixed, 10 * time.Millisecond, 0}, false},
+		{"uniform:50ms:20ms", Latency{Uniform, 50 * time.Millisecond, 20 * time.Millisecond}, false},
+		{"Normal:80ms:15ms", Latency{Normal, 80 * time.Millisecond, 15 * time.Millisecond}, false},
+		{"exponential:30ms", Latency{Exponential, 30 * time.Millisecond, 0}, false},
+		{"pareto:10ms", Latency{}, true},
+		{"fixed:soon", Latency{}, true},
+		{"fixed:-1ms", Latency{}, true},
+		{"normal:1ms:2ms:3ms", Latency{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLatency(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+			if !tt.wantErr && tt.in != "" {
+				if back, _ := ParseLatency(got.String()); back != got {
+					t.Errorf("String() did not round-trip: %q", got.String())
+				}
+			}
+		})
+	}
+}
+
+func TestLatency_Sample(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	l := Latency{Distribution: Uniform, Mean: 10 * time.Millisecond, Spread: 5 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		d := l.Sample(rng)
+		if d < 5*time.Millisecond || d > 15*time.Millisecond {
+			t.Fatalf("uniform sample %v out of range", d)
+		}
+	}
+
+	l = Latency{Distribution: Normal, Mean: time.Millisecond, Spread: 10 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		if d := l.Sample(rng); d < 0 {
+			t.Fatalf("negative sample %v", d)
+		}
+	}
+}
+
+func TestInjector_ContextCancel(t *testing.T) {
+	inj := NewInjector(Latency{Mean: time.Hour}, 0, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := inj.Inject(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestInjector_Nil(t *testing.T) {
+	if NewInjector(Latency{}, 0, 0) != nil {
+		t.Error("expected nil injector when nothing is injected")
+	}
+	var inj *Injector
+	if err := inj.Inject(context.Background()); err != nil {
+		t.Errorf("nil injector should not fail, got %v", err)
+	}
+}
@@ -0,0 +1,322 @@
+// Package local provides an embedding.Provider that runs a
+// sentence-transformer model, such as all-MiniLM-L6-v2 exported to ONNX,
+// in process through ONNX Runtime. No embedding API is called, so Distill
+// can run air-gapped.
+//
+// ONNX Runtime is a C library, loaded at run time. Binaries built without
+// the onnx build tag, or without cgo, cannot run models and report
+// ErrUnavailable:
+//
+//	CGO_ENABLED=1 go build -tags onnx .
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+// ErrUnavailable is returned by NewClient in builds without ONNX Runtime
+// support.
+var ErrUnavailable = errs.New(errs.ErrConfig, "local embeddings need a build with cgo and -tags onnx")
+
+const (
+	defaultMaxLength = 256
+	defaultBatchSize = 32
+)
+
+// Pooling modes, which turn token vectors into one text vector.
+const (
+	PoolingMean = "mean"
+	PoolingCLS  = "cls"
+)
+
+// Config holds local model configuration.
+type Config struct {
+	// ModelDir is a sentence-transformers model directory with an ONNX
+	// export, as published on Hugging Face: model.onnx or onnx/model.onnx,
+	// and vocab.txt (required). Its tokenizer_config.json,
+	// sentence_bert_config.json, 1_Pooling/config.json, and config.json
+	// fill in the settings below when present.
+	ModelDir string
+
+	// Library is the ONNX Runtime shared library. Default: the
+	// ONNXRUNTIME_LIB environment variable, or libonnxruntime.so on the
+	// library path.
+	Library string
+
+	// MaxLength is the most tokens per text; longer texts are cut.
+	// Default: the model's max_seq_length, or 256.
+	MaxLength int
+
+	// Pooling is PoolingMean or PoolingCLS. Default: the model's pooling
+	// config, or mean.
+	Pooling string
+
+	// BatchSize is the most texts per model run. Default: 32
+	BatchSize int
+}
+
+// session runs the model on a padded batch of token IDs, each row
+// seqLen long, and returns the output and its shape: [batch, seqLen, dim]
+// token vectors, or [batch, dim] text vectors.
+type session interface {
+	run(ids, mask, typeIDs []int64, batch, seqLen int) ([]float32, []int64, error)
+}
+
+// Client implements embedding.Provider with a local model.
+type Client struct {
+	cfg       Config
+	name      string
+	tokenizer *tokenizer
+	session   session
+
+	// dimension is the model's hidden size, or learned from the first
+	// output when config.json does not give it.
+	dimension atomic.Int64
+}
+
+// NewClient loads the model in cfg.ModelDir.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.ModelDir == "" {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("local embedding model directory is required"))
+	}
+	model, err := findModel(cfg.ModelDir)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Library == "" {
+		cfg.Library = os.Getenv("ONNXRUNTIME_LIB")
+	}
+
+	var meta modelMeta
+	meta.load(cfg.ModelDir)
+	if cfg.MaxLength == 0 {
+		cfg.MaxLength = meta.MaxSeqLength
+	}
+	if cfg.MaxLength == 0 {
+		cfg.MaxLength = defaultMaxLength
+	}
+	if cfg.Pooling == "" {
+		cfg.Pooling = meta.pooling()
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.MaxLength < 2 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("local embedding max length must be at least 2, got %d", cfg.MaxLength))
+	}
+	if cfg.Pooling != PoolingMean && cfg.Pooling != PoolingCLS {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("unknown pooling %q (supported: %s, %s)", cfg.Pooling, PoolingMean, PoolingCLS))
+	}
+
+	tok, err := loadTokenizer(filepath.Join(cfg.ModelDir, "vocab.txt"), meta.lowercase())
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("load tokenizer: %w", err))
+	}
+	s, err := newSession(model, cfg.Library)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		cfg:       cfg,
+		name:      filepath.Base(filepath.Clean(cfg.ModelDir)),
+		tokenizer: tok,
+		session:   s,
+	}
+	c.dimension.Store(int64(meta.HiddenSize))
+	return c, nil
+}
+
+// findModel returns the ONNX file of a model directory.
+func findModel(dir string) (string, error) {
+	for _, name := range []string{"model.onnx", filepath.Join("onnx", "model.onnx")} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", errs.Wrap(errs.ErrConfig, fmt.Errorf("no model.onnx or onnx/model.onnx in %s", dir))
+}
+
+// modelMeta is what the model directory's JSON files say, where present.
+type modelMeta struct {
+	MaxSeqLength int   `json:"max_seq_length"`
+	DoLowerCase  *bool `json:"do_lower_case"`
+	HiddenSize   int   `json:"hidden_size"`
+	PoolingCLS   bool  `json:"pooling_mode_cls_token"`
+	PoolingMean  bool  `json:"pooling_mode_mean_tokens"`
+}
+
+// load merges the directory's config files into m, skipping missing or
+// unreadable ones.
+func (m *modelMeta) load(dir string) {
+	for _, name := range []string{
+		"config.json",
+		"tokenizer_config.json",
+		"sentence_bert_config.json",
+		filepath.Join("1_Pooling", "config.json"),
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			_ = json.Unmarshal(data, m)
+		}
+	}
+}
+
+func (m modelMeta) lowercase() bool {
+	return m.DoLowerCase == nil || *m.DoLowerCase
+}
+
+func (m modelMeta) pooling() string {
+	if m.PoolingCLS && !m.PoolingMean {
+		return PoolingCLS
+	}
+	return PoolingMean
+}
+
+// Embed returns the embedding for a single text.
+func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, embedding.ErrEmptyInput
+	}
+	results, err := c.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// EmbedBatch embeds texts, BatchSize at a time.
+func (c *Client) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += c.cfg.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vecs, err := c.embedBatch(texts[start:min(start+c.cfg.BatchSize, len(texts))])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vecs...)
+	}
+	return out, nil
+}
+
+func (c *Client) embedBatch(texts []string) ([][]float32, error) {
+	encoded := make([][]int64, len(texts))
+	seqLen := 0
+	for i, text := range texts {
+		encoded[i] = c.tokenizer.encode(text, c.cfg.MaxLength)
+		seqLen = max(seqLen, len(encoded[i]))
+	}
+
+	// Pad each row to seqLen; padding is masked out
+	ids := make([]int64, len(texts)*seqLen)
+	mask := make([]int64, len(texts)*seqLen)
+	for i, row := range encoded {
+		copy(ids[i*seqLen:], row)
+		for j := range row {
+			mask[i*seqLen+j] = 1
+		}
+	}
+	output, shape, err := c.session.run(ids, mask, make([]int64, len(ids)), len(texts), seqLen)
+	if err != nil {
+		return nil, fmt.Errorf("local model: %w", err)
+	}
+
+	vecs, err := pool(output, shape, mask, c.cfg.Pooling)
+	if err != nil {
+		return nil, fmt.Errorf("local model: %w", err)
+	}
+	for _, v := range vecs {
+		normalizeL2(v)
+	}
+	if len(vecs) > 0 {
+		c.dimension.CompareAndSwap(0, int64(len(vecs[0])))
+	}
+	return vecs, nil
+}
+
+// pool reduces model output of shape [batch, seqLen, dim] to one vector
+// per text, averaging the unmasked tokens or taking the first. Output of
+// shape [batch, dim] is already pooled.
+func pool(output []float32, shape, mask []int64, mode string) ([][]float32, error) {
+	switch len(shape) {
+	case 2:
+		batch, dim := int(shape[0]), int(shape[1])
+		if len(output) != batch*dim {
+			return nil, fmt.Errorf("output has %d values for shape %v", len(output), shape)
+		}
+		vecs := make([][]float32, batch)
+		for i := range vecs {
+			vecs[i] = append([]float32(nil), output[i*dim:(i+1)*dim]...)
+		}
+		return vecs, nil
+	case 3:
+	default:
+		return nil, fmt.Errorf("unexpected output shape %v", shape)
+	}
+
+	batch, seqLen, dim := int(shape[0]), int(shape[1]), int(shape[2])
+	if len(output) != batch*seqLen*dim || len(mask) != batch*seqLen {
+		return nil, fmt.Errorf("output has %d values for shape %v", len(output), shape)
+	}
+	vecs := make([][]float32, batch)
+	for i := range vecs {
+		v := make([]float32, dim)
+		if mode == PoolingCLS {
+			copy(v, output[i*seqLen*dim:])
+			vecs[i] = v
+			continue
+		}
+		var n float32
+		for t := range seqLen {
+			if mask[i*seqLen+t] == 0 {
+				continue
+			}
+			token := output[(i*seqLen+t)*dim:]
+			for d := range v {
+				v[d] += token[d]
+			}
+			n++
+		}
+		if n > 0 {
+			for d := range v {
+				v[d] /= n
+			}
+		}
+		vecs[i] = v
+	}
+	return vecs, nil
+}
+
+// normalizeL2 scales v to unit length, as sentence-transformers' Normalize
+// module does, so dot products are cosine similarities.
+func normalizeL2(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	inv := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= inv
+	}
+}
+
+// Dimension returns the embedding dimension: the model's hidden size, or
+// 0 until the first text is embedded when config.json does not give it.
+func (c *Client) Dimension() int { return int(c.dimension.Load()) }
+
+// ModelName returns the model directory's name.
+func (c *Client) ModelName() string { return c.name }
@@ -0,0 +1,71 @@
+package local
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// sumSession returns, for each token, a 2-dim vector of its ID and 1.
+type sumSession struct{}
+
+func (sumSession) run(ids, mask, typeIDs []int64, batch, seqLen int) ([]float32, []int64, error) {
+	out := make([]float32, 0, len(ids)*2)
+	for _, id := range ids {
+		out = append(out, float32(id), 1)
+	}
+	return out, []int64{int64(batch), int64(seqLen), 2}, nil
+}
+
+func TestClient_EmbedBatch(t *testing.T) {
+	c := &Client{
+		cfg:       Config{MaxLength: 16, Pooling: PoolingMean, BatchSize: 1},
+		tokenizer: testTokenizer(t),
+		session:   sumSession{},
+	}
+	vecs, err := c.EmbedBatch(context.Background(), []string{"the", "go go go"})
+	if err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	if len(vecs) != 2 || c.Dimension() != 2 {
+		t.Fatalf("got %d vectors, dimension %d", len(vecs), c.Dimension())
+	}
+	for _, v := range vecs {
+		if n := math.Hypot(float64(v[0]), float64(v[1])); math.Abs(n-1) > 1e-6 {
+			t.Errorf("vector %v has norm %v, want 1", v, n)
+		}
+	}
+	// [CLS] the [SEP] averages to (2+4+3)/3 = 3 against 1
+	if got := vecs[0][0] / vecs[0][1]; math.Abs(float64(got)-3) > 1e-5 {
+		t.Errorf("mean pooled ratio = %v, want 3", got)
+	}
+}
+
+func TestPool(t *testing.T) {
+	// Two texts of two tokens, the second padded
+	output := []float32{1, 2, 3, 4, 5, 6, 7, 8}
+	mask := []int64{1, 1, 1, 0}
+	shape := []int64{2, 2, 2}
+
+	mean, err := pool(output, shape, mask, PoolingMean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mean[0][0] != 2 || mean[0][1] != 3 || mean[1][0] != 5 || mean[1][1] != 6 {
+		t.Errorf("mean = %v, want [[2 3] [5 6]]", mean)
+	}
+	cls, err := pool(output, shape, mask, PoolingCLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cls[1][0] != 5 || cls[1][1] != 6 {
+		t.Errorf("cls = %v, want second text [5 6]", cls)
+	}
+	pooled, err := pool([]float32{1, 2, 3, 4}, []int64{2, 2}, mask, PoolingMean)
+	if err != nil || pooled[1][1] != 4 {
+		t.Errorf("pooled output = %v, %v", pooled, err)
+	}
+	if _, err := pool(output, []int64{2, 3, 2}, mask, PoolingMean); err == nil {
+		t.Error("mismatched shape accepted")
+	}
+}
@@ -0,0 +1,11 @@
+package local
+
+import (
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+)
+
+func init() {
+	embedding.RegisterFactory(embedding.ProviderLocal, func(cfg embedding.ProviderConfig) (embedding.Provider, error) {
+		return NewClient(Config{ModelDir: cfg.Model})
+	})
+}
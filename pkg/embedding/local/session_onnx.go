@@ -0,0 +1,101 @@
+//go:build onnx && cgo
+
+package local
+
+import (
+	"fmt"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// ortMu guards loading the ONNX Runtime library, which happens once per
+// process.
+var ortMu sync.Mutex
+
+// initRuntime loads library, or the default, on first use. Later calls
+// keep the library first loaded.
+func initRuntime(library string) error {
+	ortMu.Lock()
+	defer ortMu.Unlock()
+	if ort.IsInitialized() {
+		return nil
+	}
+	if library == "" {
+		library = "libonnxruntime.so"
+	}
+	ort.SetSharedLibraryPath(library)
+	if err := ort.InitializeEnvironment(); err != nil {
+		return fmt.Errorf("load ONNX Runtime from %s: %w", library, err)
+	}
+	return nil
+}
+
+// onnxSession runs a BERT-style model: its inputs are input_ids,
+// attention_mask, and optionally token_type_ids.
+type onnxSession struct {
+	session *ort.DynamicAdvancedSession
+	inputs  []string
+}
+
+func newSession(model, library string) (session, error) {
+	if err := initRuntime(library); err != nil {
+		return nil, err
+	}
+	inputs, outputs, err := ort.GetInputOutputInfo(model)
+	if err != nil {
+		return nil, fmt.Errorf("read model %s: %w", model, err)
+	}
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("model %s has no outputs", model)
+	}
+
+	s := &onnxSession{}
+	for _, in := range inputs {
+		switch in.Name {
+		case "input_ids", "attention_mask", "token_type_ids":
+			s.inputs = append(s.inputs, in.Name)
+		default:
+			return nil, fmt.Errorf("model %s has unsupported input %q", model, in.Name)
+		}
+	}
+	// Prefer an already pooled output, as some exports include
+	output := outputs[0].Name
+	for _, out := range outputs {
+		if out.Name == "sentence_embedding" {
+			output = out.Name
+		}
+	}
+	s.session, err = ort.NewDynamicAdvancedSession(model, s.inputs, []string{output}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("load model %s: %w", model, err)
+	}
+	return s, nil
+}
+
+func (s *onnxSession) run(ids, mask, typeIDs []int64, batch, seqLen int) ([]float32, []int64, error) {
+	shape := ort.NewShape(int64(batch), int64(seqLen))
+	data := map[string][]int64{"input_ids": ids, "attention_mask": mask, "token_type_ids": typeIDs}
+	inputs := make([]ort.Value, len(s.inputs))
+	for i, name := range s.inputs {
+		t, err := ort.NewTensor(shape, data[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		defer t.Destroy() //nolint:errcheck
+		inputs[i] = t
+	}
+
+	outputs := []ort.Value{nil}
+	if err := s.session.Run(inputs, outputs); err != nil {
+		return nil, nil, err
+	}
+	defer outputs[0].Destroy() //nolint:errcheck
+	t, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, nil, fmt.Errorf("model output is %T, want float32 tensor", outputs[0])
+	}
+	// The tensor's data is freed with it
+	out := append([]float32(nil), t.GetData()...)
+	return out, t.GetShape(), nil
+}
@@ -0,0 +1,7 @@
+//go:build !onnx || !cgo
+
+package local
+
+func newSession(model, library string) (session, error) {
+	return nil, ErrUnavailable
+}
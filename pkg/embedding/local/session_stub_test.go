@@ -0,0 +1,28 @@
+//go:build !onnx || !cgo
+
+package local
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewClient_Unavailable(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"model.onnx": "",
+		"vocab.txt":  "[PAD]\n[UNK]\n[CLS]\n[SEP]\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := NewClient(Config{ModelDir: dir}); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("error = %v, want ErrUnavailable", err)
+	}
+	if _, err := NewClient(Config{ModelDir: t.TempDir()}); err == nil || errors.Is(err, ErrUnavailable) {
+		t.Errorf("error = %v, want a missing model error", err)
+	}
+}
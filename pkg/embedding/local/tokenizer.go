@@ -0,0 +1,151 @@
+package local
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxWordChars is the longest word WordPiece splits; longer words become
+// the unknown token, as in BERT.
+const maxWordChars = 100
+
+// tokenizer is BERT's WordPiece tokenizer, which sentence-transformer
+// models such as all-MiniLM-L6-v2 use.
+type tokenizer struct {
+	vocab     map[string]int64
+	lowercase bool
+
+	cls, sep, unk int64
+}
+
+// loadTokenizer reads a vocab.txt file, one token per line, the line
+// number being its ID.
+func loadTokenizer(path string, lowercase bool) (*tokenizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck
+
+	vocab := make(map[string]int64)
+	s := bufio.NewScanner(f)
+	for id := int64(0); s.Scan(); id++ {
+		vocab[strings.TrimRight(s.Text(), "\r")] = id
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return newTokenizer(vocab, lowercase)
+}
+
+func newTokenizer(vocab map[string]int64, lowercase bool) (*tokenizer, error) {
+	t := &tokenizer{vocab: vocab, lowercase: lowercase}
+	for _, special := range []struct {
+		token string
+		id    *int64
+	}{{"[CLS]", &t.cls}, {"[SEP]", &t.sep}, {"[UNK]", &t.unk}} {
+		id, ok := vocab[special.token]
+		if !ok {
+			return nil, fmt.Errorf("vocabulary has no %s token", special.token)
+		}
+		*special.id = id
+	}
+	return t, nil
+}
+
+// encode returns the token IDs of text between [CLS] and [SEP], cut to
+// maxLen tokens in all.
+func (t *tokenizer) encode(text string, maxLen int) []int64 {
+	ids := []int64{t.cls}
+	for _, word := range t.words(text) {
+		ids = t.wordPiece(word, ids)
+		if len(ids) >= maxLen-1 {
+			ids = ids[:maxLen-1]
+			break
+		}
+	}
+	return append(ids, t.sep)
+}
+
+// words splits text as BERT's basic tokenizer does: on whitespace, around
+// punctuation and CJK characters, after dropping control characters and,
+// when lowercasing, accents.
+func (t *tokenizer) words(text string) []string {
+	if t.lowercase {
+		text = norm.NFD.String(strings.ToLower(text))
+	}
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar:
+		case unicode.IsSpace(r):
+			b.WriteByte(' ')
+		case unicode.IsControl(r):
+		case t.lowercase && unicode.Is(unicode.Mn, r):
+		case isPunct(r) || isCJK(r):
+			b.WriteByte(' ')
+			b.WriteRune(r)
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Fields(b.String())
+}
+
+// wordPiece appends the IDs of word's longest-match-first subwords to ids,
+// or the unknown token if word cannot be split.
+func (t *tokenizer) wordPiece(word string, ids []int64) []int64 {
+	runes := []rune(word)
+	if len(runes) > maxWordChars {
+		return append(ids, t.unk)
+	}
+	n := len(ids)
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		found := false
+		for ; end > start; end-- {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if id, ok := t.vocab[piece]; ok {
+				ids = append(ids, id)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return append(ids[:n], t.unk)
+		}
+		start = end
+	}
+	return ids
+}
+
+// isPunct reports whether r is punctuation to BERT: any ASCII symbol, or a
+// Unicode punctuation character.
+func isPunct(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+// isCJK reports whether r is in the CJK Unified Ideographs blocks, which
+// BERT treats as words of their own.
+func isCJK(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) ||
+		(r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) ||
+		(r >= 0x2A700 && r <= 0x2B73F) ||
+		(r >= 0x2B740 && r <= 0x2B81F) ||
+		(r >= 0x2B820 && r <= 0x2CEAF) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0x2F800 && r <= 0x2FA1F)
+}
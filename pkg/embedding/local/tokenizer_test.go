@@ -0,0 +1,51 @@
+package local
+
+import (
+	"slices"
+	"testing"
+)
+
+func testTokenizer(t *testing.T) *tokenizer {
+	t.Helper()
+	vocab := map[string]int64{}
+	for i, tok := range []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "the", "cafe", "un", "##aff", "##able", ",", "!", "中", "go"} {
+		vocab[tok] = int64(i)
+	}
+	tok, err := newTokenizer(vocab, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestTokenizer_Encode(t *testing.T) {
+	tok := testTokenizer(t)
+	tests := []struct {
+		text string
+		want []int64
+	}{
+		{"The café, unaffable!", []int64{2, 4, 5, 9, 6, 7, 8, 10, 3}},
+		{"go中go", []int64{2, 12, 11, 12, 3}},
+		{"unknownword\x00 go", []int64{2, 1, 12, 3}},
+		{"", []int64{2, 3}},
+	}
+	for _, tt := range tests {
+		if got := tok.encode(tt.text, 64); !slices.Equal(got, tt.want) {
+			t.Errorf("encode(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizer_Truncate(t *testing.T) {
+	tok := testTokenizer(t)
+	got := tok.encode("the the the the the", 4)
+	if want := []int64{2, 4, 4, 3}; !slices.Equal(got, want) {
+		t.Errorf("encode = %v, want %v", got, want)
+	}
+}
+
+func TestNewTokenizer_MissingSpecial(t *testing.T) {
+	if _, err := newTokenizer(map[string]int64{"[CLS]": 0}, true); err == nil {
+		t.Error("vocabulary without [SEP] and [UNK] accepted")
+	}
+}
@@ -0,0 +1,188 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Normalization is the text contract applied before a provider embeds a
+// text. Queries and stored passages must be embedded under the same
+// contract, or their vectors drift apart; it is part of embedding cache
+// keys for the same reason.
+type Normalization struct {
+	// StripControl removes control characters other than newlines and
+	// tabs, and replaces invalid UTF-8.
+	StripControl bool `mapstructure:"strip_control"`
+
+	// MaxChars truncates texts, prefix included, to this many characters
+	// (0 = no limit). Providers reject or silently cut longer inputs.
+	MaxChars int `mapstructure:"max_chars"`
+
+	// QueryPrefix and PassagePrefix are prepended to queries and stored
+	// texts, as E5-style models expect ("query: " and "passage: ").
+	QueryPrefix   string `mapstructure:"query_prefix"`
+	PassagePrefix string `mapstructure:"passage_prefix"`
+
+	// ModelPrefixes fills empty prefixes with those of the model's family
+	// (E5, nomic-embed, BGE), once the model name is known.
+	ModelPrefixes bool `mapstructure:"model_prefixes"`
+}
+
+// modelPrefixes are the instruction prefixes of model families trained
+// with them, matched by substring of the model name.
+var modelPrefixes = []struct {
+	family         string
+	query, passage string
+}{
+	{"e5", "query: ", "passage: "},
+	{"nomic-embed", "search_query: ", "search_document: "},
+	{"bge", "Represent this sentence for searching relevant passages: ", ""},
+}
+
+// DefaultNormalization returns the contract for a provider: control
+// characters stripped and texts cut to the provider's input limit. It adds
+// no prefixes, since vectors already stored were embedded without them.
+func DefaultNormalization(t ProviderType) Normalization {
+	n := Normalization{StripControl: true}
+	switch t {
+	case ProviderOpenAI:
+		// 8191 tokens, at about four characters each
+		n.MaxChars = 30000
+	case ProviderOllama:
+		// Ollama's default context is 2048 tokens
+		n.MaxChars = 8000
+	}
+	return n
+}
+
+// withModel resolves ModelPrefixes for model.
+func (n Normalization) withModel(model string) Normalization {
+	if !n.ModelPrefixes {
+		return n
+	}
+	model = strings.ToLower(model)
+	for _, p := range modelPrefixes {
+		if strings.Contains(model, p.family) {
+			if n.QueryPrefix == "" {
+				n.QueryPrefix = p.query
+			}
+			if n.PassagePrefix == "" {
+				n.PassagePrefix = p.passage
+			}
+			break
+		}
+	}
+	n.ModelPrefixes = false
+	return n
+}
+
+// Query returns text normalized as a query.
+func (n Normalization) Query(text string) string {
+	return n.apply(text, n.QueryPrefix)
+}
+
+// Passage returns text normalized as a stored passage.
+func (n Normalization) Passage(text string) string {
+	return n.apply(text, n.PassagePrefix)
+}
+
+func (n Normalization) apply(text, prefix string) string {
+	if n.StripControl {
+		text = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) && r != '\n' && r != '\t' {
+				return -1
+			}
+			return r
+		}, strings.ToValidUTF8(text, "�"))
+	}
+	if n.MaxChars > 0 {
+		limit := n.MaxChars - utf8.RuneCountInString(prefix)
+		if limit < 0 {
+			limit = 0
+		}
+		text = truncateRunes(text, limit)
+	}
+	return prefix + text
+}
+
+// truncateRunes cuts s to at most n runes.
+func truncateRunes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos]
+		}
+		i++
+	}
+	return s
+}
+
+// String identifies the contract, for cache keys.
+func (n Normalization) String() string {
+	return fmt.Sprintf("strip=%t max=%d query=%q passage=%q", n.StripControl, n.MaxChars, n.QueryPrefix, n.PassagePrefix)
+}
+
+// NormalizedProvider applies a Normalization before embedding. Embed and
+// EmbedBatch treat texts as passages; EmbedQueries treats them as queries.
+type NormalizedProvider struct {
+	provider Provider
+	norm     Normalization
+}
+
+// NewNormalizedProvider wraps p with the contract n, resolving
+// n.ModelPrefixes for p's model.
+func NewNormalizedProvider(p Provider, n Normalization) *NormalizedProvider {
+	return &NormalizedProvider{provider: p, norm: n.withModel(p.ModelName())}
+}
+
+// Embed embeds text as a passage.
+func (p *NormalizedProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	return p.provider.Embed(ctx, p.norm.Passage(text))
+}
+
+// EmbedBatch embeds texts as passages.
+func (p *NormalizedProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return p.provider.EmbedBatch(ctx, mapTexts(texts, p.norm.Passage))
+}
+
+// EmbedQueries embeds texts as queries.
+func (p *NormalizedProvider) EmbedQueries(ctx context.Context, texts []string) ([][]float32, error) {
+	ctx = WithInputType(ctx, InputQuery)
+	if len(texts) == 1 {
+		v, err := p.provider.Embed(ctx, p.norm.Query(texts[0]))
+		if err != nil {
+			return nil, err
+		}
+		return [][]float32{v}, nil
+	}
+	return p.provider.EmbedBatch(ctx, mapTexts(texts, p.norm.Query))
+}
+
+// Contract identifies the model and normalization, for cache keys.
+func (p *NormalizedProvider) Contract() string {
+	return p.provider.ModelName() + " " + p.norm.String()
+}
+
+// Dimension returns the embedding dimension.
+func (p *NormalizedProvider) Dimension() int {
+	return p.provider.Dimension()
+}
+
+// ModelName returns the model name.
+func (p *NormalizedProvider) ModelName() string {
+	return p.provider.ModelName()
+}
+
+func mapTexts(texts []string, f func(string) string) []string {
+	out := make([]string, len(texts))
+	for i, t := range texts {
+		out[i] = f(t)
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package embedding_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+)
+
+// recordingProvider records the texts it is asked to embed.
+type recordingProvider struct {
+	model string
+	texts []string
+}
+
+func (r *recordingProvider) Embed(_ context.Context, text string) ([]float32, error) {
+	r.texts = append(r.texts, text)
+	return []float32{1}, nil
+}
+
+func (r *recordingProvider) EmbedBatch(_ context.Context, texts []string) ([][]float32, error) {
+	r.texts = append(r.texts, texts...)
+	out := make([][]float32, len(texts))
+	for i := range texts {
+		out[i] = []float32{1}
+	}
+	return out, nil
+}
+
+func (r *recordingProvider) Dimension() int    { return 1 }
+func (r *recordingProvider) ModelName() string { return r.model }
+
+func TestNormalization_StripControl(t *testing.T) {
+	n := embedding.Normalization{StripControl: true}
+	got := n.Passage("a\x00b\x1bc\nd\te\xff")
+	if want := "abc\nd\te�"; got != want {
+		t.Errorf("Passage = %q, want %q", got, want)
+	}
+}
+
+func TestNormalization_MaxChars(t *testing.T) {
+	n := embedding.Normalization{MaxChars: 10, QueryPrefix: "query: "}
+	got := n.Query(strings.Repeat("é", 20))
+	if utf8.RuneCountInString(got) != 10 || !strings.HasPrefix(got, "query: ") || !utf8.ValidString(got) {
+		t.Errorf("Query = %q, want 10 valid runes with the prefix", got)
+	}
+	if got := n.Passage("short"); got != "short" {
+		t.Errorf("Passage = %q, want it unchanged", got)
+	}
+}
+
+func TestNormalizedProvider_ModelPrefixes(t *testing.T) {
+	tests := []struct {
+		model, query, passage string
+	}{
+		{"intfloat/e5-large-v2", "query: q", "passage: p"},
+		{"nomic-embed-text", "search_query: q", "search_document: p"},
+		{"text-embedding-3-small", "q", "p"},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		rec := &recordingProvider{model: tt.model}
+		p := embedding.NewNormalizedProvider(rec, embedding.Normalization{ModelPrefixes: true})
+		if _, err := p.EmbedQueries(ctx, []string{"q"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.EmbedBatch(ctx, []string{"p"}); err != nil {
+			t.Fatal(err)
+		}
+		if rec.texts[0] != tt.query || rec.texts[1] != tt.passage {
+			t.Errorf("%s: embedded %q, want [%q %q]", tt.model, rec.texts, tt.query, tt.passage)
+		}
+	}
+}
+
+func TestNormalizedProvider_Contract(t *testing.T) {
+	rec := &recordingProvider{model: "m"}
+	a := embedding.NewNormalizedProvider(rec, embedding.DefaultNormalization(embedding.ProviderOpenAI))
+	b := embedding.NewNormalizedProvider(rec, embedding.DefaultNormalization(embedding.ProviderOllama))
+	if a.Contract() == b.Contract() {
+		t.Errorf("contracts match across normalizations: %q", a.Contract())
+	}
+	if !strings.HasPrefix(a.Contract(), "m ") {
+		t.Errorf("Contract = %q, want the model name first", a.Contract())
+	}
+}
+
+// inputTypeProvider records the input type of each text it embeds.
+type inputTypeProvider struct{ types []embedding.InputType }
+
+func (p *inputTypeProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	vecs, err := p.EmbedBatch(ctx, []string{text})
+	return vecs[0], err
+}
+
+func (p *inputTypeProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i := range texts {
+		t := embedding.InputTypeFrom(ctx)
+		p.types = append(p.types, t)
+		out[i] = []float32{float32(len(t))}
+	}
+	return out, nil
+}
+
+func (p *inputTypeProvider) Dimension() int    { return 1 }
+func (p *inputTypeProvider) ModelName() string { return "asymmetric" }
+
+func TestNormalizedProvider_QueryInputType(t *testing.T) {
+	inner := &inputTypeProvider{}
+	p := embedding.NewNormalizedProvider(embedding.NewCachedProvider(inner, 10), embedding.Normalization{})
+	ctx := context.Background()
+
+	query, err := p.EmbedQueries(ctx, []string{"refunds"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := p.Embed(ctx, "refunds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The cache must not serve the query's vector for the document
+	want := []embedding.InputType{embedding.InputQuery, embedding.InputDocument}
+	if len(inner.types) != 2 || inner.types[0] != want[0] || inner.types[1] != want[1] {
+		t.Errorf("embedded as %v, want %v", inner.types, want)
+	}
+	if query[0][0] == doc[0] {
+		t.Error("query and document got the same vector")
+	}
+}
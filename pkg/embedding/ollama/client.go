@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/Siddhant-K-code/distill/pkg/embedding"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
 )
 
 const (
@@ -89,7 +90,7 @@ func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("ollama %d: %s", resp.StatusCode, string(b))
+		return nil, &errs.StatusError{StatusCode: resp.StatusCode, Err: fmt.Errorf("ollama %d: %s", resp.StatusCode, string(b))}
 	}
 
 	var result embedResponse
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/Siddhant-K-code/distill/pkg/embedding"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
 )
 
 const (
@@ -41,6 +42,10 @@ type Config struct {
 
 	// MaxRetries for transient failures
 	MaxRetries int
+
+	// HTTPClient overrides the HTTP client, e.g. to record or replay
+	// requests in tests. Timeout is ignored when it is set.
+	HTTPClient *http.Client
 }
 
 // Client implements the embedding.Provider interface for OpenAI.
@@ -76,12 +81,17 @@ func NewClient(cfg Config) (*Client, error) {
 		dimension = 1536
 	}
 
-	return &Client{
-		cfg: cfg,
-		httpClient: &http.Client{
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{
 			Timeout: cfg.Timeout,
-		},
-		dimension: dimension,
+		}
+	}
+
+	return &Client{
+		cfg:        cfg,
+		httpClient: httpClient,
+		dimension:  dimension,
 	}, nil
 }
 
@@ -246,9 +256,9 @@ func (c *Client) doRequest(ctx context.Context, body []byte) (*embeddingResponse
 					return nil, embedding.ErrContextTooLong
 				}
 			}
-			return nil, fmt.Errorf("API error: %s", errResp.Error.Message)
+			return nil, &errs.StatusError{StatusCode: resp.StatusCode, Err: fmt.Errorf("API error: %s", errResp.Error.Message)}
 		}
-		return nil, fmt.Errorf("API error: status %d", resp.StatusCode)
+		return nil, &errs.StatusError{StatusCode: resp.StatusCode, Err: fmt.Errorf("API error: status %d", resp.StatusCode)}
 	}
 
 	// Parse response
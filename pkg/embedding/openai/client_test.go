@@ -0,0 +1,82 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+	"github.com/Siddhant-K-code/distill/pkg/vcr"
+)
+
+// These tests replay testdata/*.json. Refresh them against the live API
+// with `make fixtures` (needs OPENAI_API_KEY). Assertions check shape, not
+// values, so re-recorded fixtures keep passing.
+
+// apiKey returns the real key when recording and a placeholder otherwise.
+func apiKey(t *testing.T) string {
+	t.Helper()
+	if !vcr.Recording() {
+		return "sk-test"
+	}
+	key := os.Getenv("OPENAI_API_KEY")
+	if key == "" {
+		t.Skip("OPENAI_API_KEY is required to record fixtures")
+	}
+	return key
+}
+
+func TestReplay_EmbedBatch(t *testing.T) {
+	key := apiKey(t)
+	rec := vcr.Start(t, "testdata/embed_batch.json", key)
+
+	client, err := NewClient(Config{APIKey: key, HTTPClient: rec.Client(), MaxRetries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := []string{
+		"Invoices are generated on the first day of each month.",
+		"",
+		"Refunds are issued to the original payment method.",
+	}
+	got, err := client.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != len(texts) {
+		t.Fatalf("expected %d embeddings, got %d", len(texts), len(got))
+	}
+	if len(got[0]) == 0 || len(got[0]) != len(got[2]) {
+		t.Errorf("expected equal-length embeddings, got %d and %d", len(got[0]), len(got[2]))
+	}
+	if got[0][0] == got[2][0] && got[0][1] == got[2][1] {
+		t.Error("expected distinct embeddings for distinct texts")
+	}
+	for _, v := range got[1] {
+		if v != 0 {
+			t.Fatal("expected a zero vector for empty input")
+		}
+	}
+}
+
+func TestReplay_InvalidKey(t *testing.T) {
+	const key = "sk-invalid-key"
+	rec := vcr.Start(t, "testdata/invalid_key.json", key)
+
+	client, err := NewClient(Config{APIKey: key, HTTPClient: rec.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Embed(context.Background(), "hello")
+	if !errors.Is(err, embedding.ErrInvalidAPIKey) {
+		t.Errorf("expected ErrInvalidAPIKey, got %v", err)
+	}
+}
+
+func TestNewClient_RequiresKey(t *testing.T) {
+	if _, err := NewClient(Config{}); err == nil {
+		t.Error("expected error without API key")
+	}
+}
@@ -3,6 +3,9 @@ package embedding
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/cache"
 )
 
 // ProviderType identifies a supported embedding backend.
@@ -12,6 +15,11 @@ const (
 	ProviderOpenAI ProviderType = "openai"
 	ProviderOllama ProviderType = "ollama"
 	ProviderCohere ProviderType = "cohere"
+	ProviderVoyage ProviderType = "voyage"
+
+	// ProviderLocal runs a sentence-transformer model in process; Model
+	// is the model directory.
+	ProviderLocal ProviderType = "local"
 )
 
 // ProviderConfig holds the configuration needed to construct any supported
@@ -20,8 +28,9 @@ type ProviderConfig struct {
 	// Type selects the backend. Required.
 	Type ProviderType `yaml:"type" json:"type"`
 
-	// APIKey for cloud providers (OpenAI, Cohere). Can also be set via
-	// OPENAI_API_KEY / COHERE_API_KEY environment variables.
+	// APIKey for cloud providers (OpenAI, Cohere, Voyage). Can also be set
+	// via OPENAI_API_KEY / COHERE_API_KEY / VOYAGE_API_KEY environment
+	// variables.
 	APIKey string `yaml:"api_key,omitempty" json:"api_key,omitempty"`
 
 	// Model overrides the default model for the chosen provider.
@@ -31,9 +40,15 @@ type ProviderConfig struct {
 	// Ollama instances on non-default ports).
 	BaseURL string `yaml:"base_url,omitempty" json:"base_url,omitempty"`
 
-	// CacheSize is the number of embeddings to cache in memory.
-	// 0 disables the in-memory cache. Default: 10000.
-	CacheSize int `yaml:"cache_size,omitempty" json:"cache_size,omitempty"`
+	// CacheSize is the number of embeddings to cache in memory, each for
+	// CacheTTL (0 = until evicted). Negative disables the cache; 0 uses
+	// the default, 10000.
+	CacheSize int           `yaml:"cache_size,omitempty" json:"cache_size,omitempty"`
+	CacheTTL  time.Duration `yaml:"cache_ttl,omitempty" json:"cache_ttl,omitempty"`
+
+	// Normalization is the text contract applied before embedding. Nil
+	// uses DefaultNormalization for Type.
+	Normalization *Normalization `yaml:"normalization,omitempty" json:"normalization,omitempty"`
 }
 
 // ProviderFactory is a function that constructs a Provider from a ProviderConfig.
@@ -51,11 +66,13 @@ func RegisterFactory(t ProviderType, f ProviderFactory) {
 }
 
 // NewProvider constructs a Provider from cfg. Built-in providers (openai,
-// ollama, cohere) are always available. Custom providers must be registered
+// ollama, cohere, voyage, local) are always available. Custom providers must be registered
 // via RegisterFactory before calling NewProvider.
 //
 // When cfg.CacheSize > 0 (or unset, defaulting to 10000), the returned
-// provider is wrapped in a CachedProvider.
+// provider is wrapped in a CachedProvider. The result is a
+// NormalizedProvider applying cfg.Normalization, so the cache holds
+// normalized texts.
 func NewProvider(cfg ProviderConfig) (Provider, error) {
 	if cfg.Type == "" {
 		return nil, fmt.Errorf("embedding provider type is required")
@@ -67,7 +84,7 @@ func NewProvider(cfg ProviderConfig) (Provider, error) {
 		if err != nil {
 			return nil, err
 		}
-		return maybeCache(p, cfg.CacheSize), nil
+		return normalize(maybeCache(p, cfg), cfg), nil
 	}
 
 	var p Provider
@@ -80,13 +97,17 @@ func NewProvider(cfg ProviderConfig) (Provider, error) {
 		p, err = newOllama(cfg)
 	case string(ProviderCohere):
 		p, err = newCohere(cfg)
+	case string(ProviderVoyage):
+		p, err = newVoyage(cfg)
+	case string(ProviderLocal):
+		p, err = newLocal(cfg)
 	default:
-		return nil, fmt.Errorf("unknown embedding provider %q; supported: openai, ollama, cohere", cfg.Type)
+		return nil, fmt.Errorf("unknown embedding provider %q; supported: openai, ollama, cohere, voyage, local", cfg.Type)
 	}
 	if err != nil {
 		return nil, err
 	}
-	return maybeCache(p, cfg.CacheSize), nil
+	return normalize(maybeCache(p, cfg), cfg), nil
 }
 
 // SupportedProviders returns the list of built-in provider type strings.
@@ -95,17 +116,28 @@ func SupportedProviders() []string {
 		string(ProviderOpenAI),
 		string(ProviderOllama),
 		string(ProviderCohere),
+		string(ProviderVoyage),
+		string(ProviderLocal),
 	}
 }
 
-func maybeCache(p Provider, cacheSize int) Provider {
-	if cacheSize < 0 {
+func normalize(p Provider, cfg ProviderConfig) Provider {
+	n := DefaultNormalization(ProviderType(strings.ToLower(string(cfg.Type))))
+	if cfg.Normalization != nil {
+		n = *cfg.Normalization
+	}
+	return NewNormalizedProvider(p, n)
+}
+
+func maybeCache(p Provider, cfg ProviderConfig) Provider {
+	if cfg.CacheSize < 0 {
 		return p // explicitly disabled
 	}
-	if cacheSize == 0 {
-		cacheSize = 10000
+	size := cfg.CacheSize
+	if size == 0 {
+		size = 10000
 	}
-	return NewCachedProvider(p, cacheSize)
+	return NewCachedProviderWithCache(p, cache.NewMemoryCache(cache.Config{MaxSize: int64(size)}), cfg.CacheTTL)
 }
 
 // newOpenAI constructs an OpenAI provider. Imported lazily to avoid a hard
@@ -132,3 +164,17 @@ func newCohere(cfg ProviderConfig) (Provider, error) {
 	}
 	return nil, fmt.Errorf("cohere provider not registered; import _ \"github.com/Siddhant-K-code/distill/pkg/embedding/cohere\"")
 }
+
+func newVoyage(cfg ProviderConfig) (Provider, error) {
+	if f, ok := factories[ProviderVoyage]; ok {
+		return f(cfg)
+	}
+	return nil, fmt.Errorf("voyage provider not registered; import _ \"github.com/Siddhant-K-code/distill/pkg/embedding/voyage\"")
+}
+
+func newLocal(cfg ProviderConfig) (Provider, error) {
+	if f, ok := factories[ProviderLocal]; ok {
+		return f(cfg)
+	}
+	return nil, fmt.Errorf("local provider not registered; import _ \"github.com/Siddhant-K-code/distill/pkg/embedding/local\"")
+}
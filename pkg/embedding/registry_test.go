@@ -72,10 +72,10 @@ func TestNewProvider_OllamaRegistered(t *testing.T) {
 
 func TestSupportedProviders(t *testing.T) {
 	providers := embedding.SupportedProviders()
-	if len(providers) != 3 {
-		t.Errorf("expected 3 supported providers, got %d", len(providers))
+	if len(providers) != 5 {
+		t.Errorf("expected 5 supported providers, got %d", len(providers))
 	}
-	want := map[string]bool{"openai": true, "ollama": true, "cohere": true}
+	want := map[string]bool{"openai": true, "ollama": true, "cohere": true, "voyage": true, "local": true}
 	for _, p := range providers {
 		if !want[p] {
 			t.Errorf("unexpected provider %q", p)
@@ -0,0 +1,209 @@
+// Package voyage provides an embedding.Provider backed by the Voyage AI API.
+package voyage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+const (
+	defaultBaseURL   = "https://api.voyageai.com/v1"
+	defaultModel     = "voyage-3.5"
+	defaultTimeout   = 30 * time.Second
+	defaultBatchSize = 128
+)
+
+// Input types Voyage optimizes embeddings for.
+const (
+	InputTypeQuery    = "query"
+	InputTypeDocument = "document"
+)
+
+// Model dimensions for common Voyage embedding models, at their default
+// output dimension.
+var modelDimensions = map[string]int{
+	"voyage-3.5":            1024,
+	"voyage-3.5-lite":       1024,
+	"voyage-3-large":        1024,
+	"voyage-3":              1024,
+	"voyage-3-lite":         512,
+	"voyage-code-3":         1024,
+	"voyage-finance-2":      1024,
+	"voyage-law-2":          1024,
+	"voyage-multilingual-2": 1024,
+	"voyage-code-2":         1536,
+}
+
+// Config holds Voyage client configuration.
+type Config struct {
+	// APIKey is the Voyage API key (required).
+	APIKey string
+
+	// Model is the embedding model. Default: voyage-3.5
+	Model string
+
+	// BaseURL overrides the API endpoint. Default: https://api.voyageai.com/v1
+	BaseURL string
+
+	// InputType fixes the input type of every call. Default: empty, which
+	// sends query for texts embedded as queries (see
+	// embedding.WithInputType) and document otherwise.
+	InputType string
+
+	// BatchSize caps the texts per call; larger batches are split.
+	// Default: 128
+	BatchSize int
+
+	// Timeout for API requests. Default: 30s
+	Timeout time.Duration
+}
+
+// Client implements embedding.Provider for Voyage.
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+	dimension  int
+}
+
+// NewClient creates a new Voyage embedding client.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("voyage API key is required")
+	}
+	if cfg.Model == "" {
+		cfg.Model = defaultModel
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Client{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		dimension:  modelDimensions[cfg.Model],
+	}, nil
+}
+
+type embedRequest struct {
+	Input     []string `json:"input"`
+	Model     string   `json:"model"`
+	InputType string   `json:"input_type"`
+}
+
+type embedResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+}
+
+// Embed returns the embedding for a single text.
+func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, embedding.ErrEmptyInput
+	}
+	results, err := c.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// EmbedBatch embeds texts, BatchSize per API call.
+func (c *Client) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	inputType := c.cfg.InputType
+	if inputType == "" {
+		inputType = InputTypeDocument
+		if embedding.InputTypeFrom(ctx) == embedding.InputQuery {
+			inputType = InputTypeQuery
+		}
+	}
+
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += c.cfg.BatchSize {
+		vecs, err := c.embedBatch(ctx, texts[start:min(start+c.cfg.BatchSize, len(texts))], inputType)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vecs...)
+	}
+	return out, nil
+}
+
+func (c *Client) embedBatch(ctx context.Context, texts []string, inputType string) ([][]float32, error) {
+	body, err := json.Marshal(embedRequest{
+		Input:     texts,
+		Model:     c.cfg.Model,
+		InputType: inputType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.cfg.BaseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("voyage request: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, embedding.ErrRateLimited
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, embedding.ErrInvalidAPIKey
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &errs.StatusError{StatusCode: resp.StatusCode, Err: fmt.Errorf("voyage %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))}
+	}
+
+	var result embedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+	// Data is ordered by index, but place each vector by it to be safe
+	vecs := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) || vecs[d.Index] != nil {
+			return nil, fmt.Errorf("unexpected embedding index %d", d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	return vecs, nil
+}
+
+// Dimension returns the embedding dimension for the configured model, or
+// 0 for models not listed.
+func (c *Client) Dimension() int { return c.dimension }
+
+// ModelName returns the configured model name.
+func (c *Client) ModelName() string { return c.cfg.Model }
@@ -0,0 +1,114 @@
+package voyage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/embedding"
+)
+
+// fakeServer answers /embeddings with one vector per text, [text length],
+// listed in reverse index order, and records each request.
+func fakeServer(t *testing.T, requests *[]embedRequest) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req embedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		*requests = append(*requests, req)
+		type datum struct {
+			Embedding []float32 `json:"embedding"`
+			Index     int       `json:"index"`
+		}
+		var data []datum
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, datum{Embedding: []float32{float32(len(req.Input[i]))}, Index: i})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data, "model": req.Model})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEmbedBatch_Batches(t *testing.T) {
+	var requests []embedRequest
+	srv := fakeServer(t, &requests)
+	client, err := NewClient(Config{APIKey: "key", BaseURL: srv.URL, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := client.EmbedBatch(context.Background(), []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	if len(requests) != 2 || len(requests[0].Input) != 2 || len(requests[1].Input) != 1 {
+		t.Fatalf("requests = %+v, want batches of 2 and 1", requests)
+	}
+	for i, v := range got {
+		if v[0] != float32(i+1) {
+			t.Errorf("vector %d = %v, want [%d]", i, v, i+1)
+		}
+	}
+	if requests[0].Model != defaultModel || requests[0].InputType != InputTypeDocument {
+		t.Errorf("model = %q, input_type = %q, want %q, %q", requests[0].Model, requests[0].InputType, defaultModel, InputTypeDocument)
+	}
+	if client.Dimension() != 1024 {
+		t.Errorf("Dimension = %d, want 1024", client.Dimension())
+	}
+}
+
+func TestEmbed_QueryInputType(t *testing.T) {
+	var requests []embedRequest
+	srv := fakeServer(t, &requests)
+	client, err := NewClient(Config{APIKey: "key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := embedding.WithInputType(context.Background(), embedding.InputQuery)
+	if _, err := client.Embed(ctx, "refunds"); err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if got := requests[0].InputType; got != InputTypeQuery {
+		t.Errorf("input_type = %q, want %q", got, InputTypeQuery)
+	}
+}
+
+func TestEmbed_Errors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, embedding.ErrInvalidAPIKey},
+		{http.StatusTooManyRequests, embedding.ErrRateLimited},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		client, err := NewClient(Config{APIKey: "key", BaseURL: srv.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Embed(context.Background(), "x"); !errors.Is(err, tt.want) {
+			t.Errorf("status %d: error = %v, want %v", tt.status, err, tt.want)
+		}
+		srv.Close()
+	}
+}
+
+func TestNewClient_RequiresKey(t *testing.T) {
+	if _, err := NewClient(Config{}); err == nil {
+		t.Error("expected an error without an API key")
+	}
+}
@@ -0,0 +1,251 @@
+// Package enrich runs enrichment hooks on retrieved chunks before they are
+// clustered. A hook sees each chunk's ID, text, score, and metadata, and
+// can add metadata (ACL decisions, freshness, document titles) or drop
+// the chunk. Hooks are HTTP callbacks, Go plugins, or any Enricher set
+// from Go code.
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// ErrFailed is returned by Apply when a batch fails under FailClosed.
+var ErrFailed = errs.New(errs.ErrBackend, "enrichment failed")
+
+// Chunk is what an enricher sees of a retrieved chunk. Embeddings are
+// left out.
+type Chunk struct {
+	ID       string                 `json:"id"`
+	Text     string                 `json:"text"`
+	Score    float32                `json:"score"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Request is one batch of chunks for an enricher, with the retrieval
+// they came from.
+type Request struct {
+	Query     string  `json:"query,omitempty"`
+	Namespace string  `json:"namespace,omitempty"`
+	SessionID string  `json:"session_id,omitempty"`
+	Chunks    []Chunk `json:"chunks"`
+}
+
+// Result is an enricher's answer for one chunk. Metadata keys are merged
+// into the chunk's metadata, replacing existing keys; Drop removes the
+// chunk before clustering. Chunks without a result are left unchanged.
+type Result struct {
+	ID       string                 `json:"id"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Drop     bool                   `json:"drop,omitempty"`
+}
+
+// Enricher enriches one batch of chunks.
+type Enricher interface {
+	Enrich(ctx context.Context, req Request) ([]Result, error)
+}
+
+// Func adapts a function to Enricher.
+type Func func(ctx context.Context, req Request) ([]Result, error)
+
+// Enrich calls f.
+func (f Func) Enrich(ctx context.Context, req Request) ([]Result, error) {
+	return f(ctx, req)
+}
+
+// Policy says what happens to a batch whose enricher call fails or times
+// out.
+type Policy string
+
+const (
+	// FailOpen keeps the batch's chunks unenriched.
+	FailOpen Policy = "open"
+	// FailClosed fails the whole retrieval with ErrFailed.
+	FailClosed Policy = "closed"
+	// FailDrop drops the batch's chunks, e.g. when the hook is an ACL
+	// check and unchecked chunks must not be returned.
+	FailDrop Policy = "drop"
+)
+
+// Config configures a Hook.
+type Config struct {
+	// Timeout bounds each enricher call.
+	Timeout time.Duration
+
+	// BatchSize is the most chunks sent in one call.
+	BatchSize int
+
+	// Concurrency is the most calls in flight for one retrieval.
+	Concurrency int
+
+	// Policy handles failed calls.
+	Policy Policy
+}
+
+// DefaultConfig returns a fail-open config with a 500ms timeout.
+func DefaultConfig() Config {
+	return Config{
+		Timeout:     500 * time.Millisecond,
+		BatchSize:   100,
+		Concurrency: 4,
+		Policy:      FailOpen,
+	}
+}
+
+// Validate reports invalid settings.
+func (c Config) Validate() error {
+	var problem string
+	switch {
+	case c.Timeout <= 0:
+		problem = fmt.Sprintf("timeout must be positive, got %s", c.Timeout)
+	case c.BatchSize <= 0:
+		problem = fmt.Sprintf("batch size must be positive, got %d", c.BatchSize)
+	case c.Concurrency <= 0:
+		problem = fmt.Sprintf("concurrency must be positive, got %d", c.Concurrency)
+	}
+	if problem == "" {
+		switch c.Policy {
+		case FailOpen, FailClosed, FailDrop:
+		default:
+			problem = fmt.Sprintf("unknown failure policy %q (use %q, %q, or %q)", c.Policy, FailOpen, FailClosed, FailDrop)
+		}
+	}
+	if problem != "" {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid enrichment config: %s", problem))
+	}
+	return nil
+}
+
+// Stats describes one Apply call.
+type Stats struct {
+	// Enriched is the number of chunks that received metadata.
+	Enriched int
+
+	// Dropped is the number of chunks removed, by the enricher or by
+	// FailDrop.
+	Dropped int
+
+	// Failed is the number of batches whose call failed.
+	Failed int
+
+	// Latency is the time spent enriching.
+	Latency time.Duration
+}
+
+// Hook applies an Enricher in batches with a timeout and failure policy.
+type Hook struct {
+	enricher Enricher
+	cfg      Config
+}
+
+// New returns a hook running e under cfg.
+func New(e Enricher, cfg Config) (*Hook, error) {
+	if e == nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid enrichment config: enricher is required"))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Hook{enricher: e, cfg: cfg}, nil
+}
+
+// batchResult is the outcome of one enricher call.
+type batchResult struct {
+	results []Result
+	err     error
+}
+
+// Apply enriches chunks and returns them in order, without the dropped
+// ones. Metadata maps are copied before they are changed, so chunks shared
+// with a cache or retriever are not modified. A nil Hook returns chunks
+// unchanged.
+func (h *Hook) Apply(ctx context.Context, query, namespace, sessionID string, chunks []types.Chunk) ([]types.Chunk, Stats, error) {
+	var stats Stats
+	if h == nil || len(chunks) == 0 {
+		return chunks, stats, nil
+	}
+	start := time.Now()
+
+	batches := (len(chunks) + h.cfg.BatchSize - 1) / h.cfg.BatchSize
+	outcomes := make([]batchResult, batches)
+	sem := make(chan struct{}, h.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < batches; i++ {
+		lo := i * h.cfg.BatchSize
+		hi := min(lo+h.cfg.BatchSize, len(chunks))
+		req := Request{Query: query, Namespace: namespace, SessionID: sessionID, Chunks: make([]Chunk, hi-lo)}
+		for j, c := range chunks[lo:hi] {
+			req.Chunks[j] = Chunk{ID: c.ID, Text: c.Text, Score: c.Score, Metadata: c.Metadata}
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, req Request) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			callCtx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+			defer cancel()
+			results, err := h.enricher.Enrich(callCtx, req)
+			outcomes[i] = batchResult{results: results, err: err}
+		}(i, req)
+	}
+	wg.Wait()
+	stats.Latency = time.Since(start)
+
+	// The caller giving up is not a hook failure
+	if err := ctx.Err(); err != nil {
+		return nil, stats, err
+	}
+
+	out := make([]types.Chunk, 0, len(chunks))
+	for i, outcome := range outcomes {
+		lo := i * h.cfg.BatchSize
+		batch := chunks[lo:min(lo+h.cfg.BatchSize, len(chunks))]
+
+		if outcome.err != nil {
+			stats.Failed++
+			switch h.cfg.Policy {
+			case FailClosed:
+				return nil, stats, fmt.Errorf("%w: %v", ErrFailed, outcome.err)
+			case FailDrop:
+				stats.Dropped += len(batch)
+			default:
+				out = append(out, batch...)
+			}
+			continue
+		}
+
+		byID := make(map[string]Result, len(outcome.results))
+		for _, r := range outcome.results {
+			byID[r.ID] = r
+		}
+		for _, c := range batch {
+			r, ok := byID[c.ID]
+			switch {
+			case !ok:
+				out = append(out, c)
+			case r.Drop:
+				stats.Dropped++
+			case len(r.Metadata) == 0:
+				out = append(out, c)
+			default:
+				metadata := make(map[string]interface{}, len(c.Metadata)+len(r.Metadata))
+				for k, v := range c.Metadata {
+					metadata[k] = v
+				}
+				for k, v := range r.Metadata {
+					metadata[k] = v
+				}
+				c.Metadata = metadata
+				out = append(out, c)
+				stats.Enriched++
+			}
+		}
+	}
+	return out, stats, nil
+}
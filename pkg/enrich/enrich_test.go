@@ -0,0 +1,233 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func testChunks(n int) []types.Chunk {
+	chunks := make([]types.Chunk, n)
+	for i := range chunks {
+		chunks[i] = types.Chunk{
+			ID:       string(rune('a' + i)),
+			Text:     "chunk " + string(rune('a'+i)),
+			Metadata: map[string]interface{}{"source": "docs"},
+		}
+	}
+	return chunks
+}
+
+func newTestHook(t *testing.T, e Enricher, cfg Config) *Hook {
+	t.Helper()
+	h, err := New(e, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestHook_MergeAndDrop(t *testing.T) {
+	h := newTestHook(t, Func(func(ctx context.Context, req Request) ([]Result, error) {
+		if req.Query != "q" || req.Namespace != "ns" || req.SessionID != "s1" {
+			t.Errorf("unexpected request context: %+v", req)
+		}
+		return []Result{
+			{ID: "a", Metadata: map[string]interface{}{"title": "Alpha", "source": "wiki"}},
+			{ID: "b", Drop: true},
+		}, nil
+	}), DefaultConfig())
+
+	chunks := testChunks(3)
+	out, stats, err := h.Apply(context.Background(), "q", "ns", "s1", chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out[0].ID != "a" || out[1].ID != "c" {
+		t.Fatalf("unexpected chunks: %+v", out)
+	}
+	if out[0].Metadata["title"] != "Alpha" || out[0].Metadata["source"] != "wiki" {
+		t.Errorf("metadata not merged: %v", out[0].Metadata)
+	}
+	if chunks[0].Metadata["source"] != "docs" || chunks[0].Metadata["title"] != nil {
+		t.Errorf("input metadata was modified: %v", chunks[0].Metadata)
+	}
+	if stats.Enriched != 1 || stats.Dropped != 1 || stats.Failed != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestHook_Batching(t *testing.T) {
+	var calls, inFlight, peak atomic.Int32
+	h := newTestHook(t, Func(func(ctx context.Context, req Request) ([]Result, error) {
+		calls.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		if len(req.Chunks) > 3 {
+			t.Errorf("batch of %d exceeds batch size", len(req.Chunks))
+		}
+		time.Sleep(10 * time.Millisecond)
+		out := make([]Result, len(req.Chunks))
+		for i, c := range req.Chunks {
+			out[i] = Result{ID: c.ID, Metadata: map[string]interface{}{"seen": true}}
+		}
+		return out, nil
+	}), Config{Timeout: time.Second, BatchSize: 3, Concurrency: 2, Policy: FailOpen})
+
+	out, stats, err := h.Apply(context.Background(), "", "", "", testChunks(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 4 || peak.Load() > 2 {
+		t.Errorf("got %d calls with %d in flight, want 4 calls with at most 2", calls.Load(), peak.Load())
+	}
+	if len(out) != 10 || stats.Enriched != 10 {
+		t.Errorf("got %d chunks, %d enriched", len(out), stats.Enriched)
+	}
+	for i, c := range out {
+		if c.ID != string(rune('a'+i)) {
+			t.Fatalf("order not preserved: %v at %d", c.ID, i)
+		}
+	}
+}
+
+func TestHook_FailurePolicies(t *testing.T) {
+	slow := Func(func(ctx context.Context, req Request) ([]Result, error) {
+		if req.Chunks[0].ID == "a" {
+			return []Result{{ID: "a", Metadata: map[string]interface{}{"ok": true}}}, nil
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	cfg := Config{Timeout: 20 * time.Millisecond, BatchSize: 1, Concurrency: 4}
+
+	tests := []struct {
+		policy  Policy
+		wantErr bool
+		wantLen int
+	}{
+		{FailOpen, false, 3},
+		{FailDrop, false, 1},
+		{FailClosed, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			cfg.Policy = tt.policy
+			out, stats, err := newTestHook(t, slow, cfg).Apply(context.Background(), "", "", "", testChunks(3))
+			if tt.wantErr {
+				if !errors.Is(err, ErrFailed) {
+					t.Fatalf("expected ErrFailed, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out) != tt.wantLen || stats.Failed != 2 || stats.Enriched != 1 {
+				t.Errorf("got %d chunks, stats %+v", len(out), stats)
+			}
+		})
+	}
+}
+
+func TestHook_CallerCanceled(t *testing.T) {
+	h := newTestHook(t, Func(func(ctx context.Context, req Request) ([]Result, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}), DefaultConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := h.Apply(ctx, "", "", "", testChunks(2)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestHook_Nil(t *testing.T) {
+	var h *Hook
+	chunks := testChunks(2)
+	out, _, err := h.Apply(context.Background(), "", "", "", chunks)
+	if err != nil || len(out) != 2 {
+		t.Errorf("nil hook changed chunks: %v %v", out, err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("default config: %v", err)
+	}
+	bad := []Config{
+		{Timeout: 0, BatchSize: 1, Concurrency: 1, Policy: FailOpen},
+		{Timeout: time.Second, BatchSize: 0, Concurrency: 1, Policy: FailOpen},
+		{Timeout: time.Second, BatchSize: 1, Concurrency: 0, Policy: FailOpen},
+		{Timeout: time.Second, BatchSize: 1, Concurrency: 1, Policy: "ignore"},
+	}
+	for _, cfg := range bad {
+		if err := cfg.Validate(); !errors.Is(err, errs.ErrConfig) {
+			t.Errorf("%+v: expected a config error, got %v", cfg, err)
+		}
+	}
+	if _, err := New(nil, DefaultConfig()); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected a config error for a nil enricher, got %v", err)
+	}
+}
+
+func TestHTTPEnricher(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "no token", http.StatusUnauthorized)
+			return
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var resp Response
+		for _, c := range req.Chunks {
+			resp.Chunks = append(resp.Chunks, Result{ID: c.ID, Metadata: map[string]interface{}{"len": len(c.Text)}})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	e, err := NewHTTP(HTTPConfig{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := e.Enrich(context.Background(), Request{Chunks: []Chunk{{ID: "a", Text: "hello"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != "a" || results[0].Metadata["len"] != float64(5) {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	unauth, _ := NewHTTP(HTTPConfig{URL: srv.URL})
+	if _, err := unauth.Enrich(context.Background(), Request{}); !errors.Is(err, errs.ErrAuth) {
+		t.Errorf("expected an auth error, got %v", err)
+	}
+	if _, err := NewHTTP(HTTPConfig{URL: "localhost:9000"}); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected a config error for a URL without scheme, got %v", err)
+	}
+}
+
+func TestLoadPlugin_Missing(t *testing.T) {
+	if _, err := LoadPlugin("/nonexistent/enricher.so"); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected a config error, got %v", err)
+	}
+}
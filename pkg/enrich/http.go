@@ -0,0 +1,92 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+// maxErrorBody is how much of a failed response's body goes into the
+// error message.
+const maxErrorBody = 512
+
+// HTTPConfig configures an HTTP enricher.
+type HTTPConfig struct {
+	// URL receives each batch as a POSTed Request and answers with a
+	// Response.
+	URL string
+
+	// Headers are added to every call, e.g. Authorization.
+	Headers map[string]string
+
+	// Client overrides the HTTP client. Hook.Apply bounds each call with
+	// its own timeout either way.
+	Client *http.Client
+}
+
+// Response is the JSON body an HTTP enricher returns.
+type Response struct {
+	Chunks []Result `json:"chunks"`
+}
+
+// HTTPEnricher calls an HTTP endpoint for each batch.
+type HTTPEnricher struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewHTTP returns an enricher calling cfg.URL.
+func NewHTTP(cfg HTTPConfig) (*HTTPEnricher, error) {
+	if !strings.HasPrefix(cfg.URL, "http://") && !strings.HasPrefix(cfg.URL, "https://") {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid enrichment config: url must be http:// or https://, got %q", cfg.URL))
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &HTTPEnricher{url: cfg.URL, headers: cfg.Headers, client: client}, nil
+}
+
+// Enrich POSTs req and decodes the response.
+func (e *HTTPEnricher) Enrich(ctx context.Context, req Request) ([]Result, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrBackend, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		kind := errs.ErrBackend
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			kind = errs.ErrAuth
+		}
+		return nil, errs.Wrap(kind, fmt.Errorf("enrichment hook returned %s: %s", resp.Status, strings.TrimSpace(string(msg))))
+	}
+
+	var out Response
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, errs.Wrap(errs.ErrBackend, fmt.Errorf("decode enrichment response: %w", err))
+	}
+	return out.Chunks, nil
+}
@@ -0,0 +1,41 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"plugin"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+// LoadPlugin opens a Go plugin built with -buildmode=plugin and returns
+// its enricher: either a variable `var Enricher enrich.Enricher` or a
+// function `func Enrich(context.Context, enrich.Request) ([]enrich.Result,
+// error)`. The plugin must be built with the same Go version and module
+// versions as distill, and plugins only load in cgo-enabled builds on
+// Linux, FreeBSD, and macOS.
+func LoadPlugin(path string) (Enricher, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("open enrichment plugin: %w", err))
+	}
+
+	if sym, err := p.Lookup("Enricher"); err == nil {
+		switch v := sym.(type) {
+		case *Enricher:
+			if *v != nil {
+				return *v, nil
+			}
+		case Enricher:
+			return v, nil
+		}
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("enrichment plugin %s: Enricher is %T, not an enrich.Enricher", path, sym))
+	}
+	if sym, err := p.Lookup("Enrich"); err == nil {
+		if fn, ok := sym.(func(context.Context, Request) ([]Result, error)); ok {
+			return Func(fn), nil
+		}
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("enrichment plugin %s: Enrich has type %T", path, sym))
+	}
+	return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("enrichment plugin %s exports neither Enricher nor Enrich", path))
+}
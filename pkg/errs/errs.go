@@ -0,0 +1,137 @@
+// Package errs defines the error taxonomy shared by Distill packages and
+// the exit codes the CLI maps it to. Package-level sentinel errors are
+// tagged with one of the kinds below so callers can classify failures
+// with errors.Is without knowing which package produced them.
+package errs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error kinds. Use errors.Is(err, ErrAuth) etc. to classify an error.
+var (
+	// ErrConfig covers invalid flags, configuration, and input files.
+	ErrConfig = errors.New("configuration error")
+
+	// ErrAuth covers rejected or missing credentials at a remote service.
+	ErrAuth = errors.New("authentication error")
+
+	// ErrBackend covers failures talking to a vector database or
+	// embedding provider (connection, timeout, rate limiting, 5xx).
+	ErrBackend = errors.New("backend error")
+
+	// ErrPartialFailure means the operation completed but some items
+	// (e.g. upload batches) failed.
+	ErrPartialFailure = errors.New("partial failure")
+)
+
+// Exit codes returned by the distill CLI.
+const (
+	ExitOK             = 0
+	ExitError          = 1 // unclassified failure
+	ExitConfig         = 2
+	ExitAuth           = 3
+	ExitBackend        = 4
+	ExitPartialFailure = 5
+	ExitInterrupted    = 130 // matches the shell convention for SIGINT
+)
+
+// kindError is a sentinel error tagged with a kind.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string        { return e.msg }
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+// New returns a sentinel error with the given message that also matches
+// kind under errors.Is. The message is not prefixed with the kind.
+func New(kind error, msg string) error {
+	return &kindError{kind: kind, msg: msg}
+}
+
+// wrappedError tags an arbitrary error with a kind.
+type wrappedError struct {
+	kind error
+	err  error
+}
+
+func (e *wrappedError) Error() string   { return e.err.Error() }
+func (e *wrappedError) Unwrap() []error { return []error{e.err, e.kind} }
+
+// Wrap tags err with kind, preserving its message and chain.
+// Returns nil if err is nil.
+func Wrap(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, kind) {
+		return err
+	}
+	return &wrappedError{kind: kind, err: err}
+}
+
+// ExitCode maps an error to a CLI exit code. Auth is checked before
+// backend so that an auth failure reported by a backend is classified
+// as auth.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.Is(err, ErrConfig):
+		return ExitConfig
+	case errors.Is(err, ErrAuth):
+		return ExitAuth
+	case errors.Is(err, ErrPartialFailure):
+		return ExitPartialFailure
+	case errors.Is(err, ErrBackend):
+		return ExitBackend
+	default:
+		return ExitError
+	}
+}
+
+// StatusError is an error response from a remote HTTP service. It keeps
+// the response status so ClassifyRemote can recognize rejected credentials
+// without reading the message. Error returns Err's message unchanged.
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *StatusError) Error() string { return e.Err.Error() }
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// ClassifyRemote tags an error returned by a remote service as ErrAuth if
+// it carries a credentials status, and ErrBackend otherwise. Only
+// structured statuses count: an HTTP 401 or 403 in a StatusError, or a
+// gRPC Unauthenticated or PermissionDenied code, so a message that merely
+// mentions 401 is not mistaken for one. Errors that already carry a kind
+// are returned unchanged.
+func ClassifyRemote(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, kind := range []error{ErrConfig, ErrAuth, ErrBackend, ErrPartialFailure} {
+		if errors.Is(err, kind) {
+			return err
+		}
+	}
+	var se *StatusError
+	if errors.As(err, &se) && (se.StatusCode == http.StatusUnauthorized || se.StatusCode == http.StatusForbidden) {
+		return Wrap(ErrAuth, err)
+	}
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return Wrap(ErrAuth, err)
+	}
+	return Wrap(ErrBackend, err)
+}
@@ -0,0 +1,98 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNew_MatchesKindAndKeepsMessage(t *testing.T) {
+	sentinel := New(ErrAuth, "invalid API key")
+
+	if sentinel.Error() != "invalid API key" {
+		t.Errorf("unexpected message %q", sentinel.Error())
+	}
+	if !errors.Is(sentinel, ErrAuth) {
+		t.Error("expected sentinel to match ErrAuth")
+	}
+	if errors.Is(sentinel, ErrBackend) {
+		t.Error("sentinel should not match ErrBackend")
+	}
+
+	wrapped := fmt.Errorf("embed failed: %w", sentinel)
+	if !errors.Is(wrapped, sentinel) || !errors.Is(wrapped, ErrAuth) {
+		t.Error("wrapped sentinel should match both sentinel and kind")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if Wrap(ErrConfig, nil) != nil {
+		t.Error("Wrap(nil) should be nil")
+	}
+
+	base := errors.New("index name required")
+	err := Wrap(ErrConfig, base)
+	if err.Error() != base.Error() {
+		t.Errorf("Wrap changed message: %q", err.Error())
+	}
+	if !errors.Is(err, base) || !errors.Is(err, ErrConfig) {
+		t.Error("wrapped error should match base and kind")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{errors.New("boom"), ExitError},
+		{Wrap(ErrConfig, errors.New("bad flag")), ExitConfig},
+		{fmt.Errorf("connect: %w", New(ErrAuth, "401")), ExitAuth},
+		{Wrap(ErrBackend, New(ErrAuth, "401")), ExitAuth},
+		{Wrap(ErrBackend, errors.New("timeout")), ExitBackend},
+		{Wrap(ErrPartialFailure, errors.New("3 failed")), ExitPartialFailure},
+		{fmt.Errorf("dedup: %w", context.Canceled), ExitInterrupted},
+	}
+
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyRemote(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"grpc unauthenticated", status.Error(codes.Unauthenticated, "bad key"), ErrAuth},
+		{"wrapped grpc permission denied", fmt.Errorf("query: %w", status.Error(codes.PermissionDenied, "no access")), ErrAuth},
+		{"http 401", &StatusError{StatusCode: 401, Err: errors.New("cohere 401: invalid token")}, ErrAuth},
+		{"wrapped http 403", fmt.Errorf("describe index: %w", &StatusError{StatusCode: 403, Err: errors.New("forbidden")}), ErrAuth},
+		{"http 500", &StatusError{StatusCode: 500, Err: errors.New("ollama 500: 401 workers busy")}, ErrBackend},
+		{"grpc unavailable", status.Error(codes.Unavailable, "rpc error: code = Unauthenticated in a message"), ErrBackend},
+		{"401 in a timeout", errors.New("timeout after 401ms"), ErrBackend},
+		{"403 in an ID", errors.New(`point "doc-403" not found`), ErrBackend},
+		{"connection refused", errors.New("connection refused"), ErrBackend},
+	} {
+		got := ClassifyRemote(tc.err)
+		if !errors.Is(got, tc.want) {
+			t.Errorf("%s: got %v (exit %d), want %v", tc.name, got, ExitCode(got), tc.want)
+		}
+		if got.Error() != tc.err.Error() {
+			t.Errorf("%s: message changed to %q", tc.name, got.Error())
+		}
+	}
+
+	tagged := Wrap(ErrConfig, errors.New("401 in a filename"))
+	if ClassifyRemote(tagged) != tagged {
+		t.Error("already-classified errors should pass through")
+	}
+}
@@ -0,0 +1,339 @@
+// Package garbage detects boilerplate chunks, such as cookie banners,
+// navigation menus, and page footers in crawled web pages. They are often
+// unique, so deduplication keeps them, yet they carry no content. Detection
+// is heuristic; no LLM calls are made.
+package garbage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// DefaultThreshold is the score at or above which a chunk is dropped.
+const DefaultThreshold = 0.6
+
+// DefaultMinRepeats is how many distinct chunks must share a text before
+// it counts as boilerplate repeated across the corpus.
+const DefaultMinRepeats = 5
+
+// DefaultRepeatTTL is how long a text's sightings are remembered.
+const DefaultRepeatTTL = 24 * time.Hour
+
+// Reasons a chunk scores as boilerplate.
+const (
+	// ReasonLinkDensity is text made mostly of links.
+	ReasonLinkDensity = "link_density"
+
+	// ReasonPhrases is text dense with phrases such as "accept all
+	// cookies" or "all rights reserved".
+	ReasonPhrases = "boilerplate_phrases"
+
+	// ReasonNavigation is text of short menu items, one per line or
+	// between separators.
+	ReasonNavigation = "navigation"
+
+	// ReasonRepeated is text seen in at least MinRepeats distinct chunks.
+	ReasonRepeated = "repeated"
+)
+
+var (
+	markdownLink = regexp.MustCompile(`\[[^\]\n]*\]\([^)\s]*\)`)
+	htmlLink     = regexp.MustCompile(`(?is)<a\b[^>]*>.*?</a>`)
+	bareURL      = regexp.MustCompile(`https?://\S+`)
+	separators   = regexp.MustCompile(`\s[|·•»›]\s`)
+	spaceRun     = regexp.MustCompile(`\s+`)
+)
+
+var phrases = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(we|this (site|website)) uses? cookies\b`),
+	regexp.MustCompile(`(?i)\b(accept|allow|reject|manage) (all )?cookies\b`),
+	regexp.MustCompile(`(?i)\bcookie (policy|settings|preferences)\b`),
+	regexp.MustCompile(`(?i)\bprivacy (policy|notice|statement)\b`),
+	regexp.MustCompile(`(?i)\bterms (of (service|use)|and conditions|& conditions)\b`),
+	regexp.MustCompile(`(?i)\ball rights reserved\b`),
+	regexp.MustCompile(`(?i)(©|\(c\)\s*\d{4}|\bcopyright\s+(©\s*)?\d{4})`),
+	regexp.MustCompile(`(?i)\bsubscribe to (our|the) (newsletter|mailing list)\b`),
+	regexp.MustCompile(`(?i)\bskip to (main )?(content|navigation)\b`),
+	regexp.MustCompile(`(?i)\bfollow us on\b`),
+	regexp.MustCompile(`(?i)\bback to top\b`),
+	regexp.MustCompile(`(?i)\bshare (this|on (facebook|twitter|x|linkedin))\b`),
+	regexp.MustCompile(`(?i)\b(sign in|log in|sign up|create an account)\b`),
+	regexp.MustCompile(`(?i)\bjavascript (is )?(required|disabled)\b`),
+}
+
+// Detection is the result of scoring a text.
+type Detection struct {
+	// Score combines the weights of the signals that fired as
+	// 1 - Π(1 - weight).
+	Score float64
+
+	// Reasons are the signals that fired, sorted.
+	Reasons []string
+}
+
+// Detect scores text on its own, without corpus repetition.
+func Detect(text string) Detection {
+	var weights []float64
+	var d Detection
+	add := func(reason string, w float64) {
+		if w > 0 {
+			weights = append(weights, w)
+			d.Reasons = append(d.Reasons, reason)
+		}
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return d
+	}
+	add(ReasonLinkDensity, linkWeight(text))
+	add(ReasonPhrases, phraseWeight(text))
+	add(ReasonNavigation, navigationWeight(text))
+
+	d.Score = combine(weights)
+	sort.Strings(d.Reasons)
+	return d
+}
+
+// linkWeight scores the share of text taken by links.
+func linkWeight(text string) float64 {
+	linked := 0
+	for _, re := range []*regexp.Regexp{markdownLink, htmlLink} {
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			linked += m[1] - m[0]
+		}
+		text = re.ReplaceAllString(text, "")
+	}
+	for _, m := range bareURL.FindAllStringIndex(text, -1) {
+		linked += m[1] - m[0]
+	}
+	density := float64(linked) / float64(linked+len(strings.TrimSpace(text)))
+	switch {
+	case density >= 0.6:
+		return 0.6
+	case density >= 0.4:
+		return 0.35
+	}
+	return 0
+}
+
+// phraseWeight scores boilerplate phrases relative to the text's length,
+// so a long article mentioning its privacy policy once is not caught.
+func phraseWeight(text string) float64 {
+	matched := 0
+	for _, re := range phrases {
+		if re.MatchString(text) {
+			matched++
+		}
+	}
+	if matched == 0 {
+		return 0
+	}
+	words := max(len(strings.Fields(text)), 25)
+	return math.Min(1, float64(matched)*25/float64(words)) * 0.8
+}
+
+// navigationWeight scores text of short items: lines without sentence
+// punctuation, or runs of items between separators like "|" and "»".
+// Short lists also occur in content, so it only drops a chunk together
+// with another signal.
+func navigationWeight(text string) float64 {
+	lines := strings.Split(text, "\n")
+	var items, short int
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		items++
+		if len(strings.Fields(line)) <= 4 && !strings.ContainsAny(line[len(line)-1:], ".!?:") {
+			short++
+		}
+	}
+	if items >= 4 && float64(short)/float64(items) >= 0.7 {
+		return 0.45
+	}
+
+	if parts := separators.Split(text, -1); len(parts) >= 4 {
+		words := 0
+		for _, p := range parts {
+			words += len(strings.Fields(p))
+		}
+		if float64(words)/float64(len(parts)) <= 4 {
+			return 0.45
+		}
+	}
+	return 0
+}
+
+func combine(weights []float64) float64 {
+	miss := 1.0
+	for _, w := range weights {
+		miss *= 1 - w
+	}
+	return math.Round((1-miss)*1000) / 1000
+}
+
+// Config configures a Filter.
+type Config struct {
+	// Threshold is the score at or above which a chunk is dropped
+	// (default DefaultThreshold).
+	Threshold float64
+
+	// MinRepeats is how many distinct chunk IDs must share a text before
+	// it counts as repeated boilerplate; 0 disables the signal. Sightings
+	// are kept in Cache for RepeatTTL (default DefaultRepeatTTL).
+	MinRepeats int
+	Cache      cache.Cache
+	RepeatTTL  time.Duration
+
+	// Allow lists regular expressions; chunks whose text matches one are
+	// never dropped.
+	Allow []string
+}
+
+// Filter drops boilerplate chunks. A nil Filter keeps every chunk.
+type Filter struct {
+	threshold  float64
+	minRepeats int
+	cache      cache.Cache
+	ttl        time.Duration
+	allow      []*regexp.Regexp
+
+	// mu serializes sighting updates, which read and then write an entry.
+	mu sync.Mutex
+}
+
+// New validates cfg and builds a Filter. Repetition needs a Cache when
+// MinRepeats is set.
+func New(cfg Config) (*Filter, error) {
+	if cfg.Threshold < 0 || cfg.Threshold > 1 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("garbage threshold must be between 0 and 1, got %g", cfg.Threshold))
+	}
+	if cfg.MinRepeats < 0 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("garbage min repeats must be non-negative, got %d", cfg.MinRepeats))
+	}
+	if cfg.MinRepeats > 0 && cfg.Cache == nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("garbage min repeats needs a cache"))
+	}
+	f := &Filter{
+		threshold:  cfg.Threshold,
+		minRepeats: cfg.MinRepeats,
+		cache:      cfg.Cache,
+		ttl:        cfg.RepeatTTL,
+	}
+	if f.threshold == 0 {
+		f.threshold = DefaultThreshold
+	}
+	if f.ttl <= 0 {
+		f.ttl = DefaultRepeatTTL
+	}
+	for _, pattern := range cfg.Allow {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("garbage allow pattern %q: %w", pattern, err))
+		}
+		f.allow = append(f.allow, re)
+	}
+	return f, nil
+}
+
+// Stats counts the decisions of one Apply call.
+type Stats struct {
+	// Dropped is the number of chunks removed as boilerplate.
+	Dropped int
+
+	// Allowed is the number of chunks that scored as boilerplate but
+	// matched an allow pattern.
+	Allowed int
+
+	// Reasons counts the signals of dropped chunks.
+	Reasons map[string]int
+}
+
+// Apply returns the chunks that are not boilerplate, in order, and records
+// each chunk's text toward the repetition signal.
+func (f *Filter) Apply(ctx context.Context, chunks []types.Chunk) ([]types.Chunk, Stats) {
+	var stats Stats
+	if f == nil || len(chunks) == 0 {
+		return chunks, stats
+	}
+
+	out := make([]types.Chunk, 0, len(chunks))
+	for _, c := range chunks {
+		d := Detect(c.Text)
+		if f.repeated(ctx, c) {
+			d.Reasons = append(d.Reasons, ReasonRepeated)
+			d.Score = 1
+		}
+		if d.Score < f.threshold {
+			out = append(out, c)
+			continue
+		}
+		if f.allowed(c.Text) {
+			stats.Allowed++
+			out = append(out, c)
+			continue
+		}
+		stats.Dropped++
+		if stats.Reasons == nil {
+			stats.Reasons = make(map[string]int)
+		}
+		for _, r := range d.Reasons {
+			stats.Reasons[r]++
+		}
+	}
+	return out, stats
+}
+
+func (f *Filter) allowed(text string) bool {
+	for _, re := range f.allow {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// repeated records c's text under its ID and reports whether at least
+// minRepeats distinct IDs have shared it within the TTL.
+func (f *Filter) repeated(ctx context.Context, c types.Chunk) bool {
+	if f.minRepeats == 0 || c.ID == "" {
+		return false
+	}
+	normalized := strings.ToLower(strings.TrimSpace(spaceRun.ReplaceAllString(c.Text, " ")))
+	if normalized == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	key := "garbage:" + hex.EncodeToString(sum[:16])
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []string
+	if raw, err := f.cache.Get(ctx, key); err == nil {
+		ids = strings.Split(string(raw), "\n")
+	}
+	for _, id := range ids {
+		if id == c.ID {
+			return len(ids) >= f.minRepeats
+		}
+	}
+	if len(ids) < f.minRepeats {
+		ids = append(ids, c.ID)
+		_ = f.cache.Set(ctx, key, []byte(strings.Join(ids, "\n")), f.ttl)
+	}
+	return len(ids) >= f.minRepeats
+}
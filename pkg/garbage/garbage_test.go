@@ -0,0 +1,112 @@
+package garbage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestDetect_Boilerplate(t *testing.T) {
+	tests := []struct {
+		text   string
+		reason string
+	}{
+		{"We use cookies to improve your experience. Accept all cookies or manage cookie preferences.", ReasonPhrases},
+		{"© 2024 Acme Inc. All rights reserved. Privacy Policy | Terms of Service", ReasonPhrases},
+		{"[Home](/) [Pricing](/pricing) [Docs](/docs) [Blog](/blog) [Contact](/contact)", ReasonLinkDensity},
+		{"Home\nProducts\nPricing\nAbout us\nSign in\nContact", ReasonNavigation},
+	}
+	for _, tt := range tests {
+		d := Detect(tt.text)
+		if !slices.Contains(d.Reasons, tt.reason) {
+			t.Errorf("Detect(%q) reasons = %v, want %s", tt.text, d.Reasons, tt.reason)
+		}
+		if d.Score < DefaultThreshold {
+			t.Errorf("Detect(%q) score = %v, want >= %v", tt.text, d.Score, DefaultThreshold)
+		}
+	}
+}
+
+func TestDetect_Content(t *testing.T) {
+	content := []string{
+		"The retriever over-fetches candidates and clusters them by cosine distance before selecting one per cluster.",
+		strings.Repeat("Refunds are issued to the original payment method within five business days. ", 8) +
+			"See the privacy policy for how card data is stored.",
+		"Supported languages:\nGo\nRust\nPython\nTypeScript",
+		"Read the [migration guide](https://example.com/migrate) before upgrading, since the config format changed in this release and old keys are rejected.",
+	}
+	for _, text := range content {
+		if d := Detect(text); d.Score >= DefaultThreshold {
+			t.Errorf("Detect(%q) score = %v (%v), want below threshold", text, d.Score, d.Reasons)
+		}
+	}
+}
+
+func TestFilter_Apply(t *testing.T) {
+	f, err := New(Config{Allow: []string{`(?i)terms of service`}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	chunks := []types.Chunk{
+		{ID: "a", Text: "Deploys run nightly from the main branch."},
+		{ID: "b", Text: "We use cookies. Accept all cookies or manage cookie preferences."},
+		{ID: "c", Text: "© 2024 Acme Inc. All rights reserved. Terms of Service"},
+	}
+	out, stats := f.Apply(context.Background(), chunks)
+	if len(out) != 2 || out[0].ID != "a" || out[1].ID != "c" {
+		t.Fatalf("kept %v, want a and c", out)
+	}
+	if stats.Dropped != 1 || stats.Allowed != 1 || stats.Reasons[ReasonPhrases] != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestFilter_Repeated(t *testing.T) {
+	c := cache.NewMemoryCache(cache.Config{})
+	defer func() { _ = c.Close() }()
+	f, err := New(Config{MinRepeats: 3, Cache: c})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// A unique-looking footer shared by pages across requests
+	footer := "Questions? Write to the Acme support team any weekday."
+	ctx := context.Background()
+	for i, id := range []string{"p1#9", "p2#9", "p2#9"} {
+		out, _ := f.Apply(ctx, []types.Chunk{{ID: id, Text: footer}})
+		if len(out) != 1 {
+			t.Fatalf("sighting %d dropped before reaching min repeats", i)
+		}
+	}
+	out, stats := f.Apply(ctx, []types.Chunk{{ID: "p3#9", Text: "questions?  write to the Acme support team any weekday. "}})
+	if len(out) != 0 || stats.Reasons[ReasonRepeated] != 1 {
+		t.Errorf("third distinct sighting kept: out = %v, stats = %+v", out, stats)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	for _, cfg := range []Config{
+		{Threshold: 1.5},
+		{MinRepeats: -1},
+		{MinRepeats: 2},
+		{Allow: []string{"("}},
+	} {
+		if _, err := New(cfg); !errors.Is(err, errs.ErrConfig) {
+			t.Errorf("New(%+v) error = %v, want ErrConfig", cfg, err)
+		}
+	}
+}
+
+func TestFilter_Nil(t *testing.T) {
+	var f *Filter
+	chunks := []types.Chunk{{ID: "a", Text: "Accept all cookies. Privacy policy."}}
+	if out, _ := f.Apply(context.Background(), chunks); len(out) != 1 {
+		t.Error("nil filter dropped a chunk")
+	}
+}
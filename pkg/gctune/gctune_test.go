@@ -0,0 +1,69 @@
+package gctune
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"1024", 1024},
+		{"512MiB", 512 << 20},
+		{"1.5GiB", 3 << 29},
+		{"2GB", 2e9},
+		{"100 KB", 1e5},
+	}
+	for _, tt := range tests {
+		got, err := ParseBytes(tt.in)
+		if err != nil {
+			t.Errorf("ParseBytes(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseBytes(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "lots", "-5MiB", "GiB"} {
+		if _, err := ParseBytes(bad); err == nil {
+			t.Errorf("ParseBytes(%q) should fail", bad)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	origPercent := debug.SetGCPercent(-1)
+	debug.SetGCPercent(origPercent)
+	origLimit := debug.SetMemoryLimit(-1)
+	t.Cleanup(func() {
+		debug.SetGCPercent(origPercent)
+		debug.SetMemoryLimit(origLimit)
+		ballast = nil
+	})
+
+	applied, err := Apply(Config{MemoryLimit: "256MiB", GCPercent: 300, BallastMB: 1})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if applied.MemoryLimit != 256<<20 {
+		t.Errorf("memory limit = %d, want %d", applied.MemoryLimit, 256<<20)
+	}
+	if applied.GCPercent != 300 {
+		t.Errorf("gc percent = %d, want 300", applied.GCPercent)
+	}
+	if applied.BallastMB != 1 {
+		t.Errorf("ballast = %d MiB, want 1", applied.BallastMB)
+	}
+}
+
+func TestApply_Invalid(t *testing.T) {
+	if _, err := Apply(Config{MemoryLimit: "huge"}); err == nil {
+		t.Error("expected error for invalid memory limit")
+	}
+	if _, err := Apply(Config{BallastMB: -1}); err == nil {
+		t.Error("expected error for negative ballast")
+	}
+}
@@ -0,0 +1,2090 @@
+// Distill's gRPC API: the dedupe, retrieve, and redundancy analysis
+// pipelines of distill serve, with embeddings sent as packed floats
+// instead of JSON. The same messages are the application/x-protobuf
+// bodies of POST /v1/dedupe and /v1/retrieve. Regenerate the Go code
+// with make proto.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: distill/v1/distill.proto
+
+package distillv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Chunk is a unit of context.
+type Chunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Text  string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	// The chunk's vector. Chunks without one are embedded from their
+	// text with the server's embedding provider.
+	Embedding []float32 `protobuf:"fixed32,3,rep,packed,name=embedding,proto3" json:"embedding,omitempty"`
+	Score     float32   `protobuf:"fixed32,4,opt,name=score,proto3" json:"score,omitempty"`
+	// The cluster a returned chunk represents.
+	ClusterId int32            `protobuf:"varint,5,opt,name=cluster_id,json=clusterId,proto3" json:"cluster_id,omitempty"`
+	Metadata  *structpb.Struct `protobuf:"bytes,6,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// Marks a cache boundary for preserve_cache_prefix (POST /v1/dedupe
+	// only).
+	CacheControl string `protobuf:"bytes,7,opt,name=cache_control,json=cacheControl,proto3" json:"cache_control,omitempty"`
+	// Set on a returned chunk already sent to the session when
+	// mark_repeats is set.
+	AlreadySent   bool `protobuf:"varint,8,opt,name=already_sent,json=alreadySent,proto3" json:"already_sent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Chunk) Reset() {
+	*x = Chunk{}
+	mi := &file_distill_v1_distill_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Chunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Chunk) ProtoMessage() {}
+
+func (x *Chunk) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Chunk.ProtoReflect.Descriptor instead.
+func (*Chunk) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Chunk) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Chunk) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Chunk) GetEmbedding() []float32 {
+	if x != nil {
+		return x.Embedding
+	}
+	return nil
+}
+
+func (x *Chunk) GetScore() float32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Chunk) GetClusterId() int32 {
+	if x != nil {
+		return x.ClusterId
+	}
+	return 0
+}
+
+func (x *Chunk) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Chunk) GetCacheControl() string {
+	if x != nil {
+		return x.CacheControl
+	}
+	return ""
+}
+
+func (x *Chunk) GetAlreadySent() bool {
+	if x != nil {
+		return x.AlreadySent
+	}
+	return false
+}
+
+type DeduplicateRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Chunks []*Chunk               `protobuf:"bytes,1,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	// The cosine distance threshold for clustering (default 0.15).
+	Threshold float64 `protobuf:"fixed64,2,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	// The MMR relevance/diversity trade-off (default 0.5).
+	Lambda float64 `protobuf:"fixed64,3,opt,name=lambda,proto3" json:"lambda,omitempty"`
+	// Caps the number of chunks returned (0 = one per cluster).
+	TargetK int32 `protobuf:"varint,4,opt,name=target_k,json=targetK,proto3" json:"target_k,omitempty"`
+	// Returns each chunk's embedding.
+	IncludeEmbeddings bool `protobuf:"varint,5,opt,name=include_embeddings,json=includeEmbeddings,proto3" json:"include_embeddings,omitempty"`
+	// The options below are honored by POST /v1/dedupe only; see its JSON
+	// fields of the same names. Deduplicate and DeduplicateStream reject
+	// requests that set them with INVALID_ARGUMENT.
+	SessionId           string           `protobuf:"bytes,6,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	PreserveCachePrefix bool             `protobuf:"varint,7,opt,name=preserve_cache_prefix,json=preserveCachePrefix,proto3" json:"preserve_cache_prefix,omitempty"`
+	MarkRepeats         bool             `protobuf:"varint,8,opt,name=mark_repeats,json=markRepeats,proto3" json:"mark_repeats,omitempty"`
+	ValidateEmbeddings  string           `protobuf:"bytes,9,opt,name=validate_embeddings,json=validateEmbeddings,proto3" json:"validate_embeddings,omitempty"`
+	DedupHints          bool             `protobuf:"varint,10,opt,name=dedup_hints,json=dedupHints,proto3" json:"dedup_hints,omitempty"`
+	EmbeddingDims       int32            `protobuf:"varint,11,opt,name=embedding_dims,json=embeddingDims,proto3" json:"embedding_dims,omitempty"`
+	EmbeddingReduction  string           `protobuf:"bytes,12,opt,name=embedding_reduction,json=embeddingReduction,proto3" json:"embedding_reduction,omitempty"`
+	TokenBudget         int32            `protobuf:"varint,13,opt,name=token_budget,json=tokenBudget,proto3" json:"token_budget,omitempty"`
+	Model               string           `protobuf:"bytes,14,opt,name=model,proto3" json:"model,omitempty"`
+	Compress            *CompressOptions `protobuf:"bytes,15,opt,name=compress,proto3" json:"compress,omitempty"`
+	Redact              bool             `protobuf:"varint,16,opt,name=redact,proto3" json:"redact,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *DeduplicateRequest) Reset() {
+	*x = DeduplicateRequest{}
+	mi := &file_distill_v1_distill_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeduplicateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeduplicateRequest) ProtoMessage() {}
+
+func (x *DeduplicateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeduplicateRequest.ProtoReflect.Descriptor instead.
+func (*DeduplicateRequest) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DeduplicateRequest) GetChunks() []*Chunk {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+func (x *DeduplicateRequest) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *DeduplicateRequest) GetLambda() float64 {
+	if x != nil {
+		return x.Lambda
+	}
+	return 0
+}
+
+func (x *DeduplicateRequest) GetTargetK() int32 {
+	if x != nil {
+		return x.TargetK
+	}
+	return 0
+}
+
+func (x *DeduplicateRequest) GetIncludeEmbeddings() bool {
+	if x != nil {
+		return x.IncludeEmbeddings
+	}
+	return false
+}
+
+func (x *DeduplicateRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *DeduplicateRequest) GetPreserveCachePrefix() bool {
+	if x != nil {
+		return x.PreserveCachePrefix
+	}
+	return false
+}
+
+func (x *DeduplicateRequest) GetMarkRepeats() bool {
+	if x != nil {
+		return x.MarkRepeats
+	}
+	return false
+}
+
+func (x *DeduplicateRequest) GetValidateEmbeddings() string {
+	if x != nil {
+		return x.ValidateEmbeddings
+	}
+	return ""
+}
+
+func (x *DeduplicateRequest) GetDedupHints() bool {
+	if x != nil {
+		return x.DedupHints
+	}
+	return false
+}
+
+func (x *DeduplicateRequest) GetEmbeddingDims() int32 {
+	if x != nil {
+		return x.EmbeddingDims
+	}
+	return 0
+}
+
+func (x *DeduplicateRequest) GetEmbeddingReduction() string {
+	if x != nil {
+		return x.EmbeddingReduction
+	}
+	return ""
+}
+
+func (x *DeduplicateRequest) GetTokenBudget() int32 {
+	if x != nil {
+		return x.TokenBudget
+	}
+	return 0
+}
+
+func (x *DeduplicateRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *DeduplicateRequest) GetCompress() *CompressOptions {
+	if x != nil {
+		return x.Compress
+	}
+	return nil
+}
+
+func (x *DeduplicateRequest) GetRedact() bool {
+	if x != nil {
+		return x.Redact
+	}
+	return false
+}
+
+type DeduplicateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Chunks        []*Chunk               `protobuf:"bytes,1,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	Stats         *DeduplicateStats      `protobuf:"bytes,2,opt,name=stats,proto3" json:"stats,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeduplicateResponse) Reset() {
+	*x = DeduplicateResponse{}
+	mi := &file_distill_v1_distill_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeduplicateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeduplicateResponse) ProtoMessage() {}
+
+func (x *DeduplicateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeduplicateResponse.ProtoReflect.Descriptor instead.
+func (*DeduplicateResponse) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DeduplicateResponse) GetChunks() []*Chunk {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+func (x *DeduplicateResponse) GetStats() *DeduplicateStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+type DeduplicateStats struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	InputCount   int32                  `protobuf:"varint,1,opt,name=input_count,json=inputCount,proto3" json:"input_count,omitempty"`
+	OutputCount  int32                  `protobuf:"varint,2,opt,name=output_count,json=outputCount,proto3" json:"output_count,omitempty"`
+	ClusterCount int32                  `protobuf:"varint,3,opt,name=cluster_count,json=clusterCount,proto3" json:"cluster_count,omitempty"`
+	ReductionPct int32                  `protobuf:"varint,4,opt,name=reduction_pct,json=reductionPct,proto3" json:"reduction_pct,omitempty"`
+	LatencyMs    int64                  `protobuf:"varint,5,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	// Set by POST /v1/dedupe only.
+	CachePrefixFrozen  bool              `protobuf:"varint,6,opt,name=cache_prefix_frozen,json=cachePrefixFrozen,proto3" json:"cache_prefix_frozen,omitempty"`
+	CachePrefixTokens  int32             `protobuf:"varint,7,opt,name=cache_prefix_tokens,json=cachePrefixTokens,proto3" json:"cache_prefix_tokens,omitempty"`
+	CachePrefixHash    string            `protobuf:"bytes,8,opt,name=cache_prefix_hash,json=cachePrefixHash,proto3" json:"cache_prefix_hash,omitempty"`
+	SuffixInputCount   int32             `protobuf:"varint,9,opt,name=suffix_input_count,json=suffixInputCount,proto3" json:"suffix_input_count,omitempty"`
+	SuffixOutputCount  int32             `protobuf:"varint,10,opt,name=suffix_output_count,json=suffixOutputCount,proto3" json:"suffix_output_count,omitempty"`
+	RepeatedCount      int32             `protobuf:"varint,11,opt,name=repeated_count,json=repeatedCount,proto3" json:"repeated_count,omitempty"`
+	EmbeddingsRepaired int32             `protobuf:"varint,12,opt,name=embeddings_repaired,json=embeddingsRepaired,proto3" json:"embeddings_repaired,omitempty"`
+	MatrixOverflow     string            `protobuf:"bytes,13,opt,name=matrix_overflow,json=matrixOverflow,proto3" json:"matrix_overflow,omitempty"`
+	Tokens             int32             `protobuf:"varint,14,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	Compression        *CompressionStats `protobuf:"bytes,15,opt,name=compression,proto3" json:"compression,omitempty"`
+	Redacted           int32             `protobuf:"varint,16,opt,name=redacted,proto3" json:"redacted,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *DeduplicateStats) Reset() {
+	*x = DeduplicateStats{}
+	mi := &file_distill_v1_distill_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeduplicateStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeduplicateStats) ProtoMessage() {}
+
+func (x *DeduplicateStats) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeduplicateStats.ProtoReflect.Descriptor instead.
+func (*DeduplicateStats) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DeduplicateStats) GetInputCount() int32 {
+	if x != nil {
+		return x.InputCount
+	}
+	return 0
+}
+
+func (x *DeduplicateStats) GetOutputCount() int32 {
+	if x != nil {
+		return x.OutputCount
+	}
+	return 0
+}
+
+func (x *DeduplicateStats) GetClusterCount() int32 {
+	if x != nil {
+		return x.ClusterCount
+	}
+	return 0
+}
+
+func (x *DeduplicateStats) GetReductionPct() int32 {
+	if x != nil {
+		return x.ReductionPct
+	}
+	return 0
+}
+
+func (x *DeduplicateStats) GetLatencyMs() int64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+func (x *DeduplicateStats) GetCachePrefixFrozen() bool {
+	if x != nil {
+		return x.CachePrefixFrozen
+	}
+	return false
+}
+
+func (x *DeduplicateStats) GetCachePrefixTokens() int32 {
+	if x != nil {
+		return x.CachePrefixTokens
+	}
+	return 0
+}
+
+func (x *DeduplicateStats) GetCachePrefixHash() string {
+	if x != nil {
+		return x.CachePrefixHash
+	}
+	return ""
+}
+
+func (x *DeduplicateStats) GetSuffixInputCount() int32 {
+	if x != nil {
+		return x.SuffixInputCount
+	}
+	return 0
+}
+
+func (x *DeduplicateStats) GetSuffixOutputCount() int32 {
+	if x != nil {
+		return x.SuffixOutputCount
+	}
+	return 0
+}
+
+func (x *DeduplicateStats) GetRepeatedCount() int32 {
+	if x != nil {
+		return x.RepeatedCount
+	}
+	return 0
+}
+
+func (x *DeduplicateStats) GetEmbeddingsRepaired() int32 {
+	if x != nil {
+		return x.EmbeddingsRepaired
+	}
+	return 0
+}
+
+func (x *DeduplicateStats) GetMatrixOverflow() string {
+	if x != nil {
+		return x.MatrixOverflow
+	}
+	return ""
+}
+
+func (x *DeduplicateStats) GetTokens() int32 {
+	if x != nil {
+		return x.Tokens
+	}
+	return 0
+}
+
+func (x *DeduplicateStats) GetCompression() *CompressionStats {
+	if x != nil {
+		return x.Compression
+	}
+	return nil
+}
+
+func (x *DeduplicateStats) GetRedacted() int32 {
+	if x != nil {
+		return x.Redacted
+	}
+	return 0
+}
+
+type RetrieveRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One of query and query_embedding is required.
+	Query          string    `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	QueryEmbedding []float32 `protobuf:"fixed32,2,rep,packed,name=query_embedding,json=queryEmbedding,proto3" json:"query_embedding,omitempty"`
+	Namespace      string    `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// The number of matches to over-fetch (default: the server's), at
+	// most the server's chunk limit.
+	TopK int32 `protobuf:"varint,4,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"`
+	// Override the server's clustering threshold and MMR lambda.
+	Threshold float64 `protobuf:"fixed64,5,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	Lambda    float64 `protobuf:"fixed64,6,opt,name=lambda,proto3" json:"lambda,omitempty"`
+	// A metadata filter in the backend's syntax.
+	Filter *structpb.Struct `protobuf:"bytes,7,opt,name=filter,proto3" json:"filter,omitempty"`
+	// Drops matches scoring below it.
+	MinScore float32 `protobuf:"fixed32,8,opt,name=min_score,json=minScore,proto3" json:"min_score,omitempty"`
+	// Drops chunks already returned to the session.
+	SessionId string `protobuf:"bytes,9,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// Chunk IDs to leave out.
+	Exclude           []string `protobuf:"bytes,10,rep,name=exclude,proto3" json:"exclude,omitempty"`
+	IncludeEmbeddings bool     `protobuf:"varint,11,opt,name=include_embeddings,json=includeEmbeddings,proto3" json:"include_embeddings,omitempty"`
+	// Records each chunk's transformations in its metadata.
+	Explain bool `protobuf:"varint,12,opt,name=explain,proto3" json:"explain,omitempty"`
+	// Keeps chunks already returned to the session, flagged with
+	// already_sent, instead of dropping them.
+	MarkRepeats bool `protobuf:"varint,13,opt,name=mark_repeats,json=markRepeats,proto3" json:"mark_repeats,omitempty"`
+	// Adds dedup_cluster_size, dedup_duplicates_removed, and
+	// dedup_representative_reason to each chunk's metadata.
+	DedupHints bool `protobuf:"varint,14,opt,name=dedup_hints,json=dedupHints,proto3" json:"dedup_hints,omitempty"`
+	// Honored by POST /v1/retrieve only; see its JSON fields of the same
+	// names. Retrieve rejects requests that set them with INVALID_ARGUMENT.
+	TargetK            int32  `protobuf:"varint,15,opt,name=target_k,json=targetK,proto3" json:"target_k,omitempty"`
+	EmbeddingDims      int32  `protobuf:"varint,16,opt,name=embedding_dims,json=embeddingDims,proto3" json:"embedding_dims,omitempty"`
+	EmbeddingReduction string `protobuf:"bytes,17,opt,name=embedding_reduction,json=embeddingReduction,proto3" json:"embedding_reduction,omitempty"`
+	// Checked against chunk ACLs when the server runs with --acl. Without
+	// it, every chunk with an ACL is denied.
+	Identity *Identity `protobuf:"bytes,18,opt,name=identity,proto3" json:"identity,omitempty"`
+	// Honored by POST /v1/retrieve only, like the fields above.
+	Namespaces         []*NamespaceQuota `protobuf:"bytes,19,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	Queries            []string          `protobuf:"bytes,20,rep,name=queries,proto3" json:"queries,omitempty"`
+	QueryEmbeddings    []*Embedding      `protobuf:"bytes,21,rep,name=query_embeddings,json=queryEmbeddings,proto3" json:"query_embeddings,omitempty"`
+	Combine            string            `protobuf:"bytes,22,opt,name=combine,proto3" json:"combine,omitempty"`
+	Template           string            `protobuf:"bytes,23,opt,name=template,proto3" json:"template,omitempty"`
+	ValidateEmbeddings string            `protobuf:"bytes,24,opt,name=validate_embeddings,json=validateEmbeddings,proto3" json:"validate_embeddings,omitempty"`
+	Enable             *StageToggles     `protobuf:"bytes,25,opt,name=enable,proto3" json:"enable,omitempty"`
+	Selection          string            `protobuf:"bytes,26,opt,name=selection,proto3" json:"selection,omitempty"`
+	Model              string            `protobuf:"bytes,27,opt,name=model,proto3" json:"model,omitempty"`
+	Compress           *CompressOptions  `protobuf:"bytes,28,opt,name=compress,proto3" json:"compress,omitempty"`
+	Mode               string            `protobuf:"bytes,29,opt,name=mode,proto3" json:"mode,omitempty"`
+	Redact             bool              `protobuf:"varint,30,opt,name=redact,proto3" json:"redact,omitempty"`
+	DeadlineMs         int32             `protobuf:"varint,31,opt,name=deadline_ms,json=deadlineMs,proto3" json:"deadline_ms,omitempty"`
+	Index              string            `protobuf:"bytes,32,opt,name=index,proto3" json:"index,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *RetrieveRequest) Reset() {
+	*x = RetrieveRequest{}
+	mi := &file_distill_v1_distill_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetrieveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetrieveRequest) ProtoMessage() {}
+
+func (x *RetrieveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetrieveRequest.ProtoReflect.Descriptor instead.
+func (*RetrieveRequest) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RetrieveRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *RetrieveRequest) GetQueryEmbedding() []float32 {
+	if x != nil {
+		return x.QueryEmbedding
+	}
+	return nil
+}
+
+func (x *RetrieveRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *RetrieveRequest) GetTopK() int32 {
+	if x != nil {
+		return x.TopK
+	}
+	return 0
+}
+
+func (x *RetrieveRequest) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *RetrieveRequest) GetLambda() float64 {
+	if x != nil {
+		return x.Lambda
+	}
+	return 0
+}
+
+func (x *RetrieveRequest) GetFilter() *structpb.Struct {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *RetrieveRequest) GetMinScore() float32 {
+	if x != nil {
+		return x.MinScore
+	}
+	return 0
+}
+
+func (x *RetrieveRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *RetrieveRequest) GetExclude() []string {
+	if x != nil {
+		return x.Exclude
+	}
+	return nil
+}
+
+func (x *RetrieveRequest) GetIncludeEmbeddings() bool {
+	if x != nil {
+		return x.IncludeEmbeddings
+	}
+	return false
+}
+
+func (x *RetrieveRequest) GetExplain() bool {
+	if x != nil {
+		return x.Explain
+	}
+	return false
+}
+
+func (x *RetrieveRequest) GetMarkRepeats() bool {
+	if x != nil {
+		return x.MarkRepeats
+	}
+	return false
+}
+
+func (x *RetrieveRequest) GetDedupHints() bool {
+	if x != nil {
+		return x.DedupHints
+	}
+	return false
+}
+
+func (x *RetrieveRequest) GetTargetK() int32 {
+	if x != nil {
+		return x.TargetK
+	}
+	return 0
+}
+
+func (x *RetrieveRequest) GetEmbeddingDims() int32 {
+	if x != nil {
+		return x.EmbeddingDims
+	}
+	return 0
+}
+
+func (x *RetrieveRequest) GetEmbeddingReduction() string {
+	if x != nil {
+		return x.EmbeddingReduction
+	}
+	return ""
+}
+
+func (x *RetrieveRequest) GetIdentity() *Identity {
+	if x != nil {
+		return x.Identity
+	}
+	return nil
+}
+
+func (x *RetrieveRequest) GetNamespaces() []*NamespaceQuota {
+	if x != nil {
+		return x.Namespaces
+	}
+	return nil
+}
+
+func (x *RetrieveRequest) GetQueries() []string {
+	if x != nil {
+		return x.Queries
+	}
+	return nil
+}
+
+func (x *RetrieveRequest) GetQueryEmbeddings() []*Embedding {
+	if x != nil {
+		return x.QueryEmbeddings
+	}
+	return nil
+}
+
+func (x *RetrieveRequest) GetCombine() string {
+	if x != nil {
+		return x.Combine
+	}
+	return ""
+}
+
+func (x *RetrieveRequest) GetTemplate() string {
+	if x != nil {
+		return x.Template
+	}
+	return ""
+}
+
+func (x *RetrieveRequest) GetValidateEmbeddings() string {
+	if x != nil {
+		return x.ValidateEmbeddings
+	}
+	return ""
+}
+
+func (x *RetrieveRequest) GetEnable() *StageToggles {
+	if x != nil {
+		return x.Enable
+	}
+	return nil
+}
+
+func (x *RetrieveRequest) GetSelection() string {
+	if x != nil {
+		return x.Selection
+	}
+	return ""
+}
+
+func (x *RetrieveRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *RetrieveRequest) GetCompress() *CompressOptions {
+	if x != nil {
+		return x.Compress
+	}
+	return nil
+}
+
+func (x *RetrieveRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *RetrieveRequest) GetRedact() bool {
+	if x != nil {
+		return x.Redact
+	}
+	return false
+}
+
+func (x *RetrieveRequest) GetDeadlineMs() int32 {
+	if x != nil {
+		return x.DeadlineMs
+	}
+	return 0
+}
+
+func (x *RetrieveRequest) GetIndex() string {
+	if x != nil {
+		return x.Index
+	}
+	return ""
+}
+
+// Identity is the caller chunk ACLs are checked against.
+type Identity struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Groups        []string               `protobuf:"bytes,2,rep,name=groups,proto3" json:"groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Identity) Reset() {
+	*x = Identity{}
+	mi := &file_distill_v1_distill_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Identity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Identity) ProtoMessage() {}
+
+func (x *Identity) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Identity.ProtoReflect.Descriptor instead.
+func (*Identity) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Identity) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *Identity) GetGroups() []string {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+// NamespaceQuota is one namespace of a multi-namespace query and the
+// number of its matches to keep (0 = the request's top_k).
+type NamespaceQuota struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	TopK          int32                  `protobuf:"varint,2,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NamespaceQuota) Reset() {
+	*x = NamespaceQuota{}
+	mi := &file_distill_v1_distill_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NamespaceQuota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceQuota) ProtoMessage() {}
+
+func (x *NamespaceQuota) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceQuota.ProtoReflect.Descriptor instead.
+func (*NamespaceQuota) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *NamespaceQuota) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NamespaceQuota) GetTopK() int32 {
+	if x != nil {
+		return x.TopK
+	}
+	return 0
+}
+
+// Embedding is one vector of query_embeddings.
+type Embedding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []float32              `protobuf:"fixed32,1,rep,packed,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Embedding) Reset() {
+	*x = Embedding{}
+	mi := &file_distill_v1_distill_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Embedding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Embedding) ProtoMessage() {}
+
+func (x *Embedding) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Embedding.ProtoReflect.Descriptor instead.
+func (*Embedding) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Embedding) GetValues() []float32 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+// StageToggles turns optional stages on or off; unset stages keep the
+// server's setting.
+type StageToggles struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Clustering     *bool                  `protobuf:"varint,1,opt,name=clustering,proto3,oneof" json:"clustering,omitempty"`
+	Mmr            *bool                  `protobuf:"varint,2,opt,name=mmr,proto3,oneof" json:"mmr,omitempty"`
+	Compression    *bool                  `protobuf:"varint,3,opt,name=compression,proto3,oneof" json:"compression,omitempty"`
+	Redaction      *bool                  `protobuf:"varint,4,opt,name=redaction,proto3,oneof" json:"redaction,omitempty"`
+	Scoring        *bool                  `protobuf:"varint,5,opt,name=scoring,proto3,oneof" json:"scoring,omitempty"`
+	Rerank         *bool                  `protobuf:"varint,6,opt,name=rerank,proto3,oneof" json:"rerank,omitempty"`
+	Classification *bool                  `protobuf:"varint,7,opt,name=classification,proto3,oneof" json:"classification,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StageToggles) Reset() {
+	*x = StageToggles{}
+	mi := &file_distill_v1_distill_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StageToggles) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StageToggles) ProtoMessage() {}
+
+func (x *StageToggles) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StageToggles.ProtoReflect.Descriptor instead.
+func (*StageToggles) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StageToggles) GetClustering() bool {
+	if x != nil && x.Clustering != nil {
+		return *x.Clustering
+	}
+	return false
+}
+
+func (x *StageToggles) GetMmr() bool {
+	if x != nil && x.Mmr != nil {
+		return *x.Mmr
+	}
+	return false
+}
+
+func (x *StageToggles) GetCompression() bool {
+	if x != nil && x.Compression != nil {
+		return *x.Compression
+	}
+	return false
+}
+
+func (x *StageToggles) GetRedaction() bool {
+	if x != nil && x.Redaction != nil {
+		return *x.Redaction
+	}
+	return false
+}
+
+func (x *StageToggles) GetScoring() bool {
+	if x != nil && x.Scoring != nil {
+		return *x.Scoring
+	}
+	return false
+}
+
+func (x *StageToggles) GetRerank() bool {
+	if x != nil && x.Rerank != nil {
+		return *x.Rerank
+	}
+	return false
+}
+
+func (x *StageToggles) GetClassification() bool {
+	if x != nil && x.Classification != nil {
+		return *x.Classification
+	}
+	return false
+}
+
+// CompressOptions sets how one request's chunks are compressed; unset
+// fields keep the server's setting.
+type CompressOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// extractive, placeholder, hybrid, or cluster-merge.
+	Mode              string  `protobuf:"bytes,1,opt,name=mode,proto3" json:"mode,omitempty"`
+	TargetReduction   float64 `protobuf:"fixed64,2,opt,name=target_reduction,json=targetReduction,proto3" json:"target_reduction,omitempty"`
+	PreserveStructure *bool   `protobuf:"varint,3,opt,name=preserve_structure,json=preserveStructure,proto3,oneof" json:"preserve_structure,omitempty"`
+	MergeTokens       int32   `protobuf:"varint,4,opt,name=merge_tokens,json=mergeTokens,proto3" json:"merge_tokens,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *CompressOptions) Reset() {
+	*x = CompressOptions{}
+	mi := &file_distill_v1_distill_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompressOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompressOptions) ProtoMessage() {}
+
+func (x *CompressOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompressOptions.ProtoReflect.Descriptor instead.
+func (*CompressOptions) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CompressOptions) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *CompressOptions) GetTargetReduction() float64 {
+	if x != nil {
+		return x.TargetReduction
+	}
+	return 0
+}
+
+func (x *CompressOptions) GetPreserveStructure() bool {
+	if x != nil && x.PreserveStructure != nil {
+		return *x.PreserveStructure
+	}
+	return false
+}
+
+func (x *CompressOptions) GetMergeTokens() int32 {
+	if x != nil {
+		return x.MergeTokens
+	}
+	return 0
+}
+
+type RetrieveResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Chunks []*Chunk               `protobuf:"bytes,1,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	Stats  *RetrieveStats         `protobuf:"bytes,2,opt,name=stats,proto3" json:"stats,omitempty"`
+	// Set by POST /v1/retrieve only.
+	Rendered      string   `protobuf:"bytes,3,opt,name=rendered,proto3" json:"rendered,omitempty"`
+	FeedbackId    string   `protobuf:"bytes,4,opt,name=feedback_id,json=feedbackId,proto3" json:"feedback_id,omitempty"`
+	Stages        []string `protobuf:"bytes,5,rep,name=stages,proto3" json:"stages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RetrieveResponse) Reset() {
+	*x = RetrieveResponse{}
+	mi := &file_distill_v1_distill_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetrieveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetrieveResponse) ProtoMessage() {}
+
+func (x *RetrieveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetrieveResponse.ProtoReflect.Descriptor instead.
+func (*RetrieveResponse) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RetrieveResponse) GetChunks() []*Chunk {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+func (x *RetrieveResponse) GetStats() *RetrieveStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *RetrieveResponse) GetRendered() string {
+	if x != nil {
+		return x.Rendered
+	}
+	return ""
+}
+
+func (x *RetrieveResponse) GetFeedbackId() string {
+	if x != nil {
+		return x.FeedbackId
+	}
+	return ""
+}
+
+func (x *RetrieveResponse) GetStages() []string {
+	if x != nil {
+		return x.Stages
+	}
+	return nil
+}
+
+type RetrieveStats struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Retrieved int32                  `protobuf:"varint,1,opt,name=retrieved,proto3" json:"retrieved,omitempty"`
+	Clustered int32                  `protobuf:"varint,2,opt,name=clustered,proto3" json:"clustered,omitempty"`
+	Returned  int32                  `protobuf:"varint,3,opt,name=returned,proto3" json:"returned,omitempty"`
+	Repeated  int32                  `protobuf:"varint,4,opt,name=repeated,proto3" json:"repeated,omitempty"`
+	LatencyMs int64                  `protobuf:"varint,5,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	CacheHit  bool                   `protobuf:"varint,6,opt,name=cache_hit,json=cacheHit,proto3" json:"cache_hit,omitempty"`
+	Excluded  int32                  `protobuf:"varint,7,opt,name=excluded,proto3" json:"excluded,omitempty"`
+	CacheMiss bool                   `protobuf:"varint,8,opt,name=cache_miss,json=cacheMiss,proto3" json:"cache_miss,omitempty"`
+	// The model profile the result was fitted to, and the returned
+	// chunks' tokens and input price under it.
+	Model   string  `protobuf:"bytes,9,opt,name=model,proto3" json:"model,omitempty"`
+	Tokens  int32   `protobuf:"varint,10,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	CostUsd float64 `protobuf:"fixed64,11,opt,name=cost_usd,json=costUsd,proto3" json:"cost_usd,omitempty"`
+	// Set when the request's deadline cut the pipeline short; notes says
+	// which stages were skipped or reduced.
+	BestEffort bool     `protobuf:"varint,12,opt,name=best_effort,json=bestEffort,proto3" json:"best_effort,omitempty"`
+	Notes      []string `protobuf:"bytes,13,rep,name=notes,proto3" json:"notes,omitempty"`
+	// The chunks the request's identity may not see, including those
+	// without ACL metadata.
+	AclDenied    int32 `protobuf:"varint,14,opt,name=acl_denied,json=aclDenied,proto3" json:"acl_denied,omitempty"`
+	AclUnlabeled int32 `protobuf:"varint,15,opt,name=acl_unlabeled,json=aclUnlabeled,proto3" json:"acl_unlabeled,omitempty"`
+	// Set by POST /v1/retrieve only.
+	Compression       *CompressionStats `protobuf:"bytes,16,opt,name=compression,proto3" json:"compression,omitempty"`
+	Redacted          int32             `protobuf:"varint,17,opt,name=redacted,proto3" json:"redacted,omitempty"`
+	BudgetDropped     int32             `protobuf:"varint,18,opt,name=budget_dropped,json=budgetDropped,proto3" json:"budget_dropped,omitempty"`
+	Sparse            bool              `protobuf:"varint,19,opt,name=sparse,proto3" json:"sparse,omitempty"`
+	Passthrough       bool              `protobuf:"varint,20,opt,name=passthrough,proto3" json:"passthrough,omitempty"`
+	Sensitivity       string            `protobuf:"bytes,21,opt,name=sensitivity,proto3" json:"sensitivity,omitempty"`
+	InjectionFlagged  int32             `protobuf:"varint,22,opt,name=injection_flagged,json=injectionFlagged,proto3" json:"injection_flagged,omitempty"`
+	InjectionStripped int32             `protobuf:"varint,23,opt,name=injection_stripped,json=injectionStripped,proto3" json:"injection_stripped,omitempty"`
+	InjectionBlocked  int32             `protobuf:"varint,24,opt,name=injection_blocked,json=injectionBlocked,proto3" json:"injection_blocked,omitempty"`
+	GarbageDropped    int32             `protobuf:"varint,25,opt,name=garbage_dropped,json=garbageDropped,proto3" json:"garbage_dropped,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *RetrieveStats) Reset() {
+	*x = RetrieveStats{}
+	mi := &file_distill_v1_distill_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetrieveStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetrieveStats) ProtoMessage() {}
+
+func (x *RetrieveStats) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetrieveStats.ProtoReflect.Descriptor instead.
+func (*RetrieveStats) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RetrieveStats) GetRetrieved() int32 {
+	if x != nil {
+		return x.Retrieved
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetClustered() int32 {
+	if x != nil {
+		return x.Clustered
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetReturned() int32 {
+	if x != nil {
+		return x.Returned
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetRepeated() int32 {
+	if x != nil {
+		return x.Repeated
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetLatencyMs() int64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetCacheHit() bool {
+	if x != nil {
+		return x.CacheHit
+	}
+	return false
+}
+
+func (x *RetrieveStats) GetExcluded() int32 {
+	if x != nil {
+		return x.Excluded
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetCacheMiss() bool {
+	if x != nil {
+		return x.CacheMiss
+	}
+	return false
+}
+
+func (x *RetrieveStats) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *RetrieveStats) GetTokens() int32 {
+	if x != nil {
+		return x.Tokens
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetCostUsd() float64 {
+	if x != nil {
+		return x.CostUsd
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetBestEffort() bool {
+	if x != nil {
+		return x.BestEffort
+	}
+	return false
+}
+
+func (x *RetrieveStats) GetNotes() []string {
+	if x != nil {
+		return x.Notes
+	}
+	return nil
+}
+
+func (x *RetrieveStats) GetAclDenied() int32 {
+	if x != nil {
+		return x.AclDenied
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetAclUnlabeled() int32 {
+	if x != nil {
+		return x.AclUnlabeled
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetCompression() *CompressionStats {
+	if x != nil {
+		return x.Compression
+	}
+	return nil
+}
+
+func (x *RetrieveStats) GetRedacted() int32 {
+	if x != nil {
+		return x.Redacted
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetBudgetDropped() int32 {
+	if x != nil {
+		return x.BudgetDropped
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetSparse() bool {
+	if x != nil {
+		return x.Sparse
+	}
+	return false
+}
+
+func (x *RetrieveStats) GetPassthrough() bool {
+	if x != nil {
+		return x.Passthrough
+	}
+	return false
+}
+
+func (x *RetrieveStats) GetSensitivity() string {
+	if x != nil {
+		return x.Sensitivity
+	}
+	return ""
+}
+
+func (x *RetrieveStats) GetInjectionFlagged() int32 {
+	if x != nil {
+		return x.InjectionFlagged
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetInjectionStripped() int32 {
+	if x != nil {
+		return x.InjectionStripped
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetInjectionBlocked() int32 {
+	if x != nil {
+		return x.InjectionBlocked
+	}
+	return 0
+}
+
+func (x *RetrieveStats) GetGarbageDropped() int32 {
+	if x != nil {
+		return x.GarbageDropped
+	}
+	return 0
+}
+
+// CompressionStats reports what compression saved.
+type CompressionStats struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	InputTokens      int32                  `protobuf:"varint,1,opt,name=input_tokens,json=inputTokens,proto3" json:"input_tokens,omitempty"`
+	OutputTokens     int32                  `protobuf:"varint,2,opt,name=output_tokens,json=outputTokens,proto3" json:"output_tokens,omitempty"`
+	SavedTokens      int32                  `protobuf:"varint,3,opt,name=saved_tokens,json=savedTokens,proto3" json:"saved_tokens,omitempty"`
+	ReductionPct     float64                `protobuf:"fixed64,4,opt,name=reduction_pct,json=reductionPct,proto3" json:"reduction_pct,omitempty"`
+	ChunksCompressed int32                  `protobuf:"varint,5,opt,name=chunks_compressed,json=chunksCompressed,proto3" json:"chunks_compressed,omitempty"`
+	ChunksMerged     int32                  `protobuf:"varint,6,opt,name=chunks_merged,json=chunksMerged,proto3" json:"chunks_merged,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *CompressionStats) Reset() {
+	*x = CompressionStats{}
+	mi := &file_distill_v1_distill_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompressionStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompressionStats) ProtoMessage() {}
+
+func (x *CompressionStats) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompressionStats.ProtoReflect.Descriptor instead.
+func (*CompressionStats) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CompressionStats) GetInputTokens() int32 {
+	if x != nil {
+		return x.InputTokens
+	}
+	return 0
+}
+
+func (x *CompressionStats) GetOutputTokens() int32 {
+	if x != nil {
+		return x.OutputTokens
+	}
+	return 0
+}
+
+func (x *CompressionStats) GetSavedTokens() int32 {
+	if x != nil {
+		return x.SavedTokens
+	}
+	return 0
+}
+
+func (x *CompressionStats) GetReductionPct() float64 {
+	if x != nil {
+		return x.ReductionPct
+	}
+	return 0
+}
+
+func (x *CompressionStats) GetChunksCompressed() int32 {
+	if x != nil {
+		return x.ChunksCompressed
+	}
+	return 0
+}
+
+func (x *CompressionStats) GetChunksMerged() int32 {
+	if x != nil {
+		return x.ChunksMerged
+	}
+	return 0
+}
+
+type AnalyzeRedundancyRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Chunks to analyze. Chunks without an embedding are embedded from
+	// their text.
+	Chunks []*Chunk `protobuf:"bytes,1,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	// The cosine distance below which chunks are duplicates (default 0.05).
+	Threshold float64 `protobuf:"fixed64,2,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	// The number of k-means clusters (default sqrt(n/2)).
+	Clusters int32 `protobuf:"varint,3,opt,name=clusters,proto3" json:"clusters,omitempty"`
+	// Makes the clustering reproducible (0 = random).
+	Seed          int64 `protobuf:"varint,4,opt,name=seed,proto3" json:"seed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnalyzeRedundancyRequest) Reset() {
+	*x = AnalyzeRedundancyRequest{}
+	mi := &file_distill_v1_distill_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalyzeRedundancyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalyzeRedundancyRequest) ProtoMessage() {}
+
+func (x *AnalyzeRedundancyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalyzeRedundancyRequest.ProtoReflect.Descriptor instead.
+func (*AnalyzeRedundancyRequest) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *AnalyzeRedundancyRequest) GetChunks() []*Chunk {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+func (x *AnalyzeRedundancyRequest) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *AnalyzeRedundancyRequest) GetClusters() int32 {
+	if x != nil {
+		return x.Clusters
+	}
+	return 0
+}
+
+func (x *AnalyzeRedundancyRequest) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+type AnalyzeRedundancyResponse struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Total        int32                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Unique       int32                  `protobuf:"varint,2,opt,name=unique,proto3" json:"unique,omitempty"`
+	Duplicates   int32                  `protobuf:"varint,3,opt,name=duplicates,proto3" json:"duplicates,omitempty"`
+	ClusterCount int32                  `protobuf:"varint,4,opt,name=cluster_count,json=clusterCount,proto3" json:"cluster_count,omitempty"`
+	SavingsPct   float64                `protobuf:"fixed64,5,opt,name=savings_pct,json=savingsPct,proto3" json:"savings_pct,omitempty"`
+	// Each duplicate and the chunk it duplicates.
+	Removed       []*Removal `protobuf:"bytes,6,rep,name=removed,proto3" json:"removed,omitempty"`
+	LatencyMs     int64      `protobuf:"varint,7,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnalyzeRedundancyResponse) Reset() {
+	*x = AnalyzeRedundancyResponse{}
+	mi := &file_distill_v1_distill_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalyzeRedundancyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalyzeRedundancyResponse) ProtoMessage() {}
+
+func (x *AnalyzeRedundancyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalyzeRedundancyResponse.ProtoReflect.Descriptor instead.
+func (*AnalyzeRedundancyResponse) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *AnalyzeRedundancyResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *AnalyzeRedundancyResponse) GetUnique() int32 {
+	if x != nil {
+		return x.Unique
+	}
+	return 0
+}
+
+func (x *AnalyzeRedundancyResponse) GetDuplicates() int32 {
+	if x != nil {
+		return x.Duplicates
+	}
+	return 0
+}
+
+func (x *AnalyzeRedundancyResponse) GetClusterCount() int32 {
+	if x != nil {
+		return x.ClusterCount
+	}
+	return 0
+}
+
+func (x *AnalyzeRedundancyResponse) GetSavingsPct() float64 {
+	if x != nil {
+		return x.SavingsPct
+	}
+	return 0
+}
+
+func (x *AnalyzeRedundancyResponse) GetRemoved() []*Removal {
+	if x != nil {
+		return x.Removed
+	}
+	return nil
+}
+
+func (x *AnalyzeRedundancyResponse) GetLatencyMs() int64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+type Removal struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RemovedId     string                 `protobuf:"bytes,1,opt,name=removed_id,json=removedId,proto3" json:"removed_id,omitempty"`
+	KeptId        string                 `protobuf:"bytes,2,opt,name=kept_id,json=keptId,proto3" json:"kept_id,omitempty"`
+	Distance      float64                `protobuf:"fixed64,3,opt,name=distance,proto3" json:"distance,omitempty"`
+	Cluster       int32                  `protobuf:"varint,4,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Removal) Reset() {
+	*x = Removal{}
+	mi := &file_distill_v1_distill_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Removal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Removal) ProtoMessage() {}
+
+func (x *Removal) ProtoReflect() protoreflect.Message {
+	mi := &file_distill_v1_distill_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Removal.ProtoReflect.Descriptor instead.
+func (*Removal) Descriptor() ([]byte, []int) {
+	return file_distill_v1_distill_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Removal) GetRemovedId() string {
+	if x != nil {
+		return x.RemovedId
+	}
+	return ""
+}
+
+func (x *Removal) GetKeptId() string {
+	if x != nil {
+		return x.KeptId
+	}
+	return ""
+}
+
+func (x *Removal) GetDistance() float64 {
+	if x != nil {
+		return x.Distance
+	}
+	return 0
+}
+
+func (x *Removal) GetCluster() int32 {
+	if x != nil {
+		return x.Cluster
+	}
+	return 0
+}
+
+var File_distill_v1_distill_proto protoreflect.FileDescriptor
+
+const file_distill_v1_distill_proto_rawDesc = "" +
+	"\n" +
+	"\x18distill/v1/distill.proto\x12\n" +
+	"distill.v1\x1a\x1cgoogle/protobuf/struct.proto\"\xfb\x01\n" +
+	"\x05Chunk\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x1c\n" +
+	"\tembedding\x18\x03 \x03(\x02R\tembedding\x12\x14\n" +
+	"\x05score\x18\x04 \x01(\x02R\x05score\x12\x1d\n" +
+	"\n" +
+	"cluster_id\x18\x05 \x01(\x05R\tclusterId\x123\n" +
+	"\bmetadata\x18\x06 \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12#\n" +
+	"\rcache_control\x18\a \x01(\tR\fcacheControl\x12!\n" +
+	"\falready_sent\x18\b \x01(\bR\valreadySent\"\xe9\x04\n" +
+	"\x12DeduplicateRequest\x12)\n" +
+	"\x06chunks\x18\x01 \x03(\v2\x11.distill.v1.ChunkR\x06chunks\x12\x1c\n" +
+	"\tthreshold\x18\x02 \x01(\x01R\tthreshold\x12\x16\n" +
+	"\x06lambda\x18\x03 \x01(\x01R\x06lambda\x12\x19\n" +
+	"\btarget_k\x18\x04 \x01(\x05R\atargetK\x12-\n" +
+	"\x12include_embeddings\x18\x05 \x01(\bR\x11includeEmbeddings\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x06 \x01(\tR\tsessionId\x122\n" +
+	"\x15preserve_cache_prefix\x18\a \x01(\bR\x13preserveCachePrefix\x12!\n" +
+	"\fmark_repeats\x18\b \x01(\bR\vmarkRepeats\x12/\n" +
+	"\x13validate_embeddings\x18\t \x01(\tR\x12validateEmbeddings\x12\x1f\n" +
+	"\vdedup_hints\x18\n" +
+	" \x01(\bR\n" +
+	"dedupHints\x12%\n" +
+	"\x0eembedding_dims\x18\v \x01(\x05R\rembeddingDims\x12/\n" +
+	"\x13embedding_reduction\x18\f \x01(\tR\x12embeddingReduction\x12!\n" +
+	"\ftoken_budget\x18\r \x01(\x05R\vtokenBudget\x12\x14\n" +
+	"\x05model\x18\x0e \x01(\tR\x05model\x127\n" +
+	"\bcompress\x18\x0f \x01(\v2\x1b.distill.v1.CompressOptionsR\bcompress\x12\x16\n" +
+	"\x06redact\x18\x10 \x01(\bR\x06redact\"t\n" +
+	"\x13DeduplicateResponse\x12)\n" +
+	"\x06chunks\x18\x01 \x03(\v2\x11.distill.v1.ChunkR\x06chunks\x122\n" +
+	"\x05stats\x18\x02 \x01(\v2\x1c.distill.v1.DeduplicateStatsR\x05stats\"\x9e\x05\n" +
+	"\x10DeduplicateStats\x12\x1f\n" +
+	"\vinput_count\x18\x01 \x01(\x05R\n" +
+	"inputCount\x12!\n" +
+	"\foutput_count\x18\x02 \x01(\x05R\voutputCount\x12#\n" +
+	"\rcluster_count\x18\x03 \x01(\x05R\fclusterCount\x12#\n" +
+	"\rreduction_pct\x18\x04 \x01(\x05R\freductionPct\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\x05 \x01(\x03R\tlatencyMs\x12.\n" +
+	"\x13cache_prefix_frozen\x18\x06 \x01(\bR\x11cachePrefixFrozen\x12.\n" +
+	"\x13cache_prefix_tokens\x18\a \x01(\x05R\x11cachePrefixTokens\x12*\n" +
+	"\x11cache_prefix_hash\x18\b \x01(\tR\x0fcachePrefixHash\x12,\n" +
+	"\x12suffix_input_count\x18\t \x01(\x05R\x10suffixInputCount\x12.\n" +
+	"\x13suffix_output_count\x18\n" +
+	" \x01(\x05R\x11suffixOutputCount\x12%\n" +
+	"\x0erepeated_count\x18\v \x01(\x05R\rrepeatedCount\x12/\n" +
+	"\x13embeddings_repaired\x18\f \x01(\x05R\x12embeddingsRepaired\x12'\n" +
+	"\x0fmatrix_overflow\x18\r \x01(\tR\x0ematrixOverflow\x12\x16\n" +
+	"\x06tokens\x18\x0e \x01(\x05R\x06tokens\x12>\n" +
+	"\vcompression\x18\x0f \x01(\v2\x1c.distill.v1.CompressionStatsR\vcompression\x12\x1a\n" +
+	"\bredacted\x18\x10 \x01(\x05R\bredacted\"\xf3\b\n" +
+	"\x0fRetrieveRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12'\n" +
+	"\x0fquery_embedding\x18\x02 \x03(\x02R\x0equeryEmbedding\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\x12\x13\n" +
+	"\x05top_k\x18\x04 \x01(\x05R\x04topK\x12\x1c\n" +
+	"\tthreshold\x18\x05 \x01(\x01R\tthreshold\x12\x16\n" +
+	"\x06lambda\x18\x06 \x01(\x01R\x06lambda\x12/\n" +
+	"\x06filter\x18\a \x01(\v2\x17.google.protobuf.StructR\x06filter\x12\x1b\n" +
+	"\tmin_score\x18\b \x01(\x02R\bminScore\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\t \x01(\tR\tsessionId\x12\x18\n" +
+	"\aexclude\x18\n" +
+	" \x03(\tR\aexclude\x12-\n" +
+	"\x12include_embeddings\x18\v \x01(\bR\x11includeEmbeddings\x12\x18\n" +
+	"\aexplain\x18\f \x01(\bR\aexplain\x12!\n" +
+	"\fmark_repeats\x18\r \x01(\bR\vmarkRepeats\x12\x1f\n" +
+	"\vdedup_hints\x18\x0e \x01(\bR\n" +
+	"dedupHints\x12\x19\n" +
+	"\btarget_k\x18\x0f \x01(\x05R\atargetK\x12%\n" +
+	"\x0eembedding_dims\x18\x10 \x01(\x05R\rembeddingDims\x12/\n" +
+	"\x13embedding_reduction\x18\x11 \x01(\tR\x12embeddingReduction\x120\n" +
+	"\bidentity\x18\x12 \x01(\v2\x14.distill.v1.IdentityR\bidentity\x12:\n" +
+	"\n" +
+	"namespaces\x18\x13 \x03(\v2\x1a.distill.v1.NamespaceQuotaR\n" +
+	"namespaces\x12\x18\n" +
+	"\aqueries\x18\x14 \x03(\tR\aqueries\x12@\n" +
+	"\x10query_embeddings\x18\x15 \x03(\v2\x15.distill.v1.EmbeddingR\x0fqueryEmbeddings\x12\x18\n" +
+	"\acombine\x18\x16 \x01(\tR\acombine\x12\x1a\n" +
+	"\btemplate\x18\x17 \x01(\tR\btemplate\x12/\n" +
+	"\x13validate_embeddings\x18\x18 \x01(\tR\x12validateEmbeddings\x120\n" +
+	"\x06enable\x18\x19 \x01(\v2\x18.distill.v1.StageTogglesR\x06enable\x12\x1c\n" +
+	"\tselection\x18\x1a \x01(\tR\tselection\x12\x14\n" +
+	"\x05model\x18\x1b \x01(\tR\x05model\x127\n" +
+	"\bcompress\x18\x1c \x01(\v2\x1b.distill.v1.CompressOptionsR\bcompress\x12\x12\n" +
+	"\x04mode\x18\x1d \x01(\tR\x04mode\x12\x16\n" +
+	"\x06redact\x18\x1e \x01(\bR\x06redact\x12\x1f\n" +
+	"\vdeadline_ms\x18\x1f \x01(\x05R\n" +
+	"deadlineMs\x12\x14\n" +
+	"\x05index\x18  \x01(\tR\x05index\"6\n" +
+	"\bIdentity\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x16\n" +
+	"\x06groups\x18\x02 \x03(\tR\x06groups\"9\n" +
+	"\x0eNamespaceQuota\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x13\n" +
+	"\x05top_k\x18\x02 \x01(\x05R\x04topK\"#\n" +
+	"\tEmbedding\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\x02R\x06values\"\xdc\x02\n" +
+	"\fStageToggles\x12#\n" +
+	"\n" +
+	"clustering\x18\x01 \x01(\bH\x00R\n" +
+	"clustering\x88\x01\x01\x12\x15\n" +
+	"\x03mmr\x18\x02 \x01(\bH\x01R\x03mmr\x88\x01\x01\x12%\n" +
+	"\vcompression\x18\x03 \x01(\bH\x02R\vcompression\x88\x01\x01\x12!\n" +
+	"\tredaction\x18\x04 \x01(\bH\x03R\tredaction\x88\x01\x01\x12\x1d\n" +
+	"\ascoring\x18\x05 \x01(\bH\x04R\ascoring\x88\x01\x01\x12\x1b\n" +
+	"\x06rerank\x18\x06 \x01(\bH\x05R\x06rerank\x88\x01\x01\x12+\n" +
+	"\x0eclassification\x18\a \x01(\bH\x06R\x0eclassification\x88\x01\x01B\r\n" +
+	"\v_clusteringB\x06\n" +
+	"\x04_mmrB\x0e\n" +
+	"\f_compressionB\f\n" +
+	"\n" +
+	"_redactionB\n" +
+	"\n" +
+	"\b_scoringB\t\n" +
+	"\a_rerankB\x11\n" +
+	"\x0f_classification\"\xbe\x01\n" +
+	"\x0fCompressOptions\x12\x12\n" +
+	"\x04mode\x18\x01 \x01(\tR\x04mode\x12)\n" +
+	"\x10target_reduction\x18\x02 \x01(\x01R\x0ftargetReduction\x122\n" +
+	"\x12preserve_structure\x18\x03 \x01(\bH\x00R\x11preserveStructure\x88\x01\x01\x12!\n" +
+	"\fmerge_tokens\x18\x04 \x01(\x05R\vmergeTokensB\x15\n" +
+	"\x13_preserve_structure\"\xc3\x01\n" +
+	"\x10RetrieveResponse\x12)\n" +
+	"\x06chunks\x18\x01 \x03(\v2\x11.distill.v1.ChunkR\x06chunks\x12/\n" +
+	"\x05stats\x18\x02 \x01(\v2\x19.distill.v1.RetrieveStatsR\x05stats\x12\x1a\n" +
+	"\brendered\x18\x03 \x01(\tR\brendered\x12\x1f\n" +
+	"\vfeedback_id\x18\x04 \x01(\tR\n" +
+	"feedbackId\x12\x16\n" +
+	"\x06stages\x18\x05 \x03(\tR\x06stages\"\xcf\x06\n" +
+	"\rRetrieveStats\x12\x1c\n" +
+	"\tretrieved\x18\x01 \x01(\x05R\tretrieved\x12\x1c\n" +
+	"\tclustered\x18\x02 \x01(\x05R\tclustered\x12\x1a\n" +
+	"\breturned\x18\x03 \x01(\x05R\breturned\x12\x1a\n" +
+	"\brepeated\x18\x04 \x01(\x05R\brepeated\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\x05 \x01(\x03R\tlatencyMs\x12\x1b\n" +
+	"\tcache_hit\x18\x06 \x01(\bR\bcacheHit\x12\x1a\n" +
+	"\bexcluded\x18\a \x01(\x05R\bexcluded\x12\x1d\n" +
+	"\n" +
+	"cache_miss\x18\b \x01(\bR\tcacheMiss\x12\x14\n" +
+	"\x05model\x18\t \x01(\tR\x05model\x12\x16\n" +
+	"\x06tokens\x18\n" +
+	" \x01(\x05R\x06tokens\x12\x19\n" +
+	"\bcost_usd\x18\v \x01(\x01R\acostUsd\x12\x1f\n" +
+	"\vbest_effort\x18\f \x01(\bR\n" +
+	"bestEffort\x12\x14\n" +
+	"\x05notes\x18\r \x03(\tR\x05notes\x12\x1d\n" +
+	"\n" +
+	"acl_denied\x18\x0e \x01(\x05R\taclDenied\x12#\n" +
+	"\racl_unlabeled\x18\x0f \x01(\x05R\faclUnlabeled\x12>\n" +
+	"\vcompression\x18\x10 \x01(\v2\x1c.distill.v1.CompressionStatsR\vcompression\x12\x1a\n" +
+	"\bredacted\x18\x11 \x01(\x05R\bredacted\x12%\n" +
+	"\x0ebudget_dropped\x18\x12 \x01(\x05R\rbudgetDropped\x12\x16\n" +
+	"\x06sparse\x18\x13 \x01(\bR\x06sparse\x12 \n" +
+	"\vpassthrough\x18\x14 \x01(\bR\vpassthrough\x12 \n" +
+	"\vsensitivity\x18\x15 \x01(\tR\vsensitivity\x12+\n" +
+	"\x11injection_flagged\x18\x16 \x01(\x05R\x10injectionFlagged\x12-\n" +
+	"\x12injection_stripped\x18\x17 \x01(\x05R\x11injectionStripped\x12+\n" +
+	"\x11injection_blocked\x18\x18 \x01(\x05R\x10injectionBlocked\x12'\n" +
+	"\x0fgarbage_dropped\x18\x19 \x01(\x05R\x0egarbageDropped\"\xf4\x01\n" +
+	"\x10CompressionStats\x12!\n" +
+	"\finput_tokens\x18\x01 \x01(\x05R\vinputTokens\x12#\n" +
+	"\routput_tokens\x18\x02 \x01(\x05R\foutputTokens\x12!\n" +
+	"\fsaved_tokens\x18\x03 \x01(\x05R\vsavedTokens\x12#\n" +
+	"\rreduction_pct\x18\x04 \x01(\x01R\freductionPct\x12+\n" +
+	"\x11chunks_compressed\x18\x05 \x01(\x05R\x10chunksCompressed\x12#\n" +
+	"\rchunks_merged\x18\x06 \x01(\x05R\fchunksMerged\"\x93\x01\n" +
+	"\x18AnalyzeRedundancyRequest\x12)\n" +
+	"\x06chunks\x18\x01 \x03(\v2\x11.distill.v1.ChunkR\x06chunks\x12\x1c\n" +
+	"\tthreshold\x18\x02 \x01(\x01R\tthreshold\x12\x1a\n" +
+	"\bclusters\x18\x03 \x01(\x05R\bclusters\x12\x12\n" +
+	"\x04seed\x18\x04 \x01(\x03R\x04seed\"\xfd\x01\n" +
+	"\x19AnalyzeRedundancyResponse\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x05R\x05total\x12\x16\n" +
+	"\x06unique\x18\x02 \x01(\x05R\x06unique\x12\x1e\n" +
+	"\n" +
+	"duplicates\x18\x03 \x01(\x05R\n" +
+	"duplicates\x12#\n" +
+	"\rcluster_count\x18\x04 \x01(\x05R\fclusterCount\x12\x1f\n" +
+	"\vsavings_pct\x18\x05 \x01(\x01R\n" +
+	"savingsPct\x12-\n" +
+	"\aremoved\x18\x06 \x03(\v2\x13.distill.v1.RemovalR\aremoved\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\a \x01(\x03R\tlatencyMs\"w\n" +
+	"\aRemoval\x12\x1d\n" +
+	"\n" +
+	"removed_id\x18\x01 \x01(\tR\tremovedId\x12\x17\n" +
+	"\akept_id\x18\x02 \x01(\tR\x06keptId\x12\x1a\n" +
+	"\bdistance\x18\x03 \x01(\x01R\bdistance\x12\x18\n" +
+	"\acluster\x18\x04 \x01(\x05R\acluster2\xc4\x03\n" +
+	"\aDistill\x12N\n" +
+	"\vDeduplicate\x12\x1e.distill.v1.DeduplicateRequest\x1a\x1f.distill.v1.DeduplicateResponse\x12V\n" +
+	"\x11DeduplicateStream\x12\x1e.distill.v1.DeduplicateRequest\x1a\x1f.distill.v1.DeduplicateResponse(\x01\x12E\n" +
+	"\bRetrieve\x12\x1b.distill.v1.RetrieveRequest\x1a\x1c.distill.v1.RetrieveResponse\x12`\n" +
+	"\x11AnalyzeRedundancy\x12$.distill.v1.AnalyzeRedundancyRequest\x1a%.distill.v1.AnalyzeRedundancyResponse\x12h\n" +
+	"\x17AnalyzeRedundancyStream\x12$.distill.v1.AnalyzeRedundancyRequest\x1a%.distill.v1.AnalyzeRedundancyResponse(\x01BDZBgithub.com/Siddhant-K-code/distill/pkg/grpcapi/distillv1;distillv1b\x06proto3"
+
+var (
+	file_distill_v1_distill_proto_rawDescOnce sync.Once
+	file_distill_v1_distill_proto_rawDescData []byte
+)
+
+func file_distill_v1_distill_proto_rawDescGZIP() []byte {
+	file_distill_v1_distill_proto_rawDescOnce.Do(func() {
+		file_distill_v1_distill_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_distill_v1_distill_proto_rawDesc), len(file_distill_v1_distill_proto_rawDesc)))
+	})
+	return file_distill_v1_distill_proto_rawDescData
+}
+
+var file_distill_v1_distill_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_distill_v1_distill_proto_goTypes = []any{
+	(*Chunk)(nil),                     // 0: distill.v1.Chunk
+	(*DeduplicateRequest)(nil),        // 1: distill.v1.DeduplicateRequest
+	(*DeduplicateResponse)(nil),       // 2: distill.v1.DeduplicateResponse
+	(*DeduplicateStats)(nil),          // 3: distill.v1.DeduplicateStats
+	(*RetrieveRequest)(nil),           // 4: distill.v1.RetrieveRequest
+	(*Identity)(nil),                  // 5: distill.v1.Identity
+	(*NamespaceQuota)(nil),            // 6: distill.v1.NamespaceQuota
+	(*Embedding)(nil),                 // 7: distill.v1.Embedding
+	(*StageToggles)(nil),              // 8: distill.v1.StageToggles
+	(*CompressOptions)(nil),           // 9: distill.v1.CompressOptions
+	(*RetrieveResponse)(nil),          // 10: distill.v1.RetrieveResponse
+	(*RetrieveStats)(nil),             // 11: distill.v1.RetrieveStats
+	(*CompressionStats)(nil),          // 12: distill.v1.CompressionStats
+	(*AnalyzeRedundancyRequest)(nil),  // 13: distill.v1.AnalyzeRedundancyRequest
+	(*AnalyzeRedundancyResponse)(nil), // 14: distill.v1.AnalyzeRedundancyResponse
+	(*Removal)(nil),                   // 15: distill.v1.Removal
+	(*structpb.Struct)(nil),           // 16: google.protobuf.Struct
+}
+var file_distill_v1_distill_proto_depIdxs = []int32{
+	16, // 0: distill.v1.Chunk.metadata:type_name -> google.protobuf.Struct
+	0,  // 1: distill.v1.DeduplicateRequest.chunks:type_name -> distill.v1.Chunk
+	9,  // 2: distill.v1.DeduplicateRequest.compress:type_name -> distill.v1.CompressOptions
+	0,  // 3: distill.v1.DeduplicateResponse.chunks:type_name -> distill.v1.Chunk
+	3,  // 4: distill.v1.DeduplicateResponse.stats:type_name -> distill.v1.DeduplicateStats
+	12, // 5: distill.v1.DeduplicateStats.compression:type_name -> distill.v1.CompressionStats
+	16, // 6: distill.v1.RetrieveRequest.filter:type_name -> google.protobuf.Struct
+	5,  // 7: distill.v1.RetrieveRequest.identity:type_name -> distill.v1.Identity
+	6,  // 8: distill.v1.RetrieveRequest.namespaces:type_name -> distill.v1.NamespaceQuota
+	7,  // 9: distill.v1.RetrieveRequest.query_embeddings:type_name -> distill.v1.Embedding
+	8,  // 10: distill.v1.RetrieveRequest.enable:type_name -> distill.v1.StageToggles
+	9,  // 11: distill.v1.RetrieveRequest.compress:type_name -> distill.v1.CompressOptions
+	0,  // 12: distill.v1.RetrieveResponse.chunks:type_name -> distill.v1.Chunk
+	11, // 13: distill.v1.RetrieveResponse.stats:type_name -> distill.v1.RetrieveStats
+	12, // 14: distill.v1.RetrieveStats.compression:type_name -> distill.v1.CompressionStats
+	0,  // 15: distill.v1.AnalyzeRedundancyRequest.chunks:type_name -> distill.v1.Chunk
+	15, // 16: distill.v1.AnalyzeRedundancyResponse.removed:type_name -> distill.v1.Removal
+	1,  // 17: distill.v1.Distill.Deduplicate:input_type -> distill.v1.DeduplicateRequest
+	1,  // 18: distill.v1.Distill.DeduplicateStream:input_type -> distill.v1.DeduplicateRequest
+	4,  // 19: distill.v1.Distill.Retrieve:input_type -> distill.v1.RetrieveRequest
+	13, // 20: distill.v1.Distill.AnalyzeRedundancy:input_type -> distill.v1.AnalyzeRedundancyRequest
+	13, // 21: distill.v1.Distill.AnalyzeRedundancyStream:input_type -> distill.v1.AnalyzeRedundancyRequest
+	2,  // 22: distill.v1.Distill.Deduplicate:output_type -> distill.v1.DeduplicateResponse
+	2,  // 23: distill.v1.Distill.DeduplicateStream:output_type -> distill.v1.DeduplicateResponse
+	10, // 24: distill.v1.Distill.Retrieve:output_type -> distill.v1.RetrieveResponse
+	14, // 25: distill.v1.Distill.AnalyzeRedundancy:output_type -> distill.v1.AnalyzeRedundancyResponse
+	14, // 26: distill.v1.Distill.AnalyzeRedundancyStream:output_type -> distill.v1.AnalyzeRedundancyResponse
+	22, // [22:27] is the sub-list for method output_type
+	17, // [17:22] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
+}
+
+func init() { file_distill_v1_distill_proto_init() }
+func file_distill_v1_distill_proto_init() {
+	if File_distill_v1_distill_proto != nil {
+		return
+	}
+	file_distill_v1_distill_proto_msgTypes[8].OneofWrappers = []any{}
+	file_distill_v1_distill_proto_msgTypes[9].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_distill_v1_distill_proto_rawDesc), len(file_distill_v1_distill_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_distill_v1_distill_proto_goTypes,
+		DependencyIndexes: file_distill_v1_distill_proto_depIdxs,
+		MessageInfos:      file_distill_v1_distill_proto_msgTypes,
+	}.Build()
+	File_distill_v1_distill_proto = out.File
+	file_distill_v1_distill_proto_goTypes = nil
+	file_distill_v1_distill_proto_depIdxs = nil
+}
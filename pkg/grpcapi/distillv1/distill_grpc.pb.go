@@ -0,0 +1,290 @@
+// Distill's gRPC API: the dedupe, retrieve, and redundancy analysis
+// pipelines of distill serve, with embeddings sent as packed floats
+// instead of JSON. Regenerate the Go code with make proto.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: distill/v1/distill.proto
+
+package distillv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Distill_Deduplicate_FullMethodName             = "/distill.v1.Distill/Deduplicate"
+	Distill_DeduplicateStream_FullMethodName       = "/distill.v1.Distill/DeduplicateStream"
+	Distill_Retrieve_FullMethodName                = "/distill.v1.Distill/Retrieve"
+	Distill_AnalyzeRedundancy_FullMethodName       = "/distill.v1.Distill/AnalyzeRedundancy"
+	Distill_AnalyzeRedundancyStream_FullMethodName = "/distill.v1.Distill/AnalyzeRedundancyStream"
+)
+
+// DistillClient is the client API for Distill service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Distill deduplicates chunks and retrieves deduplicated context.
+type DistillClient interface {
+	// Deduplicate clusters chunks, keeps one representative per cluster,
+	// and re-ranks down to target_k.
+	Deduplicate(ctx context.Context, in *DeduplicateRequest, opts ...grpc.CallOption) (*DeduplicateResponse, error)
+	// DeduplicateStream is Deduplicate for chunk sets too large for one
+	// message. Options are read from the first message; the chunks of
+	// every message are deduplicated together once the client closes the
+	// stream.
+	DeduplicateStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[DeduplicateRequest, DeduplicateResponse], error)
+	// Retrieve queries the vector database and deduplicates the matches.
+	Retrieve(ctx context.Context, in *RetrieveRequest, opts ...grpc.CallOption) (*RetrieveResponse, error)
+	// AnalyzeRedundancy reports how many chunks duplicate another,
+	// without choosing which to return.
+	AnalyzeRedundancy(ctx context.Context, in *AnalyzeRedundancyRequest, opts ...grpc.CallOption) (*AnalyzeRedundancyResponse, error)
+	// AnalyzeRedundancyStream is AnalyzeRedundancy for chunk sets too
+	// large for one message, read the same way as DeduplicateStream.
+	AnalyzeRedundancyStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[AnalyzeRedundancyRequest, AnalyzeRedundancyResponse], error)
+}
+
+type distillClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDistillClient(cc grpc.ClientConnInterface) DistillClient {
+	return &distillClient{cc}
+}
+
+func (c *distillClient) Deduplicate(ctx context.Context, in *DeduplicateRequest, opts ...grpc.CallOption) (*DeduplicateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeduplicateResponse)
+	err := c.cc.Invoke(ctx, Distill_Deduplicate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *distillClient) DeduplicateStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[DeduplicateRequest, DeduplicateResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Distill_ServiceDesc.Streams[0], Distill_DeduplicateStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DeduplicateRequest, DeduplicateResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Distill_DeduplicateStreamClient = grpc.ClientStreamingClient[DeduplicateRequest, DeduplicateResponse]
+
+func (c *distillClient) Retrieve(ctx context.Context, in *RetrieveRequest, opts ...grpc.CallOption) (*RetrieveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RetrieveResponse)
+	err := c.cc.Invoke(ctx, Distill_Retrieve_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *distillClient) AnalyzeRedundancy(ctx context.Context, in *AnalyzeRedundancyRequest, opts ...grpc.CallOption) (*AnalyzeRedundancyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AnalyzeRedundancyResponse)
+	err := c.cc.Invoke(ctx, Distill_AnalyzeRedundancy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *distillClient) AnalyzeRedundancyStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[AnalyzeRedundancyRequest, AnalyzeRedundancyResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Distill_ServiceDesc.Streams[1], Distill_AnalyzeRedundancyStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AnalyzeRedundancyRequest, AnalyzeRedundancyResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Distill_AnalyzeRedundancyStreamClient = grpc.ClientStreamingClient[AnalyzeRedundancyRequest, AnalyzeRedundancyResponse]
+
+// DistillServer is the server API for Distill service.
+// All implementations must embed UnimplementedDistillServer
+// for forward compatibility.
+//
+// Distill deduplicates chunks and retrieves deduplicated context.
+type DistillServer interface {
+	// Deduplicate clusters chunks, keeps one representative per cluster,
+	// and re-ranks down to target_k.
+	Deduplicate(context.Context, *DeduplicateRequest) (*DeduplicateResponse, error)
+	// DeduplicateStream is Deduplicate for chunk sets too large for one
+	// message. Options are read from the first message; the chunks of
+	// every message are deduplicated together once the client closes the
+	// stream.
+	DeduplicateStream(grpc.ClientStreamingServer[DeduplicateRequest, DeduplicateResponse]) error
+	// Retrieve queries the vector database and deduplicates the matches.
+	Retrieve(context.Context, *RetrieveRequest) (*RetrieveResponse, error)
+	// AnalyzeRedundancy reports how many chunks duplicate another,
+	// without choosing which to return.
+	AnalyzeRedundancy(context.Context, *AnalyzeRedundancyRequest) (*AnalyzeRedundancyResponse, error)
+	// AnalyzeRedundancyStream is AnalyzeRedundancy for chunk sets too
+	// large for one message, read the same way as DeduplicateStream.
+	AnalyzeRedundancyStream(grpc.ClientStreamingServer[AnalyzeRedundancyRequest, AnalyzeRedundancyResponse]) error
+	mustEmbedUnimplementedDistillServer()
+}
+
+// UnimplementedDistillServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDistillServer struct{}
+
+func (UnimplementedDistillServer) Deduplicate(context.Context, *DeduplicateRequest) (*DeduplicateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deduplicate not implemented")
+}
+func (UnimplementedDistillServer) DeduplicateStream(grpc.ClientStreamingServer[DeduplicateRequest, DeduplicateResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method DeduplicateStream not implemented")
+}
+func (UnimplementedDistillServer) Retrieve(context.Context, *RetrieveRequest) (*RetrieveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Retrieve not implemented")
+}
+func (UnimplementedDistillServer) AnalyzeRedundancy(context.Context, *AnalyzeRedundancyRequest) (*AnalyzeRedundancyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnalyzeRedundancy not implemented")
+}
+func (UnimplementedDistillServer) AnalyzeRedundancyStream(grpc.ClientStreamingServer[AnalyzeRedundancyRequest, AnalyzeRedundancyResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method AnalyzeRedundancyStream not implemented")
+}
+func (UnimplementedDistillServer) mustEmbedUnimplementedDistillServer() {}
+func (UnimplementedDistillServer) testEmbeddedByValue()                 {}
+
+// UnsafeDistillServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DistillServer will
+// result in compilation errors.
+type UnsafeDistillServer interface {
+	mustEmbedUnimplementedDistillServer()
+}
+
+func RegisterDistillServer(s grpc.ServiceRegistrar, srv DistillServer) {
+	// If the following call pancis, it indicates UnimplementedDistillServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Distill_ServiceDesc, srv)
+}
+
+func _Distill_Deduplicate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeduplicateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DistillServer).Deduplicate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Distill_Deduplicate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DistillServer).Deduplicate(ctx, req.(*DeduplicateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Distill_DeduplicateStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DistillServer).DeduplicateStream(&grpc.GenericServerStream[DeduplicateRequest, DeduplicateResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Distill_DeduplicateStreamServer = grpc.ClientStreamingServer[DeduplicateRequest, DeduplicateResponse]
+
+func _Distill_Retrieve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RetrieveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DistillServer).Retrieve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Distill_Retrieve_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DistillServer).Retrieve(ctx, req.(*RetrieveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Distill_AnalyzeRedundancy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnalyzeRedundancyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DistillServer).AnalyzeRedundancy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Distill_AnalyzeRedundancy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DistillServer).AnalyzeRedundancy(ctx, req.(*AnalyzeRedundancyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Distill_AnalyzeRedundancyStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DistillServer).AnalyzeRedundancyStream(&grpc.GenericServerStream[AnalyzeRedundancyRequest, AnalyzeRedundancyResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Distill_AnalyzeRedundancyStreamServer = grpc.ClientStreamingServer[AnalyzeRedundancyRequest, AnalyzeRedundancyResponse]
+
+// Distill_ServiceDesc is the grpc.ServiceDesc for Distill service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Distill_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "distill.v1.Distill",
+	HandlerType: (*DistillServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Deduplicate",
+			Handler:    _Distill_Deduplicate_Handler,
+		},
+		{
+			MethodName: "Retrieve",
+			Handler:    _Distill_Retrieve_Handler,
+		},
+		{
+			MethodName: "AnalyzeRedundancy",
+			Handler:    _Distill_AnalyzeRedundancy_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "DeduplicateStream",
+			Handler:       _Distill_DeduplicateStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "AnalyzeRedundancyStream",
+			Handler:       _Distill_AnalyzeRedundancyStream_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "distill/v1/distill.proto",
+}
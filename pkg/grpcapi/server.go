@@ -0,0 +1,551 @@
+// Package grpcapi serves Distill's gRPC API, defined in
+// proto/distill/v1/distill.proto, for clients that would rather not
+// send embeddings as JSON. Deduplicate runs the same pipeline as the
+// pkg/distill library, Retrieve goes through a contextlab.Broker, and
+// AnalyzeRedundancy runs the k-means engine behind distill analyze.
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	distillcache "github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/dedup"
+	"github.com/Siddhant-K-code/distill/pkg/distill"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/grpcapi/distillv1"
+	"github.com/Siddhant-K-code/distill/pkg/metrics"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DefaultAnalyzeThreshold is AnalyzeRedundancy's duplicate distance when
+// a request sets none, the same as distill analyze's.
+const DefaultAnalyzeThreshold = 0.05
+
+// dedupeHTTPOnly and retrieveHTTPOnly are the request fields only the
+// HTTP API honors. Deduplicate and Retrieve reject requests that set
+// them rather than drop them: a client asking for redact would otherwise
+// get unredacted text back with an OK status.
+var (
+	dedupeHTTPOnly = []protoreflect.Name{
+		"session_id", "preserve_cache_prefix", "mark_repeats", "validate_embeddings",
+		"dedup_hints", "embedding_dims", "embedding_reduction", "token_budget",
+		"model", "compress", "redact",
+	}
+	retrieveHTTPOnly = []protoreflect.Name{
+		"target_k", "embedding_dims", "embedding_reduction", "namespaces",
+		"queries", "query_embeddings", "combine", "template", "validate_embeddings",
+		"enable", "selection", "model", "compress", "mode", "redact",
+		"deadline_ms", "index",
+	}
+)
+
+// Config configures a Server.
+type Config struct {
+	// Broker serves Retrieve. Without one Retrieve returns
+	// FailedPrecondition.
+	Broker *contextlab.Broker
+
+	// Embedder embeds chunks sent without embeddings.
+	Embedder retriever.EmbeddingProvider
+
+	// Limits caps the chunks of one request, counted over all messages
+	// of a stream.
+	Limits contextlab.Limits
+
+	// Metrics, when set, records each RPC like an HTTP endpoint named
+	// after the full method, e.g. /distill.v1.Distill/Deduplicate.
+	Metrics *metrics.Metrics
+}
+
+// Server implements distillv1.DistillServer.
+type Server struct {
+	distillv1.UnimplementedDistillServer
+	cfg Config
+}
+
+// NewServer creates a Server from cfg.
+func NewServer(cfg Config) *Server {
+	return &Server{cfg: cfg}
+}
+
+// ServerOptions returns the options that record s's RPCs in
+// Config.Metrics, for grpc.NewServer.
+func (s *Server) ServerOptions() []grpc.ServerOption {
+	if s.cfg.Metrics == nil {
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			start := time.Now()
+			resp, err := handler(ctx, req)
+			s.cfg.Metrics.RecordRequest(info.FullMethod, httpStatus(err), time.Since(start))
+			return resp, err
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			start := time.Now()
+			err := handler(srv, ss)
+			s.cfg.Metrics.RecordRequest(info.FullMethod, httpStatus(err), time.Since(start))
+			return err
+		}),
+	}
+}
+
+// Deduplicate clusters the request's chunks and keeps one representative
+// per cluster, re-ranked down to target_k.
+func (s *Server) Deduplicate(ctx context.Context, req *distillv1.DeduplicateRequest) (*distillv1.DeduplicateResponse, error) {
+	if err := checkHTTPOnly(req, "POST /v1/dedupe", dedupeHTTPOnly); err != nil {
+		return nil, err
+	}
+	var in chunkReader
+	if err := in.add(s.cfg.Limits, req.GetChunks()); err != nil {
+		return nil, s.statusError(distillv1.Distill_Deduplicate_FullMethodName, err)
+	}
+	return s.deduplicate(ctx, distillv1.Distill_Deduplicate_FullMethodName, req, in.chunks)
+}
+
+// DeduplicateStream deduplicates the chunks of every message together,
+// with the first message's options.
+func (s *Server) DeduplicateStream(stream grpc.ClientStreamingServer[distillv1.DeduplicateRequest, distillv1.DeduplicateResponse]) error {
+	const method = distillv1.Distill_DeduplicateStream_FullMethodName
+	var first *distillv1.DeduplicateRequest
+	var in chunkReader
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkHTTPOnly(msg, "POST /v1/dedupe", dedupeHTTPOnly); err != nil {
+			return err
+		}
+		if first == nil {
+			first = msg
+		}
+		if err := in.add(s.cfg.Limits, msg.GetChunks()); err != nil {
+			return s.statusError(method, err)
+		}
+	}
+	if first == nil {
+		first = &distillv1.DeduplicateRequest{}
+	}
+	resp, err := s.deduplicate(stream.Context(), method, first, in.chunks)
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(resp)
+}
+
+func (s *Server) deduplicate(ctx context.Context, method string, req *distillv1.DeduplicateRequest, chunks []types.Chunk) (*distillv1.DeduplicateResponse, error) {
+	if len(chunks) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one chunk is required")
+	}
+	start := time.Now()
+	res, err := distill.New(distill.Config{
+		Threshold: req.GetThreshold(),
+		Lambda:    req.GetLambda(),
+		TargetK:   int(req.GetTargetK()),
+		Embedder:  s.cfg.Embedder,
+	}).Dedupe(ctx, chunks)
+	if err != nil {
+		return nil, s.statusError(method, err)
+	}
+	if s.cfg.Metrics != nil {
+		s.cfg.Metrics.RecordDedup(method, res.Stats.InputCount, res.Stats.OutputCount, res.Stats.ClusterCount)
+	}
+
+	out, err := toProto(res.Chunks, req.GetIncludeEmbeddings())
+	if err != nil {
+		return nil, s.statusError(method, err)
+	}
+	reduction := 0
+	if n := res.Stats.InputCount; n > 0 {
+		reduction = 100 * (n - res.Stats.OutputCount) / n
+	}
+	return &distillv1.DeduplicateResponse{
+		Chunks: out,
+		Stats: &distillv1.DeduplicateStats{
+			InputCount:   int32(res.Stats.InputCount),
+			OutputCount:  int32(res.Stats.OutputCount),
+			ClusterCount: int32(res.Stats.ClusterCount),
+			ReductionPct: int32(reduction),
+			LatencyMs:    time.Since(start).Milliseconds(),
+		},
+	}, nil
+}
+
+// Retrieve queries the broker's vector database and deduplicates the
+// matches.
+func (s *Server) Retrieve(ctx context.Context, req *distillv1.RetrieveRequest) (*distillv1.RetrieveResponse, error) {
+	const method = distillv1.Distill_Retrieve_FullMethodName
+	if s.cfg.Broker == nil {
+		return nil, status.Error(codes.FailedPrecondition, "retrieve needs a vector database backend")
+	}
+	if err := checkHTTPOnly(req, "POST /v1/retrieve", retrieveHTTPOnly); err != nil {
+		return nil, err
+	}
+	if req.GetQuery() == "" && len(req.GetQueryEmbedding()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "one of query and query_embedding is required")
+	}
+	if req.GetMinScore() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "min_score must be non-negative")
+	}
+	if req.GetTopK() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "top_k must be non-negative")
+	}
+	if limit := s.cfg.Limits.MaxChunks; limit > 0 && int(req.GetTopK()) > limit {
+		return nil, s.statusError(method, &contextlab.LimitError{
+			Limit: contextlab.LimitMaxChunks,
+			Value: int64(req.GetTopK()),
+			Max:   int64(limit),
+		})
+	}
+	if limit := s.cfg.Limits.MaxDimension; limit > 0 && len(req.GetQueryEmbedding()) > limit {
+		return nil, s.statusError(method, &contextlab.LimitError{
+			Limit: contextlab.LimitMaxDimension,
+			Value: int64(len(req.GetQueryEmbedding())),
+			Max:   int64(limit),
+		})
+	}
+
+	var filter map[string]interface{}
+	if req.GetFilter() != nil {
+		filter = req.GetFilter().AsMap()
+	}
+	var identity *types.Identity
+	if id := req.GetIdentity(); id != nil {
+		identity = &types.Identity{User: id.GetUser(), Groups: id.GetGroups()}
+	}
+	result, err := s.cfg.Broker.Retrieve(ctx, &types.RetrievalRequest{
+		Query:          req.GetQuery(),
+		QueryEmbedding: req.GetQueryEmbedding(),
+		Namespace:      req.GetNamespace(),
+		TopK:           int(req.GetTopK()),
+		Threshold:      req.GetThreshold(),
+		Lambda:         req.GetLambda(),
+		Filter:         filter,
+		MinScore:       req.GetMinScore(),
+		SessionID:      req.GetSessionId(),
+		Exclude:        req.GetExclude(),
+		MarkRepeats:    req.GetMarkRepeats(),
+		DedupHints:     req.GetDedupHints(),
+		Explain:        req.GetExplain(),
+		Identity:       identity,
+	})
+	if err != nil {
+		return nil, s.statusError(method, err)
+	}
+	if s.cfg.Metrics != nil {
+		s.cfg.Metrics.RecordDedup(method, result.Stats.Retrieved, result.Stats.Returned, result.Stats.Clustered)
+	}
+
+	out, err := toProto(result.Chunks, req.GetIncludeEmbeddings())
+	if err != nil {
+		return nil, s.statusError(method, err)
+	}
+	return &distillv1.RetrieveResponse{
+		Chunks: out,
+		Stats: &distillv1.RetrieveStats{
+			Retrieved:  int32(result.Stats.Retrieved),
+			Clustered:  int32(result.Stats.Clustered),
+			Returned:   int32(result.Stats.Returned),
+			Repeated:   int32(result.Stats.Repeated),
+			LatencyMs:  result.Stats.TotalLatency.Milliseconds(),
+			CacheHit:   result.Stats.CacheHit,
+			Excluded:   int32(result.Stats.Excluded),
+			CacheMiss:  result.Stats.CacheMiss,
+			Model:      result.Stats.Model,
+			Tokens:     int32(result.Stats.Tokens),
+			CostUsd:    result.Stats.CostUSD,
+			BestEffort: result.Stats.BestEffort,
+			Notes:      result.Stats.Notes,
+
+			AclDenied:         int32(result.Stats.ACLDenied),
+			AclUnlabeled:      int32(result.Stats.ACLUnlabeled),
+			Redacted:          int32(result.Stats.Redacted),
+			BudgetDropped:     int32(result.Stats.BudgetDropped),
+			Sparse:            result.Stats.Sparse,
+			Passthrough:       result.Stats.Passthrough,
+			Sensitivity:       result.Stats.Sensitivity,
+			InjectionFlagged:  int32(result.Stats.InjectionFlagged),
+			InjectionStripped: int32(result.Stats.InjectionStripped),
+			InjectionBlocked:  int32(result.Stats.InjectionBlocked),
+			GarbageDropped:    int32(result.Stats.GarbageDropped),
+		},
+	}, nil
+}
+
+// checkHTTPOnly returns InvalidArgument naming the fields of msg, among
+// names, that are set.
+func checkHTTPOnly(msg proto.Message, endpoint string, names []protoreflect.Name) error {
+	m := msg.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	var set []string
+	for _, name := range names {
+		if m.Has(fields.ByName(name)) {
+			set = append(set, string(name))
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	return status.Errorf(codes.InvalidArgument, "%s: honored by %s only", strings.Join(set, ", "), endpoint)
+}
+
+// AnalyzeRedundancy reports the request's duplicate chunks.
+func (s *Server) AnalyzeRedundancy(ctx context.Context, req *distillv1.AnalyzeRedundancyRequest) (*distillv1.AnalyzeRedundancyResponse, error) {
+	var in chunkReader
+	if err := in.add(s.cfg.Limits, req.GetChunks()); err != nil {
+		return nil, s.statusError(distillv1.Distill_AnalyzeRedundancy_FullMethodName, err)
+	}
+	return s.analyze(ctx, distillv1.Distill_AnalyzeRedundancy_FullMethodName, req, in.chunks)
+}
+
+// AnalyzeRedundancyStream analyzes the chunks of every message together,
+// with the first message's options.
+func (s *Server) AnalyzeRedundancyStream(stream grpc.ClientStreamingServer[distillv1.AnalyzeRedundancyRequest, distillv1.AnalyzeRedundancyResponse]) error {
+	const method = distillv1.Distill_AnalyzeRedundancyStream_FullMethodName
+	var first *distillv1.AnalyzeRedundancyRequest
+	var in chunkReader
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkHTTPOnly(msg, "POST /v1/dedupe", dedupeHTTPOnly); err != nil {
+			return err
+		}
+		if first == nil {
+			first = msg
+		}
+		if err := in.add(s.cfg.Limits, msg.GetChunks()); err != nil {
+			return s.statusError(method, err)
+		}
+	}
+	if first == nil {
+		first = &distillv1.AnalyzeRedundancyRequest{}
+	}
+	resp, err := s.analyze(stream.Context(), method, first, in.chunks)
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(resp)
+}
+
+func (s *Server) analyze(ctx context.Context, method string, req *distillv1.AnalyzeRedundancyRequest, chunks []types.Chunk) (*distillv1.AnalyzeRedundancyResponse, error) {
+	if len(chunks) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one chunk is required")
+	}
+	if err := s.embedMissing(ctx, chunks); err != nil {
+		return nil, s.statusError(method, err)
+	}
+	vectors := make([]types.Vector, len(chunks))
+	for i, c := range chunks {
+		if len(c.Embedding) != len(chunks[0].Embedding) {
+			return nil, status.Errorf(codes.InvalidArgument, "chunk %q has %d dimensions, chunk %q has %d",
+				c.ID, len(c.Embedding), chunks[0].ID, len(chunks[0].Embedding))
+		}
+		vectors[i] = types.Vector{ID: c.ID, Values: c.Embedding}
+	}
+
+	threshold := req.GetThreshold()
+	if threshold <= 0 {
+		threshold = DefaultAnalyzeThreshold
+	}
+	result, err := dedup.NewEngine(dedup.Config{
+		Threshold: threshold,
+		K:         int(req.GetClusters()),
+		Seed:      req.GetSeed(),
+	}).Deduplicate(ctx, vectors)
+	if err != nil {
+		return nil, s.statusError(method, err)
+	}
+
+	removed := make([]*distillv1.Removal, len(result.Removed))
+	for i, r := range result.Removed {
+		removed[i] = &distillv1.Removal{
+			RemovedId: r.RemovedID,
+			KeptId:    r.KeptID,
+			Distance:  r.Distance,
+			Cluster:   int32(r.Cluster),
+		}
+	}
+	return &distillv1.AnalyzeRedundancyResponse{
+		Total:        int32(result.TotalProcessed),
+		Unique:       int32(len(result.UniqueVectors)),
+		Duplicates:   int32(result.DuplicateCount),
+		ClusterCount: int32(result.ClusterCount),
+		SavingsPct:   math.Round(result.SavingsPercent()*10) / 10,
+		Removed:      removed,
+		LatencyMs:    result.ProcessingTimeMs,
+	}, nil
+}
+
+// embedMissing embeds the chunks sent without an embedding.
+func (s *Server) embedMissing(ctx context.Context, chunks []types.Chunk) error {
+	var idx []int
+	var texts []string
+	for i, c := range chunks {
+		if len(c.Embedding) == 0 {
+			idx = append(idx, i)
+			texts = append(texts, c.Text)
+		}
+	}
+	if len(idx) == 0 {
+		return nil
+	}
+	if s.cfg.Embedder == nil {
+		return distill.ErrNoEmbedder
+	}
+	embeddings, err := s.cfg.Embedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	if len(embeddings) != len(idx) {
+		return fmt.Errorf("embedder returned %d embeddings for %d chunks", len(embeddings), len(idx))
+	}
+	for j, i := range idx {
+		chunks[i].Embedding = embeddings[j]
+	}
+	return nil
+}
+
+// chunkReader collects the chunks of a request or stream, enforcing
+// limits on the running total.
+type chunkReader struct {
+	chunks []types.Chunk
+	bytes  int64
+}
+
+func (r *chunkReader) add(limits contextlab.Limits, chunks []*distillv1.Chunk) error {
+	for _, c := range chunks {
+		chunk := types.Chunk{
+			ID:        c.GetId(),
+			Text:      c.GetText(),
+			Embedding: c.GetEmbedding(),
+			Score:     c.GetScore(),
+			Metadata:  c.GetMetadata().AsMap(),
+		}
+		if limits.MaxDimension > 0 && len(chunk.Embedding) > limits.MaxDimension {
+			return &contextlab.LimitError{Limit: contextlab.LimitMaxDimension, Value: int64(len(chunk.Embedding)), Max: int64(limits.MaxDimension)}
+		}
+		r.chunks = append(r.chunks, chunk)
+		r.bytes += contextlab.InputBytes(chunk)
+	}
+	if limits.MaxChunks > 0 && len(r.chunks) > limits.MaxChunks {
+		return &contextlab.LimitError{Limit: contextlab.LimitMaxChunks, Value: int64(len(r.chunks)), Max: int64(limits.MaxChunks)}
+	}
+	if limits.MaxInputBytes > 0 && r.bytes > limits.MaxInputBytes {
+		return &contextlab.LimitError{Limit: contextlab.LimitMaxInputBytes, Value: r.bytes, Max: limits.MaxInputBytes}
+	}
+	return nil
+}
+
+// toProto converts chunks for a response, leaving out embeddings unless
+// withEmbeddings is set.
+func toProto(chunks []types.Chunk, withEmbeddings bool) ([]*distillv1.Chunk, error) {
+	out := make([]*distillv1.Chunk, len(chunks))
+	for i, c := range chunks {
+		metadata, err := MetadataStruct(c.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %s metadata: %w", c.ID, err)
+		}
+		out[i] = &distillv1.Chunk{
+			Id:          c.ID,
+			Text:        c.Text,
+			Score:       c.Score,
+			ClusterId:   int32(c.ClusterID),
+			AlreadySent: distillcache.IsAlreadySent(c),
+			Metadata:    metadata,
+		}
+		if withEmbeddings {
+			out[i].Embedding = c.Embedding
+		}
+	}
+	return out, nil
+}
+
+// MetadataStruct converts chunk metadata to a Struct. Values structpb
+// does not take directly, such as the []string of explain transforms, go
+// through their JSON form.
+func MetadataStruct(m map[string]interface{}) (*structpb.Struct, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	if s, err := structpb.NewStruct(m); err == nil {
+		return s, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var generic map[string]interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(generic)
+}
+
+// statusError converts a pipeline error to a gRPC status, recording
+// limit rejections for method.
+func (s *Server) statusError(method string, err error) error {
+	var limErr *contextlab.LimitError
+	switch {
+	case errors.As(err, &limErr):
+		if s.cfg.Metrics != nil {
+			s.cfg.Metrics.RecordRejected(method, limErr.Limit)
+		}
+		return status.Error(codes.ResourceExhausted, limErr.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, distill.ErrNoEmbedder):
+		return status.Error(codes.FailedPrecondition, "chunks without embeddings need an embedding provider configured on the server")
+	case errors.Is(err, contextlab.ErrNamespaceDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, errs.ErrConfig):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// httpStatus is the HTTP status code err is recorded under in metrics.
+func httpStatus(err error) int {
+	switch status.Code(err) {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusRequestEntityTooLarge
+	case codes.Canceled:
+		// nginx's client-closed-request
+		return 499
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,302 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/grpcapi/distillv1"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// letterEmbedder embeds text by its first byte, so texts sharing a first
+// letter are identical and others are orthogonal.
+type letterEmbedder struct{}
+
+func (letterEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	v := make([]float32, 26)
+	if text != "" {
+		v[(text[0]-'a')%26] = 1
+	}
+	return v, nil
+}
+
+func (e letterEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i], _ = e.Embed(ctx, t)
+	}
+	return out, nil
+}
+
+func (letterEmbedder) Dimension() int    { return 26 }
+func (letterEmbedder) ModelName() string { return "letters" }
+
+type stubRetriever struct {
+	chunks []types.Chunk
+	topK   int
+}
+
+func (r *stubRetriever) Query(ctx context.Context, req *types.RetrievalRequest) (*types.RetrievalResult, error) {
+	if req != nil {
+		r.topK = req.TopK
+	}
+	out := make([]types.Chunk, len(r.chunks))
+	copy(out, r.chunks)
+	return &types.RetrievalResult{Chunks: out}, nil
+}
+
+func (r *stubRetriever) QueryByID(ctx context.Context, id string, topK int, namespace string) (*types.RetrievalResult, error) {
+	return r.Query(ctx, nil)
+}
+
+func (r *stubRetriever) Close() error { return nil }
+
+// dial serves srv over an in-memory listener and returns a client.
+func dial(t *testing.T, srv *Server) distillv1.DistillClient {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	g := grpc.NewServer(srv.ServerOptions()...)
+	distillv1.RegisterDistillServer(g, srv)
+	go func() { _ = g.Serve(ln) }()
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return distillv1.NewDistillClient(conn)
+}
+
+func textChunks(texts ...string) []*distillv1.Chunk {
+	out := make([]*distillv1.Chunk, len(texts))
+	for i, text := range texts {
+		out[i] = &distillv1.Chunk{Id: text, Text: text, Score: float32(len(texts) - i)}
+	}
+	return out
+}
+
+func TestDeduplicate(t *testing.T) {
+	client := dial(t, NewServer(Config{Embedder: letterEmbedder{}}))
+	ctx := context.Background()
+
+	resp, err := client.Deduplicate(ctx, &distillv1.DeduplicateRequest{
+		Chunks:            textChunks("apple", "avocado", "banana", "cherry"),
+		IncludeEmbeddings: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := resp.GetStats()
+	if st.GetInputCount() != 4 || st.GetOutputCount() != 3 || st.GetClusterCount() != 3 || st.GetReductionPct() != 25 {
+		t.Errorf("stats = %v", st)
+	}
+	for _, c := range resp.GetChunks() {
+		if c.GetId() == "avocado" {
+			t.Error("kept the lower-scored duplicate")
+		}
+		if len(c.GetEmbedding()) != 26 {
+			t.Errorf("%s: embedding has %d dimensions, want 26", c.GetId(), len(c.GetEmbedding()))
+		}
+	}
+
+	_, err = dial(t, NewServer(Config{})).Deduplicate(ctx, &distillv1.DeduplicateRequest{Chunks: textChunks("apple")})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("without an embedder: %v, want FailedPrecondition", err)
+	}
+}
+
+func TestDeduplicateStream(t *testing.T) {
+	client := dial(t, NewServer(Config{
+		Embedder: letterEmbedder{},
+		Limits:   contextlab.Limits{MaxChunks: 4},
+	}))
+	ctx := context.Background()
+
+	stream, err := client.DeduplicateStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Options come from the first message only
+	msgs := []*distillv1.DeduplicateRequest{
+		{Chunks: textChunks("apple", "banana"), TargetK: 1},
+		{Chunks: textChunks("avocado", "cherry"), TargetK: 10},
+	}
+	for _, m := range msgs {
+		if err := stream.Send(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := resp.GetStats(); st.GetInputCount() != 4 || st.GetClusterCount() != 3 || len(resp.GetChunks()) != 1 {
+		t.Errorf("stats = %v, %d chunks", st, len(resp.GetChunks()))
+	}
+
+	// The chunk limit counts every message
+	stream, err = client.DeduplicateStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		_ = stream.Send(&distillv1.DeduplicateRequest{Chunks: textChunks("apple", "banana")})
+	}
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("over the chunk limit: %v, want ResourceExhausted", err)
+	}
+}
+
+func TestAnalyzeRedundancy(t *testing.T) {
+	client := dial(t, NewServer(Config{Embedder: letterEmbedder{}}))
+
+	resp, err := client.AnalyzeRedundancy(context.Background(), &distillv1.AnalyzeRedundancyRequest{
+		Chunks: textChunks("apple", "avocado", "apricot", "banana"),
+		Seed:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetTotal() != 4 || resp.GetDuplicates() != 2 || resp.GetUnique() != 2 || resp.GetSavingsPct() != 50 {
+		t.Errorf("response = %v", resp)
+	}
+	for _, r := range resp.GetRemoved() {
+		if r.GetRemovedId()[0] != 'a' || r.GetKeptId()[0] != 'a' {
+			t.Errorf("removal = %v", r)
+		}
+	}
+
+	mixed := textChunks("apple")
+	mixed = append(mixed, &distillv1.Chunk{Id: "short", Embedding: []float32{1, 0}})
+	_, err = client.AnalyzeRedundancy(context.Background(), &distillv1.AnalyzeRedundancyRequest{Chunks: mixed})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("mixed dimensions: %v, want InvalidArgument", err)
+	}
+}
+
+func TestRetrieve(t *testing.T) {
+	var e letterEmbedder
+	chunks := make([]types.Chunk, 0, 3)
+	for i, text := range []string{"apple", "avocado", "banana"} {
+		v, _ := e.Embed(context.Background(), text)
+		chunks = append(chunks, types.Chunk{ID: text, Text: text, Embedding: v, Score: float32(3 - i)})
+	}
+	broker := contextlab.NewBrokerWithEmbedder(&stubRetriever{chunks: chunks}, e, contextlab.DefaultBrokerConfig())
+	client := dial(t, NewServer(Config{Broker: broker}))
+
+	resp, err := client.Retrieve(context.Background(), &distillv1.RetrieveRequest{Query: "anything", Explain: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := resp.GetStats(); st.GetRetrieved() != 3 || st.GetReturned() != 2 {
+		t.Errorf("stats = %v", st)
+	}
+	for _, c := range resp.GetChunks() {
+		if len(c.GetEmbedding()) != 0 {
+			t.Errorf("%s: embedding returned without include_embeddings", c.GetId())
+		}
+		if c.GetMetadata().GetFields()[contextlab.MetaTransforms].GetListValue() == nil {
+			t.Errorf("%s: no explain transforms in %v", c.GetId(), c.GetMetadata())
+		}
+	}
+
+	_, err = client.Retrieve(context.Background(), &distillv1.RetrieveRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("without a query: %v, want InvalidArgument", err)
+	}
+}
+
+func TestRetrieve_TopK(t *testing.T) {
+	ret := &stubRetriever{chunks: []types.Chunk{{ID: "a", Text: "a", Embedding: []float32{1, 0}}}}
+	broker := contextlab.NewBroker(ret, contextlab.BrokerConfig{OverFetchK: 50, TargetK: 2})
+	client := dial(t, NewServer(Config{Broker: broker, Limits: contextlab.Limits{MaxChunks: 100}}))
+	ctx := context.Background()
+
+	for _, tc := range []struct{ topK, want int32 }{{0, 50}, {10, 10}} {
+		if _, err := client.Retrieve(ctx, &distillv1.RetrieveRequest{QueryEmbedding: []float32{1, 0}, TopK: tc.topK}); err != nil {
+			t.Fatal(err)
+		}
+		if ret.topK != int(tc.want) {
+			t.Errorf("top_k %d: fetched %d, want %d", tc.topK, ret.topK, tc.want)
+		}
+	}
+	_, err := client.Retrieve(ctx, &distillv1.RetrieveRequest{QueryEmbedding: []float32{1, 0}, TopK: 101})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("top_k over the chunk limit: %v, want ResourceExhausted", err)
+	}
+}
+
+func TestRetrieve_Identity(t *testing.T) {
+	chunks := []types.Chunk{
+		{ID: "public", Text: "public", Embedding: []float32{1, 0}, Metadata: map[string]interface{}{"allowed_groups": "*"}},
+		{ID: "billing", Text: "billing", Embedding: []float32{0, 1}, Metadata: map[string]interface{}{"allowed_groups": []string{"billing"}}},
+	}
+	broker, err := contextlab.NewBrokerWithOptions(&stubRetriever{chunks: chunks}, contextlab.WithACL(contextlab.ACL{Enabled: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dial(t, NewServer(Config{Broker: broker}))
+
+	for _, tc := range []struct {
+		identity *distillv1.Identity
+		want     int32
+	}{
+		{nil, 1},
+		{&distillv1.Identity{User: "ana", Groups: []string{"billing"}}, 2},
+	} {
+		resp, err := client.Retrieve(context.Background(), &distillv1.RetrieveRequest{QueryEmbedding: []float32{1, 0}, Identity: tc.identity})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st := resp.GetStats(); st.GetReturned() != tc.want || st.GetAclDenied() != 2-tc.want {
+			t.Errorf("identity %v: stats = %v, want %d returned", tc.identity, st, tc.want)
+		}
+	}
+}
+
+func TestHTTPOnlyFields(t *testing.T) {
+	broker := contextlab.NewBrokerWithEmbedder(&stubRetriever{}, letterEmbedder{}, contextlab.DefaultBrokerConfig())
+	client := dial(t, NewServer(Config{Broker: broker, Embedder: letterEmbedder{}}))
+	ctx := context.Background()
+
+	_, err := client.Deduplicate(ctx, &distillv1.DeduplicateRequest{Chunks: textChunks("apple"), Redact: true})
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "redact") {
+		t.Errorf("dedupe with redact: %v, want InvalidArgument naming redact", err)
+	}
+
+	stream, err := client.DeduplicateStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = stream.Send(&distillv1.DeduplicateRequest{Chunks: textChunks("apple")})
+	_ = stream.Send(&distillv1.DeduplicateRequest{Chunks: textChunks("banana"), Compress: &distillv1.CompressOptions{Mode: "extractive"}})
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("stream with compress: %v, want InvalidArgument", err)
+	}
+
+	for _, req := range []*distillv1.RetrieveRequest{
+		{Query: "apple", Redact: true},
+		{Queries: []string{"apple", "banana"}},
+		{QueryEmbeddings: []*distillv1.Embedding{{Values: []float32{1}}}},
+		{Query: "apple", Enable: &distillv1.StageToggles{}},
+		{Query: "apple", DeadlineMs: 100},
+	} {
+		if _, err := client.Retrieve(ctx, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("retrieve %v: %v, want InvalidArgument", req, err)
+		}
+	}
+	if _, err := client.Retrieve(ctx, &distillv1.RetrieveRequest{Query: "apple", Identity: &distillv1.Identity{User: "ana"}}); err != nil {
+		t.Errorf("retrieve with identity: %v", err)
+	}
+}
@@ -0,0 +1,376 @@
+// Package history keeps a persistent record of job and request summaries
+// (sync and analyze runs, served requests) in SQLite, so stats survive the
+// process and can be listed or exported for dashboards.
+package history
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Record kinds.
+const (
+	KindSync    = "sync"
+	KindAnalyze = "analyze"
+	KindRequest = "request"
+
+	// KindNamespaceStats records are periodic snapshots of one
+	// namespace's rolling request aggregates; Name is the namespace.
+	KindNamespaceStats = "namespace_stats"
+)
+
+// Record statuses.
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// ErrNotFound is returned by Get for an unknown record ID.
+var ErrNotFound = errors.New("history record not found")
+
+// Record summarizes one job or request.
+type Record struct {
+	// ID is assigned by Add when empty.
+	ID string
+
+	// Kind is KindSync, KindAnalyze, KindRequest, or KindNamespaceStats.
+	Kind string
+
+	// Name says what ran: the input file for a job, the endpoint for a
+	// request.
+	Name string
+
+	StartedAt time.Time
+	Duration  time.Duration
+
+	// Input and Output are the item counts going in and coming out, such
+	// as vectors loaded and uploaded, or chunks retrieved and returned.
+	Input  int
+	Output int
+
+	// Reduction is the fraction of Input removed, set by Add.
+	Reduction float64
+
+	// Fingerprint identifies the settings the job ran with; see
+	// Fingerprint.
+	Fingerprint string
+
+	// Status is StatusOK or StatusError, with the message in Error.
+	Status string
+	Error  string
+
+	// Details holds kind-specific stats.
+	Details map[string]interface{}
+}
+
+// Filter selects records for List.
+type Filter struct {
+	// Kind and Name match exactly when set.
+	Kind string
+	Name string
+
+	// Since drops records started before it when set.
+	Since time.Time
+
+	// Limit caps the number of records, newest first (0 = no limit).
+	Limit int
+}
+
+// Fingerprint returns a short stable hash of settings, so runs with the
+// same configuration can be grouped. Maps are hashed with sorted keys.
+func Fingerprint(settings map[string]interface{}) string {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// SQLiteStore stores records in SQLite.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens or creates the history database at dsn.
+func NewSQLiteStore(dsn string) (*SQLiteStore, error) {
+	if dsn == "" {
+		dsn = ":memory:"
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("set WAL mode: %w", err)
+	}
+
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return s, nil
+}
+
+func (s *SQLiteStore) migrate() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS history (
+		id          TEXT PRIMARY KEY,
+		kind        TEXT NOT NULL,
+		name        TEXT NOT NULL DEFAULT '',
+		started_at  TEXT NOT NULL,
+		duration_us INTEGER NOT NULL DEFAULT 0,
+		input       INTEGER NOT NULL DEFAULT 0,
+		output      INTEGER NOT NULL DEFAULT 0,
+		reduction   REAL NOT NULL DEFAULT 0,
+		fingerprint TEXT NOT NULL DEFAULT '',
+		status      TEXT NOT NULL DEFAULT 'ok',
+		error       TEXT NOT NULL DEFAULT '',
+		details     TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS idx_history_started ON history(started_at);
+	CREATE INDEX IF NOT EXISTS idx_history_kind ON history(kind, started_at);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// Add stores r, filling in its ID, Reduction, and Status if unset.
+func (s *SQLiteStore) Add(ctx context.Context, r *Record) error {
+	if r.ID == "" {
+		r.ID = generateID()
+	}
+	if r.StartedAt.IsZero() {
+		r.StartedAt = time.Now()
+	}
+	if r.Status == "" {
+		r.Status = StatusOK
+	}
+	r.Reduction = 0
+	if r.Input > 0 {
+		r.Reduction = float64(r.Input-r.Output) / float64(r.Input)
+	}
+
+	details := ""
+	if len(r.Details) > 0 {
+		data, err := json.Marshal(r.Details)
+		if err != nil {
+			return fmt.Errorf("marshal details: %w", err)
+		}
+		details = string(data)
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO history (id, kind, name, started_at, duration_us, input, output, reduction, fingerprint, status, error, details)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, r.Kind, r.Name, r.StartedAt.UTC().Format(timeFormat), r.Duration.Microseconds(),
+		r.Input, r.Output, r.Reduction, r.Fingerprint, r.Status, r.Error, details,
+	)
+	if err != nil {
+		return fmt.Errorf("insert record: %w", err)
+	}
+	return nil
+}
+
+// List returns the records matching f, newest first.
+func (s *SQLiteStore) List(ctx context.Context, f Filter) ([]Record, error) {
+	var where []string
+	var args []interface{}
+	if f.Kind != "" {
+		where = append(where, "kind = ?")
+		args = append(args, f.Kind)
+	}
+	if f.Name != "" {
+		where = append(where, "name = ?")
+		args = append(args, f.Name)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "started_at >= ?")
+		args = append(args, f.Since.UTC().Format(timeFormat))
+	}
+
+	query := "SELECT " + columns + " FROM history"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY started_at DESC"
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var records []Record
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *r)
+	}
+	return records, rows.Err()
+}
+
+// Get returns the record with the given ID.
+func (s *SQLiteStore) Get(ctx context.Context, id string) (*Record, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+columns+" FROM history WHERE id = ?", id)
+	r, err := scanRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return r, err
+}
+
+// Prune deletes records started before cutoff and returns how many were
+// removed.
+func (s *SQLiteStore) Prune(ctx context.Context, cutoff time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM history WHERE started_at < ?", cutoff.UTC().Format(timeFormat))
+	if err != nil {
+		return 0, fmt.Errorf("prune history: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// timeFormat has fixed width so timestamps sort as strings.
+const timeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+const columns = "id, kind, name, started_at, duration_us, input, output, reduction, fingerprint, status, error, details"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRecord(row scanner) (*Record, error) {
+	var r Record
+	var started, details string
+	var durationUs int64
+	if err := row.Scan(&r.ID, &r.Kind, &r.Name, &started, &durationUs, &r.Input, &r.Output,
+		&r.Reduction, &r.Fingerprint, &r.Status, &r.Error, &details); err != nil {
+		return nil, err
+	}
+	r.StartedAt, _ = time.Parse(timeFormat, started)
+	r.Duration = time.Duration(durationUs) * time.Microsecond
+	if details != "" {
+		if err := json.Unmarshal([]byte(details), &r.Details); err != nil {
+			return nil, fmt.Errorf("record %s: decode details: %w", r.ID, err)
+		}
+	}
+	return &r, nil
+}
+
+// generateID returns a 24-character hex ID that sorts by creation time.
+func generateID() string {
+	b := make([]byte, 12)
+	ts := uint32(time.Now().Unix())
+	b[0] = byte(ts >> 24)
+	b[1] = byte(ts >> 16)
+	b[2] = byte(ts >> 8)
+	b[3] = byte(ts)
+	_, _ = rand.Read(b[4:])
+	return hex.EncodeToString(b)
+}
+
+// Writer adds records from a background goroutine so request handlers
+// never wait on the database. When its buffer is full, records are
+// dropped and counted rather than slowing requests down.
+type Writer struct {
+	store   *SQLiteStore
+	ch      chan Record
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+	dropped atomic.Int64
+	failed  atomic.Int64
+}
+
+// NewWriter starts a writer for store with room for buffer pending records.
+func NewWriter(store *SQLiteStore, buffer int) *Writer {
+	if buffer <= 0 {
+		buffer = 1024
+	}
+	w := &Writer{
+		store: store,
+		ch:    make(chan Record, buffer),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	for r := range w.ch {
+		if err := w.store.Add(context.Background(), &r); err != nil {
+			w.failed.Add(1)
+		}
+	}
+}
+
+// Record queues r. It is safe to call on a nil Writer.
+func (w *Writer) Record(r Record) {
+	if w == nil {
+		return
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		w.dropped.Add(1)
+		return
+	}
+	select {
+	case w.ch <- r:
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of records dropped because the buffer was
+// full or the writer was closed.
+func (w *Writer) Dropped() int64 { return w.dropped.Load() }
+
+// Failed returns the number of records the database rejected.
+func (w *Writer) Failed() int64 { return w.failed.Load() }
+
+// Close writes the queued records and closes the store. Records passed
+// to Record after Close are dropped.
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.ch)
+	}
+	w.mu.Unlock()
+	<-w.done
+	return w.store.Close()
+}
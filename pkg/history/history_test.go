@@ -0,0 +1,132 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	return newTestStoreAt(t, filepath.Join(t.TempDir(), "history.db"))
+}
+
+func newTestStoreAt(t *testing.T, path string) *SQLiteStore {
+	t.Helper()
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestSQLiteStore_AddListGet(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	job := Record{
+		Kind:        KindSync,
+		Name:        "data.jsonl",
+		StartedAt:   start,
+		Duration:    1500 * time.Millisecond,
+		Input:       1000,
+		Output:      800,
+		Fingerprint: Fingerprint(map[string]interface{}{"threshold": 0.05}),
+		Details:     map[string]interface{}{"failed": 0},
+	}
+	if err := s.Add(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == "" || job.Reduction != 0.2 || job.Status != StatusOK {
+		t.Errorf("Add did not fill in the record: %+v", job)
+	}
+
+	for i := 1; i <= 3; i++ {
+		r := Record{Kind: KindRequest, Name: "/v1/retrieve", StartedAt: start.Add(time.Duration(i) * time.Second), Input: 10, Output: 5}
+		if err := s.Add(ctx, &r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failed := Record{Kind: KindAnalyze, Name: "bad.jsonl", StartedAt: start.Add(time.Hour), Status: StatusError, Error: "boom"}
+	if err := s.Add(ctx, &failed); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := s.List(ctx, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 || all[0].ID != failed.ID || all[4].ID != job.ID {
+		t.Fatalf("expected 5 records newest first, got %d", len(all))
+	}
+
+	requests, _ := s.List(ctx, Filter{Kind: KindRequest, Limit: 2})
+	if len(requests) != 2 || !requests[0].StartedAt.Equal(start.Add(3*time.Second)) {
+		t.Errorf("unexpected requests: %+v", requests)
+	}
+	recent, _ := s.List(ctx, Filter{Since: start.Add(1500 * time.Millisecond)})
+	if len(recent) != 3 {
+		t.Errorf("expected 3 records since the first request, got %d", len(recent))
+	}
+
+	got, err := s.Get(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Duration != job.Duration || got.Fingerprint != job.Fingerprint || got.Details["failed"] != float64(0) || !got.StartedAt.Equal(start) {
+		t.Errorf("round trip mismatch: %+v", got)
+	}
+	if _, err := s.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	n, err := s.Prune(ctx, start.Add(30*time.Minute))
+	if err != nil || n != 4 {
+		t.Errorf("expected 4 records pruned, got %d (%v)", n, err)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint(map[string]interface{}{"threshold": 0.15, "lambda": 0.5})
+	b := Fingerprint(map[string]interface{}{"lambda": 0.5, "threshold": 0.15})
+	c := Fingerprint(map[string]interface{}{"threshold": 0.2, "lambda": 0.5})
+	if a != b || a == c || len(a) != 12 {
+		t.Errorf("fingerprints: %q %q %q", a, b, c)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWriter(s, 100)
+	for i := 0; i < 50; i++ {
+		w.Record(Record{Kind: KindRequest, Name: "/v1/retrieve", Input: 10, Output: 4})
+	}
+
+	// Close drains the queue before closing the store
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w.Record(Record{Kind: KindRequest})
+	if w.Dropped() != 1 || w.Failed() != 0 {
+		t.Errorf("dropped %d, failed %d", w.Dropped(), w.Failed())
+	}
+
+	reopened := newTestStoreAt(t, path)
+	if got, _ := reopened.List(context.Background(), Filter{}); len(got) != 50 {
+		t.Errorf("expected 50 records written, got %d", len(got))
+	}
+
+	var nilWriter *Writer
+	nilWriter.Record(Record{})
+	if err := nilWriter.Close(); err != nil {
+		t.Error(err)
+	}
+}
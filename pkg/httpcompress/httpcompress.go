@@ -0,0 +1,241 @@
+// Package httpcompress compresses HTTP responses with zstd or gzip,
+// negotiated from the request's Accept-Encoding header. Retrieve
+// responses that carry metadata typically shrink 5-10x.
+package httpcompress
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported encodings, in default preference order.
+const (
+	Zstd = "zstd"
+	Gzip = "gzip"
+)
+
+// Config controls response compression.
+type Config struct {
+	// MinSize is the smallest response, in bytes, worth compressing
+	// (default 1024). Smaller responses are sent as-is.
+	MinSize int
+
+	// Encodings lists the encodings offered, most preferred first
+	// (default zstd, gzip).
+	Encodings []string
+}
+
+// DefaultConfig returns the default compression settings.
+func DefaultConfig() Config {
+	return Config{
+		MinSize:   1024,
+		Encodings: []string{Zstd, Gzip},
+	}
+}
+
+var (
+	gzipPool = sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}}
+	zstdPool = sync.Pool{New: func() interface{} {
+		w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return w
+	}}
+)
+
+// Middleware compresses responses from next when the client accepts a
+// configured encoding. Server-sent event streams and responses that
+// already set Content-Encoding pass through unchanged.
+func Middleware(cfg Config, next http.Handler) http.Handler {
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = 1024
+	}
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = DefaultConfig().Encodings
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		enc := Negotiate(r.Header.Get("Accept-Encoding"), cfg.Encodings)
+		if enc == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: enc, minSize: cfg.MinSize}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// Negotiate picks the first of offered that accept allows with a non-zero
+// q-value. It returns "" if none is acceptable.
+func Negotiate(accept string, offered []string) string {
+	if accept == "" {
+		return ""
+	}
+
+	q := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		weight := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				weight = f
+			}
+		}
+		q[name] = weight
+	}
+
+	for _, enc := range offered {
+		w, ok := q[enc]
+		if !ok {
+			w, ok = q["*"]
+		}
+		if ok && w > 0 {
+			return enc
+		}
+	}
+	return ""
+}
+
+// compressWriter buffers the first MinSize bytes so small responses can
+// be sent uncompressed, then streams through the encoder.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// decide starts the response, compressed if compress is true and the
+// response is eligible, and writes any buffered bytes.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	h := cw.Header()
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	if compress && h.Get("Content-Encoding") == "" && !isStream(h.Get("Content-Type")) &&
+		cw.status != http.StatusNoContent && cw.status != http.StatusNotModified {
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(cw.buf))
+		}
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		cw.enc = newEncoder(cw.encoding, cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends buffered data immediately. An undecided response is sent
+// uncompressed, since a handler that flushes is streaming.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		_ = cw.decide(false)
+	}
+	if f, ok := cw.enc.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the response, sending small bodies uncompressed.
+func (cw *compressWriter) Close() {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			return
+		}
+		_ = cw.decide(len(cw.buf) >= cw.minSize)
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Close()
+		releaseEncoder(cw.enc)
+		cw.enc = nil
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func isStream(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/event-stream")
+}
+
+func newEncoder(encoding string, w io.Writer) io.WriteCloser {
+	switch encoding {
+	case Zstd:
+		z := zstdPool.Get().(*zstd.Encoder)
+		z.Reset(w)
+		return z
+	default:
+		g := gzipPool.Get().(*gzip.Writer)
+		g.Reset(w)
+		return g
+	}
+}
+
+func releaseEncoder(enc io.WriteCloser) {
+	switch e := enc.(type) {
+	case *zstd.Encoder:
+		e.Reset(io.Discard)
+		zstdPool.Put(e)
+	case *gzip.Writer:
+		e.Reset(io.Discard)
+		gzipPool.Put(e)
+	}
+}
@@ -0,0 +1,119 @@
+package httpcompress
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiate(t *testing.T) {
+	offered := []string{Zstd, Gzip}
+	tests := map[string]string{
+		"":                        "",
+		"gzip":                    Gzip,
+		"gzip, deflate, br, zstd": Zstd,
+		"zstd;q=0, gzip":          Gzip,
+		"*":                       Zstd,
+		"identity":                "",
+		"GZIP;q=0.5":              Gzip,
+	}
+	for accept, want := range tests {
+		if got := Negotiate(accept, offered); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func serve(t *testing.T, accept string, h http.HandlerFunc) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if accept != "" {
+		req.Header.Set("Accept-Encoding", accept)
+	}
+	rec := httptest.NewRecorder()
+	Middleware(Config{MinSize: 100}, h).ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+func TestMiddleware_Compresses(t *testing.T) {
+	body := strings.Repeat(`{"id":"chunk","text":"repeated text"}`, 50)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, body)
+	}
+
+	for _, enc := range []string{Gzip, Zstd} {
+		t.Run(enc, func(t *testing.T) {
+			resp := serve(t, enc, handler)
+			if got := resp.Header.Get("Content-Encoding"); got != enc {
+				t.Fatalf("expected Content-Encoding %s, got %q", enc, got)
+			}
+
+			var r io.Reader
+			if enc == Gzip {
+				gr, err := gzip.NewReader(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r = gr
+			} else {
+				zr, err := zstd.NewReader(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer zr.Close()
+				r = zr
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != body {
+				t.Error("decompressed body does not match")
+			}
+		})
+	}
+}
+
+func TestMiddleware_PassThrough(t *testing.T) {
+	small := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}
+	if resp := serve(t, "gzip", small); resp.Header.Get("Content-Encoding") != "" {
+		t.Error("small responses should not be compressed")
+	}
+
+	large := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, strings.Repeat("x", 500))
+	}
+	if resp := serve(t, "", large); resp.Header.Get("Content-Encoding") != "" {
+		t.Error("responses should not be compressed without Accept-Encoding")
+	}
+
+	stream := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, strings.Repeat("data: x\n\n", 50))
+		w.(http.Flusher).Flush()
+	}
+	if resp := serve(t, "gzip", stream); resp.Header.Get("Content-Encoding") != "" {
+		t.Error("event streams should not be compressed")
+	}
+}
+
+func TestMiddleware_PreservesStatus(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, strings.Repeat("bad ", 100), http.StatusBadRequest)
+	}
+	resp := serve(t, "gzip", handler)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Encoding") != Gzip {
+		t.Error("expected large error body to be compressed")
+	}
+}
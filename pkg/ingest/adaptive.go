@@ -0,0 +1,199 @@
+package ingest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AdaptiveConfig controls AIMD (additive increase, multiplicative
+// decrease) tuning of batch size and concurrency during upload.
+type AdaptiveConfig struct {
+	// Enabled turns on adaptive tuning. Config.BatchSize and
+	// Config.Workers become the ceilings the controller starts from.
+	Enabled bool
+
+	// MinBatchSize is the smallest batch the controller shrinks to.
+	MinBatchSize int
+
+	// TargetLatency is the slowest a batch may upload before it counts as
+	// congestion, like a throttled request. Zero reacts to throttling only.
+	TargetLatency time.Duration
+}
+
+// Controller tunes batch size and concurrency from upload feedback. Each
+// window of successful batches below the target latency adds one worker
+// and MinBatchSize vectors per batch, up to the configured ceilings. A
+// throttled or slow batch halves both, once per window: feedback from
+// batches that started before the last decrease is ignored, so a burst of
+// 429s from in-flight requests does not collapse the pipeline to one
+// worker.
+type Controller struct {
+	cfg          AdaptiveConfig
+	maxBatchSize int
+	maxWorkers   int
+
+	mu           sync.Mutex
+	batchSize    int
+	workers      int
+	inFlight     int
+	successes    int
+	lastDecrease time.Time
+	decreases    int64
+	changed      chan struct{}
+}
+
+// NewController creates a controller that starts at, and never exceeds,
+// maxBatchSize and maxWorkers.
+func NewController(cfg AdaptiveConfig, maxBatchSize, maxWorkers int) *Controller {
+	if maxBatchSize <= 0 {
+		maxBatchSize = 100
+	}
+	if maxWorkers <= 0 {
+		maxWorkers = 1
+	}
+	if cfg.MinBatchSize <= 0 {
+		cfg.MinBatchSize = 10
+	}
+	cfg.MinBatchSize = min(cfg.MinBatchSize, maxBatchSize)
+
+	return &Controller{
+		cfg:          cfg,
+		maxBatchSize: maxBatchSize,
+		maxWorkers:   maxWorkers,
+		batchSize:    maxBatchSize,
+		workers:      maxWorkers,
+		changed:      make(chan struct{}),
+	}
+}
+
+// BatchSize returns the current batch size.
+func (c *Controller) BatchSize() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.batchSize
+}
+
+// Workers returns the current number of concurrent uploads allowed.
+func (c *Controller) Workers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.workers
+}
+
+// Decreases returns how many times the controller has backed off.
+func (c *Controller) Decreases() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.decreases
+}
+
+// Acquire blocks until fewer than Workers uploads are in flight, then
+// claims a slot. It returns the time the slot was claimed, to be passed to
+// Observe.
+func (c *Controller) Acquire(ctx context.Context) (time.Time, error) {
+	for {
+		c.mu.Lock()
+		if c.inFlight < c.workers {
+			c.inFlight++
+			c.mu.Unlock()
+			return time.Now(), nil
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return time.Time{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Observe releases a slot claimed at start and adjusts the controller.
+// throttled reports whether the upload was rate limited at any point,
+// including retries that later succeeded.
+func (c *Controller) Observe(start time.Time, throttled bool, err error) {
+	latency := time.Since(start)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	defer c.notify()
+
+	congested := throttled || (c.cfg.TargetLatency > 0 && latency > c.cfg.TargetLatency)
+	switch {
+	case congested:
+		if start.Before(c.lastDecrease) {
+			return
+		}
+		c.batchSize = max(c.cfg.MinBatchSize, c.batchSize/2)
+		c.workers = max(1, c.workers/2)
+		c.successes = 0
+		c.lastDecrease = time.Now()
+		c.decreases++
+	case err == nil:
+		c.successes++
+		if c.successes < c.workers {
+			return
+		}
+		c.successes = 0
+		c.batchSize = min(c.maxBatchSize, c.batchSize+c.cfg.MinBatchSize)
+		c.workers = min(c.maxWorkers, c.workers+1)
+	}
+}
+
+// notify wakes goroutines blocked in Acquire. Callers hold c.mu.
+func (c *Controller) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// RateLimiter caps upload throughput at a fixed number of vectors per
+// second across all workers. Requests are spaced evenly rather than
+// released in bursts, so a large sync settles at the limit instead of
+// oscillating around it.
+type RateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewRateLimiter creates a limiter allowing perSecond vectors per second.
+// It returns nil, which never waits, when perSecond is not positive.
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until n more vectors may be sent.
+func (l *RateLimiter) Wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(time.Duration(n) * l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,163 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestController_AIMD(t *testing.T) {
+	c := NewController(AdaptiveConfig{MinBatchSize: 10}, 100, 8)
+	if c.BatchSize() != 100 || c.Workers() != 8 {
+		t.Fatalf("expected to start at the ceilings, got %d/%d", c.BatchSize(), c.Workers())
+	}
+
+	// Two in-flight batches are throttled; only the first backs off
+	ctx := context.Background()
+	s1, _ := c.Acquire(ctx)
+	s2, _ := c.Acquire(ctx)
+	c.Observe(s1, true, nil)
+	c.Observe(s2, true, nil)
+	if c.BatchSize() != 50 || c.Workers() != 4 || c.Decreases() != 1 {
+		t.Errorf("expected one halving to 50/4, got %d/%d after %d", c.BatchSize(), c.Workers(), c.Decreases())
+	}
+
+	// A full window of successes grows both additively
+	for i := 0; i < 4; i++ {
+		s, _ := c.Acquire(ctx)
+		c.Observe(s, false, nil)
+	}
+	if c.BatchSize() != 60 || c.Workers() != 5 {
+		t.Errorf("expected additive increase to 60/5, got %d/%d", c.BatchSize(), c.Workers())
+	}
+
+	// Failures that are not throttling do not move the controller
+	s, _ := c.Acquire(ctx)
+	c.Observe(s, false, errors.New("invalid vector"))
+	if c.BatchSize() != 60 || c.Workers() != 5 {
+		t.Errorf("expected no change on a plain failure, got %d/%d", c.BatchSize(), c.Workers())
+	}
+
+	// Repeated throttling bottoms out at the minimums
+	for i := 0; i < 10; i++ {
+		s, _ := c.Acquire(ctx)
+		c.Observe(s, true, nil)
+	}
+	if c.BatchSize() != 10 || c.Workers() != 1 {
+		t.Errorf("expected floor of 10/1, got %d/%d", c.BatchSize(), c.Workers())
+	}
+}
+
+func TestController_TargetLatency(t *testing.T) {
+	c := NewController(AdaptiveConfig{TargetLatency: time.Millisecond}, 100, 4)
+	s, _ := c.Acquire(context.Background())
+	time.Sleep(5 * time.Millisecond)
+	c.Observe(s, false, nil)
+	if c.Workers() != 2 {
+		t.Errorf("expected a slow batch to halve concurrency, got %d", c.Workers())
+	}
+}
+
+func TestController_AcquireBlocks(t *testing.T) {
+	c := NewController(AdaptiveConfig{}, 100, 1)
+	s, _ := c.Acquire(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Acquire to block at the concurrency limit, got %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = c.Acquire(context.Background())
+		close(done)
+	}()
+	c.Observe(s, false, nil)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected release to unblock a waiting Acquire")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	if NewRateLimiter(0) != nil {
+		t.Error("expected no limiter for a zero rate")
+	}
+	var none *RateLimiter
+	if err := none.Wait(context.Background(), 1000); err != nil {
+		t.Errorf("expected a nil limiter not to wait, got %v", err)
+	}
+
+	l := NewRateLimiter(1000)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background(), 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 30 vectors at 1000/s: the third batch waits for the first two
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected at least 20ms of spacing, got %v", elapsed)
+	}
+}
+
+// throttlingUploader rejects batches while more than limit are in flight,
+// like a server returning 429.
+type throttlingUploader struct {
+	limit    int32
+	inFlight atomic.Int32
+
+	mu      sync.Mutex
+	maxSize int
+}
+
+func (u *throttlingUploader) UpsertBatch(ctx context.Context, vectors []types.Vector) error {
+	n := u.inFlight.Add(1)
+	defer u.inFlight.Add(-1)
+	time.Sleep(time.Millisecond)
+	if n > u.limit {
+		return errors.New("429 Too Many Requests")
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.maxSize = max(u.maxSize, len(vectors))
+	return nil
+}
+
+func TestPipeline_Adaptive(t *testing.T) {
+	vectors := make([]types.Vector, 2000)
+	for i := range vectors {
+		vectors[i] = types.Vector{ID: fmt.Sprintf("v%d", i), Values: []float32{1}}
+	}
+
+	u := &throttlingUploader{limit: 2}
+	p := NewPipeline(u, Config{
+		BatchSize: 100,
+		Workers:   16,
+		Adaptive:  AdaptiveConfig{Enabled: true, MinBatchSize: 10},
+	})
+	stats, err := p.IngestVectors(context.Background(), vectors, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.ThrottledBatches == 0 {
+		t.Fatal("expected some batches to be throttled")
+	}
+	if stats.Workers >= 16 {
+		t.Errorf("expected concurrency to back off, still at %d", stats.Workers)
+	}
+	if int(stats.UploadedVectors)+int(stats.FailedVectors) != len(vectors) {
+		t.Errorf("expected every vector accounted for, got %+v", stats)
+	}
+	if u.maxSize > 100 {
+		t.Errorf("batch size exceeded the ceiling: %d", u.maxSize)
+	}
+}
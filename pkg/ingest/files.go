@@ -0,0 +1,116 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+// vectorFileExts are the extensions collected when a directory is given.
+var vectorFileExts = map[string]bool{".jsonl": true, ".ndjson": true}
+
+// ExpandPaths resolves files, directories, and glob patterns to a list of
+// vector files. Directories are walked recursively for .jsonl and .ndjson
+// files; a file named explicitly or matched by a glob is used whatever
+// its extension. Each pattern's matches are sorted, and a file listed
+// twice is kept once, at its first position. A pattern that matches
+// nothing is a config error.
+func ExpandPaths(patterns []string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			out = append(out, path)
+		}
+	}
+
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(pattern); err != nil {
+				return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid pattern %q: %w", pattern, err))
+			}
+		}
+
+		var files []string
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil {
+				return nil, errs.Wrap(errs.ErrConfig, err)
+			}
+			if !info.IsDir() {
+				files = append(files, m)
+				continue
+			}
+			err = filepath.WalkDir(m, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && vectorFileExts[strings.ToLower(filepath.Ext(path))] {
+					files = append(files, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, errs.Wrap(errs.ErrConfig, err)
+			}
+		}
+		if len(files) == 0 {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("no vector files match %q", pattern))
+		}
+		sort.Strings(files)
+		for _, f := range files {
+			add(f)
+		}
+	}
+	return out, nil
+}
+
+// FileResult is one input file's line in a sync file manifest.
+type FileResult struct {
+	Path string `json:"path"`
+
+	// Read is the number of vectors loaded from the file.
+	Read int `json:"read"`
+
+	// Dropped is the number removed before upload by validation or
+	// deduplication, or superseded by a later vector with the same ID.
+	Dropped int `json:"dropped"`
+
+	Uploaded int `json:"uploaded"`
+	Failed   int `json:"failed"`
+
+	// LoadMS is the time spent reading the file, and UploadMS the time
+	// from its first batch starting to its last batch finishing.
+	LoadMS   int64 `json:"load_ms"`
+	UploadMS int64 `json:"upload_ms"`
+
+	// Error is set when the file could not be read; its vectors, if any,
+	// were not uploaded.
+	Error string `json:"error,omitempty"`
+}
+
+// OK reports whether the file was read and all its vectors uploaded.
+func (r FileResult) OK() bool {
+	return r.Error == "" && r.Failed == 0
+}
+
+// WriteFileManifest writes one JSON line per file.
+func WriteFileManifest(w io.Writer, results []FileResult) error {
+	enc := json.NewEncoder(w)
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package ingest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+func TestExpandPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.jsonl", "a.jsonl", "notes.txt", "sub/c.ndjson", "sub/d.JSONL"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := func(name string) string { return filepath.Join(dir, name) }
+
+	got, err := ExpandPaths([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{p("a.jsonl"), p("b.jsonl"), p("sub/c.ndjson"), p("sub/d.JSONL")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("directory: got %v, want %v", got, want)
+	}
+
+	// Explicit files keep their position and are not repeated by a glob
+	got, err = ExpandPaths([]string{p("notes.txt"), p("b.jsonl"), p("*.jsonl")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{p("notes.txt"), p("b.jsonl"), p("a.jsonl")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("glob: got %v, want %v", got, want)
+	}
+
+	for _, pattern := range []string{p("*.parquet"), p("missing.jsonl")} {
+		if _, err := ExpandPaths([]string{pattern}); !errors.Is(err, errs.ErrConfig) {
+			t.Errorf("%s: expected config error, got %v", pattern, err)
+		}
+	}
+}
+
+func TestWriteFileManifest(t *testing.T) {
+	var buf bytes.Buffer
+	results := []FileResult{
+		{Path: "a.jsonl", Read: 10, Dropped: 2, Uploaded: 8, LoadMS: 3, UploadMS: 40},
+		{Path: "b.jsonl", Error: "permission denied"},
+	}
+	if err := WriteFileManifest(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"error":"permission denied"`) {
+		t.Errorf("unexpected manifest:\n%s", buf.String())
+	}
+	if !results[0].OK() || results[1].OK() {
+		t.Error("expected only the first file to be OK")
+	}
+}
@@ -26,6 +26,23 @@ type Config struct {
 
 	// ChannelBuffer is the buffer size for internal channels.
 	ChannelBuffer int
+
+	// Validator, if set, checks (and optionally normalizes) every vector
+	// before upload. In lenient mode invalid vectors are counted in
+	// Stats.SkippedVectors; in strict mode the run fails.
+	Validator *Validator
+
+	// Adaptive tunes batch size and concurrency from throttling and
+	// latency feedback instead of holding them fixed.
+	Adaptive AdaptiveConfig
+
+	// RateLimit caps upload throughput in vectors per second across all
+	// workers. Zero means unlimited.
+	RateLimit float64
+
+	// OnBatch, if set, is called after each batch upload with the batch,
+	// when its upload began, and its error. Workers call it concurrently.
+	OnBatch func(batch []types.Vector, start time.Time, err error)
 }
 
 // DefaultConfig returns sensible defaults for ingestion.
@@ -37,11 +54,21 @@ func DefaultConfig() Config {
 	}
 }
 
+// Uploader upserts a batch of vectors. *pinecone.Client implements it.
+type Uploader interface {
+	UpsertBatch(ctx context.Context, vectors []types.Vector) error
+}
+
 // Pipeline orchestrates the ingestion of vectors to Pinecone.
 type Pipeline struct {
-	cfg    Config
-	client *pc.Client
-	stats  *Stats
+	cfg     Config
+	client  Uploader
+	stats   *Stats
+	ctrl    *Controller
+	limiter *RateLimiter
+
+	failedMu  sync.Mutex
+	failedIDs []string
 }
 
 // Stats tracks ingestion metrics.
@@ -49,9 +76,19 @@ type Stats struct {
 	TotalVectors     int64
 	UploadedVectors  int64
 	FailedVectors    int64
+	SkippedVectors   int64
 	BatchesProcessed int64
 	StartTime        time.Time
 	EndTime          time.Time
+
+	// ThrottledBatches counts batches that were rate limited at least
+	// once, including those that succeeded on retry.
+	ThrottledBatches int64
+
+	// BatchSize and Workers are the settings in effect when the stats
+	// were taken; with adaptive tuning they change during the run.
+	BatchSize int
+	Workers   int
 }
 
 // Duration returns the total processing duration.
@@ -72,7 +109,7 @@ func (s *Stats) VectorsPerSecond() float64 {
 }
 
 // NewPipeline creates a new ingestion pipeline.
-func NewPipeline(client *pc.Client, cfg Config) *Pipeline {
+func NewPipeline(client Uploader, cfg Config) *Pipeline {
 	if cfg.BatchSize <= 0 {
 		cfg.BatchSize = 100
 	}
@@ -83,11 +120,33 @@ func NewPipeline(client *pc.Client, cfg Config) *Pipeline {
 		cfg.ChannelBuffer = 1000
 	}
 
-	return &Pipeline{
-		cfg:    cfg,
-		client: client,
-		stats:  &Stats{},
+	p := &Pipeline{
+		cfg:     cfg,
+		client:  client,
+		stats:   &Stats{},
+		limiter: NewRateLimiter(cfg.RateLimit),
+	}
+	if cfg.Adaptive.Enabled {
+		p.ctrl = NewController(cfg.Adaptive, cfg.BatchSize, cfg.Workers)
+	}
+	return p
+}
+
+// batchSize returns the size of the next batch.
+func (p *Pipeline) batchSize() int {
+	if p.ctrl != nil {
+		return p.ctrl.BatchSize()
 	}
+	return p.cfg.BatchSize
+}
+
+// batchBuffer returns how many batches may wait for a worker. Adaptive
+// runs cut batches just in time so each uses the current batch size.
+func (p *Pipeline) batchBuffer() int {
+	if p.ctrl != nil {
+		return 0
+	}
+	return p.cfg.Workers * 2
 }
 
 // ProgressCallback is called periodically with current stats.
@@ -107,13 +166,14 @@ func (p *Pipeline) IngestFile(ctx context.Context, filePath string, progress Pro
 // IngestReader reads vectors from an io.Reader and uploads them to Pinecone.
 func (p *Pipeline) IngestReader(ctx context.Context, r io.Reader, progress ProgressCallback) (*Stats, error) {
 	p.stats = &Stats{StartTime: time.Now()}
+	p.failedIDs = nil
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// Channels for pipeline stages
 	vectorCh := make(chan types.Vector, p.cfg.ChannelBuffer)
-	batchCh := make(chan []types.Vector, p.cfg.Workers*2)
+	batchCh := make(chan []types.Vector, p.batchBuffer())
 	errCh := make(chan error, 1)
 
 	var wg sync.WaitGroup
@@ -191,30 +251,37 @@ func (p *Pipeline) IngestVectors(ctx context.Context, vectors []types.Vector, pr
 		StartTime:    time.Now(),
 		TotalVectors: int64(len(vectors)),
 	}
+	p.failedIDs = nil
+
+	if p.cfg.Validator != nil {
+		valid, err := p.cfg.Validator.Filter(vectors)
+		if err != nil {
+			p.stats.EndTime = time.Now()
+			return p.GetStatsPtr(), err
+		}
+		p.stats.SkippedVectors = int64(len(vectors) - len(valid))
+		vectors = valid
+	}
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	batchCh := make(chan []types.Vector, p.cfg.Workers*2)
+	batchCh := make(chan []types.Vector, p.batchBuffer())
 
 	// Batcher goroutine
 	go func() {
 		defer close(batchCh)
 
-		batch := make([]types.Vector, 0, p.cfg.BatchSize)
-		for i, v := range vectors {
+		for len(vectors) > 0 {
+			n := min(p.batchSize(), len(vectors))
+			batch := make([]types.Vector, n)
+			copy(batch, vectors[:n])
+			vectors = vectors[n:]
+
 			select {
+			case batchCh <- batch:
 			case <-ctx.Done():
 				return
-			default:
-			}
-
-			batch = append(batch, v)
-			if len(batch) >= p.cfg.BatchSize || i == len(vectors)-1 {
-				batchCopy := make([]types.Vector, len(batch))
-				copy(batchCopy, batch)
-				batchCh <- batchCopy
-				batch = batch[:0]
 			}
 		}
 	}()
@@ -286,6 +353,17 @@ func (p *Pipeline) readVectors(ctx context.Context, r io.Reader, out chan<- type
 
 		atomic.AddInt64(&p.stats.TotalVectors, 1)
 
+		if p.cfg.Validator != nil {
+			ok, err := p.cfg.Validator.accept(&vec)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				atomic.AddInt64(&p.stats.SkippedVectors, 1)
+				continue
+			}
+		}
+
 		select {
 		case out <- vec:
 		case <-ctx.Done():
@@ -305,7 +383,7 @@ type jsonVector struct {
 
 // batchVectors accumulates vectors into batches.
 func (p *Pipeline) batchVectors(ctx context.Context, in <-chan types.Vector, out chan<- []types.Vector) {
-	batch := make([]types.Vector, 0, p.cfg.BatchSize)
+	batch := make([]types.Vector, 0, p.batchSize())
 
 	for {
 		select {
@@ -326,9 +404,9 @@ func (p *Pipeline) batchVectors(ctx context.Context, in <-chan types.Vector, out
 			}
 
 			batch = append(batch, v)
-			if len(batch) >= p.cfg.BatchSize {
+			if size := p.batchSize(); len(batch) >= size {
 				out <- batch
-				batch = make([]types.Vector, 0, p.cfg.BatchSize)
+				batch = make([]types.Vector, 0, size)
 			}
 		}
 	}
@@ -343,9 +421,18 @@ func (p *Pipeline) uploadWorker(ctx context.Context, batches <-chan []types.Vect
 		default:
 		}
 
-		err := p.client.UpsertBatch(ctx, batch)
+		start := time.Now()
+		err := p.upload(ctx, batch)
+		if p.cfg.OnBatch != nil {
+			p.cfg.OnBatch(batch, start, err)
+		}
 		if err != nil {
 			atomic.AddInt64(&p.stats.FailedVectors, int64(len(batch)))
+			p.failedMu.Lock()
+			for _, v := range batch {
+				p.failedIDs = append(p.failedIDs, v.ID)
+			}
+			p.failedMu.Unlock()
 		} else {
 			atomic.AddInt64(&p.stats.UploadedVectors, int64(len(batch)))
 		}
@@ -353,18 +440,74 @@ func (p *Pipeline) uploadWorker(ctx context.Context, batches <-chan []types.Vect
 	}
 }
 
+// upload sends one batch, honoring the rate limit and, when adaptive,
+// waiting for a concurrency slot and reporting the outcome to the
+// controller.
+func (p *Pipeline) upload(ctx context.Context, batch []types.Vector) error {
+	var start time.Time
+	if p.ctrl != nil {
+		var err error
+		if start, err = p.ctrl.Acquire(ctx); err != nil {
+			return err
+		}
+	}
+	if err := p.limiter.Wait(ctx, len(batch)); err != nil {
+		if p.ctrl != nil {
+			p.ctrl.Observe(start, false, err)
+		}
+		return err
+	}
+	if p.ctrl != nil {
+		// Measure latency from the send, not from the rate limit wait
+		start = time.Now()
+	}
+
+	var throttled atomic.Bool
+	uctx := pc.WithRetryObserver(ctx, func(error) { throttled.Store(true) })
+	err := p.client.UpsertBatch(uctx, batch)
+	if err != nil && pc.IsRetryable(err) {
+		throttled.Store(true)
+	}
+	if throttled.Load() {
+		atomic.AddInt64(&p.stats.ThrottledBatches, 1)
+	}
+	if p.ctrl != nil {
+		p.ctrl.Observe(start, throttled.Load(), err)
+	}
+	return err
+}
+
 // GetStats returns current statistics.
 func (p *Pipeline) GetStats() Stats {
 	return Stats{
 		TotalVectors:     atomic.LoadInt64(&p.stats.TotalVectors),
 		UploadedVectors:  atomic.LoadInt64(&p.stats.UploadedVectors),
 		FailedVectors:    atomic.LoadInt64(&p.stats.FailedVectors),
+		SkippedVectors:   atomic.LoadInt64(&p.stats.SkippedVectors),
 		BatchesProcessed: atomic.LoadInt64(&p.stats.BatchesProcessed),
+		ThrottledBatches: atomic.LoadInt64(&p.stats.ThrottledBatches),
+		BatchSize:        p.batchSize(),
+		Workers:          p.workers(),
 		StartTime:        p.stats.StartTime,
 		EndTime:          p.stats.EndTime,
 	}
 }
 
+// FailedIDs returns the IDs of vectors in batches that failed to upload.
+func (p *Pipeline) FailedIDs() []string {
+	p.failedMu.Lock()
+	defer p.failedMu.Unlock()
+	return append([]string(nil), p.failedIDs...)
+}
+
+// workers returns the number of concurrent uploads allowed.
+func (p *Pipeline) workers() int {
+	if p.ctrl != nil {
+		return p.ctrl.Workers()
+	}
+	return p.cfg.Workers
+}
+
 // GetStatsPtr returns a pointer to current statistics.
 func (p *Pipeline) GetStatsPtr() *Stats {
 	s := p.GetStats()
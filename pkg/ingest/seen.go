@@ -0,0 +1,83 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/Siddhant-K-code/distill/pkg/bloom"
+	"github.com/Siddhant-K-code/distill/pkg/dedup"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// ContentKey returns the key exact duplicates of v share: a hash of its
+// values and metadata, chunk text included. The ID is not part of it, so
+// the same chunk exported twice under different IDs matches, but a
+// vector whose metadata alone changed does not, and is written again.
+// Tombstone markers are left out, so a duplicate tombstoned by one run
+// matches its untouched copy in the next.
+func ContentKey(v types.Vector) []byte {
+	h := sha256.New()
+	var buf [4]byte
+	for _, x := range v.Values {
+		binary.LittleEndian.PutUint32(buf[:], math.Float32bits(x))
+		_, _ = h.Write(buf[:])
+	}
+	_, _ = h.Write([]byte{0})
+	metadata := v.Metadata
+	if _, ok := metadata[dedup.TombstoneKey]; ok {
+		metadata = make(map[string]interface{}, len(v.Metadata))
+		for k, val := range v.Metadata {
+			if k != dedup.TombstoneKey && k != dedup.DuplicateOfKey {
+				metadata[k] = val
+			}
+		}
+	}
+	if len(metadata) > 0 {
+		// JSON sorts map keys at every level, so equal metadata encodes
+		// the same however it was built. fmt, which also sorts them,
+		// covers values JSON cannot encode, such as NaN.
+		data, err := json.Marshal(metadata)
+		if err != nil {
+			data = []byte(fmt.Sprintf("%#v", metadata))
+		}
+		_, _ = h.Write(data)
+	}
+	return h.Sum(nil)
+}
+
+// SkipSeen drops vectors whose content seen has recorded for namespace,
+// without querying the index. It returns the rest in order and how many
+// were dropped. A false positive drops a new vector, at the rate the
+// registry was configured for.
+func SkipSeen(vectors []types.Vector, seen *bloom.Registry, namespace string) ([]types.Vector, int) {
+	if seen == nil {
+		return vectors, 0
+	}
+	kept := make([]types.Vector, 0, len(vectors))
+	for _, v := range vectors {
+		if !seen.Seen(namespace, ContentKey(v)) {
+			kept = append(kept, v)
+		}
+	}
+	return kept, len(vectors) - len(kept)
+}
+
+// MarkSeen records the content of vectors written to namespace, except
+// those whose IDs are in failed.
+func MarkSeen(vectors []types.Vector, seen *bloom.Registry, namespace string, failed []string) {
+	if seen == nil {
+		return
+	}
+	skip := make(map[string]bool, len(failed))
+	for _, id := range failed {
+		skip[id] = true
+	}
+	for _, v := range vectors {
+		if !skip[v.ID] {
+			seen.Add(namespace, ContentKey(v))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package ingest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/bloom"
+	"github.com/Siddhant-K-code/distill/pkg/dedup"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestContentKey(t *testing.T) {
+	a := types.Vector{ID: "a", Values: []float32{1, 2}, Metadata: map[string]interface{}{"text": "refunds", "source": "faq", "tags": map[string]interface{}{"x": 1, "y": 2}}}
+	copied := types.Vector{ID: "b", Values: []float32{1, 2}, Metadata: map[string]interface{}{"tags": map[string]interface{}{"y": 2.0, "x": 1.0}, "source": "faq", "text": "refunds"}}
+	if !bytes.Equal(ContentKey(a), ContentKey(copied)) {
+		t.Error("same values and metadata under another ID should share a key")
+	}
+
+	edited := types.Vector{ID: "a", Values: []float32{1, 2}, Metadata: map[string]interface{}{"text": "refunds!", "source": "faq", "tags": map[string]interface{}{"x": 1, "y": 2}}}
+	reembedded := types.Vector{ID: "a", Values: []float32{1, 2.0001}, Metadata: a.Metadata}
+	moved := types.Vector{ID: "a", Values: []float32{1, 2}, Metadata: map[string]interface{}{"text": "refunds", "source": "wiki", "tags": map[string]interface{}{"x": 1, "y": 2}}}
+	retagged := types.Vector{ID: "a", Values: []float32{1, 2}, Metadata: map[string]interface{}{"text": "refunds", "source": "faq", "tags": map[string]interface{}{"x": 1}}}
+	tombstoned := a
+	tombstoned.Metadata = map[string]interface{}{"text": "refunds", "source": "faq", "tags": map[string]interface{}{"x": 1, "y": 2}, dedup.TombstoneKey: true, dedup.DuplicateOfKey: "b"}
+	if !bytes.Equal(ContentKey(a), ContentKey(tombstoned)) {
+		t.Error("a tombstoned copy should share its original's key")
+	}
+
+	for _, v := range []types.Vector{edited, reembedded, moved, retagged} {
+		if bytes.Equal(ContentKey(a), ContentKey(v)) {
+			t.Errorf("changed content %+v shares a key", v)
+		}
+	}
+}
+
+func TestSkipSeen(t *testing.T) {
+	seen := bloom.NewRegistry(100, 0.001)
+	written := []types.Vector{
+		{ID: "a", Values: []float32{1, 0}},
+		{ID: "b", Values: []float32{0, 1}},
+	}
+	MarkSeen(written, seen, "docs", []string{"b"})
+
+	batch := []types.Vector{
+		{ID: "a2", Values: []float32{1, 0}},
+		{ID: "b", Values: []float32{0, 1}},
+		{ID: "c", Values: []float32{1, 1}},
+	}
+	kept, skipped := SkipSeen(batch, seen, "docs")
+	if skipped != 1 || len(kept) != 2 || kept[0].ID != "b" || kept[1].ID != "c" {
+		t.Errorf("kept %+v, skipped %d; want b (failed before) and c", kept, skipped)
+	}
+
+	if kept, skipped := SkipSeen(batch, seen, "other"); skipped != 0 || len(kept) != 3 {
+		t.Errorf("another namespace skipped %d", skipped)
+	}
+	if kept, skipped := SkipSeen(batch, nil, "docs"); skipped != 0 || len(kept) != 3 {
+		t.Errorf("no registry skipped %d", skipped)
+	}
+}
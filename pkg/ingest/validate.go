@@ -0,0 +1,192 @@
+package ingest
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Violation identifies why a vector failed validation.
+type Violation string
+
+const (
+	// ViolationNaN is a vector containing a NaN value.
+	ViolationNaN Violation = "nan"
+
+	// ViolationInf is a vector containing +Inf or -Inf.
+	ViolationInf Violation = "inf"
+
+	// ViolationDimension is a vector whose length differs from the expected dimension.
+	ViolationDimension Violation = "dimension"
+
+	// ViolationZero is an all-zero vector, which has no direction and
+	// breaks cosine similarity.
+	ViolationZero Violation = "zero"
+)
+
+// ValidationConfig controls vector validation and normalization.
+type ValidationConfig struct {
+	// Dimension is the expected vector length, typically the index
+	// dimension. If 0, the first valid vector sets it.
+	Dimension int
+
+	// Normalize scales valid vectors to unit L2 length in place.
+	Normalize bool
+
+	// Strict fails on the first invalid vector. Otherwise invalid
+	// vectors are skipped and counted.
+	Strict bool
+}
+
+// ValidationError reports the first invalid vector in strict mode.
+type ValidationError struct {
+	ID        string
+	Violation Violation
+	Detail    string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid vector %q: %s (%s)", e.ID, e.Violation, e.Detail)
+}
+
+// Is makes errors.Is(err, errs.ErrConfig) match, so the CLI exits with
+// the config error code.
+func (e *ValidationError) Is(target error) bool {
+	return target == errs.ErrConfig
+}
+
+// ValidationReport counts the outcome of validation.
+type ValidationReport struct {
+	Checked    int64
+	Skipped    int64
+	Normalized int64
+	Violations map[Violation]int64
+}
+
+// Validator checks vectors for NaN/Inf values, dimension mismatches, and
+// zero vectors, optionally normalizing them. It is not safe for
+// concurrent use.
+type Validator struct {
+	cfg    ValidationConfig
+	dim    int
+	report ValidationReport
+}
+
+// NewValidator creates a validator with the given config.
+func NewValidator(cfg ValidationConfig) *Validator {
+	return &Validator{
+		cfg:    cfg,
+		dim:    cfg.Dimension,
+		report: ValidationReport{Violations: make(map[Violation]int64)},
+	}
+}
+
+// Check validates vec and normalizes it in place if configured. It
+// returns a *ValidationError if vec is invalid, regardless of mode.
+func (v *Validator) Check(vec *types.Vector) error {
+	v.report.Checked++
+
+	if err := v.check(vec); err != nil {
+		v.report.Violations[err.Violation]++
+		return err
+	}
+
+	if v.dim == 0 {
+		v.dim = len(vec.Values)
+	}
+	if v.cfg.Normalize && normalize(vec.Values) {
+		v.report.Normalized++
+	}
+	return nil
+}
+
+func (v *Validator) check(vec *types.Vector) *ValidationError {
+	if v.dim > 0 && len(vec.Values) != v.dim {
+		return &ValidationError{
+			ID:        vec.ID,
+			Violation: ViolationDimension,
+			Detail:    fmt.Sprintf("got %d values, expected %d", len(vec.Values), v.dim),
+		}
+	}
+
+	var sumSq float64
+	for i, x := range vec.Values {
+		f := float64(x)
+		if math.IsNaN(f) {
+			return &ValidationError{ID: vec.ID, Violation: ViolationNaN, Detail: fmt.Sprintf("index %d", i)}
+		}
+		if math.IsInf(f, 0) {
+			return &ValidationError{ID: vec.ID, Violation: ViolationInf, Detail: fmt.Sprintf("index %d", i)}
+		}
+		sumSq += f * f
+	}
+	if sumSq == 0 {
+		return &ValidationError{ID: vec.ID, Violation: ViolationZero, Detail: "L2 norm is 0"}
+	}
+	return nil
+}
+
+// Filter validates vectors and returns the valid ones. In strict mode it
+// stops at the first invalid vector and returns its *ValidationError; in
+// lenient mode invalid vectors are dropped and counted as skipped.
+func (v *Validator) Filter(vectors []types.Vector) ([]types.Vector, error) {
+	out := vectors[:0:0]
+	for i := range vectors {
+		ok, err := v.accept(&vectors[i])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, vectors[i])
+		}
+	}
+	return out, nil
+}
+
+// accept applies the configured mode to Check: it reports whether vec
+// should be kept, returning an error only in strict mode.
+func (v *Validator) accept(vec *types.Vector) (bool, error) {
+	if err := v.Check(vec); err != nil {
+		if v.cfg.Strict {
+			return false, err
+		}
+		v.report.Skipped++
+		return false, nil
+	}
+	return true, nil
+}
+
+// Dimension returns the expected dimension, or 0 if not yet known.
+func (v *Validator) Dimension() int {
+	return v.dim
+}
+
+// Report returns a copy of the validation counters.
+func (v *Validator) Report() ValidationReport {
+	r := v.report
+	r.Violations = make(map[Violation]int64, len(v.report.Violations))
+	for k, n := range v.report.Violations {
+		r.Violations[k] = n
+	}
+	return r
+}
+
+// normalize scales values to unit L2 length. Returns false if they
+// already were (within float32 precision).
+func normalize(values []float32) bool {
+	var sumSq float64
+	for _, x := range values {
+		sumSq += float64(x) * float64(x)
+	}
+	norm := math.Sqrt(sumSq)
+	if norm == 0 || math.Abs(norm-1) < 1e-6 {
+		return false
+	}
+	inv := 1 / norm
+	for i := range values {
+		values[i] = float32(float64(values[i]) * inv)
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package ingest
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func testVectors() []types.Vector {
+	return []types.Vector{
+		{ID: "ok", Values: []float32{3, 4}},
+		{ID: "nan", Values: []float32{float32(math.NaN()), 1}},
+		{ID: "inf", Values: []float32{float32(math.Inf(1)), 1}},
+		{ID: "short", Values: []float32{1}},
+		{ID: "zero", Values: []float32{0, 0}},
+	}
+}
+
+func TestValidator_Lenient(t *testing.T) {
+	v := NewValidator(ValidationConfig{Dimension: 2, Normalize: true})
+
+	valid, err := v.Filter(testVectors())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(valid) != 1 || valid[0].ID != "ok" {
+		t.Fatalf("expected only \"ok\" to pass, got %+v", valid)
+	}
+	if valid[0].Values[0] != 0.6 || valid[0].Values[1] != 0.8 {
+		t.Errorf("expected normalized [0.6 0.8], got %v", valid[0].Values)
+	}
+
+	r := v.Report()
+	if r.Checked != 5 || r.Skipped != 4 || r.Normalized != 1 {
+		t.Errorf("unexpected report: %+v", r)
+	}
+	for _, want := range []Violation{ViolationNaN, ViolationInf, ViolationDimension, ViolationZero} {
+		if r.Violations[want] != 1 {
+			t.Errorf("expected 1 %s violation, got %d", want, r.Violations[want])
+		}
+	}
+}
+
+func TestValidator_Strict(t *testing.T) {
+	v := NewValidator(ValidationConfig{Strict: true})
+
+	_, err := v.Filter(testVectors())
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if verr.ID != "nan" || verr.Violation != ViolationNaN {
+		t.Errorf("expected nan violation, got %+v", verr)
+	}
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Error("expected validation error to match errs.ErrConfig")
+	}
+}
+
+func TestValidator_DimensionFromFirstVector(t *testing.T) {
+	v := NewValidator(ValidationConfig{})
+
+	valid, err := v.Filter([]types.Vector{
+		{ID: "a", Values: []float32{1, 0, 0}},
+		{ID: "b", Values: []float32{1, 0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(valid) != 1 || v.Dimension() != 3 {
+		t.Errorf("expected dimension 3 with one valid vector, got %d/%d", v.Dimension(), len(valid))
+	}
+	if valid[0].Values[0] != 1 {
+		t.Error("vectors should not be normalized unless configured")
+	}
+}
@@ -0,0 +1,265 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Fetcher fetches vectors back by ID. *pinecone.Client implements it.
+type Fetcher interface {
+	FetchVectors(ctx context.Context, ids []string) (map[string]types.Vector, error)
+}
+
+// VerifyConfig controls post-upload verification.
+type VerifyConfig struct {
+	// SampleSize is the number of uploaded vectors to fetch back. Zero
+	// verifies all of them.
+	SampleSize int
+
+	// Tolerance is the largest per-component difference between sent and
+	// stored values that still counts as a match.
+	Tolerance float64
+
+	// Retries is how many times vectors missing from the index are fetched
+	// again, RetryDelay apart, before being reported. Freshly upserted
+	// vectors can take a moment to become visible.
+	Retries    int
+	RetryDelay time.Duration
+
+	// FetchSize is the number of IDs per fetch request.
+	FetchSize int
+
+	// Seed seeds the sample. If 0, the current time is used.
+	Seed int64
+}
+
+// DefaultVerifyConfig returns sensible defaults for verification.
+func DefaultVerifyConfig() VerifyConfig {
+	return VerifyConfig{
+		SampleSize: 1000,
+		Tolerance:  1e-5,
+		Retries:    3,
+		RetryDelay: 2 * time.Second,
+		FetchSize:  100,
+	}
+}
+
+// Mismatch is a sampled vector whose stored copy differs from what was
+// sent.
+type Mismatch struct {
+	ID string `json:"id"`
+
+	// Field is "values" or "metadata".
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// VerifyReport is the outcome of a verification pass.
+type VerifyReport struct {
+	// Population is the number of vectors the sample was drawn from.
+	Population int `json:"population"`
+
+	// Checked is the number of vectors fetched back.
+	Checked int `json:"checked"`
+
+	Missing    []string   `json:"missing,omitempty"`
+	Mismatched []Mismatch `json:"mismatched,omitempty"`
+}
+
+// OK reports whether every sampled vector was found intact.
+func (r *VerifyReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Mismatched) == 0
+}
+
+// Failed returns the number of sampled vectors missing or mismatched.
+func (r *VerifyReport) Failed() int {
+	ids := make(map[string]bool, len(r.Missing)+len(r.Mismatched))
+	for _, id := range r.Missing {
+		ids[id] = true
+	}
+	for _, m := range r.Mismatched {
+		ids[m.ID] = true
+	}
+	return len(ids)
+}
+
+// Verify samples vectors, fetches them back through f, and reports any
+// that are missing from the index or whose values or metadata differ
+// from what was uploaded. Metadata is compared after a JSON round trip,
+// since indexes store numbers as floats. When an ID appears more than
+// once, the last copy is the one expected, as with an upsert.
+func Verify(ctx context.Context, f Fetcher, vectors []types.Vector, cfg VerifyConfig) (*VerifyReport, error) {
+	def := DefaultVerifyConfig()
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = def.Tolerance
+	}
+	if cfg.FetchSize <= 0 {
+		cfg.FetchSize = def.FetchSize
+	}
+
+	vectors = lastByID(vectors)
+	sample := sampleVectors(vectors, cfg.SampleSize, cfg.Seed)
+	report := &VerifyReport{Population: len(vectors), Checked: len(sample)}
+
+	pending := make(map[string]types.Vector, len(sample))
+	for _, v := range sample {
+		pending[v.ID] = v
+	}
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			if attempt > cfg.Retries {
+				break
+			}
+			timer := time.NewTimer(cfg.RetryDelay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return report, ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		ids := make([]string, 0, len(pending))
+		for _, v := range sample {
+			if _, ok := pending[v.ID]; ok {
+				ids = append(ids, v.ID)
+			}
+		}
+		for start := 0; start < len(ids); start += cfg.FetchSize {
+			chunk := ids[start:min(start+cfg.FetchSize, len(ids))]
+			found, err := f.FetchVectors(ctx, chunk)
+			if err != nil {
+				return report, err
+			}
+			for id, got := range found {
+				want, ok := pending[id]
+				if !ok {
+					continue
+				}
+				delete(pending, id)
+				report.Mismatched = append(report.Mismatched, compareVector(want, got, cfg.Tolerance)...)
+			}
+		}
+	}
+
+	for _, v := range sample {
+		if _, ok := pending[v.ID]; ok {
+			report.Missing = append(report.Missing, v.ID)
+		}
+	}
+	return report, nil
+}
+
+// lastByID drops all but the last copy of each ID, keeping the order of
+// the last copies.
+func lastByID(vectors []types.Vector) []types.Vector {
+	last := make(map[string]int, len(vectors))
+	for i, v := range vectors {
+		last[v.ID] = i
+	}
+	if len(last) == len(vectors) {
+		return vectors
+	}
+	out := make([]types.Vector, 0, len(last))
+	for i, v := range vectors {
+		if last[v.ID] == i {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// sampleVectors returns up to n vectors chosen uniformly without
+// replacement, or all of them if n is 0 or at least len(vectors).
+func sampleVectors(vectors []types.Vector, n int, seed int64) []types.Vector {
+	if n <= 0 || n >= len(vectors) {
+		return vectors
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	idx := rng.Perm(len(vectors))[:n]
+	out := make([]types.Vector, n)
+	for i, j := range idx {
+		out[i] = vectors[j]
+	}
+	return out
+}
+
+// compareVector returns the differences between a sent vector and its
+// stored copy.
+func compareVector(want, got types.Vector, tolerance float64) []Mismatch {
+	var out []Mismatch
+	if len(want.Values) != len(got.Values) {
+		out = append(out, Mismatch{ID: want.ID, Field: "values",
+			Detail: fmt.Sprintf("dimension %d, expected %d", len(got.Values), len(want.Values))})
+	} else {
+		for i := range want.Values {
+			if d := math.Abs(float64(want.Values[i]) - float64(got.Values[i])); d > tolerance {
+				out = append(out, Mismatch{ID: want.ID, Field: "values",
+					Detail: fmt.Sprintf("component %d is %g, expected %g", i, got.Values[i], want.Values[i])})
+				break
+			}
+		}
+	}
+
+	wantMeta, gotMeta := normalizeMetadata(want.Metadata), normalizeMetadata(got.Metadata)
+	if !reflect.DeepEqual(wantMeta, gotMeta) {
+		out = append(out, Mismatch{ID: want.ID, Field: "metadata", Detail: metadataDiff(wantMeta, gotMeta)})
+	}
+	return out
+}
+
+// normalizeMetadata round-trips metadata through JSON so that values
+// compare the way the index stores them. Empty metadata is nil.
+func normalizeMetadata(m map[string]interface{}) map[string]interface{} {
+	if len(m) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return m
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return m
+	}
+	return out
+}
+
+// metadataDiff names the first differing key, in a stable order.
+func metadataDiff(want, got map[string]interface{}) string {
+	keys := make([]string, 0, len(want)+len(got))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	for k := range got {
+		if _, ok := want[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		w, wok := want[k]
+		g, gok := got[k]
+		switch {
+		case !gok:
+			return fmt.Sprintf("key %q missing", k)
+		case !wok:
+			return fmt.Sprintf("unexpected key %q", k)
+		case !reflect.DeepEqual(w, g):
+			return fmt.Sprintf("key %q is %v, expected %v", k, g, w)
+		}
+	}
+	return "metadata differs"
+}
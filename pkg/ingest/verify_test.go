@@ -0,0 +1,101 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// fakeIndex serves fetches from a map; IDs in late appear only after the
+// first fetch, like vectors still being indexed.
+type fakeIndex struct {
+	vectors map[string]types.Vector
+	late    map[string]bool
+	fetches int
+	err     error
+}
+
+func (f *fakeIndex) FetchVectors(ctx context.Context, ids []string) (map[string]types.Vector, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.fetches++
+	out := map[string]types.Vector{}
+	for _, id := range ids {
+		if v, ok := f.vectors[id]; ok && (!f.late[id] || f.fetches > 1) {
+			out[id] = v
+		}
+	}
+	return out, nil
+}
+
+func TestVerify(t *testing.T) {
+	sent := []types.Vector{
+		{ID: "ok", Values: []float32{1, 2}, Metadata: map[string]interface{}{"page": 3, "tags": []string{"a"}}},
+		{ID: "late", Values: []float32{1, 2}},
+		{ID: "lost", Values: []float32{1, 2}},
+		{ID: "drift", Values: []float32{1, 2}},
+		{ID: "meta", Values: []float32{1, 2}, Metadata: map[string]interface{}{"source": "a.md"}},
+		{ID: "dup", Values: []float32{0, 0}},
+		{ID: "dup", Values: []float32{5, 5}},
+	}
+	index := &fakeIndex{
+		vectors: map[string]types.Vector{
+			// Stored metadata comes back with float numbers
+			"ok":    {ID: "ok", Values: []float32{1, 2.000001}, Metadata: map[string]interface{}{"page": 3.0, "tags": []interface{}{"a"}}},
+			"late":  {ID: "late", Values: []float32{1, 2}},
+			"drift": {ID: "drift", Values: []float32{1, 2.5}},
+			"meta":  {ID: "meta", Values: []float32{1, 2}},
+			"dup":   {ID: "dup", Values: []float32{5, 5}},
+		},
+		late: map[string]bool{"late": true},
+	}
+
+	r, err := Verify(context.Background(), index, sent, VerifyConfig{Retries: 1, FetchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Population != 6 || r.Checked != 6 {
+		t.Errorf("expected 6 unique vectors checked, got %d/%d", r.Checked, r.Population)
+	}
+	if !reflect.DeepEqual(r.Missing, []string{"lost"}) {
+		t.Errorf("expected only \"lost\" missing, got %v", r.Missing)
+	}
+	got := map[string]string{}
+	for _, m := range r.Mismatched {
+		got[m.ID] = m.Field
+	}
+	if !reflect.DeepEqual(got, map[string]string{"drift": "values", "meta": "metadata"}) {
+		t.Errorf("unexpected mismatches: %+v", r.Mismatched)
+	}
+	if r.OK() || r.Failed() != 3 {
+		t.Errorf("expected 3 failed vectors, got %d", r.Failed())
+	}
+}
+
+func TestVerify_Sample(t *testing.T) {
+	sent := make([]types.Vector, 50)
+	stored := map[string]types.Vector{}
+	for i := range sent {
+		sent[i] = types.Vector{ID: string(rune('A' + i)), Values: []float32{float32(i)}}
+		stored[sent[i].ID] = sent[i]
+	}
+	r, err := Verify(context.Background(), &fakeIndex{vectors: stored}, sent, VerifyConfig{SampleSize: 10, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Checked != 10 || r.Population != 50 || !r.OK() {
+		t.Errorf("expected a clean sample of 10 from 50, got %+v", r)
+	}
+}
+
+func TestVerify_FetchError(t *testing.T) {
+	boom := errors.New("unavailable")
+	_, err := Verify(context.Background(), &fakeIndex{err: boom}, []types.Vector{{ID: "a"}}, VerifyConfig{})
+	if !errors.Is(err, boom) {
+		t.Errorf("expected fetch error, got %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package math
+
+import (
+	"math"
+)
+
+// Dimension reduction methods.
+const (
+	ReduceTruncate = "truncate"
+	ReducePCA      = "pca"
+)
+
+// pcaIterations bounds the power iterations spent on each component.
+const pcaIterations = 64
+
+// Reduce projects vectors down to dim dimensions using method
+// (ReduceTruncate or ReducePCA). Vectors already at or below dim are
+// returned unchanged. The input is never modified.
+func Reduce(vectors [][]float32, dim int, method string) [][]float32 {
+	if method == ReducePCA {
+		return PCA(vectors, dim)
+	}
+	return Truncate(vectors, dim)
+}
+
+// Truncate keeps the first dim components of each vector and rescales
+// the result to unit length. This suits Matryoshka-trained models such
+// as text-embedding-3, whose leading dimensions carry most of the signal.
+func Truncate(vectors [][]float32, dim int) [][]float32 {
+	out := make([][]float32, len(vectors))
+	for i, v := range vectors {
+		if dim <= 0 || len(v) <= dim {
+			out[i] = v
+			continue
+		}
+		t := make([]float32, dim)
+		copy(t, v[:dim])
+		NormalizeInPlace(t)
+		out[i] = t
+	}
+	return out
+}
+
+// PCA projects vectors onto their top dim principal components, found by
+// power iteration with deflation on the mean-centred set. Distances
+// between the projected vectors approximate the originals well enough
+// for plotting. Components are only meaningful within one call, so
+// results from separate requests should not be compared.
+func PCA(vectors [][]float32, dim int) [][]float32 {
+	if len(vectors) == 0 || dim <= 0 {
+		return vectors
+	}
+	d := len(vectors[0])
+	for _, v := range vectors {
+		if len(v) != d {
+			// Mixed dimensions cannot share a basis; fall back.
+			return Truncate(vectors, dim)
+		}
+	}
+	if d <= dim {
+		return vectors
+	}
+
+	// Centre the data in float64 to keep the iterations stable.
+	mean := make([]float64, d)
+	for _, v := range vectors {
+		for j, x := range v {
+			mean[j] += float64(x)
+		}
+	}
+	for j := range mean {
+		mean[j] /= float64(len(vectors))
+	}
+	x := make([][]float64, len(vectors))
+	for i, v := range vectors {
+		row := make([]float64, d)
+		for j, val := range v {
+			row[j] = float64(val) - mean[j]
+		}
+		x[i] = row
+	}
+
+	out := make([][]float32, len(vectors))
+	for i := range out {
+		out[i] = make([]float32, dim)
+	}
+
+	// The covariance has rank at most n-1, so components past that are
+	// zero; leave those coordinates at zero.
+	rank := dim
+	if rank > len(vectors)-1 {
+		rank = len(vectors) - 1
+	}
+
+	proj := make([]float64, len(vectors))
+	for c := 0; c < rank; c++ {
+		comp := principalComponent(x, proj)
+		if comp == nil {
+			break
+		}
+		for i, row := range x {
+			p := dot64(row, comp)
+			out[i][c] = float32(p)
+			// Deflate so the next iteration finds the next component.
+			for j := range row {
+				row[j] -= p * comp[j]
+			}
+		}
+	}
+	return out
+}
+
+// principalComponent returns the dominant eigenvector of XᵀX by power
+// iteration, or nil if the rows have no variance left. proj is scratch
+// space of len(x).
+func principalComponent(x [][]float64, proj []float64) []float64 {
+	d := len(x[0])
+
+	// Deterministic start: the largest-norm row points toward the
+	// dominant variance and avoids a start orthogonal to it.
+	v := make([]float64, d)
+	best := 0.0
+	for _, row := range x {
+		if n := dot64(row, row); n > best {
+			best = n
+			copy(v, row)
+		}
+	}
+	if best < 1e-12 {
+		return nil
+	}
+	scale(v, 1/math.Sqrt(best))
+
+	next := make([]float64, d)
+	for it := 0; it < pcaIterations; it++ {
+		for i, row := range x {
+			proj[i] = dot64(row, v)
+		}
+		for j := range next {
+			next[j] = 0
+		}
+		for i, row := range x {
+			p := proj[i]
+			for j, val := range row {
+				next[j] += p * val
+			}
+		}
+		n := math.Sqrt(dot64(next, next))
+		if n < 1e-12 {
+			return nil
+		}
+		scale(next, 1/n)
+
+		delta := 0.0
+		for j := range v {
+			delta += math.Abs(next[j] - v[j])
+		}
+		v, next = next, v
+		if delta < 1e-9 {
+			break
+		}
+	}
+
+	// Fix the sign so the largest component is positive, keeping output
+	// stable across runs.
+	maxIdx := 0
+	for j := range v {
+		if math.Abs(v[j]) > math.Abs(v[maxIdx]) {
+			maxIdx = j
+		}
+	}
+	if v[maxIdx] < 0 {
+		scale(v, -1)
+	}
+	return v
+}
+
+func dot64(a, b []float64) float64 {
+	var s float64
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
+
+func scale(v []float64, f float64) {
+	for i := range v {
+		v[i] *= f
+	}
+}
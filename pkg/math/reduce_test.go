@@ -0,0 +1,98 @@
+package math
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	in := [][]float32{{3, 4, 12}, {1, 0}}
+	got := Truncate(in, 2)
+
+	if len(got[0]) != 2 {
+		t.Fatalf("expected 2 dims, got %d", len(got[0]))
+	}
+	if math.Abs(float64(got[0][0])-0.6) > 1e-6 || math.Abs(float64(got[0][1])-0.8) > 1e-6 {
+		t.Errorf("expected [0.6 0.8], got %v", got[0])
+	}
+	if len(got[1]) != 2 || got[1][0] != 1 {
+		t.Errorf("short vector should be unchanged, got %v", got[1])
+	}
+	if in[0][0] != 3 {
+		t.Error("input should not be modified")
+	}
+}
+
+func TestPCA_PreservesStructure(t *testing.T) {
+	// Two tight groups far apart along a diagonal in 6 dimensions.
+	in := [][]float32{
+		{1, 1, 1, 0, 0, 0.01},
+		{1.01, 1, 0.99, 0, 0.01, 0},
+		{0, 0, 0.01, 1, 1, 1},
+		{0.01, 0, 0, 0.99, 1.01, 1},
+	}
+	got := PCA(in, 2)
+
+	if len(got) != 4 || len(got[0]) != 2 {
+		t.Fatalf("expected 4x2 output, got %dx%d", len(got), len(got[0]))
+	}
+
+	within := EuclideanDistance(got[0], got[1]) + EuclideanDistance(got[2], got[3])
+	between := EuclideanDistance(got[0], got[2])
+	if between < 10*within {
+		t.Errorf("expected groups to stay separated: within=%f between=%f", within, between)
+	}
+
+	// The first component separates the groups, so they land on
+	// opposite sides of zero.
+	if got[0][0]*got[2][0] >= 0 {
+		t.Errorf("expected first component to split groups, got %v and %v", got[0], got[2])
+	}
+}
+
+func TestPCA_Deterministic(t *testing.T) {
+	in := [][]float32{{1, 2, 3, 4}, {4, 3, 2, 1}, {1, 3, 2, 4}, {2, 2, 2, 2}}
+	a := PCA(in, 2)
+	b := PCA(in, 2)
+	for i := range a {
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				t.Fatalf("expected identical output, got %v and %v", a, b)
+			}
+		}
+	}
+}
+
+func TestPCA_Edges(t *testing.T) {
+	if got := PCA(nil, 2); got != nil {
+		t.Errorf("expected nil for empty input, got %v", got)
+	}
+
+	// A single vector has no variance: all coordinates are zero.
+	got := PCA([][]float32{{1, 2, 3}}, 2)
+	if len(got[0]) != 2 || got[0][0] != 0 || got[0][1] != 0 {
+		t.Errorf("expected zero projection, got %v", got)
+	}
+
+	// Already small enough.
+	in := [][]float32{{1, 2}, {3, 4}}
+	if got := PCA(in, 4); len(got[0]) != 2 {
+		t.Errorf("expected unchanged vectors, got %v", got)
+	}
+
+	// Mixed dimensions fall back to truncation.
+	got = PCA([][]float32{{1, 0, 0}, {0, 1}}, 1)
+	if len(got[0]) != 1 || got[0][0] != 1 {
+		t.Errorf("expected truncation fallback, got %v", got)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	in := [][]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	if got := Reduce(in, 2, ReduceTruncate); len(got[0]) != 2 {
+		t.Errorf("truncate: expected 2 dims, got %d", len(got[0]))
+	}
+	if got := Reduce(in, 2, ReducePCA); len(got[0]) != 2 {
+		t.Errorf("pca: expected 2 dims, got %d", len(got[0]))
+	}
+}
@@ -68,6 +68,54 @@ func CosineDistance(a, b []float32) float64 {
 	return 1.0 - similarity
 }
 
+// SquaredNorm computes the squared L2 norm of v, summed in the same order
+// as CosineDistance sums magnitudes.
+func SquaredNorm(v []float32) float64 {
+	var sum float64
+	n := len(v)
+
+	i := 0
+	for ; i <= n-4; i += 4 {
+		sum += float64(v[i])*float64(v[i]) +
+			float64(v[i+1])*float64(v[i+1]) +
+			float64(v[i+2])*float64(v[i+2]) +
+			float64(v[i+3])*float64(v[i+3])
+	}
+
+	for ; i < n; i++ {
+		sum += float64(v[i]) * float64(v[i])
+	}
+
+	return sum
+}
+
+// CosineDistanceNorms is CosineDistance with the squared norms of a and b
+// precomputed by SquaredNorm, leaving only the dot product to compute.
+// Comparing each vector with many others, this does a third of the
+// floating-point work, and the result is identical to CosineDistance's.
+// Vectors of different lengths fall back to CosineDistance.
+func CosineDistanceNorms(a, b []float32, sqA, sqB float64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 2.0
+	}
+	if len(a) != len(b) {
+		return CosineDistance(a, b)
+	}
+
+	denom := math.Sqrt(sqA * sqB)
+	if denom == 0 {
+		return 2.0
+	}
+
+	similarity := DotProduct(a, b) / denom
+	if similarity > 1.0 {
+		similarity = 1.0
+	} else if similarity < -1.0 {
+		similarity = -1.0
+	}
+	return 1.0 - similarity
+}
+
 // CosineSimilarity computes cosine similarity (1 - distance).
 // Returns a value in [-1, 1] where 1 = identical, -1 = opposite.
 func CosineSimilarity(a, b []float32) float64 {
@@ -0,0 +1,39 @@
+package math
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestCosineDistanceNorms(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	vec := func(n int) []float32 {
+		v := make([]float32, n)
+		for i := range v {
+			v[i] = rng.Float32()*2 - 1
+		}
+		return v
+	}
+
+	for _, n := range []int{1, 3, 4, 7, 1536} {
+		a, b := vec(n), vec(n)
+		want := CosineDistance(a, b)
+		if got := CosineDistanceNorms(a, b, SquaredNorm(a), SquaredNorm(b)); got != want {
+			t.Errorf("n=%d: CosineDistanceNorms = %v, CosineDistance = %v", n, got, want)
+		}
+	}
+
+	a, short := vec(8), vec(5)
+	if got, want := CosineDistanceNorms(a, short, SquaredNorm(a), SquaredNorm(short)), CosineDistance(a, short); got != want {
+		t.Errorf("mismatched lengths: %v, want %v", got, want)
+	}
+	zero := make([]float32, 8)
+	if got := CosineDistanceNorms(a, zero, SquaredNorm(a), 0); got != 2 {
+		t.Errorf("zero vector: %v, want 2", got)
+	}
+	nan := []float32{float32(math.NaN()), 1}
+	if got := CosineDistanceNorms(nan, []float32{1, 1}, SquaredNorm(nan), 2); !math.IsNaN(got) {
+		t.Errorf("NaN component: %v, want NaN like CosineDistance", got)
+	}
+}
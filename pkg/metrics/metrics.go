@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/profiling"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -14,6 +15,7 @@ import (
 // Metrics holds all Prometheus metric collectors for Distill.
 type Metrics struct {
 	RequestsTotal    *prometheus.CounterVec
+	RequestsRejected *prometheus.CounterVec
 	RequestDuration  *prometheus.HistogramVec
 	ChunksProcessed  *prometheus.CounterVec
 	ReductionRatio   *prometheus.HistogramVec
@@ -34,6 +36,26 @@ type Metrics struct {
 	CacheBoundaryRetreats  *prometheus.CounterVec
 	CacheEstimatedSavings  *prometheus.CounterVec
 
+	// Online tuner metrics, by namespace and arm.
+	TunerServed    *prometheus.CounterVec
+	TunerFeedback  *prometheus.CounterVec
+	TunerReward    *prometheus.CounterVec
+	TunerAdoptions *prometheus.CounterVec
+	TunerParams    *prometheus.GaugeVec
+	TunerEnabled   prometheus.Gauge
+
+	// ACL audit counters.
+	ACLChunks *prometheus.CounterVec
+
+	// Prompt-injection filter counters.
+	InjectionChunks *prometheus.CounterVec
+
+	// Broker result cache lookups.
+	ResultCache *prometheus.CounterVec
+
+	// Retrievals with too few candidates to deduplicate.
+	SparseResults *prometheus.CounterVec
+
 	registry *prometheus.Registry
 }
 
@@ -41,8 +63,11 @@ type Metrics struct {
 func New() *Metrics {
 	reg := prometheus.NewRegistry()
 
-	// Include default Go and process collectors
-	reg.MustRegister(collectors.NewGoCollector())
+	// Include default Go and process collectors. GC runtime metrics add
+	// the go_gc_pauses_seconds histogram and the effective GOGC/GOMEMLIMIT.
+	reg.MustRegister(collectors.NewGoCollector(
+		collectors.WithGoCollectorRuntimeMetrics(collectors.MetricsGC),
+	))
 	reg.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 
 	m := &Metrics{
@@ -53,6 +78,13 @@ func New() *Metrics {
 			},
 			[]string{"endpoint", "status"},
 		),
+		RequestsRejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "distill_requests_rejected_total",
+				Help: "Requests rejected for exceeding input size limits, by endpoint and limit.",
+			},
+			[]string{"endpoint", "limit"},
+		),
 		RequestDuration: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name:    "distill_request_duration_seconds",
@@ -155,11 +187,91 @@ func New() *Metrics {
 			[]string{"session_id"},
 		),
 
+		// Online tuner metrics.
+		TunerServed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "distill_tuner_served_total",
+				Help: "Requests served by the online tuner, by namespace and arm.",
+			},
+			[]string{"namespace", "arm"},
+		),
+		TunerFeedback: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "distill_tuner_feedback_total",
+				Help: "Feedback reports received by the online tuner, by namespace and arm.",
+			},
+			[]string{"namespace", "arm"},
+		),
+		TunerReward: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "distill_tuner_reward_total",
+				Help: "Sum of feedback rewards (0-1) by namespace and arm; divide by distill_tuner_feedback_total for the mean.",
+			},
+			[]string{"namespace", "arm"},
+		),
+		TunerAdoptions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "distill_tuner_adoptions_total",
+				Help: "Times a challenger replaced a namespace's settings.",
+			},
+			[]string{"namespace"},
+		),
+		TunerParams: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "distill_tuner_incumbent",
+				Help: "Settings the online tuner serves most traffic with, by namespace and param (threshold, lambda).",
+			},
+			[]string{"namespace", "param"},
+		),
+		TunerEnabled: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "distill_tuner_enabled",
+				Help: "1 while the online tuner is exploring, 0 when switched off.",
+			},
+		),
+
+		// ACL audit counters.
+		ACLChunks: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "distill_acl_chunks_total",
+				Help: "Retrieved chunks checked against the caller's identity, by endpoint and decision (allowed, denied, unlabeled).",
+			},
+			[]string{"endpoint", "decision"},
+		),
+
+		// Prompt-injection filter counters.
+		InjectionChunks: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "distill_injection_chunks_total",
+				Help: "Retrieved chunks scored as prompt injection, by endpoint and action (kept, blocked).",
+			},
+			[]string{"endpoint", "action"},
+		),
+
+		// Broker result cache lookups.
+		ResultCache: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "distill_result_cache_requests_total",
+				Help: "Broker result cache lookups, by endpoint and result (hit, miss).",
+			},
+			[]string{"endpoint", "result"},
+		),
+
+		// Retrievals with too few candidates to deduplicate.
+		SparseResults: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "distill_sparse_results_total",
+				Help: "Retrievals left with too few candidates to deduplicate, by endpoint, namespace, and result (empty, sparse).",
+			},
+			[]string{"endpoint", "namespace", "result"},
+		),
+
 		registry: reg,
 	}
 
 	reg.MustRegister(
 		m.RequestsTotal,
+		m.RequestsRejected,
 		m.RequestDuration,
 		m.ChunksProcessed,
 		m.ReductionRatio,
@@ -174,6 +286,16 @@ func New() *Metrics {
 		m.CacheBoundaryAdvances,
 		m.CacheBoundaryRetreats,
 		m.CacheEstimatedSavings,
+		m.TunerServed,
+		m.TunerFeedback,
+		m.TunerReward,
+		m.TunerAdoptions,
+		m.TunerParams,
+		m.TunerEnabled,
+		m.ACLChunks,
+		m.InjectionChunks,
+		m.ResultCache,
+		m.SparseResults,
 	)
 
 	return m
@@ -191,6 +313,11 @@ func (m *Metrics) RecordRequest(endpoint string, statusCode int, duration time.D
 	m.RequestDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
 }
 
+// RecordRejected records a request rejected for exceeding an input limit.
+func (m *Metrics) RecordRejected(endpoint, limit string) {
+	m.RequestsRejected.WithLabelValues(endpoint, limit).Inc()
+}
+
 // RecordDedup records deduplication-specific metrics.
 func (m *Metrics) RecordDedup(endpoint string, inputCount, outputCount, clusterCount int) {
 	m.ChunksProcessed.WithLabelValues("input").Add(float64(inputCount))
@@ -203,6 +330,37 @@ func (m *Metrics) RecordDedup(endpoint string, inputCount, outputCount, clusterC
 	}
 }
 
+// Totals returns the requests served and chunks processed since the
+// metrics were created, summed over endpoints and status codes.
+func (m *Metrics) Totals() (requests, chunksIn, chunksOut int64) {
+	families, err := m.registry.Gather()
+	if err != nil {
+		return 0, 0, 0
+	}
+	for _, f := range families {
+		for _, metric := range f.GetMetric() {
+			v := int64(metric.GetCounter().GetValue())
+			switch f.GetName() {
+			case "distill_requests_total":
+				requests += v
+			case "distill_chunks_processed_total":
+				for _, l := range metric.GetLabel() {
+					if l.GetName() != "direction" {
+						continue
+					}
+					switch l.GetValue() {
+					case "input":
+						chunksIn += v
+					case "output":
+						chunksOut += v
+					}
+				}
+			}
+		}
+	}
+	return requests, chunksIn, chunksOut
+}
+
 // UsageRecord holds the token counts returned by the Anthropic API in the
 // usage block of every response. Pass this to RecordCacheUsage after each
 // API call to keep the cache cost metrics up to date.
@@ -261,7 +419,82 @@ func (m *Metrics) RecordCacheBoundary(sessionID string, boundaryTokens int, adva
 	}
 }
 
-// Middleware returns an HTTP middleware that instruments requests.
+// RecordTunerServed records a request the online tuner assigned to arm.
+func (m *Metrics) RecordTunerServed(namespace, arm string) {
+	m.TunerServed.WithLabelValues(namespaceLabel(namespace), arm).Inc()
+}
+
+// RecordTunerFeedback records a feedback report for arm.
+func (m *Metrics) RecordTunerFeedback(namespace, arm string, reward float64) {
+	ns := namespaceLabel(namespace)
+	m.TunerFeedback.WithLabelValues(ns, arm).Inc()
+	m.TunerReward.WithLabelValues(ns, arm).Add(reward)
+}
+
+// RecordTunerIncumbent records a namespace's incumbent settings, counting
+// an adoption if they just changed.
+func (m *Metrics) RecordTunerIncumbent(namespace string, threshold, lambda float64, adopted bool) {
+	ns := namespaceLabel(namespace)
+	m.TunerParams.WithLabelValues(ns, "threshold").Set(threshold)
+	m.TunerParams.WithLabelValues(ns, "lambda").Set(lambda)
+	if adopted {
+		m.TunerAdoptions.WithLabelValues(ns).Inc()
+	}
+}
+
+// SetTunerEnabled records whether the online tuner is on.
+func (m *Metrics) SetTunerEnabled(enabled bool) {
+	if enabled {
+		m.TunerEnabled.Set(1)
+	} else {
+		m.TunerEnabled.Set(0)
+	}
+}
+
+// RecordACL records one request's ACL decisions. Denied chunks without
+// ACL metadata are counted as unlabeled rather than denied.
+func (m *Metrics) RecordACL(endpoint string, allowed, denied, unlabeled int) {
+	m.ACLChunks.WithLabelValues(endpoint, "allowed").Add(float64(allowed))
+	m.ACLChunks.WithLabelValues(endpoint, "denied").Add(float64(denied - unlabeled))
+	m.ACLChunks.WithLabelValues(endpoint, "unlabeled").Add(float64(unlabeled))
+}
+
+// RecordInjection records one request's prompt-injection filter
+// decisions. Flagged chunks not blocked are counted as kept.
+func (m *Metrics) RecordInjection(endpoint string, flagged, blocked int) {
+	m.InjectionChunks.WithLabelValues(endpoint, "kept").Add(float64(flagged - blocked))
+	m.InjectionChunks.WithLabelValues(endpoint, "blocked").Add(float64(blocked))
+}
+
+// RecordResultCache records a broker result cache lookup.
+func (m *Metrics) RecordResultCache(endpoint string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.ResultCache.WithLabelValues(endpoint, result).Inc()
+}
+
+// RecordSparse records a retrieval left with too few candidates to
+// deduplicate; empty is true when nothing was returned.
+func (m *Metrics) RecordSparse(endpoint, namespace string, empty bool) {
+	result := "sparse"
+	if empty {
+		result = "empty"
+	}
+	m.SparseResults.WithLabelValues(endpoint, namespaceLabel(namespace), result).Inc()
+}
+
+// namespaceLabel names the default namespace "default".
+func namespaceLabel(namespace string) string {
+	if namespace == "" {
+		return "default"
+	}
+	return namespace
+}
+
+// Middleware returns an HTTP middleware that instruments requests. While
+// profiling is on, it also labels their samples with endpoint.
 func (m *Metrics) Middleware(endpoint string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		m.ActiveRequests.Inc()
@@ -270,7 +503,7 @@ func (m *Metrics) Middleware(endpoint string, next http.HandlerFunc) http.Handle
 		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 		start := time.Now()
 
-		next.ServeHTTP(rw, r)
+		profiling.Handler(endpoint, next)(rw, r)
 
 		m.RecordRequest(endpoint, rw.statusCode, time.Since(start))
 	}
@@ -39,6 +39,17 @@ func TestRecordRequest(t *testing.T) {
 	}
 }
 
+func TestRecordRejected(t *testing.T) {
+	m := New()
+	m.RecordRejected("/v1/dedupe", "max_chunks")
+	m.RecordRejected("/v1/dedupe", "max_chunks")
+
+	val := counterValue(t, m.RequestsRejected, "endpoint", "/v1/dedupe", "limit", "max_chunks")
+	if val != 2 {
+		t.Errorf("expected 2 rejections, got %f", val)
+	}
+}
+
 func TestRecordDedup(t *testing.T) {
 	m := New()
 	m.RecordDedup("/v1/dedupe", 10, 6, 6)
@@ -59,6 +70,19 @@ func TestRecordDedup(t *testing.T) {
 	}
 }
 
+func TestTotals(t *testing.T) {
+	m := New()
+	m.RecordRequest("/v1/dedupe", 200, time.Millisecond)
+	m.RecordRequest("/v1/retrieve", 400, time.Millisecond)
+	m.RecordDedup("/v1/dedupe", 10, 6, 6)
+	m.RecordDedup("/v1/retrieve", 5, 2, 2)
+
+	requests, in, out := m.Totals()
+	if requests != 2 || in != 15 || out != 8 {
+		t.Errorf("Totals() = %d, %d, %d; want 2, 15, 8", requests, in, out)
+	}
+}
+
 func TestRecordDedup_ZeroInput(t *testing.T) {
 	m := New()
 	// Should not panic on zero input
@@ -126,6 +150,12 @@ func TestHandler(t *testing.T) {
 	if !strings.Contains(body, "go_goroutines") {
 		t.Error("metrics output missing go runtime metrics")
 	}
+	if !strings.Contains(body, "go_gc_pauses_seconds") {
+		t.Error("metrics output missing GC pause histogram")
+	}
+	if !strings.Contains(body, "go_gc_gogc_percent") {
+		t.Error("metrics output missing effective GOGC")
+	}
 }
 
 func TestActiveRequests(t *testing.T) {
@@ -291,3 +321,81 @@ func counterValue(t *testing.T, cv *prometheus.CounterVec, labelPairs ...string)
 	}
 	return metric.GetCounter().GetValue()
 }
+
+func TestRecordTuner(t *testing.T) {
+	m := New()
+	m.RecordTunerServed("", "incumbent")
+	m.RecordTunerFeedback("", "incumbent", 0.25)
+	m.RecordTunerFeedback("", "incumbent", 0.5)
+	m.RecordTunerIncumbent("docs", 0.17, 0.5, true)
+
+	if val := counterValue(t, m.TunerServed, "namespace", "default", "arm", "incumbent"); val != 1 {
+		t.Errorf("expected 1 served, got %f", val)
+	}
+	if val := counterValue(t, m.TunerReward, "namespace", "default", "arm", "incumbent"); val != 0.75 {
+		t.Errorf("expected reward sum 0.75, got %f", val)
+	}
+	if val := counterValue(t, m.TunerAdoptions, "namespace", "docs"); val != 1 {
+		t.Errorf("expected 1 adoption, got %f", val)
+	}
+
+	var metric dto.Metric
+	g, err := m.TunerParams.GetMetricWithLabelValues("docs", "threshold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	if metric.GetGauge().GetValue() != 0.17 {
+		t.Errorf("expected threshold 0.17, got %f", metric.GetGauge().GetValue())
+	}
+}
+
+func TestRecordACL(t *testing.T) {
+	m := New()
+	m.RecordACL("/v1/retrieve", 5, 3, 1)
+
+	for decision, want := range map[string]float64{"allowed": 5, "denied": 2, "unlabeled": 1} {
+		if val := counterValue(t, m.ACLChunks, "endpoint", "/v1/retrieve", "decision", decision); val != want {
+			t.Errorf("%s: expected %v, got %v", decision, want, val)
+		}
+	}
+}
+
+func TestRecordInjection(t *testing.T) {
+	m := New()
+	m.RecordInjection("/v1/retrieve", 4, 1)
+
+	for action, want := range map[string]float64{"kept": 3, "blocked": 1} {
+		if val := counterValue(t, m.InjectionChunks, "endpoint", "/v1/retrieve", "action", action); val != want {
+			t.Errorf("%s: expected %v, got %v", action, want, val)
+		}
+	}
+}
+
+func TestRecordResultCache(t *testing.T) {
+	m := New()
+	m.RecordResultCache("/v1/retrieve", true)
+	m.RecordResultCache("/v1/retrieve", false)
+	m.RecordResultCache("/v1/retrieve", true)
+
+	for result, want := range map[string]float64{"hit": 2, "miss": 1} {
+		if val := counterValue(t, m.ResultCache, "endpoint", "/v1/retrieve", "result", result); val != want {
+			t.Errorf("%s: expected %v, got %v", result, want, val)
+		}
+	}
+}
+
+func TestRecordSparse(t *testing.T) {
+	m := New()
+	m.RecordSparse("/v1/retrieve", "", false)
+	m.RecordSparse("/v1/retrieve", "", true)
+	m.RecordSparse("/v1/retrieve", "", false)
+
+	for result, want := range map[string]float64{"sparse": 2, "empty": 1} {
+		if val := counterValue(t, m.SparseResults, "endpoint", "/v1/retrieve", "namespace", "default", "result", result); val != want {
+			t.Errorf("%s: expected %v, got %v", result, want, val)
+		}
+	}
+}
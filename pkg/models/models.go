@@ -0,0 +1,188 @@
+// Package models describes the downstream models that consume Distill's
+// results: how much context each takes, how it counts tokens, what its
+// input costs, and which template it reads best. A request names a
+// Profile to have its result fit that model's budget.
+package models
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Tokenizers estimate token counts without a model vocabulary. A profile
+// may also name any tokenizer in pkg/tokens, such as tokens.CL100KBase,
+// to count exactly.
+const (
+	// TokenizerChars counts CharsPerToken characters as one token.
+	TokenizerChars = "chars"
+
+	// TokenizerWords counts a word as 4/3 tokens, the usual English
+	// average for BPE vocabularies.
+	TokenizerWords = "words"
+)
+
+// DefaultCharsPerToken is the characters per token of TokenizerChars when
+// a profile sets none.
+const DefaultCharsPerToken = 4.0
+
+// Profile is one downstream model.
+type Profile struct {
+	// Name is what requests pass as model.
+	Name string `mapstructure:"name"`
+
+	// ContextTokens is the model's context window. ReserveTokens of it
+	// are kept free for the prompt and the answer; the rest is the budget
+	// for returned chunks. Zero ContextTokens sets no budget.
+	ContextTokens int `mapstructure:"context_tokens"`
+	ReserveTokens int `mapstructure:"reserve_tokens"`
+
+	// Tokenizer is TokenizerChars (the default), TokenizerWords, or a
+	// tokenizer registered in pkg/tokens.
+	Tokenizer     string  `mapstructure:"tokenizer"`
+	CharsPerToken float64 `mapstructure:"chars_per_token"`
+
+	// InputPer1K is the price of 1,000 input tokens, in USD.
+	InputPer1K float64 `mapstructure:"input_per_1k"`
+
+	// Format is the render template used when a request names none.
+	Format string `mapstructure:"format"`
+}
+
+// validate checks p and fills in defaults.
+func (p *Profile) validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if p.ContextTokens < 0 || p.ReserveTokens < 0 {
+		return fmt.Errorf("context_tokens and reserve_tokens must be non-negative")
+	}
+	if p.ContextTokens > 0 && p.ReserveTokens >= p.ContextTokens {
+		return fmt.Errorf("reserve_tokens (%d) must be below context_tokens (%d)", p.ReserveTokens, p.ContextTokens)
+	}
+	switch p.Tokenizer {
+	case "":
+		p.Tokenizer = TokenizerChars
+	case TokenizerChars, TokenizerWords:
+	default:
+		if _, err := tokens.Get(p.Tokenizer); err != nil {
+			return fmt.Errorf("unknown tokenizer %q (supported: %s, %s)", p.Tokenizer, TokenizerWords, strings.Join(tokens.Names(), ", "))
+		}
+	}
+	if p.CharsPerToken < 0 {
+		return fmt.Errorf("chars_per_token must be non-negative, got %g", p.CharsPerToken)
+	}
+	if p.CharsPerToken == 0 {
+		p.CharsPerToken = DefaultCharsPerToken
+	}
+	if p.InputPer1K < 0 {
+		return fmt.Errorf("input_per_1k must be non-negative, got %g", p.InputPer1K)
+	}
+	return nil
+}
+
+// Budget is the tokens left for returned chunks, or 0 for no budget.
+func (p Profile) Budget() int {
+	if p.ContextTokens <= 0 {
+		return 0
+	}
+	return p.ContextTokens - p.ReserveTokens
+}
+
+// Tokens counts text's tokens with the profile's tokenizer.
+func (p Profile) Tokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	switch p.Tokenizer {
+	case "", TokenizerChars:
+	case TokenizerWords:
+		return int(math.Ceil(float64(len(strings.Fields(text))) * 4 / 3))
+	default:
+		if t, err := tokens.Get(p.Tokenizer); err == nil {
+			return t.Count(text)
+		}
+	}
+	cpt := p.CharsPerToken
+	if cpt <= 0 {
+		cpt = DefaultCharsPerToken
+	}
+	return int(math.Ceil(float64(utf8.RuneCountInString(text)) / cpt))
+}
+
+// Cost is the price of tokens input tokens, in USD.
+func (p Profile) Cost(tokens int) float64 {
+	return float64(tokens) / 1000 * p.InputPer1K
+}
+
+// Fit keeps chunks, in order, while they fit the budget. A chunk that does
+// not fit is skipped, so a smaller one after it can still take the room.
+// It returns the kept chunks and their tokens.
+func (p Profile) Fit(chunks []types.Chunk) ([]types.Chunk, int) {
+	budget := p.Budget()
+	total := 0
+	kept := make([]types.Chunk, 0, len(chunks))
+	for _, c := range chunks {
+		n := p.Tokens(c.Text)
+		if budget > 0 && total+n > budget {
+			continue
+		}
+		kept = append(kept, c)
+		total += n
+	}
+	return kept, total
+}
+
+// Registry holds the configured profiles.
+type Registry struct {
+	profiles map[string]Profile
+	def      string
+}
+
+// New validates profiles and builds a Registry. def names the profile for
+// requests that name none; empty leaves them without one. Invalid profiles
+// are errs.ErrConfig errors.
+func New(profiles []Profile, def string) (*Registry, error) {
+	r := &Registry{profiles: make(map[string]Profile, len(profiles)), def: def}
+	for i, p := range profiles {
+		if err := p.validate(); err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("model profile %d: %w", i, err))
+		}
+		if _, dup := r.profiles[p.Name]; dup {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("model profile %q is defined twice", p.Name))
+		}
+		r.profiles[p.Name] = p
+	}
+	if _, ok := r.profiles[def]; def != "" && !ok {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("default model %q is not a defined profile", def))
+	}
+	return r, nil
+}
+
+// Resolve returns the profile named name, or the default profile when name
+// is empty. It returns nil when neither applies. An unknown name is an
+// errs.ErrConfig error. A nil Registry has no profiles.
+func (r *Registry) Resolve(name string) (*Profile, error) {
+	if r == nil {
+		if name != "" {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("unknown model %q: no model profiles are configured", name))
+		}
+		return nil, nil
+	}
+	if name == "" {
+		name = r.def
+	}
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := r.profiles[name]
+	if !ok {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("unknown model %q", name))
+	}
+	return &p, nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestProfile_Tokens(t *testing.T) {
+	text := "The retriever over-fetches candidates." // 38 characters, 4 words
+	tests := []struct {
+		p    Profile
+		want int
+	}{
+		{Profile{}, 10},
+		{Profile{Tokenizer: TokenizerChars, CharsPerToken: 3.5}, 11},
+		{Profile{Tokenizer: TokenizerWords}, 6},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Tokens(text); got != tt.want {
+			t.Errorf("%+v: Tokens = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if got := (Profile{}).Tokens("héllo wörld"); got != 3 {
+		t.Errorf("Tokens counts bytes: got %d, want 3", got)
+	}
+
+	bpe, err := tokens.Get(tokens.CL100KBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := (Profile{Tokenizer: tokens.CL100KBase}).Tokens(text), bpe.Count(text); got != want {
+		t.Errorf("cl100k_base: Tokens = %d, want %d", got, want)
+	}
+}
+
+func TestProfile_Fit(t *testing.T) {
+	p := Profile{ContextTokens: 40, ReserveTokens: 20, InputPer1K: 0.5}
+	chunks := []types.Chunk{
+		{ID: "a", Text: strings.Repeat("x", 40)}, // 10 tokens
+		{ID: "b", Text: strings.Repeat("x", 48)}, // 12 tokens, does not fit
+		{ID: "c", Text: strings.Repeat("x", 32)}, // 8 tokens
+	}
+	kept, tokens := p.Fit(chunks)
+	if len(kept) != 2 || kept[0].ID != "a" || kept[1].ID != "c" || tokens != 18 {
+		t.Fatalf("Fit kept %v (%d tokens), want a and c (18 tokens)", kept, tokens)
+	}
+	if cost := p.Cost(tokens); math.Abs(cost-0.009) > 1e-12 {
+		t.Errorf("Cost = %g, want 0.009", cost)
+	}
+
+	// No context window, no budget
+	if kept, _ := (Profile{}).Fit(chunks); len(kept) != 3 {
+		t.Errorf("unbudgeted Fit dropped chunks: %v", kept)
+	}
+}
+
+func TestRegistry_Resolve(t *testing.T) {
+	r, err := New([]Profile{{Name: "llama", ContextTokens: 8192}, {Name: "claude", ContextTokens: 200000}}, "llama")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if p, err := r.Resolve(""); err != nil || p == nil || p.Name != "llama" || p.Tokenizer != TokenizerChars {
+		t.Errorf("Resolve(\"\") = %+v, %v, want default llama", p, err)
+	}
+	if p, err := r.Resolve("claude"); err != nil || p.Budget() != 200000 {
+		t.Errorf("Resolve(claude) = %+v, %v", p, err)
+	}
+	if _, err := r.Resolve("gpt-4o"); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("Resolve(unknown) error = %v, want ErrConfig", err)
+	}
+
+	var none *Registry
+	if p, err := none.Resolve(""); p != nil || err != nil {
+		t.Errorf("nil Resolve(\"\") = %+v, %v", p, err)
+	}
+	if _, err := none.Resolve("claude"); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("nil Resolve(claude) error = %v, want ErrConfig", err)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		profiles []Profile
+		def      string
+	}{
+		{[]Profile{{}}, ""},
+		{[]Profile{{Name: "a", ContextTokens: 100, ReserveTokens: 100}}, ""},
+		{[]Profile{{Name: "a", Tokenizer: "bpe"}}, ""},
+		{[]Profile{{Name: "a", InputPer1K: -1}}, ""},
+		{[]Profile{{Name: "a"}, {Name: "a"}}, ""},
+		{[]Profile{{Name: "a"}}, "b"},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.profiles, tt.def); !errors.Is(err, errs.ErrConfig) {
+			t.Errorf("New(%+v, %q) error = %v, want ErrConfig", tt.profiles, tt.def, err)
+		}
+	}
+}
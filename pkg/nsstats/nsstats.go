@@ -0,0 +1,184 @@
+// Package nsstats keeps rolling per-namespace aggregates of served
+// requests in memory: how many there were, how much deduplication removed,
+// how often the result cache answered, and how long they took. Operators
+// use them to see which corpora are most redundant.
+package nsstats
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWindow is how far back aggregates look when New is given 0.
+	DefaultWindow = time.Hour
+
+	// MaxSamples caps the requests kept per namespace; the oldest are
+	// dropped first.
+	MaxSamples = 10000
+
+	// MaxNamespaces caps the namespaces tracked. Requests to further
+	// namespaces are not counted until one ages out of the window.
+	MaxNamespaces = 1000
+)
+
+// Sample is one served request.
+type Sample struct {
+	Namespace string
+
+	// Retrieved and Returned are the chunks before and after
+	// deduplication, and Clusters the clusters formed.
+	Retrieved int
+	Returned  int
+	Clusters  int
+
+	CacheHit bool
+	Latency  time.Duration
+
+	// At is when the request was served; Observe sets it when zero.
+	At time.Time
+}
+
+// Stats aggregates one namespace's requests within the window.
+type Stats struct {
+	Namespace string
+	Requests  int
+
+	// Retrieved and Returned sum the requests' chunks.
+	Retrieved int
+	Returned  int
+
+	// AvgReduction is the mean fraction of retrieved chunks removed, over
+	// requests that retrieved any.
+	AvgReduction float64
+
+	AvgClusters  float64
+	CacheHitRate float64
+	P95Latency   time.Duration
+}
+
+// Tracker aggregates samples per namespace over a rolling window. It is
+// safe for concurrent use, and a nil Tracker ignores samples.
+type Tracker struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	samples map[string][]Sample
+}
+
+// New returns a tracker over window, or DefaultWindow when it is 0.
+func New(window time.Duration) *Tracker {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Tracker{
+		window:  window,
+		now:     time.Now,
+		samples: make(map[string][]Sample),
+	}
+}
+
+// Window returns how far back aggregates look.
+func (t *Tracker) Window() time.Duration { return t.window }
+
+// Observe adds s to its namespace's aggregates.
+func (t *Tracker) Observe(s Sample) {
+	if t == nil {
+		return
+	}
+	if s.At.IsZero() {
+		s.At = t.now()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples, ok := t.samples[s.Namespace]
+	if !ok && len(t.samples) >= MaxNamespaces {
+		t.prune()
+		if len(t.samples) >= MaxNamespaces {
+			return
+		}
+	}
+	samples = t.expire(samples)
+	if len(samples) >= MaxSamples {
+		samples = samples[len(samples)-MaxSamples+1:]
+	}
+	t.samples[s.Namespace] = append(samples, s)
+}
+
+// Snapshot returns each namespace's aggregates, most redundant first.
+func (t *Tracker) Snapshot() []Stats {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	t.prune()
+	out := make([]Stats, 0, len(t.samples))
+	for ns, samples := range t.samples {
+		out = append(out, aggregate(ns, samples))
+	}
+	t.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AvgReduction != out[j].AvgReduction {
+			return out[i].AvgReduction > out[j].AvgReduction
+		}
+		return out[i].Namespace < out[j].Namespace
+	})
+	return out
+}
+
+// prune expires old samples in every namespace and forgets namespaces
+// left without any. t.mu must be held.
+func (t *Tracker) prune() {
+	for ns, samples := range t.samples {
+		if samples = t.expire(samples); len(samples) == 0 {
+			delete(t.samples, ns)
+		} else {
+			t.samples[ns] = samples
+		}
+	}
+}
+
+// expire drops samples older than the window. Samples are in the order
+// they were observed.
+func (t *Tracker) expire(samples []Sample) []Sample {
+	cutoff := t.now().Add(-t.window)
+	i := sort.Search(len(samples), func(i int) bool { return samples[i].At.After(cutoff) })
+	return samples[i:]
+}
+
+// aggregate summarizes one namespace's samples.
+func aggregate(ns string, samples []Sample) Stats {
+	st := Stats{Namespace: ns, Requests: len(samples)}
+	var reduction float64
+	var withChunks, clusters, hits int
+	latencies := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		st.Retrieved += s.Retrieved
+		st.Returned += s.Returned
+		if s.Retrieved > 0 {
+			reduction += float64(s.Retrieved-s.Returned) / float64(s.Retrieved)
+			withChunks++
+		}
+		clusters += s.Clusters
+		if s.CacheHit {
+			hits++
+		}
+		latencies[i] = s.Latency
+	}
+	if withChunks > 0 {
+		st.AvgReduction = reduction / float64(withChunks)
+	}
+	if n := len(samples); n > 0 {
+		st.AvgClusters = float64(clusters) / float64(n)
+		st.CacheHitRate = float64(hits) / float64(n)
+		slices.Sort(latencies)
+		st.P95Latency = latencies[int(math.Ceil(0.95*float64(n)))-1]
+	}
+	return st
+}
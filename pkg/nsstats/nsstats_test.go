@@ -0,0 +1,51 @@
+package nsstats
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTracker_Snapshot(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tr := New(time.Hour)
+	tr.now = func() time.Time { return now }
+
+	for i := 0; i < 20; i++ {
+		tr.Observe(Sample{Namespace: "docs", Retrieved: 10, Returned: 5, Clusters: 5, Latency: time.Duration(i+1) * time.Millisecond, CacheHit: i%4 == 0})
+	}
+	tr.Observe(Sample{Namespace: "tickets", Retrieved: 10, Returned: 9, Clusters: 9})
+	tr.Observe(Sample{Namespace: "tickets", Retrieved: 0})
+
+	stats := tr.Snapshot()
+	if len(stats) != 2 || stats[0].Namespace != "docs" {
+		t.Fatalf("expected docs first as most redundant, got %+v", stats)
+	}
+	docs, tickets := stats[0], stats[1]
+	if docs.Requests != 20 || docs.AvgReduction != 0.5 || docs.AvgClusters != 5 || docs.CacheHitRate != 0.25 {
+		t.Errorf("unexpected docs aggregates: %+v", docs)
+	}
+	if docs.P95Latency != 19*time.Millisecond {
+		t.Errorf("expected p95 latency 19ms, got %v", docs.P95Latency)
+	}
+	// Requests that retrieved nothing do not dilute the reduction
+	if tickets.Requests != 2 || math.Abs(tickets.AvgReduction-0.1) > 1e-9 || tickets.Retrieved != 10 {
+		t.Errorf("unexpected tickets aggregates: %+v", tickets)
+	}
+
+	// Samples age out of the window, and empty namespaces are forgotten
+	now = now.Add(2 * time.Hour)
+	tr.Observe(Sample{Namespace: "docs", Retrieved: 4, Returned: 4})
+	stats = tr.Snapshot()
+	if len(stats) != 1 || stats[0].Requests != 1 || stats[0].AvgReduction != 0 {
+		t.Errorf("expected only the new docs request, got %+v", stats)
+	}
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tr *Tracker
+	tr.Observe(Sample{Namespace: "docs"})
+	if stats := tr.Snapshot(); stats != nil {
+		t.Errorf("expected no stats from a nil tracker, got %v", stats)
+	}
+}
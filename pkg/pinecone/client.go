@@ -2,12 +2,16 @@ package pinecone
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"math/rand"
+	"net/http"
 	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/errs"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 	"github.com/pinecone-io/go-pinecone/v3/pinecone"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -23,6 +27,10 @@ type Config struct {
 	MaxRetries     int
 	InitialBackoff time.Duration
 	MaxBackoff     time.Duration
+
+	// HTTPClient is used for Pinecone's REST control plane, e.g. to record
+	// or replay DescribeIndex in tests. Vector operations use gRPC.
+	HTTPClient *http.Client
 }
 
 // DefaultConfig returns sensible defaults.
@@ -53,10 +61,10 @@ type Stats struct {
 // NewClient creates a new Pinecone client.
 func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 	if cfg.APIKey == "" {
-		return nil, fmt.Errorf("API key is required")
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("API key is required"))
 	}
 	if cfg.IndexName == "" {
-		return nil, fmt.Errorf("index name is required")
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("index name is required"))
 	}
 
 	// Apply defaults
@@ -72,16 +80,17 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 
 	// Create Pinecone client
 	pc, err := pinecone.NewClient(pinecone.NewClientParams{
-		ApiKey: cfg.APIKey,
+		ApiKey:     cfg.APIKey,
+		RestClient: cfg.HTTPClient,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Pinecone client: %w", err)
+		return nil, fmt.Errorf("failed to create Pinecone client: %w", classify(err))
 	}
 
 	// Get index connection (uses gRPC for data operations)
 	idx, err := pc.DescribeIndex(ctx, cfg.IndexName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to describe index %q: %w", cfg.IndexName, err)
+		return nil, fmt.Errorf("failed to describe index %q: %w", cfg.IndexName, classify(err))
 	}
 
 	idxConn, err := pc.Index(pinecone.NewIndexConnParams{
@@ -89,7 +98,7 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 		Namespace: cfg.Namespace,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to index: %w", err)
+		return nil, fmt.Errorf("failed to connect to index: %w", classify(err))
 	}
 
 	return &Client{
@@ -117,9 +126,70 @@ func (c *Client) UpsertBatch(ctx context.Context, vectors []types.Vector) error
 		}
 	}
 
-	// Retry loop with exponential backoff
+	err := c.retry(ctx, func() error {
+		_, err := c.idxConn.UpsertVectors(ctx, pcVectors)
+		return err
+	})
+	if err != nil && err == ctx.Err() {
+		return err
+	}
+	if err != nil {
+		atomic.AddInt64(&c.stats.FailedVectors, int64(len(vectors)))
+		return fmt.Errorf("upsert failed after %d retries: %w", c.cfg.MaxRetries, err)
+	}
+	atomic.AddInt64(&c.stats.UpsertedVectors, int64(len(vectors)))
+	atomic.AddInt64(&c.stats.BatchCount, 1)
+	return nil
+}
+
+// FetchVectors fetches vectors by ID, with retry logic. IDs not in the
+// index are absent from the result.
+func (c *Client) FetchVectors(ctx context.Context, ids []string) (map[string]types.Vector, error) {
+	var res *pinecone.FetchVectorsResponse
+	err := c.retry(ctx, func() error {
+		var err error
+		res, err = c.idxConn.FetchVectors(ctx, ids)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetch failed: %w", err)
+	}
+
+	out := make(map[string]types.Vector, len(res.Vectors))
+	for id, v := range res.Vectors {
+		vec := types.Vector{ID: id}
+		if v.Values != nil {
+			vec.Values = *v.Values
+		}
+		if v.Metadata != nil {
+			vec.Metadata = v.Metadata.AsMap()
+		}
+		out[id] = vec
+	}
+	return out, nil
+}
+
+// UpdateMetadata sets metadata fields on an existing vector, leaving its
+// values and other fields unchanged.
+func (c *Client) UpdateMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	req := &pinecone.UpdateVectorRequest{
+		Id:       id,
+		Metadata: convertMetadata(metadata),
+	}
+	if err := c.retry(ctx, func() error { return c.idxConn.UpdateVector(ctx, req) }); err != nil {
+		return fmt.Errorf("update %q failed: %w", id, err)
+	}
+	return nil
+}
+
+// retry runs op with exponential backoff while it fails with a retryable
+// error (429 or 503), returning the last error, or ctx.Err() unwrapped if
+// ctx ends between attempts. Each wait is drawn uniformly from [0, backoff)
+// so workers throttled together do not all retry together.
+func (c *Client) retry(ctx context.Context, op func() error) error {
 	var lastErr error
 	backoff := c.cfg.InitialBackoff
+	observe := retryObserver(ctx)
 
 	for attempt := 0; attempt <= c.cfg.MaxRetries; attempt++ {
 		select {
@@ -130,14 +200,18 @@ func (c *Client) UpsertBatch(ctx context.Context, vectors []types.Vector) error
 
 		if attempt > 0 {
 			atomic.AddInt64(&c.stats.RetryCount, 1)
-			time.Sleep(backoff)
+			timer := time.NewTimer(jitter(backoff))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
 			backoff = time.Duration(math.Min(float64(backoff*2), float64(c.cfg.MaxBackoff)))
 		}
 
-		_, err := c.idxConn.UpsertVectors(ctx, pcVectors)
+		err := op()
 		if err == nil {
-			atomic.AddInt64(&c.stats.UpsertedVectors, int64(len(vectors)))
-			atomic.AddInt64(&c.stats.BatchCount, 1)
 			return nil
 		}
 
@@ -147,10 +221,34 @@ func (c *Client) UpsertBatch(ctx context.Context, vectors []types.Vector) error
 		if !isRetryableError(err) {
 			break
 		}
+		if observe != nil {
+			observe(err)
+		}
 	}
 
-	atomic.AddInt64(&c.stats.FailedVectors, int64(len(vectors)))
-	return fmt.Errorf("upsert failed after %d retries: %w", c.cfg.MaxRetries, lastErr)
+	return classify(lastErr)
+}
+
+// jitter returns a random duration in [0, d).
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+type retryObserverKey struct{}
+
+// WithRetryObserver returns a context that makes client calls report each
+// retryable failure (throttling or unavailability) to fn, even when a
+// later attempt succeeds. Callers use it to back off on their own side.
+func WithRetryObserver(ctx context.Context, fn func(err error)) context.Context {
+	return context.WithValue(ctx, retryObserverKey{}, fn)
+}
+
+func retryObserver(ctx context.Context) func(error) {
+	fn, _ := ctx.Value(retryObserverKey{}).(func(error))
+	return fn
 }
 
 // GetStats returns current operation statistics.
@@ -189,6 +287,22 @@ func convertMetadata(m map[string]interface{}) *structpb.Struct {
 	return s
 }
 
+// classify is errs.ClassifyRemote that also reads the HTTP status of the
+// control-plane errors the Pinecone SDK returns.
+func classify(err error) error {
+	var pe *pinecone.PineconeError
+	if errors.As(err, &pe) {
+		err = &errs.StatusError{StatusCode: pe.Code, Err: err}
+	}
+	return errs.ClassifyRemote(err)
+}
+
+// IsRetryable reports whether err is throttling or a transient
+// unavailability that the client retries.
+func IsRetryable(err error) bool {
+	return isRetryableError(err)
+}
+
 // isRetryableError checks if an error should trigger a retry.
 func isRetryableError(err error) bool {
 	if err == nil {
@@ -0,0 +1,114 @@
+package pinecone
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/vcr"
+)
+
+// These tests replay DescribeIndex from testdata/*.json. Refresh them with
+// `make fixtures` (needs PINECONE_API_KEY and PINECONE_INDEX). Upserts go
+// over gRPC and are not recorded.
+
+// fixtureIndex is the index name stored in the cassettes; the real index
+// used for recording is rewritten to it.
+const fixtureIndex = "distill-fixtures"
+
+// credentials returns the real key and index when recording and
+// placeholders otherwise.
+func credentials(t *testing.T) (key, index string) {
+	t.Helper()
+	if !vcr.Recording() {
+		return "pc-test", fixtureIndex
+	}
+	key, index = os.Getenv("PINECONE_API_KEY"), os.Getenv("PINECONE_INDEX")
+	if key == "" || index == "" {
+		t.Skip("PINECONE_API_KEY and PINECONE_INDEX are required to record fixtures")
+	}
+	return key, index
+}
+
+func TestReplay_DescribeIndex(t *testing.T) {
+	key, index := credentials(t)
+	rec := vcr.Start(t, "testdata/describe_index.json", key)
+	rec.Replace(index, fixtureIndex)
+
+	c, err := NewClient(context.Background(), Config{
+		APIKey:     key,
+		IndexName:  index,
+		HTTPClient: rec.Client(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	if c.idxConn == nil {
+		t.Error("expected an index connection for the resolved host")
+	}
+	if c.cfg.MaxRetries != 5 {
+		t.Errorf("expected default retries, got %d", c.cfg.MaxRetries)
+	}
+}
+
+func TestReplay_IndexNotFound(t *testing.T) {
+	key, _ := credentials(t)
+	rec := vcr.Start(t, "testdata/index_not_found.json", key)
+
+	_, err := NewClient(context.Background(), Config{
+		APIKey:     key,
+		IndexName:  "distill-missing",
+		HTTPClient: rec.Client(),
+	})
+	if !errors.Is(err, errs.ErrBackend) {
+		t.Errorf("expected backend error, got %v", err)
+	}
+}
+
+func TestUpsertBatch_Empty(t *testing.T) {
+	c := &Client{stats: &Stats{}}
+	if err := c.UpsertBatch(context.Background(), nil); err != nil {
+		t.Errorf("expected empty batch to be a no-op, got %v", err)
+	}
+}
+
+func TestRetry_ObserverAndJitter(t *testing.T) {
+	c := &Client{
+		cfg:   Config{MaxRetries: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond},
+		stats: &Stats{},
+	}
+	var observed int
+	ctx := WithRetryObserver(context.Background(), func(error) { observed++ })
+
+	calls := 0
+	err := c.retry(ctx, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("rpc error: code = ResourceExhausted desc = 429 Too Many Requests")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if observed != 2 || c.GetStats().RetryCount != 2 {
+		t.Errorf("expected 2 observed retries, got %d (stats %d)", observed, c.GetStats().RetryCount)
+	}
+
+	observed = 0
+	err = c.retry(ctx, func() error { return errors.New("invalid vector") })
+	if err == nil || observed != 0 {
+		t.Errorf("expected a non-retryable error to fail without observation, got %v (%d)", err, observed)
+	}
+
+	for i := 0; i < 100; i++ {
+		if d := jitter(10 * time.Millisecond); d < 0 || d >= 10*time.Millisecond {
+			t.Fatalf("jitter out of range: %v", d)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/Siddhant-K-code/distill/pkg/compress"
 	"github.com/Siddhant-K-code/distill/pkg/contextlab"
 	"github.com/Siddhant-K-code/distill/pkg/summarize"
+	"github.com/Siddhant-K-code/distill/pkg/tokens"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
 
@@ -29,6 +30,24 @@ type Stats struct {
 	TotalReduction float64
 	Stages         map[string]StageStats
 	TotalLatency   time.Duration
+
+	// RepeatsRemoved and RepeatTokensSaved count the paragraphs and
+	// sentences the compress stage dropped for repeating earlier text in
+	// the same chunk, and the tokens that saved.
+	RepeatsRemoved    int
+	RepeatTokensSaved int
+
+	// CompressionLevels is the level the compress stage left each chunk
+	// at, by chunk ID, when CompressMaxTokens is set. CompressOverBudget
+	// is set when the output still exceeds it at the most aggressive
+	// level.
+	CompressionLevels  map[string]compress.Level
+	CompressOverBudget bool
+
+	// Offsets maps chunk IDs to the spans of their final text copied from
+	// the input text, for highlighting sources. Chunks whose text a stage
+	// rewrote rather than excerpted are left out.
+	Offsets map[string][]compress.Span
 }
 
 // Options configures which stages run and how.
@@ -42,6 +61,7 @@ type Options struct {
 	// Compress stage.
 	CompressEnabled         bool
 	CompressTargetReduction float64 // e.g. 0.5 = reduce to 50% of tokens
+	CompressMaxTokens       int     // output budget, compressing harder per chunk to meet it (0 = none)
 
 	// Summarize stage.
 	SummarizeEnabled   bool
@@ -72,9 +92,10 @@ func New() *Runner { return &Runner{} }
 // Run executes the configured stages against chunks and returns the result.
 func (r *Runner) Run(ctx context.Context, chunks []types.Chunk, opts Options) ([]types.Chunk, Stats, error) {
 	start := time.Now()
+	opts = opts.resolved()
 	stats := Stats{
 		Stages:         make(map[string]StageStats),
-		OriginalTokens: estimateTokens(chunks),
+		OriginalTokens: countTokens(chunks),
 	}
 
 	current := chunks
@@ -83,69 +104,81 @@ func (r *Runner) Run(ctx context.Context, chunks []types.Chunk, opts Options) ([
 	dedupStats := StageStats{Enabled: opts.DedupEnabled}
 	if opts.DedupEnabled && len(current) > 1 {
 		t0 := time.Now()
-		dedupStats.InputTokens = estimateTokens(current)
+		dedupStats.InputTokens = countTokens(current)
 
-		threshold := opts.DedupThreshold
-		if threshold <= 0 {
-			threshold = 0.15
-		}
-		lambda := opts.DedupLambda
-		if lambda <= 0 {
-			lambda = 0.7
+		clusterCfg := contextlab.DefaultClusterConfig()
+		clusterCfg.Threshold = opts.DedupThreshold
+		ctx := contextlab.WithVectorNorms(ctx)
+		clusterResult, err := contextlab.NewClusterer(clusterCfg).ClusterContext(ctx, current)
+		if err != nil {
+			return nil, stats, fmt.Errorf("dedup stage: %w", err)
 		}
-
-		clusterResult := contextlab.ClusterByThreshold(current, threshold)
 		sel := contextlab.NewSelector(contextlab.DefaultSelectorConfig())
 		selected := sel.Select(clusterResult)
 
 		if opts.DedupTargetK > 0 && len(selected) > opts.DedupTargetK {
-			mmrResult := contextlab.MMRRerank(selected, lambda, opts.DedupTargetK)
+			mmrResult, err := contextlab.NewMMR(contextlab.MMRConfig{
+				Lambda:  opts.DedupLambda,
+				TargetK: opts.DedupTargetK,
+			}).RerankContext(ctx, selected)
+			if err != nil {
+				return nil, stats, fmt.Errorf("dedup stage: %w", err)
+			}
 			current = mmrResult
 		} else {
 			current = selected
 		}
 
-		dedupStats.OutputTokens = estimateTokens(current)
+		dedupStats.OutputTokens = countTokens(current)
 		dedupStats.Reduction = reduction(dedupStats.InputTokens, dedupStats.OutputTokens)
 		dedupStats.Latency = time.Since(t0)
 	} else {
-		dedupStats.InputTokens = estimateTokens(current)
+		dedupStats.InputTokens = countTokens(current)
 		dedupStats.OutputTokens = dedupStats.InputTokens
 	}
-	stats.Stages["dedup"] = dedupStats
+	stats.Stages[StageDedup] = dedupStats
 
 	// ── Stage 2: Compress ─────────────────────────────────────────────────────
 	compressStats := StageStats{Enabled: opts.CompressEnabled}
 	if opts.CompressEnabled && len(current) > 0 {
 		t0 := time.Now()
-		compressStats.InputTokens = estimateTokens(current)
+		compressStats.InputTokens = countTokens(current)
 
 		compOpts := compress.DefaultOptions()
-		if opts.CompressTargetReduction > 0 {
-			compOpts.TargetReduction = opts.CompressTargetReduction
-		}
+		compOpts.TargetReduction = opts.CompressTargetReduction
+		compOpts.MaxOutputTokens = opts.CompressMaxTokens
 
-		c := compress.NewExtractiveCompressor()
-		compressed, _, err := c.Compress(ctx, current, compOpts)
+		// Repeats within a chunk go first so extraction budgets only
+		// distinct text.
+		var c compress.Compressor = compress.NewPipeline(compress.NewRepeatRemover(), compress.NewExtractiveCompressor())
+		if opts.CompressMaxTokens > 0 {
+			c = compress.NewBudgetCompressor(c)
+		}
+		compressed, cStats, err := c.Compress(ctx, current, compOpts)
 		if err != nil {
 			return nil, stats, fmt.Errorf("compress stage: %w", err)
 		}
 		current = compressed
+		stats.RepeatsRemoved = cStats.RepeatsRemoved
+		stats.RepeatTokensSaved = cStats.RepeatTokensSaved
+		stats.CompressionLevels = cStats.Levels
+		stats.CompressOverBudget = cStats.OverBudget
+		stats.Offsets = cStats.Offsets
 
-		compressStats.OutputTokens = estimateTokens(current)
+		compressStats.OutputTokens = countTokens(current)
 		compressStats.Reduction = reduction(compressStats.InputTokens, compressStats.OutputTokens)
 		compressStats.Latency = time.Since(t0)
 	} else {
-		compressStats.InputTokens = estimateTokens(current)
+		compressStats.InputTokens = countTokens(current)
 		compressStats.OutputTokens = compressStats.InputTokens
 	}
-	stats.Stages["compress"] = compressStats
+	stats.Stages[StageCompress] = compressStats
 
 	// ── Stage 3: Summarize ────────────────────────────────────────────────────
 	summarizeStats := StageStats{Enabled: opts.SummarizeEnabled}
 	if opts.SummarizeEnabled && len(current) > 0 {
 		t0 := time.Now()
-		summarizeStats.InputTokens = estimateTokens(current)
+		summarizeStats.InputTokens = countTokens(current)
 
 		turns := chunksToTurns(current)
 		sumOpts := summarize.DefaultOptions()
@@ -157,36 +190,33 @@ func (r *Runner) Run(ctx context.Context, chunks []types.Chunk, opts Options) ([
 		if err != nil {
 			return nil, stats, fmt.Errorf("summarize stage: %w", err)
 		}
+		before := current
 		current = turnsToChunks(summarized, current)
 		_ = sumStats
+		stats.Offsets = dropRewritten(stats.Offsets, before, current)
 
-		summarizeStats.OutputTokens = estimateTokens(current)
+		summarizeStats.OutputTokens = countTokens(current)
 		summarizeStats.Reduction = reduction(summarizeStats.InputTokens, summarizeStats.OutputTokens)
 		summarizeStats.Latency = time.Since(t0)
 	} else {
-		summarizeStats.InputTokens = estimateTokens(current)
+		summarizeStats.InputTokens = countTokens(current)
 		summarizeStats.OutputTokens = summarizeStats.InputTokens
 	}
-	stats.Stages["summarize"] = summarizeStats
+	stats.Stages[StageSummarize] = summarizeStats
 
-	stats.FinalTokens = estimateTokens(current)
+	stats.FinalTokens = countTokens(current)
 	stats.TotalReduction = reduction(stats.OriginalTokens, stats.FinalTokens)
 	stats.TotalLatency = time.Since(start)
 
 	return current, stats, nil
 }
 
-// estimateTokens approximates total token count across chunks (4 chars ≈ 1 token).
-func estimateTokens(chunks []types.Chunk) int {
+// countTokens returns the total tokens across chunks under the default
+// tokenizer.
+func countTokens(chunks []types.Chunk) int {
 	total := 0
 	for _, c := range chunks {
-		n := 0
-		for _, r := range c.Text {
-			if r != ' ' && r != '\n' && r != '\t' {
-				n++
-			}
-		}
-		total += (n + 3) / 4
+		total += tokens.Count(c.Text)
 	}
 	return total
 }
@@ -203,6 +233,25 @@ func reduction(in, out int) float64 {
 	return r
 }
 
+// dropRewritten removes offsets for chunks whose text differs between
+// before and after.
+func dropRewritten(offsets map[string][]compress.Span, before, after []types.Chunk) map[string][]compress.Span {
+	if offsets == nil {
+		return nil
+	}
+	texts := make(map[string]string, len(before))
+	for _, c := range before {
+		texts[c.ID] = c.Text
+	}
+	kept := make(map[string][]compress.Span, len(after))
+	for _, c := range after {
+		if spans, ok := offsets[c.ID]; ok && texts[c.ID] == c.Text {
+			kept[c.ID] = spans
+		}
+	}
+	return kept
+}
+
 // chunksToTurns converts chunks to summarize.Turn for the summarize stage.
 func chunksToTurns(chunks []types.Chunk) []summarize.Turn {
 	turns := make([]summarize.Turn, len(chunks))
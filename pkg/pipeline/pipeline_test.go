@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/Siddhant-K-code/distill/pkg/compress"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
 
@@ -73,6 +74,58 @@ func TestRun_CompressOnly(t *testing.T) {
 	}
 }
 
+func TestRun_CompressRemovesRepeats(t *testing.T) {
+	r := New()
+	ctx := context.Background()
+	para := "Crawled pages often repeat the same paragraph twice. Repeats waste tokens in every prompt."
+	chunks := []types.Chunk{makeChunk("a", para+"\n\n"+para)}
+	opts := Options{CompressEnabled: true, CompressTargetReduction: 1}
+
+	result, stats, err := r.Run(ctx, chunks, opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if stats.RepeatsRemoved != 1 || stats.RepeatTokensSaved == 0 {
+		t.Errorf("want 1 repeat removed with tokens saved, got %d (%d tokens)", stats.RepeatsRemoved, stats.RepeatTokensSaved)
+	}
+	if result[0].Text != para {
+		t.Errorf("want %q, got %q", para, result[0].Text)
+	}
+	spans := stats.Offsets["a"]
+	if len(spans) == 0 {
+		t.Fatal("no offsets for compressed chunk")
+	}
+	for _, s := range spans {
+		if got, src := result[0].Text[s.Start:s.End], chunks[0].Text[s.SourceStart:s.SourceEnd]; got != src {
+			t.Errorf("span %+v: %q != %q", s, got, src)
+		}
+	}
+}
+
+func TestRun_CompressMaxTokens(t *testing.T) {
+	r := New()
+	ctx := context.Background()
+	chunks := []types.Chunk{
+		makeChunk("a", "As mentioned earlier, the first sentence sets context. The second sentence adds detail. The third sentence repeats the point. The fourth sentence concludes."),
+		makeChunk("b", "Tiny."),
+	}
+	opts := Options{CompressEnabled: true, CompressTargetReduction: 0.9, CompressMaxTokens: 20}
+
+	result, stats, err := r.Run(ctx, chunks, opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if stats.CompressOverBudget {
+		t.Error("compress stage should meet a reachable budget")
+	}
+	if stats.CompressionLevels["a"] == compress.LevelBase || stats.CompressionLevels["b"] != compress.LevelBase {
+		t.Errorf("unexpected levels: %v", stats.CompressionLevels)
+	}
+	if len(result) != 2 {
+		t.Errorf("want 2 chunks, got %d", len(result))
+	}
+}
+
 func TestRun_SummarizeEnabled(t *testing.T) {
 	r := New()
 	ctx := context.Background()
@@ -146,11 +199,10 @@ func TestStats_StagesPresent(t *testing.T) {
 	}
 }
 
-func TestEstimateTokens(t *testing.T) {
-	chunks := []types.Chunk{{Text: "hello world"}}
-	n := estimateTokens(chunks)
-	if n == 0 {
-		t.Error("expected non-zero token estimate")
+func TestCountTokens(t *testing.T) {
+	chunks := []types.Chunk{{Text: "hello world"}, {Text: "hello"}}
+	if n := countTokens(chunks); n != 3 {
+		t.Errorf("countTokens = %d, want 3", n)
 	}
 }
 
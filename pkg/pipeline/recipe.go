@@ -0,0 +1,220 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/contextlab"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// RecipeFormat is the recipe format version written by NewRecipe.
+const RecipeFormat = 1
+
+// Stage names, in the order Run applies them.
+const (
+	StageDedup     = "dedup"
+	StageCompress  = "compress"
+	StageSummarize = "summarize"
+)
+
+// Methods each stage runs in this build. A recipe naming other methods
+// cannot be replayed exactly.
+const (
+	dedupMethod     = "agglomerative"
+	compressMethod  = "extractive"
+	summarizeMethod = "hierarchical"
+)
+
+// Recipe records how a pipeline run was configured, with every default
+// resolved, so the run can be reproduced later. The pipeline has no
+// random state, so the same recipe, input, and distill version give the
+// same output.
+type Recipe struct {
+	Format         int       `json:"format"`
+	DistillVersion string    `json:"distill_version"`
+	GoVersion      string    `json:"go_version"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	// Stages lists the enabled stages in the order they ran.
+	Stages []string `json:"stages"`
+
+	Dedup     DedupRecipe     `json:"dedup"`
+	Compress  CompressRecipe  `json:"compress"`
+	Summarize SummarizeRecipe `json:"summarize"`
+
+	// InputSHA256 identifies the input chunks; see InputHash.
+	InputSHA256 string `json:"input_sha256,omitempty"`
+}
+
+// DedupRecipe is the dedup stage's configuration.
+type DedupRecipe struct {
+	Enabled   bool    `json:"enabled"`
+	Method    string  `json:"method"`
+	Linkage   string  `json:"linkage"`
+	Selection string  `json:"selection"`
+	Threshold float64 `json:"threshold"`
+	Lambda    float64 `json:"lambda"`
+	TargetK   int     `json:"target_k"`
+}
+
+// CompressRecipe is the compress stage's configuration.
+type CompressRecipe struct {
+	Enabled         bool    `json:"enabled"`
+	Method          string  `json:"method"`
+	TargetReduction float64 `json:"target_reduction"`
+	MaxTokens       int     `json:"max_tokens,omitempty"`
+}
+
+// SummarizeRecipe is the summarize stage's configuration.
+type SummarizeRecipe struct {
+	Enabled        bool   `json:"enabled"`
+	Method         string `json:"method"`
+	MaxTokens      int    `json:"max_tokens"`
+	PreserveRecent int    `json:"preserve_recent"`
+}
+
+// resolved returns opts with the defaults Run applies filled in.
+func (o Options) resolved() Options {
+	if o.DedupThreshold <= 0 {
+		o.DedupThreshold = 0.15
+	}
+	if o.DedupLambda <= 0 {
+		o.DedupLambda = 0.7
+	}
+	if o.CompressTargetReduction <= 0 {
+		o.CompressTargetReduction = compress.DefaultOptions().TargetReduction
+	}
+	return o
+}
+
+// NewRecipe returns the recipe for running opts on input. Input may be
+// nil to leave the input hash out.
+func NewRecipe(opts Options, input []types.Chunk) *Recipe {
+	opts = opts.resolved()
+	r := &Recipe{
+		Format:         RecipeFormat,
+		DistillVersion: Version(),
+		GoVersion:      runtime.Version(),
+		CreatedAt:      time.Now().UTC(),
+		Stages:         []string{},
+		Dedup: DedupRecipe{
+			Enabled:   opts.DedupEnabled,
+			Method:    dedupMethod,
+			Linkage:   contextlab.DefaultClusterConfig().Linkage,
+			Selection: string(contextlab.DefaultSelectorConfig().Strategy),
+			Threshold: opts.DedupThreshold,
+			Lambda:    opts.DedupLambda,
+			TargetK:   opts.DedupTargetK,
+		},
+		Compress: CompressRecipe{
+			Enabled:         opts.CompressEnabled,
+			Method:          compressMethod,
+			TargetReduction: opts.CompressTargetReduction,
+			MaxTokens:       opts.CompressMaxTokens,
+		},
+		Summarize: SummarizeRecipe{
+			Enabled:        opts.SummarizeEnabled,
+			Method:         summarizeMethod,
+			MaxTokens:      opts.SummarizeMaxTokens,
+			PreserveRecent: opts.SummarizeRecent,
+		},
+	}
+	for _, s := range []struct {
+		name    string
+		enabled bool
+	}{{StageDedup, opts.DedupEnabled}, {StageCompress, opts.CompressEnabled}, {StageSummarize, opts.SummarizeEnabled}} {
+		if s.enabled {
+			r.Stages = append(r.Stages, s.name)
+		}
+	}
+	if input != nil {
+		r.InputSHA256 = InputHash(input)
+	}
+	return r
+}
+
+// Options returns the options that replay r. It fails with an
+// errs.ErrConfig error when r was written by a newer format or names
+// stage methods this build does not run.
+func (r *Recipe) Options() (Options, error) {
+	var problem string
+	switch {
+	case r.Format < 1 || r.Format > RecipeFormat:
+		problem = fmt.Sprintf("unsupported recipe format %d (this build reads format %d)", r.Format, RecipeFormat)
+	case r.Dedup.Method != dedupMethod:
+		problem = fmt.Sprintf("dedup method %q is not available (this build runs %q)", r.Dedup.Method, dedupMethod)
+	case r.Dedup.Linkage != contextlab.DefaultClusterConfig().Linkage:
+		problem = fmt.Sprintf("dedup linkage %q is not available (this build runs %q)", r.Dedup.Linkage, contextlab.DefaultClusterConfig().Linkage)
+	case r.Dedup.Selection != string(contextlab.DefaultSelectorConfig().Strategy):
+		problem = fmt.Sprintf("dedup selection %q is not available (this build runs %q)", r.Dedup.Selection, contextlab.DefaultSelectorConfig().Strategy)
+	case r.Compress.Method != compressMethod:
+		problem = fmt.Sprintf("compress method %q is not available (this build runs %q)", r.Compress.Method, compressMethod)
+	case r.Summarize.Method != summarizeMethod:
+		problem = fmt.Sprintf("summarize method %q is not available (this build runs %q)", r.Summarize.Method, summarizeMethod)
+	}
+	if problem != "" {
+		return Options{}, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid recipe: %s", problem))
+	}
+	return Options{
+		DedupEnabled:            r.Dedup.Enabled,
+		DedupThreshold:          r.Dedup.Threshold,
+		DedupLambda:             r.Dedup.Lambda,
+		DedupTargetK:            r.Dedup.TargetK,
+		CompressEnabled:         r.Compress.Enabled,
+		CompressTargetReduction: r.Compress.TargetReduction,
+		CompressMaxTokens:       r.Compress.MaxTokens,
+		SummarizeEnabled:        r.Summarize.Enabled,
+		SummarizeMaxTokens:      r.Summarize.MaxTokens,
+		SummarizeRecent:         r.Summarize.PreserveRecent,
+	}, nil
+}
+
+// InputHash returns the SHA-256 of the chunks' IDs, texts, and
+// embeddings, in order.
+func InputHash(chunks []types.Chunk) string {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, c := range chunks {
+		_ = enc.Encode([]interface{}{c.ID, c.Text, c.Embedding})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Version returns the distill module version, or the VCS revision for
+// development builds.
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	var revision, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if revision == "" {
+		return "devel"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified == "true" {
+		revision += "-dirty"
+	}
+	return "devel-" + revision
+}
@@ -0,0 +1,81 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestRecipe_RoundTrip(t *testing.T) {
+	chunks := []types.Chunk{
+		makeChunk("a", "The quick brown fox jumps over the lazy dog. It was a sunny day in the park."),
+		makeChunk("b", "A completely different sentence about something else entirely, with more words."),
+	}
+	opts := Options{DedupEnabled: true, CompressEnabled: true, CompressTargetReduction: 0.3, CompressMaxTokens: 20}
+
+	recipe := NewRecipe(opts, chunks)
+	if !reflect.DeepEqual(recipe.Stages, []string{StageDedup, StageCompress}) {
+		t.Errorf("unexpected stages: %v", recipe.Stages)
+	}
+	if recipe.Dedup.Threshold != 0.15 || recipe.Dedup.Lambda != 0.7 || recipe.Dedup.Linkage != "average" {
+		t.Errorf("defaults not resolved: %+v", recipe.Dedup)
+	}
+	if recipe.InputSHA256 != InputHash(chunks) || recipe.DistillVersion == "" || recipe.GoVersion == "" {
+		t.Errorf("missing provenance: %+v", recipe)
+	}
+
+	data, err := json.Marshal(recipe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Recipe
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	replay, err := loaded.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay != opts.resolved() {
+		t.Errorf("replayed options %+v, want %+v", replay, opts.resolved())
+	}
+
+	first, _, err := New().Run(context.Background(), chunks, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := New().Run(context.Background(), chunks, replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Error("replaying the recipe gave a different result")
+	}
+}
+
+func TestRecipe_Incompatible(t *testing.T) {
+	future := NewRecipe(DefaultOptions(), nil)
+	future.Format = RecipeFormat + 1
+	if _, err := future.Options(); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected a config error for a newer format, got %v", err)
+	}
+
+	other := NewRecipe(DefaultOptions(), nil)
+	other.Dedup.Linkage = "single"
+	if _, err := other.Options(); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected a config error for another linkage, got %v", err)
+	}
+}
+
+func TestInputHash(t *testing.T) {
+	a := []types.Chunk{makeChunk("a", "x"), makeChunk("b", "y")}
+	b := []types.Chunk{makeChunk("b", "y"), makeChunk("a", "x")}
+	if InputHash(a) == InputHash(b) || InputHash(a) != InputHash(a[:2]) {
+		t.Error("input hash should depend on order and content only")
+	}
+}
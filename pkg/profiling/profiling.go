@@ -0,0 +1,331 @@
+// Package profiling provides opt-in continuous profiling for the servers:
+// net/http/pprof on an internal listener, for Parca or any scraper that
+// pulls pprof endpoints, and periodic CPU profiles pushed to a Pyroscope
+// server's ingest API.
+//
+// While profiling is on, samples are labeled with the endpoint serving the
+// request and the pipeline stage it is in (see Handler and SetStage), so a
+// hot spot such as clustering under production load shows up as
+// stage=clustering on the endpoint that drives it, without reproducing the
+// load locally.
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+	"regexp"
+	runtimepprof "runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sample label keys.
+const (
+	LabelEndpoint = "endpoint"
+	LabelStage    = "stage"
+)
+
+const (
+	defaultAppName  = "distill"
+	defaultInterval = 15 * time.Second
+	defaultTimeout  = 10 * time.Second
+)
+
+// Config holds profiling settings. A zero Config profiles nothing.
+type Config struct {
+	// PprofAddr is the listen address for net/http/pprof, e.g.
+	// "127.0.0.1:6060". Keep it off the public interface. Empty disables
+	// the listener.
+	PprofAddr string
+
+	// PushURL is a Pyroscope server, e.g. "http://pyroscope:4040". CPU
+	// profiles are pushed to its /ingest API every Interval. Empty
+	// disables pushing.
+	PushURL string
+
+	// AppName names the application in Pyroscope. Default: distill
+	AppName string
+
+	// Interval is the length of each pushed CPU profile. Default: 15s
+	Interval time.Duration
+
+	// Labels are attached to every pushed profile, e.g. env or region.
+	Labels map[string]string
+
+	// AuthToken is sent as a bearer token with each push.
+	AuthToken string
+
+	// BasicAuthUser and BasicAuthPassword authenticate pushes instead of
+	// AuthToken, as Grafana Cloud expects.
+	BasicAuthUser     string
+	BasicAuthPassword string
+
+	// OnError is called when a push fails. Pushing carries on with the
+	// next profile.
+	OnError func(error)
+}
+
+var labelKey = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.]*$`)
+
+// Validate checks cfg without starting anything.
+func (cfg Config) Validate() error {
+	if cfg.PushURL != "" {
+		u, err := url.Parse(cfg.PushURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("push URL %q must be an http(s) URL", cfg.PushURL)
+		}
+	}
+	if cfg.Interval < 0 || (cfg.Interval > 0 && cfg.Interval < time.Second) {
+		return fmt.Errorf("interval must be at least 1s, got %s", cfg.Interval)
+	}
+	for k, v := range cfg.Labels {
+		if !labelKey.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+		if strings.ContainsAny(v, "{},=") {
+			return fmt.Errorf("label %s: value %q may not contain {, }, comma or =", k, v)
+		}
+	}
+	return nil
+}
+
+// enabled is set while a Profiler runs; Handler and SetStage label
+// nothing otherwise.
+var enabled atomic.Bool
+
+// Enabled reports whether profiling is running.
+func Enabled() bool { return enabled.Load() }
+
+// Handler labels the samples taken while next serves a request with
+// endpoint, including those of goroutines it starts.
+func Handler(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !enabled.Load() {
+			next(w, r)
+			return
+		}
+		runtimepprof.Do(r.Context(), runtimepprof.Labels(LabelEndpoint, endpoint), func(ctx context.Context) {
+			next(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// SetStage labels the samples the calling goroutine, and goroutines it
+// starts from now on, take with stage, keeping the labels already on
+// ctx. The label holds until the next SetStage or the end of the
+// surrounding Handler.
+func SetStage(ctx context.Context, stage string) {
+	if !enabled.Load() {
+		return
+	}
+	runtimepprof.SetGoroutineLabels(runtimepprof.WithLabels(ctx, runtimepprof.Labels(LabelStage, stage)))
+}
+
+// Profiler runs the pprof listener and the Pyroscope pusher.
+type Profiler struct {
+	cfg    Config
+	client *http.Client
+	srv    *http.Server
+	ln     net.Listener
+
+	// The CPU profile being taken
+	buf  *bytes.Buffer
+	from time.Time
+
+	stop    chan struct{}
+	done    chan struct{}
+	uploads sync.WaitGroup
+}
+
+// Start validates cfg and starts profiling. It fails if the pprof address
+// cannot be bound or another CPU profile is already being taken.
+func Start(cfg Config) (*Profiler, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = defaultAppName
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = defaultInterval
+	}
+	cfg.PushURL = strings.TrimRight(cfg.PushURL, "/")
+
+	p := &Profiler{cfg: cfg, client: &http.Client{Timeout: defaultTimeout}}
+	if cfg.PprofAddr != "" {
+		ln, err := net.Listen("tcp", cfg.PprofAddr)
+		if err != nil {
+			return nil, fmt.Errorf("pprof listener: %w", err)
+		}
+		p.ln = ln
+		p.srv = &http.Server{Handler: pprofMux(), ReadHeaderTimeout: 10 * time.Second}
+		go p.srv.Serve(ln) //nolint:errcheck // returns ErrServerClosed on Stop
+	}
+	if cfg.PushURL != "" {
+		if err := p.startCPU(); err != nil {
+			if p.srv != nil {
+				_ = p.srv.Close()
+			}
+			return nil, fmt.Errorf("cpu profile: %w", err)
+		}
+		p.stop = make(chan struct{})
+		p.done = make(chan struct{})
+		go p.push()
+	}
+	enabled.Store(true)
+	return p, nil
+}
+
+// pprofMux serves net/http/pprof on its own mux, so the handlers never
+// reach the public server through http.DefaultServeMux.
+func pprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Addr returns the address the pprof listener is bound to, or "".
+func (p *Profiler) Addr() string {
+	if p.ln == nil {
+		return ""
+	}
+	return p.ln.Addr().String()
+}
+
+// Stop pushes the profile in progress and shuts profiling down, waiting
+// for uploads until ctx ends.
+func (p *Profiler) Stop(ctx context.Context) error {
+	enabled.Store(false)
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+		uploaded := make(chan struct{})
+		go func() {
+			p.uploads.Wait()
+			close(uploaded)
+		}()
+		select {
+		case <-uploaded:
+		case <-ctx.Done():
+		}
+	}
+	if p.srv != nil {
+		return p.srv.Shutdown(ctx)
+	}
+	return nil
+}
+
+func (p *Profiler) startCPU() error {
+	p.buf = new(bytes.Buffer)
+	p.from = time.Now()
+	return runtimepprof.StartCPUProfile(p.buf)
+}
+
+// push cuts a CPU profile every Interval and uploads it while the next
+// one is taken.
+func (p *Profiler) push() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		stopping := false
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			stopping = true
+		}
+		runtimepprof.StopCPUProfile()
+		data, from, until := p.buf.Bytes(), p.from, time.Now()
+		p.uploads.Add(1)
+		go func() {
+			defer p.uploads.Done()
+			if err := p.upload(data, from, until); err != nil && p.cfg.OnError != nil {
+				p.cfg.OnError(err)
+			}
+		}()
+		if stopping {
+			return
+		}
+		if err := p.startCPU(); err != nil {
+			if p.cfg.OnError != nil {
+				p.cfg.OnError(fmt.Errorf("cpu profile: %w", err))
+			}
+			return
+		}
+	}
+}
+
+// upload posts a pprof CPU profile to the Pyroscope ingest API.
+func (p *Profiler) upload(data []byte, from, until time.Time) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("profile", "profile.pprof")
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	q.Set("name", p.name())
+	q.Set("from", strconv.FormatInt(from.Unix(), 10))
+	q.Set("until", strconv.FormatInt(until.Unix(), 10))
+	q.Set("format", "pprof")
+	q.Set("spyName", "gospy")
+	q.Set("sampleRate", "100")
+	req, err := http.NewRequest(http.MethodPost, p.cfg.PushURL+"/ingest?"+q.Encode(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	switch {
+	case p.cfg.BasicAuthUser != "":
+		req.SetBasicAuth(p.cfg.BasicAuthUser, p.cfg.BasicAuthPassword)
+	case p.cfg.AuthToken != "":
+		req.Header.Set("Authorization", "Bearer "+p.cfg.AuthToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push profile: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("push profile: %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
+
+// name returns the Pyroscope series name, app{k=v,...}.
+func (p *Profiler) name() string {
+	if len(p.cfg.Labels) == 0 {
+		return p.cfg.AppName
+	}
+	keys := slices.Sorted(maps.Keys(p.cfg.Labels))
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + p.cfg.Labels[k]
+	}
+	return p.cfg.AppName + "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,141 @@
+package profiling
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"zero", Config{}, ""},
+		{"push", Config{PushURL: "http://pyroscope:4040", Interval: 10 * time.Second, Labels: map[string]string{"env": "prod"}}, ""},
+		{"bad url", Config{PushURL: "pyroscope:4040"}, "http(s) URL"},
+		{"short interval", Config{Interval: 100 * time.Millisecond}, "at least 1s"},
+		{"bad label name", Config{Labels: map[string]string{"a-b": "x"}}, "invalid label name"},
+		{"bad label value", Config{Labels: map[string]string{"env": "a,b"}}, "may not contain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_LabelsEndpoint(t *testing.T) {
+	var got string
+	h := Handler("/v1/retrieve", func(w http.ResponseWriter, r *http.Request) {
+		got, _ = pprof.Label(r.Context(), LabelEndpoint)
+	})
+
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got != "" {
+		t.Errorf("labeled %q while profiling is off", got)
+	}
+
+	p, err := Start(Config{PprofAddr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer p.Stop(context.Background()) //nolint:errcheck
+
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got != "/v1/retrieve" {
+		t.Errorf("endpoint label = %q, want /v1/retrieve", got)
+	}
+}
+
+func TestStart_ServesPprof(t *testing.T) {
+	p, err := Start(Config{PprofAddr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	resp, err := http.Get("http://" + p.Addr() + "/debug/pprof/goroutine?debug=1")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "goroutine profile") {
+		t.Errorf("status %d, body %.80q", resp.StatusCode, body)
+	}
+
+	if err := p.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if Enabled() {
+		t.Error("still enabled after Stop")
+	}
+}
+
+func TestStart_PushesProfile(t *testing.T) {
+	type push struct {
+		name, format, auth string
+		profile            int
+	}
+	pushes := make(chan push, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ingest" {
+			http.NotFound(w, r)
+			return
+		}
+		f, _, err := r.FormFile("profile")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(f)
+		pushes <- push{
+			name:    r.URL.Query().Get("name"),
+			format:  r.URL.Query().Get("format"),
+			auth:    r.Header.Get("Authorization"),
+			profile: len(data),
+		}
+	}))
+	defer srv.Close()
+
+	var pushErr error
+	p, err := Start(Config{
+		PushURL:   srv.URL + "/",
+		Labels:    map[string]string{"region": "eu", "env": "prod"},
+		AuthToken: "secret",
+		OnError:   func(err error) { pushErr = err },
+	})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := p.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if pushErr != nil {
+		t.Fatalf("push: %v", pushErr)
+	}
+
+	select {
+	case got := <-pushes:
+		if got.name != "distill{env=prod,region=eu}" {
+			t.Errorf("name = %q", got.name)
+		}
+		if got.format != "pprof" || got.auth != "Bearer secret" || got.profile == 0 {
+			t.Errorf("format = %q, auth = %q, profile bytes = %d", got.format, got.auth, got.profile)
+		}
+	default:
+		t.Fatal("Stop returned before the profile in progress was pushed")
+	}
+}
@@ -0,0 +1,238 @@
+// Package render assembles retrieved chunks into a single context string
+// with Go text/templates, so callers get a prompt-ready block in the
+// format their model expects instead of formatting chunks themselves.
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Built-in template names.
+const (
+	// Plain joins chunk texts with blank lines.
+	Plain = "plain"
+
+	// Numbered prefixes each chunk with [n], for citation by number.
+	Numbered = "numbered"
+
+	// Markdown gives each chunk a heading with its number and ID.
+	Markdown = "markdown"
+
+	// XML wraps each chunk in a <document> element, with text escaped.
+	XML = "xml"
+)
+
+var builtins = map[string]string{
+	Plain: `{{range $i, $c := .Chunks}}{{if $i}}
+
+{{end}}{{$c.Text}}{{end}}`,
+
+	Numbered: `{{range $i, $c := .Chunks}}{{if $i}}
+{{end}}[{{$c.Index}}] {{$c.Text}}{{end}}`,
+
+	Markdown: `{{range $i, $c := .Chunks}}{{if $i}}
+
+{{end}}### {{$c.Index}}. {{$c.ID}}
+
+{{$c.Text}}{{end}}`,
+
+	XML: `<documents>
+{{range .Chunks}}<document index="{{.Index}}" id="{{xml .ID}}">
+{{xml .Text}}
+</document>
+{{end}}</documents>`,
+}
+
+// Chunk is a chunk as seen by templates.
+type Chunk struct {
+	// Index is the chunk's 1-based position in the result.
+	Index       int
+	ID          string
+	Text        string
+	Score       float32
+	ClusterID   int
+	AlreadySent bool
+	Metadata    map[string]interface{}
+}
+
+// Stats are the broker statistics as seen by templates.
+type Stats struct {
+	Retrieved int
+	Clustered int
+	Returned  int
+	Repeated  int
+	Excluded  int
+	Vetoed    int
+	Truncated bool
+}
+
+// Data is the value templates execute against.
+type Data struct {
+	Query  string
+	Chunks []Chunk
+	Stats  Stats
+}
+
+// NewData builds template data from a broker result.
+func NewData(query string, result *types.BrokerResult) Data {
+	chunks := make([]Chunk, len(result.Chunks))
+	for i, c := range result.Chunks {
+		chunks[i] = Chunk{
+			Index:       i + 1,
+			ID:          c.ID,
+			Text:        c.Text,
+			Score:       c.Score,
+			ClusterID:   c.ClusterID,
+			AlreadySent: cache.IsAlreadySent(c),
+			Metadata:    c.Metadata,
+		}
+	}
+	st := result.Stats
+	return Data{
+		Query:  query,
+		Chunks: chunks,
+		Stats: Stats{
+			Retrieved: st.Retrieved,
+			Clustered: st.Clustered,
+			Returned:  st.Returned,
+			Repeated:  st.Repeated,
+			Excluded:  st.Excluded,
+			Vetoed:    st.Vetoed,
+			Truncated: st.Truncated,
+		},
+	}
+}
+
+// xmlEscaper escapes text for XML content and attribute values. Unlike
+// encoding/xml it leaves newlines alone, keeping chunk text readable.
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// funcs are the helpers available to templates, beyond text/template's
+// own (printf, index, len, and so on).
+var funcs = template.FuncMap{
+	// meta returns a metadata value as a string, or "" if missing.
+	"meta": func(c Chunk, key string) string {
+		v, ok := c.Metadata[key]
+		if !ok || v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	},
+	"truncate": func(n int, s string) string {
+		if r := []rune(s); len(r) > n {
+			return string(r[:n]) + "..."
+		}
+		return s
+	},
+	"xml": xmlEscaper.Replace,
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	"default": func(def, v string) string {
+		if v == "" {
+			return def
+		}
+		return v
+	},
+	"trim":  strings.TrimSpace,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  func(sep string, s []string) string { return strings.Join(s, sep) },
+	"add":   func(a, b int) int { return a + b },
+}
+
+// Renderer holds the built-in and user-defined templates.
+type Renderer struct {
+	templates map[string]*template.Template
+	def       string
+}
+
+// New compiles the user templates in custom, keyed by name, alongside the
+// built-ins. A user template may replace a built-in. defaultName, if set,
+// is rendered when a request names no template. Names are
+// case-insensitive.
+func New(custom map[string]string, defaultName string) (*Renderer, error) {
+	r := &Renderer{
+		templates: make(map[string]*template.Template, len(builtins)+len(custom)),
+		def:       strings.ToLower(defaultName),
+	}
+	for name, text := range builtins {
+		r.templates[name] = template.Must(parse(name, text))
+	}
+	for name, text := range custom {
+		name = strings.ToLower(name)
+		if name == "" {
+			return nil, errs.New(errs.ErrConfig, "template name must not be empty")
+		}
+		tmpl, err := parse(name, text)
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("template %q: %w", name, err))
+		}
+		r.templates[name] = tmpl
+	}
+	if r.def != "" && !r.Has(r.def) {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("default template %q is not defined (available: %s)", r.def, strings.Join(r.Names(), ", ")))
+	}
+	return r, nil
+}
+
+func parse(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(funcs).Option("missingkey=zero").Parse(text)
+}
+
+// Has reports whether a template named name exists.
+func (r *Renderer) Has(name string) bool {
+	_, ok := r.templates[strings.ToLower(name)]
+	return ok
+}
+
+// Names returns the template names, sorted.
+func (r *Renderer) Names() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check reports whether name can be rendered, so handlers can reject a
+// request before doing any work. An empty name is always valid.
+func (r *Renderer) Check(name string) error {
+	if name == "" || r.Has(name) {
+		return nil
+	}
+	return fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(r.Names(), ", "))
+}
+
+// Render executes the template called name, or the default template if
+// name is empty. It returns "" when neither is set.
+func (r *Renderer) Render(name string, data Data) (string, error) {
+	if name == "" {
+		name = r.def
+	}
+	if name == "" {
+		return "", nil
+	}
+	if err := r.Check(name); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := r.templates[strings.ToLower(name)].Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render %s: %w", name, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,95 @@
+package render
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/cache"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func testResult() *types.BrokerResult {
+	return &types.BrokerResult{
+		Chunks: []types.Chunk{
+			{ID: "a", Text: "Use <b> & </b> tags.", Score: 0.9, Metadata: map[string]interface{}{"source": "guide.md"}},
+			{ID: "b", Text: "Second chunk.", Score: 0.7, Metadata: map[string]interface{}{cache.AlreadySentKey: true}},
+		},
+		Stats: types.BrokerStats{Retrieved: 10, Clustered: 4, Returned: 2},
+	}
+}
+
+func TestRender_Builtins(t *testing.T) {
+	r, err := New(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := NewData("tags", testResult())
+
+	tests := map[string]string{
+		Plain:    "Use <b> & </b> tags.\n\nSecond chunk.",
+		Numbered: "[1] Use <b> & </b> tags.\n[2] Second chunk.",
+		Markdown: "### 1. a\n\nUse <b> & </b> tags.\n\n### 2. b\n\nSecond chunk.",
+		XML: `<documents>
+<document index="1" id="a">
+Use &lt;b&gt; &amp; &lt;/b&gt; tags.
+</document>
+<document index="2" id="b">
+Second chunk.
+</document>
+</documents>`,
+	}
+	for name, want := range tests {
+		got, err := r.Render(name, data)
+		if err != nil {
+			t.Fatalf("Render(%s): %v", name, err)
+		}
+		if got != want {
+			t.Errorf("Render(%s) =\n%s\nwant\n%s", name, got, want)
+		}
+	}
+
+	if got, _ := r.Render("", data); got != "" {
+		t.Errorf("expected no output without a default template, got %q", got)
+	}
+}
+
+func TestRender_Custom(t *testing.T) {
+	custom := map[string]string{
+		"Cited": `Q: {{.Query}} ({{.Stats.Returned}}/{{.Stats.Retrieved}})
+{{range .Chunks}}{{if not .AlreadySent}}{{.Index}}. {{truncate 8 .Text}} [{{meta . "source" | default "unknown"}}]
+{{end}}{{end}}`,
+	}
+	r, err := New(custom, "cited")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Render("", NewData("tags", testResult()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Q: tags (2/10)\n1. Use <b> ... [guide.md]\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, _ := r.Render("plain", NewData("", testResult())); !strings.HasPrefix(got, "Use") {
+		t.Errorf("expected named template to override the default, got %q", got)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New(map[string]string{"bad": "{{.Chunks"}, ""); !errors.Is(err, errs.ErrConfig) || !strings.Contains(err.Error(), `"bad"`) {
+		t.Errorf("expected config error naming the template, got %v", err)
+	}
+	if _, err := New(nil, "missing"); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected config error for unknown default, got %v", err)
+	}
+
+	r, _ := New(nil, "")
+	if err := r.Check("nope"); err == nil || !strings.Contains(err.Error(), "plain") {
+		t.Errorf("expected error listing available templates, got %v", err)
+	}
+	if err := r.Check("XML"); err != nil {
+		t.Errorf("expected names to be case-insensitive, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package http provides a rerank.Reranker backed by a self-hosted reranker
+// server that speaks the rerank package's HTTP contract, such as a
+// bge-reranker sidecar or a Cohere-compatible server.
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/rerank"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// Config holds reranker client configuration.
+type Config struct {
+	// BaseURL is the server URL; requests go to BaseURL + "/rerank"
+	// (required).
+	BaseURL string
+
+	// Model is sent with each request, for servers that host several
+	// models. Empty leaves the server's default.
+	Model string
+
+	// APIKey, if set, is sent as a bearer token.
+	APIKey string
+
+	// BatchSize caps the documents per request; larger inputs are split.
+	// Default: 0 (all in one request).
+	BatchSize int
+
+	// Timeout for each request. Default: 5s
+	Timeout time.Duration
+}
+
+// Client implements rerank.Reranker over HTTP.
+type Client struct {
+	cfg        Config
+	url        string
+	httpClient *nethttp.Client
+}
+
+// NewClient creates a reranker client.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("reranker base URL is required")
+	}
+	if cfg.BatchSize < 0 {
+		return nil, fmt.Errorf("reranker batch size must be non-negative, got %d", cfg.BatchSize)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Client{
+		cfg:        cfg,
+		url:        strings.TrimRight(cfg.BaseURL, "/") + "/rerank",
+		httpClient: &nethttp.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Rerank scores documents against query, in document order.
+func (c *Client) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	size := c.cfg.BatchSize
+	if size == 0 {
+		size = len(documents)
+	}
+	scores := make([]float64, 0, len(documents))
+	for start := 0; start < len(documents); start += size {
+		batch := documents[start:min(start+size, len(documents))]
+		s, err := c.rerankBatch(ctx, query, batch)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, s...)
+	}
+	return scores, nil
+}
+
+func (c *Client) rerankBatch(ctx context.Context, query string, documents []string) ([]float64, error) {
+	body, err := json.Marshal(rerank.Request{Query: query, Documents: documents, Model: c.cfg.Model})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("reranker request: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != nethttp.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("reranker %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	var result rerank.Response
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	scores, err := result.ScoresFor(len(documents))
+	if err != nil {
+		return nil, fmt.Errorf("reranker response: %w", err)
+	}
+	return scores, nil
+}
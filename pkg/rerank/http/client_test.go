@@ -0,0 +1,81 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/rerank"
+)
+
+// lengthServer scores each document by its length, answering in the
+// Cohere-compatible results format.
+func lengthServer(t *testing.T, requests *[]rerank.Request) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path != "/rerank" || r.Method != nethttp.MethodPost {
+			nethttp.NotFound(w, r)
+			return
+		}
+		var req rerank.Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			nethttp.Error(w, err.Error(), nethttp.StatusBadRequest)
+			return
+		}
+		*requests = append(*requests, req)
+		var resp rerank.Response
+		for i := len(req.Documents) - 1; i >= 0; i-- {
+			score := float64(len(req.Documents[i]))
+			resp.Results = append(resp.Results, rerank.Result{Index: i, RelevanceScore: &score})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestRerank_Batches(t *testing.T) {
+	var requests []rerank.Request
+	srv := lengthServer(t, &requests)
+	defer srv.Close()
+
+	client, err := NewClient(Config{BaseURL: srv.URL + "/", Model: "bge-reranker-base", BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores, err := client.Rerank(context.Background(), "q", []string{"a", "bbb", "cc"})
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	if len(scores) != 3 || scores[0] != 1 || scores[1] != 3 || scores[2] != 2 {
+		t.Errorf("scores = %v, want [1 3 2]", scores)
+	}
+	if len(requests) != 2 || requests[0].Query != "q" || requests[0].Model != "bge-reranker-base" {
+		t.Errorf("requests = %+v", requests)
+	}
+}
+
+func TestRerank_ServerError(t *testing.T) {
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		nethttp.Error(w, "model not loaded", nethttp.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Rerank(context.Background(), "q", []string{"a"})
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("error = %v, want the status", err)
+	}
+}
+
+func TestNewClient_Invalid(t *testing.T) {
+	for _, cfg := range []Config{{}, {BaseURL: "http://localhost:8787", BatchSize: -1}} {
+		if _, err := NewClient(cfg); err == nil {
+			t.Errorf("NewClient(%+v) accepted", cfg)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package rerank scores retrieved chunks against the query with a
+// reranker, typically a cross-encoder such as bge-reranker, and reorders
+// them by that score.
+//
+// Self-hosted rerankers plug in over a small HTTP contract, implemented by
+// pkg/rerank/http:
+//
+//	POST {base_url}/rerank
+//	{"query": "...", "documents": ["...", "..."], "model": "..."}
+//
+// The response gives one score per document, higher is more relevant,
+// either in document order:
+//
+//	{"scores": [0.91, 0.12]}
+//
+// or as Cohere-compatible results, in any order:
+//
+//	{"results": [{"index": 0, "relevance_score": 0.91}, {"index": 1, "relevance_score": 0.12}]}
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Reranker scores documents against a query.
+type Reranker interface {
+	// Rerank returns one score per document, in document order. Higher
+	// scores are more relevant; their scale is the reranker's own.
+	Rerank(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+// Request is the body of POST /rerank.
+type Request struct {
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+
+	// Model selects a model on servers that host several.
+	Model string `json:"model,omitempty"`
+}
+
+// Result is one scored document of a Cohere-compatible response. Servers
+// name the score relevance_score or score.
+type Result struct {
+	Index          int      `json:"index"`
+	RelevanceScore *float64 `json:"relevance_score,omitempty"`
+	Score          *float64 `json:"score,omitempty"`
+}
+
+// Response is the body a reranker returns: Scores, or Results.
+type Response struct {
+	Scores  []float64 `json:"scores,omitempty"`
+	Results []Result  `json:"results,omitempty"`
+}
+
+// ScoresFor returns the response's scores for n documents, in document
+// order. Every document must be scored exactly once, with a finite score.
+func (r Response) ScoresFor(n int) ([]float64, error) {
+	scores := r.Scores
+	if scores == nil && r.Results != nil {
+		scores = make([]float64, n)
+		seen := make([]bool, n)
+		for _, res := range r.Results {
+			if res.Index < 0 || res.Index >= n {
+				return nil, fmt.Errorf("result index %d out of range for %d documents", res.Index, n)
+			}
+			if seen[res.Index] {
+				return nil, fmt.Errorf("document %d scored twice", res.Index)
+			}
+			seen[res.Index] = true
+			switch {
+			case res.RelevanceScore != nil:
+				scores[res.Index] = *res.RelevanceScore
+			case res.Score != nil:
+				scores[res.Index] = *res.Score
+			default:
+				return nil, fmt.Errorf("document %d has no score", res.Index)
+			}
+		}
+		for i, ok := range seen {
+			if !ok {
+				return nil, fmt.Errorf("document %d was not scored", i)
+			}
+		}
+	}
+	if len(scores) != n {
+		return nil, fmt.Errorf("expected %d scores, got %d", n, len(scores))
+	}
+	for i, s := range scores {
+		if math.IsNaN(s) || math.IsInf(s, 0) {
+			return nil, fmt.Errorf("document %d has a non-finite score", i)
+		}
+	}
+	return scores, nil
+}
+
+// Chunks scores chunks against query with r and returns them ordered by
+// that score, highest first, with Score set to it. Ties keep their input
+// order. chunks itself is not modified.
+func Chunks(ctx context.Context, r Reranker, query string, chunks []types.Chunk) ([]types.Chunk, error) {
+	if len(chunks) == 0 {
+		return chunks, nil
+	}
+	documents := make([]string, len(chunks))
+	for i, c := range chunks {
+		documents[i] = c.Text
+	}
+	scores, err := r.Rerank(ctx, query, documents)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) != len(chunks) {
+		return nil, fmt.Errorf("reranker returned %d scores for %d documents", len(scores), len(chunks))
+	}
+
+	out := make([]types.Chunk, len(chunks))
+	copy(out, chunks)
+	for i := range out {
+		out[i].Score = float32(scores[i])
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out, nil
+}
@@ -0,0 +1,77 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestResponse_ScoresFor(t *testing.T) {
+	tests := []struct {
+		body string
+		want []float64
+		err  string
+	}{
+		{body: `{"scores": [0.9, 0.1]}`, want: []float64{0.9, 0.1}},
+		{body: `{"results": [{"index": 1, "relevance_score": 0.1}, {"index": 0, "relevance_score": 0.9}]}`, want: []float64{0.9, 0.1}},
+		{body: `{"results": [{"index": 0, "score": 2}, {"index": 1, "score": -1}]}`, want: []float64{2, -1}},
+		{body: `{"scores": [0.9]}`, err: "expected 2 scores"},
+		{body: `{"results": [{"index": 0, "score": 1}]}`, err: "document 1 was not scored"},
+		{body: `{"results": [{"index": 0, "score": 1}, {"index": 0, "score": 1}]}`, err: "scored twice"},
+		{body: `{"results": [{"index": 2, "score": 1}, {"index": 0, "score": 1}]}`, err: "out of range"},
+		{body: `{"results": [{"index": 0}, {"index": 1, "score": 1}]}`, err: "no score"},
+		{body: `{}`, err: "expected 2 scores"},
+	}
+	for _, tt := range tests {
+		var r Response
+		if err := json.Unmarshal([]byte(tt.body), &r); err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.ScoresFor(2)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error = %v, want %q", tt.body, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || len(got) != 2 || got[0] != tt.want[0] || got[1] != tt.want[1] {
+			t.Errorf("%s: got %v, %v, want %v", tt.body, got, err, tt.want)
+		}
+	}
+
+	if _, err := (Response{Scores: []float64{math.NaN()}}).ScoresFor(1); err == nil {
+		t.Error("NaN score accepted")
+	}
+}
+
+type fixedReranker []float64
+
+func (f fixedReranker) Rerank(context.Context, string, []string) ([]float64, error) {
+	return f, nil
+}
+
+func TestChunks(t *testing.T) {
+	chunks := []types.Chunk{{ID: "a", Score: 0.9}, {ID: "b", Score: 0.8}, {ID: "c", Score: 0.7}}
+	out, err := Chunks(context.Background(), fixedReranker{0.2, 0.5, 0.5}, "q", chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids string
+	for _, c := range out {
+		ids += c.ID
+	}
+	if ids != "bca" || out[0].Score != 0.5 {
+		t.Errorf("got %s with top score %v, want bca with 0.5", ids, out[0].Score)
+	}
+	if chunks[0].ID != "a" || chunks[0].Score != 0.9 {
+		t.Error("Chunks modified its input")
+	}
+
+	if _, err := Chunks(context.Background(), fixedReranker{1}, "q", chunks); err == nil {
+		t.Error("short score list accepted")
+	}
+}
@@ -0,0 +1,286 @@
+// Package fake provides an in-memory retriever over a deterministic
+// synthetic corpus, for integration tests, benchmarks, and demos that
+// should run without a vector database or credentials.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	fakeembed "github.com/Siddhant-K-code/distill/pkg/embedding/fake"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	distillmath "github.com/Siddhant-K-code/distill/pkg/math"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Defaults for Config.
+const (
+	DefaultCorpusSize    = 1000
+	DefaultDuplicateRate = 0.3
+)
+
+// Config configures a fake retriever.
+type Config struct {
+	// Chunks is the corpus to search. When empty, a synthetic corpus of
+	// CorpusSize chunks is generated with Corpus. Chunks without
+	// embeddings are embedded with Embedder.
+	Chunks []types.Chunk
+
+	// CorpusSize is the size of the generated corpus (default 1000).
+	CorpusSize int
+
+	// DuplicateRate is the fraction of near-duplicates in the generated
+	// corpus (default 0.3). Set a negative value for none.
+	DuplicateRate float64
+
+	// Seed makes the corpus and injected faults reproducible.
+	Seed int64
+
+	// Embedder embeds the corpus and text queries. Defaults to a fake
+	// embedder with the default dimension.
+	Embedder *fakeembed.Embedder
+
+	// Latency is added to every Query and QueryByID call.
+	Latency fakeembed.Latency
+
+	// ErrorRate is the probability, in [0, 1], that a call fails with
+	// fakeembed.ErrInjected.
+	ErrorRate float64
+
+	// MaxTopK caps results per query like a hosted backend's top-k limit,
+	// reporting Truncated when a query asks for more. Zero means no cap.
+	MaxTopK int
+
+	// MetadataFields limits the metadata returned with each chunk.
+	MetadataFields retriever.MetadataFields
+}
+
+// Client is an in-memory retriever.Retriever. It is safe for concurrent
+// use.
+type Client struct {
+	mu       sync.RWMutex
+	chunks   []types.Chunk
+	byID     map[string]int
+	embedder *fakeembed.Embedder
+	injector *fakeembed.Injector
+	maxTopK  int
+	fields   retriever.MetadataFields
+}
+
+// NewClient builds the corpus and returns a fake retriever.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Embedder == nil {
+		cfg.Embedder = fakeembed.NewEmbedder(fakeembed.Config{})
+	}
+	if err := cfg.MetadataFields.Validate(); err != nil {
+		return nil, err
+	}
+
+	chunks := cfg.Chunks
+	if len(chunks) == 0 {
+		size := cfg.CorpusSize
+		if size <= 0 {
+			size = DefaultCorpusSize
+		}
+		rate := cfg.DuplicateRate
+		if rate == 0 {
+			rate = DefaultDuplicateRate
+		}
+		chunks = Corpus(size, rate, cfg.Seed)
+	}
+
+	c := &Client{
+		chunks:   make([]types.Chunk, len(chunks)),
+		byID:     make(map[string]int, len(chunks)),
+		embedder: cfg.Embedder,
+		injector: fakeembed.NewInjector(cfg.Latency, cfg.ErrorRate, cfg.Seed),
+		maxTopK:  cfg.MaxTopK,
+		fields:   cfg.MetadataFields,
+	}
+	dim := cfg.Embedder.Dimension()
+	for i, ch := range chunks {
+		ch = *ch.Clone()
+		if len(ch.Embedding) == 0 {
+			ch.Embedding = cfg.Embedder.Vector(ch.Text)
+		}
+		if len(ch.Embedding) != dim {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("chunk %s has dimension %d, embedder has %d", ch.ID, len(ch.Embedding), dim))
+		}
+		c.chunks[i] = ch
+		c.byID[ch.ID] = i
+	}
+	return c, nil
+}
+
+// Embedder returns the embedder used for the corpus. Use it for query
+// embeddings so they share the corpus's vector space.
+func (c *Client) Embedder() *fakeembed.Embedder {
+	return c.embedder
+}
+
+// Len returns the number of chunks in the corpus.
+func (c *Client) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.chunks)
+}
+
+// Query returns the chunks nearest to the query by cosine similarity.
+// Text queries are embedded with the corpus embedder. Filter supports
+// exact matches on metadata values.
+func (c *Client) Query(ctx context.Context, req *types.RetrievalRequest) (*types.RetrievalResult, error) {
+	start := time.Now()
+	if err := c.injector.Inject(ctx); err != nil {
+		return nil, err
+	}
+
+	query := req.QueryEmbedding
+	if len(query) == 0 && req.Query != "" {
+		query = c.embedder.Vector(req.Query)
+	}
+	if len(query) == 0 {
+		return nil, retriever.ErrInvalidQuery
+	}
+	if len(query) != c.embedder.Dimension() {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("query dimension %d does not match corpus dimension %d", len(query), c.embedder.Dimension()))
+	}
+
+	topK := req.TopK
+	if topK <= 0 {
+		topK = 10
+	}
+	truncated := false
+	if c.maxTopK > 0 && topK > c.maxTopK {
+		topK, truncated = c.maxTopK, true
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	type hit struct {
+		idx   int
+		score float64
+	}
+	hits := make([]hit, 0, len(c.chunks))
+	for i, ch := range c.chunks {
+		if !matches(ch.Metadata, req.Filter) || retriever.Excluded(ch.Metadata, req.ExcludeFilter) {
+			continue
+		}
+		score := distillmath.CosineSimilarity(query, ch.Embedding)
+		if score < float64(req.MinScore) {
+			continue
+		}
+		hits = append(hits, hit{i, score})
+	}
+	sort.SliceStable(hits, func(a, b int) bool {
+		return hits[a].score > hits[b].score
+	})
+	total := len(hits)
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+
+	fields := c.fields.ForRequest(req)
+	chunks := make([]types.Chunk, len(hits))
+	for i, h := range hits {
+		ch := *c.chunks[h.idx].Clone()
+		ch.Score = float32(h.score)
+		if !req.IncludeEmbeddings {
+			ch.Embedding = nil
+		}
+		if !req.IncludeMetadata {
+			ch.Metadata = nil
+		}
+		ch.Metadata = fields.Apply(ch.Metadata)
+		chunks[i] = ch
+	}
+
+	return &types.RetrievalResult{
+		Chunks:         chunks,
+		QueryEmbedding: query,
+		TotalMatches:   total,
+		Truncated:      truncated,
+		Latency:        time.Since(start),
+	}, nil
+}
+
+// QueryByID returns the chunks nearest to an existing chunk.
+func (c *Client) QueryByID(ctx context.Context, id string, topK int, namespace string) (*types.RetrievalResult, error) {
+	c.mu.RLock()
+	i, ok := c.byID[id]
+	var embedding []float32
+	if ok {
+		embedding = c.chunks[i].Embedding
+	}
+	c.mu.RUnlock()
+	if !ok {
+		return nil, retriever.ErrNotFound
+	}
+	return c.Query(ctx, &types.RetrievalRequest{
+		QueryEmbedding:    embedding,
+		TopK:              topK,
+		Namespace:         namespace,
+		IncludeEmbeddings: true,
+		IncludeMetadata:   true,
+	})
+}
+
+// Upsert adds vectors to the corpus, replacing chunks with the same IDs.
+// Chunk text is read from the first of retriever.TextFields present in
+// the metadata. The fake has no namespaces, so namespace is ignored.
+func (c *Client) Upsert(ctx context.Context, vectors []types.Vector, namespace string) error {
+	if err := c.injector.Inject(ctx); err != nil {
+		return err
+	}
+	dim := c.embedder.Dimension()
+	for _, v := range vectors {
+		if len(v.Values) != dim {
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("vector %s has dimension %d, corpus has %d", v.ID, len(v.Values), dim))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, v := range vectors {
+		ch := types.Chunk{
+			ID:        v.ID,
+			Embedding: append([]float32(nil), v.Values...),
+			Metadata:  make(map[string]interface{}, len(v.Metadata)),
+			ClusterID: -1,
+		}
+		for k, val := range v.Metadata {
+			ch.Metadata[k] = val
+		}
+		for _, field := range retriever.TextFields {
+			if text, ok := v.Metadata[field].(string); ok {
+				ch.Text = text
+				break
+			}
+		}
+		if i, ok := c.byID[v.ID]; ok {
+			c.chunks[i] = ch
+			continue
+		}
+		c.byID[v.ID] = len(c.chunks)
+		c.chunks = append(c.chunks, ch)
+	}
+	return nil
+}
+
+// Close is a no-op.
+func (c *Client) Close() error {
+	return nil
+}
+
+func matches(metadata, filter map[string]interface{}) bool {
+	for k, want := range filter {
+		if fmt.Sprint(metadata[k]) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
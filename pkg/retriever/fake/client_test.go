@@ -0,0 +1,225 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	fakeembed "github.com/Siddhant-K-code/distill/pkg/embedding/fake"
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+var (
+	_ retriever.Retriever = (*Client)(nil)
+	_ retriever.Upserter  = (*Client)(nil)
+)
+
+func TestCorpus_Deterministic(t *testing.T) {
+	a := Corpus(200, 0.3, 42)
+	b := Corpus(200, 0.3, 42)
+	c := Corpus(200, 0.3, 43)
+
+	if len(a) != 200 {
+		t.Fatalf("expected 200 chunks, got %d", len(a))
+	}
+	same := true
+	for i := range a {
+		if a[i].ID != b[i].ID || a[i].Text != b[i].Text {
+			t.Fatalf("chunk %d differs for the same seed", i)
+		}
+		if a[i].Text != c[i].Text {
+			same = false
+		}
+	}
+	if same {
+		t.Error("expected a different corpus for a different seed")
+	}
+
+	dups := 0
+	for _, ch := range a {
+		if _, ok := ch.Metadata["duplicate_of"]; ok {
+			dups++
+		}
+	}
+	if dups < 40 || dups > 80 {
+		t.Errorf("expected about 60 duplicates, got %d", dups)
+	}
+}
+
+func TestClient_Query(t *testing.T) {
+	c, err := NewClient(Config{CorpusSize: 300, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 300 {
+		t.Fatalf("expected 300 chunks, got %d", c.Len())
+	}
+
+	res, err := c.Query(context.Background(), &types.RetrievalRequest{
+		Query:             "how do refunds and invoices work",
+		TopK:              5,
+		IncludeEmbeddings: true,
+		IncludeMetadata:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Chunks) != 5 {
+		t.Fatalf("expected 5 chunks, got %d", len(res.Chunks))
+	}
+	for i, ch := range res.Chunks {
+		if ch.Metadata["topic"] != "billing" {
+			t.Errorf("chunk %d: expected billing topic, got %v", i, ch.Metadata["topic"])
+		}
+		if len(ch.Embedding) != fakeembed.DefaultDimension {
+			t.Errorf("chunk %d: expected embedding", i)
+		}
+		if i > 0 && ch.Score > res.Chunks[i-1].Score {
+			t.Errorf("results not sorted by score at %d", i)
+		}
+	}
+
+	res, err = c.Query(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: res.QueryEmbedding,
+		TopK:           3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Chunks[0].Embedding != nil || res.Chunks[0].Metadata != nil {
+		t.Error("expected embeddings and metadata omitted unless requested")
+	}
+}
+
+func TestClient_Filter(t *testing.T) {
+	c, err := NewClient(Config{CorpusSize: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Query(context.Background(), &types.RetrievalRequest{
+		Query:           "load balancers and dns",
+		TopK:            20,
+		Filter:          map[string]interface{}{"topic": "database"},
+		IncludeMetadata: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range res.Chunks {
+		if ch.Metadata["topic"] != "database" {
+			t.Fatalf("filter not applied: got topic %v", ch.Metadata["topic"])
+		}
+	}
+}
+
+func TestClient_MetadataFields(t *testing.T) {
+	c, err := NewClient(Config{CorpusSize: 200, MetadataFields: retriever.MetadataFields{Exclude: []string{"duplicate_of"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Query(context.Background(), &types.RetrievalRequest{
+		Query:           "load balancers and dns",
+		TopK:            200,
+		IncludeMetadata: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range res.Chunks {
+		if _, ok := ch.Metadata["duplicate_of"]; ok || ch.Metadata["topic"] == nil {
+			t.Fatalf("expected only topic kept, got %v", ch.Metadata)
+		}
+	}
+
+	_, err = NewClient(Config{MetadataFields: retriever.MetadataFields{Include: []string{"topic"}, Exclude: []string{"topic"}}})
+	if err == nil {
+		t.Error("expected an error for a field both included and excluded")
+	}
+}
+
+func TestClient_QueryByID(t *testing.T) {
+	c, err := NewClient(Config{CorpusSize: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.QueryByID(context.Background(), "doc-00007", 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Chunks[0].ID != "doc-00007" {
+		t.Errorf("expected the chunk itself first, got %s", res.Chunks[0].ID)
+	}
+
+	if _, err := c.QueryByID(context.Background(), "missing", 1, ""); !errors.Is(err, retriever.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClient_Upsert(t *testing.T) {
+	c, err := NewClient(Config{CorpusSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	embedder := c.Embedder()
+
+	vectors := []types.Vector{
+		{ID: "doc-00003", Values: embedder.Vector("replaced"), Metadata: map[string]interface{}{"text": "replaced"}},
+		{ID: "new", Values: embedder.Vector("brand new chunk"), Metadata: map[string]interface{}{"content": "brand new chunk"}},
+	}
+	if err := c.Upsert(ctx, vectors, ""); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 11 {
+		t.Errorf("expected 11 chunks after replacing one and adding one, got %d", c.Len())
+	}
+
+	res, err := c.Query(ctx, &types.RetrievalRequest{Query: "brand new chunk", TopK: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Chunks[0].ID != "new" || res.Chunks[0].Text != "brand new chunk" {
+		t.Errorf("expected the new chunk first, got %s %q", res.Chunks[0].ID, res.Chunks[0].Text)
+	}
+
+	err = c.Upsert(ctx, []types.Vector{{ID: "short", Values: []float32{1, 2}}}, "")
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected ErrConfig for a dimension mismatch, got %v", err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	c, err := NewClient(Config{CorpusSize: 10, ErrorRate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Query(context.Background(), &types.RetrievalRequest{Query: "anything"})
+	if !errors.Is(err, fakeembed.ErrInjected) {
+		t.Errorf("expected injected error, got %v", err)
+	}
+
+	c, _ = NewClient(Config{CorpusSize: 10})
+	if _, err := c.Query(context.Background(), &types.RetrievalRequest{}); !errors.Is(err, retriever.ErrInvalidQuery) {
+		t.Errorf("expected ErrInvalidQuery, got %v", err)
+	}
+	if _, err := c.Query(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1, 2}}); err == nil {
+		t.Error("expected dimension mismatch error")
+	}
+}
+
+func TestNewClient_CustomChunks(t *testing.T) {
+	c, err := NewClient(Config{Chunks: []types.Chunk{{ID: "x", Text: "hello world"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 1 {
+		t.Errorf("expected custom corpus, got %d chunks", c.Len())
+	}
+
+	_, err = NewClient(Config{Chunks: []types.Chunk{{ID: "y", Embedding: []float32{1}}}})
+	if err == nil {
+		t.Error("expected dimension mismatch error")
+	}
+}
@@ -0,0 +1,124 @@
+package fake
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// topics seed the synthetic corpus. Each document combines a topic's
+// subject with facts drawn from it, so documents on one topic share
+// vocabulary and cluster together.
+var topics = []struct {
+	name    string
+	subject string
+	facts   []string
+}{
+	{"auth", "authentication", []string{
+		"tokens expire after one hour and must be refreshed",
+		"api keys are passed in the authorization header",
+		"oauth clients register a redirect uri before login",
+		"failed logins are rate limited per account",
+		"sessions are invalidated when the password changes",
+	}},
+	{"billing", "billing", []string{
+		"invoices are generated on the first day of each month",
+		"usage is metered per request and rounded up",
+		"credit cards are charged automatically at renewal",
+		"refunds are issued to the original payment method",
+		"annual plans receive a discount over monthly plans",
+	}},
+	{"deploy", "deployment", []string{
+		"containers are rolled out one zone at a time",
+		"health checks gate traffic to new replicas",
+		"rollbacks restore the previous image tag",
+		"configuration is loaded from environment variables",
+		"canary releases receive five percent of traffic first",
+	}},
+	{"storage", "storage", []string{
+		"objects are replicated across three availability zones",
+		"snapshots are taken nightly and kept for thirty days",
+		"buckets enforce encryption at rest by default",
+		"lifecycle rules move cold data to archive storage",
+		"deleted objects remain recoverable for seven days",
+	}},
+	{"search", "search", []string{
+		"documents are chunked before they are embedded",
+		"vector indexes return the nearest neighbours by cosine similarity",
+		"metadata filters narrow results before ranking",
+		"hybrid search combines keyword and vector scores",
+		"reranking reorders the top results with a cross encoder",
+	}},
+	{"network", "networking", []string{
+		"load balancers terminate tls at the edge",
+		"private subnets reach the internet through a nat gateway",
+		"dns records are cached for the configured ttl",
+		"firewall rules deny inbound traffic by default",
+		"service meshes add retries and mutual tls between services",
+	}},
+	{"database", "database", []string{
+		"primary keys are indexed automatically",
+		"read replicas lag the primary by a few milliseconds",
+		"migrations run inside a transaction when supported",
+		"connection pools cap concurrent queries per service",
+		"point in time recovery restores any second in the last week",
+	}},
+	{"monitoring", "monitoring", []string{
+		"metrics are scraped every fifteen seconds",
+		"alerts page the on call engineer after five minutes",
+		"traces sample one percent of requests by default",
+		"logs are retained for fourteen days",
+		"dashboards show latency percentiles per endpoint",
+	}},
+}
+
+// paraphrases reword a document slightly to make a near duplicate.
+var paraphrases = []func(string) string{
+	func(s string) string { return "Note: " + s },
+	func(s string) string { return s + " See the documentation for details." },
+	func(s string) string { return strings.Replace(s, "In ", "For ", 1) },
+	func(s string) string { return strings.TrimSuffix(s, ".") + ", as described above." },
+}
+
+// Corpus returns size deterministic synthetic chunks. A duplicateRate
+// fraction of them are near-duplicates of earlier chunks, so dedup has
+// something to remove. Each chunk carries "topic" and, for duplicates,
+// "duplicate_of" metadata. The same size, duplicateRate, and seed
+// always produce the same corpus.
+func Corpus(size int, duplicateRate float64, seed int64) []types.Chunk {
+	rng := rand.New(rand.NewSource(seed))
+	chunks := make([]types.Chunk, 0, size)
+
+	for i := 0; i < size; i++ {
+		id := fmt.Sprintf("doc-%05d", i)
+
+		if i > 0 && rng.Float64() < duplicateRate {
+			orig := chunks[rng.Intn(len(chunks))]
+			para := paraphrases[rng.Intn(len(paraphrases))]
+			chunks = append(chunks, types.Chunk{
+				ID:        id,
+				Text:      para(orig.Text),
+				ClusterID: -1,
+				Metadata: map[string]interface{}{
+					"topic":        orig.Metadata["topic"],
+					"duplicate_of": orig.ID,
+				},
+			})
+			continue
+		}
+
+		t := topics[rng.Intn(len(topics))]
+		a := rng.Intn(len(t.facts))
+		b := (a + 1 + rng.Intn(len(t.facts)-1)) % len(t.facts)
+		text := fmt.Sprintf("In %s, %s. Also, %s.", t.subject, t.facts[a], t.facts[b])
+		chunks = append(chunks, types.Chunk{
+			ID:        id,
+			Text:      text,
+			ClusterID: -1,
+			Metadata:  map[string]interface{}{"topic": t.name},
+		})
+	}
+	return chunks
+}
@@ -3,7 +3,10 @@ package retriever
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/errs"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 )
 
@@ -11,9 +14,9 @@ import (
 var (
 	ErrNotFound         = errors.New("not found")
 	ErrInvalidQuery     = errors.New("invalid query: must provide query text or embedding")
-	ErrConnectionFailed = errors.New("connection to vector database failed")
-	ErrRateLimited      = errors.New("rate limited by vector database")
-	ErrTimeout          = errors.New("query timeout")
+	ErrConnectionFailed = errs.New(errs.ErrBackend, "connection to vector database failed")
+	ErrRateLimited      = errs.New(errs.ErrBackend, "rate limited by vector database")
+	ErrTimeout          = errs.New(errs.ErrBackend, "query timeout")
 )
 
 // Retriever defines the interface for vector database query operations.
@@ -28,6 +31,79 @@ type Retriever interface {
 	Close() error
 }
 
+// NamespaceSwitcher is implemented by retrievers whose default namespace
+// can change while serving, as when a reindex switches the serving
+// namespace. Requests that name a namespace are unaffected.
+type NamespaceSwitcher interface {
+	SetDefaultNamespace(namespace string)
+}
+
+// NamespaceLister is implemented by retrievers that can enumerate their
+// backend's namespaces, so a request can search all of them.
+type NamespaceLister interface {
+	// ListNamespaces returns the backend's namespace names, sorted.
+	ListNamespaces(ctx context.Context) ([]string, error)
+}
+
+// Upserter is implemented by retrievers that can also write to their
+// backend, so serve can accept vectors as well as queries.
+type Upserter interface {
+	// Upsert writes vectors into namespace, or the default namespace if
+	// it is empty, replacing any with the same IDs.
+	Upsert(ctx context.Context, vectors []types.Vector, namespace string) error
+}
+
+// ConnectionReporter is implemented by retrievers that hold a long-lived
+// connection to the backend, so readiness checks can report on it.
+type ConnectionReporter interface {
+	// ConnectionState describes the connection, e.g. "READY", and reports
+	// whether queries can be sent on it now.
+	ConnectionState() (state string, ready bool)
+}
+
+// DropBelow removes chunks scoring below minScore, in place. It is the
+// fallback for backends without a server-side score threshold. A zero
+// minScore keeps everything.
+func DropBelow(chunks []types.Chunk, minScore float32) []types.Chunk {
+	if minScore == 0 {
+		return chunks
+	}
+	kept := chunks[:0]
+	for _, c := range chunks {
+		if c.Score >= minScore {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// DropExcluded removes chunks whose metadata matches exclude (see
+// Excluded), in place. It is the fallback for backends that cannot
+// filter them out on the server.
+func DropExcluded(chunks []types.Chunk, exclude map[string]interface{}) []types.Chunk {
+	if len(exclude) == 0 {
+		return chunks
+	}
+	kept := chunks[:0]
+	for _, c := range chunks {
+		if !Excluded(c.Metadata, exclude) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// Excluded reports whether metadata equals any key/value pair in exclude.
+// Values are compared by their printed form, so true matches "true".
+func Excluded(metadata, exclude map[string]interface{}) bool {
+	for k, want := range exclude {
+		if got, ok := metadata[k]; ok && fmt.Sprint(got) == fmt.Sprint(want) {
+			return true
+		}
+	}
+	return false
+}
+
 // EmbeddingProvider defines the interface for text embedding services.
 type EmbeddingProvider interface {
 	// Embed converts a single text into a vector embedding.
@@ -43,6 +119,41 @@ type EmbeddingProvider interface {
 	ModelName() string
 }
 
+// QueryEmbedder is implemented by embedding providers that normalize
+// queries differently from stored texts, such as with an E5 "query: "
+// prefix. Contract identifies the model and normalization, for cache keys.
+type QueryEmbedder interface {
+	EmbedQueries(ctx context.Context, texts []string) ([][]float32, error)
+	Contract() string
+}
+
+// EmbedQueries embeds query texts, as queries when emb is a QueryEmbedder.
+func EmbedQueries(ctx context.Context, emb EmbeddingProvider, texts []string) ([][]float32, error) {
+	if q, ok := emb.(QueryEmbedder); ok {
+		return q.EmbedQueries(ctx, texts)
+	}
+	if len(texts) == 1 {
+		v, err := emb.Embed(ctx, texts[0])
+		if err != nil {
+			return nil, err
+		}
+		return [][]float32{v}, nil
+	}
+	return emb.EmbedBatch(ctx, texts)
+}
+
+// EmbedQuery embeds one query text; see EmbedQueries.
+func EmbedQuery(ctx context.Context, emb EmbeddingProvider, text string) ([]float32, error) {
+	vectors, err := EmbedQueries(ctx, emb, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedding provider returned %d vectors for 1 text", len(vectors))
+	}
+	return vectors[0], nil
+}
+
 // RetrieverWithEmbedding combines a retriever with an embedding provider
 // to support text-based queries.
 type RetrieverWithEmbedding struct {
@@ -58,7 +169,7 @@ func (r *RetrieverWithEmbedding) Query(ctx context.Context, req *types.Retrieval
 			return nil, errors.New("embedding provider required for text queries")
 		}
 
-		embedding, err := r.Embedder.Embed(ctx, req.Query)
+		embedding, err := EmbedQuery(ctx, r.Embedder, req.Query)
 		if err != nil {
 			return nil, err
 		}
@@ -90,14 +201,23 @@ type Config struct {
 	// Host is the vector database endpoint
 	Host string
 
-	// Timeout for operations in seconds
+	// Timeout for operations in seconds, covering retries; see WithTimeout
 	TimeoutSeconds int
 
-	// MaxRetries for transient failures
+	// MaxRetries for transient failures; negative disables retries. See
+	// Retry.
 	MaxRetries int
 
+	// InitialBackoff and MaxBackoff bound the wait between retries
+	// (default DefaultInitialBackoff and DefaultMaxBackoff).
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
 	// DefaultNamespace if not specified in requests
 	DefaultNamespace string
+
+	// MetadataFields limits the metadata returned with each chunk.
+	MetadataFields MetadataFields
 }
 
 // DefaultConfig returns sensible defaults.
@@ -105,5 +225,7 @@ func DefaultConfig() Config {
 	return Config{
 		TimeoutSeconds: 30,
 		MaxRetries:     3,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
 	}
 }
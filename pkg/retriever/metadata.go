@@ -0,0 +1,102 @@
+package retriever
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// TextFields are the metadata fields adapters read chunk text from, in
+// order of preference.
+var TextFields = []string{"text", "content", "chunk_text"}
+
+// MetadataFields limits the metadata fields a retriever returns, so large
+// payloads are trimmed before they reach the pipeline. Names are
+// top-level metadata keys. Backends with payload selectors push the
+// selection down to the server; the others trim each chunk with Apply.
+type MetadataFields struct {
+	// Include, when set, keeps only these fields.
+	Include []string
+
+	// Exclude drops these fields.
+	Exclude []string
+}
+
+// IsZero reports whether f keeps every field.
+func (f MetadataFields) IsZero() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Validate reports empty field names and fields both included and
+// excluded.
+func (f MetadataFields) Validate() error {
+	for _, name := range append(slices.Clone(f.Include), f.Exclude...) {
+		if name == "" {
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid metadata fields: empty field name"))
+		}
+	}
+	for _, name := range f.Include {
+		if slices.Contains(f.Exclude, name) {
+			return errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid metadata fields: %q is both included and excluded", name))
+		}
+	}
+	return nil
+}
+
+// Require returns f changed to keep fields, for metadata the pipeline
+// reads after retrieval such as ACL lists or timestamps.
+func (f MetadataFields) Require(fields ...string) MetadataFields {
+	if f.IsZero() {
+		return f
+	}
+	out := MetadataFields{Include: slices.Clone(f.Include)}
+	for _, name := range fields {
+		if name != "" && len(out.Include) > 0 && !slices.Contains(out.Include, name) {
+			out.Include = append(out.Include, name)
+		}
+	}
+	for _, name := range f.Exclude {
+		if !slices.Contains(fields, name) {
+			out.Exclude = append(out.Exclude, name)
+		}
+	}
+	return out
+}
+
+// ForRequest returns f changed to keep the fields req's ExcludeFilter
+// matches on, so excluded chunks are still recognized downstream.
+func (f MetadataFields) ForRequest(req *types.RetrievalRequest) MetadataFields {
+	if f.IsZero() || req == nil || len(req.ExcludeFilter) == 0 {
+		return f
+	}
+	fields := make([]string, 0, len(req.ExcludeFilter))
+	for k := range req.ExcludeFilter {
+		fields = append(fields, k)
+	}
+	slices.Sort(fields)
+	return f.Require(fields...)
+}
+
+// Apply returns the fields of metadata that f keeps, in a new map when
+// any are dropped. Metadata is not modified.
+func (f MetadataFields) Apply(metadata map[string]interface{}) map[string]interface{} {
+	if f.IsZero() || len(metadata) == 0 {
+		return metadata
+	}
+	kept := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		if len(f.Include) > 0 && !slices.Contains(f.Include, k) {
+			continue
+		}
+		if slices.Contains(f.Exclude, k) {
+			continue
+		}
+		kept[k] = v
+	}
+	if len(kept) == len(metadata) {
+		return metadata
+	}
+	return kept
+}
@@ -0,0 +1,74 @@
+package retriever
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+func TestMetadataFields_Apply(t *testing.T) {
+	metadata := map[string]interface{}{"text": "t", "title": "a", "body_html": "<p>", "lang": "go"}
+
+	tests := []struct {
+		name   string
+		fields MetadataFields
+		want   []string
+	}{
+		{"zero keeps all", MetadataFields{}, []string{"body_html", "lang", "text", "title"}},
+		{"include", MetadataFields{Include: []string{"title", "lang"}}, []string{"lang", "title"}},
+		{"exclude", MetadataFields{Exclude: []string{"body_html"}}, []string{"lang", "text", "title"}},
+		{"both", MetadataFields{Include: []string{"title", "lang"}, Exclude: []string{"lang"}}, []string{"title"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.fields.Apply(metadata)
+			var keys []string
+			for _, k := range []string{"body_html", "lang", "text", "title"} {
+				if _, ok := got[k]; ok {
+					keys = append(keys, k)
+				}
+			}
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, keys)
+			}
+		})
+	}
+	if len(metadata) != 4 {
+		t.Error("Apply modified its input")
+	}
+}
+
+func TestMetadataFields_Require(t *testing.T) {
+	f := MetadataFields{Include: []string{"title"}, Exclude: []string{"acl_groups", "body_html"}}
+	got := f.Require("acl_groups", "updated_at")
+	want := MetadataFields{Include: []string{"title", "acl_groups", "updated_at"}, Exclude: []string{"body_html"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if len(f.Include) != 1 || len(f.Exclude) != 2 {
+		t.Error("Require modified its receiver")
+	}
+
+	exclude := MetadataFields{Exclude: []string{"distill_duplicate", "body_html"}}
+	req := &types.RetrievalRequest{ExcludeFilter: map[string]interface{}{"distill_duplicate": true}}
+	if got := exclude.ForRequest(req); !reflect.DeepEqual(got, MetadataFields{Exclude: []string{"body_html"}}) {
+		t.Errorf("expected the exclude filter key kept, got %+v", got)
+	}
+}
+
+func TestMetadataFields_Validate(t *testing.T) {
+	if err := (MetadataFields{Include: []string{"a"}, Exclude: []string{"b"}}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, f := range []MetadataFields{
+		{Include: []string{""}},
+		{Include: []string{"a"}, Exclude: []string{"a"}},
+	} {
+		if err := f.Validate(); !errors.Is(err, errs.ErrConfig) {
+			t.Errorf("%+v: expected a config error, got %v", f, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package retriever
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	distillmath "github.com/Siddhant-K-code/distill/pkg/math"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Multi-vector combine modes.
+const (
+	// CombineAverage queries once with the normalized mean of the vectors.
+	CombineAverage = "average"
+
+	// CombineFanOut queries once per vector and merges the results,
+	// keeping each chunk's best score.
+	CombineFanOut = "fanout"
+)
+
+// MaxFanOut caps the number of vectors or IDs in a single multi-query.
+const MaxFanOut = 32
+
+// ErrTooManyQueries is returned when a multi-query exceeds MaxFanOut.
+var ErrTooManyQueries = errs.New(errs.ErrConfig, fmt.Sprintf("too many query vectors or IDs (max %d)", MaxFanOut))
+
+// ValidCombine reports whether mode is a known combine mode. The empty
+// string means CombineAverage.
+func ValidCombine(mode string) bool {
+	switch mode {
+	case "", CombineAverage, CombineFanOut:
+		return true
+	}
+	return false
+}
+
+// AverageVectors returns the normalized mean of vectors, which must all
+// have the same dimension.
+func AverageVectors(vectors [][]float32) ([]float32, error) {
+	if len(vectors) == 0 {
+		return nil, ErrInvalidQuery
+	}
+	dim := len(vectors[0])
+	for _, v := range vectors {
+		if len(v) != dim || dim == 0 {
+			return nil, fmt.Errorf("query vectors must share one non-zero dimension")
+		}
+	}
+	mean := make([]float32, dim)
+	distillmath.MeanVector(mean, vectors)
+	distillmath.NormalizeInPlace(mean)
+	return mean, nil
+}
+
+// QueryVectors runs req once per vector, concurrently, and merges the
+// results. req's TopK, namespace, and filter apply to every query; req
+// itself is not modified.
+func QueryVectors(ctx context.Context, r Retriever, req *types.RetrievalRequest, vectors [][]float32) (*types.RetrievalResult, error) {
+	if len(vectors) == 0 {
+		return nil, ErrInvalidQuery
+	}
+	if len(vectors) > MaxFanOut {
+		return nil, ErrTooManyQueries
+	}
+	return fanOut(ctx, len(vectors), Merge, func(ctx context.Context, i int) (*types.RetrievalResult, error) {
+		q := *req
+		q.Query = ""
+		q.QueryEmbedding = vectors[i]
+		return r.Query(ctx, &q)
+	})
+}
+
+// QueryByIDs runs QueryByID for each ID, concurrently, and merges the
+// results. It fails if any ID is not found.
+func QueryByIDs(ctx context.Context, r Retriever, ids []string, topK int, namespace string) (*types.RetrievalResult, error) {
+	if len(ids) == 0 {
+		return nil, ErrInvalidQuery
+	}
+	if len(ids) > MaxFanOut {
+		return nil, ErrTooManyQueries
+	}
+	return fanOut(ctx, len(ids), Merge, func(ctx context.Context, i int) (*types.RetrievalResult, error) {
+		res, err := r.QueryByID(ctx, ids[i], topK, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("id %q: %w", ids[i], err)
+		}
+		return res, nil
+	})
+}
+
+// AllNamespaces as a namespace name in a multi-namespace request stands
+// for every namespace a NamespaceLister reports.
+const AllNamespaces = "*"
+
+// NamespaceKey is the metadata key QueryNamespaces records each chunk's
+// namespace under.
+const NamespaceKey = "namespace"
+
+// QueryNamespaces runs req once per namespace, concurrently, asking
+// namespaces[i] for topK[i] results, and tags each chunk's metadata with
+// its namespace under NamespaceKey. When req carries QueryEmbeddings,
+// each namespace is queried with each vector as QueryVectors does.
+// Results are concatenated rather than merged by ID, since IDs are only
+// unique within a namespace. req itself is not modified.
+func QueryNamespaces(ctx context.Context, r Retriever, req *types.RetrievalRequest, namespaces []string, topK []int) (*types.RetrievalResult, error) {
+	if len(namespaces) == 0 || len(namespaces) != len(topK) {
+		return nil, ErrInvalidQuery
+	}
+	if len(namespaces)*max(1, len(req.QueryEmbeddings)) > MaxFanOut {
+		return nil, ErrTooManyQueries
+	}
+	return fanOut(ctx, len(namespaces), concat, func(ctx context.Context, i int) (*types.RetrievalResult, error) {
+		q := *req
+		q.Namespace = namespaces[i]
+		q.Namespaces = nil
+		q.TopK = topK[i]
+		var res *types.RetrievalResult
+		var err error
+		if len(q.QueryEmbeddings) > 0 {
+			res, err = QueryVectors(ctx, r, &q, q.QueryEmbeddings)
+		} else {
+			res, err = r.Query(ctx, &q)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("namespace %q: %w", namespaces[i], err)
+		}
+		for j := range res.Chunks {
+			metadata := make(map[string]interface{}, len(res.Chunks[j].Metadata)+1)
+			for k, v := range res.Chunks[j].Metadata {
+				metadata[k] = v
+			}
+			metadata[NamespaceKey] = namespaces[i]
+			res.Chunks[j].Metadata = metadata
+		}
+		return res, nil
+	})
+}
+
+// fanOut runs n queries concurrently and combines the results with merge.
+// The first error cancels the rest.
+func fanOut(ctx context.Context, n int, merge func(...*types.RetrievalResult) *types.RetrievalResult, query func(context.Context, int) (*types.RetrievalResult, error)) (*types.RetrievalResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*types.RetrievalResult, n)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, err := query(ctx, i)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = res
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return merge(results...), nil
+}
+
+// Merge combines results into one, keeping each chunk once with its best
+// score, sorted by descending score. Latency is the slowest result's, as
+// the queries are expected to have run concurrently, the merge is
+// Truncated if any result was, and Retries is the sum.
+func Merge(results ...*types.RetrievalResult) *types.RetrievalResult {
+	merged := &types.RetrievalResult{}
+	index := make(map[string]int)
+	for _, res := range results {
+		if res == nil {
+			continue
+		}
+		if res.Latency > merged.Latency {
+			merged.Latency = res.Latency
+		}
+		merged.Truncated = merged.Truncated || res.Truncated
+		merged.Retries += res.Retries
+		for _, c := range res.Chunks {
+			if i, ok := index[c.ID]; ok {
+				if c.Score > merged.Chunks[i].Score {
+					merged.Chunks[i] = c
+				}
+				continue
+			}
+			index[c.ID] = len(merged.Chunks)
+			merged.Chunks = append(merged.Chunks, c)
+		}
+	}
+	sort.SliceStable(merged.Chunks, func(i, j int) bool {
+		return merged.Chunks[i].Score > merged.Chunks[j].Score
+	})
+	merged.TotalMatches = len(merged.Chunks)
+	return merged
+}
+
+// concat combines results like Merge but keeps every chunk, including
+// chunks sharing an ID.
+func concat(results ...*types.RetrievalResult) *types.RetrievalResult {
+	merged := &types.RetrievalResult{}
+	for _, res := range results {
+		if res == nil {
+			continue
+		}
+		if res.Latency > merged.Latency {
+			merged.Latency = res.Latency
+		}
+		merged.Truncated = merged.Truncated || res.Truncated
+		merged.Retries += res.Retries
+		merged.Chunks = append(merged.Chunks, res.Chunks...)
+	}
+	sort.SliceStable(merged.Chunks, func(i, j int) bool {
+		return merged.Chunks[i].Score > merged.Chunks[j].Score
+	})
+	merged.TotalMatches = len(merged.Chunks)
+	return merged
+}
@@ -0,0 +1,133 @@
+package retriever
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// mapRetriever answers QueryByID from a fixed map and Query by echoing
+// the first vector component as a chunk ID.
+type mapRetriever map[string][]types.Chunk
+
+func (m mapRetriever) Query(ctx context.Context, req *types.RetrievalRequest) (*types.RetrievalResult, error) {
+	id := string(rune('a' + int(req.QueryEmbedding[0])))
+	return &types.RetrievalResult{Chunks: m[id]}, nil
+}
+
+func (m mapRetriever) QueryByID(ctx context.Context, id string, topK int, namespace string) (*types.RetrievalResult, error) {
+	chunks, ok := m[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &types.RetrievalResult{Chunks: chunks}, nil
+}
+
+func (m mapRetriever) Close() error { return nil }
+
+var neighbors = mapRetriever{
+	"a": {{ID: "a", Score: 1}, {ID: "x", Score: 0.5}, {ID: "y", Score: 0.4}},
+	"b": {{ID: "b", Score: 1}, {ID: "x", Score: 0.7}, {ID: "z", Score: 0.3}},
+}
+
+func TestMerge(t *testing.T) {
+	got := Merge(
+		&types.RetrievalResult{Chunks: []types.Chunk{{ID: "x", Score: 0.5}, {ID: "y", Score: 0.4}}},
+		nil,
+		&types.RetrievalResult{Chunks: []types.Chunk{{ID: "x", Score: 0.7}, {ID: "z", Score: 0.6}}},
+	)
+	want := []struct {
+		id    string
+		score float32
+	}{{"x", 0.7}, {"z", 0.6}, {"y", 0.4}}
+
+	if len(got.Chunks) != len(want) || got.TotalMatches != len(want) {
+		t.Fatalf("expected %d merged chunks, got %d", len(want), len(got.Chunks))
+	}
+	for i, w := range want {
+		if got.Chunks[i].ID != w.id || got.Chunks[i].Score != w.score {
+			t.Errorf("position %d: expected %s/%.1f, got %s/%.1f", i, w.id, w.score, got.Chunks[i].ID, got.Chunks[i].Score)
+		}
+	}
+}
+
+func TestQueryByIDs(t *testing.T) {
+	res, err := QueryByIDs(context.Background(), neighbors, []string{"a", "b"}, 3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Chunks) != 5 {
+		t.Errorf("expected 5 distinct chunks, got %d", len(res.Chunks))
+	}
+
+	_, err = QueryByIDs(context.Background(), neighbors, []string{"a", "missing"}, 3, "")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	_, err = QueryByIDs(context.Background(), neighbors, make([]string, MaxFanOut+1), 3, "")
+	if !errors.Is(err, ErrTooManyQueries) || !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected ErrTooManyQueries, got %v", err)
+	}
+}
+
+func TestQueryVectors(t *testing.T) {
+	req := &types.RetrievalRequest{QueryEmbedding: []float32{9}, TopK: 3}
+	res, err := QueryVectors(context.Background(), neighbors, req, [][]float32{{0}, {1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Chunks) != 5 || res.Chunks[0].Score != 1 {
+		t.Errorf("unexpected merge: %+v", res.Chunks)
+	}
+	if req.QueryEmbedding[0] != 9 {
+		t.Error("request was modified")
+	}
+}
+
+func TestAverageVectors(t *testing.T) {
+	got, err := AverageVectors([][]float32{{1, 0}, {0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := got[0] - got[1]; d > 1e-6 || d < -1e-6 || got[0] < 0.7 || got[0] > 0.71 {
+		t.Errorf("expected normalized mean, got %v", got)
+	}
+
+	if _, err := AverageVectors([][]float32{{1, 0}, {1}}); err == nil {
+		t.Error("expected dimension mismatch error")
+	}
+	if ValidCombine("sum") || !ValidCombine("") || !ValidCombine(CombineFanOut) {
+		t.Error("unexpected ValidCombine result")
+	}
+}
+
+func TestDropBelow(t *testing.T) {
+	chunks := []types.Chunk{{ID: "a", Score: 0.9}, {ID: "b", Score: 0.4}, {ID: "c", Score: 0.7}}
+	if got := DropBelow(chunks, 0); len(got) != 3 {
+		t.Errorf("expected zero MinScore to keep all, got %d", len(got))
+	}
+	got := DropBelow(chunks, 0.5)
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "c" {
+		t.Errorf("expected a and c, got %+v", got)
+	}
+}
+
+func TestDropExcluded(t *testing.T) {
+	chunks := []types.Chunk{
+		{ID: "a", Metadata: map[string]interface{}{"distill_duplicate": true}},
+		{ID: "b", Metadata: map[string]interface{}{"distill_duplicate": false}},
+		{ID: "c"},
+		{ID: "d", Metadata: map[string]interface{}{"lang": "go"}},
+	}
+	got := DropExcluded(chunks, map[string]interface{}{"distill_duplicate": true, "lang": "go"})
+	if len(got) != 2 || got[0].ID != "b" || got[1].ID != "c" {
+		t.Errorf("expected b and c, got %+v", got)
+	}
+	if !Excluded(map[string]interface{}{"flag": "true"}, map[string]interface{}{"flag": true}) {
+		t.Error("expected values compared by printed form")
+	}
+}
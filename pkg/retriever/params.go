@@ -0,0 +1,36 @@
+package retriever
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/mitchellh/mapstructure"
+)
+
+// DecodeParams decodes a backend's free-form retriever.params section into
+// out, a pointer to the adapter's typed params struct. Values are matched
+// by mapstructure tag and weakly typed, so numbers and booleans may come
+// from environment strings. Unknown keys are an error, so typos do not
+// silently leave the backend untuned.
+func DecodeParams(raw map[string]interface{}, out interface{}) error {
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           out,
+		ErrorUnused:      true,
+		WeaklyTypedInput: true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := dec.Decode(raw); err != nil {
+		var me *mapstructure.Error
+		if errors.As(err, &me) {
+			// Drop mapstructure's multi-line framing and its '' root name
+			msg := strings.Join(me.Errors, "; ")
+			err = errors.New(strings.ReplaceAll(msg, "'' has invalid keys", "unknown keys"))
+		}
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid params: %w", err))
+	}
+	return nil
+}
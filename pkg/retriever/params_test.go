@@ -0,0 +1,30 @@
+package retriever
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+func TestDecodeParams(t *testing.T) {
+	var p struct {
+		EF    int  `mapstructure:"ef"`
+		Exact bool `mapstructure:"exact"`
+	}
+	if err := DecodeParams(map[string]interface{}{"ef": "64", "exact": "true"}, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.EF != 64 || !p.Exact {
+		t.Errorf("expected weakly typed decode, got %+v", p)
+	}
+
+	err := DecodeParams(map[string]interface{}{"ef_search": 64}, &p)
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Fatalf("expected config error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "unknown keys: ef_search") || strings.Contains(err.Error(), "\n") {
+		t.Errorf("expected one-line unknown key error, got %q", err)
+	}
+}
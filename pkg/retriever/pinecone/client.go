@@ -2,19 +2,39 @@ package pinecone
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/errs"
 	"github.com/Siddhant-K-code/distill/pkg/retriever"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 	"github.com/pinecone-io/go-pinecone/v3/pinecone"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Pinecone's per-query top-k limits. Queries that return values or
+// metadata have the lower cap. Pinecone has no offset or cursor to page
+// past them, so larger requests are clamped and reported as Truncated.
+const (
+	MaxTopK         = 10000
+	MaxTopKWithData = 1000
 )
 
 // Client implements the Retriever interface for Pinecone.
 type Client struct {
 	cfg     Config
 	pc      *pinecone.Client
+	host    string
 	idxConn *pinecone.IndexConnection
+
+	// Connections are per namespace; idxConn is the one opened at startup
+	mu        sync.Mutex
+	namespace string
+	conns     map[string]*pinecone.IndexConnection
 }
 
 // Config holds Pinecone-specific configuration.
@@ -26,31 +46,45 @@ type Config struct {
 
 	// IndexHost is the direct host URL (optional, will be resolved from IndexName)
 	IndexHost string
+
+	// HTTPClient is used for Pinecone's REST control plane, e.g. to record
+	// or replay DescribeIndex in tests. Vector operations use gRPC.
+	HTTPClient *http.Client
+
+	// Params tunes the connection and top-k caps; see ParseParams.
+	Params Params
 }
 
 // NewClient creates a new Pinecone retriever client.
 func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 	if cfg.APIKey == "" {
-		return nil, fmt.Errorf("API key is required")
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("API key is required"))
+	}
+	if cfg.IndexHost == "" {
+		cfg.IndexHost = cfg.Params.IndexHost
 	}
 	if cfg.IndexName == "" && cfg.IndexHost == "" {
-		return nil, fmt.Errorf("index name or host is required")
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("index name or host is required"))
+	}
+	if err := cfg.MetadataFields.Validate(); err != nil {
+		return nil, err
 	}
 
 	// Apply defaults
 	if cfg.TimeoutSeconds <= 0 {
 		cfg.TimeoutSeconds = 30
 	}
-	if cfg.MaxRetries <= 0 {
+	if cfg.MaxRetries == 0 {
 		cfg.MaxRetries = 3
 	}
 
 	// Create Pinecone client
 	pc, err := pinecone.NewClient(pinecone.NewClientParams{
-		ApiKey: cfg.APIKey,
+		ApiKey:     cfg.APIKey,
+		RestClient: cfg.HTTPClient,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Pinecone client: %w", err)
+		return nil, fmt.Errorf("failed to create Pinecone client: %w", classify(err))
 	}
 
 	// Resolve index host if not provided
@@ -58,7 +92,7 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 	if host == "" {
 		idx, err := pc.DescribeIndex(ctx, cfg.IndexName)
 		if err != nil {
-			return nil, fmt.Errorf("failed to describe index %q: %w", cfg.IndexName, err)
+			return nil, fmt.Errorf("failed to describe index %q: %w", cfg.IndexName, classify(err))
 		}
 		host = idx.Host
 	}
@@ -69,18 +103,94 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 		Namespace: cfg.DefaultNamespace,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to index: %w", err)
+		return nil, fmt.Errorf("failed to connect to index: %w", classify(err))
 	}
 
 	return &Client{
-		cfg:     cfg,
-		pc:      pc,
-		idxConn: idxConn,
+		cfg:       cfg,
+		pc:        pc,
+		host:      host,
+		idxConn:   idxConn,
+		namespace: cfg.DefaultNamespace,
+		conns:     map[string]*pinecone.IndexConnection{cfg.DefaultNamespace: idxConn},
 	}, nil
 }
 
-// Query retrieves chunks similar to the given embedding.
+// SetDefaultNamespace changes the namespace queried when a request names
+// none. Connections to earlier namespaces stay open for requests that
+// name them.
+func (c *Client) SetDefaultNamespace(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.namespace = namespace
+}
+
+// conn returns the connection for namespace, or for the default
+// namespace if it is empty, connecting on first use.
+func (c *Client) conn(namespace string) (*pinecone.IndexConnection, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if namespace == "" {
+		namespace = c.namespace
+	}
+	if conn, ok := c.conns[namespace]; ok {
+		return conn, nil
+	}
+	conn, err := c.pc.Index(pinecone.NewIndexConnParams{
+		Host:      c.host,
+		Namespace: namespace,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to namespace %q: %w", namespace, classify(err))
+	}
+	c.conns[namespace] = conn
+	return conn, nil
+}
+
+// ListNamespaces returns the index's namespaces, sorted, from its stats.
+func (c *Client) ListNamespaces(ctx context.Context) ([]string, error) {
+	ctx, cancel := c.cfg.WithTimeout(ctx)
+	defer cancel()
+	var stats *pinecone.DescribeIndexStatsResponse
+	_, err := c.cfg.Retry(ctx, func(ctx context.Context) error {
+		var err error
+		stats, err = c.idxConn.DescribeIndexStats(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describe index stats failed: %w", classify(err))
+	}
+	return namespaceNames(stats.Namespaces), nil
+}
+
+// namespaceNames returns the keys of an index stats namespace map, sorted.
+func namespaceNames(namespaces map[string]*pinecone.NamespaceSummary) []string {
+	names := make([]string, 0, len(namespaces))
+	for name := range namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Query retrieves chunks similar to the given embedding. A request with
+// Namespaces fans out across them over their connections and returns the
+// results concatenated, each chunk tagged with its namespace; see
+// retriever.QueryNamespaces. Each namespace returns its quota's TopK, or
+// the request's when the quota has none.
 func (c *Client) Query(ctx context.Context, req *types.RetrievalRequest) (*types.RetrievalResult, error) {
+	if len(req.Namespaces) > 0 {
+		names := make([]string, len(req.Namespaces))
+		topK := make([]int, len(req.Namespaces))
+		for i, ns := range req.Namespaces {
+			names[i] = ns.Name
+			topK[i] = ns.TopK
+			if topK[i] <= 0 {
+				topK[i] = req.TopK
+			}
+		}
+		return retriever.QueryNamespaces(ctx, c, req, names, topK)
+	}
 	if len(req.QueryEmbedding) == 0 {
 		return nil, retriever.ErrInvalidQuery
 	}
@@ -91,27 +201,44 @@ func (c *Client) Query(ctx context.Context, req *types.RetrievalRequest) (*types
 	if topK <= 0 {
 		topK = 10
 	}
+	// Exclusions are applied to the matches below, which needs metadata
+	withMetadata := req.IncludeMetadata || len(req.ExcludeFilter) > 0
+	topK, truncated := c.cfg.Params.capTopK(topK, req.IncludeEmbeddings || withMetadata)
 
 	// Build query request
 	queryReq := &pinecone.QueryByVectorValuesRequest{
 		Vector:          req.QueryEmbedding,
 		TopK:            uint32(topK),
 		IncludeValues:   req.IncludeEmbeddings,
-		IncludeMetadata: req.IncludeMetadata,
+		IncludeMetadata: withMetadata,
 	}
 
-	// Note: namespace is set at connection level in NewClient
-	// Per-query namespace override would require creating a new connection
+	// Namespaces are set per connection
+	conn, err := c.conn(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
 
 	// Execute query
-	resp, err := c.idxConn.QueryByVectorValues(ctx, queryReq)
+	ctx, cancel := c.cfg.WithTimeout(ctx)
+	defer cancel()
+	var resp *pinecone.QueryVectorsResponse
+	retries, err := c.cfg.Retry(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = conn.QueryByVectorValues(ctx, queryReq)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+		return nil, fmt.Errorf("query failed: %w", classify(err))
 	}
 
-	// Convert response to chunks
+	// Convert response to chunks. Pinecone has no server-side score
+	// threshold, so MinScore and ExcludeFilter are applied here.
 	chunks := make([]types.Chunk, 0, len(resp.Matches))
 	for _, match := range resp.Matches {
+		if match.Score < req.MinScore {
+			continue
+		}
 		chunk := types.Chunk{
 			ID:        match.Vector.Id,
 			Score:     match.Score,
@@ -123,9 +250,16 @@ func (c *Client) Query(ctx context.Context, req *types.RetrievalRequest) (*types
 			chunk.Embedding = *match.Vector.Values
 		}
 
-		// Extract metadata if included
+		// Extract metadata if included, dropping excluded matches
+		var metadata map[string]interface{}
 		if match.Vector.Metadata != nil {
-			chunk.Metadata = convertMetadataToMap(match.Vector.Metadata)
+			metadata = convertMetadataToMap(match.Vector.Metadata)
+		}
+		if retriever.Excluded(metadata, req.ExcludeFilter) {
+			continue
+		}
+		if metadata != nil && req.IncludeMetadata {
+			chunk.Metadata = metadata
 
 			// Try to extract text from common metadata fields
 			if text, ok := chunk.Metadata["text"].(string); ok {
@@ -135,6 +269,10 @@ func (c *Client) Query(ctx context.Context, req *types.RetrievalRequest) (*types
 			} else if text, ok := chunk.Metadata["chunk_text"].(string); ok {
 				chunk.Text = text
 			}
+
+			// Pinecone has no metadata selector, so fields are trimmed
+			// after the text is read
+			chunk.Metadata = c.cfg.MetadataFields.ForRequest(req).Apply(chunk.Metadata)
 		}
 
 		chunks = append(chunks, chunk)
@@ -144,6 +282,8 @@ func (c *Client) Query(ctx context.Context, req *types.RetrievalRequest) (*types
 		Chunks:         chunks,
 		QueryEmbedding: req.QueryEmbedding,
 		TotalMatches:   len(chunks),
+		Truncated:      truncated,
+		Retries:        retries,
 		Latency:        time.Since(start),
 	}, nil
 }
@@ -155,6 +295,7 @@ func (c *Client) QueryByID(ctx context.Context, id string, topK int, namespace s
 	if topK <= 0 {
 		topK = 10
 	}
+	topK, truncated := c.cfg.Params.capTopK(topK, true)
 
 	// Build query request
 	queryReq := &pinecone.QueryByVectorIdRequest{
@@ -164,10 +305,22 @@ func (c *Client) QueryByID(ctx context.Context, id string, topK int, namespace s
 		IncludeMetadata: true,
 	}
 
+	conn, err := c.conn(namespace)
+	if err != nil {
+		return nil, err
+	}
+
 	// Execute query
-	resp, err := c.idxConn.QueryByVectorId(ctx, queryReq)
+	ctx, cancel := c.cfg.WithTimeout(ctx)
+	defer cancel()
+	var resp *pinecone.QueryVectorsResponse
+	retries, err := c.cfg.Retry(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = conn.QueryByVectorId(ctx, queryReq)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("query by ID failed: %w", err)
+		return nil, fmt.Errorf("query by ID failed: %w", classify(err))
 	}
 
 	// Convert response to chunks
@@ -191,6 +344,7 @@ func (c *Client) QueryByID(ctx context.Context, id string, topK int, namespace s
 			} else if text, ok := chunk.Metadata["content"].(string); ok {
 				chunk.Text = text
 			}
+			chunk.Metadata = c.cfg.MetadataFields.Apply(chunk.Metadata)
 		}
 
 		chunks = append(chunks, chunk)
@@ -199,16 +353,80 @@ func (c *Client) QueryByID(ctx context.Context, id string, topK int, namespace s
 	return &types.RetrievalResult{
 		Chunks:       chunks,
 		TotalMatches: len(chunks),
+		Truncated:    truncated,
+		Retries:      retries,
 		Latency:      time.Since(start),
 	}, nil
 }
 
+// Upsert writes vectors into namespace in batches of
+// retriever.UpsertBatchSize, each retried on transient failures. Metadata
+// must convert to a protobuf Struct; Pinecone further rejects nested
+// objects and nulls.
+func (c *Client) Upsert(ctx context.Context, vectors []types.Vector, namespace string) error {
+	conn, err := c.conn(namespace)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.cfg.WithTimeout(ctx)
+	defer cancel()
+	return retriever.Batches(vectors, retriever.UpsertBatchSize, func(batch []types.Vector) error {
+		pcVectors, err := toPineconeVectors(batch)
+		if err != nil {
+			return err
+		}
+		_, err = c.cfg.Retry(ctx, func(ctx context.Context) error {
+			_, err := conn.UpsertVectors(ctx, pcVectors)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("upsert failed: %w", retriever.ClassifyUpsert(err))
+		}
+		return nil
+	})
+}
+
+// toPineconeVectors converts vectors for an upsert.
+func toPineconeVectors(vectors []types.Vector) ([]*pinecone.Vector, error) {
+	out := make([]*pinecone.Vector, len(vectors))
+	for i, v := range vectors {
+		values := v.Values
+		out[i] = &pinecone.Vector{Id: v.ID, Values: &values}
+		if len(v.Metadata) == 0 {
+			continue
+		}
+		metadata, err := structpb.NewStruct(v.Metadata)
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("vector %q has invalid metadata: %w", v.ID, err))
+		}
+		out[i].Metadata = metadata
+	}
+	return out, nil
+}
+
 // Close releases resources.
 func (c *Client) Close() error {
-	if c.idxConn != nil {
-		return c.idxConn.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var firstErr error
+	for _, conn := range c.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	c.conns = nil
+	return firstErr
+}
+
+// classify is errs.ClassifyRemote that also reads the HTTP status of the
+// control-plane errors the Pinecone SDK returns.
+func classify(err error) error {
+	var pe *pinecone.PineconeError
+	if errors.As(err, &pe) {
+		err = &errs.StatusError{StatusCode: pe.Code, Err: err}
 	}
-	return nil
+	return errs.ClassifyRemote(err)
 }
 
 // convertMetadataToMap converts Pinecone Struct metadata to a Go map.
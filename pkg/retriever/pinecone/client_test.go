@@ -0,0 +1,182 @@
+package pinecone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"github.com/Siddhant-K-code/distill/pkg/vcr"
+	"github.com/pinecone-io/go-pinecone/v3/pinecone"
+)
+
+// These tests replay DescribeIndex from testdata/*.json. Refresh them with
+// `make fixtures` (needs PINECONE_API_KEY and PINECONE_INDEX). Queries go
+// over gRPC and are not recorded.
+
+// fixtureIndex is the index name stored in the cassettes; the real index
+// used for recording is rewritten to it.
+const fixtureIndex = "distill-fixtures"
+
+var (
+	_ retriever.Retriever       = (*Client)(nil)
+	_ retriever.NamespaceLister = (*Client)(nil)
+	_ retriever.Upserter        = (*Client)(nil)
+)
+
+// credentials returns the real key and index when recording and
+// placeholders otherwise.
+func credentials(t *testing.T) (key, index string) {
+	t.Helper()
+	if !vcr.Recording() {
+		return "pc-test", fixtureIndex
+	}
+	key, index = os.Getenv("PINECONE_API_KEY"), os.Getenv("PINECONE_INDEX")
+	if key == "" || index == "" {
+		t.Skip("PINECONE_API_KEY and PINECONE_INDEX are required to record fixtures")
+	}
+	return key, index
+}
+
+func TestReplay_DescribeIndex(t *testing.T) {
+	key, index := credentials(t)
+	rec := vcr.Start(t, "testdata/describe_index.json", key)
+	rec.Replace(index, fixtureIndex)
+
+	c, err := NewClient(context.Background(), Config{
+		Config:     retriever.Config{APIKey: key},
+		IndexName:  index,
+		HTTPClient: rec.Client(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	if c.idxConn == nil {
+		t.Error("expected an index connection for the resolved host")
+	}
+}
+
+func TestReplay_IndexNotFound(t *testing.T) {
+	key, _ := credentials(t)
+	rec := vcr.Start(t, "testdata/index_not_found.json", key)
+
+	_, err := NewClient(context.Background(), Config{
+		Config:     retriever.Config{APIKey: key},
+		IndexName:  "distill-missing",
+		HTTPClient: rec.Client(),
+	})
+	if !errors.Is(err, errs.ErrBackend) {
+		t.Errorf("expected backend error, got %v", err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	forbidden := fmt.Errorf("describe index: %w", &pinecone.PineconeError{Code: 403, Msg: errors.New("project 401 is not allowed")})
+	if !errors.Is(classify(forbidden), errs.ErrAuth) {
+		t.Errorf("403 response: got %v, want an auth error", classify(forbidden))
+	}
+	missing := &pinecone.PineconeError{Code: 404, Msg: errors.New("index distill-401 not found")}
+	if got := classify(missing); errors.Is(got, errs.ErrAuth) || !errors.Is(got, errs.ErrBackend) {
+		t.Errorf("404 response: got %v, want a backend error", got)
+	}
+}
+
+func TestNewClient_Validation(t *testing.T) {
+	_, err := NewClient(context.Background(), Config{IndexName: fixtureIndex})
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected config error without API key, got %v", err)
+	}
+	_, err = NewClient(context.Background(), Config{Config: retriever.Config{APIKey: "pc-test"}})
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected config error without index, got %v", err)
+	}
+}
+
+func TestCapTopK(t *testing.T) {
+	lowered := Params{MaxTopK: 500, MaxTopKWithData: 200}
+	tests := []struct {
+		params    Params
+		topK      int
+		withData  bool
+		want      int
+		truncated bool
+	}{
+		{Params{}, 50, true, 50, false},
+		{Params{}, 1000, true, 1000, false},
+		{Params{}, 1001, true, MaxTopKWithData, true},
+		{Params{}, 5000, false, 5000, false},
+		{Params{}, 20000, false, MaxTopK, true},
+		{lowered, 300, true, 200, true},
+		{lowered, 300, false, 300, false},
+		{lowered, 600, false, 500, true},
+		{Params{MaxTopK: 100}, 300, true, 100, true},
+	}
+	for _, tt := range tests {
+		got, truncated := tt.params.capTopK(tt.topK, tt.withData)
+		if got != tt.want || truncated != tt.truncated {
+			t.Errorf("%+v.capTopK(%d, %v) = %d, %v; want %d, %v", tt.params, tt.topK, tt.withData, got, truncated, tt.want, tt.truncated)
+		}
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	p, err := ParseParams(map[string]interface{}{"index_host": "docs-abc.svc.pinecone.io", "max_top_k": "2000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.IndexHost != "docs-abc.svc.pinecone.io" || p.MaxTopK != 2000 {
+		t.Errorf("unexpected params %+v", p)
+	}
+
+	for name, raw := range map[string]map[string]interface{}{
+		"unknown key":   {"pod_type": "p1"},
+		"cap too large": {"max_top_k_with_data": 5000},
+		"negative cap":  {"max_top_k": -1},
+	} {
+		if _, err := ParseParams(raw); !errors.Is(err, errs.ErrConfig) {
+			t.Errorf("%s: expected config error, got %v", name, err)
+		}
+	}
+}
+
+func TestNamespaceNames(t *testing.T) {
+	got := namespaceNames(map[string]*pinecone.NamespaceSummary{
+		"tenant-b": {VectorCount: 3},
+		"":         {VectorCount: 1},
+		"tenant-a": {VectorCount: 2},
+	})
+	if want := []string{"", "tenant-a", "tenant-b"}; !slices.Equal(got, want) {
+		t.Errorf("namespaceNames() = %v, want %v", got, want)
+	}
+}
+
+func TestToPineconeVectors(t *testing.T) {
+	got, err := toPineconeVectors([]types.Vector{
+		{ID: "a", Values: []float32{1, 0}, Metadata: map[string]interface{}{"text": "hello", "year": 2024}},
+		{ID: "b", Values: []float32{0, 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Id != "a" || !slices.Equal(*got[1].Values, []float32{0, 1}) {
+		t.Fatalf("unexpected vectors: %v", got)
+	}
+	if text := got[0].Metadata.AsMap()["text"]; text != "hello" {
+		t.Errorf("metadata text = %v, want hello", text)
+	}
+	if got[1].Metadata != nil {
+		t.Errorf("empty metadata converted to %v, want nil", got[1].Metadata)
+	}
+
+	_, err = toPineconeVectors([]types.Vector{{ID: "c", Values: []float32{1}, Metadata: map[string]interface{}{"bad": make(chan int)}}})
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("invalid metadata: got %v, want ErrConfig", err)
+	}
+}
@@ -0,0 +1,57 @@
+package pinecone
+
+import (
+	"fmt"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+)
+
+// Params are Pinecone tuning knobs, set under retriever.params.pinecone.
+// Pinecone queries expose no search-quality settings, so these cover how
+// distill talks to the index. Zero values keep the defaults.
+type Params struct {
+	// IndexHost connects straight to the index's data plane host,
+	// skipping the DescribeIndex lookup at startup. Useful for private
+	// endpoints and keys without control plane access.
+	IndexHost string `mapstructure:"index_host"`
+
+	// MaxTopK and MaxTopKWithData lower the per-query caps below
+	// Pinecone's limits, e.g. to keep latency down on small pod indexes.
+	// Queries above the cap are clamped and reported as Truncated.
+	MaxTopK         int `mapstructure:"max_top_k"`
+	MaxTopKWithData int `mapstructure:"max_top_k_with_data"`
+}
+
+// ParseParams decodes and validates the retriever.params.pinecone section.
+func ParseParams(raw map[string]interface{}) (Params, error) {
+	var p Params
+	if err := retriever.DecodeParams(raw, &p); err != nil {
+		return Params{}, err
+	}
+	if p.MaxTopK < 0 || p.MaxTopK > MaxTopK {
+		return Params{}, errs.Wrap(errs.ErrConfig, fmt.Errorf("max_top_k must be between 0 and %d, got %d", MaxTopK, p.MaxTopK))
+	}
+	if p.MaxTopKWithData < 0 || p.MaxTopKWithData > MaxTopKWithData {
+		return Params{}, errs.Wrap(errs.ErrConfig, fmt.Errorf("max_top_k_with_data must be between 0 and %d, got %d", MaxTopKWithData, p.MaxTopKWithData))
+	}
+	return p, nil
+}
+
+// capTopK clamps topK to the effective limit and reports whether it did.
+func (p Params) capTopK(topK int, withData bool) (int, bool) {
+	limit := MaxTopK
+	if p.MaxTopK > 0 {
+		limit = p.MaxTopK
+	}
+	if withData {
+		limit = min(limit, MaxTopKWithData)
+		if p.MaxTopKWithData > 0 {
+			limit = min(limit, p.MaxTopKWithData)
+		}
+	}
+	if topK > limit {
+		return limit, true
+	}
+	return topK, false
+}
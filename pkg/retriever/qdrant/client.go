@@ -4,12 +4,16 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/errs"
 	"github.com/Siddhant-K-code/distill/pkg/retriever"
 	"github.com/Siddhant-K-code/distill/pkg/types"
 	pb "github.com/qdrant/go-client/qdrant"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
@@ -17,10 +21,15 @@ import (
 
 // Client implements the Retriever interface for Qdrant.
 type Client struct {
-	cfg        Config
-	conn       *grpc.ClientConn
-	points     pb.PointsClient
-	collection string
+	cfg         Config
+	conn        *grpc.ClientConn
+	points      pb.PointsClient
+	collection  string
+	search      *pb.SearchParams
+	consistency *pb.ReadConsistency
+
+	// stopMonitor ends the connection monitor started by NewClient
+	stopMonitor context.CancelFunc
 }
 
 // Config holds Qdrant-specific configuration.
@@ -35,27 +44,48 @@ type Config struct {
 
 	// GRPCPort is the gRPC port (default: 6334)
 	GRPCPort int
+
+	// PageSize is the most points fetched per search call (default: 256).
+	// Larger TopK values are paged with offsets, so a big over-fetch does
+	// not become one oversized response.
+	PageSize int
+
+	// Params tunes search quality; see ParseParams.
+	Params Params
 }
 
+// DefaultPageSize is the default Config.PageSize.
+const DefaultPageSize = 256
+
 // NewClient creates a new Qdrant retriever client.
 func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 	if cfg.Host == "" {
-		return nil, fmt.Errorf("host is required")
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("host is required"))
 	}
 	if cfg.Collection == "" {
-		return nil, fmt.Errorf("collection is required")
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("collection is required"))
 	}
 
 	// Apply defaults
 	if cfg.TimeoutSeconds <= 0 {
 		cfg.TimeoutSeconds = 30
 	}
-	if cfg.MaxRetries <= 0 {
+	if cfg.MaxRetries == 0 {
 		cfg.MaxRetries = 3
 	}
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = DefaultPageSize
+	}
 	if cfg.GRPCPort <= 0 {
 		cfg.GRPCPort = 6334
 	}
+	consistency, err := cfg.Params.readConsistency()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.MetadataFields.Validate(); err != nil {
+		return nil, err
+	}
 
 	// Build connection options
 	var opts []grpc.DialOption
@@ -70,15 +100,48 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.GRPCPort)
 	conn, err := grpc.NewClient(addr, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Qdrant at %s: %w", addr, err)
+		return nil, fmt.Errorf("failed to connect to Qdrant at %s: %w", addr, errs.ClassifyRemote(err))
 	}
 
-	return &Client{
-		cfg:        cfg,
-		conn:       conn,
-		points:     pb.NewPointsClient(conn),
-		collection: cfg.Collection,
-	}, nil
+	monitorCtx, stop := context.WithCancel(context.Background())
+	c := &Client{
+		cfg:         cfg,
+		conn:        conn,
+		points:      pb.NewPointsClient(conn),
+		collection:  cfg.Collection,
+		search:      cfg.Params.searchParams(),
+		consistency: consistency,
+		stopMonitor: stop,
+	}
+	go c.monitor(monitorCtx)
+	return c, nil
+}
+
+// monitor keeps the connection up. gRPC drops a failed connection to IDLE
+// and would only redial on the next query, which then fails or waits, so
+// an idle connection is redialed at once. A restarted Qdrant is picked up
+// in the background, with gRPC's reconnect backoff.
+func (c *Client) monitor(ctx context.Context) {
+	for {
+		state := c.conn.GetState()
+		if state == connectivity.Idle {
+			c.conn.Connect()
+		}
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+}
+
+// ConnectionState reports the gRPC connection state. Only READY counts as
+// ready; a connection still dialing or backing off after a failure does
+// not.
+func (c *Client) ConnectionState() (string, bool) {
+	if c.conn == nil {
+		return connectivity.Shutdown.String(), false
+	}
+	state := c.conn.GetState()
+	return state.String(), state == connectivity.Ready
 }
 
 // Query retrieves chunks similar to the given embedding.
@@ -98,81 +161,79 @@ func (c *Client) Query(ctx context.Context, req *types.RetrievalRequest) (*types
 	if c.cfg.APIKey != "" {
 		ctx = metadata.AppendToOutgoingContext(ctx, "api-key", c.cfg.APIKey)
 	}
+	ctx, cancel := c.cfg.WithTimeout(ctx)
+	defer cancel()
 
 	// Convert float32 to float64 for Qdrant
 	vector := make([]float32, len(req.QueryEmbedding))
 	copy(vector, req.QueryEmbedding)
 
 	// Build search request
+	fields := c.cfg.MetadataFields.ForRequest(req)
 	searchReq := &pb.SearchPoints{
 		CollectionName: c.collection,
 		Vector:         vector,
-		Limit:          uint64(topK),
-		WithPayload: &pb.WithPayloadSelector{
-			SelectorOptions: &pb.WithPayloadSelector_Enable{Enable: req.IncludeMetadata},
-		},
+		WithPayload:    payloadSelector(req.IncludeMetadata, fields),
 		WithVectors: &pb.WithVectorsSelector{
 			SelectorOptions: &pb.WithVectorsSelector_Enable{Enable: req.IncludeEmbeddings},
 		},
-	}
-
-	// Add filter if provided
-	if len(req.Filter) > 0 {
-		searchReq.Filter = buildFilter(req.Filter)
-	}
-
-	// Execute search
-	resp, err := c.points.Search(ctx, searchReq)
-	if err != nil {
-		return nil, fmt.Errorf("search failed: %w", err)
-	}
-
-	// Convert response to chunks
-	chunks := make([]types.Chunk, 0, len(resp.Result))
-	for _, point := range resp.Result {
-		chunk := types.Chunk{
-			Score:     point.Score,
-			ClusterID: -1,
+		Params:          c.search,
+		ReadConsistency: c.consistency,
+	}
+
+	// Add filters if provided
+	searchReq.Filter = buildFilter(req.Filter, req.ExcludeFilter)
+
+	// Push the score threshold down so low matches are never sent. Qdrant
+	// compares it against the collection's metric as-is.
+	if req.MinScore > 0 {
+		minScore := req.MinScore
+		searchReq.ScoreThreshold = &minScore
+	}
+
+	// Execute search, a page at a time. Points upserted between pages can
+	// shift results, so IDs already seen are skipped.
+	chunks := make([]types.Chunk, 0, min(topK, c.cfg.PageSize))
+	seen := make(map[string]bool)
+	retries := 0
+	for offset := 0; offset < topK; offset += c.cfg.PageSize {
+		limit := min(c.cfg.PageSize, topK-offset)
+		searchReq.Limit = uint64(limit)
+		pageOffset := uint64(offset)
+		searchReq.Offset = &pageOffset
+
+		var resp *pb.SearchResponse
+		n, err := c.cfg.Retry(ctx, func(ctx context.Context) error {
+			var err error
+			resp, err = c.points.Search(ctx, searchReq)
+			return err
+		})
+		retries += n
+		if err != nil {
+			return nil, fmt.Errorf("search failed: %w", errs.ClassifyRemote(err))
 		}
 
-		// Extract ID
-		if point.Id != nil {
-			switch id := point.Id.PointIdOptions.(type) {
-			case *pb.PointId_Num:
-				chunk.ID = fmt.Sprintf("%d", id.Num)
-			case *pb.PointId_Uuid:
-				chunk.ID = id.Uuid
+		for _, point := range resp.Result {
+			chunk := toChunk(point)
+			if seen[chunk.ID] {
+				continue
 			}
+			// Text fields are fetched for the chunk text even when not
+			// selected, so trim them here
+			chunk.Metadata = fields.Apply(chunk.Metadata)
+			seen[chunk.ID] = true
+			chunks = append(chunks, chunk)
 		}
-
-		// Extract embedding if included
-		if point.Vectors != nil {
-			if vec := point.Vectors.GetVector(); vec != nil {
-				chunk.Embedding = vec.GetData() //nolint:staticcheck // Qdrant SDK deprecation, no replacement yet
-			}
+		if len(resp.Result) < limit {
+			break
 		}
-
-		// Extract payload/metadata
-		if point.Payload != nil {
-			chunk.Metadata = convertPayloadToMap(point.Payload)
-
-			// Try to extract text from common fields
-			if text, ok := chunk.Metadata["text"].(string); ok {
-				chunk.Text = text
-			} else if text, ok := chunk.Metadata["content"].(string); ok {
-				chunk.Text = text
-			} else if text, ok := chunk.Metadata["chunk_text"].(string); ok {
-				chunk.Text = text
-			}
-		}
-
-		chunks = append(chunks, chunk)
 	}
 
 	return &types.RetrievalResult{
 		Chunks:         chunks,
 		QueryEmbedding: req.QueryEmbedding,
 		TotalMatches:   len(chunks),
+		Retries:        retries,
 		Latency:        time.Since(start),
 	}, nil
 }
@@ -193,20 +254,27 @@ func (c *Client) QueryByID(ctx context.Context, id string, topK int, namespace s
 	// First, fetch the vector by ID
 	getReq := &pb.GetPoints{
 		CollectionName: c.collection,
-		Ids: []*pb.PointId{
-			{PointIdOptions: &pb.PointId_Uuid{Uuid: id}},
-		},
+		Ids:            []*pb.PointId{pointID(id)},
 		WithPayload: &pb.WithPayloadSelector{
 			SelectorOptions: &pb.WithPayloadSelector_Enable{Enable: true},
 		},
 		WithVectors: &pb.WithVectorsSelector{
 			SelectorOptions: &pb.WithVectorsSelector_Enable{Enable: true},
 		},
-	}
-
-	getResp, err := c.points.Get(ctx, getReq)
+		ReadConsistency: c.consistency,
+	}
+
+	// The lookup and the search below have a deadline each
+	getCtx, cancel := c.cfg.WithTimeout(ctx)
+	defer cancel()
+	var getResp *pb.GetResponse
+	retries, err := c.cfg.Retry(getCtx, func(ctx context.Context) error {
+		var err error
+		getResp, err = c.points.Get(ctx, getReq)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("get point failed: %w", err)
+		return nil, fmt.Errorf("get point failed: %w", errs.ClassifyRemote(err))
 	}
 
 	if len(getResp.Result) == 0 {
@@ -240,24 +308,167 @@ func (c *Client) QueryByID(ctx context.Context, id string, topK int, namespace s
 		return nil, err
 	}
 
+	result.Retries += retries
 	result.Latency = time.Since(start)
 	return result, nil
 }
 
+// Upsert writes vectors to the collection in batches of
+// retriever.UpsertBatchSize, waiting for each to be applied. IDs must be
+// unsigned integers or UUIDs, as Qdrant requires. Qdrant has no
+// namespaces, so namespace is ignored as it is by Query.
+func (c *Client) Upsert(ctx context.Context, vectors []types.Vector, namespace string) error {
+	if c.cfg.APIKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "api-key", c.cfg.APIKey)
+	}
+	ctx, cancel := c.cfg.WithTimeout(ctx)
+	defer cancel()
+
+	wait := true
+	return retriever.Batches(vectors, retriever.UpsertBatchSize, func(batch []types.Vector) error {
+		points := make([]*pb.PointStruct, len(batch))
+		for i, v := range batch {
+			payload, err := pb.TryValueMap(v.Metadata)
+			if err != nil {
+				return errs.Wrap(errs.ErrConfig, fmt.Errorf("vector %q has invalid metadata: %w", v.ID, err))
+			}
+			points[i] = &pb.PointStruct{
+				Id:      pointID(v.ID),
+				Vectors: pb.NewVectorsDense(v.Values),
+				Payload: payload,
+			}
+		}
+		req := &pb.UpsertPoints{CollectionName: c.collection, Wait: &wait, Points: points}
+		_, err := c.cfg.Retry(ctx, func(ctx context.Context) error {
+			_, err := c.points.Upsert(ctx, req)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("upsert failed: %w", retriever.ClassifyUpsert(err))
+		}
+		return nil
+	})
+}
+
+// payloadSelector selects the payload fields a search returns. An
+// include list also fetches the text fields, which chunk text is read
+// from, and an exclude list never drops them.
+func payloadSelector(include bool, fields retriever.MetadataFields) *pb.WithPayloadSelector {
+	switch {
+	case !include || fields.IsZero():
+		return &pb.WithPayloadSelector{
+			SelectorOptions: &pb.WithPayloadSelector_Enable{Enable: include},
+		}
+	case len(fields.Include) > 0:
+		selected := slices.Clone(fields.Include)
+		for _, name := range retriever.TextFields {
+			if !slices.Contains(selected, name) {
+				selected = append(selected, name)
+			}
+		}
+		return &pb.WithPayloadSelector{
+			SelectorOptions: &pb.WithPayloadSelector_Include{
+				Include: &pb.PayloadIncludeSelector{Fields: selected},
+			},
+		}
+	default:
+		var dropped []string
+		for _, name := range fields.Exclude {
+			if !slices.Contains(retriever.TextFields, name) {
+				dropped = append(dropped, name)
+			}
+		}
+		if len(dropped) == 0 {
+			return &pb.WithPayloadSelector{
+				SelectorOptions: &pb.WithPayloadSelector_Enable{Enable: true},
+			}
+		}
+		return &pb.WithPayloadSelector{
+			SelectorOptions: &pb.WithPayloadSelector_Exclude{
+				Exclude: &pb.PayloadExcludeSelector{Fields: dropped},
+			},
+		}
+	}
+}
+
+// toChunk converts a search hit to a chunk.
+func toChunk(point *pb.ScoredPoint) types.Chunk {
+	chunk := types.Chunk{
+		Score:     point.Score,
+		ClusterID: -1,
+	}
+
+	// Extract ID
+	if point.Id != nil {
+		switch id := point.Id.PointIdOptions.(type) {
+		case *pb.PointId_Num:
+			chunk.ID = fmt.Sprintf("%d", id.Num)
+		case *pb.PointId_Uuid:
+			chunk.ID = id.Uuid
+		}
+	}
+
+	// Extract embedding if included
+	if point.Vectors != nil {
+		if vec := point.Vectors.GetVector(); vec != nil {
+			chunk.Embedding = vec.GetData() //nolint:staticcheck // Qdrant SDK deprecation, no replacement yet
+		}
+	}
+
+	// Extract payload/metadata
+	if point.Payload != nil {
+		chunk.Metadata = convertPayloadToMap(point.Payload)
+
+		// Try to extract text from common fields
+		if text, ok := chunk.Metadata["text"].(string); ok {
+			chunk.Text = text
+		} else if text, ok := chunk.Metadata["content"].(string); ok {
+			chunk.Text = text
+		} else if text, ok := chunk.Metadata["chunk_text"].(string); ok {
+			chunk.Text = text
+		}
+	}
+
+	return chunk
+}
+
+// pointID parses an ID as reported in chunk IDs: numeric IDs are
+// formatted as decimal, anything else is a UUID.
+func pointID(id string) *pb.PointId {
+	if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+		return &pb.PointId{PointIdOptions: &pb.PointId_Num{Num: n}}
+	}
+	return &pb.PointId{PointIdOptions: &pb.PointId_Uuid{Uuid: id}}
+}
+
 // Close releases resources.
 func (c *Client) Close() error {
+	if c.stopMonitor != nil {
+		c.stopMonitor()
+	}
 	if c.conn != nil {
 		return c.conn.Close()
 	}
 	return nil
 }
 
-// buildFilter converts a map to Qdrant filter.
-func buildFilter(filter map[string]interface{}) *pb.Filter {
-	if len(filter) == 0 {
+// buildFilter converts equality criteria to a Qdrant filter: points must
+// match all of filter and none of exclude.
+func buildFilter(filter, exclude map[string]interface{}) *pb.Filter {
+	must := conditions(filter)
+	mustNot := conditions(exclude)
+	if len(must) == 0 && len(mustNot) == 0 {
 		return nil
 	}
+	return &pb.Filter{
+		Must:    must,
+		MustNot: mustNot,
+	}
+}
 
+// conditions converts equality criteria to Qdrant match conditions.
+// Values of other types are skipped.
+func conditions(filter map[string]interface{}) []*pb.Condition {
 	conditions := make([]*pb.Condition, 0, len(filter))
 
 	for key, value := range filter {
@@ -310,14 +521,7 @@ func buildFilter(filter map[string]interface{}) *pb.Filter {
 			conditions = append(conditions, condition)
 		}
 	}
-
-	if len(conditions) == 0 {
-		return nil
-	}
-
-	return &pb.Filter{
-		Must: conditions,
-	}
+	return conditions
 }
 
 // convertPayloadToMap converts Qdrant payload to a Go map.
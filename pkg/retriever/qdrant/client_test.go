@@ -0,0 +1,340 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	pb "github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	_ retriever.Retriever          = (*Client)(nil)
+	_ retriever.ConnectionReporter = (*Client)(nil)
+	_ retriever.Upserter           = (*Client)(nil)
+)
+
+// pagedPoints serves Search from a ranked list of n points, honoring the
+// score threshold, and records each call's limit and offset.
+type pagedPoints struct {
+	pb.PointsClient
+	n         int
+	calls     [][2]uint64
+	threshold *float32
+}
+
+func (p *pagedPoints) Search(ctx context.Context, in *pb.SearchPoints, opts ...grpc.CallOption) (*pb.SearchResponse, error) {
+	p.calls = append(p.calls, [2]uint64{in.GetLimit(), in.GetOffset()})
+	p.threshold = in.ScoreThreshold
+	resp := &pb.SearchResponse{}
+	for i := int(in.GetOffset()); i < p.n && i < int(in.GetOffset()+in.GetLimit()); i++ {
+		score := 1 - float32(i)/float32(p.n)
+		if score < in.GetScoreThreshold() {
+			break
+		}
+		resp.Result = append(resp.Result, &pb.ScoredPoint{
+			Id:    pb.NewIDNum(uint64(i)),
+			Score: score,
+		})
+	}
+	return resp, nil
+}
+
+func TestQuery_Paging(t *testing.T) {
+	tests := []struct {
+		name      string
+		corpus    int
+		topK      int
+		wantCalls int
+		wantLen   int
+	}{
+		{"single page", 1000, 100, 1, 100},
+		{"exact pages", 1000, 512, 2, 512},
+		{"partial last page", 1000, 600, 3, 600},
+		{"short corpus stops early", 300, 1000, 2, 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := &pagedPoints{n: tt.corpus}
+			c := &Client{cfg: Config{PageSize: DefaultPageSize}, points: points}
+
+			res, err := c.Query(context.Background(), &types.RetrievalRequest{
+				QueryEmbedding: []float32{1},
+				TopK:           tt.topK,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(points.calls) != tt.wantCalls {
+				t.Errorf("expected %d search calls, got %d: %v", tt.wantCalls, len(points.calls), points.calls)
+			}
+			if len(res.Chunks) != tt.wantLen {
+				t.Fatalf("expected %d chunks, got %d", tt.wantLen, len(res.Chunks))
+			}
+			for i, ch := range res.Chunks {
+				if ch.ID != fmt.Sprint(i) {
+					t.Fatalf("chunk %d: expected ID %d, got %s", i, i, ch.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestQuery_ScoreThreshold(t *testing.T) {
+	points := &pagedPoints{n: 1000}
+	c := &Client{cfg: Config{PageSize: DefaultPageSize}, points: points}
+
+	res, err := c.Query(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1},
+		TopK:           1000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points.threshold != nil {
+		t.Errorf("expected no threshold without MinScore, got %v", *points.threshold)
+	}
+	if len(res.Chunks) != 1000 {
+		t.Fatalf("expected 1000 chunks, got %d", len(res.Chunks))
+	}
+
+	points.calls = nil
+	res, err = c.Query(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding: []float32{1},
+		TopK:           1000,
+		MinScore:       0.9,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points.threshold == nil || *points.threshold != 0.9 {
+		t.Fatalf("expected threshold 0.9 sent to the server, got %v", points.threshold)
+	}
+	if len(res.Chunks) != 101 || len(points.calls) != 1 {
+		t.Errorf("expected 101 chunks from one page, got %d from %d", len(res.Chunks), len(points.calls))
+	}
+}
+
+// flakyPoints fails the first failures Search calls with Unavailable.
+type flakyPoints struct {
+	pagedPoints
+	failures int
+}
+
+func (p *flakyPoints) Search(ctx context.Context, in *pb.SearchPoints, opts ...grpc.CallOption) (*pb.SearchResponse, error) {
+	if p.failures > 0 {
+		p.failures--
+		return nil, status.Error(codes.Unavailable, "connection reset")
+	}
+	return p.pagedPoints.Search(ctx, in, opts...)
+}
+
+func TestQuery_Retries(t *testing.T) {
+	cfg := Config{PageSize: DefaultPageSize}
+	cfg.MaxRetries = 3
+	cfg.InitialBackoff = time.Millisecond
+
+	points := &flakyPoints{pagedPoints: pagedPoints{n: 10}, failures: 2}
+	c := &Client{cfg: cfg, points: points}
+	res, err := c.Query(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1}, TopK: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Retries != 2 || len(res.Chunks) != 5 {
+		t.Errorf("expected 5 chunks after 2 retries, got %d after %d", len(res.Chunks), res.Retries)
+	}
+
+	points.failures = 10
+	_, err = c.Query(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1}, TopK: 5})
+	if !errors.Is(err, retriever.ErrConnectionFailed) || !errors.Is(err, errs.ErrBackend) {
+		t.Errorf("expected ErrConnectionFailed after retries run out, got %v", err)
+	}
+}
+
+// upsertPoints records Upsert calls, rejecting points without a numeric
+// ID as Qdrant rejects malformed UUIDs.
+type upsertPoints struct {
+	pb.PointsClient
+	calls []*pb.UpsertPoints
+}
+
+func (p *upsertPoints) Upsert(ctx context.Context, in *pb.UpsertPoints, opts ...grpc.CallOption) (*pb.PointsOperationResponse, error) {
+	for _, pt := range in.Points {
+		if pt.Id.GetNum() == 0 {
+			return nil, status.Error(codes.InvalidArgument, "unable to parse UUID")
+		}
+	}
+	p.calls = append(p.calls, in)
+	return &pb.PointsOperationResponse{}, nil
+}
+
+func TestUpsert(t *testing.T) {
+	points := &upsertPoints{}
+	c := &Client{cfg: Config{}, points: points, collection: "docs"}
+
+	vectors := make([]types.Vector, retriever.UpsertBatchSize+1)
+	for i := range vectors {
+		vectors[i] = types.Vector{
+			ID:       fmt.Sprint(i + 1),
+			Values:   []float32{1, float32(i)},
+			Metadata: map[string]interface{}{"text": fmt.Sprint("chunk ", i)},
+		}
+	}
+	if err := c.Upsert(context.Background(), vectors, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(points.calls) != 2 || len(points.calls[0].Points) != retriever.UpsertBatchSize {
+		t.Fatalf("expected 2 batches, got %d", len(points.calls))
+	}
+	first := points.calls[0]
+	if first.CollectionName != "docs" || !first.GetWait() {
+		t.Errorf("upsert to %q, wait %v; want docs, true", first.CollectionName, first.GetWait())
+	}
+	if got := first.Points[0].Payload["text"].GetStringValue(); got != "chunk 0" {
+		t.Errorf("payload text = %q, want %q", got, "chunk 0")
+	}
+
+	err := c.Upsert(context.Background(), []types.Vector{{ID: "not-a-uuid", Values: []float32{1}}}, "")
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("rejected ID: got %v, want ErrConfig", err)
+	}
+}
+
+func TestPointID(t *testing.T) {
+	if pointID("42").GetNum() != 42 {
+		t.Error("expected numeric ID")
+	}
+	if id := "5c56c793-69f3-4fbf-87e6-c4bf54c28c26"; pointID(id).GetUuid() != id {
+		t.Error("expected UUID")
+	}
+}
+
+func TestBuildFilter(t *testing.T) {
+	if buildFilter(nil, nil) != nil {
+		t.Error("expected no filter without criteria")
+	}
+	f := buildFilter(map[string]interface{}{"lang": "go"}, map[string]interface{}{"distill_duplicate": true})
+	if len(f.GetMust()) != 1 || f.GetMust()[0].GetField().GetKey() != "lang" {
+		t.Errorf("expected lang in must, got %v", f.GetMust())
+	}
+	if len(f.GetMustNot()) != 1 || !f.GetMustNot()[0].GetField().GetMatch().GetBoolean() {
+		t.Errorf("expected tombstone in must_not, got %v", f.GetMustNot())
+	}
+}
+
+func TestPayloadSelector(t *testing.T) {
+	if sel := payloadSelector(false, retriever.MetadataFields{Include: []string{"title"}}); sel.GetEnable() {
+		t.Error("expected no payload without IncludeMetadata")
+	}
+	if sel := payloadSelector(true, retriever.MetadataFields{}); !sel.GetEnable() {
+		t.Error("expected the full payload without field lists")
+	}
+
+	include := payloadSelector(true, retriever.MetadataFields{Include: []string{"title"}}).GetInclude().GetFields()
+	if len(include) != 1+len(retriever.TextFields) || include[0] != "title" {
+		t.Errorf("expected title plus the text fields, got %v", include)
+	}
+
+	exclude := payloadSelector(true, retriever.MetadataFields{Exclude: []string{"body_html", "text"}}).GetExclude().GetFields()
+	if len(exclude) != 1 || exclude[0] != "body_html" {
+		t.Errorf("expected text fields never excluded, got %v", exclude)
+	}
+}
+
+func TestQuery_MetadataFields(t *testing.T) {
+	points := &payloadPoints{payload: map[string]*pb.Value{
+		"text":      pb.NewValueString("hello"),
+		"title":     pb.NewValueString("greeting"),
+		"body_html": pb.NewValueString("<p>hello</p>"),
+	}}
+	c := &Client{cfg: Config{PageSize: DefaultPageSize}, points: points}
+	c.cfg.MetadataFields = retriever.MetadataFields{Include: []string{"title"}}
+
+	res, err := c.Query(context.Background(), &types.RetrievalRequest{
+		QueryEmbedding:  []float32{1},
+		TopK:            1,
+		IncludeMetadata: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := res.Chunks[0]
+	if ch.Text != "hello" || len(ch.Metadata) != 1 || ch.Metadata["title"] != "greeting" {
+		t.Errorf("expected text read and only title kept, got %q %v", ch.Text, ch.Metadata)
+	}
+}
+
+// payloadPoints answers Search with one point carrying the selected
+// fields of payload.
+type payloadPoints struct {
+	pb.PointsClient
+	payload map[string]*pb.Value
+}
+
+func (p *payloadPoints) Search(ctx context.Context, in *pb.SearchPoints, opts ...grpc.CallOption) (*pb.SearchResponse, error) {
+	selected := make(map[string]*pb.Value)
+	for k, v := range p.payload {
+		if fields := in.GetWithPayload().GetInclude().GetFields(); fields == nil || slices.Contains(fields, k) {
+			selected[k] = v
+		}
+	}
+	return &pb.SearchResponse{Result: []*pb.ScoredPoint{{Id: pb.NewIDNum(1), Score: 1, Payload: selected}}}, nil
+}
+
+// waitReady polls c until its readiness is want.
+func waitReady(t *testing.T, c *Client, want bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, ready := c.ConnectionState(); ready == want {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	state, _ := c.ConnectionState()
+	t.Fatalf("connection still %s, want ready=%v", state, want)
+}
+
+func TestConnectionState_Reconnects(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+	srv := grpc.NewServer()
+	go func() { _ = srv.Serve(ln) }()
+
+	cfg := Config{Collection: "docs", GRPCPort: addr.Port}
+	cfg.Host = "127.0.0.1"
+	c, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = c.Close() }()
+
+	// The monitor dials without waiting for a query
+	waitReady(t, c, true)
+
+	srv.Stop()
+	waitReady(t, c, false)
+
+	ln, err = net.Listen("tcp", addr.String())
+	if err != nil {
+		t.Skipf("port %d not reusable: %v", addr.Port, err)
+	}
+	srv = grpc.NewServer()
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Stop()
+	waitReady(t, c, true)
+}
@@ -0,0 +1,109 @@
+package qdrant
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/retriever"
+	pb "github.com/qdrant/go-client/qdrant"
+)
+
+// Params are Qdrant search tuning knobs, set under retriever.params.qdrant.
+// Zero values leave Qdrant's defaults in place.
+type Params struct {
+	// HnswEF is the HNSW beam size. Larger is more accurate and slower.
+	HnswEF uint64 `mapstructure:"hnsw_ef"`
+
+	// Exact disables approximation and scans every vector.
+	Exact bool `mapstructure:"exact"`
+
+	// IndexedOnly skips segments that are not indexed yet, trading
+	// recall of fresh points for predictable latency.
+	IndexedOnly bool `mapstructure:"indexed_only"`
+
+	// Quantization tunes search on quantized collections.
+	Quantization QuantizationParams `mapstructure:"quantization"`
+
+	// Consistency is the read consistency across replicas: "all",
+	// "majority", "quorum", or a number of replicas. Empty reads from
+	// one replica.
+	Consistency string `mapstructure:"consistency"`
+}
+
+// QuantizationParams tune search on quantized collections.
+type QuantizationParams struct {
+	// Ignore searches the original vectors instead of quantized ones.
+	Ignore bool `mapstructure:"ignore"`
+
+	// Rescore re-scores candidates with the original vectors. Unset lets
+	// Qdrant decide.
+	Rescore *bool `mapstructure:"rescore"`
+
+	// Oversampling pre-selects this many times top-k candidates before
+	// rescoring. Must be at least 1 when set.
+	Oversampling float64 `mapstructure:"oversampling"`
+}
+
+// ParseParams decodes and validates the retriever.params.qdrant section.
+func ParseParams(raw map[string]interface{}) (Params, error) {
+	var p Params
+	if err := retriever.DecodeParams(raw, &p); err != nil {
+		return Params{}, err
+	}
+	if p.Quantization.Oversampling != 0 && p.Quantization.Oversampling < 1 {
+		return Params{}, errs.Wrap(errs.ErrConfig, fmt.Errorf("quantization.oversampling must be at least 1, got %g", p.Quantization.Oversampling))
+	}
+	if _, err := p.readConsistency(); err != nil {
+		return Params{}, err
+	}
+	return p, nil
+}
+
+// searchParams returns the SearchPoints params, or nil when nothing is
+// tuned.
+func (p Params) searchParams() *pb.SearchParams {
+	var sp pb.SearchParams
+	tuned := false
+	if p.HnswEF > 0 {
+		sp.HnswEf, tuned = &p.HnswEF, true
+	}
+	if p.Exact {
+		sp.Exact, tuned = &p.Exact, true
+	}
+	if p.IndexedOnly {
+		sp.IndexedOnly, tuned = &p.IndexedOnly, true
+	}
+	if q := p.Quantization; q.Ignore || q.Rescore != nil || q.Oversampling > 0 {
+		sp.Quantization, tuned = &pb.QuantizationSearchParams{Rescore: q.Rescore}, true
+		if q.Ignore {
+			sp.Quantization.Ignore = &q.Ignore
+		}
+		if q.Oversampling > 0 {
+			sp.Quantization.Oversampling = &q.Oversampling
+		}
+	}
+	if !tuned {
+		return nil
+	}
+	return &sp
+}
+
+// readConsistency parses Consistency, returning nil when it is empty.
+func (p Params) readConsistency() (*pb.ReadConsistency, error) {
+	switch p.Consistency {
+	case "":
+		return nil, nil
+	case "all":
+		return pb.NewReadConsistencyType(pb.ReadConsistencyType_All), nil
+	case "majority":
+		return pb.NewReadConsistencyType(pb.ReadConsistencyType_Majority), nil
+	case "quorum":
+		return pb.NewReadConsistencyType(pb.ReadConsistencyType_Quorum), nil
+	}
+	n, err := strconv.ParseUint(p.Consistency, 10, 64)
+	if err != nil || n == 0 {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("consistency must be all, majority, quorum, or a replica count, got %q", p.Consistency))
+	}
+	return pb.NewReadConsistencyFactor(n), nil
+}
@@ -0,0 +1,96 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	pb "github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc"
+)
+
+func TestParseParams(t *testing.T) {
+	p, err := ParseParams(map[string]interface{}{
+		"hnsw_ef":     "128",
+		"exact":       false,
+		"consistency": 2,
+		"quantization": map[string]interface{}{
+			"rescore":      true,
+			"oversampling": 2.5,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := p.searchParams()
+	if sp.GetHnswEf() != 128 || sp.Exact != nil {
+		t.Errorf("unexpected search params %v", sp)
+	}
+	if q := sp.GetQuantization(); !q.GetRescore() || q.GetOversampling() != 2.5 || q.Ignore != nil {
+		t.Errorf("unexpected quantization params %v", q)
+	}
+	rc, _ := p.readConsistency()
+	if rc.GetFactor() != 2 {
+		t.Errorf("expected consistency factor 2, got %v", rc)
+	}
+
+	for name, raw := range map[string]map[string]interface{}{
+		"unknown key":      {"hnsw": 64},
+		"bad consistency":  {"consistency": "strong"},
+		"zero replicas":    {"consistency": "0"},
+		"low oversampling": {"quantization": map[string]interface{}{"oversampling": 0.5}},
+		"wrong type":       {"exact": "sometimes"},
+		"nested unknown":   {"quantization": map[string]interface{}{"bits": 8}},
+	} {
+		if _, err := ParseParams(raw); !errors.Is(err, errs.ErrConfig) {
+			t.Errorf("%s: expected config error, got %v", name, err)
+		}
+	}
+}
+
+func TestParseParams_Defaults(t *testing.T) {
+	p, err := ParseParams(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.searchParams() != nil {
+		t.Error("expected no search params when nothing is tuned")
+	}
+	if rc, _ := p.readConsistency(); rc != nil {
+		t.Error("expected default read consistency")
+	}
+	rc, _ := Params{Consistency: "majority"}.readConsistency()
+	if rc.GetType() != pb.ReadConsistencyType_Majority {
+		t.Errorf("expected majority, got %v", rc)
+	}
+}
+
+// searchCapture records the last search request.
+type searchCapture struct {
+	pb.PointsClient
+	last *pb.SearchPoints
+}
+
+func (s *searchCapture) Search(ctx context.Context, in *pb.SearchPoints, opts ...grpc.CallOption) (*pb.SearchResponse, error) {
+	s.last = in
+	return &pb.SearchResponse{}, nil
+}
+
+func TestQuery_Params(t *testing.T) {
+	p := Params{HnswEF: 256, IndexedOnly: true, Consistency: "quorum"}
+	rc, _ := p.readConsistency()
+	points := &searchCapture{}
+	c := &Client{cfg: Config{PageSize: DefaultPageSize}, points: points, search: p.searchParams(), consistency: rc}
+
+	if _, err := c.Query(context.Background(), &types.RetrievalRequest{QueryEmbedding: []float32{1}, TopK: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if points.last.GetParams().GetHnswEf() != 256 || !points.last.GetParams().GetIndexedOnly() {
+		t.Errorf("expected tuned search params, got %v", points.last.GetParams())
+	}
+	if points.last.GetReadConsistency().GetType() != pb.ReadConsistencyType_Quorum {
+		t.Errorf("expected quorum reads, got %v", points.last.GetReadConsistency())
+	}
+}
@@ -0,0 +1,106 @@
+package retriever
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Backoff defaults for Config.InitialBackoff and Config.MaxBackoff.
+const (
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 2 * time.Second
+)
+
+// WithTimeout bounds ctx by c.TimeoutSeconds, which covers a whole query
+// including its retries and pages. Zero or less leaves ctx unbounded.
+func (c Config) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.TimeoutSeconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(c.TimeoutSeconds)*time.Second)
+}
+
+// Retry runs op, retrying up to c.MaxRetries times while it fails with a
+// transient gRPC status (Unavailable or ResourceExhausted). Waits start at
+// c.InitialBackoff and double up to c.MaxBackoff, each drawn uniformly
+// below the current backoff so concurrent callers spread out. It returns
+// the number of retries made and op's last error, tagged ErrTimeout,
+// ErrRateLimited or ErrConnectionFailed where one applies.
+func (c Config) Retry(ctx context.Context, op func(ctx context.Context) error) (int, error) {
+	backoff := c.InitialBackoff
+	if backoff <= 0 {
+		backoff = DefaultInitialBackoff
+	}
+	maxBackoff := c.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	retries := 0
+	for {
+		err := op(ctx)
+		if err == nil {
+			return retries, nil
+		}
+		if timedOut(ctx, err) {
+			return retries, errs.Wrap(ErrTimeout, err)
+		}
+		if ctx.Err() != nil || !IsTransient(err) {
+			return retries, err
+		}
+		if retries >= c.MaxRetries {
+			return retries, classifyTransient(err)
+		}
+
+		timer := time.NewTimer(jitter(backoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if timedOut(ctx, nil) {
+				return retries, errs.Wrap(ErrTimeout, err)
+			}
+			return retries, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, maxBackoff)
+		retries++
+	}
+}
+
+// IsTransient reports whether err is a gRPC failure worth retrying: the
+// backend is unavailable or throttling.
+func IsTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// timedOut reports whether ctx's deadline passed or err is a gRPC
+// deadline failure.
+func timedOut(ctx context.Context, err error) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded
+}
+
+// classifyTransient tags a transient error with the matching sentinel.
+func classifyTransient(err error) error {
+	if status.Code(err) == codes.ResourceExhausted {
+		return errs.Wrap(ErrRateLimited, err)
+	}
+	return errs.Wrap(ErrConnectionFailed, err)
+}
+
+// jitter returns a random duration in [0, d).
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
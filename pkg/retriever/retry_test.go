@@ -0,0 +1,89 @@
+package retriever
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// failing returns an op that fails with code n times, then succeeds.
+func failing(code codes.Code, n int) (func(context.Context) error, *int) {
+	calls := 0
+	return func(context.Context) error {
+		calls++
+		if calls <= n {
+			return status.Error(code, "transient")
+		}
+		return nil
+	}, &calls
+}
+
+func TestRetry(t *testing.T) {
+	cfg := Config{MaxRetries: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	t.Run("recovers", func(t *testing.T) {
+		op, calls := failing(codes.Unavailable, 2)
+		retries, err := cfg.Retry(context.Background(), op)
+		if err != nil || retries != 2 || *calls != 3 {
+			t.Errorf("got %d retries, %d calls, err %v", retries, *calls, err)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		op, calls := failing(codes.ResourceExhausted, 10)
+		retries, err := cfg.Retry(context.Background(), op)
+		if retries != 3 || *calls != 4 {
+			t.Errorf("got %d retries, %d calls", retries, *calls)
+		}
+		if !errors.Is(err, ErrRateLimited) || !errors.Is(err, errs.ErrBackend) {
+			t.Errorf("err = %v, want ErrRateLimited", err)
+		}
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		op, calls := failing(codes.InvalidArgument, 10)
+		retries, err := cfg.Retry(context.Background(), op)
+		if retries != 0 || *calls != 1 || status.Code(err) != codes.InvalidArgument {
+			t.Errorf("got %d retries, %d calls, err %v", retries, *calls, err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		op, calls := failing(codes.Unavailable, 10)
+		retries, err := Config{MaxRetries: -1}.Retry(context.Background(), op)
+		if retries != 0 || *calls != 1 || !errors.Is(err, ErrConnectionFailed) {
+			t.Errorf("got %d retries, %d calls, err %v", retries, *calls, err)
+		}
+	})
+}
+
+func TestRetry_Timeout(t *testing.T) {
+	cfg := Config{TimeoutSeconds: 1, MaxRetries: 100, InitialBackoff: time.Hour}
+	ctx, cancel := cfg.WithTimeout(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > time.Second {
+		t.Fatalf("deadline = %v, %v", deadline, ok)
+	}
+
+	// A backoff past the deadline ends the query as a timeout
+	short, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	op, _ := failing(codes.Unavailable, 100)
+	_, err := cfg.Retry(short, op)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want ErrTimeout", err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = cfg.Retry(cancelled, func(ctx context.Context) error { return ctx.Err() })
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
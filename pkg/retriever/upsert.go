@@ -0,0 +1,37 @@
+package retriever
+
+import (
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UpsertBatchSize is the most vectors an adapter writes per upsert call,
+// within Pinecone's request size limit for typical dimensions.
+const UpsertBatchSize = 100
+
+// Batches calls fn with consecutive slices of at most size vectors, in
+// order, stopping at the first error. A size of zero or less uses
+// UpsertBatchSize.
+func Batches(vectors []types.Vector, size int, fn func(batch []types.Vector) error) error {
+	if size <= 0 {
+		size = UpsertBatchSize
+	}
+	for start := 0; start < len(vectors); start += size {
+		if err := fn(vectors[start:min(start+size, len(vectors))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClassifyUpsert tags a failed upsert: vectors the backend rejected as
+// invalid, such as a malformed ID or a dimension mismatch, are ErrConfig;
+// anything else is classified as errs.ClassifyRemote does.
+func ClassifyUpsert(err error) error {
+	if status.Code(err) == codes.InvalidArgument {
+		return errs.Wrap(errs.ErrConfig, err)
+	}
+	return errs.ClassifyRemote(err)
+}
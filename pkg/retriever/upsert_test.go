@@ -0,0 +1,59 @@
+package retriever
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBatches(t *testing.T) {
+	vectors := make([]types.Vector, 7)
+	for i := range vectors {
+		vectors[i].ID = fmt.Sprint(i)
+	}
+
+	var sizes []int
+	err := Batches(vectors, 3, func(batch []types.Vector) error {
+		sizes = append(sizes, len(batch))
+		return nil
+	})
+	if err != nil || fmt.Sprint(sizes) != "[3 3 1]" {
+		t.Errorf("got batch sizes %v, err %v; want [3 3 1]", sizes, err)
+	}
+
+	boom := errors.New("boom")
+	calls := 0
+	err = Batches(vectors, 3, func([]types.Vector) error {
+		calls++
+		return boom
+	})
+	if !errors.Is(err, boom) || calls != 1 {
+		t.Errorf("got err %v after %d calls, want boom after 1", err, calls)
+	}
+
+	sizes = nil
+	_ = Batches(make([]types.Vector, UpsertBatchSize+1), 0, func(batch []types.Vector) error {
+		sizes = append(sizes, len(batch))
+		return nil
+	})
+	if len(sizes) != 2 || sizes[0] != UpsertBatchSize {
+		t.Errorf("default size: got batch sizes %v", sizes)
+	}
+}
+
+func TestClassifyUpsert(t *testing.T) {
+	if err := ClassifyUpsert(status.Error(codes.InvalidArgument, "bad id")); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("InvalidArgument: got %v, want ErrConfig", err)
+	}
+	if err := ClassifyUpsert(status.Error(codes.Internal, "oops")); !errors.Is(err, errs.ErrBackend) {
+		t.Errorf("Internal: got %v, want ErrBackend", err)
+	}
+	if err := ClassifyUpsert(ErrRateLimited); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("classified error: got %v, want it unchanged", err)
+	}
+}
@@ -0,0 +1,277 @@
+// Package safety detects prompt-injection content in retrieved text, such
+// as "ignore previous instructions" planted in crawled web pages. Detection
+// is pattern-based with heuristic scoring; no LLM calls are made.
+package safety
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// Mode selects what a Filter does with chunks scoring at or above its
+// threshold.
+type Mode string
+
+const (
+	// ModeOff disables the filter.
+	ModeOff Mode = "off"
+	// ModeFlag annotates flagged chunks and leaves their text alone.
+	ModeFlag Mode = "flag"
+	// ModeStrip removes the sentences holding injection spans from
+	// flagged chunks and annotates them.
+	ModeStrip Mode = "strip"
+	// ModeBlock drops flagged chunks.
+	ModeBlock Mode = "block"
+)
+
+// DefaultThreshold is the score at or above which a chunk is flagged. No
+// combination of heuristic signals reaches it without a pattern match.
+const DefaultThreshold = 0.5
+
+// Metadata keys set on flagged chunks.
+const (
+	// ScoreKey is the chunk's injection score, 0–1.
+	ScoreKey = "injection_score"
+
+	// PatternsKey lists the names of the patterns that matched.
+	PatternsKey = "injection_patterns"
+)
+
+// ParseMode parses a mode name; empty means ModeOff.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return ModeOff, nil
+	case ModeOff, ModeFlag, ModeStrip, ModeBlock:
+		return m, nil
+	}
+	return "", errs.Wrap(errs.ErrConfig, fmt.Errorf("unknown injection filter mode %q (use off, flag, strip, or block)", s))
+}
+
+// signal is a pattern contributing weight to a chunk's score. Patterns
+// mark text that is an injection attempt on its own and are stripped;
+// heuristics only raise the score of text that also matches a pattern.
+type signal struct {
+	name      string
+	re        *regexp.Regexp
+	weight    float64
+	heuristic bool
+}
+
+var signals = []signal{
+	{name: "ignore_instructions", weight: 0.9, re: regexp.MustCompile(
+		`(?i)\b(ignore|disregard|forget|override|bypass)\b[^.!?\n]{0,40}?\b(previous|prior|preceding|above|earlier|all|any|your|the)\b[^.!?\n]{0,20}?\b(instructions?|prompts?|rules|directions|guidelines|context)\b`)},
+	{name: "system_prompt_leak", weight: 0.8, re: regexp.MustCompile(
+		`(?i)\b(reveal|print|show|repeat|output|disclose|leak)\b[^.!?\n]{0,30}?\b(system prompt|hidden instructions|initial instructions|original instructions|developer message)\b`)},
+	{name: "exfiltration", weight: 0.7, re: regexp.MustCompile(
+		`(?i)\b(send|post|upload|forward|exfiltrate|email)\b[^.!?\n]{0,40}?\b(api keys?|credentials|passwords?|secrets?|tokens?|conversation|chat history)\b[^.!?\n]{0,30}?\b(to|at)\b`)},
+	{name: "fake_role_marker", weight: 0.7, re: regexp.MustCompile(
+		`(?im)(^[ \t]*(system|assistant)[ \t]*:|<\|?(system|im_start|im_end|endoftext)\|?>|\[/?(INST|SYS|SYSTEM)\])`)},
+	{name: "new_instructions", weight: 0.6, re: regexp.MustCompile(
+		`(?i)\b(new|updated|real|actual|revised)\s+(instructions?|system prompt|directives?)\s*:`)},
+	{name: "role_override", weight: 0.6, re: regexp.MustCompile(
+		`(?i)(\byou are now\b|\bfrom now on,? you\b|\bact as (an? )?(unrestricted|unfiltered|jailbroken)\b|\bpretend (to be|you are)\b)`)},
+	{name: "jailbreak", weight: 0.6, re: regexp.MustCompile(
+		`(?i)\b(jailbreak|developer mode|do anything now|DAN mode)\b`)},
+
+	{name: "addresses_model", weight: 0.25, heuristic: true, re: regexp.MustCompile(
+		`(?i)\b(ai assistant|language model|llm|chatbot|dear (ai|assistant|model))\b`)},
+	{name: "hidden_text", weight: 0.25, heuristic: true, re: regexp.MustCompile(
+		`[\x{200B}-\x{200F}\x{2060}-\x{2064}\x{FEFF}]`)},
+	{name: "urgency", weight: 0.1, heuristic: true, re: regexp.MustCompile(
+		`(?i)\b(important|urgent|immediately|you must)\b[^.!?\n]*!`)},
+}
+
+// Span is a match of one signal in a text.
+type Span struct {
+	Start, End int
+	Pattern    string
+}
+
+// Detection is the result of scanning a text.
+type Detection struct {
+	// Score combines the weights of the signals that matched, each
+	// counted once, as 1 - Π(1 - weight).
+	Score float64
+
+	// Spans are the pattern matches, in order. Heuristic matches count
+	// toward Score but are not listed.
+	Spans []Span
+
+	// Patterns are the names of all signals that matched, sorted.
+	Patterns []string
+}
+
+// Detect scans text for injection signals.
+func Detect(text string) Detection {
+	var d Detection
+	miss := 1.0
+	for _, s := range signals {
+		matches := s.re.FindAllStringIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		miss *= 1 - s.weight
+		d.Patterns = append(d.Patterns, s.name)
+		if s.heuristic {
+			continue
+		}
+		for _, m := range matches {
+			d.Spans = append(d.Spans, Span{Start: m[0], End: m[1], Pattern: s.name})
+		}
+	}
+	d.Score = math.Round((1-miss)*1000) / 1000
+	sort.Strings(d.Patterns)
+	sort.Slice(d.Spans, func(i, j int) bool { return d.Spans[i].Start < d.Spans[j].Start })
+	return d
+}
+
+// Strip removes the sentences or lines holding spans from text.
+func Strip(text string, spans []Span) string {
+	if len(spans) == 0 {
+		return text
+	}
+	var out strings.Builder
+	pos := 0
+	for _, sp := range spans {
+		start, end := sentenceBounds(text, sp.Start, sp.End)
+		if end <= pos {
+			continue
+		}
+		if start > pos {
+			out.WriteString(text[pos:start])
+		}
+		pos = max(pos, end)
+	}
+	out.WriteString(text[pos:])
+	return strings.TrimSpace(collapseSpaces(out.String()))
+}
+
+// sentenceBounds widens [start, end) to the enclosing sentence or line,
+// including its trailing spaces but not its leading ones.
+func sentenceBounds(text string, start, end int) (int, int) {
+	if i := strings.LastIndexAny(text[:start], ".!?\n"); i >= 0 {
+		start = i + 1
+	} else {
+		start = 0
+	}
+	for start < end && (text[start] == ' ' || text[start] == '\t') {
+		start++
+	}
+	if i := strings.IndexAny(text[end:], ".!?\n"); i >= 0 {
+		end += i + 1
+	} else {
+		end = len(text)
+	}
+	for end < len(text) && (text[end] == ' ' || text[end] == '\t') {
+		end++
+	}
+	return start, end
+}
+
+var spaceRun = regexp.MustCompile(`[ \t]{2,}`)
+
+func collapseSpaces(s string) string {
+	return spaceRun.ReplaceAllString(s, " ")
+}
+
+// Filter applies a Mode to chunks.
+type Filter struct {
+	Mode Mode
+
+	// Threshold is the score at or above which a chunk is flagged
+	// (default DefaultThreshold).
+	Threshold float64
+}
+
+// Validate reports an unknown mode or a threshold outside [0, 1]; zero
+// uses the default.
+func (f Filter) Validate() error {
+	if _, err := ParseMode(string(f.Mode)); err != nil {
+		return err
+	}
+	if f.Threshold < 0 || f.Threshold > 1 {
+		return errs.Wrap(errs.ErrConfig, fmt.Errorf("injection threshold must be between 0 and 1, got %g", f.Threshold))
+	}
+	return nil
+}
+
+// Enabled reports whether f does anything.
+func (f Filter) Enabled() bool {
+	return f.Mode != "" && f.Mode != ModeOff
+}
+
+// Stats counts the decisions of one Apply call.
+type Stats struct {
+	// Flagged is the number of chunks at or above the threshold,
+	// including Blocked ones.
+	Flagged int
+
+	// Stripped is the number of sentences removed from flagged chunks.
+	Stripped int
+
+	// Blocked is the number of chunks dropped: flagged chunks in block
+	// mode, and chunks stripping left empty.
+	Blocked int
+}
+
+// Apply returns chunks with f's mode applied, in order. Flagged chunks are
+// cloned before their text or metadata changes, so the input is not
+// modified.
+func (f Filter) Apply(chunks []types.Chunk) ([]types.Chunk, Stats) {
+	var stats Stats
+	if !f.Enabled() {
+		return chunks, stats
+	}
+	threshold := f.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	out := make([]types.Chunk, 0, len(chunks))
+	for _, c := range chunks {
+		d := Detect(c.Text)
+		if d.Score < threshold {
+			out = append(out, c)
+			continue
+		}
+		stats.Flagged++
+		if f.Mode == ModeBlock {
+			stats.Blocked++
+			continue
+		}
+
+		flagged := c.Clone()
+		if f.Mode == ModeStrip {
+			flagged.Text = Strip(c.Text, d.Spans)
+			stats.Stripped += countSentences(c.Text, d.Spans)
+			if flagged.Text == "" {
+				stats.Blocked++
+				continue
+			}
+		}
+		flagged.Metadata[ScoreKey] = d.Score
+		flagged.Metadata[PatternsKey] = d.Patterns
+		out = append(out, *flagged)
+	}
+	return out, stats
+}
+
+// countSentences counts the distinct sentences Strip removes for spans.
+func countSentences(text string, spans []Span) int {
+	n, last := 0, -1
+	for _, sp := range spans {
+		start, end := sentenceBounds(text, sp.Start, sp.End)
+		if start >= last {
+			n++
+		}
+		last = max(last, end)
+	}
+	return n
+}
@@ -4,8 +4,12 @@
 package sensitivity
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+
+	distillmath "github.com/Siddhant-K-code/distill/pkg/math"
 )
 
 // Level represents the sensitivity classification of a piece of content.
@@ -34,6 +38,16 @@ func (l Level) String() string {
 	}
 }
 
+// ParseLevel returns the level named s, as written by String.
+func ParseLevel(s string) (Level, error) {
+	for _, l := range []Level{None, PII, InternalIP, Credentials} {
+		if s == l.String() {
+			return l, nil
+		}
+	}
+	return None, fmt.Errorf("unknown sensitivity level %q (supported: pii, internal, credentials)", s)
+}
+
 // Match describes a single pattern match found during classification.
 type Match struct {
 	Pattern string `json:"pattern"`
@@ -50,6 +64,26 @@ type Result struct {
 type Config struct {
 	// InternalDomains are domain suffixes treated as internal (e.g. ".internal", ".corp").
 	InternalDomains []string
+
+	// Examples classify chunks by meaning where no pattern applies, such
+	// as pricing sheets or roadmaps; see ClassifyChunk.
+	Examples []Example
+
+	// ExampleThreshold is the cosine similarity at which a chunk matches
+	// an example (0 = DefaultExampleThreshold).
+	ExampleThreshold float64
+}
+
+// DefaultExampleThreshold is the similarity at which a chunk matches an
+// Example when Config sets none.
+const DefaultExampleThreshold = 0.85
+
+// Example is a labeled passage for embedding-based classification. Chunks
+// whose embeddings are close to it are classified at its Level.
+type Example struct {
+	Name      string
+	Level     Level
+	Embedding []float32
 }
 
 // DefaultConfig returns a config with common internal domain patterns.
@@ -63,6 +97,9 @@ func DefaultConfig() Config {
 type Classifier struct {
 	cfg      Config
 	patterns []pattern
+
+	// hosts matches host names under InternalDomains, for Redact.
+	hosts *regexp.Regexp
 }
 
 type pattern struct {
@@ -102,6 +139,13 @@ func New(cfg Config) *Classifier {
 			level: bp.level,
 		})
 	}
+	if len(cfg.InternalDomains) > 0 {
+		suffixes := make([]string, len(cfg.InternalDomains))
+		for i, d := range cfg.InternalDomains {
+			suffixes[i] = regexp.QuoteMeta(strings.TrimPrefix(d, "."))
+		}
+		c.hosts = regexp.MustCompile(`(?i)\b[a-z0-9][a-z0-9-]*(?:\.[a-z0-9-]+)*\.(?:` + strings.Join(suffixes, "|") + `)\b`)
+	}
 	return c
 }
 
@@ -135,6 +179,28 @@ func (c *Classifier) Classify(text string) Result {
 	return Result{Level: maxLevel, Matches: matches}
 }
 
+// ClassifyChunk is Classify, also matching embedding against the
+// configured Examples. Each example at least ExampleThreshold similar
+// adds a match named "example:" plus its name.
+func (c *Classifier) ClassifyChunk(text string, embedding []float32) Result {
+	r := c.Classify(text)
+	if len(embedding) == 0 {
+		return r
+	}
+	threshold := c.cfg.ExampleThreshold
+	if threshold <= 0 {
+		threshold = DefaultExampleThreshold
+	}
+	for _, e := range c.cfg.Examples {
+		if len(e.Embedding) != len(embedding) || distillmath.CosineSimilarity(embedding, e.Embedding) < threshold {
+			continue
+		}
+		r.Matches = append(r.Matches, Match{Pattern: "example:" + e.Name, Level: e.Level})
+		r.Level = max(r.Level, e.Level)
+	}
+	return r
+}
+
 // ClassifyBatch classifies multiple texts and returns the highest level
 // across all of them, along with per-text results for any that matched.
 func (c *Classifier) ClassifyBatch(texts []string) (Level, []Result) {
@@ -148,3 +214,132 @@ func (c *Classifier) ClassifyBatch(texts []string) (Level, []Result) {
 	}
 	return maxLevel, results
 }
+
+// DefaultRedaction replaces redacted text when Redact is given none.
+const DefaultRedaction = "[REDACTED]"
+
+// Redact replaces the text matched by patterns at any of levels with
+// replacement, or DefaultRedaction if it is empty, and returns the result
+// and the number of ranges replaced. InternalIP redacts host names under
+// InternalDomains. Overlapping matches are replaced once.
+func (c *Classifier) Redact(text, replacement string, levels ...Level) (string, int) {
+	text, counts := c.RedactCounts(text, replacement, levels...)
+	n := 0
+	for _, k := range counts {
+		n += k
+	}
+	return text, n
+}
+
+// RedactCounts is Redact returning the number of ranges replaced by
+// pattern name, e.g. "email_address", or nil if none were. Overlapping
+// matches count for the pattern matching first.
+func (c *Classifier) RedactCounts(text, replacement string, levels ...Level) (string, map[string]int) {
+	if replacement == "" {
+		replacement = DefaultRedaction
+	}
+	text, spans := c.redact(text, func(string) string { return replacement }, levels)
+	if spans == nil {
+		return text, nil
+	}
+	counts := make(map[string]int)
+	for _, sp := range spans {
+		counts[sp.Pattern]++
+	}
+	return text, counts
+}
+
+// Span is a range of text replaced by RedactTyped.
+type Span struct {
+	// Pattern is the pattern that matched, e.g. "email_address", and
+	// Placeholder the text that replaced the range, e.g. "[EMAIL]".
+	Pattern     string `json:"pattern"`
+	Placeholder string `json:"placeholder"`
+
+	// Start and End are the byte offsets of the range in the original
+	// text, and Offset is where Placeholder starts in the redacted text.
+	Start  int `json:"start"`
+	End    int `json:"end"`
+	Offset int `json:"offset"`
+}
+
+// placeholders are the typed replacements RedactTyped uses by pattern.
+var placeholders = map[string]string{
+	"aws_access_key":   "[API_KEY]",
+	"openai_api_key":   "[API_KEY]",
+	"github_token":     "[TOKEN]",
+	"github_token_old": "[TOKEN]",
+	"slack_token":      "[TOKEN]",
+	"generic_secret":   "[SECRET]",
+	"email_address":    "[EMAIL]",
+	"phone_number":     "[PHONE]",
+	"credit_card":      "[CARD_NUMBER]",
+	"ssn":              "[SSN]",
+	"internal_domain":  "[INTERNAL_HOST]",
+}
+
+// Placeholder returns the typed replacement for text matched by pattern,
+// such as "[EMAIL]" for "email_address", or DefaultRedaction for a
+// pattern it does not know.
+func Placeholder(pattern string) string {
+	if p, ok := placeholders[pattern]; ok {
+		return p
+	}
+	return DefaultRedaction
+}
+
+// RedactTyped is Redact replacing each range with its pattern's
+// Placeholder, so readers can tell an email from a key, and returning the
+// ranges replaced, or nil if none were. With the spans, a caller allowed
+// to see the original can restore it.
+func (c *Classifier) RedactTyped(text string, levels ...Level) (string, []Span) {
+	return c.redact(text, Placeholder, levels)
+}
+
+// redact replaces the ranges matched at levels with replace's result for
+// the pattern matching first, merging overlapping ranges.
+func (c *Classifier) redact(text string, replace func(pattern string) string, levels []Level) (string, []Span) {
+	want := make(map[Level]bool, len(levels))
+	for _, l := range levels {
+		want[l] = true
+	}
+
+	type match struct {
+		start, end int
+		pattern    string
+	}
+	var ranges []match
+	for _, p := range c.patterns {
+		if want[p.level] {
+			for _, r := range p.re.FindAllStringIndex(text, -1) {
+				ranges = append(ranges, match{r[0], r[1], p.name})
+			}
+		}
+	}
+	if want[InternalIP] && c.hosts != nil {
+		for _, r := range c.hosts.FindAllStringIndex(text, -1) {
+			ranges = append(ranges, match{r[0], r[1], "internal_domain"})
+		}
+	}
+	if len(ranges) == 0 {
+		return text, nil
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	var b strings.Builder
+	var spans []Span
+	last := 0
+	for i := 0; i < len(ranges); {
+		start, end, name := ranges[i].start, ranges[i].end, ranges[i].pattern
+		for i++; i < len(ranges) && ranges[i].start < end; i++ {
+			end = max(end, ranges[i].end)
+		}
+		b.WriteString(text[last:start])
+		replacement := replace(name)
+		spans = append(spans, Span{Pattern: name, Placeholder: replacement, Start: start, End: end, Offset: b.Len()})
+		b.WriteString(replacement)
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String(), spans
+}
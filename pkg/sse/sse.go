@@ -18,6 +18,10 @@ const (
 	StageSelection  Stage = "selection"
 	StageCompress   Stage = "compress"
 	StageMMR        Stage = "mmr"
+
+	// StageDedup reports vector deduplication progress. Stats carry a
+	// dedup.Progress snapshot.
+	StageDedup Stage = "dedup"
 )
 
 // ProgressEvent is sent during processing to report stage progress.
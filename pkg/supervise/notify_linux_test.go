@@ -0,0 +1,71 @@
+package supervise
+
+import (
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func listenNotifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+func readState(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 256)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestReadyAndStopping(t *testing.T) {
+	conn := listenNotifySocket(t)
+
+	Ready()
+	if got := readState(t, conn); got != "READY=1" {
+		t.Errorf("expected READY=1, got %q", got)
+	}
+
+	Stopping()
+	if got := readState(t, conn); got != "STOPPING=1" {
+		t.Errorf("expected STOPPING=1, got %q", got)
+	}
+}
+
+func TestNotify_NoSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := notify("READY=1"); err != nil {
+		t.Errorf("expected no-op without NOTIFY_SOCKET, got %v", err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "")
+	if watchdogInterval() != 0 {
+		t.Error("expected watchdog disabled without WATCHDOG_USEC")
+	}
+
+	t.Setenv("WATCHDOG_USEC", "2000000")
+	t.Setenv("WATCHDOG_PID", "")
+	if got := watchdogInterval(); got != 2*time.Second {
+		t.Errorf("expected 2s, got %v", got)
+	}
+
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(1))
+	if watchdogInterval() != 0 {
+		t.Error("expected watchdog disabled for another PID")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package supervise
+
+import "time"
+
+// notify is a no-op outside Linux; systemd is the only supported notifier.
+func notify(string) error { return nil }
+
+// watchdogInterval is always 0 outside Linux.
+func watchdogInterval() time.Duration { return 0 }
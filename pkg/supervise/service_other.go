@@ -0,0 +1,13 @@
+//go:build !windows
+
+package supervise
+
+import "context"
+
+// runService reports false: only Windows has a service manager to attach to.
+func runService(string, func(context.Context) error) (bool, error) {
+	return false, nil
+}
+
+// serviceReady is a no-op outside Windows.
+func serviceReady() {}
@@ -0,0 +1,78 @@
+//go:build windows
+
+package supervise
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// readyCh is closed by serviceReady to move the service to Running.
+var (
+	readyCh   = make(chan struct{})
+	readyOnce sync.Once
+)
+
+// runService runs fn under the Service Control Manager when the process
+// was started as a Windows service.
+func runService(name string, fn func(context.Context) error) (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false, nil
+	}
+
+	h := &handler{fn: fn}
+	if err := svc.Run(name, h); err != nil {
+		return true, err
+	}
+	return true, h.err
+}
+
+func serviceReady() {
+	readyOnce.Do(func() { close(readyCh) })
+}
+
+type handler struct {
+	fn  func(context.Context) error
+	err error
+}
+
+// Execute implements svc.Handler. Stop and Shutdown requests cancel the
+// context passed to fn; the service reports StopPending until fn returns.
+func (h *handler) Execute(_ []string, req <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	const accepted = svc.AcceptStop | svc.AcceptShutdown
+
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- h.fn(ctx) }()
+
+	ready := readyCh
+	for {
+		select {
+		case <-ready:
+			status <- svc.Status{State: svc.Running, Accepts: accepted}
+			ready = nil
+		case c := <-req:
+			switch c.Cmd {
+			case svc.Interrogate:
+				status <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+			}
+		case err := <-done:
+			h.err = err
+			status <- svc.Status{State: svc.Stopped}
+			if err != nil {
+				return true, 1
+			}
+			return false, 0
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package supervise integrates long-running Distill servers with native
+// process supervisors: systemd (sd_notify readiness, watchdog, stopping
+// notifications) on Linux and the Service Control Manager on Windows.
+//
+// Outside a supervisor every function is a no-op, so servers can call
+// them unconditionally.
+package supervise
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Run executes fn with a context that is cancelled when the process is
+// asked to stop: SIGINT/SIGTERM everywhere, plus Stop/Shutdown requests
+// when running as a Windows service. fn should perform its graceful
+// shutdown after the context is cancelled and then return.
+//
+// Under the Windows service manager, name is the registered service name.
+func Run(name string, fn func(ctx context.Context) error) error {
+	if handled, err := runService(name, fn); handled {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return fn(ctx)
+}
+
+// Ready reports that the server has finished starting and is accepting
+// requests. Under systemd this sends READY=1 (required for Type=notify
+// units); as a Windows service it reports the Running state.
+func Ready() {
+	_ = notify("READY=1")
+	serviceReady()
+}
+
+// Stopping reports that graceful shutdown has begun.
+func Stopping() {
+	_ = notify("STOPPING=1")
+}
+
+// Status publishes a free-form status line (shown by systemctl status).
+func Status(msg string) {
+	_ = notify("STATUS=" + msg)
+}
+
+// Watchdog sends keep-alive pings at half the interval systemd expects
+// (WatchdogSec=) until ctx is cancelled. It returns immediately if the
+// watchdog is not enabled for this process.
+func Watchdog(ctx context.Context) {
+	interval := watchdogInterval()
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = notify("WATCHDOG=1")
+			}
+		}
+	}()
+}
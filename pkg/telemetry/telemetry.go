@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Siddhant-K-code/distill/pkg/profiling"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
@@ -150,6 +151,9 @@ func (p *Provider) Tracer() trace.Tracer {
 }
 
 // --- Span helpers for pipeline stages ---
+//
+// The stage helpers also label profiling samples with their stage; see
+// profiling.SetStage.
 
 // StartRequest creates a root span for an incoming HTTP request.
 func (p *Provider) StartRequest(ctx context.Context, endpoint string) (context.Context, trace.Span) {
@@ -161,6 +165,7 @@ func (p *Provider) StartRequest(ctx context.Context, endpoint string) (context.C
 
 // StartEmbedding creates a span for the embedding generation stage.
 func (p *Provider) StartEmbedding(ctx context.Context, chunkCount int) (context.Context, trace.Span) {
+	profiling.SetStage(ctx, "embedding")
 	return p.tracer.Start(ctx, "distill.embedding",
 		trace.WithAttributes(attribute.Int("distill.embedding.chunk_count", chunkCount)),
 	)
@@ -168,6 +173,7 @@ func (p *Provider) StartEmbedding(ctx context.Context, chunkCount int) (context.
 
 // StartClustering creates a span for the clustering stage.
 func (p *Provider) StartClustering(ctx context.Context, inputCount int, threshold float64) (context.Context, trace.Span) {
+	profiling.SetStage(ctx, "clustering")
 	return p.tracer.Start(ctx, "distill.clustering",
 		trace.WithAttributes(
 			attribute.Int("distill.clustering.input_count", inputCount),
@@ -178,6 +184,7 @@ func (p *Provider) StartClustering(ctx context.Context, inputCount int, threshol
 
 // StartSelection creates a span for the representative selection stage.
 func (p *Provider) StartSelection(ctx context.Context, clusterCount int) (context.Context, trace.Span) {
+	profiling.SetStage(ctx, "selection")
 	return p.tracer.Start(ctx, "distill.selection",
 		trace.WithAttributes(attribute.Int("distill.selection.cluster_count", clusterCount)),
 	)
@@ -185,6 +192,7 @@ func (p *Provider) StartSelection(ctx context.Context, clusterCount int) (contex
 
 // StartMMR creates a span for the MMR re-ranking stage.
 func (p *Provider) StartMMR(ctx context.Context, inputCount int, lambda float64) (context.Context, trace.Span) {
+	profiling.SetStage(ctx, "mmr")
 	return p.tracer.Start(ctx, "distill.mmr",
 		trace.WithAttributes(
 			attribute.Int("distill.mmr.input_count", inputCount),
@@ -195,6 +203,7 @@ func (p *Provider) StartMMR(ctx context.Context, inputCount int, lambda float64)
 
 // StartCompress creates a span for the compression stage.
 func (p *Provider) StartCompress(ctx context.Context, chunkCount int, mode string) (context.Context, trace.Span) {
+	profiling.SetStage(ctx, "compression")
 	return p.tracer.Start(ctx, "distill.compress",
 		trace.WithAttributes(
 			attribute.Int("distill.compress.chunk_count", chunkCount),
@@ -212,6 +221,7 @@ func (p *Provider) StartCacheLookup(ctx context.Context, key string) (context.Co
 
 // StartRetrieval creates a span for vector DB retrieval.
 func (p *Provider) StartRetrieval(ctx context.Context, topK int, backend string) (context.Context, trace.Span) {
+	profiling.SetStage(ctx, "retrieval")
 	return p.tracer.Start(ctx, "distill.retrieval",
 		trace.WithAttributes(
 			attribute.Int("distill.retrieval.top_k", topK),
@@ -0,0 +1,37 @@
+package tokens
+
+import (
+	"github.com/pkoukk/tiktoken-go"
+	loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// The vocabularies are embedded in the binary, so counting works offline
+// and never downloads at request time.
+func init() {
+	tiktoken.SetBpeLoader(loader.NewOfflineLoader())
+}
+
+// bpe counts tokens with a tiktoken vocabulary.
+type bpe struct {
+	name string
+	enc  *tiktoken.Tiktoken
+}
+
+func newTiktoken(name string) (Tokenizer, error) {
+	enc, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	return &bpe{name: name, enc: enc}, nil
+}
+
+func (b *bpe) Name() string { return b.name }
+
+// Count encodes text as ordinary text: special tokens such as
+// <|endoftext|> in it count as the text they spell.
+func (b *bpe) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(b.enc.EncodeOrdinary(text))
+}
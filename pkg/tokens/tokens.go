@@ -0,0 +1,164 @@
+// Package tokens counts the tokens a model sees in a text. It wraps the
+// tiktoken BPE vocabularies (cl100k_base, o200k_base), whose counts match
+// the models that use them, and keeps a character heuristic for when an
+// estimate is enough. Other tokenizers plug in with Register.
+package tokens
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+// Built-in tokenizer names.
+const (
+	// CL100KBase is the vocabulary of GPT-4 and GPT-3.5 and OpenAI's
+	// embedding models.
+	CL100KBase = "cl100k_base"
+	// O200KBase is the vocabulary of GPT-4o and later OpenAI models.
+	O200KBase = "o200k_base"
+	// Chars estimates a token per four characters. It needs no
+	// vocabulary, but is badly off for code and non-English text.
+	Chars = "chars"
+)
+
+// DefaultName is the tokenizer Count uses until SetDefault changes it.
+const DefaultName = CL100KBase
+
+// Tokenizer counts the tokens in a text. Implementations must be safe for
+// concurrent use.
+type Tokenizer interface {
+	// Name is the name the tokenizer is registered under.
+	Name() string
+	// Count returns the number of tokens in text.
+	Count(text string) int
+}
+
+// Factory builds a tokenizer. Get calls it at most once per name.
+type Factory func() (Tokenizer, error)
+
+type entry struct {
+	factory Factory
+	once    sync.Once
+	tok     Tokenizer
+	err     error
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]*entry{}
+	def      Tokenizer
+)
+
+func init() {
+	Register(CL100KBase, func() (Tokenizer, error) { return newTiktoken(CL100KBase) })
+	Register(O200KBase, func() (Tokenizer, error) { return newTiktoken(O200KBase) })
+	Register(Chars, func() (Tokenizer, error) { return CharTokenizer{}, nil })
+}
+
+// Register makes a tokenizer available to Get under name, replacing any
+// tokenizer already registered there.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[name] = &entry{factory: f}
+	if def != nil && def.Name() == name {
+		def = nil
+	}
+}
+
+// Get returns the tokenizer registered under name, building it on first
+// use. An unknown name is an errs.ErrConfig error.
+func Get(name string) (Tokenizer, error) {
+	mu.RLock()
+	e, ok := registry[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("unknown tokenizer %q (supported: %s)", name, strings.Join(Names(), ", ")))
+	}
+	e.once.Do(func() {
+		e.tok, e.err = e.factory()
+		if e.err != nil {
+			e.err = fmt.Errorf("tokenizer %s: %w", name, e.err)
+		}
+	})
+	return e.tok, e.err
+}
+
+// Names returns the registered tokenizer names, sorted.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetDefault makes the tokenizer registered under name the one Count and
+// Default use.
+func SetDefault(name string) error {
+	t, err := Get(name)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	def = t
+	mu.Unlock()
+	return nil
+}
+
+// Default returns the default tokenizer. If it cannot be built, Default
+// falls back to Chars, so counting never fails.
+func Default() Tokenizer {
+	mu.RLock()
+	t := def
+	mu.RUnlock()
+	if t != nil {
+		return t
+	}
+	t, err := Get(DefaultName)
+	if err != nil {
+		t = CharTokenizer{}
+	}
+	mu.Lock()
+	if def == nil {
+		def = t
+	}
+	t = def
+	mu.Unlock()
+	return t
+}
+
+// Count returns the number of tokens in text under the default tokenizer.
+func Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return Default().Count(text)
+}
+
+// CharTokenizer estimates tokens from the character count.
+type CharTokenizer struct {
+	// PerToken is the characters per token. Zero means 4.
+	PerToken float64
+}
+
+// Name returns Chars.
+func (CharTokenizer) Name() string { return Chars }
+
+// Count returns the characters in text divided by PerToken, rounded up.
+func (c CharTokenizer) Count(text string) int {
+	per := c.PerToken
+	if per <= 0 {
+		per = 4
+	}
+	return int(math.Ceil(float64(utf8.RuneCountInString(text)) / per))
+}
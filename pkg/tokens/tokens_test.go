@@ -0,0 +1,126 @@
+package tokens
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+func TestGet_BPE(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{CL100KBase, "hello world", 2},
+		{CL100KBase, "", 0},
+		{CL100KBase, "<|endoftext|>", 7},
+		{O200KBase, "hello world", 2},
+	}
+	for _, tt := range tests {
+		tok, err := Get(tt.name)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", tt.name, err)
+		}
+		if tok.Name() != tt.name {
+			t.Errorf("Name = %q, want %q", tok.Name(), tt.name)
+		}
+		if got := tok.Count(tt.text); got != tt.want {
+			t.Errorf("%s: Count(%q) = %d, want %d", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestGet_CodeAndNonEnglish(t *testing.T) {
+	// The four-characters heuristic undercounts both.
+	tok, err := Get(CL100KBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{
+		"if (x != nil) { return fmt.Errorf(\"%w\", err) }",
+		"東京都は日本の首都です。",
+	} {
+		if got, est := tok.Count(text), (CharTokenizer{}).Count(text); got <= est {
+			t.Errorf("Count(%q) = %d, want more than the estimate %d", text, got, est)
+		}
+	}
+}
+
+func TestGet_Unknown(t *testing.T) {
+	if _, err := Get("bpe"); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("Get(bpe) error = %v, want ErrConfig", err)
+	}
+	if err := SetDefault("bpe"); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("SetDefault(bpe) error = %v, want ErrConfig", err)
+	}
+}
+
+type fixed struct{ n int }
+
+func (fixed) Name() string       { return "fixed" }
+func (f fixed) Count(string) int { return f.n }
+
+func TestRegister_AndSetDefault(t *testing.T) {
+	calls := 0
+	Register("fixed", func() (Tokenizer, error) {
+		calls++
+		return fixed{n: 7}, nil
+	})
+	t.Cleanup(func() {
+		mu.Lock()
+		delete(registry, "fixed")
+		def = nil
+		mu.Unlock()
+	})
+
+	if err := SetDefault("fixed"); err != nil {
+		t.Fatalf("SetDefault: %v", err)
+	}
+	if got := Count("anything"); got != 7 {
+		t.Errorf("Count = %d, want 7", got)
+	}
+	if got := Count(""); got != 0 {
+		t.Errorf("Count(\"\") = %d, want 0", got)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = Get("fixed")
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("factory called %d times, want 1", calls)
+	}
+	found := false
+	for _, name := range Names() {
+		found = found || name == "fixed"
+	}
+	if !found {
+		t.Errorf("Names() = %v, missing fixed", Names())
+	}
+}
+
+func TestDefault(t *testing.T) {
+	if got := Default().Name(); got != DefaultName {
+		t.Errorf("Default = %s, want %s", got, DefaultName)
+	}
+}
+
+func TestCharTokenizer(t *testing.T) {
+	if got := (CharTokenizer{}).Count(strings.Repeat("x", 38)); got != 10 {
+		t.Errorf("Count = %d, want 10", got)
+	}
+	if got := (CharTokenizer{PerToken: 3.5}).Count(strings.Repeat("x", 38)); got != 11 {
+		t.Errorf("Count = %d, want 11", got)
+	}
+	if got := (CharTokenizer{}).Count("héllo wörld"); got != 3 {
+		t.Errorf("Count counts bytes: got %d, want 3", got)
+	}
+}
@@ -0,0 +1,309 @@
+// Package transcript compacts agent conversation exports: JSONL files with
+// one message per line. Repeated messages are replaced with a reference to
+// their first occurrence, structured tool outputs are replaced with compact
+// placeholders, and prose is pruned of filler. Everything else in a line is
+// written back unchanged.
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Siddhant-K-code/distill/pkg/compress"
+	"github.com/Siddhant-K-code/distill/pkg/types"
+)
+
+// maxLineSize is the longest transcript line Parse accepts.
+const maxLineSize = 64 << 20
+
+// Kind says what a segment of a message holds.
+type Kind int
+
+const (
+	// KindText is prose written by a user or the model.
+	KindText Kind = iota
+	// KindToolOutput is the result of a tool call.
+	KindToolOutput
+)
+
+// Segment is one piece of text in a message: a string content field, a
+// text block, or a tool result.
+type Segment struct {
+	Kind Kind
+	Text string
+
+	set func(string)
+}
+
+// Message is one line of a transcript.
+type Message struct {
+	// Line is the 1-based line number in the input.
+	Line int
+
+	// Role is the message role, or "" if the line has none.
+	Role string
+
+	// Segments are the message's text fields in order.
+	Segments []*Segment
+
+	raw map[string]interface{}
+}
+
+// Parse reads a JSONL transcript. Each non-blank line must be a JSON
+// object. Messages are read from "role" and "content", or from a nested
+// "message" object holding them. Content may be a string or a list of
+// blocks; "text" blocks are prose, and "tool_result" and
+// "function_call_output" blocks, as well as messages with the "tool" or
+// "function" role, are tool output. Lines with neither are kept as they are.
+func Parse(r io.Reader) ([]*Message, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var msgs []*Message
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var raw map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		m := &Message{Line: line, raw: raw}
+		body := raw
+		if inner, ok := raw["message"].(map[string]interface{}); ok {
+			body = inner
+		}
+		m.Role, _ = body["role"].(string)
+		if typ, _ := body["type"].(string); typ == "function_call_output" {
+			m.addContent(body, "output", true)
+		} else {
+			m.addContent(body, "content", m.Role == "tool" || m.Role == "function")
+		}
+		msgs = append(msgs, m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", line+1, err)
+	}
+	return msgs, nil
+}
+
+// addContent records the text under parent[key] as segments.
+func (m *Message) addContent(parent map[string]interface{}, key string, tool bool) {
+	kind := KindText
+	if tool {
+		kind = KindToolOutput
+	}
+
+	switch v := parent[key].(type) {
+	case string:
+		m.Segments = append(m.Segments, &Segment{
+			Kind: kind,
+			Text: v,
+			set:  func(s string) { parent[key] = s },
+		})
+	case []interface{}:
+		for i, item := range v {
+			switch b := item.(type) {
+			case string:
+				m.Segments = append(m.Segments, &Segment{
+					Kind: kind,
+					Text: b,
+					set:  func(s string) { v[i] = s },
+				})
+			case map[string]interface{}:
+				switch typ, _ := b["type"].(string); typ {
+				case "tool_result":
+					m.addContent(b, "content", true)
+				case "function_call_output":
+					m.addContent(b, "output", true)
+				default:
+					if _, ok := b["text"].(string); ok {
+						m.addContent(b, "text", tool)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Write writes messages as JSONL, one line per message.
+func Write(w io.Writer, msgs []*Message) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, m := range msgs {
+		if err := enc.Encode(m.raw); err != nil {
+			return fmt.Errorf("line %d: %w", m.Line, err)
+		}
+	}
+	return nil
+}
+
+// Options configures compaction.
+type Options struct {
+	// Dedup replaces segments that repeat an earlier one.
+	Dedup bool
+
+	// CompressToolOutputs replaces structured tool outputs (JSON, XML,
+	// tables) with compact placeholders.
+	CompressToolOutputs bool
+
+	// Prune removes filler phrases and extra whitespace from prose.
+	// Prose containing code fences is left alone so code keeps its layout.
+	Prune bool
+
+	// MinDedupLength is the shortest segment, in characters, replaced as a
+	// duplicate. Short replies like "ok" are never replaced.
+	MinDedupLength int
+
+	// MinToolOutputLength is the shortest tool output, in characters,
+	// that is compressed.
+	MinToolOutputLength int
+}
+
+// DefaultOptions returns options with every stage enabled.
+func DefaultOptions() Options {
+	return Options{
+		Dedup:               true,
+		CompressToolOutputs: true,
+		Prune:               true,
+		MinDedupLength:      64,
+		MinToolOutputLength: 200,
+	}
+}
+
+// Report describes what compaction saved.
+type Report struct {
+	Messages     int
+	Segments     int
+	InputTokens  int
+	OutputTokens int
+
+	Deduplicated     int
+	DedupTokensSaved int
+
+	ToolOutputsCompressed int
+	ToolTokensSaved       int
+
+	Pruned           int
+	PruneTokensSaved int
+}
+
+// ReductionPercent is the percentage of tokens removed.
+func (r Report) ReductionPercent() float64 {
+	if r.InputTokens == 0 {
+		return 0
+	}
+	return float64(r.InputTokens-r.OutputTokens) / float64(r.InputTokens) * 100
+}
+
+// Compact compacts msgs in place and reports the savings. Duplicates are
+// found first, against the original text, so a repeated tool output is
+// replaced by a reference rather than compressed twice.
+func Compact(ctx context.Context, msgs []*Message, opts Options) (Report, error) {
+	report := Report{Messages: len(msgs)}
+	seen := make(map[[32]byte]int)
+	var tools, prose []*Segment
+
+	for _, m := range msgs {
+		for _, seg := range m.Segments {
+			report.Segments++
+			tokens := estimateTokens(seg.Text)
+			report.InputTokens += tokens
+
+			if opts.Dedup && len(seg.Text) >= opts.MinDedupLength {
+				key := sha256.Sum256([]byte(strings.Join(strings.Fields(seg.Text), " ")))
+				if first, ok := seen[key]; ok {
+					placeholder := fmt.Sprintf("[duplicate of line %d]", first)
+					if saved := tokens - estimateTokens(placeholder); saved > 0 {
+						seg.replace(placeholder)
+						report.Deduplicated++
+						report.DedupTokensSaved += saved
+						continue
+					}
+				} else {
+					seen[key] = m.Line
+				}
+			}
+
+			switch {
+			case seg.Kind == KindToolOutput && opts.CompressToolOutputs:
+				tools = append(tools, seg)
+			case seg.Kind == KindText && opts.Prune && !strings.Contains(seg.Text, "```"):
+				prose = append(prose, seg)
+			}
+		}
+	}
+
+	copts := compress.DefaultOptions()
+	copts.MinChunkLength = opts.MinToolOutputLength
+	n, saved, err := compressSegments(ctx, compress.NewPlaceholderCompressor(), tools, copts)
+	if err != nil {
+		return report, fmt.Errorf("compressing tool outputs: %w", err)
+	}
+	report.ToolOutputsCompressed, report.ToolTokensSaved = n, saved
+
+	n, saved, err = compressSegments(ctx, compress.NewPruner(), prose, compress.DefaultOptions())
+	if err != nil {
+		return report, fmt.Errorf("pruning: %w", err)
+	}
+	report.Pruned, report.PruneTokensSaved = n, saved
+
+	for _, m := range msgs {
+		for _, seg := range m.Segments {
+			report.OutputTokens += estimateTokens(seg.Text)
+		}
+	}
+	return report, nil
+}
+
+// compressSegments runs c over segs and keeps each result that is
+// shorter. Returns the number of segments changed and tokens saved.
+func compressSegments(ctx context.Context, c compress.Compressor, segs []*Segment, opts compress.Options) (int, int, error) {
+	if len(segs) == 0 {
+		return 0, 0, nil
+	}
+	chunks := make([]types.Chunk, len(segs))
+	for i, seg := range segs {
+		chunks[i] = types.Chunk{ID: fmt.Sprint(i), Text: seg.Text}
+	}
+	out, _, err := c.Compress(ctx, chunks, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	changed, saved := 0, 0
+	for i, chunk := range out {
+		before, after := estimateTokens(segs[i].Text), estimateTokens(chunk.Text)
+		if chunk.Text == segs[i].Text || after >= before {
+			continue
+		}
+		segs[i].replace(chunk.Text)
+		changed++
+		saved += before - after
+	}
+	return changed, saved, nil
+}
+
+// replace sets the segment's text and the field it came from.
+func (s *Segment) replace(text string) {
+	s.Text = text
+	s.set(text)
+}
+
+// estimateTokens approximates token count (1 token ≈ 4 chars).
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
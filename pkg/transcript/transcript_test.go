@@ -0,0 +1,130 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := `{"role":"user","content":"hello"}
+
+{"type":"assistant","uuid":"a1","message":{"role":"assistant","content":[{"type":"text","text":"looking"},{"type":"tool_use","id":"t1","input":{}}]}}
+{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"result"}]}]}
+{"role":"tool","tool_call_id":"c1","content":"output"}
+{"type":"function_call_output","call_id":"c2","output":"more output"}
+{"type":"summary","summary":"kept as is"}
+`
+	msgs, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 6 {
+		t.Fatalf("expected 6 messages, got %d", len(msgs))
+	}
+
+	want := []struct {
+		line int
+		role string
+		kind Kind
+		text string
+	}{
+		{1, "user", KindText, "hello"},
+		{3, "assistant", KindText, "looking"},
+		{4, "user", KindToolOutput, "result"},
+		{5, "tool", KindToolOutput, "output"},
+		{6, "", KindToolOutput, "more output"},
+	}
+	for i, w := range want {
+		m := msgs[i]
+		if m.Line != w.line || m.Role != w.role || len(m.Segments) != 1 {
+			t.Fatalf("message %d: line %d, role %q, %d segments", i, m.Line, m.Role, len(m.Segments))
+		}
+		if s := m.Segments[0]; s.Kind != w.kind || s.Text != w.text {
+			t.Errorf("message %d: segment %+v", i, s)
+		}
+	}
+	if len(msgs[5].Segments) != 0 {
+		t.Errorf("expected no segments in a summary line, got %d", len(msgs[5].Segments))
+	}
+
+	if _, err := Parse(strings.NewReader("{\"role\":\"user\"}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error naming line 2, got %v", err)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	listing := `[` + strings.Repeat(`{"id":1,"name":"file.go","size":1234,"mode":"0644","owner":"dev","modified":"2024-01-01"},`, 20) + `{"id":2}]`
+	prose := "It is important to note that the build is very slow. Basically the cache is cold, and as you know that takes a while."
+	lines := []map[string]interface{}{
+		{"role": "user", "content": "ok"},
+		{"role": "tool", "content": listing, "tool_call_id": "c1", "n": json.Number("12345678901234567890")},
+		{"role": "assistant", "content": prose},
+		{"role": "tool", "content": listing, "tool_call_id": "c2"},
+		{"role": "user", "content": "ok"},
+	}
+	var buf bytes.Buffer
+	for _, l := range lines {
+		b, _ := json.Marshal(l)
+		buf.Write(append(b, '\n'))
+	}
+
+	msgs, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Compact(context.Background(), msgs, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Messages != 5 || report.Deduplicated != 1 || report.ToolOutputsCompressed != 1 || report.Pruned != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if report.OutputTokens >= report.InputTokens || report.ReductionPercent() <= 50 {
+		t.Errorf("expected a large reduction, got %d -> %d", report.InputTokens, report.OutputTokens)
+	}
+	saved := report.DedupTokensSaved + report.ToolTokensSaved + report.PruneTokensSaved
+	if report.InputTokens-report.OutputTokens != saved {
+		t.Errorf("stage savings %d do not add up to %d", saved, report.InputTokens-report.OutputTokens)
+	}
+	if got := msgs[3].Segments[0].Text; got != "[duplicate of line 2]" {
+		t.Errorf("expected a duplicate reference, got %q", got)
+	}
+	if got := msgs[4].Segments[0].Text; got != "ok" {
+		t.Errorf("short messages should not be deduplicated, got %q", got)
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, msgs); err != nil {
+		t.Fatal(err)
+	}
+	outLines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(outLines) != 5 {
+		t.Fatalf("expected 5 lines, got %d", len(outLines))
+	}
+	if !strings.Contains(outLines[1], `"n":12345678901234567890`) || !strings.Contains(outLines[1], `"tool_call_id":"c1"`) {
+		t.Errorf("other fields not preserved: %s", outLines[1])
+	}
+	if strings.Contains(outLines[2], "Basically") || !strings.Contains(outLines[2], "the build is slow") {
+		t.Errorf("prose not pruned: %s", outLines[2])
+	}
+}
+
+func TestCompact_StagesOff(t *testing.T) {
+	text := strings.Repeat("The same long message, repeated word for word. ", 4)
+	input := `{"role":"user","content":"` + text + `"}` + "\n" + `{"role":"user","content":"` + text + `"}` + "\n"
+	msgs, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Compact(context.Background(), msgs, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.InputTokens != report.OutputTokens || msgs[1].Segments[0].Text != text {
+		t.Errorf("expected no changes with every stage off: %+v", report)
+	}
+}
@@ -0,0 +1,481 @@
+// Package tuner tunes the clustering threshold and MMR lambda online from
+// implicit feedback. For each namespace it keeps the settings in use (the
+// incumbent) and a few small perturbations of them (challengers). A share
+// of requests is served with a challenger chosen by Thompson sampling;
+// callers then report how useful each response was, such as the fraction
+// of returned chunks the model cited. Once a challenger has enough
+// feedback and beats the incumbent by a margin, it becomes the incumbent
+// and new challengers are drawn around it.
+package tuner
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	mathrand "math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+// ErrUnknownFeedback is returned for feedback on a request the tuner did
+// not assign, whose feedback window has passed, or that already has
+// feedback.
+var ErrUnknownFeedback = errs.New(errs.ErrConfig, "unknown or expired feedback id")
+
+// Params are the settings the tuner varies.
+type Params struct {
+	Threshold float64
+	Lambda    float64
+}
+
+// Arm names. The incumbent serves most traffic; each challenger moves one
+// setting one step.
+const (
+	ArmIncumbent     = "incumbent"
+	ArmThresholdUp   = "threshold_up"
+	ArmThresholdDown = "threshold_down"
+	ArmLambdaUp      = "lambda_up"
+	ArmLambdaDown    = "lambda_down"
+)
+
+// Bounds the tuner keeps settings within.
+const (
+	MinThreshold = 0.02
+	MaxThreshold = 0.5
+	MinLambda    = 0.05
+	MaxLambda    = 1
+)
+
+// Config controls exploration and adoption.
+type Config struct {
+	// Fraction is the share of requests served with a challenger.
+	Fraction float64
+
+	// ThresholdStep and LambdaStep are how far challengers move each
+	// setting from the incumbent.
+	ThresholdStep float64
+	LambdaStep    float64
+
+	// MinFeedback is how many feedback reports both the incumbent and a
+	// challenger need before they are compared.
+	MinFeedback int
+
+	// MinLift is how much higher a challenger's mean reward must be than
+	// the incumbent's for it to be adopted.
+	MinLift float64
+
+	// FeedbackWindow is how long after a request its feedback is
+	// accepted. MaxPending caps how many requests await feedback; past
+	// it, requests are served with the incumbent and no feedback ID.
+	FeedbackWindow time.Duration
+	MaxPending     int
+
+	// Seed seeds challenger selection. If 0, the current time is used.
+	Seed int64
+}
+
+// DefaultConfig returns conservative defaults: 10% of traffic explores,
+// and a challenger needs 200 reports and 0.03 more reward to be adopted.
+func DefaultConfig() Config {
+	return Config{
+		Fraction:       0.1,
+		ThresholdStep:  0.02,
+		LambdaStep:     0.1,
+		MinFeedback:    200,
+		MinLift:        0.03,
+		FeedbackWindow: 15 * time.Minute,
+		MaxPending:     10000,
+	}
+}
+
+// arm is one candidate setting and the feedback it has collected.
+type arm struct {
+	name     string
+	params   Params
+	served   int
+	feedback int
+	reward   float64
+}
+
+func (a *arm) mean() float64 {
+	if a.feedback == 0 {
+		return 0
+	}
+	return a.reward / float64(a.feedback)
+}
+
+// space is one namespace's arms. arms[0] is the incumbent.
+type space struct {
+	arms      []*arm
+	adoptions int
+}
+
+// pending is a served request awaiting feedback.
+type pending struct {
+	namespace string
+	arm       *arm
+	chunkIDs  []string
+	at        time.Time
+}
+
+// Tuner assigns settings to requests and learns from feedback. It is
+// safe for concurrent use.
+type Tuner struct {
+	cfg  Config
+	base Params
+
+	mu      sync.Mutex
+	enabled bool
+	spaces  map[string]*space
+	pending map[string]*pending
+	rng     *mathrand.Rand
+	now     func() time.Time
+}
+
+// New creates an enabled tuner that starts every namespace from base.
+func New(cfg Config, base Params) *Tuner {
+	def := DefaultConfig()
+	if cfg.Fraction <= 0 || cfg.Fraction > 1 {
+		cfg.Fraction = def.Fraction
+	}
+	if cfg.ThresholdStep <= 0 {
+		cfg.ThresholdStep = def.ThresholdStep
+	}
+	if cfg.LambdaStep <= 0 {
+		cfg.LambdaStep = def.LambdaStep
+	}
+	if cfg.MinFeedback <= 0 {
+		cfg.MinFeedback = def.MinFeedback
+	}
+	if cfg.MinLift < 0 {
+		cfg.MinLift = def.MinLift
+	}
+	if cfg.FeedbackWindow <= 0 {
+		cfg.FeedbackWindow = def.FeedbackWindow
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = def.MaxPending
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Tuner{
+		cfg:     cfg,
+		base:    base,
+		enabled: true,
+		spaces:  make(map[string]*space),
+		pending: make(map[string]*pending),
+		rng:     mathrand.New(mathrand.NewSource(seed)),
+		now:     time.Now,
+	}
+}
+
+// Assignment is the settings chosen for one request.
+type Assignment struct {
+	// ID identifies the request in Feedback. Empty when the tuner is
+	// disabled or too many requests await feedback.
+	ID string
+
+	Arm string
+	Params
+}
+
+// Choose picks the settings for a request to namespace.
+func (t *Tuner) Choose(namespace string) Assignment {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.enabled {
+		return Assignment{Arm: ArmIncumbent, Params: t.base}
+	}
+
+	sp := t.space(namespace)
+	chosen := sp.arms[0]
+	if len(sp.arms) > 1 && t.rng.Float64() < t.cfg.Fraction {
+		chosen = t.sample(sp.arms[1:])
+	}
+	chosen.served++
+
+	out := Assignment{Arm: chosen.name, Params: chosen.params}
+	if len(t.pending) >= t.cfg.MaxPending {
+		t.expire()
+	}
+	if len(t.pending) < t.cfg.MaxPending {
+		out.ID = newID()
+		t.pending[out.ID] = &pending{namespace: namespace, arm: chosen, at: t.now()}
+	}
+	return out
+}
+
+// Served records the chunks returned for id, so Feedback can score which
+// of them were cited.
+func (t *Tuner) Served(id string, chunkIDs []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p, ok := t.pending[id]; ok {
+		p.chunkIDs = chunkIDs
+	}
+}
+
+// Feedback is a report on how useful a response was. Set Reward, a score
+// from 0 to 1, or Cited, the returned chunk IDs the model used; the
+// reward is then the fraction of returned chunks cited.
+type Feedback struct {
+	Reward *float64
+	Cited  []string
+}
+
+// FeedbackResult reports what a feedback report changed.
+type FeedbackResult struct {
+	Namespace string
+	Arm       string
+	Reward    float64
+
+	// Adopted is set when the report made a challenger the incumbent,
+	// whose settings are Incumbent.
+	Adopted   bool
+	Incumbent Params
+}
+
+// Feedback records feedback for the request assigned id. Each request
+// takes one report.
+func (t *Tuner) Feedback(id string, fb Feedback) (FeedbackResult, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.pending[id]
+	if !ok || t.now().Sub(p.at) > t.cfg.FeedbackWindow {
+		delete(t.pending, id)
+		return FeedbackResult{}, ErrUnknownFeedback
+	}
+
+	var reward float64
+	switch {
+	case fb.Reward != nil:
+		if *fb.Reward < 0 || *fb.Reward > 1 || math.IsNaN(*fb.Reward) {
+			return FeedbackResult{}, errs.New(errs.ErrConfig, "reward must be between 0 and 1")
+		}
+		reward = *fb.Reward
+	case len(p.chunkIDs) > 0:
+		cited := make(map[string]bool, len(fb.Cited))
+		for _, id := range fb.Cited {
+			cited[id] = true
+		}
+		n := 0
+		for _, id := range p.chunkIDs {
+			if cited[id] {
+				n++
+			}
+		}
+		reward = float64(n) / float64(len(p.chunkIDs))
+	}
+	delete(t.pending, id)
+
+	p.arm.feedback++
+	p.arm.reward += reward
+	out := FeedbackResult{Namespace: p.namespace, Arm: p.arm.name, Reward: reward}
+
+	sp := t.spaces[p.namespace]
+	if sp == nil || !t.enabled {
+		return out, nil
+	}
+	incumbent := sp.arms[0]
+	out.Incumbent = incumbent.params
+	if incumbent.feedback < t.cfg.MinFeedback {
+		return out, nil
+	}
+	var best *arm
+	for _, a := range sp.arms[1:] {
+		if a.feedback >= t.cfg.MinFeedback && a.mean() >= incumbent.mean()+t.cfg.MinLift && (best == nil || a.mean() > best.mean()) {
+			best = a
+		}
+	}
+	if best != nil {
+		sp.arms = t.arms(best.params)
+		sp.adoptions++
+		out.Adopted = true
+		out.Incumbent = best.params
+	}
+	return out, nil
+}
+
+// SetEnabled turns tuning on or off. Off is a kill switch: every request
+// gets the configured settings until tuning is turned back on, when each
+// namespace resumes from what it had learned.
+func (t *Tuner) SetEnabled(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.enabled = enabled
+	if !enabled {
+		t.pending = make(map[string]*pending)
+	}
+}
+
+// Enabled reports whether tuning is on.
+func (t *Tuner) Enabled() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.enabled
+}
+
+// Reset forgets what was learned for namespace, or for every namespace
+// if namespace is "*".
+func (t *Tuner) Reset(namespace string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if namespace == "*" {
+		t.spaces = make(map[string]*space)
+		t.pending = make(map[string]*pending)
+		return
+	}
+	delete(t.spaces, namespace)
+	for id, p := range t.pending {
+		if p.namespace == namespace {
+			delete(t.pending, id)
+		}
+	}
+}
+
+// ArmStatus describes one arm.
+type ArmStatus struct {
+	Name string
+	Params
+	Served     int
+	Feedback   int
+	MeanReward float64
+}
+
+// NamespaceStatus describes one namespace's arms; Arms[0] is the
+// incumbent.
+type NamespaceStatus struct {
+	Namespace string
+	Arms      []ArmStatus
+	Adoptions int
+}
+
+// Status describes the tuner.
+type Status struct {
+	Enabled    bool
+	Base       Params
+	Pending    int
+	Namespaces []NamespaceStatus
+}
+
+// Status returns the tuner's state, with namespaces sorted by name.
+func (t *Tuner) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := Status{Enabled: t.enabled, Base: t.base, Pending: len(t.pending)}
+	for ns, sp := range t.spaces {
+		st := NamespaceStatus{Namespace: ns, Adoptions: sp.adoptions}
+		for _, a := range sp.arms {
+			st.Arms = append(st.Arms, ArmStatus{
+				Name:       a.name,
+				Params:     a.params,
+				Served:     a.served,
+				Feedback:   a.feedback,
+				MeanReward: a.mean(),
+			})
+		}
+		out.Namespaces = append(out.Namespaces, st)
+	}
+	sort.Slice(out.Namespaces, func(i, j int) bool {
+		return out.Namespaces[i].Namespace < out.Namespaces[j].Namespace
+	})
+	return out
+}
+
+// space returns namespace's arms, creating them around the base settings.
+func (t *Tuner) space(namespace string) *space {
+	sp, ok := t.spaces[namespace]
+	if !ok {
+		sp = &space{arms: t.arms(t.base)}
+		t.spaces[namespace] = sp
+	}
+	return sp
+}
+
+// arms returns an incumbent at p and challengers one step away in each
+// direction, skipping steps the bounds rule out.
+func (t *Tuner) arms(p Params) []*arm {
+	out := []*arm{{name: ArmIncumbent, params: p}}
+	add := func(name string, q Params) {
+		q.Threshold = math.Round(clamp(q.Threshold, MinThreshold, MaxThreshold)*1000) / 1000
+		q.Lambda = math.Round(clamp(q.Lambda, MinLambda, MaxLambda)*1000) / 1000
+		if q != p {
+			out = append(out, &arm{name: name, params: q})
+		}
+	}
+	add(ArmThresholdUp, Params{p.Threshold + t.cfg.ThresholdStep, p.Lambda})
+	add(ArmThresholdDown, Params{p.Threshold - t.cfg.ThresholdStep, p.Lambda})
+	add(ArmLambdaUp, Params{p.Threshold, p.Lambda + t.cfg.LambdaStep})
+	add(ArmLambdaDown, Params{p.Threshold, p.Lambda - t.cfg.LambdaStep})
+	return out
+}
+
+// sample picks the challenger whose reward, drawn from its Beta
+// posterior, is highest, so challengers that do well get more traffic.
+func (t *Tuner) sample(arms []*arm) *arm {
+	var best *arm
+	bestDraw := -1.0
+	for _, a := range arms {
+		draw := t.beta(1+a.reward, 1+float64(a.feedback)-a.reward)
+		if draw > bestDraw {
+			best, bestDraw = a, draw
+		}
+	}
+	return best
+}
+
+// beta draws from Beta(a, b) as a ratio of gamma draws.
+func (t *Tuner) beta(a, b float64) float64 {
+	x, y := t.gamma(a), t.gamma(b)
+	return x / (x + y)
+}
+
+// gamma draws from Gamma(shape, 1) by Marsaglia and Tsang's method.
+func (t *Tuner) gamma(shape float64) float64 {
+	if shape < 1 {
+		return t.gamma(shape+1) * math.Pow(t.rng.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := t.rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := t.rng.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+// expire drops requests whose feedback window has passed.
+func (t *Tuner) expire() {
+	cutoff := t.now().Add(-t.cfg.FeedbackWindow)
+	for id, p := range t.pending {
+		if p.at.Before(cutoff) {
+			delete(t.pending, id)
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Min(math.Max(v, lo), hi)
+}
@@ -0,0 +1,151 @@
+package tuner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Siddhant-K-code/distill/pkg/errs"
+)
+
+func reward(r float64) Feedback { return Feedback{Reward: &r} }
+
+func TestTuner_AdoptsBetterChallenger(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Fraction = 0.5
+	cfg.MinFeedback = 30
+	cfg.Seed = 1
+	base := Params{Threshold: 0.15, Lambda: 0.5}
+	tn := New(cfg, base)
+
+	// A higher threshold earns more reward than anything else
+	var adopted *FeedbackResult
+	for i := 0; i < 5000 && adopted == nil; i++ {
+		a := tn.Choose("docs")
+		if a.ID == "" {
+			t.Fatal("expected a feedback id")
+		}
+		r := 0.5
+		if a.Threshold > base.Threshold {
+			r = 0.8
+		}
+		res, err := tn.Feedback(a.ID, reward(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Adopted {
+			adopted = &res
+		}
+	}
+	if adopted == nil {
+		t.Fatal("no challenger adopted")
+	}
+	if adopted.Arm != ArmThresholdUp || adopted.Incumbent != (Params{Threshold: 0.17, Lambda: 0.5}) {
+		t.Errorf("adopted %s with %+v", adopted.Arm, adopted.Incumbent)
+	}
+
+	st := tn.Status()
+	if len(st.Namespaces) != 1 || st.Namespaces[0].Adoptions != 1 || st.Namespaces[0].Arms[0].Params != adopted.Incumbent {
+		t.Errorf("unexpected status: %+v", st)
+	}
+
+	// Other namespaces are tuned separately
+	tn.Choose("code")
+	if st := tn.Status(); st.Namespaces[0].Namespace != "code" || st.Namespaces[0].Arms[0].Params != base {
+		t.Errorf("new namespace started at %+v, want %+v", st.Namespaces[0], base)
+	}
+}
+
+func TestTuner_KeepsIncumbentWithoutLift(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Fraction = 0.5
+	cfg.MinFeedback = 20
+	cfg.Seed = 2
+	tn := New(cfg, Params{Threshold: 0.15, Lambda: 0.5})
+	for i := 0; i < 2000; i++ {
+		a := tn.Choose("")
+		if res, _ := tn.Feedback(a.ID, reward(0.6)); res.Adopted {
+			t.Fatalf("adopted %s with equal rewards", res.Arm)
+		}
+	}
+}
+
+func TestTuner_CitedFeedback(t *testing.T) {
+	tn := New(DefaultConfig(), Params{Threshold: 0.15, Lambda: 0.5})
+	a := tn.Choose("")
+	tn.Served(a.ID, []string{"a", "b", "c", "d"})
+	res, err := tn.Feedback(a.ID, Feedback{Cited: []string{"b", "d", "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reward != 0.5 {
+		t.Errorf("expected reward 0.5, got %v", res.Reward)
+	}
+
+	// One report per request
+	if _, err := tn.Feedback(a.ID, reward(1)); !errors.Is(err, ErrUnknownFeedback) {
+		t.Errorf("expected ErrUnknownFeedback, got %v", err)
+	}
+
+	b := tn.Choose("")
+	if _, err := tn.Feedback(b.ID, reward(1.5)); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("expected a config error for an out-of-range reward, got %v", err)
+	}
+}
+
+func TestTuner_FeedbackWindow(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxPending = 2
+	tn := New(cfg, Params{Threshold: 0.15, Lambda: 0.5})
+	now := time.Now()
+	tn.now = func() time.Time { return now }
+
+	a := tn.Choose("")
+	tn.Choose("")
+	if c := tn.Choose(""); c.ID != "" {
+		t.Error("expected no feedback id past MaxPending")
+	}
+
+	now = now.Add(cfg.FeedbackWindow + time.Second)
+	if _, err := tn.Feedback(a.ID, reward(1)); !errors.Is(err, ErrUnknownFeedback) {
+		t.Errorf("expected expired feedback to be rejected, got %v", err)
+	}
+	if c := tn.Choose(""); c.ID == "" {
+		t.Error("expected expired requests to free pending slots")
+	}
+}
+
+func TestTuner_KillSwitch(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Fraction = 1
+	base := Params{Threshold: 0.15, Lambda: 0.5}
+	tn := New(cfg, base)
+	pending := tn.Choose("")
+
+	tn.SetEnabled(false)
+	for i := 0; i < 20; i++ {
+		if a := tn.Choose(""); a.Params != base || a.ID != "" {
+			t.Fatalf("disabled tuner assigned %+v", a)
+		}
+	}
+	if _, err := tn.Feedback(pending.ID, reward(1)); !errors.Is(err, ErrUnknownFeedback) {
+		t.Errorf("expected feedback to be dropped when disabled, got %v", err)
+	}
+
+	tn.SetEnabled(true)
+	if a := tn.Choose(""); a.Arm == ArmIncumbent {
+		t.Error("expected exploration to resume")
+	}
+}
+
+func TestTuner_ArmsStayInBounds(t *testing.T) {
+	tn := New(DefaultConfig(), Params{Threshold: MinThreshold, Lambda: MaxLambda})
+	for _, a := range tn.arms(tn.base) {
+		if a.params.Threshold < MinThreshold || a.params.Lambda > MaxLambda {
+			t.Errorf("arm %s out of bounds: %+v", a.name, a.params)
+		}
+		if a.name == ArmThresholdDown || a.name == ArmLambdaUp {
+			t.Errorf("arm %s should be skipped at the bound", a.name)
+		}
+	}
+}
@@ -68,20 +68,162 @@ type RetrievalRequest struct {
 	// QueryEmbedding is the pre-computed query vector (optional if Query is set)
 	QueryEmbedding []float32
 
-	// TopK is the number of results to retrieve
+	// Queries and QueryEmbeddings add query vectors for a multi-vector
+	// query. They are combined with Query/QueryEmbedding as Combine says.
+	Queries         []string
+	QueryEmbeddings [][]float32
+
+	// Combine is how multiple query vectors are combined: "average"
+	// (default) queries with their mean, "fanout" queries with each and
+	// merges the results.
+	Combine string
+
+	// TopK is the number of results to retrieve. Broker.Retrieve
+	// over-fetches TopK matches when it is positive, and the broker's
+	// OverFetchK otherwise.
 	TopK int
 
 	// Namespace is the vector DB namespace/collection
 	Namespace string
 
+	// Namespaces, when set, searches each namespace concurrently instead
+	// of Namespace, and deduplicates across the merged matches while
+	// returning at most each namespace's TopK.
+	Namespaces []NamespaceQuota
+
 	// Filter is metadata filter criteria
 	Filter map[string]interface{}
 
+	// MinScore drops matches scoring below it, on the server where the
+	// backend supports a score threshold. Zero disables it.
+	MinScore float32
+
+	// ExcludeFilter drops matches whose metadata equals any of its
+	// key/value pairs, on the server where the backend supports it.
+	ExcludeFilter map[string]interface{}
+
 	// IncludeEmbeddings requests embeddings in the response
 	IncludeEmbeddings bool
 
 	// IncludeMetadata requests metadata in the response
 	IncludeMetadata bool
+
+	// SessionID scopes the "already sent" filter. Chunks returned to the
+	// same session within the filter TTL are excluded (or marked).
+	SessionID string
+
+	// MarkRepeats keeps already-sent chunks and tags them with
+	// metadata already_sent=true instead of excluding them.
+	MarkRepeats bool
+
+	// Exclude lists chunk IDs or content hashes the caller knows are
+	// irrelevant or harmful. Matching chunks are dropped before clustering.
+	Exclude []string
+
+	// Threshold and Lambda override the broker's clustering threshold and
+	// MMR lambda for this request only. Zero keeps the broker's setting.
+	Threshold float64
+	Lambda    float64
+
+	// Identity is the caller chunk ACLs are checked against, when the
+	// broker enforces them.
+	Identity *Identity
+
+	// DedupHints adds dedup context (cluster size, duplicates removed,
+	// representative reason) to returned chunks' metadata.
+	DedupHints bool
+
+	// Explain lists the transformations applied to each returned chunk,
+	// such as the cluster it was selected from or how much it was
+	// compressed, in its metadata; see contextlab.MetaTransforms.
+	Explain bool
+
+	// Stages turns the broker's optional stages on or off for this
+	// request only.
+	Stages StageToggles
+
+	// Selection overrides the broker's selection strategy for this
+	// request only. Empty keeps the broker's setting.
+	Selection string
+
+	// Model names the downstream model's profile, whose token budget the
+	// result must fit. Empty uses the broker's default profile, if any.
+	Model string
+
+	// Compress overrides the broker's compression settings for this
+	// request only, and turns compression on unless Stages turns it off.
+	Compress *CompressOptions
+
+	// Redact replaces secrets and PII in returned chunks with typed
+	// placeholders, such as "[EMAIL]", recording where each was under
+	// the chunk's "redactions" metadata. It turns redaction on unless
+	// Stages turns it off.
+	Redact bool
+
+	// Mode is "dedupe" (the default) or "passthrough", which returns the
+	// highest-scoring candidates unchanged, annotated with the clusters
+	// deduplication would have put them in; see contextlab.ModePassthrough.
+	Mode string
+
+	// Deadline is the time budget for the whole request. Within it the
+	// broker works best-effort: it fetches fewer chunks when retrieval has
+	// been slow and skips clustering, MMR, reranking, or compression when
+	// too little time is left, rather than miss the deadline. Zero means
+	// no budget.
+	Deadline time.Duration
+}
+
+// StageToggles turns optional broker stages on or off for one request.
+// Nil fields keep the broker's setting.
+type StageToggles struct {
+	Clustering  *bool
+	MMR         *bool
+	Compression *bool
+	Redaction   *bool
+
+	// Scoring applies recency weighting to MMR relevance.
+	Scoring *bool
+
+	// Rerank runs a declared rerank stage.
+	Rerank *bool
+
+	// Classification tags returned chunks with their sensitivity level.
+	Classification *bool
+}
+
+// CompressOptions configures compression of returned chunks. Zero fields
+// keep the default.
+type CompressOptions struct {
+	// Mode is "extractive", "placeholder", "hybrid", or "cluster-merge";
+	// see compress.Mode.
+	Mode string
+
+	// TargetReduction is the share of each chunk's tokens to keep, in
+	// (0, 1].
+	TargetReduction float64
+
+	// PreserveStructure keeps JSON and code structure intact when
+	// possible. Nil means true.
+	PreserveStructure *bool
+
+	// MergeTokens caps each chunk cluster-merge builds. Zero is the
+	// representative's own length.
+	MergeTokens int
+}
+
+// NamespaceQuota is one namespace of a multi-namespace request.
+type NamespaceQuota struct {
+	Name string
+
+	// TopK is the most chunks returned from the namespace. Zero means
+	// an even share of the broker's target.
+	TopK int
+}
+
+// Identity is a caller's user and group memberships.
+type Identity struct {
+	User   string
+	Groups []string
 }
 
 // RetrievalResult holds the output of a vector database query.
@@ -95,6 +237,14 @@ type RetrievalResult struct {
 	// TotalMatches is the total number of matches (may exceed len(Chunks))
 	TotalMatches int
 
+	// Truncated is set when the backend capped TopK below the request
+	// and could not page past the cap.
+	Truncated bool
+
+	// Retries is the number of calls retried after transient backend
+	// failures while serving the query.
+	Retries int
+
 	// Latency is the query execution time
 	Latency time.Duration
 }
@@ -133,6 +283,15 @@ type ClusterResult struct {
 	// ClusterCount is the number of clusters formed
 	ClusterCount int
 
+	// Vetoed is the number of chunk pairs within the merge threshold kept
+	// apart because they name different entities
+	Vetoed int
+
+	// Overflow is how clustering kept its distance matrix under the
+	// configured memory cap: "spill" or "window". Empty when the matrix
+	// fit in memory.
+	Overflow string
+
 	// Latency is the clustering execution time
 	Latency time.Duration
 }
@@ -152,6 +311,9 @@ type BrokerResult struct {
 
 	// Stats contains processing statistics
 	Stats BrokerStats
+
+	// Stages lists the stages that ran, in order
+	Stages []string
 }
 
 // BrokerStats tracks broker operation metrics.
@@ -165,12 +327,123 @@ type BrokerStats struct {
 	// Returned is the number of chunks in final output
 	Returned int
 
+	// Repeated is the number of retrieved chunks already sent to the session
+	Repeated int
+
+	// Excluded is the number of retrieved chunks dropped by the exclude list
+	Excluded int
+
+	// ACLAllowed and ACLDenied count chunks the caller's identity was
+	// allowed and denied; ACLUnlabeled counts denied chunks that had no ACL
+	ACLAllowed   int
+	ACLDenied    int
+	ACLUnlabeled int
+
+	// InjectionFlagged counts chunks the injection filter scored as
+	// prompt injection, InjectionStripped the sentences it removed, and
+	// InjectionBlocked the chunks it dropped
+	InjectionFlagged  int
+	InjectionStripped int
+	InjectionBlocked  int
+
+	// GarbageDropped counts chunks the garbage filter dropped as
+	// boilerplate, and GarbageAllowed those it kept for matching an
+	// allow pattern
+	GarbageDropped int
+	GarbageAllowed int
+
+	// Model is the profile the result was fitted to; Tokens and CostUSD
+	// are the returned chunks' tokens and input price under it.
+	// BudgetDropped counts chunks that did not fit its budget
+	Model         string
+	Tokens        int
+	CostUSD       float64
+	BudgetDropped int
+
+	// Vetoed is the number of near-duplicate pairs kept apart by the
+	// entity veto
+	Vetoed int
+
+	// MatrixOverflow is ClusterResult.Overflow: "spill" or "window" when
+	// clustering exceeded its matrix memory cap
+	MatrixOverflow string
+
+	// Redacted is the number of sensitive spans replaced by a redact
+	// pipeline stage
+	Redacted int
+
+	// CompressInputTokens and CompressOutputTokens are the tokens of the
+	// chunks compression ran on, before and after; Compressed is the
+	// number of chunks it changed
+	CompressInputTokens  int
+	CompressOutputTokens int
+	Compressed           int
+
+	// Merged is the number of representatives cluster-merge compression
+	// rebuilt from their clusters
+	Merged int
+
+	// Reranked is the number of chunks scored by a rerank stage;
+	// RerankFailed is true when the reranker failed and the chunks kept
+	// their retrieval order
+	Reranked     int
+	RerankFailed bool
+
+	// Enriched is the number of chunks an enrichment hook added metadata to
+	Enriched int
+
+	// Denied is the number of chunks dropped by an enrichment hook
+	Denied int
+
+	// EnrichmentFailed is the number of enrichment calls that failed or
+	// timed out
+	EnrichmentFailed int
+
+	// Truncated is true when the vector DB returned fewer chunks than
+	// OverFetchK because of a backend top-k cap
+	Truncated bool
+
+	// RetrievalRetries is the number of vector DB calls retried after
+	// transient failures
+	RetrievalRetries int
+
+	// CacheHit is true when the result was served from the broker's result cache
+	CacheHit bool
+
+	// CacheMiss is true when the result cache was consulted but had no
+	// result for the request
+	CacheMiss bool
+
+	// BestEffort is true when the request's deadline cut the pipeline
+	// short; Notes says how (a reduced over-fetch, skipped stages)
+	BestEffort bool
+	Notes      []string
+
+	// Sparse is true when too few candidates survived retrieval and
+	// filtering to deduplicate, so clustering and MMR were skipped; Notes
+	// carries a hint
+	Sparse bool
+
+	// Passthrough is true when the request skipped deduplication; see
+	// RetrievalRequest.Mode
+	Passthrough bool
+
+	// Sensitivity is the highest sensitivity level among the returned
+	// chunks, such as "pii", when classification ran
+	Sensitivity string
+
 	// RetrievalLatency is time spent querying vector DB
 	RetrievalLatency time.Duration
 
+	// EnrichmentLatency is time spent in enrichment hooks
+	EnrichmentLatency time.Duration
+
 	// ClusteringLatency is time spent clustering
 	ClusteringLatency time.Duration
 
+	// RerankLatency is time spent in rerank stages
+	RerankLatency time.Duration
+
 	// TotalLatency is end-to-end processing time
 	TotalLatency time.Duration
 }
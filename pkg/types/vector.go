@@ -57,12 +57,22 @@ func (v *Vector) Dimension() int {
 // DeduplicationResult holds the output of the deduplication process.
 type DeduplicationResult struct {
 	UniqueVectors    []Vector
+	Removed          []Removal
 	DuplicateCount   int
 	TotalProcessed   int
 	ClusterCount     int
 	ProcessingTimeMs int64
 }
 
+// Removal records a vector dropped as a duplicate and the vector kept in
+// its place.
+type Removal struct {
+	RemovedID string  `json:"removed_id"`
+	KeptID    string  `json:"kept_id"`
+	Distance  float64 `json:"distance"`
+	Cluster   int     `json:"cluster"`
+}
+
 // SavingsPercent calculates the percentage of duplicates found.
 func (r *DeduplicationResult) SavingsPercent() float64 {
 	if r.TotalProcessed == 0 {
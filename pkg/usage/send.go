@@ -0,0 +1,109 @@
+//go:build !no_telemetry
+
+package usage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const compiledIn = true
+
+// Sender pings on an interval until stopped.
+type Sender struct {
+	endpoint string
+	interval time.Duration
+	base     Payload
+	counts   func() Counts
+	client   *http.Client
+
+	last   Counts
+	lastAt time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start starts pinging with base and the counts since the previous ping,
+// or returns nil when Status says pings are off. counts returns totals
+// since the process started. The first ping goes out after an hour, or
+// after the interval if it is shorter, so short-lived runs send nothing.
+func Start(cfg Config, base Payload, counts func() Counts) *Sender {
+	if ok, _ := Status(cfg); !ok {
+		return nil
+	}
+	s := &Sender{
+		endpoint: endpoint(cfg),
+		interval: interval(cfg),
+		base:     base,
+		counts:   counts,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		lastAt:   time.Now(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *Sender) run() {
+	defer close(s.done)
+	timer := time.NewTimer(min(s.interval, time.Hour))
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			s.ping()
+			timer.Reset(s.interval)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// ping sends the counts since the previous ping. Failures are ignored:
+// statistics are never worth a retry or a log line.
+func (s *Sender) ping() {
+	now, counts := time.Now(), s.counts()
+	p := s.base
+	p.PeriodHours = now.Sub(s.lastAt).Hours()
+	p.Requests = counts.Requests - s.last.Requests
+	p.ChunksIn = counts.ChunksIn - s.last.ChunksIn
+	p.ChunksOut = counts.ChunksOut - s.last.ChunksOut
+	s.last, s.lastAt = counts, now
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if resp, err := s.client.Do(req); err == nil {
+		_ = resp.Body.Close()
+	}
+}
+
+// Stop stops pinging, abandoning a ping in flight. It is safe to call on
+// a nil Sender.
+func (s *Sender) Stop() {
+	if s == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
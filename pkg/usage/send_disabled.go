@@ -0,0 +1,14 @@
+//go:build no_telemetry
+
+package usage
+
+const compiledIn = false
+
+// Sender does nothing in builds with the no_telemetry tag.
+type Sender struct{}
+
+// Start returns nil: this build never sends usage statistics.
+func Start(Config, Payload, func() Counts) *Sender { return nil }
+
+// Stop does nothing.
+func (s *Sender) Stop() {}
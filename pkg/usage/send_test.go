@@ -0,0 +1,65 @@
+//go:build !no_telemetry
+
+package usage
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSender_PingsCountsSinceLastPing(t *testing.T) {
+	t.Setenv("DO_NOT_TRACK", "")
+	pings := make(chan map[string]interface{}, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var p map[string]interface{}
+		_ = json.Unmarshal(body, &p)
+		pings <- p
+	}))
+	defer srv.Close()
+
+	var requests atomic.Int64
+	requests.Store(10)
+	s := Start(Config{Enabled: true, Endpoint: srv.URL, Interval: 20 * time.Millisecond},
+		NewPayload("v1.2.3", "serve", "qdrant", "openai"),
+		func() Counts { return Counts{Requests: requests.Load(), ChunksIn: 5 * requests.Load()} })
+	if s == nil {
+		t.Fatal("Start returned nil with pings opted in")
+	}
+	defer s.Stop()
+
+	first := <-pings
+	requests.Add(3)
+	second := <-pings
+
+	if first["requests"] != 10.0 || first["chunks_in"] != 50.0 {
+		t.Errorf("first ping counts = %v, %v; want 10, 50", first["requests"], first["chunks_in"])
+	}
+	if second["requests"] != 3.0 {
+		t.Errorf("second ping requests = %v, want the 3 since the first", second["requests"])
+	}
+	for key := range first {
+		found := false
+		for _, f := range Schema {
+			found = found || f.Name == key
+		}
+		if !found {
+			t.Errorf("ping carries undocumented field %q", key)
+		}
+	}
+	if first["backend"] != "qdrant" || first["version"] != "v1.2.3" {
+		t.Errorf("ping = %v", first)
+	}
+}
+
+func TestStart_OffByDefault(t *testing.T) {
+	if s := Start(Config{Endpoint: "http://127.0.0.1:1"}, Payload{}, func() Counts { return Counts{} }); s != nil {
+		s.Stop()
+		t.Error("Start pinged without opting in")
+	}
+}
@@ -0,0 +1,125 @@
+// Package usage sends anonymized usage statistics, strictly opt-in: a
+// periodic ping with the version, OS and architecture, backend type, and
+// aggregate request and chunk counts. It never carries text, queries,
+// embeddings, keys, hostnames, or any identifier, so pings cannot be tied
+// to a deployment. Maintainers use the totals to decide which backends
+// and providers to prioritize.
+//
+// Pinging is off unless telemetry.usage.enabled is set, is always off
+// when DO_NOT_TRACK is set, and is compiled out entirely by the
+// no_telemetry build tag.
+package usage
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+// SchemaVersion is the version of Payload; it changes whenever a field
+// is added or removed.
+const SchemaVersion = 1
+
+// DefaultEndpoint receives pings when telemetry.usage.endpoint is empty.
+// Builds set it with
+// -ldflags "-X github.com/Siddhant-K-code/distill/pkg/usage.DefaultEndpoint=<url>".
+var DefaultEndpoint = ""
+
+// Payload is the entire body of a ping, sent as JSON.
+type Payload struct {
+	Schema            int     `json:"schema"`
+	Version           string  `json:"version"`
+	OS                string  `json:"os"`
+	Arch              string  `json:"arch"`
+	Command           string  `json:"command"`
+	Backend           string  `json:"backend,omitempty"`
+	EmbeddingProvider string  `json:"embedding_provider,omitempty"`
+	PeriodHours       float64 `json:"period_hours"`
+	Requests          int64   `json:"requests"`
+	ChunksIn          int64   `json:"chunks_in"`
+	ChunksOut         int64   `json:"chunks_out"`
+}
+
+// Field documents one Payload field for distill telemetry show.
+type Field struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// Schema lists every field of Payload, in order.
+var Schema = []Field{
+	{"schema", "int", "payload schema version"},
+	{"version", "string", "distill version"},
+	{"os", "string", "operating system, e.g. linux"},
+	{"arch", "string", "CPU architecture, e.g. arm64"},
+	{"command", "string", "the server running: serve or api"},
+	{"backend", "string", "vector DB type, e.g. qdrant; omitted for api"},
+	{"embedding_provider", "string", "embedding provider type, e.g. openai; omitted when none"},
+	{"period_hours", "float", "hours the counts below cover"},
+	{"requests", "int", "requests served in the period"},
+	{"chunks_in", "int", "chunks received or retrieved in the period"},
+	{"chunks_out", "int", "chunks returned in the period"},
+}
+
+// Counts are cumulative totals since the process started.
+type Counts struct {
+	Requests  int64
+	ChunksIn  int64
+	ChunksOut int64
+}
+
+// NewPayload returns the static part of a ping for command.
+func NewPayload(version, command, backend, embeddingProvider string) Payload {
+	return Payload{
+		Schema:            SchemaVersion,
+		Version:           version,
+		OS:                runtime.GOOS,
+		Arch:              runtime.GOARCH,
+		Command:           command,
+		Backend:           backend,
+		EmbeddingProvider: embeddingProvider,
+	}
+}
+
+// Config holds ping settings.
+type Config struct {
+	// Enabled opts in to pinging.
+	Enabled bool
+
+	// Endpoint receives pings. Default: DefaultEndpoint
+	Endpoint string
+
+	// Interval is the time between pings. Default: 24h
+	Interval time.Duration
+}
+
+// Status reports whether pings will be sent and, if not, why.
+func Status(cfg Config) (bool, string) {
+	switch {
+	case !compiledIn:
+		return false, "this build excludes usage statistics (no_telemetry build tag)"
+	case os.Getenv("DO_NOT_TRACK") != "" && os.Getenv("DO_NOT_TRACK") != "0":
+		return false, "DO_NOT_TRACK is set"
+	case !cfg.Enabled:
+		return false, "not opted in (telemetry.usage.enabled is false)"
+	case endpoint(cfg) == "":
+		return false, "no endpoint configured (telemetry.usage.endpoint)"
+	}
+	return true, fmt.Sprintf("sending to %s every %s", endpoint(cfg), interval(cfg))
+}
+
+func endpoint(cfg Config) string {
+	if cfg.Endpoint != "" {
+		return cfg.Endpoint
+	}
+	return DefaultEndpoint
+}
+
+func interval(cfg Config) time.Duration {
+	if cfg.Interval > 0 {
+		return cfg.Interval
+	}
+	return 24 * time.Hour
+}
@@ -0,0 +1,42 @@
+package usage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchema_MatchesPayload(t *testing.T) {
+	typ := reflect.TypeOf(Payload{})
+	if typ.NumField() != len(Schema) {
+		t.Fatalf("Payload has %d fields, Schema documents %d", typ.NumField(), len(Schema))
+	}
+	for i, f := range Schema {
+		tag, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if tag != f.Name {
+			t.Errorf("field %d: Payload has %q, Schema documents %q", i, tag, f.Name)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	if !compiledIn {
+		t.Skip("built with no_telemetry")
+	}
+	t.Setenv("DO_NOT_TRACK", "")
+
+	if ok, why := Status(Config{}); ok || !strings.Contains(why, "not opted in") {
+		t.Errorf("default: got %v, %q; want off, not opted in", ok, why)
+	}
+	if ok, why := Status(Config{Enabled: true}); ok || !strings.Contains(why, "no endpoint") {
+		t.Errorf("no endpoint: got %v, %q", ok, why)
+	}
+	if ok, _ := Status(Config{Enabled: true, Endpoint: "https://example.com/ping"}); !ok {
+		t.Error("opted in with an endpoint: want on")
+	}
+
+	t.Setenv("DO_NOT_TRACK", "1")
+	if ok, why := Status(Config{Enabled: true, Endpoint: "https://example.com/ping"}); ok || !strings.Contains(why, "DO_NOT_TRACK") {
+		t.Errorf("DO_NOT_TRACK: got %v, %q", ok, why)
+	}
+}
@@ -0,0 +1,304 @@
+// Package vcr records HTTP interactions to JSON cassettes and replays them,
+// so client tests can exercise real API responses without network access
+// or credentials.
+//
+// Tests replay by default. Setting DISTILL_VCR=record sends requests to the
+// live API and rewrites the cassette (see `make fixtures`). Request headers
+// are never stored, and registered secrets are redacted from URLs and
+// bodies before anything is written or matched.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// EnvMode is the environment variable that selects the recording mode.
+const EnvMode = "DISTILL_VCR"
+
+// Redacted replaces secrets in stored interactions.
+const Redacted = "REDACTED"
+
+// Mode controls whether a Recorder talks to the network.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and fails on any
+	// request it has not seen.
+	ModeReplay Mode = iota
+
+	// ModeRecord forwards requests to the live API and stores the
+	// interactions.
+	ModeRecord
+)
+
+// ModeFromEnv returns ModeRecord when DISTILL_VCR=record, otherwise
+// ModeReplay.
+func ModeFromEnv() Mode {
+	if strings.EqualFold(os.Getenv(EnvMode), "record") {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// Recording reports whether DISTILL_VCR selects record mode.
+func Recording() bool {
+	return ModeFromEnv() == ModeRecord
+}
+
+// redactedParams are query parameters whose values are always redacted.
+var redactedParams = []string{"api_key", "apikey", "key", "token", "access_token"}
+
+// Cassette is the on-disk format: interactions in the order they happened.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the stored part of an HTTP request. Headers are deliberately
+// omitted; they carry credentials and are not used for matching.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is the stored part of an HTTP response.
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that records or replays a cassette.
+// It is safe for concurrent use.
+type Recorder struct {
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+	replace  []string // old, new pairs for strings.NewReplacer
+}
+
+// New creates a recorder for the cassette at path. In replay mode the
+// cassette must exist; in record mode it is overwritten by Save.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode, next: http.DefaultTransport}
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("vcr: read cassette (record with %s=record): %w", EnvMode, err)
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("vcr: parse cassette %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Start opens the cassette for a test in the mode selected by DISTILL_VCR,
+// redacts secrets, and saves the cassette when the test passes.
+func Start(tb testing.TB, path string, secrets ...string) *Recorder {
+	tb.Helper()
+	r, err := New(path, ModeFromEnv())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	r.Redact(secrets...)
+	tb.Cleanup(func() {
+		if tb.Failed() {
+			return
+		}
+		if err := r.Save(); err != nil {
+			tb.Error(err)
+		}
+	})
+	return r
+}
+
+// Redact registers secrets to replace with "REDACTED". Empty strings are
+// ignored.
+func (r *Recorder) Redact(secrets ...string) {
+	for _, s := range secrets {
+		r.Replace(s, Redacted)
+	}
+}
+
+// Replace registers a substitution applied to URLs and bodies, for values
+// that vary between accounts (index names, hosts) and must be stable in
+// the cassette.
+func (r *Recorder) Replace(old, replacement string) {
+	if old == "" || old == replacement {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replace = append(r.replace, old, replacement)
+}
+
+// Mode returns the recorder's mode.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Client returns an http.Client that uses the recorder as its transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	stored := Request{
+		Method: req.Method,
+		URL:    r.sanitizeURL(req.URL),
+		Body:   r.sanitize(string(body)),
+	}
+
+	if r.mode == ModeRecord {
+		out := req.Clone(req.Context())
+		if body != nil {
+			out.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		return r.record(out, stored)
+	}
+	return r.replay(req, stored)
+}
+
+func (r *Recorder) record(req *http.Request, stored Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("vcr: read response: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: stored,
+		Response: Response{
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        r.sanitizeLocked(string(data)),
+		},
+	})
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, stored Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || in.Request != stored {
+			continue
+		}
+		r.used[i] = true
+
+		header := make(http.Header)
+		if in.Response.ContentType != "" {
+			header.Set("Content-Type", in.Response.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("vcr: no recorded interaction for %s %s in %s", stored.Method, stored.URL, r.path)
+}
+
+// Save writes the cassette in record mode. It is a no-op when replaying.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("vcr: encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("vcr: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("vcr: write cassette: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) sanitize(s string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sanitizeLocked(s)
+}
+
+func (r *Recorder) sanitizeLocked(s string) string {
+	if len(r.replace) == 0 || s == "" {
+		return s
+	}
+	return strings.NewReplacer(r.replace...).Replace(s)
+}
+
+func (r *Recorder) sanitizeURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	if q := clean.Query(); len(q) > 0 {
+		for _, name := range redactedParams {
+			if q.Has(name) {
+				q.Set(name, Redacted)
+			}
+		}
+		clean.RawQuery = q.Encode()
+	}
+	return r.sanitize(clean.String())
+}
+
+// readBody reads and closes the request body.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("vcr: read request: %w", err)
+	}
+	return data, nil
+}
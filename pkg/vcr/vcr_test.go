@@ -0,0 +1,148 @@
+package vcr
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const secret = "sk-live-123456"
+
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"call":%d,"echo":%q,"auth":%q}`, calls, body, r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func post(t *testing.T, c *http.Client, url, body string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+secret)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestRecordReplay(t *testing.T) {
+	upstream := newUpstream(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	url := upstream.URL + "/v1/embed?key=" + secret
+
+	rec, err := New(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Redact(secret)
+	_, first := post(t, rec.Client(), url, "token "+secret)
+	_, second := post(t, rec.Client(), url, "token "+secret)
+	if !strings.Contains(first, secret) {
+		t.Fatalf("record mode should pass live responses through unchanged: %s", first)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), secret) {
+		t.Fatalf("cassette leaks credentials:\n%s", data)
+	}
+
+	upstream.Close()
+	play, err := New(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	play.Redact(secret)
+	status, got := post(t, play.Client(), url, "token "+secret)
+	if status != http.StatusOK || got != strings.ReplaceAll(first, secret, Redacted) {
+		t.Errorf("unexpected first replay %d %s", status, got)
+	}
+	_, got = post(t, play.Client(), url, "token "+secret)
+	if got != strings.ReplaceAll(second, secret, Redacted) {
+		t.Errorf("expected interactions replayed in order, got %s", got)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader("token "+secret))
+	if _, err := play.Client().Do(req); err == nil {
+		t.Error("expected an error once recorded interactions are used up")
+	}
+}
+
+func TestReplay_Mismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette := `{"interactions":[{"request":{"method":"GET","url":"https://api.example.com/indexes/docs"},"response":{"status":404,"body":"not found"}}]}`
+	if err := os.WriteFile(path, []byte(cassette), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	play, err := New(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := play.Client().Get("https://api.example.com/indexes/other"); err == nil {
+		t.Error("expected unrecorded request to fail")
+	}
+	resp, err := play.Client().Get("https://api.example.com/indexes/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected recorded status 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette := `{"interactions":[{"request":{"method":"GET","url":"https://api.example.com/indexes/fixture"},"response":{"status":200,"body":"{}"}}]}`
+	if err := os.WriteFile(path, []byte(cassette), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	play, _ := New(path, ModeReplay)
+	play.Replace("my-private-index", "fixture")
+
+	resp, err := play.Client().Get("https://api.example.com/indexes/my-private-index")
+	if err != nil {
+		t.Fatalf("expected replacement applied before matching: %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestNew_MissingCassette(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing.json"), ModeReplay); err == nil {
+		t.Error("expected error for missing cassette in replay mode")
+	}
+	if _, err := New(filepath.Join(t.TempDir(), "missing.json"), ModeRecord); err != nil {
+		t.Errorf("record mode should not need an existing cassette: %v", err)
+	}
+}
+
+func TestModeFromEnv(t *testing.T) {
+	t.Setenv(EnvMode, "")
+	if ModeFromEnv() != ModeReplay {
+		t.Error("expected replay by default")
+	}
+	t.Setenv(EnvMode, "record")
+	if !Recording() {
+		t.Error("expected record mode")
+	}
+}